@@ -0,0 +1,633 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LetsEncryptURL is the directory URL of the production ACME server of
+// Let's Encrypt.
+const LetsEncryptURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+const (
+	// defaultPollInterval is how long the client waits between polls of
+	// pending authorizations and orders when the server does not say.
+	defaultPollInterval = time.Second * 2
+
+	// maxPollDuration is the longest the client waits for an
+	// authorization or order to leave the pending state.
+	maxPollDuration = time.Minute * 5
+
+	// maxResponseSize is the maximum size of the responses the client
+	// reads from the server.
+	maxResponseSize = 1 << 20
+
+	// challengePath is the path prefix under which http-01 challenges
+	// are served.
+	challengePath = "/.well-known/acme-challenge/"
+
+	// errBadNonce is the problem type returned by the server when a
+	// request has an invalid or expired nonce.  Such requests are retried
+	// once with a fresh nonce.
+	errBadNonce = "urn:ietf:params:acme:error:badNonce"
+)
+
+var (
+	// ErrNotRegistered describes an error where a certificate is ordered
+	// before the account is registered.
+	ErrNotRegistered = errors.New("acme account is not registered")
+
+	// ErrNoHTTP01Challenge describes an error where the server does not
+	// offer an http-01 challenge to prove control of a name.
+	ErrNoHTTP01Challenge = errors.New("acme server offers no http-01 " +
+		"challenge")
+)
+
+// Problem is an error returned by an ACME server.
+type Problem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+}
+
+// Error satisfies the error interface and prints human-readable errors.
+func (p *Problem) Error() string {
+	return fmt.Sprintf("acme: %s (%d): %s", p.Type, p.Status, p.Detail)
+}
+
+// Responder makes the key authorizations of http-01 challenges available to
+// the ACME server.  Present is called before the server is asked to validate a
+// challenge and CleanUp once the challenge is no longer pending.
+type Responder interface {
+	Present(token, keyAuth string) error
+	CleanUp(token string)
+}
+
+// HTTP01Responder is a Responder which serves the key authorizations of the
+// pending http-01 challenges under /.well-known/acme-challenge/.  It must be
+// reachable on port 80 of every name a certificate is ordered for.
+type HTTP01Responder struct {
+	mtx      sync.RWMutex
+	keyAuths map[string]string
+}
+
+// Ensure HTTP01Responder satisfies the Responder and http.Handler interfaces.
+var _ Responder = (*HTTP01Responder)(nil)
+var _ http.Handler = (*HTTP01Responder)(nil)
+
+// NewHTTP01Responder returns an HTTP01Responder without pending challenges.
+func NewHTTP01Responder() *HTTP01Responder {
+	return &HTTP01Responder{keyAuths: make(map[string]string)}
+}
+
+// Present serves the passed key authorization for the challenge with the
+// passed token.
+//
+// This function is safe for concurrent access.
+func (r *HTTP01Responder) Present(token, keyAuth string) error {
+	r.mtx.Lock()
+	r.keyAuths[token] = keyAuth
+	r.mtx.Unlock()
+	return nil
+}
+
+// CleanUp stops serving the key authorization of the challenge with the
+// passed token.
+//
+// This function is safe for concurrent access.
+func (r *HTTP01Responder) CleanUp(token string) {
+	r.mtx.Lock()
+	delete(r.keyAuths, token)
+	r.mtx.Unlock()
+}
+
+// ServeHTTP serves the key authorization of the pending challenge named by the
+// request path.
+//
+// This function is safe for concurrent access.
+func (r *HTTP01Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, challengePath) {
+		http.NotFound(w, req)
+		return
+	}
+	r.mtx.RLock()
+	keyAuth, ok := r.keyAuths[strings.TrimPrefix(req.URL.Path, challengePath)]
+	r.mtx.RUnlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write([]byte(keyAuth))
+}
+
+// directory holds the URLs of the resources of an ACME server.
+type directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+// identifier is a name a certificate is ordered for.
+type identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// order is a request for a certificate.
+type order struct {
+	Status         string       `json:"status"`
+	Identifiers    []identifier `json:"identifiers"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate,omitempty"`
+	Error          *Problem     `json:"error,omitempty"`
+}
+
+// challenge is a way of proving control of a name.
+type challenge struct {
+	Type   string   `json:"type"`
+	URL    string   `json:"url"`
+	Token  string   `json:"token"`
+	Status string   `json:"status"`
+	Error  *Problem `json:"error,omitempty"`
+}
+
+// authorization is the proof of control of a name an order requires.
+type authorization struct {
+	Status     string      `json:"status"`
+	Identifier identifier  `json:"identifier"`
+	Challenges []challenge `json:"challenges"`
+}
+
+// jwk is the JSON web key of an ECDSA P-256 public key.  The members are in
+// lexicographic order so its encoding can be hashed for the thumbprint.
+type jwk struct {
+	Crv string `json:"crv"`
+	Kty string `json:"kty"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Client is an ACME client which obtains certificates for an account.
+type Client struct {
+	// DirectoryURL is the URL of the directory of the ACME server.
+	DirectoryURL string
+
+	// Key is the ECDSA P-256 private key of the account.
+	Key *ecdsa.PrivateKey
+
+	// HTTPClient is used to make requests to the ACME server.  The
+	// http.DefaultClient is used when it is nil.
+	HTTPClient *http.Client
+
+	// PollInterval is how long the client waits between polls of pending
+	// authorizations and orders when the server does not say.  Two
+	// seconds are used when it is zero.
+	PollInterval time.Duration
+
+	mtx    sync.Mutex
+	dir    *directory
+	kid    string
+	nonces []string
+}
+
+// httpClient returns the HTTP client used to make requests to the server.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// directory returns the directory of the server, fetching it the first time.
+// The client lock must be held.
+func (c *Client) directory() (*directory, error) {
+	if c.dir != nil {
+		return c.dir, nil
+	}
+	resp, err := c.httpClient().Get(c.DirectoryURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	var dir directory
+	err = json.NewDecoder(io.LimitReader(resp.Body,
+		maxResponseSize)).Decode(&dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid acme directory: %v", err)
+	}
+	if dir.NewNonce == "" || dir.NewAccount == "" || dir.NewOrder == "" {
+		return nil, errors.New("incomplete acme directory")
+	}
+	c.dir = &dir
+	return c.dir, nil
+}
+
+// nonce returns a nonce for the next request, either one returned with a
+// previous response or a new one.  The client lock must be held.
+func (c *Client) nonce() (string, error) {
+	if n := len(c.nonces); n > 0 {
+		nonce := c.nonces[n-1]
+		c.nonces = c.nonces[:n-1]
+		return nonce, nil
+	}
+	dir, err := c.directory()
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient().Head(dir.NewNonce)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New("acme server returned no nonce")
+	}
+	return nonce, nil
+}
+
+// jwk returns the JSON web key of the account key.
+func (c *Client) jwk() *jwk {
+	size := (c.Key.Curve.Params().BitSize + 7) / 8
+	return &jwk{
+		Crv: c.Key.Curve.Params().Name,
+		Kty: "EC",
+		X:   encode(padBytes(c.Key.X.Bytes(), size)),
+		Y:   encode(padBytes(c.Key.Y.Bytes(), size)),
+	}
+}
+
+// keyAuthorization returns the key authorization of the challenge with the
+// passed token, which is the token followed by the thumbprint of the account
+// key as defined by RFC 7638.
+func (c *Client) keyAuthorization(token string) (string, error) {
+	encoded, err := json.Marshal(c.jwk())
+	if err != nil {
+		return "", err
+	}
+	thumbprint := sha256.Sum256(encoded)
+	return token + "." + encode(thumbprint[:]), nil
+}
+
+// post sends the passed payload to the passed URL in a request signed with the
+// account key and decodes the response into out unless it is nil.  A nil
+// payload makes a POST-as-GET request.  The account is identified by its key
+// when useJWK is set and by its URL otherwise.  Requests rejected for a bad
+// nonce are retried once.  The client lock must be held.
+func (c *Client) post(url string, payload, out interface{}, useJWK bool) (*http.Response, []byte, error) {
+	var encodedPayload string
+	if payload != nil {
+		serialized, err := json.Marshal(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		encodedPayload = encode(serialized)
+	}
+
+	for retry := 0; ; retry++ {
+		nonce, err := c.nonce()
+		if err != nil {
+			return nil, nil, err
+		}
+		protected := map[string]interface{}{
+			"alg":   "ES256",
+			"nonce": nonce,
+			"url":   url,
+		}
+		if useJWK {
+			protected["jwk"] = c.jwk()
+		} else {
+			protected["kid"] = c.kid
+		}
+		body, err := c.sign(protected, encodedPayload)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, err := c.httpClient().Post(url, "application/jose+json",
+			bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body,
+			maxResponseSize))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		if nonce := resp.Header.Get("Replay-Nonce"); nonce != "" {
+			c.nonces = append(c.nonces, nonce)
+		}
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			problem := parseProblem(resp, respBody)
+			if problem.Type == errBadNonce && retry == 0 {
+				continue
+			}
+			return nil, nil, problem
+		}
+		if out != nil {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return nil, nil, fmt.Errorf("invalid acme "+
+					"response from %s: %v", url, err)
+			}
+		}
+		return resp, respBody, nil
+	}
+}
+
+// sign returns the flattened JSON web signature of the passed protected header
+// and encoded payload made with the account key.
+func (c *Client) sign(protected map[string]interface{}, payload string) ([]byte, error) {
+	serialized, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+	encodedProtected := encode(serialized)
+	hash := sha256.Sum256([]byte(encodedProtected + "." + payload))
+	r, s, err := ecdsa.Sign(rand.Reader, c.Key, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	size := (c.Key.Curve.Params().BitSize + 7) / 8
+	sig := append(padBytes(r.Bytes(), size), padBytes(s.Bytes(), size)...)
+	return json.Marshal(map[string]string{
+		"protected": encodedProtected,
+		"payload":   payload,
+		"signature": encode(sig),
+	})
+}
+
+// Register creates the account of the client key with the passed contact URLs,
+// such as "mailto:admin@example.com", or looks it up when it already exists.
+// It agrees to the terms of service of the server.
+//
+// This function is safe for concurrent access.
+func (c *Client) Register(contact []string) error {
+	if c.Key == nil || c.Key.Curve != elliptic.P256() {
+		return errors.New("acme account key must be an ECDSA P-256 key")
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	dir, err := c.directory()
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{
+		"termsOfServiceAgreed": true,
+	}
+	if len(contact) > 0 {
+		payload["contact"] = contact
+	}
+	resp, _, err := c.post(dir.NewAccount, payload, nil, true)
+	if err != nil {
+		return err
+	}
+	kid := resp.Header.Get("Location")
+	if kid == "" {
+		return errors.New("acme server returned no account URL")
+	}
+	c.kid = kid
+	return nil
+}
+
+// ObtainCertificate orders a certificate for the passed DNS names and the
+// public key of the passed private key, proves control of the names with
+// http-01 challenges presented by the passed responder, and returns the
+// PEM-encoded certificate chain the server issued.  The first name is used as
+// the common name of the certificate.
+//
+// This function is safe for concurrent access.
+func (c *Client) ObtainCertificate(names []string, key crypto.Signer, responder Responder) ([]byte, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no names to obtain a certificate for")
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.kid == "" {
+		return nil, ErrNotRegistered
+	}
+	dir, err := c.directory()
+	if err != nil {
+		return nil, err
+	}
+
+	identifiers := make([]identifier, 0, len(names))
+	for _, name := range names {
+		identifiers = append(identifiers, identifier{
+			Type:  "dns",
+			Value: name,
+		})
+	}
+	var o order
+	payload := map[string]interface{}{"identifiers": identifiers}
+	resp, _, err := c.post(dir.NewOrder, payload, &o, false)
+	if err != nil {
+		return nil, err
+	}
+	orderURL := resp.Header.Get("Location")
+	if orderURL == "" {
+		return nil, errors.New("acme server returned no order URL")
+	}
+
+	for _, authzURL := range o.Authorizations {
+		if err := c.authorize(authzURL, responder); err != nil {
+			return nil, err
+		}
+	}
+
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: names[0]},
+		DNSNames: names,
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+	payload = map[string]interface{}{"csr": encode(csr)}
+	if _, _, err := c.post(o.Finalize, payload, &o, false); err != nil {
+		return nil, err
+	}
+	err = c.poll(orderURL, &o, func() (bool, error) {
+		switch o.Status {
+		case "valid":
+			return true, nil
+		case "pending", "ready", "processing":
+			return false, nil
+		}
+		return false, orderError(&o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if o.Certificate == "" {
+		return nil, errors.New("acme server returned no certificate URL")
+	}
+
+	_, chain, err := c.post(o.Certificate, nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// authorize proves control of the name of the authorization with the passed
+// URL using its http-01 challenge unless it is already valid.  The client lock
+// must be held.
+func (c *Client) authorize(authzURL string, responder Responder) error {
+	var authz authorization
+	if _, _, err := c.post(authzURL, nil, &authz, false); err != nil {
+		return err
+	}
+	if authz.Status == "valid" {
+		return nil
+	}
+
+	var chal *challenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == "http-01" {
+			chal = &authz.Challenges[i]
+			break
+		}
+	}
+	if chal == nil {
+		return ErrNoHTTP01Challenge
+	}
+	keyAuth, err := c.keyAuthorization(chal.Token)
+	if err != nil {
+		return err
+	}
+	if err := responder.Present(chal.Token, keyAuth); err != nil {
+		return err
+	}
+	defer responder.CleanUp(chal.Token)
+
+	// An empty object tells the server the challenge is ready to be
+	// validated.
+	if _, _, err := c.post(chal.URL, struct{}{}, nil, false); err != nil {
+		return err
+	}
+	return c.poll(authzURL, &authz, func() (bool, error) {
+		switch authz.Status {
+		case "valid":
+			return true, nil
+		case "pending":
+			return false, nil
+		}
+		for _, chal := range authz.Challenges {
+			if chal.Error != nil {
+				return false, fmt.Errorf("acme authorization "+
+					"of %s failed: %v", authz.Identifier.Value,
+					chal.Error)
+			}
+		}
+		return false, fmt.Errorf("acme authorization of %s is %s",
+			authz.Identifier.Value, authz.Status)
+	})
+}
+
+// poll fetches the resource at the passed URL into out until the passed done
+// function reports it is done or fails.  The client lock must be held.
+func (c *Client) poll(url string, out interface{}, done func() (bool, error)) error {
+	deadline := time.Now().Add(maxPollDuration)
+	for {
+		resp, _, err := c.post(url, nil, out, false)
+		if err != nil {
+			return err
+		}
+		ok, err := done()
+		if err != nil || ok {
+			return err
+		}
+
+		wait := c.PollInterval
+		if wait == 0 {
+			wait = defaultPollInterval
+		}
+		retryAfter := resp.Header.Get("Retry-After")
+		if secs, err := strconv.Atoi(retryAfter); err == nil && secs > 0 {
+			wait = time.Duration(secs) * time.Second
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("timed out waiting for acme resource %s",
+				url)
+		}
+		time.Sleep(wait)
+	}
+}
+
+// orderError returns the error describing why the passed order failed.
+func orderError(o *order) error {
+	if o.Error != nil {
+		return o.Error
+	}
+	return fmt.Errorf("acme order is %s", o.Status)
+}
+
+// responseError returns the error described by the passed failed response.
+func responseError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	return parseProblem(resp, body)
+}
+
+// parseProblem returns the problem held by the passed body of a failed
+// response, or one describing its status when the body is not a problem
+// document.
+func parseProblem(resp *http.Response, body []byte) *Problem {
+	var problem Problem
+	if err := json.Unmarshal(body, &problem); err != nil || problem.Type == "" {
+		problem = Problem{
+			Type:   "about:blank",
+			Detail: http.StatusText(resp.StatusCode),
+		}
+	}
+	problem.Status = resp.StatusCode
+	return &problem
+}
+
+// encode returns the unpadded base64url encoding of the passed bytes used
+// throughout the protocol.
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// padBytes left-pads the passed big-endian integer with zeros to the passed
+// size.
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
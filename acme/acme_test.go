@@ -0,0 +1,580 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package acme_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/acme"
+)
+
+// testJWK is the JSON web key of an ECDSA public key as sent by the client.
+type testJWK struct {
+	Crv string `json:"crv"`
+	Kty string `json:"kty"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fakeAuthz is an authorization of the fake ACME server.
+type fakeAuthz struct {
+	name   string
+	token  string
+	status string
+	err    string
+}
+
+// fakeServer is an ACME server which issues certificates signed by a test
+// certificate authority.  It verifies the signatures and nonces of all
+// requests and validates http-01 challenges by requesting the key
+// authorizations from the responder passed to it instead of the names.
+type fakeServer struct {
+	t         *testing.T
+	srv       *httptest.Server
+	responder http.Handler
+	caKey     *ecdsa.PrivateKey
+	caCert    *x509.Certificate
+
+	mtx         sync.Mutex
+	nextNonce   int
+	nonces      map[string]struct{}
+	accounts    map[string]*testJWK
+	authzs      []*fakeAuthz
+	names       []string
+	orderStatus string
+	cert        []byte
+	rejectNonce bool
+}
+
+// newFakeServer starts a fake ACME server which validates challenges with the
+// passed responder.
+func newFakeServer(t *testing.T, responder http.Handler) *fakeServer {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate: unexpected error: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: unexpected error: %v", err)
+	}
+
+	s := &fakeServer{
+		t:         t,
+		responder: responder,
+		caKey:     caKey,
+		caCert:    caCert,
+		nonces:    make(map[string]struct{}),
+		accounts:  make(map[string]*testJWK),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// problem replies with an ACME problem document.
+func (s *fakeServer) problem(w http.ResponseWriter, status int, typ, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&acme.Problem{
+		Type:   "urn:ietf:params:acme:error:" + typ,
+		Detail: detail,
+		Status: status,
+	})
+}
+
+// reply replies with the passed object encoded as JSON.
+func (s *fakeServer) reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// verify checks the signature, nonce and URL of the passed signed request and
+// returns its payload and the JSON web key of the account which signed it.
+// The server lock must be held.
+func (s *fakeServer) verify(r *http.Request) ([]byte, string, *testJWK, error) {
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		return nil, "", nil, err
+	}
+	serialized, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	var protected struct {
+		Alg   string   `json:"alg"`
+		Nonce string   `json:"nonce"`
+		URL   string   `json:"url"`
+		JWK   *testJWK `json:"jwk"`
+		KID   string   `json:"kid"`
+	}
+	if err := json.Unmarshal(serialized, &protected); err != nil {
+		return nil, "", nil, err
+	}
+	if protected.Alg != "ES256" {
+		return nil, "", nil, fmt.Errorf("unexpected alg %q", protected.Alg)
+	}
+	if _, ok := s.nonces[protected.Nonce]; !ok {
+		return nil, "", nil, fmt.Errorf("unknown nonce %q", protected.Nonce)
+	}
+	delete(s.nonces, protected.Nonce)
+	if protected.URL != s.srv.URL+r.URL.Path {
+		return nil, "", nil, fmt.Errorf("unexpected url %q", protected.URL)
+	}
+
+	key := protected.JWK
+	if (key == nil) == (protected.KID == "") {
+		return nil, "", nil, fmt.Errorf("need exactly one of jwk and kid")
+	}
+	if key == nil {
+		key = s.accounts[protected.KID]
+		if key == nil {
+			return nil, "", nil, fmt.Errorf("unknown kid %q",
+				protected.KID)
+		}
+	}
+	x, _ := base64.RawURLEncoding.DecodeString(key.X)
+	y, _ := base64.RawURLEncoding.DecodeString(key.Y)
+	pubKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if err != nil || len(sig) != 64 {
+		return nil, "", nil, fmt.Errorf("malformed signature")
+	}
+	hash := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	if !ecdsa.Verify(pubKey, hash[:], new(big.Int).SetBytes(sig[:32]),
+		new(big.Int).SetBytes(sig[32:])) {
+
+		return nil, "", nil, fmt.Errorf("invalid signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return payload, protected.KID, key, nil
+}
+
+// authzJSON returns the JSON representation of the authorization with the
+// passed index.
+func (s *fakeServer) authzJSON(i int) interface{} {
+	authz := s.authzs[i]
+	chal := map[string]interface{}{
+		"type":   "http-01",
+		"url":    fmt.Sprintf("%s/chal/%d", s.srv.URL, i),
+		"token":  authz.token,
+		"status": authz.status,
+	}
+	if authz.err != "" {
+		chal["error"] = &acme.Problem{
+			Type:   "urn:ietf:params:acme:error:unauthorized",
+			Detail: authz.err,
+			Status: http.StatusForbidden,
+		}
+	}
+	return map[string]interface{}{
+		"status":     authz.status,
+		"identifier": map[string]string{"type": "dns", "value": authz.name},
+		"challenges": []interface{}{
+			map[string]interface{}{
+				"type":  "dns-01",
+				"url":   fmt.Sprintf("%s/dns/%d", s.srv.URL, i),
+				"token": "unused",
+			},
+			chal,
+		},
+	}
+}
+
+// orderJSON returns the JSON representation of the order.
+func (s *fakeServer) orderJSON() interface{} {
+	authzs := make([]string, len(s.authzs))
+	for i := range s.authzs {
+		authzs[i] = fmt.Sprintf("%s/authz/%d", s.srv.URL, i)
+	}
+	order := map[string]interface{}{
+		"status":         s.orderStatus,
+		"authorizations": authzs,
+		"finalize":       s.srv.URL + "/finalize",
+	}
+	if s.cert != nil {
+		order["certificate"] = s.srv.URL + "/cert"
+	}
+	return order
+}
+
+// thumbprint returns the thumbprint of the passed JSON web key.
+func thumbprint(key *testJWK) string {
+	encoded, _ := json.Marshal(key)
+	hash := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// serveHTTP serves the requests of the fake ACME server.
+func (s *fakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.nextNonce++
+	nonce := fmt.Sprintf("nonce-%d", s.nextNonce)
+	s.nonces[nonce] = struct{}{}
+	w.Header().Set("Replay-Nonce", nonce)
+
+	switch r.URL.Path {
+	case "/dir":
+		s.reply(w, http.StatusOK, map[string]string{
+			"newNonce":   s.srv.URL + "/nonce",
+			"newAccount": s.srv.URL + "/acct",
+			"newOrder":   s.srv.URL + "/order",
+		})
+		return
+
+	case "/nonce":
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	payload, kid, key, err := s.verify(r)
+	if err != nil {
+		s.problem(w, http.StatusBadRequest, "malformed", err.Error())
+		return
+	}
+	if r.URL.Path != "/acct" && kid == "" {
+		s.problem(w, http.StatusBadRequest, "malformed", "need kid")
+		return
+	}
+
+	var i int
+	switch {
+	case r.URL.Path == "/acct":
+		kid := s.srv.URL + "/acct/1"
+		s.accounts[kid] = key
+		w.Header().Set("Location", kid)
+		s.reply(w, http.StatusCreated, map[string]string{
+			"status": "valid",
+		})
+
+	case r.URL.Path == "/order":
+		if s.rejectNonce {
+			s.rejectNonce = false
+			s.problem(w, http.StatusBadRequest, "badNonce",
+				"nonce expired")
+			return
+		}
+		var req struct {
+			Identifiers []struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+			} `json:"identifiers"`
+		}
+		if err := json.Unmarshal(payload, &req); err != nil {
+			s.problem(w, http.StatusBadRequest, "malformed",
+				err.Error())
+			return
+		}
+		s.names = nil
+		s.authzs = nil
+		for j, id := range req.Identifiers {
+			s.names = append(s.names, id.Value)
+			s.authzs = append(s.authzs, &fakeAuthz{
+				name:   id.Value,
+				token:  fmt.Sprintf("token-%d", j),
+				status: "pending",
+			})
+		}
+		s.orderStatus = "pending"
+		w.Header().Set("Location", s.srv.URL+"/order/1")
+		s.reply(w, http.StatusCreated, s.orderJSON())
+
+	case sscanf(r.URL.Path, "/authz/%d", &i) && i < len(s.authzs):
+		s.reply(w, http.StatusOK, s.authzJSON(i))
+
+	case sscanf(r.URL.Path, "/chal/%d", &i) && i < len(s.authzs):
+		if string(payload) != "{}" {
+			s.problem(w, http.StatusBadRequest, "malformed",
+				"unexpected challenge payload")
+			return
+		}
+		authz := s.authzs[i]
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://"+authz.name+
+			"/.well-known/acme-challenge/"+authz.token, nil)
+		s.responder.ServeHTTP(rec, req)
+		want := authz.token + "." + thumbprint(key)
+		if rec.Code == http.StatusOK && rec.Body.String() == want {
+			authz.status = "valid"
+		} else {
+			authz.status = "invalid"
+			authz.err = "wrong key authorization"
+		}
+		s.reply(w, http.StatusOK, map[string]string{"status": "processing"})
+
+	case r.URL.Path == "/order/1":
+		// Orders which are processing become valid once polled.
+		if s.orderStatus == "processing" {
+			s.orderStatus = "valid"
+		}
+		s.reply(w, http.StatusOK, s.orderJSON())
+
+	case r.URL.Path == "/finalize":
+		for _, authz := range s.authzs {
+			if authz.status != "valid" {
+				s.problem(w, http.StatusForbidden,
+					"orderNotReady", "unauthorized")
+				return
+			}
+		}
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil || csr.CheckSignature() != nil ||
+			strings.Join(csr.DNSNames, ",") != strings.Join(s.names, ",") {
+
+			s.problem(w, http.StatusBadRequest, "badCSR",
+				"invalid csr")
+			return
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		leaf, err := x509.CreateCertificate(rand.Reader, template,
+			s.caCert, csr.PublicKey, s.caKey)
+		if err != nil {
+			s.t.Errorf("CreateCertificate: unexpected error: %v", err)
+			s.problem(w, http.StatusInternalServerError,
+				"serverInternal", err.Error())
+			return
+		}
+		s.cert = append(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: leaf,
+		}), pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: s.caCert.Raw,
+		})...)
+		s.orderStatus = "processing"
+		s.reply(w, http.StatusOK, s.orderJSON())
+
+	case r.URL.Path == "/cert" && s.cert != nil:
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(s.cert)
+
+	default:
+		s.problem(w, http.StatusNotFound, "malformed", "not found")
+	}
+}
+
+// sscanf returns whether the passed string matches the passed format.
+func sscanf(str, format string, args ...interface{}) bool {
+	n, err := fmt.Sscanf(str, format, args...)
+	return err == nil && n == len(args)
+}
+
+// wrongResponder is a responder which serves wrong key authorizations.
+type wrongResponder struct {
+	*acme.HTTP01Responder
+}
+
+// Present serves a wrong key authorization for the passed token.
+func (r wrongResponder) Present(token, keyAuth string) error {
+	return r.HTTP01Responder.Present(token, keyAuth+"x")
+}
+
+// TestObtainCertificate ensures certificates are obtained by registering an
+// account, proving control of the names with http-01 challenges and
+// finalizing the order.
+func TestObtainCertificate(t *testing.T) {
+	responder := acme.NewHTTP01Responder()
+	server := newFakeServer(t, responder)
+	defer server.srv.Close()
+
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: unexpected error: %v", err)
+	}
+	client := &acme.Client{
+		DirectoryURL: server.srv.URL + "/dir",
+		Key:          accountKey,
+		PollInterval: time.Millisecond,
+	}
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: unexpected error: %v", err)
+	}
+	names := []string{"node.example.com", "rpc.example.com"}
+
+	// Certificates may not be ordered before registering.
+	_, err = client.ObtainCertificate(names, certKey, responder)
+	if err != acme.ErrNotRegistered {
+		t.Fatalf("ObtainCertificate: got %v - want %v", err,
+			acme.ErrNotRegistered)
+	}
+
+	if err := client.Register([]string{"mailto:admin@example.com"}); err != nil {
+		t.Fatalf("Register: unexpected error: %v", err)
+	}
+
+	// Requests with a bad nonce are retried.
+	server.mtx.Lock()
+	server.rejectNonce = true
+	server.mtx.Unlock()
+
+	chain, err := client.ObtainCertificate(names, certKey, responder)
+	if err != nil {
+		t.Fatalf("ObtainCertificate: unexpected error: %v", err)
+	}
+	block, rest := pem.Decode(chain)
+	if block == nil {
+		t.Fatalf("ObtainCertificate: no certificate in chain")
+	}
+	if caBlock, _ := pem.Decode(rest); caBlock == nil {
+		t.Fatalf("ObtainCertificate: no issuer in chain")
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate: unexpected error: %v", err)
+	}
+	if err := leaf.CheckSignatureFrom(server.caCert); err != nil {
+		t.Fatalf("CheckSignatureFrom: unexpected error: %v", err)
+	}
+	if strings.Join(leaf.DNSNames, ",") != strings.Join(names, ",") ||
+		leaf.Subject.CommonName != names[0] {
+
+		t.Fatalf("unexpected names - got %v (%s), want %v",
+			leaf.DNSNames, leaf.Subject.CommonName, names)
+	}
+	pubKey, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pubKey.X.Cmp(certKey.X) != 0 || pubKey.Y.Cmp(certKey.Y) != 0 {
+		t.Fatalf("certificate is not for the passed key")
+	}
+
+	// The challenges are no longer served once the certificate was
+	// obtained.
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://node.example.com"+
+		"/.well-known/acme-challenge/token-0", nil)
+	responder.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("challenge after cleanup: got status %d - want %d",
+			rec.Code, http.StatusNotFound)
+	}
+}
+
+// TestObtainCertificateInvalidChallenge ensures orders fail when the server
+// can not validate a challenge.
+func TestObtainCertificateInvalidChallenge(t *testing.T) {
+	responder := acme.NewHTTP01Responder()
+	server := newFakeServer(t, responder)
+	defer server.srv.Close()
+
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: unexpected error: %v", err)
+	}
+	client := &acme.Client{
+		DirectoryURL: server.srv.URL + "/dir",
+		Key:          accountKey,
+		PollInterval: time.Millisecond,
+	}
+	if err := client.Register(nil); err != nil {
+		t.Fatalf("Register: unexpected error: %v", err)
+	}
+
+	_, err = client.ObtainCertificate([]string{"node.example.com"},
+		accountKey, wrongResponder{responder})
+	if err == nil || !strings.Contains(err.Error(),
+		"wrong key authorization") {
+
+		t.Fatalf("ObtainCertificate: got %v - want wrong key "+
+			"authorization error", err)
+	}
+}
+
+// TestHTTP01Responder ensures the responder only serves the key
+// authorizations of pending challenges.
+func TestHTTP01Responder(t *testing.T) {
+	responder := acme.NewHTTP01Responder()
+	responder.Present("abc", "abc.thumbprint")
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/.well-known/acme-challenge/abc", http.StatusOK, "abc.thumbprint"},
+		{"/.well-known/acme-challenge/abd", http.StatusNotFound, ""},
+		{"/abc", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://example.com"+test.path,
+			nil)
+		responder.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("%s: got status %d - want %d", test.path,
+				rec.Code, test.status)
+			continue
+		}
+		body, _ := ioutil.ReadAll(rec.Body)
+		if test.status == http.StatusOK && string(body) != test.body {
+			t.Errorf("%s: got body %q - want %q", test.path, body,
+				test.body)
+		}
+	}
+
+	responder.CleanUp("abc")
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET",
+		"http://example.com/.well-known/acme-challenge/abc", nil)
+	responder.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("after CleanUp: got status %d - want %d", rec.Code,
+			http.StatusNotFound)
+	}
+}
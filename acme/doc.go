@@ -0,0 +1,32 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package acme implements a minimal client of the Automatic Certificate
+Management Environment (ACME) protocol for obtaining TLS certificates from
+certificate authorities such as Let's Encrypt.
+
+Only what is needed to obtain certificates for DNS names is supported:
+registering an account with an ECDSA P-256 key, ordering a certificate,
+proving control of the names with http-01 challenges, and downloading the
+issued certificate chain.  Revocation, key rollover and the other challenge
+types are not supported.
+
+A certificate is obtained by registering the account and then ordering the
+certificate with a responder which serves the key authorizations of the
+challenges over HTTP on port 80 of every name:
+
+	client := &acme.Client{
+		DirectoryURL: acme.LetsEncryptURL,
+		Key:          accountKey,
+	}
+	if err := client.Register([]string{"mailto:admin@example.com"}); err != nil {
+		// Handle error
+	}
+	responder := acme.NewHTTP01Responder()
+	go http.ListenAndServe(":80", responder)
+	chain, err := client.ObtainCertificate([]string{"example.com"}, certKey,
+		responder)
+*/
+package acme
@@ -0,0 +1,166 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// addrBalanceIndexer maintains the address balance index which holds the
+// confirmed balance, unspent outputs and balance changes of each address
+// involved in the main chain.  Addresses are keyed by the same hash160 as the
+// address index.
+//
+// Like the spent output index, disconnected blocks are removed using the
+// balance changes recorded for each block.
+type addrBalanceIndexer struct {
+	db database.Db
+}
+
+// Ensure the addrBalanceIndexer type implements the Indexer interface.
+var _ Indexer = (*addrBalanceIndexer)(nil)
+
+// newAddrBalanceIndexer creates a new address balance indexer to be added to
+// the index manager.
+func newAddrBalanceIndexer() *addrBalanceIndexer {
+	return &addrBalanceIndexer{}
+}
+
+// Name returns the name of the index.  It is part of the Indexer interface.
+func (x *addrBalanceIndexer) Name() string {
+	return "address balance"
+}
+
+// Init sets the database holding the index.  It is part of the Indexer
+// interface.
+func (x *addrBalanceIndexer) Init(db database.Db) error {
+	x.db = db
+	return nil
+}
+
+// Tip returns the hash and height of the most recent block in the index.  It
+// is part of the Indexer interface.
+func (x *addrBalanceIndexer) Tip() (*wire.ShaHash, int32, error) {
+	sha, height, err := x.db.FetchAddrBalanceIndexTip()
+	if err == database.ErrAddrBalanceIndexDoesNotExist {
+		err = nil
+	}
+	return sha, height, err
+}
+
+// ConnectBlock indexes the balance changes made by the passed block.  It is
+// part of the Indexer interface.
+func (x *addrBalanceIndexer) ConnectBlock(block *coinutil.Block) error {
+	deltas, err := x.blockDeltas(block, block.Height())
+	if err != nil {
+		return err
+	}
+	return x.db.UpdateAddrBalanceIndexForBlock(block.Sha(), block.Height(),
+		&block.MsgBlock().Header.PrevBlock, deltas)
+}
+
+// DisconnectBlock reverts the balance changes of the block at the tip of the
+// index.  It is part of the Indexer interface.
+func (x *addrBalanceIndexer) DisconnectBlock(sha *wire.ShaHash, height int32) error {
+	return x.db.RewindAddrBalanceIndex()
+}
+
+// addrBalanceKey returns the hash160 the address paid by the passed public key
+// script is keyed by.  Only scripts which pay a single pay-to-pubkey-hash,
+// pay-to-script-hash or pay-to-pubkey address are indexed since the balance of
+// the other kinds of outputs can't be attributed to one address.  Pay-to-pubkey
+// outputs are keyed by the hash160 of the public key like the address index.
+func addrBalanceKey(pkScript []byte) ([database.AddrIndexKeySize]byte, bool) {
+	var key [database.AddrIndexKeySize]byte
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript,
+		activeNetParams.Params)
+	if err != nil || len(addrs) != 1 {
+		return key, false
+	}
+
+	switch addr := addrs[0].(type) {
+	case *coinutil.AddressPubKeyHash:
+		key = *addr.Hash160()
+	case *coinutil.AddressScriptHash:
+		key = *addr.Hash160()
+	case *coinutil.AddressPubKey:
+		key = *addr.AddressPubKeyHash().Hash160()
+	default:
+		return key, false
+	}
+	return key, true
+}
+
+// blockDeltas returns the changes to the balances of addresses made by the
+// inputs and outputs of the passed block at the passed height, in the order
+// they appear in the block.
+func (x *addrBalanceIndexer) blockDeltas(block *coinutil.Block, height int32) ([]database.AddrDelta, error) {
+	db := x.db
+	var deltas []database.AddrDelta
+	for txIdx, tx := range block.Transactions() {
+		txSha := *tx.Sha()
+
+		// Coinbases don't have any inputs.
+		if !blockchain.IsCoinBase(tx) {
+			for i, txIn := range tx.MsgTx().TxIn {
+				prevOut := txIn.PreviousOutPoint
+				txList, err := db.FetchTxBySha(&prevOut.Hash)
+				if err != nil {
+					return nil, err
+				}
+				if len(txList) == 0 {
+					return nil, fmt.Errorf("transaction %v not "+
+						"found", prevOut.Hash)
+				}
+				prevTx := txList[len(txList)-1]
+				if prevOut.Index >= uint32(len(prevTx.Tx.TxOut)) {
+					return nil, fmt.Errorf("output %v does not "+
+						"exist", prevOut)
+				}
+				txOut := prevTx.Tx.TxOut[prevOut.Index]
+
+				addrKey, ok := addrBalanceKey(txOut.PkScript)
+				if !ok {
+					continue
+				}
+				deltas = append(deltas, database.AddrDelta{
+					Addr:       addrKey,
+					TxSha:      txSha,
+					BlockIndex: uint32(txIdx),
+					Index:      uint32(i),
+					Spending:   true,
+					Amount:     -txOut.Value,
+					OutPoint:   prevOut,
+					OutHeight:  prevTx.Height,
+					PkScript:   txOut.PkScript,
+				})
+			}
+		}
+
+		for i, txOut := range tx.MsgTx().TxOut {
+			addrKey, ok := addrBalanceKey(txOut.PkScript)
+			if !ok {
+				continue
+			}
+			deltas = append(deltas, database.AddrDelta{
+				Addr:       addrKey,
+				TxSha:      txSha,
+				BlockIndex: uint32(txIdx),
+				Index:      uint32(i),
+				Amount:     txOut.Value,
+				OutPoint:   *wire.NewOutPoint(&txSha, uint32(i)),
+				OutHeight:  height,
+				PkScript:   txOut.PkScript,
+			})
+		}
+	}
+	return deltas, nil
+}
@@ -211,6 +211,21 @@ func TestAddLocalAddress(t *testing.T) {
 			continue
 		}
 	}
+
+	// Only the accepted addresses are known, and adding an address again
+	// with a better priority raises its score.
+	localAddrs := amgr.LocalAddresses()
+	if len(localAddrs) != 2 {
+		t.Fatalf("LocalAddresses: got %d addresses, want 2",
+			len(localAddrs))
+	}
+	scores := make(map[addrmgr.AddressPriority]int)
+	for _, la := range localAddrs {
+		scores[la.Score]++
+	}
+	if scores[addrmgr.InterfacePrio] != 1 || scores[addrmgr.BoundPrio+1] != 1 {
+		t.Errorf("LocalAddresses: unexpected scores %v", scores)
+	}
 }
 
 func TestAttempt(t *testing.T) {
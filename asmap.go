@@ -0,0 +1,251 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/conseweb/stcd/addrmgr"
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/wire"
+)
+
+// asMap maps IP prefixes to the numbers of the autonomous systems announcing
+// them.  It is loaded from the file given with the asmap option, where each
+// line holds a prefix in CIDR notation followed by the AS number and
+// optionally the name of the AS, such as:
+//
+//	1.0.0.0/24 AS13335 CLOUDFLARENET
+//
+// Empty lines and lines starting with '#' are ignored.
+type asMap struct {
+	// prefixes maps the lengths of the prefixes, counted in bits of IPv6
+	// addresses, to the masked addresses of the prefixes with that length
+	// and their AS numbers.  IPv4 prefixes are stored as IPv4-mapped IPv6
+	// prefixes.
+	prefixes map[int]map[string]uint32
+
+	// lengths holds the prefix lengths of the map from the longest to the
+	// shortest so the most specific prefix of an address is found first.
+	lengths []int
+
+	// names holds the names of the autonomous systems which have one.
+	names map[uint32]string
+}
+
+// parseASN parses an AS number with or without the AS prefix.
+func parseASN(s string) (uint32, error) {
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || asn == 0 {
+		return 0, fmt.Errorf("invalid AS number %q", s)
+	}
+	return uint32(asn), nil
+}
+
+// parseASMap reads an AS map from the passed reader.
+func parseASMap(r io.Reader) (*asMap, error) {
+	m := &asMap{
+		prefixes: make(map[int]map[string]uint32),
+		names:    make(map[uint32]string),
+	}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected a prefix and "+
+				"an AS number", lineNum)
+		}
+		_, ipNet, err := net.ParseCIDR(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		asn, err := parseASN(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+
+		ones, bits := ipNet.Mask.Size()
+		if bits == 8*net.IPv4len {
+			ones += 8 * (net.IPv6len - net.IPv4len)
+		}
+		prefixes, ok := m.prefixes[ones]
+		if !ok {
+			prefixes = make(map[string]uint32)
+			m.prefixes[ones] = prefixes
+			m.lengths = append(m.lengths, ones)
+		}
+		prefixes[string(ipNet.IP.To16())] = asn
+		if len(fields) > 2 {
+			m.names[asn] = strings.Join(fields[2:], " ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(m.lengths)))
+	return m, nil
+}
+
+// loadASMap reads the AS map from the passed file.
+func loadASMap(file string) (*asMap, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseASMap(f)
+}
+
+// Lookup returns the number of the autonomous system announcing the most
+// specific prefix of the passed address.  False is returned when no prefix of
+// the map contains the address.
+func (m *asMap) Lookup(ip net.IP) (uint32, bool) {
+	ip = ip.To16()
+	if ip == nil {
+		return 0, false
+	}
+	for _, ones := range m.lengths {
+		mask := net.CIDRMask(ones, 8*net.IPv6len)
+		if asn, ok := m.prefixes[ones][string(ip.Mask(mask))]; ok {
+			return asn, true
+		}
+	}
+	return 0, false
+}
+
+// Name returns the name of the passed autonomous system, or an empty string
+// when the map does not name it.
+func (m *asMap) Name(asn uint32) string {
+	return m.names[asn]
+}
+
+// unknownASGroup is the group reported by getnetworktopology for the peers
+// whose addresses are not covered by the AS map.
+const unknownASGroup = "unknown"
+
+// topologyPeer describes a connected peer for the network topology report.
+type topologyPeer struct {
+	na      *wire.NetAddress
+	inbound bool
+}
+
+// topologyGroups counts the peers of the groups of a network topology report.
+type topologyGroups map[string]*btcjson.NetworkTopologyGroup
+
+// add counts the passed peer in the passed group and returns the counts of the
+// group.
+func (g topologyGroups) add(group string, inbound bool) *btcjson.NetworkTopologyGroup {
+	counts, ok := g[group]
+	if !ok {
+		counts = &btcjson.NetworkTopologyGroup{Group: group}
+		g[group] = counts
+	}
+	counts.Peers++
+	if inbound {
+		counts.Inbound++
+	} else {
+		counts.Outbound++
+	}
+	return counts
+}
+
+// sorted returns the groups ordered by their numbers of peers, the largest
+// first, and their names.
+func (g topologyGroups) sorted() []btcjson.NetworkTopologyGroup {
+	groups := make([]btcjson.NetworkTopologyGroup, 0, len(g))
+	for _, counts := range g {
+		groups = append(groups, *counts)
+	}
+	sort.Sort(topologyGroupsByPeers(groups))
+	return groups
+}
+
+// outboundShare returns the number of groups with outbound peers, ignoring
+// the passed group, and the largest share of the passed number of outbound
+// peers in any one of those groups.
+func (g topologyGroups) outboundShare(outbound int, ignore string) (int, float64) {
+	var groups, max int
+	for group, counts := range g {
+		if group == ignore || counts.Outbound == 0 {
+			continue
+		}
+		groups++
+		if counts.Outbound > max {
+			max = counts.Outbound
+		}
+	}
+	if outbound == 0 {
+		return groups, 0
+	}
+	return groups, float64(max) / float64(outbound)
+}
+
+// topologyGroupsByPeers sorts network topology groups by their numbers of
+// peers, the largest first, and their names.
+type topologyGroupsByPeers []btcjson.NetworkTopologyGroup
+
+func (s topologyGroupsByPeers) Len() int      { return len(s) }
+func (s topologyGroupsByPeers) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s topologyGroupsByPeers) Less(i, j int) bool {
+	if s[i].Peers != s[j].Peers {
+		return s[i].Peers > s[j].Peers
+	}
+	return s[i].Group < s[j].Group
+}
+
+// networkTopology returns the distribution of the passed peers over network
+// groups, and over autonomous systems when an AS map is passed, as reported by
+// the getnetworktopology command.
+func networkTopology(peers []topologyPeer, m *asMap) *btcjson.GetNetworkTopologyResult {
+	result := &btcjson.GetNetworkTopologyResult{
+		ASMap:     m != nil,
+		NetGroups: []btcjson.NetworkTopologyGroup{},
+		ASNs:      []btcjson.NetworkTopologyGroup{},
+	}
+	netGroups := make(topologyGroups)
+	asns := make(topologyGroups)
+	for _, p := range peers {
+		result.Peers++
+		if p.inbound {
+			result.Inbound++
+		} else {
+			result.Outbound++
+		}
+		netGroups.add(addrmgr.GroupKey(p.na), p.inbound)
+		if m == nil {
+			continue
+		}
+		group, name := unknownASGroup, ""
+		if asn, ok := m.Lookup(p.na.IP); ok {
+			group = "AS" + strconv.FormatUint(uint64(asn), 10)
+			name = m.Name(asn)
+		}
+		asns.add(group, p.inbound).Name = name
+	}
+
+	result.NetGroups = netGroups.sorted()
+	result.OutboundNetGroups, result.MaxNetGroupShare =
+		netGroups.outboundShare(result.Outbound, "")
+	if m != nil {
+		result.ASNs = asns.sorted()
+		result.OutboundASNs, result.MaxASNShare =
+			asns.outboundShare(result.Outbound, unknownASGroup)
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// testASMap is the AS map used by the tests.
+const testASMap = `# test map
+1.0.0.0/8 AS100 BROAD
+1.2.0.0/16 200 NARROW
+2001:db8::/32 AS300
+`
+
+// TestASMapLookup ensures addresses are mapped to the autonomous system of
+// their most specific prefix and that invalid maps are rejected.
+func TestASMapLookup(t *testing.T) {
+	t.Parallel()
+
+	m, err := parseASMap(strings.NewReader(testASMap))
+	if err != nil {
+		t.Fatalf("parseASMap: unexpected error: %v", err)
+	}
+	tests := []struct {
+		ip    string
+		asn   uint32
+		found bool
+	}{
+		{"1.1.1.1", 100, true},
+		{"1.2.3.4", 200, true},
+		{"2001:db8::1", 300, true},
+		{"3.3.3.3", 0, false},
+		{"2001:db9::1", 0, false},
+	}
+	for _, test := range tests {
+		asn, ok := m.Lookup(net.ParseIP(test.ip))
+		if asn != test.asn || ok != test.found {
+			t.Errorf("Lookup %s: got %d (%v) - want %d (%v)", test.ip,
+				asn, ok, test.asn, test.found)
+		}
+	}
+	if name := m.Name(200); name != "NARROW" {
+		t.Errorf("Name: got %q - want NARROW", name)
+	}
+
+	invalid := []string{
+		"1.0.0.0/8",
+		"1.0.0.0 AS100",
+		"1.0.0.0/8 ASX",
+		"1.0.0.0/8 0",
+	}
+	for _, line := range invalid {
+		if _, err := parseASMap(strings.NewReader(line)); err == nil {
+			t.Errorf("parseASMap: accepted invalid line %q", line)
+		}
+	}
+}
+
+// TestNetworkTopology ensures peers are counted in their network groups and
+// autonomous systems and that the shares of outbound peers are computed.
+func TestNetworkTopology(t *testing.T) {
+	t.Parallel()
+
+	m, err := parseASMap(strings.NewReader(testASMap))
+	if err != nil {
+		t.Fatalf("parseASMap: unexpected error: %v", err)
+	}
+	newPeer := func(ip string, inbound bool) topologyPeer {
+		na := wire.NewNetAddressIPPort(net.ParseIP(ip), 8333, 0)
+		return topologyPeer{na: na, inbound: inbound}
+	}
+	peers := []topologyPeer{
+		newPeer("1.2.3.4", false),
+		newPeer("1.2.5.6", false),
+		newPeer("1.3.0.1", false),
+		newPeer("8.8.8.8", false),
+		newPeer("1.2.7.7", true),
+	}
+
+	result := networkTopology(peers, m)
+	if !result.ASMap || result.Peers != 5 || result.Inbound != 1 ||
+		result.Outbound != 4 {
+
+		t.Fatalf("networkTopology: unexpected counts %+v", result)
+	}
+	if len(result.NetGroups) != 3 || result.NetGroups[0].Group != "1.2.0.0" ||
+		result.NetGroups[0].Peers != 3 || result.NetGroups[0].Inbound != 1 {
+
+		t.Fatalf("networkTopology: unexpected network groups %+v",
+			result.NetGroups)
+	}
+	if result.OutboundNetGroups != 3 || result.MaxNetGroupShare != 0.5 {
+		t.Fatalf("networkTopology: got %d network groups with a max "+
+			"share of %v - want 3 and 0.5", result.OutboundNetGroups,
+			result.MaxNetGroupShare)
+	}
+	if len(result.ASNs) != 3 || result.ASNs[0].Group != "AS200" ||
+		result.ASNs[0].Name != "NARROW" || result.ASNs[0].Peers != 3 {
+
+		t.Fatalf("networkTopology: unexpected autonomous systems %+v",
+			result.ASNs)
+	}
+
+	// The peer outside of the map is not counted as a known autonomous
+	// system.
+	if result.OutboundASNs != 2 || result.MaxASNShare != 0.5 {
+		t.Fatalf("networkTopology: got %d autonomous systems with a max "+
+			"share of %v - want 2 and 0.5", result.OutboundASNs,
+			result.MaxASNShare)
+	}
+
+	// Without a map only the network groups are reported.
+	result = networkTopology(peers, nil)
+	if result.ASMap || len(result.ASNs) != 0 || result.OutboundASNs != 0 {
+		t.Fatalf("networkTopology: unexpected autonomous systems "+
+			"without a map %+v", result)
+	}
+}
@@ -0,0 +1,844 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// A node started with --assumeutxo bootstraps from a UTXO snapshot produced
+// by the dumputxoset command.  The block database stores full blocks rather
+// than a standalone UTXO set, so the snapshot can't seed it.  Instead, the
+// snapshot is loaded as a second chainstate which is kept in memory:
+//
+//  - The headers up to the best known block are downloaded from the sync peer
+//    and the base block of the snapshot must be part of the best header chain
+//  - The blocks after the base block are downloaded and connected to the
+//    snapshot chainstate with full validation of their transactions
+//  - Afterwards the main chain is synced from the genesis block as usual and
+//    new blocks keep being connected to the snapshot chainstate
+//  - Once the main chain reaches the base block, the UTXO set of the main
+//    chain is compared against the snapshot in the background
+//  - The snapshot chainstate is dropped once it is validated and the main
+//    chain caught up with it, or when it turns out to be invalid
+//
+// The snapshot chainstate isn't persisted, so it is rebuilt from the snapshot
+// file when the node is restarted before the main chain caught up.
+const (
+	// snapshotBlocksPerRequest is the number of blocks requested at once
+	// to extend the snapshot chainstate.
+	snapshotBlocksPerRequest = 16
+
+	// maxSnapshotUndo is the number of the latest blocks connected to the
+	// snapshot chainstate which can be disconnected again when the best
+	// header chain is reorganized.
+	maxSnapshotUndo = 100
+
+	// maxSnapshotOutputIndex is the largest output index a transaction
+	// can possibly have, since every output takes at least 9 bytes of a
+	// block.
+	maxSnapshotOutputIndex = wire.MaxBlockPayload / 9
+)
+
+var (
+	// errSnapshotNoUndo is returned when a block has to be disconnected
+	// from the snapshot chainstate which is older than the blocks it can
+	// undo.
+	errSnapshotNoUndo = errors.New("reorganization is deeper than the " +
+		"blocks the UTXO snapshot can disconnect")
+)
+
+// snapshotUndo holds the changes a block made to the snapshot chainstate so
+// they can be reverted.
+type snapshotUndo struct {
+	prevHash wire.ShaHash
+	spent    []*utxoSnapshotCoin
+	created  []wire.OutPoint
+}
+
+// snapshotChainstate is the UTXO set loaded from a UTXO snapshot along with
+// the blocks connected to it since its base block.  The set hash and base
+// block never change, while the rest is protected by the mutex since the RPC
+// server reads it concurrently with the block handler.  Only the block
+// handler modifies it.
+type snapshotChainstate struct {
+	setHash    wire.ShaHash
+	baseHash   wire.ShaHash
+	baseHeight int32
+
+	mtx       sync.RWMutex
+	tipHash   wire.ShaHash
+	tipHeight int32
+	coins     map[wire.OutPoint]*utxoSnapshotCoin
+	undo      []*snapshotUndo
+	validated bool
+	dropped   bool
+}
+
+// loadSnapshotChainstate reads the UTXO snapshot at the passed path and
+// returns it as a chainstate at its base block.  An error is returned when
+// the snapshot is not for the passed network or its hash is not the passed
+// one.
+func loadSnapshotChainstate(path string, setHash *wire.ShaHash, net wire.StonecoinNet) (*snapshotChainstate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, coins, hash, err := readUtxoSnapshot(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	if header.net != net {
+		return nil, fmt.Errorf("UTXO snapshot is for network %v, not %v",
+			header.net, net)
+	}
+	if !hash.IsEqual(setHash) {
+		return nil, fmt.Errorf("UTXO snapshot hash %v does not match "+
+			"the expected hash %v", hash, setHash)
+	}
+
+	s := &snapshotChainstate{
+		setHash:    *hash,
+		baseHash:   header.baseHash,
+		baseHeight: header.baseHeight,
+		tipHash:    header.baseHash,
+		tipHeight:  header.baseHeight,
+		coins:      make(map[wire.OutPoint]*utxoSnapshotCoin, len(coins)),
+	}
+	for _, coin := range coins {
+		s.coins[coin.outPoint] = coin
+	}
+	return s, nil
+}
+
+// tip returns the hash and height of the latest block connected to the
+// snapshot chainstate.
+func (s *snapshotChainstate) tip() (wire.ShaHash, int32) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.tipHash, s.tipHeight
+}
+
+// isDropped returns whether the snapshot chainstate is no longer used.
+func (s *snapshotChainstate) isDropped() bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.dropped
+}
+
+// isValidated returns whether the snapshot was found to match the UTXO set of
+// the main chain at its base block.
+func (s *snapshotChainstate) isValidated() bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.validated
+}
+
+// setValidated marks the snapshot as matching the UTXO set of the main chain.
+func (s *snapshotChainstate) setValidated() {
+	s.mtx.Lock()
+	s.validated = true
+	s.mtx.Unlock()
+}
+
+// drop releases the UTXO set of the snapshot chainstate, which is no longer
+// used afterwards.
+func (s *snapshotChainstate) drop() {
+	s.mtx.Lock()
+	s.coins = nil
+	s.undo = nil
+	s.dropped = true
+	s.mtx.Unlock()
+}
+
+// fetchCoin returns the unspent output at the passed outpoint as of the tip
+// of the snapshot chainstate along with the hash and height of the tip.  The
+// coin is nil when the output is spent or doesn't exist.
+func (s *snapshotChainstate) fetchCoin(outPoint *wire.OutPoint) (*utxoSnapshotCoin, wire.ShaHash, int32) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.coins[*outPoint], s.tipHash, s.tipHeight
+}
+
+// connectBlock validates the transactions of the passed block against the
+// snapshot chainstate and connects it when they are valid.  The block must
+// extend the tip of the chainstate and must already have passed the checks
+// which don't depend on the outputs it spends.  Its scripts are verified
+// with the passed flags and its coinbase may claim the passed subsidy in
+// addition to the fees.  The chainstate is unchanged when an error is
+// returned.
+//
+// This function must only be called from the block handler.
+func (s *snapshotChainstate) connectBlock(block *coinutil.Block, scriptFlags txscript.ScriptFlags, subsidy int64, sigCache *txscript.SigCache) error {
+	tipHash, tipHeight := s.tip()
+	if block.MsgBlock().Header.PrevBlock != tipHash {
+		return fmt.Errorf("block %v does not extend the UTXO snapshot "+
+			"tip %v", block.Sha(), tipHash)
+	}
+	height := tipHeight + 1
+
+	// The changes of the block are staged until all of its transactions
+	// are known to be valid.  Only the block handler modifies the coins,
+	// so they can be read without holding the lock.
+	created := make(map[wire.OutPoint]*utxoSnapshotCoin)
+	spent := make(map[wire.OutPoint]*utxoSnapshotCoin)
+	lookup := func(outPoint wire.OutPoint) *utxoSnapshotCoin {
+		if coin, ok := created[outPoint]; ok {
+			return coin
+		}
+		if _, ok := spent[outPoint]; ok {
+			return nil
+		}
+		return s.coins[outPoint]
+	}
+
+	enforceBIP0016 := scriptFlags&txscript.ScriptBip16 != 0
+	enforceSegwit := scriptFlags&txscript.ScriptVerifyWitness != 0
+	var totalSigOps, totalSigOpCost int
+	var totalFees int64
+	for i, tx := range block.Transactions() {
+		txStore := snapshotTxStore(tx, lookup)
+
+		numSigOps := blockchain.CountSigOps(tx)
+		if enforceBIP0016 {
+			numP2SHSigOps, err := blockchain.CountP2SHSigOps(tx,
+				i == 0, txStore)
+			if err != nil {
+				return err
+			}
+			numSigOps += numP2SHSigOps
+		}
+		lastSigOps := totalSigOps
+		totalSigOps += numSigOps
+		if totalSigOps < lastSigOps ||
+			totalSigOps > blockchain.MaxSigOpsPerBlock {
+
+			str := fmt.Sprintf("block contains too many signature "+
+				"operations - got %v, max %v", totalSigOps,
+				blockchain.MaxSigOpsPerBlock)
+			return blockchain.RuleError{
+				ErrorCode:   blockchain.ErrTooManySigOps,
+				Description: str,
+			}
+		}
+		if enforceSegwit {
+			numWitnessSigOps, err := blockchain.CountWitnessSigOps(tx,
+				i == 0, txStore)
+			if err != nil {
+				return err
+			}
+			lastSigOpCost := totalSigOpCost
+			totalSigOpCost += numSigOps*blockchain.WitnessScaleFactor +
+				numWitnessSigOps
+			if totalSigOpCost < lastSigOpCost ||
+				totalSigOpCost > blockchain.MaxBlockSigOpsCost {
+
+				str := fmt.Sprintf("block contains too many "+
+					"signature operations - got cost %v, "+
+					"max %v", totalSigOpCost,
+					blockchain.MaxBlockSigOpsCost)
+				return blockchain.RuleError{
+					ErrorCode:   blockchain.ErrTooManySigOps,
+					Description: str,
+				}
+			}
+		}
+
+		txFee, err := blockchain.CheckTransactionInputs(tx, height,
+			txStore)
+		if err != nil {
+			return err
+		}
+		lastTotalFees := totalFees
+		totalFees += txFee
+		if totalFees < lastTotalFees {
+			return blockchain.RuleError{
+				ErrorCode:   blockchain.ErrBadFees,
+				Description: "total fees for block overflows accumulator",
+			}
+		}
+		err = blockchain.ValidateTransactionScripts(tx, txStore,
+			scriptFlags, sigCache)
+		if err != nil {
+			return err
+		}
+
+		isCoinBase := i == 0
+		if !isCoinBase {
+			for _, txIn := range tx.MsgTx().TxIn {
+				outPoint := txIn.PreviousOutPoint
+				if _, ok := created[outPoint]; ok {
+					delete(created, outPoint)
+					continue
+				}
+				spent[outPoint] = s.coins[outPoint]
+			}
+		}
+
+		// Provably unspendable outputs are not part of the set, just
+		// like in the snapshot.
+		for txOutIdx, txOut := range tx.MsgTx().TxOut {
+			if len(txOut.PkScript) > 0 &&
+				txOut.PkScript[0] == txscript.OP_RETURN {
+				continue
+			}
+			outPoint := wire.NewOutPoint(tx.Sha(), uint32(txOutIdx))
+			if lookup(*outPoint) != nil {
+				str := fmt.Sprintf("tried to overwrite transaction "+
+					"%v which has unspent outputs", tx.Sha())
+				return blockchain.RuleError{
+					ErrorCode:   blockchain.ErrOverwriteTx,
+					Description: str,
+				}
+			}
+			created[*outPoint] = &utxoSnapshotCoin{
+				outPoint: *outPoint,
+				height:   height,
+				coinbase: isCoinBase,
+				amount:   txOut.Value,
+				pkScript: txOut.PkScript,
+			}
+		}
+	}
+
+	// The coinbase must not pay more than the subsidy and the fees.
+	var totalSatoshiOut int64
+	for _, txOut := range block.Transactions()[0].MsgTx().TxOut {
+		totalSatoshiOut += txOut.Value
+	}
+	if totalSatoshiOut > subsidy+totalFees {
+		str := fmt.Sprintf("coinbase transaction for block pays %v "+
+			"which is more than expected value of %v",
+			totalSatoshiOut, subsidy+totalFees)
+		return blockchain.RuleError{
+			ErrorCode:   blockchain.ErrBadCoinbaseValue,
+			Description: str,
+		}
+	}
+
+	undo := &snapshotUndo{
+		prevHash: tipHash,
+		spent:    make([]*utxoSnapshotCoin, 0, len(spent)),
+		created:  make([]wire.OutPoint, 0, len(created)),
+	}
+	s.mtx.Lock()
+	for outPoint, coin := range spent {
+		delete(s.coins, outPoint)
+		undo.spent = append(undo.spent, coin)
+	}
+	for outPoint, coin := range created {
+		s.coins[outPoint] = coin
+		undo.created = append(undo.created, outPoint)
+	}
+	s.undo = append(s.undo, undo)
+	if len(s.undo) > maxSnapshotUndo {
+		s.undo[0] = nil
+		s.undo = s.undo[1:]
+	}
+	s.tipHash = *block.Sha()
+	s.tipHeight = height
+	s.mtx.Unlock()
+	return nil
+}
+
+// disconnectTip reverts the changes the latest block connected to the
+// snapshot chainstate made.  The base block of the snapshot can't be
+// disconnected.
+//
+// This function must only be called from the block handler.
+func (s *snapshotChainstate) disconnectTip() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if len(s.undo) == 0 {
+		return errSnapshotNoUndo
+	}
+	undo := s.undo[len(s.undo)-1]
+	s.undo[len(s.undo)-1] = nil
+	s.undo = s.undo[:len(s.undo)-1]
+	for i := range undo.created {
+		delete(s.coins, undo.created[i])
+	}
+	for _, coin := range undo.spent {
+		s.coins[coin.outPoint] = coin
+	}
+	s.tipHash = undo.prevHash
+	s.tipHeight--
+	return nil
+}
+
+// snapshotTxStore returns the outputs the inputs of the passed transaction
+// spend in the form expected by the transaction validation functions of the
+// blockchain package.  The snapshot only has the unspent outputs, so the
+// transactions which created them are recreated with the outputs at their
+// original index.  The other outputs are marked spent, while coinbase
+// transactions get a coinbase input so the maturity of their outputs is
+// enforced.  Outputs which are not found are left out, which makes the
+// validation fail for the transaction.
+func snapshotTxStore(tx *coinutil.Tx, lookup func(wire.OutPoint) *utxoSnapshotCoin) blockchain.TxStore {
+	txStore := make(blockchain.TxStore)
+	if blockchain.IsCoinBase(tx) {
+		return txStore
+	}
+
+	coins := make(map[wire.ShaHash][]*utxoSnapshotCoin)
+	for _, txIn := range tx.MsgTx().TxIn {
+		outPoint := txIn.PreviousOutPoint
+		if outPoint.Index > maxSnapshotOutputIndex {
+			continue
+		}
+		if coin := lookup(outPoint); coin != nil {
+			coins[outPoint.Hash] = append(coins[outPoint.Hash], coin)
+		}
+	}
+
+	for hash, txCoins := range coins {
+		var numOutputs uint32
+		for _, coin := range txCoins {
+			if coin.outPoint.Index >= numOutputs {
+				numOutputs = coin.outPoint.Index + 1
+			}
+		}
+
+		msgTx := wire.NewMsgTx()
+		if txCoins[0].coinbase {
+			prevOut := wire.NewOutPoint(&wire.ShaHash{},
+				wire.MaxPrevOutIndex)
+			msgTx.AddTxIn(wire.NewTxIn(prevOut, nil))
+		}
+		spent := make([]bool, numOutputs)
+		for i := range spent {
+			msgTx.AddTxOut(wire.NewTxOut(0, nil))
+			spent[i] = true
+		}
+		for _, coin := range txCoins {
+			msgTx.TxOut[coin.outPoint.Index] = wire.NewTxOut(
+				coin.amount, coin.pkScript)
+			spent[coin.outPoint.Index] = false
+		}
+
+		hash := hash
+		txStore[hash] = &blockchain.TxData{
+			Tx:          coinutil.NewTx(msgTx),
+			Hash:        &hash,
+			BlockHeight: txCoins[0].height,
+			Spent:       spent,
+		}
+	}
+	return txStore
+}
+
+// validateUtxoSnapshot compares the UTXO set of the main chain at the base
+// block of the passed snapshot chainstate against the snapshot.  It returns
+// whether they match, or an error when the UTXO set of the main chain could
+// not be built, in which case the comparison can be retried later.
+func validateUtxoSnapshot(db database.Db, s *snapshotChainstate) (bool, error) {
+	sha, err := db.FetchBlockShaByHeight(s.baseHeight)
+	if err != nil {
+		return false, err
+	}
+	if !sha.IsEqual(&s.baseHash) {
+		return false, nil
+	}
+
+	coins, baseHash, err := buildUtxoSet(db, s.baseHeight)
+	if err != nil {
+		return false, err
+	}
+	if !baseHash.IsEqual(&s.baseHash) {
+		return false, errUtxoSnapshotChainChanged
+	}
+	header := &utxoSnapshotHeader{
+		baseHash:   s.baseHash,
+		baseHeight: s.baseHeight,
+		numCoins:   uint64(len(coins)),
+	}
+	setHash, err := writeUtxoSnapshot(ioutil.Discard, header, coins)
+	if err != nil {
+		return false, err
+	}
+	return setHash.IsEqual(&s.setHash), nil
+}
+
+// snapshotValidatedMsg reports the result of comparing the UTXO set of the
+// main chain against the snapshot to the block handler.
+type snapshotValidatedMsg struct {
+	valid bool
+	err   error
+}
+
+// needSnapshotSync returns whether the snapshot chainstate has to be brought
+// up to date before the main chain is synced.
+func (b *blockManager) needSnapshotSync() bool {
+	return b.snapshot != nil && !b.snapshotSynced && !b.snapshot.isDropped()
+}
+
+// snapshotAhead returns whether the snapshot chainstate is ahead of the main
+// chain, in which case it is used to look up unspent outputs.  It is safe for
+// concurrent access.
+func (b *blockManager) snapshotAhead() bool {
+	if b.snapshot == nil || b.snapshot.isDropped() {
+		return false
+	}
+	_, tipHeight := b.snapshot.tip()
+	_, height := b.chainState.Best()
+	return tipHeight > height
+}
+
+// startSnapshotSync starts bringing the snapshot chainstate up to date from
+// the passed peer by downloading the headers up to the best block it knows.
+func (b *blockManager) startSnapshotSync(sp *serverPeer) {
+	_, tipHeight := b.snapshot.tip()
+	bmgrLog.Infof("Syncing the UTXO snapshot from height %d to height "+
+		"%d from peer %v", tipHeight, sp.LastBlock(), sp.Addr())
+	b.snapshotPeer = sp
+	locator := b.snapshotHeaders.LatestBlockLocator()
+	if err := sp.PushGetHeadersMsg(locator, &zeroHash); err != nil {
+		bmgrLog.Warnf("Failed to send getheaders message to peer %s: %v",
+			sp.Addr(), err)
+	}
+}
+
+// isSnapshotHeadersMsg returns whether the passed headers message from the
+// passed peer is meant for the snapshot chainstate rather than the main
+// chain, which only requests headers from the sync peer while downloading
+// the headers up to a checkpoint or the assumed-valid block.
+func (b *blockManager) isSnapshotHeadersMsg(sp *serverPeer) bool {
+	if b.snapshot == nil || b.snapshot.isDropped() {
+		return false
+	}
+	if sp == b.snapshotPeer {
+		return true
+	}
+	return sp != b.syncPeer || (!b.headersFirstMode &&
+		b.assumeValidHeaders == nil)
+}
+
+// handleSnapshotHeadersMsg adds the headers a peer sent to the headers of the
+// snapshot chainstate and requests the blocks which extend it once all of the
+// headers are known.
+func (b *blockManager) handleSnapshotHeadersMsg(hmsg *headersMsg) {
+	msg := hmsg.headers
+	numHeaders := len(msg.Headers)
+	if numHeaders == 0 {
+		if hmsg.peer == b.snapshotPeer && len(b.snapshotRequested) == 0 {
+			b.fetchSnapshotBlocks()
+		}
+		return
+	}
+
+	for _, header := range msg.Headers {
+		_, err := b.snapshotHeaders.ProcessHeader(header,
+			b.server.timeSource, blockchain.BFNone)
+		if err != nil {
+			rerr, ok := err.(blockchain.RuleError)
+			if ok && rerr.ErrorCode == blockchain.ErrDuplicateBlock {
+				continue
+			}
+			if ok && rerr.ErrorCode == blockchain.ErrPrevBlockNotFound {
+				locator := b.snapshotHeaders.LatestBlockLocator()
+				hmsg.peer.PushGetHeadersMsg(locator, &zeroHash)
+				return
+			}
+
+			bmgrLog.Warnf("Rejected block header %v from %s: %v "+
+				"-- disconnecting", header.BlockSha(),
+				hmsg.peer.Addr(), err)
+			if ok {
+				hmsg.peer.addBanScore(misbehaviorInvalidBlock,
+					fmt.Sprintf("invalid header %v: %v",
+						header.BlockSha(), err))
+			}
+			hmsg.peer.Disconnect()
+			return
+		}
+	}
+
+	// The peer has at least the final header of the message.
+	finalHash := msg.Headers[numHeaders-1].BlockSha()
+	_, height, _, err := b.snapshotHeaders.HeaderByHash(&finalHash)
+	if err == nil && height > hmsg.peer.LastBlock() {
+		hmsg.peer.UpdateLastBlockHeight(height)
+	}
+
+	// A full message means the peer most likely has more headers.
+	if numHeaders == wire.MaxBlockHeadersPerMsg {
+		locator := blockchain.BlockLocator([]*wire.ShaHash{&finalHash})
+		err := hmsg.peer.PushGetHeadersMsg(locator, &zeroHash)
+		if err != nil {
+			bmgrLog.Warnf("Failed to send getheaders message to "+
+				"peer %s: %v", hmsg.peer.Addr(), err)
+		}
+		return
+	}
+
+	// Announcements of new blocks are downloaded from the announcing peer
+	// unless blocks are already being downloaded from another one.
+	if b.snapshotPeer == nil && b.snapshotSynced {
+		b.snapshotPeer = hmsg.peer
+	}
+	if hmsg.peer == b.snapshotPeer && len(b.snapshotRequested) == 0 {
+		b.fetchSnapshotBlocks()
+	}
+}
+
+// fetchSnapshotBlocks requests the next blocks of the best header chain which
+// extend the snapshot chainstate from the peer the snapshot is synced from.
+// Blocks connected to the snapshot chainstate which are no longer part of the
+// best header chain are disconnected first.  The initial sync of the snapshot
+// chainstate is finished once there are no more blocks to request.
+func (b *blockManager) fetchSnapshotBlocks() {
+	sp := b.snapshotPeer
+	for {
+		tipHash, tipHeight := b.snapshot.tip()
+		hash, err := b.snapshotHeaders.HashByHeight(tipHeight)
+		if err == nil && hash.IsEqual(&tipHash) {
+			break
+		}
+
+		// The base block can't be disconnected, so the snapshot can't
+		// be extended when it is not part of the best header chain.
+		if tipHeight == b.snapshot.baseHeight {
+			bmgrLog.Warnf("UTXO snapshot base block %v is not part "+
+				"of the best header chain of peer %s",
+				b.snapshot.baseHash, sp.Addr())
+			b.finishSnapshotSync()
+			return
+		}
+		if err := b.snapshot.disconnectTip(); err != nil {
+			bmgrLog.Errorf("Failed to disconnect UTXO snapshot tip "+
+				"%v: %v -- dropping the snapshot", tipHash, err)
+			b.dropSnapshot()
+			return
+		}
+	}
+
+	_, tipHeight := b.snapshot.tip()
+	_, bestHeight := b.snapshotHeaders.BestHeader()
+	gdmsg := wire.NewMsgGetData()
+	for height := tipHeight + 1; height <= bestHeight &&
+		len(gdmsg.InvList) < snapshotBlocksPerRequest; height++ {
+
+		hash, err := b.snapshotHeaders.HashByHeight(height)
+		if err != nil {
+			bmgrLog.Warnf("Failed to look up block at height %d: %v",
+				height, err)
+			break
+		}
+		iv := wire.NewInvVect(wire.InvTypeBlock, hash)
+		if sp.witnessEnabled() {
+			iv.Type = wire.InvTypeWitnessBlock
+		}
+		b.snapshotRequested[*hash] = struct{}{}
+		sp.requestedBlocks[*hash] = struct{}{}
+		gdmsg.AddInvVect(iv)
+	}
+	if len(gdmsg.InvList) == 0 {
+		b.finishSnapshotSync()
+		return
+	}
+	sp.QueueMessage(gdmsg, nil)
+}
+
+// finishSnapshotSync stops downloading blocks for the snapshot chainstate.
+// When the initial sync of the snapshot chainstate finishes, the main chain is
+// synced from the same peer afterwards.
+func (b *blockManager) finishSnapshotSync() {
+	sp := b.snapshotPeer
+	b.snapshotPeer = nil
+	if b.snapshotSynced {
+		return
+	}
+	b.snapshotSynced = true
+
+	if !b.snapshot.isDropped() {
+		tipHash, tipHeight := b.snapshot.tip()
+		bmgrLog.Infof("UTXO snapshot synced to height %d (hash %v) "+
+			"-- validating the blocks before its base block %v in "+
+			"the background", tipHeight, tipHash,
+			b.snapshot.baseHash)
+	}
+
+	if sp == nil || sp != b.syncPeer {
+		return
+	}
+	_, height, err := b.server.db.NewestSha()
+	if err != nil {
+		bmgrLog.Errorf("%v", err)
+		b.setSyncPeer(nil)
+		return
+	}
+	if !b.syncChain(sp, height) {
+		b.setSyncPeer(nil)
+	}
+}
+
+// handleSnapshotBlockMsg connects a block requested for the snapshot
+// chainstate and requests the next blocks once all requested blocks arrived.
+func (b *blockManager) handleSnapshotBlockMsg(bmsg *blockMsg) {
+	blockSha := bmsg.block.Sha()
+	delete(b.snapshotRequested, *blockSha)
+	if _, exists := b.requestedBlocks[*blockSha]; !exists {
+		delete(bmsg.peer.requestedBlocks, *blockSha)
+	}
+	if b.snapshot.isDropped() {
+		return
+	}
+
+	err := b.connectSnapshotBlock(bmsg.block)
+	if err != nil {
+		if _, ok := err.(blockchain.RuleError); ok {
+			bmgrLog.Infof("Rejected block %v for the UTXO snapshot "+
+				"from %s: %v", blockSha, bmsg.peer, err)
+		} else {
+			bmgrLog.Errorf("Failed to connect block %v to the UTXO "+
+				"snapshot: %v", blockSha, err)
+		}
+
+		// Penalize the peer for blocks violating the consensus rules
+		// just like for blocks of the main chain.
+		if rerr, ok := err.(blockchain.RuleError); ok &&
+			rerr.ErrorCode != blockchain.ErrTimeTooNew &&
+			!(rerr.ErrorCode == blockchain.ErrInvalidWitnessCommitment &&
+				!bmsg.peer.witnessEnabled()) {
+
+			bmsg.peer.addBanScore(misbehaviorInvalidBlock,
+				fmt.Sprintf("invalid block %v: %v", blockSha,
+					err))
+		}
+		return
+	}
+
+	if b.snapshotPeer != nil && len(b.snapshotRequested) == 0 {
+		b.fetchSnapshotBlocks()
+	}
+}
+
+// connectSnapshotBlock validates the passed block and connects it to the
+// snapshot chainstate when it extends the tip.  Blocks which don't extend the
+// tip, such as blocks of a side chain, are only added to the headers.
+func (b *blockManager) connectSnapshotBlock(block *coinutil.Block) error {
+	header := &block.MsgBlock().Header
+	_, err := b.snapshotHeaders.ProcessHeader(header, b.server.timeSource,
+		blockchain.BFNone)
+	if err != nil {
+		rerr, ok := err.(blockchain.RuleError)
+		if !ok || rerr.ErrorCode != blockchain.ErrDuplicateBlock {
+			return err
+		}
+	}
+
+	tipHash, tipHeight := b.snapshot.tip()
+	if header.PrevBlock != tipHash {
+		bmgrLog.Debugf("Block %v does not extend the UTXO snapshot "+
+			"tip %v", block.Sha(), tipHash)
+		return nil
+	}
+
+	err = blockchain.CheckBlockSanity(block, b.server.chainParams.PowLimit,
+		b.server.timeSource)
+	if err != nil {
+		return err
+	}
+	scriptFlags, err := b.snapshotHeaders.CheckBlockContext(block)
+	if err != nil {
+		return err
+	}
+	subsidy := b.blockChain.CalcBlockSubsidy(tipHeight + 1)
+	err = b.snapshot.connectBlock(block, scriptFlags, subsidy,
+		b.server.sigCache)
+	if err != nil {
+		return err
+	}
+
+	b.progressLogger.LogBlockHeight(block)
+	return nil
+}
+
+// checkSnapshot starts comparing the UTXO set of the main chain against the
+// snapshot once the main chain reached the base block of the snapshot, and
+// drops the snapshot chainstate once it is validated and the main chain
+// caught up with it.  It must only be called from the block handler.
+func (b *blockManager) checkSnapshot(height int32) {
+	if b.snapshot == nil || b.snapshot.isDropped() {
+		return
+	}
+
+	if !b.snapshot.isValidated() {
+		if height < b.snapshot.baseHeight || b.snapshotValidating {
+			return
+		}
+		b.snapshotValidating = true
+		bmgrLog.Infof("Main chain reached UTXO snapshot base block %v "+
+			"-- validating the snapshot", b.snapshot.baseHash)
+		go func() {
+			valid, err := validateUtxoSnapshot(b.server.db, b.snapshot)
+			select {
+			case b.msgChan <- snapshotValidatedMsg{valid: valid, err: err}:
+			case <-b.quit:
+			}
+		}()
+		return
+	}
+
+	_, tipHeight := b.snapshot.tip()
+	if height >= tipHeight {
+		bmgrLog.Infof("Main chain caught up with the UTXO snapshot " +
+			"-- no longer using the snapshot")
+		b.dropSnapshot()
+	}
+}
+
+// handleSnapshotValidatedMsg handles the result of comparing the UTXO set of
+// the main chain against the snapshot.
+func (b *blockManager) handleSnapshotValidatedMsg(msg snapshotValidatedMsg) {
+	b.snapshotValidating = false
+	if b.snapshot.isDropped() {
+		return
+	}
+
+	// The comparison is retried when the next block is connected.
+	if msg.err != nil {
+		bmgrLog.Warnf("Failed to validate the UTXO snapshot: %v",
+			msg.err)
+		return
+	}
+	if !msg.valid {
+		bmgrLog.Errorf("UTXO snapshot %v does not match the UTXO set "+
+			"of the main chain at height %d -- dropping the snapshot",
+			b.snapshot.setHash, b.snapshot.baseHeight)
+		b.dropSnapshot()
+		return
+	}
+
+	b.snapshot.setValidated()
+	bmgrLog.Infof("UTXO snapshot %v matches the UTXO set of the main "+
+		"chain at height %d", b.snapshot.setHash, b.snapshot.baseHeight)
+	_, height := b.chainState.Best()
+	b.checkSnapshot(height)
+}
+
+// dropSnapshot stops using the snapshot chainstate.  Blocks which are still
+// requested for it are ignored when they arrive.  When it is dropped during
+// its initial sync, the main chain is synced instead.
+func (b *blockManager) dropSnapshot() {
+	b.snapshot.drop()
+	if b.snapshotPeer != nil {
+		b.finishSnapshotSync()
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// testSnapshotBlock returns a block extending prevHash with a coinbase paying
+// coinbaseValue followed by one transaction per passed set of spent outputs,
+// each paying 1 to an OP_TRUE output.
+func testSnapshotBlock(prevHash *wire.ShaHash, height int32, coinbaseValue int64, spends ...[]*wire.OutPoint) *coinutil.Block {
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff},
+		[]byte{byte(height), byte(height >> 8), 0}))
+	coinbase.AddTxOut(wire.NewTxOut(coinbaseValue, []byte{0x51}))
+
+	merkleRoot := coinbase.TxSha()
+	block := wire.NewMsgBlock(wire.NewBlockHeader(prevHash, &merkleRoot,
+		0x207fffff, 0))
+	block.AddTransaction(coinbase)
+	for _, outPoints := range spends {
+		tx := wire.NewMsgTx()
+		for _, outPoint := range outPoints {
+			tx.AddTxIn(wire.NewTxIn(outPoint, nil))
+		}
+		tx.AddTxOut(wire.NewTxOut(1, []byte{0x51}))
+		block.AddTransaction(tx)
+	}
+	return coinutil.NewBlock(block)
+}
+
+// TestSnapshotConnectBlock ensures blocks connected to a snapshot chainstate
+// spend its outputs, that blocks spending missing, spent or immature outputs
+// or paying too much to the coinbase are rejected without changing it, and
+// that connected blocks can be disconnected again.
+func TestSnapshotConnectBlock(t *testing.T) {
+	baseHash := wire.ShaHash{0x01}
+	baseHeight := int32(blockchain.CoinbaseMaturity + 10)
+	coinbaseCoin := &utxoSnapshotCoin{
+		outPoint: wire.OutPoint{Hash: wire.ShaHash{0x02}, Index: 1},
+		height:   1,
+		coinbase: true,
+		amount:   50,
+		pkScript: []byte{0x51},
+	}
+	immatureCoin := &utxoSnapshotCoin{
+		outPoint: wire.OutPoint{Hash: wire.ShaHash{0x03}},
+		height:   baseHeight,
+		coinbase: true,
+		amount:   50,
+		pkScript: []byte{0x51},
+	}
+	txCoin := &utxoSnapshotCoin{
+		outPoint: wire.OutPoint{Hash: wire.ShaHash{0x04}, Index: 2},
+		height:   baseHeight,
+		amount:   20,
+		pkScript: []byte{0x51},
+	}
+	s := &snapshotChainstate{
+		baseHash:   baseHash,
+		baseHeight: baseHeight,
+		tipHash:    baseHash,
+		tipHeight:  baseHeight,
+		coins:      make(map[wire.OutPoint]*utxoSnapshotCoin),
+	}
+	for _, coin := range []*utxoSnapshotCoin{coinbaseCoin, immatureCoin, txCoin} {
+		s.coins[coin.outPoint] = coin
+	}
+
+	// Spend the mature coinbase output and the other output in one
+	// transaction, which leaves a fee of 69 for the coinbase.
+	height := baseHeight + 1
+	block := testSnapshotBlock(&baseHash, height, 10+69,
+		[]*wire.OutPoint{&coinbaseCoin.outPoint, &txCoin.outPoint})
+	if err := s.connectBlock(block, 0, 10, nil); err != nil {
+		t.Fatalf("connectBlock: %v", err)
+	}
+	tipHash, tipHeight := s.tip()
+	if tipHash != *block.Sha() || tipHeight != height {
+		t.Fatalf("connectBlock: tip %v (%d), want %v (%d)", tipHash,
+			tipHeight, block.Sha(), height)
+	}
+	if len(s.coins) != 3 {
+		t.Fatalf("connectBlock: got %d coins, want 3", len(s.coins))
+	}
+	for _, outPoint := range []wire.OutPoint{coinbaseCoin.outPoint,
+		txCoin.outPoint} {
+
+		if coin, _, _ := s.fetchCoin(&outPoint); coin != nil {
+			t.Fatalf("connectBlock: spent output %v not removed",
+				outPoint)
+		}
+	}
+	spendHash := block.Transactions()[1].Sha()
+	coin, _, _ := s.fetchCoin(wire.NewOutPoint(spendHash, 0))
+	if coin == nil || coin.height != height || coin.coinbase {
+		t.Fatalf("connectBlock: created output %v:0 is %+v", spendHash,
+			coin)
+	}
+
+	tests := []struct {
+		name  string
+		block *coinutil.Block
+		code  blockchain.ErrorCode
+	}{
+		{
+			name: "double spend",
+			block: testSnapshotBlock(block.Sha(), height+1, 10,
+				[]*wire.OutPoint{&txCoin.outPoint}),
+			code: blockchain.ErrMissingTx,
+		},
+		{
+			name: "missing output of known transaction",
+			block: testSnapshotBlock(block.Sha(), height+1, 10,
+				[]*wire.OutPoint{wire.NewOutPoint(spendHash, 0),
+					wire.NewOutPoint(spendHash, 1)}),
+			code: blockchain.ErrBadTxInput,
+		},
+		{
+			name: "immature coinbase",
+			block: testSnapshotBlock(block.Sha(), height+1, 10,
+				[]*wire.OutPoint{&immatureCoin.outPoint}),
+			code: blockchain.ErrImmatureSpend,
+		},
+		{
+			name:  "excessive coinbase",
+			block: testSnapshotBlock(block.Sha(), height+1, 11),
+			code:  blockchain.ErrBadCoinbaseValue,
+		},
+	}
+	for _, test := range tests {
+		err := s.connectBlock(test.block, 0, 10, nil)
+		rerr, ok := err.(blockchain.RuleError)
+		if !ok || rerr.ErrorCode != test.code {
+			t.Errorf("%s: connectBlock: got %v, want %v", test.name,
+				err, test.code)
+			continue
+		}
+		tipHash, tipHeight := s.tip()
+		if tipHash != *block.Sha() || tipHeight != height ||
+			len(s.coins) != 3 {
+
+			t.Errorf("%s: chainstate changed by rejected block",
+				test.name)
+		}
+	}
+
+	// A block which doesn't extend the tip is rejected.
+	orphan := testSnapshotBlock(&baseHash, height, 10)
+	if err := s.connectBlock(orphan, 0, 10, nil); err == nil {
+		t.Fatalf("connectBlock: block not extending the tip accepted")
+	}
+
+	// Disconnecting the block restores the outputs it spent.
+	if err := s.disconnectTip(); err != nil {
+		t.Fatalf("disconnectTip: %v", err)
+	}
+	tipHash, tipHeight = s.tip()
+	if tipHash != baseHash || tipHeight != baseHeight {
+		t.Fatalf("disconnectTip: tip %v (%d), want %v (%d)", tipHash,
+			tipHeight, baseHash, baseHeight)
+	}
+	if len(s.coins) != 3 {
+		t.Fatalf("disconnectTip: got %d coins, want 3", len(s.coins))
+	}
+	if coin, _, _ := s.fetchCoin(&txCoin.outPoint); coin != txCoin {
+		t.Fatalf("disconnectTip: spent output %v not restored",
+			txCoin.outPoint)
+	}
+	if err := s.disconnectTip(); err != errSnapshotNoUndo {
+		t.Fatalf("disconnectTip: got %v, want %v", err,
+			errSnapshotNoUndo)
+	}
+}
+
+// TestValidateUtxoSnapshot ensures a snapshot chainstate is only validated
+// when it matches the UTXO set of the main chain at its base block.
+func TestValidateUtxoSnapshot(t *testing.T) {
+	db, err := database.CreateDB("memdb")
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	defer db.Close()
+
+	addTestBlocks(t, db, 5, 0x207fffff, 0)
+	coins, baseHash, err := buildUtxoSet(db, 3)
+	if err != nil {
+		t.Fatalf("buildUtxoSet: %v", err)
+	}
+	header := &utxoSnapshotHeader{
+		baseHash:   *baseHash,
+		baseHeight: 3,
+		numCoins:   uint64(len(coins)),
+	}
+	setHash, err := writeUtxoSnapshot(ioutil.Discard, header, coins)
+	if err != nil {
+		t.Fatalf("writeUtxoSnapshot: %v", err)
+	}
+
+	s := &snapshotChainstate{
+		setHash:    setHash,
+		baseHash:   *baseHash,
+		baseHeight: 3,
+	}
+	valid, err := validateUtxoSnapshot(db, s)
+	if err != nil || !valid {
+		t.Fatalf("validateUtxoSnapshot: got %v (%v), want valid", valid,
+			err)
+	}
+
+	// A snapshot with a different set of outputs is invalid.
+	s.setHash[0] ^= 0xff
+	valid, err = validateUtxoSnapshot(db, s)
+	if err != nil || valid {
+		t.Fatalf("validateUtxoSnapshot: got %v (%v), want invalid",
+			valid, err)
+	}
+
+	// So is a snapshot of a block which is not part of the main chain.
+	s.setHash = setHash
+	s.baseHash[0] ^= 0xff
+	valid, err = validateUtxoSnapshot(db, s)
+	if err != nil || valid {
+		t.Fatalf("validateUtxoSnapshot: got %v (%v), want invalid",
+			valid, err)
+	}
+}
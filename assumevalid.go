@@ -0,0 +1,130 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/wire"
+)
+
+// needAssumeValidHeaders returns whether the headers leading to the
+// assumed-valid block have to be downloaded before requesting blocks.  That is
+// the case when there is an assumed-valid block which is not known yet and
+// its ancestors haven't been learned from an earlier sync peer.
+func (b *blockManager) needAssumeValidHeaders() bool {
+	if b.assumeValid == nil || len(b.assumedValid) != 0 ||
+		cfg.RegressionTest {
+
+		return false
+	}
+	have, err := b.blockChain.HaveBlock(b.assumeValid)
+	return err == nil && !have
+}
+
+// requestBlocks starts downloading the blocks after the passed locator from
+// the passed sync peer.  When the ancestors of the assumed-valid block are not
+// known yet, the headers leading to it are downloaded first so the blocks can
+// be connected without validating their scripts.
+func (b *blockManager) requestBlocks(sp *serverPeer, locator blockchain.BlockLocator) error {
+	if !b.needAssumeValidHeaders() {
+		return sp.PushGetBlocksMsg(locator, &zeroHash)
+	}
+
+	// The headers must connect to the latest known block.
+	newestHash, _, err := b.server.db.NewestSha()
+	if err != nil {
+		return err
+	}
+	b.assumeValidHeaders = []*wire.ShaHash{newestHash}
+	bmgrLog.Infof("Downloading headers up to assumed-valid block %v from "+
+		"peer %s", b.assumeValid, sp.Addr())
+	return sp.PushGetHeadersMsg(locator, b.assumeValid)
+}
+
+// addAssumeValidHeaders adds the passed headers to the headers leading to the
+// assumed-valid block and returns whether it was reached.  Once it is reached,
+// all of the headers are remembered as its ancestors.  An error is returned
+// when the headers don't connect.
+func (b *blockManager) addAssumeValidHeaders(headers []*wire.BlockHeader) (bool, error) {
+	for _, header := range headers {
+		prevHash := b.assumeValidHeaders[len(b.assumeValidHeaders)-1]
+		if !prevHash.IsEqual(&header.PrevBlock) {
+			return false, fmt.Errorf("header %v does not connect to "+
+				"the previous header %v", header.BlockSha(),
+				prevHash)
+		}
+
+		hash := header.BlockSha()
+		if !hash.IsEqual(b.assumeValid) {
+			b.assumeValidHeaders = append(b.assumeValidHeaders, &hash)
+			continue
+		}
+
+		// The first entry is the latest known block which was only
+		// used to ensure the headers connect.
+		b.assumedValid = make(map[wire.ShaHash]struct{},
+			len(b.assumeValidHeaders))
+		for _, hash := range b.assumeValidHeaders[1:] {
+			b.assumedValid[*hash] = struct{}{}
+		}
+		b.assumeValidHeaders = nil
+		return true, nil
+	}
+	return false, nil
+}
+
+// handleAssumeValidHeadersMsg handles the headers the sync peer sent while
+// the headers leading to the assumed-valid block are downloaded.  Blocks are
+// requested once the assumed-valid block is reached or the peer turns out not
+// to know it.
+func (b *blockManager) handleAssumeValidHeadersMsg(hmsg *headersMsg) {
+	headers := hmsg.headers.Headers
+	reached, err := b.addAssumeValidHeaders(headers)
+	if err != nil {
+		bmgrLog.Warnf("Received block header that does not properly "+
+			"connect to the chain from peer %s -- disconnecting: %v",
+			hmsg.peer.Addr(), err)
+		hmsg.peer.Disconnect()
+		return
+	}
+
+	switch {
+	case reached:
+		bmgrLog.Infof("Received %d block headers up to assumed-valid "+
+			"block %v -- skipping script validation for them",
+			len(b.assumedValid)+1, b.assumeValid)
+
+	// Request the next batch of headers when the message was full.
+	case len(headers) == wire.MaxBlockHeadersPerMsg:
+		finalHash := headers[len(headers)-1].BlockSha()
+		locator := blockchain.BlockLocator([]*wire.ShaHash{&finalHash})
+		err := hmsg.peer.PushGetHeadersMsg(locator, b.assumeValid)
+		if err != nil {
+			bmgrLog.Warnf("Failed to send getheaders message to "+
+				"peer %s: %v", hmsg.peer.Addr(), err)
+		}
+		return
+
+	default:
+		bmgrLog.Infof("Peer %s does not know assumed-valid block %v "+
+			"-- validating all scripts", hmsg.peer.Addr(),
+			b.assumeValid)
+	}
+
+	b.assumeValidHeaders = nil
+	locator, err := b.blockChain.LatestBlockLocator()
+	if err != nil {
+		bmgrLog.Errorf("Failed to get block locator for the latest "+
+			"block: %v", err)
+		return
+	}
+	err = hmsg.peer.PushGetBlocksMsg(locator, &zeroHash)
+	if err != nil {
+		bmgrLog.Warnf("Failed to send getblocks message to peer %s: %v",
+			hmsg.peer.Addr(), err)
+	}
+}
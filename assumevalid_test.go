@@ -0,0 +1,70 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestAddAssumeValidHeaders ensures the headers leading to the assumed-valid
+// block must connect to the latest known block and each other, and that all
+// of them except the latest known block are remembered as its ancestors once
+// it is reached.
+func TestAddAssumeValidHeaders(t *testing.T) {
+	// Build a chain of headers on top of the latest known block.
+	newest := wire.ShaHash{0x01}
+	headers := make([]*wire.BlockHeader, 5)
+	prevHash := newest
+	for i := range headers {
+		headers[i] = &wire.BlockHeader{PrevBlock: prevHash, Nonce: uint32(i)}
+		prevHash = headers[i].BlockSha()
+	}
+	assumeValid := headers[3].BlockSha()
+
+	b := &blockManager{
+		assumeValid:        &assumeValid,
+		assumeValidHeaders: []*wire.ShaHash{&newest},
+	}
+
+	// Headers which don't lead to the assumed-valid block yet are kept.
+	reached, err := b.addAssumeValidHeaders(headers[:2])
+	if err != nil || reached {
+		t.Fatalf("addAssumeValidHeaders: got %v, %v, want the headers "+
+			"to be kept", reached, err)
+	}
+	if len(b.assumeValidHeaders) != 3 {
+		t.Fatalf("addAssumeValidHeaders: got %d headers, want 3",
+			len(b.assumeValidHeaders))
+	}
+
+	// Headers which don't connect are rejected.
+	if _, err := b.addAssumeValidHeaders(headers[3:]); err == nil {
+		t.Fatalf("addAssumeValidHeaders: accepted headers which don't " +
+			"connect")
+	}
+
+	// Reaching the assumed-valid block remembers its ancestors, but
+	// neither the latest known block nor the later headers.
+	reached, err = b.addAssumeValidHeaders(headers[2:])
+	if err != nil || !reached {
+		t.Fatalf("addAssumeValidHeaders: got %v, %v, want the "+
+			"assumed-valid block to be reached", reached, err)
+	}
+	if len(b.assumedValid) != 3 {
+		t.Fatalf("addAssumeValidHeaders: got %d ancestors, want 3",
+			len(b.assumedValid))
+	}
+	for _, header := range headers[:3] {
+		if _, ok := b.assumedValid[header.BlockSha()]; !ok {
+			t.Fatalf("addAssumeValidHeaders: ancestor %v is not "+
+				"remembered", header.BlockSha())
+		}
+	}
+	if b.assumeValidHeaders != nil {
+		t.Fatalf("addAssumeValidHeaders: headers are still downloaded")
+	}
+}
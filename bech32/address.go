@@ -0,0 +1,279 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bech32
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/chaincfg"
+)
+
+var (
+	// ErrUnsupportedWitnessVersion describes an error where a segwit
+	// address has a witness version for which no address type exists yet.
+	ErrUnsupportedWitnessVersion = errors.New("unsupported witness version")
+
+	// ErrInvalidProgramLength describes an error where the witness program
+	// of a segwit address has a length which is not valid for its witness
+	// version.
+	ErrInvalidProgramLength = errors.New("invalid witness program length")
+)
+
+// encodeSegWitAddress encodes the passed witness version and program as a
+// bech32 segwit address with the passed human-readable part.
+func encodeSegWitAddress(hrp string, witnessVersion byte, witnessProgram []byte) (string, error) {
+	converted, err := ConvertBits(witnessProgram, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	data := append([]byte{witnessVersion}, converted...)
+	return Encode(hrp, data)
+}
+
+// decodeSegWitAddress decodes the passed bech32 segwit address into its
+// human-readable part, witness version and witness program.
+func decodeSegWitAddress(address string) (string, byte, []byte, error) {
+	hrp, data, err := Decode(address)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(data) < 1 {
+		return "", 0, nil, fmt.Errorf("no witness version")
+	}
+
+	witnessVersion := data[0]
+	if witnessVersion > 16 {
+		return "", 0, nil, fmt.Errorf("invalid witness version %d",
+			witnessVersion)
+	}
+	witnessProgram, err := ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(witnessProgram) < 2 || len(witnessProgram) > 40 {
+		return "", 0, nil, ErrInvalidProgramLength
+	}
+	if witnessVersion == 0 && len(witnessProgram) != 20 &&
+		len(witnessProgram) != 32 {
+
+		return "", 0, nil, ErrInvalidProgramLength
+	}
+
+	return hrp, witnessVersion, witnessProgram, nil
+}
+
+// DecodeAddress decodes the string encoding of an address and returns the
+// Address if it is a valid encoding for a known address type.
+//
+// Bech32 segwit addresses are only recognized when they use the
+// human-readable part of the passed network.  All other addresses are decoded
+// by coinutil.DecodeAddress.
+func DecodeAddress(addr string, defaultNet *chaincfg.Params) (coinutil.Address, error) {
+	hrp := defaultNet.Bech32HRPSegwit
+	if hrp == "" || !strings.HasPrefix(strings.ToLower(addr), hrp+"1") {
+		return coinutil.DecodeAddress(addr, defaultNet)
+	}
+
+	decodedHRP, witnessVersion, witnessProgram, err :=
+		decodeSegWitAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The human-readable part is everything before the last separator, so
+	// it may be longer than the prefix checked above.
+	if decodedHRP != hrp {
+		return nil, coinutil.ErrUnknownAddressType
+	}
+	if witnessVersion != 0 {
+		return nil, ErrUnsupportedWitnessVersion
+	}
+
+	switch len(witnessProgram) {
+	case 20:
+		return newAddressWitnessPubKeyHash(hrp, witnessProgram)
+	case 32:
+		return newAddressWitnessScriptHash(hrp, witnessProgram)
+	default:
+		return nil, ErrInvalidProgramLength
+	}
+}
+
+// AddressWitnessPubKeyHash is an Address for a pay-to-witness-pubkey-hash
+// (P2WPKH) output.  See BIP0173 for further details regarding native segwit
+// addresses.
+type AddressWitnessPubKeyHash struct {
+	hrp            string
+	witnessVersion byte
+	witnessProgram [20]byte
+}
+
+// NewAddressWitnessPubKeyHash returns a new AddressWitnessPubKeyHash.
+func NewAddressWitnessPubKeyHash(witnessProg []byte, net *chaincfg.Params) (*AddressWitnessPubKeyHash, error) {
+	return newAddressWitnessPubKeyHash(net.Bech32HRPSegwit, witnessProg)
+}
+
+// newAddressWitnessPubKeyHash is an internal helper function to create an
+// AddressWitnessPubKeyHash with a known human-readable part.
+func newAddressWitnessPubKeyHash(hrp string, witnessProg []byte) (*AddressWitnessPubKeyHash, error) {
+	// Check for valid program length for witness version 0, which is 20
+	// for P2WPKH.
+	if len(witnessProg) != 20 {
+		return nil, ErrInvalidProgramLength
+	}
+
+	addr := &AddressWitnessPubKeyHash{
+		hrp:            strings.ToLower(hrp),
+		witnessVersion: 0x00,
+	}
+	copy(addr.witnessProgram[:], witnessProg)
+	return addr, nil
+}
+
+// EncodeAddress returns the bech32 string encoding of an
+// AddressWitnessPubKeyHash.
+//
+// Part of the Address interface.
+func (a *AddressWitnessPubKeyHash) EncodeAddress() string {
+	str, err := encodeSegWitAddress(a.hrp, a.witnessVersion,
+		a.witnessProgram[:])
+	if err != nil {
+		return ""
+	}
+	return str
+}
+
+// ScriptAddress returns the witness program for this address.
+//
+// Part of the Address interface.
+func (a *AddressWitnessPubKeyHash) ScriptAddress() []byte {
+	return a.witnessProgram[:]
+}
+
+// IsForNet returns whether or not the AddressWitnessPubKeyHash is associated
+// with the passed network.
+//
+// Part of the Address interface.
+func (a *AddressWitnessPubKeyHash) IsForNet(net *chaincfg.Params) bool {
+	return a.hrp == net.Bech32HRPSegwit
+}
+
+// String returns a human-readable string for the AddressWitnessPubKeyHash.
+// This is equivalent to calling EncodeAddress, but is provided so the type
+// can be used as a fmt.Stringer.
+//
+// Part of the Address interface.
+func (a *AddressWitnessPubKeyHash) String() string {
+	return a.EncodeAddress()
+}
+
+// Hrp returns the human-readable part of the bech32 encoded
+// AddressWitnessPubKeyHash.
+func (a *AddressWitnessPubKeyHash) Hrp() string {
+	return a.hrp
+}
+
+// WitnessVersion returns the witness version of the AddressWitnessPubKeyHash.
+func (a *AddressWitnessPubKeyHash) WitnessVersion() byte {
+	return a.witnessVersion
+}
+
+// WitnessProgram returns the witness program of the AddressWitnessPubKeyHash.
+func (a *AddressWitnessPubKeyHash) WitnessProgram() []byte {
+	return a.witnessProgram[:]
+}
+
+// Hash160 returns the witness program of the AddressWitnessPubKeyHash as a
+// byte array.
+func (a *AddressWitnessPubKeyHash) Hash160() *[20]byte {
+	return &a.witnessProgram
+}
+
+// AddressWitnessScriptHash is an Address for a pay-to-witness-script-hash
+// (P2WSH) output.  See BIP0173 for further details regarding native segwit
+// addresses.
+type AddressWitnessScriptHash struct {
+	hrp            string
+	witnessVersion byte
+	witnessProgram [32]byte
+}
+
+// NewAddressWitnessScriptHash returns a new AddressWitnessScriptHash.
+func NewAddressWitnessScriptHash(witnessProg []byte, net *chaincfg.Params) (*AddressWitnessScriptHash, error) {
+	return newAddressWitnessScriptHash(net.Bech32HRPSegwit, witnessProg)
+}
+
+// newAddressWitnessScriptHash is an internal helper function to create an
+// AddressWitnessScriptHash with a known human-readable part.
+func newAddressWitnessScriptHash(hrp string, witnessProg []byte) (*AddressWitnessScriptHash, error) {
+	// Check for valid program length for witness version 0, which is 32
+	// for P2WSH.
+	if len(witnessProg) != 32 {
+		return nil, ErrInvalidProgramLength
+	}
+
+	addr := &AddressWitnessScriptHash{
+		hrp:            strings.ToLower(hrp),
+		witnessVersion: 0x00,
+	}
+	copy(addr.witnessProgram[:], witnessProg)
+	return addr, nil
+}
+
+// EncodeAddress returns the bech32 string encoding of an
+// AddressWitnessScriptHash.
+//
+// Part of the Address interface.
+func (a *AddressWitnessScriptHash) EncodeAddress() string {
+	str, err := encodeSegWitAddress(a.hrp, a.witnessVersion,
+		a.witnessProgram[:])
+	if err != nil {
+		return ""
+	}
+	return str
+}
+
+// ScriptAddress returns the witness program for this address.
+//
+// Part of the Address interface.
+func (a *AddressWitnessScriptHash) ScriptAddress() []byte {
+	return a.witnessProgram[:]
+}
+
+// IsForNet returns whether or not the AddressWitnessScriptHash is associated
+// with the passed network.
+//
+// Part of the Address interface.
+func (a *AddressWitnessScriptHash) IsForNet(net *chaincfg.Params) bool {
+	return a.hrp == net.Bech32HRPSegwit
+}
+
+// String returns a human-readable string for the AddressWitnessScriptHash.
+// This is equivalent to calling EncodeAddress, but is provided so the type
+// can be used as a fmt.Stringer.
+//
+// Part of the Address interface.
+func (a *AddressWitnessScriptHash) String() string {
+	return a.EncodeAddress()
+}
+
+// Hrp returns the human-readable part of the bech32 encoded
+// AddressWitnessScriptHash.
+func (a *AddressWitnessScriptHash) Hrp() string {
+	return a.hrp
+}
+
+// WitnessVersion returns the witness version of the AddressWitnessScriptHash.
+func (a *AddressWitnessScriptHash) WitnessVersion() byte {
+	return a.witnessVersion
+}
+
+// WitnessProgram returns the witness program of the AddressWitnessScriptHash.
+func (a *AddressWitnessScriptHash) WitnessProgram() []byte {
+	return a.witnessProgram[:]
+}
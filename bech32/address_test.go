@@ -0,0 +1,175 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bech32_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/bech32"
+	"github.com/conseweb/stcd/chaincfg"
+)
+
+// TestDecodeAddress ensures segwit addresses are decoded as specified by the
+// test vectors in BIP0173 and that other addresses are still decoded.
+func TestDecodeAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		net     *chaincfg.Params
+		valid   bool
+		program string
+	}{
+		{
+			name:    "mainnet p2wpkh upper case",
+			addr:    "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+			net:     &chaincfg.MainNetParams,
+			valid:   true,
+			program: "751e76e8199196d454941c45d1b3a323f1433bd6",
+		},
+		{
+			name:    "testnet p2wsh",
+			addr:    "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+			net:     &chaincfg.TestNet3Params,
+			valid:   true,
+			program: "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+		},
+		{
+			name:  "mainnet address on testnet",
+			addr:  "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			net:   &chaincfg.TestNet3Params,
+			valid: false,
+		},
+		{
+			name:  "invalid human-readable part",
+			addr:  "tc1qw508d6qejxtdg4y5r3zarvary0c5xw7kg3g4ty",
+			net:   &chaincfg.TestNet3Params,
+			valid: false,
+		},
+		{
+			name:  "invalid checksum",
+			addr:  "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5",
+			net:   &chaincfg.MainNetParams,
+			valid: false,
+		},
+		{
+			name:  "invalid witness version",
+			addr:  "BC13W508D6QEJXTDG4Y5R3ZARVARY0C5XW7KN40WF2",
+			net:   &chaincfg.MainNetParams,
+			valid: false,
+		},
+		{
+			name:  "invalid program length",
+			addr:  "bc1rw5uspcuh",
+			net:   &chaincfg.MainNetParams,
+			valid: false,
+		},
+		{
+			name:  "mixed case",
+			addr:  "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sL5k7",
+			net:   &chaincfg.TestNet3Params,
+			valid: false,
+		},
+		{
+			name:  "non-zero padding",
+			addr:  "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3pjxtptv",
+			net:   &chaincfg.TestNet3Params,
+			valid: false,
+		},
+		{
+			name:  "empty data",
+			addr:  "bc1gmk9yu",
+			net:   &chaincfg.MainNetParams,
+			valid: false,
+		},
+	}
+
+	for _, test := range tests {
+		addr, err := bech32.DecodeAddress(test.addr, test.net)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("DecodeAddress (%s): expected error",
+					test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("DecodeAddress (%s): unexpected error: %v",
+				test.name, err)
+			continue
+		}
+
+		program, _ := hex.DecodeString(test.program)
+		if !bytes.Equal(addr.ScriptAddress(), program) {
+			t.Errorf("ScriptAddress (%s): got %x, want %x",
+				test.name, addr.ScriptAddress(), program)
+		}
+		if !addr.IsForNet(test.net) {
+			t.Errorf("IsForNet (%s): address is not for its "+
+				"network", test.name)
+		}
+		if addr.EncodeAddress() != strings.ToLower(test.addr) {
+			t.Errorf("EncodeAddress (%s): got %s, want %s",
+				test.name, addr.EncodeAddress(),
+				strings.ToLower(test.addr))
+		}
+
+		switch len(program) {
+		case 20:
+			if _, ok := addr.(*bech32.AddressWitnessPubKeyHash); !ok {
+				t.Errorf("DecodeAddress (%s): got type %T, "+
+					"want *AddressWitnessPubKeyHash",
+					test.name, addr)
+			}
+		case 32:
+			if _, ok := addr.(*bech32.AddressWitnessScriptHash); !ok {
+				t.Errorf("DecodeAddress (%s): got type %T, "+
+					"want *AddressWitnessScriptHash",
+					test.name, addr)
+			}
+		}
+	}
+
+	// Base58 addresses must still decode.
+	addr, err := bech32.DecodeAddress("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("DecodeAddress (base58): unexpected error: %v", err)
+	}
+	if _, ok := addr.(*coinutil.AddressPubKeyHash); !ok {
+		t.Errorf("DecodeAddress (base58): got type %T, want "+
+			"*coinutil.AddressPubKeyHash", addr)
+	}
+}
+
+// TestNewAddressWitness ensures the segwit address constructors use the
+// human-readable part of the network and reject invalid program lengths.
+func TestNewAddressWitness(t *testing.T) {
+	program := make([]byte, 20)
+	addr, err := bech32.NewAddressWitnessPubKeyHash(program,
+		&chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressWitnessPubKeyHash: unexpected error: %v",
+			err)
+	}
+	if !strings.HasPrefix(addr.EncodeAddress(), "sb1") {
+		t.Errorf("NewAddressWitnessPubKeyHash: got %s, want sb1 prefix",
+			addr.EncodeAddress())
+	}
+	if addr.IsForNet(&chaincfg.MainNetParams) {
+		t.Errorf("NewAddressWitnessPubKeyHash: simnet address is for " +
+			"mainnet")
+	}
+
+	_, err = bech32.NewAddressWitnessScriptHash(program,
+		&chaincfg.SimNetParams)
+	if err != bech32.ErrInvalidProgramLength {
+		t.Errorf("NewAddressWitnessScriptHash: got error %v, want %v",
+			err, bech32.ErrInvalidProgramLength)
+	}
+}
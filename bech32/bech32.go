@@ -0,0 +1,189 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bech32
+
+import (
+	"fmt"
+	"strings"
+)
+
+// charset is the set of characters used in the data section of bech32
+// strings.  The index of a character is the 5-bit value it encodes.
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// gen holds the generator coefficients of the BCH code used for the checksum.
+var gen = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// maxLength is the maximum length of a bech32 string.
+const maxLength = 90
+
+// checksumLength is the number of 5-bit values in the checksum.
+const checksumLength = 6
+
+// polymod calculates the BCH checksum of the passed 5-bit values.
+func polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := uint(0); i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// hrpExpand expands the human-readable part into the values which are
+// prepended to the data when calculating the checksum.
+func hrpExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// createChecksum returns the checksum for the passed human-readable part and
+// data as 5-bit values.
+func createChecksum(hrp string, data []byte) []byte {
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, make([]byte, checksumLength)...)
+	mod := polymod(values) ^ 1
+	checksum := make([]byte, checksumLength)
+	for i := range checksum {
+		checksum[i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// Encode encodes the passed human-readable part and data, which must consist
+// of 5-bit values, into a bech32 string.
+func Encode(hrp string, data []byte) (string, error) {
+	if len(hrp)+1+len(data)+checksumLength > maxLength {
+		return "", fmt.Errorf("bech32 string length %d exceeds the "+
+			"maximum of %d", len(hrp)+1+len(data)+checksumLength,
+			maxLength)
+	}
+	if len(hrp) == 0 {
+		return "", fmt.Errorf("empty human-readable part")
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", fmt.Errorf("invalid character in "+
+				"human-readable part: %q", hrp[i])
+		}
+	}
+	if strings.ToLower(hrp) != hrp {
+		return "", fmt.Errorf("human-readable part %q is not lower "+
+			"case", hrp)
+	}
+
+	combined := append(append([]byte{}, data...),
+		createChecksum(hrp, data)...)
+	encoded := make([]byte, 0, len(hrp)+1+len(combined))
+	encoded = append(encoded, hrp...)
+	encoded = append(encoded, '1')
+	for _, v := range combined {
+		if v >= 32 {
+			return "", fmt.Errorf("invalid data value %d", v)
+		}
+		encoded = append(encoded, charset[v])
+	}
+	return string(encoded), nil
+}
+
+// Decode decodes the passed bech32 string into its human-readable part and
+// data, as 5-bit values, after verifying its checksum.  The returned
+// human-readable part is always lower case.
+func Decode(bech string) (string, []byte, error) {
+	if len(bech) < 8 || len(bech) > maxLength {
+		return "", nil, fmt.Errorf("invalid bech32 string length %d",
+			len(bech))
+	}
+
+	// Characters must be printable ASCII and the string must not mix
+	// upper and lower case.
+	for i := 0; i < len(bech); i++ {
+		if bech[i] < 33 || bech[i] > 126 {
+			return "", nil, fmt.Errorf("invalid character in "+
+				"bech32 string: %q", bech[i])
+		}
+	}
+	lower := strings.ToLower(bech)
+	if lower != bech && strings.ToUpper(bech) != bech {
+		return "", nil, fmt.Errorf("bech32 string %q mixes upper and "+
+			"lower case", bech)
+	}
+
+	// The human-readable part is everything before the last '1', and it
+	// must be followed by at least the checksum.
+	sep := strings.LastIndexByte(lower, '1')
+	if sep < 1 || sep+checksumLength+1 > len(lower) {
+		return "", nil, fmt.Errorf("invalid separator position %d",
+			sep)
+	}
+	hrp := lower[:sep]
+
+	data := make([]byte, 0, len(lower)-sep-1)
+	for i := sep + 1; i < len(lower); i++ {
+		v := strings.IndexByte(charset, lower[i])
+		if v == -1 {
+			return "", nil, fmt.Errorf("invalid character in "+
+				"bech32 data: %q", lower[i])
+		}
+		data = append(data, byte(v))
+	}
+
+	if polymod(append(hrpExpand(hrp), data...)) != 1 {
+		return "", nil, fmt.Errorf("invalid bech32 checksum")
+	}
+
+	return hrp, data[:len(data)-checksumLength], nil
+}
+
+// ConvertBits regroups the passed values of fromBits bits each into values of
+// toBits bits each.  When pad is true, the final value is padded with zero
+// bits as needed.  Otherwise, an error is returned if there are leftover bits
+// which are not all zero or if there are too many of them.
+func ConvertBits(data []byte, fromBits, toBits uint8, pad bool) ([]byte, error) {
+	if fromBits < 1 || fromBits > 8 || toBits < 1 || toBits > 8 {
+		return nil, fmt.Errorf("invalid bit group sizes %d and %d",
+			fromBits, toBits)
+	}
+
+	var acc uint32
+	var bits uint8
+	maxV := uint32(1)<<toBits - 1
+	regrouped := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, v := range data {
+		if v>>fromBits != 0 {
+			return nil, fmt.Errorf("value %d does not fit in %d "+
+				"bits", v, fromBits)
+		}
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			regrouped = append(regrouped, byte(acc>>bits&maxV))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			regrouped = append(regrouped,
+				byte(acc<<(toBits-bits)&maxV))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxV != 0 {
+		return nil, fmt.Errorf("invalid padding")
+	}
+
+	return regrouped, nil
+}
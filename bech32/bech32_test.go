@@ -0,0 +1,101 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bech32_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/conseweb/stcd/bech32"
+)
+
+// TestBech32 ensures strings are decoded and their checksums verified as
+// specified by the test vectors in BIP0173.
+func TestBech32(t *testing.T) {
+	tests := []struct {
+		str   string
+		valid bool
+	}{
+		{"A12UEL5L", true},
+		{"a12uel5l", true},
+		{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", true},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", true},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", true},
+		{"?1ezyfcl", true},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e2w", false}, // invalid checksum
+		{"s lit1checkupstagehandshakeupstreamerranterredcaperredp8hs2p", false}, // invalid character (space) in hrp
+		{"x1b4n0q5v", false}, // invalid character (b) in data
+		{"li1dgmt3", false},  // too short data part
+		{"A1G7SGD8", false},  // checksum calculated with uppercase hrp
+		{"10a06t8", false},   // empty hrp
+		{"1qzzfhee", false},  // empty hrp
+		{"a12UEL5L", false},  // mixed case
+		{"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", false}, // too long
+	}
+
+	for i, test := range tests {
+		hrp, data, err := bech32.Decode(test.str)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Decode #%d (%s): expected error",
+					i, test.str)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Decode #%d (%s): unexpected error: %v", i,
+				test.str, err)
+			continue
+		}
+
+		// Encoding the decoded parts must result in the original
+		// string in lower case.
+		encoded, err := bech32.Encode(hrp, data)
+		if err != nil {
+			t.Errorf("Encode #%d (%s): unexpected error: %v", i,
+				test.str, err)
+			continue
+		}
+		if encoded != strings.ToLower(test.str) {
+			t.Errorf("Encode #%d: got %s, want %s", i, encoded,
+				strings.ToLower(test.str))
+		}
+	}
+}
+
+// TestConvertBits ensures regrouping bytes into 5-bit values and back works
+// as expected and rejects invalid padding.
+func TestConvertBits(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x7f, 0x80, 0xff, 0x42}
+	converted, err := bech32.ConvertBits(data, 8, 5, true)
+	if err != nil {
+		t.Fatalf("ConvertBits (8 to 5): unexpected error: %v", err)
+	}
+	for _, v := range converted {
+		if v >= 32 {
+			t.Fatalf("ConvertBits (8 to 5): value %d does not fit "+
+				"in 5 bits", v)
+		}
+	}
+	roundTrip, err := bech32.ConvertBits(converted, 5, 8, false)
+	if err != nil {
+		t.Fatalf("ConvertBits (5 to 8): unexpected error: %v", err)
+	}
+	if !bytes.Equal(roundTrip, data) {
+		t.Fatalf("ConvertBits: round trip mismatch -- got %x, want %x",
+			roundTrip, data)
+	}
+
+	// Non-zero padding bits must be rejected.
+	if _, err := bech32.ConvertBits([]byte{0x1f}, 5, 8, false); err == nil {
+		t.Errorf("ConvertBits: expected error for non-zero padding")
+	}
+
+	// Values which do not fit in the source group size must be rejected.
+	if _, err := bech32.ConvertBits([]byte{0x20}, 5, 8, true); err == nil {
+		t.Errorf("ConvertBits: expected error for oversized value")
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package bech32 provides the bech32 encoding defined by BIP0173 along with the
+native segregated witness address types encoded with it.
+
+A bech32 string consists of a human-readable part, the separator '1', and a
+data part made up of 5-bit values followed by a six character checksum.  The
+checksum detects any error affecting up to four characters.
+
+Segwit addresses use the human-readable part configured in the Bech32HRPSegwit
+field of chaincfg.Params for the network, such as "bc" for the main network.
+The AddressWitnessPubKeyHash and AddressWitnessScriptHash types implement the
+coinutil.Address interface so they can be used anywhere the other address
+types are.  DecodeAddress decodes segwit addresses for the passed network and
+falls back to coinutil.DecodeAddress for every other address.
+*/
+package bech32
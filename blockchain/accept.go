@@ -62,6 +62,13 @@ func (b *BlockChain) maybeAcceptBlock(block *coinutil.Block, flags BehaviorFlags
 		newNode.workSum.Add(prevNode.workSum, newNode.workSum)
 	}
 
+	// Track the median time of the block now that it is linked to its
+	// parent so it doesn't need to be recalculated when validating its
+	// children.
+	if _, err := b.calcPastMedianTime(newNode); err != nil {
+		return err
+	}
+
 	// Connect the passed block to the chain while respecting proper chain
 	// selection according to the chain with the most proof of work.  This
 	// also handles validation of the transaction scripts.
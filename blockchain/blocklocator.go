@@ -52,6 +52,11 @@ func (b *BlockChain) BlockLocatorFromHash(hash *wire.ShaHash) BlockLocator {
 	forkHeight := int32(-1)
 	node, exists := b.index[*hash]
 	if !exists {
+		// The block isn't known when the full index is kept in memory.
+		if b.fullIndex {
+			return locator
+		}
+
 		// Try to look up the height for passed block hash.  Assume an
 		// error means it doesn't exist and just return the locator for
 		// the block itself.
@@ -111,7 +116,12 @@ func (b *BlockChain) BlockLocatorFromHash(hash *wire.ShaHash) BlockLocator {
 		}
 
 		// The desired block height is in the main chain, so look it up
-		// from the main chain database.
+		// from the memory main chain when the full index is kept in
+		// memory and from the main chain database otherwise.
+		if n := b.mainChainNodeByHeight(blockHeight); n != nil {
+			locator = append(locator, n.hash)
+			continue
+		}
 		h, err := b.db.FetchBlockShaByHeight(blockHeight)
 		if err != nil {
 			// This shouldn't happen and it's ok to ignore block
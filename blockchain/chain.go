@@ -5,6 +5,7 @@
 package blockchain
 
 import (
+	"bytes"
 	"container/list"
 	"errors"
 	"fmt"
@@ -25,6 +26,10 @@ const (
 	// queued.
 	maxOrphanBlocks = 100
 
+	// maxOrphanBlocksSize is the maximum total serialized size in bytes of
+	// the orphan blocks that can be queued.
+	maxOrphanBlocksSize = 16 * wire.MaxBlockPayload
+
 	// minMemoryNodes is the minimum number of consecutive nodes needed
 	// in memory in order to perform all necessary validation.  It is used
 	// to determine when it's safe to prune nodes from memory without
@@ -74,6 +79,16 @@ type blockNode struct {
 	version   int32
 	bits      uint32
 	timestamp time.Time
+
+	// medianTime is the median timestamp of the previous few blocks up to
+	// and including this node.  It is calculated on demand and cached
+	// since it never changes for a given block.
+	medianTime time.Time
+
+	// utxoSetHash is the rolling hash of the unspent transaction outputs
+	// as of this block.  It is nil until it is calculated or loaded from
+	// the database and never changes once set.
+	utxoSetHash *utxoSetHash
 }
 
 // newBlockNode returns a new block node for the given block header.  It is
@@ -102,6 +117,7 @@ func newBlockNode(blockHeader *wire.BlockHeader, blockSha *wire.ShaHash, height
 // forever.
 type orphanBlock struct {
 	block      *coinutil.Block
+	size       int
 	expiration time.Time
 }
 
@@ -150,10 +166,12 @@ type BlockChain struct {
 	root                *blockNode
 	bestChain           *blockNode
 	index               map[wire.ShaHash]*blockNode
+	mainChain           []*blockNode
+	fullIndex           bool
 	depNodes            map[wire.ShaHash][]*blockNode
 	orphans             map[wire.ShaHash]*orphanBlock
 	prevOrphans         map[wire.ShaHash][]*orphanBlock
-	oldestOrphan        *orphanBlock
+	orphansSize         int
 	orphanLock          sync.RWMutex
 	blockCache          map[wire.ShaHash]*coinutil.Block
 	noVerify            bool
@@ -161,6 +179,14 @@ type BlockChain struct {
 	nextCheckpoint      *chaincfg.Checkpoint
 	checkpointBlock     *coinutil.Block
 	sigCache            *txscript.SigCache
+
+	// hooks implements the parts of block validation which differ between
+	// proof-of-work and proof-of-stake blocks.
+	hooks ConsensusHooks
+
+	// deploymentCaches caches the threshold states of each rule change
+	// deployment defined by the chain params.
+	deploymentCaches []thresholdStateCache
 }
 
 // DisableVerify provides a mechanism to disable transaction script validation
@@ -243,6 +269,9 @@ func (b *BlockChain) removeOrphanBlock(orphan *orphanBlock) {
 
 	// Remove the orphan block from the orphan pool.
 	orphanHash := orphan.block.Sha()
+	if _, exists := b.orphans[*orphanHash]; exists {
+		b.orphansSize -= orphan.size
+	}
 	delete(b.orphans, *orphanHash)
 
 	// Remove the reference from the previous orphan index too.  An indexing
@@ -269,32 +298,44 @@ func (b *BlockChain) removeOrphanBlock(orphan *orphanBlock) {
 	}
 }
 
+// oldestOrphanBlock returns the orphan block which expires first.
+//
+// This function MUST be called with the orphan lock held (for reads).
+func (b *BlockChain) oldestOrphanBlock() *orphanBlock {
+	var oldest *orphanBlock
+	for _, oBlock := range b.orphans {
+		if oldest == nil || oBlock.expiration.Before(oldest.expiration) {
+			oldest = oBlock
+		}
+	}
+	return oldest
+}
+
 // addOrphanBlock adds the passed block (which is already determined to be
 // an orphan prior calling this function) to the orphan pool.  It lazily cleans
 // up any expired blocks so a separate cleanup poller doesn't need to be run.
-// It also imposes a maximum limit on the number of outstanding orphan
-// blocks and will remove the oldest received orphan block if the limit is
-// exceeded.
+// It also imposes a maximum limit on the number and total size of outstanding
+// orphan blocks and will remove the oldest received orphan blocks until the
+// new block fits within the limits.
 func (b *BlockChain) addOrphanBlock(block *coinutil.Block) {
 	// Remove expired orphan blocks.
 	for _, oBlock := range b.orphans {
 		if time.Now().After(oBlock.expiration) {
 			b.removeOrphanBlock(oBlock)
-			continue
-		}
-
-		// Update the oldest orphan block pointer so it can be discarded
-		// in case the orphan pool fills up.
-		if b.oldestOrphan == nil || oBlock.expiration.Before(b.oldestOrphan.expiration) {
-			b.oldestOrphan = oBlock
 		}
 	}
 
-	// Limit orphan blocks to prevent memory exhaustion.
-	if len(b.orphans)+1 > maxOrphanBlocks {
+	// Limit orphan blocks to prevent memory exhaustion.  The block is
+	// never larger than the size limit since it passed the sanity checks.
+	blockSize := block.MsgBlock().SerializeSize()
+	for len(b.orphans) > 0 && (len(b.orphans)+1 > maxOrphanBlocks ||
+		b.orphansSize+blockSize > maxOrphanBlocksSize) {
+
 		// Remove the oldest orphan to make room for the new one.
-		b.removeOrphanBlock(b.oldestOrphan)
-		b.oldestOrphan = nil
+		b.orphanLock.RLock()
+		oldest := b.oldestOrphanBlock()
+		b.orphanLock.RUnlock()
+		b.removeOrphanBlock(oldest)
 	}
 
 	// Protect concurrent access.  This is intentionally done here instead
@@ -308,9 +349,11 @@ func (b *BlockChain) addOrphanBlock(block *coinutil.Block) {
 	expiration := time.Now().Add(time.Hour)
 	oBlock := &orphanBlock{
 		block:      block,
+		size:       blockSize,
 		expiration: expiration,
 	}
 	b.orphans[*block.Sha()] = oBlock
+	b.orphansSize += blockSize
 
 	// Add to previous hash lookup index for faster dependency lookups.
 	prevHash := &block.MsgBlock().Header.PrevBlock
@@ -319,12 +362,134 @@ func (b *BlockChain) addOrphanBlock(block *coinutil.Block) {
 	return
 }
 
-// GenerateInitialIndex is an optional function which generates the required
-// number of initial block nodes in an optimized fashion.  This is optional
-// because the memory block index is sparse and previous nodes are dynamically
-// loaded as needed.  However, during initial startup (when there are no nodes
-// in memory yet), dynamically loading all of the required nodes on the fly in
-// the usual way is much slower than preloading them.
+// OrphanChain describes the orphan blocks which descend from the same block
+// which is not known.
+type OrphanChain struct {
+	// RootPrevHash is the hash of the unknown block the orphans descend
+	// from.
+	RootPrevHash wire.ShaHash
+
+	// Length is the number of blocks in the longest chain of orphans.
+	Length int
+
+	// NumBlocks is the total number of orphans which descend from the
+	// unknown block.
+	NumBlocks int
+
+	// TotalSize is the total serialized size of the orphans in bytes.
+	TotalSize int
+
+	// TipHash is the hash of the last block in the longest chain of
+	// orphans.
+	TipHash wire.ShaHash
+}
+
+// walkOrphans calls the passed function for each of the passed orphans and
+// all orphans which descend from them, parents first, along with their depth
+// relative to the passed orphans.
+//
+// This function MUST be called with the orphan lock held (for reads).
+func (b *BlockChain) walkOrphans(orphans []*orphanBlock, depth int, fn func(*orphanBlock, int)) {
+	for _, orphan := range orphans {
+		fn(orphan, depth)
+		b.walkOrphans(b.prevOrphans[*orphan.block.Sha()], depth+1, fn)
+	}
+}
+
+// orphanRoots returns the hashes of the unknown blocks orphans descend from.
+//
+// This function MUST be called with the orphan lock held (for reads).
+func (b *BlockChain) orphanRoots() []wire.ShaHash {
+	roots := make([]wire.ShaHash, 0, len(b.prevOrphans))
+	for prevHash := range b.prevOrphans {
+		if _, exists := b.orphans[prevHash]; !exists {
+			roots = append(roots, prevHash)
+		}
+	}
+	return roots
+}
+
+// OrphanChains returns a description of each chain of orphan blocks currently
+// in the orphan pool, sorted by descending total size.  Orphans which descend
+// from the same unknown block are described together.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) OrphanChains() []OrphanChain {
+	b.orphanLock.RLock()
+	defer b.orphanLock.RUnlock()
+
+	roots := b.orphanRoots()
+	chains := make([]OrphanChain, 0, len(roots))
+	for _, root := range roots {
+		chain := OrphanChain{RootPrevHash: root}
+		b.walkOrphans(b.prevOrphans[root], 1, func(orphan *orphanBlock, depth int) {
+			chain.NumBlocks++
+			chain.TotalSize += orphan.size
+			if depth > chain.Length {
+				chain.Length = depth
+				chain.TipHash = *orphan.block.Sha()
+			}
+		})
+		chains = append(chains, chain)
+	}
+
+	sort.Sort(orphanChainSorter(chains))
+	return chains
+}
+
+// OrphanBlocks returns all blocks currently in the orphan pool.  Each block is
+// returned after its parent when the parent is also an orphan.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) OrphanBlocks() []*coinutil.Block {
+	b.orphanLock.RLock()
+	defer b.orphanLock.RUnlock()
+
+	blocks := make([]*coinutil.Block, 0, len(b.orphans))
+	for _, root := range b.orphanRoots() {
+		b.walkOrphans(b.prevOrphans[root], 1, func(orphan *orphanBlock, depth int) {
+			blocks = append(blocks, orphan.block)
+		})
+	}
+	return blocks
+}
+
+// orphanChainSorter implements sort.Interface to allow a slice of orphan chains
+// to be sorted by descending total size.  Chains of the same size are sorted
+// by root hash so the order is stable.
+type orphanChainSorter []OrphanChain
+
+// Len returns the number of orphan chains in the slice.  It is part of the
+// sort.Interface implementation.
+func (s orphanChainSorter) Len() int {
+	return len(s)
+}
+
+// Swap swaps the orphan chains at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s orphanChainSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the orphan chain with index i should sort before the
+// orphan chain with index j.  It is part of the sort.Interface implementation.
+func (s orphanChainSorter) Less(i, j int) bool {
+	if s[i].TotalSize != s[j].TotalSize {
+		return s[i].TotalSize > s[j].TotalSize
+	}
+	return bytes.Compare(s[i].RootPrevHash[:], s[j].RootPrevHash[:]) < 0
+}
+
+// GenerateInitialIndex is an optional function which loads the block nodes of
+// the entire main chain into the memory block index.  Only the block headers
+// are read from the database; the block bodies are still fetched lazily when
+// they are needed.
+//
+// Once the full index has been generated, it is kept in memory for the life of
+// the chain instance.  Nodes are no longer pruned, and block existence checks,
+// best chain selection and block locator construction are served from memory
+// without any database round trips.  Without calling this function, the memory
+// block index is sparse and previous nodes are dynamically loaded as needed.
 //
 // This function can only be called once and it must be called before any nodes
 // are added to the block index.  ErrIndexAlreadyInitialized is returned if
@@ -342,22 +507,12 @@ func (b *BlockChain) GenerateInitialIndex() error {
 		return err
 	}
 
-	// Calculate the starting height based on the minimum number of nodes
-	// needed in memory.
-	startHeight := endHeight - minMemoryNodes
-	if startHeight < 0 {
-		startHeight = 0
-	}
-
-	// Loop forwards through each block loading the node into the index for
-	// the block.
-	//
-	// Due to a bug in the SQLite btcdb driver, the FetchBlockBySha call is
-	// limited to a maximum number of hashes per invocation.  Since SQLite
-	// is going to be nuked eventually, the bug isn't being fixed in the
-	// driver.  In the mean time, work around the issue by calling
-	// FetchBlockBySha multiple times with the appropriate indices as needed.
-	for start := startHeight; start <= endHeight; {
+	// Loop forwards through each block of the main chain loading the node
+	// into the index for the block.  The database returns a limited number
+	// of hashes per call, so keep asking for the remaining ones until it
+	// runs out.
+	mainChain := make([]*blockNode, 0, endHeight+1)
+	for start := int32(0); start <= endHeight; {
 		hashList, err := b.db.FetchHeightRange(start, endHeight+1)
 		if err != nil {
 			return err
@@ -369,17 +524,40 @@ func (b *BlockChain) GenerateInitialIndex() error {
 			break
 		}
 
-		// Loop forwards through each block loading the node into the
-		// index for the block.
-		for _, hash := range hashList {
+		for i := range hashList {
 			// Make a copy of the hash to make sure there are no
 			// references into the list so it can be freed.
-			hashCopy := hash
-			node, err := b.loadBlockNode(&hashCopy)
+			hash := hashList[i]
+			height := start + int32(i)
+			blockHeader, err := b.db.FetchBlockHeaderBySha(&hash)
 			if err != nil {
 				return err
 			}
 
+			// Link the node to the previous one, which is the end of
+			// the main chain loaded so far.
+			node := newBlockNode(blockHeader, &hash, height)
+			node.inMainChain = true
+			if parent := b.bestChain; parent != nil {
+				if !parent.hash.IsEqual(&blockHeader.PrevBlock) {
+					str := "GenerateInitialIndex: block %v at " +
+						"height %d does not connect to %v"
+					return fmt.Errorf(str, hash, height,
+						parent.hash)
+				}
+				node.workSum.Add(parent.workSum, node.workSum)
+				parent.children = append(parent.children, node)
+				node.parent = parent
+			} else {
+				b.root = node
+			}
+
+			// Add the new node to the indices for faster lookups.
+			b.index[hash] = node
+			prevHash := node.parentHash
+			b.depNodes[*prevHash] = append(b.depNodes[*prevHash], node)
+			mainChain = append(mainChain, node)
+
 			// This node is now the end of the best chain.
 			b.bestChain = node
 		}
@@ -389,9 +567,23 @@ func (b *BlockChain) GenerateInitialIndex() error {
 		start += int32(len(hashList))
 	}
 
+	b.mainChain = mainChain
+	b.fullIndex = true
+	log.Infof("Loaded %d block headers into the block index",
+		len(mainChain))
 	return nil
 }
 
+// mainChainNodeByHeight returns the main chain node at the passed height.  It
+// returns nil when the full block index hasn't been generated or the height
+// is out of the range of the main chain.
+func (b *BlockChain) mainChainNodeByHeight(height int32) *blockNode {
+	if !b.fullIndex || height < 0 || height >= int32(len(b.mainChain)) {
+		return nil
+	}
+	return b.mainChain[height]
+}
+
 // loadBlockNode loads the block identified by hash from the block database,
 // creates a block node from it, and updates the memory block chain accordingly.
 // It is used mainly to dynamically load previous blocks from database as they
@@ -559,8 +751,9 @@ func (b *BlockChain) removeBlockNode(node *blockNode) error {
 // chain are needed in memory.  This function walks the chain backwards from the
 // current best chain to find any nodes before the first needed block node.
 func (b *BlockChain) pruneBlockNodes() error {
-	// Nothing to do if there is not a best chain selected yet.
-	if b.bestChain == nil {
+	// Nothing to do if there is not a best chain selected yet or the full
+	// block index is kept in memory.
+	if b.bestChain == nil || b.fullIndex {
 		return nil
 	}
 
@@ -643,6 +836,11 @@ func (b *BlockChain) calcPastMedianTime(startNode *blockNode) (time.Time, error)
 		return b.chainParams.GenesisBlock.Header.Timestamp, nil
 	}
 
+	// Use the cached median time when it has already been calculated.
+	if !startNode.medianTime.IsZero() {
+		return startNode.medianTime, nil
+	}
+
 	// Create a slice of the previous few block timestamps used to calculate
 	// the median per the number defined by the constant medianTimeBlocks.
 	timestamps := make([]time.Time, medianTimeBlocks)
@@ -666,9 +864,15 @@ func (b *BlockChain) calcPastMedianTime(startNode *blockNode) (time.Time, error)
 	}
 
 	// Prune the slice to the actual number of available timestamps which
-	// will be fewer than desired near the beginning of the block chain
-	// and sort them.
-	timestamps = timestamps[:numNodes]
+	// will be fewer than desired near the beginning of the block chain.
+	medianTimestamp := calcMedianTimestamp(timestamps[:numNodes])
+	startNode.medianTime = medianTimestamp
+	return medianTimestamp, nil
+}
+
+// calcMedianTimestamp sorts the passed timestamps and returns their median as
+// it is defined by the consensus rules.
+func calcMedianTimestamp(timestamps []time.Time) time.Time {
 	sort.Sort(timeSorter(timestamps))
 
 	// NOTE: bitcoind incorrectly calculates the median for even numbers of
@@ -683,8 +887,32 @@ func (b *BlockChain) calcPastMedianTime(startNode *blockNode) (time.Time, error)
 	// This code follows suit to ensure the same rules are used as bitcoind
 	// however, be aware that should the medianTimeBlocks constant ever be
 	// changed to an even number, this code will be wrong.
-	medianTimestamp := timestamps[numNodes/2]
-	return medianTimestamp, nil
+	return timestamps[len(timestamps)/2]
+}
+
+// PastMedianTime calculates the median time of the previous few blocks prior
+// to, and including, the block with the passed header and height in the same
+// way it is calculated to validate block timestamps.  The headers of the
+// previous blocks are looked up with the passed function, so it works for
+// blocks which are not held in the memory block chain, such as old main chain
+// blocks in the block database or the headers of a header chain.
+//
+// This function is safe for concurrent access as long as fetchHeader is.
+func PastMedianTime(header *wire.BlockHeader, height int32,
+	fetchHeader func(hash *wire.ShaHash) (*wire.BlockHeader, error)) (time.Time, error) {
+
+	timestamps := make([]time.Time, 0, medianTimeBlocks)
+	for i := int32(0); i < medianTimeBlocks && i <= height; i++ {
+		if i > 0 {
+			var err error
+			header, err = fetchHeader(&header.PrevBlock)
+			if err != nil {
+				return time.Time{}, err
+			}
+		}
+		timestamps = append(timestamps, header.Timestamp)
+	}
+	return calcMedianTimestamp(timestamps), nil
 }
 
 // CalcPastMedianTime calculates the median time of the previous few blocks
@@ -752,17 +980,52 @@ func (b *BlockChain) connectBlock(node *blockNode, block *coinutil.Block) error
 			"that extends the main chain")
 	}
 
+	// Roll the UTXO set hash forward when it is known as of the previous
+	// block but wasn't calculated while the block was checked, which is
+	// the case for blocks before the latest checkpoint.
+	if node.utxoSetHash == nil && node.height > 0 {
+		prevNode, err := b.getPrevNodeFromNode(node)
+		if err != nil {
+			return err
+		}
+		prevUtxoSetHash, err := b.knownUtxoSetHash(prevNode)
+		if err != nil {
+			return err
+		}
+		if prevUtxoSetHash != nil {
+			txStore := fetchSpentTxStore(b.db, block, node.height)
+			h := prevUtxoSetHash.clone()
+			err := h.connectBlock(block, node.height, txStore)
+			if err != nil {
+				return err
+			}
+			node.utxoSetHash = h
+		}
+	}
+
 	// Insert the block into the database which houses the main chain.
 	_, err := b.db.InsertBlock(block)
 	if err != nil {
 		return err
 	}
 
+	// Store the UTXO set hash as of the block, which the coinbase of the
+	// next block commits to once the rule change is active.
+	if node.utxoSetHash != nil && node.height > 0 {
+		err := b.storeUtxoSetHash(node.hash, node.utxoSetHash)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Add the new node to the memory main chain indices for faster
 	// lookups.
 	node.inMainChain = true
 	b.index[*node.hash] = node
 	b.depNodes[*prevHash] = append(b.depNodes[*prevHash], node)
+	if b.fullIndex {
+		b.mainChain = append(b.mainChain[:node.height], node)
+	}
 
 	// This node is now the end of the best chain.
 	b.bestChain = node
@@ -797,6 +1060,9 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *coinutil.Block) err
 	// Put block in the side chain cache.
 	node.inMainChain = false
 	b.blockCache[*node.hash] = block
+	if b.fullIndex {
+		b.mainChain = b.mainChain[:node.height]
+	}
 
 	// This node's parent is now the end of the best chain.
 	b.bestChain = node.parent
@@ -845,7 +1111,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 	for e := attachNodes.Front(); e != nil; e = e.Next() {
 		n := e.Value.(*blockNode)
 		block := b.blockCache[*n.hash]
-		err := b.checkConnectBlock(n, block)
+		err := b.checkConnectBlock(n, block, BFNone)
 		if err != nil {
 			return err
 		}
@@ -857,7 +1123,15 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 		return nil
 	}
 
-	// Disconnect blocks from the main chain.
+	// Disconnect blocks from the main chain.  The detach list starts with
+	// the tip of the main chain, so the disconnected notifications are sent
+	// in reverse order, and all of them are sent before the connected
+	// notifications of the new best chain blocks.  Notification consumers
+	// such as websocket clients rely on this ordering.
+	reorg := ChainReorganization{
+		Detached: make([]*wire.ShaHash, 0, detachNodes.Len()),
+		Attached: make([]*wire.ShaHash, 0, attachNodes.Len()),
+	}
 	for e := detachNodes.Front(); e != nil; e = e.Next() {
 		n := e.Value.(*blockNode)
 		block, err := b.db.FetchBlockBySha(n.hash)
@@ -868,6 +1142,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 		if err != nil {
 			return err
 		}
+		reorg.Detached = append(reorg.Detached, n.hash)
 	}
 
 	// Connect the new best chain blocks.
@@ -879,6 +1154,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 			return err
 		}
 		delete(b.blockCache, *n.hash)
+		reorg.Attached = append(reorg.Attached, n.hash)
 	}
 
 	// Log the point where the chain forked.
@@ -894,6 +1170,12 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 	log.Infof("REORGANIZE: Old best chain head was %v", firstDetachNode.hash)
 	log.Infof("REORGANIZE: New best chain head is %v", lastAttachNode.hash)
 
+	// Notify the caller that the main chain was reorganized.  The common
+	// ancestor is the parent of the first attached block.
+	reorg.ForkHash = firstAttachNode.parentHash
+	reorg.ForkHeight = firstAttachNode.height - 1
+	b.sendNotification(NTChainReorganized, &reorg)
+
 	return nil
 }
 
@@ -907,6 +1189,8 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 // The flags modify the behavior of this function as follows:
 //  - BFFastAdd: Avoids the call to checkConnectBlock which does several
 //    expensive transaction validation operations.
+//  - BFAssumeValid: Avoids the transaction script validation when the block
+//    extends the main chain.
 //  - BFDryRun: Prevents the block from being connected and avoids modifying the
 //    state of the memory chain index.  Also, any log messages related to
 //    modifying the state are avoided.
@@ -923,7 +1207,7 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *coinutil.Block, fl
 		// violating any rules and without actually connecting the
 		// block.
 		if !fastAdd {
-			err := b.checkConnectBlock(node, block)
+			err := b.checkConnectBlock(node, block, flags)
 			if err != nil {
 				return err
 			}
@@ -1093,6 +1377,8 @@ func New(db database.Db, params *chaincfg.Params, c NotificationCallback, sigCac
 		orphans:             make(map[wire.ShaHash]*orphanBlock),
 		prevOrphans:         make(map[wire.ShaHash][]*orphanBlock),
 		blockCache:          make(map[wire.ShaHash]*coinutil.Block),
+		hooks:               powConsensus{},
+		deploymentCaches:    newThresholdCaches(chaincfg.DefinedDeployments),
 	}
 	return &b
 }
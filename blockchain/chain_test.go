@@ -5,11 +5,15 @@
 package blockchain_test
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/stcd/blockchain"
 	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/database"
 	"github.com/conseweb/stcd/wire"
 )
 
@@ -112,3 +116,144 @@ func TestHaveBlock(t *testing.T) {
 		}
 	}
 }
+
+// TestGenerateInitialIndex ensures the full block index generated on startup
+// serves block existence checks and block locators without going to the
+// database.
+func TestGenerateInitialIndex(t *testing.T) {
+	db, err := database.CreateDB("memdb")
+	if err != nil {
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	defer db.Close()
+
+	// Build a main chain of blocks which only contain a coinbase on top
+	// of the genesis block.  The blocks are inserted into the database
+	// directly since they are not valid.
+	const numBlocks = 100
+	params := &chaincfg.MainNetParams
+	hashes := []wire.ShaHash{*params.GenesisHash}
+	genesis := coinutil.NewBlock(params.GenesisBlock)
+	if _, err := db.InsertBlock(genesis); err != nil {
+		t.Fatalf("InsertBlock: %v", err)
+	}
+	for i := 1; i < numBlocks; i++ {
+		coinbase := wire.NewMsgTx()
+		coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&wire.ShaHash{},
+			wire.MaxPrevOutIndex), []byte{byte(i), byte(i >> 8)}))
+		coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+		msgBlock := wire.NewMsgBlock(wire.NewBlockHeader(&hashes[i-1],
+			&wire.ShaHash{}, params.PowLimitBits, uint32(i)))
+		msgBlock.AddTransaction(coinbase)
+		block := coinutil.NewBlock(msgBlock)
+		if _, err := db.InsertBlock(block); err != nil {
+			t.Fatalf("InsertBlock #%d: %v", i, err)
+		}
+		hashes = append(hashes, *block.Sha())
+	}
+
+	chain := blockchain.New(db, params, nil, nil)
+	if err := chain.GenerateInitialIndex(); err != nil {
+		t.Fatalf("GenerateInitialIndex: %v", err)
+	}
+	if err := chain.GenerateInitialIndex(); err != blockchain.ErrIndexAlreadyInitialized {
+		t.Fatalf("GenerateInitialIndex: got %v on second call, want "+
+			"ErrIndexAlreadyInitialized", err)
+	}
+
+	// The expected locator of the tip holds the 11 most recent hashes and
+	// then steps back exponentially down to the genesis block.
+	var want blockchain.BlockLocator
+	step := 1
+	for height := numBlocks - 1; height > 0; height -= step {
+		want = append(want, &hashes[height])
+		if len(want) > 10 {
+			step *= 2
+		}
+	}
+	want = append(want, params.GenesisHash)
+
+	// Close the database so any lookup that isn't served from the memory
+	// block index fails.
+	db.Close()
+
+	locator, err := chain.LatestBlockLocator()
+	if err != nil {
+		t.Fatalf("LatestBlockLocator: %v", err)
+	}
+	if !reflect.DeepEqual(locator, want) {
+		t.Fatalf("LatestBlockLocator: got %v, want %v", locator, want)
+	}
+	for _, height := range []int{0, 1, 50, numBlocks - 1} {
+		have, err := chain.HaveBlock(&hashes[height])
+		if err != nil || !have {
+			t.Fatalf("HaveBlock(%d): got %v err %v, want true",
+				height, have, err)
+		}
+	}
+	unknown := wire.ShaHash{0x01}
+	if have, err := chain.HaveBlock(&unknown); err != nil || have {
+		t.Fatalf("HaveBlock(unknown): got %v err %v, want false", have,
+			err)
+	}
+	locator = chain.BlockLocatorFromHash(&unknown)
+	if len(locator) != 1 || *locator[0] != unknown {
+		t.Fatalf("BlockLocatorFromHash(unknown): got %v", locator)
+	}
+}
+
+// TestPastMedianTime ensures the median time of a block is calculated from the
+// timestamps of the block and the blocks before it, using the upper middle
+// timestamp when there is an even number of them near the start of the chain.
+func TestPastMedianTime(t *testing.T) {
+	// The timestamps are out of order to make sure they are sorted.
+	timestamps := []int64{10, 30, 20, 50, 40, 70, 60, 90, 80, 110, 100, 130,
+		120, 150, 140}
+	headers := make(map[wire.ShaHash]*wire.BlockHeader)
+	var prevHash wire.ShaHash
+	var chain []*wire.BlockHeader
+	for _, timestamp := range timestamps {
+		header := &wire.BlockHeader{
+			PrevBlock: prevHash,
+			Timestamp: time.Unix(timestamp, 0),
+		}
+		prevHash = header.BlockSha()
+		headers[prevHash] = header
+		chain = append(chain, header)
+	}
+	fetchHeader := func(hash *wire.ShaHash) (*wire.BlockHeader, error) {
+		header, ok := headers[*hash]
+		if !ok {
+			return nil, fmt.Errorf("header %v not found", hash)
+		}
+		return header, nil
+	}
+
+	tests := []struct {
+		height int32
+		want   int64
+	}{
+		{0, 10},
+		{1, 30},
+		{3, 30},
+		{10, 60},
+		{14, 100},
+	}
+	for _, test := range tests {
+		got, err := blockchain.PastMedianTime(chain[test.height],
+			test.height, fetchHeader)
+		if err != nil {
+			t.Fatalf("PastMedianTime(%d): %v", test.height, err)
+		}
+		if got.Unix() != test.want {
+			t.Errorf("PastMedianTime(%d): got %d want %d",
+				test.height, got.Unix(), test.want)
+		}
+	}
+
+	// Errors looking up previous headers are returned.
+	delete(headers, chain[12].BlockSha())
+	if _, err := blockchain.PastMedianTime(chain[14], 14, fetchHeader); err == nil {
+		t.Errorf("PastMedianTime: missing header not reported")
+	}
+}
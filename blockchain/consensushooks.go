@@ -0,0 +1,260 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+var (
+	// BlockSignatureMagicBytes is the prefix marker within the public key
+	// script of a coinbase output to indicate that this output holds the
+	// signature of a proof-of-stake block.  The signature is pushed
+	// directly after it.
+	BlockSignatureMagicBytes = []byte{
+		txscript.OP_RETURN,
+		txscript.OP_DATA_4,
+		0x73,
+		0x69,
+		0x67,
+		0x6e,
+	}
+)
+
+// ConsensusHooks defines the parts of block validation which differ between
+// proof-of-work and proof-of-stake blocks.  It allows stake based validation to
+// be layered on top of the rules implemented by this package without changing
+// them.
+//
+// The hooks are called with the chain lock held, so they must not call back
+// into the BlockChain instance they are installed on.
+type ConsensusHooks interface {
+	// CheckBlockHeader performs the checks which prove the passed header
+	// is allowed to extend the block chain at the passed height.  It is
+	// called for every header after the checks which depend on its block
+	// type and position in the chain have passed.  The block type is the
+	// one the header has at its height, which is always BlockTypePoW below
+	// the ProofOfStakeHeight of the network.
+	//
+	// Proof-of-stake headers are exempt from the proof of work and
+	// difficulty retarget checks, so the implementation is responsible for
+	// enforcing the equivalent rules for them.  The flags are those the
+	// block is being processed with.
+	CheckBlockHeader(header *wire.BlockHeader, blockType wire.BlockType, height int32, flags BehaviorFlags) error
+
+	// CalcBlockSubsidy returns the subsidy the coinbase of the passed block
+	// may claim in addition to the transaction fees.  The block is nil when
+	// the subsidy is calculated for a block which does not exist yet, such
+	// as the next block of a block template.
+	CalcBlockSubsidy(block *coinutil.Block, height int32, chainParams *chaincfg.Params) int64
+
+	// CheckBlockSignature verifies the signature of the passed block.  The
+	// signature of a proof-of-stake block is returned by
+	// ExtractBlockSignature and signs the hash returned by
+	// BlockSignatureHash.  The passed store contains the outputs spent by
+	// the block, which allows the signature to be checked against the
+	// owner of the stake.  It is called after all of the transactions in
+	// the block have been validated.
+	CheckBlockSignature(block *coinutil.Block, blockType wire.BlockType, txStore TxStore) error
+}
+
+// powConsensus implements the ConsensusHooks interface with the rules of a
+// proof-of-work only chain.  It is used unless other hooks are installed with
+// SetConsensusHooks.
+type powConsensus struct{}
+
+// Ensure the powConsensus type implements the ConsensusHooks interface.
+var _ ConsensusHooks = powConsensus{}
+
+// CheckBlockHeader rejects proof-of-stake headers.  Proof-of-work headers have
+// been fully checked by the time it is called.
+//
+// This is part of the ConsensusHooks interface implementation.
+func (powConsensus) CheckBlockHeader(header *wire.BlockHeader, blockType wire.BlockType, height int32, flags BehaviorFlags) error {
+	if blockType != wire.BlockTypePoW {
+		str := fmt.Sprintf("block type %v is not supported", blockType)
+		return ruleError(ErrUnsupportedBlockType, str)
+	}
+	return nil
+}
+
+// CalcBlockSubsidy returns the subsidy defined by CalcBlockSubsidy.
+//
+// This is part of the ConsensusHooks interface implementation.
+func (powConsensus) CalcBlockSubsidy(block *coinutil.Block, height int32, chainParams *chaincfg.Params) int64 {
+	return CalcBlockSubsidy(height, chainParams)
+}
+
+// CheckBlockSignature rejects proof-of-stake blocks.  Proof-of-work blocks are
+// not signed.
+//
+// This is part of the ConsensusHooks interface implementation.
+func (powConsensus) CheckBlockSignature(block *coinutil.Block, blockType wire.BlockType, txStore TxStore) error {
+	if blockType != wire.BlockTypePoW {
+		str := fmt.Sprintf("block type %v is not supported", blockType)
+		return ruleError(ErrUnsupportedBlockType, str)
+	}
+	return nil
+}
+
+// SetConsensusHooks installs the passed hooks, which replace the rules of a
+// proof-of-work only chain for the parts of block validation they define.
+// Passing nil restores the proof-of-work rules.
+//
+// This function MUST be called before any blocks are processed.
+func (b *BlockChain) SetConsensusHooks(hooks ConsensusHooks) {
+	if hooks == nil {
+		hooks = powConsensus{}
+	}
+	b.hooks = hooks
+}
+
+// CalcBlockSubsidy returns the subsidy the coinbase of a block at the passed
+// height may claim in addition to the transaction fees according to the
+// installed consensus hooks.  Unlike the package level CalcBlockSubsidy, it
+// therefore matches the subsidy blocks are validated against.
+func (b *BlockChain) CalcBlockSubsidy(height int32) int64 {
+	return b.hooks.CalcBlockSubsidy(nil, height, b.chainParams)
+}
+
+// calcBlockType returns the type of the block with the passed header at the
+// passed height.  The proof-of-stake bit of the block version only marks
+// proof-of-stake blocks from the ProofOfStakeHeight of the network, so earlier
+// blocks, which may set it as an ordinary version bit, are proof-of-work
+// blocks.
+func calcBlockType(header *wire.BlockHeader, height int32, chainParams *chaincfg.Params) wire.BlockType {
+	posHeight := chainParams.ProofOfStakeHeight
+	if posHeight == 0 || height < posHeight {
+		return wire.BlockTypePoW
+	}
+	return header.BlockType()
+}
+
+// extractBlockSignature returns the signature held by the passed coinbase
+// transaction along with the index of the output holding it.  The index is -1
+// when no output starts with the BlockSignatureMagicBytes, and the signature is
+// nil when the output does not push one yet.
+func extractBlockSignature(coinbase *wire.MsgTx) ([]byte, int, error) {
+	for i, txOut := range coinbase.TxOut {
+		pkScript := txOut.PkScript
+		if !bytes.HasPrefix(pkScript, BlockSignatureMagicBytes) {
+			continue
+		}
+
+		// The rest of the script must be a single push of the
+		// signature, if any.
+		sigScript := pkScript[len(BlockSignatureMagicBytes):]
+		if len(sigScript) == 0 {
+			return nil, i, nil
+		}
+		pushes, err := txscript.PushedData(sigScript)
+		if err != nil || len(pushes) != 1 ||
+			!txscript.IsPushOnlyScript(sigScript) {
+
+			str := fmt.Sprintf("coinbase output %d does not hold a "+
+				"single block signature push", i)
+			return nil, i, ruleError(ErrBadBlockSignature, str)
+		}
+		return pushes[0], i, nil
+	}
+	return nil, -1, nil
+}
+
+// ExtractBlockSignature returns the signature of the passed proof-of-stake
+// block.  The signature is pushed by the first output of the coinbase
+// transaction whose public key script starts with the BlockSignatureMagicBytes,
+// so the block hash commits to it through the merkle root.
+func ExtractBlockSignature(block *coinutil.Block) ([]byte, error) {
+	transactions := block.MsgBlock().Transactions
+	if len(transactions) == 0 {
+		return nil, ruleError(ErrNoTransactions, "block does not "+
+			"contain any transactions")
+	}
+
+	sig, _, err := extractBlockSignature(transactions[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) == 0 {
+		return nil, ruleError(ErrBadBlockSignature, "block is not "+
+			"signed")
+	}
+	if len(sig) > wire.MaxBlockSignatureLen {
+		str := fmt.Sprintf("block signature is %d bytes, which is more "+
+			"than the max allowed of %d", len(sig),
+			wire.MaxBlockSignatureLen)
+		return nil, ruleError(ErrBadBlockSignature, str)
+	}
+	return sig, nil
+}
+
+// BlockSignatureHash returns the hash the signature of the passed
+// proof-of-stake block signs.  It is the hash the block would have if the
+// coinbase output holding the signature only held the
+// BlockSignatureMagicBytes.  This allows the block hash to commit to the
+// signature while the signature signs everything else in the block.
+//
+// A block is therefore signed by adding an output holding only the magic bytes
+// to its coinbase, signing the hash returned by this function, then pushing the
+// signature onto the output and updating the merkle root of the header.
+func BlockSignatureHash(block *coinutil.Block) (wire.ShaHash, error) {
+	transactions := block.Transactions()
+	if len(transactions) == 0 {
+		return wire.ShaHash{}, ruleError(ErrNoTransactions, "block "+
+			"does not contain any transactions")
+	}
+
+	coinbase := transactions[0].MsgTx()
+	_, index, err := extractBlockSignature(coinbase)
+	if err != nil {
+		return wire.ShaHash{}, err
+	}
+	if index == -1 {
+		return wire.ShaHash{}, ruleError(ErrBadBlockSignature, "block "+
+			"does not have a signature output")
+	}
+
+	// Replace the output holding the signature in a copy of the coinbase
+	// and hash the header with the resulting merkle root.
+	unsigned := *coinbase
+	unsigned.TxOut = make([]*wire.TxOut, len(coinbase.TxOut))
+	copy(unsigned.TxOut, coinbase.TxOut)
+	unsigned.TxOut[index] = wire.NewTxOut(coinbase.TxOut[index].Value,
+		BlockSignatureMagicBytes)
+	unsignedTxns := make([]*coinutil.Tx, len(transactions))
+	unsignedTxns[0] = coinutil.NewTx(&unsigned)
+	copy(unsignedTxns[1:], transactions[1:])
+	merkles := BuildMerkleTreeStore(unsignedTxns, false)
+
+	header := block.MsgBlock().Header
+	header.MerkleRoot = *merkles[len(merkles)-1]
+	return header.BlockSha(), nil
+}
+
+// headerBlockType returns the type of the passed header at the passed height.
+// Headers which set the proof-of-stake bit skip the proof of work check of
+// checkBlockHeaderSanity since their height is not known there, so it is
+// performed here for the ones which turn out to be proof-of-work headers.
+//
+// The flags do not modify the behavior of this function directly, however they
+// are needed to pass along to checkProofOfWork.
+func (b *BlockChain) headerBlockType(header *wire.BlockHeader, height int32, flags BehaviorFlags) (wire.BlockType, error) {
+	blockType := calcBlockType(header, height, b.chainParams)
+	if blockType == wire.BlockTypePoW &&
+		header.BlockType() != wire.BlockTypePoW {
+
+		err := checkProofOfWork(header, b.chainParams.PowLimit, flags)
+		if err != nil {
+			return blockType, err
+		}
+	}
+	return blockType, nil
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// newHooksTestBlock returns a block with the passed version which passes the
+// context free checks apart from the proof of work, which it is unlikely to
+// satisfy.
+func newHooksTestBlock(version int32) *coinutil.Block {
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxTxInSequenceNum},
+		SignatureScript:  []byte{0x51, 0x51},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	coinbase.AddTxOut(wire.NewTxOut(0, []byte{0x51}))
+
+	msgBlock := wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    version,
+			MerkleRoot: coinbase.TxSha(),
+			Timestamp:  time.Unix(time.Now().Unix(), 0),
+			Bits:       chaincfg.MainNetParams.PowLimitBits,
+		},
+		Transactions: []*wire.MsgTx{coinbase},
+	}
+	return coinutil.NewBlock(&msgBlock)
+}
+
+// TestProofOfStakeSanity ensures proof-of-stake blocks are exempt from the
+// context free proof of work check.
+func TestProofOfStakeSanity(t *testing.T) {
+	powLimit := chaincfg.MainNetParams.PowLimit
+	timeSource := blockchain.NewMedianTime()
+
+	powBlock := newHooksTestBlock(1)
+	err := blockchain.CheckBlockSanity(powBlock, powLimit, timeSource)
+	if rerr, ok := err.(blockchain.RuleError); !ok ||
+		rerr.ErrorCode != blockchain.ErrHighHash {
+
+		t.Fatalf("CheckBlockSanity (proof of work): got error %v, "+
+			"want %v", err, blockchain.ErrHighHash)
+	}
+
+	posBlock := newHooksTestBlock(1 | wire.BlockVersionProofOfStake)
+	err = blockchain.CheckBlockSanity(posBlock, powLimit, timeSource)
+	if err != nil {
+		t.Fatalf("CheckBlockSanity (proof of stake): unexpected "+
+			"error: %v", err)
+	}
+}
+
+// TestPoWConsensusHooks ensures the default consensus hooks accept
+// proof-of-work blocks and reject proof-of-stake blocks.
+func TestPoWConsensusHooks(t *testing.T) {
+	hooks := blockchain.TstPoWConsensus
+	params := &chaincfg.MainNetParams
+
+	tests := []struct {
+		name    string
+		version int32
+		wantErr bool
+	}{
+		{"proof of work", 1, false},
+		{"proof of stake", 1 | wire.BlockVersionProofOfStake, true},
+	}
+
+	for _, test := range tests {
+		block := newHooksTestBlock(test.version)
+		header := &block.MsgBlock().Header
+
+		checks := []struct {
+			hook string
+			err  error
+		}{
+			{"CheckBlockHeader", hooks.CheckBlockHeader(header,
+				header.BlockType(), 1, blockchain.BFNone)},
+			{"CheckBlockSignature", hooks.CheckBlockSignature(block,
+				header.BlockType(), nil)},
+		}
+		for _, check := range checks {
+			if !test.wantErr {
+				if check.err != nil {
+					t.Errorf("%s (%s): unexpected error: %v",
+						check.hook, test.name, check.err)
+				}
+				continue
+			}
+			rerr, ok := check.err.(blockchain.RuleError)
+			if !ok || rerr.ErrorCode != blockchain.ErrUnsupportedBlockType {
+				t.Errorf("%s (%s): got error %v, want %v",
+					check.hook, test.name, check.err,
+					blockchain.ErrUnsupportedBlockType)
+			}
+		}
+
+		subsidy := hooks.CalcBlockSubsidy(block, 210000, params)
+		if want := blockchain.CalcBlockSubsidy(210000, params); subsidy != want {
+			t.Errorf("CalcBlockSubsidy (%s): got %d, want %d",
+				test.name, subsidy, want)
+		}
+	}
+}
+
+// halvedSubsidyHooks are proof-of-work consensus hooks which only allow half
+// of the standard subsidy.
+type halvedSubsidyHooks struct {
+	blockchain.ConsensusHooks
+}
+
+// CalcBlockSubsidy returns half of the standard subsidy.
+func (h halvedSubsidyHooks) CalcBlockSubsidy(block *coinutil.Block, height int32, chainParams *chaincfg.Params) int64 {
+	return h.ConsensusHooks.CalcBlockSubsidy(block, height, chainParams) / 2
+}
+
+// TestChainCalcBlockSubsidy ensures the subsidy calculated by a chain is the
+// one of its installed consensus hooks.
+func TestChainCalcBlockSubsidy(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	chain := blockchain.New(nil, params, nil, nil)
+	want := blockchain.CalcBlockSubsidy(1, params)
+	if subsidy := chain.CalcBlockSubsidy(1); subsidy != want {
+		t.Errorf("CalcBlockSubsidy (default hooks): got %d, want %d",
+			subsidy, want)
+	}
+
+	chain.SetConsensusHooks(halvedSubsidyHooks{blockchain.TstPoWConsensus})
+	if subsidy := chain.CalcBlockSubsidy(1); subsidy != want/2 {
+		t.Errorf("CalcBlockSubsidy (installed hooks): got %d, want %d",
+			subsidy, want/2)
+	}
+}
+
+// TestCalcBlockType ensures the proof-of-stake bit of the block version only
+// marks proof-of-stake blocks from the proof-of-stake height of the network.
+func TestCalcBlockType(t *testing.T) {
+	params := chaincfg.RegressionNetParams
+	powHeader := &newHooksTestBlock(1).MsgBlock().Header
+	posHeader := &newHooksTestBlock(1 |
+		wire.BlockVersionProofOfStake).MsgBlock().Header
+
+	tests := []struct {
+		name     string
+		posStart int32
+		header   *wire.BlockHeader
+		height   int32
+		want     wire.BlockType
+	}{
+		{"disabled", 0, posHeader, 1000, wire.BlockTypePoW},
+		{"before start", 100, posHeader, 99, wire.BlockTypePoW},
+		{"at start", 100, posHeader, 100, wire.BlockTypePoS},
+		{"after start", 100, posHeader, 101, wire.BlockTypePoS},
+		{"proof of work", 100, powHeader, 101, wire.BlockTypePoW},
+	}
+	for _, test := range tests {
+		params.ProofOfStakeHeight = test.posStart
+		got := blockchain.TstCalcBlockType(test.header, test.height,
+			&params)
+		if got != test.want {
+			t.Errorf("calcBlockType (%s): got %v, want %v",
+				test.name, got, test.want)
+		}
+	}
+}
+
+// TestBlockSignature ensures the signature of a proof-of-stake block is
+// extracted from its coinbase and the hash it signs leaves it out while the
+// block hash commits to it.
+func TestBlockSignature(t *testing.T) {
+	// signBlock pushes the passed data after the signature magic bytes in
+	// the last coinbase output of the passed block and returns the block
+	// with its merkle root updated.
+	signBlock := func(msgBlock *wire.MsgBlock, sigScript []byte) *coinutil.Block {
+		coinbase := msgBlock.Transactions[0]
+		txOut := coinbase.TxOut[len(coinbase.TxOut)-1]
+		txOut.PkScript = append(append([]byte{},
+			blockchain.BlockSignatureMagicBytes...), sigScript...)
+		merkles := blockchain.BuildMerkleTreeStore(
+			coinutil.NewBlock(msgBlock).Transactions(), false)
+		msgBlock.Header.MerkleRoot = *merkles[len(merkles)-1]
+		return coinutil.NewBlock(msgBlock)
+	}
+
+	msgBlock := newHooksTestBlock(1 |
+		wire.BlockVersionProofOfStake).MsgBlock()
+
+	// A block without a signature output has nothing to sign.
+	_, err := blockchain.BlockSignatureHash(coinutil.NewBlock(msgBlock))
+	if rerr, ok := err.(blockchain.RuleError); !ok ||
+		rerr.ErrorCode != blockchain.ErrBadBlockSignature {
+
+		t.Errorf("BlockSignatureHash (no output): got error %v, want "+
+			"%v", err, blockchain.ErrBadBlockSignature)
+	}
+
+	// The hash to sign is the hash of the block before the signature is
+	// pushed.
+	msgBlock.Transactions[0].AddTxOut(wire.NewTxOut(0, nil))
+	unsigned := signBlock(msgBlock, nil)
+	sigHash, err := blockchain.BlockSignatureHash(unsigned)
+	if err != nil {
+		t.Fatalf("BlockSignatureHash (unsigned): unexpected error: %v",
+			err)
+	}
+	if !sigHash.IsEqual(unsigned.Sha()) {
+		t.Errorf("BlockSignatureHash (unsigned): got %v, want %v",
+			sigHash, unsigned.Sha())
+	}
+	if _, err := blockchain.ExtractBlockSignature(unsigned); err == nil {
+		t.Errorf("ExtractBlockSignature (unsigned): expected error")
+	}
+
+	// Pushing the signature changes the block hash, but not the hash the
+	// signature signs.
+	sig := []byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01}
+	signed := signBlock(msgBlock, append([]byte{byte(len(sig))}, sig...))
+	gotSig, err := blockchain.ExtractBlockSignature(signed)
+	if err != nil {
+		t.Fatalf("ExtractBlockSignature: unexpected error: %v", err)
+	}
+	if !bytes.Equal(gotSig, sig) {
+		t.Errorf("ExtractBlockSignature: got %x, want %x", gotSig, sig)
+	}
+	gotHash, err := blockchain.BlockSignatureHash(signed)
+	if err != nil {
+		t.Fatalf("BlockSignatureHash: unexpected error: %v", err)
+	}
+	if !gotHash.IsEqual(&sigHash) {
+		t.Errorf("BlockSignatureHash: got %v, want %v", gotHash,
+			sigHash)
+	}
+	if signed.Sha().IsEqual(&sigHash) {
+		t.Errorf("block hash does not commit to the signature")
+	}
+
+	// Signature outputs which do not hold a single push or push too much
+	// data are rejected.
+	oversized := make([]byte, wire.MaxBlockSignatureLen+1)
+	badScripts := [][]byte{
+		{txscript.OP_1},
+		{0x01, 0x01, 0x01, 0x02},
+		append([]byte{txscript.OP_PUSHDATA1, byte(len(oversized))},
+			oversized...),
+	}
+	for _, sigScript := range badScripts {
+		block := signBlock(msgBlock, sigScript)
+		_, err := blockchain.ExtractBlockSignature(block)
+		if rerr, ok := err.(blockchain.RuleError); !ok ||
+			rerr.ErrorCode != blockchain.ErrBadBlockSignature {
+
+			t.Errorf("ExtractBlockSignature (%x): got error %v, "+
+				"want %v", sigScript, err,
+				blockchain.ErrBadBlockSignature)
+		}
+	}
+}
@@ -55,6 +55,16 @@ func TestErrorCodeStringer(t *testing.T) {
 		{blockchain.ErrBadCoinbaseHeight, "ErrBadCoinbaseHeight"},
 		{blockchain.ErrScriptMalformed, "ErrScriptMalformed"},
 		{blockchain.ErrScriptValidation, "ErrScriptValidation"},
+		{blockchain.ErrUnexpectedWitness, "ErrUnexpectedWitness"},
+		{blockchain.ErrBlockWeightTooHigh, "ErrBlockWeightTooHigh"},
+		{blockchain.ErrInvalidWitnessCommitment, "ErrInvalidWitnessCommitment"},
+		{blockchain.ErrWitnessCommitmentMismatch, "ErrWitnessCommitmentMismatch"},
+		{blockchain.ErrUnsupportedBlockType, "ErrUnsupportedBlockType"},
+		{blockchain.ErrBadBlockSignature, "ErrBadBlockSignature"},
+		{blockchain.ErrPrevBlockNotFound, "ErrPrevBlockNotFound"},
+		{blockchain.ErrMissingUtxoCommitment, "ErrMissingUtxoCommitment"},
+		{blockchain.ErrUtxoCommitmentMismatch, "ErrUtxoCommitmentMismatch"},
+		{blockchain.ErrScriptBudgetExceeded, "ErrScriptBudgetExceeded"},
 		{0xffff, "Unknown ErrorCode (65535)"},
 	}
 
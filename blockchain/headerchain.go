@@ -0,0 +1,475 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// HeaderInfo houses a block header along with its hash and height in the
+// chain.  It is the data of the NTHeaderConnected and NTHeaderDisconnected
+// notifications.
+type HeaderInfo struct {
+	Header *wire.BlockHeader
+	Hash   *wire.ShaHash
+	Height int32
+}
+
+// headerChainNode is a node of a header chain.  It is a block node along with
+// the full header it was created from since there is no block database to load
+// the header from.
+type headerChainNode struct {
+	node   *blockNode
+	header wire.BlockHeader
+}
+
+// HeaderChain provides best chain selection with reorganization for a chain
+// of block headers without their blocks.  It is used by nodes which only track
+// the tip of the chain and its reorganizations, such as monitoring nodes, and
+// don't need to store or validate the transactions.
+//
+// Headers are subjected to every check that doesn't need the transactions of
+// the block: proof of work, difficulty retargets, timestamps, block versions
+// and checkpoints.  Every header ever accepted, including the ones of side
+// chains, is kept in memory.
+//
+// Notifications are sent through the callback passed to NewHeaderChain:
+// 	- NTHeaderConnected:    *HeaderInfo
+// 	- NTHeaderDisconnected: *HeaderInfo
+// 	- NTChainReorganized:   *ChainReorganization
+type HeaderChain struct {
+	// rules performs the checks which depend on the position of a header
+	// in the chain.  All of the nodes it operates on are linked to their
+	// parents, so it never needs to load nodes from a database.
+	rules         *BlockChain
+	notifications NotificationCallback
+
+	mtx       sync.RWMutex
+	index     map[wire.ShaHash]*headerChainNode
+	mainChain []*headerChainNode
+}
+
+// NewHeaderChain returns a header chain for the passed network which only
+// contains the genesis block header.  The provided callback can be nil if the
+// caller is not interested in receiving notifications.
+func NewHeaderChain(params *chaincfg.Params, c NotificationCallback) *HeaderChain {
+	genesisHeader := params.GenesisBlock.Header
+	genesis := &headerChainNode{
+		node:   newBlockNode(&genesisHeader, params.GenesisHash, 0),
+		header: genesisHeader,
+	}
+	genesis.node.inMainChain = true
+
+	h := HeaderChain{
+		rules:         New(nil, params, nil, nil),
+		notifications: c,
+		index:         make(map[wire.ShaHash]*headerChainNode),
+		mainChain:     []*headerChainNode{genesis},
+	}
+	h.index[*params.GenesisHash] = genesis
+	return &h
+}
+
+// DisableCheckpoints provides a mechanism to disable validation against
+// checkpoints which you DO NOT want to do in production.  It is provided only
+// for debug purposes.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) DisableCheckpoints(disable bool) {
+	h.mtx.Lock()
+	h.rules.DisableCheckpoints(disable)
+	h.mtx.Unlock()
+}
+
+// tip returns the node at the end of the main chain.  The caller must hold the
+// lock.
+func (h *HeaderChain) tip() *headerChainNode {
+	return h.mainChain[len(h.mainChain)-1]
+}
+
+// checkHeaderContext performs the validation checks on the passed header
+// which depend on its position within the header chain.  They are the checks
+// of checkBlockHeaderContext, except that forks before the latest checkpoint
+// are detected from the headers in memory.  The caller must hold the lock.
+func (h *HeaderChain) checkHeaderContext(header *wire.BlockHeader, prevNode *blockNode, flags BehaviorFlags) error {
+	b := h.rules
+	blockHeight := prevNode.height + 1
+	blockType, err := b.headerBlockType(header, blockHeight, flags)
+	if err != nil {
+		return err
+	}
+
+	fastAdd := flags&BFFastAdd == BFFastAdd
+	if !fastAdd && blockType == wire.BlockTypePoW {
+		// Ensure the difficulty specified in the block header matches
+		// the calculated difficulty based on the previous block and
+		// difficulty retarget rules.
+		expectedDifficulty, err := b.calcNextRequiredDifficulty(prevNode,
+			header.Timestamp)
+		if err != nil {
+			return err
+		}
+		if header.Bits != expectedDifficulty {
+			str := "block difficulty of %d is not the expected value of %d"
+			str = fmt.Sprintf(str, header.Bits, expectedDifficulty)
+			return ruleError(ErrUnexpectedDifficulty, str)
+		}
+	}
+
+	if !fastAdd {
+		// Ensure the timestamp for the block header is after the
+		// median time of the last several blocks (medianTimeBlocks).
+		medianTime, err := b.calcPastMedianTime(prevNode)
+		if err != nil {
+			return err
+		}
+		if !header.Timestamp.After(medianTime) {
+			str := "block timestamp of %v is not after expected %v"
+			str = fmt.Sprintf(str, header.Timestamp, medianTime)
+			return ruleError(ErrTimeTooOld, str)
+		}
+	}
+
+	// Ensure chain matches up to predetermined checkpoints.
+	blockHash := header.BlockSha()
+	if !b.verifyCheckpoint(blockHeight, &blockHash) {
+		str := fmt.Sprintf("block at height %d does not match "+
+			"checkpoint hash", blockHeight)
+		return ruleError(ErrBadCheckpoint, str)
+	}
+
+	// Prevent headers which fork the main chain before the latest
+	// checkpoint it has reached.
+	if !b.noCheckpoints {
+		tipHeight := h.tip().node.height
+		checkpoints := b.chainParams.Checkpoints
+		for i := len(checkpoints) - 1; i >= 0; i-- {
+			if checkpoints[i].Height > tipHeight {
+				continue
+			}
+			if blockHeight < checkpoints[i].Height {
+				str := fmt.Sprintf("block at height %d forks "+
+					"the main chain before the previous "+
+					"checkpoint at height %d", blockHeight,
+					checkpoints[i].Height)
+				return ruleError(ErrForkTooOld, str)
+			}
+			break
+		}
+	}
+
+	if !fastAdd {
+		// Reject blocks with versions which a majority of the network
+		// has upgraded from.  This is part of BIP0034, BIP0066 and
+		// BIP0065.
+		for minVersion := int32(4); minVersion > 1; minVersion-- {
+			if header.Version < minVersion && b.isMajorityVersion(
+				minVersion, prevNode,
+				b.chainParams.BlockRejectNumRequired) {
+
+				str := "new blocks with version %d are no " +
+					"longer valid"
+				str = fmt.Sprintf(str, header.Version)
+				return ruleError(ErrBlockVersionTooOld, str)
+			}
+		}
+	}
+
+	// Perform the checks defined by the consensus hooks, which prove
+	// proof-of-stake headers.
+	return b.hooks.CheckBlockHeader(header, blockType, blockHeight, flags)
+}
+
+// ProcessHeader validates the passed header and adds it to the header chain.
+// The main chain is reorganized when the header ends a side chain with more
+// work than the main chain.  It returns whether the header is part of the main
+// chain once it has been added.
+//
+// The header must connect to a header which is already known.  A rule error
+// with the ErrDuplicateBlock code is returned for headers which are already
+// known and one with the ErrPrevBlockNotFound code for headers which don't
+// connect.
+//
+// The flags modify the behavior of this function as follows:
+//  - BFFastAdd: The checks of the difficulty, timestamp and version are not
+//    performed.  This is useful when adding headers which were validated
+//    before, such as headers loaded from disk.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) ProcessHeader(header *wire.BlockHeader, timeSource MedianTimeSource, flags BehaviorFlags) (bool, error) {
+	h.mtx.Lock()
+	ntfns, isMainChain, err := h.processHeader(header, timeSource, flags)
+	h.mtx.Unlock()
+
+	// Send the notifications once the lock is released so the callback is
+	// free to query the header chain.
+	if h.notifications != nil {
+		for _, n := range ntfns {
+			h.notifications(n)
+		}
+	}
+	return isMainChain, err
+}
+
+// processHeader is the implementation of ProcessHeader.  It returns the
+// notifications to send once the lock is released.  The caller must hold the
+// lock.
+func (h *HeaderChain) processHeader(header *wire.BlockHeader, timeSource MedianTimeSource, flags BehaviorFlags) ([]*Notification, bool, error) {
+	hash := header.BlockSha()
+	if _, exists := h.index[hash]; exists {
+		str := fmt.Sprintf("already have block header %v", hash)
+		return nil, false, ruleError(ErrDuplicateBlock, str)
+	}
+
+	err := checkBlockHeaderSanity(header, h.rules.chainParams.PowLimit,
+		timeSource, flags)
+	if err != nil {
+		return nil, false, err
+	}
+
+	prev, exists := h.index[header.PrevBlock]
+	if !exists {
+		str := fmt.Sprintf("previous block header %v of %v is not "+
+			"known", header.PrevBlock, hash)
+		return nil, false, ruleError(ErrPrevBlockNotFound, str)
+	}
+	err = h.checkHeaderContext(header, prev.node, flags)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Link the new node to its parent and accumulate the work of the
+	// chain ending with it.
+	node := newBlockNode(header, &hash, prev.node.height+1)
+	node.parent = prev.node
+	node.workSum.Add(node.workSum, prev.node.workSum)
+	prev.node.children = append(prev.node.children, node)
+	entry := &headerChainNode{node: node, header: *header}
+	h.index[hash] = entry
+
+	// Nothing more to do when the header doesn't give its chain more work
+	// than the main chain.
+	if node.workSum.Cmp(h.tip().node.workSum) <= 0 {
+		log.Debugf("Accepted side chain header %v at height %d", hash,
+			node.height)
+		return nil, false, nil
+	}
+
+	// Find the headers to attach by walking back from the new header to
+	// the main chain.
+	var attach []*headerChainNode
+	for n := entry; !n.node.inMainChain; n = h.index[*n.node.parentHash] {
+		attach = append(attach, n)
+	}
+	forkHeight := attach[len(attach)-1].node.height - 1
+
+	// Disconnect the headers of the main chain after the fork point
+	// starting with the tip and connect the new ones.
+	var ntfns []*Notification
+	var reorg *ChainReorganization
+	if forkHeight < h.tip().node.height {
+		fork := h.mainChain[forkHeight].node
+		reorg = &ChainReorganization{
+			ForkHash:   fork.hash,
+			ForkHeight: forkHeight,
+		}
+		log.Infof("REORGANIZE: Header chain forks at %v (height %d)",
+			fork.hash, forkHeight)
+	}
+	for i := len(h.mainChain) - 1; i > int(forkHeight); i-- {
+		detached := h.mainChain[i]
+		detached.node.inMainChain = false
+		h.mainChain[i] = nil
+		h.mainChain = h.mainChain[:i]
+		reorg.Detached = append(reorg.Detached, detached.node.hash)
+		ntfns = append(ntfns, &Notification{
+			Type: NTHeaderDisconnected,
+			Data: detached.info(),
+		})
+	}
+	for i := len(attach) - 1; i >= 0; i-- {
+		attached := attach[i]
+		attached.node.inMainChain = true
+		h.mainChain = append(h.mainChain, attached)
+		if reorg != nil {
+			reorg.Attached = append(reorg.Attached,
+				attached.node.hash)
+		}
+		ntfns = append(ntfns, &Notification{
+			Type: NTHeaderConnected,
+			Data: attached.info(),
+		})
+	}
+	if reorg != nil {
+		ntfns = append(ntfns, &Notification{
+			Type: NTChainReorganized,
+			Data: reorg,
+		})
+	}
+
+	return ntfns, true, nil
+}
+
+// info returns the header, hash and height of the node.
+func (n *headerChainNode) info() *HeaderInfo {
+	header := n.header
+	return &HeaderInfo{
+		Header: &header,
+		Hash:   n.node.hash,
+		Height: n.node.height,
+	}
+}
+
+// CheckBlockContext performs the checks on the passed block which depend on
+// its position within the header chain, but not on the outputs it spends, and
+// returns the flags its scripts must be verified with.  These are the checks
+// of checkBlockContext which concern the transactions, such as the witness
+// commitment and the finality of the transactions.  The header of the block
+// must already have been added to the header chain.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) CheckBlockContext(block *coinutil.Block) (txscript.ScriptFlags, error) {
+	// The lock is held exclusively since the deployment states are cached
+	// while they are calculated.
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	entry, exists := h.index[*block.Sha()]
+	if !exists {
+		return 0, fmt.Errorf("block header %v is not known",
+			block.Sha())
+	}
+	prevNode := entry.node.parent
+	if prevNode == nil {
+		return 0, fmt.Errorf("block %v has no previous block",
+			block.Sha())
+	}
+
+	err := h.rules.checkBlockTxContext(block, prevNode, BFNone)
+	if err != nil {
+		return 0, err
+	}
+	return h.rules.blockScriptFlags(&entry.header, prevNode)
+}
+
+// HaveHeader returns whether or not the header with the passed hash is known,
+// either as part of the main chain or a side chain.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) HaveHeader(hash *wire.ShaHash) bool {
+	h.mtx.RLock()
+	_, exists := h.index[*hash]
+	h.mtx.RUnlock()
+	return exists
+}
+
+// BestHeader returns the hash and height of the header at the end of the main
+// chain.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) BestHeader() (*wire.ShaHash, int32) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	tip := h.tip().node
+	return tip.hash, tip.height
+}
+
+// HeaderByHash returns the header with the passed hash along with its height
+// and whether it is part of the main chain.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) HeaderByHash(hash *wire.ShaHash) (*wire.BlockHeader, int32, bool, error) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	entry, exists := h.index[*hash]
+	if !exists {
+		return nil, 0, false, fmt.Errorf("block header %v is not known",
+			hash)
+	}
+	header := entry.header
+	return &header, entry.node.height, entry.node.inMainChain, nil
+}
+
+// ChainWork returns the total work of the chain ending with the header with the
+// passed hash.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) ChainWork(hash *wire.ShaHash) (*big.Int, error) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	entry, exists := h.index[*hash]
+	if !exists {
+		return nil, fmt.Errorf("block header %v is not known", hash)
+	}
+	return new(big.Int).Set(entry.node.workSum), nil
+}
+
+// HashByHeight returns the hash of the header at the passed height in the main
+// chain.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) HashByHeight(height int32) (*wire.ShaHash, error) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	if height < 0 || height >= int32(len(h.mainChain)) {
+		return nil, fmt.Errorf("no block header at height %d", height)
+	}
+	return h.mainChain[height].node.hash, nil
+}
+
+// LatestBlockLocator returns a block locator for the header at the end of the
+// main chain.  See BlockLocator for details on the algorithm used to create a
+// block locator.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) LatestBlockLocator() BlockLocator {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	locator := make(BlockLocator, 0, wire.MaxBlockLocatorsPerMsg)
+	step := int32(1)
+	for height := h.tip().node.height; height > 0; height -= step {
+		locator = append(locator, h.mainChain[height].node.hash)
+
+		// Once 11 entries have been included, start doubling the
+		// distance between included hashes.
+		if len(locator) > 10 {
+			step *= 2
+		}
+	}
+	return append(locator, h.mainChain[0].node.hash)
+}
+
+// IsCurrent returns whether or not the header chain believes it is current.
+// The same criteria as for the block chain are used: the header at the end of
+// the main chain must be past the latest checkpoint and less than 24 hours
+// old.
+//
+// This function is safe for concurrent access.
+func (h *HeaderChain) IsCurrent(timeSource MedianTimeSource) bool {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	tip := h.tip().node
+	checkpoint := h.rules.LatestCheckpoint()
+	if checkpoint != nil && tip.height < checkpoint.Height {
+		return false
+	}
+
+	minus24Hours := timeSource.AdjustedTime().Add(-24 * time.Hour)
+	return !tip.timestamp.Before(minus24Hours)
+}
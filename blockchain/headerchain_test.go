@@ -0,0 +1,216 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/wire"
+)
+
+// solveHeader returns a header which extends the passed one, has the passed
+// timestamp offset from it and satisfies the minimum difficulty of the
+// regression test network.
+func solveHeader(prev *wire.BlockHeader, offset time.Duration, tag byte) *wire.BlockHeader {
+	params := &chaincfg.RegressionNetParams
+	prevHash := prev.BlockSha()
+	header := wire.NewBlockHeader(&prevHash, &wire.ShaHash{tag},
+		params.PowLimitBits, 0)
+	header.Timestamp = prev.Timestamp.Add(offset)
+	for {
+		hash := header.BlockSha()
+		if blockchain.ShaHashToBig(&hash).Cmp(params.PowLimit) <= 0 {
+			return header
+		}
+		header.Nonce++
+	}
+}
+
+// TestHeaderChain ensures the header chain validates headers, selects the
+// chain with the most work and sends the expected notifications when it is
+// extended and reorganized.
+func TestHeaderChain(t *testing.T) {
+	// Use the hash of the genesis header the test headers extend as the
+	// genesis hash of the network.
+	params := chaincfg.RegressionNetParams
+	genesisHash := params.GenesisBlock.Header.BlockSha()
+	params.GenesisHash = &genesisHash
+	var ntfns []blockchain.NotificationType
+	var reorg *blockchain.ChainReorganization
+	chain := blockchain.NewHeaderChain(&params, func(n *blockchain.Notification) {
+		ntfns = append(ntfns, n.Type)
+		if n.Type == blockchain.NTChainReorganized {
+			reorg = n.Data.(*blockchain.ChainReorganization)
+		}
+	})
+	timeSource := blockchain.NewMedianTime()
+
+	// Build a main chain of 20 headers.
+	mainHeaders := []*wire.BlockHeader{&params.GenesisBlock.Header}
+	for i := 1; i <= 20; i++ {
+		header := solveHeader(mainHeaders[i-1], time.Minute*10, 1)
+		isMainChain, err := chain.ProcessHeader(header, timeSource,
+			blockchain.BFNone)
+		if err != nil || !isMainChain {
+			t.Fatalf("ProcessHeader #%d: got %v err %v", i,
+				isMainChain, err)
+		}
+		mainHeaders = append(mainHeaders, header)
+	}
+	if len(ntfns) != 20 || reorg != nil {
+		t.Fatalf("got %d notifications and reorg %v, want 20 connected",
+			len(ntfns), reorg)
+	}
+	tipHash, tipHeight := chain.BestHeader()
+	if want := mainHeaders[20].BlockSha(); *tipHash != want ||
+		tipHeight != 20 {
+
+		t.Fatalf("BestHeader: got %v (%d), want %v (20)", tipHash,
+			tipHeight, want)
+	}
+
+	// Duplicate headers, headers which don't connect and headers with the
+	// wrong difficulty are rejected.
+	_, err := chain.ProcessHeader(mainHeaders[5], timeSource,
+		blockchain.BFNone)
+	if rerr, ok := err.(blockchain.RuleError); !ok ||
+		rerr.ErrorCode != blockchain.ErrDuplicateBlock {
+
+		t.Fatalf("ProcessHeader: got %v for a duplicate header", err)
+	}
+	orphan := solveHeader(solveHeader(mainHeaders[20], time.Minute, 2),
+		time.Minute, 2)
+	_, err = chain.ProcessHeader(orphan, timeSource, blockchain.BFNone)
+	if rerr, ok := err.(blockchain.RuleError); !ok ||
+		rerr.ErrorCode != blockchain.ErrPrevBlockNotFound {
+
+		t.Fatalf("ProcessHeader: got %v for an orphan header", err)
+	}
+	badBits := solveHeader(mainHeaders[20], time.Minute, 3)
+	badBits.Bits = 0x1d00ffff
+	_, err = chain.ProcessHeader(badBits, timeSource, blockchain.BFNone)
+	if err == nil {
+		t.Fatalf("ProcessHeader: header with the wrong difficulty " +
+			"was accepted")
+	}
+
+	// A side chain forking after the header at height 15 doesn't become
+	// the main chain until it has more work.
+	ntfns = nil
+	sideHeaders := []*wire.BlockHeader{mainHeaders[15]}
+	for i := 1; i <= 6; i++ {
+		header := solveHeader(sideHeaders[i-1], time.Minute*10, 4)
+		isMainChain, err := chain.ProcessHeader(header, timeSource,
+			blockchain.BFNone)
+		if err != nil {
+			t.Fatalf("ProcessHeader side #%d: unexpected error %v",
+				i, err)
+		}
+		if isMainChain != (i == 6) {
+			t.Fatalf("ProcessHeader side #%d: got main chain %v",
+				i, isMainChain)
+		}
+		sideHeaders = append(sideHeaders, header)
+	}
+	want := []blockchain.NotificationType{
+		blockchain.NTHeaderDisconnected, blockchain.NTHeaderDisconnected,
+		blockchain.NTHeaderDisconnected, blockchain.NTHeaderDisconnected,
+		blockchain.NTHeaderDisconnected, blockchain.NTHeaderConnected,
+		blockchain.NTHeaderConnected, blockchain.NTHeaderConnected,
+		blockchain.NTHeaderConnected, blockchain.NTHeaderConnected,
+		blockchain.NTHeaderConnected, blockchain.NTChainReorganized,
+	}
+	if !reflect.DeepEqual(ntfns, want) {
+		t.Fatalf("notifications: got %v, want %v", ntfns, want)
+	}
+	if reorg.ForkHeight != 15 || len(reorg.Detached) != 5 ||
+		len(reorg.Attached) != 6 {
+
+		t.Fatalf("reorganization: got %+v", reorg)
+	}
+
+	// The lookups reflect the new main chain.
+	hash, err := chain.HashByHeight(21)
+	if want := sideHeaders[6].BlockSha(); err != nil || *hash != want {
+		t.Fatalf("HashByHeight: got %v err %v, want %v", hash, err,
+			want)
+	}
+	oldTip := mainHeaders[20].BlockSha()
+	_, height, inMainChain, err := chain.HeaderByHash(&oldTip)
+	if err != nil || height != 20 || inMainChain {
+		t.Fatalf("HeaderByHash: got height %d main chain %v err %v",
+			height, inMainChain, err)
+	}
+	if !chain.HaveHeader(&oldTip) {
+		t.Fatalf("HaveHeader: detached header is not known")
+	}
+	oldWork, err := chain.ChainWork(&oldTip)
+	if err != nil {
+		t.Fatalf("ChainWork: unexpected error %v", err)
+	}
+	newTip := sideHeaders[6].BlockSha()
+	newWork, err := chain.ChainWork(&newTip)
+	if err != nil || newWork.Cmp(oldWork) <= 0 {
+		t.Fatalf("ChainWork: got %v for the new tip and %v for the "+
+			"old one", newWork, oldWork)
+	}
+	locator := chain.LatestBlockLocator()
+	if *locator[0] != sideHeaders[6].BlockSha() ||
+		!locator[len(locator)-1].IsEqual(params.GenesisHash) {
+
+		t.Fatalf("LatestBlockLocator: unexpected locator %v", locator)
+	}
+}
+
+// TestHeaderChainCheckBlockContext ensures the transactions of blocks whose
+// header is part of the header chain are checked against their position in
+// it.
+func TestHeaderChainCheckBlockContext(t *testing.T) {
+	params := chaincfg.RegressionNetParams
+	genesisHash := params.GenesisBlock.Header.BlockSha()
+	params.GenesisHash = &genesisHash
+	chain := blockchain.NewHeaderChain(&params, nil)
+	timeSource := blockchain.NewMedianTime()
+
+	// newBlock returns a block with the passed header and a coinbase with
+	// the passed lock time.
+	newBlock := func(header *wire.BlockHeader, lockTime uint32) *coinutil.Block {
+		coinbase := wire.NewMsgTx()
+		txIn := wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff},
+			[]byte{0x51, 0x51})
+		txIn.Sequence = 0
+		coinbase.AddTxIn(txIn)
+		coinbase.AddTxOut(wire.NewTxOut(0, []byte{0x51}))
+		coinbase.LockTime = lockTime
+		block := wire.NewMsgBlock(header)
+		block.AddTransaction(coinbase)
+		return coinutil.NewBlock(block)
+	}
+
+	header := solveHeader(&params.GenesisBlock.Header, time.Minute*10, 1)
+	if _, err := chain.CheckBlockContext(newBlock(header, 0)); err == nil {
+		t.Fatalf("CheckBlockContext: block with unknown header accepted")
+	}
+	_, err := chain.ProcessHeader(header, timeSource, blockchain.BFNone)
+	if err != nil {
+		t.Fatalf("ProcessHeader: unexpected error %v", err)
+	}
+	if _, err := chain.CheckBlockContext(newBlock(header, 0)); err != nil {
+		t.Fatalf("CheckBlockContext: unexpected error %v", err)
+	}
+
+	// A transaction locked until a later height is not final.
+	_, err = chain.CheckBlockContext(newBlock(header, 2))
+	rerr, ok := err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrUnfinalizedTx {
+		t.Fatalf("CheckBlockContext: got %v, want %v", err,
+			blockchain.ErrUnfinalizedTx)
+	}
+}
@@ -15,6 +15,10 @@ package blockchain
 import (
 	"sort"
 	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/wire"
 )
 
 // TstSetCoinbaseMaturity makes the ability to set the coinbase maturity
@@ -42,3 +46,75 @@ func TstSetMaxMedianTimeEntries(val int) {
 // TstCheckBlockScripts makes the internal checkBlockScripts function available
 // to the test package.
 var TstCheckBlockScripts = checkBlockScripts
+
+// TstAddOrphanBlock makes the internal addOrphanBlock function available to
+// the test package.
+func (b *BlockChain) TstAddOrphanBlock(block *coinutil.Block) {
+	b.addOrphanBlock(block)
+}
+
+// TstMaxOrphanBlocksSize makes the internal maxOrphanBlocksSize constant
+// available to the test package.
+const TstMaxOrphanBlocksSize = maxOrphanBlocksSize
+
+// TstDeploymentStates builds an in-memory chain for the passed network
+// parameters that starts at the genesis block and has a block for each of the
+// passed versions, spaced ten minutes apart from the genesis block timestamp.
+// It returns the state of the passed deployment for the block after each of
+// them.
+func TstDeploymentStates(params *chaincfg.Params, versions []int32, deploymentID uint32) ([]ThresholdState, error) {
+	b := New(nil, params, nil, nil)
+
+	genesisHeader := params.GenesisBlock.Header
+	prevNode := newBlockNode(&genesisHeader, params.GenesisHash, 0)
+	states := make([]ThresholdState, 0, len(versions))
+	for i, version := range versions {
+		header := wire.BlockHeader{
+			Version:   version,
+			PrevBlock: *prevNode.hash,
+			Timestamp: genesisHeader.Timestamp.Add(
+				time.Duration(i+1) * 10 * time.Minute),
+			Bits:  genesisHeader.Bits,
+			Nonce: uint32(i),
+		}
+		hash := header.BlockSha()
+		node := newBlockNode(&header, &hash, prevNode.height+1)
+		node.parent = prevNode
+		prevNode.children = append(prevNode.children, node)
+		prevNode = node
+
+		state, err := b.deploymentState(node, deploymentID)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// TstPoWConsensus makes the internal powConsensus type, which implements the
+// default consensus hooks, available to the test package.
+var TstPoWConsensus ConsensusHooks = powConsensus{}
+
+// TstUtxoSetHash returns the hash of the unspent transaction outputs after
+// connecting the passed blocks at their heights to an empty set, with the
+// transactions they spend taken from the passed transaction store.  The hash
+// is serialized and deserialized after each block.
+func TstUtxoSetHash(blocks []*coinutil.Block, txStore TxStore) (*wire.ShaHash, error) {
+	h := newUtxoSetHash()
+	for _, block := range blocks {
+		err := h.connectBlock(block, block.Height(), txStore)
+		if err != nil {
+			return nil, err
+		}
+		h, err = deserializeUtxoSetHash(h.serialize())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h.digest(), nil
+}
+
+// TstCalcBlockType makes the internal calcBlockType function available to the
+// test package.
+var TstCalcBlockType = calcBlockType
@@ -45,6 +45,12 @@ type MedianTimeSource interface {
 	// Offset returns the number of seconds to adjust the local clock based
 	// upon the median of the time samples added by AddTimeData.
 	Offset() time.Duration
+
+	// MedianOffset returns the median offset of the time samples added by
+	// AddTimeSample from the local clock.  Unlike Offset, it is not limited
+	// to the maximum allowed adjustment, so it may be used to detect a
+	// local clock which is wrong.
+	MedianOffset() time.Duration
 }
 
 // int64Sorter implements sort.Interface to allow a slice of 64-bit integers to
@@ -79,6 +85,7 @@ type medianTime struct {
 	knownIDs           map[string]struct{}
 	offsets            []int64
 	offsetSecs         int64
+	medianOffsetSecs   int64
 	invalidTimeChecked bool
 }
 
@@ -137,6 +144,13 @@ func (m *medianTime) AddTimeSample(sourceID string, timeVal time.Time) {
 	log.Debugf("Added time sample of %v (total: %v)", offsetDuration,
 		numOffsets)
 
+	// Track the median offset of the samples for detecting a wrong local
+	// clock.  This is not used in the consensus rules, so it is updated
+	// for every sample once there are enough of them.
+	if numOffsets >= 5 {
+		m.medianOffsetSecs = sortedOffsets[numOffsets/2]
+	}
+
 	// NOTE: The following code intentionally has a bug to mirror the
 	// buggy behavior in Bitcoin Core since the median time is used in the
 	// consensus rules.
@@ -205,6 +219,18 @@ func (m *medianTime) Offset() time.Duration {
 	return time.Duration(m.offsetSecs) * time.Second
 }
 
+// MedianOffset returns the median offset of the time samples from the local
+// clock without limiting it to the maximum allowed adjustment.
+//
+// This function is safe for concurrent access and is part of the
+// MedianTimeSource interface implementation.
+func (m *medianTime) MedianOffset() time.Duration {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return time.Duration(m.medianOffsetSecs) * time.Second
+}
+
 // NewMedianTime returns a new instance of concurrency-safe implementation of
 // the MedianTimeSource interface.  The returned implementation contains the
 // rules necessary for proper time handling in the chain consensus rules and
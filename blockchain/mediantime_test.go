@@ -104,3 +104,46 @@ func TestMedianTime(t *testing.T) {
 		}
 	}
 }
+
+// TestMedianOffset ensures the median offset is reported even when it is too
+// large to be used to adjust the local clock.
+func TestMedianOffset(t *testing.T) {
+	tests := []struct {
+		in         []int64
+		wantMedian int64
+	}{
+		// Not enough samples must result in a median of 0.
+		{in: []int64{1, 2, 3, 4}, wantMedian: 0},
+
+		// Unlike the offset, the median is updated on an even number
+		// of elements.
+		{in: []int64{-13, 57, -4, -23, -12}, wantMedian: -12},
+		{in: []int64{55, -13, 61, -52, 39, 55}, wantMedian: 55},
+
+		// Offsets that are too far away from the local time are still
+		// reported.
+		{in: []int64{-4201, 4202, -4203, 4204, -4205}, wantMedian: -4201},
+		{in: []int64{4201, 4202, 4203, 4204, -299}, wantMedian: 4202},
+	}
+
+	for i, test := range tests {
+		filter := blockchain.NewMedianTime()
+		for j, offset := range test.in {
+			id := strconv.Itoa(j)
+			now := time.Unix(time.Now().Unix(), 0)
+			tOffset := now.Add(time.Duration(offset) * time.Second)
+			filter.AddTimeSample(id, tOffset)
+		}
+
+		// Allow the same fudge factor as the offset tests to
+		// compensate for the time.Now calls being a second apart.
+		gotMedian := filter.MedianOffset()
+		wantMedian := time.Duration(test.wantMedian) * time.Second
+		wantMedian2 := time.Duration(test.wantMedian-1) * time.Second
+		if gotMedian != wantMedian && gotMedian != wantMedian2 {
+			t.Errorf("MedianOffset #%d: unexpected median -- got %v, "+
+				"want %v or %v", i, gotMedian, wantMedian,
+				wantMedian2)
+		}
+	}
+}
@@ -9,12 +9,14 @@ import (
 
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
 )
 
 // TestMerkle tests the BuildMerkleTreeStore API.
 func TestMerkle(t *testing.T) {
 	block := coinutil.NewBlock(&Block100000)
-	merkles := blockchain.BuildMerkleTreeStore(block.Transactions())
+	merkles := blockchain.BuildMerkleTreeStore(block.Transactions(), false)
 	calculatedMerkleRoot := merkles[len(merkles)-1]
 	wantMerkle := &Block100000.Header.MerkleRoot
 	if !wantMerkle.IsEqual(calculatedMerkleRoot) {
@@ -22,3 +24,78 @@ func TestMerkle(t *testing.T) {
 			"got %v, want %v", calculatedMerkleRoot, wantMerkle)
 	}
 }
+
+// TestValidateWitnessCommitment ensures the witness commitment in the coinbase
+// of a block is validated against the witness data of its transactions.
+func TestValidateWitnessCommitment(t *testing.T) {
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  []byte{txscript.OP_0, txscript.OP_0},
+		Sequence:         wire.MaxTxInSequenceNum,
+		Witness:          wire.TxWitness{make([]byte, 32)},
+	})
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{txscript.OP_TRUE}))
+
+	spend := wire.NewMsgTx()
+	spend.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: wire.ShaHash{0x01}},
+		Sequence:         wire.MaxTxInSequenceNum,
+		Witness:          wire.TxWitness{{0x01, 0x02}},
+	})
+	spend.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_TRUE}))
+
+	// newBlock returns a block containing the test transactions whose
+	// coinbase commits to the passed witness root, if any.
+	newBlock := func(witnessRoot *wire.ShaHash) *coinutil.Block {
+		cb := coinbase.Copy()
+		if witnessRoot != nil {
+			var preimage [wire.HashSize * 2]byte
+			copy(preimage[:], witnessRoot[:])
+			commitment := wire.DoubleSha256(preimage[:])
+			pkScript := append(append([]byte{},
+				blockchain.WitnessMagicBytes...), commitment...)
+			cb.AddTxOut(wire.NewTxOut(0, pkScript))
+		}
+		msgBlock := wire.MsgBlock{
+			Transactions: []*wire.MsgTx{cb, spend.Copy()},
+		}
+		return coinutil.NewBlock(&msgBlock)
+	}
+
+	// The witness root does not depend on the coinbase, so it may be
+	// calculated from a block without a commitment.
+	merkles := blockchain.BuildMerkleTreeStore(newBlock(nil).Transactions(),
+		true)
+	witnessRoot := merkles[len(merkles)-1]
+
+	err := blockchain.ValidateWitnessCommitment(newBlock(witnessRoot))
+	if err != nil {
+		t.Fatalf("ValidateWitnessCommitment: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		block *coinutil.Block
+		code  blockchain.ErrorCode
+	}{
+		{
+			name:  "missing commitment",
+			block: newBlock(nil),
+			code:  blockchain.ErrUnexpectedWitness,
+		},
+		{
+			name:  "wrong commitment",
+			block: newBlock(&wire.ShaHash{0x01}),
+			code:  blockchain.ErrWitnessCommitmentMismatch,
+		},
+	}
+	for _, test := range tests {
+		err := blockchain.ValidateWitnessCommitment(test.block)
+		rerr, ok := err.(blockchain.RuleError)
+		if !ok || rerr.ErrorCode != test.code {
+			t.Errorf("%s: unexpected error: got %v, want %v",
+				test.name, err, test.code)
+		}
+	}
+}
@@ -6,6 +6,8 @@ package blockchain
 
 import (
 	"fmt"
+
+	"github.com/conseweb/stcd/wire"
 )
 
 // NotificationType represents the type of a notification message.
@@ -27,16 +29,35 @@ const (
 	NTBlockConnected
 
 	// NTBlockDisconnected indicates the associated block was disconnected
-	// from the main chain.
+	// from the main chain.  During a reorganization, the blocks are
+	// disconnected starting with the tip of the old main chain, and all of
+	// them are disconnected before the first block of the new main chain
+	// is connected.
 	NTBlockDisconnected
+
+	// NTChainReorganized indicates the main chain was reorganized.  It is
+	// sent once the blocks of the reorganization have been disconnected
+	// and connected.
+	NTChainReorganized
+
+	// NTHeaderConnected indicates the associated block header was
+	// connected to the main chain of a header chain.
+	NTHeaderConnected
+
+	// NTHeaderDisconnected indicates the associated block header was
+	// disconnected from the main chain of a header chain.
+	NTHeaderDisconnected
 )
 
 // notificationTypeStrings is a map of notification types back to their constant
 // names for pretty printing.
 var notificationTypeStrings = map[NotificationType]string{
-	NTBlockAccepted:     "NTBlockAccepted",
-	NTBlockConnected:    "NTBlockConnected",
-	NTBlockDisconnected: "NTBlockDisconnected",
+	NTBlockAccepted:      "NTBlockAccepted",
+	NTBlockConnected:     "NTBlockConnected",
+	NTBlockDisconnected:  "NTBlockDisconnected",
+	NTChainReorganized:   "NTChainReorganized",
+	NTHeaderConnected:    "NTHeaderConnected",
+	NTHeaderDisconnected: "NTHeaderDisconnected",
 }
 
 // String returns the NotificationType in human-readable form.
@@ -48,16 +69,38 @@ func (n NotificationType) String() string {
 }
 
 // Notification defines notification that is sent to the caller via the callback
-// function provided during the call to New and consists of a notification type
-// as well as associated data that depends on the type as follows:
-// 	- NTBlockAccepted:     *coinutil.Block
-// 	- NTBlockConnected:    *coinutil.Block
-// 	- NTBlockDisconnected: *coinutil.Block
+// function provided during the call to New or NewHeaderChain and consists of a
+// notification type as well as associated data that depends on the type as
+// follows:
+// 	- NTBlockAccepted:      *coinutil.Block
+// 	- NTBlockConnected:     *coinutil.Block
+// 	- NTBlockDisconnected:  *coinutil.Block
+// 	- NTChainReorganized:   *ChainReorganization
+// 	- NTHeaderConnected:    *HeaderInfo
+// 	- NTHeaderDisconnected: *HeaderInfo
 type Notification struct {
 	Type NotificationType
 	Data interface{}
 }
 
+// ChainReorganization describes a reorganization of the main chain for the
+// NTChainReorganized notification.
+type ChainReorganization struct {
+	// ForkHash and ForkHeight identify the common ancestor of the old and
+	// the new main chain.
+	ForkHash   *wire.ShaHash
+	ForkHeight int32
+
+	// Detached houses the hashes of the blocks disconnected from the main
+	// chain in the order they were disconnected, which starts with the old
+	// best block.
+	Detached []*wire.ShaHash
+
+	// Attached houses the hashes of the blocks connected to the main chain
+	// in the order they were connected, which ends with the new best block.
+	Attached []*wire.ShaHash
+}
+
 // sendNotification sends a notification with the passed type and data if the
 // caller requested notifications by providing a callback function in the call
 // to New.
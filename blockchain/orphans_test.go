@@ -0,0 +1,220 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// newOrphanTestBlock returns a block with the passed parent.  The nonce keeps
+// blocks with the same parent unique and the block is padded with an output
+// script of the passed size.
+func newOrphanTestBlock(prevHash *wire.ShaHash, nonce uint32, padding int) *coinutil.Block {
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff},
+		[]byte{0x01, 0x02}))
+	coinbase.AddTxOut(wire.NewTxOut(1, make([]byte, padding)))
+
+	merkleRoot := coinbase.TxSha()
+	header := wire.NewBlockHeader(prevHash, &merkleRoot, 0x207fffff, nonce)
+	block := wire.NewMsgBlock(header)
+	block.AddTransaction(coinbase)
+	return coinutil.NewBlock(block)
+}
+
+// newAssumeValidTestBlock returns a version 1 block which extends the passed
+// block of the regression test network, has a coinbase paying to the passed
+// script and holds the passed transactions.
+func newAssumeValidTestBlock(prev *wire.MsgBlock, pkScript []byte, txns ...*wire.MsgTx) *coinutil.Block {
+	params := &chaincfg.RegressionNetParams
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&wire.ShaHash{},
+		wire.MaxPrevOutIndex), []byte{0x01, 0x02}))
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, pkScript))
+
+	prevHash := prev.Header.BlockSha()
+	msgBlock := wire.NewMsgBlock(wire.NewBlockHeader(&prevHash,
+		&wire.ShaHash{}, params.PowLimitBits, 0))
+	msgBlock.Header.Version = 1
+	msgBlock.Header.Timestamp = prev.Header.Timestamp.Add(time.Minute)
+	msgBlock.AddTransaction(coinbase)
+	for _, tx := range txns {
+		msgBlock.AddTransaction(tx)
+	}
+	merkles := blockchain.BuildMerkleTreeStore(
+		coinutil.NewBlock(msgBlock).Transactions(), false)
+	msgBlock.Header.MerkleRoot = *merkles[len(merkles)-1]
+	for {
+		hash := msgBlock.Header.BlockSha()
+		if blockchain.ShaHashToBig(&hash).Cmp(params.PowLimit) <= 0 {
+			return coinutil.NewBlock(msgBlock)
+		}
+		msgBlock.Header.Nonce++
+	}
+}
+
+// TestOrphanAssumeValid ensures the scripts of an orphan are validated when
+// it is accepted along with an assumed-valid parent.
+func TestOrphanAssumeValid(t *testing.T) {
+	db, err := database.CreateDB("memdb")
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	defer db.Close()
+
+	// Use the hash of the genesis block the test blocks extend as the
+	// genesis hash of the network.
+	params := chaincfg.RegressionNetParams
+	genesis := coinutil.NewBlock(params.GenesisBlock)
+	params.GenesisHash = genesis.Sha()
+	if _, err := db.InsertBlock(genesis); err != nil {
+		t.Fatalf("InsertBlock: %v", err)
+	}
+	chain := blockchain.New(db, &params, nil, nil)
+	blockchain.TstSetCoinbaseMaturity(1)
+	defer blockchain.TstSetCoinbaseMaturity(blockchain.CoinbaseMaturity)
+
+	// The parent pays to a script which always fails and the orphan spends
+	// the output.
+	parent := newAssumeValidTestBlock(params.GenesisBlock,
+		[]byte{txscript.OP_FALSE})
+	spend := wire.NewMsgTx()
+	spend.AddTxIn(wire.NewTxIn(wire.NewOutPoint(
+		parent.Transactions()[0].Sha(), 0), nil))
+	spend.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_TRUE}))
+	orphan := newAssumeValidTestBlock(parent.MsgBlock(),
+		[]byte{txscript.OP_TRUE}, spend)
+
+	timeSource := blockchain.NewMedianTime()
+	isOrphan, err := chain.ProcessBlock(orphan, timeSource,
+		blockchain.BFNone)
+	if err != nil || !isOrphan {
+		t.Fatalf("ProcessBlock (orphan): got %v err %v, want orphan",
+			isOrphan, err)
+	}
+
+	// Only the parent is assumed valid, so accepting it must reject the
+	// orphan.
+	_, err = chain.ProcessBlock(parent, timeSource, blockchain.BFAssumeValid)
+	if rerr, ok := err.(blockchain.RuleError); !ok ||
+		rerr.ErrorCode != blockchain.ErrScriptValidation {
+
+		t.Fatalf("ProcessBlock (parent): got error %v, want %v", err,
+			blockchain.ErrScriptValidation)
+	}
+	tipHash, tipHeight, err := db.NewestSha()
+	if err != nil {
+		t.Fatalf("NewestSha: %v", err)
+	}
+	if !tipHash.IsEqual(parent.Sha()) || tipHeight != 1 {
+		t.Fatalf("NewestSha: got %v (%d), want %v (1)", tipHash,
+			tipHeight, parent.Sha())
+	}
+}
+
+// TestOrphanChains ensures orphan blocks are grouped into chains by the
+// unknown block they descend from, are returned parents first, and that the
+// orphan pool is limited by total size.
+func TestOrphanChains(t *testing.T) {
+	db, err := database.CreateDB("memdb")
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	defer db.Close()
+	chain := blockchain.New(db, &chaincfg.SimNetParams, nil, nil)
+
+	// Create two groups of orphans:
+	//   rootA -> a1 -> a2 -> a3
+	//                \-> a2b
+	//   rootB -> b1
+	rootA := wire.ShaHash{0x01}
+	rootB := wire.ShaHash{0x02}
+	a1 := newOrphanTestBlock(&rootA, 0, 10)
+	a2 := newOrphanTestBlock(a1.Sha(), 0, 10)
+	a2b := newOrphanTestBlock(a1.Sha(), 1, 10)
+	a3 := newOrphanTestBlock(a2.Sha(), 0, 10)
+	b1 := newOrphanTestBlock(&rootB, 0, 10)
+
+	// Add the blocks children first to ensure the order they were added
+	// in does not matter.
+	for _, block := range []*coinutil.Block{a3, a2b, b1, a2, a1} {
+		chain.TstAddOrphanBlock(block)
+	}
+
+	chains := chain.OrphanChains()
+	if len(chains) != 2 {
+		t.Fatalf("OrphanChains: got %d chains, want 2", len(chains))
+	}
+	blockSize := a1.MsgBlock().SerializeSize()
+	want := []blockchain.OrphanChain{
+		{
+			RootPrevHash: rootA,
+			Length:       3,
+			NumBlocks:    4,
+			TotalSize:    4 * blockSize,
+			TipHash:      *a3.Sha(),
+		},
+		{
+			RootPrevHash: rootB,
+			Length:       1,
+			NumBlocks:    1,
+			TotalSize:    blockSize,
+			TipHash:      *b1.Sha(),
+		},
+	}
+	for i := range want {
+		if chains[i] != want[i] {
+			t.Fatalf("OrphanChains #%d: got %+v, want %+v", i,
+				chains[i], want[i])
+		}
+	}
+
+	// Every orphan must come after its parent.
+	seen := make(map[wire.ShaHash]bool)
+	blocks := chain.OrphanBlocks()
+	if len(blocks) != 5 {
+		t.Fatalf("OrphanBlocks: got %d blocks, want 5", len(blocks))
+	}
+	for _, block := range blocks {
+		prevHash := block.MsgBlock().Header.PrevBlock
+		if chain.IsKnownOrphan(&prevHash) && !seen[prevHash] {
+			t.Fatalf("OrphanBlocks: block %v returned before its "+
+				"parent", block.Sha())
+		}
+		seen[*block.Sha()] = true
+	}
+
+	// Fill the pool with large orphans and ensure the total size stays
+	// within the limit by evicting the oldest orphans first.
+	padding := wire.MaxBlockPayload / 2
+	var large []*coinutil.Block
+	for i := uint32(0); i < 40; i++ {
+		block := newOrphanTestBlock(&rootB, i+1, padding)
+		large = append(large, block)
+		chain.TstAddOrphanBlock(block)
+	}
+	totalSize := 0
+	for _, c := range chain.OrphanChains() {
+		totalSize += c.TotalSize
+	}
+	if totalSize > blockchain.TstMaxOrphanBlocksSize {
+		t.Fatalf("orphan pool size %d exceeds limit %d", totalSize,
+			blockchain.TstMaxOrphanBlocksSize)
+	}
+	if chain.IsKnownOrphan(a1.Sha()) {
+		t.Fatalf("oldest orphan was not evicted")
+	}
+	if !chain.IsKnownOrphan(large[len(large)-1].Sha()) {
+		t.Fatalf("newest orphan was evicted")
+	}
+}
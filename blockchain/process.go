@@ -32,6 +32,12 @@ const (
 	// without modifying the current state.
 	BFDryRun
 
+	// BFAssumeValid may be set to indicate the block is known to be an
+	// ancestor of the assumed-valid block, so its transaction scripts are
+	// not validated.  It only applies when the block extends the main
+	// chain.
+	BFAssumeValid
+
 	// BFNone is a convenience value to specifically indicate no flags.
 	BFNone BehaviorFlags = 0
 )
@@ -44,6 +50,12 @@ func (b *BlockChain) blockExists(hash *wire.ShaHash) (bool, error) {
 		return true, nil
 	}
 
+	// The memory chain holds every block when the full index is kept in
+	// memory.
+	if b.fullIndex {
+		return false, nil
+	}
+
 	// Check in database (rest of main chain not in memory).
 	return b.db.ExistsSha(hash)
 }
@@ -54,8 +66,12 @@ func (b *BlockChain) blockExists(hash *wire.ShaHash) (bool, error) {
 // orphans which may no longer be orphans) until there are no more.
 //
 // The flags do not modify the behavior of this function directly, however they
-// are needed to pass along to maybeAcceptBlock.
+// are needed to pass along to maybeAcceptBlock.  BFAssumeValid is cleared since
+// it only applies to the block it was set for and the orphans were never
+// checked against the assumed-valid chain.
 func (b *BlockChain) processOrphans(hash *wire.ShaHash, flags BehaviorFlags) error {
+	flags &^= BFAssumeValid
+
 	// Start with processing at least the passed hash.  Leave a little room
 	// for additional orphan blocks that need to be processed without
 	// needing to grow the array in the common case.
@@ -14,11 +14,14 @@ import (
 	"github.com/conseweb/stcd/wire"
 )
 
-// txValidateItem holds a transaction along with which input to validate.
+// txValidateItem holds a transaction along with which input to validate and
+// the partial signature hashes of the transaction, which are shared by all of
+// its inputs.
 type txValidateItem struct {
 	txInIndex int
 	txIn      *wire.TxIn
 	tx        *coinutil.Tx
+	sigHashes *txscript.TxSigHashes
 }
 
 // txValidator provides a type which asynchronously validates transaction
@@ -31,6 +34,7 @@ type txValidator struct {
 	txStore      TxStore
 	flags        txscript.ScriptFlags
 	sigCache     *txscript.SigCache
+	budget       txscript.Budget
 }
 
 // sendResult sends the result of a script pair validation on the internal
@@ -84,8 +88,10 @@ out:
 			// Create a new script engine for the script pair.
 			sigScript := txIn.SignatureScript
 			pkScript := originMsgTx.TxOut[originTxIndex].PkScript
-			vm, err := txscript.NewEngine(pkScript, txVI.tx.MsgTx(),
-				txVI.txInIndex, v.flags, v.sigCache)
+			inputAmount := originMsgTx.TxOut[originTxIndex].Value
+			vm, err := txscript.NewEngineWithSigHashes(pkScript,
+				txVI.tx.MsgTx(), txVI.txInIndex, v.flags,
+				v.sigCache, txVI.sigHashes, inputAmount)
 			if err != nil {
 				str := fmt.Sprintf("failed to parse input "+
 					"%s:%d which references output %s:%d - "+
@@ -97,8 +103,11 @@ out:
 				v.sendResult(err)
 				break out
 			}
+			vm.SetBudget(v.budget)
 
-			// Execute the script pair.
+			// Execute the script pair.  Running out of budget is
+			// reported with a distinct error code since it says
+			// nothing about the validity of the scripts.
 			if err := vm.Execute(); err != nil {
 				str := fmt.Sprintf("failed to validate input "+
 					"%s:%d which references output %s:%d - "+
@@ -106,7 +115,11 @@ out:
 					"script bytes %x)", txVI.tx.Sha(),
 					txVI.txInIndex, originTxHash,
 					originTxIndex, err, sigScript, pkScript)
-				err := ruleError(ErrScriptValidation, str)
+				code := ErrScriptValidation
+				if err == txscript.ErrBudgetExceeded {
+					code = ErrScriptBudgetExceeded
+				}
+				err := ruleError(code, str)
 				v.sendResult(err)
 				break out
 			}
@@ -179,8 +192,9 @@ func (v *txValidator) Validate(items []*txValidateItem) error {
 }
 
 // newTxValidator returns a new instance of txValidator to be used for
-// validating transaction scripts asynchronously.
-func newTxValidator(txStore TxStore, flags txscript.ScriptFlags, sigCache *txscript.SigCache) *txValidator {
+// validating transaction scripts asynchronously.  The execution of the scripts
+// of each input is limited by the passed budget.
+func newTxValidator(txStore TxStore, flags txscript.ScriptFlags, sigCache *txscript.SigCache, budget txscript.Budget) *txValidator {
 	return &txValidator{
 		validateChan: make(chan *txValidateItem),
 		quitChan:     make(chan struct{}),
@@ -188,16 +202,42 @@ func newTxValidator(txStore TxStore, flags txscript.ScriptFlags, sigCache *txscr
 		txStore:      txStore,
 		sigCache:     sigCache,
 		flags:        flags,
+		budget:       budget,
 	}
 }
 
+// txSigHashes returns the partial signature hashes of the passed transaction
+// which are shared by the script engines verifying its inputs.  Only inputs
+// spending witness programs use them, so nil is returned for transactions
+// without witness data.
+func txSigHashes(tx *coinutil.Tx) *txscript.TxSigHashes {
+	if !tx.MsgTx().HasWitness() {
+		return nil
+	}
+	return txscript.NewTxSigHashes(tx.MsgTx())
+}
+
 // ValidateTransactionScripts validates the scripts for the passed transaction
 // using multiple goroutines.
 func ValidateTransactionScripts(tx *coinutil.Tx, txStore TxStore, flags txscript.ScriptFlags, sigCache *txscript.SigCache) error {
+	return ValidateTransactionScriptsWithBudget(tx, txStore, flags,
+		sigCache, txscript.Budget{})
+}
+
+// ValidateTransactionScriptsWithBudget validates the scripts for the passed
+// transaction using multiple goroutines like ValidateTransactionScripts, but
+// limits the execution of the scripts of each input by the passed budget.  A
+// RuleError with the ErrScriptBudgetExceeded error code is returned when the
+// scripts of an input exceed the budget.
+func ValidateTransactionScriptsWithBudget(tx *coinutil.Tx, txStore TxStore,
+	flags txscript.ScriptFlags, sigCache *txscript.SigCache,
+	budget txscript.Budget) error {
+
 	// Collect all of the transaction inputs and required information for
 	// validation.
 	txIns := tx.MsgTx().TxIn
 	txValItems := make([]*txValidateItem, 0, len(txIns))
+	sigHashes := txSigHashes(tx)
 	for txInIdx, txIn := range txIns {
 		// Skip coinbases.
 		if txIn.PreviousOutPoint.Index == math.MaxUint32 {
@@ -208,12 +248,13 @@ func ValidateTransactionScripts(tx *coinutil.Tx, txStore TxStore, flags txscript
 			txInIndex: txInIdx,
 			txIn:      txIn,
 			tx:        tx,
+			sigHashes: sigHashes,
 		}
 		txValItems = append(txValItems, txVI)
 	}
 
 	// Validate all of the inputs.
-	validator := newTxValidator(txStore, flags, sigCache)
+	validator := newTxValidator(txStore, flags, sigCache, budget)
 	if err := validator.Validate(txValItems); err != nil {
 		return err
 	}
@@ -222,7 +263,9 @@ func ValidateTransactionScripts(tx *coinutil.Tx, txStore TxStore, flags txscript
 }
 
 // checkBlockScripts executes and validates the scripts for all transactions in
-// the passed block.
+// the passed block.  The execution of the scripts is never limited by a budget
+// since rejecting a valid block would split the node from the rest of the
+// network.
 func checkBlockScripts(block *coinutil.Block, txStore TxStore,
 	scriptFlags txscript.ScriptFlags, sigCache *txscript.SigCache) error {
 
@@ -234,6 +277,7 @@ func checkBlockScripts(block *coinutil.Block, txStore TxStore,
 	}
 	txValItems := make([]*txValidateItem, 0, numInputs)
 	for _, tx := range block.Transactions() {
+		sigHashes := txSigHashes(tx)
 		for txInIdx, txIn := range tx.MsgTx().TxIn {
 			// Skip coinbases.
 			if txIn.PreviousOutPoint.Index == math.MaxUint32 {
@@ -244,13 +288,15 @@ func checkBlockScripts(block *coinutil.Block, txStore TxStore,
 				txInIndex: txInIdx,
 				txIn:      txIn,
 				tx:        tx,
+				sigHashes: sigHashes,
 			}
 			txValItems = append(txValItems, txVI)
 		}
 	}
 
 	// Validate all of the inputs.
-	validator := newTxValidator(txStore, scriptFlags, sigCache)
+	validator := newTxValidator(txStore, scriptFlags, sigCache,
+		txscript.Budget{})
 	if err := validator.Validate(txValItems); err != nil {
 		return err
 	}
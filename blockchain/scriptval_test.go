@@ -43,4 +43,17 @@ func TestCheckBlockScripts(t *testing.T) {
 			err)
 		return
 	}
+
+	// Ensure scripts which exceed the execution budget are reported with
+	// a distinct error code.
+	budget := txscript.Budget{MaxSteps: 1}
+	tx := blocks[0].Transactions()[1]
+	err = blockchain.ValidateTransactionScriptsWithBudget(tx, txStore,
+		scriptFlags, nil, budget)
+	rerr, ok := err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrScriptBudgetExceeded {
+		t.Errorf("Transaction script validation with a budget of %d "+
+			"steps: got %v, want %v", budget.MaxSteps, err,
+			blockchain.ErrScriptBudgetExceeded)
+	}
 }
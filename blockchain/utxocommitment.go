@@ -0,0 +1,346 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+const (
+	// CoinbaseUtxoCommitmentPkScriptLength is the length of the public key
+	// script containing an OP_RETURN, the UtxoCommitmentMagicBytes, and the
+	// UTXO commitment itself, which is the minimum length of an output
+	// holding the UTXO commitment.
+	CoinbaseUtxoCommitmentPkScriptLength = 38
+
+	// utxoSetHashLogInterval is the number of blocks between the progress
+	// messages logged while the UTXO set hash of past blocks is computed.
+	utxoSetHashLogInterval = 10000
+)
+
+var (
+	// UtxoCommitmentMagicBytes is the prefix marker within the public key
+	// script of a coinbase output to indicate that this output holds the
+	// UTXO commitment for a block.
+	UtxoCommitmentMagicBytes = []byte{
+		txscript.OP_RETURN,
+		txscript.OP_DATA_36,
+		0x75, // u
+		0x74, // t
+		0x78, // x
+		0x6f, // o
+	}
+)
+
+// UtxoCommitmentScript returns the public key script of the coinbase output
+// which commits to the passed UTXO set hash.
+func UtxoCommitmentScript(utxoSetHash *wire.ShaHash) []byte {
+	pkScript := make([]byte, 0, CoinbaseUtxoCommitmentPkScriptLength)
+	pkScript = append(pkScript, UtxoCommitmentMagicBytes...)
+	return append(pkScript, utxoSetHash[:]...)
+}
+
+// ExtractUtxoCommitment attempts to locate, and return the UTXO commitment of
+// a block, which is the hash of the unspent transaction outputs as of its
+// previous block.  The function additionally returns a boolean indicating if
+// the commitment was located within any of the txOut's in the passed
+// transaction.  The commitment is stored as the data push for an OP_RETURN
+// with special magic bytes to aide in location.
+func ExtractUtxoCommitment(tx *coinutil.Tx) (*wire.ShaHash, bool) {
+	// The UTXO commitment *must* be located within one of the coinbase
+	// transaction's outputs.
+	if !IsCoinBase(tx) {
+		return nil, false
+	}
+
+	msgTx := tx.MsgTx()
+	for i := len(msgTx.TxOut) - 1; i >= 0; i-- {
+		pkScript := msgTx.TxOut[i].PkScript
+		if len(pkScript) >= CoinbaseUtxoCommitmentPkScriptLength &&
+			bytes.HasPrefix(pkScript, UtxoCommitmentMagicBytes) {
+
+			var commitment wire.ShaHash
+			start := len(UtxoCommitmentMagicBytes)
+			end := CoinbaseUtxoCommitmentPkScriptLength
+			copy(commitment[:], pkScript[start:end])
+			return &commitment, true
+		}
+	}
+
+	return nil, false
+}
+
+// ValidateUtxoCommitment ensures the coinbase of the passed block commits to
+// the passed hash of the unspent transaction outputs as of its previous block.
+func ValidateUtxoCommitment(blk *coinutil.Block, utxoSetHash *wire.ShaHash) error {
+	transactions := blk.Transactions()
+	if len(transactions) == 0 {
+		str := "cannot validate UTXO commitment of block without " +
+			"transactions"
+		return ruleError(ErrNoTransactions, str)
+	}
+
+	commitment, found := ExtractUtxoCommitment(transactions[0])
+	if !found {
+		str := "the coinbase transaction does not commit to the UTXO set"
+		return ruleError(ErrMissingUtxoCommitment, str)
+	}
+	if !commitment.IsEqual(utxoSetHash) {
+		str := fmt.Sprintf("UTXO commitment does not match: computed "+
+			"%v, coinbase includes %v", utxoSetHash, commitment)
+		return ruleError(ErrUtxoCommitmentMismatch, str)
+	}
+
+	return nil
+}
+
+// fetchUtxoSetHash returns the UTXO set hash stored for the passed block.  It
+// returns nil when no hash is stored, including for databases which don't
+// store them.
+func (b *BlockChain) fetchUtxoSetHash(hash *wire.ShaHash) (*utxoSetHash, error) {
+	serialized, err := b.db.FetchUtxoSetHash(hash)
+	if err == database.ErrUtxoSetHashMissing ||
+		err == database.ErrNotImplemented {
+
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deserializeUtxoSetHash(serialized)
+}
+
+// storeUtxoSetHash stores the UTXO set hash as of the passed block.
+func (b *BlockChain) storeUtxoSetHash(hash *wire.ShaHash, h *utxoSetHash) error {
+	err := b.db.InsertUtxoSetHash(hash, h.serialize())
+	if err == database.ErrNotImplemented {
+		return nil
+	}
+	return err
+}
+
+// genesisUtxoSetHash returns the UTXO set hash as of the genesis block, whose
+// set only holds the outputs of its coinbase.
+func (b *BlockChain) genesisUtxoSetHash() (*utxoSetHash, error) {
+	h := newUtxoSetHash()
+	genesis := coinutil.NewBlock(b.chainParams.GenesisBlock)
+	if err := h.connectBlock(genesis, 0, nil); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// knownUtxoSetHash returns the UTXO set hash as of the passed node when it is
+// readily available, which is when it was already calculated for the node,
+// is stored in the database or the node is the genesis block.  It returns nil
+// otherwise.
+func (b *BlockChain) knownUtxoSetHash(node *blockNode) (*utxoSetHash, error) {
+	if node.utxoSetHash != nil {
+		return node.utxoSetHash, nil
+	}
+	if node.height == 0 {
+		h, err := b.genesisUtxoSetHash()
+		if err != nil {
+			return nil, err
+		}
+		node.utxoSetHash = h
+		return h, nil
+	}
+
+	h, err := b.fetchUtxoSetHash(node.hash)
+	if err != nil {
+		return nil, err
+	}
+	node.utxoSetHash = h
+	return h, nil
+}
+
+// calcUtxoSetHash returns the UTXO set hash as of the passed node.  When the
+// hash isn't known, it is calculated from the nearest ancestor whose hash is
+// known, which can take a long time when none of the hashes of the main chain
+// have been calculated yet.
+func (b *BlockChain) calcUtxoSetHash(node *blockNode) (*utxoSetHash, error) {
+	// Collect the side chain nodes back to the main chain whose hashes
+	// need to be calculated.
+	var attachNodes []*blockNode
+	var h *utxoSetHash
+	for n := node; ; {
+		var err error
+		h, err = b.knownUtxoSetHash(n)
+		if err != nil {
+			return nil, err
+		}
+		if h != nil {
+			break
+		}
+		if n.inMainChain {
+			h, err = b.calcMainChainUtxoSetHash(n.hash, n.height)
+			if err != nil {
+				return nil, err
+			}
+			n.utxoSetHash = h
+			break
+		}
+
+		attachNodes = append(attachNodes, n)
+		n, err = b.getPrevNodeFromNode(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the side chain blocks from the point of view of each of them.
+	for i := len(attachNodes) - 1; i >= 0; i-- {
+		n := attachNodes[i]
+		block, exists := b.blockCache[*n.hash]
+		if !exists {
+			return nil, fmt.Errorf("unable to find block %v in side "+
+				"chain cache to calculate its UTXO set hash",
+				n.hash)
+		}
+		txStore, err := b.fetchInputTransactions(n, block)
+		if err != nil {
+			return nil, err
+		}
+		h = h.clone()
+		if err := h.connectBlock(block, n.height, txStore); err != nil {
+			return nil, err
+		}
+		n.utxoSetHash = h
+	}
+
+	return h, nil
+}
+
+// calcMainChainUtxoSetHash returns the UTXO set hash as of the main chain
+// block with the passed hash and height.  The hash is calculated from the
+// most recent block before it whose hash is stored, and the hashes of the
+// blocks in between are stored along the way.
+func (b *BlockChain) calcMainChainUtxoSetHash(hash *wire.ShaHash, height int32) (*utxoSetHash, error) {
+	// Find the most recent block with a stored hash, or start from the
+	// genesis block when there is none.
+	var h *utxoSetHash
+	startHeight := height
+	for ; startHeight > 0; startHeight-- {
+		sha := hash
+		if startHeight != height {
+			var err error
+			sha, err = b.db.FetchBlockShaByHeight(startHeight)
+			if err != nil {
+				return nil, err
+			}
+		}
+		stored, err := b.fetchUtxoSetHash(sha)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			h = stored
+			break
+		}
+	}
+	if h == nil {
+		var err error
+		h, err = b.genesisUtxoSetHash()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if startHeight == height {
+		return h, nil
+	}
+
+	log.Infof("Calculating the UTXO set hash of blocks %d to %d",
+		startHeight+1, height)
+	for blockHeight := startHeight + 1; blockHeight <= height; blockHeight++ {
+		sha, err := b.db.FetchBlockShaByHeight(blockHeight)
+		if err != nil {
+			return nil, err
+		}
+		block, err := b.db.FetchBlockBySha(sha)
+		if err != nil {
+			return nil, err
+		}
+		txStore := fetchSpentTxStore(b.db, block, blockHeight)
+		if err := h.connectBlock(block, blockHeight, txStore); err != nil {
+			return nil, err
+		}
+		if err := b.storeUtxoSetHash(sha, h); err != nil {
+			return nil, err
+		}
+
+		if blockHeight%utxoSetHashLogInterval == 0 {
+			log.Infof("Calculated the UTXO set hash up to height %d",
+				blockHeight)
+		}
+	}
+
+	return h, nil
+}
+
+// fetchSpentTxStore returns a transaction store with the transactions spent by
+// the passed main chain block at the passed height.  Unlike
+// fetchInputTransactions, the spent transactions are not checked for double
+// spends, so it must only be used for blocks which were already validated or
+// are known to be valid from a checkpoint.
+func fetchSpentTxStore(db database.Db, block *coinutil.Block, height int32) TxStore {
+	txInFlight := make(map[wire.ShaHash]*coinutil.Tx)
+	txNeededSet := make(map[wire.ShaHash]struct{})
+	for i, tx := range block.Transactions() {
+		if i != 0 {
+			for _, txIn := range tx.MsgTx().TxIn {
+				originHash := txIn.PreviousOutPoint.Hash
+				if _, ok := txInFlight[originHash]; !ok {
+					txNeededSet[originHash] = struct{}{}
+				}
+			}
+		}
+		txInFlight[*tx.Sha()] = tx
+	}
+
+	txStore := fetchTxStoreMain(db, txNeededSet, true)
+	for hash, tx := range txInFlight {
+		txStore[hash] = &TxData{
+			Tx:          tx,
+			Hash:        tx.Sha(),
+			BlockHeight: height,
+			Spent:       make([]bool, len(tx.MsgTx().TxOut)),
+		}
+	}
+	return txStore
+}
+
+// UtxoSetHash returns the hash of the unspent transaction outputs as of the
+// block with the passed hash, which must be in the main chain or a known side
+// chain.  The coinbase of the next block commits to it once the UTXO
+// commitment rule change is active.  The first call can take a long time when
+// the hashes of past blocks haven't been calculated yet.
+//
+// This function is NOT safe for concurrent access.
+func (b *BlockChain) UtxoSetHash(hash *wire.ShaHash) (*wire.ShaHash, error) {
+	if node, ok := b.index[*hash]; ok {
+		h, err := b.calcUtxoSetHash(node)
+		if err != nil {
+			return nil, err
+		}
+		return h.digest(), nil
+	}
+
+	height, err := b.db.FetchBlockHeightBySha(hash)
+	if err != nil {
+		return nil, err
+	}
+	h, err := b.calcMainChainUtxoSetHash(hash, height)
+	if err != nil {
+		return nil, err
+	}
+	return h.digest(), nil
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// utxoTestBlock returns a block at the passed height with a coinbase paying
+// to the passed outputs followed by the passed transactions.
+func utxoTestBlock(height int32, coinbaseOuts []*wire.TxOut, txns ...*wire.MsgTx) *coinutil.Block {
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&wire.ShaHash{},
+			wire.MaxPrevOutIndex),
+		SignatureScript: []byte{0x01, byte(height), 0x00},
+		Sequence:        wire.MaxTxInSequenceNum,
+	})
+	for _, txOut := range coinbaseOuts {
+		coinbase.AddTxOut(txOut)
+	}
+
+	var msgBlock wire.MsgBlock
+	msgBlock.AddTransaction(coinbase)
+	for _, tx := range txns {
+		msgBlock.AddTransaction(tx)
+	}
+	block := coinutil.NewBlock(&msgBlock)
+	block.SetHeight(height)
+	return block
+}
+
+// TestUtxoSetHash ensures the hash of the unspent transaction outputs doesn't
+// depend on the order outputs are added in, that removing an output undoes
+// adding it and that unspendable outputs are never part of the set.
+func TestUtxoSetHash(t *testing.T) {
+	payScript := []byte{txscript.OP_TRUE}
+	nullData := []byte{txscript.OP_RETURN, txscript.OP_DATA_1, 0x01}
+	blockA := utxoTestBlock(1, []*wire.TxOut{
+		wire.NewTxOut(5000000000, payScript),
+		wire.NewTxOut(0, nullData),
+	})
+	blockB := utxoTestBlock(2, []*wire.TxOut{
+		wire.NewTxOut(5000000000, payScript),
+	})
+
+	// The transaction store holds the coinbase of the first block, which
+	// the last block spends.
+	coinbaseA := blockA.Transactions()[0]
+	txStore := blockchain.TxStore{
+		*coinbaseA.Sha(): &blockchain.TxData{
+			Tx:          coinbaseA,
+			Hash:        coinbaseA.Sha(),
+			BlockHeight: 1,
+			Spent:       make([]bool, 2),
+		},
+	}
+	spend := wire.NewMsgTx()
+	spend.AddTxIn(wire.NewTxIn(wire.NewOutPoint(coinbaseA.Sha(), 0), nil))
+	spend.AddTxOut(wire.NewTxOut(5000000000, nullData))
+	blockC := utxoTestBlock(3, []*wire.TxOut{
+		wire.NewTxOut(0, nullData),
+	}, spend)
+
+	empty, err := blockchain.TstUtxoSetHash(nil, txStore)
+	if err != nil {
+		t.Fatalf("TstUtxoSetHash: %v", err)
+	}
+	ab, err := blockchain.TstUtxoSetHash([]*coinutil.Block{blockA, blockB},
+		txStore)
+	if err != nil {
+		t.Fatalf("TstUtxoSetHash: %v", err)
+	}
+	ba, err := blockchain.TstUtxoSetHash([]*coinutil.Block{blockB, blockA},
+		txStore)
+	if err != nil {
+		t.Fatalf("TstUtxoSetHash: %v", err)
+	}
+	if !ab.IsEqual(ba) {
+		t.Errorf("UTXO set hash depends on the order: got %v and %v",
+			ab, ba)
+	}
+	if ab.IsEqual(empty) {
+		t.Errorf("UTXO set hash didn't change when outputs were added")
+	}
+
+	// Spending the only spendable output of the first block while only
+	// creating unspendable outputs leaves the set empty.
+	ac, err := blockchain.TstUtxoSetHash([]*coinutil.Block{blockA, blockC},
+		txStore)
+	if err != nil {
+		t.Fatalf("TstUtxoSetHash: %v", err)
+	}
+	if !ac.IsEqual(empty) {
+		t.Errorf("UTXO set hash of the empty set: got %v, want %v", ac,
+			empty)
+	}
+
+	// Spending an output which isn't in the transaction store fails.
+	_, err = blockchain.TstUtxoSetHash([]*coinutil.Block{blockC},
+		blockchain.TxStore{})
+	if err == nil {
+		t.Errorf("TstUtxoSetHash: spent an unknown output")
+	}
+}
+
+// TestValidateUtxoCommitment ensures the UTXO commitment of a block is located
+// in its coinbase and is required to match the expected UTXO set hash.
+func TestValidateUtxoCommitment(t *testing.T) {
+	payScript := []byte{txscript.OP_TRUE}
+	utxoSetHash := wire.ShaHash{0x01, 0x02, 0x03}
+	otherHash := wire.ShaHash{0x04}
+
+	tests := []struct {
+		name  string
+		block *coinutil.Block
+		valid bool
+		code  blockchain.ErrorCode
+	}{
+		{
+			name: "matching commitment",
+			block: utxoTestBlock(1, []*wire.TxOut{
+				wire.NewTxOut(5000000000, payScript),
+				wire.NewTxOut(0, blockchain.UtxoCommitmentScript(
+					&utxoSetHash)),
+			}),
+			valid: true,
+		},
+		{
+			name: "missing commitment",
+			block: utxoTestBlock(1, []*wire.TxOut{
+				wire.NewTxOut(5000000000, payScript),
+			}),
+			code: blockchain.ErrMissingUtxoCommitment,
+		},
+		{
+			name: "mismatched commitment",
+			block: utxoTestBlock(1, []*wire.TxOut{
+				wire.NewTxOut(0, blockchain.UtxoCommitmentScript(
+					&otherHash)),
+			}),
+			code: blockchain.ErrUtxoCommitmentMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		err := blockchain.ValidateUtxoCommitment(test.block, &utxoSetHash)
+		if test.valid {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		rerr, ok := err.(blockchain.RuleError)
+		if !ok || rerr.ErrorCode != test.code {
+			t.Errorf("%s: got %v, want error code %v", test.name, err,
+				test.code)
+		}
+	}
+
+	// Only coinbase transactions carry the commitment.
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&otherHash, 0), nil))
+	tx.AddTxOut(wire.NewTxOut(0, blockchain.UtxoCommitmentScript(
+		&utxoSetHash)))
+	if _, ok := blockchain.ExtractUtxoCommitment(coinutil.NewTx(tx)); ok {
+		t.Errorf("ExtractUtxoCommitment: found a commitment outside of " +
+			"the coinbase")
+	}
+}
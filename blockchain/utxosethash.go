@@ -0,0 +1,129 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/muhash"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// utxoSetHash is a rolling hash of a set of unspent transaction outputs which
+// is updated as blocks add and remove outputs.  It only depends on the outputs
+// in the set, not on the order they were added in.
+type utxoSetHash struct {
+	set *muhash.Hash
+}
+
+// newUtxoSetHash returns the hash of an empty set of unspent outputs.
+func newUtxoSetHash() *utxoSetHash {
+	return &utxoSetHash{set: muhash.New()}
+}
+
+// deserializeUtxoSetHash decodes a hash serialized with serialize.
+func deserializeUtxoSetHash(serialized []byte) (*utxoSetHash, error) {
+	set, err := muhash.Deserialize(serialized)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UTXO set hash: %v", err)
+	}
+	return &utxoSetHash{set: set}, nil
+}
+
+// clone returns a copy of the hash which can be modified without affecting
+// the original.
+func (h *utxoSetHash) clone() *utxoSetHash {
+	return &utxoSetHash{set: h.set.Clone()}
+}
+
+// serializeUtxo serializes an unspent output along with its outpoint, the
+// height of the block containing it and whether it was created by a coinbase.
+// The serialization is laid out like the coins of UTXO snapshots.
+func serializeUtxo(outPoint *wire.OutPoint, height int32, isCoinBase bool, txOut *wire.TxOut) []byte {
+	var buf bytes.Buffer
+	var scratch [8]byte
+	buf.Write(outPoint.Hash[:])
+	binary.LittleEndian.PutUint32(scratch[:4], outPoint.Index)
+	buf.Write(scratch[:4])
+	code := uint32(height) << 1
+	if isCoinBase {
+		code |= 1
+	}
+	binary.LittleEndian.PutUint32(scratch[:4], code)
+	buf.Write(scratch[:4])
+	binary.LittleEndian.PutUint64(scratch[:], uint64(txOut.Value))
+	buf.Write(scratch[:])
+	binary.LittleEndian.PutUint32(scratch[:4], uint32(len(txOut.PkScript)))
+	buf.Write(scratch[:4])
+	buf.Write(txOut.PkScript)
+	return buf.Bytes()
+}
+
+// add adds the passed unspent output to the set.
+func (h *utxoSetHash) add(outPoint *wire.OutPoint, height int32, isCoinBase bool, txOut *wire.TxOut) {
+	h.set.Add(serializeUtxo(outPoint, height, isCoinBase, txOut))
+}
+
+// remove removes the passed unspent output from the set.
+func (h *utxoSetHash) remove(outPoint *wire.OutPoint, height int32, isCoinBase bool, txOut *wire.TxOut) {
+	h.set.Remove(serializeUtxo(outPoint, height, isCoinBase, txOut))
+}
+
+// connectBlock updates the set for the passed block at the passed height by
+// removing the outputs its transactions spend and adding the outputs they
+// create.  Outputs whose script starts with OP_RETURN are provably unspendable
+// and are never part of the set, like in UTXO snapshots.  The passed
+// transaction store must contain the transactions spent by the block as
+// returned by fetchInputTransactions.
+func (h *utxoSetHash) connectBlock(block *coinutil.Block, height int32, txStore TxStore) error {
+	for i, tx := range block.Transactions() {
+		msgTx := tx.MsgTx()
+		if i != 0 {
+			for _, txIn := range msgTx.TxIn {
+				prevOut := &txIn.PreviousOutPoint
+				originTx, exists := txStore[prevOut.Hash]
+				if !exists || originTx.Err != nil ||
+					originTx.Tx == nil || prevOut.Index >=
+					uint32(len(originTx.Tx.MsgTx().TxOut)) {
+
+					return fmt.Errorf("unable to find output "+
+						"%v spent by transaction %v",
+						prevOut, tx.Sha())
+				}
+				originTxOut := originTx.Tx.MsgTx().TxOut[prevOut.Index]
+				h.remove(prevOut, originTx.BlockHeight,
+					IsCoinBase(originTx.Tx), originTxOut)
+			}
+		}
+
+		for txOutIdx, txOut := range msgTx.TxOut {
+			if len(txOut.PkScript) > 0 &&
+				txOut.PkScript[0] == txscript.OP_RETURN {
+
+				continue
+			}
+			outPoint := wire.NewOutPoint(tx.Sha(), uint32(txOutIdx))
+			h.add(outPoint, height, i == 0, txOut)
+		}
+	}
+
+	return nil
+}
+
+// serialize returns the serialized hash.
+func (h *utxoSetHash) serialize() []byte {
+	return h.set.Serialize()
+}
+
+// digest returns the double SHA-256 of the serialized hash, which is what
+// blocks commit to.
+func (h *utxoSetHash) digest() *wire.ShaHash {
+	digest := wire.ShaHash(h.set.Digest())
+	return &digest
+}
@@ -20,8 +20,8 @@ import (
 
 const (
 	// MaxSigOpsPerBlock is the maximum number of signature operations
-	// allowed for a block.  It is a fraction of the max block payload size.
-	MaxSigOpsPerBlock = wire.MaxBlockPayload / 50
+	// allowed for a block.  It is a fraction of the max block base size.
+	MaxSigOpsPerBlock = MaxBlockBaseSize / 50
 
 	// MaxTimeOffsetSeconds is the maximum number of seconds a block time
 	// is allowed to be ahead of the current time.  This is currently 2
@@ -42,10 +42,6 @@ const (
 	// coinbases to start with the serialized block height.
 	serializedHeightVersion = 2
 
-	// baseSubsidy is the starting subsidy amount for mined blocks.  This
-	// value is halved every SubsidyHalvingInterval blocks.
-	baseSubsidy = 50 * coinutil.SatoshiPerBitcoin
-
 	// CoinbaseMaturity is the number of blocks required before newly
 	// mined bitcoins (coinbase transactions) can be spent.
 	CoinbaseMaturity = 100
@@ -182,18 +178,11 @@ func isBIP0030Node(node *blockNode) bool {
 // newly generated blocks awards as well as validating the coinbase for blocks
 // has the expected value.
 //
-// The subsidy is halved every SubsidyHalvingInterval blocks.  Mathematically
-// this is: baseSubsidy / 2^(height/subsidyHalvingInterval)
-//
-// At the target block generation rate for the main network, this is
-// approximately every 4 years.
+// The subsidy schedule is defined by the chain parameters.  See
+// chaincfg.Params.BlockSubsidy for details.  With the parameters of the main
+// network, the subsidy is halved approximately every 4 years.
 func CalcBlockSubsidy(height int32, chainParams *chaincfg.Params) int64 {
-	if chainParams.SubsidyHalvingInterval == 0 {
-		return baseSubsidy
-	}
-
-	// Equivalent to: baseSubsidy / 2^(height/subsidyHalvingInterval)
-	return baseSubsidy >> uint(height/chainParams.SubsidyHalvingInterval)
+	return chainParams.BlockSubsidy(height)
 }
 
 // CheckTransactionSanity performs some preliminary checks on a transaction to
@@ -210,12 +199,12 @@ func CheckTransactionSanity(tx *coinutil.Tx) error {
 		return ruleError(ErrNoTxOutputs, "transaction has no outputs")
 	}
 
-	// A transaction must not exceed the maximum allowed block payload when
-	// serialized.
-	serializedTxSize := tx.MsgTx().SerializeSize()
-	if serializedTxSize > wire.MaxBlockPayload {
+	// A transaction must not exceed the maximum allowed block base size
+	// when serialized without its witness data.
+	serializedTxSize := tx.MsgTx().SerializeSizeStripped()
+	if serializedTxSize > MaxBlockBaseSize {
 		str := fmt.Sprintf("serialized transaction is too big - got "+
-			"%d, max %d", serializedTxSize, wire.MaxBlockPayload)
+			"%d, max %d", serializedTxSize, MaxBlockBaseSize)
 		return ruleError(ErrTxTooBig, str)
 	}
 
@@ -439,10 +428,14 @@ func CountP2SHSigOps(tx *coinutil.Tx, isCoinBaseTx bool, txStore TxStore) (int,
 func checkBlockHeaderSanity(header *wire.BlockHeader, powLimit *big.Int, timeSource MedianTimeSource, flags BehaviorFlags) error {
 	// Ensure the proof of work bits in the block header is in min/max range
 	// and the block hash is less than the target value described by the
-	// bits.
-	err := checkProofOfWork(header, powLimit, flags)
-	if err != nil {
-		return err
+	// bits.  Headers which set the proof-of-stake bit are checked once
+	// their height is known instead, since the bit only marks
+	// proof-of-stake blocks from the ProofOfStakeHeight of the network.
+	if header.BlockType() == wire.BlockTypePoW {
+		err := checkProofOfWork(header, powLimit, flags)
+		if err != nil {
+			return err
+		}
 	}
 
 	// A block timestamp must not have a greater precision than one second.
@@ -488,19 +481,21 @@ func checkBlockSanity(block *coinutil.Block, powLimit *big.Int, timeSource Media
 			"any transactions")
 	}
 
-	// A block must not have more transactions than the max block payload.
-	if numTx > wire.MaxBlockPayload {
+	// A block must not have more transactions than the max block base
+	// size.
+	if numTx > MaxBlockBaseSize {
 		str := fmt.Sprintf("block contains too many transactions - "+
-			"got %d, max %d", numTx, wire.MaxBlockPayload)
+			"got %d, max %d", numTx, MaxBlockBaseSize)
 		return ruleError(ErrTooManyTransactions, str)
 	}
 
-	// A block must not exceed the maximum allowed block payload when
-	// serialized.
-	serializedSize := msgBlock.SerializeSize()
-	if serializedSize > wire.MaxBlockPayload {
+	// A block must not exceed the maximum allowed block base size when
+	// serialized without witness data.  The witness data is limited by the
+	// block weight once the segregated witness soft fork is active.
+	serializedSize := msgBlock.SerializeSizeStripped()
+	if serializedSize > MaxBlockBaseSize {
 		str := fmt.Sprintf("serialized block is too big - got %d, "+
-			"max %d", serializedSize, wire.MaxBlockPayload)
+			"max %d", serializedSize, MaxBlockBaseSize)
 		return ruleError(ErrBlockTooBig, str)
 	}
 
@@ -535,7 +530,7 @@ func checkBlockSanity(block *coinutil.Block, powLimit *big.Int, timeSource Media
 	// checks.  Bitcoind builds the tree here and checks the merkle root
 	// after the following checks, but there is no reason not to check the
 	// merkle root matches here.
-	merkles := BuildMerkleTreeStore(block.Transactions())
+	merkles := BuildMerkleTreeStore(block.Transactions(), false)
 	calculatedMerkleRoot := merkles[len(merkles)-1]
 	if !header.MerkleRoot.IsEqual(calculatedMerkleRoot) {
 		str := fmt.Sprintf("block merkle root is invalid - block "+
@@ -595,8 +590,17 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 		return nil
 	}
 
+	// The height of this block is one more than the referenced previous
+	// block.
+	blockHeight := prevNode.height + 1
+
+	blockType, err := b.headerBlockType(header, blockHeight, flags)
+	if err != nil {
+		return err
+	}
+
 	fastAdd := flags&BFFastAdd == BFFastAdd
-	if !fastAdd {
+	if !fastAdd && blockType == wire.BlockTypePoW {
 		// Ensure the difficulty specified in the block header matches
 		// the calculated difficulty based on the previous block and
 		// difficulty retarget rules.
@@ -611,7 +615,9 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 			str = fmt.Sprintf(str, blockDifficulty, expectedDifficulty)
 			return ruleError(ErrUnexpectedDifficulty, str)
 		}
+	}
 
+	if !fastAdd {
 		// Ensure the timestamp for the block header is after the
 		// median time of the last several blocks (medianTimeBlocks).
 		medianTime, err := b.calcPastMedianTime(prevNode)
@@ -626,10 +632,6 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 		}
 	}
 
-	// The height of this block is one more than the referenced previous
-	// block.
-	blockHeight := prevNode.height + 1
-
 	// Ensure chain matches up to predetermined checkpoints.
 	blockHash := header.BlockSha()
 	if !b.verifyCheckpoint(blockHeight, &blockHash) {
@@ -685,7 +687,9 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 		}
 	}
 
-	return nil
+	// Perform the checks defined by the consensus hooks, which prove
+	// proof-of-stake headers.
+	return b.hooks.CheckBlockHeader(header, blockType, blockHeight, flags)
 }
 
 // checkBlockContext peforms several validation checks on the block which depend
@@ -710,6 +714,55 @@ func (b *BlockChain) checkBlockContext(block *coinutil.Block, prevNode *blockNod
 		return err
 	}
 
+	return b.checkBlockTxContext(block, prevNode, flags)
+}
+
+// checkBlockTxContext performs the checks of checkBlockContext which concern
+// the transactions of the block rather than its header.  The previous node
+// must not be nil.  See checkBlockContext for how the flags modify its
+// behavior.
+func (b *BlockChain) checkBlockTxContext(block *coinutil.Block, prevNode *blockNode, flags BehaviorFlags) error {
+	// Proof-of-stake blocks must hold their signature in the coinbase,
+	// where the block hash commits to it.  The consensus hooks verify it
+	// once the outputs spent by the block are known.
+	header := &block.MsgBlock().Header
+	blockType := calcBlockType(header, prevNode.height+1, b.chainParams)
+	if blockType == wire.BlockTypePoS {
+		if _, err := ExtractBlockSignature(block); err != nil {
+			return err
+		}
+	}
+
+	// Once the segregated witness soft fork is active, the witness data of
+	// the block must match the commitment in its coinbase and the block
+	// must not exceed the maximum weight.  Before then, blocks must not
+	// contain any witness data at all.  This is part of BIP0141.
+	segwitState, err := b.deploymentState(prevNode, chaincfg.DeploymentSegwit)
+	if err != nil {
+		return err
+	}
+	if segwitState == ThresholdActive {
+		if err := ValidateWitnessCommitment(block); err != nil {
+			return err
+		}
+
+		blockWeight := GetBlockWeight(block)
+		if blockWeight > MaxBlockWeight {
+			str := fmt.Sprintf("block's weight metric is too high "+
+				"- got %v, max %v", blockWeight, MaxBlockWeight)
+			return ruleError(ErrBlockWeightTooHigh, str)
+		}
+	} else {
+		for _, tx := range block.Transactions() {
+			if tx.MsgTx().HasWitness() {
+				str := fmt.Sprintf("block contains transaction "+
+					"%v with witness data before the "+
+					"segwit deployment is active", tx.Sha())
+				return ruleError(ErrUnexpectedWitness, str)
+			}
+		}
+	}
+
 	fastAdd := flags&BFFastAdd == BFFastAdd
 	if !fastAdd {
 		// The height of this block is one more than the referenced
@@ -967,6 +1020,74 @@ func CheckTransactionInputs(tx *coinutil.Tx, txHeight int32, txStore TxStore) (i
 	return txFeeInSatoshi, nil
 }
 
+// blockScriptFlags returns the flags the scripts of a block with the passed
+// header are verified with, which depend on the rule changes in effect after
+// the passed previous node.
+func (b *BlockChain) blockScriptFlags(header *wire.BlockHeader, prevNode *blockNode) (txscript.ScriptFlags, error) {
+	// Blocks created after the BIP0016 activation time need to have the
+	// pay-to-script-hash checks enabled.
+	var scriptFlags txscript.ScriptFlags
+	if header.Timestamp.After(txscript.Bip16Activation) {
+		scriptFlags |= txscript.ScriptBip16
+	}
+
+	// Enforce DER signatures for block versions 3+ once the majority of the
+	// network has upgraded to the enforcement threshold, or once its rule
+	// change deployment is active.  This is part of BIP0066.
+	derSigState, err := b.deploymentState(prevNode, chaincfg.DeploymentDERSig)
+	if err != nil {
+		return 0, err
+	}
+	if derSigState == ThresholdActive || (header.Version >= 3 &&
+		b.isMajorityVersion(3, prevNode,
+			b.chainParams.BlockEnforceNumRequired)) {
+
+		scriptFlags |= txscript.ScriptVerifyDERSignatures
+	}
+
+	// Enforce CHECKLOCKTIMEVERIFY for block versions 4+ once the majority
+	// of the network has upgraded to the enforcement threshold, or once
+	// its rule change deployment is active.  This is part of BIP0065.
+	cltvState, err := b.deploymentState(prevNode, chaincfg.DeploymentCLTV)
+	if err != nil {
+		return 0, err
+	}
+	if cltvState == ThresholdActive || (header.Version >= 4 &&
+		b.isMajorityVersion(4, prevNode,
+			b.chainParams.BlockEnforceNumRequired)) {
+
+		scriptFlags |= txscript.ScriptVerifyCheckLockTimeVerify
+	}
+
+	// Verify the witnesses of inputs which spend witness programs once the
+	// segregated witness soft fork is active.  This is part of BIP0141 and
+	// BIP0143.
+	segwitState, err := b.deploymentState(prevNode, chaincfg.DeploymentSegwit)
+	if err != nil {
+		return 0, err
+	}
+	if segwitState == ThresholdActive {
+		scriptFlags |= txscript.ScriptVerifyWitness
+	}
+
+	// Once the malleability rule change is active, signature scripts must
+	// only push data, data must be pushed with the smallest possible
+	// opcode and scripts must leave a single item on the stack.  These
+	// checks were previously only enforced by policy.
+	malleabilityState, err := b.deploymentState(prevNode,
+		chaincfg.DeploymentMalleability)
+	if err != nil {
+		return 0, err
+	}
+	if malleabilityState == ThresholdActive {
+		scriptFlags |= txscript.ScriptVerifySigPushOnly |
+			txscript.ScriptVerifyMinimalData |
+			txscript.ScriptVerifyCleanStack
+	}
+
+	return scriptFlags, nil
+}
+
 // checkConnectBlock performs several checks to confirm connecting the passed
 // block to the main chain (including whatever reorganization might be necessary
 // to get this node to the main chain) does not violate any rules.
@@ -979,7 +1100,10 @@ func CheckTransactionInputs(tx *coinutil.Tx, txHeight int32, txStore TxStore) (i
 //
 // See the comments for CheckConnectBlock for some examples of the type of
 // checks performed by this function.
-func (b *BlockChain) checkConnectBlock(node *blockNode, block *coinutil.Block) error {
+//
+// The flags modify the behavior of this function as follows:
+//  - BFAssumeValid: The transaction scripts are not validated.
+func (b *BlockChain) checkConnectBlock(node *blockNode, block *coinutil.Block, flags BehaviorFlags) error {
 	// If the side chain blocks end up in the database, a call to
 	// CheckBlockSanity should be done here in case a previous version
 	// allowed a block that is no longer valid.  However, since the
@@ -1026,6 +1150,25 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *coinutil.Block) e
 		enforceBIP0016 = true
 	}
 
+	// Get the previous block node.  This function is used over simply
+	// accessing node.parent directly as it will dynamically create previous
+	// block nodes as needed.  This helps allow only the pieces of the chain
+	// that are needed to remain in memory.
+	prevNode, err := b.getPrevNodeFromNode(node)
+	if err != nil {
+		log.Errorf("getPrevNodeFromNode: %v", err)
+		return err
+	}
+
+	// The segregated witness soft fork changes the signature operation
+	// limit to a cost which counts the signature operations in witnesses
+	// at a discount.
+	segwitState, err := b.deploymentState(prevNode, chaincfg.DeploymentSegwit)
+	if err != nil {
+		return err
+	}
+	enforceSegwit := segwitState == ThresholdActive
+
 	// The number of signature operations must be less than the maximum
 	// allowed per block.  Note that the preliminary sanity checks on a
 	// block also include a check similar to this one, but this check
@@ -1034,6 +1177,7 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *coinutil.Block) e
 	// scripts.
 	transactions := block.Transactions()
 	totalSigOps := 0
+	totalSigOpCost := 0
 	for i, tx := range transactions {
 		numsigOps := CountSigOps(tx)
 		if enforceBIP0016 {
@@ -1061,6 +1205,27 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *coinutil.Block) e
 				totalSigOps, MaxSigOpsPerBlock)
 			return ruleError(ErrTooManySigOps, str)
 		}
+
+		if enforceSegwit {
+			numWitnessSigOps, err := CountWitnessSigOps(tx, i == 0,
+				txInputStore)
+			if err != nil {
+				return err
+			}
+
+			lastSigOpCost := totalSigOpCost
+			totalSigOpCost += numsigOps*WitnessScaleFactor +
+				numWitnessSigOps
+			if totalSigOpCost < lastSigOpCost ||
+				totalSigOpCost > MaxBlockSigOpsCost {
+
+				str := fmt.Sprintf("block contains too many "+
+					"signature operations - got cost %v, "+
+					"max %v", totalSigOpCost,
+					MaxBlockSigOpsCost)
+				return ruleError(ErrTooManySigOps, str)
+			}
+		}
 	}
 
 	// Perform several checks on the inputs for each transaction.  Also
@@ -1096,8 +1261,8 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *coinutil.Block) e
 	for _, txOut := range transactions[0].MsgTx().TxOut {
 		totalSatoshiOut += txOut.Value
 	}
-	expectedSatoshiOut := CalcBlockSubsidy(node.height, b.chainParams) +
-		totalFees
+	expectedSatoshiOut := b.hooks.CalcBlockSubsidy(block, node.height,
+		b.chainParams) + totalFees
 	if totalSatoshiOut > expectedSatoshiOut {
 		str := fmt.Sprintf("coinbase transaction for block pays %v "+
 			"which is more than expected value of %v",
@@ -1117,40 +1282,44 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *coinutil.Block) e
 		runScripts = false
 	}
 
-	// Get the previous block node.  This function is used over simply
-	// accessing node.parent directly as it will dynamically create previous
-	// block nodes as needed.  This helps allow only the pieces of the chain
-	// that are needed to remain in memory.
-	prevNode, err := b.getPrevNodeFromNode(node)
-	if err != nil {
-		log.Errorf("getPrevNodeFromNode: %v", err)
-		return err
+	// Likewise, don't run scripts for the ancestors of the assumed-valid
+	// block.
+	if flags&BFAssumeValid == BFAssumeValid {
+		runScripts = false
 	}
 
-	// Blocks created after the BIP0016 activation time need to have the
-	// pay-to-script-hash checks enabled.
-	var scriptFlags txscript.ScriptFlags
-	if block.MsgBlock().Header.Timestamp.After(txscript.Bip16Activation) {
-		scriptFlags |= txscript.ScriptBip16
+	// Determine the rules the scripts of the block are verified with.
+	scriptFlags, err := b.blockScriptFlags(&block.MsgBlock().Header,
+		prevNode)
+	if err != nil {
+		return err
 	}
 
-	// Enforce DER signatures for block versions 3+ once the majority of the
-	// network has upgraded to the enforcement threshold.  This is part of
-	// BIP0066.
-	blockHeader := &block.MsgBlock().Header
-	if blockHeader.Version >= 3 && b.isMajorityVersion(3, prevNode,
-		b.chainParams.BlockEnforceNumRequired) {
-
-		scriptFlags |= txscript.ScriptVerifyDERSignatures
+	// Once the UTXO commitment rule change is active, the coinbase must
+	// commit to the hash of the unspent transaction outputs as of the
+	// previous block, which is calculated when it isn't known yet.
+	// Otherwise, the hash is only maintained when it is already known as
+	// of the previous block.
+	utxoCommitmentState, err := b.deploymentState(prevNode,
+		chaincfg.DeploymentUtxoCommitment)
+	if err != nil {
+		return err
 	}
-
-	// Enforce CHECKLOCKTIMEVERIFY for block versions 4+ once the majority
-	// of the network has upgraded to the enforcement threshold.  This is
-	// part of BIP0065.
-	if blockHeader.Version >= 4 && b.isMajorityVersion(4, prevNode,
-		b.chainParams.BlockEnforceNumRequired) {
-
-		scriptFlags |= txscript.ScriptVerifyCheckLockTimeVerify
+	var prevUtxoSetHash *utxoSetHash
+	if utxoCommitmentState == ThresholdActive {
+		prevUtxoSetHash, err = b.calcUtxoSetHash(prevNode)
+		if err != nil {
+			return err
+		}
+		err := ValidateUtxoCommitment(block, prevUtxoSetHash.digest())
+		if err != nil {
+			return err
+		}
+	} else {
+		prevUtxoSetHash, err = b.knownUtxoSetHash(prevNode)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Now that the inexpensive checks are done and have passed, verify the
@@ -1158,10 +1327,30 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *coinutil.Block) e
 	// expensive ECDSA signature check scripts.  Doing this last helps
 	// prevent CPU exhaustion attacks.
 	if runScripts {
-		err := checkBlockScripts(block, txInputStore, scriptFlags, b.sigCache)
+		err := checkBlockScripts(block, txInputStore, scriptFlags,
+			b.sigCache)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Verify the signature of the block, which proof-of-stake blocks
+	// carry, against the outputs it spends.
+	blockType := calcBlockType(&block.MsgBlock().Header, node.height,
+		b.chainParams)
+	err = b.hooks.CheckBlockSignature(block, blockType, txInputStore)
+	if err != nil {
+		return err
+	}
+
+	// Roll the UTXO set hash forward to the block.
+	if prevUtxoSetHash != nil {
+		h := prevUtxoSetHash.clone()
+		err := h.connectBlock(block, node.height, txInputStore)
 		if err != nil {
 			return err
 		}
+		node.utxoSetHash = h
 	}
 
 	return nil
@@ -1185,5 +1374,5 @@ func (b *BlockChain) CheckConnectBlock(block *coinutil.Block) error {
 		newNode.workSum.Add(prevNode.workSum, newNode.workSum)
 	}
 
-	return b.checkConnectBlock(newNode, block)
+	return b.checkConnectBlock(newNode, block, BFNone)
 }
@@ -0,0 +1,207 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
+)
+
+// TestDeploymentStates ensures the version bits deployment state machine moves
+// through the expected states for various voting patterns.
+func TestDeploymentStates(t *testing.T) {
+	const (
+		window    = 10
+		threshold = 8
+		signaling = 0x20000000 | 1<<28
+		// Top bits are not set, so the deployment bit does not count.
+		legacy = 1 << 28
+	)
+	genesisTime := uint64(chaincfg.SimNetParams.GenesisBlock.Header.
+		Timestamp.Unix())
+
+	// repeat returns a slice of n copies of the passed version.
+	repeat := func(version int32, n int) []int32 {
+		versions := make([]int32, n)
+		for i := range versions {
+			versions[i] = version
+		}
+		return versions
+	}
+
+	// expect returns the expected states given pairs of a state and the
+	// number of blocks it applies to.  The state reported for a block is
+	// the state of the block after it.
+	expect := func(counts ...interface{}) []blockchain.ThresholdState {
+		var states []blockchain.ThresholdState
+		for i := 0; i < len(counts); i += 2 {
+			state := counts[i].(blockchain.ThresholdState)
+			for j := 0; j < counts[i+1].(int); j++ {
+				states = append(states, state)
+			}
+		}
+		return states
+	}
+
+	tests := []struct {
+		name       string
+		startTime  uint64
+		expireTime uint64
+		versions   []int32
+		want       []blockchain.ThresholdState
+	}{
+		{
+			name:       "activated",
+			startTime:  genesisTime,
+			expireTime: math.MaxInt64,
+			versions:   repeat(signaling, 40),
+			want: expect(blockchain.ThresholdDefined, 8,
+				blockchain.ThresholdStarted, 10,
+				blockchain.ThresholdLockedIn, 10,
+				blockchain.ThresholdActive, 12),
+		},
+		{
+			name:       "not started",
+			startTime:  math.MaxInt64,
+			expireTime: math.MaxInt64,
+			versions:   repeat(signaling, 30),
+			want:       expect(blockchain.ThresholdDefined, 30),
+		},
+		{
+			name:       "below threshold",
+			startTime:  genesisTime,
+			expireTime: math.MaxInt64,
+			// Heights 10 through 19 make up the first voting
+			// window, which is one vote short of the threshold.
+			versions: append(repeat(signaling, 9+threshold-1),
+				repeat(0x20000000, 14)...),
+			want: expect(blockchain.ThresholdDefined, 8,
+				blockchain.ThresholdStarted, 22),
+		},
+		{
+			name:       "legacy versions do not vote",
+			startTime:  genesisTime,
+			expireTime: math.MaxInt64,
+			versions:   repeat(legacy, 30),
+			want: expect(blockchain.ThresholdDefined, 8,
+				blockchain.ThresholdStarted, 22),
+		},
+		{
+			name:       "expired",
+			startTime:  genesisTime,
+			expireTime: genesisTime + 1,
+			versions:   repeat(signaling, 30),
+			want: expect(blockchain.ThresholdDefined, 8,
+				blockchain.ThresholdFailed, 22),
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		params := chaincfg.SimNetParams
+		params.RuleChangeActivationThreshold = threshold
+		params.MinerConfirmationWindow = window
+		params.Deployments[chaincfg.DeploymentTestDummy] =
+			chaincfg.ConsensusDeployment{
+				BitNumber:  28,
+				StartTime:  test.startTime,
+				ExpireTime: test.expireTime,
+			}
+
+		states, err := blockchain.TstDeploymentStates(&params,
+			test.versions, chaincfg.DeploymentTestDummy)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if len(states) != len(test.want) {
+			t.Errorf("%s: got %d states, want %d", test.name,
+				len(states), len(test.want))
+			continue
+		}
+		for i := range states {
+			if states[i] != test.want[i] {
+				t.Errorf("%s: state after height %d: got %v, "+
+					"want %v", test.name, i+1, states[i],
+					test.want[i])
+				break
+			}
+		}
+	}
+}
+
+// TestScriptDeployments ensures the deployments of the script rule changes on
+// the regression test network activate once blocks signal their bits.
+func TestScriptDeployments(t *testing.T) {
+	params := chaincfg.RegressionNetParams
+	window := int(params.MinerConfirmationWindow)
+	want := []blockchain.ThresholdState{
+		blockchain.ThresholdDefined,
+		blockchain.ThresholdStarted,
+		blockchain.ThresholdLockedIn,
+		blockchain.ThresholdActive,
+	}
+
+	for _, id := range []uint32{chaincfg.DeploymentCLTV,
+		chaincfg.DeploymentDERSig} {
+
+		name := chaincfg.DeploymentNames[id]
+		bit := params.Deployments[id].BitNumber
+		versions := make([]int32, window*3)
+		for i := range versions {
+			versions[i] = 0x20000000 | 1<<bit
+		}
+
+		states, err := blockchain.TstDeploymentStates(&params, versions,
+			id)
+		if err != nil {
+			t.Errorf("TstDeploymentStates (%s): unexpected error: "+
+				"%v", name, err)
+			continue
+		}
+
+		// The state reported at each index is the state of the block
+		// two heights later, so check the state of the last block of
+		// each window.
+		for i, height := range []int{window - 3, window*2 - 3,
+			window*3 - 3, window*3 - 2} {
+
+			if states[height] != want[i] {
+				t.Errorf("%s state after height %d: got %v, "+
+					"want %v", name, height+1,
+					states[height], want[i])
+			}
+		}
+	}
+}
+
+// TestThresholdStateStringer tests the stringized output for the
+// ThresholdState type.
+func TestThresholdStateStringer(t *testing.T) {
+	tests := []struct {
+		in   blockchain.ThresholdState
+		want string
+	}{
+		{blockchain.ThresholdDefined, "ThresholdDefined"},
+		{blockchain.ThresholdStarted, "ThresholdStarted"},
+		{blockchain.ThresholdLockedIn, "ThresholdLockedIn"},
+		{blockchain.ThresholdActive, "ThresholdActive"},
+		{blockchain.ThresholdFailed, "ThresholdFailed"},
+		{0xff, "Unknown ThresholdState (255)"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := test.in.String()
+		if result != test.want {
+			t.Errorf("String #%d\n got: %s want: %s", i, result,
+				test.want)
+			continue
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/txscript"
+)
+
+const (
+	// MaxBlockWeight defines the maximum block weight, where "block
+	// weight" is interpreted as defined in BIP0141.  A block's weight is
+	// calculated as the sum of the bytes in the existing transactions
+	// and header, plus the weight of each byte within a transaction.  The
+	// weight of a "base" byte is 4, while the weight of a witness byte is
+	// 1.  As a result, for a block to be valid, the block weight must be
+	// less than, or equal to MaxBlockWeight.
+	MaxBlockWeight = 4000000
+
+	// MaxBlockBaseSize is the maximum number of bytes within a block
+	// which can be allocated to non-witness data.
+	MaxBlockBaseSize = 1000000
+
+	// MaxBlockSigOpsCost is the maximum number of signature operations
+	// allowed for a block once the segregated witness soft fork is active.
+	// It is calculated via a weighted algorithm which weights segregated
+	// witness sig ops lower than regular sig ops.
+	MaxBlockSigOpsCost = 80000
+
+	// WitnessScaleFactor determines the level of "discount" witness data
+	// receives compared to "base" data.  A scale factor of 4, denotes that
+	// witness data is 1/4 as cheap as regular non-witness data.
+	WitnessScaleFactor = 4
+)
+
+// GetBlockWeight computes the value of the weight metric for a given block.
+// Currently the weight metric is simply the sum of the block's serialized size
+// without any witness data scaled proportionally by the WitnessScaleFactor,
+// and the block's serialized size including any witness data.
+func GetBlockWeight(blk *coinutil.Block) int64 {
+	msgBlock := blk.MsgBlock()
+
+	baseSize := msgBlock.SerializeSizeStripped()
+	totalSize := msgBlock.SerializeSize()
+
+	// (baseSize * 3) + totalSize
+	return int64((baseSize * (WitnessScaleFactor - 1)) + totalSize)
+}
+
+// GetTransactionWeight computes the value of the weight metric for a given
+// transaction.  Currently the weight metric is simply the sum of the
+// transaction's serialized size without any witness data scaled
+// proportionally by the WitnessScaleFactor, and the transaction's serialized
+// size including any witness data.
+func GetTransactionWeight(tx *coinutil.Tx) int64 {
+	msgTx := tx.MsgTx()
+
+	baseSize := msgTx.SerializeSizeStripped()
+	totalSize := msgTx.SerializeSize()
+
+	// (baseSize * 3) + totalSize
+	return int64((baseSize * (WitnessScaleFactor - 1)) + totalSize)
+}
+
+// GetTxVirtualSize computes the virtual size of a given transaction.  A
+// transaction's virtual size is based off its weight, creating a discount
+// for any witness data it contains, proportional to the current
+// WitnessScaleFactor value.
+func GetTxVirtualSize(tx *coinutil.Tx) int64 {
+	// vSize := (weight(tx) + 3) / 4
+	//       := (((baseSize * 3) + totalSize) + 3) / 4
+	// We add 3 here as a way to compute the ceiling of the prior arithmetic
+	// to 4.  The division by 4 creates a discount for witness data.
+	return (GetTransactionWeight(tx) + (WitnessScaleFactor - 1)) /
+		WitnessScaleFactor
+}
+
+// CountWitnessSigOps returns the number of signature operations executed by
+// the witnesses of all inputs of the passed transaction which spend witness
+// programs, either directly or nested in pay-to-script-hash outputs.  This
+// requires access to the input transaction scripts.
+func CountWitnessSigOps(tx *coinutil.Tx, isCoinBaseTx bool, txStore TxStore) (int, error) {
+	// Coinbase transactions have no interesting inputs.
+	if isCoinBaseTx {
+		return 0, nil
+	}
+
+	msgTx := tx.MsgTx()
+	totalSigOps := 0
+	for _, txIn := range msgTx.TxIn {
+		// Ensure the referenced input transaction is available.
+		txInHash := &txIn.PreviousOutPoint.Hash
+		originTx, exists := txStore[*txInHash]
+		if !exists || originTx.Err != nil || originTx.Tx == nil {
+			str := fmt.Sprintf("unable to find input transaction "+
+				"%v referenced from transaction %v", txInHash,
+				tx.Sha())
+			return 0, ruleError(ErrMissingTx, str)
+		}
+		originMsgTx := originTx.Tx.MsgTx()
+
+		// Ensure the output index in the referenced transaction is
+		// available.
+		originTxIndex := txIn.PreviousOutPoint.Index
+		if originTxIndex >= uint32(len(originMsgTx.TxOut)) {
+			str := fmt.Sprintf("out of bounds input index %d in "+
+				"transaction %v referenced from transaction %v",
+				originTxIndex, txInHash, tx.Sha())
+			return 0, ruleError(ErrBadTxInput, str)
+		}
+
+		pkScript := originMsgTx.TxOut[originTxIndex].PkScript
+		totalSigOps += txscript.GetWitnessSigOpCount(txIn.SignatureScript,
+			pkScript, txIn.Witness)
+	}
+
+	return totalSigOps, nil
+}
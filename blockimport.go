@@ -0,0 +1,316 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/wire"
+)
+
+const (
+	// importDirName is the name of the directory, relative to the blocks
+	// directory in the data directory, which is scanned for block files
+	// to import on start up.
+	importDirName = "import"
+
+	// importedSuffix is appended to the name of block files in the import
+	// directory once they have been imported so they are not imported
+	// again on the next start up.
+	importedSuffix = ".imported"
+)
+
+// importStatus describes the progress of a block import.
+type importStatus struct {
+	active          bool
+	file            string
+	filesRemaining  int
+	blocksProcessed int64
+	blocksImported  int64
+	err             error
+}
+
+// blockImporter imports blocks from bootstrap files in the background on
+// start up.  The files are read in the same format produced by the addblock
+// and findcheckpoint utilities, that is, a series of blocks each prefixed by
+// the network magic and the length of the serialized block.
+//
+// Every block is handed to the block manager, so it goes through the same
+// validation as blocks received from peers.  Blocks which are already known
+// are skipped, which makes it safe to import the same file more than once.
+type blockImporter struct {
+	server         *server
+	started        int32
+	shutdown       int32
+	quit           chan struct{}
+	wg             sync.WaitGroup
+	progressLogger *blockProgressLogger
+
+	statusMtx sync.Mutex
+	status    importStatus
+}
+
+// newBlockImporter returns a new block importer.  Use Start to begin
+// importing.
+func newBlockImporter(s *server) *blockImporter {
+	return &blockImporter{
+		server:         s,
+		quit:           make(chan struct{}),
+		progressLogger: newBlockProgressLogger("Imported", bmgrLog),
+	}
+}
+
+// importFiles returns the block files which should be imported, those passed
+// via --loadblock followed by those in the import directory sorted by name.
+func importFiles() []string {
+	files := make([]string, 0, len(cfg.LoadBlocks))
+	for _, file := range cfg.LoadBlocks {
+		files = append(files, cleanAndExpandPath(file))
+	}
+
+	importDir := filepath.Join(cfg.DataDir, "blocks", importDirName)
+	entries, err := ioutil.ReadDir(importDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			bmgrLog.Warnf("Unable to read block import directory "+
+				"%s: %v", importDir, err)
+		}
+		return files
+	}
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() ||
+			strings.HasSuffix(entry.Name(), importedSuffix) {
+			continue
+		}
+		files = append(files, filepath.Join(importDir, entry.Name()))
+	}
+	return files
+}
+
+// Start begins importing any block files passed via --loadblock or placed in
+// the import directory.
+func (bi *blockImporter) Start() {
+	// Already started?
+	if atomic.AddInt32(&bi.started, 1) != 1 {
+		return
+	}
+
+	files := importFiles()
+	if len(files) == 0 {
+		return
+	}
+
+	bi.statusMtx.Lock()
+	bi.status.active = true
+	bi.status.filesRemaining = len(files)
+	bi.statusMtx.Unlock()
+
+	bmgrLog.Trace("Starting block importer")
+	bi.wg.Add(1)
+	go bi.importHandler(files)
+}
+
+// Stop gracefully shuts down the block importer, waiting for the block
+// currently being processed to finish.
+func (bi *blockImporter) Stop() error {
+	if atomic.AddInt32(&bi.shutdown, 1) != 1 {
+		bmgrLog.Warnf("Block importer is already in the process of " +
+			"shutting down")
+		return nil
+	}
+	close(bi.quit)
+	bi.wg.Wait()
+	return nil
+}
+
+// Status returns the progress of the block import.
+func (bi *blockImporter) Status() importStatus {
+	bi.statusMtx.Lock()
+	defer bi.statusMtx.Unlock()
+	return bi.status
+}
+
+// importHandler imports each of the passed block files in turn.  An error
+// importing one file is logged and the remaining files are still imported.
+// NOTE: Must be run as a goroutine.
+func (bi *blockImporter) importHandler(files []string) {
+	defer bi.wg.Done()
+
+	for _, file := range files {
+		bi.statusMtx.Lock()
+		bi.status.file = file
+		bi.statusMtx.Unlock()
+
+		bmgrLog.Infof("Importing blocks from %s", file)
+		err := bi.importFile(file)
+		select {
+		case <-bi.quit:
+			bmgrLog.Infof("Block import from %s interrupted", file)
+			return
+		default:
+		}
+
+		bi.statusMtx.Lock()
+		bi.status.filesRemaining--
+		if err != nil {
+			bi.status.err = fmt.Errorf("%s: %v", file, err)
+		}
+		bi.statusMtx.Unlock()
+
+		if err != nil {
+			bmgrLog.Errorf("Failed to import blocks from %s: %v",
+				file, err)
+			continue
+		}
+		bmgrLog.Infof("Finished importing blocks from %s", file)
+
+		// Rename files in the import directory so they are not
+		// imported again on the next start up.
+		if filepath.Base(filepath.Dir(file)) == importDirName {
+			err := os.Rename(file, file+importedSuffix)
+			if err != nil {
+				bmgrLog.Warnf("Unable to rename imported block "+
+					"file %s: %v", file, err)
+			}
+		}
+	}
+
+	bi.statusMtx.Lock()
+	bi.status.active = false
+	bi.status.file = ""
+	bi.statusMtx.Unlock()
+}
+
+// importFile imports every block in the passed file.  It returns early
+// without an error when the importer is stopped.
+func (bi *blockImporter) importFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	for {
+		select {
+		case <-bi.quit:
+			return nil
+		default:
+		}
+
+		serializedBlock, err := readImportBlock(r)
+		if err != nil {
+			return err
+		}
+
+		// A nil block with no error means we're done.
+		if serializedBlock == nil {
+			return nil
+		}
+
+		imported, err := bi.processBlock(serializedBlock)
+		if err != nil {
+			return err
+		}
+
+		bi.statusMtx.Lock()
+		bi.status.blocksProcessed++
+		if imported {
+			bi.status.blocksImported++
+		}
+		bi.statusMtx.Unlock()
+	}
+}
+
+// readImportBlock reads the next serialized block from a block file.  It
+// returns nil without an error once there are no more blocks to read.
+func readImportBlock(r io.Reader) ([]byte, error) {
+	// The block file format is:
+	//  <network> <block length> <serialized block>
+	var net uint32
+	err := binary.Read(r, binary.LittleEndian, &net)
+	if err != nil {
+		if err != io.EOF {
+			return nil, err
+		}
+
+		// No block and no error means there are no more blocks to read.
+		return nil, nil
+	}
+	if net != uint32(activeNetParams.Net) {
+		return nil, fmt.Errorf("network mismatch -- got %x, want %x",
+			net, uint32(activeNetParams.Net))
+	}
+
+	// Read the block length and ensure it is sane.
+	var blockLen uint32
+	if err := binary.Read(r, binary.LittleEndian, &blockLen); err != nil {
+		return nil, err
+	}
+	if blockLen > wire.MaxBlockPayload {
+		return nil, fmt.Errorf("block payload of %d bytes is larger "+
+			"than the max allowed %d bytes", blockLen,
+			wire.MaxBlockPayload)
+	}
+
+	serializedBlock := make([]byte, blockLen)
+	if _, err := io.ReadFull(r, serializedBlock); err != nil {
+		return nil, err
+	}
+
+	return serializedBlock, nil
+}
+
+// processBlock deserializes the passed block and hands it to the block
+// manager unless it is already known.  Returns whether the block was added to
+// the block chain.
+func (bi *blockImporter) processBlock(serializedBlock []byte) (bool, error) {
+	// Deserialize the block which includes checks for malformed blocks.
+	block, err := coinutil.NewBlockFromBytes(serializedBlock)
+	if err != nil {
+		return false, err
+	}
+
+	// Skip blocks that already exist.
+	exists, err := bi.server.db.ExistsSha(block.Sha())
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	// Blocks on side chains and orphans are not in the database, so they
+	// are only detected as duplicates by the chain.
+	isOrphan, err := bi.server.blockManager.ProcessBlock(block,
+		blockchain.BFNone)
+	if rerr, ok := err.(blockchain.RuleError); ok &&
+		rerr.ErrorCode == blockchain.ErrDuplicateBlock {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Orphans are kept by the chain and connected once their parent is
+	// processed, so they are not treated as an error.
+	if isOrphan {
+		return false, nil
+	}
+
+	bi.progressLogger.LogBlockHeight(block)
+	return true, nil
+}
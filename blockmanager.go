@@ -5,7 +5,10 @@
 package main
 
 import (
+	"bufio"
 	"container/list"
+	"encoding/binary"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
@@ -28,10 +31,20 @@ const (
 	// more.
 	minInFlightBlocks = 10
 
+	// maxRequestQueueSize is the maximum number of announced inventory
+	// items which may wait to be requested from a peer.  Peers announcing
+	// more inventory than that are flooding.
+	maxRequestQueueSize = wire.MaxInvPerMsg
+
 	// blockDbNamePrefix is the prefix for the block database name.  The
 	// database type is appended to this value to form the full block
 	// database name.
 	blockDbNamePrefix = "blocks"
+
+	// orphansFileName is the name of the file in the data directory the
+	// orphan blocks are saved to on shutdown so they can still be connected
+	// once their parents are received after a restart.
+	orphansFileName = "orphans.dat"
 )
 
 // zeroHash is the zero value hash (all zeros).  It is defined as a convenience.
@@ -81,6 +94,12 @@ type getSyncPeerMsg struct {
 	reply chan *serverPeer
 }
 
+// getSyncStatusMsg is a message type to be sent across the message channel
+// for retrieving the sync status of the block manager.
+type getSyncStatusMsg struct {
+	reply chan *syncStatus
+}
+
 // checkConnectBlockMsg is a message type to be sent across the message channel
 // for requesting chain to check if a block connects to the end of the current
 // main chain.
@@ -103,6 +122,27 @@ type calcNextReqDifficultyMsg struct {
 	reply     chan calcNextReqDifficultyResponse
 }
 
+// utxoSetHashResponse is a response sent to the reply channel of a
+// utxoSetHashMsg query.
+type utxoSetHashResponse struct {
+	utxoSetHash *wire.ShaHash
+	err         error
+}
+
+// utxoSetHashMsg is a message type to be sent across the message channel for
+// requesting the hash of the unspent transaction outputs as of a block.
+type utxoSetHashMsg struct {
+	hash  *wire.ShaHash
+	reply chan utxoSetHashResponse
+}
+
+// calcBlockSubsidyMsg is a message type to be sent across the message channel
+// for requesting the subsidy of a block at the passed height.
+type calcBlockSubsidyMsg struct {
+	height int32
+	reply  chan int64
+}
+
 // processBlockResponse is a response sent to the reply channel of a
 // processBlockMsg.
 type processBlockResponse struct {
@@ -169,6 +209,13 @@ type chainState struct {
 	newestHeight      int32
 	pastMedianTime    time.Time
 	pastMedianTimeErr error
+
+	// nextBlockVersion is the expected version of the block after the tip
+	// and deploymentStates holds the threshold state of each rule change
+	// deployment for that block.
+	nextBlockVersion    int32
+	deploymentStates    [chaincfg.DefinedDeployments]blockchain.ThresholdState
+	deploymentStatesErr error
 }
 
 // Best returns the block hash and height known for the tip of the best known
@@ -182,12 +229,40 @@ func (c *chainState) Best() (*wire.ShaHash, int32) {
 	return c.newestHash, c.newestHeight
 }
 
+// DeploymentStates returns the threshold state of each rule change deployment
+// for the block after the tip of the best known chain.
+//
+// This function is safe for concurrent access.
+func (c *chainState) DeploymentStates() ([chaincfg.DefinedDeployments]blockchain.ThresholdState, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.deploymentStates, c.deploymentStatesErr
+}
+
+// IsDeploymentActive returns whether the passed rule change deployment is
+// active for the block after the tip of the best known chain.
+//
+// This function is safe for concurrent access.
+func (c *chainState) IsDeploymentActive(deploymentID uint32) (bool, error) {
+	states, err := c.DeploymentStates()
+	if err != nil {
+		return false, err
+	}
+	if deploymentID >= uint32(len(states)) {
+		return false, blockchain.DeploymentError(deploymentID)
+	}
+
+	return states[deploymentID] == blockchain.ThresholdActive, nil
+}
+
 // blockManager provides a concurrency safe block manager for handling all
 // incoming blocks.
 type blockManager struct {
 	server            *server
 	started           int32
 	shutdown          int32
+	initialDownload   int32
 	blockChain        *blockchain.BlockChain
 	requestedTxns     map[wire.ShaHash]struct{}
 	requestedBlocks   map[wire.ShaHash]struct{}
@@ -206,6 +281,39 @@ type blockManager struct {
 	headerList       *list.List
 	startHeader      *list.Element
 	nextCheckpoint   *chaincfg.Checkpoint
+
+	// The following fields are used for headers-only mode.
+	headerChain *blockchain.HeaderChain
+	headersFile *os.File
+
+	// The following fields are used to detect a stalled sync peer.
+	// syncPeerDeadline is when the sync peer has to deliver the next
+	// requested block and stalls counts the stalls per host.
+	syncPeerDeadline time.Time
+	stalls           map[string]int32
+
+	// The following fields are used to skip the script validation of the
+	// ancestors of the assumed-valid block.  assumeValidHeaders holds the
+	// hashes of the headers leading to it while they are downloaded and
+	// assumedValid the hashes of the blocks known to be its ancestors
+	// which are not connected yet.
+	assumeValid        *wire.ShaHash
+	assumeValidHeaders []*wire.ShaHash
+	assumedValid       map[wire.ShaHash]struct{}
+
+	// The following fields are used to bootstrap the node from a UTXO
+	// snapshot.  snapshotHeaders holds the headers the blocks connected to
+	// the snapshot chainstate are validated against, snapshotPeer is the
+	// peer they are downloaded from, and snapshotRequested holds the
+	// hashes of the blocks requested for it.  snapshotSynced is set once
+	// the snapshot chainstate caught up the first time.  The snapshot and
+	// its headers are never replaced, so the RPC server can access them.
+	snapshot           *snapshotChainstate
+	snapshotHeaders    *blockchain.HeaderChain
+	snapshotPeer       *serverPeer
+	snapshotRequested  map[wire.ShaHash]struct{}
+	snapshotSynced     bool
+	snapshotValidating bool
 }
 
 // resetHeaderState sets the headers-first mode state to values appropriate for
@@ -240,6 +348,25 @@ func (b *blockManager) updateChainState(newestHash *wire.ShaHash, newestHeight i
 	} else {
 		b.chainState.pastMedianTime = medianTime
 	}
+
+	// Track the expected version of the next block and the state of each
+	// rule change deployment for it since they are needed when generating
+	// block templates.
+	nextBlockVersion, err := b.blockChain.CalcNextBlockVersion()
+	if err != nil {
+		b.chainState.deploymentStatesErr = err
+		return
+	}
+	for id := range b.chainState.deploymentStates {
+		state, err := b.blockChain.ThresholdState(uint32(id))
+		if err != nil {
+			b.chainState.deploymentStatesErr = err
+			return
+		}
+		b.chainState.deploymentStates[id] = state
+	}
+	b.chainState.nextBlockVersion = nextBlockVersion
+	b.chainState.deploymentStatesErr = nil
 }
 
 // findNextHeaderCheckpoint returns the next checkpoint after the passed height.
@@ -291,6 +418,9 @@ func (b *blockManager) startSync(peers *list.List) {
 		bmgrLog.Errorf("%v", err)
 		return
 	}
+	if cfg.HeadersOnly {
+		_, height = b.headerChain.BestHeader()
+	}
 
 	var bestPeer *serverPeer
 	var enext *list.Element
@@ -309,57 +439,132 @@ func (b *blockManager) startSync(peers *list.List) {
 			continue
 		}
 
-		// TODO(davec): Use a better algorithm to choose the best peer.
-		// For now, just pick the first available candidate.
-		bestPeer = sp
-	}
+		// Peers which became candidates before segwit activated are
+		// no longer candidates when they are unable to serve witness
+		// data, since the stripped blocks they send fail validation.
+		if !b.isSyncCandidate(sp) {
+			peers.Remove(e)
+			continue
+		}
 
-	// Start syncing from the best peer if one was selected.
-	if bestPeer != nil {
-		locator, err := b.blockChain.LatestBlockLocator()
-		if err != nil {
-			bmgrLog.Errorf("Failed to get block locator for the "+
-				"latest block: %v", err)
-			return
+		// Prefer the candidates which stalled the fewest times and
+		// among those the one with the latest block.
+		if bestPeer == nil {
+			bestPeer = sp
+			continue
 		}
+		stalls, bestStalls := b.stalls[stallKey(sp)],
+			b.stalls[stallKey(bestPeer)]
+		if stalls < bestStalls || (stalls == bestStalls &&
+			sp.LastBlock() > bestPeer.LastBlock()) {
 
-		bmgrLog.Infof("Syncing to block height %d from peer %v",
+			bestPeer = sp
+		}
+	}
+
+	// Only the headers are downloaded in headers-only mode.
+	if bestPeer != nil && cfg.HeadersOnly {
+		bmgrLog.Infof("Syncing headers to height %d from peer %v",
 			bestPeer.LastBlock(), bestPeer.Addr())
+		locator := b.headerChain.LatestBlockLocator()
+		bestPeer.PushGetHeadersMsg(locator, &zeroHash)
+		b.setSyncPeer(bestPeer)
+		return
+	}
 
-		// When the current height is less than a known checkpoint we
-		// can use block headers to learn about which blocks comprise
-		// the chain up to the checkpoint and perform less validation
-		// for them.  This is possible since each header contains the
-		// hash of the previous header and a merkle root.  Therefore if
-		// we validate all of the received headers link together
-		// properly and the checkpoint hashes match, we can be sure the
-		// hashes for the blocks in between are accurate.  Further, once
-		// the full blocks are downloaded, the merkle root is computed
-		// and compared against the value in the header which proves the
-		// full block hasn't been tampered with.
-		//
-		// Once we have passed the final checkpoint, or checkpoints are
-		// disabled, use standard inv messages learn about the blocks
-		// and fully validate them.  Finally, regression test mode does
-		// not support the headers-first approach so do normal block
-		// downloads when in regression test mode.
-		if b.nextCheckpoint != nil && height < b.nextCheckpoint.Height &&
-			!cfg.RegressionTest && !cfg.DisableCheckpoints {
-
-			bestPeer.PushGetHeadersMsg(locator, b.nextCheckpoint.Hash)
-			b.headersFirstMode = true
-			bmgrLog.Infof("Downloading headers for blocks %d to "+
-				"%d from peer %s", height+1,
-				b.nextCheckpoint.Height, bestPeer.Addr())
-		} else {
-			bestPeer.PushGetBlocksMsg(locator, &zeroHash)
+	// Bring the UTXO snapshot the node is bootstrapped from up to date
+	// before syncing the main chain.
+	if bestPeer != nil && b.needSnapshotSync() {
+		b.startSnapshotSync(bestPeer)
+		b.setSyncPeer(bestPeer)
+		b.syncPeerDeadline = time.Time{}
+		return
+	}
+
+	// Start syncing from the best peer if one was selected.
+	if bestPeer != nil {
+		if !b.syncChain(bestPeer, height) {
+			return
 		}
-		b.syncPeer = bestPeer
+		b.setSyncPeer(bestPeer)
+		b.syncPeerDeadline = time.Time{}
 	} else {
 		bmgrLog.Warnf("No sync peer candidates available")
 	}
 }
 
+// syncChain starts syncing the main chain from the passed peer, given the
+// height of the latest block in the database.  It returns false when syncing
+// could not be started.
+func (b *blockManager) syncChain(sp *serverPeer, height int32) bool {
+	locator, err := b.blockChain.LatestBlockLocator()
+	if err != nil {
+		bmgrLog.Errorf("Failed to get block locator for the "+
+			"latest block: %v", err)
+		return false
+	}
+
+	bmgrLog.Infof("Syncing to block height %d from peer %v",
+		sp.LastBlock(), sp.Addr())
+
+	// When the current height is less than a known checkpoint we
+	// can use block headers to learn about which blocks comprise
+	// the chain up to the checkpoint and perform less validation
+	// for them.  This is possible since each header contains the
+	// hash of the previous header and a merkle root.  Therefore if
+	// we validate all of the received headers link together
+	// properly and the checkpoint hashes match, we can be sure the
+	// hashes for the blocks in between are accurate.  Further, once
+	// the full blocks are downloaded, the merkle root is computed
+	// and compared against the value in the header which proves the
+	// full block hasn't been tampered with.
+	//
+	// Once we have passed the final checkpoint, or checkpoints are
+	// disabled, use standard inv messages learn about the blocks
+	// and fully validate them.  Finally, regression test mode does
+	// not support the headers-first approach so do normal block
+	// downloads when in regression test mode.
+	if b.nextCheckpoint != nil && height < b.nextCheckpoint.Height &&
+		!cfg.RegressionTest && !cfg.DisableCheckpoints {
+
+		sp.PushGetHeadersMsg(locator, b.nextCheckpoint.Hash)
+		b.headersFirstMode = true
+		bmgrLog.Infof("Downloading headers for blocks %d to "+
+			"%d from peer %s", height+1,
+			b.nextCheckpoint.Height, sp.Addr())
+	} else if err := b.requestBlocks(sp, locator); err != nil {
+		bmgrLog.Warnf("Failed to request blocks from peer %s: "+
+			"%v", sp.Addr(), err)
+	}
+	return true
+}
+
+// setSyncPeer sets the peer the block chain is synced from, or clears it when
+// the passed peer is nil, and notifies websocket clients registered for peer
+// events when it changes.  It must be called from the block handler
+// goroutine.
+func (b *blockManager) setSyncPeer(sp *serverPeer) {
+	if sp == b.syncPeer {
+		return
+	}
+	b.syncPeer = sp
+	if b.server.rpcServer != nil {
+		b.server.rpcServer.ntfnMgr.NotifySyncPeerChanged(sp)
+	}
+}
+
+// segwitActive returns whether the segregated witness soft fork is active
+// for the block after the tip of the best known chain.  Failure to determine
+// the deployment state is treated as not active.
+func (b *blockManager) segwitActive() bool {
+	active, err := b.chainState.IsDeploymentActive(chaincfg.DeploymentSegwit)
+	if err != nil {
+		bmgrLog.Debugf("Unable to determine segwit state: %v", err)
+		return false
+	}
+	return active
+}
+
 // isSyncCandidate returns whether or not the peer is a candidate to consider
 // syncing from.
 func (b *blockManager) isSyncCandidate(sp *serverPeer) bool {
@@ -384,6 +589,12 @@ func (b *blockManager) isSyncCandidate(sp *serverPeer) bool {
 		}
 	}
 
+	// Once segwit is active, blocks must be downloaded along with their
+	// witness data, so the peer is not a candidate if it can't serve it.
+	if b.segwitActive() && !sp.witnessEnabled() {
+		return false
+	}
+
 	// Candidate if all checks passed.
 	return true
 }
@@ -443,8 +654,16 @@ func (b *blockManager) handleDonePeerMsg(peers *list.List, sp *serverPeer) {
 	// Attempt to find a new peer to sync from if the quitting peer is the
 	// sync peer.  Also, reset the headers-first state if in headers-first
 	// mode so
+	for k := range sp.requestedBlocks {
+		delete(b.snapshotRequested, k)
+	}
+	if b.snapshotPeer == sp {
+		b.snapshotPeer = nil
+	}
+
 	if b.syncPeer != nil && b.syncPeer == sp {
-		b.syncPeer = nil
+		b.setSyncPeer(nil)
+		b.assumeValidHeaders = nil
 		if b.headersFirstMode {
 			// This really shouldn't fail.  We have a fairly
 			// unrecoverable database issue if it does.
@@ -472,11 +691,33 @@ func (b *blockManager) handleTxMsg(tmsg *txMsg) {
 	// to disconnect peers for sending unsolicited transactions to provide
 	// interoperability.
 
+	// Transactions can't be validated without the blocks, so they are
+	// ignored in headers-only mode.
+	if cfg.HeadersOnly {
+		return
+	}
+
+	// Transactions relayed during the initial block download are likely
+	// to spend outputs of blocks which haven't been downloaded yet and
+	// would only end up as orphans, so they are ignored until the chain
+	// has caught up.
+	if b.IsInitialBlockDownload() {
+		txHash := tmsg.tx.Sha()
+		delete(tmsg.peer.requestedTxns, *txHash)
+		delete(b.requestedTxns, *txHash)
+		if tmsg.tx.MsgTx().HasWitness() {
+			witnessHash := tmsg.tx.MsgTx().WitnessHash()
+			delete(tmsg.peer.requestedTxns, witnessHash)
+			delete(b.requestedTxns, witnessHash)
+		}
+		return
+	}
+
 	// Process the transaction to include validation, insertion in the
 	// memory pool, orphan handling, etc.
 	allowOrphans := cfg.MaxOrphanTxs > 0
 	err := b.server.txMemPool.ProcessTransaction(tmsg.tx,
-		allowOrphans, true)
+		allowOrphans, true, 0)
 
 	// Remove transaction from request maps. Either the mempool/chain
 	// already knows about it and as such we shouldn't have any more
@@ -485,6 +726,11 @@ func (b *blockManager) handleTxMsg(tmsg *txMsg) {
 	txHash := tmsg.tx.Sha()
 	delete(tmsg.peer.requestedTxns, *txHash)
 	delete(b.requestedTxns, *txHash)
+	if tmsg.tx.MsgTx().HasWitness() {
+		witnessHash := tmsg.tx.MsgTx().WitnessHash()
+		delete(tmsg.peer.requestedTxns, witnessHash)
+		delete(b.requestedTxns, witnessHash)
+	}
 
 	if err != nil {
 		// When the error is a rule error, it means the transaction was
@@ -511,6 +757,10 @@ func (b *blockManager) handleTxMsg(tmsg *txMsg) {
 // current returns true if we believe we are synced with our peers, false if we
 // still have blocks to check
 func (b *blockManager) current() bool {
+	if cfg.HeadersOnly {
+		return b.headersCurrent()
+	}
+
 	if !b.blockChain.IsCurrent(b.server.timeSource) {
 		return false
 	}
@@ -535,18 +785,22 @@ func (b *blockManager) current() bool {
 
 // handleBlockMsg handles block messages from all peers.
 func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
+	// Blocks are never requested in headers-only mode.
+	if cfg.HeadersOnly {
+		return
+	}
+
 	// If we didn't ask for this block then the peer is misbehaving.
 	blockSha := bmsg.block.Sha()
 	if _, exists := bmsg.peer.requestedBlocks[*blockSha]; !exists {
 		// The regression test intentionally sends some blocks twice
-		// to test duplicate block insertion fails.  Don't disconnect
+		// to test duplicate block insertion fails.  Don't penalize
 		// the peer or ignore the block when we're in regression test
 		// mode in this case so the chain code is actually fed the
 		// duplicate blocks.
 		if !cfg.RegressionTest {
-			bmgrLog.Warnf("Got unrequested block %v from %s -- "+
-				"disconnecting", blockSha, bmsg.peer.Addr())
-			bmsg.peer.Disconnect()
+			bmsg.peer.addBanScore(misbehaviorUnrequested,
+				fmt.Sprintf("unrequested block %v", blockSha))
 			return
 		}
 	}
@@ -575,6 +829,29 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
+	// Blocks known to be ancestors of the assumed-valid block don't need
+	// their scripts validated.
+	if _, ok := b.assumedValid[*blockSha]; ok {
+		behaviorFlags |= blockchain.BFAssumeValid
+		delete(b.assumedValid, *blockSha)
+	}
+
+	// The sync peer delivered a requested block, so give it more time
+	// for the next one.
+	if bmsg.peer == b.syncPeer {
+		b.extendSyncDeadline()
+	}
+
+	// Blocks requested for the UTXO snapshot the node is bootstrapped from
+	// are only processed by the main chain as well when it requested them
+	// too.
+	if _, exists := b.snapshotRequested[*blockSha]; exists {
+		b.handleSnapshotBlockMsg(bmsg)
+		if _, exists := b.requestedBlocks[*blockSha]; !exists {
+			return
+		}
+	}
+
 	// Remove block from request maps. Either chain will know about it and
 	// so we shouldn't have any more instances of trying to fetch it, or we
 	// will fail the insert and thus we'll retry next time we get an inv.
@@ -603,6 +880,23 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		code, reason := errToRejectErr(err)
 		bmsg.peer.PushRejectMsg(wire.CmdBlock, code, reason,
 			blockSha, false)
+
+		// Penalize the peer for blocks violating the consensus
+		// rules.  Blocks which are already known or whose timestamp
+		// is merely ahead of the local clock don't count since honest
+		// peers send them as well.  Neither does a missing witness
+		// commitment from a peer without witness support, since it
+		// can only send blocks stripped of their witness data.
+		if rerr, ok := err.(blockchain.RuleError); ok &&
+			rerr.ErrorCode != blockchain.ErrDuplicateBlock &&
+			rerr.ErrorCode != blockchain.ErrTimeTooNew &&
+			!(rerr.ErrorCode == blockchain.ErrInvalidWitnessCommitment &&
+				!bmsg.peer.witnessEnabled()) {
+
+			bmsg.peer.addBanScore(misbehaviorInvalidBlock,
+				fmt.Sprintf("invalid block %v: %v", blockSha,
+					err))
+		}
 		return
 	}
 
@@ -658,6 +952,8 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		// a reorg.
 		newestSha, newestHeight, _ := b.server.db.NewestSha()
 		b.updateChainState(newestSha, newestHeight)
+		b.updateInitialBlockDownload()
+		b.checkSnapshot(newestHeight)
 
 		// Update this peer's latest block height, for future
 		// potential sync node candidacy.
@@ -665,11 +961,17 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		blkShaUpdate = newestSha
 
 		// Allow any clients performing long polling via the
-		// getblocktemplate RPC to be notified when the new block causes
-		// their old block template to become stale.
+		// getblocktemplate RPC or registered for block template
+		// notifications to be notified when the new block causes their
+		// old block template to become stale.  Templates aren't pushed
+		// during the initial block download since they would be stale
+		// before anyone could mine on them.
 		rpcServer := b.server.rpcServer
 		if rpcServer != nil {
 			rpcServer.gbtWorkState.NotifyBlockConnected(blockSha)
+			if !b.IsInitialBlockDownload() {
+				rpcServer.templateNtfn.NotifyBlockConnected()
+			}
 		}
 	}
 
@@ -730,9 +1032,9 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 	b.headerList.Init()
 	bmgrLog.Infof("Reached the final checkpoint -- switching to normal mode")
 	locator := blockchain.BlockLocator([]*wire.ShaHash{blockSha})
-	err = bmsg.peer.PushGetBlocksMsg(locator, &zeroHash)
+	err = b.requestBlocks(bmsg.peer, locator)
 	if err != nil {
-		bmgrLog.Warnf("Failed to send getblocks message to peer %s: %v",
+		bmgrLog.Warnf("Failed to request blocks from peer %s: %v",
 			bmsg.peer.Addr(), err)
 		return
 	}
@@ -769,6 +1071,9 @@ func (b *blockManager) fetchHeaderBlocks() {
 		if !haveInv {
 			b.requestedBlocks[*node.sha] = struct{}{}
 			b.syncPeer.requestedBlocks[*node.sha] = struct{}{}
+			if b.syncPeer.witnessEnabled() {
+				iv.Type = wire.InvTypeWitnessBlock
+			}
 			gdmsg.AddInvVect(iv)
 			numRequested++
 		}
@@ -784,13 +1089,31 @@ func (b *blockManager) fetchHeaderBlocks() {
 
 // handleHeadersMsghandles headers messages from all peers.
 func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
+	if cfg.HeadersOnly {
+		b.handleHeadersOnlyMsg(hmsg)
+		return
+	}
+
+	// Headers which are not requested by the main chain are used to bring
+	// the UTXO snapshot the node is bootstrapped from up to date.
+	if b.isSnapshotHeadersMsg(hmsg.peer) {
+		b.handleSnapshotHeadersMsg(hmsg)
+		return
+	}
+
+	// The headers leading to the assumed-valid block are requested from
+	// the sync peer outside of headers-first mode.
+	if b.assumeValidHeaders != nil && hmsg.peer == b.syncPeer {
+		b.handleAssumeValidHeadersMsg(hmsg)
+		return
+	}
+
 	// The remote peer is misbehaving if we didn't request headers.
 	msg := hmsg.headers
 	numHeaders := len(msg.Headers)
 	if !b.headersFirstMode {
-		bmgrLog.Warnf("Got %d unrequested headers from %s -- "+
-			"disconnecting", numHeaders, hmsg.peer.Addr())
-		hmsg.peer.Disconnect()
+		hmsg.peer.addBanScore(misbehaviorUnrequested,
+			fmt.Sprintf("%d unrequested headers", numHeaders))
 		return
 	}
 
@@ -848,6 +1171,9 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 					"disconnecting", node.height,
 					node.sha, hmsg.peer.Addr(),
 					b.nextCheckpoint.Hash)
+				hmsg.peer.addBanScore(misbehaviorInvalidBlock,
+					fmt.Sprintf("header %v does not "+
+						"match checkpoint", node.sha))
 				hmsg.peer.Disconnect()
 				return
 			}
@@ -904,6 +1230,11 @@ func (b *blockManager) haveInventory(invVect *wire.InvVect) (bool, error) {
 		// Check if the transaction exists from the point of view of the
 		// end of the main chain.
 		return b.server.db.ExistsTxSha(&invVect.Hash)
+
+	case wire.InvTypeWTx:
+		// Transactions are only announced by their witness hash while
+		// they are unconfirmed, so only the memory pool is checked.
+		return b.server.txMemPool.HaveWitnessHash(&invVect.Hash), nil
 	}
 
 	// The requested inventory is is an unsupported type, so just claim
@@ -914,6 +1245,11 @@ func (b *blockManager) haveInventory(invVect *wire.InvVect) (bool, error) {
 // handleInvMsg handles inv messages from all peers.
 // We examine the inventory advertised by the remote peer and act accordingly.
 func (b *blockManager) handleInvMsg(imsg *invMsg) {
+	if cfg.HeadersOnly {
+		b.handleHeadersOnlyInv(imsg)
+		return
+	}
+
 	// Attempt to find the final block in the inventory list.  There may
 	// not be one.
 	lastBlock := -1
@@ -934,6 +1270,23 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 		imsg.peer.UpdateLastAnnouncedBlock(&invVects[lastBlock].Hash)
 	}
 
+	// Announcements of blocks the UTXO snapshot doesn't know yet are
+	// followed by requesting the headers leading to them, except from the
+	// sync peer while the main chain requests headers from it.
+	if lastBlock != -1 && b.snapshotSynced && !b.snapshot.isDropped() &&
+		!b.snapshotHeaders.HaveHeader(&invVects[lastBlock].Hash) &&
+		(imsg.peer != b.syncPeer || (!b.headersFirstMode &&
+			b.assumeValidHeaders == nil)) {
+
+		locator := b.snapshotHeaders.LatestBlockLocator()
+		imsg.peer.PushGetHeadersMsg(locator, &zeroHash)
+	}
+
+	// The main chain isn't synced until the snapshot caught up.
+	if b.needSnapshotSync() {
+		return
+	}
+
 	// Ignore invs from peers that aren't the sync if we are not current.
 	// Helps prevent fetching a mass of orphans.
 	if imsg.peer != b.syncPeer && !b.current() {
@@ -962,7 +1315,8 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 	chain := b.blockChain
 	for i, iv := range invVects {
 		// Ignore unsupported inventory types.
-		if iv.Type != wire.InvTypeBlock && iv.Type != wire.InvTypeTx {
+		if iv.Type != wire.InvTypeBlock && iv.Type != wire.InvTypeTx &&
+			iv.Type != wire.InvTypeWTx {
 			continue
 		}
 
@@ -975,6 +1329,12 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 			continue
 		}
 
+		// Don't request transactions during the initial block
+		// download since they are ignored until it completes.
+		if iv.Type != wire.InvTypeBlock && b.IsInitialBlockDownload() {
+			continue
+		}
+
 		// Request the inventory if we don't already have it.
 		haveInv, err := b.haveInventory(iv)
 		if err != nil {
@@ -984,7 +1344,16 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 			continue
 		}
 		if !haveInv {
-			// Add it to the request queue.
+			// Add it to the request queue unless the peer already
+			// announced more than can be requested, in which case
+			// the rest of the announcements are ignored.
+			if len(imsg.peer.requestQueue) >= maxRequestQueueSize {
+				imsg.peer.addBanScore(misbehaviorInvFlood,
+					fmt.Sprintf("more than %d announced "+
+						"items waiting to be requested",
+						maxRequestQueueSize))
+				break
+			}
 			imsg.peer.requestQueue = append(imsg.peer.requestQueue, iv)
 			continue
 		}
@@ -1035,6 +1404,7 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 	numRequested := 0
 	gdmsg := wire.NewMsgGetData()
 	requestQueue := imsg.peer.requestQueue
+	strippedBlocks := b.segwitActive() && !imsg.peer.witnessEnabled()
 	for len(requestQueue) != 0 {
 		iv := requestQueue[0]
 		requestQueue[0] = nil
@@ -1042,21 +1412,39 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 
 		switch iv.Type {
 		case wire.InvTypeBlock:
+			// Blocks from a peer without witness support would
+			// arrive stripped of their witness data once segwit is
+			// active, so leave them to the witness peers.
+			if strippedBlocks {
+				continue
+			}
+
 			// Request the block if there is not already a pending
 			// request.
 			if _, exists := b.requestedBlocks[iv.Hash]; !exists {
 				b.requestedBlocks[iv.Hash] = struct{}{}
 				imsg.peer.requestedBlocks[iv.Hash] = struct{}{}
+				if imsg.peer.witnessEnabled() {
+					iv = wire.NewInvVect(wire.InvTypeWitnessBlock,
+						&iv.Hash)
+				}
 				gdmsg.AddInvVect(iv)
 				numRequested++
 			}
 
-		case wire.InvTypeTx:
+		case wire.InvTypeTx, wire.InvTypeWTx:
 			// Request the transaction if there is not already a
-			// pending request.
+			// pending request.  Transactions announced by their
+			// witness hash are requested by it as well.
 			if _, exists := b.requestedTxns[iv.Hash]; !exists {
 				b.requestedTxns[iv.Hash] = struct{}{}
 				imsg.peer.requestedTxns[iv.Hash] = struct{}{}
+				if iv.Type == wire.InvTypeTx &&
+					imsg.peer.witnessEnabled() {
+
+					iv = wire.NewInvVect(wire.InvTypeWitnessTx,
+						&iv.Hash)
+				}
 				gdmsg.AddInvVect(iv)
 				numRequested++
 			}
@@ -1079,10 +1467,17 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 // important because the block manager controls which blocks are needed and how
 // the fetching should proceed.
 func (b *blockManager) blockHandler() {
+	b.loadOrphanBlocks()
+
 	candidatePeers := list.New()
+	stallTicker := time.NewTicker(stallCheckInterval)
+	defer stallTicker.Stop()
 out:
 	for {
 		select {
+		case <-stallTicker.C:
+			b.checkSyncStall(candidatePeers)
+
 		case m := <-b.msgChan:
 			switch msg := m.(type) {
 			case *newPeerMsg:
@@ -1105,9 +1500,23 @@ out:
 			case *donePeerMsg:
 				b.handleDonePeerMsg(candidatePeers, msg.peer)
 
+			case snapshotValidatedMsg:
+				b.handleSnapshotValidatedMsg(msg)
+
 			case getSyncPeerMsg:
 				msg.reply <- b.syncPeer
 
+			case getSyncStatusMsg:
+				status := &syncStatus{
+					current: b.current(),
+					stalls:  b.stallCounts(),
+				}
+				if b.syncPeer != nil {
+					status.syncPeerAddr = b.syncPeer.Addr()
+					status.syncPeerHeight = b.syncPeer.LastBlock()
+				}
+				msg.reply <- status
+
 			case checkConnectBlockMsg:
 				err := b.blockChain.CheckConnectBlock(msg.block)
 				msg.reply <- err
@@ -1121,6 +1530,16 @@ out:
 					err:        err,
 				}
 
+			case utxoSetHashMsg:
+				utxoSetHash, err := b.blockChain.UtxoSetHash(msg.hash)
+				msg.reply <- utxoSetHashResponse{
+					utxoSetHash: utxoSetHash,
+					err:         err,
+				}
+
+			case calcBlockSubsidyMsg:
+				msg.reply <- b.blockChain.CalcBlockSubsidy(msg.height)
+
 			case fetchTransactionStoreMsg:
 				txStore, err := b.blockChain.FetchTransactionStore(msg.tx, false)
 				msg.reply <- fetchTransactionStoreResponse{
@@ -1144,13 +1563,18 @@ out:
 				// side chain or have caused a reorg.
 				newestSha, newestHeight, _ := b.server.db.NewestSha()
 				b.updateChainState(newestSha, newestHeight)
+				b.updateInitialBlockDownload()
 
 				// Allow any clients performing long polling via the
-				// getblocktemplate RPC to be notified when the new block causes
+				// getblocktemplate RPC or registered for block template
+				// notifications to be notified when the new block causes
 				// their old block template to become stale.
 				rpcServer := b.server.rpcServer
 				if rpcServer != nil {
 					rpcServer.gbtWorkState.NotifyBlockConnected(msg.block.Sha())
+					if !b.IsInitialBlockDownload() {
+						rpcServer.templateNtfn.NotifyBlockConnected()
+					}
 				}
 
 				msg.reply <- processBlockResponse{
@@ -1175,10 +1599,114 @@ out:
 		}
 	}
 
+	b.saveOrphanBlocks()
+	b.closeHeaders()
+
 	b.wg.Done()
 	bmgrLog.Trace("Block handler done")
 }
 
+// saveOrphanBlocks writes the blocks in the orphan pool to the orphans file in
+// the same format used for bootstrap files.  It must only be called from the
+// block handler.
+func (b *blockManager) saveOrphanBlocks() {
+	orphans := b.blockChain.OrphanBlocks()
+	if len(orphans) == 0 {
+		return
+	}
+
+	path := filepath.Join(cfg.DataDir, orphansFileName)
+	f, err := os.Create(path)
+	if err != nil {
+		bmgrLog.Warnf("Unable to save orphan blocks: %v", err)
+		return
+	}
+	w := bufio.NewWriter(f)
+	for _, block := range orphans {
+		serializedBlock, err := block.Bytes()
+		if err == nil {
+			err = binary.Write(w, binary.LittleEndian,
+				uint32(activeNetParams.Net))
+		}
+		if err == nil {
+			err = binary.Write(w, binary.LittleEndian,
+				uint32(len(serializedBlock)))
+		}
+		if err == nil {
+			_, err = w.Write(serializedBlock)
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(path)
+			bmgrLog.Warnf("Unable to save orphan blocks: %v", err)
+			return
+		}
+	}
+	err = w.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		bmgrLog.Warnf("Unable to save orphan blocks: %v", err)
+		return
+	}
+	bmgrLog.Infof("Saved %d orphan blocks", len(orphans))
+}
+
+// loadOrphanBlocks processes the orphan blocks saved by saveOrphanBlocks on
+// the last shutdown and removes the orphans file.  Blocks which are no longer
+// orphans are connected to the chain and the rest are added back to the
+// orphan pool.  It must only be called from the block handler.
+func (b *blockManager) loadOrphanBlocks() {
+	path := filepath.Join(cfg.DataDir, orphansFileName)
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			bmgrLog.Warnf("Unable to load orphan blocks: %v", err)
+		}
+		return
+	}
+	defer os.Remove(path)
+	defer f.Close()
+
+	var numLoaded int
+	r := bufio.NewReader(f)
+	for {
+		serializedBlock, err := readImportBlock(r)
+		if err != nil {
+			bmgrLog.Warnf("Unable to load orphan blocks: %v", err)
+			break
+		}
+		if serializedBlock == nil {
+			break
+		}
+		block, err := coinutil.NewBlockFromBytes(serializedBlock)
+		if err != nil {
+			bmgrLog.Warnf("Unable to load orphan blocks: %v", err)
+			break
+		}
+
+		_, err = b.blockChain.ProcessBlock(block, b.server.timeSource,
+			blockchain.BFNone)
+		if err != nil {
+			bmgrLog.Debugf("Discarding saved orphan block %v: %v",
+				block.Sha(), err)
+			continue
+		}
+		numLoaded++
+	}
+	if numLoaded == 0 {
+		return
+	}
+
+	// Saved orphans may have been connected if their parents were added in
+	// the meantime.
+	newestSha, newestHeight, _ := b.server.db.NewestSha()
+	b.updateChainState(newestSha, newestHeight)
+	bmgrLog.Infof("Loaded %d saved orphan blocks", numLoaded)
+}
+
 // handleNotifyMsg handles notifications from blockchain.  It does things such
 // as request orphan block parents and relay accepted blocks to connected peers.
 func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
@@ -1219,30 +1747,26 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 		// transaction are NOT removed recursively because they are still
 		// valid.
 		for _, tx := range block.Transactions()[1:] {
-			b.server.txMemPool.RemoveTransaction(tx, false)
+			b.server.txMemPool.RemoveTransaction(tx, false,
+				txRemovedBlock)
 			b.server.txMemPool.RemoveDoubleSpends(tx)
 			b.server.txMemPool.RemoveOrphan(tx.Sha())
 			b.server.txMemPool.ProcessOrphans(tx.Sha())
 		}
 
-		if r := b.server.rpcServer; r != nil {
-			// Now that this block is in the blockchain we can mark
-			// all the transactions (except the coinbase) as no
-			// longer needing rebroadcasting.
+		// Now that this block is in the blockchain we can mark all the
+		// transactions (except the coinbase) as no longer needing
+		// rebroadcasting.
+		if b.server.rpcServer != nil {
 			for _, tx := range block.Transactions()[1:] {
 				iv := wire.NewInvVect(wire.InvTypeTx, tx.Sha())
 				b.server.RemoveRebroadcastInventory(iv)
 			}
-
-			// Notify registered websocket clients of incoming block.
-			r.ntfnMgr.NotifyBlockConnected(block)
 		}
 
-		// If we're maintaining the address index, and it is up to date
-		// then update it based off this new block.
-		if cfg.AddrIndex && b.server.addrIndexer.IsCaughtUp() {
-			b.server.addrIndexer.UpdateAddressIndex(block)
-		}
+		// Let the websocket clients and the indexes know about the new
+		// block.
+		b.server.events.PublishBlockConnected(block)
 
 	// A block has been disconnected from the main block chain.
 	case blockchain.NTBlockDisconnected:
@@ -1261,14 +1785,25 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 				// Remove the transaction and all transactions
 				// that depend on it if it wasn't accepted into
 				// the transaction pool.
-				b.server.txMemPool.RemoveTransaction(tx, true)
+				b.server.txMemPool.RemoveTransaction(tx, true,
+					txRemovedEviction)
 			}
 		}
 
-		// Notify registered websocket clients.
-		if r := b.server.rpcServer; r != nil {
-			r.ntfnMgr.NotifyBlockDisconnected(block)
+		// Let the websocket clients and the indexes know the block is
+		// no longer part of the main chain.
+		b.server.events.PublishBlockDisconnected(block)
+
+	// The main chain has been reorganized.
+	case blockchain.NTChainReorganized:
+		reorg, ok := notification.Data.(*blockchain.ChainReorganization)
+		if !ok {
+			bmgrLog.Warnf("Chain reorganized notification is not a " +
+				"reorganization.")
+			break
 		}
+
+		b.server.events.PublishChainReorganized(reorg)
 	}
 }
 
@@ -1393,6 +1928,28 @@ func (b *blockManager) CalcNextRequiredDifficulty(timestamp time.Time) (uint32,
 	return response.difficulty, response.err
 }
 
+// UtxoSetHash returns the hash of the unspent transaction outputs as of the
+// block with the passed hash.  This function makes use of UtxoSetHash on an
+// internal instance of a block chain.  It is funneled through the block manager
+// since btcchain is not safe for concurrent access.
+func (b *blockManager) UtxoSetHash(hash *wire.ShaHash) (*wire.ShaHash, error) {
+	reply := make(chan utxoSetHashResponse, 1)
+	b.msgChan <- utxoSetHashMsg{hash: hash, reply: reply}
+	response := <-reply
+	return response.utxoSetHash, response.err
+}
+
+// CalcBlockSubsidy returns the subsidy the coinbase of a block at the passed
+// height may claim according to the consensus hooks of the chain.  This
+// function makes use of CalcBlockSubsidy on an internal instance of a block
+// chain.  It is funneled through the block manager since btcchain is not safe
+// for concurrent access.
+func (b *blockManager) CalcBlockSubsidy(height int32) int64 {
+	reply := make(chan int64, 1)
+	b.msgChan <- calcBlockSubsidyMsg{height: height, reply: reply}
+	return <-reply
+}
+
 // FetchTransactionStore makes use of FetchTransactionStore on an internal
 // instance of a block chain. It is safe for concurrent access.
 func (b *blockManager) FetchTransactionStore(tx *coinutil.Tx) (blockchain.TxStore, error) {
@@ -1445,7 +2002,10 @@ func newBlockManager(s *server) (*blockManager, error) {
 		progressLogger:  newBlockProgressLogger("Processed", bmgrLog),
 		msgChan:         make(chan interface{}, cfg.MaxPeers*3),
 		headerList:      list.New(),
+		stalls:          make(map[string]int32),
+		assumeValid:     cfg.assumeValid,
 		quit:            make(chan struct{}),
+		initialDownload: 1,
 	}
 	bm.progressLogger = newBlockProgressLogger("Processed", bmgrLog)
 	bm.blockChain = blockchain.New(s.db, s.chainParams, bm.handleNotifyMsg,
@@ -1469,9 +2029,45 @@ func newBlockManager(s *server) (*blockManager, error) {
 	}
 	bmgrLog.Infof("Block index generation complete")
 
+	// Load the stored headers when only the headers are tracked.
+	if cfg.HeadersOnly {
+		bm.headerChain = blockchain.NewHeaderChain(s.chainParams,
+			bm.handleHeaderNotifyMsg)
+		bm.headerChain.DisableCheckpoints(cfg.DisableCheckpoints)
+		if err := bm.loadHeaders(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Load the UTXO snapshot to bootstrap from unless the main chain
+	// already reached its base block.
+	if cfg.assumeUtxo != nil {
+		path := utxoSnapshotPath(cfg.AssumeUtxoFile)
+		snapshot, err := loadSnapshotChainstate(path, cfg.assumeUtxo,
+			s.chainParams.Net)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load UTXO snapshot "+
+				"%s: %v", path, err)
+		}
+		if height >= snapshot.baseHeight {
+			bmgrLog.Infof("Main chain is already past the base "+
+				"block of UTXO snapshot %v -- not using it",
+				cfg.assumeUtxo)
+		} else {
+			bmgrLog.Infof("Loaded UTXO snapshot %v at height %d",
+				cfg.assumeUtxo, snapshot.baseHeight)
+			bm.snapshot = snapshot
+			bm.snapshotHeaders = blockchain.NewHeaderChain(
+				s.chainParams, nil)
+			bm.snapshotHeaders.DisableCheckpoints(cfg.DisableCheckpoints)
+			bm.snapshotRequested = make(map[wire.ShaHash]struct{})
+		}
+	}
+
 	// Initialize the chain state now that the intial block node index has
 	// been generated.
 	bm.updateChainState(newestHash, height)
+	bm.updateInitialBlockDownload()
 
 	return &bm, nil
 }
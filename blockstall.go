@@ -0,0 +1,105 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+const (
+	// blockStallTimeout is how long the sync peer may take to deliver the
+	// next of the blocks requested from it.  The deadline moves forward
+	// with every requested block the sync peer delivers, so a slow but
+	// steady peer is never considered stalled.
+	blockStallTimeout = time.Second * 30
+
+	// stallCheckInterval is the interval at which the block manager checks
+	// whether the sync peer missed its deadline.
+	stallCheckInterval = time.Second * 5
+)
+
+// stallKey returns the key the stalls of the passed peer are recorded under.
+// Stalls are recorded per host so they are remembered when the peer connects
+// again from a different port.
+func stallKey(sp *serverPeer) string {
+	host, _, err := net.SplitHostPort(sp.Addr())
+	if err != nil {
+		return sp.Addr()
+	}
+	return host
+}
+
+// extendSyncDeadline moves the deadline for the sync peer to deliver the next
+// requested block forward.  It must only be called from the block handler.
+func (b *blockManager) extendSyncDeadline() {
+	b.syncPeerDeadline = time.Now().Add(blockStallTimeout)
+}
+
+// checkSyncStall detects a sync peer which failed to deliver the blocks
+// requested from it before its deadline.  The stall is recorded, the peer is
+// penalized and disconnected and syncing continues from the next best
+// candidate.  When there is no other candidate, the peer is kept since a slow
+// sync peer is better than none.  It must only be called from the block
+// handler.
+func (b *blockManager) checkSyncStall(peers *list.List) {
+	sp := b.syncPeer
+	if sp == nil || cfg.HeadersOnly {
+		return
+	}
+
+	// There is no deadline while no blocks are requested from the sync
+	// peer.  It starts once blocks are requested.
+	if len(sp.requestedBlocks) == 0 {
+		b.syncPeerDeadline = time.Time{}
+		return
+	}
+	if b.syncPeerDeadline.IsZero() {
+		b.extendSyncDeadline()
+		return
+	}
+	if time.Now().Before(b.syncPeerDeadline) {
+		return
+	}
+
+	key := stallKey(sp)
+	b.stalls[key]++
+	if peers.Len() < 2 {
+		bmgrLog.Warnf("Sync peer %s stalled with %d requested blocks "+
+			"outstanding, but there is no other sync candidate",
+			sp, len(sp.requestedBlocks))
+		b.extendSyncDeadline()
+		return
+	}
+
+	bmgrLog.Warnf("Sync peer %s stalled with %d requested blocks "+
+		"outstanding for %v -- switching sync peer", sp,
+		len(sp.requestedBlocks), blockStallTimeout)
+	sp.addBanScore(misbehaviorStalling, fmt.Sprintf("%d requested "+
+		"blocks not delivered within %v", len(sp.requestedBlocks),
+		blockStallTimeout))
+
+	// Remove the peer as a candidate and pick a new sync peer right away.
+	// Its requests are forgotten so the blocks requested from the new sync
+	// peer stay requested when the stalled peer is done later on.
+	sp.Disconnect()
+	b.handleDonePeerMsg(peers, sp)
+	sp.requestedTxns = make(map[wire.ShaHash]struct{})
+	sp.requestedBlocks = make(map[wire.ShaHash]struct{})
+}
+
+// stallCounts returns a copy of the number of stalls recorded per host.  It
+// must only be called from the block handler.
+func (b *blockManager) stallCounts() map[string]int32 {
+	stalls := make(map[string]int32, len(b.stalls))
+	for key, n := range b.stalls {
+		stalls[key] = n
+	}
+	return stalls
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/list"
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/peer"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestCheckSyncStall ensures the deadline of the sync peer only runs while
+// blocks are requested from it and that a stall is recorded for its host once
+// the deadline passed, while the only sync candidate is kept.
+func TestCheckSyncStall(t *testing.T) {
+	savedCfg := cfg
+	cfg = &config{}
+	defer func() { cfg = savedCfg }()
+
+	sp := newServerPeer(nil, false)
+	p, err := peer.NewOutboundPeer(&peer.Config{}, "192.0.2.1:8333")
+	if err != nil {
+		t.Fatalf("NewOutboundPeer: %v", err)
+	}
+	sp.Peer = p
+	if key := stallKey(sp); key != "192.0.2.1" {
+		t.Fatalf("stallKey: got %q, want the host", key)
+	}
+
+	b := &blockManager{syncPeer: sp, stalls: make(map[string]int32)}
+	peers := list.New()
+	peers.PushBack(sp)
+
+	// No deadline runs without requested blocks.
+	b.checkSyncStall(peers)
+	if !b.syncPeerDeadline.IsZero() {
+		t.Fatalf("checkSyncStall: deadline set without requested blocks")
+	}
+
+	// The deadline starts once blocks are requested.
+	sp.requestedBlocks[wire.ShaHash{0x01}] = struct{}{}
+	b.checkSyncStall(peers)
+	if b.syncPeerDeadline.IsZero() {
+		t.Fatalf("checkSyncStall: deadline not set with requested blocks")
+	}
+	b.checkSyncStall(peers)
+	if len(b.stalls) != 0 {
+		t.Fatalf("checkSyncStall: stall recorded before the deadline")
+	}
+
+	// The stall is recorded once the deadline passed, but the only sync
+	// candidate is kept with a new deadline.
+	b.syncPeerDeadline = time.Now().Add(-time.Second)
+	b.checkSyncStall(peers)
+	if b.stalls["192.0.2.1"] != 1 {
+		t.Fatalf("checkSyncStall: got stalls %v, want one for the "+
+			"sync peer", b.stalls)
+	}
+	if b.syncPeer != sp || !b.syncPeerDeadline.After(time.Now()) {
+		t.Fatalf("checkSyncStall: only sync candidate was not kept")
+	}
+	if stalls := b.stallCounts(); stalls["192.0.2.1"] != 1 {
+		t.Fatalf("stallCounts: got %v", stalls)
+	}
+}
+
+// TestSyncCandidateWitness ensures full nodes without witness support stop
+// being sync candidates once segwit is active.
+func TestSyncCandidateWitness(t *testing.T) {
+	savedCfg := cfg
+	cfg = &config{}
+	defer func() { cfg = savedCfg }()
+
+	newPeer := func(services wire.ServiceFlag) *serverPeer {
+		sp := newServerPeer(nil, false)
+		p, err := peer.NewOutboundPeer(&peer.Config{Services: services},
+			"192.0.2.1:8333")
+		if err != nil {
+			t.Fatalf("NewOutboundPeer: %v", err)
+		}
+		sp.Peer = p
+		return sp
+	}
+	legacy := newPeer(wire.SFNodeNetwork)
+	witness := newPeer(wire.SFNodeNetwork | wire.SFNodeWitness)
+
+	b := &blockManager{}
+	if !b.isSyncCandidate(legacy) || !b.isSyncCandidate(witness) {
+		t.Fatalf("isSyncCandidate: full nodes rejected before segwit")
+	}
+
+	b.chainState.deploymentStates[chaincfg.DeploymentSegwit] =
+		blockchain.ThresholdActive
+	if b.isSyncCandidate(legacy) {
+		t.Fatalf("isSyncCandidate: legacy peer accepted after segwit")
+	}
+	if !b.isSyncCandidate(witness) {
+		t.Fatalf("isSyncCandidate: witness peer rejected after segwit")
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/btcjson"
+)
+
+// blockTemplateNtfnTxs is the number of new fee-paying transactions which
+// must arrive in the memory pool before a fresh block template is pushed to
+// the clients registered for block template notifications.  Pushes due to new
+// transactions are additionally limited to one per gbtRegenerateSeconds since
+// that is how often a new template is generated for the same best block.
+const blockTemplateNtfnTxs = 10
+
+// templateSubscription houses the block template request of a websocket
+// client registered for block template notifications.
+type templateSubscription struct {
+	wsc              *wsClient
+	useCoinbaseValue bool
+	opts             *CoinbaseOptions
+	auditSource      bool
+
+	// pending is set when the client is due a fresh template and
+	// lastLongPollID is the ID of the template last sent to the client,
+	// which prevents sending the same template twice.
+	pending        bool
+	lastLongPollID string
+}
+
+// blockTemplateNotifier pushes fresh block templates to websocket clients
+// registered with the notifyblocktemplate command whenever the best block
+// changes or enough new fee-paying transactions have arrived in the memory
+// pool.  This saves pool servers from polling getblocktemplate.
+type blockTemplateNotifier struct {
+	sync.Mutex
+	server        *rpcServer
+	subscriptions map[chan struct{}]*templateSubscription
+	newFeeTxs     int
+	lastPush      time.Time
+
+	// trigger is signalled when there are pending subscriptions.  It is
+	// buffered so signalling never blocks.
+	trigger chan struct{}
+}
+
+// newBlockTemplateNotifier returns a new block template notifier for the
+// passed RPC server.  Its run method must be started for templates to be
+// pushed.
+func newBlockTemplateNotifier(server *rpcServer) *blockTemplateNotifier {
+	return &blockTemplateNotifier{
+		server:        server,
+		subscriptions: make(map[chan struct{}]*templateSubscription),
+		trigger:       make(chan struct{}, 1),
+	}
+}
+
+// signal wakes the run loop up without blocking.
+func (n *blockTemplateNotifier) signal() {
+	select {
+	case n.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe registers the passed websocket client for block template
+// notifications, replacing any earlier registration of the client.  The
+// client is sent a template right away.
+//
+// This function is safe for concurrent access.
+func (n *blockTemplateNotifier) Subscribe(sub *templateSubscription) {
+	n.Lock()
+	sub.pending = true
+	n.subscriptions[sub.wsc.quit] = sub
+	n.Unlock()
+
+	n.signal()
+}
+
+// Unsubscribe removes the block template notification registration of the
+// passed websocket client, if any.
+//
+// This function is safe for concurrent access.
+func (n *blockTemplateNotifier) Unsubscribe(wsc *wsClient) {
+	n.Lock()
+	delete(n.subscriptions, wsc.quit)
+	n.Unlock()
+}
+
+// Subscribed returns whether the passed websocket client is registered for
+// block template notifications.
+//
+// This function is safe for concurrent access.
+func (n *blockTemplateNotifier) Subscribed(wsc *wsClient) bool {
+	n.Lock()
+	_, ok := n.subscriptions[wsc.quit]
+	n.Unlock()
+	return ok
+}
+
+// markPending flags every subscription as due a fresh template and wakes the
+// run loop up.  The caller must hold the lock.
+func (n *blockTemplateNotifier) markPending() {
+	for _, sub := range n.subscriptions {
+		sub.pending = true
+	}
+	n.newFeeTxs = 0
+	n.signal()
+}
+
+// NotifyBlockConnected informs the notifier that a block has been connected
+// to the main chain so a template building on it is pushed to all registered
+// clients.
+//
+// This function is safe for concurrent access.
+func (n *blockTemplateNotifier) NotifyBlockConnected() {
+	n.Lock()
+	if len(n.subscriptions) != 0 {
+		n.markPending()
+	}
+	n.Unlock()
+}
+
+// NotifyMempoolTx informs the notifier that the passed transaction has been
+// accepted into the memory pool.  A template is pushed to all registered
+// clients once enough fee-paying transactions have arrived.
+//
+// This function is safe for concurrent access.
+func (n *blockTemplateNotifier) NotifyMempoolTx(tx *coinutil.Tx) {
+	n.Lock()
+	defer n.Unlock()
+
+	if len(n.subscriptions) == 0 {
+		return
+	}
+	txDesc, err := n.server.server.txMemPool.FetchTxDesc(tx.Sha())
+	if err != nil || txDesc.Fee <= 0 {
+		return
+	}
+	n.newFeeTxs++
+	if n.newFeeTxs >= blockTemplateNtfnTxs &&
+		time.Since(n.lastPush) >= time.Second*gbtRegenerateSeconds {
+
+		n.markPending()
+	}
+}
+
+// pendingSubscriptions returns the subscriptions due a fresh template and
+// clears their pending flag.
+//
+// This function is safe for concurrent access.
+func (n *blockTemplateNotifier) pendingSubscriptions() []*templateSubscription {
+	n.Lock()
+	defer n.Unlock()
+
+	var pending []*templateSubscription
+	for _, sub := range n.subscriptions {
+		if sub.pending {
+			sub.pending = false
+			pending = append(pending, sub)
+		}
+	}
+	n.lastPush = time.Now()
+	return pending
+}
+
+// push sends a fresh block template to the client of the passed subscription
+// unless it was already sent the same template.
+func (n *blockTemplateNotifier) push(sub *templateSubscription) error {
+	state := n.server.gbtWorkState
+	state.Lock()
+	err := state.updateBlockTemplate(n.server,
+		!requiresPayAddress(sub.useCoinbaseValue, sub.opts))
+	var result *btcjson.GetBlockTemplateResult
+	if err == nil {
+		result, err = state.blockTemplateResult(sub.useCoinbaseValue,
+			nil, sub.opts, sub.auditSource)
+	}
+	state.Unlock()
+	if err != nil {
+		return err
+	}
+
+	n.Lock()
+	duplicate := result.LongPollID == sub.lastLongPollID
+	sub.lastLongPollID = result.LongPollID
+	n.Unlock()
+	if duplicate {
+		return nil
+	}
+
+	ntfn := btcjson.NewBlockTemplateNtfn(*result)
+	marshalledJSON, err := marshalNotification(ntfn, newNtfnEventID())
+	if err != nil {
+		return err
+	}
+	return sub.wsc.QueueNotification(marshalledJSON)
+}
+
+// run pushes block templates to the registered clients as they become due.
+// It must be run as a goroutine and exits when the passed channel is closed.
+func (n *blockTemplateNotifier) run(quit <-chan int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-n.trigger:
+		case <-quit:
+			return
+		}
+
+		// Leave the subscriptions pending while no useful work can be
+		// generated.  They are retried on the next trigger.
+		if err := checkTemplateReady(n.server); err != nil {
+			rpcsLog.Debugf("Not pushing block templates: %v", err)
+			continue
+		}
+
+		for _, sub := range n.pendingSubscriptions() {
+			if err := n.push(sub); err != nil {
+				rpcsLog.Debugf("Unable to push block template to "+
+					"%s: %v", sub.wsc.addr, err)
+			}
+		}
+	}
+}
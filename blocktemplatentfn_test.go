@@ -0,0 +1,46 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+// TestBlockTemplateNotifierPending ensures registered clients are due a
+// template when they subscribe and whenever a block is connected, and that
+// unsubscribed clients are no longer sent templates.
+func TestBlockTemplateNotifierPending(t *testing.T) {
+	n := newBlockTemplateNotifier(nil)
+	wsc1 := &wsClient{quit: make(chan struct{})}
+	wsc2 := &wsClient{quit: make(chan struct{})}
+
+	n.Subscribe(&templateSubscription{wsc: wsc1})
+	if pending := n.pendingSubscriptions(); len(pending) != 1 ||
+		pending[0].wsc != wsc1 {
+
+		t.Fatalf("pending after subscribe: got %v - want client 1",
+			pending)
+	}
+	if pending := n.pendingSubscriptions(); len(pending) != 0 {
+		t.Fatalf("pending after push: got %d - want 0", len(pending))
+	}
+
+	n.Subscribe(&templateSubscription{wsc: wsc2})
+	n.NotifyBlockConnected()
+	if pending := n.pendingSubscriptions(); len(pending) != 2 {
+		t.Fatalf("pending after block: got %d - want 2", len(pending))
+	}
+
+	// Signalling never blocks, even when nobody drains the trigger.
+	n.NotifyBlockConnected()
+	n.NotifyBlockConnected()
+
+	n.Unsubscribe(wsc1)
+	pending := n.pendingSubscriptions()
+	if len(pending) != 1 || pending[0].wsc != wsc2 {
+		t.Fatalf("pending after unsubscribe: got %v - want client 2",
+			pending)
+	}
+}
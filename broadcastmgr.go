@@ -0,0 +1,116 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/wire"
+)
+
+// unconfirmedBroadcast houses a transaction submitted through the RPC server
+// along with details about how it has been broadcast so far.
+type unconfirmedBroadcast struct {
+	tx            *coinutil.Tx
+	submitted     time.Time
+	lastBroadcast time.Time
+	broadcasts    int
+}
+
+// broadcastsBySubmitted provides sorting of unconfirmed broadcasts from the
+// oldest to the newest submission.
+type broadcastsBySubmitted []*unconfirmedBroadcast
+
+// Len returns the number of broadcasts in the slice.  It is part of the
+// sort.Interface implementation.
+func (s broadcastsBySubmitted) Len() int { return len(s) }
+
+// Swap swaps the broadcasts at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s broadcastsBySubmitted) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// Less returns whether the broadcast with index i was submitted before the
+// broadcast with index j.  It is part of the sort.Interface implementation.
+func (s broadcastsBySubmitted) Less(i, j int) bool {
+	return s[i].submitted.Before(s[j].submitted)
+}
+
+// broadcastManager remembers the transactions submitted through the RPC server
+// which have not been confirmed yet so they can be rebroadcast in case peers
+// restarted or otherwise lost track of them.  Transactions are forgotten once
+// they are confirmed, evicted from the memory pool or removed on request.
+//
+// The broadcast manager is not safe for concurrent access.  It is owned by the
+// server's rebroadcast handler.
+type broadcastManager struct {
+	pending map[wire.ShaHash]*unconfirmedBroadcast
+}
+
+// newBroadcastManager returns a new broadcast manager without any
+// transactions.
+func newBroadcastManager() *broadcastManager {
+	return &broadcastManager{
+		pending: make(map[wire.ShaHash]*unconfirmedBroadcast),
+	}
+}
+
+// Add remembers the passed transaction, which was broadcast at the passed
+// time, for rebroadcasting.  Transactions which are already known are left
+// untouched.
+func (m *broadcastManager) Add(tx *coinutil.Tx, now time.Time) {
+	if _, exists := m.pending[*tx.Sha()]; exists {
+		return
+	}
+	m.pending[*tx.Sha()] = &unconfirmedBroadcast{
+		tx:            tx,
+		submitted:     now,
+		lastBroadcast: now,
+		broadcasts:    1,
+	}
+}
+
+// Remove forgets the transaction with the passed hash and returns whether it
+// was known.
+func (m *broadcastManager) Remove(txHash *wire.ShaHash) bool {
+	if _, exists := m.pending[*txHash]; !exists {
+		return false
+	}
+	delete(m.pending, *txHash)
+	return true
+}
+
+// Rebroadcast relays every remembered transaction which is still in the
+// memory pool according to the passed function and forgets the others, which
+// were evicted.  The hashes of the evicted transactions are returned.
+func (m *broadcastManager) Rebroadcast(inPool func(*wire.ShaHash) bool,
+	relay func(*coinutil.Tx), now time.Time) []wire.ShaHash {
+
+	var evicted []wire.ShaHash
+	for txHash, broadcast := range m.pending {
+		if !inPool(broadcast.tx.Sha()) {
+			delete(m.pending, txHash)
+			evicted = append(evicted, txHash)
+			continue
+		}
+		relay(broadcast.tx)
+		broadcast.lastBroadcast = now
+		broadcast.broadcasts++
+	}
+	return evicted
+}
+
+// Broadcasts returns copies of the remembered transactions ordered from the
+// oldest to the newest submission.
+func (m *broadcastManager) Broadcasts() []*unconfirmedBroadcast {
+	broadcasts := make([]*unconfirmedBroadcast, 0, len(m.pending))
+	for _, broadcast := range m.pending {
+		b := *broadcast
+		broadcasts = append(broadcasts, &b)
+	}
+	sort.Sort(broadcastsBySubmitted(broadcasts))
+	return broadcasts
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestBroadcastManager ensures submitted transactions are rebroadcast while
+// they are in the memory pool, forgotten once they are evicted or removed, and
+// reported from the oldest to the newest submission.
+func TestBroadcastManager(t *testing.T) {
+	newTx := func(value int64) *coinutil.Tx {
+		msgTx := wire.NewMsgTx()
+		msgTx.AddTxOut(wire.NewTxOut(value, []byte{0x51}))
+		return coinutil.NewTx(msgTx)
+	}
+	kept, evicted, removed := newTx(1), newTx(2), newTx(3)
+
+	m := newBroadcastManager()
+	start := time.Unix(1438900000, 0)
+	m.Add(removed, start.Add(2*time.Second))
+	m.Add(kept, start)
+	m.Add(evicted, start.Add(time.Second))
+	m.Add(kept, start.Add(3*time.Second))
+
+	broadcasts := m.Broadcasts()
+	if len(broadcasts) != 3 {
+		t.Fatalf("broadcasts: got %d - want 3", len(broadcasts))
+	}
+	for i, want := range []*coinutil.Tx{kept, evicted, removed} {
+		if broadcasts[i].tx != want {
+			t.Errorf("broadcast #%d: got %v - want %v", i,
+				broadcasts[i].tx.Sha(), want.Sha())
+		}
+	}
+
+	if !m.Remove(removed.Sha()) {
+		t.Errorf("Remove: transaction was not known")
+	}
+	if m.Remove(removed.Sha()) {
+		t.Errorf("Remove: transaction was removed twice")
+	}
+
+	var relayed []*coinutil.Tx
+	inPool := func(txHash *wire.ShaHash) bool {
+		return !txHash.IsEqual(evicted.Sha())
+	}
+	relay := func(tx *coinutil.Tx) {
+		relayed = append(relayed, tx)
+	}
+	now := start.Add(time.Hour)
+	gone := m.Rebroadcast(inPool, relay, now)
+	if len(gone) != 1 || !gone[0].IsEqual(evicted.Sha()) {
+		t.Errorf("Rebroadcast: got evicted %v - want %v", gone,
+			evicted.Sha())
+	}
+	if len(relayed) != 1 || relayed[0] != kept {
+		t.Fatalf("Rebroadcast: got %d relayed - want only the kept "+
+			"transaction", len(relayed))
+	}
+
+	broadcasts = m.Broadcasts()
+	if len(broadcasts) != 1 {
+		t.Fatalf("broadcasts after rebroadcast: got %d - want 1",
+			len(broadcasts))
+	}
+	got := broadcasts[0]
+	if !got.submitted.Equal(start) || !got.lastBroadcast.Equal(now) ||
+		got.broadcasts != 2 {
+
+		t.Errorf("broadcast after rebroadcast: got submitted %v, last "+
+			"broadcast %v, %d broadcasts - want %v, %v, 2",
+			got.submitted, got.lastBroadcast, got.broadcasts, start,
+			now)
+	}
+}
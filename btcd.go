@@ -92,6 +92,57 @@ func btcdMain(serverChan chan<- *server) error {
 		return nil
 	}
 
+	if cfg.DropCFIndex {
+		btcdLog.Info("Deleting entire cfindex.")
+		err := db.DeleteCFIndex()
+		if err != nil {
+			btcdLog.Errorf("Unable to delete the cfindex: %v", err)
+			return err
+		}
+		btcdLog.Info("Successfully deleted cfindex, exiting")
+		return nil
+	}
+
+	if cfg.DropCoinAgeIndex {
+		btcdLog.Info("Deleting entire coinageindex.")
+		err := db.DeleteCoinAgeIndex()
+		if err != nil {
+			btcdLog.Errorf("Unable to delete the coinageindex: %v", err)
+			return err
+		}
+		btcdLog.Info("Successfully deleted coinageindex, exiting")
+		return nil
+	}
+
+	if cfg.DropSTXOIndex {
+		btcdLog.Info("Deleting entire stxoindex.")
+		err := db.DeleteSTXOIndex()
+		if err != nil {
+			btcdLog.Errorf("Unable to delete the stxoindex: %v", err)
+			return err
+		}
+		btcdLog.Info("Successfully deleted stxoindex, exiting")
+		return nil
+	}
+
+	if cfg.DropBalanceIndex {
+		btcdLog.Info("Deleting entire addrbalanceindex.")
+		err := db.DeleteAddrBalanceIndex()
+		if err != nil {
+			btcdLog.Errorf("Unable to delete the addrbalanceindex: %v",
+				err)
+			return err
+		}
+		btcdLog.Info("Successfully deleted addrbalanceindex, exiting")
+		return nil
+	}
+
+	// Verify the database and repair it when corruption is detected.
+	if err := checkDatabase(db); err != nil {
+		btcdLog.Errorf("Unable to repair the database: %v", err)
+		return err
+	}
+
 	// Ensure the database is sync'd and closed on Ctrl+C.
 	addInterruptHandler(func() {
 		btcdLog.Infof("Gracefully shutting down the database...")
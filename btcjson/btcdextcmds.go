@@ -43,18 +43,103 @@ func NewNodeCmd(subCmd NodeSubCmd, target string, connectSubCmd *string) *NodeCm
 	}
 }
 
+// AddFilterEntryCmd defines the addfilterentry JSON-RPC command.  This command
+// is not a standard Bitcoin command.  It is an extension for btcd.
+type AddFilterEntryCmd struct {
+	Entry string
+}
+
+// NewAddFilterEntryCmd returns a new instance which can be used to issue an
+// addfilterentry JSON-RPC command.
+func NewAddFilterEntryCmd(entry string) *AddFilterEntryCmd {
+	return &AddFilterEntryCmd{
+		Entry: entry,
+	}
+}
+
+// BackupChainStateCmd defines the backupchainstate JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type BackupChainStateCmd struct {
+	DestDir string
+}
+
+// NewBackupChainStateCmd returns a new instance which can be used to issue a
+// backupchainstate JSON-RPC command.
+func NewBackupChainStateCmd(destDir string) *BackupChainStateCmd {
+	return &BackupChainStateCmd{
+		DestDir: destDir,
+	}
+}
+
+// BroadcastAlertCmd defines the broadcastalert JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type BroadcastAlertCmd struct {
+	Message  string
+	Severity *string `jsonrpcdefault:"\"info\"" jsonrpcusage:"\"info|warning|critical\""`
+	Webhooks *bool   `jsonrpcdefault:"true"`
+}
+
+// NewBroadcastAlertCmd returns a new instance which can be used to issue a
+// broadcastalert JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewBroadcastAlertCmd(message string, severity *string, webhooks *bool) *BroadcastAlertCmd {
+	return &BroadcastAlertCmd{
+		Message:  message,
+		Severity: severity,
+		Webhooks: webhooks,
+	}
+}
+
+// CompactDatabaseCmd defines the compactdatabase JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type CompactDatabaseCmd struct{}
+
+// NewCompactDatabaseCmd returns a new instance which can be used to issue a
+// compactdatabase JSON-RPC command.
+func NewCompactDatabaseCmd() *CompactDatabaseCmd {
+	return &CompactDatabaseCmd{}
+}
+
 // DebugLevelCmd defines the debuglevel JSON-RPC command.  This command is not a
 // standard Bitcoin command.  It is an extension for btcd.
 type DebugLevelCmd struct {
 	LevelSpec string
+	Persist   *bool `jsonrpcdefault:"false"`
 }
 
 // NewDebugLevelCmd returns a new DebugLevelCmd which can be used to issue a
 // debuglevel JSON-RPC command.  This command is not a standard Bitcoin command.
 // It is an extension for btcd.
-func NewDebugLevelCmd(levelSpec string) *DebugLevelCmd {
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewDebugLevelCmd(levelSpec string, persist *bool) *DebugLevelCmd {
 	return &DebugLevelCmd{
 		LevelSpec: levelSpec,
+		Persist:   persist,
+	}
+}
+
+// DebugScriptCmd defines the debugscript JSON-RPC command.  This command is not
+// a standard Bitcoin command.  It is an extension for btcd.
+type DebugScriptCmd struct {
+	SigScript string
+	PkScript  string
+	Standard  *bool `jsonrpcdefault:"false"`
+}
+
+// NewDebugScriptCmd returns a new instance which can be used to issue a
+// debugscript JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewDebugScriptCmd(sigScript, pkScript string, standard *bool) *DebugScriptCmd {
+	return &DebugScriptCmd{
+		SigScript: sigScript,
+		PkScript:  pkScript,
+		Standard:  standard,
 	}
 }
 
@@ -71,6 +156,117 @@ func NewGenerateCmd(numBlocks uint32) *GenerateCmd {
 	}
 }
 
+// GenerateToDescriptorCmd defines the generatetodescriptor JSON-RPC command.
+type GenerateToDescriptorCmd struct {
+	NumBlocks  uint32
+	Descriptor string
+}
+
+// NewGenerateToDescriptorCmd returns a new instance which can be used to issue
+// a generatetodescriptor JSON-RPC command.
+func NewGenerateToDescriptorCmd(numBlocks uint32, descriptor string) *GenerateToDescriptorCmd {
+	return &GenerateToDescriptorCmd{
+		NumBlocks:  numBlocks,
+		Descriptor: descriptor,
+	}
+}
+
+// ClearNetworkFaultsCmd defines the clearnetworkfaults JSON-RPC command.
+type ClearNetworkFaultsCmd struct {
+	Target *string
+}
+
+// NewClearNetworkFaultsCmd returns a new instance which can be used to issue a
+// clearnetworkfaults JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewClearNetworkFaultsCmd(target *string) *ClearNetworkFaultsCmd {
+	return &ClearNetworkFaultsCmd{
+		Target: target,
+	}
+}
+
+// FindOrphanChainsCmd defines the findorphanchains JSON-RPC command.
+type FindOrphanChainsCmd struct{}
+
+// NewFindOrphanChainsCmd returns a new instance which can be used to issue a
+// findorphanchains JSON-RPC command.
+func NewFindOrphanChainsCmd() *FindOrphanChainsCmd {
+	return &FindOrphanChainsCmd{}
+}
+
+// AddressRequest models the object of addresses passed to the
+// getaddressbalance and getaddressutxos JSON-RPC commands.
+type AddressRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+// AddressDeltasRequest models the object passed to the getaddressdeltas
+// JSON-RPC command.  The start and end heights are optional.
+type AddressDeltasRequest struct {
+	Addresses []string `json:"addresses"`
+	Start     int32    `json:"start,omitempty"`
+	End       int32    `json:"end,omitempty"`
+}
+
+// GetAddressBalanceCmd defines the getaddressbalance JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type GetAddressBalanceCmd struct {
+	Request AddressRequest
+}
+
+// NewGetAddressBalanceCmd returns a new instance which can be used to issue a
+// getaddressbalance JSON-RPC command.
+func NewGetAddressBalanceCmd(addresses []string) *GetAddressBalanceCmd {
+	return &GetAddressBalanceCmd{
+		Request: AddressRequest{Addresses: addresses},
+	}
+}
+
+// GetAddressDeltasCmd defines the getaddressdeltas JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type GetAddressDeltasCmd struct {
+	Request AddressDeltasRequest
+}
+
+// NewGetAddressDeltasCmd returns a new instance which can be used to issue a
+// getaddressdeltas JSON-RPC command.  Passing zero for the start or end height
+// leaves the range of blocks open at that end.
+func NewGetAddressDeltasCmd(addresses []string, start, end int32) *GetAddressDeltasCmd {
+	return &GetAddressDeltasCmd{
+		Request: AddressDeltasRequest{
+			Addresses: addresses,
+			Start:     start,
+			End:       end,
+		},
+	}
+}
+
+// GetAddressUtxosCmd defines the getaddressutxos JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type GetAddressUtxosCmd struct {
+	Request AddressRequest
+}
+
+// NewGetAddressUtxosCmd returns a new instance which can be used to issue a
+// getaddressutxos JSON-RPC command.
+func NewGetAddressUtxosCmd(addresses []string) *GetAddressUtxosCmd {
+	return &GetAddressUtxosCmd{
+		Request: AddressRequest{Addresses: addresses},
+	}
+}
+
+// GetAPISchemaCmd defines the getapischema JSON-RPC command.  This command is
+// not a standard Bitcoin command.  It is an extension for btcd.
+type GetAPISchemaCmd struct{}
+
+// NewGetAPISchemaCmd returns a new instance which can be used to issue a
+// getapischema JSON-RPC command.
+func NewGetAPISchemaCmd() *GetAPISchemaCmd {
+	return &GetAPISchemaCmd{}
+}
+
 // GetBestBlockCmd defines the getbestblock JSON-RPC command.
 type GetBestBlockCmd struct{}
 
@@ -80,6 +276,23 @@ func NewGetBestBlockCmd() *GetBestBlockCmd {
 	return &GetBestBlockCmd{}
 }
 
+// GetCoinAgeInfoCmd defines the getcoinageinfo JSON-RPC command.  This command
+// is not a standard Bitcoin command.  It is an extension for btcd.
+type GetCoinAgeInfoCmd struct {
+	Hash *string
+}
+
+// NewGetCoinAgeInfoCmd returns a new instance which can be used to issue a
+// getcoinageinfo JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetCoinAgeInfoCmd(hash *string) *GetCoinAgeInfoCmd {
+	return &GetCoinAgeInfoCmd{
+		Hash: hash,
+	}
+}
+
 // GetCurrentNetCmd defines the getcurrentnet JSON-RPC command.
 type GetCurrentNetCmd struct{}
 
@@ -89,13 +302,327 @@ func NewGetCurrentNetCmd() *GetCurrentNetCmd {
 	return &GetCurrentNetCmd{}
 }
 
+// GetDatabaseInfoCmd defines the getdatabaseinfo JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type GetDatabaseInfoCmd struct{}
+
+// NewGetDatabaseInfoCmd returns a new instance which can be used to issue a
+// getdatabaseinfo JSON-RPC command.
+func NewGetDatabaseInfoCmd() *GetDatabaseInfoCmd {
+	return &GetDatabaseInfoCmd{}
+}
+
+// GetDBCacheStatsCmd defines the getdbcachestats JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type GetDBCacheStatsCmd struct{}
+
+// NewGetDBCacheStatsCmd returns a new instance which can be used to issue a
+// getdbcachestats JSON-RPC command.
+func NewGetDBCacheStatsCmd() *GetDBCacheStatsCmd {
+	return &GetDBCacheStatsCmd{}
+}
+
+// GetDifficultyHistoryCmd defines the getdifficultyhistory JSON-RPC command.
+// This command is not a standard Bitcoin command.  It is an extension for
+// btcd.
+type GetDifficultyHistoryCmd struct {
+	Periods *int `jsonrpcdefault:"10"`
+	Height  *int `jsonrpcdefault:"-1"`
+}
+
+// NewGetDifficultyHistoryCmd returns a new instance which can be used to issue
+// a getdifficultyhistory JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetDifficultyHistoryCmd(numPeriods, height *int) *GetDifficultyHistoryCmd {
+	return &GetDifficultyHistoryCmd{
+		Periods: numPeriods,
+		Height:  height,
+	}
+}
+
+// GetRPCInfoCmd defines the getrpcinfo JSON-RPC command.  This command is not
+// a standard Bitcoin command.  It is an extension for btcd.
+type GetRPCInfoCmd struct{}
+
+// NewGetRPCInfoCmd returns a new instance which can be used to issue a
+// getrpcinfo JSON-RPC command.
+func NewGetRPCInfoCmd() *GetRPCInfoCmd {
+	return &GetRPCInfoCmd{}
+}
+
+// GetSpendingInfoCmd defines the getspendinginfo JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type GetSpendingInfoCmd struct {
+	Txid           string
+	Vout           uint32
+	IncludeMempool *bool `jsonrpcdefault:"true"`
+}
+
+// NewGetSpendingInfoCmd returns a new instance which can be used to issue a
+// getspendinginfo JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetSpendingInfoCmd(txHash string, vout uint32, includeMempool *bool) *GetSpendingInfoCmd {
+	return &GetSpendingInfoCmd{
+		Txid:           txHash,
+		Vout:           vout,
+		IncludeMempool: includeMempool,
+	}
+}
+
+// GetSyncStatusCmd defines the getsyncstatus JSON-RPC command.  This command
+// is not a standard Bitcoin command.  It is an extension for btcd.
+type GetSyncStatusCmd struct{}
+
+// NewGetSyncStatusCmd returns a new instance which can be used to issue a
+// getsyncstatus JSON-RPC command.
+func NewGetSyncStatusCmd() *GetSyncStatusCmd {
+	return &GetSyncStatusCmd{}
+}
+
+// GetUtxoCommitmentCmd defines the getutxocommitment JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type GetUtxoCommitmentCmd struct {
+	Hash *string
+}
+
+// NewGetUtxoCommitmentCmd returns a new instance which can be used to issue a
+// getutxocommitment JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetUtxoCommitmentCmd(hash *string) *GetUtxoCommitmentCmd {
+	return &GetUtxoCommitmentCmd{
+		Hash: hash,
+	}
+}
+
+// GetAllSubscriptionsCmd defines the getallsubscriptions JSON-RPC command.
+// This command is not a standard Bitcoin command.  It is an extension for
+// btcd.
+type GetAllSubscriptionsCmd struct{}
+
+// NewGetAllSubscriptionsCmd returns a new instance which can be used to issue
+// a getallsubscriptions JSON-RPC command.
+func NewGetAllSubscriptionsCmd() *GetAllSubscriptionsCmd {
+	return &GetAllSubscriptionsCmd{}
+}
+
+// GetUnconfirmedBroadcastsCmd defines the getunconfirmedbroadcasts JSON-RPC
+// command.  This command is not a standard Bitcoin command.  It is an
+// extension for btcd.
+type GetUnconfirmedBroadcastsCmd struct{}
+
+// NewGetUnconfirmedBroadcastsCmd returns a new instance which can be used to
+// issue a getunconfirmedbroadcasts JSON-RPC command.
+func NewGetUnconfirmedBroadcastsCmd() *GetUnconfirmedBroadcastsCmd {
+	return &GetUnconfirmedBroadcastsCmd{}
+}
+
+// GetEvictedTransactionsCmd defines the getevictedtransactions JSON-RPC
+// command.  This command is not a standard Bitcoin command.  It is an
+// extension for btcd.
+type GetEvictedTransactionsCmd struct{}
+
+// NewGetEvictedTransactionsCmd returns a new instance which can be used to
+// issue a getevictedtransactions JSON-RPC command.
+func NewGetEvictedTransactionsCmd() *GetEvictedTransactionsCmd {
+	return &GetEvictedTransactionsCmd{}
+}
+
+// GetImportStatusCmd defines the getimportstatus JSON-RPC command.
+type GetImportStatusCmd struct{}
+
+// NewGetImportStatusCmd returns a new instance which can be used to issue a
+// getimportstatus JSON-RPC command.
+func NewGetImportStatusCmd() *GetImportStatusCmd {
+	return &GetImportStatusCmd{}
+}
+
+// GetLogCmd defines the getlog JSON-RPC command.  This command is not a
+// standard Bitcoin command.  It is an extension for btcd.
+type GetLogCmd struct {
+	Subsystem string
+	Count     *int `jsonrpcdefault:"100"`
+}
+
+// NewGetLogCmd returns a new instance which can be used to issue a getlog
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetLogCmd(subsystem string, count *int) *GetLogCmd {
+	return &GetLogCmd{
+		Subsystem: subsystem,
+		Count:     count,
+	}
+}
+
+// GetNetworkFaultsCmd defines the getnetworkfaults JSON-RPC command.
+type GetNetworkFaultsCmd struct{}
+
+// NewGetNetworkFaultsCmd returns a new instance which can be used to issue a
+// getnetworkfaults JSON-RPC command.
+func NewGetNetworkFaultsCmd() *GetNetworkFaultsCmd {
+	return &GetNetworkFaultsCmd{}
+}
+
+// GetNetworkTopologyCmd defines the getnetworktopology JSON-RPC command.
+type GetNetworkTopologyCmd struct{}
+
+// NewGetNetworkTopologyCmd returns a new instance which can be used to issue a
+// getnetworktopology JSON-RPC command.
+func NewGetNetworkTopologyCmd() *GetNetworkTopologyCmd {
+	return &GetNetworkTopologyCmd{}
+}
+
+// GetPolicyInfoCmd defines the getpolicyinfo JSON-RPC command.
+type GetPolicyInfoCmd struct{}
+
+// NewGetPolicyInfoCmd returns a new instance which can be used to issue a
+// getpolicyinfo JSON-RPC command.
+func NewGetPolicyInfoCmd() *GetPolicyInfoCmd {
+	return &GetPolicyInfoCmd{}
+}
+
+// BlockTemplatePolicy describes changes to the policy block templates are
+// generated with.  Fields which are nil are left unchanged.
+type BlockTemplatePolicy struct {
+	BlockMaxSize      *uint32  `json:"blockmaxsize,omitempty"`
+	BlockMinSize      *uint32  `json:"blockminsize,omitempty"`
+	BlockPrioritySize *uint32  `json:"blockprioritysize,omitempty"`
+	MinFeeRate        *float64 `json:"minfeerate,omitempty"`
+	MinPriority       *float64 `json:"minpriority,omitempty"`
+	RecentTxAge       *int64   `json:"recenttxage,omitempty"`
+	RecentTxWeight    *float64 `json:"recenttxweight,omitempty"`
+}
+
+// RemoveBroadcastCmd defines the removebroadcast JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type RemoveBroadcastCmd struct {
+	TxID string
+}
+
+// NewRemoveBroadcastCmd returns a new instance which can be used to issue a
+// removebroadcast JSON-RPC command.
+func NewRemoveBroadcastCmd(txID string) *RemoveBroadcastCmd {
+	return &RemoveBroadcastCmd{
+		TxID: txID,
+	}
+}
+
+// RemoveFilterEntryCmd defines the removefilterentry JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for btcd.
+type RemoveFilterEntryCmd struct {
+	Entry string
+}
+
+// NewRemoveFilterEntryCmd returns a new instance which can be used to issue a
+// removefilterentry JSON-RPC command.
+func NewRemoveFilterEntryCmd(entry string) *RemoveFilterEntryCmd {
+	return &RemoveFilterEntryCmd{
+		Entry: entry,
+	}
+}
+
+// SetBlockTemplatePolicyCmd defines the setblocktemplatepolicy JSON-RPC
+// command.  This command is not a standard Bitcoin command.  It is an
+// extension for btcd.
+type SetBlockTemplatePolicyCmd struct {
+	Policy *BlockTemplatePolicy
+}
+
+// NewSetBlockTemplatePolicyCmd returns a new instance which can be used to
+// issue a setblocktemplatepolicy JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSetBlockTemplatePolicyCmd(policy *BlockTemplatePolicy) *SetBlockTemplatePolicyCmd {
+	return &SetBlockTemplatePolicyCmd{
+		Policy: policy,
+	}
+}
+
+// NetworkFault describes the messages exchanged with a peer which are dropped
+// or delayed by the setnetworkfault command.
+type NetworkFault struct {
+	Direction string   `json:"direction,omitempty"`
+	Commands  []string `json:"commands,omitempty"`
+	DropRate  float64  `json:"droprate,omitempty"`
+	Delay     int64    `json:"delay,omitempty"`
+}
+
+// SetNetworkFaultCmd defines the setnetworkfault JSON-RPC command.
+type SetNetworkFaultCmd struct {
+	Target string
+	Fault  NetworkFault
+}
+
+// NewSetNetworkFaultCmd returns a new instance which can be used to issue a
+// setnetworkfault JSON-RPC command.
+func NewSetNetworkFaultCmd(target string, fault NetworkFault) *SetNetworkFaultCmd {
+	return &SetNetworkFaultCmd{
+		Target: target,
+		Fault:  fault,
+	}
+}
+
+// SetBlockVersionCmd defines the setblockversion JSON-RPC command.
+type SetBlockVersionCmd struct {
+	Version int32
+}
+
+// NewSetBlockVersionCmd returns a new instance which can be used to issue a
+// setblockversion JSON-RPC command.
+func NewSetBlockVersionCmd(version int32) *SetBlockVersionCmd {
+	return &SetBlockVersionCmd{
+		Version: version,
+	}
+}
+
 func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
 
+	MustRegisterCmd("addfilterentry", (*AddFilterEntryCmd)(nil), flags)
+	MustRegisterCmd("backupchainstate", (*BackupChainStateCmd)(nil), flags)
+	MustRegisterCmd("broadcastalert", (*BroadcastAlertCmd)(nil), flags)
+	MustRegisterCmd("clearnetworkfaults", (*ClearNetworkFaultsCmd)(nil), flags)
+	MustRegisterCmd("compactdatabase", (*CompactDatabaseCmd)(nil), flags)
 	MustRegisterCmd("debuglevel", (*DebugLevelCmd)(nil), flags)
+	MustRegisterCmd("debugscript", (*DebugScriptCmd)(nil), flags)
 	MustRegisterCmd("node", (*NodeCmd)(nil), flags)
+	MustRegisterCmd("findorphanchains", (*FindOrphanChainsCmd)(nil), flags)
 	MustRegisterCmd("generate", (*GenerateCmd)(nil), flags)
+	MustRegisterCmd("generatetodescriptor", (*GenerateToDescriptorCmd)(nil), flags)
+	MustRegisterCmd("getaddressbalance", (*GetAddressBalanceCmd)(nil), flags)
+	MustRegisterCmd("getaddressdeltas", (*GetAddressDeltasCmd)(nil), flags)
+	MustRegisterCmd("getaddressutxos", (*GetAddressUtxosCmd)(nil), flags)
+	MustRegisterCmd("getallsubscriptions", (*GetAllSubscriptionsCmd)(nil), flags)
+	MustRegisterCmd("getapischema", (*GetAPISchemaCmd)(nil), flags)
 	MustRegisterCmd("getbestblock", (*GetBestBlockCmd)(nil), flags)
+	MustRegisterCmd("getcoinageinfo", (*GetCoinAgeInfoCmd)(nil), flags)
 	MustRegisterCmd("getcurrentnet", (*GetCurrentNetCmd)(nil), flags)
+	MustRegisterCmd("getdatabaseinfo", (*GetDatabaseInfoCmd)(nil), flags)
+	MustRegisterCmd("getdbcachestats", (*GetDBCacheStatsCmd)(nil), flags)
+	MustRegisterCmd("getdifficultyhistory", (*GetDifficultyHistoryCmd)(nil), flags)
+	MustRegisterCmd("getevictedtransactions", (*GetEvictedTransactionsCmd)(nil), flags)
+	MustRegisterCmd("getimportstatus", (*GetImportStatusCmd)(nil), flags)
+	MustRegisterCmd("getlog", (*GetLogCmd)(nil), flags)
+	MustRegisterCmd("getnetworkfaults", (*GetNetworkFaultsCmd)(nil), flags)
+	MustRegisterCmd("getnetworktopology", (*GetNetworkTopologyCmd)(nil), flags)
+	MustRegisterCmd("getpolicyinfo", (*GetPolicyInfoCmd)(nil), flags)
+	MustRegisterCmd("getrpcinfo", (*GetRPCInfoCmd)(nil), flags)
+	MustRegisterCmd("getspendinginfo", (*GetSpendingInfoCmd)(nil), flags)
+	MustRegisterCmd("getsyncstatus", (*GetSyncStatusCmd)(nil), flags)
+	MustRegisterCmd("getunconfirmedbroadcasts", (*GetUnconfirmedBroadcastsCmd)(nil), flags)
+	MustRegisterCmd("getutxocommitment", (*GetUtxoCommitmentCmd)(nil), flags)
+	MustRegisterCmd("removebroadcast", (*RemoveBroadcastCmd)(nil), flags)
+	MustRegisterCmd("removefilterentry", (*RemoveFilterEntryCmd)(nil), flags)
+	MustRegisterCmd("setblocktemplatepolicy", (*SetBlockTemplatePolicyCmd)(nil), flags)
+	MustRegisterCmd("setblockversion", (*SetBlockVersionCmd)(nil), flags)
+	MustRegisterCmd("setnetworkfault", (*SetNetworkFaultCmd)(nil), flags)
 }
@@ -29,17 +29,121 @@ func TestBtcdExtCmds(t *testing.T) {
 		marshalled   string
 		unmarshalled interface{}
 	}{
+		{
+			name: "addfilterentry",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("addfilterentry", "script:6a*")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewAddFilterEntryCmd("script:6a*")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"addfilterentry","params":["script:6a*"],"id":1}`,
+			unmarshalled: &btcjson.AddFilterEntryCmd{
+				Entry: "script:6a*",
+			},
+		},
+		{
+			name: "backupchainstate",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("backupchainstate", "backups")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBackupChainStateCmd("backups")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"backupchainstate","params":["backups"],"id":1}`,
+			unmarshalled: &btcjson.BackupChainStateCmd{DestDir: "backups"},
+		},
+		{
+			name: "broadcastalert",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("broadcastalert", "maintenance at 12:00 UTC")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBroadcastAlertCmd("maintenance at 12:00 UTC", nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"broadcastalert","params":["maintenance at 12:00 UTC"],"id":1}`,
+			unmarshalled: &btcjson.BroadcastAlertCmd{
+				Message:  "maintenance at 12:00 UTC",
+				Severity: btcjson.String("info"),
+				Webhooks: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "broadcastalert optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("broadcastalert", "deep reorg", "critical", false)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBroadcastAlertCmd("deep reorg",
+					btcjson.String("critical"), btcjson.Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"broadcastalert","params":["deep reorg","critical",false],"id":1}`,
+			unmarshalled: &btcjson.BroadcastAlertCmd{
+				Message:  "deep reorg",
+				Severity: btcjson.String("critical"),
+				Webhooks: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "clearnetworkfaults",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("clearnetworkfaults")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewClearNetworkFaultsCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"clearnetworkfaults","params":[],"id":1}`,
+			unmarshalled: &btcjson.ClearNetworkFaultsCmd{Target: nil},
+		},
+		{
+			name: "clearnetworkfaults optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("clearnetworkfaults", "3")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewClearNetworkFaultsCmd(btcjson.String("3"))
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"clearnetworkfaults","params":["3"],"id":1}`,
+			unmarshalled: &btcjson.ClearNetworkFaultsCmd{Target: btcjson.String("3")},
+		},
+		{
+			name: "compactdatabase",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("compactdatabase")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewCompactDatabaseCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"compactdatabase","params":[],"id":1}`,
+			unmarshalled: &btcjson.CompactDatabaseCmd{},
+		},
 		{
 			name: "debuglevel",
 			newCmd: func() (interface{}, error) {
 				return btcjson.NewCmd("debuglevel", "trace")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewDebugLevelCmd("trace")
+				return btcjson.NewDebugLevelCmd("trace", nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"debuglevel","params":["trace"],"id":1}`,
 			unmarshalled: &btcjson.DebugLevelCmd{
 				LevelSpec: "trace",
+				Persist:   btcjson.Bool(false),
+			},
+		},
+		{
+			name: "debuglevel optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("debuglevel", "RPCS=debug", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDebugLevelCmd("RPCS=debug",
+					btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"debuglevel","params":["RPCS=debug",true],"id":1}`,
+			unmarshalled: &btcjson.DebugLevelCmd{
+				LevelSpec: "RPCS=debug",
+				Persist:   btcjson.Bool(true),
 			},
 		},
 		{
@@ -113,6 +217,148 @@ func TestBtcdExtCmds(t *testing.T) {
 				NumBlocks: 1,
 			},
 		},
+		{
+			name: "generatetodescriptor",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("generatetodescriptor", 2,
+					"raw(51)")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGenerateToDescriptorCmd(2, "raw(51)")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"generatetodescriptor","params":[2,"raw(51)"],"id":1}`,
+			unmarshalled: &btcjson.GenerateToDescriptorCmd{
+				NumBlocks:  2,
+				Descriptor: "raw(51)",
+			},
+		},
+		{
+			name: "debugscript",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("debugscript", "5152", "935487")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDebugScriptCmd("5152", "935487", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"debugscript","params":["5152","935487"],"id":1}`,
+			unmarshalled: &btcjson.DebugScriptCmd{
+				SigScript: "5152",
+				PkScript:  "935487",
+				Standard:  btcjson.Bool(false),
+			},
+		},
+		{
+			name: "debugscript optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("debugscript", "5152", "935487", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDebugScriptCmd("5152", "935487",
+					btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"debugscript","params":["5152","935487",true],"id":1}`,
+			unmarshalled: &btcjson.DebugScriptCmd{
+				SigScript: "5152",
+				PkScript:  "935487",
+				Standard:  btcjson.Bool(true),
+			},
+		},
+		{
+			name: "findorphanchains",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("findorphanchains")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewFindOrphanChainsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"findorphanchains","params":[],"id":1}`,
+			unmarshalled: &btcjson.FindOrphanChainsCmd{},
+		},
+		{
+			name: "getaddressbalance",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddressbalance", `{"addresses":["1Address"]}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressBalanceCmd([]string{"1Address"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressbalance","params":[{"addresses":["1Address"]}],"id":1}`,
+			unmarshalled: &btcjson.GetAddressBalanceCmd{
+				Request: btcjson.AddressRequest{
+					Addresses: []string{"1Address"},
+				},
+			},
+		},
+		{
+			name: "getaddressdeltas",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddressdeltas", `{"addresses":["1Address"]}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressDeltasCmd([]string{"1Address"}, 0, 0)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressdeltas","params":[{"addresses":["1Address"]}],"id":1}`,
+			unmarshalled: &btcjson.GetAddressDeltasCmd{
+				Request: btcjson.AddressDeltasRequest{
+					Addresses: []string{"1Address"},
+				},
+			},
+		},
+		{
+			name: "getaddressdeltas range",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddressdeltas", `{"addresses":["1Address","3Address"],"start":100,"end":200}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressDeltasCmd([]string{"1Address",
+					"3Address"}, 100, 200)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressdeltas","params":[{"addresses":["1Address","3Address"],"start":100,"end":200}],"id":1}`,
+			unmarshalled: &btcjson.GetAddressDeltasCmd{
+				Request: btcjson.AddressDeltasRequest{
+					Addresses: []string{"1Address", "3Address"},
+					Start:     100,
+					End:       200,
+				},
+			},
+		},
+		{
+			name: "getaddressutxos",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddressutxos", `{"addresses":["1Address"]}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressUtxosCmd([]string{"1Address"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressutxos","params":[{"addresses":["1Address"]}],"id":1}`,
+			unmarshalled: &btcjson.GetAddressUtxosCmd{
+				Request: btcjson.AddressRequest{
+					Addresses: []string{"1Address"},
+				},
+			},
+		},
+		{
+			name: "getallsubscriptions",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getallsubscriptions")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAllSubscriptionsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getallsubscriptions","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetAllSubscriptionsCmd{},
+		},
+		{
+			name: "getapischema",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getapischema")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAPISchemaCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getapischema","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetAPISchemaCmd{},
+		},
 		{
 			name: "getbestblock",
 			newCmd: func() (interface{}, error) {
@@ -124,6 +370,30 @@ func TestBtcdExtCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getbestblock","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetBestBlockCmd{},
 		},
+		{
+			name: "getcoinageinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getcoinageinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetCoinAgeInfoCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getcoinageinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetCoinAgeInfoCmd{},
+		},
+		{
+			name: "getcoinageinfo optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getcoinageinfo", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetCoinAgeInfoCmd(btcjson.String("123"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getcoinageinfo","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetCoinAgeInfoCmd{
+				Hash: btcjson.String("123"),
+			},
+		},
 		{
 			name: "getcurrentnet",
 			newCmd: func() (interface{}, error) {
@@ -135,6 +405,328 @@ func TestBtcdExtCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getcurrentnet","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetCurrentNetCmd{},
 		},
+		{
+			name: "getdatabaseinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getdatabaseinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetDatabaseInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getdatabaseinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetDatabaseInfoCmd{},
+		},
+		{
+			name: "getdbcachestats",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getdbcachestats")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetDBCacheStatsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getdbcachestats","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetDBCacheStatsCmd{},
+		},
+		{
+			name: "getdifficultyhistory",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getdifficultyhistory")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetDifficultyHistoryCmd(nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getdifficultyhistory","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetDifficultyHistoryCmd{
+				Periods: btcjson.Int(10),
+				Height:  btcjson.Int(-1),
+			},
+		},
+		{
+			name: "getdifficultyhistory optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getdifficultyhistory", 5, 4032)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetDifficultyHistoryCmd(btcjson.Int(5), btcjson.Int(4032))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getdifficultyhistory","params":[5,4032],"id":1}`,
+			unmarshalled: &btcjson.GetDifficultyHistoryCmd{
+				Periods: btcjson.Int(5),
+				Height:  btcjson.Int(4032),
+			},
+		},
+		{
+			name: "getimportstatus",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getimportstatus")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetImportStatusCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getimportstatus","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetImportStatusCmd{},
+		},
+		{
+			name: "getlog",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getlog", "PEER")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetLogCmd("PEER", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getlog","params":["PEER"],"id":1}`,
+			unmarshalled: &btcjson.GetLogCmd{
+				Subsystem: "PEER",
+				Count:     btcjson.Int(100),
+			},
+		},
+		{
+			name: "getlog optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getlog", "PEER", 10)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetLogCmd("PEER", btcjson.Int(10))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getlog","params":["PEER",10],"id":1}`,
+			unmarshalled: &btcjson.GetLogCmd{
+				Subsystem: "PEER",
+				Count:     btcjson.Int(10),
+			},
+		},
+		{
+			name: "getnetworkfaults",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getnetworkfaults")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNetworkFaultsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getnetworkfaults","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetNetworkFaultsCmd{},
+		},
+		{
+			name: "getnetworktopology",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getnetworktopology")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNetworkTopologyCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getnetworktopology","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetNetworkTopologyCmd{},
+		},
+		{
+			name: "getpolicyinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getpolicyinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetPolicyInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getpolicyinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetPolicyInfoCmd{},
+		},
+		{
+			name: "getrpcinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getrpcinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetRPCInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getrpcinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetRPCInfoCmd{},
+		},
+		{
+			name: "getspendinginfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getspendinginfo", "123", 1)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetSpendingInfoCmd("123", 1, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getspendinginfo","params":["123",1],"id":1}`,
+			unmarshalled: &btcjson.GetSpendingInfoCmd{
+				Txid:           "123",
+				Vout:           1,
+				IncludeMempool: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "getspendinginfo optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getspendinginfo", "123", 1, false)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetSpendingInfoCmd("123", 1,
+					btcjson.Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getspendinginfo","params":["123",1,false],"id":1}`,
+			unmarshalled: &btcjson.GetSpendingInfoCmd{
+				Txid:           "123",
+				Vout:           1,
+				IncludeMempool: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getsyncstatus",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getsyncstatus")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetSyncStatusCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getsyncstatus","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetSyncStatusCmd{},
+		},
+		{
+			name: "getevictedtransactions",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getevictedtransactions")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetEvictedTransactionsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getevictedtransactions","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetEvictedTransactionsCmd{},
+		},
+		{
+			name: "getunconfirmedbroadcasts",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getunconfirmedbroadcasts")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetUnconfirmedBroadcastsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getunconfirmedbroadcasts","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetUnconfirmedBroadcastsCmd{},
+		},
+		{
+			name: "getutxocommitment",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getutxocommitment")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetUtxoCommitmentCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getutxocommitment","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetUtxoCommitmentCmd{},
+		},
+		{
+			name: "getutxocommitment optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getutxocommitment", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetUtxoCommitmentCmd(btcjson.String("123"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getutxocommitment","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetUtxoCommitmentCmd{
+				Hash: btcjson.String("123"),
+			},
+		},
+		{
+			name: "removebroadcast",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("removebroadcast", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewRemoveBroadcastCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"removebroadcast","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.RemoveBroadcastCmd{
+				TxID: "123",
+			},
+		},
+		{
+			name: "removefilterentry",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("removefilterentry", "script:6a*")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewRemoveFilterEntryCmd("script:6a*")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"removefilterentry","params":["script:6a*"],"id":1}`,
+			unmarshalled: &btcjson.RemoveFilterEntryCmd{
+				Entry: "script:6a*",
+			},
+		},
+		{
+			name: "setblocktemplatepolicy",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setblocktemplatepolicy")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSetBlockTemplatePolicyCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setblocktemplatepolicy","params":[],"id":1}`,
+			unmarshalled: &btcjson.SetBlockTemplatePolicyCmd{
+				Policy: nil,
+			},
+		},
+		{
+			name: "setblocktemplatepolicy optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setblocktemplatepolicy",
+					`{"blockprioritysize":0,"minfeerate":0.0001,"recenttxage":10}`)
+			},
+			staticCmd: func() interface{} {
+				policy := btcjson.BlockTemplatePolicy{
+					BlockPrioritySize: btcjson.Uint32(0),
+					MinFeeRate:        btcjson.Float64(0.0001),
+					RecentTxAge:       btcjson.Int64(10),
+				}
+				return btcjson.NewSetBlockTemplatePolicyCmd(&policy)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setblocktemplatepolicy","params":[{"blockprioritysize":0,"minfeerate":0.0001,"recenttxage":10}],"id":1}`,
+			unmarshalled: &btcjson.SetBlockTemplatePolicyCmd{
+				Policy: &btcjson.BlockTemplatePolicy{
+					BlockPrioritySize: btcjson.Uint32(0),
+					MinFeeRate:        btcjson.Float64(0.0001),
+					RecentTxAge:       btcjson.Int64(10),
+				},
+			},
+		},
+		{
+			name: "setblockversion",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setblockversion", 0x20000004)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSetBlockVersionCmd(0x20000004)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setblockversion","params":[536870916],"id":1}`,
+			unmarshalled: &btcjson.SetBlockVersionCmd{
+				Version: 0x20000004,
+			},
+		},
+		{
+			name: "setnetworkfault",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setnetworkfault", "127.0.0.1:18555",
+					`{"direction":"in","commands":["block","inv"],"droprate":0.5,"delay":200}`)
+			},
+			staticCmd: func() interface{} {
+				fault := btcjson.NetworkFault{
+					Direction: "in",
+					Commands:  []string{"block", "inv"},
+					DropRate:  0.5,
+					Delay:     200,
+				}
+				return btcjson.NewSetNetworkFaultCmd("127.0.0.1:18555", fault)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setnetworkfault","params":["127.0.0.1:18555",{"direction":"in","commands":["block","inv"],"droprate":0.5,"delay":200}],"id":1}`,
+			unmarshalled: &btcjson.SetNetworkFaultCmd{
+				Target: "127.0.0.1:18555",
+				Fault: btcjson.NetworkFault{
+					Direction: "in",
+					Commands:  []string{"block", "inv"},
+					DropRate:  0.5,
+					Delay:     200,
+				},
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
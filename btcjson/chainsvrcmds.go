@@ -51,10 +51,68 @@ type TransactionInput struct {
 	Vout uint32 `json:"vout"`
 }
 
+// TransactionOutputDataKey is the key of the outputs of a createrawtransaction
+// command which holds the data of a provably-prunable OP_RETURN output rather
+// than an amount paid to an address.
+const TransactionOutputDataKey = "data"
+
+// TransactionOutputs represents the outputs of a transaction created with the
+// createrawtransaction command.  The destination addresses map to the amounts
+// paid to them as float64 values, and the optional TransactionOutputDataKey
+// maps to the hex-encoded data of an OP_RETURN output as a string.
+type TransactionOutputs map[string]interface{}
+
+// newTransactionOutputs returns the outputs paying the passed amounts to their
+// addresses.
+func newTransactionOutputs(amounts map[string]float64) TransactionOutputs {
+	outputs := make(TransactionOutputs, len(amounts))
+	for addr, amount := range amounts {
+		outputs[addr] = amount
+	}
+	return outputs
+}
+
+// UnmarshalJSON unmarshals the outputs from a JSON object.  It is part of the
+// json.Unmarshaler interface and ensures the amounts are numbers and the data
+// is a string.
+func (o *TransactionOutputs) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	outputs := make(TransactionOutputs, len(raw))
+	for key, value := range raw {
+		if key == TransactionOutputDataKey {
+			var hexData string
+			if err := json.Unmarshal(value, &hexData); err != nil {
+				str := fmt.Sprintf("the %s field must be a "+
+					"string", TransactionOutputDataKey)
+				return makeError(ErrInvalidType, str)
+			}
+			outputs[key] = hexData
+			continue
+		}
+
+		var amount float64
+		if err := json.Unmarshal(value, &amount); err != nil {
+			str := fmt.Sprintf("the amount for %q must be a number",
+				key)
+			return makeError(ErrInvalidType, str)
+		}
+		outputs[key] = amount
+	}
+	*o = outputs
+	return nil
+}
+
 // CreateRawTransactionCmd defines the createrawtransaction JSON-RPC command.
+//
+// Besides the amounts paid to the addresses, Amounts may hold hex-encoded data
+// for a provably-prunable OP_RETURN output under TransactionOutputDataKey.
 type CreateRawTransactionCmd struct {
 	Inputs   []TransactionInput
-	Amounts  map[string]float64 `jsonrpcusage:"{\"address\":amount,...}"` // In XCoin
+	Amounts  TransactionOutputs `jsonrpcusage:"{\"address\":amount,...,\"data\":\"hex\"}"` // In XCoin
 	LockTime *int64
 }
 
@@ -67,11 +125,41 @@ func NewCreateRawTransactionCmd(inputs []TransactionInput, amounts map[string]fl
 
 	return &CreateRawTransactionCmd{
 		Inputs:   inputs,
-		Amounts:  amounts,
+		Amounts:  newTransactionOutputs(amounts),
 		LockTime: lockTime,
 	}
 }
 
+// NewCreateRawTransactionDataCmd returns a new instance which can be used to
+// issue a createrawtransaction JSON-RPC command which also creates an OP_RETURN
+// output carrying the passed hex-encoded data.
+//
+// Amounts are in XCoin.
+func NewCreateRawTransactionDataCmd(inputs []TransactionInput, amounts map[string]float64,
+	lockTime *int64, data string) *CreateRawTransactionCmd {
+
+	outputs := newTransactionOutputs(amounts)
+	outputs[TransactionOutputDataKey] = data
+	return &CreateRawTransactionCmd{
+		Inputs:   inputs,
+		Amounts:  outputs,
+		LockTime: lockTime,
+	}
+}
+
+// DecodePaymentURICmd defines the decodepaymenturi JSON-RPC command.
+type DecodePaymentURICmd struct {
+	URI string
+}
+
+// NewDecodePaymentURICmd returns a new instance which can be used to issue a
+// decodepaymenturi JSON-RPC command.
+func NewDecodePaymentURICmd(uri string) *DecodePaymentURICmd {
+	return &DecodePaymentURICmd{
+		URI: uri,
+	}
+}
+
 // DecodeRawTransactionCmd defines the decoderawtransaction JSON-RPC command.
 type DecodeRawTransactionCmd struct {
 	HexTx string
@@ -98,6 +186,54 @@ func NewDecodeScriptCmd(hexScript string) *DecodeScriptCmd {
 	}
 }
 
+// FundRawTransactionUtxo models an unspent transaction output which may be
+// used to fund a transaction via the fundrawtransaction command.
+type FundRawTransactionUtxo struct {
+	Txid   string  `json:"txid"`
+	Vout   uint32  `json:"vout"`
+	Amount float64 `json:"amount"`
+}
+
+// FundRawTransactionOpts models the options for the fundrawtransaction
+// command.
+//
+// The inputs are selected from Utxos when provided.  Otherwise, the unspent
+// outputs of Addresses are looked up via the address index.
+type FundRawTransactionOpts struct {
+	ChangeAddress string                   `json:"changeAddress"`
+	FeeRate       *float64                 `json:"feeRate,omitempty"`
+	Utxos         []FundRawTransactionUtxo `json:"utxos,omitempty"`
+	Addresses     []string                 `json:"addresses,omitempty"`
+}
+
+// DumpUtxoSetCmd defines the dumputxoset JSON-RPC command.
+type DumpUtxoSetCmd struct {
+	Path string
+}
+
+// NewDumpUtxoSetCmd returns a new instance which can be used to issue a
+// dumputxoset JSON-RPC command.
+func NewDumpUtxoSetCmd(path string) *DumpUtxoSetCmd {
+	return &DumpUtxoSetCmd{
+		Path: path,
+	}
+}
+
+// FundRawTransactionCmd defines the fundrawtransaction JSON-RPC command.
+type FundRawTransactionCmd struct {
+	HexTx   string
+	Options FundRawTransactionOpts
+}
+
+// NewFundRawTransactionCmd returns a new instance which can be used to issue
+// a fundrawtransaction JSON-RPC command.
+func NewFundRawTransactionCmd(hexTx string, opts FundRawTransactionOpts) *FundRawTransactionCmd {
+	return &FundRawTransactionCmd{
+		HexTx:   hexTx,
+		Options: opts,
+	}
+}
+
 // GetAddedNodeInfoCmd defines the getaddednodeinfo JSON-RPC command.
 type GetAddedNodeInfoCmd struct {
 	DNS  bool
@@ -176,6 +312,24 @@ func NewGetBlockHashCmd(index int64) *GetBlockHashCmd {
 	}
 }
 
+// GetBlockFilterCmd defines the getblockfilter JSON-RPC command.
+type GetBlockFilterCmd struct {
+	BlockHash  string
+	FilterType *string `jsonrpcdefault:"\"basic\""`
+}
+
+// NewGetBlockFilterCmd returns a new instance which can be used to issue a
+// getblockfilter JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetBlockFilterCmd(blockHash string, filterType *string) *GetBlockFilterCmd {
+	return &GetBlockFilterCmd{
+		BlockHash:  blockHash,
+		FilterType: filterType,
+	}
+}
+
 // GetBlockHeaderCmd defines the getblockheader JSON-RPC command.
 type GetBlockHeaderCmd struct {
 	Hash    string
@@ -191,6 +345,19 @@ func NewGetBlockHeaderCmd(hash string, verbose *bool) *GetBlockHeaderCmd {
 	}
 }
 
+// GetBlockStatsCmd defines the getblockstats JSON-RPC command.
+type GetBlockStatsCmd struct {
+	Hash string
+}
+
+// NewGetBlockStatsCmd returns a new instance which can be used to issue a
+// getblockstats JSON-RPC command.
+func NewGetBlockStatsCmd(hash string) *GetBlockStatsCmd {
+	return &GetBlockStatsCmd{
+		Hash: hash,
+	}
+}
+
 // TemplateRequest is a request object as defined in BIP22
 // (https://en.bitcoin.it/wiki/BIP_0022), it is optionally provided as an
 // pointer argument to GetBlockTemplateCmd.
@@ -214,6 +381,14 @@ type TemplateRequest struct {
 	// "proposal".
 	Data   string `json:"data,omitempty"`
 	WorkID string `json:"workid,omitempty"`
+
+	// Coinbase customization, which is an extension for btcd.
+	// CoinbaseFlags is the hex-encoded data to push in the coinbase
+	// script instead of the default flags.  PayoutScript is the
+	// hex-encoded public key script the coinbase pays the block reward to
+	// instead of the configured mining addresses.
+	CoinbaseFlags string `json:"coinbaseflags,omitempty"`
+	PayoutScript  string `json:"payoutscript,omitempty"`
 }
 
 // convertTemplateRequestField potentially converts the provided value as
@@ -288,6 +463,15 @@ func NewGetChainTipsCmd() *GetChainTipsCmd {
 	return &GetChainTipsCmd{}
 }
 
+// GetChainStatesCmd defines the getchainstates JSON-RPC command.
+type GetChainStatesCmd struct{}
+
+// NewGetChainStatesCmd returns a new instance which can be used to issue a
+// getchainstates JSON-RPC command.
+func NewGetChainStatesCmd() *GetChainStatesCmd {
+	return &GetChainStatesCmd{}
+}
+
 // GetConnectionCountCmd defines the getconnectioncount JSON-RPC command.
 type GetConnectionCountCmd struct{}
 
@@ -472,12 +656,19 @@ func NewGetTxOutProofCmd(txIDs []string, blockHash *string) *GetTxOutProofCmd {
 }
 
 // GetTxOutSetInfoCmd defines the gettxoutsetinfo JSON-RPC command.
-type GetTxOutSetInfoCmd struct{}
+type GetTxOutSetInfoCmd struct {
+	HashType *string `jsonrpcdefault:"\"hash_serialized\""`
+}
 
 // NewGetTxOutSetInfoCmd returns a new instance which can be used to issue a
 // gettxoutsetinfo JSON-RPC command.
-func NewGetTxOutSetInfoCmd() *GetTxOutSetInfoCmd {
-	return &GetTxOutSetInfoCmd{}
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetTxOutSetInfoCmd(hashType *string) *GetTxOutSetInfoCmd {
+	return &GetTxOutSetInfoCmd{
+		HashType: hashType,
+	}
 }
 
 // GetWorkCmd defines the getwork JSON-RPC command.
@@ -525,6 +716,24 @@ func NewInvalidateBlockCmd(blockHash string) *InvalidateBlockCmd {
 	}
 }
 
+// LoadUtxoSetCmd defines the loadutxoset JSON-RPC command.
+type LoadUtxoSetCmd struct {
+	Path         string
+	ExpectedHash *string
+}
+
+// NewLoadUtxoSetCmd returns a new instance which can be used to issue a
+// loadutxoset JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewLoadUtxoSetCmd(path string, expectedHash *string) *LoadUtxoSetCmd {
+	return &LoadUtxoSetCmd{
+		Path:         path,
+		ExpectedHash: expectedHash,
+	}
+}
+
 // PingCmd defines the ping JSON-RPC command.
 type PingCmd struct{}
 
@@ -579,6 +788,7 @@ func NewSearchRawTransactionsCmd(address string, verbose, skip, count *int, vinE
 type SendRawTransactionCmd struct {
 	HexTx         string
 	AllowHighFees *bool `jsonrpcdefault:"false"`
+	MaxFeeRate    *float64
 }
 
 // NewSendRawTransactionCmd returns a new instance which can be used to issue a
@@ -586,10 +796,11 @@ type SendRawTransactionCmd struct {
 //
 // The parameters which are pointers indicate they are optional.  Passing nil
 // for optional parameters will use the default value.
-func NewSendRawTransactionCmd(hexTx string, allowHighFees *bool) *SendRawTransactionCmd {
+func NewSendRawTransactionCmd(hexTx string, allowHighFees *bool, maxFeeRate *float64) *SendRawTransactionCmd {
 	return &SendRawTransactionCmd{
 		HexTx:         hexTx,
 		AllowHighFees: allowHighFees,
+		MaxFeeRate:    maxFeeRate,
 	}
 }
 
@@ -611,6 +822,35 @@ func NewSetGenerateCmd(generate bool, genProcLimit *int) *SetGenerateCmd {
 	}
 }
 
+// SetMockTimeCmd defines the setmocktime JSON-RPC command.
+type SetMockTimeCmd struct {
+	Timestamp int64
+}
+
+// NewSetMockTimeCmd returns a new instance which can be used to issue a
+// setmocktime JSON-RPC command.
+func NewSetMockTimeCmd(timestamp int64) *SetMockTimeCmd {
+	return &SetMockTimeCmd{
+		Timestamp: timestamp,
+	}
+}
+
+// SignMessageWithPrivKeyCmd defines the signmessagewithprivkey JSON-RPC
+// command.
+type SignMessageWithPrivKeyCmd struct {
+	PrivKey string
+	Message string
+}
+
+// NewSignMessageWithPrivKeyCmd returns a new instance which can be used to
+// issue a signmessagewithprivkey JSON-RPC command.
+func NewSignMessageWithPrivKeyCmd(privKey, message string) *SignMessageWithPrivKeyCmd {
+	return &SignMessageWithPrivKeyCmd{
+		PrivKey: privKey,
+		Message: message,
+	}
+}
+
 // StopCmd defines the stop JSON-RPC command.
 type StopCmd struct{}
 
@@ -706,22 +946,80 @@ func NewVerifyTxOutProofCmd(proof string) *VerifyTxOutProofCmd {
 	}
 }
 
+// WaitForBlockCmd defines the waitforblock JSON-RPC command.
+type WaitForBlockCmd struct {
+	BlockHash string
+	Timeout   *int64 `jsonrpcdefault:"0"` // milliseconds, 0 = no timeout
+}
+
+// NewWaitForBlockCmd returns a new instance which can be used to issue a
+// waitforblock JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewWaitForBlockCmd(blockHash string, timeout *int64) *WaitForBlockCmd {
+	return &WaitForBlockCmd{
+		BlockHash: blockHash,
+		Timeout:   timeout,
+	}
+}
+
+// WaitForBlockHeightCmd defines the waitforblockheight JSON-RPC command.
+type WaitForBlockHeightCmd struct {
+	Height  int32
+	Timeout *int64 `jsonrpcdefault:"0"` // milliseconds, 0 = no timeout
+}
+
+// NewWaitForBlockHeightCmd returns a new instance which can be used to issue a
+// waitforblockheight JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewWaitForBlockHeightCmd(height int32, timeout *int64) *WaitForBlockHeightCmd {
+	return &WaitForBlockHeightCmd{
+		Height:  height,
+		Timeout: timeout,
+	}
+}
+
+// WaitForNewBlockCmd defines the waitfornewblock JSON-RPC command.
+type WaitForNewBlockCmd struct {
+	Timeout *int64 `jsonrpcdefault:"0"` // milliseconds, 0 = no timeout
+}
+
+// NewWaitForNewBlockCmd returns a new instance which can be used to issue a
+// waitfornewblock JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewWaitForNewBlockCmd(timeout *int64) *WaitForNewBlockCmd {
+	return &WaitForNewBlockCmd{
+		Timeout: timeout,
+	}
+}
+
 func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
 
 	MustRegisterCmd("addnode", (*AddNodeCmd)(nil), flags)
 	MustRegisterCmd("createrawtransaction", (*CreateRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("decodepaymenturi", (*DecodePaymentURICmd)(nil), flags)
 	MustRegisterCmd("decoderawtransaction", (*DecodeRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decodescript", (*DecodeScriptCmd)(nil), flags)
+	MustRegisterCmd("dumputxoset", (*DumpUtxoSetCmd)(nil), flags)
+	MustRegisterCmd("fundrawtransaction", (*FundRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("getaddednodeinfo", (*GetAddedNodeInfoCmd)(nil), flags)
 	MustRegisterCmd("getbestblockhash", (*GetBestBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblock", (*GetBlockCmd)(nil), flags)
 	MustRegisterCmd("getblockchaininfo", (*GetBlockChainInfoCmd)(nil), flags)
 	MustRegisterCmd("getblockcount", (*GetBlockCountCmd)(nil), flags)
+	MustRegisterCmd("getblockfilter", (*GetBlockFilterCmd)(nil), flags)
 	MustRegisterCmd("getblockhash", (*GetBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblockheader", (*GetBlockHeaderCmd)(nil), flags)
+	MustRegisterCmd("getblockstats", (*GetBlockStatsCmd)(nil), flags)
 	MustRegisterCmd("getblocktemplate", (*GetBlockTemplateCmd)(nil), flags)
+	MustRegisterCmd("getchainstates", (*GetChainStatesCmd)(nil), flags)
 	MustRegisterCmd("getchaintips", (*GetChainTipsCmd)(nil), flags)
 	MustRegisterCmd("getconnectioncount", (*GetConnectionCountCmd)(nil), flags)
 	MustRegisterCmd("getdifficulty", (*GetDifficultyCmd)(nil), flags)
@@ -742,15 +1040,21 @@ func init() {
 	MustRegisterCmd("getwork", (*GetWorkCmd)(nil), flags)
 	MustRegisterCmd("help", (*HelpCmd)(nil), flags)
 	MustRegisterCmd("invalidateblock", (*InvalidateBlockCmd)(nil), flags)
+	MustRegisterCmd("loadutxoset", (*LoadUtxoSetCmd)(nil), flags)
 	MustRegisterCmd("ping", (*PingCmd)(nil), flags)
 	MustRegisterCmd("reconsiderblock", (*ReconsiderBlockCmd)(nil), flags)
 	MustRegisterCmd("searchrawtransactions", (*SearchRawTransactionsCmd)(nil), flags)
 	MustRegisterCmd("sendrawtransaction", (*SendRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("setgenerate", (*SetGenerateCmd)(nil), flags)
+	MustRegisterCmd("setmocktime", (*SetMockTimeCmd)(nil), flags)
+	MustRegisterCmd("signmessagewithprivkey", (*SignMessageWithPrivKeyCmd)(nil), flags)
 	MustRegisterCmd("stop", (*StopCmd)(nil), flags)
 	MustRegisterCmd("submitblock", (*SubmitBlockCmd)(nil), flags)
 	MustRegisterCmd("validateaddress", (*ValidateAddressCmd)(nil), flags)
 	MustRegisterCmd("verifychain", (*VerifyChainCmd)(nil), flags)
 	MustRegisterCmd("verifymessage", (*VerifyMessageCmd)(nil), flags)
 	MustRegisterCmd("verifytxoutproof", (*VerifyTxOutProofCmd)(nil), flags)
+	MustRegisterCmd("waitforblock", (*WaitForBlockCmd)(nil), flags)
+	MustRegisterCmd("waitforblockheight", (*WaitForBlockHeightCmd)(nil), flags)
+	MustRegisterCmd("waitfornewblock", (*WaitForNewBlockCmd)(nil), flags)
 }
@@ -56,7 +56,7 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled: `{"jsonrpc":"1.0","method":"createrawtransaction","params":[[{"txid":"123","vout":1}],{"456":0.0123}],"id":1}`,
 			unmarshalled: &btcjson.CreateRawTransactionCmd{
 				Inputs:  []btcjson.TransactionInput{{Txid: "123", Vout: 1}},
-				Amounts: map[string]float64{"456": .0123},
+				Amounts: btcjson.TransactionOutputs{"456": .0123},
 			},
 		},
 		{
@@ -75,11 +75,42 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled: `{"jsonrpc":"1.0","method":"createrawtransaction","params":[[{"txid":"123","vout":1}],{"456":0.0123},12312333333],"id":1}`,
 			unmarshalled: &btcjson.CreateRawTransactionCmd{
 				Inputs:   []btcjson.TransactionInput{{Txid: "123", Vout: 1}},
-				Amounts:  map[string]float64{"456": .0123},
+				Amounts:  btcjson.TransactionOutputs{"456": .0123},
 				LockTime: btcjson.Int64(12312333333),
 			},
 		},
+		{
+			name: "createrawtransaction data",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("createrawtransaction", `[{"txid":"123","vout":1}]`,
+					`{"456":0.0123,"data":"abcd"}`, 0)
+			},
+			staticCmd: func() interface{} {
+				txInputs := []btcjson.TransactionInput{
+					{Txid: "123", Vout: 1},
+				}
+				amounts := map[string]float64{"456": .0123}
+				return btcjson.NewCreateRawTransactionDataCmd(txInputs, amounts, btcjson.Int64(0), "abcd")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createrawtransaction","params":[[{"txid":"123","vout":1}],{"456":0.0123,"data":"abcd"},0],"id":1}`,
+			unmarshalled: &btcjson.CreateRawTransactionCmd{
+				Inputs:   []btcjson.TransactionInput{{Txid: "123", Vout: 1}},
+				Amounts:  btcjson.TransactionOutputs{"456": .0123, "data": "abcd"},
+				LockTime: btcjson.Int64(0),
+			},
+		},
 
+		{
+			name: "decodepaymenturi",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("decodepaymenturi", "bitcoin:1Address")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDecodePaymentURICmd("bitcoin:1Address")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"decodepaymenturi","params":["bitcoin:1Address"],"id":1}`,
+			unmarshalled: &btcjson.DecodePaymentURICmd{URI: "bitcoin:1Address"},
+		},
 		{
 			name: "decoderawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -102,6 +133,67 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"decodescript","params":["00"],"id":1}`,
 			unmarshalled: &btcjson.DecodeScriptCmd{HexScript: "00"},
 		},
+		{
+			name: "dumputxoset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("dumputxoset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDumpUtxoSetCmd("utxo.dat")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"dumputxoset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.DumpUtxoSetCmd{Path: "utxo.dat"},
+		},
+		{
+			name: "fundrawtransaction",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("fundrawtransaction", "00",
+					`{"changeAddress":"1Address","utxos":[{"txid":"123","vout":1,"amount":0.5}]}`)
+			},
+			staticCmd: func() interface{} {
+				opts := btcjson.FundRawTransactionOpts{
+					ChangeAddress: "1Address",
+					Utxos: []btcjson.FundRawTransactionUtxo{
+						{Txid: "123", Vout: 1, Amount: 0.5},
+					},
+				}
+				return btcjson.NewFundRawTransactionCmd("00", opts)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"fundrawtransaction","params":["00",{"changeAddress":"1Address","utxos":[{"txid":"123","vout":1,"amount":0.5}]}],"id":1}`,
+			unmarshalled: &btcjson.FundRawTransactionCmd{
+				HexTx: "00",
+				Options: btcjson.FundRawTransactionOpts{
+					ChangeAddress: "1Address",
+					Utxos: []btcjson.FundRawTransactionUtxo{
+						{Txid: "123", Vout: 1, Amount: 0.5},
+					},
+				},
+			},
+		},
+		{
+			name: "fundrawtransaction addresses",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("fundrawtransaction", "00",
+					`{"changeAddress":"1Address","feeRate":0.0001,"addresses":["1Address"]}`)
+			},
+			staticCmd: func() interface{} {
+				opts := btcjson.FundRawTransactionOpts{
+					ChangeAddress: "1Address",
+					FeeRate:       btcjson.Float64(0.0001),
+					Addresses:     []string{"1Address"},
+				}
+				return btcjson.NewFundRawTransactionCmd("00", opts)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"fundrawtransaction","params":["00",{"changeAddress":"1Address","feeRate":0.0001,"addresses":["1Address"]}],"id":1}`,
+			unmarshalled: &btcjson.FundRawTransactionCmd{
+				HexTx: "00",
+				Options: btcjson.FundRawTransactionOpts{
+					ChangeAddress: "1Address",
+					FeeRate:       btcjson.Float64(0.0001),
+					Addresses:     []string{"1Address"},
+				},
+			},
+		},
 		{
 			name: "getaddednodeinfo",
 			newCmd: func() (interface{}, error) {
@@ -220,6 +312,35 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getblockhash","params":[123],"id":1}`,
 			unmarshalled: &btcjson.GetBlockHashCmd{Index: 123},
 		},
+		{
+			name: "getblockfilter",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockfilter", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockFilterCmd("123", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockfilter","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetBlockFilterCmd{
+				BlockHash:  "123",
+				FilterType: btcjson.String("basic"),
+			},
+		},
+		{
+			name: "getblockfilter optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockfilter", "123", "basic")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockFilterCmd("123",
+					btcjson.String("basic"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockfilter","params":["123","basic"],"id":1}`,
+			unmarshalled: &btcjson.GetBlockFilterCmd{
+				BlockHash:  "123",
+				FilterType: btcjson.String("basic"),
+			},
+		},
 		{
 			name: "getblockheader",
 			newCmd: func() (interface{}, error) {
@@ -234,6 +355,19 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: btcjson.Bool(true),
 			},
 		},
+		{
+			name: "getblockstats",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockstats", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockStatsCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockstats","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetBlockStatsCmd{
+				Hash: "123",
+			},
+		},
 		{
 			name: "getblocktemplate",
 			newCmd: func() (interface{}, error) {
@@ -317,6 +451,41 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "getblocktemplate optional - template request with coinbase customization",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblocktemplate", `{"mode":"template","capabilities":["coinbasetxn"],"coinbaseflags":"2f706f6f6c2f","payoutscript":"51"}`)
+			},
+			staticCmd: func() interface{} {
+				template := btcjson.TemplateRequest{
+					Mode:          "template",
+					Capabilities:  []string{"coinbasetxn"},
+					CoinbaseFlags: "2f706f6f6c2f",
+					PayoutScript:  "51",
+				}
+				return btcjson.NewGetBlockTemplateCmd(&template)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblocktemplate","params":[{"mode":"template","capabilities":["coinbasetxn"],"coinbaseflags":"2f706f6f6c2f","payoutscript":"51"}],"id":1}`,
+			unmarshalled: &btcjson.GetBlockTemplateCmd{
+				Request: &btcjson.TemplateRequest{
+					Mode:          "template",
+					Capabilities:  []string{"coinbasetxn"},
+					CoinbaseFlags: "2f706f6f6c2f",
+					PayoutScript:  "51",
+				},
+			},
+		},
+		{
+			name: "getchainstates",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getchainstates")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetChainStatesCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getchainstates","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetChainStatesCmd{},
+		},
 		{
 			name: "getchaintips",
 			newCmd: func() (interface{}, error) {
@@ -600,10 +769,25 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("gettxoutsetinfo")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewGetTxOutSetInfoCmd()
+				return btcjson.NewGetTxOutSetInfoCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetTxOutSetInfoCmd{
+				HashType: btcjson.String("hash_serialized"),
+			},
+		},
+		{
+			name: "gettxoutsetinfo optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("gettxoutsetinfo", "muhash")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetTxOutSetInfoCmd(btcjson.String("muhash"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":["muhash"],"id":1}`,
+			unmarshalled: &btcjson.GetTxOutSetInfoCmd{
+				HashType: btcjson.String("muhash"),
 			},
-			marshalled:   `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":[],"id":1}`,
-			unmarshalled: &btcjson.GetTxOutSetInfoCmd{},
 		},
 		{
 			name: "getwork",
@@ -670,6 +854,35 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "loadutxoset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadutxoset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewLoadUtxoSetCmd("utxo.dat", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadutxoset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.LoadUtxoSetCmd{
+				Path:         "utxo.dat",
+				ExpectedHash: nil,
+			},
+		},
+		{
+			name: "loadutxoset optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadutxoset", "utxo.dat", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewLoadUtxoSetCmd("utxo.dat",
+					btcjson.String("123"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadutxoset","params":["utxo.dat","123"],"id":1}`,
+			unmarshalled: &btcjson.LoadUtxoSetCmd{
+				Path:         "utxo.dat",
+				ExpectedHash: btcjson.String("123"),
+			},
+		},
 		{
 			name: "ping",
 			newCmd: func() (interface{}, error) {
@@ -839,7 +1052,7 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("sendrawtransaction", "1122")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewSendRawTransactionCmd("1122", nil)
+				return btcjson.NewSendRawTransactionCmd("1122", nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"sendrawtransaction","params":["1122"],"id":1}`,
 			unmarshalled: &btcjson.SendRawTransactionCmd{
@@ -853,7 +1066,7 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("sendrawtransaction", "1122", false)
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewSendRawTransactionCmd("1122", btcjson.Bool(false))
+				return btcjson.NewSendRawTransactionCmd("1122", btcjson.Bool(false), nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"sendrawtransaction","params":["1122",false],"id":1}`,
 			unmarshalled: &btcjson.SendRawTransactionCmd{
@@ -861,6 +1074,22 @@ func TestChainSvrCmds(t *testing.T) {
 				AllowHighFees: btcjson.Bool(false),
 			},
 		},
+		{
+			name: "sendrawtransaction optional maxfeerate",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("sendrawtransaction", "1122", false, 0.05)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSendRawTransactionCmd("1122",
+					btcjson.Bool(false), btcjson.Float64(0.05))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sendrawtransaction","params":["1122",false,0.05],"id":1}`,
+			unmarshalled: &btcjson.SendRawTransactionCmd{
+				HexTx:         "1122",
+				AllowHighFees: btcjson.Bool(false),
+				MaxFeeRate:    btcjson.Float64(0.05),
+			},
+		},
 		{
 			name: "setgenerate",
 			newCmd: func() (interface{}, error) {
@@ -889,6 +1118,33 @@ func TestChainSvrCmds(t *testing.T) {
 				GenProcLimit: btcjson.Int(6),
 			},
 		},
+		{
+			name: "setmocktime",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setmocktime", 1431000000)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSetMockTimeCmd(1431000000)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setmocktime","params":[1431000000],"id":1}`,
+			unmarshalled: &btcjson.SetMockTimeCmd{
+				Timestamp: 1431000000,
+			},
+		},
+		{
+			name: "signmessagewithprivkey",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("signmessagewithprivkey", "5Key", "test")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSignMessageWithPrivKeyCmd("5Key", "test")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"signmessagewithprivkey","params":["5Key","test"],"id":1}`,
+			unmarshalled: &btcjson.SignMessageWithPrivKeyCmd{
+				PrivKey: "5Key",
+				Message: "test",
+			},
+		},
 		{
 			name: "stop",
 			newCmd: func() (interface{}, error) {
@@ -1016,6 +1272,88 @@ func TestChainSvrCmds(t *testing.T) {
 				Proof: "test",
 			},
 		},
+		{
+			name: "waitforblock",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("waitforblock", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewWaitForBlockCmd("123", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"waitforblock","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.WaitForBlockCmd{
+				BlockHash: "123",
+				Timeout:   btcjson.Int64(0),
+			},
+		},
+		{
+			name: "waitforblock optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("waitforblock", "123", 1000)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewWaitForBlockCmd("123", btcjson.Int64(1000))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"waitforblock","params":["123",1000],"id":1}`,
+			unmarshalled: &btcjson.WaitForBlockCmd{
+				BlockHash: "123",
+				Timeout:   btcjson.Int64(1000),
+			},
+		},
+		{
+			name: "waitforblockheight",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("waitforblockheight", 100)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewWaitForBlockHeightCmd(100, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"waitforblockheight","params":[100],"id":1}`,
+			unmarshalled: &btcjson.WaitForBlockHeightCmd{
+				Height:  100,
+				Timeout: btcjson.Int64(0),
+			},
+		},
+		{
+			name: "waitforblockheight optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("waitforblockheight", 100, 1000)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewWaitForBlockHeightCmd(100, btcjson.Int64(1000))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"waitforblockheight","params":[100,1000],"id":1}`,
+			unmarshalled: &btcjson.WaitForBlockHeightCmd{
+				Height:  100,
+				Timeout: btcjson.Int64(1000),
+			},
+		},
+		{
+			name: "waitfornewblock",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("waitfornewblock")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewWaitForNewBlockCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"waitfornewblock","params":[],"id":1}`,
+			unmarshalled: &btcjson.WaitForNewBlockCmd{
+				Timeout: btcjson.Int64(0),
+			},
+		},
+		{
+			name: "waitfornewblock optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("waitfornewblock", 1000)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewWaitForNewBlockCmd(btcjson.Int64(1000))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"waitfornewblock","params":[1000],"id":1}`,
+			unmarshalled: &btcjson.WaitForNewBlockCmd{
+				Timeout: btcjson.Int64(1000),
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -1115,6 +1453,18 @@ func TestChainSvrCmdErrors(t *testing.T) {
 			marshalled: `{"sizelimit":"invalid"}`,
 			err:        btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
 		},
+		{
+			name:       "transaction outputs with invalid amount",
+			result:     &btcjson.TransactionOutputs{},
+			marshalled: `{"456":"0.0123"}`,
+			err:        btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:       "transaction outputs with invalid data",
+			result:     &btcjson.TransactionOutputs{},
+			marshalled: `{"data":12}`,
+			err:        btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
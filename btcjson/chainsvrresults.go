@@ -6,6 +6,12 @@ package btcjson
 
 import "encoding/json"
 
+// GetBlockFilterResult models the data from the getblockfilter command.
+type GetBlockFilterResult struct {
+	Filter string `json:"filter"`
+	Header string `json:"header"`
+}
+
 // GetBlockHeaderVerboseResult models the data from the getblockheader command when
 // the verbose flag is set.  When the verbose flag is not set, getblockheader
 // returns a hex-encoded string.
@@ -16,9 +22,11 @@ type GetBlockHeaderVerboseResult struct {
 	Version       int32   `json:"version"`
 	MerkleRoot    string  `json:"merkleroot"`
 	Time          int64   `json:"time"`
+	MedianTime    int64   `json:"mediantime"`
 	Nonce         uint64  `json:"nonce"`
 	Bits          string  `json:"bits"`
 	Difficulty    float64 `json:"difficulty"`
+	ChainWork     string  `json:"chainwork"`
 	PreviousHash  string  `json:"previousblockhash,omitempty"`
 	NextHash      string  `json:"nextblockhash,omitempty"`
 }
@@ -36,6 +44,7 @@ type GetBlockVerboseResult struct {
 	Tx            []string      `json:"tx,omitempty"`
 	RawTx         []TxRawResult `json:"rawtx,omitempty"`
 	Time          int64         `json:"time"`
+	MedianTime    int64         `json:"mediantime"`
 	Nonce         uint32        `json:"nonce"`
 	Bits          string        `json:"bits"`
 	Difficulty    float64       `json:"difficulty"`
@@ -43,6 +52,14 @@ type GetBlockVerboseResult struct {
 	NextHash      string        `json:"nextblockhash,omitempty"`
 }
 
+// BackupChainStateResult models the data returned from the backupchainstate
+// command.
+type BackupChainStateResult struct {
+	Path   string `json:"path"`
+	Hash   string `json:"hash"`
+	Height int32  `json:"height"`
+}
+
 // CreateMultiSigResult models the data returned from the createmultisig
 // command.
 type CreateMultiSigResult struct {
@@ -50,6 +67,16 @@ type CreateMultiSigResult struct {
 	RedeemScript string `json:"redeemScript"`
 }
 
+// DecodePaymentURIResult models the data returned from the decodepaymenturi
+// command.
+type DecodePaymentURIResult struct {
+	Address string            `json:"address"`
+	Amount  float64           `json:"amount,omitempty"`
+	Label   string            `json:"label,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+}
+
 // DecodeScriptResult models the data returned from the decodescript command.
 type DecodeScriptResult struct {
 	Asm       string   `json:"asm"`
@@ -59,6 +86,118 @@ type DecodeScriptResult struct {
 	P2sh      string   `json:"p2sh"`
 }
 
+// DumpUtxoSetResult models the data returned from the dumputxoset command.
+type DumpUtxoSetResult struct {
+	CoinsWritten int64  `json:"coins_written"`
+	BaseHash     string `json:"base_hash"`
+	BaseHeight   int32  `json:"base_height"`
+	Path         string `json:"path"`
+	TxOutSetHash string `json:"txoutset_hash"`
+}
+
+// DebugScriptStep models the state of the script engine after each executed
+// opcode returned from the debugscript command.
+type DebugScriptStep struct {
+	Script   int      `json:"script"`
+	Offset   int      `json:"offset"`
+	Opcode   string   `json:"opcode"`
+	Stack    []string `json:"stack"`
+	AltStack []string `json:"altstack"`
+}
+
+// DebugScriptResult models the data returned from the debugscript command.
+type DebugScriptResult struct {
+	Valid bool              `json:"valid"`
+	Error string            `json:"error,omitempty"`
+	Trace []DebugScriptStep `json:"trace"`
+}
+
+// FindOrphanChainsResult models the data of each orphan chain returned from
+// the findorphanchains command.
+type FindOrphanChainsResult struct {
+	RootPrevHash string `json:"rootprevhash"`
+	Length       int    `json:"length"`
+	Blocks       int    `json:"blocks"`
+	Size         int    `json:"size"`
+	TipHash      string `json:"tiphash"`
+}
+
+// FundRawTransactionResult models the data returned from the
+// fundrawtransaction command.
+type FundRawTransactionResult struct {
+	Hex       string  `json:"hex"`
+	Fee       float64 `json:"fee"`
+	ChangePos int     `json:"changepos"`
+}
+
+// LoadUtxoSetResult models the data returned from the loadutxoset command.
+type LoadUtxoSetResult struct {
+	Coins        int64  `json:"coins"`
+	BaseHash     string `json:"base_hash"`
+	BaseHeight   int32  `json:"base_height"`
+	TxOutSetHash string `json:"txoutset_hash"`
+}
+
+// GetAddressBalanceResult models the data returned from the getaddressbalance
+// command.  The amounts are in satoshi.
+type GetAddressBalanceResult struct {
+	Balance  int64 `json:"balance"`
+	Received int64 `json:"received"`
+}
+
+// AddressDeltaResult models a change to the balance of an address returned
+// from the getaddressdeltas command.  The index is the one of the input when
+// satoshis is negative and the one of the output otherwise.
+type AddressDeltaResult struct {
+	Satoshis   int64  `json:"satoshis"`
+	TxID       string `json:"txid"`
+	Index      uint32 `json:"index"`
+	BlockIndex uint32 `json:"blockindex"`
+	Height     int32  `json:"height"`
+	Address    string `json:"address"`
+}
+
+// AddressUtxoResult models an unspent output of an address returned from the
+// getaddressutxos command.
+type AddressUtxoResult struct {
+	Address     string `json:"address"`
+	TxID        string `json:"txid"`
+	OutputIndex uint32 `json:"outputIndex"`
+	Script      string `json:"script"`
+	Satoshis    int64  `json:"satoshis"`
+	Height      int32  `json:"height"`
+}
+
+// GetAPISchemaResult models the data returned from the getapischema command.
+// The schemas of the methods and notifications are keyed by their names.
+type GetAPISchemaResult struct {
+	Methods       map[string]*MethodSchema `json:"methods"`
+	Notifications map[string]*MethodSchema `json:"notifications"`
+}
+
+// GetCoinAgeInfoResult models the data returned from the getcoinageinfo
+// command.  Ages are in days and coin days are coins multiplied by days.
+type GetCoinAgeInfoResult struct {
+	Hash                   string  `json:"hash"`
+	Height                 int32   `json:"height"`
+	SpentValue             float64 `json:"spentvalue"`
+	CoinDaysDestroyed      float64 `json:"coindaysdestroyed"`
+	AvgCoinAge             float64 `json:"avgcoinage"`
+	TotalCoinDaysDestroyed float64 `json:"totalcoindaysdestroyed"`
+}
+
+// GetDifficultyHistoryResult models the data of a retarget period returned
+// from the getdifficultyhistory command.
+type GetDifficultyHistoryResult struct {
+	Height        int32   `json:"height"`
+	EndHeight     int32   `json:"endheight"`
+	Hash          string  `json:"hash"`
+	Time          int64   `json:"time"`
+	Bits          string  `json:"bits"`
+	Difficulty    float64 `json:"difficulty"`
+	NetworkHashPS int64   `json:"networkhashps"`
+}
+
 // GetAddedNodeInfoResultAddr models the data of the addresses portion of the
 // getaddednodeinfo command.
 type GetAddedNodeInfoResultAddr struct {
@@ -73,6 +212,15 @@ type GetAddedNodeInfoResult struct {
 	Addresses *[]GetAddedNodeInfoResultAddr `json:"addresses,omitempty"`
 }
 
+// Bip9SoftForkDescription describes the current state of a rule change
+// deployment defined by BIP0009.
+type Bip9SoftForkDescription struct {
+	Status    string `json:"status"`
+	Bit       uint8  `json:"bit"`
+	StartTime int64  `json:"startTime"`
+	Timeout   int64  `json:"timeout"`
+}
+
 // GetBlockChainInfoResult models the data returned from the getblockchaininfo
 // command.
 type GetBlockChainInfoResult struct {
@@ -81,18 +229,62 @@ type GetBlockChainInfoResult struct {
 	Headers              int32   `json:"headers"`
 	BestBlockHash        string  `json:"bestblockhash"`
 	Difficulty           float64 `json:"difficulty"`
-	VerificationProgress float64 `json:"verificationprogress"`
+	MedianTime           int64   `json:"mediantime"`
+	VerificationProgress float64 `json:"verificationprogress,omitempty"`
+	InitialBlockDownload bool    `json:"initialblockdownload"`
 	ChainWork            string  `json:"chainwork"`
+	TimeOffset           int64   `json:"timeoffset"`
+	Warnings             string  `json:"warnings"`
+
+	Bip9SoftForks map[string]*Bip9SoftForkDescription `json:"bip9_softforks"`
+}
+
+// ChainStateResult models the data of each chain state returned from the
+// getchainstates command.
+type ChainStateResult struct {
+	Blocks            int32  `json:"blocks"`
+	BestBlockHash     string `json:"bestblockhash"`
+	SnapshotBlockHash string `json:"snapshot_blockhash,omitempty"`
+	Validated         bool   `json:"validated"`
+}
+
+// GetChainStatesResult models the data returned from the getchainstates
+// command.
+type GetChainStatesResult struct {
+	Headers     int32              `json:"headers"`
+	ChainStates []ChainStateResult `json:"chainstates"`
+}
+
+// GetBlockStatsResult models the data returned from the getblockstats command.
+// All amounts are in satoshi.  The coin age statistics are only set when the
+// coin age index is enabled.
+type GetBlockStatsResult struct {
+	Hash              string   `json:"hash"`
+	Height            int32    `json:"height"`
+	Time              int64    `json:"time"`
+	Txs               int64    `json:"txs"`
+	Ins               int64    `json:"ins"`
+	Outs              int64    `json:"outs"`
+	TotalSize         int64    `json:"total_size"`
+	TotalOut          int64    `json:"total_out"`
+	TotalFee          int64    `json:"totalfee"`
+	AvgFee            int64    `json:"avgfee"`
+	Subsidy           int64    `json:"subsidy"`
+	UtxoIncrease      int64    `json:"utxo_increase"`
+	CoinDaysDestroyed *float64 `json:"coindaysdestroyed,omitempty"`
+	AvgCoinAge        *float64 `json:"avgcoinage,omitempty"`
 }
 
 // GetBlockTemplateResultTx models the transactions field of the
 // getblocktemplate command.
 type GetBlockTemplateResultTx struct {
 	Data    string  `json:"data"`
+	TxID    string  `json:"txid"`
 	Hash    string  `json:"hash"`
 	Depends []int64 `json:"depends"`
 	Fee     int64   `json:"fee"`
 	SigOps  int64   `json:"sigops"`
+	Weight  int64   `json:"weight"`
 }
 
 // GetBlockTemplateResultAux models the coinbaseaux field of the
@@ -137,6 +329,266 @@ type GetBlockTemplateResult struct {
 	// Block proposal from BIP 0023.
 	Capabilities  []string `json:"capabilities,omitempty"`
 	RejectReasion string   `json:"reject-reason,omitempty"`
+
+	// Version bits deployments from BIP 0009.
+	Rules       []string          `json:"rules,omitempty"`
+	VbAvailable map[string]uint32 `json:"vbavailable,omitempty"`
+	VbRequired  uint32            `json:"vbrequired,omitempty"`
+
+	// Segregated witness fields from BIP 0145.
+	WeightLimit              int64  `json:"weightlimit,omitempty"`
+	DefaultWitnessCommitment string `json:"default_witness_commitment,omitempty"`
+
+	// UTXO commitment field (btcd extension).
+	DefaultUtxoCommitment string `json:"default_utxo_commitment,omitempty"`
+
+	// Transaction selection audit trail (btcd extension).
+	AuditSource *GetBlockTemplateResultAudit `json:"auditsource,omitempty"`
+}
+
+// GetBlockTemplateResultAuditTx models why a transaction of a block template
+// was selected.
+type GetBlockTemplateResultAuditTx struct {
+	TxID     string  `json:"txid"`
+	Reason   string  `json:"reason"`
+	Rank     int     `json:"rank"`
+	Package  bool    `json:"package"`
+	FeeRate  float64 `json:"feerate"`
+	Priority float64 `json:"priority"`
+}
+
+// GetBlockTemplateResultExclusion models a transaction which was excluded
+// from a block template by the policy or the block limits.
+type GetBlockTemplateResultExclusion struct {
+	TxID     string  `json:"txid"`
+	Reason   string  `json:"reason"`
+	FeeRate  float64 `json:"feerate"`
+	Priority float64 `json:"priority"`
+}
+
+// GetBlockTemplateResultAudit models the transaction selection audit trail of
+// a block template.
+type GetBlockTemplateResultAudit struct {
+	Transactions []GetBlockTemplateResultAuditTx   `json:"transactions"`
+	Exclusions   []GetBlockTemplateResultExclusion `json:"exclusions"`
+}
+
+// GetDatabaseInfoResult models the data returned from the getdatabaseinfo
+// command.
+type GetDatabaseInfoResult struct {
+	Type                   string           `json:"type"`
+	Height                 int32            `json:"height"`
+	Size                   int64            `json:"size"`
+	Sizes                  map[string]int64 `json:"sizes,omitempty"`
+	Compacting             bool             `json:"compacting"`
+	CompactWindow          string           `json:"compactwindow,omitempty"`
+	LastCompactionTime     int64            `json:"lastcompactiontime,omitempty"`
+	LastCompactionDuration float64          `json:"lastcompactionduration,omitempty"`
+	LastCompactionError    string           `json:"lastcompactionerror,omitempty"`
+}
+
+// CacheLookupsResult models the hit and miss counters of one kind of lookup
+// returned from the getdbcachestats command.
+type CacheLookupsResult struct {
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hitrate"`
+}
+
+// GetDBCacheStatsResult models the data returned from the getdbcachestats
+// command.
+type GetDBCacheStatsResult struct {
+	Entries       int                `json:"entries"`
+	MaxEntries    int                `json:"maxentries"`
+	HeightLookups CacheLookupsResult `json:"heightlookups"`
+	HashLookups   CacheLookupsResult `json:"hashlookups"`
+	HeaderLookups CacheLookupsResult `json:"headerlookups"`
+}
+
+// GetImportStatusResult models the data returned from the getimportstatus
+// command.
+type GetImportStatusResult struct {
+	Active          bool   `json:"active"`
+	File            string `json:"file,omitempty"`
+	FilesRemaining  int    `json:"filesremaining"`
+	BlocksProcessed int64  `json:"blocksprocessed"`
+	BlocksImported  int64  `json:"blocksimported"`
+	Height          int32  `json:"height"`
+	Error           string `json:"error,omitempty"`
+}
+
+// GetPolicyInfoResult models the data returned from the getpolicyinfo
+// command.
+type GetPolicyInfoResult struct {
+	RelayNonStd              bool    `json:"relaynonstd"`
+	MinRelayTxFee            float64 `json:"minrelaytxfee"`
+	DustRelayFee             float64 `json:"dustrelayfee"`
+	AcceptDataCarrier        bool    `json:"acceptdatacarrier"`
+	MaxDataCarrierSize       int     `json:"maxdatacarriersize"`
+	MaxDataCarrierOutputs    int     `json:"maxdatacarrieroutputs"`
+	AcceptBareMultisig       bool    `json:"acceptbaremultisig"`
+	MaxSigOpsPerTx           int     `json:"maxsigopspertx"`
+	MaxStandardTxWeight      int     `json:"maxstandardtxweight"`
+	MaxStandardSigScriptSize int     `json:"maxstandardsigscriptsize"`
+	MaxStandardMultiSigKeys  int     `json:"maxstandardmultisigkeys"`
+	MaxAncestorCount         int     `json:"maxancestorcount"`
+	MaxAncestorSize          int64   `json:"maxancestorsize"`
+	MaxDescendantCount       int     `json:"maxdescendantcount"`
+	MaxDescendantSize        int64   `json:"maxdescendantsize"`
+}
+
+// SpendingInputResult models the transaction input which spends an output
+// returned from the getspendinginfo command.  The block hash and height are
+// omitted when the spending transaction is in the memory pool.
+type SpendingInputResult struct {
+	TxID      string `json:"txid"`
+	Vin       uint32 `json:"vin"`
+	BlockHash string `json:"blockhash,omitempty"`
+	Height    int32  `json:"height,omitempty"`
+}
+
+// GetSpendingInfoResult models the data returned from the getspendinginfo
+// command.
+type GetSpendingInfoResult struct {
+	TxID    string               `json:"txid"`
+	Vout    uint32               `json:"vout"`
+	Spent   bool                 `json:"spent"`
+	SpentBy *SpendingInputResult `json:"spentby,omitempty"`
+}
+
+// GetSyncStatusResult models the data returned from the getsyncstatus command.
+type GetSyncStatusResult struct {
+	InitialBlockDownload bool             `json:"initialblockdownload"`
+	Current              bool             `json:"current"`
+	Blocks               int32            `json:"blocks"`
+	BestBlockHash        string           `json:"bestblockhash"`
+	TipTime              int64            `json:"tiptime"`
+	TipAge               int64            `json:"tipage"`
+	SyncPeer             string           `json:"syncpeer,omitempty"`
+	SyncPeerHeight       int32            `json:"syncpeerheight,omitempty"`
+	VerificationProgress float64          `json:"verificationprogress"`
+	Stalls               map[string]int32 `json:"stalls,omitempty"`
+}
+
+// GetUtxoCommitmentResult models the data returned from the getutxocommitment
+// command.
+type GetUtxoCommitmentResult struct {
+	Hash        string `json:"hash"`
+	Height      int32  `json:"height"`
+	UtxoSetHash string `json:"utxosethash"`
+	Commitment  string `json:"commitment,omitempty"`
+}
+
+// BlockTemplatePolicyResult models the data returned from the
+// setblocktemplatepolicy command.
+type BlockTemplatePolicyResult struct {
+	BlockMaxSize      uint32  `json:"blockmaxsize"`
+	BlockMinSize      uint32  `json:"blockminsize"`
+	BlockPrioritySize uint32  `json:"blockprioritysize"`
+	MinFeeRate        float64 `json:"minfeerate"`
+	MinPriority       float64 `json:"minpriority"`
+	RecentTxAge       int64   `json:"recenttxage"`
+	RecentTxWeight    float64 `json:"recenttxweight"`
+}
+
+// BroadcastAlertResult models the data returned from the broadcastalert
+// command.
+type BroadcastAlertResult struct {
+	Clients  int `json:"clients"`
+	Webhooks int `json:"webhooks"`
+}
+
+// NetworkTopologyGroup models the number of peers in a network group or
+// autonomous system returned from the getnetworktopology command.
+type NetworkTopologyGroup struct {
+	Group    string `json:"group"`
+	Name     string `json:"name,omitempty"`
+	Peers    int    `json:"peers"`
+	Inbound  int    `json:"inbound"`
+	Outbound int    `json:"outbound"`
+}
+
+// GetNetworkTopologyResult models the data returned from the
+// getnetworktopology command.
+type GetNetworkTopologyResult struct {
+	ASMap             bool                   `json:"asmap"`
+	Peers             int                    `json:"peers"`
+	Inbound           int                    `json:"inbound"`
+	Outbound          int                    `json:"outbound"`
+	OutboundNetGroups int                    `json:"outboundnetgroups"`
+	MaxNetGroupShare  float64                `json:"maxnetgroupshare"`
+	OutboundASNs      int                    `json:"outboundasns"`
+	MaxASNShare       float64                `json:"maxasnshare"`
+	NetGroups         []NetworkTopologyGroup `json:"netgroups"`
+	ASNs              []NetworkTopologyGroup `json:"asns"`
+}
+
+// NetworkFaultResult models the data of a fault returned from the
+// getnetworkfaults command.
+type NetworkFaultResult struct {
+	Target    string   `json:"target"`
+	Direction string   `json:"direction"`
+	Commands  []string `json:"commands,omitempty"`
+	DropRate  float64  `json:"droprate"`
+	Delay     int64    `json:"delay"`
+	Dropped   uint64   `json:"dropped"`
+	Delayed   uint64   `json:"delayed"`
+}
+
+// ClientSubscriptionsResult models the registrations of a websocket client
+// returned from the getallsubscriptions command.
+type ClientSubscriptionsResult struct {
+	Addr          string   `json:"addr"`
+	SessionID     uint64   `json:"sessionid"`
+	Notifications []string `json:"notifications"`
+	Addresses     int      `json:"addresses"`
+	OutPoints     int      `json:"outpoints"`
+	ScriptHashes  int      `json:"scripthashes"`
+	Filters       int      `json:"filters"`
+	FilterEntries int      `json:"filterentries"`
+}
+
+// WebsocketClientInfo models the data of a websocket client returned from the
+// getrpcinfo command.
+type WebsocketClientInfo struct {
+	Addr             string `json:"addr"`
+	SessionID        uint64 `json:"sessionid"`
+	WatchedAddrs     int    `json:"watchedaddrs"`
+	WatchedOutPoints int    `json:"watchedoutpoints"`
+}
+
+// GetRPCInfoResult models the data returned from the getrpcinfo command.
+type GetRPCInfoResult struct {
+	HTTPClients         int                   `json:"httpclients"`
+	MaxHTTPClients      int                   `json:"maxhttpclients"`
+	ReadOnlyClients     int                   `json:"readonlyclients"`
+	MaxReadOnlyClients  int                   `json:"maxreadonlyclients"`
+	Connections         int                   `json:"connections"`
+	MaxConnections      int                   `json:"maxconnections"`
+	ReadOnlyConns       int                   `json:"readonlyconnections"`
+	MaxReadOnlyConns    int                   `json:"maxreadonlyconnections"`
+	MaxWebsockets       int                   `json:"maxwebsockets"`
+	MaxWatchedAddrs     int                   `json:"maxwatchedaddrs"`
+	MaxWatchedOutPoints int                   `json:"maxwatchedoutpoints"`
+	Websockets          []WebsocketClientInfo `json:"websockets"`
+}
+
+// UnconfirmedBroadcastResult models the data of a transaction returned from
+// the getunconfirmedbroadcasts command.
+type UnconfirmedBroadcastResult struct {
+	TxID          string `json:"txid"`
+	Submitted     int64  `json:"submitted"`
+	LastBroadcast int64  `json:"lastbroadcast"`
+	Broadcasts    int    `json:"broadcasts"`
+}
+
+// EvictedTransactionResult models the data of a transaction returned from the
+// getevictedtransactions command.
+type EvictedTransactionResult struct {
+	TxID    string `json:"txid"`
+	Hex     string `json:"hex"`
+	Added   int64  `json:"added"`
+	Evicted int64  `json:"evicted"`
 }
 
 // GetMempoolInfoResult models the data returned from the getmempoolinfo
@@ -155,30 +607,33 @@ type GetNetworkInfoResult struct {
 	Connections     int32                  `json:"connections"`
 	Networks        []NetworksResult       `json:"networks"`
 	RelayFee        float64                `json:"relayfee"`
+	DustRelayFee    float64                `json:"dustrelayfee"`
+	DustLimit       float64                `json:"dustlimit"`
 	LocalAddresses  []LocalAddressesResult `json:"localaddresses"`
 }
 
 // GetPeerInfoResult models the data returned from the getpeerinfo command.
 type GetPeerInfoResult struct {
-	ID             int32   `json:"id"`
-	Addr           string  `json:"addr"`
-	AddrLocal      string  `json:"addrlocal,omitempty"`
-	Services       string  `json:"services"`
-	LastSend       int64   `json:"lastsend"`
-	LastRecv       int64   `json:"lastrecv"`
-	BytesSent      uint64  `json:"bytessent"`
-	BytesRecv      uint64  `json:"bytesrecv"`
-	ConnTime       int64   `json:"conntime"`
-	TimeOffset     int64   `json:"timeoffset"`
-	PingTime       float64 `json:"pingtime"`
-	PingWait       float64 `json:"pingwait,omitempty"`
-	Version        uint32  `json:"version"`
-	SubVer         string  `json:"subver"`
-	Inbound        bool    `json:"inbound"`
-	StartingHeight int32   `json:"startingheight"`
-	CurrentHeight  int32   `json:"currentheight,omitempty"`
-	BanScore       int32   `json:"banscore"`
-	SyncNode       bool    `json:"syncnode"`
+	ID              int32            `json:"id"`
+	Addr            string           `json:"addr"`
+	AddrLocal       string           `json:"addrlocal,omitempty"`
+	Services        string           `json:"services"`
+	LastSend        int64            `json:"lastsend"`
+	LastRecv        int64            `json:"lastrecv"`
+	BytesSent       uint64           `json:"bytessent"`
+	BytesRecv       uint64           `json:"bytesrecv"`
+	ConnTime        int64            `json:"conntime"`
+	TimeOffset      int64            `json:"timeoffset"`
+	PingTime        float64          `json:"pingtime"`
+	PingWait        float64          `json:"pingwait,omitempty"`
+	Version         uint32           `json:"version"`
+	SubVer          string           `json:"subver"`
+	Inbound         bool             `json:"inbound"`
+	StartingHeight  int32            `json:"startingheight"`
+	CurrentHeight   int32            `json:"currentheight,omitempty"`
+	BanScore        int32            `json:"banscore"`
+	BanScoreReasons map[string]int32 `json:"banscorereasons,omitempty"`
+	SyncNode        bool             `json:"syncnode"`
 }
 
 // GetRawMempoolVerboseResult models the data returned from the getrawmempool
@@ -214,6 +669,17 @@ type GetTxOutResult struct {
 	Coinbase      bool               `json:"coinbase"`
 }
 
+// GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
+type GetTxOutSetInfoResult struct {
+	Height         int32   `json:"height"`
+	BestBlock      string  `json:"bestblock"`
+	Transactions   int64   `json:"transactions,omitempty"`
+	TxOuts         int64   `json:"txouts,omitempty"`
+	HashSerialized string  `json:"hash_serialized,omitempty"`
+	MuHash         string  `json:"muhash,omitempty"`
+	TotalAmount    float64 `json:"total_amount,omitempty"`
+}
+
 // GetNetTotalsResult models the data returned from the getnettotals command.
 type GetNetTotalsResult struct {
 	TotalBytesRecv uint64 `json:"totalbytesrecv"`
@@ -237,6 +703,7 @@ type Vin struct {
 	Txid      string     `json:"txid"`
 	Vout      uint32     `json:"vout"`
 	ScriptSig *ScriptSig `json:"scriptSig"`
+	Witness   []string   `json:"txinwitness"`
 	Sequence  uint32     `json:"sequence"`
 }
 
@@ -249,10 +716,12 @@ func (v *Vin) IsCoinBase() bool {
 func (v *Vin) MarshalJSON() ([]byte, error) {
 	if v.IsCoinBase() {
 		coinbaseStruct := struct {
-			Coinbase string `json:"coinbase"`
-			Sequence uint32 `json:"sequence"`
+			Coinbase string   `json:"coinbase"`
+			Witness  []string `json:"txinwitness,omitempty"`
+			Sequence uint32   `json:"sequence"`
 		}{
 			Coinbase: v.Coinbase,
+			Witness:  v.Witness,
 			Sequence: v.Sequence,
 		}
 		return json.Marshal(coinbaseStruct)
@@ -262,11 +731,13 @@ func (v *Vin) MarshalJSON() ([]byte, error) {
 		Txid      string     `json:"txid"`
 		Vout      uint32     `json:"vout"`
 		ScriptSig *ScriptSig `json:"scriptSig"`
+		Witness   []string   `json:"txinwitness,omitempty"`
 		Sequence  uint32     `json:"sequence"`
 	}{
 		Txid:      v.Txid,
 		Vout:      v.Vout,
 		ScriptSig: v.ScriptSig,
+		Witness:   v.Witness,
 		Sequence:  v.Sequence,
 	}
 	return json.Marshal(txStruct)
@@ -387,6 +858,9 @@ type NetworksResult struct {
 type TxRawResult struct {
 	Hex           string `json:"hex"`
 	Txid          string `json:"txid"`
+	Hash          string `json:"hash,omitempty"`
+	Size          int32  `json:"size,omitempty"`
+	Vsize         int32  `json:"vsize,omitempty"`
 	Version       int32  `json:"version"`
 	LockTime      uint32 `json:"locktime"`
 	Vin           []Vin  `json:"vin"`
@@ -424,6 +898,23 @@ type TxRawDecodeResult struct {
 // ValidateAddressChainResult models the data returned by the chain server
 // validateaddress command.
 type ValidateAddressChainResult struct {
-	IsValid bool   `json:"isvalid"`
-	Address string `json:"address,omitempty"`
+	IsValid          bool     `json:"isvalid"`
+	Address          string   `json:"address,omitempty"`
+	ScriptPubKey     string   `json:"scriptPubKey,omitempty"`
+	Script           string   `json:"script,omitempty"`
+	IsScript         bool     `json:"isscript,omitempty"`
+	IsWitness        bool     `json:"iswitness,omitempty"`
+	WitnessProgram   string   `json:"witnessprogram,omitempty"`
+	SigsRequired     int32    `json:"sigsrequired,omitempty"`
+	PubKeys          []string `json:"pubkeys,omitempty"`
+	RedeemScript     string   `json:"redeemscript,omitempty"`
+	RedeemScriptType string   `json:"redeemscripttype,omitempty"`
+	RedeemScriptTxID string   `json:"redeemscripttxid,omitempty"`
+}
+
+// WaitForBlockResult models the data returned from the waitforblock,
+// waitforblockheight and waitfornewblock commands.
+type WaitForBlockResult struct {
+	Hash   string `json:"hash"`
+	Height int32  `json:"height"`
 }
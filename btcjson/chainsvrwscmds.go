@@ -40,6 +40,89 @@ func NewStopNotifyBlocksCmd() *StopNotifyBlocksCmd {
 	return &StopNotifyBlocksCmd{}
 }
 
+// NotifyBlockHeadersCmd defines the notifyblockheaders JSON-RPC command.
+type NotifyBlockHeadersCmd struct{}
+
+// NewNotifyBlockHeadersCmd returns a new instance which can be used to issue a
+// notifyblockheaders JSON-RPC command.
+func NewNotifyBlockHeadersCmd() *NotifyBlockHeadersCmd {
+	return &NotifyBlockHeadersCmd{}
+}
+
+// StopNotifyBlockHeadersCmd defines the stopnotifyblockheaders JSON-RPC
+// command.
+type StopNotifyBlockHeadersCmd struct{}
+
+// NewStopNotifyBlockHeadersCmd returns a new instance which can be used to
+// issue a stopnotifyblockheaders JSON-RPC command.
+func NewStopNotifyBlockHeadersCmd() *StopNotifyBlockHeadersCmd {
+	return &StopNotifyBlockHeadersCmd{}
+}
+
+// NotifyBlockTemplateCmd defines the notifyblocktemplate JSON-RPC command.
+// The optional request selects the capabilities and coinbase customization
+// of the pushed templates in the same way as for getblocktemplate.
+type NotifyBlockTemplateCmd struct {
+	Request *TemplateRequest
+}
+
+// NewNotifyBlockTemplateCmd returns a new instance which can be used to issue
+// a notifyblocktemplate JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNotifyBlockTemplateCmd(request *TemplateRequest) *NotifyBlockTemplateCmd {
+	return &NotifyBlockTemplateCmd{
+		Request: request,
+	}
+}
+
+// StopNotifyBlockTemplateCmd defines the stopnotifyblocktemplate JSON-RPC
+// command.
+type StopNotifyBlockTemplateCmd struct{}
+
+// NewStopNotifyBlockTemplateCmd returns a new instance which can be used to
+// issue a stopnotifyblocktemplate JSON-RPC command.
+func NewStopNotifyBlockTemplateCmd() *StopNotifyBlockTemplateCmd {
+	return &StopNotifyBlockTemplateCmd{}
+}
+
+// NotifyClockSkewCmd defines the notifyclockskew JSON-RPC command.
+type NotifyClockSkewCmd struct{}
+
+// NewNotifyClockSkewCmd returns a new instance which can be used to issue a
+// notifyclockskew JSON-RPC command.
+func NewNotifyClockSkewCmd() *NotifyClockSkewCmd {
+	return &NotifyClockSkewCmd{}
+}
+
+// StopNotifyClockSkewCmd defines the stopnotifyclockskew JSON-RPC command.
+type StopNotifyClockSkewCmd struct{}
+
+// NewStopNotifyClockSkewCmd returns a new instance which can be used to issue
+// a stopnotifyclockskew JSON-RPC command.
+func NewStopNotifyClockSkewCmd() *StopNotifyClockSkewCmd {
+	return &StopNotifyClockSkewCmd{}
+}
+
+// NotifyPeerEventsCmd defines the notifypeerevents JSON-RPC command.
+type NotifyPeerEventsCmd struct{}
+
+// NewNotifyPeerEventsCmd returns a new instance which can be used to issue a
+// notifypeerevents JSON-RPC command.
+func NewNotifyPeerEventsCmd() *NotifyPeerEventsCmd {
+	return &NotifyPeerEventsCmd{}
+}
+
+// StopNotifyPeerEventsCmd defines the stopnotifypeerevents JSON-RPC command.
+type StopNotifyPeerEventsCmd struct{}
+
+// NewStopNotifyPeerEventsCmd returns a new instance which can be used to issue
+// a stopnotifypeerevents JSON-RPC command.
+func NewStopNotifyPeerEventsCmd() *StopNotifyPeerEventsCmd {
+	return &StopNotifyPeerEventsCmd{}
+}
+
 // NotifyNewTransactionsCmd defines the notifynewtransactions JSON-RPC command.
 type NotifyNewTransactionsCmd struct {
 	Verbose *bool `jsonrpcdefault:"false"`
@@ -56,6 +139,15 @@ func NewNotifyNewTransactionsCmd(verbose *bool) *NotifyNewTransactionsCmd {
 	}
 }
 
+// GetSubscriptionsCmd defines the getsubscriptions JSON-RPC command.
+type GetSubscriptionsCmd struct{}
+
+// NewGetSubscriptionsCmd returns a new instance which can be used to issue a
+// getsubscriptions JSON-RPC command.
+func NewGetSubscriptionsCmd() *GetSubscriptionsCmd {
+	return &GetSubscriptionsCmd{}
+}
+
 // SessionCmd defines the session JSON-RPC command.
 type SessionCmd struct{}
 
@@ -123,6 +215,79 @@ func NewStopNotifyReceivedCmd(addresses []string) *StopNotifyReceivedCmd {
 	}
 }
 
+// SubscribeScriptHashCmd defines the subscribescripthash JSON-RPC command.
+// Script hashes are the hex-encoded SHA256 hashes of output scripts in the
+// reversed byte order used by the Electrum protocol.
+type SubscribeScriptHashCmd struct {
+	ScriptHashes []string
+}
+
+// NewSubscribeScriptHashCmd returns a new instance which can be used to issue
+// a subscribescripthash JSON-RPC command.
+func NewSubscribeScriptHashCmd(scriptHashes []string) *SubscribeScriptHashCmd {
+	return &SubscribeScriptHashCmd{
+		ScriptHashes: scriptHashes,
+	}
+}
+
+// UnsubscribeScriptHashCmd defines the unsubscribescripthash JSON-RPC command.
+type UnsubscribeScriptHashCmd struct {
+	ScriptHashes []string
+}
+
+// NewUnsubscribeScriptHashCmd returns a new instance which can be used to
+// issue an unsubscribescripthash JSON-RPC command.
+func NewUnsubscribeScriptHashCmd(scriptHashes []string) *UnsubscribeScriptHashCmd {
+	return &UnsubscribeScriptHashCmd{
+		ScriptHashes: scriptHashes,
+	}
+}
+
+// SubscribeRawBlocksCmd defines the subscriberawblocks JSON-RPC command.
+type SubscribeRawBlocksCmd struct {
+	Encoding   *string `jsonrpcdefault:"\"hex\""`
+	FromHeight *int32
+}
+
+// NewSubscribeRawBlocksCmd returns a new instance which can be used to issue a
+// subscriberawblocks JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSubscribeRawBlocksCmd(encoding *string, fromHeight *int32) *SubscribeRawBlocksCmd {
+	return &SubscribeRawBlocksCmd{
+		Encoding:   encoding,
+		FromHeight: fromHeight,
+	}
+}
+
+// UnsubscribeRawBlocksCmd defines the unsubscriberawblocks JSON-RPC command.
+type UnsubscribeRawBlocksCmd struct{}
+
+// NewUnsubscribeRawBlocksCmd returns a new instance which can be used to issue
+// an unsubscriberawblocks JSON-RPC command.
+func NewUnsubscribeRawBlocksCmd() *UnsubscribeRawBlocksCmd {
+	return &UnsubscribeRawBlocksCmd{}
+}
+
+// SubscribeMempoolCmd defines the subscribemempool JSON-RPC command.
+type SubscribeMempoolCmd struct{}
+
+// NewSubscribeMempoolCmd returns a new instance which can be used to issue a
+// subscribemempool JSON-RPC command.
+func NewSubscribeMempoolCmd() *SubscribeMempoolCmd {
+	return &SubscribeMempoolCmd{}
+}
+
+// UnsubscribeMempoolCmd defines the unsubscribemempool JSON-RPC command.
+type UnsubscribeMempoolCmd struct{}
+
+// NewUnsubscribeMempoolCmd returns a new instance which can be used to issue
+// an unsubscribemempool JSON-RPC command.
+func NewUnsubscribeMempoolCmd() *UnsubscribeMempoolCmd {
+	return &UnsubscribeMempoolCmd{}
+}
+
 // StopNotifySpentCmd defines the stopnotifyspent JSON-RPC command.
 type StopNotifySpentCmd struct {
 	OutPoints []OutPoint
@@ -136,6 +301,35 @@ func NewStopNotifySpentCmd(outPoints []OutPoint) *StopNotifySpentCmd {
 	}
 }
 
+// NotifyConfirmationsCmd defines the notifyconfirmations JSON-RPC command.
+type NotifyConfirmationsCmd struct {
+	TxID          string
+	Confirmations int32
+}
+
+// NewNotifyConfirmationsCmd returns a new instance which can be used to issue
+// a notifyconfirmations JSON-RPC command.
+func NewNotifyConfirmationsCmd(txHash string, confirmations int32) *NotifyConfirmationsCmd {
+	return &NotifyConfirmationsCmd{
+		TxID:          txHash,
+		Confirmations: confirmations,
+	}
+}
+
+// StopNotifyConfirmationsCmd defines the stopnotifyconfirmations JSON-RPC
+// command.
+type StopNotifyConfirmationsCmd struct {
+	TxID string
+}
+
+// NewStopNotifyConfirmationsCmd returns a new instance which can be used to
+// issue a stopnotifyconfirmations JSON-RPC command.
+func NewStopNotifyConfirmationsCmd(txHash string) *StopNotifyConfirmationsCmd {
+	return &StopNotifyConfirmationsCmd{
+		TxID: txHash,
+	}
+}
+
 // RescanCmd defines the rescan JSON-RPC command.
 type RescanCmd struct {
 	BeginBlock string
@@ -158,19 +352,164 @@ func NewRescanCmd(beginBlock string, addresses []string, outPoints []OutPoint, e
 	}
 }
 
+// StartRescanCmd defines the startrescan JSON-RPC command.
+type StartRescanCmd struct {
+	BeginBlock string
+	Addresses  []string
+	OutPoints  []OutPoint
+	EndBlock   *string
+}
+
+// NewStartRescanCmd returns a new instance which can be used to issue a
+// startrescan JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewStartRescanCmd(beginBlock string, addresses []string, outPoints []OutPoint, endBlock *string) *StartRescanCmd {
+	return &StartRescanCmd{
+		BeginBlock: beginBlock,
+		Addresses:  addresses,
+		OutPoints:  outPoints,
+		EndBlock:   endBlock,
+	}
+}
+
+// GetRescanStatusCmd defines the getrescanstatus JSON-RPC command.
+type GetRescanStatusCmd struct {
+	ID string
+}
+
+// NewGetRescanStatusCmd returns a new instance which can be used to issue a
+// getrescanstatus JSON-RPC command.
+func NewGetRescanStatusCmd(id string) *GetRescanStatusCmd {
+	return &GetRescanStatusCmd{
+		ID: id,
+	}
+}
+
+// CancelRescanCmd defines the cancelrescan JSON-RPC command.
+type CancelRescanCmd struct {
+	ID string
+}
+
+// NewCancelRescanCmd returns a new instance which can be used to issue a
+// cancelrescan JSON-RPC command.
+func NewCancelRescanCmd(id string) *CancelRescanCmd {
+	return &CancelRescanCmd{
+		ID: id,
+	}
+}
+
+// LoadTxFilterCmd defines the loadtxfilter JSON-RPC command.
+type LoadTxFilterCmd struct {
+	FilterID  string
+	Reload    bool
+	Addresses []string
+	OutPoints []OutPoint
+}
+
+// NewLoadTxFilterCmd returns a new instance which can be used to issue a
+// loadtxfilter JSON-RPC command.
+func NewLoadTxFilterCmd(filterID string, reload bool, addresses []string, outPoints []OutPoint) *LoadTxFilterCmd {
+	return &LoadTxFilterCmd{
+		FilterID:  filterID,
+		Reload:    reload,
+		Addresses: addresses,
+		OutPoints: outPoints,
+	}
+}
+
+// RemoveTxFilterCmd defines the removetxfilter JSON-RPC command.
+type RemoveTxFilterCmd struct {
+	FilterID  string
+	Addresses *[]string
+	OutPoints *[]OutPoint
+}
+
+// NewRemoveTxFilterCmd returns a new instance which can be used to issue a
+// removetxfilter JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewRemoveTxFilterCmd(filterID string, addresses *[]string, outPoints *[]OutPoint) *RemoveTxFilterCmd {
+	return &RemoveTxFilterCmd{
+		FilterID:  filterID,
+		Addresses: addresses,
+		OutPoints: outPoints,
+	}
+}
+
+// RescanFilterCmd defines the rescanfilter JSON-RPC command.
+type RescanFilterCmd struct {
+	FilterID   string
+	BeginBlock string
+	EndBlock   *string
+}
+
+// NewRescanFilterCmd returns a new instance which can be used to issue a
+// rescanfilter JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewRescanFilterCmd(filterID, beginBlock string, endBlock *string) *RescanFilterCmd {
+	return &RescanFilterCmd{
+		FilterID:   filterID,
+		BeginBlock: beginBlock,
+		EndBlock:   endBlock,
+	}
+}
+
+// ReplayEventsCmd defines the replayevents JSON-RPC command.
+type ReplayEventsCmd struct {
+	Since string
+}
+
+// NewReplayEventsCmd returns a new instance which can be used to issue a
+// replayevents JSON-RPC command.  The since parameter is either the hash of a
+// block or a journal sequence number in decimal.
+func NewReplayEventsCmd(since string) *ReplayEventsCmd {
+	return &ReplayEventsCmd{
+		Since: since,
+	}
+}
+
 func init() {
 	// The commands in this file are only usable by websockets.
 	flags := UFWebsocketOnly
 
 	MustRegisterCmd("authenticate", (*AuthenticateCmd)(nil), flags)
+	MustRegisterCmd("cancelrescan", (*CancelRescanCmd)(nil), flags)
+	MustRegisterCmd("getrescanstatus", (*GetRescanStatusCmd)(nil), flags)
+	MustRegisterCmd("getsubscriptions", (*GetSubscriptionsCmd)(nil), flags)
+	MustRegisterCmd("loadtxfilter", (*LoadTxFilterCmd)(nil), flags)
+	MustRegisterCmd("notifyblockheaders", (*NotifyBlockHeadersCmd)(nil), flags)
 	MustRegisterCmd("notifyblocks", (*NotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("notifyblocktemplate", (*NotifyBlockTemplateCmd)(nil), flags)
+	MustRegisterCmd("notifyclockskew", (*NotifyClockSkewCmd)(nil), flags)
+	MustRegisterCmd("notifyconfirmations", (*NotifyConfirmationsCmd)(nil), flags)
 	MustRegisterCmd("notifynewtransactions", (*NotifyNewTransactionsCmd)(nil), flags)
+	MustRegisterCmd("notifypeerevents", (*NotifyPeerEventsCmd)(nil), flags)
 	MustRegisterCmd("notifyreceived", (*NotifyReceivedCmd)(nil), flags)
 	MustRegisterCmd("notifyspent", (*NotifySpentCmd)(nil), flags)
+	MustRegisterCmd("replayevents", (*ReplayEventsCmd)(nil), flags)
+	MustRegisterCmd("removetxfilter", (*RemoveTxFilterCmd)(nil), flags)
 	MustRegisterCmd("session", (*SessionCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyblockheaders", (*StopNotifyBlockHeadersCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyblocks", (*StopNotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyblocktemplate", (*StopNotifyBlockTemplateCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyclockskew", (*StopNotifyClockSkewCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyconfirmations", (*StopNotifyConfirmationsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifynewtransactions", (*StopNotifyNewTransactionsCmd)(nil), flags)
+	MustRegisterCmd("stopnotifypeerevents", (*StopNotifyPeerEventsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyspent", (*StopNotifySpentCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyreceived", (*StopNotifyReceivedCmd)(nil), flags)
+	MustRegisterCmd("subscribemempool", (*SubscribeMempoolCmd)(nil), flags)
+	MustRegisterCmd("subscriberawblocks", (*SubscribeRawBlocksCmd)(nil), flags)
+	MustRegisterCmd("subscribescripthash", (*SubscribeScriptHashCmd)(nil), flags)
+	MustRegisterCmd("unsubscribemempool", (*UnsubscribeMempoolCmd)(nil), flags)
+	MustRegisterCmd("unsubscriberawblocks", (*UnsubscribeRawBlocksCmd)(nil), flags)
+	MustRegisterCmd("unsubscribescripthash", (*UnsubscribeScriptHashCmd)(nil), flags)
 	MustRegisterCmd("rescan", (*RescanCmd)(nil), flags)
+	MustRegisterCmd("rescanfilter", (*RescanFilterCmd)(nil), flags)
+	MustRegisterCmd("startrescan", (*StartRescanCmd)(nil), flags)
 }
@@ -62,6 +62,113 @@ func TestChainSvrWsCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyblocks","params":[],"id":1}`,
 			unmarshalled: &btcjson.StopNotifyBlocksCmd{},
 		},
+		{
+			name: "notifyblockheaders",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyblockheaders")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyBlockHeadersCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyblockheaders","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyBlockHeadersCmd{},
+		},
+		{
+			name: "stopnotifyblockheaders",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyblockheaders")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyBlockHeadersCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyblockheaders","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyBlockHeadersCmd{},
+		},
+		{
+			name: "notifyblocktemplate",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyblocktemplate")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyBlockTemplateCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyblocktemplate","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyBlockTemplateCmd{},
+		},
+		{
+			name: "notifyblocktemplate optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyblocktemplate", `{"capabilities":["coinbasetxn"],"payoutscript":"51"}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyBlockTemplateCmd(&btcjson.TemplateRequest{
+					Capabilities: []string{"coinbasetxn"},
+					PayoutScript: "51",
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifyblocktemplate","params":[{"capabilities":["coinbasetxn"],"payoutscript":"51"}],"id":1}`,
+			unmarshalled: &btcjson.NotifyBlockTemplateCmd{
+				Request: &btcjson.TemplateRequest{
+					Capabilities: []string{"coinbasetxn"},
+					PayoutScript: "51",
+				},
+			},
+		},
+		{
+			name: "stopnotifyblocktemplate",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyblocktemplate")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyBlockTemplateCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyblocktemplate","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyBlockTemplateCmd{},
+		},
+		{
+			name: "notifyclockskew",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyclockskew")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyClockSkewCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyclockskew","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyClockSkewCmd{},
+		},
+		{
+			name: "stopnotifyclockskew",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyclockskew")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyClockSkewCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyclockskew","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyClockSkewCmd{},
+		},
+		{
+			name: "notifypeerevents",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifypeerevents")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyPeerEventsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifypeerevents","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyPeerEventsCmd{},
+		},
+		{
+			name: "stopnotifypeerevents",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifypeerevents")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyPeerEventsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifypeerevents","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyPeerEventsCmd{},
+		},
 		{
 			name: "notifynewtransactions",
 			newCmd: func() (interface{}, error) {
@@ -125,6 +232,104 @@ func TestChainSvrWsCmds(t *testing.T) {
 				Addresses: []string{"1Address"},
 			},
 		},
+		{
+			name: "subscriberawblocks",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("subscriberawblocks")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSubscribeRawBlocksCmd(nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"subscriberawblocks","params":[],"id":1}`,
+			unmarshalled: &btcjson.SubscribeRawBlocksCmd{
+				Encoding: btcjson.String("hex"),
+			},
+		},
+		{
+			name: "subscriberawblocks optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("subscriberawblocks", "binary", 100)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSubscribeRawBlocksCmd(
+					btcjson.String("binary"), btcjson.Int32(100))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"subscriberawblocks","params":["binary",100],"id":1}`,
+			unmarshalled: &btcjson.SubscribeRawBlocksCmd{
+				Encoding:   btcjson.String("binary"),
+				FromHeight: btcjson.Int32(100),
+			},
+		},
+		{
+			name: "unsubscriberawblocks",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("unsubscriberawblocks")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewUnsubscribeRawBlocksCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"unsubscriberawblocks","params":[],"id":1}`,
+			unmarshalled: &btcjson.UnsubscribeRawBlocksCmd{},
+		},
+		{
+			name: "subscribemempool",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("subscribemempool")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSubscribeMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"subscribemempool","params":[],"id":1}`,
+			unmarshalled: &btcjson.SubscribeMempoolCmd{},
+		},
+		{
+			name: "unsubscribemempool",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("unsubscribemempool")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewUnsubscribeMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"unsubscribemempool","params":[],"id":1}`,
+			unmarshalled: &btcjson.UnsubscribeMempoolCmd{},
+		},
+		{
+			name: "getsubscriptions",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getsubscriptions")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetSubscriptionsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getsubscriptions","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetSubscriptionsCmd{},
+		},
+		{
+			name: "subscribescripthash",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("subscribescripthash", []string{"123"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSubscribeScriptHashCmd([]string{"123"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"subscribescripthash","params":[["123"]],"id":1}`,
+			unmarshalled: &btcjson.SubscribeScriptHashCmd{
+				ScriptHashes: []string{"123"},
+			},
+		},
+		{
+			name: "unsubscribescripthash",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("unsubscribescripthash", []string{"123"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewUnsubscribeScriptHashCmd([]string{"123"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"unsubscribescripthash","params":[["123"]],"id":1}`,
+			unmarshalled: &btcjson.UnsubscribeScriptHashCmd{
+				ScriptHashes: []string{"123"},
+			},
+		},
 		{
 			name: "notifyspent",
 			newCmd: func() (interface{}, error) {
@@ -153,6 +358,33 @@ func TestChainSvrWsCmds(t *testing.T) {
 				OutPoints: []btcjson.OutPoint{{Hash: "123", Index: 0}},
 			},
 		},
+		{
+			name: "notifyconfirmations",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyconfirmations", "123", 6)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyConfirmationsCmd("123", 6)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifyconfirmations","params":["123",6],"id":1}`,
+			unmarshalled: &btcjson.NotifyConfirmationsCmd{
+				TxID:          "123",
+				Confirmations: 6,
+			},
+		},
+		{
+			name: "stopnotifyconfirmations",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyconfirmations", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyConfirmationsCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"stopnotifyconfirmations","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyConfirmationsCmd{
+				TxID: "123",
+			},
+		},
 		{
 			name: "rescan",
 			newCmd: func() (interface{}, error) {
@@ -192,6 +424,159 @@ func TestChainSvrWsCmds(t *testing.T) {
 				EndBlock:   btcjson.String("456"),
 			},
 		},
+		{
+			name: "startrescan",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("startrescan", "123", `["1Address"]`, `[{"hash":"123","index":0}]`)
+			},
+			staticCmd: func() interface{} {
+				addrs := []string{"1Address"}
+				ops := []btcjson.OutPoint{{Hash: "123", Index: 0}}
+				return btcjson.NewStartRescanCmd("123", addrs, ops, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"startrescan","params":["123",["1Address"],[{"hash":"123","index":0}]],"id":1}`,
+			unmarshalled: &btcjson.StartRescanCmd{
+				BeginBlock: "123",
+				Addresses:  []string{"1Address"},
+				OutPoints:  []btcjson.OutPoint{{Hash: "123", Index: 0}},
+				EndBlock:   nil,
+			},
+		},
+		{
+			name: "startrescan optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("startrescan", "123", `["1Address"]`, `[{"hash":"123","index":0}]`, "456")
+			},
+			staticCmd: func() interface{} {
+				addrs := []string{"1Address"}
+				ops := []btcjson.OutPoint{{Hash: "123", Index: 0}}
+				return btcjson.NewStartRescanCmd("123", addrs, ops, btcjson.String("456"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"startrescan","params":["123",["1Address"],[{"hash":"123","index":0}],"456"],"id":1}`,
+			unmarshalled: &btcjson.StartRescanCmd{
+				BeginBlock: "123",
+				Addresses:  []string{"1Address"},
+				OutPoints:  []btcjson.OutPoint{{Hash: "123", Index: 0}},
+				EndBlock:   btcjson.String("456"),
+			},
+		},
+		{
+			name: "getrescanstatus",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getrescanstatus", "rescan-1")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetRescanStatusCmd("rescan-1")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getrescanstatus","params":["rescan-1"],"id":1}`,
+			unmarshalled: &btcjson.GetRescanStatusCmd{
+				ID: "rescan-1",
+			},
+		},
+		{
+			name: "cancelrescan",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("cancelrescan", "rescan-1")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewCancelRescanCmd("rescan-1")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"cancelrescan","params":["rescan-1"],"id":1}`,
+			unmarshalled: &btcjson.CancelRescanCmd{
+				ID: "rescan-1",
+			},
+		},
+		{
+			name: "loadtxfilter",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadtxfilter", "wallet1", false, `["1Address"]`, `[{"hash":"123","index":0}]`)
+			},
+			staticCmd: func() interface{} {
+				addrs := []string{"1Address"}
+				ops := []btcjson.OutPoint{{Hash: "123", Index: 0}}
+				return btcjson.NewLoadTxFilterCmd("wallet1", false, addrs, ops)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadtxfilter","params":["wallet1",false,["1Address"],[{"hash":"123","index":0}]],"id":1}`,
+			unmarshalled: &btcjson.LoadTxFilterCmd{
+				FilterID:  "wallet1",
+				Reload:    false,
+				Addresses: []string{"1Address"},
+				OutPoints: []btcjson.OutPoint{{Hash: "123", Index: 0}},
+			},
+		},
+		{
+			name: "replayevents",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("replayevents", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewReplayEventsCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"replayevents","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.ReplayEventsCmd{
+				Since: "123",
+			},
+		},
+		{
+			name: "removetxfilter",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("removetxfilter", "wallet1")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewRemoveTxFilterCmd("wallet1", nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"removetxfilter","params":["wallet1"],"id":1}`,
+			unmarshalled: &btcjson.RemoveTxFilterCmd{
+				FilterID: "wallet1",
+			},
+		},
+		{
+			name: "removetxfilter optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("removetxfilter", "wallet1", `["1Address"]`, `[{"hash":"123","index":0}]`)
+			},
+			staticCmd: func() interface{} {
+				addrs := []string{"1Address"}
+				ops := []btcjson.OutPoint{{Hash: "123", Index: 0}}
+				return btcjson.NewRemoveTxFilterCmd("wallet1", &addrs, &ops)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"removetxfilter","params":["wallet1",["1Address"],[{"hash":"123","index":0}]],"id":1}`,
+			unmarshalled: &btcjson.RemoveTxFilterCmd{
+				FilterID:  "wallet1",
+				Addresses: &[]string{"1Address"},
+				OutPoints: &[]btcjson.OutPoint{{Hash: "123", Index: 0}},
+			},
+		},
+		{
+			name: "rescanfilter",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("rescanfilter", "wallet1", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewRescanFilterCmd("wallet1", "123", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"rescanfilter","params":["wallet1","123"],"id":1}`,
+			unmarshalled: &btcjson.RescanFilterCmd{
+				FilterID:   "wallet1",
+				BeginBlock: "123",
+				EndBlock:   nil,
+			},
+		},
+		{
+			name: "rescanfilter optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("rescanfilter", "wallet1", "123", "456")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewRescanFilterCmd("wallet1", "123", btcjson.String("456"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"rescanfilter","params":["wallet1","123","456"],"id":1}`,
+			unmarshalled: &btcjson.RescanFilterCmd{
+				FilterID:   "wallet1",
+				BeginBlock: "123",
+				EndBlock:   btcjson.String("456"),
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -16,6 +16,23 @@ const (
 	// the chain server that a block has been disconnected.
 	BlockDisconnectedNtfnMethod = "blockdisconnected"
 
+	// BlockHeaderConnectedNtfnMethod is the method used for notifications
+	// from the chain server that a block header has been connected.
+	BlockHeaderConnectedNtfnMethod = "blockheaderconnected"
+
+	// BlockHeaderDisconnectedNtfnMethod is the method used for
+	// notifications from the chain server that a block header has been
+	// disconnected.
+	BlockHeaderDisconnectedNtfnMethod = "blockheaderdisconnected"
+
+	// BlockTemplateNtfnMethod is the method used for notifications from
+	// the chain server that a new block template is available.
+	BlockTemplateNtfnMethod = "blocktemplate"
+
+	// ChainReorgNtfnMethod is the method used for notifications from the
+	// chain server that the main chain has been reorganized.
+	ChainReorgNtfnMethod = "chainreorg"
+
 	// RecvTxNtfnMethod is the method used for notifications from the chain
 	// server that a transaction which pays to a registered address has been
 	// processed.
@@ -44,6 +61,93 @@ const (
 	// mempool.  This differs from TxAcceptedNtfnMethod in that it provides
 	// more details in the notification.
 	TxAcceptedVerboseNtfnMethod = "txacceptedverbose"
+
+	// TxExpiredNtfnMethod is the method used for notifications from the
+	// chain server that a transaction has been evicted from the mempool
+	// because it wasn't mined before the configured expiry.
+	TxExpiredNtfnMethod = "txexpired"
+
+	// MempoolTxAddedNtfnMethod is the method used for notifications from
+	// the chain server that a transaction has been added to the mempool.
+	MempoolTxAddedNtfnMethod = "mempooltxadded"
+
+	// MempoolTxRemovedNtfnMethod is the method used for notifications from
+	// the chain server that a transaction has been removed from the
+	// mempool.
+	MempoolTxRemovedNtfnMethod = "mempooltxremoved"
+
+	// TxConfirmedNtfnMethod is the method used for notifications from the
+	// chain server that a transaction has reached the number of
+	// confirmations requested with notifyconfirmations.
+	TxConfirmedNtfnMethod = "txconfirmed"
+
+	// ClockSkewNtfnMethod is the method used for notifications from the
+	// chain server that the local clock has started or stopped deviating
+	// from the median time of its peers by more than the allowed amount.
+	ClockSkewNtfnMethod = "clockskew"
+
+	// PeerConnectedNtfnMethod is the method used for notifications from
+	// the chain server that a peer has connected.
+	PeerConnectedNtfnMethod = "peerconnected"
+
+	// PeerDisconnectedNtfnMethod is the method used for notifications from
+	// the chain server that a peer has disconnected.
+	PeerDisconnectedNtfnMethod = "peerdisconnected"
+
+	// SyncPeerChangedNtfnMethod is the method used for notifications from
+	// the chain server that the peer it syncs the block chain from has
+	// changed.
+	SyncPeerChangedNtfnMethod = "syncpeerchanged"
+
+	// DoubleSpendSeenNtfnMethod is the method used for notifications from
+	// the chain server that a valid transaction which conflicts with a
+	// transaction in the memory pool has been seen.
+	DoubleSpendSeenNtfnMethod = "doublespendseen"
+
+	// FilteredRecvTxNtfnMethod is the method used for notifications from
+	// the chain server that a transaction which pays to an address of a
+	// loaded transaction filter has been processed.
+	FilteredRecvTxNtfnMethod = "filteredrecvtx"
+
+	// FilteredRedeemingTxNtfnMethod is the method used for notifications
+	// from the chain server that a transaction which spends an outpoint of
+	// a loaded transaction filter has been processed.
+	FilteredRedeemingTxNtfnMethod = "filteredredeemingtx"
+
+	// FilteredRescanProgressNtfnMethod is the method used for notifications
+	// from the chain server that a rescan of a loaded transaction filter
+	// which is underway has made progress.
+	FilteredRescanProgressNtfnMethod = "filteredrescanprogress"
+
+	// FilteredRescanFinishedNtfnMethod is the method used for notifications
+	// from the chain server that a rescan of a loaded transaction filter
+	// has finished.
+	FilteredRescanFinishedNtfnMethod = "filteredrescanfinished"
+
+	// RawBlockNtfnMethod is the method used for notifications from the
+	// chain server which carry a serialized block of the main chain.
+	RawBlockNtfnMethod = "rawblock"
+
+	// ReplayedEventNtfnMethod is the method used for notifications from
+	// the chain server which replay a journaled event.
+	ReplayedEventNtfnMethod = "replayedevent"
+
+	// ReplayFinishedNtfnMethod is the method used for notifications from
+	// the chain server that a replay of journaled events has finished.
+	ReplayFinishedNtfnMethod = "replayfinished"
+
+	// ScriptHashTxNtfnMethod is the method used for notifications from the
+	// chain server that a transaction which pays to or spends from a
+	// subscribed script hash has been processed.
+	ScriptHashTxNtfnMethod = "scripthashtx"
+
+	// ShutdownNtfnMethod is the method used for notifications from the
+	// chain server that it is shutting down.
+	ShutdownNtfnMethod = "shutdown"
+
+	// AlertNtfnMethod is the method used for notifications from the chain
+	// server of an alert broadcast by its operator.
+	AlertNtfnMethod = "alert"
 )
 
 // BlockConnectedNtfn defines the blockconnected JSON-RPC notification.
@@ -63,6 +167,19 @@ func NewBlockConnectedNtfn(hash string, height int32, time int64) *BlockConnecte
 	}
 }
 
+// BlockTemplateNtfn defines the blocktemplate JSON-RPC notification.
+type BlockTemplateNtfn struct {
+	Template GetBlockTemplateResult
+}
+
+// NewBlockTemplateNtfn returns a new instance which can be used to issue a
+// blocktemplate JSON-RPC notification.
+func NewBlockTemplateNtfn(template GetBlockTemplateResult) *BlockTemplateNtfn {
+	return &BlockTemplateNtfn{
+		Template: template,
+	}
+}
+
 // BlockDisconnectedNtfn defines the blockdisconnected JSON-RPC notification.
 type BlockDisconnectedNtfn struct {
 	Hash   string
@@ -80,6 +197,67 @@ func NewBlockDisconnectedNtfn(hash string, height int32, time int64) *BlockDisco
 	}
 }
 
+// BlockHeaderConnectedNtfn defines the blockheaderconnected JSON-RPC
+// notification.  The header is the hex-encoded serialized block header.
+type BlockHeaderConnectedNtfn struct {
+	Hash   string
+	Height int32
+	Header string
+}
+
+// NewBlockHeaderConnectedNtfn returns a new instance which can be used to issue
+// a blockheaderconnected JSON-RPC notification.
+func NewBlockHeaderConnectedNtfn(hash string, height int32, header string) *BlockHeaderConnectedNtfn {
+	return &BlockHeaderConnectedNtfn{
+		Hash:   hash,
+		Height: height,
+		Header: header,
+	}
+}
+
+// BlockHeaderDisconnectedNtfn defines the blockheaderdisconnected JSON-RPC
+// notification.  The header is the hex-encoded serialized block header.
+type BlockHeaderDisconnectedNtfn struct {
+	Hash   string
+	Height int32
+	Header string
+}
+
+// NewBlockHeaderDisconnectedNtfn returns a new instance which can be used to
+// issue a blockheaderdisconnected JSON-RPC notification.
+func NewBlockHeaderDisconnectedNtfn(hash string, height int32, header string) *BlockHeaderDisconnectedNtfn {
+	return &BlockHeaderDisconnectedNtfn{
+		Hash:   hash,
+		Height: height,
+		Header: header,
+	}
+}
+
+// ChainReorgNtfn defines the chainreorg JSON-RPC notification.  The detached
+// blocks are listed in the order they were disconnected, which starts with the
+// old best block, and the attached blocks in the order they were connected,
+// which ends with the new best block.
+type ChainReorgNtfn struct {
+	ForkHash   string
+	ForkHeight int32
+	Detached   []string
+	Attached   []string
+	Depth      int32
+}
+
+// NewChainReorgNtfn returns a new instance which can be used to issue a
+// chainreorg JSON-RPC notification.  The depth is the number of detached
+// blocks.
+func NewChainReorgNtfn(forkHash string, forkHeight int32, detached, attached []string) *ChainReorgNtfn {
+	return &ChainReorgNtfn{
+		ForkHash:   forkHash,
+		ForkHeight: forkHeight,
+		Detached:   detached,
+		Attached:   attached,
+		Depth:      int32(len(detached)),
+	}
+}
+
 // BlockDetails describes details of a tx in a block.
 type BlockDetails struct {
 	Height int32  `json:"height"`
@@ -103,6 +281,39 @@ func NewRecvTxNtfn(hexTx string, block *BlockDetails) *RecvTxNtfn {
 	}
 }
 
+// RawBlockNtfn defines the rawblock JSON-RPC notification.
+type RawBlockNtfn struct {
+	Height   int32
+	HexBlock string
+}
+
+// NewRawBlockNtfn returns a new instance which can be used to issue a rawblock
+// JSON-RPC notification.
+func NewRawBlockNtfn(height int32, hexBlock string) *RawBlockNtfn {
+	return &RawBlockNtfn{
+		Height:   height,
+		HexBlock: hexBlock,
+	}
+}
+
+// ScriptHashTxNtfn defines the scripthashtx JSON-RPC notification.  The block
+// details are nil for transactions accepted into the memory pool.
+type ScriptHashTxNtfn struct {
+	ScriptHash string
+	HexTx      string
+	Block      *BlockDetails
+}
+
+// NewScriptHashTxNtfn returns a new instance which can be used to issue a
+// scripthashtx JSON-RPC notification.
+func NewScriptHashTxNtfn(scriptHash, hexTx string, block *BlockDetails) *ScriptHashTxNtfn {
+	return &ScriptHashTxNtfn{
+		ScriptHash: scriptHash,
+		HexTx:      hexTx,
+		Block:      block,
+	}
+}
+
 // RedeemingTxNtfn defines the redeemingtx JSON-RPC notification.
 type RedeemingTxNtfn struct {
 	HexTx string
@@ -180,17 +391,347 @@ func NewTxAcceptedVerboseNtfn(rawTx TxRawResult) *TxAcceptedVerboseNtfn {
 	}
 }
 
+// TxExpiredNtfn defines the txexpired JSON-RPC notification.
+type TxExpiredNtfn struct {
+	TxID string
+}
+
+// NewTxExpiredNtfn returns a new instance which can be used to issue a
+// txexpired JSON-RPC notification.
+func NewTxExpiredNtfn(txHash string) *TxExpiredNtfn {
+	return &TxExpiredNtfn{
+		TxID: txHash,
+	}
+}
+
+// MempoolTxAddedNtfn defines the mempooltxadded JSON-RPC notification.
+type MempoolTxAddedNtfn struct {
+	TxID  string
+	VSize int64
+	Fee   float64
+	Time  int64
+}
+
+// NewMempoolTxAddedNtfn returns a new instance which can be used to issue a
+// mempooltxadded JSON-RPC notification.
+func NewMempoolTxAddedNtfn(txHash string, vsize int64, fee float64, time int64) *MempoolTxAddedNtfn {
+	return &MempoolTxAddedNtfn{
+		TxID:  txHash,
+		VSize: vsize,
+		Fee:   fee,
+		Time:  time,
+	}
+}
+
+// MempoolTxRemovedNtfn defines the mempooltxremoved JSON-RPC notification.
+type MempoolTxRemovedNtfn struct {
+	TxID   string
+	Reason string
+}
+
+// NewMempoolTxRemovedNtfn returns a new instance which can be used to issue a
+// mempooltxremoved JSON-RPC notification.
+func NewMempoolTxRemovedNtfn(txHash, reason string) *MempoolTxRemovedNtfn {
+	return &MempoolTxRemovedNtfn{
+		TxID:   txHash,
+		Reason: reason,
+	}
+}
+
+// TxConfirmedNtfn defines the txconfirmed JSON-RPC notification.
+type TxConfirmedNtfn struct {
+	TxID          string
+	Confirmations int32
+	BlockHash     string
+	BlockHeight   int32
+}
+
+// NewTxConfirmedNtfn returns a new instance which can be used to issue a
+// txconfirmed JSON-RPC notification.
+func NewTxConfirmedNtfn(txHash string, confirmations int32, blockHash string,
+	blockHeight int32) *TxConfirmedNtfn {
+
+	return &TxConfirmedNtfn{
+		TxID:          txHash,
+		Confirmations: confirmations,
+		BlockHash:     blockHash,
+		BlockHeight:   blockHeight,
+	}
+}
+
+// ClockSkewNtfn defines the clockskew JSON-RPC notification.
+type ClockSkewNtfn struct {
+	Offset int64
+	Skewed bool
+}
+
+// NewClockSkewNtfn returns a new instance which can be used to issue a
+// clockskew JSON-RPC notification.
+func NewClockSkewNtfn(offset int64, skewed bool) *ClockSkewNtfn {
+	return &ClockSkewNtfn{
+		Offset: offset,
+		Skewed: skewed,
+	}
+}
+
+// PeerDetails describes the peer of a peerconnected, peerdisconnected or
+// syncpeerchanged notification.
+type PeerDetails struct {
+	ID             int32  `json:"id"`
+	Addr           string `json:"addr"`
+	Inbound        bool   `json:"inbound"`
+	Persistent     bool   `json:"persistent"`
+	Services       string `json:"services"`
+	Version        uint32 `json:"version"`
+	SubVer         string `json:"subver"`
+	StartingHeight int32  `json:"startingheight"`
+	CurrentHeight  int32  `json:"currentheight"`
+	ConnTime       int64  `json:"conntime"`
+	BytesSent      uint64 `json:"bytessent"`
+	BytesRecv      uint64 `json:"bytesrecv"`
+}
+
+// PeerConnectedNtfn defines the peerconnected JSON-RPC notification.
+type PeerConnectedNtfn struct {
+	Peer PeerDetails
+}
+
+// NewPeerConnectedNtfn returns a new instance which can be used to issue a
+// peerconnected JSON-RPC notification.
+func NewPeerConnectedNtfn(peer PeerDetails) *PeerConnectedNtfn {
+	return &PeerConnectedNtfn{
+		Peer: peer,
+	}
+}
+
+// PeerDisconnectedNtfn defines the peerdisconnected JSON-RPC notification.
+type PeerDisconnectedNtfn struct {
+	Peer PeerDetails
+}
+
+// NewPeerDisconnectedNtfn returns a new instance which can be used to issue a
+// peerdisconnected JSON-RPC notification.
+func NewPeerDisconnectedNtfn(peer PeerDetails) *PeerDisconnectedNtfn {
+	return &PeerDisconnectedNtfn{
+		Peer: peer,
+	}
+}
+
+// SyncPeerChangedNtfn defines the syncpeerchanged JSON-RPC notification.
+type SyncPeerChangedNtfn struct {
+	Peer *PeerDetails
+}
+
+// NewSyncPeerChangedNtfn returns a new instance which can be used to issue a
+// syncpeerchanged JSON-RPC notification.  peer is nil when there is no peer
+// to sync from.
+func NewSyncPeerChangedNtfn(peer *PeerDetails) *SyncPeerChangedNtfn {
+	return &SyncPeerChangedNtfn{
+		Peer: peer,
+	}
+}
+
+// DoubleSpendSeenNtfn defines the doublespendseen JSON-RPC notification.
+type DoubleSpendSeenNtfn struct {
+	TxID         string
+	ConflictTxID string
+	Inputs       []OutPoint
+}
+
+// NewDoubleSpendSeenNtfn returns a new instance which can be used to issue a
+// doublespendseen JSON-RPC notification.  txID is the hash of the newly seen
+// transaction, conflictTxID the hash of the memory pool transaction it
+// conflicts with and inputs the outpoints both of them spend.
+func NewDoubleSpendSeenNtfn(txID, conflictTxID string, inputs []OutPoint) *DoubleSpendSeenNtfn {
+	return &DoubleSpendSeenNtfn{
+		TxID:         txID,
+		ConflictTxID: conflictTxID,
+		Inputs:       inputs,
+	}
+}
+
+// FilteredRecvTxNtfn defines the filteredrecvtx JSON-RPC notification.
+type FilteredRecvTxNtfn struct {
+	FilterID string
+	HexTx    string
+	Block    *BlockDetails
+}
+
+// NewFilteredRecvTxNtfn returns a new instance which can be used to issue a
+// filteredrecvtx JSON-RPC notification.
+func NewFilteredRecvTxNtfn(filterID, hexTx string, block *BlockDetails) *FilteredRecvTxNtfn {
+	return &FilteredRecvTxNtfn{
+		FilterID: filterID,
+		HexTx:    hexTx,
+		Block:    block,
+	}
+}
+
+// FilteredRedeemingTxNtfn defines the filteredredeemingtx JSON-RPC
+// notification.
+type FilteredRedeemingTxNtfn struct {
+	FilterID string
+	HexTx    string
+	Block    *BlockDetails
+}
+
+// NewFilteredRedeemingTxNtfn returns a new instance which can be used to issue
+// a filteredredeemingtx JSON-RPC notification.
+func NewFilteredRedeemingTxNtfn(filterID, hexTx string, block *BlockDetails) *FilteredRedeemingTxNtfn {
+	return &FilteredRedeemingTxNtfn{
+		FilterID: filterID,
+		HexTx:    hexTx,
+		Block:    block,
+	}
+}
+
+// FilteredRescanProgressNtfn defines the filteredrescanprogress JSON-RPC
+// notification.
+type FilteredRescanProgressNtfn struct {
+	FilterID string
+	Hash     string
+	Height   int32
+	Time     int64
+}
+
+// NewFilteredRescanProgressNtfn returns a new instance which can be used to
+// issue a filteredrescanprogress JSON-RPC notification.
+func NewFilteredRescanProgressNtfn(filterID, hash string, height int32, time int64) *FilteredRescanProgressNtfn {
+	return &FilteredRescanProgressNtfn{
+		FilterID: filterID,
+		Hash:     hash,
+		Height:   height,
+		Time:     time,
+	}
+}
+
+// FilteredRescanFinishedNtfn defines the filteredrescanfinished JSON-RPC
+// notification.
+type FilteredRescanFinishedNtfn struct {
+	FilterID string
+	Hash     string
+	Height   int32
+	Time     int64
+}
+
+// NewFilteredRescanFinishedNtfn returns a new instance which can be used to
+// issue a filteredrescanfinished JSON-RPC notification.
+func NewFilteredRescanFinishedNtfn(filterID, hash string, height int32, time int64) *FilteredRescanFinishedNtfn {
+	return &FilteredRescanFinishedNtfn{
+		FilterID: filterID,
+		Hash:     hash,
+		Height:   height,
+		Time:     time,
+	}
+}
+
+// JournalEvent describes an event replayed from the event journal of the
+// chain server.  Event is the method of the notification which announced the
+// event, such as blockconnected or txaccepted.  For block events, Hash, Height
+// and Time describe the block.  For transaction events, Hash is the
+// transaction hash, Time is the time it was accepted into the memory pool and
+// Amount is the total value of its outputs.
+type JournalEvent struct {
+	Sequence uint64  `json:"sequence"`
+	Event    string  `json:"event"`
+	Hash     string  `json:"hash"`
+	Height   int32   `json:"height,omitempty"`
+	Time     int64   `json:"time"`
+	Amount   float64 `json:"amount,omitempty"`
+}
+
+// ReplayedEventNtfn defines the replayedevent JSON-RPC notification.
+type ReplayedEventNtfn struct {
+	Event JournalEvent
+}
+
+// NewReplayedEventNtfn returns a new instance which can be used to issue a
+// replayedevent JSON-RPC notification.
+func NewReplayedEventNtfn(event JournalEvent) *ReplayedEventNtfn {
+	return &ReplayedEventNtfn{
+		Event: event,
+	}
+}
+
+// ReplayFinishedNtfn defines the replayfinished JSON-RPC notification.
+type ReplayFinishedNtfn struct {
+	Sequence uint64
+}
+
+// NewReplayFinishedNtfn returns a new instance which can be used to issue a
+// replayfinished JSON-RPC notification.
+func NewReplayFinishedNtfn(sequence uint64) *ReplayFinishedNtfn {
+	return &ReplayFinishedNtfn{
+		Sequence: sequence,
+	}
+}
+
+// ShutdownNtfn defines the shutdown JSON-RPC notification.
+type ShutdownNtfn struct {
+	GracePeriod int64
+}
+
+// NewShutdownNtfn returns a new instance which can be used to issue a shutdown
+// JSON-RPC notification.  gracePeriod is the number of seconds until the
+// server disconnects all websocket clients.
+func NewShutdownNtfn(gracePeriod int64) *ShutdownNtfn {
+	return &ShutdownNtfn{
+		GracePeriod: gracePeriod,
+	}
+}
+
+// AlertNtfn defines the alert JSON-RPC notification.
+type AlertNtfn struct {
+	Message  string
+	Severity string
+	Time     int64
+}
+
+// NewAlertNtfn returns a new instance which can be used to issue an alert
+// JSON-RPC notification.  time is when the alert was broadcast in seconds
+// since 1 Jan 1970 GMT.
+func NewAlertNtfn(message, severity string, time int64) *AlertNtfn {
+	return &AlertNtfn{
+		Message:  message,
+		Severity: severity,
+		Time:     time,
+	}
+}
+
 func init() {
 	// The commands in this file are only usable by websockets and are
 	// notifications.
 	flags := UFWebsocketOnly | UFNotification
 
+	MustRegisterCmd(AlertNtfnMethod, (*AlertNtfn)(nil), flags)
 	MustRegisterCmd(BlockConnectedNtfnMethod, (*BlockConnectedNtfn)(nil), flags)
 	MustRegisterCmd(BlockDisconnectedNtfnMethod, (*BlockDisconnectedNtfn)(nil), flags)
+	MustRegisterCmd(BlockHeaderConnectedNtfnMethod, (*BlockHeaderConnectedNtfn)(nil), flags)
+	MustRegisterCmd(BlockHeaderDisconnectedNtfnMethod, (*BlockHeaderDisconnectedNtfn)(nil), flags)
+	MustRegisterCmd(BlockTemplateNtfnMethod, (*BlockTemplateNtfn)(nil), flags)
+	MustRegisterCmd(ChainReorgNtfnMethod, (*ChainReorgNtfn)(nil), flags)
+	MustRegisterCmd(ClockSkewNtfnMethod, (*ClockSkewNtfn)(nil), flags)
+	MustRegisterCmd(DoubleSpendSeenNtfnMethod, (*DoubleSpendSeenNtfn)(nil), flags)
+	MustRegisterCmd(FilteredRecvTxNtfnMethod, (*FilteredRecvTxNtfn)(nil), flags)
+	MustRegisterCmd(FilteredRedeemingTxNtfnMethod, (*FilteredRedeemingTxNtfn)(nil), flags)
+	MustRegisterCmd(FilteredRescanFinishedNtfnMethod, (*FilteredRescanFinishedNtfn)(nil), flags)
+	MustRegisterCmd(FilteredRescanProgressNtfnMethod, (*FilteredRescanProgressNtfn)(nil), flags)
+	MustRegisterCmd(MempoolTxAddedNtfnMethod, (*MempoolTxAddedNtfn)(nil), flags)
+	MustRegisterCmd(MempoolTxRemovedNtfnMethod, (*MempoolTxRemovedNtfn)(nil), flags)
+	MustRegisterCmd(PeerConnectedNtfnMethod, (*PeerConnectedNtfn)(nil), flags)
+	MustRegisterCmd(PeerDisconnectedNtfnMethod, (*PeerDisconnectedNtfn)(nil), flags)
+	MustRegisterCmd(RawBlockNtfnMethod, (*RawBlockNtfn)(nil), flags)
 	MustRegisterCmd(RecvTxNtfnMethod, (*RecvTxNtfn)(nil), flags)
 	MustRegisterCmd(RedeemingTxNtfnMethod, (*RedeemingTxNtfn)(nil), flags)
+	MustRegisterCmd(ReplayedEventNtfnMethod, (*ReplayedEventNtfn)(nil), flags)
+	MustRegisterCmd(ReplayFinishedNtfnMethod, (*ReplayFinishedNtfn)(nil), flags)
 	MustRegisterCmd(RescanFinishedNtfnMethod, (*RescanFinishedNtfn)(nil), flags)
 	MustRegisterCmd(RescanProgressNtfnMethod, (*RescanProgressNtfn)(nil), flags)
+	MustRegisterCmd(ScriptHashTxNtfnMethod, (*ScriptHashTxNtfn)(nil), flags)
+	MustRegisterCmd(ShutdownNtfnMethod, (*ShutdownNtfn)(nil), flags)
+	MustRegisterCmd(SyncPeerChangedNtfnMethod, (*SyncPeerChangedNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedVerboseNtfnMethod, (*TxAcceptedVerboseNtfn)(nil), flags)
+	MustRegisterCmd(TxConfirmedNtfnMethod, (*TxConfirmedNtfn)(nil), flags)
+	MustRegisterCmd(TxExpiredNtfnMethod, (*TxExpiredNtfn)(nil), flags)
 }
@@ -58,6 +58,80 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Time:   123456789,
 			},
 		},
+		{
+			name: "blockheaderconnected",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("blockheaderconnected", "123", 100000, "0100")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewBlockHeaderConnectedNtfn("123", 100000, "0100")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"blockheaderconnected","params":["123",100000,"0100"],"id":null}`,
+			unmarshalled: &btcjson.BlockHeaderConnectedNtfn{
+				Hash:   "123",
+				Height: 100000,
+				Header: "0100",
+			},
+		},
+		{
+			name: "blockheaderdisconnected",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("blockheaderdisconnected", "123", 100000, "0100")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewBlockHeaderDisconnectedNtfn("123", 100000, "0100")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"blockheaderdisconnected","params":["123",100000,"0100"],"id":null}`,
+			unmarshalled: &btcjson.BlockHeaderDisconnectedNtfn{
+				Hash:   "123",
+				Height: 100000,
+				Header: "0100",
+			},
+		},
+		{
+			name: "blocktemplate",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("blocktemplate", `{"bits":"207fffff","curtime":1445000000,"height":1,"previousblockhash":"123","transactions":[],"version":4}`)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewBlockTemplateNtfn(btcjson.GetBlockTemplateResult{
+					Bits:         "207fffff",
+					CurTime:      1445000000,
+					Height:       1,
+					PreviousHash: "123",
+					Transactions: []btcjson.GetBlockTemplateResultTx{},
+					Version:      4,
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"blocktemplate","params":[{"bits":"207fffff","curtime":1445000000,"height":1,"previousblockhash":"123","transactions":[],"version":4}],"id":null}`,
+			unmarshalled: &btcjson.BlockTemplateNtfn{
+				Template: btcjson.GetBlockTemplateResult{
+					Bits:         "207fffff",
+					CurTime:      1445000000,
+					Height:       1,
+					PreviousHash: "123",
+					Transactions: []btcjson.GetBlockTemplateResultTx{},
+					Version:      4,
+				},
+			},
+		},
+		{
+			name: "chainreorg",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("chainreorg", "100", 99999, `["124","123"]`, `["223","224","225"]`, 2)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewChainReorgNtfn("100", 99999, []string{"124", "123"}, []string{"223", "224", "225"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"chainreorg","params":["100",99999,["124","123"],["223","224","225"],2],"id":null}`,
+			unmarshalled: &btcjson.ChainReorgNtfn{
+				ForkHash:   "100",
+				ForkHeight: 99999,
+				Detached:   []string{"124", "123"},
+				Attached:   []string{"223", "224", "225"},
+				Depth:      2,
+			},
+		},
 		{
 			name: "recvtx",
 			newNtfn: func() (interface{}, error) {
@@ -108,6 +182,157 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "rawblock",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("rawblock", 100000, "001122")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewRawBlockNtfn(100000, "001122")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"rawblock","params":[100000,"001122"],"id":null}`,
+			unmarshalled: &btcjson.RawBlockNtfn{
+				Height:   100000,
+				HexBlock: "001122",
+			},
+		},
+		{
+			name: "scripthashtx",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("scripthashtx", "456", "001122", `{"height":100000,"hash":"123","index":0,"time":12345678}`)
+			},
+			staticNtfn: func() interface{} {
+				blockDetails := btcjson.BlockDetails{
+					Height: 100000,
+					Hash:   "123",
+					Index:  0,
+					Time:   12345678,
+				}
+				return btcjson.NewScriptHashTxNtfn("456", "001122", &blockDetails)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scripthashtx","params":["456","001122",{"height":100000,"hash":"123","index":0,"time":12345678}],"id":null}`,
+			unmarshalled: &btcjson.ScriptHashTxNtfn{
+				ScriptHash: "456",
+				HexTx:      "001122",
+				Block: &btcjson.BlockDetails{
+					Height: 100000,
+					Hash:   "123",
+					Index:  0,
+					Time:   12345678,
+				},
+			},
+		},
+		{
+			name: "filteredrecvtx",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("filteredrecvtx", "wallet1", "001122", `{"height":100000,"hash":"123","index":0,"time":12345678}`)
+			},
+			staticNtfn: func() interface{} {
+				blockDetails := btcjson.BlockDetails{
+					Height: 100000,
+					Hash:   "123",
+					Index:  0,
+					Time:   12345678,
+				}
+				return btcjson.NewFilteredRecvTxNtfn("wallet1", "001122", &blockDetails)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"filteredrecvtx","params":["wallet1","001122",{"height":100000,"hash":"123","index":0,"time":12345678}],"id":null}`,
+			unmarshalled: &btcjson.FilteredRecvTxNtfn{
+				FilterID: "wallet1",
+				HexTx:    "001122",
+				Block: &btcjson.BlockDetails{
+					Height: 100000,
+					Hash:   "123",
+					Index:  0,
+					Time:   12345678,
+				},
+			},
+		},
+		{
+			name: "filteredredeemingtx",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("filteredredeemingtx", "wallet1", "001122")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewFilteredRedeemingTxNtfn("wallet1", "001122", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"filteredredeemingtx","params":["wallet1","001122"],"id":null}`,
+			unmarshalled: &btcjson.FilteredRedeemingTxNtfn{
+				FilterID: "wallet1",
+				HexTx:    "001122",
+			},
+		},
+		{
+			name: "filteredrescanprogress",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("filteredrescanprogress", "wallet1", "123", 100000, 12345678)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewFilteredRescanProgressNtfn("wallet1", "123", 100000, 12345678)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"filteredrescanprogress","params":["wallet1","123",100000,12345678],"id":null}`,
+			unmarshalled: &btcjson.FilteredRescanProgressNtfn{
+				FilterID: "wallet1",
+				Hash:     "123",
+				Height:   100000,
+				Time:     12345678,
+			},
+		},
+		{
+			name: "filteredrescanfinished",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("filteredrescanfinished", "wallet1", "123", 100000, 12345678)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewFilteredRescanFinishedNtfn("wallet1", "123", 100000, 12345678)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"filteredrescanfinished","params":["wallet1","123",100000,12345678],"id":null}`,
+			unmarshalled: &btcjson.FilteredRescanFinishedNtfn{
+				FilterID: "wallet1",
+				Hash:     "123",
+				Height:   100000,
+				Time:     12345678,
+			},
+		},
+		{
+			name: "replayedevent",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("replayedevent", `{"sequence":7,"event":"txaccepted","hash":"123","time":12345678,"amount":1.5}`)
+			},
+			staticNtfn: func() interface{} {
+				event := btcjson.JournalEvent{
+					Sequence: 7,
+					Event:    "txaccepted",
+					Hash:     "123",
+					Time:     12345678,
+					Amount:   1.5,
+				}
+				return btcjson.NewReplayedEventNtfn(event)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"replayedevent","params":[{"sequence":7,"event":"txaccepted","hash":"123","time":12345678,"amount":1.5}],"id":null}`,
+			unmarshalled: &btcjson.ReplayedEventNtfn{
+				Event: btcjson.JournalEvent{
+					Sequence: 7,
+					Event:    "txaccepted",
+					Hash:     "123",
+					Time:     12345678,
+					Amount:   1.5,
+				},
+			},
+		},
+		{
+			name: "replayfinished",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("replayfinished", 7)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewReplayFinishedNtfn(7)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"replayfinished","params":[7],"id":null}`,
+			unmarshalled: &btcjson.ReplayFinishedNtfn{
+				Sequence: 7,
+			},
+		},
 		{
 			name: "rescanfinished",
 			newNtfn: func() (interface{}, error) {
@@ -138,6 +363,19 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Time:   12345678,
 			},
 		},
+		{
+			name: "shutdown",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("shutdown", 5)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewShutdownNtfn(5)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"shutdown","params":[5],"id":null}`,
+			unmarshalled: &btcjson.ShutdownNtfn{
+				GracePeriod: 5,
+			},
+		},
 		{
 			name: "txaccepted",
 			newNtfn: func() (interface{}, error) {
@@ -182,6 +420,202 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "txexpired",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("txexpired", "123")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewTxExpiredNtfn("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"txexpired","params":["123"],"id":null}`,
+			unmarshalled: &btcjson.TxExpiredNtfn{
+				TxID: "123",
+			},
+		},
+		{
+			name: "mempooltxadded",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("mempooltxadded", "123", 141, 0.0001, 1431542226)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewMempoolTxAddedNtfn("123", 141, 0.0001, 1431542226)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"mempooltxadded","params":["123",141,0.0001,1431542226],"id":null}`,
+			unmarshalled: &btcjson.MempoolTxAddedNtfn{
+				TxID:  "123",
+				VSize: 141,
+				Fee:   0.0001,
+				Time:  1431542226,
+			},
+		},
+		{
+			name: "mempooltxremoved",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("mempooltxremoved", "123", "block")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewMempoolTxRemovedNtfn("123", "block")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"mempooltxremoved","params":["123","block"],"id":null}`,
+			unmarshalled: &btcjson.MempoolTxRemovedNtfn{
+				TxID:   "123",
+				Reason: "block",
+			},
+		},
+		{
+			name: "txconfirmed",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("txconfirmed", "123", 6, "456", 100000)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewTxConfirmedNtfn("123", 6, "456", 100000)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"txconfirmed","params":["123",6,"456",100000],"id":null}`,
+			unmarshalled: &btcjson.TxConfirmedNtfn{
+				TxID:          "123",
+				Confirmations: 6,
+				BlockHash:     "456",
+				BlockHeight:   100000,
+			},
+		},
+		{
+			name: "clockskew",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("clockskew", -900, true)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewClockSkewNtfn(-900, true)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"clockskew","params":[-900,true],"id":null}`,
+			unmarshalled: &btcjson.ClockSkewNtfn{
+				Offset: -900,
+				Skewed: true,
+			},
+		},
+		{
+			name: "alert",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("alert", "maintenance at 12:00 UTC", "warning", 1431542226)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewAlertNtfn("maintenance at 12:00 UTC", "warning", 1431542226)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"alert","params":["maintenance at 12:00 UTC","warning",1431542226],"id":null}`,
+			unmarshalled: &btcjson.AlertNtfn{
+				Message:  "maintenance at 12:00 UTC",
+				Severity: "warning",
+				Time:     1431542226,
+			},
+		},
+		{
+			name: "peerconnected",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("peerconnected",
+					`{"id":3,"addr":"127.0.0.1:18555","inbound":true,"persistent":false,"services":"00000001","version":70012,"subver":"/btcd:0.12.0/","startingheight":100,"currentheight":100,"conntime":1431542226,"bytessent":0,"bytesrecv":0}`)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewPeerConnectedNtfn(btcjson.PeerDetails{
+					ID:             3,
+					Addr:           "127.0.0.1:18555",
+					Inbound:        true,
+					Services:       "00000001",
+					Version:        70012,
+					SubVer:         "/btcd:0.12.0/",
+					StartingHeight: 100,
+					CurrentHeight:  100,
+					ConnTime:       1431542226,
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"peerconnected","params":[{"id":3,"addr":"127.0.0.1:18555","inbound":true,"persistent":false,"services":"00000001","version":70012,"subver":"/btcd:0.12.0/","startingheight":100,"currentheight":100,"conntime":1431542226,"bytessent":0,"bytesrecv":0}],"id":null}`,
+			unmarshalled: &btcjson.PeerConnectedNtfn{
+				Peer: btcjson.PeerDetails{
+					ID:             3,
+					Addr:           "127.0.0.1:18555",
+					Inbound:        true,
+					Services:       "00000001",
+					Version:        70012,
+					SubVer:         "/btcd:0.12.0/",
+					StartingHeight: 100,
+					CurrentHeight:  100,
+					ConnTime:       1431542226,
+				},
+			},
+		},
+		{
+			name: "peerdisconnected",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("peerdisconnected",
+					`{"id":3,"addr":"127.0.0.1:18555","inbound":false,"persistent":true,"services":"00000001","version":70012,"subver":"/btcd:0.12.0/","startingheight":100,"currentheight":102,"conntime":1431542226,"bytessent":512,"bytesrecv":2048}`)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewPeerDisconnectedNtfn(btcjson.PeerDetails{
+					ID:             3,
+					Addr:           "127.0.0.1:18555",
+					Persistent:     true,
+					Services:       "00000001",
+					Version:        70012,
+					SubVer:         "/btcd:0.12.0/",
+					StartingHeight: 100,
+					CurrentHeight:  102,
+					ConnTime:       1431542226,
+					BytesSent:      512,
+					BytesRecv:      2048,
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"peerdisconnected","params":[{"id":3,"addr":"127.0.0.1:18555","inbound":false,"persistent":true,"services":"00000001","version":70012,"subver":"/btcd:0.12.0/","startingheight":100,"currentheight":102,"conntime":1431542226,"bytessent":512,"bytesrecv":2048}],"id":null}`,
+			unmarshalled: &btcjson.PeerDisconnectedNtfn{
+				Peer: btcjson.PeerDetails{
+					ID:             3,
+					Addr:           "127.0.0.1:18555",
+					Persistent:     true,
+					Services:       "00000001",
+					Version:        70012,
+					SubVer:         "/btcd:0.12.0/",
+					StartingHeight: 100,
+					CurrentHeight:  102,
+					ConnTime:       1431542226,
+					BytesSent:      512,
+					BytesRecv:      2048,
+				},
+			},
+		},
+		{
+			name: "syncpeerchanged no peer",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("syncpeerchanged")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewSyncPeerChangedNtfn(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"syncpeerchanged","params":[],"id":null}`,
+			unmarshalled: &btcjson.SyncPeerChangedNtfn{
+				Peer: nil,
+			},
+		},
+		{
+			name: "doublespendseen",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("doublespendseen", "456", "123",
+					`[{"hash":"0000000000000000000000000000000000000000000000000000000000000123","index":0}]`)
+			},
+			staticNtfn: func() interface{} {
+				inputs := []btcjson.OutPoint{{
+					Hash:  "0000000000000000000000000000000000000000000000000000000000000123",
+					Index: 0,
+				}}
+				return btcjson.NewDoubleSpendSeenNtfn("456", "123", inputs)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"doublespendseen","params":["456","123",[{"hash":"0000000000000000000000000000000000000000000000000000000000000123","index":0}]],"id":null}`,
+			unmarshalled: &btcjson.DoubleSpendSeenNtfn{
+				TxID:         "456",
+				ConflictTxID: "123",
+				Inputs: []btcjson.OutPoint{{
+					Hash:  "0000000000000000000000000000000000000000000000000000000000000123",
+					Index: 0,
+				}},
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -8,3 +8,47 @@ package btcjson
 type SessionResult struct {
 	SessionID uint64 `json:"sessionid"`
 }
+
+// GetRescanStatusResult models the data from the getrescanstatus command.
+type GetRescanStatusResult struct {
+	ID          string `json:"id"`
+	State       string `json:"state"`
+	BeginHeight int32  `json:"beginheight"`
+	EndHeight   int32  `json:"endheight"`
+	Height      int32  `json:"height"`
+	Hash        string `json:"hash,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// TxFilterSubscription models the data of a transaction filter returned from
+// the getsubscriptions command.
+type TxFilterSubscription struct {
+	ID        string `json:"id"`
+	Addresses int    `json:"addresses"`
+	OutPoints int    `json:"outpoints"`
+}
+
+// ConfirmationsSubscription models a transaction registered with the
+// notifyconfirmations command as returned from the getsubscriptions command.
+type ConfirmationsSubscription struct {
+	TxID          string `json:"txid"`
+	Confirmations int32  `json:"confirmations"`
+}
+
+// GetSubscriptionsResult models the data from the getsubscriptions command.
+type GetSubscriptionsResult struct {
+	Blocks              bool                        `json:"blocks"`
+	BlockHeaders        bool                        `json:"blockheaders"`
+	BlockTemplate       bool                        `json:"blocktemplate"`
+	RawBlocks           bool                        `json:"rawblocks"`
+	ClockSkew           bool                        `json:"clockskew"`
+	PeerEvents          bool                        `json:"peerevents"`
+	Mempool             bool                        `json:"mempool"`
+	NewTransactions     bool                        `json:"newtransactions"`
+	VerboseTransactions bool                        `json:"verbosetransactions"`
+	Addresses           []string                    `json:"addresses"`
+	OutPoints           []OutPoint                  `json:"outpoints"`
+	ScriptHashes        []string                    `json:"scripthashes"`
+	Filters             []TxFilterSubscription      `json:"filters"`
+	Confirmations       []ConfirmationsSubscription `json:"confirmations"`
+}
@@ -459,6 +459,49 @@ func isValidResultType(kind reflect.Kind) bool {
 	return false
 }
 
+// validateResultTypes returns an error if any of the passed result types is
+// not a pointer to one of the acceptable types for results (or nil).
+func validateResultTypes(resultTypes []interface{}) error {
+	for i, resultType := range resultTypes {
+		if resultType == nil {
+			continue
+		}
+
+		rtp := reflect.TypeOf(resultType)
+		if rtp.Kind() != reflect.Ptr {
+			str := fmt.Sprintf("result #%d (%v) is not a pointer",
+				i, rtp.Kind())
+			return makeError(ErrInvalidType, str)
+		}
+
+		elemKind := rtp.Elem().Kind()
+		if !isValidResultType(elemKind) {
+			str := fmt.Sprintf("result #%d (%v) is not an allowed "+
+				"type", i, elemKind)
+			return makeError(ErrInvalidType, str)
+		}
+	}
+	return nil
+}
+
+// descLookup returns a description lookup function for the passed
+// descriptions map which falls back to the base help descriptions map for
+// unrecognized keys.  Keys which are not found in either are returned in place
+// of their description and recorded in missingKey.
+func descLookup(descs map[string]string, missingKey *string) descLookupFunc {
+	return func(key string) string {
+		if desc, ok := descs[key]; ok {
+			return desc
+		}
+		if desc, ok := baseHelpDescs[key]; ok {
+			return desc
+		}
+
+		*missingKey = key
+		return key
+	}
+}
+
 // GenerateHelp generates and returns help output for the provided method and
 // result types given a map to provide the appropriate keys for the method
 // synopsis, field descriptions, conditions, and result descriptions.  The
@@ -517,46 +560,281 @@ func GenerateHelp(method string, descs map[string]string, resultTypes ...interfa
 	}
 
 	// Validate each result type is a pointer to a supported type (or nil).
-	for i, resultType := range resultTypes {
-		if resultType == nil {
-			continue
+	if err := validateResultTypes(resultTypes); err != nil {
+		return "", err
+	}
+
+	// Create the description lookup function which falls back to the base
+	// help descriptions map for unrecognized keys and tracks any missing
+	// keys.
+	var missingKey string
+	xT := descLookup(descs, &missingKey)
+
+	// Generate and return the help for the method.
+	help := methodHelp(xT, rtp, info.defaults, method, resultTypes)
+	if missingKey != "" {
+		return help, makeError(ErrMissingDescription, missingKey)
+	}
+	return help, nil
+}
+
+// schemaTypeToJSONType returns a string that represents the JSON type
+// described by the provided type schema in the format used by the help output.
+func schemaTypeToJSONType(xT descLookupFunc, ts *TypeSchema) string {
+	switch ts.Type {
+	case "numeric":
+		return xT("json-type-numeric")
+
+	case "string":
+		return xT("json-type-string")
+
+	case "boolean":
+		return xT("json-type-bool")
+
+	case "array":
+		if ts.Items == nil {
+			return xT("json-type-array") + xT("json-type-value")
 		}
+		return xT("json-type-array") + schemaTypeToJSONType(xT, ts.Items)
 
-		rtp := reflect.TypeOf(resultType)
-		if rtp.Kind() != reflect.Ptr {
-			str := fmt.Sprintf("result #%d (%v) is not a pointer",
-				i, rtp.Kind())
-			return "", makeError(ErrInvalidType, str)
+	case "object":
+		return xT("json-type-object")
+	}
+
+	return xT("json-type-value")
+}
+
+// schemaFieldsHelp returns a slice of strings containing the help output for
+// the provided fields of an object.  It is the equivalent of resultStructHelp
+// for type schemas.
+func schemaFieldsHelp(xT descLookupFunc, fields []FieldSchema, indentLevel int) []string {
+	indent := strings.Repeat(" ", indentLevel)
+	results := make([]string, 0, len(fields))
+	for i := range fields {
+		field := &fields[i]
+		fieldType := schemaTypeToJSONType(xT, &field.TypeSchema)
+		fieldExamples, isComplex := schemaTypeToJSONExample(xT,
+			&field.TypeSchema, indentLevel)
+		if isComplex {
+			brace := "{"
+			if field.Type == "array" {
+				brace = "[{"
+			}
+			result := fmt.Sprintf("%s\"%s\": %s\t(%s)\t%s", indent,
+				field.Name, brace, fieldType, field.Description)
+			results = append(results, result)
+			results = append(results, fieldExamples...)
+		} else {
+			result := fmt.Sprintf("%s\"%s\": %s,\t(%s)\t%s", indent,
+				field.Name, fieldExamples[0], fieldType,
+				field.Description)
+			results = append(results, result)
 		}
+	}
 
-		elemKind := rtp.Elem().Kind()
-		if !isValidResultType(elemKind) {
-			str := fmt.Sprintf("result #%d (%v) is not an allowed "+
-				"type", i, elemKind)
-			return "", makeError(ErrInvalidType, str)
+	return results
+}
+
+// schemaTypeToJSONExample generates example usage in the format used by the
+// help output for the provided type schema.  It is the equivalent of
+// reflectTypeToJSONExample for type schemas.
+func schemaTypeToJSONExample(xT descLookupFunc, ts *TypeSchema, indentLevel int) ([]string, bool) {
+	switch ts.Type {
+	case "numeric":
+		if ts.Decimal {
+			return []string{"n.nnn"}, false
+		}
+		return []string{"n"}, false
+
+	case "string":
+		return []string{`"` + xT("json-example-string") + `"`}, false
+
+	case "boolean":
+		return []string{xT("json-example-bool")}, false
+
+	case "object":
+		indent := strings.Repeat(" ", indentLevel)
+
+		// Objects with arbitrary keys need to have the key, value, and
+		// description of their entries specifically called out.
+		if ts.Values != nil {
+			var entries EntrySchema
+			if ts.Entries != nil {
+				entries = *ts.Entries
+			}
+
+			results := make([]string, 0, 4)
+			if indentLevel == 0 {
+				results = append(results, indent+"{")
+			}
+			innerIndent := strings.Repeat(" ", indentLevel+1)
+			result := fmt.Sprintf("%s%q: %s, (%s) %s", innerIndent,
+				entries.Key, entries.Value,
+				schemaTypeToJSONType(xT, ts), entries.Description)
+			results = append(results, result)
+			results = append(results, innerIndent+"...")
+			results = append(results, indent+"}")
+			return results, true
+		}
+
+		results := schemaFieldsHelp(xT, ts.Fields, indentLevel+1)
+		if indentLevel == 0 {
+			newResults := make([]string, len(results)+1)
+			newResults[0] = "{"
+			copy(newResults[1:], results)
+			results = newResults
+		}
+		closingBrace := indent + "}"
+		if indentLevel > 0 {
+			closingBrace += ","
 		}
+		results = append(results, closingBrace+"\t\t")
+		return results, true
+
+	case "array":
+		if ts.Items == nil {
+			return []string{"[...]"}, false
+		}
+		results, isComplex := schemaTypeToJSONExample(xT, ts.Items,
+			indentLevel)
+		if isComplex {
+			indent := strings.Repeat(" ", indentLevel)
+			if indentLevel == 0 {
+				results[0] = indent + "[{"
+				results[len(results)-1] = indent + "},...]"
+				return results, true
+			}
+			results[len(results)-1] = indent + "},...],\t\t"
+			return results, true
+		}
+		return []string{fmt.Sprintf("[%s,...]", results[0])}, false
 	}
 
-	// Create a closure for the description lookup function which falls back
-	// to the base help descritptions map for unrecognized keys and tracks
-	// and missing keys.
-	var missingKey string
-	xT := func(key string) string {
-		if desc, ok := descs[key]; ok {
-			return desc
+	return []string{xT("json-example-unknown")}, false
+}
+
+// schemaTypeHelp generates and returns formatted help for the provided type
+// schema of a result or parameter with the passed description.
+func schemaTypeHelp(xT descLookupFunc, ts *TypeSchema, desc string) string {
+	results, isComplex := schemaTypeToJSONExample(xT, ts, 0)
+	if !isComplex {
+		return fmt.Sprintf("%s (%s) %s", results[0],
+			schemaTypeToJSONType(xT, ts), desc)
+	}
+
+	var formatted bytes.Buffer
+	w := new(tabwriter.Writer)
+	w.Init(&formatted, 0, 4, 1, ' ', 0)
+	for i, text := range results {
+		if i == len(results)-1 {
+			fmt.Fprint(w, text)
+		} else {
+			fmt.Fprintln(w, text)
 		}
-		if desc, ok := baseHelpDescs[key]; ok {
-			return desc
+	}
+	w.Flush()
+	return formatted.String()
+}
+
+// schemaArgHelp generates and returns formatted help for the parameters of the
+// provided method schema.
+func schemaArgHelp(xT descLookupFunc, schema *MethodSchema) string {
+	if len(schema.Params) == 0 {
+		return ""
+	}
+
+	args := make([]string, 0, len(schema.Params))
+	for i := range schema.Params {
+		param := &schema.Params[i]
+		details := []string{schemaTypeToJSONType(xT, &param.TypeSchema)}
+		if param.Optional {
+			details = append(details, xT("help-optional"))
+			if param.Default != nil {
+				val := param.Default
+				if str, ok := val.(string); ok {
+					val = fmt.Sprintf(`"%s"`, str)
+				}
+				details = append(details, fmt.Sprintf("%s=%v",
+					xT("help-default"), val))
+			}
+		} else {
+			details = append(details, xT("help-required"))
 		}
+		helpText := fmt.Sprintf("%d.\t%s\t(%s)\t%s", i+1, param.Name,
+			strings.Join(details, ", "), param.Description)
+		args = append(args, helpText)
 
-		missingKey = key
-		return key
+		// For parameters which require a JSON object, or an array of
+		// JSON objects, generate the full syntax for the argument.
+		objectType := &param.TypeSchema
+		if param.Type == "array" && param.Items != nil {
+			objectType = param.Items
+		}
+		if objectType.Type == "object" {
+			args = append(args, schemaTypeHelp(xT, &param.TypeSchema,
+				param.Description))
+		}
 	}
 
-	// Generate and return the help for the method.
-	help := methodHelp(xT, rtp, info.defaults, method, resultTypes)
-	if missingKey != "" {
-		return help, makeError(ErrMissingDescription, missingKey)
+	var formatted bytes.Buffer
+	w := new(tabwriter.Writer)
+	w.Init(&formatted, 0, 4, 1, ' ', 0)
+	for _, text := range args {
+		fmt.Fprintln(w, text)
 	}
-	return help, nil
+	w.Flush()
+	return formatted.String()
+}
+
+// GenerateSchemaHelp generates and returns help output for the method
+// described by the provided schema in the same format as GenerateHelp.  The
+// descriptions are taken from the schema, so a localized schema as returned by
+// LocalizeSchema produces localized help.  The labels map provides the help
+// labels, JSON types and examples, such as "help-arguments", in the language
+// of the schema.  The labels which are not in the map are shown in English.
+func GenerateSchemaHelp(schema *MethodSchema, labels map[string]string) string {
+	var missingKey string
+	xT := descLookup(labels, &missingKey)
+
+	// Start off with the method usage and help synopsis.
+	help := fmt.Sprintf("%s\n\n%s\n", schema.Usage, schema.Synopsis)
+
+	// Generate the help for each argument in the command.
+	if argText := schemaArgHelp(xT, schema); argText != "" {
+		help += fmt.Sprintf("\n%s:\n%s", xT("help-arguments"),
+			argText)
+	} else {
+		help += fmt.Sprintf("\n%s:\n%s\n", xT("help-arguments"),
+			xT("help-arguments-none"))
+	}
+
+	// Generate the help text for each result.
+	resultTexts := make([]string, 0, len(schema.Results))
+	for i := range schema.Results {
+		result := &schema.Results[i]
+		if result.Type == "null" {
+			resultTexts = append(resultTexts,
+				xT("help-result-nothing"))
+			continue
+		}
+		resultTexts = append(resultTexts, schemaTypeHelp(xT,
+			&result.TypeSchema, result.Description))
+	}
+
+	// Add result types and descriptions.  When there is more than one
+	// result, also add the condition which triggers it.
+	if len(resultTexts) > 1 {
+		for i, resultText := range resultTexts {
+			help += fmt.Sprintf("\n%s (%s):\n%s\n",
+				xT("help-result"), schema.Results[i].Condition,
+				resultText)
+		}
+	} else if len(resultTexts) > 0 {
+		help += fmt.Sprintf("\n%s:\n%s\n", xT("help-result"),
+			resultTexts[0])
+	} else {
+		help += fmt.Sprintf("\n%s:\n%s\n", xT("help-result"),
+			xT("help-result-nothing"))
+	}
+	return help
 }
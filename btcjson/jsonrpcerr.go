@@ -76,8 +76,30 @@ const (
 	ErrRPCDecodeHexString   RPCErrorCode = -22
 )
 
+// Errors returned when a transaction submitted via sendrawtransaction is
+// rejected.  Each rejection reason is assigned a distinct code so callers can
+// react appropriately without having to parse the error message.
+const (
+	ErrRPCTxMissingInputs       RPCErrorCode = -25
+	ErrRPCTxRejected            RPCErrorCode = -26
+	ErrRPCTxAlreadyKnown        RPCErrorCode = -27
+	ErrRPCTxInsufficientFee     RPCErrorCode = -60
+	ErrRPCTxTooLongMempoolChain RPCErrorCode = -61
+	ErrRPCTxHighFee             RPCErrorCode = -62
+)
+
 // Errors that are specific to btcd.
 const (
 	ErrRPCNoWallet      RPCErrorCode = -1
 	ErrRPCUnimplemented RPCErrorCode = -1
+
+	// ErrRPCRateLimited indicates the request was rejected because the
+	// client exceeded its request rate limit or too many expensive
+	// requests are in progress.  The request may be retried later.
+	ErrRPCRateLimited RPCErrorCode = -70
+
+	// ErrRPCWatchLimit indicates the request was rejected because the
+	// websocket client would watch more addresses or outpoints than
+	// allowed.
+	ErrRPCWatchLimit RPCErrorCode = -71
 )
@@ -0,0 +1,515 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypeSchema describes the JSON type of a method parameter, a method result or
+// a field of a JSON object.
+type TypeSchema struct {
+	// Type is the JSON type which is one of numeric, string, boolean,
+	// array, object, value (any type) or null (only for results).
+	Type string `json:"type"`
+
+	// Decimal is set for numbers which are not limited to integers.
+	Decimal bool `json:"decimal,omitempty"`
+
+	// Items describes the elements of an array.
+	Items *TypeSchema `json:"items,omitempty"`
+
+	// TypeName is the lowercase name of an object with a fixed set of
+	// fields.  The keys of the localized descriptions of the fields are of
+	// the form "<typename>-<fieldname>".
+	TypeName string `json:"typename,omitempty"`
+
+	// Fields describes the fields of an object with a fixed set of fields.
+	Fields []FieldSchema `json:"fields,omitempty"`
+
+	// Values describes the values of an object with arbitrary keys.
+	Values *TypeSchema `json:"values,omitempty"`
+
+	// Entries describes the entries of an object with arbitrary keys.
+	Entries *EntrySchema `json:"entries,omitempty"`
+}
+
+// EntrySchema describes the entries of a JSON object with arbitrary keys by
+// naming its keys and values.
+type EntrySchema struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+// FieldSchema describes a field of a JSON object.
+type FieldSchema struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Optional    bool   `json:"optional,omitempty"`
+	TypeSchema
+}
+
+// ParamSchema describes a positional parameter of a method.
+type ParamSchema struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Optional    bool        `json:"optional,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	TypeSchema
+}
+
+// ResultSchema describes one of the possible results of a method.  The
+// condition which triggers the result is only set when a method has more than
+// one possible result.
+type ResultSchema struct {
+	Condition   string `json:"condition,omitempty"`
+	Description string `json:"description,omitempty"`
+	TypeSchema
+}
+
+// MethodSchema is a machine-readable description of a registered method.  The
+// help of a method is generated from its schema by GenerateSchemaHelp and its
+// parameters are checked against the schema by ValidateParams.
+type MethodSchema struct {
+	Method       string         `json:"method"`
+	Synopsis     string         `json:"synopsis"`
+	Usage        string         `json:"usage"`
+	Websocket    bool           `json:"websocket,omitempty"`
+	Notification bool           `json:"notification,omitempty"`
+	Params       []ParamSchema  `json:"params"`
+	Results      []ResultSchema `json:"results,omitempty"`
+}
+
+// typeSchema returns the schema of the JSON type associated with the provided
+// Go type.  The fields of structs are described by the keys of the form
+// "<typename>-<fieldname>" like in the generated help, and the entries of maps
+// by the keys of the form "<descKey>--key", "<descKey>--value" and
+// "<descKey>--desc".  The parents are the struct types being described by the
+// callers, which is used to describe a recursive struct type as a plain object
+// instead of recursing forever.
+func typeSchema(xT descLookupFunc, rt reflect.Type, descKey string, parents map[reflect.Type]bool) TypeSchema {
+	// Indirect pointer if needed.
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	kind := rt.Kind()
+	if isNumeric(kind) {
+		decimal := kind == reflect.Float32 || kind == reflect.Float64
+		return TypeSchema{Type: "numeric", Decimal: decimal}
+	}
+
+	switch kind {
+	case reflect.String:
+		return TypeSchema{Type: "string"}
+
+	case reflect.Bool:
+		return TypeSchema{Type: "boolean"}
+
+	case reflect.Array, reflect.Slice:
+		items := typeSchema(xT, rt.Elem(), descKey, parents)
+		return TypeSchema{Type: "array", Items: &items}
+
+	case reflect.Struct:
+		if parents[rt] {
+			return TypeSchema{Type: "object"}
+		}
+		parents[rt] = true
+		fields := structSchema(xT, rt, parents)
+		delete(parents, rt)
+		return TypeSchema{
+			Type:     "object",
+			TypeName: strings.ToLower(rt.Name()),
+			Fields:   fields,
+		}
+
+	case reflect.Map:
+		values := typeSchema(xT, rt.Elem(), descKey, parents)
+		return TypeSchema{
+			Type:   "object",
+			Values: &values,
+			Entries: &EntrySchema{
+				Key:         xT(descKey + "--key"),
+				Value:       xT(descKey + "--value"),
+				Description: xT(descKey + "--desc"),
+			},
+		}
+	}
+
+	return TypeSchema{Type: "value"}
+}
+
+// structSchema returns the schemas of the fields of the provided struct type.
+// The field names are the json names when available and the lowercase field
+// names otherwise.  The fields of embedded structs without a json name are
+// included in place of the embedded struct, matching how they are marshalled.
+func structSchema(xT descLookupFunc, rt reflect.Type, parents map[reflect.Type]bool) []FieldSchema {
+	typeName := strings.ToLower(rt.Name())
+	numField := rt.NumField()
+	fields := make([]FieldSchema, 0, numField)
+	for i := 0; i < numField; i++ {
+		rtf := rt.Field(i)
+		tag := rtf.Tag.Get("json")
+		if rtf.Anonymous && tag == "" && rtf.Type.Kind() == reflect.Struct {
+			fields = append(fields, structSchema(xT, rtf.Type,
+				parents)...)
+			continue
+		}
+
+		fieldName := strings.ToLower(rtf.Name)
+		var optional bool
+		if tag != "" {
+			parts := strings.Split(tag, ",")
+			fieldName = parts[0]
+			for _, option := range parts[1:] {
+				optional = optional || option == "omitempty"
+			}
+		}
+
+		descKey := typeName + "-" + fieldName
+		fields = append(fields, FieldSchema{
+			Name:        fieldName,
+			Description: xT(descKey),
+			Optional:    optional,
+			TypeSchema:  typeSchema(xT, rtf.Type, descKey, parents),
+		})
+	}
+	return fields
+}
+
+// isComplexType returns whether the provided type is shown as a JSON object in
+// the generated help, in which case its fields are described instead of the
+// type itself.
+func isComplexType(rt reflect.Type) bool {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	switch rt.Kind() {
+	case reflect.Struct, reflect.Map:
+		return true
+	case reflect.Array, reflect.Slice:
+		return isComplexType(rt.Elem())
+	}
+	return false
+}
+
+// GenerateSchema generates and returns a machine-readable description of the
+// provided method and result types.  It requires the same description keys as
+// GenerateHelp and returns an error which includes the missing key when one of
+// them is missing.  The method must be associated with a registered type.
+//
+// Callers which declare their schemas directly can use the generated schema to
+// check that a declared schema matches the registered command and result types.
+func GenerateSchema(method string, descs map[string]string, resultTypes ...interface{}) (*MethodSchema, error) {
+	// Look up details about the provided method and error out if not
+	// registered.
+	registerLock.RLock()
+	rtp, ok := methodToConcreteType[method]
+	info := methodToInfo[method]
+	registerLock.RUnlock()
+	if !ok {
+		str := fmt.Sprintf("%q is not registered", method)
+		return nil, makeError(ErrUnregisteredMethod, str)
+	}
+
+	if err := validateResultTypes(resultTypes); err != nil {
+		return nil, err
+	}
+
+	var missingKey string
+	xT := descLookup(descs, &missingKey)
+	parents := make(map[reflect.Type]bool)
+
+	schema := &MethodSchema{
+		Method:       method,
+		Synopsis:     xT(method + "--synopsis"),
+		Usage:        methodUsageText(rtp, info.defaults, method),
+		Websocket:    info.flags&UFWebsocketOnly != 0,
+		Notification: info.flags&UFNotification != 0,
+	}
+
+	// Describe each parameter.  Optional parameters are pointers and only
+	// they can have default values due to the rules enforced by
+	// RegisterCmd.
+	rt := rtp.Elem()
+	schema.Params = make([]ParamSchema, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		rtf := rt.Field(i)
+		descKey := method + "-" + strings.ToLower(rtf.Name)
+		param := ParamSchema{
+			Name:        strings.ToLower(rtf.Name),
+			Description: xT(descKey),
+			Optional:    rtf.Type.Kind() == reflect.Ptr,
+			TypeSchema:  typeSchema(xT, rtf.Type, descKey, parents),
+		}
+		if defaultVal, ok := info.defaults[i]; ok {
+			param.Default = defaultVal.Elem().Interface()
+		}
+		schema.Params = append(schema.Params, param)
+	}
+
+	// Describe each result.  Only results which are not objects have a
+	// description of their own and the conditions are only needed when
+	// there is more than one result.
+	for i, resultType := range resultTypes {
+		var result ResultSchema
+		if resultType == nil {
+			result.Type = "null"
+		} else {
+			descKey := fmt.Sprintf("%s--result%d", method, i)
+			rt := reflect.TypeOf(resultType).Elem()
+			result.TypeSchema = typeSchema(xT, rt, descKey, parents)
+			if !isComplexType(rt) {
+				result.Description = xT(descKey)
+			}
+		}
+		if len(resultTypes) > 1 {
+			key := fmt.Sprintf("%s--condition%d", method, i)
+			result.Condition = xT(key)
+		}
+		schema.Results = append(schema.Results, result)
+	}
+
+	if missingKey != "" {
+		return schema, makeError(ErrMissingDescription, missingKey)
+	}
+	return schema, nil
+}
+
+// localizeType returns a copy of the provided type schema with the
+// descriptions of the fields and entries replaced by the ones looked up with
+// the passed function.  The descKey is the key of the description of the
+// value of the type, which is used for the keys of map entries.
+func localizeType(xT func(key, desc string) string, ts TypeSchema, descKey string) TypeSchema {
+	if ts.Items != nil {
+		items := localizeType(xT, *ts.Items, descKey)
+		ts.Items = &items
+	}
+	if ts.Fields != nil {
+		fields := make([]FieldSchema, len(ts.Fields))
+		for i, field := range ts.Fields {
+			fieldKey := ts.TypeName + "-" + field.Name
+			field.Description = xT(fieldKey, field.Description)
+			field.TypeSchema = localizeType(xT, field.TypeSchema,
+				fieldKey)
+			fields[i] = field
+		}
+		ts.Fields = fields
+	}
+	if ts.Values != nil {
+		values := localizeType(xT, *ts.Values, descKey)
+		ts.Values = &values
+	}
+	if ts.Entries != nil {
+		entries := EntrySchema{
+			Key:   xT(descKey+"--key", ts.Entries.Key),
+			Value: xT(descKey+"--value", ts.Entries.Value),
+			Description: xT(descKey+"--desc",
+				ts.Entries.Description),
+		}
+		ts.Entries = &entries
+	}
+	return ts
+}
+
+// LocalizeSchema returns a copy of the provided method schema with its
+// descriptions replaced by the ones in the passed descriptions map.  The map
+// uses the same keys as the descriptions map passed to GenerateHelp, and the
+// descriptions which are not in the map are kept as they are.  This allows
+// the descriptions of a schema to be translated by only providing the
+// descriptions which differ.
+func LocalizeSchema(schema *MethodSchema, descs map[string]string) *MethodSchema {
+	xT := func(key, desc string) string {
+		if localized, ok := descs[key]; ok {
+			return localized
+		}
+		return desc
+	}
+
+	localized := *schema
+	localized.Synopsis = xT(schema.Method+"--synopsis", schema.Synopsis)
+	localized.Params = make([]ParamSchema, len(schema.Params))
+	for i, param := range schema.Params {
+		descKey := schema.Method + "-" + param.Name
+		param.Description = xT(descKey, param.Description)
+		param.TypeSchema = localizeType(xT, param.TypeSchema, descKey)
+		localized.Params[i] = param
+	}
+	localized.Results = make([]ResultSchema, len(schema.Results))
+	for i, result := range schema.Results {
+		descKey := fmt.Sprintf("%s--result%d", schema.Method, i)
+		if result.Description != "" {
+			result.Description = xT(descKey, result.Description)
+		}
+		if result.Condition != "" {
+			condKey := fmt.Sprintf("%s--condition%d", schema.Method, i)
+			result.Condition = xT(condKey, result.Condition)
+		}
+		result.TypeSchema = localizeType(xT, result.TypeSchema, descKey)
+		localized.Results[i] = result
+	}
+	return &localized
+}
+
+// jsonValueType returns the JSON type of the passed value as decoded by a
+// json.Decoder which uses json.Number for numbers.
+func jsonValueType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case json.Number:
+		return "numeric"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// validateValue returns an error when the passed decoded JSON value does not
+// conform to the provided type schema.  The name identifies the value in the
+// error and includes the path to it for the elements of arrays and the fields
+// and entries of objects.
+func validateValue(ts *TypeSchema, value interface{}, name string, paramNum int) error {
+	if ts.Type == "value" {
+		return nil
+	}
+	gotType := jsonValueType(value)
+	if gotType != ts.Type {
+		str := fmt.Sprintf("parameter #%d '%s' must be type %s (got "+
+			"%s)", paramNum, name, ts.Type, gotType)
+		return makeError(ErrInvalidType, str)
+	}
+
+	switch value := value.(type) {
+	case json.Number:
+		if ts.Decimal {
+			return nil
+		}
+		if _, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+			return nil
+		}
+		if _, err := strconv.ParseUint(value.String(), 10, 64); err == nil {
+			return nil
+		}
+		str := fmt.Sprintf("parameter #%d '%s' must be an integer "+
+			"(got %s)", paramNum, name, value)
+		return makeError(ErrInvalidType, str)
+
+	case []interface{}:
+		if ts.Items == nil {
+			return nil
+		}
+		for i, elem := range value {
+			elemName := fmt.Sprintf("%s[%d]", name, i)
+			err := validateValue(ts.Items, elem, elemName, paramNum)
+			if err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		if ts.Values != nil {
+			for key, elem := range value {
+				elemName := fmt.Sprintf("%s[%q]", name, key)
+				err := validateValue(ts.Values, elem, elemName,
+					paramNum)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// The fields are matched case insensitively like they are when
+		// the object is unmarshalled into its struct.  Unknown fields
+		// are ignored for the same reason.
+		for i := range ts.Fields {
+			field := &ts.Fields[i]
+			fieldName := name + "." + field.Name
+			var fieldValue interface{}
+			var found bool
+			for key, elem := range value {
+				if strings.EqualFold(key, field.Name) {
+					fieldValue, found = elem, true
+					break
+				}
+			}
+			if !found || fieldValue == nil {
+				if field.Optional {
+					continue
+				}
+				str := fmt.Sprintf("parameter #%d '%s' is "+
+					"required", paramNum, fieldName)
+				return makeError(ErrInvalidType, str)
+			}
+			err := validateValue(&field.TypeSchema, fieldValue,
+				fieldName, paramNum)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateParams returns an error when the passed parameters of a call of the
+// method described by the provided schema do not conform to the schema.  The
+// number of parameters must be within the number of required parameters and
+// the total number of parameters, and each parameter must be of the type of
+// its schema, recursively including the elements of arrays and the fields of
+// objects.  Optional parameters may be null to use their default value.
+//
+// The errors use the same error codes and messages as UnmarshalCmd so callers
+// can validate the parameters of every method the same way before unmarshalling
+// them into a command.
+func ValidateParams(schema *MethodSchema, params []json.RawMessage) error {
+	var numReqParams int
+	for i := range schema.Params {
+		if !schema.Params[i].Optional {
+			numReqParams++
+		}
+	}
+	maxParams := len(schema.Params)
+	err := checkNumParams(len(params), &methodInfo{
+		numReqParams: numReqParams,
+		maxParams:    maxParams,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, param := range params {
+		paramSchema := &schema.Params[i]
+
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(param))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			str := fmt.Sprintf("parameter #%d '%s' failed to "+
+				"unmarshal: %v", i+1, paramSchema.Name, err)
+			return makeError(ErrInvalidType, str)
+		}
+		if value == nil && paramSchema.Optional {
+			continue
+		}
+
+		err := validateValue(&paramSchema.TypeSchema, value,
+			paramSchema.Name, i+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
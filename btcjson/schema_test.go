@@ -0,0 +1,505 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcjson_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// schemaTestResult is a result type used to test the generated schemas of
+// object results.
+type schemaTestResult struct {
+	Hash  string            `json:"hash"`
+	Sizes []int32           `json:"sizes,omitempty"`
+	Extra map[string]string `json:"extra"`
+}
+
+// schemaTestEmbedded is embedded into schemaTestNode to test the generated
+// schemas of embedded structs.
+type schemaTestEmbedded struct {
+	Name string `json:"name"`
+}
+
+// schemaTestNode is a recursive result type used to test the generated
+// schemas of recursive types.
+type schemaTestNode struct {
+	schemaTestEmbedded
+	Children []schemaTestNode `json:"children,omitempty"`
+}
+
+// TestGenerateSchema ensures the schema generated for a registered method
+// describes its parameters and results.
+func TestGenerateSchema(t *testing.T) {
+	t.Parallel()
+
+	descs := map[string]string{
+		"getblock--synopsis":     "synopsis",
+		"getblock-hash":          "hash",
+		"getblock-verbose":       "verbose",
+		"getblock-verbosetx":     "verbosetx",
+		"getblock--condition0":   "verbose=false",
+		"getblock--condition1":   "verbose=true",
+		"getblock--result0":      "hex",
+		"schematestresult-hash":  "result hash",
+		"schematestresult-sizes": "result sizes",
+		"schematestresult-extra": "result extra",
+
+		"schematestresult-extra--key":   "name",
+		"schematestresult-extra--value": "value",
+		"schematestresult-extra--desc":  "extra entry",
+	}
+	schema, err := btcjson.GenerateSchema("getblock", descs,
+		(*string)(nil), (*schemaTestResult)(nil))
+	if err != nil {
+		t.Fatalf("GenerateSchema: unexpected error: %v", err)
+	}
+
+	want := &btcjson.MethodSchema{
+		Method:   "getblock",
+		Synopsis: "synopsis",
+		Usage:    `getblock "hash" (verbose=true verbosetx=false)`,
+		Params: []btcjson.ParamSchema{
+			{
+				Name:        "hash",
+				Description: "hash",
+				TypeSchema:  btcjson.TypeSchema{Type: "string"},
+			},
+			{
+				Name:        "verbose",
+				Description: "verbose",
+				Optional:    true,
+				Default:     true,
+				TypeSchema:  btcjson.TypeSchema{Type: "boolean"},
+			},
+			{
+				Name:        "verbosetx",
+				Description: "verbosetx",
+				Optional:    true,
+				Default:     false,
+				TypeSchema:  btcjson.TypeSchema{Type: "boolean"},
+			},
+		},
+		Results: []btcjson.ResultSchema{
+			{
+				Condition:   "verbose=false",
+				Description: "hex",
+				TypeSchema:  btcjson.TypeSchema{Type: "string"},
+			},
+			{
+				Condition: "verbose=true",
+				TypeSchema: btcjson.TypeSchema{
+					Type:     "object",
+					TypeName: "schematestresult",
+					Fields: []btcjson.FieldSchema{
+						{
+							Name:        "hash",
+							Description: "result hash",
+							TypeSchema: btcjson.TypeSchema{
+								Type: "string",
+							},
+						},
+						{
+							Name:        "sizes",
+							Description: "result sizes",
+							Optional:    true,
+							TypeSchema: btcjson.TypeSchema{
+								Type: "array",
+								Items: &btcjson.TypeSchema{
+									Type: "numeric",
+								},
+							},
+						},
+						{
+							Name:        "extra",
+							Description: "result extra",
+							TypeSchema: btcjson.TypeSchema{
+								Type: "object",
+								Values: &btcjson.TypeSchema{
+									Type: "string",
+								},
+								Entries: &btcjson.EntrySchema{
+									Key:         "name",
+									Value:       "value",
+									Description: "extra entry",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		t.Fatalf("GenerateSchema: unexpected schema - got %v\nwant %v",
+			spew.Sdump(schema), spew.Sdump(want))
+	}
+}
+
+// TestGenerateSchemaNested ensures the fields of embedded structs are described
+// in place and recursive types don't recurse forever.
+func TestGenerateSchemaNested(t *testing.T) {
+	t.Parallel()
+
+	descs := map[string]string{
+		"getbestblock--synopsis":  "synopsis",
+		"schematestembedded-name": "node name",
+		"schematestnode-children": "node children",
+	}
+	schema, err := btcjson.GenerateSchema("getbestblock", descs,
+		(*schemaTestNode)(nil))
+	if err != nil {
+		t.Fatalf("GenerateSchema: unexpected error: %v", err)
+	}
+
+	want := []btcjson.ResultSchema{
+		{
+			TypeSchema: btcjson.TypeSchema{
+				Type:     "object",
+				TypeName: "schematestnode",
+				Fields: []btcjson.FieldSchema{
+					{
+						Name:        "name",
+						Description: "node name",
+						TypeSchema: btcjson.TypeSchema{
+							Type: "string",
+						},
+					},
+					{
+						Name:        "children",
+						Description: "node children",
+						Optional:    true,
+						TypeSchema: btcjson.TypeSchema{
+							Type: "array",
+							Items: &btcjson.TypeSchema{
+								Type: "object",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(schema.Results, want) {
+		t.Fatalf("GenerateSchema: unexpected results - got %v\nwant %v",
+			spew.Sdump(schema.Results), spew.Sdump(want))
+	}
+}
+
+// TestGenerateSchemaErrors ensures GenerateSchema returns the expected errors.
+func TestGenerateSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		method      string
+		resultTypes []interface{}
+		err         btcjson.Error
+	}{
+		{
+			name:   "unregistered command",
+			method: "boguscommand",
+			err:    btcjson.Error{ErrorCode: btcjson.ErrUnregisteredMethod},
+		},
+		{
+			name:        "non-pointer result type",
+			method:      "help",
+			resultTypes: []interface{}{0},
+			err:         btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:        "missing description",
+			method:      "help",
+			resultTypes: []interface{}{(*string)(nil), nil},
+			err:         btcjson.Error{ErrorCode: btcjson.ErrMissingDescription},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		_, err := btcjson.GenerateSchema(test.method, nil,
+			test.resultTypes...)
+		gotErr, ok := err.(btcjson.Error)
+		if !ok || gotErr.ErrorCode != test.err.ErrorCode {
+			t.Errorf("Test #%d (%s) wrong error - got %v, want "+
+				"%v", i, test.name, err, test.err.ErrorCode)
+		}
+	}
+}
+
+// TestGenerateSchemaHelp ensures the help generated from a schema matches the
+// help generated by GenerateHelp from the same descriptions and result types.
+func TestGenerateSchemaHelp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		method      string
+		descs       map[string]string
+		resultTypes []interface{}
+	}{
+		{
+			name:   "object and primitive results",
+			method: "getblock",
+			descs: map[string]string{
+				"getblock--synopsis":     "synopsis",
+				"getblock-hash":          "hash",
+				"getblock-verbose":       "verbose",
+				"getblock-verbosetx":     "verbosetx",
+				"getblock--condition0":   "verbose=false",
+				"getblock--condition1":   "verbose=true",
+				"getblock--result0":      "hex",
+				"schematestresult-hash":  "result hash",
+				"schematestresult-sizes": "result sizes",
+				"schematestresult-extra": "result extra",
+
+				"schematestresult-extra--key":   "name",
+				"schematestresult-extra--value": "value",
+				"schematestresult-extra--desc":  "extra entry",
+			},
+			resultTypes: []interface{}{(*string)(nil),
+				(*schemaTestResult)(nil)},
+		},
+		{
+			name:   "object parameters",
+			method: "createrawtransaction",
+			descs: map[string]string{
+				"createrawtransaction--synopsis":      "synopsis",
+				"createrawtransaction-inputs":         "inputs",
+				"createrawtransaction-amounts":        "amounts",
+				"createrawtransaction-amounts--key":   "address",
+				"createrawtransaction-amounts--value": "n.nnn",
+				"createrawtransaction-amounts--desc":  "amount",
+				"createrawtransaction-locktime":       "locktime",
+				"createrawtransaction--result0":       "hex",
+				"transactioninput-txid":               "txid",
+				"transactioninput-vout":               "vout",
+			},
+			resultTypes: []interface{}{(*string)(nil)},
+		},
+		{
+			name:   "no parameters or results",
+			method: "ping",
+			descs: map[string]string{
+				"ping--synopsis": "synopsis",
+			},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		want, err := btcjson.GenerateHelp(test.method, test.descs,
+			test.resultTypes...)
+		if err != nil {
+			t.Errorf("Test #%d (%s) GenerateHelp: unexpected error: %v",
+				i, test.name, err)
+			continue
+		}
+		schema, err := btcjson.GenerateSchema(test.method, test.descs,
+			test.resultTypes...)
+		if err != nil {
+			t.Errorf("Test #%d (%s) GenerateSchema: unexpected "+
+				"error: %v", i, test.name, err)
+			continue
+		}
+		help := btcjson.GenerateSchemaHelp(schema, nil)
+		if help != want {
+			t.Errorf("Test #%d (%s) mismatched help - got:\n%s\nwant:"+
+				"\n%s", i, test.name, help, want)
+		}
+	}
+}
+
+// TestLocalizeSchema ensures localizing a schema replaces the descriptions
+// with keys in the passed map, keeps the others and leaves the original schema
+// unchanged.
+func TestLocalizeSchema(t *testing.T) {
+	t.Parallel()
+
+	descs := map[string]string{
+		"getblock--synopsis":     "synopsis",
+		"getblock-hash":          "hash",
+		"getblock-verbose":       "verbose",
+		"getblock-verbosetx":     "verbosetx",
+		"getblock--condition0":   "verbose=false",
+		"getblock--condition1":   "verbose=true",
+		"getblock--result0":      "hex",
+		"schematestresult-hash":  "result hash",
+		"schematestresult-sizes": "result sizes",
+		"schematestresult-extra": "result extra",
+
+		"schematestresult-extra--key":   "name",
+		"schematestresult-extra--value": "value",
+		"schematestresult-extra--desc":  "extra entry",
+	}
+	schema, err := btcjson.GenerateSchema("getblock", descs,
+		(*string)(nil), (*schemaTestResult)(nil))
+	if err != nil {
+		t.Fatalf("GenerateSchema: unexpected error: %v", err)
+	}
+	original := spew.Sdump(schema)
+
+	localized := btcjson.LocalizeSchema(schema, map[string]string{
+		"getblock--synopsis":           "localized synopsis",
+		"getblock-verbose":             "localized verbose",
+		"getblock--condition1":         "localized condition",
+		"getblock--result0":            "localized hex",
+		"schematestresult-hash":        "localized hash",
+		"schematestresult-extra--desc": "localized entry",
+	})
+	if got := spew.Sdump(schema); got != original {
+		t.Fatalf("LocalizeSchema: original schema changed - got %v\n"+
+			"want %v", got, original)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"synopsis", localized.Synopsis, "localized synopsis"},
+		{"hash", localized.Params[0].Description, "hash"},
+		{"verbose", localized.Params[1].Description, "localized verbose"},
+		{"condition0", localized.Results[0].Condition, "verbose=false"},
+		{"condition1", localized.Results[1].Condition,
+			"localized condition"},
+		{"result0", localized.Results[0].Description, "localized hex"},
+		{"field hash", localized.Results[1].Fields[0].Description,
+			"localized hash"},
+		{"field sizes", localized.Results[1].Fields[1].Description,
+			"result sizes"},
+		{"entry key", localized.Results[1].Fields[2].Entries.Key,
+			"name"},
+		{"entry desc",
+			localized.Results[1].Fields[2].Entries.Description,
+			"localized entry"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("LocalizeSchema: %s: got %q, want %q", test.name,
+				test.got, test.want)
+		}
+	}
+}
+
+// TestValidateParams ensures the parameters of a method are validated against
+// its schema.
+func TestValidateParams(t *testing.T) {
+	t.Parallel()
+
+	descs := map[string]string{
+		"createrawtransaction--synopsis":      "synopsis",
+		"createrawtransaction-inputs":         "inputs",
+		"createrawtransaction-amounts":        "amounts",
+		"createrawtransaction-amounts--key":   "address",
+		"createrawtransaction-amounts--value": "n.nnn",
+		"createrawtransaction-amounts--desc":  "amount",
+		"createrawtransaction-locktime":       "locktime",
+		"createrawtransaction--result0":       "hex",
+		"transactioninput-txid":               "txid",
+		"transactioninput-vout":               "vout",
+	}
+	schema, err := btcjson.GenerateSchema("createrawtransaction", descs,
+		(*string)(nil))
+	if err != nil {
+		t.Fatalf("GenerateSchema: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		params []string
+		err    error
+	}{
+		{
+			name:   "required params",
+			params: []string{`[{"txid":"123","vout":1}]`, `{"addr":1.5}`},
+		},
+		{
+			name: "all params",
+			params: []string{`[{"TxID":"123","vout":1,"extra":1}]`,
+				`{"addr":1.5,"data":"00"}`, `100`},
+		},
+		{
+			name:   "null optional param",
+			params: []string{`[]`, `{}`, `null`},
+		},
+		{
+			name:   "too few params",
+			params: []string{`[]`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrNumParams},
+		},
+		{
+			name:   "too many params",
+			params: []string{`[]`, `{}`, `1`, `2`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrNumParams},
+		},
+		{
+			name:   "invalid JSON",
+			params: []string{`[`, `{}`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:   "null required param",
+			params: []string{`null`, `{}`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:   "wrong param type",
+			params: []string{`{}`, `{}`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:   "wrong element type",
+			params: []string{`["123"]`, `{}`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:   "missing field",
+			params: []string{`[{"txid":"123"}]`, `{}`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:   "wrong field type",
+			params: []string{`[{"txid":"123","vout":"1"}]`, `{}`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:   "fractional integer",
+			params: []string{`[{"txid":"123","vout":1.5}]`, `{}`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+		{
+			name:   "fractional optional integer",
+			params: []string{`[]`, `{}`, `1.5`},
+			err:    btcjson.Error{ErrorCode: btcjson.ErrInvalidType},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		params := make([]json.RawMessage, 0, len(test.params))
+		for _, param := range test.params {
+			params = append(params, json.RawMessage(param))
+		}
+		err := btcjson.ValidateParams(schema, params)
+		if test.err == nil {
+			if err != nil {
+				t.Errorf("Test #%d (%s) unexpected error: %v", i,
+					test.name, err)
+			}
+			continue
+		}
+		gotErr, ok := err.(btcjson.Error)
+		wantErr := test.err.(btcjson.Error)
+		if !ok || gotErr.ErrorCode != wantErr.ErrorCode {
+			t.Errorf("Test #%d (%s) wrong error - got %v, want %v", i,
+				test.name, err, wantErr.ErrorCode)
+		}
+	}
+}
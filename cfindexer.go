@@ -0,0 +1,144 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/gcs"
+	"github.com/conseweb/stcd/wire"
+)
+
+// cfIndexer maintains the committed filter index which holds the basic
+// filter (BIP0158) and filter header for each block in the main chain.
+//
+// Filters are stored by block hash, so disconnecting a block only requires
+// rewinding the index tip, and filters which were built for blocks on a side
+// chain remain valid should the chain become the main chain again.
+type cfIndexer struct {
+	db database.Db
+}
+
+// Ensure the cfIndexer type implements the Indexer interface.
+var _ Indexer = (*cfIndexer)(nil)
+
+// newCFIndexer creates a new committed filter indexer to be added to the index
+// manager.
+func newCFIndexer() *cfIndexer {
+	return &cfIndexer{}
+}
+
+// Name returns the name of the index.  It is part of the Indexer interface.
+func (c *cfIndexer) Name() string {
+	return "committed filter"
+}
+
+// Init sets the database holding the index.  It is part of the Indexer
+// interface.
+func (c *cfIndexer) Init(db database.Db) error {
+	c.db = db
+	return nil
+}
+
+// Tip returns the hash and height of the most recent block with a filter in
+// the index.  It is part of the Indexer interface.
+func (c *cfIndexer) Tip() (*wire.ShaHash, int32, error) {
+	sha, height, err := c.db.FetchCFIndexTip()
+	if err == database.ErrCFIndexDoesNotExist {
+		err = nil
+	}
+	return sha, height, err
+}
+
+// ConnectBlock builds the filter and filter header of the passed block and
+// makes it the tip of the index.  It is part of the Indexer interface.
+func (c *cfIndexer) ConnectBlock(block *coinutil.Block) error {
+	var prevHeader wire.ShaHash
+	if block.Height() > 0 {
+		_, header, err := c.db.FetchCFilterBySha(
+			&block.MsgBlock().Header.PrevBlock)
+		if err != nil {
+			return err
+		}
+		prevHeader = *header
+	}
+
+	filter, err := c.buildBasicFilter(block)
+	if err != nil {
+		return err
+	}
+	header := gcs.MakeHeaderForFilter(filter, &prevHeader)
+	return c.db.UpdateCFIndexForBlock(block.Sha(), block.Height(), filter,
+		&header)
+}
+
+// DisconnectBlock rewinds the tip of the index to the most recent main chain
+// block below the passed height which has a filter.  The filters themselves
+// are kept.  The index is deleted when no such block is left so it is built
+// again.  It is part of the Indexer interface.
+func (c *cfIndexer) DisconnectBlock(sha *wire.ShaHash, height int32) error {
+	_, bestHeight, err := c.db.NewestSha()
+	if err != nil {
+		return err
+	}
+	if height > bestHeight+1 {
+		height = bestHeight + 1
+	}
+	for height--; height >= 0; height-- {
+		sha, err := c.db.FetchBlockShaByHeight(height)
+		if err != nil {
+			return err
+		}
+		filter, header, err := c.db.FetchCFilterBySha(sha)
+		if err == database.ErrCFilterMissing {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return c.db.UpdateCFIndexForBlock(sha, height, filter, header)
+	}
+	return c.db.DeleteCFIndex()
+}
+
+// buildBasicFilter returns the serialized basic filter for the passed block.
+func (c *cfIndexer) buildBasicFilter(block *coinutil.Block) ([]byte, error) {
+	var prevScripts [][]byte
+	for _, tx := range block.Transactions() {
+		// Coinbases don't have any inputs.
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+
+		// Lookup the script of each input's previous output.
+		for _, txIn := range tx.MsgTx().TxIn {
+			prevOut := txIn.PreviousOutPoint
+			txList, err := c.db.FetchTxBySha(&prevOut.Hash)
+			if err != nil {
+				return nil, err
+			}
+			if len(txList) == 0 {
+				return nil, fmt.Errorf("transaction %v not found",
+					prevOut.Hash)
+			}
+			prevOutTx := txList[len(txList)-1].Tx
+			if prevOut.Index >= uint32(len(prevOutTx.TxOut)) {
+				return nil, fmt.Errorf("output %v does not exist",
+					prevOut)
+			}
+			prevScripts = append(prevScripts,
+				prevOutTx.TxOut[prevOut.Index].PkScript)
+		}
+	}
+
+	filter, err := gcs.BuildBasicFilter(block.MsgBlock(), prevScripts)
+	if err != nil {
+		return nil, err
+	}
+	return filter.NBytes(), nil
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaincfg_test
+
+import (
+	"testing"
+
+	. "github.com/conseweb/stcd/chaincfg"
+)
+
+// TestAssumeValidCheckpoint ensures the networks with checkpoints assume the
+// blocks up to their newest checkpoint valid, so the assumed-valid block is not
+// forgotten when checkpoints are added.
+func TestAssumeValidCheckpoint(t *testing.T) {
+	for _, params := range []*Params{&MainNetParams, &TestNet3Params} {
+		checkpoints := params.Checkpoints
+		newest := checkpoints[len(checkpoints)-1].Hash
+		if params.AssumeValid == nil || !params.AssumeValid.IsEqual(newest) {
+			t.Errorf("%s: assumed-valid block %v is not the newest "+
+				"checkpoint %v", params.Name, params.AssumeValid,
+				newest)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaincfg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// jsonCheckpoint is the JSON representation of a Checkpoint.
+type jsonCheckpoint struct {
+	Height int32  `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// jsonDeployment is the JSON representation of a ConsensusDeployment.
+type jsonDeployment struct {
+	BitNumber  uint8  `json:"bit"`
+	StartTime  uint64 `json:"starttime"`
+	ExpireTime uint64 `json:"expiretime"`
+}
+
+// jsonParams is the JSON representation of the parameters of a custom
+// network.  Byte strings, such as the genesis block and the proof of work
+// limit, are hex encoded.  Deployments are keyed by the names in
+// DeploymentNames and any deployment which is not listed never activates.
+type jsonParams struct {
+	Name                          string                    `json:"name"`
+	Net                           uint32                    `json:"net"`
+	DefaultPort                   string                    `json:"defaultport"`
+	DNSSeeds                      []string                  `json:"dnsseeds"`
+	GenesisBlock                  string                    `json:"genesisblock"`
+	GenesisHash                   string                    `json:"genesishash"`
+	PowLimit                      string                    `json:"powlimit"`
+	PowLimitBits                  uint32                    `json:"powlimitbits"`
+	SubsidyHalvingInterval        int32                     `json:"subsidyhalvinginterval"`
+	BaseSubsidy                   *int64                    `json:"basesubsidy"`
+	TailEmission                  int64                     `json:"tailemission"`
+	ResetMinDifficulty            bool                      `json:"resetmindifficulty"`
+	GenerateSupported             bool                      `json:"generatesupported"`
+	Checkpoints                   []jsonCheckpoint          `json:"checkpoints"`
+	AssumeValid                   string                    `json:"assumevalid"`
+	ProofOfStakeHeight            int32                     `json:"proofofstakeheight"`
+	BlockEnforceNumRequired       uint64                    `json:"blockenforcenumrequired"`
+	BlockRejectNumRequired        uint64                    `json:"blockrejectnumrequired"`
+	BlockUpgradeNumToCheck        uint64                    `json:"blockupgradenumtocheck"`
+	RuleChangeActivationThreshold uint32                    `json:"rulechangeactivationthreshold"`
+	MinerConfirmationWindow       uint32                    `json:"minerconfirmationwindow"`
+	Deployments                   map[string]jsonDeployment `json:"deployments"`
+	RelayNonStdTxs                bool                      `json:"relaynonstdtxs"`
+	PubKeyHashAddrID              byte                      `json:"pubkeyhashaddrid"`
+	ScriptHashAddrID              byte                      `json:"scripthashaddrid"`
+	PrivateKeyID                  byte                      `json:"privatekeyid"`
+	Bech32HRPSegwit               string                    `json:"bech32hrpsegwit"`
+	PaymentURIScheme              string                    `json:"paymenturischeme"`
+	HDPrivateKeyID                string                    `json:"hdprivatekeyid"`
+	HDPublicKeyID                 string                    `json:"hdpublickeyid"`
+	HDCoinType                    uint32                    `json:"hdcointype"`
+}
+
+// decodeHDKeyID decodes the passed hex encoded HD key version bytes.
+func decodeHDKeyID(field, s string) ([4]byte, error) {
+	var id [4]byte
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(id) {
+		return id, fmt.Errorf("%s must be %d hex encoded bytes", field,
+			len(id))
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// DecodeParams decodes the JSON definition of a custom network read from the
+// passed reader into network parameters.  The genesis hash is calculated from
+// the genesis block, and when the definition also includes the hash, it must
+// match.
+//
+// The returned parameters are not registered.  Callers which use them must
+// call Register before any addresses or keys for the network are decoded.
+func DecodeParams(r io.Reader) (*Params, error) {
+	var jp jsonParams
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&jp); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case jp.Name == "":
+		return nil, fmt.Errorf("name is required")
+	case jp.Net == 0:
+		return nil, fmt.Errorf("net is required")
+	case jp.DefaultPort == "":
+		return nil, fmt.Errorf("defaultport is required")
+	case jp.GenesisBlock == "":
+		return nil, fmt.Errorf("genesisblock is required")
+	case jp.PowLimit == "" || jp.PowLimitBits == 0:
+		return nil, fmt.Errorf("powlimit and powlimitbits are required")
+	case jp.ProofOfStakeHeight < 0:
+		return nil, fmt.Errorf("proofofstakeheight must not be negative")
+	case jp.MinerConfirmationWindow == 0:
+		return nil, fmt.Errorf("minerconfirmationwindow is required")
+	case jp.RuleChangeActivationThreshold == 0 ||
+		jp.RuleChangeActivationThreshold > jp.MinerConfirmationWindow:
+		return nil, fmt.Errorf("rulechangeactivationthreshold must be " +
+			"between 1 and minerconfirmationwindow")
+	case jp.BlockEnforceNumRequired > jp.BlockUpgradeNumToCheck ||
+		jp.BlockRejectNumRequired > jp.BlockUpgradeNumToCheck:
+		return nil, fmt.Errorf("blockenforcenumrequired and " +
+			"blockrejectnumrequired must not exceed " +
+			"blockupgradenumtocheck")
+	}
+
+	params := Params{
+		Name:                          jp.Name,
+		Net:                           wire.StonecoinNet(jp.Net),
+		DefaultPort:                   jp.DefaultPort,
+		DNSSeeds:                      jp.DNSSeeds,
+		PowLimitBits:                  jp.PowLimitBits,
+		SubsidyHalvingInterval:        jp.SubsidyHalvingInterval,
+		BaseSubsidy:                   defaultBaseSubsidy,
+		TailEmission:                  jp.TailEmission,
+		ResetMinDifficulty:            jp.ResetMinDifficulty,
+		GenerateSupported:             jp.GenerateSupported,
+		ProofOfStakeHeight:            jp.ProofOfStakeHeight,
+		BlockEnforceNumRequired:       jp.BlockEnforceNumRequired,
+		BlockRejectNumRequired:        jp.BlockRejectNumRequired,
+		BlockUpgradeNumToCheck:        jp.BlockUpgradeNumToCheck,
+		RuleChangeActivationThreshold: jp.RuleChangeActivationThreshold,
+		MinerConfirmationWindow:       jp.MinerConfirmationWindow,
+		RelayNonStdTxs:                jp.RelayNonStdTxs,
+		PubKeyHashAddrID:              jp.PubKeyHashAddrID,
+		ScriptHashAddrID:              jp.ScriptHashAddrID,
+		PrivateKeyID:                  jp.PrivateKeyID,
+		Bech32HRPSegwit:               jp.Bech32HRPSegwit,
+		PaymentURIScheme:              jp.PaymentURIScheme,
+		HDCoinType:                    jp.HDCoinType,
+	}
+
+	// Payment requests use the scheme of the default networks unless the
+	// network has its own.
+	if params.PaymentURIScheme == "" {
+		params.PaymentURIScheme = defaultPaymentURIScheme
+	}
+
+	// The base subsidy defaults to that of the default networks, but a
+	// network may explicitly have no subsidy at all.
+	if jp.BaseSubsidy != nil {
+		params.BaseSubsidy = *jp.BaseSubsidy
+	}
+	if params.BaseSubsidy < 0 || params.TailEmission < 0 {
+		return nil, fmt.Errorf("basesubsidy and tailemission must not " +
+			"be negative")
+	}
+
+	// Decode the genesis block and make sure it hashes to the expected
+	// value when one is given.
+	serializedBlock, err := hex.DecodeString(jp.GenesisBlock)
+	if err != nil {
+		return nil, fmt.Errorf("genesisblock: %v", err)
+	}
+	var genesisBlock wire.MsgBlock
+	err = genesisBlock.Deserialize(bytes.NewReader(serializedBlock))
+	if err != nil {
+		return nil, fmt.Errorf("genesisblock: %v", err)
+	}
+	genesisHash := genesisBlock.BlockSha()
+	if jp.GenesisHash != "" {
+		wantHash, err := wire.NewShaHashFromStr(jp.GenesisHash)
+		if err != nil {
+			return nil, fmt.Errorf("genesishash: %v", err)
+		}
+		if !wantHash.IsEqual(&genesisHash) {
+			return nil, fmt.Errorf("genesishash %v does not match "+
+				"the hash of the genesis block %v", wantHash,
+				genesisHash)
+		}
+	}
+	params.GenesisBlock = &genesisBlock
+	params.GenesisHash = &genesisHash
+
+	powLimit, ok := new(big.Int).SetString(jp.PowLimit, 16)
+	if !ok || powLimit.Sign() <= 0 {
+		return nil, fmt.Errorf("powlimit must be a positive hex encoded " +
+			"number")
+	}
+	params.PowLimit = powLimit
+
+	// The chain takes the last checkpoint as the latest one and walks the
+	// others in order, so their heights must strictly increase.
+	for i, cp := range jp.Checkpoints {
+		if i > 0 && cp.Height <= jp.Checkpoints[i-1].Height {
+			return nil, fmt.Errorf("checkpoint at height %d does "+
+				"not follow the one at height %d", cp.Height,
+				jp.Checkpoints[i-1].Height)
+		}
+		hash, err := wire.NewShaHashFromStr(cp.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint at height %d: %v",
+				cp.Height, err)
+		}
+		params.Checkpoints = append(params.Checkpoints, Checkpoint{
+			Height: cp.Height,
+			Hash:   hash,
+		})
+	}
+
+	if jp.AssumeValid != "" {
+		hash, err := wire.NewShaHashFromStr(jp.AssumeValid)
+		if err != nil {
+			return nil, fmt.Errorf("assumevalid: %v", err)
+		}
+		params.AssumeValid = hash
+	}
+
+	for name, d := range jp.Deployments {
+		id := -1
+		for i, deploymentName := range DeploymentNames {
+			if deploymentName == name {
+				id = i
+				break
+			}
+		}
+		if id == -1 {
+			return nil, fmt.Errorf("unknown deployment %q", name)
+		}
+		if int32(1)<<d.BitNumber == wire.BlockVersionProofOfStake {
+			return nil, fmt.Errorf("deployment %q uses bit %d, which "+
+				"marks proof-of-stake blocks", name, d.BitNumber)
+		}
+		params.Deployments[id] = ConsensusDeployment{
+			BitNumber:  d.BitNumber,
+			StartTime:  d.StartTime,
+			ExpireTime: d.ExpireTime,
+		}
+	}
+
+	params.HDPrivateKeyID, err = decodeHDKeyID("hdprivatekeyid",
+		jp.HDPrivateKeyID)
+	if err != nil {
+		return nil, err
+	}
+	params.HDPublicKeyID, err = decodeHDKeyID("hdpublickeyid",
+		jp.HDPublicKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &params, nil
+}
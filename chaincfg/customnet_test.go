@@ -0,0 +1,161 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaincfg_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/conseweb/stcd/chaincfg"
+)
+
+// customNetJSON returns the JSON definition of a custom network which uses the
+// regression test network genesis block along with the passed extra fields.
+func customNetJSON(t *testing.T, extra string) string {
+	var buf bytes.Buffer
+	if err := RegressionNetParams.GenesisBlock.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	genesisHash := RegressionNetParams.GenesisBlock.BlockSha()
+
+	return fmt.Sprintf(`{
+		"name": "privnet",
+		"net": 3735928559,
+		"defaultport": "28444",
+		"genesisblock": "%x",
+		"genesishash": "%v",
+		"powlimit": "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"powlimitbits": 545259519,
+		"subsidyhalvinginterval": 150,
+		"tailemission": 100000000,
+		"generatesupported": true,
+		"minerconfirmationwindow": 144,
+		"rulechangeactivationthreshold": 108,
+		"deployments": {
+			"segwit": {"bit": 2, "starttime": 0, "expiretime": 4294967295}
+		},
+		"checkpoints": [
+			{"height": 1, "hash": "%v"}
+		],
+		"pubkeyhashaddrid": 88,
+		"scripthashaddrid": 89,
+		"privatekeyid": 90,
+		"bech32hrpsegwit": "pn",
+		"hdprivatekeyid": "0a0b0c0d",
+		"hdpublickeyid": "0e0f1011"%s
+	}`, buf.Bytes(), genesisHash, genesisHash, extra)
+}
+
+// TestDecodeParams ensures the JSON definition of a custom network is decoded
+// into the expected parameters.
+func TestDecodeParams(t *testing.T) {
+	params, err := DecodeParams(strings.NewReader(customNetJSON(t, "")))
+	if err != nil {
+		t.Fatalf("DecodeParams: unexpected error: %v", err)
+	}
+
+	genesisHash := RegressionNetParams.GenesisBlock.BlockSha()
+	switch {
+	case params.Name != "privnet":
+		t.Errorf("Name: got %s, want privnet", params.Name)
+	case uint32(params.Net) != 0xdeadbeef:
+		t.Errorf("Net: got %v, want 0xdeadbeef", params.Net)
+	case params.DefaultPort != "28444":
+		t.Errorf("DefaultPort: got %s, want 28444", params.DefaultPort)
+	case !params.GenesisHash.IsEqual(&genesisHash):
+		t.Errorf("GenesisHash: got %v, want %v", params.GenesisHash,
+			genesisHash)
+	case params.PowLimit.Cmp(RegressionNetParams.PowLimit) != 0:
+		t.Errorf("PowLimit: got %x, want %x", params.PowLimit,
+			RegressionNetParams.PowLimit)
+	case params.PowLimitBits != RegressionNetParams.PowLimitBits:
+		t.Errorf("PowLimitBits: got %x, want %x", params.PowLimitBits,
+			RegressionNetParams.PowLimitBits)
+	case len(params.Checkpoints) != 1 || params.Checkpoints[0].Height != 1:
+		t.Errorf("Checkpoints: got %v, want one at height 1",
+			params.Checkpoints)
+	case params.Deployments[DeploymentSegwit].BitNumber != 2:
+		t.Errorf("Deployments: segwit bit got %d, want 2",
+			params.Deployments[DeploymentSegwit].BitNumber)
+	case params.Deployments[DeploymentCLTV].ExpireTime != 0:
+		t.Errorf("Deployments: unlisted cltv deployment is defined")
+	case params.BaseSubsidy != 5000000000 ||
+		params.TailEmission != 100000000:
+		t.Errorf("Subsidy: got base %d and tail %d, want 5000000000 "+
+			"and 100000000", params.BaseSubsidy, params.TailEmission)
+	case params.Bech32HRPSegwit != "pn":
+		t.Errorf("Bech32HRPSegwit: got %s, want pn",
+			params.Bech32HRPSegwit)
+	case params.PaymentURIScheme != "bitcoin":
+		t.Errorf("PaymentURIScheme: got %s, want bitcoin",
+			params.PaymentURIScheme)
+	case hex.EncodeToString(params.HDPrivateKeyID[:]) != "0a0b0c0d":
+		t.Errorf("HDPrivateKeyID: got %x, want 0a0b0c0d",
+			params.HDPrivateKeyID)
+	}
+
+	// The decoded network must be registrable so its address and key
+	// magics are recognized.
+	if err := Register(params); err != nil {
+		t.Fatalf("Register: unexpected error: %v", err)
+	}
+	if !IsPubKeyHashAddrID(88) || !IsScriptHashAddrID(89) {
+		t.Errorf("Register: address magics of custom network are not " +
+			"registered")
+	}
+}
+
+// TestDecodeParamsErrors ensures invalid custom network definitions are
+// rejected.
+func TestDecodeParamsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"not json", "{"},
+		{"missing fields", `{"name": "privnet"}`},
+		{"bad genesis hash", customNetJSON(t, `,
+			"genesishash": "0000000000000000000000000000000000000000000000000000000000000001"`)},
+		{"unknown deployment", customNetJSON(t, `,
+			"deployments": {"bogus": {"bit": 5}}`)},
+		{"proof-of-stake deployment bit", customNetJSON(t, `,
+			"deployments": {"segwit": {"bit": 27}}`)},
+		{"negative subsidy", customNetJSON(t, `,
+			"basesubsidy": -1`)},
+		{"negative proof-of-stake height", customNetJSON(t, `,
+			"proofofstakeheight": -1`)},
+		{"bad hd key id", customNetJSON(t, `,
+			"hdpublickeyid": "0e0f"`)},
+		{"no confirmation window", customNetJSON(t, `,
+			"minerconfirmationwindow": 0`)},
+		{"no activation threshold", customNetJSON(t, `,
+			"rulechangeactivationthreshold": 0`)},
+		{"activation threshold above window", customNetJSON(t, `,
+			"rulechangeactivationthreshold": 145`)},
+		{"upgrade threshold above blocks checked", customNetJSON(t, `,
+			"blockenforcenumrequired": 51,
+			"blockupgradenumtocheck": 50`)},
+		{"unordered checkpoints", customNetJSON(t, `,
+			"checkpoints": [
+				{"height": 2, "hash": "00"},
+				{"height": 1, "hash": "00"}
+			]`)},
+		{"duplicate checkpoint heights", customNetJSON(t, `,
+			"checkpoints": [
+				{"height": 1, "hash": "00"},
+				{"height": 1, "hash": "00"}
+			]`)},
+	}
+
+	for _, test := range tests {
+		_, err := DecodeParams(strings.NewReader(test.json))
+		if err == nil {
+			t.Errorf("DecodeParams (%s): expected error", test.name)
+		}
+	}
+}
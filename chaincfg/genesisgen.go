@@ -0,0 +1,170 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaincfg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// maxGenesisCoinbaseScriptLen is the maximum length of the signature script
+// of the genesis coinbase.  It matches the maximum length of a coinbase script
+// enforced by the consensus rules.
+const maxGenesisCoinbaseScriptLen = 100
+
+// ErrGenesisCanceled describes an error where the search for a genesis block
+// nonce was canceled before a solution was found.
+var ErrGenesisCanceled = errors.New("genesis block generation canceled")
+
+// GenesisTemplate describes the contents of a genesis block to be generated
+// by GenerateGenesisBlock.
+type GenesisTemplate struct {
+	// CoinbaseMessage is the message, typically a newspaper headline,
+	// embedded in the signature script of the coinbase.
+	CoinbaseMessage []byte
+
+	// PkScript is the public key script of the single coinbase output.
+	PkScript []byte
+
+	// Value is the value of the coinbase output in satoshi.
+	Value int64
+
+	// Timestamp is the timestamp of the block.  It is only used with one
+	// second precision.
+	Timestamp time.Time
+
+	// Bits is the target difficulty of the block in compact form.
+	Bits uint32
+
+	// Version is the version of the block.  Zero means version 1.
+	Version int32
+}
+
+// compactToBig converts a compact representation of a whole number to a
+// big.Int.  It is a copy of blockchain.CompactToBig, which can't be used here
+// since the blockchain package depends on this one.
+func compactToBig(compact uint32) *big.Int {
+	mantissa := compact & 0x007fffff
+	isNegative := compact&0x00800000 != 0
+	exponent := uint(compact >> 24)
+
+	var bn *big.Int
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+		bn = big.NewInt(int64(mantissa))
+	} else {
+		bn = big.NewInt(int64(mantissa))
+		bn.Lsh(bn, 8*(exponent-3))
+	}
+	if isNegative {
+		bn = bn.Neg(bn)
+	}
+	return bn
+}
+
+// shaHashToBig converts the passed hash, which is little endian, to a big.Int.
+func shaHashToBig(hash *wire.ShaHash) *big.Int {
+	buf := *hash
+	for i := 0; i < wire.HashSize/2; i++ {
+		buf[i], buf[wire.HashSize-1-i] = buf[wire.HashSize-1-i], buf[i]
+	}
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// GenesisCoinbaseScript returns the signature script of a genesis coinbase
+// which embeds the passed message.  Like the genesis blocks defined by this
+// package, the script pushes the compact target 0x1d00ffff and the number 4,
+// followed by the length of the message as a single byte and the message.
+func GenesisCoinbaseScript(message []byte) ([]byte, error) {
+	scriptLen := 8 + len(message)
+	if scriptLen > maxGenesisCoinbaseScriptLen {
+		return nil, fmt.Errorf("coinbase message of %d bytes is too "+
+			"long -- the coinbase script must not exceed %d bytes",
+			len(message), maxGenesisCoinbaseScriptLen)
+	}
+
+	script := make([]byte, 0, scriptLen)
+	script = append(script, 0x04, 0xff, 0xff, 0x00, 0x1d) // 0x1d00ffff
+	script = append(script, 0x01, 0x04)                   // 4
+	script = append(script, byte(len(message)))
+	return append(script, message...), nil
+}
+
+// GenerateGenesisBlock creates a genesis block with a single coinbase
+// transaction from the passed template and searches for a nonce which makes
+// the block hash satisfy the target difficulty.  When every nonce has been
+// tried without success, the timestamp is advanced by one second and the
+// search starts over, so the timestamp of the returned block may be later
+// than the one in the template.
+//
+// The search can be aborted by closing the quit channel, in which case
+// ErrGenesisCanceled is returned.  A nil quit channel never aborts.
+func GenerateGenesisBlock(tmpl *GenesisTemplate, quit <-chan struct{}) (*wire.MsgBlock, error) {
+	target := compactToBig(tmpl.Bits)
+	if target.Sign() <= 0 {
+		return nil, fmt.Errorf("target difficulty bits %08x are not "+
+			"positive", tmpl.Bits)
+	}
+	if tmpl.Value < 0 {
+		return nil, fmt.Errorf("coinbase value %d is negative",
+			tmpl.Value)
+	}
+	sigScript, err := GenesisCoinbaseScript(tmpl.CoinbaseMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{
+			Hash:  wire.ShaHash{},
+			Index: math.MaxUint32,
+		},
+		SignatureScript: sigScript,
+		Sequence:        wire.MaxTxInSequenceNum,
+	})
+	coinbase.AddTxOut(wire.NewTxOut(tmpl.Value, tmpl.PkScript))
+
+	version := tmpl.Version
+	if version == 0 {
+		version = 1
+	}
+	block := wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    version,
+			MerkleRoot: coinbase.TxSha(),
+			Timestamp:  time.Unix(tmpl.Timestamp.Unix(), 0),
+			Bits:       tmpl.Bits,
+		},
+		Transactions: []*wire.MsgTx{coinbase},
+	}
+
+	header := &block.Header
+	for {
+		for nonce := uint64(0); nonce <= math.MaxUint32; nonce++ {
+			// Check for cancellation periodically rather than for
+			// every hash.
+			if nonce&0xffff == 0 {
+				select {
+				case <-quit:
+					return nil, ErrGenesisCanceled
+				default:
+				}
+			}
+
+			header.Nonce = uint32(nonce)
+			hash := header.BlockSha()
+			if shaHashToBig(&hash).Cmp(target) <= 0 {
+				return &block, nil
+			}
+		}
+		header.Timestamp = header.Timestamp.Add(time.Second)
+	}
+}
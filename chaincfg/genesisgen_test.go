@@ -0,0 +1,120 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaincfg_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/wire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGenerateGenesisBlock ensures a genesis block generated from the contents
+// of the regression test network genesis block has the same coinbase and a
+// header which commits to it and satisfies the target difficulty.
+func TestGenerateGenesisBlock(t *testing.T) {
+	want := chaincfg.RegressionNetParams.GenesisBlock
+	coinbase := want.Transactions[0]
+
+	// The message is everything after the pushes of the target bits and
+	// the number 4, and the one byte push of the message itself.
+	sigScript := coinbase.TxIn[0].SignatureScript
+	tmpl := &chaincfg.GenesisTemplate{
+		CoinbaseMessage: sigScript[8:],
+		PkScript:        coinbase.TxOut[0].PkScript,
+		Value:           coinbase.TxOut[0].Value,
+		Timestamp:       want.Header.Timestamp,
+		Bits:            want.Header.Bits,
+	}
+	got, err := chaincfg.GenerateGenesisBlock(tmpl, nil)
+	if err != nil {
+		t.Fatalf("GenerateGenesisBlock: unexpected error: %v", err)
+	}
+
+	var gotBuf, wantBuf bytes.Buffer
+	if err := got.Transactions[0].Serialize(&gotBuf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if err := coinbase.Serialize(&wantBuf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if len(got.Transactions) != 1 ||
+		!bytes.Equal(gotBuf.Bytes(), wantBuf.Bytes()) {
+
+		t.Fatalf("GenerateGenesisBlock: mismatched coinbase - got %v, "+
+			"want %v", spew.Sdump(got.Transactions),
+			spew.Sdump(coinbase))
+	}
+
+	header := &got.Header
+	coinbaseHash := coinbase.TxSha()
+	switch {
+	case header.Version != 1:
+		t.Errorf("GenerateGenesisBlock: got version %d, want 1",
+			header.Version)
+	case !header.PrevBlock.IsEqual(&wire.ShaHash{}):
+		t.Errorf("GenerateGenesisBlock: got previous block %v, want "+
+			"zero hash", header.PrevBlock)
+	case !header.MerkleRoot.IsEqual(&coinbaseHash):
+		t.Errorf("GenerateGenesisBlock: got merkle root %v, want %v",
+			header.MerkleRoot, coinbaseHash)
+	case !header.Timestamp.Equal(want.Header.Timestamp):
+		t.Errorf("GenerateGenesisBlock: got timestamp %v, want %v",
+			header.Timestamp, want.Header.Timestamp)
+	case header.Bits != want.Header.Bits:
+		t.Errorf("GenerateGenesisBlock: got bits %08x, want %08x",
+			header.Bits, want.Header.Bits)
+	}
+
+	hash := got.BlockSha()
+	if blockchain.ShaHashToBig(&hash).Cmp(
+		blockchain.CompactToBig(header.Bits)) > 0 {
+
+		t.Errorf("GenerateGenesisBlock: block hash %v does not satisfy "+
+			"the target difficulty %08x", hash, header.Bits)
+	}
+}
+
+// TestGenerateGenesisBlockErrors ensures invalid genesis templates and
+// cancellation are reported.
+func TestGenerateGenesisBlockErrors(t *testing.T) {
+	now := time.Unix(1453199035, 0)
+	tests := []struct {
+		name string
+		tmpl chaincfg.GenesisTemplate
+	}{
+		{"zero bits", chaincfg.GenesisTemplate{Timestamp: now}},
+		{"negative bits", chaincfg.GenesisTemplate{Timestamp: now,
+			Bits: 0x20800001}},
+		{"negative value", chaincfg.GenesisTemplate{Timestamp: now,
+			Bits: 0x207fffff, Value: -1}},
+		{"long message", chaincfg.GenesisTemplate{Timestamp: now,
+			Bits: 0x207fffff, CoinbaseMessage: []byte(
+				strings.Repeat("x", 100))}},
+	}
+	for _, test := range tests {
+		_, err := chaincfg.GenerateGenesisBlock(&test.tmpl, nil)
+		if err == nil {
+			t.Errorf("GenerateGenesisBlock (%s): expected error",
+				test.name)
+		}
+	}
+
+	// The highest difficulty can't be solved, so the search only ends
+	// when it is canceled.
+	quit := make(chan struct{})
+	close(quit)
+	tmpl := chaincfg.GenesisTemplate{Timestamp: now, Bits: 0x01010000}
+	_, err := chaincfg.GenerateGenesisBlock(&tmpl, quit)
+	if err != chaincfg.ErrGenesisCanceled {
+		t.Errorf("GenerateGenesisBlock (canceled): got error %v, want %v",
+			err, chaincfg.ErrGenesisCanceled)
+	}
+}
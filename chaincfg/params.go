@@ -6,6 +6,7 @@ package chaincfg
 
 import (
 	"errors"
+	"math"
 	"math/big"
 
 	"github.com/conseweb/stcd/wire"
@@ -48,6 +49,72 @@ type Checkpoint struct {
 	Hash   *wire.ShaHash
 }
 
+// ConsensusDeployment defines details related to a specific consensus rule
+// change that is voted in.  This is part of BIP0009.
+type ConsensusDeployment struct {
+	// BitNumber defines the specific bit number within the block version
+	// this particular soft-fork deployment refers to.  Bit 27 marks
+	// proof-of-stake blocks, so it must not be used.
+	BitNumber uint8
+
+	// StartTime is the median block time after which voting on the
+	// deployment starts.
+	StartTime uint64
+
+	// ExpireTime is the median block time after which the attempted
+	// deployment expires.
+	ExpireTime uint64
+}
+
+// Constants that define the deployment offset in the deployments field of the
+// parameters for each deployment.  This is useful to be able to get the details
+// of a specific deployment by name.
+const (
+	// DeploymentTestDummy defines the rule change deployment ID for testing
+	// purposes.
+	DeploymentTestDummy = iota
+
+	// DeploymentCLTV defines the rule change deployment ID for the
+	// CHECKLOCKTIMEVERIFY opcode defined by BIP0065.
+	DeploymentCLTV
+
+	// DeploymentSegwit defines the rule change deployment ID for the
+	// segregated witness soft fork defined by BIP0141, BIP0143, and
+	// BIP0147.
+	DeploymentSegwit
+
+	// DeploymentDERSig defines the rule change deployment ID for the strict
+	// DER signature encoding defined by BIP0066.
+	DeploymentDERSig
+
+	// DeploymentUtxoCommitment defines the rule change deployment ID for
+	// the commitment to the hash of the unspent transaction outputs in the
+	// coinbase of each block.
+	DeploymentUtxoCommitment
+
+	// DeploymentMalleability defines the rule change deployment ID for
+	// requiring push only signature scripts, minimally encoded data pushes
+	// and a clean stack after script execution, which are otherwise only
+	// enforced by policy.
+	DeploymentMalleability
+
+	// NOTE: DefinedDeployments must always come last since it is used to
+	// determine how many defined deployments there currently are.
+
+	// DefinedDeployments is the number of currently defined deployments.
+	DefinedDeployments
+)
+
+// DeploymentNames maps the deployment IDs to the names they are reported by.
+var DeploymentNames = [DefinedDeployments]string{
+	DeploymentTestDummy:      "testdummy",
+	DeploymentCLTV:           "cltv",
+	DeploymentSegwit:         "segwit",
+	DeploymentDERSig:         "dersig",
+	DeploymentUtxoCommitment: "utxocommitment",
+	DeploymentMalleability:   "malleability",
+}
+
 // Params defines a Bitcoin network by its parameters.  These parameters may be
 // used by Bitcoin applications to differentiate networks as well as addresses
 // and keys for one network from those intended for use on another network.
@@ -66,9 +133,34 @@ type Params struct {
 	ResetMinDifficulty     bool
 	GenerateSupported      bool
 
+	// BaseSubsidy is the subsidy of the blocks before the first halving
+	// in satoshi.  It is halved every SubsidyHalvingInterval blocks.
+	BaseSubsidy int64
+
+	// TailEmission is the subsidy, in satoshi, which is paid forever once
+	// the halvings would bring the subsidy below it.  Zero means the
+	// subsidy eventually drops to nothing.
+	TailEmission int64
+
+	// SubsidyFunc, when set, replaces the halving schedule defined by the
+	// above parameters.  It returns the subsidy of the block at the passed
+	// height in satoshi.
+	SubsidyFunc func(params *Params, height int32) int64
+
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
 
+	// AssumeValid is the hash of a block whose ancestors are assumed to
+	// have valid scripts, so script validation is skipped for them during
+	// the initial block download.  Nil means scripts are always validated.
+	AssumeValid *wire.ShaHash
+
+	// ProofOfStakeHeight is the height of the first block which may be a
+	// proof-of-stake block.  Below it, the proof-of-stake bit of the block
+	// version is an ordinary version bit and every block is proven by its
+	// work.  Zero means the network has no proof-of-stake blocks.
+	ProofOfStakeHeight int32
+
 	// Enforce current block version once network has
 	// upgraded.  This is part of BIP0034.
 	BlockEnforceNumRequired uint64
@@ -80,6 +172,23 @@ type Params struct {
 	// The number of nodes to check.  This is part of BIP0034.
 	BlockUpgradeNumToCheck uint64
 
+	// These fields are related to voting on consensus rule changes as
+	// defined by BIP0009.
+	//
+	// RuleChangeActivationThreshold is the number of blocks in a threshold
+	// state retarget window for which a positive vote for a rule change
+	// must be cast in order to lock in a rule change.  It should typically
+	// be 95% for the main network and 75% for test networks.
+	//
+	// MinerConfirmationWindow is the number of blocks in each threshold
+	// state retarget window.
+	//
+	// Deployments define the specific consensus rule changes to be voted
+	// on.
+	RuleChangeActivationThreshold uint32
+	MinerConfirmationWindow       uint32
+	Deployments                   [DefinedDeployments]ConsensusDeployment
+
 	// Mempool parameters
 	RelayNonStdTxs bool
 
@@ -88,6 +197,13 @@ type Params struct {
 	ScriptHashAddrID byte // First byte of a P2SH address
 	PrivateKeyID     byte // First byte of a WIF private key
 
+	// Human-readable part for bech32 encoded segwit addresses, as defined
+	// in BIP0173.
+	Bech32HRPSegwit string
+
+	// URI scheme of payment requests, as defined in BIP0021.
+	PaymentURIScheme string
+
 	// BIP32 hierarchical deterministic extended key magics
 	HDPrivateKeyID [4]byte
 	HDPublicKeyID  [4]byte
@@ -97,6 +213,10 @@ type Params struct {
 	HDCoinType uint32
 }
 
+// defaultPaymentURIScheme is the URI scheme of payment requests on the default
+// networks.
+const defaultPaymentURIScheme = "bitcoin"
+
 // MainNetParams defines the network parameters for the main Bitcoin network.
 var MainNetParams = Params{
 	Name:        "mainnet",
@@ -120,6 +240,7 @@ var MainNetParams = Params{
 	SubsidyHalvingInterval: 210000,
 	ResetMinDifficulty:     false,
 	GenerateSupported:      false,
+	BaseSubsidy:            defaultBaseSubsidy,
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{
@@ -143,6 +264,10 @@ var MainNetParams = Params{
 		// {382320, newShaHashFromStr("00000000000000000a8dc6ed5b133d0eb2fd6af56203e4159789b092defd8ab2")},
 	},
 
+	// Blocks assumed to have valid scripts.  This is the newest checkpoint
+	// and must be moved along when checkpoints are added.
+	AssumeValid: newShaHashFromStr("0000000069e244f73d78e8fd29ba2fd2ed618bd6fa2ee92559f542fdb26e7c1d"),
+
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 75% (750 / 1000)
@@ -153,6 +278,50 @@ var MainNetParams = Params{
 	BlockRejectNumRequired:  950,
 	BlockUpgradeNumToCheck:  1000,
 
+	// Consensus rule change deployments.
+	//
+	// The miner confirmation window is defined as:
+	//   target proof of work timespan / target proof of work spacing
+	RuleChangeActivationThreshold: 1916, // 95% of MinerConfirmationWindow
+	MinerConfirmationWindow:       2016,
+	Deployments: [DefinedDeployments]ConsensusDeployment{
+		DeploymentTestDummy: {
+			BitNumber:  28,
+			StartTime:  1199145601, // January 1, 2008 UTC
+			ExpireTime: 1230767999, // December 31, 2008 UTC
+		},
+		// Also enforced by the version 4 block majority.  The vote
+		// lets the chains which never reached it activate the rule.
+		DeploymentCLTV: {
+			BitNumber:  1,
+			StartTime:  1798761600, // January 1, 2027 UTC
+			ExpireTime: 1830297599, // December 31, 2027 UTC
+		},
+		DeploymentSegwit: {
+			BitNumber:  2,
+			StartTime:  1798761600, // January 1, 2027 UTC
+			ExpireTime: 1830297599, // December 31, 2027 UTC
+		},
+		// Also enforced by the version 3 block majority.  The vote
+		// lets the chains which never reached it activate the rule.
+		DeploymentDERSig: {
+			BitNumber:  3,
+			StartTime:  1798761600, // January 1, 2027 UTC
+			ExpireTime: 1830297599, // December 31, 2027 UTC
+		},
+		// Not scheduled yet, so the deployment is never voted on.
+		DeploymentUtxoCommitment: {
+			BitNumber:  4,
+			StartTime:  math.MaxInt64, // Never available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentMalleability: {
+			BitNumber:  5,
+			StartTime:  math.MaxInt64, // Never available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+	},
+
 	// Mempool parameters
 	RelayNonStdTxs: false,
 
@@ -161,6 +330,13 @@ var MainNetParams = Params{
 	ScriptHashAddrID: 0x05, // starts with 3
 	PrivateKeyID:     0x80, // starts with 5 (uncompressed) or K (compressed)
 
+	// Human-readable part for bech32 encoded segwit addresses, as defined
+	// in BIP0173.
+	Bech32HRPSegwit: "bc", // starts with bc1
+
+	// URI scheme of payment requests, as defined in BIP0021.
+	PaymentURIScheme: defaultPaymentURIScheme,
+
 	// BIP32 hierarchical deterministic extended key magics
 	HDPrivateKeyID: [4]byte{0x04, 0x88, 0xad, 0xe4}, // starts with xprv
 	HDPublicKeyID:  [4]byte{0x04, 0x88, 0xb2, 0x1e}, // starts with xpub
@@ -187,10 +363,14 @@ var RegressionNetParams = Params{
 	SubsidyHalvingInterval: 150,
 	ResetMinDifficulty:     true,
 	GenerateSupported:      true,
+	BaseSubsidy:            defaultBaseSubsidy,
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
 
+	// Blocks assumed to have valid scripts.
+	AssumeValid: nil,
+
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 75% (750 / 1000)
@@ -201,6 +381,45 @@ var RegressionNetParams = Params{
 	BlockRejectNumRequired:  950,
 	BlockUpgradeNumToCheck:  1000,
 
+	// Consensus rule change deployments.
+	//
+	// The miner confirmation window is defined as:
+	//   target proof of work timespan / target proof of work spacing
+	RuleChangeActivationThreshold: 108, // 75% of MinerConfirmationWindow
+	MinerConfirmationWindow:       144,
+	Deployments: [DefinedDeployments]ConsensusDeployment{
+		DeploymentTestDummy: {
+			BitNumber:  28,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentCLTV: {
+			BitNumber:  1,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentSegwit: {
+			BitNumber:  2,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentDERSig: {
+			BitNumber:  3,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentUtxoCommitment: {
+			BitNumber:  4,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentMalleability: {
+			BitNumber:  5,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+	},
+
 	// Mempool parameters
 	RelayNonStdTxs: true,
 
@@ -209,6 +428,13 @@ var RegressionNetParams = Params{
 	ScriptHashAddrID: 0xc4, // starts with 2
 	PrivateKeyID:     0xef, // starts with 9 (uncompressed) or c (compressed)
 
+	// Human-readable part for bech32 encoded segwit addresses, as defined
+	// in BIP0173.
+	Bech32HRPSegwit: "bcrt", // starts with bcrt1
+
+	// URI scheme of payment requests, as defined in BIP0021.
+	PaymentURIScheme: defaultPaymentURIScheme,
+
 	// BIP32 hierarchical deterministic extended key magics
 	HDPrivateKeyID: [4]byte{0x04, 0x35, 0x83, 0x94}, // starts with tprv
 	HDPublicKeyID:  [4]byte{0x04, 0x35, 0x87, 0xcf}, // starts with tpub
@@ -239,12 +465,17 @@ var TestNet3Params = Params{
 	SubsidyHalvingInterval: 210000,
 	ResetMinDifficulty:     true,
 	GenerateSupported:      false,
+	BaseSubsidy:            defaultBaseSubsidy,
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{
 		{546, newShaHashFromStr("000000002a936ca763904c3c35fce2f3556c559c0214345d31b1bcebf76acb70")},
 	},
 
+	// Blocks assumed to have valid scripts.  This is the newest checkpoint
+	// and must be moved along when checkpoints are added.
+	AssumeValid: newShaHashFromStr("000000002a936ca763904c3c35fce2f3556c559c0214345d31b1bcebf76acb70"),
+
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 51% (51 / 100)
@@ -255,6 +486,50 @@ var TestNet3Params = Params{
 	BlockRejectNumRequired:  75,
 	BlockUpgradeNumToCheck:  100,
 
+	// Consensus rule change deployments.
+	//
+	// The miner confirmation window is defined as:
+	//   target proof of work timespan / target proof of work spacing
+	RuleChangeActivationThreshold: 1512, // 75% of MinerConfirmationWindow
+	MinerConfirmationWindow:       2016,
+	Deployments: [DefinedDeployments]ConsensusDeployment{
+		DeploymentTestDummy: {
+			BitNumber:  28,
+			StartTime:  1199145601, // January 1, 2008 UTC
+			ExpireTime: 1230767999, // December 31, 2008 UTC
+		},
+		// Also enforced by the version 4 block majority.  The vote
+		// lets the chains which never reached it activate the rule.
+		DeploymentCLTV: {
+			BitNumber:  1,
+			StartTime:  1798761600, // January 1, 2027 UTC
+			ExpireTime: 1830297599, // December 31, 2027 UTC
+		},
+		DeploymentSegwit: {
+			BitNumber:  2,
+			StartTime:  1798761600, // January 1, 2027 UTC
+			ExpireTime: 1830297599, // December 31, 2027 UTC
+		},
+		// Also enforced by the version 3 block majority.  The vote
+		// lets the chains which never reached it activate the rule.
+		DeploymentDERSig: {
+			BitNumber:  3,
+			StartTime:  1798761600, // January 1, 2027 UTC
+			ExpireTime: 1830297599, // December 31, 2027 UTC
+		},
+		// Not scheduled yet, so the deployment is never voted on.
+		DeploymentUtxoCommitment: {
+			BitNumber:  4,
+			StartTime:  math.MaxInt64, // Never available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentMalleability: {
+			BitNumber:  5,
+			StartTime:  math.MaxInt64, // Never available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+	},
+
 	// Mempool parameters
 	RelayNonStdTxs: true,
 
@@ -263,6 +538,13 @@ var TestNet3Params = Params{
 	ScriptHashAddrID: 0xc4, // starts with 2
 	PrivateKeyID:     0xef, // starts with 9 (uncompressed) or c (compressed)
 
+	// Human-readable part for bech32 encoded segwit addresses, as defined
+	// in BIP0173.
+	Bech32HRPSegwit: "tb", // starts with tb1
+
+	// URI scheme of payment requests, as defined in BIP0021.
+	PaymentURIScheme: defaultPaymentURIScheme,
+
 	// BIP32 hierarchical deterministic extended key magics
 	HDPrivateKeyID: [4]byte{0x04, 0x35, 0x83, 0x94}, // starts with tprv
 	HDPublicKeyID:  [4]byte{0x04, 0x35, 0x87, 0xcf}, // starts with tpub
@@ -293,10 +575,14 @@ var SimNetParams = Params{
 	SubsidyHalvingInterval: 210000,
 	ResetMinDifficulty:     true,
 	GenerateSupported:      true,
+	BaseSubsidy:            defaultBaseSubsidy,
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
 
+	// Blocks assumed to have valid scripts.
+	AssumeValid: nil,
+
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 51% (51 / 100)
@@ -307,6 +593,45 @@ var SimNetParams = Params{
 	BlockRejectNumRequired:  75,
 	BlockUpgradeNumToCheck:  100,
 
+	// Consensus rule change deployments.
+	//
+	// The miner confirmation window is defined as:
+	//   target proof of work timespan / target proof of work spacing
+	RuleChangeActivationThreshold: 75, // 75% of MinerConfirmationWindow
+	MinerConfirmationWindow:       100,
+	Deployments: [DefinedDeployments]ConsensusDeployment{
+		DeploymentTestDummy: {
+			BitNumber:  28,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentCLTV: {
+			BitNumber:  1,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentSegwit: {
+			BitNumber:  2,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentDERSig: {
+			BitNumber:  3,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentUtxoCommitment: {
+			BitNumber:  4,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+		DeploymentMalleability: {
+			BitNumber:  5,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
+	},
+
 	// Mempool parameters
 	RelayNonStdTxs: true,
 
@@ -315,6 +640,13 @@ var SimNetParams = Params{
 	ScriptHashAddrID: 0x7b, // starts with s
 	PrivateKeyID:     0x64, // starts with 4 (uncompressed) or F (compressed)
 
+	// Human-readable part for bech32 encoded segwit addresses, as defined
+	// in BIP0173.
+	Bech32HRPSegwit: "sb", // starts with sb1
+
+	// URI scheme of payment requests, as defined in BIP0021.
+	PaymentURIScheme: defaultPaymentURIScheme,
+
 	// BIP32 hierarchical deterministic extended key magics
 	HDPrivateKeyID: [4]byte{0x04, 0x20, 0xb9, 0x00}, // starts with sprv
 	HDPublicKeyID:  [4]byte{0x04, 0x20, 0xbd, 0x3a}, // starts with spub
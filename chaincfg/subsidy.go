@@ -0,0 +1,39 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaincfg
+
+// defaultBaseSubsidy is the base subsidy of the default networks, which is 50
+// coins in satoshi.
+const defaultBaseSubsidy = 50 * 100000000
+
+// BlockSubsidy returns the subsidy the coinbase of the block at the passed
+// height may claim in addition to the transaction fees.  It is calculated by
+// SubsidyFunc when it is set, and by HalvingSubsidy otherwise.
+func (p *Params) BlockSubsidy(height int32) int64 {
+	if p.SubsidyFunc != nil {
+		return p.SubsidyFunc(p, height)
+	}
+	return HalvingSubsidy(p, height)
+}
+
+// HalvingSubsidy returns the subsidy of the block at the passed height under
+// the halving schedule defined by the passed parameters.
+//
+// The subsidy is halved every SubsidyHalvingInterval blocks, but never drops
+// below TailEmission.  Mathematically this is:
+// max(BaseSubsidy / 2^(height/SubsidyHalvingInterval), TailEmission)
+func HalvingSubsidy(p *Params, height int32) int64 {
+	if p.SubsidyHalvingInterval == 0 {
+		return p.BaseSubsidy
+	}
+
+	// Shifting by 64 or more bits results in zero, so there is no need to
+	// limit the number of halvings.
+	subsidy := p.BaseSubsidy >> uint(height/p.SubsidyHalvingInterval)
+	if subsidy < p.TailEmission {
+		return p.TailEmission
+	}
+	return subsidy
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaincfg_test
+
+import (
+	"testing"
+
+	. "github.com/conseweb/stcd/chaincfg"
+)
+
+// TestBlockSubsidy ensures the subsidy schedule follows the halving interval,
+// the tail emission, and the subsidy function of the network parameters.
+func TestBlockSubsidy(t *testing.T) {
+	tail := MainNetParams
+	tail.TailEmission = 60000000
+
+	flat := MainNetParams
+	flat.SubsidyFunc = func(params *Params, height int32) int64 {
+		return params.BaseSubsidy / 10
+	}
+
+	tests := []struct {
+		name   string
+		params *Params
+		height int32
+		want   int64
+	}{
+		{"mainnet genesis", &MainNetParams, 0, 5000000000},
+		{"mainnet before halving", &MainNetParams, 209999, 5000000000},
+		{"mainnet first halving", &MainNetParams, 210000, 2500000000},
+		{"mainnet third halving", &MainNetParams, 630000, 625000000},
+		{"mainnet exhausted", &MainNetParams, 210000 * 64, 0},
+		{"regtest first halving", &RegressionNetParams, 150, 2500000000},
+		{"tail before floor", &tail, 210000 * 6, 78125000},
+		{"tail at floor", &tail, 210000 * 7, 60000000},
+		{"tail exhausted", &tail, 210000 * 64, 60000000},
+		{"subsidy func", &flat, 210000, 500000000},
+	}
+
+	for _, test := range tests {
+		got := test.params.BlockSubsidy(test.height)
+		if got != test.want {
+			t.Errorf("BlockSubsidy (%s): got %d, want %d", test.name,
+				got, test.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// chainWorkInterval is the number of blocks between the cumulative work
+// totals remembered by chainWorkCache.  It bounds the number of headers which
+// have to be loaded to calculate the chain work of any block.
+const chainWorkInterval = 1000
+
+// chainWorkEntry is the cumulative work of the main chain up to and including
+// the block with the given hash.
+type chainWorkEntry struct {
+	hash wire.ShaHash
+	work *big.Int
+}
+
+// chainWorkCache calculates the total work of the main chain up to a given
+// height.  The block database only tracks the main chain and the in-memory
+// block index only holds the most recent blocks, so the cumulative work at
+// every chainWorkInterval'th height is cached to avoid summing the work of
+// every block back to the genesis block for each request.
+//
+// Each cached entry remembers the hash of its block, and entries which are no
+// longer in the main chain due to a reorganization are discarded when they
+// are next used, so the cache never needs to be notified of chain changes.
+type chainWorkCache struct {
+	sync.Mutex
+	db database.Db
+
+	// entries[i] holds the cumulative work up to height
+	// i*chainWorkInterval.
+	entries []chainWorkEntry
+}
+
+// newChainWorkCache returns a new empty chain work cache for the passed
+// database.
+func newChainWorkCache(db database.Db) *chainWorkCache {
+	return &chainWorkCache{db: db}
+}
+
+// ChainWork returns the total work of the main chain up to and including the
+// block at the passed height.
+//
+// This function is safe for concurrent access.
+func (c *chainWorkCache) ChainWork(height int32) (*big.Int, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	// Find the closest cached entry at or below the height which is still
+	// in the main chain, dropping any stale entries along the way.
+	idx := int(height / chainWorkInterval)
+	if idx >= len(c.entries) {
+		idx = len(c.entries) - 1
+	}
+	for ; idx >= 0; idx-- {
+		hash, err := c.db.FetchBlockShaByHeight(int32(idx) * chainWorkInterval)
+		if err != nil {
+			return nil, err
+		}
+		if hash.IsEqual(&c.entries[idx].hash) {
+			break
+		}
+	}
+	c.entries = c.entries[:idx+1]
+
+	work := new(big.Int)
+	start := int32(0)
+	if idx >= 0 {
+		work.Set(c.entries[idx].work)
+		start = int32(idx)*chainWorkInterval + 1
+	}
+
+	// Sum the work of the remaining blocks, caching totals as they are
+	// passed.
+	for start <= height {
+		hashList, err := c.db.FetchHeightRange(start, height+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(hashList) == 0 {
+			return nil, database.ErrBlockShaMissing
+		}
+
+		for i := range hashList {
+			hash := &hashList[i]
+			header, err := c.db.FetchBlockHeaderBySha(hash)
+			if err != nil {
+				return nil, err
+			}
+			work.Add(work, blockchain.CalcWork(header.Bits))
+
+			blockHeight := start + int32(i)
+			if blockHeight%chainWorkInterval == 0 &&
+				int(blockHeight/chainWorkInterval) == len(c.entries) {
+
+				c.entries = append(c.entries, chainWorkEntry{
+					hash: *hash,
+					work: new(big.Int).Set(work),
+				})
+			}
+		}
+		start += int32(len(hashList))
+	}
+
+	return work, nil
+}
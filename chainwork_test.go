@@ -0,0 +1,102 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// addTestBlocks extends the main chain in db by the passed number of blocks
+// with the given difficulty bits.  The extra nonce keeps the coinbases of
+// different branches unique.
+func addTestBlocks(t *testing.T, db database.Db, count int, bits uint32, extraNonce byte) {
+	prevHash, height, err := db.NewestSha()
+	if err != nil {
+		t.Fatalf("NewestSha: %v", err)
+	}
+	for i := 0; i < count; i++ {
+		height++
+		coinbase := wire.NewMsgTx()
+		coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff},
+			[]byte{byte(height), byte(height >> 8), extraNonce}))
+		coinbase.AddTxOut(wire.NewTxOut(1, []byte{0x51}))
+
+		merkleRoot := coinbase.TxSha()
+		header := wire.NewBlockHeader(prevHash, &merkleRoot, bits, 0)
+		block := wire.NewMsgBlock(header)
+		block.AddTransaction(coinbase)
+		if _, err := db.InsertBlock(coinutil.NewBlock(block)); err != nil {
+			t.Fatalf("InsertBlock: %v", err)
+		}
+		hash := block.BlockSha()
+		prevHash = &hash
+	}
+}
+
+// sumChainWork returns the total work of the main chain up to height by
+// summing the work of every block.
+func sumChainWork(t *testing.T, db database.Db, height int32) *big.Int {
+	work := new(big.Int)
+	for h := int32(0); h <= height; h++ {
+		hash, err := db.FetchBlockShaByHeight(h)
+		if err != nil {
+			t.Fatalf("FetchBlockShaByHeight: %v", err)
+		}
+		header, err := db.FetchBlockHeaderBySha(hash)
+		if err != nil {
+			t.Fatalf("FetchBlockHeaderBySha: %v", err)
+		}
+		work.Add(work, blockchain.CalcWork(header.Bits))
+	}
+	return work
+}
+
+// TestChainWorkCache ensures the chain work cache returns the same totals as
+// summing the work of every block, including after a reorganization replaces
+// blocks which have cached totals.
+func TestChainWorkCache(t *testing.T) {
+	db, err := database.CreateDB("memdb")
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	defer db.Close()
+
+	addTestBlocks(t, db, chainWorkInterval*2+500, 0x207fffff, 0)
+	cache := newChainWorkCache(db)
+
+	heights := []int32{0, 1, chainWorkInterval - 1, chainWorkInterval,
+		chainWorkInterval*2 + 499, chainWorkInterval + 1}
+	check := func() {
+		for _, height := range heights {
+			got, err := cache.ChainWork(height)
+			if err != nil {
+				t.Fatalf("ChainWork(%d): %v", height, err)
+			}
+			if want := sumChainWork(t, db, height); got.Cmp(want) != 0 {
+				t.Fatalf("ChainWork(%d): got %v want %v", height,
+					got, want)
+			}
+		}
+	}
+	check()
+
+	// Replace everything after the first cached interval with blocks of a
+	// different difficulty.
+	forkHash, err := db.FetchBlockShaByHeight(chainWorkInterval + 10)
+	if err != nil {
+		t.Fatalf("FetchBlockShaByHeight: %v", err)
+	}
+	if err := db.DropAfterBlockBySha(forkHash); err != nil {
+		t.Fatalf("DropAfterBlockBySha: %v", err)
+	}
+	addTestBlocks(t, db, chainWorkInterval+489, 0x1f7fffff, 1)
+	check()
+}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -14,6 +15,7 @@ import (
 	"github.com/conseweb/stcd/database"
 	_ "github.com/conseweb/stcd/database/ldb"
 	"github.com/conseweb/stcd/limits"
+	"github.com/conseweb/stcd/wire"
 )
 
 const (
@@ -91,17 +93,49 @@ func realMain() error {
 	}
 	defer db.Close()
 
-	fi, err := os.Open(cfg.InFile)
+	// Resume an interrupted import of the same file unless told not to.
+	// The saved progress is only trusted when the last block it recorded
+	// is still in the database.
+	progress, err := loadProgress(cfg.DataDir, cfg.InFile)
+	if err != nil {
+		log.Errorf("Failed to load import progress: %v", err)
+		return err
+	}
+	if progress.Offset != 0 && !cfg.NoResume {
+		hash, err := wire.NewShaHashFromStr(progress.Hash)
+		if err == nil {
+			var exists bool
+			exists, err = db.ExistsSha(hash)
+			if err == nil && !exists {
+				err = fmt.Errorf("block %v is not in the "+
+					"database", hash)
+			}
+		}
+		if err != nil {
+			log.Warnf("Unable to resume import, starting from the "+
+				"beginning: %v", err)
+			progress.Offset = 0
+		}
+	}
+	if cfg.NoResume || progress.Offset == 0 {
+		progress.Offset = 0
+		progress.Blocks = 0
+		progress.Hash = ""
+	} else {
+		log.Infof("Resuming import after %d blocks", progress.Blocks)
+	}
+
+	r, closer, err := openBlockFile(cfg.InFile, progress.Offset)
 	if err != nil {
 		log.Errorf("Failed to open file %v: %v", cfg.InFile, err)
 		return err
 	}
-	defer fi.Close()
+	defer closer.Close()
 
 	// Create a block importer for the database and input file and start it.
 	// The done channel returned from start will contain an error if
 	// anything went wrong.
-	importer := newBlockImporter(db, fi)
+	importer := newBlockImporter(db, r, progress)
 
 	// Perform the import asynchronously.  This allows blocks to be
 	// processed and read in parallel.  The results channel returned from
@@ -118,6 +152,11 @@ func realMain() error {
 	log.Infof("Processed a total of %d blocks (%d imported, %d already "+
 		"known)", results.blocksProcessed, results.blocksImported,
 		results.blocksProcessed-results.blocksImported)
+
+	// The import is complete, so there is nothing left to resume.
+	if err := progress.remove(); err != nil {
+		log.Warnf("Unable to remove import progress: %v", err)
+	}
 	return nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 
 	"github.com/conseweb/coinutil"
 	flags "github.com/conseweb/go-flags"
@@ -41,6 +42,8 @@ type config struct {
 	SimNet         bool   `long:"simnet" description:"Use the simulation test network"`
 	InFile         string `short:"i" long:"infile" description:"File containing the block(s)"`
 	Progress       int    `short:"p" long:"progress" description:"Show a progress message each time this number of seconds have passed -- Use 0 to disable progress announcements"`
+	Workers        int    `long:"workers" description:"Number of workers verifying scripts ahead of the chain (default: number of CPUs)"`
+	NoResume       bool   `long:"noresume" description:"Import the file from the beginning even if a previous import of it was interrupted"`
 }
 
 // filesExists reports whether the named file or directory exists.
@@ -90,6 +93,7 @@ func loadConfig() (*config, []string, error) {
 		DbType:   defaultDbType,
 		InFile:   defaultDataFile,
 		Progress: defaultProgress,
+		Workers:  runtime.NumCPU(),
 	}
 
 	// Parse command line options.
@@ -128,6 +132,15 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// There must be at least one script verification worker.
+	if cfg.Workers < 1 {
+		str := "%s: The number of workers must be at least 1"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		parser.WriteHelp(os.Stderr)
+		return nil, nil, err
+	}
+
 	// Validate database type.
 	if !validDbType(cfg.DbType) {
 		str := "%s: The specified database type [%v] is invalid -- " +
@@ -15,9 +15,20 @@ import (
 	"github.com/conseweb/stcd/blockchain"
 	"github.com/conseweb/stcd/database"
 	_ "github.com/conseweb/stcd/database/ldb"
+	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
 )
 
+const (
+	// sigCacheMaxSize is the maximum number of entries in the signature
+	// cache shared by the script verification workers and the chain.
+	sigCacheMaxSize = 100000
+
+	// progressSaveInterval is the minimum amount of time between saves of
+	// the import progress.
+	progressSaveInterval = time.Second * 10
+)
+
 var zeroHash = wire.ShaHash{}
 
 // importResults houses the stats and result as an import operation.
@@ -27,14 +38,30 @@ type importResults struct {
 	err             error
 }
 
+// importItem houses a block read from the input file until it is processed.
+type importItem struct {
+	block     *coinutil.Block
+	endOffset int64
+	verified  chan struct{}
+}
+
 // blockImporter houses information about an ongoing import from a block data
 // file to the block database.
+//
+// Blocks are read ahead of the chain and handed to a pool of workers which
+// run the scripts of every input whose referenced output is already known.
+// The signatures which verify are recorded in a signature cache shared with
+// the chain, so the expensive signature checks are performed in parallel
+// across blocks while the blocks themselves are still connected in order.
 type blockImporter struct {
 	db                database.Db
 	chain             *blockchain.BlockChain
 	medianTime        blockchain.MedianTimeSource
-	r                 io.ReadSeeker
-	processQueue      chan []byte
+	sigCache          *txscript.SigCache
+	r                 io.Reader
+	offset            int64
+	processQueue      chan *importItem
+	verifyQueue       chan *importItem
 	doneChan          chan bool
 	errChan           chan error
 	quit              chan struct{}
@@ -46,6 +73,16 @@ type blockImporter struct {
 	lastHeight        int64
 	lastBlockTime     time.Time
 	lastLogTime       time.Time
+	lastSaveTime      time.Time
+	progress          *importProgress
+
+	// verifyScripts is set once the blocks being read are past the latest
+	// checkpoint since the chain does not run scripts before it.  It is
+	// only accessed by the read handler.
+	verifyScripts  bool
+	checkpointHash *wire.ShaHash
+	pendingTxnsMtx sync.RWMutex
+	pendingTxns    map[wire.ShaHash]*wire.MsgTx
 }
 
 // readBlock reads the next block from the input file.
@@ -82,23 +119,77 @@ func (bi *blockImporter) readBlock() ([]byte, error) {
 	if _, err := io.ReadFull(bi.r, serializedBlock); err != nil {
 		return nil, err
 	}
+	bi.offset += 8 + int64(blockLen)
 
 	return serializedBlock, nil
 }
 
-// processBlock potentially imports the block into the database.  It first
-// deserializes the raw block while checking for errors.  Already known blocks
-// are skipped and orphan blocks are considered errors.  Finally, it runs the
-// block through the chain rules to ensure it follows all rules and matches
-// up to the known checkpoint.  Returns whether the block was imported along
-// with any potential errors.
-func (bi *blockImporter) processBlock(serializedBlock []byte) (bool, error) {
-	// Deserialize the block which includes checks for malformed blocks.
-	block, err := coinutil.NewBlockFromBytes(serializedBlock)
-	if err != nil {
-		return false, err
+// lookupTx returns the transaction with the passed hash from the blocks which
+// have been read but not yet processed, or from the database.  It returns nil
+// when the transaction is not known.
+func (bi *blockImporter) lookupTx(hash *wire.ShaHash) *wire.MsgTx {
+	bi.pendingTxnsMtx.RLock()
+	tx, ok := bi.pendingTxns[*hash]
+	bi.pendingTxnsMtx.RUnlock()
+	if ok {
+		return tx
+	}
+
+	replies, err := bi.db.FetchTxBySha(hash)
+	if err != nil || len(replies) == 0 {
+		return nil
+	}
+	reply := replies[len(replies)-1]
+	if reply.Err != nil {
+		return nil
+	}
+	return reply.Tx
+}
+
+// verifyBlockScripts runs the scripts of each transaction input in the block
+// whose referenced output is known so that the signatures which verify are
+// added to the signature cache.  Failures are ignored here since the scripts
+// are validated again when the block is connected to the chain, which is what
+// determines whether or not the block is valid.
+func (bi *blockImporter) verifyBlockScripts(block *wire.MsgBlock) {
+	var flags txscript.ScriptFlags
+	if block.Header.Timestamp.After(txscript.Bip16Activation) {
+		flags |= txscript.ScriptBip16
+	}
+
+	for txIdx, tx := range block.Transactions {
+		// The coinbase does not have any inputs to verify.
+		if txIdx == 0 {
+			continue
+		}
+
+		sigHashes := txscript.NewTxSigHashes(tx)
+		for txInIdx, txIn := range tx.TxIn {
+			prevOut := &txIn.PreviousOutPoint
+			originTx := bi.lookupTx(&prevOut.Hash)
+			if originTx == nil ||
+				prevOut.Index >= uint32(len(originTx.TxOut)) {
+				continue
+			}
+
+			prevTxOut := originTx.TxOut[prevOut.Index]
+			vm, err := txscript.NewEngineWithSigHashes(
+				prevTxOut.PkScript, tx, txInIdx, flags,
+				bi.sigCache, sigHashes, prevTxOut.Value)
+			if err != nil {
+				continue
+			}
+			vm.Execute()
+		}
 	}
+}
 
+// processBlock potentially imports the block into the database.  Already
+// known blocks are skipped and orphan blocks are considered errors.  Finally,
+// it runs the block through the chain rules to ensure it follows all rules and
+// matches up to the known checkpoint.  Returns whether the block was imported
+// along with any potential errors.
+func (bi *blockImporter) processBlock(block *coinutil.Block) (bool, error) {
 	// update progress statistics
 	bi.lastBlockTime = block.MsgBlock().Header.Timestamp
 	bi.receivedLogTx += int64(len(block.MsgBlock().Transactions))
@@ -162,17 +253,80 @@ out:
 			break out
 		}
 
+		// Deserialize the block which includes checks for malformed
+		// blocks.
+		block, err := coinutil.NewBlockFromBytes(serializedBlock)
+		if err != nil {
+			bi.errChan <- err
+			break out
+		}
+
+		// Make the transactions available to the script verification
+		// workers for the blocks which follow.
+		msgBlock := block.MsgBlock()
+		bi.pendingTxnsMtx.Lock()
+		for _, tx := range msgBlock.Transactions {
+			bi.pendingTxns[tx.TxSha()] = tx
+		}
+		bi.pendingTxnsMtx.Unlock()
+
+		item := &importItem{
+			block:     block,
+			endOffset: bi.offset,
+			verified:  make(chan struct{}),
+		}
+		if bi.verifyScripts {
+			select {
+			case bi.verifyQueue <- item:
+			case <-bi.quit:
+				break out
+			}
+		} else {
+			close(item.verified)
+		}
+
 		// Send the block or quit if we've been signalled to exit by
 		// the status handler due to an error elsewhere.
 		select {
-		case bi.processQueue <- serializedBlock:
+		case bi.processQueue <- item:
 		case <-bi.quit:
 			break out
 		}
+
+		// Scripts are only run by the chain once it is past the
+		// latest checkpoint, so start verifying them after it.
+		if bi.checkpointHash != nil &&
+			bi.checkpointHash.IsEqual(&msgBlock.Header.PrevBlock) {
+			bi.verifyScripts = true
+		}
 	}
 
-	// Close the processing channel to signal no more blocks are coming.
+	// Close the processing channels to signal no more blocks are coming.
 	close(bi.processQueue)
+	close(bi.verifyQueue)
+	bi.wg.Done()
+}
+
+// verifyHandler is the handler for the script verification workers.  Several
+// of them run concurrently, each verifying the scripts of a different block.
+// It must be run as a goroutine.
+func (bi *blockImporter) verifyHandler() {
+out:
+	for {
+		select {
+		case item, ok := <-bi.verifyQueue:
+			// We're done when the channel is closed.
+			if !ok {
+				break out
+			}
+
+			bi.verifyBlockScripts(item.block.MsgBlock())
+			close(item.verified)
+
+		case <-bi.quit:
+			break out
+		}
+	}
 	bi.wg.Done()
 }
 
@@ -210,22 +364,54 @@ func (bi *blockImporter) logProgress() {
 	bi.lastLogTime = now
 }
 
+// saveProgress records how far into the input file the import has gotten so
+// an interrupted import can resume from there.  The database is synced first
+// so the recorded progress never gets ahead of the stored blocks.
+func (bi *blockImporter) saveProgress(item *importItem, force bool) error {
+	if bi.progress == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if !force && now.Sub(bi.lastSaveTime) < progressSaveInterval {
+		return nil
+	}
+	bi.lastSaveTime = now
+
+	if err := bi.db.Sync(); err != nil {
+		return err
+	}
+	bi.progress.Offset = item.endOffset
+	bi.progress.Blocks = bi.lastHeight
+	bi.progress.Hash = item.block.Sha().String()
+	return bi.progress.save()
+}
+
 // processHandler is the main handler for processing blocks.  This allows block
 // processing to take place in parallel with block reads from the import file.
 // It must be run as a goroutine.
 func (bi *blockImporter) processHandler() {
+	var lastItem *importItem
 out:
 	for {
 		select {
-		case serializedBlock, ok := <-bi.processQueue:
+		case item, ok := <-bi.processQueue:
 			// We're done when the channel is closed.
 			if !ok {
 				break out
 			}
 
+			// Wait for the scripts of the block to be verified so
+			// the chain finds the signatures in the cache.
+			select {
+			case <-item.verified:
+			case <-bi.quit:
+				break out
+			}
+
 			bi.blocksProcessed++
 			bi.lastHeight++
-			imported, err := bi.processBlock(serializedBlock)
+			imported, err := bi.processBlock(item.block)
 			if err != nil {
 				bi.errChan <- err
 				break out
@@ -235,12 +421,33 @@ out:
 				bi.blocksImported++
 			}
 
+			// The transactions are now available from the database.
+			bi.pendingTxnsMtx.Lock()
+			for _, tx := range item.block.Transactions() {
+				delete(bi.pendingTxns, *tx.Sha())
+			}
+			bi.pendingTxnsMtx.Unlock()
+
+			if err := bi.saveProgress(item, false); err != nil {
+				log.Warnf("Unable to save import progress: %v",
+					err)
+			}
+			lastItem = item
+
 			bi.logProgress()
 
 		case <-bi.quit:
 			break out
 		}
 	}
+
+	// Save the final progress so an interrupted import resumes from the
+	// last processed block.
+	if lastItem != nil {
+		if err := bi.saveProgress(lastItem, true); err != nil {
+			log.Warnf("Unable to save import progress: %v", err)
+		}
+	}
 	bi.wg.Done()
 }
 
@@ -273,10 +480,14 @@ func (bi *blockImporter) statusHandler(resultsChan chan *importResults) {
 // associated with the block importer to the database.  It returns a channel
 // on which the results will be returned when the operation has completed.
 func (bi *blockImporter) Import() chan *importResults {
-	// Start up the read and process handling goroutines.  This setup allows
-	// blocks to be read from disk in parallel while being processed.
-	bi.wg.Add(2)
+	// Start up the read, script verification, and process handling
+	// goroutines.  This setup allows blocks to be read from disk and
+	// their scripts verified in parallel while being processed.
+	bi.wg.Add(2 + cfg.Workers)
 	go bi.readHandler()
+	for i := 0; i < cfg.Workers; i++ {
+		go bi.verifyHandler()
+	}
 	go bi.processHandler()
 
 	// Wait for the import to finish in a separate goroutine and signal
@@ -293,18 +504,42 @@ func (bi *blockImporter) Import() chan *importResults {
 	return resultChan
 }
 
-// newBlockImporter returns a new importer for the provided file reader seeker
-// and database.
-func newBlockImporter(db database.Db, r io.ReadSeeker) *blockImporter {
-	return &blockImporter{
+// newBlockImporter returns a new importer for the provided file reader and
+// database.  The reader must be positioned at the offset recorded in the
+// passed progress, which may be nil to disable saving progress.
+func newBlockImporter(db database.Db, r io.Reader, progress *importProgress) *blockImporter {
+	sigCache := txscript.NewSigCache(sigCacheMaxSize)
+	chain := blockchain.New(db, activeNetParams, nil, sigCache)
+	bi := &blockImporter{
 		db:           db,
 		r:            r,
-		processQueue: make(chan []byte, 2),
+		processQueue: make(chan *importItem, cfg.Workers*2),
+		verifyQueue:  make(chan *importItem, cfg.Workers),
 		doneChan:     make(chan bool),
 		errChan:      make(chan error),
 		quit:         make(chan struct{}),
-		chain:        blockchain.New(db, activeNetParams, nil, nil),
+		chain:        chain,
 		medianTime:   blockchain.NewMedianTime(),
+		sigCache:     sigCache,
 		lastLogTime:  time.Now(),
+		lastSaveTime: time.Now(),
+		progress:     progress,
+		pendingTxns:  make(map[wire.ShaHash]*wire.MsgTx),
 	}
+	if progress != nil {
+		bi.offset = progress.Offset
+		bi.lastHeight = progress.Blocks
+	}
+
+	// Scripts are verified right away when there is no checkpoint or the
+	// database is already past the latest one.
+	checkpoint := chain.LatestCheckpoint()
+	_, height, err := db.NewestSha()
+	if checkpoint == nil || (err == nil && height >= checkpoint.Height) {
+		bi.verifyScripts = true
+	} else {
+		bi.checkpointHash = checkpoint.Hash
+	}
+
+	return bi
 }
@@ -0,0 +1,142 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// progressFileName is the name of the file in the data directory which
+// records the progress of an import.
+const progressFileName = "addblock.progress"
+
+var (
+	// gzipMagic and xzMagic are the leading bytes of gzip and xz
+	// compressed files.
+	gzipMagic = []byte{0x1f, 0x8b}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// importProgress records how far an import of a block file has gotten so an
+// interrupted import can resume from there.  The offset refers to the
+// uncompressed contents of the file.
+type importProgress struct {
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+	Blocks int64  `json:"blocks"`
+	Hash   string `json:"hash"`
+
+	path string
+}
+
+// loadProgress returns the saved progress of importing the passed file.  The
+// returned progress starts at the beginning of the file when there is no
+// saved progress for it.
+func loadProgress(dataDir, inFile string) (*importProgress, error) {
+	absFile, err := filepath.Abs(inFile)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dataDir, progressFileName)
+	fresh := &importProgress{File: absFile, path: path}
+
+	serialized, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var progress importProgress
+	if err := json.Unmarshal(serialized, &progress); err != nil {
+		log.Warnf("Ignoring unreadable import progress in %s: %v",
+			path, err)
+		return fresh, nil
+	}
+	if progress.File != absFile {
+		return fresh, nil
+	}
+	progress.path = path
+	return &progress, nil
+}
+
+// save writes the progress to the progress file.  A temporary file is written
+// and renamed over the old one so the progress is never left half written.
+func (p *importProgress) save() error {
+	serialized, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tmpPath := p.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, serialized, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, p.path)
+}
+
+// remove deletes the progress file once the import has completed.
+func (p *importProgress) remove() error {
+	err := os.Remove(p.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// openBlockFile opens the passed block file positioned at the passed offset
+// into its uncompressed contents.  Gzip compressed files are decompressed
+// transparently.
+func openBlockFile(file string, offset int64) (io.Reader, io.Closer, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(len(xzMagic))
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, nil, err
+	}
+
+	var r io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		r = zr
+
+	case bytes.HasPrefix(magic, xzMagic):
+		f.Close()
+		return nil, nil, errors.New("xz compressed files are not " +
+			"supported -- decompress the file with xz -d first")
+
+	// Uncompressed files can seek directly to the offset.
+	default:
+		if _, err := f.Seek(offset, os.SEEK_SET); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return bufio.NewReader(f), f, nil
+	}
+
+	if _, err := io.CopyN(ioutil.Discard, r, offset); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return r, f, nil
+}
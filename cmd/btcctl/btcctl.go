@@ -46,16 +46,15 @@ func usage(errorMessage string) {
 	fmt.Fprintln(os.Stderr, listCmdMessage)
 }
 
-func main() {
-	cfg, args, err := loadConfig()
-	if err != nil {
-		os.Exit(1)
-	}
-	if len(args) < 1 {
-		usage("No command specified")
-		os.Exit(1)
-	}
-
+// parseCmd creates the command described by the passed method and arguments
+// and exits with the appropriate message when that fails.  Commands with any
+// of the passed unusable usage flags are rejected.
+//
+// Since some commands, such as submitblock, can involve data which is too
+// large for the Operating System to allow as a normal command line parameter,
+// '-' is supported as an argument to read the argument from the next line of
+// the passed stdin reader.
+func parseCmd(args []string, unusable btcjson.UsageFlag, bio *bufio.Reader) interface{} {
 	// Ensure the specified method identifies a valid registered command and
 	// is one of the usable types.
 	method := args[0]
@@ -65,7 +64,13 @@ func main() {
 		fmt.Fprintln(os.Stderr, listCmdMessage)
 		os.Exit(1)
 	}
-	if usageFlags&unusableFlags != 0 {
+	if usageFlags&unusable&btcjson.UFNotification != 0 {
+		fmt.Fprintf(os.Stderr, "The '%s' method is a notification "+
+			"sent by the server\n", method)
+		fmt.Fprintln(os.Stderr, listCmdMessage)
+		os.Exit(1)
+	}
+	if usageFlags&unusable != 0 {
 		fmt.Fprintf(os.Stderr, "The '%s' command can only be used via "+
 			"websockets\n", method)
 		fmt.Fprintln(os.Stderr, listCmdMessage)
@@ -74,12 +79,6 @@ func main() {
 
 	// Convert remaining command line args to a slice of interface values
 	// to be passed along as parameters to new command creation function.
-	//
-	// Since some commands, such as submitblock, can involve data which is
-	// too large for the Operating System to allow as a normal command line
-	// parameter, support using '-' as an argument to allow the argument
-	// to be read from a stdin pipe.
-	bio := bufio.NewReader(os.Stdin)
 	params := make([]interface{}, 0, len(args[1:]))
 	for _, arg := range args[1:] {
 		if arg == "-" {
@@ -124,6 +123,31 @@ func main() {
 		commandUsage(method)
 		os.Exit(1)
 	}
+	return cmd
+}
+
+func main() {
+	cfg, args, err := loadConfig()
+	if err != nil {
+		os.Exit(1)
+	}
+	if len(args) < 1 {
+		usage("No command specified")
+		os.Exit(1)
+	}
+
+	// Run the commands over a websocket and stream the notifications
+	// they register for when requested.
+	bio := bufio.NewReader(os.Stdin)
+	if cfg.Subscribe {
+		if err := subscribe(cfg, args, bio); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cmd := parseCmd(args, unusableFlags, bio)
 
 	// Marshal the command into a JSON-RPC byte slice in preparation for
 	// sending it to the RPC server.
@@ -19,7 +19,7 @@ import (
 const (
 	// unusableFlags are the command usage flags which this utility are not
 	// able to use.  In particular it doesn't support websockets and
-	// consequently notifications.
+	// consequently notifications outside of subscription mode.
 	unusableFlags = btcjson.UFWebsocketOnly | btcjson.UFNotification
 )
 
@@ -105,6 +105,8 @@ type config struct {
 	SimNet        bool   `long:"simnet" description:"Connect to the simulation test network"`
 	TLSSkipVerify bool   `long:"skipverify" description:"Do not verify tls certificates (not recommended!)"`
 	Wallet        bool   `long:"wallet" description:"Connect to wallet"`
+	Subscribe     bool   `long:"subscribe" description:"Run the commands, separated by ';' arguments, over a websocket and print the notifications they register for as JSON lines"`
+	NoReconnect   bool   `long:"noreconnect" description:"Exit instead of reconnecting when the websocket of --subscribe is lost"`
 }
 
 // normalizeAddress returns addr with the passed default port appended if
@@ -195,6 +197,11 @@ func loadConfig() (*config, []string, error) {
 				"indicates that a parameter should be read "+
 				"from the\nnext unread line from standard "+
 				"input.")
+			fmt.Fprintln(os.Stderr, "")
+			fmt.Fprintln(os.Stderr, "With --subscribe, several "+
+				"commands may be passed separated by `;`, for "+
+				"example:\n  notifyblocks \\; loadtxfilter w1 "+
+				"false '[\"addr\"]' '[]'")
 			return nil, nil, err
 		}
 	}
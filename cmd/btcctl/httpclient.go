@@ -14,47 +14,61 @@ import (
 	"github.com/conseweb/stcd/btcjson"
 )
 
-// newHTTPClient returns a new HTTP client that is configured according to the
-// proxy and TLS settings in the associated connection configuration.
-func newHTTPClient(cfg *config) (*http.Client, error) {
-	// Configure proxy if needed.
-	var dial func(network, addr string) (net.Conn, error)
-	if cfg.Proxy != "" {
-		proxy := &socks.Proxy{
-			Addr:     cfg.Proxy,
-			Username: cfg.ProxyUser,
-			Password: cfg.ProxyPass,
-		}
-		dial = func(network, addr string) (net.Conn, error) {
-			c, err := proxy.Dial(network, addr)
-			if err != nil {
-				return nil, err
-			}
-			return c, nil
-		}
+// proxyDial returns a dial function which connects via the SOCKS5 proxy in the
+// associated connection configuration, or nil when no proxy is configured.
+func proxyDial(cfg *config) func(network, addr string) (net.Conn, error) {
+	if cfg.Proxy == "" {
+		return nil
 	}
-
-	// Configure TLS if needed.
-	var tlsConfig *tls.Config
-	if !cfg.NoTLS && cfg.RPCCert != "" {
-		pem, err := ioutil.ReadFile(cfg.RPCCert)
+	proxy := &socks.Proxy{
+		Addr:     cfg.Proxy,
+		Username: cfg.ProxyUser,
+		Password: cfg.ProxyPass,
+	}
+	return func(network, addr string) (net.Conn, error) {
+		c, err := proxy.Dial(network, addr)
 		if err != nil {
 			return nil, err
 		}
+		return c, nil
+	}
+}
 
-		pool := x509.NewCertPool()
-		pool.AppendCertsFromPEM(pem)
-		tlsConfig = &tls.Config{
-			RootCAs:            pool,
-			InsecureSkipVerify: cfg.TLSSkipVerify,
-		}
+// newTLSConfig returns the TLS configuration described by the associated
+// connection configuration, or nil when the default configuration is to be
+// used.
+func newTLSConfig(cfg *config) (*tls.Config, error) {
+	if cfg.NoTLS || cfg.RPCCert == "" {
+		return nil, nil
+	}
+
+	pem, err := ioutil.ReadFile(cfg.RPCCert)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(pem)
+	return &tls.Config{
+		RootCAs:            pool,
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	}, nil
+}
+
+// newHTTPClient returns a new HTTP client that is configured according to the
+// proxy and TLS settings in the associated connection configuration.
+func newHTTPClient(cfg *config) (*http.Client, error) {
+	// Configure TLS if needed.
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create and return the new HTTP client potentially configured with a
 	// proxy and TLS.
 	client := http.Client{
 		Transport: &http.Transport{
-			Dial:            dial,
+			Dial:            proxyDial(cfg),
 			TLSClientConfig: tlsConfig,
 		},
 	}
@@ -0,0 +1,310 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/websocket"
+)
+
+const (
+	// subscribeCmdSeparator is the argument which separates the commands
+	// passed in subscription mode.
+	subscribeCmdSeparator = ";"
+
+	// reconnectMinDelay and reconnectMaxDelay are the bounds of the delay
+	// before reconnecting a lost websocket.  The delay doubles after each
+	// failed attempt.
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = time.Minute
+)
+
+// errInterrupted is returned by a websocket session which ended because the
+// user interrupted the process.
+var errInterrupted = errors.New("interrupted")
+
+// subscribeCmdError describes a subscription command which was rejected by
+// the server.  Running the command again after reconnecting would fail the
+// same way, so it ends subscription mode.
+type subscribeCmdError struct {
+	method string
+	err    *btcjson.RPCError
+}
+
+// Error satisfies the error interface.
+func (e subscribeCmdError) Error() string {
+	return fmt.Sprintf("%s command: %v", e.method, e.err)
+}
+
+// wsMessage models a message received over the websocket, which is either a
+// response to a command or a notification.
+type wsMessage struct {
+	ID     *int              `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Error  *btcjson.RPCError `json:"error"`
+}
+
+// subscriber runs subscription commands over a websocket and prints the
+// notifications received as JSON lines.  It reconnects when the websocket is
+// lost and replays the events missed in the meantime from the event journal of
+// the server when it is enabled.
+type subscriber struct {
+	methods []string
+	cmds    [][]byte
+
+	// lastBlock is the hash of the most recently connected block seen in
+	// a notification.  It is where the events are replayed from after
+	// reconnecting.
+	lastBlock string
+}
+
+// dialWebsocket opens a websocket to the RPC server described in the passed
+// config struct and authenticates with its credentials.
+func dialWebsocket(cfg *config) (*websocket.Conn, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dialer := websocket.Dialer{
+		NetDial:         proxyDial(cfg),
+		TLSClientConfig: tlsConfig,
+	}
+
+	scheme := "wss"
+	if cfg.NoTLS {
+		scheme = "ws"
+	}
+	login := cfg.RPCUser + ":" + cfg.RPCPassword
+	header := make(http.Header)
+	header.Set("Authorization", "Basic "+
+		base64.StdEncoding.EncodeToString([]byte(login)))
+	conn, resp, err := dialer.Dial(scheme+"://"+cfg.RPCServer+"/ws", header)
+	if err != nil {
+		if err == websocket.ErrBadHandshake && resp != nil {
+			return nil, fmt.Errorf("websocket handshake failed: %s",
+				resp.Status)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// printMessage writes the passed message to stdout as a single line.
+func printMessage(msg json.RawMessage) error {
+	var line bytes.Buffer
+	if err := json.Compact(&line, msg); err != nil {
+		return err
+	}
+	line.WriteByte('\n')
+	_, err := os.Stdout.Write(line.Bytes())
+	return err
+}
+
+// trackBlock updates the most recently connected block from the passed
+// notification.
+func (s *subscriber) trackBlock(msg *wsMessage) {
+	if len(msg.Params) == 0 {
+		return
+	}
+	switch msg.Method {
+	case btcjson.BlockConnectedNtfnMethod:
+		var hash string
+		if json.Unmarshal(msg.Params[0], &hash) == nil {
+			s.lastBlock = hash
+		}
+
+	case btcjson.ReplayedEventNtfnMethod:
+		var event btcjson.JournalEvent
+		if json.Unmarshal(msg.Params[0], &event) == nil &&
+			event.Event == btcjson.BlockConnectedNtfnMethod {
+
+			s.lastBlock = event.Hash
+		}
+	}
+}
+
+// handleMessage prints the passed notification or checks the response to one
+// of the commands sent.  The id of the replayevents command sent after
+// reconnecting is replayID.
+func (s *subscriber) handleMessage(raw json.RawMessage, replayID int) error {
+	var msg wsMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return fmt.Errorf("malformed message: %v", err)
+	}
+
+	// Responses are only checked for errors since the commands just
+	// register for notifications.
+	if msg.ID != nil {
+		if msg.Error == nil {
+			return nil
+		}
+		if *msg.ID == replayID {
+			fmt.Fprintf(os.Stderr, "Unable to replay the events "+
+				"missed since block %s, some notifications may "+
+				"have been lost: %v\n", s.lastBlock, msg.Error)
+			return nil
+		}
+		method := "unknown"
+		if *msg.ID > 0 && *msg.ID <= len(s.methods) {
+			method = s.methods[*msg.ID-1]
+		}
+		return subscribeCmdError{method: method, err: msg.Error}
+	}
+
+	s.trackBlock(&msg)
+	return printMessage(raw)
+}
+
+// session runs the subscription commands over the passed websocket and prints
+// the notifications received until the websocket is lost or the passed quit
+// channel is closed.  The events since the most recently connected block seen
+// are replayed first when resume is set.
+func (s *subscriber) session(conn *websocket.Conn, resume bool, quit <-chan struct{}) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-quit:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for _, cmd := range s.cmds {
+		if err := conn.WriteMessage(websocket.TextMessage, cmd); err != nil {
+			return err
+		}
+	}
+
+	replayID := len(s.cmds) + 1
+	if resume && s.lastBlock != "" {
+		cmd := btcjson.NewReplayEventsCmd(s.lastBlock)
+		marshalled, err := btcjson.MarshalCmd(replayID, cmd)
+		if err != nil {
+			return err
+		}
+		err = conn.WriteMessage(websocket.TextMessage, marshalled)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		_, frame, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-quit:
+				return errInterrupted
+			default:
+			}
+			return err
+		}
+
+		// The server may batch several messages into a single frame
+		// as a JSON array.
+		msgs := []json.RawMessage{frame}
+		if trimmed := bytes.TrimSpace(frame); len(trimmed) > 0 &&
+			trimmed[0] == '[' {
+
+			if err := json.Unmarshal(trimmed, &msgs); err != nil {
+				return fmt.Errorf("malformed message: %v", err)
+			}
+		}
+		for _, msg := range msgs {
+			if err := s.handleMessage(msg, replayID); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// subscribe runs the commands in the passed arguments, separated by
+// subscribeCmdSeparator, over a websocket and prints the notifications they
+// register for to stdout as JSON lines until interrupted.  Unless disabled,
+// the websocket is reestablished when lost and the commands are run again.
+func subscribe(cfg *config, args []string, bio *bufio.Reader) error {
+	var s subscriber
+	for len(args) > 0 {
+		n := 0
+		for n < len(args) && args[n] != subscribeCmdSeparator {
+			n++
+		}
+		if n == 0 {
+			usage("No command specified")
+			os.Exit(1)
+		}
+
+		cmd := parseCmd(args[:n], btcjson.UFNotification, bio)
+		marshalled, err := btcjson.MarshalCmd(len(s.cmds)+1, cmd)
+		if err != nil {
+			return err
+		}
+		s.methods = append(s.methods, args[0])
+		s.cmds = append(s.cmds, marshalled)
+
+		if n < len(args) {
+			n++
+		}
+		args = args[n:]
+	}
+
+	// Stop streaming when interrupted.
+	quit := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		close(quit)
+	}()
+
+	delay := reconnectMinDelay
+	for resume := false; ; resume = true {
+		conn, err := dialWebsocket(cfg)
+		if err == nil {
+			started := time.Now()
+			err = s.session(conn, resume, quit)
+			conn.Close()
+
+			// Start over with the minimum delay once a session
+			// lasted longer than the delay would have grown to.
+			if time.Since(started) > reconnectMaxDelay {
+				delay = reconnectMinDelay
+			}
+		}
+		if _, ok := err.(subscribeCmdError); ok {
+			return err
+		}
+		if err == errInterrupted {
+			return nil
+		}
+		if cfg.NoReconnect {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Websocket lost: %v -- reconnecting "+
+			"in %v\n", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-quit:
+			return nil
+		}
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
@@ -22,7 +22,8 @@ var (
 	cfg *config
 )
 
-// loadBlockDB opens the block database and returns a handle to it.
+// loadBlockDB opens the block database read-only and returns a handle to it.
+// The database may be in use by a running node.
 func loadBlockDB() (database.Db, error) {
 	// The database name is based on the database type.
 	dbType := cfg.DbType
@@ -32,7 +33,7 @@ func loadBlockDB() (database.Db, error) {
 	}
 	dbPath := filepath.Join(cfg.DataDir, dbName)
 	fmt.Printf("Loading block database from '%s'\n", dbPath)
-	db, err := database.OpenDB(dbType, dbPath)
+	db, err := database.OpenReadOnlyDB(dbType, dbPath)
 	if err != nil {
 		return nil, err
 	}
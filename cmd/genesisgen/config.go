@@ -0,0 +1,108 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	flags "github.com/conseweb/go-flags"
+)
+
+const (
+	defaultValue     = 5000000000
+	defaultBits      = "207fffff"
+	defaultOutput    = outputGo
+	defaultVarPrefix = "customNet"
+
+	outputGo   = "go"
+	outputJSON = "json"
+)
+
+// config defines the configuration options for genesisgen.
+//
+// See loadConfig for details on the configuration load process.
+type config struct {
+	Message   string `short:"m" long:"message" description:"Message to embed in the coinbase of the genesis block"`
+	PkScript  string `short:"s" long:"pkscript" description:"Hex encoded public key script of the coinbase output"`
+	Value     int64  `short:"v" long:"value" description:"Value of the coinbase output in satoshi"`
+	Timestamp int64  `short:"t" long:"timestamp" description:"Timestamp of the genesis block in seconds since the Unix epoch (default: now)"`
+	Bits      string `short:"b" long:"bits" description:"Hex encoded target difficulty of the genesis block in compact form"`
+	Output    string `short:"o" long:"output" description:"Output format {go, json}"`
+	VarPrefix string `long:"varprefix" description:"Prefix of the variable names in the Go output"`
+	Name      string `long:"name" description:"Name of the network in the JSON output"`
+	Net       uint32 `long:"net" description:"Magic bytes of the network in the JSON output"`
+	Port      string `long:"port" description:"Default peer-to-peer port of the network in the JSON output"`
+
+	pkScript []byte
+	bits     uint32
+}
+
+// loadConfig initializes and parses the config using command line options.
+func loadConfig() (*config, []string, error) {
+	// Default config.
+	cfg := config{
+		Value:     defaultValue,
+		Bits:      defaultBits,
+		Output:    defaultOutput,
+		VarPrefix: defaultVarPrefix,
+	}
+
+	// Parse command line options.
+	parser := flags.NewParser(&cfg, flags.Default)
+	remainingArgs, err := parser.Parse()
+	if err != nil {
+		if e, ok := err.(*flags.Error); !ok || e.Type != flags.ErrHelp {
+			parser.WriteHelp(os.Stderr)
+		}
+		return nil, nil, err
+	}
+
+	// Validate the options and decode the hex encoded ones.
+	funcName := "loadConfig"
+	var errStr string
+	switch {
+	case cfg.Message == "":
+		errStr = "%s: A coinbase message must be specified with --message"
+	case cfg.PkScript == "":
+		errStr = "%s: A public key script must be specified with --pkscript"
+	case cfg.Value < 0:
+		errStr = "%s: The coinbase value must not be negative"
+	case cfg.Output != outputGo && cfg.Output != outputJSON:
+		errStr = "%s: The output format must be go or json"
+	case cfg.Output == outputJSON &&
+		(cfg.Name == "" || cfg.Net == 0 || cfg.Port == ""):
+		errStr = "%s: The json output requires --name, --net, and --port"
+	}
+	if errStr == "" {
+		cfg.pkScript, err = hex.DecodeString(cfg.PkScript)
+		if err != nil {
+			errStr = "%s: The public key script is not valid hex"
+		}
+	}
+	if errStr == "" {
+		var bits uint64
+		bits, err = strconv.ParseUint(cfg.Bits, 16, 32)
+		if err != nil {
+			errStr = "%s: The bits are not a valid 32-bit hex number"
+		}
+		cfg.bits = uint32(bits)
+	}
+	if errStr != "" {
+		err := fmt.Errorf(errStr, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		parser.WriteHelp(os.Stderr)
+		return nil, nil, err
+	}
+
+	if cfg.Timestamp == 0 {
+		cfg.Timestamp = time.Now().Unix()
+	}
+
+	return &cfg, remainingArgs, nil
+}
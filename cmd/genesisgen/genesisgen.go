@@ -0,0 +1,205 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/wire"
+)
+
+// jsonParams is the subset of the custom network definition read by the
+// --customnet option of btcd which is written by genesisgen.  The version bits
+// thresholds and the address and key prefixes are those of the regression test
+// network, and they, along with the remaining fields, can be changed in the
+// output by hand.
+type jsonParams struct {
+	Name                          string `json:"name"`
+	Net                           uint32 `json:"net"`
+	DefaultPort                   string `json:"defaultport"`
+	GenesisBlock                  string `json:"genesisblock"`
+	GenesisHash                   string `json:"genesishash"`
+	PowLimit                      string `json:"powlimit"`
+	PowLimitBits                  uint32 `json:"powlimitbits"`
+	RuleChangeActivationThreshold uint32 `json:"rulechangeactivationthreshold"`
+	MinerConfirmationWindow       uint32 `json:"minerconfirmationwindow"`
+	PubKeyHashAddrID              byte   `json:"pubkeyhashaddrid"`
+	ScriptHashAddrID              byte   `json:"scripthashaddrid"`
+	PrivateKeyID                  byte   `json:"privatekeyid"`
+	Bech32HRPSegwit               string `json:"bech32hrpsegwit"`
+	HDPrivateKeyID                string `json:"hdprivatekeyid"`
+	HDPublicKeyID                 string `json:"hdpublickeyid"`
+	HDCoinType                    uint32 `json:"hdcointype"`
+}
+
+// writeBytes writes the passed bytes as the elements of a Go byte slice or
+// array literal, eight per line, indented by the passed number of tabs.
+func writeBytes(w io.Writer, b []byte, indent int) {
+	tabs := strings.Repeat("\t", indent)
+	for i := 0; i < len(b); i += 8 {
+		end := i + 8
+		if end > len(b) {
+			end = len(b)
+		}
+		elems := make([]string, 0, end-i)
+		for _, v := range b[i:end] {
+			elems = append(elems, fmt.Sprintf("0x%02x", v))
+		}
+		fmt.Fprintf(w, "%s%s,\n", tabs, strings.Join(elems, ", "))
+	}
+}
+
+// writeHash writes the passed hash as a Go variable with the passed name.
+func writeHash(w io.Writer, name string, hash *wire.ShaHash) {
+	fmt.Fprintf(w, "var %s = wire.ShaHash([wire.HashSize]byte{ // "+
+		"Make go vet happy.\n", name)
+	writeBytes(w, hash[:], 1)
+	fmt.Fprintf(w, "})\n")
+}
+
+// writeGo writes the passed genesis block as Go variables in the same form as
+// the genesis blocks in the chaincfg package.
+func writeGo(w io.Writer, prefix string, block *wire.MsgBlock) {
+	coinbase := block.Transactions[0]
+	txIn := coinbase.TxIn[0]
+	txOut := coinbase.TxOut[0]
+	header := &block.Header
+	hash := block.BlockSha()
+
+	fmt.Fprintf(w, "// %sGenesisCoinbaseTx is the coinbase transaction for "+
+		"the genesis block.\n", prefix)
+	fmt.Fprintf(w, "var %sGenesisCoinbaseTx = wire.MsgTx{\n", prefix)
+	fmt.Fprintf(w, "\tVersion: %d,\n", coinbase.Version)
+	fmt.Fprintf(w, "\tTxIn: []*wire.TxIn{\n\t\t{\n")
+	fmt.Fprintf(w, "\t\t\tPreviousOutPoint: wire.OutPoint{\n")
+	fmt.Fprintf(w, "\t\t\t\tHash:  wire.ShaHash{},\n")
+	fmt.Fprintf(w, "\t\t\t\tIndex: 0x%08x,\n", txIn.PreviousOutPoint.Index)
+	fmt.Fprintf(w, "\t\t\t},\n\t\t\tSignatureScript: []byte{\n")
+	writeBytes(w, txIn.SignatureScript, 4)
+	fmt.Fprintf(w, "\t\t\t},\n\t\t\tSequence: 0x%08x,\n", txIn.Sequence)
+	fmt.Fprintf(w, "\t\t},\n\t},\n")
+	fmt.Fprintf(w, "\tTxOut: []*wire.TxOut{\n\t\t{\n")
+	fmt.Fprintf(w, "\t\t\tValue: 0x%x,\n", txOut.Value)
+	fmt.Fprintf(w, "\t\t\tPkScript: []byte{\n")
+	writeBytes(w, txOut.PkScript, 4)
+	fmt.Fprintf(w, "\t\t\t},\n\t\t},\n\t},\n")
+	fmt.Fprintf(w, "\tLockTime: %d,\n}\n\n", coinbase.LockTime)
+
+	fmt.Fprintf(w, "// %sGenesisHash is the hash of the genesis block.\n",
+		prefix)
+	fmt.Fprintf(w, "// %v\n", hash)
+	writeHash(w, prefix+"GenesisHash", &hash)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "// %sGenesisMerkleRoot is the hash of the coinbase "+
+		"transaction of the genesis block.\n", prefix)
+	fmt.Fprintf(w, "// %v\n", header.MerkleRoot)
+	writeHash(w, prefix+"GenesisMerkleRoot", &header.MerkleRoot)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "// %sGenesisBlock defines the genesis block of the "+
+		"block chain.\n", prefix)
+	fmt.Fprintf(w, "var %sGenesisBlock = wire.MsgBlock{\n", prefix)
+	fmt.Fprintf(w, "\tHeader: wire.BlockHeader{\n")
+	fmt.Fprintf(w, "\t\tVersion:    %d,\n", header.Version)
+	fmt.Fprintf(w, "\t\tPrevBlock:  wire.ShaHash{},\n")
+	fmt.Fprintf(w, "\t\tMerkleRoot: %sGenesisMerkleRoot,\n", prefix)
+	fmt.Fprintf(w, "\t\tTimestamp:  time.Unix(%d, 0), // %v\n",
+		header.Timestamp.Unix(), header.Timestamp.UTC())
+	fmt.Fprintf(w, "\t\tBits:       0x%08x,\n", header.Bits)
+	fmt.Fprintf(w, "\t\tNonce:      0x%08x, // %d\n", header.Nonce,
+		header.Nonce)
+	fmt.Fprintf(w, "\t},\n")
+	fmt.Fprintf(w, "\tTransactions: []*wire.MsgTx{&%sGenesisCoinbaseTx},\n",
+		prefix)
+	fmt.Fprintf(w, "}\n")
+}
+
+// writeJSON writes the passed genesis block as a custom network definition
+// which can be loaded with the --customnet option of btcd.
+func writeJSON(w io.Writer, cfg *config, block *wire.MsgBlock) error {
+	var buf bytes.Buffer
+	if err := block.Serialize(&buf); err != nil {
+		return err
+	}
+	hash := block.BlockSha()
+
+	params := jsonParams{
+		Name:         cfg.Name,
+		Net:          cfg.Net,
+		DefaultPort:  cfg.Port,
+		GenesisBlock: hex.EncodeToString(buf.Bytes()),
+		GenesisHash:  hash.String(),
+		PowLimit: fmt.Sprintf("%064x",
+			blockchain.CompactToBig(block.Header.Bits)),
+		PowLimitBits: block.Header.Bits,
+	}
+	regNet := &chaincfg.RegressionNetParams
+	params.RuleChangeActivationThreshold = regNet.RuleChangeActivationThreshold
+	params.MinerConfirmationWindow = regNet.MinerConfirmationWindow
+	params.PubKeyHashAddrID = regNet.PubKeyHashAddrID
+	params.ScriptHashAddrID = regNet.ScriptHashAddrID
+	params.PrivateKeyID = regNet.PrivateKeyID
+	params.Bech32HRPSegwit = regNet.Bech32HRPSegwit
+	params.HDPrivateKeyID = hex.EncodeToString(regNet.HDPrivateKeyID[:])
+	params.HDPublicKeyID = hex.EncodeToString(regNet.HDPublicKeyID[:])
+	params.HDCoinType = regNet.HDCoinType
+	serialized, err := json.MarshalIndent(&params, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", serialized)
+	return err
+}
+
+func main() {
+	cfg, _, err := loadConfig()
+	if err != nil {
+		os.Exit(1)
+	}
+
+	// Stop searching for a nonce on an interrupt.
+	quit := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		close(quit)
+	}()
+
+	tmpl := chaincfg.GenesisTemplate{
+		CoinbaseMessage: []byte(cfg.Message),
+		PkScript:        cfg.pkScript,
+		Value:           cfg.Value,
+		Timestamp:       time.Unix(cfg.Timestamp, 0),
+		Bits:            cfg.bits,
+	}
+	block, err := chaincfg.GenerateGenesisBlock(&tmpl, quit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot generate genesis block: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch cfg.Output {
+	case outputJSON:
+		err = writeJSON(os.Stdout, cfg, block)
+	default:
+		writeGo(os.Stdout, cfg.VarPrefix, block)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot write genesis block: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// secondsPerDay is the number of seconds in a day which is the unit the age of
+// spent coins is measured in.
+const secondsPerDay = 24 * 60 * 60
+
+// coinAgeIndexer maintains the coin age index which holds the value spent and
+// the coin days destroyed by each block in the main chain along with the total
+// coin days destroyed by the chain up to the block.
+//
+// Like the committed filter index, the statistics are stored by block hash so
+// disconnecting a block only requires rewinding the index tip.
+type coinAgeIndexer struct {
+	db database.Db
+}
+
+// Ensure the coinAgeIndexer type implements the Indexer interface.
+var _ Indexer = (*coinAgeIndexer)(nil)
+
+// newCoinAgeIndexer creates a new coin age indexer to be added to the index
+// manager.
+func newCoinAgeIndexer() *coinAgeIndexer {
+	return &coinAgeIndexer{}
+}
+
+// Name returns the name of the index.  It is part of the Indexer interface.
+func (c *coinAgeIndexer) Name() string {
+	return "coin age"
+}
+
+// Init sets the database holding the index.  It is part of the Indexer
+// interface.
+func (c *coinAgeIndexer) Init(db database.Db) error {
+	c.db = db
+	return nil
+}
+
+// Tip returns the hash and height of the most recent block with coin age
+// statistics in the index.  It is part of the Indexer interface.
+func (c *coinAgeIndexer) Tip() (*wire.ShaHash, int32, error) {
+	sha, height, err := c.db.FetchCoinAgeIndexTip()
+	if err == database.ErrCoinAgeIndexDoesNotExist {
+		err = nil
+	}
+	return sha, height, err
+}
+
+// ConnectBlock computes the coin age statistics of the passed block and makes
+// it the tip of the index.  It is part of the Indexer interface.
+func (c *coinAgeIndexer) ConnectBlock(block *coinutil.Block) error {
+	prevCoinAge := &database.BlockCoinAge{}
+	if block.Height() > 0 {
+		var err error
+		prevCoinAge, err = c.db.FetchCoinAgeBySha(
+			&block.MsgBlock().Header.PrevBlock)
+		if err != nil {
+			return err
+		}
+	}
+
+	coinAge, err := c.blockCoinAge(block)
+	if err != nil {
+		return err
+	}
+	coinAge.TotalCoinDaysDestroyed = prevCoinAge.TotalCoinDaysDestroyed +
+		coinAge.CoinDaysDestroyed
+	return c.db.UpdateCoinAgeIndexForBlock(block.Sha(), block.Height(),
+		coinAge)
+}
+
+// DisconnectBlock rewinds the tip of the index to the most recent main chain
+// block below the passed height which has coin age statistics.  The
+// statistics themselves are kept.  The index is deleted when no such block is
+// left so it is built again.  It is part of the Indexer interface.
+func (c *coinAgeIndexer) DisconnectBlock(sha *wire.ShaHash, height int32) error {
+	_, bestHeight, err := c.db.NewestSha()
+	if err != nil {
+		return err
+	}
+	if height > bestHeight+1 {
+		height = bestHeight + 1
+	}
+	for height--; height >= 0; height-- {
+		sha, err := c.db.FetchBlockShaByHeight(height)
+		if err != nil {
+			return err
+		}
+		coinAge, err := c.db.FetchCoinAgeBySha(sha)
+		if err == database.ErrCoinAgeMissing {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return c.db.UpdateCoinAgeIndexForBlock(sha, height, coinAge)
+	}
+	return c.db.DeleteCoinAgeIndex()
+}
+
+// blockCoinAge returns the value spent and the coin days destroyed by the
+// passed block.  The total coin days destroyed is left for the caller to fill
+// in.
+func (c *coinAgeIndexer) blockCoinAge(block *coinutil.Block) (*database.BlockCoinAge, error) {
+	db := c.db
+	blockTime := block.MsgBlock().Header.Timestamp
+
+	// Several inputs commonly spend outputs created in the same block, so
+	// cache the timestamps of the blocks looked up.
+	blockTimes := make(map[wire.ShaHash]time.Time)
+	var coinAge database.BlockCoinAge
+	for _, tx := range block.Transactions() {
+		// Coinbases don't have any inputs.
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+
+		for _, txIn := range tx.MsgTx().TxIn {
+			prevOut := txIn.PreviousOutPoint
+			txList, err := db.FetchTxBySha(&prevOut.Hash)
+			if err != nil {
+				return nil, err
+			}
+			if len(txList) == 0 {
+				return nil, fmt.Errorf("transaction %v not found",
+					prevOut.Hash)
+			}
+			prevTx := txList[len(txList)-1]
+			if prevOut.Index >= uint32(len(prevTx.Tx.TxOut)) {
+				return nil, fmt.Errorf("output %v does not exist",
+					prevOut)
+			}
+			value := prevTx.Tx.TxOut[prevOut.Index].Value
+
+			prevTime, ok := blockTimes[*prevTx.BlkSha]
+			if !ok {
+				header, err := db.FetchBlockHeaderBySha(prevTx.BlkSha)
+				if err != nil {
+					return nil, err
+				}
+				prevTime = header.Timestamp
+				blockTimes[*prevTx.BlkSha] = prevTime
+			}
+
+			// Block timestamps are not strictly increasing, so the
+			// age of an output spent shortly after it was created
+			// may come out negative.
+			age := blockTime.Sub(prevTime)
+			if age < 0 {
+				age = 0
+			}
+
+			coinAge.SpentValue += value
+			coinAge.CoinDaysDestroyed += float64(value) /
+				coinutil.SatoshiPerBitcoin *
+				age.Seconds() / secondsPerDay
+		}
+	}
+	return &coinAge, nil
+}
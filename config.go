@@ -7,7 +7,9 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -19,9 +21,12 @@ import (
 	"github.com/conseweb/coinutil"
 	flags "github.com/conseweb/go-flags"
 	"github.com/conseweb/go-socks/socks"
+	"github.com/conseweb/stcd/acme"
+	"github.com/conseweb/stcd/blockchain"
 	"github.com/conseweb/stcd/database"
 	_ "github.com/conseweb/stcd/database/ldb"
 	_ "github.com/conseweb/stcd/database/memdb"
+	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
 )
 
@@ -31,18 +36,48 @@ const (
 	defaultLogLevel          = "info"
 	defaultLogDirname        = "logs"
 	defaultLogFilename       = "stcd.log"
+	defaultLogBuffer         = 500
+	debugLevelsFilename      = "debuglevels"
 	defaultMaxPeers          = 125
 	defaultBanDuration       = time.Hour * 24
+	defaultBanThreshold      = 100
+	defaultInboundRatePerIP  = 6
+	defaultInboundRate       = 120
+	defaultInboundIPv4Prefix = 32
+	defaultInboundIPv6Prefix = 64
+	defaultMaxClockSkew      = time.Minute * 10
 	defaultMaxRPCClients     = 10
 	defaultMaxRPCWebsockets  = 25
+	defaultMaxRPCROClients   = 50
+	defaultMaxRPCConns       = 200
+	defaultRPCReadTimeout    = time.Second * 10
+	defaultRPCIdleTimeout    = time.Minute * 2
+	defaultRPCMaxHeaderBytes = 1 << 16
+	defaultMaxWatchedAddrs   = 100000
+	defaultMaxWatchedOutPts  = 100000
+	defaultRPCAuthFailures   = 5
+	defaultRPCAuthLockout    = time.Minute
+	defaultACMEDirectory     = acme.LetsEncryptURL
+	defaultACMEListen        = ":80"
+	defaultRPCShutdownGrace  = time.Second * 5
+	defaultNotifyTimeout     = time.Minute
+	defaultAssumeUtxoFile    = "utxosnapshot.dat"
+	defaultMempoolExpiry     = 336
+	defaultMaxAncestors      = 25
+	defaultMaxAncestorSize   = 101
+	defaultMaxDescendants    = 25
+	defaultMaxDescendantSize = 101
 	defaultVerifyEnabled     = false
 	defaultDbType            = "leveldb"
+	defaultCheckBlocks       = 6
+	defaultCheckLevel        = 3
 	defaultFreeTxRelayLimit  = 15.0
 	defaultBlockMinSize      = 0
 	defaultBlockMaxSize      = 750000
 	blockMaxSizeMin          = 1000
-	blockMaxSizeMax          = wire.MaxBlockPayload - 1000
+	blockMaxSizeMax          = blockchain.MaxBlockBaseSize - 1000
 	defaultBlockPrioritySize = 50000
+	defaultRecentTxWeight    = 1.0
 	defaultGenerate          = false
 	defaultAddrIndex         = false
 	defaultSigCacheMaxSize   = 50000
@@ -85,15 +120,54 @@ type config struct {
 	Listeners          []string      `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 6682, testnet: 16682)"`
 	MaxPeers           int           `long:"maxpeers" description:"Max number of inbound and outbound peers"`
 	BanDuration        time.Duration `long:"banduration" description:"How long to ban misbehaving peers.  Valid time units are {s, m, h}.  Minimum 1 second"`
+	BanThreshold       uint32        `long:"banthreshold" description:"Misbehavior score at which peers are banned and disconnected -- Scores halve every 10 minutes.  Minimum 1"`
+	InboundRatePerIP   int           `long:"inboundrateperip" description:"Max number of inbound connections per minute from a single IP, or network of the inboundipv4prefix and inboundipv6prefix lengths -- IPs exceeding it are refused for 30 seconds, doubling with every repeated offense up to an hour -- 0 disables the limit"`
+	InboundIPv4Prefix  int           `long:"inboundipv4prefix" description:"Prefix length of the IPv4 networks counted as a single IP by inboundrateperip, such as 24 -- Between 1 and 32"`
+	InboundIPv6Prefix  int           `long:"inboundipv6prefix" description:"Prefix length of the IPv6 networks counted as a single IP by inboundrateperip -- Between 1 and 128"`
+	InboundRate        int           `long:"inboundrate" description:"Max number of inbound connections per minute in total -- 0 disables the limit"`
+	InboundChallenge   bool          `long:"inboundchallenge" description:"Accept inbound connections exceeding the inboundrate limit and require the peers to answer a ping before they are added instead of refusing them"`
+	Whitelists         []string      `long:"whitelist" description:"Add an IP network or IP that is exempt from the inbound connection rate limits and challenges"`
+	ASMap              string        `long:"asmap" description:"Path to a file mapping IP prefixes to the autonomous systems announcing them, used by getnetworktopology to report the distribution of peers over autonomous systems -- Each line holds a prefix in CIDR notation, an AS number and optionally the name of the AS"`
+	MaxClockSkew       time.Duration `long:"maxclockskew" description:"Warn when the local clock differs from the median time of peers by more than this.  Valid time units are {s, m, h}.  Minimum 1 second"`
 	RPCUser            string        `short:"u" long:"rpcuser" description:"Username for RPC connections"`
 	RPCPass            string        `short:"P" long:"rpcpass" default-mask:"-" description:"Password for RPC connections"`
 	RPCLimitUser       string        `long:"rpclimituser" description:"Username for limited RPC connections"`
 	RPCLimitPass       string        `long:"rpclimitpass" default-mask:"-" description:"Password for limited RPC connections"`
 	RPCListeners       []string      `long:"rpclisten" description:"Add an interface/port to listen for RPC connections (default port: 6684, testnet: 16684)"`
+	RPCReadOnlyListen  []string      `long:"rpcreadonlylisten" description:"Add an interface/port to listen for read-only RPC connections which may only call query methods"`
+	RPCReadOnlyUser    string        `long:"rpcreadonlyuser" description:"Username for read-only RPC connections -- read-only connections require no authentication if unset"`
+	RPCReadOnlyPass    string        `long:"rpcreadonlypass" default-mask:"-" description:"Password for read-only RPC connections"`
 	RPCCert            string        `long:"rpccert" description:"File containing the certificate file"`
 	RPCKey             string        `long:"rpckey" description:"File containing the certificate key"`
+	RPCACMEDomains     []string      `long:"rpcacmedomain" description:"Obtain the RPC TLS certificate for this domain from an ACME certificate authority such as Let's Encrypt and renew it before it expires -- may be specified multiple times for more domains"`
+	RPCACMEEmail       string        `long:"rpcacmeemail" description:"Contact email address of the ACME account"`
+	RPCACMEDirectory   string        `long:"rpcacmedirectory" description:"Directory URL of the ACME certificate authority"`
+	RPCACMEListen      string        `long:"rpcacmelisten" description:"Interface/port to serve the ACME http-01 challenges on while obtaining certificates -- must be reachable on port 80 of every domain"`
 	RPCMaxClients      int           `long:"rpcmaxclients" description:"Max number of RPC clients for standard connections"`
 	RPCMaxWebsockets   int           `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
+	RPCMaxROClients    int           `long:"rpcreadonlymaxclients" description:"Max number of RPC clients for read-only connections, which are limited separately from the standard ones"`
+	RPCMaxConns        int           `long:"rpcmaxconns" description:"Max number of open connections on the standard RPC listeners, including idle keep-alive and websocket connections -- 0 disables the limit"`
+	RPCMaxROConns      int           `long:"rpcreadonlymaxconns" description:"Max number of open connections on the read-only RPC listeners, including idle keep-alive connections -- 0 disables the limit"`
+	RPCReadTimeout     time.Duration `long:"rpcreadtimeout" description:"Max time RPC clients may take to send a request.  Valid time units are {s, m, h}"`
+	RPCWriteTimeout    time.Duration `long:"rpcwritetimeout" description:"How long RPC clients may take to receive a response, not including the time spent handling the request -- 0 disables the timeout.  Valid time units are {s, m, h}"`
+	RPCIdleTimeout     time.Duration `long:"rpcidletimeout" description:"How long idle keep-alive connections to the RPC server are kept open -- 0 disables keep-alive.  Valid time units are {s, m, h}"`
+	RPCMaxHeaderBytes  int           `long:"rpcmaxheaderbytes" description:"Max size of the headers of RPC requests in bytes"`
+	RPCNoHTTP2         bool          `long:"rpcnohttp2" description:"Disable HTTP/2 on the TLS RPC listeners"`
+	RPCMaxWatchAddrs   int           `long:"rpcmaxwatchedaddrs" description:"Max number of addresses and script hashes each websocket client may watch, including those of its transaction filters -- 0 disables the limit"`
+	RPCMaxWatchOuts    int           `long:"rpcmaxwatchedoutpoints" description:"Max number of outpoints each websocket client may watch, including those of its transaction filters -- 0 disables the limit"`
+	RPCWSBatchBytes    int           `long:"rpcwsbatchbytes" description:"Combine websocket notifications waiting to be sent into frames of up to this many bytes holding a JSON array of them -- 0 sends every notification in its own frame"`
+	RPCWSBatchInterval time.Duration `long:"rpcwsbatchinterval" description:"How long websocket notifications are held back for more of them to join the same frame when batching is enabled.  Valid time units are {ms, s}"`
+	RPCAuthFailures    int           `long:"rpcauthmaxfailures" description:"Lock out RPC client addresses after this many failed authentication attempts over HTTP or websockets -- 0 disables the lockouts"`
+	RPCAuthLockout     time.Duration `long:"rpcauthlockout" description:"How long RPC client addresses are locked out after failing to authenticate too often.  Every further lockout of the same address is twice as long, up to 24 hours.  Valid time units are {s, m, h}"`
+	RPCAuthFail2Ban    bool          `long:"rpcauthfail2ban" description:"Log failed RPC authentication attempts as 'Failed RPC authentication from <IP>' so they can be matched by fail2ban"`
+	RPCRateLimit       float64       `long:"rpcratelimit" description:"Max number of RPC requests per second each client address may make -- 0 disables the limit"`
+	RPCExpensiveLimit  float64       `long:"rpcexpensiveratelimit" description:"Max number of expensive RPC requests such as getblock, searchrawtransactions and rescan per second each client address may make -- 0 disables the limit"`
+	RPCMaxExpensive    int           `long:"rpcmaxexpensive" description:"Max number of expensive RPC requests served concurrently -- 0 disables the limit"`
+	RPCHelpLocale      string        `long:"rpchelplocale" description:"Locale of the descriptions in the RPC help and API schema"`
+	RPCShutdownGrace   time.Duration `long:"rpcshutdowngrace" description:"How long in-flight RPC requests are given to complete and websocket clients to disconnect on shutdown.  Valid time units are {s, m, h}"`
+	RPCAlertWebhooks   []string      `long:"rpcalertwebhook" description:"POST the alerts broadcast with the broadcastalert RPC as JSON to the specified http or https URL (may be used multiple times)"`
+	EventJournal       int           `long:"eventjournal" description:"Journal the block and transaction notification events of this many most recent blocks in the data directory so websocket clients can replay them -- 0 disables the journal"`
+	REST               bool          `long:"rest" description:"Serve unauthenticated REST requests for blocks, headers, transactions, memory pool contents and chain info on the RPC listeners"`
 	DisableRPC         bool          `long:"norpc" description:"Disable built-in RPC server -- NOTE: The RPC server is disabled by default if no rpcuser/rpcpass or rpclimituser/rpclimitpass is specified"`
 	DisableTLS         bool          `long:"notls" description:"Disable TLS for the RPC server -- NOTE: This is only allowed if the RPC server is bound to localhost"`
 	DisableDNSSeed     bool          `long:"nodnsseed" description:"Disable DNS seeding for peers"`
@@ -109,32 +183,82 @@ type config struct {
 	TestNet3           bool          `long:"testnet" description:"Use the test network"`
 	RegressionTest     bool          `long:"regtest" description:"Use the regression test network"`
 	SimNet             bool          `long:"simnet" description:"Use the simulation test network"`
+	CustomNet          string        `long:"customnet" description:"Use the custom network defined by the parameters in the given JSON file"`
 	DisableCheckpoints bool          `long:"nocheckpoints" description:"Disable built-in checkpoints.  Don't do this unless you know what you're doing."`
+	AssumeValid        string        `long:"assumevalid" description:"Skip script validation for the ancestors of this block during the initial block download -- 0 validates all scripts (default: the newest checkpoint of the selected network, if any)"`
+	AssumeUtxo         string        `long:"assumeutxo" description:"Bootstrap the node from the UTXO snapshot with this hash, as reported by dumputxoset, while the blocks before its base block are downloaded and validated in the background"`
+	AssumeUtxoFile     string        `long:"assumeutxofile" description:"Path to the UTXO snapshot used by assumeutxo -- Relative paths are in the data directory"`
+	HeadersOnly        bool          `long:"headersonly" description:"Only download and validate block headers without downloading or storing the blocks -- Useful for monitoring nodes which only track the chain tip and reorganizations"`
+	BlockNotify        string        `long:"blocknotify" description:"Execute the command when a block is connected to the main chain -- %s in the command is replaced by the block hash"`
+	TxNotify           string        `long:"txnotify" description:"Execute the command when a transaction paying to one of the txnotifyaddr addresses is accepted to the memory pool or mined -- %s in the command is replaced by the transaction hash"`
+	TxNotifyAddrs      []string      `long:"txnotifyaddr" description:"Add the specified address to the list of addresses whose transactions execute the txnotify command"`
+	NotifyTimeout      time.Duration `long:"notifytimeout" description:"How long a blocknotify or txnotify command may run before it is killed.  Valid time units are {s, m, h}.  Minimum 1 second"`
 	DbType             string        `long:"dbtype" description:"Database backend to use for the Block Chain"`
+	CheckBlocks        int           `long:"checkblocks" description:"Number of most recent blocks to verify on start up -- The chain is rolled back to the last consistent block when corruption is detected -- 0 verifies all blocks"`
+	CheckLevel         int           `long:"checklevel" description:"How thorough the start up verification is {0: block index, 1: block sanity, 2: transactions and spent outputs, 3: optional indexes}"`
+	CompactWindow      string        `long:"compactwindow" description:"Compact the database once a day within this time window in local time, such as 02:00-04:00, to reclaim the space of deleted and overwritten data -- Compaction can also be started with the compactdatabase RPC"`
+	LoadBlocks         []string      `long:"loadblock" description:"Import blocks from the specified bootstrap file on start up -- Files placed in the blocks/import directory of the data directory are also imported"`
 	Profile            string        `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
 	CPUProfile         string        `long:"cpuprofile" description:"Write CPU profile to the specified file"`
 	DebugLevel         string        `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
+	LogBuffer          int           `long:"logbuffer" description:"Number of recent log lines to keep in memory per subsystem for the getlog RPC -- 0 disables log capture"`
 	Upnp               bool          `long:"upnp" description:"Use UPnP to map our listening port outside of NAT"`
 	MinRelayTxFee      float64       `long:"minrelaytxfee" description:"The minimum transaction fee in BTC/kB to be considered a non-zero fee."`
 	FreeTxRelayLimit   float64       `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
 	NoRelayPriority    bool          `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
 	MaxOrphanTxs       int           `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
+	MempoolExpiry      int           `long:"mempoolexpiry" description:"Evict transactions which have not been mined within this many hours of entering the memory pool -- 0 disables expiry"`
+	MaxAncestors       int           `long:"limitancestorcount" description:"Do not accept transactions with more than this many unconfirmed ancestors in the memory pool, including the transaction itself -- 0 disables the limit"`
+	MaxAncestorSize    int           `long:"limitancestorsize" description:"Do not accept transactions whose unconfirmed ancestors in the memory pool, including the transaction itself, exceed this many thousands of virtual bytes -- 0 disables the limit"`
+	MaxDescendants     int           `long:"limitdescendantcount" description:"Do not accept transactions which would give an unconfirmed transaction in the memory pool more than this many descendants, including itself -- 0 disables the limit"`
+	MaxDescendantSize  int           `long:"limitdescendantsize" description:"Do not accept transactions which would make the descendants of an unconfirmed transaction in the memory pool, including itself, exceed this many thousands of virtual bytes -- 0 disables the limit"`
+	NoDataCarrier      bool          `long:"nodatacarrier" description:"Do not relay or mine transactions with nulldata (OP_RETURN) outputs"`
+	DataCarrierSize    int           `long:"datacarriersize" description:"Maximum number of bytes a relayed or mined nulldata (OP_RETURN) output may carry"`
+	DataCarrierCount   int           `long:"datacarriercount" description:"Maximum number of nulldata (OP_RETURN) outputs a relayed or mined transaction may have"`
+	RejectBareMultisig bool          `long:"rejectbaremultisig" description:"Do not relay or mine transactions with outputs which pay to a multi-signature script directly rather than through pay-to-script-hash"`
+	DustRelayFee       float64       `long:"dustrelayfee" description:"The fee rate in BTC/kB used to determine whether an output is dust -- Outputs which cost more than a third of their value to spend at this rate are not relayed or mined -- Defaults to the minrelaytxfee option"`
+	MaxSigOpsPerTx     int           `long:"maxsigopspertx" description:"Max number of signature operations in a relayed or mined transaction"`
+	TxBlacklist        string        `long:"txblacklist" description:"Path to a file of banned transactions which are neither accepted into the memory pool nor relayed -- Each line holds a txid, an outpoint as <txid>:<index>, an output script as script:<hex>, a script prefix as script:<hex>* or an address as address:<address>.  Entries can be changed with the addfilterentry and removefilterentry RPCs"`
 	Generate           bool          `long:"generate" description:"Generate (mine) xcoins using the CPU"`
 	MiningAddrs        []string      `long:"miningaddr" description:"Add the specified payment address to the list of addresses to use for generated blocks -- At least one address is required if the generate option is set"`
 	BlockMinSize       uint32        `long:"blockminsize" description:"Mininum block size in bytes to be used when creating a block"`
 	BlockMaxSize       uint32        `long:"blockmaxsize" description:"Maximum block size in bytes to be used when creating a block"`
 	BlockPrioritySize  uint32        `long:"blockprioritysize" description:"Size in bytes for high-priority/low-fee transactions when creating a block"`
+	BlockMinFeeRate    float64       `long:"blockminfeerate" description:"The minimum fee rate in BTC/kB of transactions included in a block beyond the minimum block size and the high-priority area"`
+	BlockMinPriority   float64       `long:"blockminpriority" description:"The minimum priority of transactions included in the high-priority area of a block"`
+	RecentTxAge        time.Duration `long:"blockrecenttxage" description:"Transactions seen more recently than this are weighted by blockrecenttxweight when ordering them by fee for a block -- 0 disables the weighting"`
+	RecentTxWeight     float64       `long:"blockrecenttxweight" description:"The factor, greater than 0 and at most 1, the fee rate of recent transactions is multiplied by when ordering transactions by fee for a block"`
 	GetWorkKeys        []string      `long:"getworkkey" description:"DEPRECATED -- Use the --miningaddr option instead"`
 	AddrIndex          bool          `long:"addrindex" description:"Build and maintain a full address index. Currently only supported by leveldb."`
 	DropAddrIndex      bool          `long:"dropaddrindex" description:"Deletes the address-based transaction index from the database on start up, and then exits."`
+	CFIndex            bool          `long:"cfindex" description:"Build and maintain a committed block filter index and serve the filters to light clients. Currently only supported by leveldb."`
+	DropCFIndex        bool          `long:"dropcfindex" description:"Deletes the committed block filter index from the database on start up, and then exits."`
+	CoinAgeIndex       bool          `long:"coinageindex" description:"Build and maintain an index of the coin days destroyed by each block. Currently only supported by leveldb."`
+	DropCoinAgeIndex   bool          `long:"dropcoinageindex" description:"Deletes the coin age index from the database on start up, and then exits."`
+	STXOIndex          bool          `long:"stxoindex" description:"Build and maintain an index of the transaction input which spends each output. Currently only supported by leveldb."`
+	DropSTXOIndex      bool          `long:"dropstxoindex" description:"Deletes the spent output index from the database on start up, and then exits."`
+	AddrBalanceIndex   bool          `long:"addrbalanceindex" description:"Build and maintain an index of the balance, unspent outputs and balance changes of each address. Currently only supported by leveldb."`
+	DropBalanceIndex   bool          `long:"dropaddrbalanceindex" description:"Deletes the address balance index from the database on start up, and then exits."`
 	NoPeerBloomFilters bool          `long:"nopeerbloomfilters" description:"Disable bloom filtering support."`
 	SigCacheMaxSize    uint          `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache."`
+	ScriptMaxSteps     int           `long:"scriptmaxsteps" description:"Maximum number of opcodes executed to verify the scripts of a transaction input of a transaction submitted to the memory pool -- 0 disables the limit"`
+	ScriptTimeout      time.Duration `long:"scripttimeout" description:"Maximum time spent executing the scripts of a transaction input of a transaction submitted to the memory pool -- Transactions exceeding it are rejected without being considered invalid.  Blocks are never limited.  Valid time units are {ms, s} -- 0 disables the limit"`
 	onionlookup        func(string) ([]net.IP, error)
 	lookup             func(string) ([]net.IP, error)
 	oniondial          func(string, string) (net.Conn, error)
 	dial               func(string, string) (net.Conn, error)
 	miningAddrs        []coinutil.Address
+	txNotifyAddrs      []coinutil.Address
+	whitelists         []*net.IPNet
+	assumeValid        *wire.ShaHash
+	assumeUtxo         *wire.ShaHash
+	compactWindow      *compactWindow
+	asMap              *asMap
+	txBlacklist        *txBlacklist
 	minRelayTxFee      coinutil.Amount
+	dustRelayFee       coinutil.Amount
+	blockMinFeeRate    coinutil.Amount
+	scriptBudget       txscript.Budget
 }
 
 // serviceOptions defines the configuration options for xcoind as a service on
@@ -241,6 +365,69 @@ func parseAndSetDebugLevels(debugLevel string) error {
 	return nil
 }
 
+// debugLevelPairs returns the log level of every subsystem set by the passed
+// debug level specification, which must be valid.
+func debugLevelPairs(debugLevel string) map[string]string {
+	levels := make(map[string]string)
+	if !strings.Contains(debugLevel, ",") && !strings.Contains(debugLevel, "=") {
+		for subsysID := range subsystemLoggers {
+			levels[subsysID] = debugLevel
+		}
+		return levels
+	}
+	for _, logLevelPair := range strings.Split(debugLevel, ",") {
+		fields := strings.Split(logLevelPair, "=")
+		levels[fields[0]] = fields[1]
+	}
+	return levels
+}
+
+// loadPersistedDebugLevels returns the debug level specification persisted
+// in the passed file.  An empty specification is returned when the file does
+// not exist.
+func loadPersistedDebugLevels(path string) (string, error) {
+	persisted, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(persisted)), nil
+}
+
+// persistDebugLevels merges the levels set by the passed debug level
+// specification, which must be valid, into the ones persisted in the passed
+// file, so they are applied again when the process is restarted.
+func persistDebugLevels(path, debugLevel string) error {
+	persisted, err := loadPersistedDebugLevels(path)
+	if err != nil {
+		return err
+	}
+	levels := make(map[string]string)
+	if persisted != "" {
+		levels = debugLevelPairs(persisted)
+	}
+	for subsysID, logLevel := range debugLevelPairs(debugLevel) {
+		levels[subsysID] = logLevel
+	}
+
+	pairs := make([]string, 0, len(levels))
+	for subsysID, logLevel := range levels {
+		pairs = append(pairs, subsysID+"="+logLevel)
+	}
+	sort.Strings(pairs)
+
+	// Write the new file next to the old one and rename it over the old
+	// one, so a crash never leaves a truncated file behind.
+	tmpPath := path + ".tmp"
+	spec := strings.Join(pairs, ",") + "\n"
+	if err := ioutil.WriteFile(tmpPath, []byte(spec), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
 // validDbType returns whether or not dbType is a supported database type.
 func validDbType(dbType string) bool {
 	for _, knownType := range knownDbTypes {
@@ -322,22 +509,58 @@ func loadConfig() (*config, []string, error) {
 	cfg := config{
 		ConfigFile:        defaultConfigFile,
 		DebugLevel:        defaultLogLevel,
+		LogBuffer:         defaultLogBuffer,
 		MaxPeers:          defaultMaxPeers,
 		BanDuration:       defaultBanDuration,
+		BanThreshold:      defaultBanThreshold,
+		InboundRatePerIP:  defaultInboundRatePerIP,
+		InboundRate:       defaultInboundRate,
+		InboundIPv4Prefix: defaultInboundIPv4Prefix,
+		InboundIPv6Prefix: defaultInboundIPv6Prefix,
+		MaxClockSkew:      defaultMaxClockSkew,
 		RPCMaxClients:     defaultMaxRPCClients,
 		RPCMaxWebsockets:  defaultMaxRPCWebsockets,
+		RPCMaxROClients:   defaultMaxRPCROClients,
+		RPCMaxConns:       defaultMaxRPCConns,
+		RPCMaxROConns:     defaultMaxRPCConns,
+		RPCReadTimeout:    defaultRPCReadTimeout,
+		RPCIdleTimeout:    defaultRPCIdleTimeout,
+		RPCMaxHeaderBytes: defaultRPCMaxHeaderBytes,
+		RPCMaxWatchAddrs:  defaultMaxWatchedAddrs,
+		RPCMaxWatchOuts:   defaultMaxWatchedOutPts,
+		RPCAuthFailures:   defaultRPCAuthFailures,
+		RPCAuthLockout:    defaultRPCAuthLockout,
+		RPCHelpLocale:     defaultHelpLocale,
+		RPCShutdownGrace:  defaultRPCShutdownGrace,
+		NotifyTimeout:     defaultNotifyTimeout,
+		AssumeUtxoFile:    defaultAssumeUtxoFile,
 		DataDir:           defaultDataDir,
 		LogDir:            defaultLogDir,
 		DbType:            defaultDbType,
+		CheckBlocks:       defaultCheckBlocks,
+		CheckLevel:        defaultCheckLevel,
 		RPCKey:            defaultRPCKeyFile,
 		RPCCert:           defaultRPCCertFile,
+		RPCACMEDirectory:  defaultACMEDirectory,
+		RPCACMEListen:     defaultACMEListen,
 		MinRelayTxFee:     defaultMinRelayTxFee.ToBTC(),
 		FreeTxRelayLimit:  defaultFreeTxRelayLimit,
 		BlockMinSize:      defaultBlockMinSize,
 		BlockMaxSize:      defaultBlockMaxSize,
 		BlockPrioritySize: defaultBlockPrioritySize,
+		BlockMinFeeRate:   defaultMinRelayTxFee.ToBTC(),
+		BlockMinPriority:  minHighPriority,
+		RecentTxWeight:    defaultRecentTxWeight,
 		SigCacheMaxSize:   defaultSigCacheMaxSize,
 		MaxOrphanTxs:      maxOrphanTransactions,
+		MempoolExpiry:     defaultMempoolExpiry,
+		MaxAncestors:      defaultMaxAncestors,
+		MaxAncestorSize:   defaultMaxAncestorSize,
+		MaxDescendants:    defaultMaxDescendants,
+		MaxDescendantSize: defaultMaxDescendantSize,
+		DataCarrierSize:   txscript.MaxDataCarrierSize,
+		DataCarrierCount:  defaultMaxDataCarrierOutputs,
+		MaxSigOpsPerTx:    defaultMaxSigOpsPerTx,
 		Generate:          defaultGenerate,
 		AddrIndex:         defaultAddrIndex,
 	}
@@ -449,15 +672,62 @@ func loadConfig() (*config, []string, error) {
 		activeNetParams = &simNetParams
 		cfg.DisableDNSSeed = true
 	}
+	if cfg.CustomNet != "" {
+		numNets++
+		customNetParams, err := loadCustomNetParams(
+			cleanAndExpandPath(cfg.CustomNet))
+		if err != nil {
+			str := "%s: Failed to load the custom network " +
+				"parameters from %s: %v"
+			err := fmt.Errorf(str, funcName, cfg.CustomNet, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		activeNetParams = customNetParams
+	}
 	if numNets > 1 {
-		str := "%s: The testnet, regtest, and simnet params can't be " +
-			"used together -- choose one of the three"
+		str := "%s: The testnet, regtest, simnet, and customnet params " +
+			"can't be used together -- choose one of the four"
 		err := fmt.Errorf(str, funcName)
 		fmt.Fprintln(os.Stderr, err)
 		fmt.Fprintln(os.Stderr, usageMessage)
 		return nil, nil, err
 	}
 
+	// Parse the assumed-valid block hash.  The network default is used
+	// when it isn't specified.
+	switch cfg.AssumeValid {
+	case "":
+		cfg.assumeValid = activeNetParams.AssumeValid
+	case "0":
+		cfg.assumeValid = nil
+	default:
+		hash, err := wire.NewShaHashFromStr(cfg.AssumeValid)
+		if err != nil {
+			str := "%s: The assumevalid value of '%s' is not a " +
+				"valid block hash: %v"
+			err := fmt.Errorf(str, funcName, cfg.AssumeValid, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.assumeValid = hash
+	}
+
+	// Parse the hash of the UTXO snapshot to bootstrap the node from.
+	if cfg.AssumeUtxo != "" {
+		hash, err := wire.NewShaHashFromStr(cfg.AssumeUtxo)
+		if err != nil {
+			str := "%s: The assumeutxo value of '%s' is not a " +
+				"valid UTXO snapshot hash: %v"
+			err := fmt.Errorf(str, funcName, cfg.AssumeUtxo, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.assumeUtxo = hash
+	}
+
 	// Append the network type to the data directory so it is "namespaced"
 	// per network.  In addition to the block database, there are other
 	// pieces of data that are saved to disk such as address manager state.
@@ -478,6 +748,17 @@ func loadConfig() (*config, []string, error) {
 		os.Exit(0)
 	}
 
+	// The number of captured log lines must not be negative.
+	if cfg.LogBuffer < 0 {
+		str := "%s: The logbuffer option may not be negative -- " +
+			"parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.LogBuffer)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	logBufferLines = cfg.LogBuffer
+
 	// Initialize logging at the default logging level.
 	initSeelogLogger(filepath.Join(cfg.LogDir, defaultLogFilename))
 	setLogLevels(defaultLogLevel)
@@ -490,6 +771,23 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Apply the debug levels persisted with the debuglevel RPC on top of
+	// the configured ones.  Persisted levels which are no longer valid, for
+	// example because a subsystem was removed, are ignored.
+	debugLevelsFile := filepath.Join(cfg.DataDir, debugLevelsFilename)
+	persistedLevels, err := loadPersistedDebugLevels(debugLevelsFile)
+	if err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if persistedLevels != "" {
+		if err := parseAndSetDebugLevels(persistedLevels); err != nil {
+			btcdLog.Warnf("Ignoring persisted debug levels in %s: %v",
+				debugLevelsFile, err)
+		}
+	}
+
 	// Validate database type.
 	if !validDbType(cfg.DbType) {
 		str := "%s: The specified database type [%v] is invalid -- " +
@@ -500,6 +798,58 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Validate the start up verification options.
+	if cfg.CheckBlocks < 0 {
+		str := "%s: The checkblocks option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.CheckBlocks)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.CheckLevel < 0 || cfg.CheckLevel > maxCheckLevel {
+		str := "%s: The checklevel option must be between 0 and %d " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, maxCheckLevel, cfg.CheckLevel)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the compaction window.  Memdb doesn't store anything on
+	// disk, so there is nothing to compact.
+	if cfg.CompactWindow != "" {
+		if cfg.DbType == "memdb" {
+			str := "%s: memdb does not support the compactwindow " +
+				"option"
+			err := fmt.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		window, err := parseCompactWindow(cfg.CompactWindow)
+		if err != nil {
+			str := "%s: The compactwindow option is invalid: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.compactWindow = window
+	}
+
+	// Load the AS map used to report the autonomous systems of peers.
+	if cfg.ASMap != "" {
+		asMap, err := loadASMap(cleanAndExpandPath(cfg.ASMap))
+		if err != nil {
+			str := "%s: Failed to load the AS map from %s: %v"
+			err := fmt.Errorf(str, funcName, cfg.ASMap, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		cfg.asMap = asMap
+	}
+
 	if cfg.AddrIndex && cfg.DropAddrIndex {
 		err := fmt.Errorf("addrindex and dropaddrindex cannot be " +
 			"activated at the same")
@@ -516,6 +866,113 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	if cfg.CFIndex && cfg.DropCFIndex {
+		err := fmt.Errorf("cfindex and dropcfindex cannot be " +
+			"activated at the same")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Memdb does not currently support the cfindex.
+	if cfg.DbType == "memdb" && cfg.CFIndex {
+		err := fmt.Errorf("memdb does not currently support the cfindex")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.CoinAgeIndex && cfg.DropCoinAgeIndex {
+		err := fmt.Errorf("coinageindex and dropcoinageindex cannot be " +
+			"activated at the same")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Memdb does not currently support the coinageindex.
+	if cfg.DbType == "memdb" && cfg.CoinAgeIndex {
+		err := fmt.Errorf("memdb does not currently support the " +
+			"coinageindex")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.STXOIndex && cfg.DropSTXOIndex {
+		err := fmt.Errorf("stxoindex and dropstxoindex cannot be " +
+			"activated at the same")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Memdb does not currently support the stxoindex.
+	if cfg.DbType == "memdb" && cfg.STXOIndex {
+		err := fmt.Errorf("memdb does not currently support the " +
+			"stxoindex")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.AddrBalanceIndex && cfg.DropBalanceIndex {
+		err := fmt.Errorf("addrbalanceindex and dropaddrbalanceindex " +
+			"cannot be activated at the same")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Memdb does not currently support the addrbalanceindex.
+	if cfg.DbType == "memdb" && cfg.AddrBalanceIndex {
+		err := fmt.Errorf("memdb does not currently support the " +
+			"addrbalanceindex")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Headers-only mode doesn't download any blocks, so the features which
+	// need them can't be enabled.
+	if cfg.HeadersOnly && (cfg.Generate || len(cfg.LoadBlocks) > 0 ||
+		cfg.AddrIndex || cfg.CFIndex || cfg.CoinAgeIndex ||
+		cfg.STXOIndex || cfg.AddrBalanceIndex) {
+
+		err := fmt.Errorf("headersonly cannot be used with generate, " +
+			"loadblock or any of the indexes")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Headers-only mode doesn't download any transactions, so there is
+	// nothing to run the txnotify command for.
+	if cfg.HeadersOnly && cfg.TxNotify != "" {
+		err := fmt.Errorf("headersonly cannot be used with txnotify")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Headers-only mode doesn't download the blocks a UTXO snapshot is
+	// brought up to date with.
+	if cfg.HeadersOnly && cfg.assumeUtxo != nil {
+		err := fmt.Errorf("headersonly cannot be used with assumeutxo")
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Don't allow notification command timeouts that are too short.
+	if cfg.NotifyTimeout < time.Duration(time.Second) {
+		str := "%s: The notifytimeout option may not be less than 1s -- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.NotifyTimeout)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Validate profile port number
 	if cfg.Profile != "" {
 		profilePort, err := strconv.Atoi(cfg.Profile)
@@ -537,6 +994,76 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Don't allow a ban threshold every peer would reach at once.
+	if cfg.BanThreshold < 1 {
+		str := "%s: The banthreshold option may not be less than 1 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.BanThreshold)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Don't allow negative inbound connection rate limits.
+	if cfg.InboundRatePerIP < 0 || cfg.InboundRate < 0 {
+		str := "%s: The inboundrateperip and inboundrate options may " +
+			"not be less than 0 -- parsed [%d] and [%d]"
+		err := fmt.Errorf(str, funcName, cfg.InboundRatePerIP,
+			cfg.InboundRate)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The inbound prefix lengths must be valid for their address family.
+	if cfg.InboundIPv4Prefix < 1 || cfg.InboundIPv4Prefix > net.IPv4len*8 ||
+		cfg.InboundIPv6Prefix < 1 || cfg.InboundIPv6Prefix > net.IPv6len*8 {
+
+		str := "%s: The inboundipv4prefix option must be between 1 and " +
+			"32 and the inboundipv6prefix option between 1 and 128 " +
+			"-- parsed [%d] and [%d]"
+		err := fmt.Errorf(str, funcName, cfg.InboundIPv4Prefix,
+			cfg.InboundIPv6Prefix)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Parse the whitelisted IP networks.  A single IP is treated as a
+	// network containing only that IP.
+	for _, addr := range cfg.Whitelists {
+		_, ipnet, err := net.ParseCIDR(addr)
+		if err != nil {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				str := "%s: The whitelist value of '%s' is invalid"
+				err = fmt.Errorf(str, funcName, addr)
+				fmt.Fprintln(os.Stderr, err)
+				fmt.Fprintln(os.Stderr, usageMessage)
+				return nil, nil, err
+			}
+			bits := net.IPv6len * 8
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+				bits = net.IPv4len * 8
+			}
+			ipnet = &net.IPNet{
+				IP:   ip,
+				Mask: net.CIDRMask(bits, bits),
+			}
+		}
+		cfg.whitelists = append(cfg.whitelists, ipnet)
+	}
+
+	// Don't allow clock skew thresholds that are too short.
+	if cfg.MaxClockSkew < time.Duration(time.Second) {
+		str := "%s: The maxclockskew option may not be less than 1s -- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.MaxClockSkew)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// --addPeer and --connect do not mix.
 	if len(cfg.AddPeers) > 0 && len(cfg.ConnectPeers) > 0 {
 		str := "%s: the --addpeer and --connect options can not be " +
@@ -587,9 +1114,33 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
-	// The RPC server is disabled if no username or password is provided.
+	// The read-only RPC credentials must either both be set or unset and
+	// must not grant access with the password of another user.
+	if (cfg.RPCReadOnlyUser == "") != (cfg.RPCReadOnlyPass == "") {
+		str := "%s: --rpcreadonlyuser and --rpcreadonlypass must " +
+			"either both be specified or both be omitted"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.RPCReadOnlyPass != "" && (cfg.RPCReadOnlyPass == cfg.RPCPass ||
+		cfg.RPCReadOnlyPass == cfg.RPCLimitPass) {
+
+		str := "%s: --rpcreadonlypass must not specify the same " +
+			"password as --rpcpass or --rpclimitpass"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The RPC server is disabled if no username or password is provided
+	// and there are no read-only listeners, which don't necessarily
+	// require credentials.
 	if (cfg.RPCUser == "" || cfg.RPCPass == "") &&
-		(cfg.RPCLimitUser == "" || cfg.RPCLimitPass == "") {
+		(cfg.RPCLimitUser == "" || cfg.RPCLimitPass == "") &&
+		len(cfg.RPCReadOnlyListen) == 0 {
 		cfg.DisableRPC = true
 	}
 
@@ -616,6 +1167,77 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Validate the the dustrelayfee.  The dust limit follows the minimum
+	// relay fee unless a different fee rate is specified.
+	cfg.dustRelayFee, err = coinutil.NewAmount(cfg.DustRelayFee)
+	if err != nil {
+		str := "%s: invalid dustrelayfee: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.dustRelayFee == 0 {
+		cfg.dustRelayFee = cfg.minRelayTxFee
+	}
+
+	// Limit the data carrier size to the size of a standard transaction.
+	if cfg.DataCarrierSize < 0 || cfg.DataCarrierSize > maxStandardTxSize {
+		str := "%s: The datacarriersize option must be in between 0 " +
+			"and %d -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, maxStandardTxSize,
+			cfg.DataCarrierSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Don't allow a negative number of data carrier outputs.
+	if cfg.DataCarrierCount < 0 {
+		str := "%s: The datacarriercount option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.DataCarrierCount)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Don't allow negative script execution budgets.
+	if cfg.ScriptMaxSteps < 0 {
+		str := "%s: The scriptmaxsteps option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.ScriptMaxSteps)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.ScriptTimeout < 0 {
+		str := "%s: The scripttimeout option may not be less than 0 " +
+			"-- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.ScriptTimeout)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	cfg.scriptBudget = txscript.Budget{
+		MaxSteps: cfg.ScriptMaxSteps,
+		Timeout:  cfg.ScriptTimeout,
+	}
+
+	// Limit the max signature operations per transaction to what is
+	// allowed in a block.
+	if cfg.MaxSigOpsPerTx < 1 ||
+		cfg.MaxSigOpsPerTx > blockchain.MaxSigOpsPerBlock {
+
+		str := "%s: The maxsigopspertx option must be in between 1 " +
+			"and %d -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, blockchain.MaxSigOpsPerBlock,
+			cfg.MaxSigOpsPerTx)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Limit the max block size to a sane value.
 	if cfg.BlockMaxSize < blockMaxSizeMin || cfg.BlockMaxSize >
 		blockMaxSizeMax {
@@ -629,6 +1251,125 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// The RPC shutdown grace period may not be negative.
+	if cfg.RPCShutdownGrace < 0 {
+		str := "%s: The rpcshutdowngrace option may not be less " +
+			"than 0 -- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.RPCShutdownGrace)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Alerts can only be posted to http and https webhooks.
+	for _, webhook := range cfg.RPCAlertWebhooks {
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+			u.Host == "" {
+
+			str := "%s: The rpcalertwebhook option must be an http " +
+				"or https URL -- parsed [%v]"
+			err := fmt.Errorf(str, funcName, webhook)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	// The RPC help must be available in the requested locale.
+	if _, ok := helpDescs[cfg.RPCHelpLocale]; !ok {
+		str := "%s: The rpchelplocale option must be one of %v " +
+			"-- parsed [%v]"
+		err := fmt.Errorf(str, funcName, helpLocales(),
+			cfg.RPCHelpLocale)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The websocket notification batching options may not be negative.
+	if cfg.RPCWSBatchBytes < 0 || cfg.RPCWSBatchInterval < 0 {
+		str := "%s: The rpcwsbatchbytes and rpcwsbatchinterval options " +
+			"may not be less than 0 -- parsed [%d, %v]"
+		err := fmt.Errorf(str, funcName, cfg.RPCWSBatchBytes,
+			cfg.RPCWSBatchInterval)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The limits on the entries watched by websocket clients may not be
+	// negative.
+	if cfg.RPCMaxWatchAddrs < 0 || cfg.RPCMaxWatchOuts < 0 {
+		str := "%s: The rpcmaxwatchedaddrs and rpcmaxwatchedoutpoints " +
+			"options may not be less than 0 -- parsed [%d, %d]"
+		err := fmt.Errorf(str, funcName, cfg.RPCMaxWatchAddrs,
+			cfg.RPCMaxWatchOuts)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The RPC connection limits and timeouts may not be negative, and
+	// clients must be given some time to send their requests.
+	if cfg.RPCMaxROClients < 0 || cfg.RPCMaxConns < 0 ||
+		cfg.RPCMaxROConns < 0 || cfg.RPCReadTimeout <= 0 ||
+		cfg.RPCWriteTimeout < 0 || cfg.RPCIdleTimeout < 0 ||
+		cfg.RPCMaxHeaderBytes <= 0 {
+
+		str := "%s: The rpcreadonlymaxclients, rpcmaxconns, " +
+			"rpcreadonlymaxconns, rpcwritetimeout and " +
+			"rpcidletimeout options may not be less than 0 and " +
+			"the rpcreadtimeout and rpcmaxheaderbytes options " +
+			"must be positive -- parsed [%d, %d, %d, %v, %v, %v, %d]"
+		err := fmt.Errorf(str, funcName, cfg.RPCMaxROClients,
+			cfg.RPCMaxConns, cfg.RPCMaxROConns, cfg.RPCReadTimeout,
+			cfg.RPCWriteTimeout, cfg.RPCIdleTimeout,
+			cfg.RPCMaxHeaderBytes)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The RPC authentication lockouts must last some time unless they are
+	// disabled.
+	if cfg.RPCAuthFailures < 0 ||
+		(cfg.RPCAuthFailures > 0 && cfg.RPCAuthLockout <= 0) {
+
+		str := "%s: The rpcauthmaxfailures option may not be less " +
+			"than 0 and the rpcauthlockout option must be positive " +
+			"unless the lockouts are disabled -- parsed [%d, %v]"
+		err := fmt.Errorf(str, funcName, cfg.RPCAuthFailures,
+			cfg.RPCAuthLockout)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The RPC rate limits may not be negative.
+	if cfg.RPCRateLimit < 0 || cfg.RPCExpensiveLimit < 0 ||
+		cfg.RPCMaxExpensive < 0 {
+
+		str := "%s: The rpcratelimit, rpcexpensiveratelimit and " +
+			"rpcmaxexpensive options may not be less than 0 -- " +
+			"parsed [%v, %v, %d]"
+		err := fmt.Errorf(str, funcName, cfg.RPCRateLimit,
+			cfg.RPCExpensiveLimit, cfg.RPCMaxExpensive)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The event journal retention may not be negative.
+	if cfg.EventJournal < 0 {
+		str := "%s: The eventjournal option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.EventJournal)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Limit the max orphan count to a sane vlue.
 	if cfg.MaxOrphanTxs < 0 {
 		str := "%s: The maxorphantx option may not be less than 0 " +
@@ -639,10 +1380,81 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Don't allow negative memory pool expiries.
+	if cfg.MempoolExpiry < 0 {
+		str := "%s: The mempoolexpiry option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.MempoolExpiry)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Don't allow negative ancestor and descendant limits.
+	chainLimits := []struct {
+		name  string
+		value int
+	}{
+		{"limitancestorcount", cfg.MaxAncestors},
+		{"limitancestorsize", cfg.MaxAncestorSize},
+		{"limitdescendantcount", cfg.MaxDescendants},
+		{"limitdescendantsize", cfg.MaxDescendantSize},
+	}
+	for _, limit := range chainLimits {
+		if limit.value < 0 {
+			str := "%s: The %s option may not be less than 0 " +
+				"-- parsed [%d]"
+			err := fmt.Errorf(str, funcName, limit.name, limit.value)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
 	// Limit the block priority and minimum block sizes to max block size.
 	cfg.BlockPrioritySize = minUint32(cfg.BlockPrioritySize, cfg.BlockMaxSize)
 	cfg.BlockMinSize = minUint32(cfg.BlockMinSize, cfg.BlockMaxSize)
 
+	// Validate the the blockminfeerate.
+	cfg.blockMinFeeRate, err = coinutil.NewAmount(cfg.BlockMinFeeRate)
+	if err == nil && cfg.blockMinFeeRate < 0 {
+		err = fmt.Errorf("the fee rate may not be negative")
+	}
+	if err != nil {
+		str := "%s: invalid blockminfeerate: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The minimum priority and recent transaction age may not be negative
+	// and the recent transaction weight must be a fraction.
+	if cfg.BlockMinPriority < 0 {
+		str := "%s: The blockminpriority option may not be negative " +
+			"-- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.BlockMinPriority)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.RecentTxAge < 0 {
+		str := "%s: The blockrecenttxage option may not be negative " +
+			"-- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.RecentTxAge)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.RecentTxWeight <= 0 || cfg.RecentTxWeight > 1 {
+		str := "%s: The blockrecenttxweight option must be greater " +
+			"than 0 and at most 1 -- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.RecentTxWeight)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Check getwork keys are valid and saved parsed versions.
 	cfg.miningAddrs = make([]coinutil.Address, 0, len(cfg.GetWorkKeys)+
 		len(cfg.MiningAddrs))
@@ -697,6 +1509,52 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Check txnotify addresses are valid and saved parsed versions.
+	for _, strAddr := range cfg.TxNotifyAddrs {
+		addr, err := coinutil.DecodeAddress(strAddr, activeNetParams.Params)
+		if err != nil {
+			str := "%s: txnotify address '%s' failed to decode: %v"
+			err := fmt.Errorf(str, funcName, strAddr, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		if !addr.IsForNet(activeNetParams.Params) {
+			str := "%s: txnotify address '%s' is on the wrong network"
+			err := fmt.Errorf(str, funcName, strAddr)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.txNotifyAddrs = append(cfg.txNotifyAddrs, addr)
+	}
+
+	// Load the transaction blacklist.  It is loaded after the network
+	// parameters are known since it may contain addresses.
+	if cfg.TxBlacklist != "" {
+		txBlacklist, err := loadTxBlacklist(
+			cleanAndExpandPath(cfg.TxBlacklist), activeNetParams.Params)
+		if err != nil {
+			str := "%s: Failed to load the transaction blacklist " +
+				"from %s: %v"
+			err := fmt.Errorf(str, funcName, cfg.TxBlacklist, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		cfg.txBlacklist = txBlacklist
+	}
+
+	// Ensure there is at least one address to watch when the txnotify
+	// command is set.
+	if cfg.TxNotify != "" && len(cfg.TxNotifyAddrs) == 0 {
+		str := "%s: the txnotify option is set, but there are no " +
+			"txnotifyaddr addresses specified"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Add default port to all listener addresses if needed and remove
 	// duplicate addresses.
 	cfg.Listeners = normalizeAddresses(cfg.Listeners,
@@ -706,6 +1564,35 @@ func loadConfig() (*config, []string, error) {
 	// duplicate addresses.
 	cfg.RPCListeners = normalizeAddresses(cfg.RPCListeners,
 		activeNetParams.rpcPort)
+	cfg.RPCReadOnlyListen = normalizeAddresses(cfg.RPCReadOnlyListen,
+		activeNetParams.rpcPort)
+
+	// The read-only RPC listeners must not share an address with the
+	// regular RPC listeners.
+	for _, readOnlyAddr := range cfg.RPCReadOnlyListen {
+		for _, addr := range cfg.RPCListeners {
+			if readOnlyAddr != addr {
+				continue
+			}
+			str := "%s: the read-only RPC listen interface '%s' " +
+				"is also a regular RPC listen interface"
+			err := fmt.Errorf(str, funcName, addr)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	// Certificates can only be obtained through ACME for the TLS RPC
+	// listeners.
+	if len(cfg.RPCACMEDomains) > 0 && cfg.DisableTLS {
+		str := "%s: the --rpcacmedomain and --notls options may not " +
+			"be used together"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
 
 	// Only allow TLS to be disabled if the RPC is bound to localhost
 	// addresses.
@@ -715,7 +1602,11 @@ func loadConfig() (*config, []string, error) {
 			"127.0.0.1": struct{}{},
 			"::1":       struct{}{},
 		}
-		for _, addr := range cfg.RPCListeners {
+		rpcListeners := make([]string, 0, len(cfg.RPCListeners)+
+			len(cfg.RPCReadOnlyListen))
+		rpcListeners = append(rpcListeners, cfg.RPCListeners...)
+		rpcListeners = append(rpcListeners, cfg.RPCReadOnlyListen...)
+		for _, addr := range rpcListeners {
 			host, _, err := net.SplitHostPort(addr)
 			if err != nil {
 				str := "%s: RPC listen interface '%s' is " +
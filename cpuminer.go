@@ -15,6 +15,7 @@ import (
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/stcd/blockchain"
 	"github.com/conseweb/stcd/mining"
+	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
 )
 
@@ -53,7 +54,7 @@ var (
 // system which is typically sufficient.
 type CPUMiner struct {
 	sync.Mutex
-	policy            *mining.Policy
+	policy            *blockTemplatePolicy
 	txSource          mining.TxSource
 	server            *server
 	numWorkers        uint32
@@ -305,7 +306,7 @@ out:
 		// Create a new block template using the available transactions
 		// in the memory pool as a source of transactions to potentially
 		// include in the block.
-		template, err := NewBlockTemplate(m.policy, m.server, payToAddr)
+		template, err := NewBlockTemplate(m.policy.Policy(), m.server, payToAddr)
 		m.submitBlockLock.Unlock()
 		if err != nil {
 			errStr := fmt.Sprintf("Failed to create new block "+
@@ -507,8 +508,10 @@ func (m *CPUMiner) NumWorkers() int32 {
 // contained in that it creates block templates and attempts to solve them while
 // detecting when it is performing stale work and reacting accordingly by
 // generating a new block template.  When a block is solved, it is submitted.
+// The coinbase of each block pays to the passed public key script, or to one of
+// the configured mining addresses chosen at random when the script is nil.
 // The function returns a list of the hashes of generated blocks.
-func (m *CPUMiner) GenerateNBlocks(n uint32) ([]*wire.ShaHash, error) {
+func (m *CPUMiner) GenerateNBlocks(n uint32, pkScript []byte) ([]*wire.ShaHash, error) {
 	m.Lock()
 
 	// Respond with an error if there's virtually 0 chance of CPU-mining a block.
@@ -560,14 +563,26 @@ func (m *CPUMiner) GenerateNBlocks(n uint32) ([]*wire.ShaHash, error) {
 		m.submitBlockLock.Lock()
 		_, curHeight := m.server.blockManager.chainState.Best()
 
-		// Choose a payment address at random.
-		rand.Seed(time.Now().UnixNano())
-		payToAddr := cfg.miningAddrs[rand.Intn(len(cfg.miningAddrs))]
+		// Choose a payment address at random unless the script to pay
+		// to was provided.
+		payToScript := pkScript
+		if payToScript == nil {
+			rand.Seed(time.Now().UnixNano())
+			payToAddr := cfg.miningAddrs[rand.Intn(len(cfg.miningAddrs))]
+			script, err := txscript.PayToAddrScript(payToAddr)
+			if err != nil {
+				m.submitBlockLock.Unlock()
+				m.stopDiscreteMining()
+				return nil, err
+			}
+			payToScript = script
+		}
 
 		// Create a new block template using the available transactions
 		// in the memory pool as a source of transactions to potentially
 		// include in the block.
-		template, err := NewBlockTemplate(m.policy, m.server, payToAddr)
+		template, err := newBlockTemplate(m.policy.Policy(), m.server,
+			payToScript)
 		m.submitBlockLock.Unlock()
 		if err != nil {
 			errStr := fmt.Sprintf("Failed to create new block "+
@@ -587,22 +602,28 @@ func (m *CPUMiner) GenerateNBlocks(n uint32) ([]*wire.ShaHash, error) {
 			i++
 			if i == n {
 				minrLog.Tracef("Generated %d blocks", i)
-				m.Lock()
-				close(m.speedMonitorQuit)
-				m.wg.Wait()
-				m.started = false
-				m.discreteMining = false
-				m.Unlock()
+				m.stopDiscreteMining()
 				return blockHashes, nil
 			}
 		}
 	}
 }
 
+// stopDiscreteMining stops the speed monitor and marks the miner as no longer
+// mining once GenerateNBlocks is done.
+func (m *CPUMiner) stopDiscreteMining() {
+	m.Lock()
+	close(m.speedMonitorQuit)
+	m.wg.Wait()
+	m.started = false
+	m.discreteMining = false
+	m.Unlock()
+}
+
 // newCPUMiner returns a new instance of a CPU miner for the provided server.
 // Use Start to begin the mining process.  See the documentation for CPUMiner
 // type for more details.
-func newCPUMiner(policy *mining.Policy, s *server) *CPUMiner {
+func newCPUMiner(policy *blockTemplatePolicy, s *server) *CPUMiner {
 	return &CPUMiner{
 		policy:            policy,
 		txSource:          s.txMemPool,
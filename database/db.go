@@ -14,16 +14,25 @@ import (
 
 // Errors that the various database functions may return.
 var (
-	ErrAddrIndexDoesNotExist  = errors.New("address index hasn't been built or is an older version")
-	ErrUnsupportedAddressType = errors.New("address type is not supported " +
+	ErrAddrIndexDoesNotExist        = errors.New("address index hasn't been built or is an older version")
+	ErrAddrBalanceIndexDoesNotExist = errors.New("address balance index hasn't been built")
+	ErrCFIndexDoesNotExist          = errors.New("committed filter index hasn't been built")
+	ErrCoinAgeIndexDoesNotExist     = errors.New("coin age index hasn't been built")
+	ErrSTXOIndexDoesNotExist        = errors.New("spent output index hasn't been built")
+	ErrUnsupportedAddressType       = errors.New("address type is not supported " +
 		"by the address-index")
-	ErrPrevShaMissing  = errors.New("previous sha missing from database")
-	ErrTxShaMissing    = errors.New("requested transaction does not exist")
-	ErrBlockShaMissing = errors.New("requested block does not exist")
-	ErrDuplicateSha    = errors.New("duplicate insert attempted")
-	ErrDbDoesNotExist  = errors.New("non-existent database")
-	ErrDbUnknownType   = errors.New("non-existent database type")
-	ErrNotImplemented  = errors.New("method has not yet been implemented")
+	ErrPrevShaMissing     = errors.New("previous sha missing from database")
+	ErrTxShaMissing       = errors.New("requested transaction does not exist")
+	ErrBlockShaMissing    = errors.New("requested block does not exist")
+	ErrCFilterMissing     = errors.New("requested filter does not exist")
+	ErrCoinAgeMissing     = errors.New("requested coin age statistics do not exist")
+	ErrSpendMissing       = errors.New("requested output has no indexed spend")
+	ErrUtxoSetHashMissing = errors.New("requested UTXO set hash does not exist")
+	ErrDuplicateSha       = errors.New("duplicate insert attempted")
+	ErrDbDoesNotExist     = errors.New("non-existent database")
+	ErrDbUnknownType      = errors.New("non-existent database type")
+	ErrNotImplemented     = errors.New("method has not yet been implemented")
+	ErrInterrupted        = errors.New("operation was interrupted")
 )
 
 // AllShas is a special value that can be used as the final sha when requesting
@@ -111,6 +120,32 @@ type Db interface {
 	// the database yet.
 	NewestSha() (sha *wire.ShaHash, height int32, err error)
 
+	// FetchCacheStats returns the statistics of the cache the
+	// implementation keeps of block hashes, heights and headers to serve
+	// repeated lookups, such as those made while rescanning or serving
+	// headers to peers, without going to disk.
+	FetchCacheStats() *CacheStats
+
+	// FetchDatabaseSizes returns the approximate on-disk sizes of the
+	// chain data and of each of the optional indexes stored within the
+	// DB.
+	FetchDatabaseSizes() ([]*DatabaseSize, error)
+
+	// Backup writes a consistent copy of the entire DB as of the time
+	// it is called to a new database at the passed path, which can be
+	// opened with OpenDB of the same backend, and returns the hash and
+	// height of the most recent block in the copy.  Other operations on
+	// the DB can proceed while the copy is written.  ErrInterrupted is
+	// returned, and the partial copy removed, when the interrupt channel
+	// is closed before the copy is complete.
+	Backup(dbPath string, interrupt <-chan struct{}) (sha *wire.ShaHash, height int32, err error)
+
+	// Compact compacts the underlying storage of the entire DB to
+	// reclaim the space used by deleted and overwritten data.  This may
+	// take a long time, but other operations on the DB can proceed while
+	// it runs.
+	Compact() error
+
 	// FetchAddrIndexTip returns the hash and block height of the most recent
 	// block which has had its address index populated. It will return
 	// ErrAddrIndexDoesNotExist along with a zero hash, and -1 if the
@@ -123,9 +158,15 @@ type Db interface {
 	// addr index. These two operations are performed in an atomic
 	// transaction which is commited before the function returns.
 	// Addresses are indexed by the raw bytes of their base58 decoded
-	// hash160.
+	// hash160.  The hash of the previous block is stored along with the
+	// entries so the block can later be rewound without its contents.
 	UpdateAddrIndexForBlock(blkSha *wire.ShaHash, height int32,
-		addrIndex BlockAddrIndex) error
+		prevSha *wire.ShaHash, addrIndex BlockAddrIndex) error
+
+	// RewindAddrIndex removes the entries added by the block at the tip
+	// of the addrindex and marks the previous block as the tip.  It is
+	// used when the block is no longer part of the main chain.
+	RewindAddrIndex() error
 
 	// FetchTxsForAddr looks up and returns all transactions which either
 	// spend a previously created output of the passed address, or create
@@ -143,6 +184,129 @@ type Db interface {
 	// DeleteAddrIndex deletes the entire addrindex stored within the DB.
 	DeleteAddrIndex() error
 
+	// FetchCFIndexTip returns the hash and block height of the most recent
+	// block which has had its committed filter stored.  It will return
+	// ErrCFIndexDoesNotExist along with a zero hash, and -1 if the filter
+	// index hasn't yet been built up.
+	FetchCFIndexTip() (sha *wire.ShaHash, height int32, err error)
+
+	// UpdateCFIndexForBlock stores the serialized basic committed filter
+	// and filter header for a particular block and marks the block as the
+	// tip of the filter index.  These two operations are performed in an
+	// atomic transaction which is commited before the function returns.
+	UpdateCFIndexForBlock(blkSha *wire.ShaHash, height int32, filter []byte,
+		filterHeader *wire.ShaHash) error
+
+	// FetchCFilterBySha returns the serialized basic committed filter and
+	// filter header stored for the passed block.  ErrCFilterMissing is
+	// returned when no filter has been stored for the block.
+	FetchCFilterBySha(blkSha *wire.ShaHash) (filter []byte,
+		filterHeader *wire.ShaHash, err error)
+
+	// DeleteCFIndex deletes the entire committed filter index stored
+	// within the DB.
+	DeleteCFIndex() error
+
+	// FetchCoinAgeIndexTip returns the hash and block height of the most
+	// recent block which has had its coin age statistics stored.  It will
+	// return ErrCoinAgeIndexDoesNotExist along with a zero hash, and -1 if
+	// the coin age index hasn't yet been built up.
+	FetchCoinAgeIndexTip() (sha *wire.ShaHash, height int32, err error)
+
+	// UpdateCoinAgeIndexForBlock stores the coin age statistics for a
+	// particular block and marks the block as the tip of the coin age
+	// index.  These two operations are performed in an atomic transaction
+	// which is commited before the function returns.
+	UpdateCoinAgeIndexForBlock(blkSha *wire.ShaHash, height int32,
+		coinAge *BlockCoinAge) error
+
+	// FetchCoinAgeBySha returns the coin age statistics stored for the
+	// passed block.  ErrCoinAgeMissing is returned when no statistics have
+	// been stored for the block.
+	FetchCoinAgeBySha(blkSha *wire.ShaHash) (*BlockCoinAge, error)
+
+	// DeleteCoinAgeIndex deletes the entire coin age index stored within
+	// the DB.
+	DeleteCoinAgeIndex() error
+
+	// FetchSTXOIndexTip returns the hash and block height of the most
+	// recent block which has had the outputs it spends indexed.  It will
+	// return ErrSTXOIndexDoesNotExist along with a zero hash, and -1 if
+	// the spent output index hasn't yet been built up.
+	FetchSTXOIndexTip() (sha *wire.ShaHash, height int32, err error)
+
+	// UpdateSTXOIndexForBlock records which transaction input of the
+	// passed block spends each of the passed outputs and marks the block
+	// as the tip of the spent output index.  The hash of the previous
+	// block is stored along with the spent outputs so the block can later
+	// be rewound without its contents.  These operations are performed in
+	// an atomic transaction which is commited before the function returns.
+	UpdateSTXOIndexForBlock(blkSha *wire.ShaHash, height int32,
+		prevSha *wire.ShaHash, spends []SpentOutput) error
+
+	// RewindSTXOIndex removes the spends of the block at the tip of the
+	// spent output index and marks the previous block as the tip.  It is
+	// used when the block is no longer part of the main chain.
+	RewindSTXOIndex() error
+
+	// FetchSpendingTx returns the transaction input which spends the
+	// passed output along with the block it is in.  ErrSpendMissing is
+	// returned when no spend of the output has been indexed.
+	FetchSpendingTx(outPoint *wire.OutPoint) (*SpendingTx, error)
+
+	// DeleteSTXOIndex deletes the entire spent output index stored within
+	// the DB.
+	DeleteSTXOIndex() error
+
+	// FetchAddrBalanceIndexTip returns the hash and block height of the
+	// most recent block which has had its balance changes indexed.  It
+	// will return ErrAddrBalanceIndexDoesNotExist along with a zero hash,
+	// and -1 if the address balance index hasn't yet been built up.
+	FetchAddrBalanceIndexTip() (sha *wire.ShaHash, height int32, err error)
+
+	// UpdateAddrBalanceIndexForBlock applies the passed balance changes of
+	// a block to the balances, unspent outputs and deltas stored for each
+	// address and marks the block as the tip of the address balance
+	// index.  The changes are stored along with the hash of the previous
+	// block so the block can later be rewound without its contents.
+	// These operations are performed in an atomic transaction which is
+	// commited before the function returns.
+	UpdateAddrBalanceIndexForBlock(blkSha *wire.ShaHash, height int32,
+		prevSha *wire.ShaHash, deltas []AddrDelta) error
+
+	// RewindAddrBalanceIndex reverts the balance changes of the block at
+	// the tip of the address balance index and marks the previous block
+	// as the tip.  It is used when the block is no longer part of the
+	// main chain.
+	RewindAddrBalanceIndex() error
+
+	// FetchAddrBalance returns the confirmed balance of the passed
+	// address.  Addresses are keyed the same way as the address index.
+	FetchAddrBalance(addr coinutil.Address) (*AddrBalance, error)
+
+	// FetchAddrUtxos returns the confirmed unspent outputs of the passed
+	// address ordered by transaction hash.
+	FetchAddrUtxos(addr coinutil.Address) ([]*AddrUtxo, error)
+
+	// FetchAddrDeltas returns the balance changes of the passed address
+	// made by the blocks from startHeight to endHeight inclusive, in the
+	// order they appear in the chain.  The PkScript of the returned
+	// deltas is not set.
+	FetchAddrDeltas(addr coinutil.Address, startHeight, endHeight int32) ([]*AddrDelta, error)
+
+	// DeleteAddrBalanceIndex deletes the entire address balance index
+	// stored within the DB.
+	DeleteAddrBalanceIndex() error
+
+	// FetchUtxoSetHash returns the serialized rolling hash of the unspent
+	// transaction outputs as of the passed block.  ErrUtxoSetHashMissing
+	// is returned when no hash has been stored for the block.
+	FetchUtxoSetHash(blkSha *wire.ShaHash) ([]byte, error)
+
+	// InsertUtxoSetHash stores the serialized rolling hash of the unspent
+	// transaction outputs as of the passed block.
+	InsertUtxoSetHash(blkSha *wire.ShaHash, hash []byte) error
+
 	// RollbackClose discards the recent database changes to the previously
 	// saved data at last Sync and closes the database.
 	RollbackClose() (err error)
@@ -153,11 +317,13 @@ type Db interface {
 }
 
 // DriverDB defines a structure for backend drivers to use when they registered
-// themselves as a backend which implements the Db interface.
+// themselves as a backend which implements the Db interface.  OpenReadOnlyDB
+// may be nil for backends which can't be opened read-only.
 type DriverDB struct {
-	DbType   string
-	CreateDB func(args ...interface{}) (pbdb Db, err error)
-	OpenDB   func(args ...interface{}) (pbdb Db, err error)
+	DbType         string
+	CreateDB       func(args ...interface{}) (pbdb Db, err error)
+	OpenDB         func(args ...interface{}) (pbdb Db, err error)
+	OpenReadOnlyDB func(args ...interface{}) (pbdb Db, err error)
 }
 
 // TxListReply is used to return individual transaction information when
@@ -171,6 +337,128 @@ type TxListReply struct {
 	Err     error
 }
 
+// BlockCoinAge holds the coin age statistics of a block.  The age of a spent
+// output is the time between the timestamps of the block which created it and
+// the block which spends it.
+type BlockCoinAge struct {
+	// SpentValue is the total value in satoshi of the outputs spent by
+	// the block.
+	SpentValue int64
+
+	// CoinDaysDestroyed is the sum over the outputs spent by the block of
+	// their value in coins multiplied by their age in days.
+	CoinDaysDestroyed float64
+
+	// TotalCoinDaysDestroyed is the sum of CoinDaysDestroyed for every
+	// block of the chain up to and including the block.
+	TotalCoinDaysDestroyed float64
+}
+
+// SpentOutput describes an output spent by a transaction input of a block.
+type SpentOutput struct {
+	// OutPoint is the output which is spent.
+	OutPoint wire.OutPoint
+
+	// TxSha is the hash of the spending transaction.
+	TxSha wire.ShaHash
+
+	// InputIndex is the index of the spending input of the transaction.
+	InputIndex uint32
+}
+
+// SpendingTx describes the transaction input which spends an output along with
+// the block it is in.
+type SpendingTx struct {
+	TxSha      wire.ShaHash
+	InputIndex uint32
+	BlkSha     wire.ShaHash
+	Height     int32
+}
+
+// AddrDelta describes the change to the balance of an address made by an input
+// or an output of a transaction in the main chain.
+type AddrDelta struct {
+	// Addr is the hash160 the address is keyed by in the address index.
+	Addr [AddrIndexKeySize]byte
+
+	// TxSha is the hash of the transaction and BlockIndex is its position
+	// within the block.
+	TxSha      wire.ShaHash
+	BlockIndex uint32
+
+	// Index is the index of the input when Spending is set and the index
+	// of the output otherwise.
+	Index    uint32
+	Spending bool
+
+	// Amount is the change to the balance in satoshi, which is negative
+	// for inputs.
+	Amount int64
+
+	// Height is the height of the block containing the transaction.
+	Height int32
+
+	// OutPoint, OutHeight and PkScript describe the output created or
+	// spent, which allows the unspent outputs of the address to be
+	// restored when the block is rewound.
+	OutPoint  wire.OutPoint
+	OutHeight int32
+	PkScript  []byte
+}
+
+// AddrBalance holds the confirmed balance of an address along with the total
+// value it has received, both in satoshi.
+type AddrBalance struct {
+	Balance  int64
+	Received int64
+}
+
+// AddrUtxo describes a confirmed unspent output of an address.
+type AddrUtxo struct {
+	OutPoint wire.OutPoint
+	Value    int64
+	Height   int32
+	PkScript []byte
+}
+
+// CacheStats holds the statistics of the cache a database implementation
+// keeps of block hashes, heights and headers.  The hit and miss counters are
+// kept separately for each kind of lookup and only ever increase.
+type CacheStats struct {
+	// Entries is the number of blocks currently cached while MaxEntries is
+	// the number of blocks at which the least recently used ones start to
+	// be evicted.
+	Entries    int
+	MaxEntries int
+
+	// HeightHits and HeightMisses count the lookups of the hash of the
+	// block at a height.
+	HeightHits   uint64
+	HeightMisses uint64
+
+	// ShaHits and ShaMisses count the lookups of the height of a block by
+	// its hash.
+	ShaHits   uint64
+	ShaMisses uint64
+
+	// HeaderHits and HeaderMisses count the lookups of a block header by
+	// the hash of the block.
+	HeaderHits   uint64
+	HeaderMisses uint64
+}
+
+// DatabaseSize describes the approximate on-disk size of a part of the data
+// stored within a database.
+type DatabaseSize struct {
+	// Name is "chain" for the blocks, transactions and their metadata
+	// and the name of the index, such as "addrindex", for the optional
+	// indexes.
+	Name string
+
+	// Size is the approximate number of bytes used on disk.
+	Size int64
+}
+
 // AddrIndexKeySize is the number of bytes used by keys into the BlockAddrIndex.
 const AddrIndexKeySize = ripemd160.Size
 
@@ -214,6 +502,22 @@ func OpenDB(dbtype string, args ...interface{}) (pbdb Db, err error) {
 	return nil, ErrDbUnknownType
 }
 
+// OpenReadOnlyDB opens an existing database for reading only.  It is intended
+// for tools which inspect the database of a node, and can be used while the
+// node is running.  Methods which modify the database return an error.
+// ErrNotImplemented is returned when the backend can't be opened read-only.
+func OpenReadOnlyDB(dbtype string, args ...interface{}) (pbdb Db, err error) {
+	for _, drv := range driverList {
+		if drv.DbType == dbtype {
+			if drv.OpenReadOnlyDB == nil {
+				return nil, ErrNotImplemented
+			}
+			return drv.OpenReadOnlyDB(args...)
+		}
+	}
+	return nil, ErrDbUnknownType
+}
+
 // SupportedDBs returns a slice of strings that represent the database drivers
 // that have been registered and are therefore supported.
 func SupportedDBs() []string {
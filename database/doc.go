@@ -27,5 +27,14 @@ setup a database, insert some data into it, and optionally, query the data back.
 The first block inserted into the database will be treated as the genesis block.
 Every subsequent block insert requires the referenced parent block to already
 exist.
+
+Read-Only Access
+
+Tools which only inspect the block chain, such as block explorer batch jobs,
+should open the database with OpenReadOnlyDB.  It never modifies the database
+files and can be used while a node is running.  For leveldb, a database which
+no node uses is opened in place and keeps nodes from opening it until it is
+closed, while the database of a running node is opened from a snapshot which
+doesn't see the blocks the node connects afterwards.
 */
 package database
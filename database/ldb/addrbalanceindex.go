@@ -0,0 +1,623 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/golangcrypto/ripemd160"
+	"github.com/conseweb/goleveldb/leveldb"
+	"github.com/conseweb/goleveldb/leveldb/util"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// The address balance index is made of four kinds of entries, all of which
+// are keyed by the same hash160 as the address index:
+//
+// The balance of each address is stored under a 24 byte key made of a prefix
+// and the hash160.  The value is the balance followed by the total received.
+//
+// Each unspent output of an address is stored under a 60 byte key:
+// -------------------------------------------------
+// | Prefix  | Hash160  | Tx Sha   | Output Index |
+// -------------------------------------------------
+// | 4 bytes | 20 bytes | 32 bytes |   4 bytes    |
+// -------------------------------------------------
+// The value is the output value, the height of the block which created it and
+// the public key script.
+//
+// Each input and output of an address is stored as a delta under a 37 byte
+// key:
+// ------------------------------------------------------------------------
+// | Prefix  | Hash160  | BlkHeight | Tx Index | Is Output | Input/Output |
+// ------------------------------------------------------------------------
+// | 4 bytes | 20 bytes |  4 bytes  | 4 bytes  |  1 byte   |   4 bytes    |
+// ------------------------------------------------------------------------
+// Like the address index, the numbers are big endian so the deltas of an
+// address are sorted in the order they appear in the chain, with the inputs of
+// a transaction before its outputs.  The value is the tx sha, the amount, and
+// the output created or spent along with its height.
+//
+// Finally, the deltas of each block are stored under a 36 byte key made of a
+// prefix and the block sha.  The value is the sha of the previous block
+// followed by the serialized deltas, which allows rewinding the block once it
+// is no longer in the database.
+const (
+	addrBalanceKeyLength         = 4 + ripemd160.Size
+	addrBalanceValueLength       = 8 + 8
+	addrUtxoKeyLength            = 4 + ripemd160.Size + wire.HashSize + 4
+	addrDeltaKeyLength           = 4 + ripemd160.Size + 4 + 4 + 1 + 4
+	addrDeltaValueLength         = wire.HashSize + 8 + wire.HashSize + 4 + 4
+	addrBalanceBlockKeyLength    = 4 + wire.HashSize
+	addrBalanceBlockDeltaMinSize = ripemd160.Size + wire.HashSize + 4 + 4 +
+		1 + 8 + wire.HashSize + 4 + 4 + 4
+)
+
+var addrBalanceIndexMetaDataKey = []byte("addrbalanceindex")
+
+// Each kind of entry of the address balance index shares a prefix to
+// facilitate the use of iterators.
+var (
+	addrBalanceKeyPrefix      = []byte("ab+-")
+	addrUtxoKeyPrefix         = []byte("au+-")
+	addrDeltaKeyPrefix        = []byte("ad+-")
+	addrBalanceBlockKeyPrefix = []byte("ak+-")
+)
+
+// errCorruptAddrBalanceBlock is returned when the stored deltas of a block
+// can't be deserialized.
+var errCorruptAddrBalanceBlock = errors.New("corrupt address balance " +
+	"index block entry")
+
+// addrBalanceKey returns the key the balance of the passed hash160 is stored
+// under.
+func addrBalanceKey(addrKey []byte) []byte {
+	key := make([]byte, addrBalanceKeyLength)
+	copy(key[0:4], addrBalanceKeyPrefix)
+	copy(key[4:24], addrKey)
+	return key
+}
+
+// addrUtxoKey returns the key the passed unspent output of the passed hash160
+// is stored under.
+func addrUtxoKey(addrKey []byte, outPoint *wire.OutPoint) []byte {
+	key := make([]byte, addrUtxoKeyLength)
+	copy(key[0:4], addrUtxoKeyPrefix)
+	copy(key[4:24], addrKey)
+	copy(key[24:56], outPoint.Hash[:])
+	binary.LittleEndian.PutUint32(key[56:60], outPoint.Index)
+	return key
+}
+
+// addrDeltaKey returns the key the passed delta of a block at the passed height
+// is stored under.
+func addrDeltaKey(delta *database.AddrDelta, height int32) []byte {
+	key := make([]byte, addrDeltaKeyLength)
+	copy(key[0:4], addrDeltaKeyPrefix)
+	copy(key[4:24], delta.Addr[:])
+	binary.BigEndian.PutUint32(key[24:28], uint32(height))
+	binary.BigEndian.PutUint32(key[28:32], delta.BlockIndex)
+	if !delta.Spending {
+		key[32] = 1
+	}
+	binary.BigEndian.PutUint32(key[33:37], delta.Index)
+	return key
+}
+
+// addrDeltaValue serializes the parts of the passed delta which are not
+// stored in its key.
+func addrDeltaValue(delta *database.AddrDelta) []byte {
+	value := make([]byte, addrDeltaValueLength)
+	copy(value[0:32], delta.TxSha[:])
+	binary.LittleEndian.PutUint64(value[32:40], uint64(delta.Amount))
+	copy(value[40:72], delta.OutPoint.Hash[:])
+	binary.LittleEndian.PutUint32(value[72:76], delta.OutPoint.Index)
+	binary.LittleEndian.PutUint32(value[76:80], uint32(delta.OutHeight))
+	return value
+}
+
+// addrBalanceBlockKey returns the key the deltas of the passed block are
+// stored under.
+func addrBalanceBlockKey(blkSha *wire.ShaHash) []byte {
+	key := make([]byte, addrBalanceBlockKeyLength)
+	copy(key[0:4], addrBalanceBlockKeyPrefix)
+	copy(key[4:], blkSha[:])
+	return key
+}
+
+// serializeAddrBalanceBlock serializes the passed previous block sha and
+// deltas of a block.  The height of the deltas is not stored since it is the
+// one of the block.
+func serializeAddrBalanceBlock(prevSha *wire.ShaHash, deltas []database.AddrDelta) []byte {
+	size := wire.HashSize
+	for i := range deltas {
+		size += addrBalanceBlockDeltaMinSize + len(deltas[i].PkScript)
+	}
+
+	data := make([]byte, size)
+	copy(data[0:32], prevSha[:])
+	offset := wire.HashSize
+	for i := range deltas {
+		delta := &deltas[i]
+		copy(data[offset:], delta.Addr[:])
+		offset += ripemd160.Size
+		copy(data[offset:], delta.TxSha[:])
+		offset += wire.HashSize
+		binary.LittleEndian.PutUint32(data[offset:], delta.BlockIndex)
+		binary.LittleEndian.PutUint32(data[offset+4:], delta.Index)
+		if delta.Spending {
+			data[offset+8] = 1
+		}
+		binary.LittleEndian.PutUint64(data[offset+9:], uint64(delta.Amount))
+		offset += 17
+		copy(data[offset:], delta.OutPoint.Hash[:])
+		offset += wire.HashSize
+		binary.LittleEndian.PutUint32(data[offset:], delta.OutPoint.Index)
+		binary.LittleEndian.PutUint32(data[offset+4:],
+			uint32(delta.OutHeight))
+		binary.LittleEndian.PutUint32(data[offset+8:],
+			uint32(len(delta.PkScript)))
+		offset += 12
+		copy(data[offset:], delta.PkScript)
+		offset += len(delta.PkScript)
+	}
+	return data
+}
+
+// deserializeAddrBalanceBlock deserializes the previous block sha and deltas
+// of a block stored by serializeAddrBalanceBlock.
+func deserializeAddrBalanceBlock(data []byte, height int32) (*wire.ShaHash, []database.AddrDelta, error) {
+	if len(data) < wire.HashSize {
+		return nil, nil, errCorruptAddrBalanceBlock
+	}
+	var prevSha wire.ShaHash
+	prevSha.SetBytes(data[0:32])
+
+	var deltas []database.AddrDelta
+	offset := wire.HashSize
+	for offset < len(data) {
+		if len(data)-offset < addrBalanceBlockDeltaMinSize {
+			return nil, nil, errCorruptAddrBalanceBlock
+		}
+		delta := database.AddrDelta{Height: height}
+		copy(delta.Addr[:], data[offset:])
+		offset += ripemd160.Size
+		delta.TxSha.SetBytes(data[offset : offset+32])
+		offset += wire.HashSize
+		delta.BlockIndex = binary.LittleEndian.Uint32(data[offset:])
+		delta.Index = binary.LittleEndian.Uint32(data[offset+4:])
+		delta.Spending = data[offset+8] != 0
+		delta.Amount = int64(binary.LittleEndian.Uint64(data[offset+9:]))
+		offset += 17
+		delta.OutPoint.Hash.SetBytes(data[offset : offset+32])
+		offset += wire.HashSize
+		delta.OutPoint.Index = binary.LittleEndian.Uint32(data[offset:])
+		delta.OutHeight = int32(binary.LittleEndian.Uint32(data[offset+4:]))
+		scriptLen := int(binary.LittleEndian.Uint32(data[offset+8:]))
+		offset += 12
+		if scriptLen > len(data)-offset {
+			return nil, nil, errCorruptAddrBalanceBlock
+		}
+		delta.PkScript = make([]byte, scriptLen)
+		copy(delta.PkScript, data[offset:offset+scriptLen])
+		offset += scriptLen
+		deltas = append(deltas, delta)
+	}
+	return &prevSha, deltas, nil
+}
+
+// fetchAddrBalanceIndexTip returns the last block height and block sha to have
+// its address balance changes indexed.  Like the other indexes, the tip is
+// cached in memory and this function is only used on start up to load it.
+func (db *LevelDb) fetchAddrBalanceIndexTip() (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	data, err := db.lDb.Get(addrBalanceIndexMetaDataKey, db.ro)
+	if err != nil {
+		return &wire.ShaHash{}, -1, database.ErrAddrBalanceIndexDoesNotExist
+	}
+
+	var blkSha wire.ShaHash
+	blkSha.SetBytes(data[0:32])
+
+	blkHeight := binary.LittleEndian.Uint64(data[32:])
+
+	return &blkSha, int32(blkHeight), nil
+}
+
+// FetchAddrBalanceIndexTip returns the hash and block height of the most recent
+// block which has had its address balance changes indexed.  It will return
+// ErrAddrBalanceIndexDoesNotExist along with a zero hash, and -1 if the
+// address balance index hasn't yet been built up.
+func (db *LevelDb) FetchAddrBalanceIndexTip() (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	if db.lastAddrBalanceIndexBlkIdx == -1 {
+		return &wire.ShaHash{}, -1, database.ErrAddrBalanceIndexDoesNotExist
+	}
+	sha := db.lastAddrBalanceIndexBlkSha
+
+	return &sha, db.lastAddrBalanceIndexBlkIdx, nil
+}
+
+// putAddrBalanceIndexTip adds the passed block as the new tip of the address
+// balance index to the passed batch.
+func putAddrBalanceIndexTip(batch *leveldb.Batch, blkSha *wire.ShaHash, blkHeight int32) {
+	newIndexTip := make([]byte, 40, 40)
+	copy(newIndexTip[0:32], blkSha[:])
+	binary.LittleEndian.PutUint64(newIndexTip[32:40], uint64(blkHeight))
+	batch.Put(addrBalanceIndexMetaDataKey, newIndexTip)
+}
+
+// fetchAddrBalance returns the stored balance of the passed hash160 or a zero
+// balance when there is none.
+//
+// This function MUST be called with the db lock held.
+func (db *LevelDb) fetchAddrBalance(addrKey []byte) (*database.AddrBalance, error) {
+	balance := &database.AddrBalance{}
+	data, err := db.lDb.Get(addrBalanceKey(addrKey), db.ro)
+	switch {
+	case err == leveldb.ErrNotFound:
+	case err != nil:
+		return nil, err
+	case len(data) >= addrBalanceValueLength:
+		balance.Balance = int64(binary.LittleEndian.Uint64(data[0:8]))
+		balance.Received = int64(binary.LittleEndian.Uint64(data[8:16]))
+	}
+	return balance, nil
+}
+
+// addrBalanceUpdater accumulates the changes to the balances of addresses made
+// while applying or reverting the deltas of a block so each balance is only
+// read and written once.
+type addrBalanceUpdater struct {
+	db       *LevelDb
+	balances map[[ripemd160.Size]byte]*database.AddrBalance
+}
+
+// balance returns the balance of the passed hash160, loading it from the
+// database when it hasn't been changed yet.
+//
+// This function MUST be called with the db lock held.
+func (u *addrBalanceUpdater) balance(addrKey [ripemd160.Size]byte) (*database.AddrBalance, error) {
+	if balance, ok := u.balances[addrKey]; ok {
+		return balance, nil
+	}
+
+	balance, err := u.db.fetchAddrBalance(addrKey[:])
+	if err != nil {
+		return nil, err
+	}
+	u.balances[addrKey] = balance
+	return balance, nil
+}
+
+// write adds the changed balances to the passed batch.  Addresses which are
+// left without any balance or received value are removed.
+func (u *addrBalanceUpdater) write(batch *leveldb.Batch) {
+	for addrKey, balance := range u.balances {
+		key := addrBalanceKey(addrKey[:])
+		if balance.Balance == 0 && balance.Received == 0 {
+			batch.Delete(key)
+			continue
+		}
+		value := make([]byte, addrBalanceValueLength)
+		binary.LittleEndian.PutUint64(value[0:8], uint64(balance.Balance))
+		binary.LittleEndian.PutUint64(value[8:16], uint64(balance.Received))
+		batch.Put(key, value)
+	}
+}
+
+// addrUtxoValue serializes the value of an unspent output entry.
+func addrUtxoValue(value int64, height int32, pkScript []byte) []byte {
+	data := make([]byte, 12+len(pkScript))
+	binary.LittleEndian.PutUint64(data[0:8], uint64(value))
+	binary.LittleEndian.PutUint32(data[8:12], uint32(height))
+	copy(data[12:], pkScript)
+	return data
+}
+
+// UpdateAddrBalanceIndexForBlock applies the passed balance changes of a block
+// to the balances, unspent outputs and deltas of each address and marks the
+// block as the tip of the address balance index.
+func (db *LevelDb) UpdateAddrBalanceIndexForBlock(blkSha *wire.ShaHash, blkHeight int32,
+	prevSha *wire.ShaHash, deltas []database.AddrDelta) error {
+
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	batch := db.lBatch()
+	defer db.lbatch.Reset()
+
+	// The deltas are applied in order, so an output created and spent in
+	// the same block is put and then deleted within the batch.
+	updater := &addrBalanceUpdater{
+		db:       db,
+		balances: make(map[[ripemd160.Size]byte]*database.AddrBalance),
+	}
+	for i := range deltas {
+		delta := &deltas[i]
+		balance, err := updater.balance(delta.Addr)
+		if err != nil {
+			return err
+		}
+		balance.Balance += delta.Amount
+
+		utxoKey := addrUtxoKey(delta.Addr[:], &delta.OutPoint)
+		if delta.Spending {
+			batch.Delete(utxoKey)
+		} else {
+			balance.Received += delta.Amount
+			batch.Put(utxoKey, addrUtxoValue(delta.Amount,
+				delta.OutHeight, delta.PkScript))
+		}
+		batch.Put(addrDeltaKey(delta, blkHeight), addrDeltaValue(delta))
+	}
+	updater.write(batch)
+
+	batch.Put(addrBalanceBlockKey(blkSha),
+		serializeAddrBalanceBlock(prevSha, deltas))
+	putAddrBalanceIndexTip(batch, blkSha, blkHeight)
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	db.lastAddrBalanceIndexBlkIdx = blkHeight
+	db.lastAddrBalanceIndexBlkSha = *blkSha
+
+	return nil
+}
+
+// RewindAddrBalanceIndex reverts the balance changes of the block at the tip of
+// the address balance index and marks the previous block as the tip.
+func (db *LevelDb) RewindAddrBalanceIndex() error {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	if db.lastAddrBalanceIndexBlkIdx == -1 {
+		return database.ErrAddrBalanceIndexDoesNotExist
+	}
+	tipSha := db.lastAddrBalanceIndexBlkSha
+	tipHeight := db.lastAddrBalanceIndexBlkIdx
+
+	blockKey := addrBalanceBlockKey(&tipSha)
+	data, err := db.lDb.Get(blockKey, db.ro)
+	if err != nil {
+		return err
+	}
+	prevSha, deltas, err := deserializeAddrBalanceBlock(data, tipHeight)
+	if err != nil {
+		return err
+	}
+
+	batch := db.lBatch()
+	defer db.lbatch.Reset()
+
+	// Revert the deltas in the reverse order they were applied in so an
+	// output created and spent in the same block ends up deleted.
+	updater := &addrBalanceUpdater{
+		db:       db,
+		balances: make(map[[ripemd160.Size]byte]*database.AddrBalance),
+	}
+	for i := len(deltas) - 1; i >= 0; i-- {
+		delta := &deltas[i]
+		balance, err := updater.balance(delta.Addr)
+		if err != nil {
+			return err
+		}
+		balance.Balance -= delta.Amount
+
+		utxoKey := addrUtxoKey(delta.Addr[:], &delta.OutPoint)
+		if delta.Spending {
+			batch.Put(utxoKey, addrUtxoValue(-delta.Amount,
+				delta.OutHeight, delta.PkScript))
+		} else {
+			balance.Received -= delta.Amount
+			batch.Delete(utxoKey)
+		}
+		batch.Delete(addrDeltaKey(delta, tipHeight))
+	}
+	updater.write(batch)
+	batch.Delete(blockKey)
+
+	if tipHeight == 0 {
+		batch.Delete(addrBalanceIndexMetaDataKey)
+	} else {
+		putAddrBalanceIndexTip(batch, prevSha, tipHeight-1)
+	}
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	if tipHeight == 0 {
+		db.lastAddrBalanceIndexBlkIdx = -1
+		db.lastAddrBalanceIndexBlkSha = wire.ShaHash{}
+	} else {
+		db.lastAddrBalanceIndexBlkIdx = tipHeight - 1
+		db.lastAddrBalanceIndexBlkSha = *prevSha
+	}
+
+	return nil
+}
+
+// FetchAddrBalance returns the confirmed balance of the passed address.  An
+// address which has never received anything has a zero balance.
+func (db *LevelDb) FetchAddrBalance(addr coinutil.Address) (*database.AddrBalance, error) {
+	addrKey, err := addrToKey(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	return db.fetchAddrBalance(addrKey)
+}
+
+// FetchAddrUtxos returns the confirmed unspent outputs of the passed address
+// ordered by transaction hash.
+func (db *LevelDb) FetchAddrUtxos(addr coinutil.Address) ([]*database.AddrUtxo, error) {
+	addrKey, err := addrToKey(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	prefix := make([]byte, 4+ripemd160.Size)
+	copy(prefix[0:4], addrUtxoKeyPrefix)
+	copy(prefix[4:], addrKey)
+
+	var utxos []*database.AddrUtxo
+	iter := db.lDb.NewIterator(bytesPrefix(prefix), db.ro)
+	for iter.Next() {
+		key := iter.Key()
+		value := iter.Value()
+		if len(key) != addrUtxoKeyLength || len(value) < 12 {
+			continue
+		}
+
+		utxo := &database.AddrUtxo{
+			Value:    int64(binary.LittleEndian.Uint64(value[0:8])),
+			Height:   int32(binary.LittleEndian.Uint32(value[8:12])),
+			PkScript: make([]byte, len(value)-12),
+		}
+		utxo.OutPoint.Hash.SetBytes(key[24:56])
+		utxo.OutPoint.Index = binary.LittleEndian.Uint32(key[56:60])
+		copy(utxo.PkScript, value[12:])
+		utxos = append(utxos, utxo)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	return utxos, nil
+}
+
+// FetchAddrDeltas returns the balance changes of the passed address made by
+// the blocks from startHeight to endHeight inclusive, in the order they appear
+// in the chain.
+func (db *LevelDb) FetchAddrDeltas(addr coinutil.Address, startHeight,
+	endHeight int32) ([]*database.AddrDelta, error) {
+
+	addrKey, err := addrToKey(addr)
+	if err != nil {
+		return nil, err
+	}
+	if startHeight < 0 || endHeight < startHeight {
+		return nil, nil
+	}
+
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	// The heights are big endian, so the deltas of the range lie between
+	// the keys of the start height and the height after the end.
+	start := make([]byte, 4+ripemd160.Size+4)
+	copy(start[0:4], addrDeltaKeyPrefix)
+	copy(start[4:24], addrKey)
+	limit := make([]byte, len(start))
+	copy(limit, start)
+	binary.BigEndian.PutUint32(start[24:28], uint32(startHeight))
+	binary.BigEndian.PutUint32(limit[24:28], uint32(endHeight)+1)
+
+	var deltas []*database.AddrDelta
+	iter := db.lDb.NewIterator(&util.Range{Start: start, Limit: limit}, db.ro)
+	for iter.Next() {
+		key := iter.Key()
+		value := iter.Value()
+		if len(key) != addrDeltaKeyLength ||
+			len(value) != addrDeltaValueLength {
+			continue
+		}
+
+		delta := &database.AddrDelta{
+			Height:     int32(binary.BigEndian.Uint32(key[24:28])),
+			BlockIndex: binary.BigEndian.Uint32(key[28:32]),
+			Spending:   key[32] == 0,
+			Index:      binary.BigEndian.Uint32(key[33:37]),
+			Amount:     int64(binary.LittleEndian.Uint64(value[32:40])),
+			OutHeight:  int32(binary.LittleEndian.Uint32(value[76:80])),
+		}
+		copy(delta.Addr[:], addrKey)
+		delta.TxSha.SetBytes(value[0:32])
+		delta.OutPoint.Hash.SetBytes(value[40:72])
+		delta.OutPoint.Index = binary.LittleEndian.Uint32(value[72:76])
+		deltas = append(deltas, delta)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	return deltas, nil
+}
+
+// DeleteAddrBalanceIndex deletes the entire address balance index stored within
+// the DB.  It also resets the cached in-memory metadata about the index.
+func (db *LevelDb) DeleteAddrBalanceIndex() error {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	batch := db.lBatch()
+	defer batch.Reset()
+
+	// Delete the entire index along with any metadata about it.
+	prefixes := []struct {
+		prefix    []byte
+		keyLength int
+	}{
+		{addrBalanceKeyPrefix, addrBalanceKeyLength},
+		{addrUtxoKeyPrefix, addrUtxoKeyLength},
+		{addrDeltaKeyPrefix, addrDeltaKeyLength},
+		{addrBalanceBlockKeyPrefix, addrBalanceBlockKeyLength},
+	}
+	numInBatch := 0
+	for _, p := range prefixes {
+		iter := db.lDb.NewIterator(bytesPrefix(p.prefix), db.ro)
+		for iter.Next() {
+			key := iter.Key()
+			if len(key) == p.keyLength {
+				batch.Delete(key)
+				numInBatch++
+			}
+
+			// Delete in chunks to potentially avoid very large
+			// batches.
+			if numInBatch >= batchDeleteThreshold {
+				if err := db.lDb.Write(batch, db.wo); err != nil {
+					iter.Release()
+					return err
+				}
+				batch.Reset()
+				numInBatch = 0
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+
+	batch.Delete(addrBalanceIndexMetaDataKey)
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	db.lastAddrBalanceIndexBlkIdx = -1
+	db.lastAddrBalanceIndexBlkSha = wire.ShaHash{}
+
+	return nil
+}
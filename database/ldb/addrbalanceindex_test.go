@@ -0,0 +1,179 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestAddrBalanceIndex tests applying, fetching, rewinding and deleting the
+// balance changes of addresses along with the persistence of the address
+// balance index tip.
+func TestAddrBalanceIndex(t *testing.T) {
+	dbname := "tstdbaddrbalanceindex"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+	defer func() { db.Close() }()
+
+	// A fresh database has no address balance index.
+	if _, height, err := db.FetchAddrBalanceIndexTip(); err != database.ErrAddrBalanceIndexDoesNotExist ||
+		height != -1 {
+		t.Fatalf("FetchAddrBalanceIndexTip: got height %v err %v",
+			height, err)
+	}
+
+	var addrKey [database.AddrIndexKeySize]byte
+	addrKey[0] = 0xaa
+	addr, err := coinutil.NewAddressPubKeyHash(addrKey[:],
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error %v", err)
+	}
+	pkScript := []byte{0x76, 0xa9}
+
+	assertState := func(wantBalance, wantReceived int64,
+		wantUtxos []wire.OutPoint, wantDeltas int) {
+
+		balance, err := db.FetchAddrBalance(addr)
+		if err != nil {
+			t.Fatalf("FetchAddrBalance: unexpected error %v", err)
+		}
+		if balance.Balance != wantBalance ||
+			balance.Received != wantReceived {
+			t.Fatalf("FetchAddrBalance: got %+v, want balance %d "+
+				"received %d", balance, wantBalance, wantReceived)
+		}
+
+		utxos, err := db.FetchAddrUtxos(addr)
+		if err != nil {
+			t.Fatalf("FetchAddrUtxos: unexpected error %v", err)
+		}
+		if len(utxos) != len(wantUtxos) {
+			t.Fatalf("FetchAddrUtxos: got %d outputs, want %d",
+				len(utxos), len(wantUtxos))
+		}
+		for i, utxo := range utxos {
+			if utxo.OutPoint != wantUtxos[i] {
+				t.Fatalf("FetchAddrUtxos #%d: got %v, want %v",
+					i, utxo.OutPoint, wantUtxos[i])
+			}
+		}
+
+		deltas, err := db.FetchAddrDeltas(addr, 0, 100)
+		if err != nil {
+			t.Fatalf("FetchAddrDeltas: unexpected error %v", err)
+		}
+		if len(deltas) != wantDeltas {
+			t.Fatalf("FetchAddrDeltas: got %d deltas, want %d",
+				len(deltas), wantDeltas)
+		}
+	}
+
+	// Block 1 pays the address twice.
+	prevSha := wire.ShaHash{0x01}
+	blk1Sha := wire.ShaHash{0x02}
+	tx1Sha := wire.ShaHash{0x10}
+	out1 := wire.OutPoint{Hash: tx1Sha, Index: 0}
+	out2 := wire.OutPoint{Hash: tx1Sha, Index: 1}
+	deltas := []database.AddrDelta{
+		{Addr: addrKey, TxSha: tx1Sha, Index: 0, Amount: 50,
+			OutPoint: out1, OutHeight: 1, PkScript: pkScript},
+		{Addr: addrKey, TxSha: tx1Sha, Index: 1, Amount: 10,
+			OutPoint: out2, OutHeight: 1, PkScript: pkScript},
+	}
+	err = db.UpdateAddrBalanceIndexForBlock(&blk1Sha, 1, &prevSha, deltas)
+	if err != nil {
+		t.Fatalf("UpdateAddrBalanceIndexForBlock: unexpected error %v",
+			err)
+	}
+	assertState(60, 60, []wire.OutPoint{out1, out2}, 2)
+
+	// The index must survive a restart.
+	db.Sync()
+	db.Close()
+	db, err = database.OpenDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Unable to re-open created db, err %v", err)
+	}
+	assertState(60, 60, []wire.OutPoint{out1, out2}, 2)
+
+	// Block 2 spends the first output and pays the address an output
+	// which is spent by a later transaction of the same block.
+	blk2Sha := wire.ShaHash{0x03}
+	tx2Sha := wire.ShaHash{0x11}
+	tx3Sha := wire.ShaHash{0x12}
+	out3 := wire.OutPoint{Hash: tx2Sha, Index: 0}
+	deltas = []database.AddrDelta{
+		{Addr: addrKey, TxSha: tx2Sha, BlockIndex: 1, Index: 0,
+			Spending: true, Amount: -50, OutPoint: out1,
+			OutHeight: 1, PkScript: pkScript},
+		{Addr: addrKey, TxSha: tx2Sha, BlockIndex: 1, Index: 0,
+			Amount: 20, OutPoint: out3, OutHeight: 2,
+			PkScript: pkScript},
+		{Addr: addrKey, TxSha: tx3Sha, BlockIndex: 2, Index: 0,
+			Spending: true, Amount: -20, OutPoint: out3,
+			OutHeight: 2, PkScript: pkScript},
+	}
+	err = db.UpdateAddrBalanceIndexForBlock(&blk2Sha, 2, &blk1Sha, deltas)
+	if err != nil {
+		t.Fatalf("UpdateAddrBalanceIndexForBlock: unexpected error %v",
+			err)
+	}
+	assertState(10, 80, []wire.OutPoint{out2}, 5)
+
+	// The deltas of a height range are returned in chain order.
+	got, err := db.FetchAddrDeltas(addr, 2, 2)
+	if err != nil {
+		t.Fatalf("FetchAddrDeltas: unexpected error %v", err)
+	}
+	if len(got) != 3 || got[0].TxSha != tx2Sha || !got[0].Spending ||
+		got[1].Spending || got[2].TxSha != tx3Sha ||
+		got[2].Amount != -20 || got[2].Height != 2 {
+		t.Fatalf("FetchAddrDeltas: unexpected deltas %+v", got)
+	}
+
+	// Rewinding block 2 restores the spent output and removes the one
+	// created and spent within the block.
+	if err := db.RewindAddrBalanceIndex(); err != nil {
+		t.Fatalf("RewindAddrBalanceIndex: unexpected error %v", err)
+	}
+	sha, height, err := db.FetchAddrBalanceIndexTip()
+	if err != nil || height != 1 || *sha != blk1Sha {
+		t.Fatalf("FetchAddrBalanceIndexTip: got %v %v err %v", sha,
+			height, err)
+	}
+	assertState(60, 60, []wire.OutPoint{out1, out2}, 2)
+
+	// Rewinding block 1 as well leaves nothing for the address.
+	if err := db.RewindAddrBalanceIndex(); err != nil {
+		t.Fatalf("RewindAddrBalanceIndex: unexpected error %v", err)
+	}
+	sha, height, err = db.FetchAddrBalanceIndexTip()
+	if err != nil || height != 0 || *sha != prevSha {
+		t.Fatalf("FetchAddrBalanceIndexTip: got %v %v err %v", sha,
+			height, err)
+	}
+	assertState(0, 0, nil, 0)
+
+	if err := db.DeleteAddrBalanceIndex(); err != nil {
+		t.Fatalf("DeleteAddrBalanceIndex: unexpected error %v", err)
+	}
+	if _, _, err := db.FetchAddrBalanceIndexTip(); err != database.ErrAddrBalanceIndexDoesNotExist {
+		t.Fatalf("FetchAddrBalanceIndexTip after delete: unexpected "+
+			"error %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"os"
+
+	"github.com/conseweb/goleveldb/leveldb"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// backupBatchSize is the number of bytes of keys and values which are written
+// to a backup at once.
+const backupBatchSize = 4 * 1024 * 1024
+
+// Backup writes a consistent copy of the entire database as of the time it is
+// called to a new database at the passed path, and returns the hash and height
+// of the most recent block in the copy.
+//
+// The copy is read from a leveldb snapshot taken while holding the database
+// lock, so it always reflects the database between two operations.  The lock
+// is released while the copy is written, which allows blocks to be connected
+// in the meantime.  This is part of the database.Db interface implementation.
+func (db *LevelDb) Backup(dbPath string, interrupt <-chan struct{}) (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	snapshot, err := db.lDb.GetSnapshot()
+	sha, height := db.lastBlkSha, db.lastBlkIdx
+	db.dbLock.Unlock()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer snapshot.Release()
+
+	backup, err := openDB(dbPath, true, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	bdb := backup.(*LevelDb)
+	err = copySnapshot(snapshot, bdb, interrupt)
+	if cerr := bdb.close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.RemoveAll(dbPath)
+		os.Remove(dbPath + ".ver")
+		return nil, 0, err
+	}
+	return &sha, height, nil
+}
+
+// copySnapshot writes every key and value of the passed snapshot to the
+// passed database in batches.
+func copySnapshot(snapshot *leveldb.Snapshot, db *LevelDb, interrupt <-chan struct{}) error {
+	iter := snapshot.NewIterator(nil, nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	batchBytes := 0
+	writeBatch := func() error {
+		select {
+		case <-interrupt:
+			return database.ErrInterrupted
+		default:
+		}
+		if err := db.lDb.Write(batch, db.wo); err != nil {
+			return err
+		}
+		batch.Reset()
+		batchBytes = 0
+		return nil
+	}
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		batchBytes += len(iter.Key()) + len(iter.Value())
+		if batchBytes >= backupBatchSize {
+			if err := writeBatch(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return writeBatch()
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/conseweb/stcd/database"
+)
+
+// TestBackup ensures a backup holds the blocks of the database at the time it
+// was started, doesn't overwrite existing databases and is removed when it is
+// interrupted.
+func TestBackup(t *testing.T) {
+	dbname := "tstdbbackup"
+	backupname := "tstdbbackup_copy"
+	for _, path := range []string{dbname, backupname} {
+		_ = os.RemoveAll(path)
+		_ = os.RemoveAll(path + ".ver")
+		defer os.RemoveAll(path)
+		defer os.RemoveAll(path + ".ver")
+	}
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer func() { db.Close() }()
+
+	blocks := makeCacheTestBlocks(30)
+	for height, block := range blocks[:20] {
+		if _, err := db.InsertBlock(block); err != nil {
+			t.Fatalf("failed to insert block %v err %v", height, err)
+		}
+	}
+
+	// An interrupted backup is removed.
+	interrupt := make(chan struct{})
+	close(interrupt)
+	_, _, err = db.Backup(backupname, interrupt)
+	if err != database.ErrInterrupted {
+		t.Fatalf("Backup: got %v, want %v", err, database.ErrInterrupted)
+	}
+	if _, err := os.Stat(backupname); !os.IsNotExist(err) {
+		t.Fatalf("Backup: the interrupted backup was not removed")
+	}
+
+	sha, height, err := db.Backup(backupname, nil)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if height != 19 || !sha.IsEqual(blocks[19].Sha()) {
+		t.Fatalf("Backup: got block %v (height %d), want %v (height 19)",
+			sha, height, blocks[19].Sha())
+	}
+	if _, _, err := db.Backup(backupname, nil); err == nil {
+		t.Fatalf("Backup: overwrote an existing database")
+	}
+
+	// Blocks inserted afterwards are not in the backup.
+	for height, block := range blocks[20:] {
+		if _, err := db.InsertBlock(block); err != nil {
+			t.Fatalf("failed to insert block %v err %v", height+20, err)
+		}
+	}
+	backup, err := database.OpenDB("leveldb", backupname)
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	defer backup.Close()
+	if gotSha, gotHeight, _ := backup.NewestSha(); gotHeight != 19 ||
+		!gotSha.IsEqual(sha) {
+
+		t.Fatalf("NewestSha: got %v (height %d), want %v (height 19)",
+			gotSha, gotHeight, sha)
+	}
+	for height, block := range blocks[:20] {
+		got, err := backup.FetchBlockBySha(block.Sha())
+		if err != nil || !got.Sha().IsEqual(block.Sha()) {
+			t.Fatalf("FetchBlockBySha: block %d is missing from the "+
+				"backup: %v", height, err)
+		}
+	}
+}
@@ -53,8 +53,12 @@ func (db *LevelDb) FetchBlockHeaderBySha(sha *wire.ShaHash) (bh *wire.BlockHeade
 	db.dbLock.Lock()
 	defer db.dbLock.Unlock()
 
+	if header, ok := db.blkCache.lookupHeader(sha); ok {
+		return header, nil
+	}
+
 	// Read the raw block from the database.
-	buf, _, err := db.fetchSha(sha)
+	buf, height, err := db.fetchSha(sha)
 	if err != nil {
 		return nil, err
 	}
@@ -67,11 +71,16 @@ func (db *LevelDb) FetchBlockHeaderBySha(sha *wire.ShaHash) (bh *wire.BlockHeade
 		return nil, err
 	}
 	bh = &blockHeader
+	db.blkCache.add(sha, height, bh)
 
 	return bh, err
 }
 
 func (db *LevelDb) getBlkLoc(sha *wire.ShaHash) (int32, error) {
+	if height, ok := db.blkCache.lookupSha(sha); ok {
+		return height, nil
+	}
+
 	key := shaBlkToKey(sha)
 
 	data, err := db.lDb.Get(key, db.ro)
@@ -83,9 +92,10 @@ func (db *LevelDb) getBlkLoc(sha *wire.ShaHash) (int32, error) {
 	}
 
 	// deserialize
-	blkHeight := binary.LittleEndian.Uint64(data)
+	blkHeight := int32(binary.LittleEndian.Uint64(data))
+	db.blkCache.add(sha, blkHeight, nil)
 
-	return int32(blkHeight), nil
+	return blkHeight, nil
 }
 
 func (db *LevelDb) getBlkByHeight(blkHeight int32) (rsha *wire.ShaHash, rbuf []byte, err error) {
@@ -201,6 +211,10 @@ func (db *LevelDb) ExistsSha(sha *wire.ShaHash) (bool, error) {
 // returns true if it is present in the database.
 // CALLED WITH LOCK HELD
 func (db *LevelDb) blkExistsSha(sha *wire.ShaHash) (bool, error) {
+	if _, ok := db.blkCache.lookupSha(sha); ok {
+		return true, nil
+	}
+
 	key := shaBlkToKey(sha)
 
 	return db.lDb.Has(key, db.ro)
@@ -218,6 +232,10 @@ func (db *LevelDb) FetchBlockShaByHeight(height int32) (sha *wire.ShaHash, err e
 // fetchBlockShaByHeight returns a block hash based on its height in the
 // block chain.
 func (db *LevelDb) fetchBlockShaByHeight(height int32) (rsha *wire.ShaHash, err error) {
+	if sha, ok := db.blkCache.lookupHeight(height); ok {
+		return sha, nil
+	}
+
 	key := int64ToKey(int64(height))
 
 	blkVal, err := db.lDb.Get(key, db.ro)
@@ -228,6 +246,7 @@ func (db *LevelDb) fetchBlockShaByHeight(height int32) (rsha *wire.ShaHash, err
 
 	var sha wire.ShaHash
 	sha.SetBytes(blkVal[0:32])
+	db.blkCache.add(&sha, height, nil)
 
 	return &sha, nil
 }
@@ -249,6 +268,11 @@ func (db *LevelDb) FetchHeightRange(startHeight, endHeight int32) (rshalist []wi
 
 	shalist := make([]wire.ShaHash, 0, endidx-startHeight)
 	for height := startHeight; height < endidx; height++ {
+		if sha, ok := db.blkCache.lookupHeight(height); ok {
+			shalist = append(shalist, *sha)
+			continue
+		}
+
 		// TODO(drahn) fix blkFile from height
 
 		key := int64ToKey(int64(height))
@@ -260,6 +284,7 @@ func (db *LevelDb) FetchHeightRange(startHeight, endHeight int32) (rshalist []wi
 		var sha wire.ShaHash
 		sha.SetBytes(blkVal[0:32])
 		shalist = append(shalist, sha)
+		db.blkCache.add(&sha, height, nil)
 	}
 
 	if err != nil {
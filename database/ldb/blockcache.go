@@ -0,0 +1,175 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"container/list"
+
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// blockCacheSize is the maximum number of blocks whose hash, height and header
+// are kept by the block cache.  Each entry takes a couple of hundred bytes, so
+// the cache stays at a few megabytes while still covering the blocks walked by
+// a typical rescan or a full getheaders response many times over.
+const blockCacheSize = 20000
+
+// blockCacheEntry is the cached hash, height and, once it has been loaded,
+// header of a block in the main chain.
+type blockCacheEntry struct {
+	sha    wire.ShaHash
+	height int32
+	header *wire.BlockHeader
+}
+
+// blockCache is a least recently used cache of the hashes, heights and headers
+// of the blocks in the main chain.  It serves the repeated lookups made while
+// rescanning, building block locators and serving headers to peers without
+// going to disk.
+//
+// Blocks are added as they are inserted into the database, so the lookups of
+// the blocks near the tip of the chain are served from the cache straight
+// away, and whenever a lookup misses.  Blocks are removed when they are
+// dropped from the database.
+//
+// The cache is not safe for concurrent access and is protected by the database
+// lock.
+type blockCache struct {
+	maxEntries int
+	lru        *list.List
+	byHeight   map[int32]*list.Element
+	bySha      map[wire.ShaHash]*list.Element
+	stats      database.CacheStats
+}
+
+// newBlockCache returns a new empty block cache which holds up to maxEntries
+// blocks.
+func newBlockCache(maxEntries int) *blockCache {
+	return &blockCache{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		byHeight:   make(map[int32]*list.Element),
+		bySha:      make(map[wire.ShaHash]*list.Element),
+	}
+}
+
+// lookupHeight returns the hash of the block at the passed height if it is
+// cached.
+func (c *blockCache) lookupHeight(height int32) (*wire.ShaHash, bool) {
+	elem, ok := c.byHeight[height]
+	if !ok {
+		c.stats.HeightMisses++
+		return nil, false
+	}
+	c.stats.HeightHits++
+	c.lru.MoveToFront(elem)
+	sha := elem.Value.(*blockCacheEntry).sha
+	return &sha, true
+}
+
+// lookupSha returns the height of the block with the passed hash if it is
+// cached.
+func (c *blockCache) lookupSha(sha *wire.ShaHash) (int32, bool) {
+	elem, ok := c.bySha[*sha]
+	if !ok {
+		c.stats.ShaMisses++
+		return 0, false
+	}
+	c.stats.ShaHits++
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*blockCacheEntry).height, true
+}
+
+// lookupHeader returns a copy of the header of the block with the passed hash
+// if it is cached.
+func (c *blockCache) lookupHeader(sha *wire.ShaHash) (*wire.BlockHeader, bool) {
+	elem, ok := c.bySha[*sha]
+	if !ok || elem.Value.(*blockCacheEntry).header == nil {
+		c.stats.HeaderMisses++
+		return nil, false
+	}
+	c.stats.HeaderHits++
+	c.lru.MoveToFront(elem)
+	header := *elem.Value.(*blockCacheEntry).header
+	return &header, true
+}
+
+// add caches the hash and height of a block along with its header when it is
+// not nil, evicting the least recently used block when the cache is full.  The
+// header is copied so the caller is free to modify it afterwards.
+func (c *blockCache) add(sha *wire.ShaHash, height int32, header *wire.BlockHeader) {
+	var headerCopy *wire.BlockHeader
+	if header != nil {
+		h := *header
+		headerCopy = &h
+	}
+
+	// A block can't be at another height than the one it was cached at
+	// unless the chain was rewritten underneath the cache.
+	if elem, ok := c.bySha[*sha]; ok &&
+		elem.Value.(*blockCacheEntry).height != height {
+
+		c.remove(elem)
+	}
+
+	// Refresh the existing entry, keeping a previously loaded header.
+	if elem, ok := c.byHeight[height]; ok {
+		entry := elem.Value.(*blockCacheEntry)
+		if entry.sha == *sha {
+			if headerCopy != nil {
+				entry.header = headerCopy
+			}
+			c.lru.MoveToFront(elem)
+			return
+		}
+		c.remove(elem)
+	}
+
+	if c.lru.Len() >= c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+	elem := c.lru.PushFront(&blockCacheEntry{
+		sha:    *sha,
+		height: height,
+		header: headerCopy,
+	})
+	c.byHeight[height] = elem
+	c.bySha[*sha] = elem
+}
+
+// remove removes the passed element from the cache.
+func (c *blockCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*blockCacheEntry)
+	delete(c.byHeight, entry.height)
+	delete(c.bySha, entry.sha)
+}
+
+// removeAbove removes the blocks above the passed height from the cache.  It
+// is used when the blocks are dropped from the database.
+func (c *blockCache) removeAbove(height int32) {
+	for h, elem := range c.byHeight {
+		if h > height {
+			c.remove(elem)
+		}
+	}
+}
+
+// statistics returns a snapshot of the statistics of the cache.
+func (c *blockCache) statistics() *database.CacheStats {
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.MaxEntries = c.maxEntries
+	return &stats
+}
+
+// FetchCacheStats returns the statistics of the cache of block hashes, heights
+// and headers.  This is part of the database.Db interface implementation.
+func (db *LevelDb) FetchCacheStats() *database.CacheStats {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	return db.blkCache.statistics()
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// makeCacheTestBlocks returns a chain of the passed number of blocks which
+// only contain a coinbase transaction.
+func makeCacheTestBlocks(numBlocks int) []*coinutil.Block {
+	blocks := make([]*coinutil.Block, 0, numBlocks)
+	var prevSha wire.ShaHash
+	for i := 0; i < numBlocks; i++ {
+		coinbase := wire.NewMsgTx()
+		coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&wire.ShaHash{},
+			wire.MaxPrevOutIndex), []byte{byte(i), byte(i >> 8)}))
+		coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+
+		msgBlock := wire.NewMsgBlock(wire.NewBlockHeader(&prevSha,
+			&wire.ShaHash{}, 0x1d00ffff, uint32(i)))
+		msgBlock.AddTransaction(coinbase)
+		block := coinutil.NewBlock(msgBlock)
+		blocks = append(blocks, block)
+		prevSha = *block.Sha()
+	}
+	return blocks
+}
+
+// TestBlockCache ensures the lookups of block hashes, heights and headers are
+// served from the block cache once the blocks have been inserted or looked up,
+// that dropped blocks are no longer served and that the hit and miss counters
+// reflect the lookups.
+func TestBlockCache(t *testing.T) {
+	dbname := "tstdbblockcache"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+	defer func() { db.Close() }()
+
+	const numBlocks = 120
+	blocks := makeCacheTestBlocks(numBlocks)
+	for height := int32(0); height < numBlocks; height++ {
+		_, err := db.InsertBlock(blocks[height])
+		if err != nil {
+			t.Fatalf("failed to insert block %v err %v", height, err)
+		}
+	}
+
+	assertRange := func(start, end int32) {
+		shas, err := db.FetchHeightRange(start, end)
+		if err != nil {
+			t.Fatalf("FetchHeightRange: unexpected error %v", err)
+		}
+		if len(shas) != int(end-start) {
+			t.Fatalf("FetchHeightRange: got %d hashes, want %d",
+				len(shas), end-start)
+		}
+		for i := range shas {
+			if want := blocks[start+int32(i)].Sha(); shas[i] != *want {
+				t.Fatalf("FetchHeightRange #%d: got %v, want %v",
+					i, shas[i], want)
+			}
+		}
+	}
+
+	// Inserted blocks are cached along with their headers, so neither the
+	// hashes nor the headers are read from disk.
+	stats := db.FetchCacheStats()
+	if stats.Entries != numBlocks || stats.MaxEntries < numBlocks {
+		t.Fatalf("FetchCacheStats: got %d of %d entries, want %d",
+			stats.Entries, stats.MaxEntries, numBlocks)
+	}
+	before := *stats
+	assertRange(0, numBlocks)
+	sha := blocks[50].Sha()
+	header, err := db.FetchBlockHeaderBySha(sha)
+	if err != nil {
+		t.Fatalf("FetchBlockHeaderBySha: unexpected error %v", err)
+	}
+	if header.BlockSha() != *sha {
+		t.Fatalf("FetchBlockHeaderBySha: got header of %v, want %v",
+			header.BlockSha(), sha)
+	}
+	height, err := db.FetchBlockHeightBySha(sha)
+	if err != nil || height != 50 {
+		t.Fatalf("FetchBlockHeightBySha: got %d err %v, want 50",
+			height, err)
+	}
+	stats = db.FetchCacheStats()
+	if stats.HeightHits-before.HeightHits != numBlocks ||
+		stats.HeightMisses != before.HeightMisses ||
+		stats.HeaderHits-before.HeaderHits != 1 ||
+		stats.HeaderMisses != before.HeaderMisses ||
+		stats.ShaHits-before.ShaHits != 1 {
+
+		t.Fatalf("FetchCacheStats: unexpected counters %+v after %+v",
+			stats, before)
+	}
+
+	// Modifying a returned header must not affect the cached one.
+	header.Nonce++
+	header, err = db.FetchBlockHeaderBySha(sha)
+	if err != nil || header.BlockSha() != *sha {
+		t.Fatalf("FetchBlockHeaderBySha: cached header was modified")
+	}
+
+	// Dropped blocks must no longer be served from the cache.
+	err = db.DropAfterBlockBySha(blocks[99].Sha())
+	if err != nil {
+		t.Fatalf("DropAfterBlockBySha: unexpected error %v", err)
+	}
+	if stats := db.FetchCacheStats(); stats.Entries != 100 {
+		t.Fatalf("FetchCacheStats: got %d entries after drop, want 100",
+			stats.Entries)
+	}
+	if _, err := db.FetchBlockShaByHeight(110); err == nil {
+		t.Fatalf("FetchBlockShaByHeight: dropped block was returned")
+	}
+	exists, err := db.ExistsSha(blocks[110].Sha())
+	if err != nil || exists {
+		t.Fatalf("ExistsSha: got %v err %v for dropped block", exists, err)
+	}
+
+	// A reopened database starts with an empty cache which is filled by
+	// the lookups that miss.
+	db.Sync()
+	db.Close()
+	db, err = database.OpenDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Unable to re-open created db, err %v", err)
+	}
+	before = *db.FetchCacheStats()
+	assertRange(10, 20)
+	stats = db.FetchCacheStats()
+	if stats.HeightMisses == before.HeightMisses {
+		t.Fatalf("FetchCacheStats: no misses after reopening")
+	}
+	before = *stats
+	assertRange(10, 20)
+	stats = db.FetchCacheStats()
+	if stats.HeightHits-before.HeightHits != 10 ||
+		stats.HeightMisses != before.HeightMisses {
+
+		t.Fatalf("FetchCacheStats: unexpected counters %+v after %+v",
+			stats, before)
+	}
+}
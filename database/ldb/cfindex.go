@@ -0,0 +1,177 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"encoding/binary"
+
+	"github.com/conseweb/goleveldb/leveldb"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// Each committed filter is stored under a 36 byte key:
+// ------------------------
+// | Prefix  | Block Sha  |
+// ------------------------
+// | 4 bytes |  32 bytes  |
+// ------------------------
+// The value is the 32 byte filter header followed by the serialized filter.
+const cfIndexKeyLength = 4 + wire.HashSize
+
+var cfIndexMetaDataKey = []byte("cfindex")
+
+// All committed filter entries share this prefix to facilitate the use of
+// iterators.
+var cfIndexKeyPrefix = []byte("cf+-")
+
+// cfIndexKey returns the key the committed filter for the passed block is
+// stored under.
+func cfIndexKey(blkSha *wire.ShaHash) []byte {
+	key := make([]byte, cfIndexKeyLength)
+	copy(key[0:4], cfIndexKeyPrefix)
+	copy(key[4:], blkSha[:])
+	return key
+}
+
+// fetchCFIndexTip returns the last block height and block sha to have its
+// committed filter stored.  Like the address index, the tip is cached in
+// memory and this function is only used on start up to load it.
+func (db *LevelDb) fetchCFIndexTip() (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	data, err := db.lDb.Get(cfIndexMetaDataKey, db.ro)
+	if err != nil {
+		return &wire.ShaHash{}, -1, database.ErrCFIndexDoesNotExist
+	}
+
+	var blkSha wire.ShaHash
+	blkSha.SetBytes(data[0:32])
+
+	blkHeight := binary.LittleEndian.Uint64(data[32:])
+
+	return &blkSha, int32(blkHeight), nil
+}
+
+// FetchCFIndexTip returns the hash and block height of the most recent block
+// which has had its committed filter stored.  It will return
+// ErrCFIndexDoesNotExist along with a zero hash, and -1 if the filter index
+// hasn't yet been built up.
+func (db *LevelDb) FetchCFIndexTip() (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	if db.lastCFIndexBlkIdx == -1 {
+		return &wire.ShaHash{}, -1, database.ErrCFIndexDoesNotExist
+	}
+	sha := db.lastCFIndexBlkSha
+
+	return &sha, db.lastCFIndexBlkIdx, nil
+}
+
+// UpdateCFIndexForBlock stores the serialized basic committed filter and
+// filter header for a particular block and marks the block as the tip of the
+// filter index.  Filters are keyed by block hash rather than height so the
+// filters of blocks which are later disconnected remain valid should the
+// blocks be reconnected.
+func (db *LevelDb) UpdateCFIndexForBlock(blkSha *wire.ShaHash, blkHeight int32,
+	filter []byte, filterHeader *wire.ShaHash) error {
+
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	batch := db.lBatch()
+	defer db.lbatch.Reset()
+
+	value := make([]byte, wire.HashSize+len(filter))
+	copy(value[0:wire.HashSize], filterHeader[:])
+	copy(value[wire.HashSize:], filter)
+	batch.Put(cfIndexKey(blkSha), value)
+
+	// Update tip of cfindex.
+	newIndexTip := make([]byte, 40, 40)
+	copy(newIndexTip[0:32], blkSha[:])
+	binary.LittleEndian.PutUint64(newIndexTip[32:40], uint64(blkHeight))
+	batch.Put(cfIndexMetaDataKey, newIndexTip)
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	db.lastCFIndexBlkIdx = blkHeight
+	db.lastCFIndexBlkSha = *blkSha
+
+	return nil
+}
+
+// FetchCFilterBySha returns the serialized basic committed filter and filter
+// header stored for the passed block.
+func (db *LevelDb) FetchCFilterBySha(blkSha *wire.ShaHash) ([]byte, *wire.ShaHash, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	data, err := db.lDb.Get(cfIndexKey(blkSha), db.ro)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, nil, database.ErrCFilterMissing
+		}
+		return nil, nil, err
+	}
+	if len(data) < wire.HashSize {
+		return nil, nil, database.ErrCFilterMissing
+	}
+
+	var filterHeader wire.ShaHash
+	filterHeader.SetBytes(data[0:wire.HashSize])
+
+	return data[wire.HashSize:], &filterHeader, nil
+}
+
+// DeleteCFIndex deletes the entire committed filter index stored within the
+// DB.  It also resets the cached in-memory metadata about the index.
+func (db *LevelDb) DeleteCFIndex() error {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	batch := db.lBatch()
+	defer batch.Reset()
+
+	// Delete the entire index along with any metadata about it.
+	iter := db.lDb.NewIterator(bytesPrefix(cfIndexKeyPrefix), db.ro)
+	numInBatch := 0
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) == cfIndexKeyLength {
+			batch.Delete(key)
+			numInBatch++
+		}
+
+		// Delete in chunks to potentially avoid very large batches.
+		if numInBatch >= batchDeleteThreshold {
+			if err := db.lDb.Write(batch, db.wo); err != nil {
+				iter.Release()
+				return err
+			}
+			batch.Reset()
+			numInBatch = 0
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	batch.Delete(cfIndexMetaDataKey)
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	db.lastCFIndexBlkIdx = -1
+	db.lastCFIndexBlkSha = wire.ShaHash{}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestCFIndex tests storing, fetching and deleting committed filters along
+// with the persistence of the filter index tip.
+func TestCFIndex(t *testing.T) {
+	dbname := "tstdbcfindex"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+	defer func() { db.Close() }()
+
+	// A fresh database has no filter index.
+	if _, height, err := db.FetchCFIndexTip(); err != database.ErrCFIndexDoesNotExist ||
+		height != -1 {
+		t.Fatalf("FetchCFIndexTip: got height %v err %v", height, err)
+	}
+
+	blkSha := wire.ShaHash{0x01}
+	filter := []byte{0x01, 0xde, 0xad}
+	filterHeader := wire.ShaHash{0x02}
+	err = db.UpdateCFIndexForBlock(&blkSha, 5, filter, &filterHeader)
+	if err != nil {
+		t.Fatalf("UpdateCFIndexForBlock: unexpected error %v", err)
+	}
+
+	assertCFilter := func() {
+		gotFilter, gotHeader, err := db.FetchCFilterBySha(&blkSha)
+		if err != nil {
+			t.Fatalf("FetchCFilterBySha: unexpected error %v", err)
+		}
+		if !bytes.Equal(gotFilter, filter) || *gotHeader != filterHeader {
+			t.Fatalf("FetchCFilterBySha: got filter %x header %v, "+
+				"want filter %x header %v", gotFilter, gotHeader,
+				filter, filterHeader)
+		}
+		sha, height, err := db.FetchCFIndexTip()
+		if err != nil || height != 5 || *sha != blkSha {
+			t.Fatalf("FetchCFIndexTip: got %v %v err %v", sha,
+				height, err)
+		}
+	}
+	assertCFilter()
+
+	// The index must survive a restart.
+	db.Sync()
+	db.Close()
+	db, err = database.OpenDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Unable to re-open created db, err %v", err)
+	}
+	assertCFilter()
+
+	// Unknown blocks have no filter.
+	if _, _, err := db.FetchCFilterBySha(&wire.ShaHash{0x03}); err != database.ErrCFilterMissing {
+		t.Fatalf("FetchCFilterBySha: unexpected error %v", err)
+	}
+
+	if err := db.DeleteCFIndex(); err != nil {
+		t.Fatalf("DeleteCFIndex: unexpected error %v", err)
+	}
+	if _, _, err := db.FetchCFilterBySha(&blkSha); err != database.ErrCFilterMissing {
+		t.Fatalf("FetchCFilterBySha after delete: unexpected error %v",
+			err)
+	}
+	if _, _, err := db.FetchCFIndexTip(); err != database.ErrCFIndexDoesNotExist {
+		t.Fatalf("FetchCFIndexTip after delete: unexpected error %v",
+			err)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/conseweb/goleveldb/leveldb"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// The coin age statistics of each block are stored under a 36 byte key:
+// ------------------------
+// | Prefix  | Block Sha  |
+// ------------------------
+// | 4 bytes |  32 bytes  |
+// ------------------------
+// The value is the spent value followed by the coin days destroyed by the
+// block and the total coin days destroyed by the chain up to the block, each
+// encoded as 8 bytes.
+const (
+	coinAgeIndexKeyLength   = 4 + wire.HashSize
+	coinAgeIndexValueLength = 24
+)
+
+var coinAgeIndexMetaDataKey = []byte("coinageindex")
+
+// All coin age entries share this prefix to facilitate the use of iterators.
+var coinAgeIndexKeyPrefix = []byte("ca+-")
+
+// coinAgeIndexKey returns the key the coin age statistics of the passed block
+// are stored under.
+func coinAgeIndexKey(blkSha *wire.ShaHash) []byte {
+	key := make([]byte, coinAgeIndexKeyLength)
+	copy(key[0:4], coinAgeIndexKeyPrefix)
+	copy(key[4:], blkSha[:])
+	return key
+}
+
+// fetchCoinAgeIndexTip returns the last block height and block sha to have its
+// coin age statistics stored.  Like the other indexes, the tip is cached in
+// memory and this function is only used on start up to load it.
+func (db *LevelDb) fetchCoinAgeIndexTip() (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	data, err := db.lDb.Get(coinAgeIndexMetaDataKey, db.ro)
+	if err != nil {
+		return &wire.ShaHash{}, -1, database.ErrCoinAgeIndexDoesNotExist
+	}
+
+	var blkSha wire.ShaHash
+	blkSha.SetBytes(data[0:32])
+
+	blkHeight := binary.LittleEndian.Uint64(data[32:])
+
+	return &blkSha, int32(blkHeight), nil
+}
+
+// FetchCoinAgeIndexTip returns the hash and block height of the most recent
+// block which has had its coin age statistics stored.  It will return
+// ErrCoinAgeIndexDoesNotExist along with a zero hash, and -1 if the coin age
+// index hasn't yet been built up.
+func (db *LevelDb) FetchCoinAgeIndexTip() (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	if db.lastCoinAgeIndexBlkIdx == -1 {
+		return &wire.ShaHash{}, -1, database.ErrCoinAgeIndexDoesNotExist
+	}
+	sha := db.lastCoinAgeIndexBlkSha
+
+	return &sha, db.lastCoinAgeIndexBlkIdx, nil
+}
+
+// UpdateCoinAgeIndexForBlock stores the coin age statistics of a particular
+// block and marks the block as the tip of the coin age index.  Like committed
+// filters, the statistics are keyed by block hash so the ones of blocks which
+// are later disconnected remain valid should the blocks be reconnected.
+func (db *LevelDb) UpdateCoinAgeIndexForBlock(blkSha *wire.ShaHash, blkHeight int32,
+	coinAge *database.BlockCoinAge) error {
+
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	batch := db.lBatch()
+	defer db.lbatch.Reset()
+
+	value := make([]byte, coinAgeIndexValueLength)
+	binary.LittleEndian.PutUint64(value[0:8], uint64(coinAge.SpentValue))
+	binary.LittleEndian.PutUint64(value[8:16],
+		math.Float64bits(coinAge.CoinDaysDestroyed))
+	binary.LittleEndian.PutUint64(value[16:24],
+		math.Float64bits(coinAge.TotalCoinDaysDestroyed))
+	batch.Put(coinAgeIndexKey(blkSha), value)
+
+	// Update tip of coin age index.
+	newIndexTip := make([]byte, 40, 40)
+	copy(newIndexTip[0:32], blkSha[:])
+	binary.LittleEndian.PutUint64(newIndexTip[32:40], uint64(blkHeight))
+	batch.Put(coinAgeIndexMetaDataKey, newIndexTip)
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	db.lastCoinAgeIndexBlkIdx = blkHeight
+	db.lastCoinAgeIndexBlkSha = *blkSha
+
+	return nil
+}
+
+// FetchCoinAgeBySha returns the coin age statistics stored for the passed
+// block.
+func (db *LevelDb) FetchCoinAgeBySha(blkSha *wire.ShaHash) (*database.BlockCoinAge, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	data, err := db.lDb.Get(coinAgeIndexKey(blkSha), db.ro)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, database.ErrCoinAgeMissing
+		}
+		return nil, err
+	}
+	if len(data) < coinAgeIndexValueLength {
+		return nil, database.ErrCoinAgeMissing
+	}
+
+	return &database.BlockCoinAge{
+		SpentValue: int64(binary.LittleEndian.Uint64(data[0:8])),
+		CoinDaysDestroyed: math.Float64frombits(
+			binary.LittleEndian.Uint64(data[8:16])),
+		TotalCoinDaysDestroyed: math.Float64frombits(
+			binary.LittleEndian.Uint64(data[16:24])),
+	}, nil
+}
+
+// DeleteCoinAgeIndex deletes the entire coin age index stored within the DB.
+// It also resets the cached in-memory metadata about the index.
+func (db *LevelDb) DeleteCoinAgeIndex() error {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	batch := db.lBatch()
+	defer batch.Reset()
+
+	// Delete the entire index along with any metadata about it.
+	iter := db.lDb.NewIterator(bytesPrefix(coinAgeIndexKeyPrefix), db.ro)
+	numInBatch := 0
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) == coinAgeIndexKeyLength {
+			batch.Delete(key)
+			numInBatch++
+		}
+
+		// Delete in chunks to potentially avoid very large batches.
+		if numInBatch >= batchDeleteThreshold {
+			if err := db.lDb.Write(batch, db.wo); err != nil {
+				iter.Release()
+				return err
+			}
+			batch.Reset()
+			numInBatch = 0
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	batch.Delete(coinAgeIndexMetaDataKey)
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	db.lastCoinAgeIndexBlkIdx = -1
+	db.lastCoinAgeIndexBlkSha = wire.ShaHash{}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestCoinAgeIndex tests storing, fetching and deleting coin age statistics
+// along with the persistence of the coin age index tip.
+func TestCoinAgeIndex(t *testing.T) {
+	dbname := "tstdbcoinageindex"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+	defer func() { db.Close() }()
+
+	// A fresh database has no coin age index.
+	if _, height, err := db.FetchCoinAgeIndexTip(); err != database.ErrCoinAgeIndexDoesNotExist ||
+		height != -1 {
+		t.Fatalf("FetchCoinAgeIndexTip: got height %v err %v", height, err)
+	}
+
+	blkSha := wire.ShaHash{0x01}
+	coinAge := &database.BlockCoinAge{
+		SpentValue:             5000000000,
+		CoinDaysDestroyed:      12.5,
+		TotalCoinDaysDestroyed: 1234.75,
+	}
+	err = db.UpdateCoinAgeIndexForBlock(&blkSha, 5, coinAge)
+	if err != nil {
+		t.Fatalf("UpdateCoinAgeIndexForBlock: unexpected error %v", err)
+	}
+
+	assertCoinAge := func() {
+		got, err := db.FetchCoinAgeBySha(&blkSha)
+		if err != nil {
+			t.Fatalf("FetchCoinAgeBySha: unexpected error %v", err)
+		}
+		if !reflect.DeepEqual(got, coinAge) {
+			t.Fatalf("FetchCoinAgeBySha: got %+v, want %+v", got,
+				coinAge)
+		}
+		sha, height, err := db.FetchCoinAgeIndexTip()
+		if err != nil || height != 5 || *sha != blkSha {
+			t.Fatalf("FetchCoinAgeIndexTip: got %v %v err %v", sha,
+				height, err)
+		}
+	}
+	assertCoinAge()
+
+	// The index must survive a restart.
+	db.Sync()
+	db.Close()
+	db, err = database.OpenDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Unable to re-open created db, err %v", err)
+	}
+	assertCoinAge()
+
+	// Unknown blocks have no statistics.
+	if _, err := db.FetchCoinAgeBySha(&wire.ShaHash{0x03}); err != database.ErrCoinAgeMissing {
+		t.Fatalf("FetchCoinAgeBySha: unexpected error %v", err)
+	}
+
+	if err := db.DeleteCoinAgeIndex(); err != nil {
+		t.Fatalf("DeleteCoinAgeIndex: unexpected error %v", err)
+	}
+	if _, err := db.FetchCoinAgeBySha(&blkSha); err != database.ErrCoinAgeMissing {
+		t.Fatalf("FetchCoinAgeBySha after delete: unexpected error %v",
+			err)
+	}
+	if _, _, err := db.FetchCoinAgeIndexTip(); err != database.ErrCoinAgeIndexDoesNotExist {
+		t.Fatalf("FetchCoinAgeIndexTip after delete: unexpected error %v",
+			err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"bytes"
+
+	"github.com/conseweb/goleveldb/leveldb/util"
+	"github.com/conseweb/stcd/database"
+)
+
+// indexKeyPrefixes maps the name of each optional index to the prefixes its
+// entries are stored under.  Everything else stored within the database is
+// chain data.
+var indexKeyPrefixes = []struct {
+	name     string
+	prefixes [][]byte
+}{
+	{"addrindex", [][]byte{addrIndexKeyPrefix, addrIndexBlockKeyPrefix}},
+	{"cfindex", [][]byte{cfIndexKeyPrefix}},
+	{"coinageindex", [][]byte{coinAgeIndexKeyPrefix}},
+	{"stxoindex", [][]byte{stxoIndexKeyPrefix, stxoIndexBlockKeyPrefix}},
+	{"addrbalanceindex", [][]byte{addrBalanceKeyPrefix, addrUtxoKeyPrefix,
+		addrDeltaKeyPrefix, addrBalanceBlockKeyPrefix}},
+}
+
+// endOfKeys sorts after every key stored within the database since no key
+// starts with this many 0xff bytes.  It is used as the limit of the range
+// covering the entire database because leveldb treats a nil limit as the
+// start of the keys when estimating sizes.
+var endOfKeys = bytes.Repeat([]byte{0xff}, 64)
+
+// FetchDatabaseSizes returns the approximate on-disk sizes of the chain data
+// and of each of the optional indexes.  The sizes are estimated by leveldb from
+// the tables covering the key ranges, so data which is still in the journal is
+// not accounted for.  This is part of the database.Db interface implementation.
+func (db *LevelDb) FetchDatabaseSizes() ([]*database.DatabaseSize, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	// The first range covers the entire database.
+	ranges := []util.Range{{Limit: endOfKeys}}
+	for _, index := range indexKeyPrefixes {
+		for _, prefix := range index.prefixes {
+			ranges = append(ranges, *util.BytesPrefix(prefix))
+		}
+	}
+	rangeSizes, err := db.lDb.SizeOf(ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	chainSize := rangeSizes[0]
+	sizes := make([]*database.DatabaseSize, 1, len(indexKeyPrefixes)+1)
+	i := 1
+	for _, index := range indexKeyPrefixes {
+		var size int64
+		for range index.prefixes {
+			size += rangeSizes[i]
+			i++
+		}
+		sizes = append(sizes, &database.DatabaseSize{
+			Name: index.name,
+			Size: size,
+		})
+
+		// The estimates of the ranges may overlap at the table
+		// boundaries, so don't let the chain data go negative.
+		if size > chainSize {
+			size = chainSize
+		}
+		chainSize -= size
+	}
+	sizes[0] = &database.DatabaseSize{Name: "chain", Size: chainSize}
+	return sizes, nil
+}
+
+// Compact compacts all of the leveldb tables to reclaim the space used by
+// deleted and overwritten entries, such as those of dropped blocks and
+// indexes.  The database lock is not held while compacting since leveldb
+// synchronizes the compaction with concurrent reads and writes itself.  This
+// is part of the database.Db interface implementation.
+func (db *LevelDb) Compact() error {
+	return db.lDb.CompactRange(util.Range{})
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/conseweb/stcd/database"
+)
+
+// TestDatabaseSizes ensures the sizes of the chain data and every optional
+// index are reported and that the chain data is accounted for once compacting
+// has written it to the tables.
+func TestDatabaseSizes(t *testing.T) {
+	dbname := "tstdbdbsize"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+	defer func() { db.Close() }()
+
+	for height, block := range makeCacheTestBlocks(50) {
+		if _, err := db.InsertBlock(block); err != nil {
+			t.Fatalf("failed to insert block %v err %v", height, err)
+		}
+	}
+	if err := db.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	sizes, err := db.FetchDatabaseSizes()
+	if err != nil {
+		t.Fatalf("FetchDatabaseSizes: %v", err)
+	}
+	wantNames := []string{"chain", "addrindex", "cfindex", "coinageindex",
+		"stxoindex", "addrbalanceindex"}
+	if len(sizes) != len(wantNames) {
+		t.Fatalf("FetchDatabaseSizes: got %d sizes, want %d", len(sizes),
+			len(wantNames))
+	}
+	for i, size := range sizes {
+		if size.Name != wantNames[i] {
+			t.Errorf("FetchDatabaseSizes #%d: got name %q, want %q", i,
+				size.Name, wantNames[i])
+		}
+		if size.Size < 0 {
+			t.Errorf("FetchDatabaseSizes #%d: got negative size %d", i,
+				size.Size)
+		}
+	}
+	if sizes[0].Size == 0 {
+		t.Errorf("FetchDatabaseSizes: the chain data has no size")
+	}
+	for _, size := range sizes[1:] {
+		if size.Size != 0 {
+			t.Errorf("FetchDatabaseSizes: the %s which wasn't built "+
+				"has size %d", size.Name, size.Size)
+		}
+	}
+}
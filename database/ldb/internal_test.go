@@ -11,6 +11,8 @@ import (
 
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/golangcrypto/ripemd160"
+	"github.com/conseweb/stcd/bech32"
+	"github.com/conseweb/stcd/chaincfg"
 )
 
 func TestAddrIndexKeySerialization(t *testing.T) {
@@ -64,3 +66,41 @@ func TestBytesPrefix(t *testing.T) {
 			[]byte("b"))
 	}
 }
+
+// TestAddrToKeyWitness ensures witness addresses are looked up by the keys
+// their output scripts are indexed by: the witness program itself when it is a
+// hash160 and the hash160 of the program otherwise.
+func TestAddrToKeyWitness(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	program := bytes.Repeat([]byte{0x01}, 32)
+
+	pkHashAddr, err := bech32.NewAddressWitnessPubKeyHash(program[:20],
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressWitnessPubKeyHash: %v", err)
+	}
+	scriptHashAddr, err := bech32.NewAddressWitnessScriptHash(program,
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressWitnessScriptHash: %v", err)
+	}
+
+	tests := []struct {
+		addr coinutil.Address
+		want []byte
+	}{
+		{pkHashAddr, program[:20]},
+		{scriptHashAddr, coinutil.Hash160(program)},
+	}
+	for _, test := range tests {
+		key, err := addrToKey(test.addr)
+		if err != nil {
+			t.Errorf("addrToKey(%v): %v", test.addr, err)
+			continue
+		}
+		if !bytes.Equal(key, test.want) {
+			t.Errorf("addrToKey(%v): got key %x - want %x",
+				test.addr, key, test.want)
+		}
+	}
+}
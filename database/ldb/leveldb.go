@@ -47,6 +47,10 @@ type LevelDb struct {
 
 	lbatch *leveldb.Batch
 
+	// blkCache caches the hashes, heights and headers of recently used
+	// blocks.
+	blkCache *blockCache
+
 	nextBlock int32
 
 	lastBlkShaCached bool
@@ -56,11 +60,31 @@ type LevelDb struct {
 	lastAddrIndexBlkSha wire.ShaHash
 	lastAddrIndexBlkIdx int32
 
+	lastCFIndexBlkSha wire.ShaHash
+	lastCFIndexBlkIdx int32
+
+	lastCoinAgeIndexBlkSha wire.ShaHash
+	lastCoinAgeIndexBlkIdx int32
+
+	lastSTXOIndexBlkSha wire.ShaHash
+	lastSTXOIndexBlkIdx int32
+
+	lastAddrBalanceIndexBlkSha wire.ShaHash
+	lastAddrBalanceIndexBlkIdx int32
+
 	txUpdateMap      map[wire.ShaHash]*txUpdateObj
 	txSpentUpdateMap map[wire.ShaHash]*spentTxUpdate
+
+	// readOnly is set when the database was opened with OpenReadOnlyDB.
+	// snapshotDir is the temporary directory holding the snapshot of a
+	// database which is in use by another process, if any, and is
+	// removed when the database is closed.
+	readOnly    bool
+	snapshotDir string
 }
 
-var self = database.DriverDB{DbType: "leveldb", CreateDB: CreateDB, OpenDB: OpenDB}
+var self = database.DriverDB{DbType: "leveldb", CreateDB: CreateDB, OpenDB: OpenDB,
+	OpenReadOnlyDB: OpenReadOnlyDB}
 
 func init() {
 	database.AddDBDriver(self)
@@ -92,16 +116,21 @@ func OpenDB(args ...interface{}) (database.Db, error) {
 
 	log = database.GetLog()
 
-	db, err := openDB(dbpath, false)
+	db, err := openDB(dbpath, false, false)
 	if err != nil {
 		return nil, err
 	}
+	db.(*LevelDb).loadState()
+	return db, nil
+}
 
+// loadState finds the most recent block and the tips of the optional indexes
+// of an existing database which was just opened.
+func (ldb *LevelDb) loadState() {
 	// Need to find last block and tx
 	var lastknownblock, nextunknownblock, testblock int32
 
 	increment := int32(100000)
-	ldb := db.(*LevelDb)
 
 	var lastSha *wire.ShaHash
 	// forward scan
@@ -152,6 +181,9 @@ blocknarrow:
 			ldb.lastAddrIndexBlkSha = *sha
 			ldb.lastAddrIndexBlkIdx = idx
 			log.Infof("Address index good, continuing")
+		} else if ldb.readOnly {
+			log.Infof("Address index in old, incompatible format, ignoring")
+			ldb.lastAddrIndexBlkIdx = -1
 		} else {
 			log.Infof("Address index in old, incompatible format, dropping...")
 			ldb.deleteOldAddrIndex()
@@ -162,14 +194,44 @@ blocknarrow:
 		ldb.lastAddrIndexBlkIdx = -1
 	}
 
+	// Load the last block whose committed filter has been stored.
+	if sha, idx, err := ldb.fetchCFIndexTip(); err == nil {
+		ldb.lastCFIndexBlkSha = *sha
+		ldb.lastCFIndexBlkIdx = idx
+	} else {
+		ldb.lastCFIndexBlkIdx = -1
+	}
+
+	// Load the last block whose coin age statistics have been stored.
+	if sha, idx, err := ldb.fetchCoinAgeIndexTip(); err == nil {
+		ldb.lastCoinAgeIndexBlkSha = *sha
+		ldb.lastCoinAgeIndexBlkIdx = idx
+	} else {
+		ldb.lastCoinAgeIndexBlkIdx = -1
+	}
+
+	// Load the last block whose spent outputs have been indexed.
+	if sha, idx, err := ldb.fetchSTXOIndexTip(); err == nil {
+		ldb.lastSTXOIndexBlkSha = *sha
+		ldb.lastSTXOIndexBlkIdx = idx
+	} else {
+		ldb.lastSTXOIndexBlkIdx = -1
+	}
+
+	// Load the last block whose address balance changes have been indexed.
+	if sha, idx, err := ldb.fetchAddrBalanceIndexTip(); err == nil {
+		ldb.lastAddrBalanceIndexBlkSha = *sha
+		ldb.lastAddrBalanceIndexBlkIdx = idx
+	} else {
+		ldb.lastAddrBalanceIndexBlkIdx = -1
+	}
+
 	ldb.lastBlkSha = *lastSha
 	ldb.lastBlkIdx = lastknownblock
 	ldb.nextBlock = lastknownblock + 1
-
-	return db, nil
 }
 
-func openDB(dbpath string, create bool) (pbdb database.Db, err error) {
+func openDB(dbpath string, create, readOnly bool) (pbdb database.Db, err error) {
 	var db LevelDb
 	var tlDb *leveldb.DB
 	var dbversion int32
@@ -180,6 +242,7 @@ func openDB(dbpath string, create bool) (pbdb database.Db, err error) {
 
 			db.txUpdateMap = map[wire.ShaHash]*txUpdateObj{}
 			db.txSpentUpdateMap = make(map[wire.ShaHash]*spentTxUpdate)
+			db.blkCache = newBlockCache(blockCacheSize)
 
 			pbdb = &db
 		}
@@ -231,6 +294,7 @@ func openDB(dbpath string, create bool) (pbdb database.Db, err error) {
 		err = fmt.Errorf("unsupported db version %v", dbversion)
 		return
 	}
+	opts.ReadOnly = readOnly
 
 	tlDb, err = leveldb.OpenFile(dbpath, opts)
 	if err != nil {
@@ -266,18 +330,26 @@ func CreateDB(args ...interface{}) (database.Db, error) {
 	log = database.GetLog()
 
 	// No special setup needed, just OpenBB
-	db, err := openDB(dbpath, true)
+	db, err := openDB(dbpath, true, false)
 	if err == nil {
 		ldb := db.(*LevelDb)
 		ldb.lastBlkIdx = -1
 		ldb.lastAddrIndexBlkIdx = -1
+		ldb.lastCFIndexBlkIdx = -1
+		ldb.lastCoinAgeIndexBlkIdx = -1
+		ldb.lastSTXOIndexBlkIdx = -1
+		ldb.lastAddrBalanceIndexBlkIdx = -1
 		ldb.nextBlock = 0
 	}
 	return db, err
 }
 
 func (db *LevelDb) close() error {
-	return db.lDb.Close()
+	err := db.lDb.Close()
+	if db.snapshotDir != "" {
+		os.RemoveAll(db.snapshotDir)
+	}
+	return err
 }
 
 // Sync verifies that the database is coherent on disk,
@@ -347,6 +419,7 @@ func (db *LevelDb) DropAfterBlockBySha(sha *wire.ShaHash) (rerr error) {
 		db.lBatch().Delete(shaBlkToKey(blksha))
 		db.lBatch().Delete(int64ToKey(int64(height)))
 	}
+	db.blkCache.removeAbove(keepidx)
 
 	// update the last block cache
 	db.lastBlkShaCached = true
@@ -370,6 +443,13 @@ func (db *LevelDb) InsertBlock(block *coinutil.Block) (height int32, rerr error)
 		} else {
 			db.lBatch().Reset()
 		}
+
+		// Cache the new block since the blocks near the tip of the
+		// chain are the ones most likely to be looked up.
+		if rerr == nil {
+			db.blkCache.add(block.Sha(), height,
+				&block.MsgBlock().Header)
+		}
 	}()
 
 	blocksha := block.Sha()
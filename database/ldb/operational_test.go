@@ -125,7 +125,8 @@ func testAddrIndexOperations(t *testing.T, db database.Db, newestBlock *coinutil
 	testIndex[hash160Bytes] = []*wire.TxLoc{&blktxLoc[0]}
 
 	// Insert our test addr index into the DB.
-	err = db.UpdateAddrIndexForBlock(newestSha, newestBlockIdx, testIndex)
+	err = db.UpdateAddrIndexForBlock(newestSha, newestBlockIdx,
+		&newestBlock.MsgBlock().Header.PrevBlock, testIndex)
 	if err != nil {
 		t.Fatalf("UpdateAddrIndexForBlock: failed to index"+
 			" addrs for block #%d (%s) "+
@@ -524,7 +525,11 @@ func TestLimitAndSkipFetchTxsForAddr(t *testing.T) {
 	fakeHeader := wire.NewBlockHeader(&emptyHash, &emptyHash, 1, 1)
 	msgBlock := wire.NewMsgBlock(fakeHeader)
 	for i := 0; i < 10; i++ {
+		// Transactions without inputs are indistinguishable from the
+		// witness serialization, so give each one a coinbase input.
 		mtx := wire.NewMsgTx()
+		prevOut := wire.NewOutPoint(&emptyHash, wire.MaxPrevOutIndex)
+		mtx.AddTxIn(wire.NewTxIn(prevOut, nil))
 		mtx.AddTxOut(fakeTxOut)
 		msgBlock.AddTransaction(mtx)
 	}
@@ -546,7 +551,8 @@ func TestLimitAndSkipFetchTxsForAddr(t *testing.T) {
 		index[hash160] = append(index[hash160], &txLoc[i])
 	}
 	blkSha := testBlock.Sha()
-	err = testDb.db.UpdateAddrIndexForBlock(blkSha, newheight, index)
+	err = testDb.db.UpdateAddrIndexForBlock(blkSha, newheight,
+		&emptyHash, index)
 	if err != nil {
 		t.Fatalf("UpdateAddrIndexForBlock: failed to index"+
 			" addrs for block #%d (%s) "+
@@ -596,3 +602,85 @@ func TestLimitAndSkipFetchTxsForAddr(t *testing.T) {
 			" got %v txs, expected %v", len(txReply), 5)
 	}
 }
+
+// TestRewindAddrIndex ensures rewinding the address index removes the entries
+// of the block at its tip only and makes the previous block the tip.
+func TestRewindAddrIndex(t *testing.T) {
+	dbname := "tstdbrewindaddr"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+	defer db.Close()
+
+	// Insert a chain of three blocks and index the coinbase of each of
+	// them under the same address.
+	var hash160 [ripemd160.Size]byte
+	hash160[0] = 0x01
+	addr, err := coinutil.NewAddressPubKeyHash(hash160[:],
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	var blocks []*coinutil.Block
+	var prevSha wire.ShaHash
+	for height := 0; height < 3; height++ {
+		mtx := wire.NewMsgTx()
+		prevOut := wire.NewOutPoint(&wire.ShaHash{}, wire.MaxPrevOutIndex)
+		mtx.AddTxIn(wire.NewTxIn(prevOut, []byte{byte(height)}))
+		mtx.AddTxOut(wire.NewTxOut(10, nil))
+		msgBlock := wire.NewMsgBlock(wire.NewBlockHeader(&prevSha,
+			&wire.ShaHash{}, 1, 1))
+		msgBlock.AddTransaction(mtx)
+		blocks = append(blocks, coinutil.NewBlock(msgBlock))
+		prevSha = msgBlock.Header.BlockSha()
+	}
+	for height, block := range blocks {
+		if _, err := db.InsertBlock(block); err != nil {
+			t.Fatalf("InsertBlock #%d: %v", height, err)
+		}
+		txLocs, err := block.TxLoc()
+		if err != nil {
+			t.Fatalf("TxLoc #%d: %v", height, err)
+		}
+		index := database.BlockAddrIndex{hash160: {&txLocs[0]}}
+		err = db.UpdateAddrIndexForBlock(block.Sha(), int32(height),
+			&block.MsgBlock().Header.PrevBlock, index)
+		if err != nil {
+			t.Fatalf("UpdateAddrIndexForBlock #%d: %v", height, err)
+		}
+	}
+
+	// Each rewind removes the transaction of the block at the tip even
+	// though the block is still in the database.
+	for height := len(blocks) - 1; height >= 0; height-- {
+		assertAddrIndexTipIsUpdated(db, t, blocks[height].Sha(),
+			int32(height))
+		txReplies, _, err := db.FetchTxsForAddr(addr, 0, 1000, false)
+		if err != nil {
+			t.Fatalf("FetchTxsForAddr: %v", err)
+		}
+		if len(txReplies) != height+1 {
+			t.Fatalf("FetchTxsForAddr: got %d transactions with the "+
+				"tip at height %d, want %d", len(txReplies), height,
+				height+1)
+		}
+		if err := db.RewindAddrIndex(); err != nil {
+			t.Fatalf("RewindAddrIndex at height %d: %v", height, err)
+		}
+	}
+
+	// Rewinding the genesis block leaves no index.
+	if _, _, err := db.FetchAddrIndexTip(); err != database.ErrAddrIndexDoesNotExist {
+		t.Fatalf("FetchAddrIndexTip: got error %v, want %v", err,
+			database.ErrAddrIndexDoesNotExist)
+	}
+	if err := db.RewindAddrIndex(); err != database.ErrAddrIndexDoesNotExist {
+		t.Fatalf("RewindAddrIndex: got error %v, want %v", err,
+			database.ErrAddrIndexDoesNotExist)
+	}
+}
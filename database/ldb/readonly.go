@@ -0,0 +1,208 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/conseweb/stcd/database"
+)
+
+// maxSnapshotAttempts is the number of times taking a snapshot of a database
+// which is in use is attempted before giving up because the process using it
+// keeps compacting it.
+const maxSnapshotAttempts = 5
+
+// OpenReadOnlyDB opens an existing database for reading only.
+//
+// The database is opened in place with a shared lock when no other process
+// uses it, which prevents a node from opening it for writing until the
+// database is closed.  While a node holds the exclusive lock on the database,
+// a snapshot of its current state is opened instead.  The snapshot hard links
+// the table files, which leveldb never modifies, and copies the small journal
+// and manifest files into a temporary directory, so the files of the node
+// are never written to.  The snapshot doesn't see the blocks the node connects
+// after it was taken and is removed when the database is closed.
+func OpenReadOnlyDB(args ...interface{}) (database.Db, error) {
+	dbpath, err := parseArgs("OpenReadOnlyDB", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	log = database.GetLog()
+
+	db, err := openDB(dbpath, false, true)
+	if err == database.ErrDbDoesNotExist {
+		return nil, err
+	}
+	if err != nil {
+		log.Debugf("Unable to open %s in place (%v), opening a snapshot",
+			dbpath, err)
+		db, err = openSnapshotDB(dbpath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ldb := db.(*LevelDb)
+	ldb.readOnly = true
+	ldb.loadState()
+	return db, nil
+}
+
+// openSnapshotDB takes a snapshot of the database at the passed path and opens
+// it for reading only.
+func openSnapshotDB(dbpath string) (database.Db, error) {
+	snapshotDir, err := ioutil.TempDir("", "btcd-readonly-")
+	if err != nil {
+		return nil, err
+	}
+
+	// The version file is kept next to the database, so the snapshot is
+	// created in a directory of its own.
+	snapshotPath := filepath.Join(snapshotDir, filepath.Base(dbpath))
+	for attempt := 1; ; attempt++ {
+		err = takeSnapshot(dbpath, snapshotPath)
+		if err != errSnapshotChanged || attempt == maxSnapshotAttempts {
+			break
+		}
+		log.Debugf("The database changed while taking a snapshot, " +
+			"retrying")
+		os.RemoveAll(snapshotPath)
+	}
+	if err == nil {
+		err = copyFile(dbpath+".ver", snapshotPath+".ver")
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	var db database.Db
+	if err == nil {
+		db, err = openDB(snapshotPath, false, true)
+	}
+	if err != nil {
+		os.RemoveAll(snapshotDir)
+		return nil, fmt.Errorf("unable to open a snapshot of %s: %v",
+			dbpath, err)
+	}
+	db.(*LevelDb).snapshotDir = snapshotDir
+	return db, nil
+}
+
+// errSnapshotChanged is returned by takeSnapshot when the database was
+// compacted while the snapshot was taken.
+var errSnapshotChanged = errors.New("the database changed while taking a " +
+	"snapshot")
+
+// takeSnapshot hard links or copies the files of the leveldb database at the
+// passed path into a new directory at the snapshot path.
+//
+// leveldb records every change to the set of table files in the manifest
+// before deleting the tables which are no longer used, and only ever appends
+// to the manifest and the journals.  When neither the current manifest nor its
+// size changed while the files were linked and copied, every table the
+// manifest refers to was linked before it could be deleted.  Records still
+// being written to the end of a journal when it is copied are dropped when the
+// snapshot is opened.
+func takeSnapshot(dbpath, snapshotPath string) error {
+	current, err := ioutil.ReadFile(filepath.Join(dbpath, "CURRENT"))
+	if err != nil {
+		return err
+	}
+	manifest := strings.TrimSpace(string(current))
+	manifestPath := filepath.Join(dbpath, manifest)
+	fi, err := os.Stat(manifestPath)
+	if err != nil {
+		return errSnapshotChanged
+	}
+	manifestSize := fi.Size()
+
+	if err := os.Mkdir(snapshotPath, 0700); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(dbpath)
+	if err != nil {
+		return err
+	}
+	for _, fi := range files {
+		name := fi.Name()
+		src := filepath.Join(dbpath, name)
+		dst := filepath.Join(snapshotPath, name)
+		switch ext := filepath.Ext(name); {
+		case ext == ".ldb" || ext == ".sst":
+			err = os.Link(src, dst)
+			if err != nil && !os.IsNotExist(err) {
+				err = copyFile(src, dst)
+			}
+		case ext == ".log":
+			err = copyFile(src, dst)
+		default:
+			continue
+		}
+
+		// Tables and journals which no longer exist were deleted
+		// after they became obsolete.
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Only copy the part of the manifest written before the files were
+	// linked, in case it was appended to in the meantime.
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		return errSnapshotChanged
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, f, manifestSize); err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(snapshotPath, manifest),
+		buf.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(snapshotPath, "CURRENT"), current,
+		0600)
+	if err != nil {
+		return err
+	}
+
+	current, err = ioutil.ReadFile(filepath.Join(dbpath, "CURRENT"))
+	if err != nil || strings.TrimSpace(string(current)) != manifest {
+		return errSnapshotChanged
+	}
+	fi, err = os.Stat(manifestPath)
+	if err != nil || fi.Size() != manifestSize {
+		return errSnapshotChanged
+	}
+	return nil
+}
+
+// copyFile copies the file at the source path to the destination path.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
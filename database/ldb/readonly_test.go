@@ -0,0 +1,88 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/conseweb/stcd/database"
+)
+
+// TestOpenReadOnlyDB ensures a database which is in use by a node can be
+// opened read-only from a snapshot, and that opening an unused database
+// read-only keeps nodes from opening it for writing until it is closed.
+func TestOpenReadOnlyDB(t *testing.T) {
+	dbname := "tstdbreadonly"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+
+	blocks := makeCacheTestBlocks(20)
+	for height, block := range blocks {
+		if _, err := db.InsertBlock(block); err != nil {
+			t.Fatalf("failed to insert block %v err %v", height, err)
+		}
+	}
+
+	// The database is in use, so a snapshot is opened which is unaffected
+	// by the blocks inserted afterwards.
+	roDb, err := database.OpenReadOnlyDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("OpenReadOnlyDB: %v", err)
+	}
+	newest := blocks[len(blocks)-1].Sha()
+	sha, height, err := roDb.NewestSha()
+	if err != nil || height != 19 || !sha.IsEqual(newest) {
+		t.Fatalf("NewestSha: got %v, %d, %v, want %v, 19", sha, height,
+			err, newest)
+	}
+	if err := db.DropAfterBlockBySha(blocks[9].Sha()); err != nil {
+		t.Fatalf("DropAfterBlockBySha: %v", err)
+	}
+	if _, err := roDb.FetchBlockBySha(newest); err != nil {
+		t.Fatalf("FetchBlockBySha: the snapshot lost block %v: %v",
+			newest, err)
+	}
+	if err := roDb.DeleteAddrIndex(); err == nil {
+		t.Fatalf("DeleteAddrIndex: modified a read-only database")
+	}
+	if err := roDb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The unused database is opened in place and can't be opened for
+	// writing while it is open read-only.
+	roDb, err = database.OpenReadOnlyDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("OpenReadOnlyDB: %v", err)
+	}
+	if _, height, _ := roDb.NewestSha(); height != 9 {
+		t.Fatalf("NewestSha: got height %d, want 9", height)
+	}
+	if db, err := database.OpenDB("leveldb", dbname); err == nil {
+		db.Close()
+		t.Fatalf("OpenDB: opened a database which is open read-only")
+	}
+	roDb.Close()
+	db, err = database.OpenDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	db.Close()
+
+	if _, err := database.OpenReadOnlyDB("leveldb", "tstdbmissing"); err != database.ErrDbDoesNotExist {
+		t.Fatalf("OpenReadOnlyDB: got %v, want %v", err,
+			database.ErrDbDoesNotExist)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/conseweb/goleveldb/leveldb"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// The spend of each output is stored under a 40 byte key:
+// -----------------------------------
+// | Prefix  | Tx Sha   | Output Index |
+// -----------------------------------
+// | 4 bytes | 32 bytes |   4 bytes    |
+// -----------------------------------
+// The value is the spending tx sha, the index of the spending input, the
+// block sha and the block height.
+//
+// The outputs spent by each block are also stored under a 36 byte key made of
+// a prefix and the block sha.  The value is the sha of the previous block
+// followed by the spent outputs, which allows rewinding the block once it is
+// no longer in the database.
+const (
+	stxoIndexKeyLength      = 4 + wire.HashSize + 4
+	stxoIndexValueLength    = wire.HashSize + 4 + wire.HashSize + 4
+	stxoIndexBlockKeyLength = 4 + wire.HashSize
+	stxoOutPointLength      = wire.HashSize + 4
+)
+
+var stxoIndexMetaDataKey = []byte("stxoindex")
+
+// All spend entries share this prefix to facilitate the use of iterators.
+var stxoIndexKeyPrefix = []byte("sx+-")
+
+// All entries of the outputs spent by a block share this prefix to facilitate
+// the use of iterators.
+var stxoIndexBlockKeyPrefix = []byte("sb+-")
+
+// stxoIndexKey returns the key the spend of the passed output is stored under.
+func stxoIndexKey(outPoint *wire.OutPoint) []byte {
+	key := make([]byte, stxoIndexKeyLength)
+	copy(key[0:4], stxoIndexKeyPrefix)
+	copy(key[4:36], outPoint.Hash[:])
+	binary.LittleEndian.PutUint32(key[36:40], outPoint.Index)
+	return key
+}
+
+// stxoIndexBlockKey returns the key the outputs spent by the passed block are
+// stored under.
+func stxoIndexBlockKey(blkSha *wire.ShaHash) []byte {
+	key := make([]byte, stxoIndexBlockKeyLength)
+	copy(key[0:4], stxoIndexBlockKeyPrefix)
+	copy(key[4:], blkSha[:])
+	return key
+}
+
+// fetchSTXOIndexTip returns the last block height and block sha to have the
+// outputs it spends indexed.  Like the other indexes, the tip is cached in
+// memory and this function is only used on start up to load it.
+func (db *LevelDb) fetchSTXOIndexTip() (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	data, err := db.lDb.Get(stxoIndexMetaDataKey, db.ro)
+	if err != nil {
+		return &wire.ShaHash{}, -1, database.ErrSTXOIndexDoesNotExist
+	}
+
+	var blkSha wire.ShaHash
+	blkSha.SetBytes(data[0:32])
+
+	blkHeight := binary.LittleEndian.Uint64(data[32:])
+
+	return &blkSha, int32(blkHeight), nil
+}
+
+// FetchSTXOIndexTip returns the hash and block height of the most recent block
+// which has had the outputs it spends indexed.  It will return
+// ErrSTXOIndexDoesNotExist along with a zero hash, and -1 if the spent output
+// index hasn't yet been built up.
+func (db *LevelDb) FetchSTXOIndexTip() (*wire.ShaHash, int32, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	if db.lastSTXOIndexBlkIdx == -1 {
+		return &wire.ShaHash{}, -1, database.ErrSTXOIndexDoesNotExist
+	}
+	sha := db.lastSTXOIndexBlkSha
+
+	return &sha, db.lastSTXOIndexBlkIdx, nil
+}
+
+// putSTXOIndexTip adds the passed block as the new tip of the spent output
+// index to the passed batch.
+func putSTXOIndexTip(batch *leveldb.Batch, blkSha *wire.ShaHash, blkHeight int32) {
+	newIndexTip := make([]byte, 40, 40)
+	copy(newIndexTip[0:32], blkSha[:])
+	binary.LittleEndian.PutUint64(newIndexTip[32:40], uint64(blkHeight))
+	batch.Put(stxoIndexMetaDataKey, newIndexTip)
+}
+
+// UpdateSTXOIndexForBlock records the transaction input of the passed block
+// which spends each of the passed outputs and marks the block as the tip of
+// the spent output index.
+func (db *LevelDb) UpdateSTXOIndexForBlock(blkSha *wire.ShaHash, blkHeight int32,
+	prevSha *wire.ShaHash, spends []database.SpentOutput) error {
+
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	batch := db.lBatch()
+	defer db.lbatch.Reset()
+
+	blockValue := make([]byte, wire.HashSize+len(spends)*stxoOutPointLength)
+	copy(blockValue[0:wire.HashSize], prevSha[:])
+	offset := wire.HashSize
+	for i := range spends {
+		spend := &spends[i]
+		value := make([]byte, stxoIndexValueLength)
+		copy(value[0:32], spend.TxSha[:])
+		binary.LittleEndian.PutUint32(value[32:36], spend.InputIndex)
+		copy(value[36:68], blkSha[:])
+		binary.LittleEndian.PutUint32(value[68:72], uint32(blkHeight))
+		batch.Put(stxoIndexKey(&spend.OutPoint), value)
+
+		copy(blockValue[offset:offset+32], spend.OutPoint.Hash[:])
+		binary.LittleEndian.PutUint32(blockValue[offset+32:],
+			spend.OutPoint.Index)
+		offset += stxoOutPointLength
+	}
+	batch.Put(stxoIndexBlockKey(blkSha), blockValue)
+
+	putSTXOIndexTip(batch, blkSha, blkHeight)
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	db.lastSTXOIndexBlkIdx = blkHeight
+	db.lastSTXOIndexBlkSha = *blkSha
+
+	return nil
+}
+
+// RewindSTXOIndex removes the spends of the block at the tip of the spent
+// output index and marks the previous block as the tip.  Spends which have
+// been overwritten by a spend in another block are left alone.
+func (db *LevelDb) RewindSTXOIndex() error {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	if db.lastSTXOIndexBlkIdx == -1 {
+		return database.ErrSTXOIndexDoesNotExist
+	}
+	tipSha := db.lastSTXOIndexBlkSha
+	tipHeight := db.lastSTXOIndexBlkIdx
+
+	blockKey := stxoIndexBlockKey(&tipSha)
+	blockValue, err := db.lDb.Get(blockKey, db.ro)
+	if err != nil {
+		return err
+	}
+
+	batch := db.lBatch()
+	defer db.lbatch.Reset()
+
+	for offset := wire.HashSize; offset < len(blockValue); offset += stxoOutPointLength {
+		var outPoint wire.OutPoint
+		outPoint.Hash.SetBytes(blockValue[offset : offset+32])
+		outPoint.Index = binary.LittleEndian.Uint32(blockValue[offset+32:])
+
+		key := stxoIndexKey(&outPoint)
+		value, err := db.lDb.Get(key, db.ro)
+		if err == leveldb.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(value) == stxoIndexValueLength &&
+			bytes.Equal(value[36:68], tipSha[:]) {
+
+			batch.Delete(key)
+		}
+	}
+	batch.Delete(blockKey)
+
+	var prevSha wire.ShaHash
+	prevSha.SetBytes(blockValue[0:wire.HashSize])
+	if tipHeight == 0 {
+		batch.Delete(stxoIndexMetaDataKey)
+	} else {
+		putSTXOIndexTip(batch, &prevSha, tipHeight-1)
+	}
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	if tipHeight == 0 {
+		db.lastSTXOIndexBlkIdx = -1
+		db.lastSTXOIndexBlkSha = wire.ShaHash{}
+	} else {
+		db.lastSTXOIndexBlkIdx = tipHeight - 1
+		db.lastSTXOIndexBlkSha = prevSha
+	}
+
+	return nil
+}
+
+// FetchSpendingTx returns the transaction input which spends the passed
+// output along with the block it is in.
+func (db *LevelDb) FetchSpendingTx(outPoint *wire.OutPoint) (*database.SpendingTx, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	data, err := db.lDb.Get(stxoIndexKey(outPoint), db.ro)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, database.ErrSpendMissing
+		}
+		return nil, err
+	}
+	if len(data) < stxoIndexValueLength {
+		return nil, database.ErrSpendMissing
+	}
+
+	spend := &database.SpendingTx{
+		InputIndex: binary.LittleEndian.Uint32(data[32:36]),
+		Height:     int32(binary.LittleEndian.Uint32(data[68:72])),
+	}
+	spend.TxSha.SetBytes(data[0:32])
+	spend.BlkSha.SetBytes(data[36:68])
+	return spend, nil
+}
+
+// DeleteSTXOIndex deletes the entire spent output index stored within the DB.
+// It also resets the cached in-memory metadata about the index.
+func (db *LevelDb) DeleteSTXOIndex() error {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	batch := db.lBatch()
+	defer batch.Reset()
+
+	// Delete the entire index along with any metadata about it.
+	prefixes := []struct {
+		prefix    []byte
+		keyLength int
+	}{
+		{stxoIndexKeyPrefix, stxoIndexKeyLength},
+		{stxoIndexBlockKeyPrefix, stxoIndexBlockKeyLength},
+	}
+	numInBatch := 0
+	for _, p := range prefixes {
+		iter := db.lDb.NewIterator(bytesPrefix(p.prefix), db.ro)
+		for iter.Next() {
+			key := iter.Key()
+			if len(key) == p.keyLength {
+				batch.Delete(key)
+				numInBatch++
+			}
+
+			// Delete in chunks to potentially avoid very large
+			// batches.
+			if numInBatch >= batchDeleteThreshold {
+				if err := db.lDb.Write(batch, db.wo); err != nil {
+					iter.Release()
+					return err
+				}
+				batch.Reset()
+				numInBatch = 0
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+
+	batch.Delete(stxoIndexMetaDataKey)
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	db.lastSTXOIndexBlkIdx = -1
+	db.lastSTXOIndexBlkSha = wire.ShaHash{}
+
+	return nil
+}
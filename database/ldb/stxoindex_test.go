@@ -0,0 +1,135 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestSTXOIndex tests storing, fetching, rewinding and deleting the spends of
+// outputs along with the persistence of the spent output index tip.
+func TestSTXOIndex(t *testing.T) {
+	dbname := "tstdbstxoindex"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+	defer func() { db.Close() }()
+
+	// A fresh database has no spent output index.
+	if _, height, err := db.FetchSTXOIndexTip(); err != database.ErrSTXOIndexDoesNotExist ||
+		height != -1 {
+		t.Fatalf("FetchSTXOIndexTip: got height %v err %v", height, err)
+	}
+
+	assertTip := func(wantSha *wire.ShaHash, wantHeight int32) {
+		sha, height, err := db.FetchSTXOIndexTip()
+		if err != nil || height != wantHeight || *sha != *wantSha {
+			t.Fatalf("FetchSTXOIndexTip: got %v %v err %v, want "+
+				"%v %v", sha, height, err, wantSha, wantHeight)
+		}
+	}
+	assertSpend := func(outPoint *wire.OutPoint, want *database.SpendingTx) {
+		got, err := db.FetchSpendingTx(outPoint)
+		if want == nil {
+			if err != database.ErrSpendMissing {
+				t.Fatalf("FetchSpendingTx(%v): got %+v err %v, "+
+					"want ErrSpendMissing", outPoint, got, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("FetchSpendingTx(%v): unexpected error %v",
+				outPoint, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("FetchSpendingTx(%v): got %+v, want %+v",
+				outPoint, got, want)
+		}
+	}
+
+	// Block 1 spends two outputs.
+	prevSha := wire.ShaHash{0x01}
+	blk1Sha := wire.ShaHash{0x02}
+	out1 := wire.OutPoint{Hash: wire.ShaHash{0x10}, Index: 0}
+	out2 := wire.OutPoint{Hash: wire.ShaHash{0x10}, Index: 1}
+	spends := []database.SpentOutput{
+		{OutPoint: out1, TxSha: wire.ShaHash{0x20}, InputIndex: 0},
+		{OutPoint: out2, TxSha: wire.ShaHash{0x20}, InputIndex: 1},
+	}
+	err = db.UpdateSTXOIndexForBlock(&blk1Sha, 5, &prevSha, spends)
+	if err != nil {
+		t.Fatalf("UpdateSTXOIndexForBlock: unexpected error %v", err)
+	}
+	spend1 := &database.SpendingTx{TxSha: wire.ShaHash{0x20},
+		InputIndex: 0, BlkSha: blk1Sha, Height: 5}
+	spend2 := &database.SpendingTx{TxSha: wire.ShaHash{0x20},
+		InputIndex: 1, BlkSha: blk1Sha, Height: 5}
+	assertTip(&blk1Sha, 5)
+	assertSpend(&out1, spend1)
+	assertSpend(&out2, spend2)
+
+	// The index must survive a restart.
+	db.Sync()
+	db.Close()
+	db, err = database.OpenDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Unable to re-open created db, err %v", err)
+	}
+	assertTip(&blk1Sha, 5)
+	assertSpend(&out1, spend1)
+
+	// Block 2 spends another output.
+	blk2Sha := wire.ShaHash{0x03}
+	out3 := wire.OutPoint{Hash: wire.ShaHash{0x11}, Index: 0}
+	spends = []database.SpentOutput{
+		{OutPoint: out3, TxSha: wire.ShaHash{0x21}, InputIndex: 0},
+	}
+	err = db.UpdateSTXOIndexForBlock(&blk2Sha, 6, &blk1Sha, spends)
+	if err != nil {
+		t.Fatalf("UpdateSTXOIndexForBlock: unexpected error %v", err)
+	}
+	assertTip(&blk2Sha, 6)
+	assertSpend(&out3, &database.SpendingTx{TxSha: wire.ShaHash{0x21},
+		InputIndex: 0, BlkSha: blk2Sha, Height: 6})
+
+	// Rewinding block 2 removes its spends only and makes block 1 the tip
+	// again.
+	if err := db.RewindSTXOIndex(); err != nil {
+		t.Fatalf("RewindSTXOIndex: unexpected error %v", err)
+	}
+	assertTip(&blk1Sha, 5)
+	assertSpend(&out1, spend1)
+	assertSpend(&out2, spend2)
+	assertSpend(&out3, nil)
+
+	// Rewinding block 1 as well makes its parent the tip.
+	if err := db.RewindSTXOIndex(); err != nil {
+		t.Fatalf("RewindSTXOIndex: unexpected error %v", err)
+	}
+	assertTip(&prevSha, 4)
+	assertSpend(&out1, nil)
+
+	if err := db.DeleteSTXOIndex(); err != nil {
+		t.Fatalf("DeleteSTXOIndex: unexpected error %v", err)
+	}
+	if _, _, err := db.FetchSTXOIndexTip(); err != database.ErrSTXOIndexDoesNotExist {
+		t.Fatalf("FetchSTXOIndexTip after delete: unexpected error %v",
+			err)
+	}
+	if err := db.RewindSTXOIndex(); err != database.ErrSTXOIndexDoesNotExist {
+		t.Fatalf("RewindSTXOIndex after delete: unexpected error %v",
+			err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/conseweb/goleveldb/leveldb"
 	"github.com/conseweb/goleveldb/leveldb/iterator"
 	"github.com/conseweb/goleveldb/leveldb/util"
+	"github.com/conseweb/stcd/bech32"
 	"github.com/conseweb/stcd/database"
 	"github.com/conseweb/stcd/wire"
 )
@@ -27,9 +28,17 @@ const (
 	// --------------------------------------------------------
 	addrIndexKeyLength = 3 + ripemd160.Size + 4 + 4 + 4
 
+	// The entries added by each block are also stored under a 36 byte key
+	// made of a prefix and the block sha.  The value is the sha of the
+	// previous block followed by the hash160, tx offset and tx size of
+	// each entry, which allows rewinding the block once it is no longer in
+	// the database.
+	addrIndexBlockKeyLength   = 4 + wire.HashSize
+	addrIndexBlockEntryLength = ripemd160.Size + 4 + 4
+
 	batchDeleteThreshold = 10000
 
-	addrIndexCurrentVersion = 1
+	addrIndexCurrentVersion = 2
 )
 
 var addrIndexMetaDataKey = []byte("addrindex")
@@ -38,10 +47,15 @@ var addrIndexMetaDataKey = []byte("addrindex")
 // iterators.
 var addrIndexKeyPrefix = []byte("a+-")
 
+// All entries of the address indexes added by a block share this prefix to
+// facilitate the use of iterators.
+var addrIndexBlockKeyPrefix = []byte("ab+-")
+
 // Address index version is required to drop/rebuild address index if version
 // is older than current as the format of the index may have changed. This is
 // true when going from no version to version 1 as the address index is stored
-// as big endian in version 1 and little endian in the original code. Version
+// as big endian in version 1 and little endian in the original code, and when
+// going to version 2 which records the entries added by each block. Version
 // is stored as two bytes, little endian (to match all the code but the index).
 var addrIndexVersionKey = []byte("addrindexversion")
 
@@ -399,6 +413,15 @@ func addrIndexToKey(index *txAddrIndex) []byte {
 	return record
 }
 
+// addrIndexBlockKey returns the key the address index entries added by the
+// passed block are stored under.
+func addrIndexBlockKey(blkSha *wire.ShaHash) []byte {
+	key := make([]byte, addrIndexBlockKeyLength)
+	copy(key[0:4], addrIndexBlockKeyPrefix)
+	copy(key[4:], blkSha[:])
+	return key
+}
+
 // unpackTxIndex deserializes the raw bytes of a address tx index.
 func unpackTxIndex(rawIndex [12]byte) *txAddrIndex {
 	return &txAddrIndex{
@@ -408,6 +431,31 @@ func unpackTxIndex(rawIndex [12]byte) *txAddrIndex {
 	}
 }
 
+// addrToKey returns the hash160 the passed address is indexed by.  Pay-to-pubkey
+// addresses are indexed by the hash160 of the public key, the same as their
+// pay-to-pubkey-hash counterparts.  Witness addresses are indexed by their
+// witness program, which is hashed when it is longer than a hash160 just like
+// the other pushes of an output script.
+func addrToKey(addr coinutil.Address) ([]byte, error) {
+	switch addr := addr.(type) {
+	case *coinutil.AddressPubKeyHash:
+		hash160 := addr.Hash160()
+		return hash160[:], nil
+	case *coinutil.AddressScriptHash:
+		hash160 := addr.Hash160()
+		return hash160[:], nil
+	case *coinutil.AddressPubKey:
+		hash160 := addr.AddressPubKeyHash().Hash160()
+		return hash160[:], nil
+	case *bech32.AddressWitnessPubKeyHash:
+		hash160 := addr.Hash160()
+		return hash160[:], nil
+	case *bech32.AddressWitnessScriptHash:
+		return coinutil.Hash160(addr.WitnessProgram()), nil
+	}
+	return nil, database.ErrUnsupportedAddressType
+}
+
 // bytesPrefix returns key range that satisfy the given prefix.
 // This only applicable for the standard 'bytes comparer'.
 func bytesPrefix(prefix []byte) *util.Range {
@@ -450,20 +498,9 @@ func (db *LevelDb) FetchTxsForAddr(addr coinutil.Address, skip int,
 		return nil, 0, errors.New("value for limit must be positive")
 	}
 
-	// Parse address type, bailing on an unknown type.
-	var addrKey []byte
-	switch addr := addr.(type) {
-	case *coinutil.AddressPubKeyHash:
-		hash160 := addr.Hash160()
-		addrKey = hash160[:]
-	case *coinutil.AddressScriptHash:
-		hash160 := addr.Hash160()
-		addrKey = hash160[:]
-	case *coinutil.AddressPubKey:
-		hash160 := addr.AddressPubKeyHash().Hash160()
-		addrKey = hash160[:]
-	default:
-		return nil, 0, database.ErrUnsupportedAddressType
+	addrKey, err := addrToKey(addr)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Create the prefix for our search.
@@ -518,6 +555,15 @@ func (db *LevelDb) FetchTxsForAddr(addr coinutil.Address, skip int,
 	return replies, skipped, nil
 }
 
+// putAddrIndexTip adds the passed block as the new tip of the address index
+// to the passed batch.
+func putAddrIndexTip(batch *leveldb.Batch, blkSha *wire.ShaHash, blkHeight int32) {
+	newIndexTip := make([]byte, 40, 40)
+	copy(newIndexTip[0:32], blkSha[:])
+	binary.LittleEndian.PutUint64(newIndexTip[32:40], uint64(blkHeight))
+	batch.Put(addrIndexMetaDataKey, newIndexTip)
+}
+
 // UpdateAddrIndexForBlock updates the stored addrindex with passed
 // index information for a particular block height. Additionally, it
 // will update the stored meta-data related to the curent tip of the
@@ -532,7 +578,12 @@ func (db *LevelDb) FetchTxsForAddr(addr coinutil.Address, skip int,
 // append-only list for the stored value. However, this add unnecessary
 // overhead when storing and retrieving since the entire list must
 // be fetched each time.
-func (db *LevelDb) UpdateAddrIndexForBlock(blkSha *wire.ShaHash, blkHeight int32, addrIndex database.BlockAddrIndex) error {
+// The entries are also recorded under the block along with the sha of the
+// previous block so RewindAddrIndex can remove them once the block is no
+// longer in the database.
+func (db *LevelDb) UpdateAddrIndexForBlock(blkSha *wire.ShaHash, blkHeight int32,
+	prevSha *wire.ShaHash, addrIndex database.BlockAddrIndex) error {
+
 	db.dbLock.Lock()
 	defer db.dbLock.Unlock()
 
@@ -542,6 +593,8 @@ func (db *LevelDb) UpdateAddrIndexForBlock(blkSha *wire.ShaHash, blkHeight int32
 
 	// Write all data for the new address indexes in a single batch
 	// transaction.
+	blockValue := make([]byte, wire.HashSize)
+	copy(blockValue[0:wire.HashSize], prevSha[:])
 	for addrKey, indexes := range addrIndex {
 		for _, txLoc := range indexes {
 			index := &txAddrIndex{
@@ -553,14 +606,17 @@ func (db *LevelDb) UpdateAddrIndexForBlock(blkSha *wire.ShaHash, blkHeight int32
 			// The index is stored purely in the key.
 			packedIndex := addrIndexToKey(index)
 			batch.Put(packedIndex, blankData)
+
+			// Record the entry without the prefix and block
+			// height, which are the same for the whole block.
+			blockValue = append(blockValue, packedIndex[3:23]...)
+			blockValue = append(blockValue, packedIndex[27:35]...)
 		}
 	}
+	batch.Put(addrIndexBlockKey(blkSha), blockValue)
 
 	// Update tip of addrindex.
-	newIndexTip := make([]byte, 40, 40)
-	copy(newIndexTip[0:32], blkSha[:])
-	binary.LittleEndian.PutUint64(newIndexTip[32:40], uint64(blkHeight))
-	batch.Put(addrIndexMetaDataKey, newIndexTip)
+	putAddrIndexTip(batch, blkSha, blkHeight)
 
 	// Ensure we're writing an address index version
 	newIndexVersion := make([]byte, 2, 2)
@@ -578,6 +634,60 @@ func (db *LevelDb) UpdateAddrIndexForBlock(blkSha *wire.ShaHash, blkHeight int32
 	return nil
 }
 
+// RewindAddrIndex removes the entries added by the block at the tip of the
+// address index and marks the previous block as the tip.
+func (db *LevelDb) RewindAddrIndex() error {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	if db.lastAddrIndexBlkIdx == -1 {
+		return database.ErrAddrIndexDoesNotExist
+	}
+	tipSha := db.lastAddrIndexBlkSha
+	tipHeight := db.lastAddrIndexBlkIdx
+
+	blockKey := addrIndexBlockKey(&tipSha)
+	blockValue, err := db.lDb.Get(blockKey, db.ro)
+	if err != nil {
+		return err
+	}
+
+	batch := db.lBatch()
+	defer db.lbatch.Reset()
+
+	index := &txAddrIndex{blkHeight: tipHeight}
+	for offset := wire.HashSize; offset < len(blockValue); offset += addrIndexBlockEntryLength {
+		entry := blockValue[offset : offset+addrIndexBlockEntryLength]
+		copy(index.hash160[:], entry[0:20])
+		index.txoffset = int(binary.BigEndian.Uint32(entry[20:24]))
+		index.txlen = int(binary.BigEndian.Uint32(entry[24:28]))
+		batch.Delete(addrIndexToKey(index))
+	}
+	batch.Delete(blockKey)
+
+	var prevSha wire.ShaHash
+	prevSha.SetBytes(blockValue[0:wire.HashSize])
+	if tipHeight == 0 {
+		batch.Delete(addrIndexMetaDataKey)
+	} else {
+		putAddrIndexTip(batch, &prevSha, tipHeight-1)
+	}
+
+	if err := db.lDb.Write(batch, db.wo); err != nil {
+		return err
+	}
+
+	if tipHeight == 0 {
+		db.lastAddrIndexBlkIdx = -1
+		db.lastAddrIndexBlkSha = wire.ShaHash{}
+	} else {
+		db.lastAddrIndexBlkIdx = tipHeight - 1
+		db.lastAddrIndexBlkSha = prevSha
+	}
+
+	return nil
+}
+
 // DeleteAddrIndex deletes the entire addrindex stored within the DB.
 // It also resets the cached in-memory metadata about the addr index.
 func (db *LevelDb) DeleteAddrIndex() error {
@@ -588,30 +698,41 @@ func (db *LevelDb) DeleteAddrIndex() error {
 	defer batch.Reset()
 
 	// Delete the entire index along with any metadata about it.
-	iter := db.lDb.NewIterator(bytesPrefix(addrIndexKeyPrefix), db.ro)
+	prefixes := []struct {
+		prefix    []byte
+		keyLength int
+	}{
+		// With a 24-bit index key prefix, 1 in every 2^24 keys is a
+		// collision.  We check the length to make sure we only delete
+		// address index keys.
+		{addrIndexKeyPrefix, addrIndexKeyLength},
+		{addrIndexBlockKeyPrefix, addrIndexBlockKeyLength},
+	}
 	numInBatch := 0
-	for iter.Next() {
-		key := iter.Key()
-		// With a 24-bit index key prefix, 1 in every 2^24 keys is a collision.
-		// We check the length to make sure we only delete address index keys.
-		if len(key) == addrIndexKeyLength {
-			batch.Delete(key)
-			numInBatch++
-		}
+	for _, p := range prefixes {
+		iter := db.lDb.NewIterator(bytesPrefix(p.prefix), db.ro)
+		for iter.Next() {
+			key := iter.Key()
+			if len(key) == p.keyLength {
+				batch.Delete(key)
+				numInBatch++
+			}
 
-		// Delete in chunks to potentially avoid very large batches.
-		if numInBatch >= batchDeleteThreshold {
-			if err := db.lDb.Write(batch, db.wo); err != nil {
-				iter.Release()
-				return err
+			// Delete in chunks to potentially avoid very large
+			// batches.
+			if numInBatch >= batchDeleteThreshold {
+				if err := db.lDb.Write(batch, db.wo); err != nil {
+					iter.Release()
+					return err
+				}
+				batch.Reset()
+				numInBatch = 0
 			}
-			batch.Reset()
-			numInBatch = 0
 		}
-	}
-	iter.Release()
-	if err := iter.Error(); err != nil {
-		return err
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
 	}
 
 	batch.Delete(addrIndexMetaDataKey)
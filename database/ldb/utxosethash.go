@@ -0,0 +1,63 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb
+
+import (
+	"github.com/conseweb/goleveldb/leveldb"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// Each UTXO set hash is stored under a 36 byte key:
+// ------------------------
+// | Prefix  | Block Sha  |
+// ------------------------
+// | 4 bytes |  32 bytes  |
+// ------------------------
+// The value is the serialized rolling hash of the unspent transaction outputs
+// as of the block.
+const utxoSetHashKeyLength = 4 + wire.HashSize
+
+// All UTXO set hash entries share this prefix to facilitate the use of
+// iterators.
+var utxoSetHashKeyPrefix = []byte("uc+-")
+
+// utxoSetHashKey returns the key the UTXO set hash as of the passed block is
+// stored under.
+func utxoSetHashKey(blkSha *wire.ShaHash) []byte {
+	key := make([]byte, utxoSetHashKeyLength)
+	copy(key[0:4], utxoSetHashKeyPrefix)
+	copy(key[4:], blkSha[:])
+	return key
+}
+
+// FetchUtxoSetHash returns the serialized rolling hash of the unspent
+// transaction outputs as of the passed block.  This is part of the
+// database.Db interface implementation.
+func (db *LevelDb) FetchUtxoSetHash(blkSha *wire.ShaHash) ([]byte, error) {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	data, err := db.lDb.Get(utxoSetHashKey(blkSha), db.ro)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, database.ErrUtxoSetHashMissing
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// InsertUtxoSetHash stores the serialized rolling hash of the unspent
+// transaction outputs as of the passed block.  The hashes are keyed by block
+// hash rather than height, so the hashes of blocks which are later
+// disconnected remain valid should the blocks be reconnected.  This is part of
+// the database.Db interface implementation.
+func (db *LevelDb) InsertUtxoSetHash(blkSha *wire.ShaHash, hash []byte) error {
+	db.dbLock.Lock()
+	defer db.dbLock.Unlock()
+
+	return db.lDb.Put(utxoSetHashKey(blkSha), hash, db.wo)
+}
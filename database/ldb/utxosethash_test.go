@@ -0,0 +1,52 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ldb_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/conseweb/stcd/database"
+)
+
+// TestUtxoSetHash ensures UTXO set hashes are stored by block and that a
+// missing hash is reported as such.
+func TestUtxoSetHash(t *testing.T) {
+	dbname := "tstdbutxosethash"
+	_ = os.RemoveAll(dbname)
+	_ = os.RemoveAll(dbname + ".ver")
+	db, err := database.CreateDB("leveldb", dbname)
+	if err != nil {
+		t.Fatalf("Failed to open test database %v", err)
+	}
+	defer os.RemoveAll(dbname)
+	defer os.RemoveAll(dbname + ".ver")
+	defer db.Close()
+
+	blocks := makeCacheTestBlocks(2)
+	if _, err := db.FetchUtxoSetHash(blocks[0].Sha()); err != database.ErrUtxoSetHashMissing {
+		t.Fatalf("FetchUtxoSetHash: got %v, want %v", err,
+			database.ErrUtxoSetHashMissing)
+	}
+
+	hashes := [][]byte{bytes.Repeat([]byte{0x01}, 384),
+		bytes.Repeat([]byte{0x02}, 384)}
+	for i, block := range blocks {
+		if err := db.InsertUtxoSetHash(block.Sha(), hashes[i]); err != nil {
+			t.Fatalf("InsertUtxoSetHash: %v", err)
+		}
+	}
+	for i, block := range blocks {
+		hash, err := db.FetchUtxoSetHash(block.Sha())
+		if err != nil {
+			t.Fatalf("FetchUtxoSetHash: %v", err)
+		}
+		if !bytes.Equal(hash, hashes[i]) {
+			t.Fatalf("FetchUtxoSetHash: got %x, want %x", hash,
+				hashes[i])
+		}
+	}
+}
@@ -675,6 +675,34 @@ func (db *MemDb) NewestSha() (*wire.ShaHash, int32, error) {
 	return &blockSha, int32(numBlocks - 1), nil
 }
 
+// FetchCacheStats returns empty statistics since the memory database has no
+// need to cache blocks.  This is part of the database.Db interface
+// implementation.
+func (db *MemDb) FetchCacheStats() *database.CacheStats {
+	return &database.CacheStats{}
+}
+
+// FetchDatabaseSizes isn't currently implemented since the memory database
+// doesn't store anything on disk.  This is part of the database.Db interface
+// implementation.
+func (db *MemDb) FetchDatabaseSizes() ([]*database.DatabaseSize, error) {
+	return nil, database.ErrNotImplemented
+}
+
+// Backup isn't currently implemented since the memory database can't be
+// opened again once it is closed.  This is part of the database.Db interface
+// implementation.
+func (db *MemDb) Backup(dbPath string, interrupt <-chan struct{}) (*wire.ShaHash, int32, error) {
+	return nil, 0, database.ErrNotImplemented
+}
+
+// Compact isn't currently implemented since the memory database doesn't store
+// anything on disk.  This is part of the database.Db interface
+// implementation.
+func (db *MemDb) Compact() error {
+	return database.ErrNotImplemented
+}
+
 // FetchAddrIndexTip isn't currently implemented. This is a part of the
 // database.Db interface implementation.
 func (db *MemDb) FetchAddrIndexTip() (*wire.ShaHash, int32, error) {
@@ -683,11 +711,17 @@ func (db *MemDb) FetchAddrIndexTip() (*wire.ShaHash, int32, error) {
 
 // UpdateAddrIndexForBlock isn't currently implemented. This is a part of the
 // database.Db interface implementation.
-func (db *MemDb) UpdateAddrIndexForBlock(*wire.ShaHash, int32,
+func (db *MemDb) UpdateAddrIndexForBlock(*wire.ShaHash, int32, *wire.ShaHash,
 	database.BlockAddrIndex) error {
 	return database.ErrNotImplemented
 }
 
+// RewindAddrIndex isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) RewindAddrIndex() error {
+	return database.ErrNotImplemented
+}
+
 // FetchTxsForAddr isn't currently implemented. This is a part of the database.Db
 // interface implementation.
 func (db *MemDb) FetchTxsForAddr(coinutil.Address, int, int, bool) ([]*database.TxListReply, int, error) {
@@ -700,6 +734,142 @@ func (db *MemDb) DeleteAddrIndex() error {
 	return database.ErrNotImplemented
 }
 
+// FetchCFIndexTip isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchCFIndexTip() (*wire.ShaHash, int32, error) {
+	return nil, 0, database.ErrNotImplemented
+}
+
+// UpdateCFIndexForBlock isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) UpdateCFIndexForBlock(*wire.ShaHash, int32, []byte,
+	*wire.ShaHash) error {
+	return database.ErrNotImplemented
+}
+
+// FetchCFilterBySha isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchCFilterBySha(*wire.ShaHash) ([]byte, *wire.ShaHash, error) {
+	return nil, nil, database.ErrNotImplemented
+}
+
+// DeleteCFIndex isn't currently implemented. This is a part of the database.Db
+// interface implementation.
+func (db *MemDb) DeleteCFIndex() error {
+	return database.ErrNotImplemented
+}
+
+// FetchCoinAgeIndexTip isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchCoinAgeIndexTip() (*wire.ShaHash, int32, error) {
+	return nil, 0, database.ErrNotImplemented
+}
+
+// UpdateCoinAgeIndexForBlock isn't currently implemented. This is a part of
+// the database.Db interface implementation.
+func (db *MemDb) UpdateCoinAgeIndexForBlock(*wire.ShaHash, int32,
+	*database.BlockCoinAge) error {
+	return database.ErrNotImplemented
+}
+
+// FetchCoinAgeBySha isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchCoinAgeBySha(*wire.ShaHash) (*database.BlockCoinAge, error) {
+	return nil, database.ErrNotImplemented
+}
+
+// DeleteCoinAgeIndex isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) DeleteCoinAgeIndex() error {
+	return database.ErrNotImplemented
+}
+
+// FetchSTXOIndexTip isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchSTXOIndexTip() (*wire.ShaHash, int32, error) {
+	return nil, 0, database.ErrNotImplemented
+}
+
+// UpdateSTXOIndexForBlock isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) UpdateSTXOIndexForBlock(*wire.ShaHash, int32, *wire.ShaHash,
+	[]database.SpentOutput) error {
+	return database.ErrNotImplemented
+}
+
+// RewindSTXOIndex isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) RewindSTXOIndex() error {
+	return database.ErrNotImplemented
+}
+
+// FetchSpendingTx isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchSpendingTx(*wire.OutPoint) (*database.SpendingTx, error) {
+	return nil, database.ErrNotImplemented
+}
+
+// DeleteSTXOIndex isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) DeleteSTXOIndex() error {
+	return database.ErrNotImplemented
+}
+
+// FetchAddrBalanceIndexTip isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchAddrBalanceIndexTip() (*wire.ShaHash, int32, error) {
+	return nil, 0, database.ErrNotImplemented
+}
+
+// UpdateAddrBalanceIndexForBlock isn't currently implemented. This is a part
+// of the database.Db interface implementation.
+func (db *MemDb) UpdateAddrBalanceIndexForBlock(*wire.ShaHash, int32,
+	*wire.ShaHash, []database.AddrDelta) error {
+	return database.ErrNotImplemented
+}
+
+// RewindAddrBalanceIndex isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) RewindAddrBalanceIndex() error {
+	return database.ErrNotImplemented
+}
+
+// FetchAddrBalance isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchAddrBalance(coinutil.Address) (*database.AddrBalance, error) {
+	return nil, database.ErrNotImplemented
+}
+
+// FetchAddrUtxos isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchAddrUtxos(coinutil.Address) ([]*database.AddrUtxo, error) {
+	return nil, database.ErrNotImplemented
+}
+
+// FetchAddrDeltas isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchAddrDeltas(coinutil.Address, int32, int32) ([]*database.AddrDelta, error) {
+	return nil, database.ErrNotImplemented
+}
+
+// DeleteAddrBalanceIndex isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) DeleteAddrBalanceIndex() error {
+	return database.ErrNotImplemented
+}
+
+// FetchUtxoSetHash isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) FetchUtxoSetHash(*wire.ShaHash) ([]byte, error) {
+	return nil, database.ErrNotImplemented
+}
+
+// InsertUtxoSetHash isn't currently implemented. This is a part of the
+// database.Db interface implementation.
+func (db *MemDb) InsertUtxoSetHash(*wire.ShaHash, []byte) error {
+	return database.ErrNotImplemented
+}
+
 // RollbackClose discards the recent database changes to the previously saved
 // data at last Sync and closes the database.  This is part of the database.Db
 // interface implementation.
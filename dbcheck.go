@@ -0,0 +1,294 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// These constants define the levels of the database integrity checks.  Every
+// level includes the checks of the levels below it.
+const (
+	// checkLevelIndex ensures each block can be loaded by its height and
+	// hash and links to the previous block.
+	checkLevelIndex = iota
+
+	// checkLevelSanity performs the context-free sanity checks on each
+	// block.
+	checkLevelSanity
+
+	// checkLevelTxs ensures the transactions of each block are in the
+	// transaction index and the outputs they spend are marked spent.
+	checkLevelTxs
+
+	// checkLevelIndexes ensures the tips of the optional indexes can be
+	// loaded.
+	checkLevelIndexes
+
+	// maxCheckLevel is the most thorough check level.
+	maxCheckLevel = checkLevelIndexes
+)
+
+// blockCheckError describes an inconsistency of the block at a height found
+// by checkBlocks.
+type blockCheckError struct {
+	height int32
+	err    error
+}
+
+// Error satisfies the error interface and prints human-readable errors.
+func (e blockCheckError) Error() string {
+	return fmt.Sprintf("block at height %d is inconsistent: %v", e.height,
+		e.err)
+}
+
+// checkBlock performs the checks of the passed level on the block at the
+// passed height.
+func checkBlock(db database.Db, height, level int32, timeSource blockchain.MedianTimeSource) error {
+	sha, err := db.FetchBlockShaByHeight(height)
+	if err != nil {
+		return err
+	}
+	block, err := db.FetchBlockBySha(sha)
+	if err != nil {
+		return err
+	}
+	if got, err := db.FetchBlockHeightBySha(sha); err != nil || got != height {
+		return fmt.Errorf("block %v is indexed at height %d: %v", sha,
+			got, err)
+	}
+	if height > 0 {
+		prevSha, err := db.FetchBlockShaByHeight(height - 1)
+		if err != nil {
+			return err
+		}
+		if !block.MsgBlock().Header.PrevBlock.IsEqual(prevSha) {
+			return fmt.Errorf("block %v does not link to the "+
+				"previous block %v", sha, prevSha)
+		}
+	}
+
+	if level < checkLevelSanity {
+		return nil
+	}
+	err = blockchain.CheckBlockSanity(block, activeNetParams.PowLimit,
+		timeSource)
+	if err != nil {
+		return err
+	}
+
+	if level < checkLevelTxs {
+		return nil
+	}
+	for i, tx := range block.Transactions() {
+		// Ensure the transaction is indexed in this block.
+		replies, err := db.FetchTxBySha(tx.Sha())
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, reply := range replies {
+			if reply.Height == height && reply.BlkSha.IsEqual(sha) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("transaction %v is missing from the "+
+				"transaction index", tx.Sha())
+		}
+
+		// Ensure the outputs spent by the transaction are marked spent.
+		if i == 0 {
+			continue
+		}
+		for _, txIn := range tx.MsgTx().TxIn {
+			prevOut := &txIn.PreviousOutPoint
+			replies, err := db.FetchTxBySha(&prevOut.Hash)
+			if err != nil {
+				return err
+			}
+			var spent *database.TxListReply
+			for _, reply := range replies {
+				if reply.Height <= height && (spent == nil ||
+					reply.Height > spent.Height) {
+
+					spent = reply
+				}
+			}
+			if spent == nil {
+				return fmt.Errorf("output %v spent by transaction "+
+					"%v is missing", prevOut, tx.Sha())
+			}
+			if int(prevOut.Index) >= len(spent.TxSpent) ||
+				!spent.TxSpent[prevOut.Index] {
+
+				return fmt.Errorf("output %v spent by transaction "+
+					"%v is not marked spent", prevOut, tx.Sha())
+			}
+		}
+	}
+	return nil
+}
+
+// checkBlocks performs the checks of the passed level on the passed number of
+// most recent blocks, or all blocks when it is 0, and returns an inconsistency
+// of the oldest inconsistent block as a blockCheckError.  The error of the
+// passed context is returned instead once it is canceled.
+func checkBlocks(ctx context.Context, db database.Db, level, depth int32, timeSource blockchain.MedianTimeSource) error {
+	_, curHeight, err := db.NewestSha()
+	if err != nil {
+		return err
+	}
+	finishHeight := int32(-1)
+	if depth > 0 && curHeight-depth > finishHeight {
+		finishHeight = curHeight - depth
+	}
+
+	var checkErr error
+	for height := curHeight; height > finishHeight; height-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := checkBlock(db, height, level, timeSource); err != nil {
+			checkErr = blockCheckError{height: height, err: err}
+		}
+	}
+	return checkErr
+}
+
+// optionalIndex describes one of the optional indexes for the integrity
+// checks.
+type optionalIndex struct {
+	name        string
+	notExistErr error
+	fetchTip    func() (*wire.ShaHash, int32, error)
+	fetchRecord func(sha *wire.ShaHash) error
+	drop        func() error
+}
+
+// optionalIndexes returns the optional indexes of the passed database.
+func optionalIndexes(db database.Db) []optionalIndex {
+	return []optionalIndex{
+		{
+			name:        "addrindex",
+			notExistErr: database.ErrAddrIndexDoesNotExist,
+			fetchTip:    db.FetchAddrIndexTip,
+			drop:        db.DeleteAddrIndex,
+		},
+		{
+			name:        "cfindex",
+			notExistErr: database.ErrCFIndexDoesNotExist,
+			fetchTip:    db.FetchCFIndexTip,
+			fetchRecord: func(sha *wire.ShaHash) error {
+				_, _, err := db.FetchCFilterBySha(sha)
+				return err
+			},
+			drop: db.DeleteCFIndex,
+		},
+		{
+			name:        "coinageindex",
+			notExistErr: database.ErrCoinAgeIndexDoesNotExist,
+			fetchTip:    db.FetchCoinAgeIndexTip,
+			fetchRecord: func(sha *wire.ShaHash) error {
+				_, err := db.FetchCoinAgeBySha(sha)
+				return err
+			},
+			drop: db.DeleteCoinAgeIndex,
+		},
+		{
+			name:        "stxoindex",
+			notExistErr: database.ErrSTXOIndexDoesNotExist,
+			fetchTip:    db.FetchSTXOIndexTip,
+			drop:        db.DeleteSTXOIndex,
+		},
+		{
+			name:        "addrbalanceindex",
+			notExistErr: database.ErrAddrBalanceIndexDoesNotExist,
+			fetchTip:    db.FetchAddrBalanceIndexTip,
+			drop:        db.DeleteAddrBalanceIndex,
+		},
+	}
+}
+
+// checkIndex ensures the tip of the passed optional index, and when possible
+// the record stored for it, can be loaded.  Indexes which haven't been built
+// are consistent.
+func checkIndex(index *optionalIndex) error {
+	sha, _, err := index.fetchTip()
+	if err == index.notExistErr || err == database.ErrNotImplemented {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if index.fetchRecord != nil {
+		if err := index.fetchRecord(sha); err != nil {
+			return fmt.Errorf("tip %v: %v", sha, err)
+		}
+	}
+	return nil
+}
+
+// checkDatabase verifies the consistency of the block database on startup
+// according to the checkblocks and checklevel options and repairs it when
+// corruption is detected.  The chain is rolled back to the last consistent
+// block before the oldest inconsistent block, and inconsistent optional indexes
+// are deleted so they are rebuilt.  An error is only returned when the database
+// can't be repaired.
+func checkDatabase(db database.Db) error {
+	level, depth := int32(cfg.CheckLevel), int32(cfg.CheckBlocks)
+	if depth == 0 {
+		btcdLog.Infof("Verifying all blocks at level %d", level)
+	} else {
+		btcdLog.Infof("Verifying the last %d blocks at level %d",
+			depth, level)
+	}
+	timeSource := blockchain.NewMedianTime()
+	err := checkBlocks(context.Background(), db, level, depth,
+		timeSource)
+	if cerr, ok := err.(blockCheckError); ok {
+		btcdLog.Warnf("Database corruption detected: %v", cerr)
+		if cerr.height == 0 {
+			return fmt.Errorf("the genesis block is corrupt -- the " +
+				"database must be deleted")
+		}
+		sha, err := db.FetchBlockShaByHeight(cerr.height - 1)
+		if err == nil {
+			err = db.DropAfterBlockBySha(sha)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to roll back to height %d: %v "+
+				"-- the database must be deleted", cerr.height-1,
+				err)
+		}
+		btcdLog.Warnf("Rolled the chain back to block %v (height %d)",
+			sha, cerr.height-1)
+	} else if err != nil {
+		return err
+	}
+
+	if level < checkLevelIndexes {
+		return nil
+	}
+	for _, index := range optionalIndexes(db) {
+		err := checkIndex(&index)
+		if err == nil {
+			continue
+		}
+		btcdLog.Warnf("The %s is corrupt: %v -- deleting it so it is "+
+			"rebuilt", index.name, err)
+		if err := index.drop(); err != nil {
+			return fmt.Errorf("unable to delete the %s: %v",
+				index.name, err)
+		}
+	}
+	return nil
+}
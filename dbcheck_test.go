@@ -0,0 +1,138 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// corruptDb is a database which fails to load the blocks with the given
+// hashes.
+type corruptDb struct {
+	database.Db
+	corrupt map[wire.ShaHash]struct{}
+}
+
+// FetchBlockBySha returns an error for the corrupt blocks.
+func (db *corruptDb) FetchBlockBySha(sha *wire.ShaHash) (*coinutil.Block, error) {
+	if _, ok := db.corrupt[*sha]; ok {
+		return nil, errors.New("corrupt block")
+	}
+	return db.Db.FetchBlockBySha(sha)
+}
+
+// TestCheckDatabase ensures the oldest inconsistent block within the checked
+// depth is detected and the chain is rolled back to the block before it.
+func TestCheckDatabase(t *testing.T) {
+	savedCfg := cfg
+	cfg = &config{CheckLevel: checkLevelIndex}
+	defer func() { cfg = savedCfg }()
+
+	memDb, err := database.CreateDB("memdb")
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	defer memDb.Close()
+	addTestBlocks(t, memDb, 10, 0x207fffff, 0)
+	db := &corruptDb{Db: memDb, corrupt: make(map[wire.ShaHash]struct{})}
+
+	ctx := context.Background()
+	timeSource := blockchain.NewMedianTime()
+	if err := checkBlocks(ctx, db, checkLevelIndex, 0, timeSource); err != nil {
+		t.Fatalf("checkBlocks: unexpected error: %v", err)
+	}
+
+	// Corrupt the blocks at heights 6 and 8.
+	for _, height := range []int32{6, 8} {
+		sha, err := db.FetchBlockShaByHeight(height)
+		if err != nil {
+			t.Fatalf("FetchBlockShaByHeight: %v", err)
+		}
+		db.corrupt[*sha] = struct{}{}
+	}
+
+	// Only the most recent blocks are checked with a depth.
+	if err := checkBlocks(ctx, db, checkLevelIndex, 1, timeSource); err != nil {
+		t.Fatalf("checkBlocks: unexpected error for the last block: %v",
+			err)
+	}
+	err = checkBlocks(ctx, db, checkLevelIndex, 2, timeSource)
+	if cerr, ok := err.(blockCheckError); !ok || cerr.height != 8 {
+		t.Fatalf("checkBlocks: got %v, want block 8 inconsistent", err)
+	}
+	err = checkBlocks(ctx, db, checkLevelIndex, 0, timeSource)
+	if cerr, ok := err.(blockCheckError); !ok || cerr.height != 6 {
+		t.Fatalf("checkBlocks: got %v, want block 6 inconsistent", err)
+	}
+
+	// The chain is rolled back to the block before the oldest corrupt
+	// block, after which it is consistent.
+	if err := checkDatabase(db); err != nil {
+		t.Fatalf("checkDatabase: %v", err)
+	}
+	if _, height, err := db.NewestSha(); err != nil || height != 5 {
+		t.Fatalf("checkDatabase: chain at height %d (%v), want 5",
+			height, err)
+	}
+	if err := checkBlocks(ctx, db, checkLevelIndex, 0, timeSource); err != nil {
+		t.Fatalf("checkBlocks: unexpected error after repair: %v", err)
+	}
+
+	// The checks stop once the context is canceled.
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = checkBlocks(canceledCtx, db, checkLevelIndex, 0, timeSource)
+	if err != context.Canceled {
+		t.Fatalf("checkBlocks: got %v, want %v after cancel", err,
+			context.Canceled)
+	}
+}
+
+// TestCheckIndex ensures optional indexes which haven't been built are
+// consistent while indexes whose tip or tip record can't be loaded are not.
+func TestCheckIndex(t *testing.T) {
+	errNotExist := errors.New("index does not exist")
+	tip := func(err error) func() (*wire.ShaHash, int32, error) {
+		return func() (*wire.ShaHash, int32, error) {
+			return &wire.ShaHash{0x01}, 1, err
+		}
+	}
+	record := func(err error) func(*wire.ShaHash) error {
+		return func(*wire.ShaHash) error { return err }
+	}
+
+	tests := []struct {
+		name       string
+		index      optionalIndex
+		consistent bool
+	}{
+		{"not built", optionalIndex{fetchTip: tip(errNotExist)}, true},
+		{"not implemented",
+			optionalIndex{fetchTip: tip(database.ErrNotImplemented)},
+			true},
+		{"tip", optionalIndex{fetchTip: tip(nil)}, true},
+		{"tip record", optionalIndex{fetchTip: tip(nil),
+			fetchRecord: record(nil)}, true},
+		{"corrupt tip",
+			optionalIndex{fetchTip: tip(errors.New("corrupt"))}, false},
+		{"corrupt tip record", optionalIndex{fetchTip: tip(nil),
+			fetchRecord: record(errors.New("corrupt"))}, false},
+	}
+	for _, test := range tests {
+		test.index.notExistErr = errNotExist
+		err := checkIndex(&test.index)
+		if (err == nil) != test.consistent {
+			t.Errorf("%s: got %v, want consistent %v", test.name, err,
+				test.consistent)
+		}
+	}
+}
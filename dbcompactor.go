@@ -0,0 +1,214 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conseweb/stcd/database"
+)
+
+// compactWindowInterval is how often the scheduled compaction handler checks
+// whether the compaction window has been entered.
+const compactWindowInterval = time.Minute
+
+// errCompacting is returned when a compaction is requested while another one
+// is still running.
+var errCompacting = errors.New("the database is already being compacted")
+
+// compactWindow is a daily time window in local time during which the
+// database is compacted.  The window wraps around midnight when it ends before
+// it starts.
+type compactWindow struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// parseCompactWindow parses a compaction window of the form HH:MM-HH:MM.
+func parseCompactWindow(window string) (*compactWindow, error) {
+	var startHour, startMin, endHour, endMin int
+	_, err := fmt.Sscanf(window, "%d:%d-%d:%d", &startHour, &startMin,
+		&endHour, &endMin)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not of the form HH:MM-HH:MM",
+			window)
+	}
+	for _, hm := range [][2]int{{startHour, startMin}, {endHour, endMin}} {
+		if hm[0] < 0 || hm[0] > 23 || hm[1] < 0 || hm[1] > 59 {
+			return nil, fmt.Errorf("%02d:%02d is not a valid time of "+
+				"day", hm[0], hm[1])
+		}
+	}
+	w := &compactWindow{
+		start: time.Duration(startHour)*time.Hour +
+			time.Duration(startMin)*time.Minute,
+		end: time.Duration(endHour)*time.Hour +
+			time.Duration(endMin)*time.Minute,
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("%q is empty", window)
+	}
+	return w, nil
+}
+
+// String returns the window in the form HH:MM-HH:MM.
+func (w *compactWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.start/time.Hour,
+		w.start%time.Hour/time.Minute, w.end/time.Hour,
+		w.end%time.Hour/time.Minute)
+}
+
+// opened returns when the occurrence of the window which contains the passed
+// time opened, and false when the time is outside of the window.
+func (w *compactWindow) opened(t time.Time) (time.Time, bool) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0,
+		t.Location())
+	offset := t.Sub(midnight)
+	switch {
+	case w.start < w.end && offset >= w.start && offset < w.end:
+		return midnight.Add(w.start), true
+
+	// The window wraps around midnight, so it either opened today or
+	// yesterday.
+	case w.start > w.end && offset >= w.start:
+		return midnight.Add(w.start), true
+	case w.start > w.end && offset < w.end:
+		return midnight.AddDate(0, 0, -1).Add(w.start), true
+	}
+	return time.Time{}, false
+}
+
+// compactionStatus describes the running and last finished compaction of the
+// database.
+type compactionStatus struct {
+	running bool
+
+	// lastStart is zero when no compaction has finished since start up.
+	lastStart    time.Time
+	lastDuration time.Duration
+	lastErr      error
+}
+
+// dbCompactor compacts the database on request and, when a compaction window
+// is configured, once during every occurrence of the window.  Only one
+// compaction runs at a time.
+type dbCompactor struct {
+	db     database.Db
+	window *compactWindow
+
+	mtx        sync.Mutex
+	status     compactionStatus
+	lastWindow time.Time
+}
+
+// newDBCompactor returns a new compactor of the passed database.  The window
+// is nil when the database is only compacted on request.
+func newDBCompactor(db database.Db, window *compactWindow) *dbCompactor {
+	return &dbCompactor{db: db, window: window}
+}
+
+// Compact starts compacting the database in the background and returns
+// errCompacting when a compaction is already running.  The done channel is
+// closed once the compaction finished and may be nil.
+//
+// This function is safe for concurrent access.
+func (c *dbCompactor) Compact(done chan struct{}) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.status.running {
+		return errCompacting
+	}
+	c.status.running = true
+	go c.compact(done)
+	return nil
+}
+
+// compact compacts the database and records the outcome.
+//
+// This must be run as a goroutine.
+func (c *dbCompactor) compact(done chan struct{}) {
+	srvrLog.Infof("Compacting the database")
+	start := time.Now()
+	err := c.db.Compact()
+	duration := time.Since(start)
+	if err != nil {
+		srvrLog.Errorf("Failed to compact the database: %v", err)
+	} else {
+		srvrLog.Infof("Compacted the database in %v", duration)
+	}
+
+	c.mtx.Lock()
+	c.status = compactionStatus{
+		lastStart:    start,
+		lastDuration: duration,
+		lastErr:      err,
+	}
+	c.mtx.Unlock()
+
+	if done != nil {
+		close(done)
+	}
+}
+
+// compactInWindow starts compacting the database when the passed time is
+// within the compaction window and it hasn't been compacted during this
+// occurrence of the window yet.  It returns whether a compaction was started.
+//
+// This function is safe for concurrent access.
+func (c *dbCompactor) compactInWindow(now time.Time, done chan struct{}) bool {
+	if c.window == nil {
+		return false
+	}
+	opened, ok := c.window.opened(now)
+	if !ok {
+		return false
+	}
+
+	c.mtx.Lock()
+	if c.lastWindow.Equal(opened) {
+		c.mtx.Unlock()
+		return false
+	}
+	c.lastWindow = opened
+	c.mtx.Unlock()
+
+	// A compaction requested during the window counts for it.
+	return c.Compact(done) == nil
+}
+
+// Status returns the status of the running and last finished compaction.
+//
+// This function is safe for concurrent access.
+func (c *dbCompactor) Status() compactionStatus {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.status
+}
+
+// dbCompactionHandler compacts the database once during every occurrence of
+// the configured compaction window.
+//
+// This must be run as a goroutine.
+func (s *server) dbCompactionHandler() {
+	ticker := time.NewTicker(compactWindowInterval)
+	defer ticker.Stop()
+
+out:
+	for {
+		select {
+		case <-ticker.C:
+			s.dbCompactor.compactInWindow(time.Now(), nil)
+
+		case <-s.quit:
+			break out
+		}
+	}
+	s.wg.Done()
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/database"
+)
+
+// TestParseCompactWindow ensures compaction windows are parsed and invalid or
+// empty windows are rejected.
+func TestParseCompactWindow(t *testing.T) {
+	tests := []struct {
+		window string
+		valid  bool
+	}{
+		{"02:00-04:30", true},
+		{"23:00-01:00", true},
+		{"2:05-3:00", true},
+		{"02:00", false},
+		{"02:00-24:00", false},
+		{"02:60-04:00", false},
+		{"04:00-04:00", false},
+		{"later", false},
+	}
+	for _, test := range tests {
+		w, err := parseCompactWindow(test.window)
+		if (err == nil) != test.valid {
+			t.Errorf("parseCompactWindow(%q): got %v, want valid %v",
+				test.window, err, test.valid)
+			continue
+		}
+		if err == nil && test.window == "02:00-04:30" &&
+			w.String() != test.window {
+
+			t.Errorf("String: got %q, want %q", w.String(),
+				test.window)
+		}
+	}
+}
+
+// TestCompactWindowOpened ensures the opening time of the occurrence of a
+// window containing a time is found, including for windows which wrap around
+// midnight.
+func TestCompactWindowOpened(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2015, time.October, day, hour, min, 0, 0,
+			time.UTC)
+	}
+	day, _ := parseCompactWindow("02:00-04:00")
+	night, _ := parseCompactWindow("23:00-01:00")
+
+	tests := []struct {
+		window *compactWindow
+		t      time.Time
+		opened time.Time
+		within bool
+	}{
+		{day, at(10, 1, 59), time.Time{}, false},
+		{day, at(10, 2, 0), at(10, 2, 0), true},
+		{day, at(10, 3, 59), at(10, 2, 0), true},
+		{day, at(10, 4, 0), time.Time{}, false},
+		{night, at(10, 22, 59), time.Time{}, false},
+		{night, at(10, 23, 30), at(10, 23, 0), true},
+		{night, at(11, 0, 30), at(10, 23, 0), true},
+		{night, at(11, 1, 0), time.Time{}, false},
+	}
+	for i, test := range tests {
+		opened, within := test.window.opened(test.t)
+		if within != test.within || !opened.Equal(test.opened) {
+			t.Errorf("opened #%d: got %v, %v, want %v, %v", i, opened,
+				within, test.opened, test.within)
+		}
+	}
+}
+
+// blockingCompactDb is a database whose compaction blocks until it is
+// released.
+type blockingCompactDb struct {
+	database.Db
+	release chan struct{}
+}
+
+// Compact blocks until the compaction is released.
+func (db *blockingCompactDb) Compact() error {
+	<-db.release
+	return nil
+}
+
+// TestDBCompactor ensures only one compaction runs at a time, the outcome of
+// the last compaction is reported and the database is compacted only once
+// during each occurrence of the compaction window.
+func TestDBCompactor(t *testing.T) {
+	db := &blockingCompactDb{release: make(chan struct{})}
+	window, _ := parseCompactWindow("02:00-04:00")
+	c := newDBCompactor(db, window)
+	at := func(day, hour int) time.Time {
+		return time.Date(2015, time.October, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	if c.compactInWindow(at(10, 1), nil) {
+		t.Fatalf("compactInWindow: compacted outside of the window")
+	}
+	done := make(chan struct{})
+	if !c.compactInWindow(at(10, 2), done) {
+		t.Fatalf("compactInWindow: did not compact within the window")
+	}
+	if !c.Status().running {
+		t.Fatalf("Status: the compaction is not running")
+	}
+	if err := c.Compact(nil); err != errCompacting {
+		t.Fatalf("Compact: got %v, want %v", err, errCompacting)
+	}
+	db.release <- struct{}{}
+	<-done
+
+	status := c.Status()
+	if status.running || status.lastStart.IsZero() || status.lastErr != nil {
+		t.Fatalf("Status: got %+v, want a finished compaction", status)
+	}
+
+	// The window was already compacted in, but the next one isn't.
+	if c.compactInWindow(at(10, 3), nil) {
+		t.Fatalf("compactInWindow: compacted twice within the window")
+	}
+	done = make(chan struct{})
+	if !c.compactInWindow(at(11, 3), done) {
+		t.Fatalf("compactInWindow: did not compact within the next " +
+			"window")
+	}
+	db.release <- struct{}{}
+	<-done
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/conseweb/stcd/bech32"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/txscript"
+)
+
+// descriptorInputCharset is the set of characters an output descriptor may
+// consist of, ordered so the checksum detects the most common typing errors.
+const descriptorInputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+	"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+	"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+
+// descriptorChecksumCharset is the set of characters the checksum of an output
+// descriptor is encoded with.
+const descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// descriptorChecksumLen is the number of characters of the checksum of an
+// output descriptor.
+const descriptorChecksumLen = 8
+
+// descriptorPolyMod updates the passed checksum state with the passed 5-bit
+// value using the BCH code of output descriptor checksums.
+func descriptorPolyMod(c uint64, val int) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ uint64(val)
+	if c0&1 != 0 {
+		c ^= 0xf5dee51989
+	}
+	if c0&2 != 0 {
+		c ^= 0xa9fdca3312
+	}
+	if c0&4 != 0 {
+		c ^= 0x1bab10e32d
+	}
+	if c0&8 != 0 {
+		c ^= 0x3706b1677a
+	}
+	if c0&16 != 0 {
+		c ^= 0x644d626ffd
+	}
+	return c
+}
+
+// descriptorChecksum returns the checksum of the passed output descriptor,
+// which must not include a checksum itself.
+func descriptorChecksum(desc string) (string, error) {
+	c := uint64(1)
+	cls, clsCount := 0, 0
+	for i := 0; i < len(desc); i++ {
+		pos := strings.IndexByte(descriptorInputCharset, desc[i])
+		if pos == -1 {
+			return "", fmt.Errorf("invalid character %q", desc[i])
+		}
+
+		// Every character is fed to the checksum by its position within
+		// its group of 32 characters, while the groups are packed three
+		// at a time.
+		c = descriptorPolyMod(c, pos&31)
+		cls = cls*3 + pos>>5
+		clsCount++
+		if clsCount == 3 {
+			c = descriptorPolyMod(c, cls)
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = descriptorPolyMod(c, cls)
+	}
+	for i := 0; i < descriptorChecksumLen; i++ {
+		c = descriptorPolyMod(c, 0)
+	}
+	c ^= 1
+
+	var checksum [descriptorChecksumLen]byte
+	for i := range checksum {
+		shift := uint(5 * (descriptorChecksumLen - 1 - i))
+		checksum[i] = descriptorChecksumCharset[(c>>shift)&31]
+	}
+	return string(checksum[:]), nil
+}
+
+// parseOutputDescriptor returns the public key script described by the passed
+// output descriptor.  The raw(HEX) descriptor describes the script encoded by
+// the hex string and the addr(ADDRESS) descriptor the script which pays to the
+// address on the passed network.  The checksum which may follow the descriptor
+// after a '#' is verified when present.
+func parseOutputDescriptor(desc string, params *chaincfg.Params) ([]byte, error) {
+	if i := strings.IndexByte(desc, '#'); i != -1 {
+		checksum := desc[i+1:]
+		desc = desc[:i]
+		want, err := descriptorChecksum(desc)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != want {
+			return nil, fmt.Errorf("checksum %q does not match the "+
+				"expected %q", checksum, want)
+		}
+	}
+
+	open := strings.IndexByte(desc, '(')
+	if open == -1 || !strings.HasSuffix(desc, ")") {
+		return nil, errors.New("descriptor must be of the form " +
+			"raw(HEX) or addr(ADDRESS)")
+	}
+	arg := desc[open+1 : len(desc)-1]
+	switch desc[:open] {
+	case "raw":
+		pkScript, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid script hex: %v", err)
+		}
+		if len(pkScript) == 0 {
+			return nil, errors.New("script must not be empty")
+		}
+		return pkScript, nil
+
+	case "addr":
+		addr, err := bech32.DecodeAddress(arg, params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address: %v", err)
+		}
+		if !addr.IsForNet(params) {
+			return nil, fmt.Errorf("address %s is for the wrong "+
+				"network", arg)
+		}
+		return txscript.PayToAddrScript(addr)
+	}
+
+	return nil, fmt.Errorf("unsupported descriptor type %q", desc[:open])
+}
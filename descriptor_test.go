@@ -0,0 +1,130 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/conseweb/stcd/chaincfg"
+)
+
+// TestParseOutputDescriptor ensures output descriptors are parsed to the
+// scripts they describe and that invalid descriptors and checksums are
+// rejected.
+func TestParseOutputDescriptor(t *testing.T) {
+	t.Parallel()
+
+	p2pkh := "76a914399c39ac90dac26965fb55fdb2035e6715fdac4e88ac"
+	tests := []struct {
+		name   string
+		desc   string
+		script string
+		valid  bool
+	}{
+		{
+			name:   "raw",
+			desc:   "raw(deadbeef)",
+			script: "deadbeef",
+			valid:  true,
+		},
+		{
+			name:   "raw with checksum",
+			desc:   "raw(deadbeef)#89f8spxm",
+			script: "deadbeef",
+			valid:  true,
+		},
+		{
+			name:   "addr",
+			desc:   "addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)",
+			script: p2pkh,
+			valid:  true,
+		},
+		{
+			name:   "addr with checksum",
+			desc:   "addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)#02wpgw69",
+			script: p2pkh,
+			valid:  true,
+		},
+		{
+			name: "bad checksum",
+			desc: "raw(deadbeef)#89f8spxn",
+		},
+		{
+			name: "checksum of another descriptor",
+			desc: "raw(deadbeee)#89f8spxm",
+		},
+		{
+			name: "empty raw script",
+			desc: "raw()",
+		},
+		{
+			name: "invalid hex",
+			desc: "raw(deadbee)",
+		},
+		{
+			name: "mainnet address",
+			desc: "addr(1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2)",
+		},
+		{
+			name: "unsupported type",
+			desc: "pkh(deadbeef)",
+		},
+		{
+			name: "missing parenthesis",
+			desc: "raw(deadbeef",
+		},
+	}
+
+	for _, test := range tests {
+		script, err := parseOutputDescriptor(test.desc,
+			&chaincfg.TestNet3Params)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: parsed invalid descriptor %q",
+					test.name, test.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		want, _ := hex.DecodeString(test.script)
+		if !bytes.Equal(script, want) {
+			t.Errorf("%s: got script %x - want %x", test.name,
+				script, want)
+		}
+	}
+}
+
+// TestDescriptorChecksum ensures the checksums of output descriptors match the
+// ones of known descriptors.
+func TestDescriptorChecksum(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		checksum string
+	}{
+		{"raw(deadbeef)", "89f8spxm"},
+		{"addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)", "02wpgw69"},
+	}
+	for _, test := range tests {
+		checksum, err := descriptorChecksum(test.desc)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.desc, err)
+			continue
+		}
+		if checksum != test.checksum {
+			t.Errorf("%s: got checksum %s - want %s", test.desc,
+				checksum, test.checksum)
+		}
+	}
+	if _, err := descriptorChecksum("raw(é)"); err == nil {
+		t.Errorf("descriptorChecksum: accepted invalid character")
+	}
+}
@@ -36,17 +36,131 @@ Application Options:
       --maxpeers=           Max number of inbound and outbound peers (125)
       --banduration=        How long to ban misbehaving peers.  Valid time units
                             are {s, m, h}.  Minimum 1 second (24h0m0s)
+      --banthreshold=       Misbehavior score at which peers are banned and
+                            disconnected -- Scores halve every 10 minutes.
+                            Minimum 1 (100)
+      --inboundrateperip=   Max number of inbound connections per minute from a
+                            single IP, or network of the inboundipv4prefix and
+                            inboundipv6prefix lengths -- IPs exceeding it are
+                            refused for 30 seconds, doubling with every
+                            repeated offense up to an hour -- 0 disables the
+                            limit (6)
+      --inboundipv4prefix=  Prefix length of the IPv4 networks counted as a
+                            single IP by inboundrateperip, such as 24 --
+                            Between 1 and 32 (32)
+      --inboundipv6prefix=  Prefix length of the IPv6 networks counted as a
+                            single IP by inboundrateperip -- Between 1 and 128
+                            (64)
+      --inboundrate=        Max number of inbound connections per minute in
+                            total -- 0 disables the limit (120)
+      --inboundchallenge    Accept inbound connections exceeding the inboundrate
+                            limit and require the peers to answer a ping before
+                            they are added instead of refusing them
+      --whitelist=          Add an IP network or IP that is exempt from the
+                            inbound connection rate limits and challenges
+      --asmap=              Path to a file mapping IP prefixes to the
+                            autonomous systems announcing them, used by
+                            getnetworktopology to report the distribution of
+                            peers over autonomous systems -- Each line holds a
+                            prefix in CIDR notation, an AS number and
+                            optionally the name of the AS
+      --maxclockskew=       Warn when the local clock differs from the median
+                            time of peers by more than this.  Valid time units
+                            are {s, m, h}.  Minimum 1 second (10m0s)
   -u, --rpcuser=            Username for RPC connections
   -P, --rpcpass=            Password for RPC connections
       --rpclimituser=       Username for limited RPC connections
       --rpclimitpass=       Password for limited RPC connections
       --rpclisten=          Add an interface/port to listen for RPC connections
                             (default port: 6684, testnet: 16684)
+      --rpcreadonlylisten=  Add an interface/port to listen for read-only RPC
+                            connections which may only call query methods
+      --rpcreadonlyuser=    Username for read-only RPC connections -- read-only
+                            connections require no authentication if unset
+      --rpcreadonlypass=    Password for read-only RPC connections
       --rpccert=            File containing the certificate file
       --rpckey=             File containing the certificate key
+      --rpcacmedomain=      Obtain the RPC TLS certificate for this domain from
+                            an ACME certificate authority such as Let's
+                            Encrypt and renew it before it expires -- may be
+                            specified multiple times for more domains
+      --rpcacmeemail=       Contact email address of the ACME account
+      --rpcacmedirectory=   Directory URL of the ACME certificate authority
+                            (https://acme-v02.api.letsencrypt.org/directory)
+      --rpcacmelisten=      Interface/port to serve the ACME http-01 challenges
+                            on while obtaining certificates -- must be
+                            reachable on port 80 of every domain (:80)
       --rpcmaxclients=      Max number of RPC clients for standard connections
                             (10)
       --rpcmaxwebsockets=   Max number of RPC websocket connections (25)
+      --rpcreadonlymaxclients= Max number of RPC clients for read-only
+                            connections, which are limited separately from the
+                            standard ones (50)
+      --rpcmaxconns=        Max number of open connections on the standard RPC
+                            listeners, including idle keep-alive and websocket
+                            connections -- 0 disables the limit (200)
+      --rpcreadonlymaxconns= Max number of open connections on the read-only
+                            RPC listeners, including idle keep-alive
+                            connections -- 0 disables the limit (200)
+      --rpcreadtimeout=     Max time RPC clients may take to send a request.
+                            Valid time units are {s, m, h} (10s)
+      --rpcwritetimeout=    How long RPC clients may take to receive a
+                            response, not including the time spent handling
+                            the request -- 0 disables the timeout.  Valid time
+                            units are {s, m, h}
+      --rpcidletimeout=     How long idle keep-alive connections to the RPC
+                            server are kept open -- 0 disables keep-alive.
+                            Valid time units are {s, m, h} (2m0s)
+      --rpcmaxheaderbytes=  Max size of the headers of RPC requests in bytes
+                            (65536)
+      --rpcnohttp2          Disable HTTP/2 on the TLS RPC listeners
+      --rpcmaxwatchedaddrs= Max number of addresses and script hashes each
+                            websocket client may watch, including those of its
+                            transaction filters -- 0 disables the limit
+                            (100000)
+      --rpcmaxwatchedoutpoints= Max number of outpoints each websocket client
+                            may watch, including those of its transaction
+                            filters -- 0 disables the limit (100000)
+      --rpcwsbatchbytes=    Combine websocket notifications waiting to be sent
+                            into frames of up to this many bytes holding a
+                            JSON array of them -- 0 sends every notification
+                            in its own frame
+      --rpcwsbatchinterval= How long websocket notifications are held back for
+                            more of them to join the same frame when batching
+                            is enabled.  Valid time units are {ms, s}
+      --rpcauthmaxfailures= Lock out RPC client addresses after this many
+                            failed authentication attempts over HTTP or
+                            websockets -- 0 disables the lockouts (5)
+      --rpcauthlockout=     How long RPC client addresses are locked out after
+                            failing to authenticate too often.  Every further
+                            lockout of the same address is twice as long, up
+                            to 24 hours.  Valid time units are {s, m, h} (1m0s)
+      --rpcauthfail2ban     Log failed RPC authentication attempts as 'Failed
+                            RPC authentication from <IP>' so they can be
+                            matched by fail2ban
+      --rpcratelimit=       Max number of RPC requests per second each client
+                            address may make -- 0 disables the limit
+      --rpcexpensiveratelimit= Max number of expensive RPC requests such as
+                            getblock, searchrawtransactions and rescan per
+                            second each client address may make -- 0 disables
+                            the limit
+      --rpcmaxexpensive=    Max number of expensive RPC requests served
+                            concurrently -- 0 disables the limit
+      --rpchelplocale=      Locale of the descriptions in the RPC help and API
+                            schema (en_US)
+      --rpcshutdowngrace=   How long in-flight RPC requests are given to
+                            complete and websocket clients to disconnect on
+                            shutdown.  Valid time units are {s, m, h} (5s)
+      --rpcalertwebhook=    POST the alerts broadcast with the broadcastalert
+                            RPC as JSON to the specified http or https URL
+                            (may be used multiple times)
+      --eventjournal=       Journal the block and transaction notification
+                            events of this many most recent blocks in the data
+                            directory so websocket clients can replay them -- 0
+                            disables the journal
+      --rest                Serve unauthenticated REST requests for blocks,
+                            headers, transactions, memory pool contents and
+                            chain info on the RPC listeners
       --norpc               Disable built-in RPC server -- NOTE: The RPC server
                             is disabled by default if no rpcuser/rpcpass or
                             rpclimituser/rpclimitpass is specified
@@ -68,10 +182,54 @@ Application Options:
       --testnet             Use the test network
       --regtest             Use the regression test network
       --simnet              Use the simulation test network
+      --customnet=          Use the custom network defined by the parameters
+                            in the given JSON file
       --nocheckpoints       Disable built-in checkpoints.  Don't do this unless
                             you know what you're doing.
+      --assumevalid=        Skip script validation for the ancestors of this
+                            block during the initial block download -- 0
+                            validates all scripts (default: the newest
+                            checkpoint of the selected network, if any)
+      --assumeutxo=         Bootstrap the node from the UTXO snapshot with this
+                            hash, as reported by dumputxoset, while the blocks
+                            before its base block are downloaded and validated
+                            in the background
+      --assumeutxofile=     Path to the UTXO snapshot used by assumeutxo --
+                            Relative paths are in the data directory
+                            (utxosnapshot.dat)
+      --headersonly         Only download and validate block headers without
+                            downloading or storing the blocks -- Useful for
+                            monitoring nodes which only track the chain tip
+                            and reorganizations
+      --blocknotify=        Execute the command when a block is connected to
+                            the main chain -- %s in the command is replaced by
+                            the block hash
+      --txnotify=           Execute the command when a transaction paying to
+                            one of the txnotifyaddr addresses is accepted to
+                            the memory pool or mined -- %s in the command is
+                            replaced by the transaction hash
+      --txnotifyaddr=       Add the specified address to the list of addresses
+                            whose transactions execute the txnotify command
+      --notifytimeout=      How long a blocknotify or txnotify command may run
+                            before it is killed.  Valid time units are {s, m,
+                            h}.  Minimum 1 second (1m0s)
       --dbtype=             Database backend to use for the Block Chain
                             (leveldb)
+      --checkblocks=        Number of most recent blocks to verify on start up
+                            -- The chain is rolled back to the last consistent
+                            block when corruption is detected -- 0 verifies
+                            all blocks (6)
+      --checklevel=         How thorough the start up verification is {0:
+                            block index, 1: block sanity, 2: transactions and
+                            spent outputs, 3: optional indexes} (3)
+      --compactwindow=      Compact the database once a day within this time
+                            window in local time, such as 02:00-04:00, to
+                            reclaim the space of deleted and overwritten data
+                            -- Compaction can also be started with the
+                            compactdatabase RPC
+      --loadblock=          Import blocks from the specified bootstrap file on
+                            start up -- Files placed in the blocks/import
+                            directory of the data directory are also imported
       --profile=            Enable HTTP profiling on given port -- NOTE port
                             must be between 1024 and 65536
       --cpuprofile=         Write CPU profile to the specified file
@@ -80,6 +238,9 @@ Application Options:
                             <subsystem>=<level>,<subsystem2>=<level>,... to set
                             the log level for individual subsystems -- Use show
                             to list available subsystems (info)
+      --logbuffer=          Number of recent log lines to keep in memory per
+                            subsystem for the getlog RPC -- 0 disables log
+                            capture (500)
       --upnp                Use UPnP to map our listening port outside of NAT
       --minrelaytxfee=      The minimum transaction fee in BTC/kB to be
                             considered a non-zero fee.
@@ -90,6 +251,47 @@ Application Options:
                             high priority for relaying
       --maxorphantx=        Max number of orphan transactions to keep in memory
                             (1000)
+      --mempoolexpiry=      Evict transactions which have not been mined within
+                            this many hours of entering the memory pool -- 0
+                            disables expiry (336)
+      --limitancestorcount= Do not accept transactions with more than this many
+                            unconfirmed ancestors in the memory pool, including
+                            the transaction itself -- 0 disables the limit (25)
+      --limitancestorsize=  Do not accept transactions whose unconfirmed
+                            ancestors in the memory pool, including the
+                            transaction itself, exceed this many thousands of
+                            virtual bytes -- 0 disables the limit (101)
+      --limitdescendantcount=
+                            Do not accept transactions which would give an
+                            unconfirmed transaction in the memory pool more
+                            than this many descendants, including itself -- 0
+                            disables the limit (25)
+      --limitdescendantsize=
+                            Do not accept transactions which would make the
+                            descendants of an unconfirmed transaction in the
+                            memory pool, including itself, exceed this many
+                            thousands of virtual bytes -- 0 disables the limit
+                            (101)
+      --dustrelayfee=       The fee rate in BTC/kB used to determine whether an
+                            output is dust -- Defaults to the minrelaytxfee
+                            option
+      --nodatacarrier       Do not relay or mine transactions with nulldata
+                            (OP_RETURN) outputs
+      --datacarriersize=    Maximum number of bytes a relayed or mined nulldata
+                            (OP_RETURN) output may carry (80)
+      --datacarriercount=   Maximum number of nulldata (OP_RETURN) outputs a
+                            relayed or mined transaction may have (1)
+      --rejectbaremultisig  Do not relay or mine transactions with outputs which
+                            pay to a multi-signature script directly
+      --maxsigopspertx=     Max number of signature operations in a relayed or
+                            mined transaction (4000)
+      --txblacklist=        Path to a file of banned transactions which are
+                            neither accepted into the memory pool nor relayed
+                            -- Each line holds a txid, an outpoint as
+                            <txid>:<index>, an output script as script:<hex>,
+                            a script prefix as script:<hex>* or an address as
+                            address:<address>.  Entries can be changed with the
+                            addfilterentry and removefilterentry RPCs
       --generate            Generate (mine) bitcoins using the CPU
       --miningaddr=         Add the specified payment address to the list of
                             addresses to use for generated blocks -- At least
@@ -101,14 +303,55 @@ Application Options:
                             a block (750000)
       --blockprioritysize=  Size in bytes for high-priority/low-fee transactions
                             when creating a block (50000)
+      --blockminfeerate=    The minimum fee rate in BTC/kB of transactions
+                            included in a block beyond the minimum block size
+                            and the high-priority area (1e-05)
+      --blockminpriority=   The minimum priority of transactions included in
+                            the high-priority area of a block (5.76e+07)
+      --blockrecenttxage=   Transactions seen more recently than this are
+                            weighted by blockrecenttxweight when ordering them
+                            by fee for a block -- 0 disables the weighting
+      --blockrecenttxweight= The factor, greater than 0 and at most 1, the fee
+                            rate of recent transactions is multiplied by when
+                            ordering transactions by fee for a block (1)
       --getworkkey=         DEPRECATED -- Use the --miningaddr option instead
       --addrindex           Build and maintain a full address index. Currently
                             only supported by leveldb.
       --dropaddrindex       Deletes the address-based transaction index from the
                             database on start up, and the exits.
+      --cfindex             Build and maintain a committed block filter index
+                            and serve the filters to light clients. Currently
+                            only supported by leveldb.
+      --dropcfindex         Deletes the committed block filter index from the
+                            database on start up, and then exits.
+      --coinageindex        Build and maintain an index of the coin days
+                            destroyed by each block. Currently only supported
+                            by leveldb.
+      --dropcoinageindex    Deletes the coin age index from the database on
+                            start up, and then exits.
+      --stxoindex           Build and maintain an index of the transaction
+                            input which spends each output. Currently only
+                            supported by leveldb.
+      --dropstxoindex       Deletes the spent output index from the database
+                            on start up, and then exits.
+      --addrbalanceindex    Build and maintain an index of the balance,
+                            unspent outputs and balance changes of each
+                            address. Currently only supported by leveldb.
+      --dropaddrbalanceindex  Deletes the address balance index from the
+                            database on start up, and then exits.
       --nopeerbloomfilters  Disable bloom filtering support.
       --sigcachemaxsize=    The maximum number of entries in the signature
                             verification cache.
+      --scriptmaxsteps=     Maximum number of opcodes executed to verify the
+                            scripts of a transaction input of a transaction
+                            submitted to the memory pool -- 0 disables the
+                            limit
+      --scripttimeout=      Maximum time spent executing the scripts of a
+                            transaction input of a transaction submitted to
+                            the memory pool -- Transactions exceeding it are
+                            rejected without being considered invalid.
+                            Blocks are never limited.  Valid time units are
+                            {ms, s} -- 0 disables the limit
 
 Help Options:
   -h, --help           Show this help message
@@ -0,0 +1,246 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+)
+
+// blockConnectedSubscriber is implemented by consumers of the event bus which
+// need to know when a block is connected to the main chain.
+type blockConnectedSubscriber interface {
+	BlockConnected(block *coinutil.Block)
+}
+
+// blockDisconnectedSubscriber is implemented by consumers of the event bus
+// which need to know when a block is disconnected from the main chain.
+type blockDisconnectedSubscriber interface {
+	BlockDisconnected(block *coinutil.Block)
+}
+
+// chainReorgSubscriber is implemented by consumers of the event bus which need
+// to know when the main chain has been reorganized.  The event is published
+// after the blocks involved have been disconnected and connected.
+type chainReorgSubscriber interface {
+	ChainReorganized(reorg *blockchain.ChainReorganization)
+}
+
+// headerConnectedSubscriber is implemented by consumers of the event bus which
+// need to know when a header is connected to the main chain of a node running
+// in headers-only mode.
+type headerConnectedSubscriber interface {
+	HeaderConnected(header *blockchain.HeaderInfo)
+}
+
+// headerDisconnectedSubscriber is implemented by consumers of the event bus
+// which need to know when a header is disconnected from the main chain of a
+// node running in headers-only mode.
+type headerDisconnectedSubscriber interface {
+	HeaderDisconnected(header *blockchain.HeaderInfo)
+}
+
+// mempoolTxSubscriber is implemented by consumers of the event bus which need
+// to know when a transaction is accepted into the memory pool.
+type mempoolTxSubscriber interface {
+	MempoolTxAccepted(tx *coinutil.Tx)
+}
+
+// mempoolTxExpiredSubscriber is implemented by consumers of the event bus which
+// need to know when a transaction is evicted from the memory pool because it
+// wasn't mined before the configured expiry.
+type mempoolTxExpiredSubscriber interface {
+	MempoolTxExpired(tx *coinutil.Tx)
+}
+
+// mempoolTxAddedSubscriber is implemented by consumers of the event bus which
+// need to know about every transaction added to the memory pool, including the
+// transactions returned to the pool when a block is disconnected.  The event is
+// published with the pool locked, so subscribers must not call back into it.
+type mempoolTxAddedSubscriber interface {
+	MempoolTxAdded(txDesc *mempoolTxDesc)
+}
+
+// mempoolTxRemovedSubscriber is implemented by consumers of the event bus which
+// need to know about every transaction removed from the memory pool along with
+// the reason.  The event is published with the pool locked, so subscribers must
+// not call back into it.
+type mempoolTxRemovedSubscriber interface {
+	MempoolTxRemoved(tx *coinutil.Tx, reason txRemovalReason)
+}
+
+// eventBus distributes the block and memory pool events of the node to the
+// features which consume them, such as the websocket notification manager and
+// the optional indexes, so the block manager and memory pool don't need to
+// know about each of them.
+//
+// Subscribers implement any of the subscriber interfaces above and receive
+// the events of each interface they implement in the order the events are
+// published.  Events are delivered synchronously from the goroutine which
+// publishes them, so subscribers must hand off any lengthy work instead of
+// blocking.
+type eventBus struct {
+	sync.RWMutex
+	blockConnected     []blockConnectedSubscriber
+	blockDisconnected  []blockDisconnectedSubscriber
+	chainReorg         []chainReorgSubscriber
+	headerConnected    []headerConnectedSubscriber
+	headerDisconnected []headerDisconnectedSubscriber
+	mempoolTx          []mempoolTxSubscriber
+	mempoolTxExpired   []mempoolTxExpiredSubscriber
+	mempoolTxAdded     []mempoolTxAddedSubscriber
+	mempoolTxRemoved   []mempoolTxRemovedSubscriber
+}
+
+// newEventBus returns a new event bus without any subscribers.
+func newEventBus() *eventBus {
+	return &eventBus{}
+}
+
+// Subscribe registers the passed subscriber for the events of each subscriber
+// interface it implements.  It returns false when the subscriber doesn't
+// implement any of them.
+func (b *eventBus) Subscribe(subscriber interface{}) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	subscribed := false
+	if s, ok := subscriber.(blockConnectedSubscriber); ok {
+		b.blockConnected = append(b.blockConnected, s)
+		subscribed = true
+	}
+	if s, ok := subscriber.(blockDisconnectedSubscriber); ok {
+		b.blockDisconnected = append(b.blockDisconnected, s)
+		subscribed = true
+	}
+	if s, ok := subscriber.(chainReorgSubscriber); ok {
+		b.chainReorg = append(b.chainReorg, s)
+		subscribed = true
+	}
+	if s, ok := subscriber.(headerConnectedSubscriber); ok {
+		b.headerConnected = append(b.headerConnected, s)
+		subscribed = true
+	}
+	if s, ok := subscriber.(headerDisconnectedSubscriber); ok {
+		b.headerDisconnected = append(b.headerDisconnected, s)
+		subscribed = true
+	}
+	if s, ok := subscriber.(mempoolTxSubscriber); ok {
+		b.mempoolTx = append(b.mempoolTx, s)
+		subscribed = true
+	}
+	if s, ok := subscriber.(mempoolTxExpiredSubscriber); ok {
+		b.mempoolTxExpired = append(b.mempoolTxExpired, s)
+		subscribed = true
+	}
+	if s, ok := subscriber.(mempoolTxAddedSubscriber); ok {
+		b.mempoolTxAdded = append(b.mempoolTxAdded, s)
+		subscribed = true
+	}
+	if s, ok := subscriber.(mempoolTxRemovedSubscriber); ok {
+		b.mempoolTxRemoved = append(b.mempoolTxRemoved, s)
+		subscribed = true
+	}
+	return subscribed
+}
+
+// PublishBlockConnected delivers a block connected event to the subscribers.
+func (b *eventBus) PublishBlockConnected(block *coinutil.Block) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.blockConnected {
+		s.BlockConnected(block)
+	}
+}
+
+// PublishBlockDisconnected delivers a block disconnected event to the
+// subscribers.
+func (b *eventBus) PublishBlockDisconnected(block *coinutil.Block) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.blockDisconnected {
+		s.BlockDisconnected(block)
+	}
+}
+
+// PublishChainReorganized delivers a chain reorganization event to the
+// subscribers.
+func (b *eventBus) PublishChainReorganized(reorg *blockchain.ChainReorganization) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.chainReorg {
+		s.ChainReorganized(reorg)
+	}
+}
+
+// PublishHeaderConnected delivers a header connected event to the subscribers.
+func (b *eventBus) PublishHeaderConnected(header *blockchain.HeaderInfo) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.headerConnected {
+		s.HeaderConnected(header)
+	}
+}
+
+// PublishHeaderDisconnected delivers a header disconnected event to the
+// subscribers.
+func (b *eventBus) PublishHeaderDisconnected(header *blockchain.HeaderInfo) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.headerDisconnected {
+		s.HeaderDisconnected(header)
+	}
+}
+
+// PublishMempoolTxAccepted delivers a memory pool acceptance event to the
+// subscribers.
+func (b *eventBus) PublishMempoolTxAccepted(tx *coinutil.Tx) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.mempoolTx {
+		s.MempoolTxAccepted(tx)
+	}
+}
+
+// PublishMempoolTxExpired delivers a memory pool expiry event to the
+// subscribers.
+func (b *eventBus) PublishMempoolTxExpired(tx *coinutil.Tx) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.mempoolTxExpired {
+		s.MempoolTxExpired(tx)
+	}
+}
+
+// PublishMempoolTxAdded delivers a memory pool addition event to the
+// subscribers.
+func (b *eventBus) PublishMempoolTxAdded(txDesc *mempoolTxDesc) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.mempoolTxAdded {
+		s.MempoolTxAdded(txDesc)
+	}
+}
+
+// PublishMempoolTxRemoved delivers a memory pool removal event to the
+// subscribers.
+func (b *eventBus) PublishMempoolTxRemoved(tx *coinutil.Tx, reason txRemovalReason) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, s := range b.mempoolTxRemoved {
+		s.MempoolTxRemoved(tx, reason)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/wire"
+)
+
+// blockEventRecorder records the block events it receives.
+type blockEventRecorder struct {
+	events *[]string
+	name   string
+}
+
+// BlockConnected records a block connected event.
+func (r *blockEventRecorder) BlockConnected(block *coinutil.Block) {
+	*r.events = append(*r.events, r.name+" connected")
+}
+
+// BlockDisconnected records a block disconnected event.
+func (r *blockEventRecorder) BlockDisconnected(block *coinutil.Block) {
+	*r.events = append(*r.events, r.name+" disconnected")
+}
+
+// allEventRecorder records every kind of event it receives.
+type allEventRecorder struct {
+	blockEventRecorder
+}
+
+// ChainReorganized records a chain reorganization event.
+func (r *allEventRecorder) ChainReorganized(reorg *blockchain.ChainReorganization) {
+	*r.events = append(*r.events, r.name+" reorganized")
+}
+
+// HeaderConnected records a header connected event.
+func (r *allEventRecorder) HeaderConnected(header *blockchain.HeaderInfo) {
+	*r.events = append(*r.events, r.name+" header connected")
+}
+
+// HeaderDisconnected records a header disconnected event.
+func (r *allEventRecorder) HeaderDisconnected(header *blockchain.HeaderInfo) {
+	*r.events = append(*r.events, r.name+" header disconnected")
+}
+
+// MempoolTxAccepted records a memory pool acceptance event.
+func (r *allEventRecorder) MempoolTxAccepted(tx *coinutil.Tx) {
+	*r.events = append(*r.events, r.name+" mempool")
+}
+
+// MempoolTxExpired records a memory pool expiry event.
+func (r *allEventRecorder) MempoolTxExpired(tx *coinutil.Tx) {
+	*r.events = append(*r.events, r.name+" expired")
+}
+
+// MempoolTxAdded records a memory pool addition event.
+func (r *allEventRecorder) MempoolTxAdded(txDesc *mempoolTxDesc) {
+	*r.events = append(*r.events, r.name+" added")
+}
+
+// MempoolTxRemoved records a memory pool removal event.
+func (r *allEventRecorder) MempoolTxRemoved(tx *coinutil.Tx, reason txRemovalReason) {
+	*r.events = append(*r.events, r.name+" removed "+reason.String())
+}
+
+// TestEventBus ensures the event bus delivers each event to the subscribers
+// implementing the matching interface in the order they subscribed.
+func TestEventBus(t *testing.T) {
+	bus := newEventBus()
+	var events []string
+
+	if bus.Subscribe(struct{}{}) {
+		t.Fatal("Subscribe: subscriber without any event methods was " +
+			"accepted")
+	}
+	if !bus.Subscribe(&blockEventRecorder{events: &events, name: "a"}) {
+		t.Fatal("Subscribe: block event subscriber was rejected")
+	}
+	if !bus.Subscribe(&allEventRecorder{blockEventRecorder{events: &events,
+		name: "b"}}) {
+
+		t.Fatal("Subscribe: subscriber of all events was rejected")
+	}
+
+	block := coinutil.NewBlock(&wire.MsgBlock{})
+	tx := coinutil.NewTx(&wire.MsgTx{})
+	bus.PublishBlockConnected(block)
+	bus.PublishBlockDisconnected(block)
+	bus.PublishChainReorganized(&blockchain.ChainReorganization{})
+	bus.PublishHeaderConnected(&blockchain.HeaderInfo{})
+	bus.PublishHeaderDisconnected(&blockchain.HeaderInfo{})
+	bus.PublishMempoolTxAccepted(tx)
+	bus.PublishMempoolTxExpired(tx)
+	bus.PublishMempoolTxAdded(&mempoolTxDesc{})
+	bus.PublishMempoolTxRemoved(tx, txRemovedConflict)
+
+	want := []string{
+		"a connected", "b connected",
+		"a disconnected", "b disconnected",
+		"b reorganized",
+		"b header connected",
+		"b header disconnected",
+		"b mempool",
+		"b expired",
+		"b added",
+		"b removed conflict",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events: got %v, want %v", events, want)
+	}
+}
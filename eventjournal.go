@@ -0,0 +1,293 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/wire"
+)
+
+// eventJournalFilename is the name of the file in the data directory which
+// holds the event journal.
+const eventJournalFilename = "events.journal"
+
+// journalRecordSize is the size of a serialized journal event.  Records have
+// a fixed size so that a record which was only partially written, for example
+// due to a crash, can be detected and discarded when the journal is loaded.
+//
+//	sequence (8) + type (1) + hash (32) + height (4) + time (8) + amount (8)
+const journalRecordSize = 8 + 1 + wire.HashSize + 4 + 8 + 8
+
+// journalEventType identifies the kind of event recorded in the journal.
+type journalEventType uint8
+
+// These constants define the kinds of events recorded in the journal.
+const (
+	journalBlockConnected journalEventType = iota + 1
+	journalBlockDisconnected
+	journalTxAccepted
+)
+
+// journalEventMethods maps journal event types to the method of the
+// notification which announces the event to websocket clients.
+var journalEventMethods = map[journalEventType]string{
+	journalBlockConnected:    btcjson.BlockConnectedNtfnMethod,
+	journalBlockDisconnected: btcjson.BlockDisconnectedNtfnMethod,
+	journalTxAccepted:        btcjson.TxAcceptedNtfnMethod,
+}
+
+// journalEvent is a single event of the journal.  For block events, hash,
+// height and time describe the block.  For transaction events, hash is the
+// transaction hash, time is the time it was accepted into the memory pool and
+// amount is the total value of its outputs.
+type journalEvent struct {
+	seq    uint64
+	typ    journalEventType
+	hash   wire.ShaHash
+	height int32
+	time   int64
+	amount int64
+}
+
+// serialize returns the fixed size serialization of the event.
+func (e *journalEvent) serialize() []byte {
+	var buf [journalRecordSize]byte
+	binary.LittleEndian.PutUint64(buf[0:8], e.seq)
+	buf[8] = byte(e.typ)
+	copy(buf[9:9+wire.HashSize], e.hash[:])
+	offset := 9 + wire.HashSize
+	binary.LittleEndian.PutUint32(buf[offset:offset+4], uint32(e.height))
+	binary.LittleEndian.PutUint64(buf[offset+4:offset+12], uint64(e.time))
+	binary.LittleEndian.PutUint64(buf[offset+12:offset+20], uint64(e.amount))
+	return buf[:]
+}
+
+// deserializeJournalEvent decodes a single serialized event.
+func deserializeJournalEvent(buf []byte) (journalEvent, error) {
+	var e journalEvent
+	e.seq = binary.LittleEndian.Uint64(buf[0:8])
+	e.typ = journalEventType(buf[8])
+	if _, ok := journalEventMethods[e.typ]; !ok {
+		return e, fmt.Errorf("journal event %d has unknown type %d",
+			e.seq, e.typ)
+	}
+	copy(e.hash[:], buf[9:9+wire.HashSize])
+	offset := 9 + wire.HashSize
+	e.height = int32(binary.LittleEndian.Uint32(buf[offset : offset+4]))
+	e.time = int64(binary.LittleEndian.Uint64(buf[offset+4 : offset+12]))
+	e.amount = int64(binary.LittleEndian.Uint64(buf[offset+12 : offset+20]))
+	return e, nil
+}
+
+// eventJournal is a rolling journal of the block and transaction events
+// announced to websocket clients.  Every event is assigned an increasing
+// sequence number and appended to a file in the data directory, so clients
+// that were disconnected, or a node that was restarted, can replay the events
+// they missed instead of rescanning the chain.
+//
+// Only the events since the retention'th most recent connected block are
+// kept.  Older events are dropped from memory immediately and from the file
+// whenever it has grown to more than twice the size of the retained events.
+type eventJournal struct {
+	sync.Mutex
+	path      string
+	file      *os.File
+	retention int
+	events    []journalEvent
+	nextSeq   uint64
+
+	// dropped is the number of records in the file which are no longer
+	// retained.
+	dropped int
+}
+
+// openEventJournal loads the journal stored at path, creating it if needed,
+// and returns it ready to record new events.
+func openEventJournal(path string, retention int) (*eventJournal, error) {
+	j := &eventJournal{
+		path:      path,
+		retention: retention,
+		nextSeq:   1,
+	}
+
+	serialized, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	numRecords := len(serialized) / journalRecordSize
+	for i := 0; i < numRecords; i++ {
+		offset := i * journalRecordSize
+		e, err := deserializeJournalEvent(
+			serialized[offset : offset+journalRecordSize])
+		if err != nil {
+			return nil, err
+		}
+		j.events = append(j.events, e)
+		j.nextSeq = e.seq + 1
+	}
+	j.trim()
+
+	// Rewrite the file when records were dropped or a partially written
+	// record must be discarded.  Otherwise simply append to it.
+	if j.dropped != 0 || len(serialized)%journalRecordSize != 0 {
+		if len(serialized)%journalRecordSize != 0 {
+			rpcsLog.Warnf("Discarding partially written event "+
+				"journal record in %s", path)
+		}
+		if err := j.compact(); err != nil {
+			return nil, err
+		}
+		return j, nil
+	}
+	j.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+		0600)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// trim drops all events before the retention'th most recent connected block.
+func (j *eventJournal) trim() {
+	connected := 0
+	for i := len(j.events) - 1; i >= 0; i-- {
+		if j.events[i].typ != journalBlockConnected {
+			continue
+		}
+		connected++
+		if connected == j.retention {
+			j.dropped += i
+			j.events = j.events[i:]
+			return
+		}
+	}
+}
+
+// compact rewrites the journal file so that it only holds the retained
+// events.  The new file is written next to the old one and renamed over it,
+// so a crash never leaves a truncated journal behind.
+func (j *eventJournal) compact() error {
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+
+	tmpPath := j.path + ".tmp"
+	serialized := make([]byte, 0, len(j.events)*journalRecordSize)
+	for i := range j.events {
+		serialized = append(serialized, j.events[i].serialize()...)
+	}
+	if err := ioutil.WriteFile(tmpPath, serialized, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return err
+	}
+	j.dropped = 0
+
+	var err error
+	j.file, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+		0600)
+	return err
+}
+
+// Record assigns the next sequence number to the passed event, appends it to
+// the journal and drops events which are no longer retained.
+//
+// This function is safe for concurrent access.
+func (j *eventJournal) Record(e journalEvent) error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.file == nil {
+		return fmt.Errorf("event journal %s is not open", j.path)
+	}
+	e.seq = j.nextSeq
+	j.nextSeq++
+	j.events = append(j.events, e)
+	if _, err := j.file.Write(e.serialize()); err != nil {
+		return err
+	}
+
+	j.trim()
+	if j.dropped > len(j.events) {
+		return j.compact()
+	}
+	return nil
+}
+
+// EventsSinceSeq returns all retained events with a sequence number greater
+// than the passed one along with the sequence number of the most recent
+// event.  An error is returned when events following seq have already been
+// dropped from the journal.
+//
+// This function is safe for concurrent access.
+func (j *eventJournal) EventsSinceSeq(seq uint64) ([]journalEvent, uint64, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	lastSeq := j.nextSeq - 1
+	if seq > lastSeq {
+		return nil, 0, fmt.Errorf("sequence number %d has not been "+
+			"journaled yet", seq)
+	}
+	first := j.nextSeq
+	if len(j.events) != 0 {
+		first = j.events[0].seq
+	}
+	if seq+1 < first {
+		return nil, 0, fmt.Errorf("events after sequence number %d "+
+			"are no longer journaled", seq)
+	}
+	return j.copyEvents(len(j.events) - int(lastSeq-seq)), lastSeq, nil
+}
+
+// EventsSinceBlock returns all retained events following the most recent
+// connection of the block with the passed hash along with the sequence number
+// of the most recent event.  An error is returned when no connection of the
+// block is retained.
+//
+// This function is safe for concurrent access.
+func (j *eventJournal) EventsSinceBlock(hash *wire.ShaHash) ([]journalEvent, uint64, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	for i := len(j.events) - 1; i >= 0; i-- {
+		e := &j.events[i]
+		if e.typ == journalBlockConnected && e.hash == *hash {
+			return j.copyEvents(i + 1), j.nextSeq - 1, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("block %v is not in the event journal", hash)
+}
+
+// copyEvents returns a copy of the retained events starting at the passed
+// index.  The journal lock must be held.
+func (j *eventJournal) copyEvents(start int) []journalEvent {
+	events := make([]journalEvent, len(j.events)-start)
+	copy(events, j.events[start:])
+	return events
+}
+
+// Close closes the journal file.
+//
+// This function is safe for concurrent access.
+func (j *eventJournal) Close() error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// journalBlockEvent returns a block connected event for a fake block at the
+// passed height.
+func journalBlockEvent(height int32) journalEvent {
+	var hash wire.ShaHash
+	hash[0] = byte(height)
+	return journalEvent{
+		typ:    journalBlockConnected,
+		hash:   hash,
+		height: height,
+		time:   int64(height) * 600,
+	}
+}
+
+// journalSeqs returns the sequence numbers of the passed events.
+func journalSeqs(events []journalEvent) []uint64 {
+	seqs := make([]uint64, 0, len(events))
+	for _, e := range events {
+		seqs = append(seqs, e.seq)
+	}
+	return seqs
+}
+
+// equalSeqs returns whether the two slices of sequence numbers are equal.
+func equalSeqs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestEventJournal ensures the event journal assigns sequence numbers, only
+// retains the events of the configured number of blocks, replays events since
+// a sequence number or block, and survives being reopened.
+func TestEventJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventjournal")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, eventJournalFilename)
+
+	journal, err := openEventJournal(path, 2)
+	if err != nil {
+		t.Fatalf("openEventJournal: %v", err)
+	}
+
+	// An empty journal is up to date as of sequence number 0.
+	events, lastSeq, err := journal.EventsSinceSeq(0)
+	if err != nil || len(events) != 0 || lastSeq != 0 {
+		t.Fatalf("EventsSinceSeq on empty journal: got %v, %d, %v",
+			events, lastSeq, err)
+	}
+
+	// Record three blocks with a transaction after each of them.  Only
+	// the events since the second most recent block are retained.
+	tx := journalEvent{typ: journalTxAccepted, time: 1, amount: 100}
+	for height := int32(1); height <= 3; height++ {
+		if err := journal.Record(journalBlockEvent(height)); err != nil {
+			t.Fatalf("Record block: %v", err)
+		}
+		if err := journal.Record(tx); err != nil {
+			t.Fatalf("Record tx: %v", err)
+		}
+	}
+
+	events, lastSeq, err = journal.EventsSinceSeq(3)
+	if err != nil {
+		t.Fatalf("EventsSinceSeq: %v", err)
+	}
+	if want := []uint64{4, 5, 6}; !equalSeqs(journalSeqs(events), want) ||
+		lastSeq != 6 {
+		t.Fatalf("EventsSinceSeq: got %v, last %d - want %v, last 6",
+			journalSeqs(events), lastSeq, want)
+	}
+	if _, _, err := journal.EventsSinceSeq(1); err == nil {
+		t.Fatalf("EventsSinceSeq: expected error for dropped events")
+	}
+	if _, _, err := journal.EventsSinceSeq(7); err == nil {
+		t.Fatalf("EventsSinceSeq: expected error for future sequence")
+	}
+
+	block3 := journalBlockEvent(3)
+	events, _, err = journal.EventsSinceBlock(&block3.hash)
+	if err != nil {
+		t.Fatalf("EventsSinceBlock: %v", err)
+	}
+	if want := []uint64{6}; !equalSeqs(journalSeqs(events), want) {
+		t.Fatalf("EventsSinceBlock: got %v - want %v",
+			journalSeqs(events), want)
+	}
+	block1 := journalBlockEvent(1)
+	if _, _, err := journal.EventsSinceBlock(&block1.hash); err == nil {
+		t.Fatalf("EventsSinceBlock: expected error for dropped block")
+	}
+
+	if err := journal.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Append a partially written record, which must be discarded when the
+	// journal is reopened, along with the events which are no longer
+	// retained.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	f.Write([]byte{0x07, 0x00, 0x00})
+	f.Close()
+
+	journal, err = openEventJournal(path, 2)
+	if err != nil {
+		t.Fatalf("openEventJournal after reopen: %v", err)
+	}
+	defer journal.Close()
+
+	events, lastSeq, err = journal.EventsSinceSeq(2)
+	if err != nil {
+		t.Fatalf("EventsSinceSeq after reopen: %v", err)
+	}
+	if want := []uint64{3, 4, 5, 6}; !equalSeqs(journalSeqs(events), want) ||
+		lastSeq != 6 {
+		t.Fatalf("EventsSinceSeq after reopen: got %v, last %d - "+
+			"want %v, last 6", journalSeqs(events), lastSeq, want)
+	}
+	if e := events[3]; e.typ != journalTxAccepted || e.amount != 100 {
+		t.Fatalf("EventsSinceSeq after reopen: unexpected event %+v", e)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.Size() != 4*journalRecordSize {
+		t.Fatalf("journal file size: got %d - want %d", fi.Size(),
+			4*journalRecordSize)
+	}
+
+	// Sequence numbers continue where they left off.
+	if err := journal.Record(journalBlockEvent(4)); err != nil {
+		t.Fatalf("Record after reopen: %v", err)
+	}
+	_, lastSeq, _ = journal.EventsSinceSeq(6)
+	if lastSeq != 7 {
+		t.Fatalf("Record after reopen: got last sequence %d - want 7",
+			lastSeq)
+	}
+}
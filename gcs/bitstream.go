@@ -0,0 +1,74 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import "io"
+
+// bitWriter accumulates individual bits, most significant bit first, into a
+// byte slice.
+type bitWriter struct {
+	bytes []byte
+	// used is the number of bits of the final byte which have been
+	// written.  A value of 8 (or an empty slice) means a new byte must be
+	// appended before the next bit is written.
+	used uint
+}
+
+// writeBit appends a single bit to the stream.
+func (w *bitWriter) writeBit(bit bool) {
+	if len(w.bytes) == 0 || w.used == 8 {
+		w.bytes = append(w.bytes, 0)
+		w.used = 0
+	}
+	if bit {
+		w.bytes[len(w.bytes)-1] |= 0x80 >> w.used
+	}
+	w.used++
+}
+
+// writeBits appends the count least significant bits of data to the stream,
+// most significant bit first.
+func (w *bitWriter) writeBits(data uint64, count uint) {
+	for count > 0 {
+		count--
+		w.writeBit(data&(1<<count) != 0)
+	}
+}
+
+// bitReader reads individual bits, most significant bit first, from a byte
+// slice.
+type bitReader struct {
+	bytes []byte
+	// pos is the index of the next bit to read.
+	pos uint
+}
+
+// readBit returns the next bit from the stream.  io.EOF is returned once all
+// of the bits have been consumed.
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos >= uint(len(r.bytes))*8 {
+		return false, io.EOF
+	}
+	bit := r.bytes[r.pos/8]&(0x80>>(r.pos%8)) != 0
+	r.pos++
+	return bit, nil
+}
+
+// readBits returns the next count bits from the stream as the least
+// significant bits of the returned value.
+func (r *bitReader) readBits(count uint) (uint64, error) {
+	var value uint64
+	for i := uint(0); i < count; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+	return value, nil
+}
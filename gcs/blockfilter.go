@@ -0,0 +1,83 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+const (
+	// DefaultP is the default collision probability (2^-19) used by basic
+	// block filters.
+	DefaultP = 19
+
+	// DefaultM is the default modulus multiplier used by basic block
+	// filters.  It is chosen to minimize the filter size for DefaultP.
+	DefaultM uint64 = 784931
+)
+
+// DeriveKey derives the SipHash key used for a block's filter from the first
+// KeySize bytes of its hash.
+func DeriveKey(blockHash *wire.ShaHash) [KeySize]byte {
+	var key [KeySize]byte
+	copy(key[:], blockHash[:KeySize])
+	return key
+}
+
+// BuildBasicFilter builds a basic filter for the passed block.  The filter
+// commits to every output script created by the block, other than empty and
+// data carrier (OP_RETURN) scripts, along with the script of every previous
+// output spent by the block.  prevScripts must contain the previous output
+// scripts for the inputs of all non-coinbase transactions in the block.
+func BuildBasicFilter(block *wire.MsgBlock, prevScripts [][]byte) (*Filter, error) {
+	blockHash := block.BlockSha()
+	key := DeriveKey(&blockHash)
+
+	// Each script is only added to the filter once.
+	seen := make(map[string]struct{})
+	var data [][]byte
+	addScript := func(script []byte) {
+		if len(script) == 0 {
+			return
+		}
+		if _, ok := seen[string(script)]; ok {
+			return
+		}
+		seen[string(script)] = struct{}{}
+		data = append(data, script)
+	}
+
+	for _, tx := range block.Transactions {
+		for _, txOut := range tx.TxOut {
+			if len(txOut.PkScript) > 0 &&
+				txOut.PkScript[0] == txscript.OP_RETURN {
+				continue
+			}
+			addScript(txOut.PkScript)
+		}
+	}
+	for _, script := range prevScripts {
+		addScript(script)
+	}
+
+	return BuildGCSFilter(DefaultP, DefaultM, key, data)
+}
+
+// FilterHash returns the double sha256 hash of the passed serialized filter.
+func FilterHash(nBytes []byte) wire.ShaHash {
+	return wire.DoubleSha256SH(nBytes)
+}
+
+// MakeHeaderForFilter returns the filter header for the passed serialized
+// filter which commits to the filter and the header of the filter for the
+// previous block.  The previous header for the genesis block is all zeros.
+func MakeHeaderForFilter(nBytes []byte, prevHeader *wire.ShaHash) wire.ShaHash {
+	filterHash := FilterHash(nBytes)
+	var buf [wire.HashSize * 2]byte
+	copy(buf[:wire.HashSize], filterHash[:])
+	copy(buf[wire.HashSize:], prevHeader[:])
+	return wire.DoubleSha256SH(buf[:])
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package gcs provides Golomb-coded set filters and the compact block filters
+built from them.
+
+A Golomb-coded set is a probabilistic data structure similar to a bloom filter
+which is smaller to store and relay at the cost of slower queries.  The basic
+block filter commits to the scripts created and spent by a block so light
+clients can determine whether a block is relevant to them without revealing
+their addresses to the full node serving the filter.  Filter headers chain
+each filter to the previous one so a client can verify filters received from
+untrusted peers against a header chain obtained from several of them.
+*/
+package gcs
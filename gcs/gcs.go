@@ -0,0 +1,325 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+)
+
+// KeySize is the size of the byte array required for key material for the
+// SipHash keyed hash function.
+const KeySize = 16
+
+var (
+	// ErrNTooBig signifies that the filter can't handle N items.
+	ErrNTooBig = errors.New("N is too big to fit in uint32")
+
+	// ErrPTooBig signifies that the filter can't handle `1/2**P`
+	// collision probability.
+	ErrPTooBig = errors.New("P is too big to fit in uint32")
+
+	// ErrMisserialized signifies a filter was misserialized and is
+	// missing its N value.
+	ErrMisserialized = errors.New("filter is missing its N value")
+)
+
+// Filter describes an immutable Golomb-coded set filter.  Items are hashed
+// into the range [0, N*M) with SipHash, sorted, and the differences between
+// consecutive values are stored Golomb-Rice coded with parameter P.
+type Filter struct {
+	n          uint32
+	p          uint8
+	modulusNM  uint64
+	filterData []byte
+}
+
+// mulHigh64 returns the high 64 bits of the 128-bit product of a and b.
+func mulHigh64(a, b uint64) uint64 {
+	aHi, aLo := a>>32, a&0xffffffff
+	bHi, bLo := b>>32, b&0xffffffff
+
+	loLo := aLo * bLo
+	hiLo := aHi * bLo
+	loHi := aLo * bHi
+	hiHi := aHi * bHi
+
+	cross := (loLo >> 32) + (hiLo & 0xffffffff) + loHi
+	return hiHi + (hiLo >> 32) + (cross >> 32)
+}
+
+// hashToRange hashes the passed data with SipHash using the given key and
+// maps the result uniformly into the range [0, modulus) without a division.
+func hashToRange(k0, k1 uint64, data []byte, modulus uint64) uint64 {
+	return mulHigh64(SipHash(k0, k1, data), modulus)
+}
+
+// splitKey returns the two little-endian 64-bit halves of the passed key.
+func splitKey(key [KeySize]byte) (uint64, uint64) {
+	return binary.LittleEndian.Uint64(key[0:8]),
+		binary.LittleEndian.Uint64(key[8:16])
+}
+
+// uint64Slice attaches the methods of sort.Interface to []uint64.
+type uint64Slice []uint64
+
+func (s uint64Slice) Len() int           { return len(s) }
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// BuildGCSFilter builds a new Golomb-coded set filter with the collision
+// probability of `1/(2**P)` and modulus multiplier M, using the given key and
+// data.  Duplicate entries in data should be removed by the caller since each
+// one is stored in the filter.
+func BuildGCSFilter(P uint8, M uint64, key [KeySize]byte, data [][]byte) (*Filter, error) {
+	if uint64(len(data)) > math.MaxUint32 {
+		return nil, ErrNTooBig
+	}
+	if P > 32 {
+		return nil, ErrPTooBig
+	}
+
+	f := Filter{
+		n: uint32(len(data)),
+		p: P,
+	}
+	f.modulusNM = uint64(f.n) * M
+
+	// Hash all of the items into the target range and sort them.
+	k0, k1 := splitKey(key)
+	values := make(uint64Slice, 0, len(data))
+	for _, d := range data {
+		values = append(values, hashToRange(k0, k1, d, f.modulusNM))
+	}
+	sort.Sort(values)
+
+	// Golomb-Rice code the differences between consecutive values.  The
+	// quotient is written in unary followed by the P-bit remainder.
+	var w bitWriter
+	var lastValue uint64
+	for _, v := range values {
+		delta := v - lastValue
+		lastValue = v
+
+		for q := delta >> f.p; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, uint(f.p))
+	}
+	f.filterData = w.bytes
+
+	return &f, nil
+}
+
+// FromBytes deserializes a GCS filter from a known N, P, M and the raw filter
+// data which does not include N.
+func FromBytes(N uint32, P uint8, M uint64, d []byte) (*Filter, error) {
+	if P > 32 {
+		return nil, ErrPTooBig
+	}
+
+	f := Filter{
+		n:         N,
+		p:         P,
+		modulusNM: uint64(N) * M,
+	}
+	f.filterData = make([]byte, len(d))
+	copy(f.filterData, d)
+
+	return &f, nil
+}
+
+// FromNBytes deserializes a GCS filter from a known P and M, and the
+// serialized filter as returned by NBytes.
+func FromNBytes(P uint8, M uint64, d []byte) (*Filter, error) {
+	n, size, err := readCompactSize(d)
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxUint32 {
+		return nil, ErrNTooBig
+	}
+
+	return FromBytes(uint32(n), P, M, d[size:])
+}
+
+// Bytes returns the serialized format of the GCS filter, which does not
+// include N.
+func (f *Filter) Bytes() []byte {
+	d := make([]byte, len(f.filterData))
+	copy(d, f.filterData)
+	return d
+}
+
+// NBytes returns the serialized format of the GCS filter with N prepended as
+// a variable length integer.  This is the format the filter is stored and
+// relayed in.
+func (f *Filter) NBytes() []byte {
+	d := putCompactSize(make([]byte, 0, 9+len(f.filterData)), uint64(f.n))
+	return append(d, f.filterData...)
+}
+
+// N returns the number of items in the filter.
+func (f *Filter) N() uint32 {
+	return f.n
+}
+
+// P returns the filter's collision probability as a negative power of 2.
+func (f *Filter) P() uint8 {
+	return f.p
+}
+
+// readFullUint64 reads the next Golomb-Rice coded delta from the stream.
+func (f *Filter) readFullUint64(r *bitReader) (uint64, error) {
+	var quotient uint64
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		quotient++
+	}
+
+	remainder, err := r.readBits(uint(f.p))
+	if err != nil {
+		return 0, err
+	}
+	return quotient<<f.p | remainder, nil
+}
+
+// Match checks whether a []byte value is likely (within collision
+// probability) to be a member of the set represented by the filter.
+func (f *Filter) Match(key [KeySize]byte, data []byte) (bool, error) {
+	if f.n == 0 {
+		return false, nil
+	}
+
+	k0, k1 := splitKey(key)
+	term := hashToRange(k0, k1, data, f.modulusNM)
+
+	r := bitReader{bytes: f.filterData}
+	var value uint64
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := f.readFullUint64(&r)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return false, err
+		}
+		value += delta
+
+		switch {
+		case value == term:
+			return true, nil
+		case value > term:
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// MatchAny checks whether any []byte value is likely (within collision
+// probability) to be a member of the set represented by the filter faster
+// than calling Match() for each value individually.
+func (f *Filter) MatchAny(key [KeySize]byte, data [][]byte) (bool, error) {
+	if f.n == 0 || len(data) == 0 {
+		return false, nil
+	}
+
+	k0, k1 := splitKey(key)
+	terms := make(uint64Slice, 0, len(data))
+	for _, d := range data {
+		terms = append(terms, hashToRange(k0, k1, d, f.modulusNM))
+	}
+	sort.Sort(terms)
+
+	// Walk both sorted sets in lockstep looking for a common value.
+	r := bitReader{bytes: f.filterData}
+	var value uint64
+	next := 0
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := f.readFullUint64(&r)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return false, err
+		}
+		value += delta
+
+		for next < len(terms) && terms[next] < value {
+			next++
+		}
+		if next == len(terms) {
+			return false, nil
+		}
+		if terms[next] == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// putCompactSize appends the bitcoin variable length integer encoding of val
+// to the passed slice.
+func putCompactSize(b []byte, val uint64) []byte {
+	switch {
+	case val < 0xfd:
+		return append(b, byte(val))
+	case val <= math.MaxUint16:
+		var buf [3]byte
+		buf[0] = 0xfd
+		binary.LittleEndian.PutUint16(buf[1:], uint16(val))
+		return append(b, buf[:]...)
+	case val <= math.MaxUint32:
+		var buf [5]byte
+		buf[0] = 0xfe
+		binary.LittleEndian.PutUint32(buf[1:], uint32(val))
+		return append(b, buf[:]...)
+	}
+	var buf [9]byte
+	buf[0] = 0xff
+	binary.LittleEndian.PutUint64(buf[1:], val)
+	return append(b, buf[:]...)
+}
+
+// readCompactSize decodes a bitcoin variable length integer from the start of
+// the passed slice and returns it along with the number of bytes it occupied.
+func readCompactSize(b []byte) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, ErrMisserialized
+	}
+
+	var size int
+	switch b[0] {
+	case 0xfd:
+		size = 3
+	case 0xfe:
+		size = 5
+	case 0xff:
+		size = 9
+	default:
+		return uint64(b[0]), 1, nil
+	}
+	if len(b) < size {
+		return 0, 0, ErrMisserialized
+	}
+
+	switch size {
+	case 3:
+		return uint64(binary.LittleEndian.Uint16(b[1:])), size, nil
+	case 5:
+		return uint64(binary.LittleEndian.Uint32(b[1:])), size, nil
+	}
+	return binary.LittleEndian.Uint64(b[1:]), size, nil
+}
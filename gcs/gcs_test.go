@@ -0,0 +1,257 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/conseweb/stcd/gcs"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestSipHash ensures SipHash-2-4 produces the reference test vectors.
+func TestSipHash(t *testing.T) {
+	var key [16]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+
+	tests := []struct {
+		size int
+		want uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{15, 0xa129ca6149be45e5},
+	}
+
+	for i, test := range tests {
+		data := make([]byte, test.size)
+		for j := range data {
+			data[j] = byte(j)
+		}
+		got := gcs.SipHash(k0, k1, data)
+		if got != test.want {
+			t.Errorf("SipHash #%d: got %x want %x", i, got, test.want)
+		}
+	}
+}
+
+// TestGCSFilter ensures filters match all of their members, round trip
+// through serialization, and reject obvious non-members.
+func TestGCSFilter(t *testing.T) {
+	var key [gcs.KeySize]byte
+	copy(key[:], "gcs filter tests")
+
+	var contents [][]byte
+	for i := 0; i < 500; i++ {
+		contents = append(contents, []byte{byte(i), byte(i >> 8), 0xaa})
+	}
+
+	filter, err := gcs.BuildGCSFilter(gcs.DefaultP, gcs.DefaultM, key,
+		contents)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+	}
+	if filter.N() != uint32(len(contents)) {
+		t.Fatalf("N: got %d want %d", filter.N(), len(contents))
+	}
+
+	for i, item := range contents {
+		match, err := filter.Match(key, item)
+		if err != nil {
+			t.Fatalf("Match #%d: unexpected error: %v", i, err)
+		}
+		if !match {
+			t.Fatalf("Match #%d: filter did not match member", i)
+		}
+	}
+
+	// With a false positive rate of 2^-19 none of these should match.
+	nonMembers := [][]byte{
+		[]byte("not a member"),
+		[]byte("also not a member"),
+	}
+	for i, item := range nonMembers {
+		match, err := filter.Match(key, item)
+		if err != nil {
+			t.Fatalf("Match non-member #%d: unexpected error: %v",
+				i, err)
+		}
+		if match {
+			t.Errorf("Match non-member #%d: unexpected match", i)
+		}
+	}
+	match, err := filter.MatchAny(key, nonMembers)
+	if err != nil || match {
+		t.Errorf("MatchAny non-members: got %v, %v", match, err)
+	}
+	match, err = filter.MatchAny(key, append(nonMembers, contents[250]))
+	if err != nil || !match {
+		t.Errorf("MatchAny with member: got %v, %v", match, err)
+	}
+
+	// Round trip through the serialized form.
+	filter2, err := gcs.FromNBytes(gcs.DefaultP, gcs.DefaultM,
+		filter.NBytes())
+	if err != nil {
+		t.Fatalf("FromNBytes: unexpected error: %v", err)
+	}
+	if !bytes.Equal(filter.NBytes(), filter2.NBytes()) {
+		t.Fatalf("FromNBytes: serialized filters do not match")
+	}
+	match, err = filter2.Match(key, contents[499])
+	if err != nil || !match {
+		t.Errorf("Match after round trip: got %v, %v", match, err)
+	}
+
+	// A truncated filter must not be mistaken for a valid one.
+	truncated, err := gcs.FromNBytes(gcs.DefaultP, gcs.DefaultM,
+		filter.NBytes()[:10])
+	if err != nil {
+		t.Fatalf("FromNBytes truncated: unexpected error: %v", err)
+	}
+	if _, err := truncated.Match(key, nonMembers[0]); err == nil {
+		t.Errorf("Match truncated: expected error")
+	}
+}
+
+// TestEmptyFilter ensures an empty filter serializes to a single zero byte
+// and matches nothing.
+func TestEmptyFilter(t *testing.T) {
+	var key [gcs.KeySize]byte
+	filter, err := gcs.BuildGCSFilter(gcs.DefaultP, gcs.DefaultM, key, nil)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+	}
+	if !bytes.Equal(filter.NBytes(), []byte{0x00}) {
+		t.Errorf("NBytes: got %x want 00", filter.NBytes())
+	}
+	match, err := filter.Match(key, []byte("anything"))
+	if err != nil || match {
+		t.Errorf("Match: got %v, %v", match, err)
+	}
+}
+
+// TestBasicFilter ensures the basic filter for a block commits to its output
+// scripts and the previous output scripts it spends while skipping data
+// carrier outputs, and that filter headers chain as expected.
+func TestBasicFilter(t *testing.T) {
+	outScript := []byte{0x76, 0xa9, 0x14, 0x01, 0x88, 0xac}
+	dataScript := []byte{0x6a, 0x04, 0xde, 0xad, 0xbe, 0xef}
+	prevScript := []byte{0xa9, 0x14, 0x02, 0x87}
+
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff}, nil))
+	tx.AddTxOut(wire.NewTxOut(5000000000, outScript))
+	tx.AddTxOut(wire.NewTxOut(0, dataScript))
+
+	block := wire.NewMsgBlock(&wire.BlockHeader{Nonce: 1})
+	block.AddTransaction(tx)
+
+	filter, err := gcs.BuildBasicFilter(block, [][]byte{prevScript})
+	if err != nil {
+		t.Fatalf("BuildBasicFilter: unexpected error: %v", err)
+	}
+	if filter.N() != 2 {
+		t.Fatalf("N: got %d want 2", filter.N())
+	}
+
+	blockHash := block.BlockSha()
+	key := gcs.DeriveKey(&blockHash)
+	for _, script := range [][]byte{outScript, prevScript} {
+		match, err := filter.Match(key, script)
+		if err != nil || !match {
+			t.Errorf("Match %x: got %v, %v", script, match, err)
+		}
+	}
+	match, err := filter.Match(key, dataScript)
+	if err != nil || match {
+		t.Errorf("Match data script: got %v, %v", match, err)
+	}
+
+	var zeroHash wire.ShaHash
+	header := gcs.MakeHeaderForFilter(filter.NBytes(), &zeroHash)
+	filterHash := gcs.FilterHash(filter.NBytes())
+	want := wire.DoubleSha256SH(append(filterHash[:], zeroHash[:]...))
+	if header != want {
+		t.Errorf("MakeHeaderForFilter: got %v want %v", header, want)
+	}
+}
+
+// TestBIP158Vectors ensures basic filters and their headers match the test
+// vectors of BIP158 so the filters served to light clients are compatible with
+// other implementations.
+func TestBIP158Vectors(t *testing.T) {
+	tests := []struct {
+		name        string
+		blockHash   string
+		block       string
+		prevScripts []string
+		prevHeader  string
+		filter      string
+		header      string
+	}{
+		{
+			name:       "testnet genesis block",
+			blockHash:  "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943",
+			block:      "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4adae5494dffff001d1aa4ae180101000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000",
+			prevHeader: "0000000000000000000000000000000000000000000000000000000000000000",
+			filter:     "019dfca8",
+			header:     "21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750",
+		},
+	}
+
+	for _, test := range tests {
+		blockBytes, err := hex.DecodeString(test.block)
+		if err != nil {
+			t.Fatalf("%s: invalid block hex: %v", test.name, err)
+		}
+		var block wire.MsgBlock
+		if err := block.Deserialize(bytes.NewReader(blockBytes)); err != nil {
+			t.Fatalf("%s: Deserialize: %v", test.name, err)
+		}
+		blockHash := block.BlockSha()
+		if blockHash.String() != test.blockHash {
+			t.Fatalf("%s: block hash: got %v want %v", test.name,
+				blockHash, test.blockHash)
+		}
+		var prevScripts [][]byte
+		for _, s := range test.prevScripts {
+			script, err := hex.DecodeString(s)
+			if err != nil {
+				t.Fatalf("%s: invalid script hex: %v", test.name,
+					err)
+			}
+			prevScripts = append(prevScripts, script)
+		}
+
+		filter, err := gcs.BuildBasicFilter(&block, prevScripts)
+		if err != nil {
+			t.Fatalf("%s: BuildBasicFilter: %v", test.name, err)
+		}
+		nBytes := filter.NBytes()
+		if hex.EncodeToString(nBytes) != test.filter {
+			t.Errorf("%s: filter: got %x want %s", test.name, nBytes,
+				test.filter)
+		}
+
+		// Filter headers are displayed in the byte-reversed order of
+		// hashes.
+		prevHeader, err := wire.NewShaHashFromStr(test.prevHeader)
+		if err != nil {
+			t.Fatalf("%s: invalid previous header: %v", test.name, err)
+		}
+		header := gcs.MakeHeaderForFilter(nBytes, prevHeader)
+		if header.String() != test.header {
+			t.Errorf("%s: header: got %v want %s", test.name, header,
+				test.header)
+		}
+	}
+}
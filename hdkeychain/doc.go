@@ -0,0 +1,35 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package hdkeychain provides an API for hierarchical deterministic extended keys
+as defined by BIP0032.
+
+An extended key is a private or public key along with a chain code which
+allows child keys to be derived from it.  A master node is created from a seed
+with NewMaster and children are derived from it with Child.  Child indices at
+or above HardenedKeyStart produce hardened keys which can only be derived from
+a private extended key.
+
+The version bytes used when serializing extended keys are taken from the
+HDPrivateKeyID and HDPublicKeyID fields of the chaincfg.Params of the network
+the key is for, so keys for any registered network, including custom ones,
+can be encoded and decoded.
+
+Watch-Only Monitoring
+
+A public extended key, obtained from a private one with Neuter, can derive
+every non-hardened child public key and therefore every address of an account
+without access to any private key material.  This makes it possible to
+generate a batch of addresses for an account, register them with a node via
+the notifyreceived websocket command, and be notified of payments to them:
+
+	acct, _ := hdkeychain.NewKeyFromString(xpub)
+	for i := uint32(0); i < 20; i++ {
+		child, _ := acct.Child(i)
+		addr, _ := child.Address(&chaincfg.MainNetParams)
+		addrs = append(addrs, addr.EncodeAddress())
+	}
+*/
+package hdkeychain
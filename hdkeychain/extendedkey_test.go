@@ -0,0 +1,235 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/hdkeychain"
+)
+
+// TestBIP0032Vectors tests the vectors provided by [BIP32] to ensure the
+// derivation works as intended.
+func TestBIP0032Vectors(t *testing.T) {
+	// The master seed used for the first test vector.
+	hkStart := uint32(hdkeychain.HardenedKeyStart)
+	testVec1MasterHex := "000102030405060708090a0b0c0d0e0f"
+
+	tests := []struct {
+		name     string
+		master   string
+		path     []uint32
+		wantPub  string
+		wantPriv string
+	}{
+		{
+			name:     "test vector 1 chain m",
+			master:   testVec1MasterHex,
+			path:     []uint32{},
+			wantPub:  "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+			wantPriv: "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+		},
+		{
+			name:     "test vector 1 chain m/0H",
+			master:   testVec1MasterHex,
+			path:     []uint32{hkStart},
+			wantPub:  "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+			wantPriv: "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+		},
+		{
+			name:     "test vector 1 chain m/0H/1",
+			master:   testVec1MasterHex,
+			path:     []uint32{hkStart, 1},
+			wantPub:  "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+			wantPriv: "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+		},
+	}
+
+	for i, test := range tests {
+		masterSeed, err := hex.DecodeString(test.master)
+		if err != nil {
+			t.Errorf("DecodeString #%d (%s): unexpected error: %v",
+				i, test.name, err)
+			continue
+		}
+
+		extKey, err := hdkeychain.NewMaster(masterSeed,
+			&chaincfg.MainNetParams)
+		if err != nil {
+			t.Errorf("NewMaster #%d (%s): unexpected error when "+
+				"creating new master key: %v", i, test.name,
+				err)
+			continue
+		}
+
+		for _, childNum := range test.path {
+			var err error
+			extKey, err = extKey.Child(childNum)
+			if err != nil {
+				t.Errorf("err: %v", err)
+				break
+			}
+		}
+		if extKey == nil {
+			continue
+		}
+
+		privStr := extKey.String()
+		if privStr != test.wantPriv {
+			t.Errorf("Serialize #%d (%s): mismatched serialized "+
+				"private extended key -- got: %s, want: %s", i,
+				test.name, privStr, test.wantPriv)
+			continue
+		}
+
+		pubKey, err := extKey.Neuter()
+		if err != nil {
+			t.Errorf("Neuter #%d (%s): unexpected error: %v ", i,
+				test.name, err)
+			continue
+		}
+
+		pubStr := pubKey.String()
+		if pubStr != test.wantPub {
+			t.Errorf("Neuter #%d (%s): mismatched serialized "+
+				"public extended key -- got: %s, want: %s", i,
+				test.name, pubStr, test.wantPub)
+			continue
+		}
+	}
+}
+
+// TestPublicDerivation ensures non-hardened child public keys derived from a
+// public extended key match the public keys of the children derived from the
+// corresponding private extended key, which is what allows a watch-only
+// client to derive the addresses of an account.
+func TestPublicDerivation(t *testing.T) {
+	seed := make([]byte, hdkeychain.RecommendedSeedLen)
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.SimNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	acctPub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+
+	for i := uint32(0); i < 5; i++ {
+		privChild, err := master.Child(i)
+		if err != nil {
+			t.Fatalf("Child #%d (private): unexpected error: %v", i,
+				err)
+		}
+		pubChild, err := acctPub.Child(i)
+		if err != nil {
+			t.Fatalf("Child #%d (public): unexpected error: %v", i,
+				err)
+		}
+		if pubChild.IsPrivate() {
+			t.Fatalf("Child #%d: child of public key is private", i)
+		}
+
+		privAddr, err := privChild.Address(&chaincfg.SimNetParams)
+		if err != nil {
+			t.Fatalf("Address #%d (private): unexpected error: %v",
+				i, err)
+		}
+		pubAddr, err := pubChild.Address(&chaincfg.SimNetParams)
+		if err != nil {
+			t.Fatalf("Address #%d (public): unexpected error: %v",
+				i, err)
+		}
+		if privAddr.EncodeAddress() != pubAddr.EncodeAddress() {
+			t.Errorf("Address #%d: mismatched addresses -- got %s, "+
+				"want %s", i, pubAddr.EncodeAddress(),
+				privAddr.EncodeAddress())
+		}
+	}
+
+	// Hardened children can't be derived from a public key.
+	_, err = acctPub.Child(hdkeychain.HardenedKeyStart)
+	if err != hdkeychain.ErrDeriveHardFromPublic {
+		t.Errorf("Child (hardened from public): got error %v, want %v",
+			err, hdkeychain.ErrDeriveHardFromPublic)
+	}
+}
+
+// TestNetworkVersions ensures extended keys are serialized with the HD version
+// bytes of their network and round trip through NewKeyFromString.
+func TestNetworkVersions(t *testing.T) {
+	seed := make([]byte, hdkeychain.RecommendedSeedLen)
+	seed[0] = 0x01
+
+	tests := []struct {
+		name       string
+		net        *chaincfg.Params
+		privPrefix string
+		pubPrefix  string
+	}{
+		{"mainnet", &chaincfg.MainNetParams, "xprv", "xpub"},
+		{"testnet3", &chaincfg.TestNet3Params, "tprv", "tpub"},
+		{"simnet", &chaincfg.SimNetParams, "sprv", "spub"},
+	}
+
+	for _, test := range tests {
+		priv, err := hdkeychain.NewMaster(seed, test.net)
+		if err != nil {
+			t.Errorf("NewMaster (%s): unexpected error: %v",
+				test.name, err)
+			continue
+		}
+		pub, err := priv.Neuter()
+		if err != nil {
+			t.Errorf("Neuter (%s): unexpected error: %v", test.name,
+				err)
+			continue
+		}
+
+		for _, key := range []*hdkeychain.ExtendedKey{priv, pub} {
+			prefix := test.pubPrefix
+			if key.IsPrivate() {
+				prefix = test.privPrefix
+			}
+			str := key.String()
+			if str[:4] != prefix {
+				t.Errorf("String (%s): got prefix %s, want %s",
+					test.name, str[:4], prefix)
+			}
+			if !key.IsForNet(test.net) {
+				t.Errorf("IsForNet (%s): key is not for its "+
+					"network", test.name)
+			}
+
+			decoded, err := hdkeychain.NewKeyFromString(str)
+			if err != nil {
+				t.Errorf("NewKeyFromString (%s): unexpected "+
+					"error: %v", test.name, err)
+				continue
+			}
+			if decoded.String() != str {
+				t.Errorf("NewKeyFromString (%s): round trip "+
+					"mismatch -- got %s, want %s", test.name,
+					decoded.String(), str)
+			}
+		}
+	}
+
+	// A corrupted key must fail the checksum.
+	priv, _ := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	str := []byte(priv.String())
+	if str[len(str)-1] == 'a' {
+		str[len(str)-1] = 'b'
+	} else {
+		str[len(str)-1] = 'a'
+	}
+	if _, err := hdkeychain.NewKeyFromString(string(str)); err !=
+		hdkeychain.ErrBadChecksum {
+
+		t.Errorf("NewKeyFromString (corrupted): got error %v, want %v",
+			err, hdkeychain.ErrBadChecksum)
+	}
+}
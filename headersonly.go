@@ -0,0 +1,452 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/wire"
+)
+
+// headersFileName is the name of the file in the data directory which holds
+// the block headers accepted by a node running in headers-only mode.  It is a
+// sequence of serialized headers in the order they were accepted, so every
+// header follows its parent.
+const headersFileName = "headers.dat"
+
+// loadHeaders adds the block headers stored in the headers file to the header
+// chain and opens the file for appending the headers accepted later.  Headers
+// which can't be read or added, such as a header that was only partially
+// written when the node stopped, are discarded along with all of the headers
+// after them.
+func (b *blockManager) loadHeaders() error {
+	path := filepath.Join(cfg.DataDir, headersFileName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		var header wire.BlockHeader
+		err := header.Deserialize(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			bmgrLog.Warnf("Discarding truncated block header at "+
+				"offset %d of %s", offset, path)
+			break
+		}
+
+		// The headers were validated before they were stored.
+		_, err = b.headerChain.ProcessHeader(&header,
+			b.server.timeSource, blockchain.BFFastAdd)
+		if err != nil {
+			bmgrLog.Warnf("Discarding block headers from %s "+
+				"starting with %v: %v", path,
+				header.BlockSha(), err)
+			break
+		}
+		offset += wire.MaxBlockHeaderPayload
+	}
+
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(offset, os.SEEK_SET); err != nil {
+		f.Close()
+		return err
+	}
+	b.headersFile = f
+
+	hash, height := b.headerChain.BestHeader()
+	bmgrLog.Infof("Loaded block headers up to height %d (hash %v)",
+		height, hash)
+	return nil
+}
+
+// storeHeader appends the passed header to the headers file.
+func (b *blockManager) storeHeader(header *wire.BlockHeader) error {
+	return header.Serialize(b.headersFile)
+}
+
+// closeHeaders closes the headers file when running in headers-only mode.  It
+// must only be called from the block handler.
+func (b *blockManager) closeHeaders() {
+	if b.headersFile == nil {
+		return
+	}
+	if err := b.headersFile.Close(); err != nil {
+		bmgrLog.Errorf("Failed to close headers file: %v", err)
+	}
+}
+
+// headersCurrent returns true if the header chain is believed to be synced
+// with the peers.  It is the headers-only counterpart of current.
+func (b *blockManager) headersCurrent() bool {
+	if !b.headerChain.IsCurrent(b.server.timeSource) {
+		return false
+	}
+
+	// No matter what the header chain thinks, it is not current when it
+	// is behind the sync peer.
+	if b.syncPeer == nil {
+		return true
+	}
+	_, height := b.headerChain.BestHeader()
+	return height >= b.syncPeer.LastBlock()
+}
+
+// handleHeadersOnlyMsg handles headers messages from all peers when running
+// in headers-only mode.  The headers are added to the header chain and the
+// next batch of headers is requested when the message was full.
+func (b *blockManager) handleHeadersOnlyMsg(hmsg *headersMsg) {
+	msg := hmsg.headers
+	numHeaders := len(msg.Headers)
+	if numHeaders == 0 {
+		return
+	}
+
+	for _, header := range msg.Headers {
+		_, err := b.headerChain.ProcessHeader(header,
+			b.server.timeSource, blockchain.BFNone)
+		if err != nil {
+			rerr, ok := err.(blockchain.RuleError)
+			if ok && rerr.ErrorCode == blockchain.ErrDuplicateBlock {
+				continue
+			}
+
+			// Headers which don't connect are most likely the
+			// answer to an announcement of a block which extends
+			// headers we don't know about yet, so ask for the
+			// missing headers instead of treating it as
+			// misbehavior.
+			if ok && rerr.ErrorCode == blockchain.ErrPrevBlockNotFound {
+				locator := b.headerChain.LatestBlockLocator()
+				hmsg.peer.PushGetHeadersMsg(locator, &zeroHash)
+				return
+			}
+
+			bmgrLog.Warnf("Rejected block header %v from %s: %v "+
+				"-- disconnecting", header.BlockSha(),
+				hmsg.peer.Addr(), err)
+			if ok {
+				hmsg.peer.addBanScore(misbehaviorInvalidBlock,
+					fmt.Sprintf("invalid header %v: %v",
+						header.BlockSha(), err))
+			}
+			hmsg.peer.Disconnect()
+			return
+		}
+
+		if err := b.storeHeader(header); err != nil {
+			bmgrLog.Errorf("Failed to store block header %v: %v",
+				header.BlockSha(), err)
+		}
+	}
+
+	// The peer has at least the final header of the message.
+	finalHash := msg.Headers[numHeaders-1].BlockSha()
+	_, height, _, err := b.headerChain.HeaderByHash(&finalHash)
+	if err == nil && height > hmsg.peer.LastBlock() {
+		hmsg.peer.UpdateLastBlockHeight(height)
+	}
+	b.updateInitialBlockDownload()
+
+	// A full message means the peer most likely has more headers, so
+	// request the next batch starting from the final one.
+	if numHeaders == wire.MaxBlockHeadersPerMsg {
+		locator := blockchain.BlockLocator([]*wire.ShaHash{&finalHash})
+		err := hmsg.peer.PushGetHeadersMsg(locator, &zeroHash)
+		if err != nil {
+			bmgrLog.Warnf("Failed to send getheaders message to "+
+				"peer %s: %v", hmsg.peer.Addr(), err)
+		}
+	}
+}
+
+// handleHeadersOnlyInv handles inv messages from all peers when running in
+// headers-only mode.  Transactions are ignored and the headers of announced
+// blocks which aren't known yet are requested.
+func (b *blockManager) handleHeadersOnlyInv(imsg *invMsg) {
+	// Attempt to find the final block in the inventory list.  There may
+	// not be one.
+	invVects := imsg.inv.InvList
+	lastBlock := -1
+	for i, iv := range invVects {
+		if iv.Type == wire.InvTypeBlock {
+			imsg.peer.AddKnownInventory(iv)
+			lastBlock = i
+		}
+	}
+	if lastBlock == -1 {
+		return
+	}
+
+	// Update the height of the peer when the announced block is known
+	// already.
+	hash := &invVects[lastBlock].Hash
+	_, height, _, err := b.headerChain.HeaderByHash(hash)
+	if err == nil {
+		if height > imsg.peer.LastBlock() {
+			imsg.peer.UpdateLastBlockHeight(height)
+		}
+		return
+	}
+	imsg.peer.UpdateLastAnnouncedBlock(hash)
+
+	// Ignore announcements from peers that aren't the sync peer if we are
+	// not current.
+	if imsg.peer != b.syncPeer && !b.current() {
+		return
+	}
+
+	locator := b.headerChain.LatestBlockLocator()
+	err = imsg.peer.PushGetHeadersMsg(locator, hash)
+	if err != nil {
+		bmgrLog.Warnf("Failed to send getheaders message to peer %s: "+
+			"%v", imsg.peer.Addr(), err)
+	}
+}
+
+// handleHeaderNotifyMsg handles notifications from the header chain of a node
+// running in headers-only mode.  The notifications sent while the stored
+// headers are loaded at startup are ignored.
+func (b *blockManager) handleHeaderNotifyMsg(notification *blockchain.Notification) {
+	if atomic.LoadInt32(&b.started) == 0 {
+		return
+	}
+
+	switch notification.Type {
+	// A header has been connected to the main chain.
+	case blockchain.NTHeaderConnected:
+		header, ok := notification.Data.(*blockchain.HeaderInfo)
+		if !ok {
+			bmgrLog.Warnf("Header connected notification is not a " +
+				"header.")
+			break
+		}
+		b.server.events.PublishHeaderConnected(header)
+
+	// A header has been disconnected from the main chain.
+	case blockchain.NTHeaderDisconnected:
+		header, ok := notification.Data.(*blockchain.HeaderInfo)
+		if !ok {
+			bmgrLog.Warnf("Header disconnected notification is not " +
+				"a header.")
+			break
+		}
+		b.server.events.PublishHeaderDisconnected(header)
+
+	// The main chain has been reorganized.
+	case blockchain.NTChainReorganized:
+		reorg, ok := notification.Data.(*blockchain.ChainReorganization)
+		if !ok {
+			bmgrLog.Warnf("Chain reorganized notification is not a " +
+				"reorganization.")
+			break
+		}
+		b.server.events.PublishChainReorganized(reorg)
+	}
+}
+
+// rpcHeadersOnlyHandlers replaces the handlers of the RPC commands about the
+// best chain when running in headers-only mode since the block database only
+// contains the genesis block.  The replacements serve the same results from
+// the header chain instead.
+var rpcHeadersOnlyHandlers = map[string]commandHandler{
+	"getbestblock":      handleHeadersOnlyGetBestBlock,
+	"getbestblockhash":  handleHeadersOnlyGetBestBlockHash,
+	"getblockchaininfo": handleHeadersOnlyGetBlockChainInfo,
+	"getblockcount":     handleHeadersOnlyGetBlockCount,
+	"getblockhash":      handleHeadersOnlyGetBlockHash,
+	"getblockheader":    handleHeadersOnlyGetBlockHeader,
+}
+
+// headerMedianTime returns the median time of the passed header and the
+// headers before it, looking up the previous headers in the passed header
+// chain.
+func headerMedianTime(chain *blockchain.HeaderChain, header *wire.BlockHeader, height int32) (time.Time, error) {
+	fetchHeader := func(hash *wire.ShaHash) (*wire.BlockHeader, error) {
+		header, _, _, err := chain.HeaderByHash(hash)
+		return header, err
+	}
+	return blockchain.PastMedianTime(header, height, fetchHeader)
+}
+
+// handleHeadersOnlyGetBestBlock implements the getbestblock command in
+// headers-only mode.
+func handleHeadersOnlyGetBestBlock(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	hash, height := s.server.blockManager.headerChain.BestHeader()
+	return &btcjson.GetBestBlockResult{
+		Hash:   hash.String(),
+		Height: height,
+	}, nil
+}
+
+// handleHeadersOnlyGetBestBlockHash implements the getbestblockhash command in
+// headers-only mode.
+func handleHeadersOnlyGetBestBlockHash(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	hash, _ := s.server.blockManager.headerChain.BestHeader()
+	return hash.String(), nil
+}
+
+// handleHeadersOnlyGetBlockChainInfo implements the getblockchaininfo command
+// in headers-only mode.  No blocks are downloaded, so the block count is the
+// one of the block database and the deployment states are not reported.
+func handleHeadersOnlyGetBlockChainInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	_, blocks, err := s.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get newest hash"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	bm := s.server.blockManager
+	chain := bm.headerChain
+	hash, height := chain.BestHeader()
+	header, _, _, err := chain.HeaderByHash(hash)
+	if err != nil {
+		context := "Failed to get block header"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	medianTime, err := headerMedianTime(chain, header, height)
+	if err != nil {
+		context := "Failed to calculate median time"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	chainWork, err := chain.ChainWork(hash)
+	if err != nil {
+		context := "Failed to calculate chain work"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	status := bm.SyncStatus()
+	tipAge := s.server.timeSource.AdjustedTime().Sub(header.Timestamp)
+	return &btcjson.GetBlockChainInfoResult{
+		Chain:         s.server.chainParams.Name,
+		Blocks:        blocks,
+		Headers:       height,
+		BestBlockHash: hash.String(),
+		Difficulty:    getDifficultyRatio(header.Bits),
+		MedianTime:    medianTime.Unix(),
+		VerificationProgress: verificationProgress(height,
+			status.syncPeerHeight, tipAge),
+		InitialBlockDownload: bm.IsInitialBlockDownload(),
+		ChainWork:            fmt.Sprintf("%064x", chainWork),
+		TimeOffset:           int64(s.server.timeSource.Offset().Seconds()),
+		Warnings:             s.server.clockSkewWarning(),
+	}, nil
+}
+
+// handleHeadersOnlyGetBlockCount implements the getblockcount command in
+// headers-only mode.
+func handleHeadersOnlyGetBlockCount(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	_, height := s.server.blockManager.headerChain.BestHeader()
+	return height, nil
+}
+
+// handleHeadersOnlyGetBlockHash implements the getblockhash command in
+// headers-only mode.
+func handleHeadersOnlyGetBlockHash(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockHashCmd)
+	hash, err := s.server.blockManager.headerChain.HashByHeight(int32(c.Index))
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCOutOfRange,
+			Message: "Block number out of range",
+		}
+	}
+
+	return hash.String(), nil
+}
+
+// handleHeadersOnlyGetBlockHeader implements the getblockheader command in
+// headers-only mode.  Headers of side chains are reported with zero
+// confirmations.
+func handleHeadersOnlyGetBlockHeader(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockHeaderCmd)
+
+	hash, err := wire.NewShaHashFromStr(c.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := s.server.blockManager.headerChain
+	header, height, inMainChain, err := chain.HeaderByHash(hash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid address or key: " + err.Error(),
+		}
+	}
+
+	if c.Verbose != nil && !*c.Verbose {
+		buf := bytes.NewBuffer(make([]byte, 0, wire.MaxBlockHeaderPayload))
+		if err := header.BtcEncode(buf, maxProtocolVersion); err != nil {
+			errStr := fmt.Sprintf("Failed to serialize data: %v", err)
+			return nil, internalRPCError(errStr, "")
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+
+	var confirmations uint64
+	var nextHashStr string
+	if inMainChain {
+		_, bestHeight := chain.BestHeader()
+		confirmations = uint64(1 + bestHeight - height)
+		nextHash, err := chain.HashByHeight(height + 1)
+		if err == nil {
+			nextHashStr = nextHash.String()
+		}
+	}
+
+	// The genesis block has no previous block.
+	var prevHashStr string
+	if height > 0 {
+		prevHashStr = header.PrevBlock.String()
+	}
+
+	medianTime, err := headerMedianTime(chain, header, height)
+	if err != nil {
+		context := "Failed to calculate median time"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	chainWork, err := chain.ChainWork(hash)
+	if err != nil {
+		context := "Failed to calculate chain work"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return btcjson.GetBlockHeaderVerboseResult{
+		Hash:          c.Hash,
+		Confirmations: confirmations,
+		Height:        height,
+		Version:       header.Version,
+		MerkleRoot:    header.MerkleRoot.String(),
+		NextHash:      nextHashStr,
+		PreviousHash:  prevHashStr,
+		Nonce:         uint64(header.Nonce),
+		Time:          header.Timestamp.Unix(),
+		MedianTime:    medianTime.Unix(),
+		Bits:          strconv.FormatInt(int64(header.Bits), 16),
+		Difficulty:    getDifficultyRatio(header.Bits),
+		ChainWork:     fmt.Sprintf("%064x", chainWork),
+	}, nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+
+	"github.com/conseweb/coinutil"
+)
+
+// BlockHook is implemented by custom code, such as the extensions of
+// downstream forks, which needs to act on the blocks connected to and
+// disconnected from the main chain without modifying the block manager.  The
+// callbacks are made synchronously from the block manager, so hooks must hand
+// off any lengthy work instead of blocking.
+type BlockHook interface {
+	BlockConnected(block *coinutil.Block)
+	BlockDisconnected(block *coinutil.Block)
+}
+
+// TxHook is implemented by custom code which needs to enforce its own policy
+// on the transactions admitted into the memory pool or to act on the
+// transactions accepted into it without modifying the memory pool.
+type TxHook interface {
+	// CheckMempoolTx is called with each transaction which passed all other
+	// checks of the memory pool and the fee it pays right before it is
+	// added to the pool.  Returning an error rejects the transaction.  The
+	// reject code sent to the peer which relayed the transaction can be
+	// chosen by returning a RuleError, otherwise the transaction is
+	// rejected as non-standard.
+	//
+	// It is called with the memory pool locked, so it must not call back
+	// into the memory pool.
+	CheckMempoolTx(tx *coinutil.Tx, fee int64) error
+
+	// MempoolTxAccepted is called after a transaction has been accepted into
+	// the memory pool and relayed.
+	MempoolTxAccepted(tx *coinutil.Tx)
+}
+
+// txHooks holds the transaction hooks registered with the server.
+type txHooks struct {
+	sync.RWMutex
+	hooks []TxHook
+}
+
+// add registers the passed hook.
+//
+// This function is safe for concurrent access.
+func (h *txHooks) add(hook TxHook) {
+	h.Lock()
+	h.hooks = append(h.hooks, hook)
+	h.Unlock()
+}
+
+// CheckMempoolTx asks each registered hook in the order they were registered
+// whether the passed transaction may be added to the memory pool and returns
+// the error of the first one to reject it.
+//
+// This function is safe for concurrent access and is used as the CheckTx
+// callback of the memory pool.
+func (h *txHooks) CheckMempoolTx(tx *coinutil.Tx, fee int64) error {
+	h.RLock()
+	defer h.RUnlock()
+
+	for _, hook := range h.hooks {
+		if err := hook.CheckMempoolTx(tx, fee); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterBlockHook registers the passed hook to be called for each block
+// connected to and disconnected from the main chain from then on.  The hook
+// also receives the other events of the event bus whose subscriber interfaces
+// it implements, such as chain reorganizations.
+//
+// This function is safe for concurrent access.
+func (s *server) RegisterBlockHook(hook BlockHook) {
+	s.events.Subscribe(hook)
+}
+
+// RegisterTxHook registers the passed hook to veto the transactions entering
+// the memory pool and to be called for each transaction accepted into it from
+// then on.
+//
+// This function is safe for concurrent access.
+func (s *server) RegisterTxHook(hook TxHook) {
+	s.txHooks.add(hook)
+	s.events.Subscribe(hook)
+}
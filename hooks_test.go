@@ -0,0 +1,81 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/wire"
+)
+
+// policyTxHook rejects the transactions paying less than a minimum fee and
+// records the calls it receives.
+type policyTxHook struct {
+	events *[]string
+	name   string
+	minFee int64
+}
+
+// CheckMempoolTx rejects the transaction when it pays less than the minimum
+// fee of the hook.
+func (h *policyTxHook) CheckMempoolTx(tx *coinutil.Tx, fee int64) error {
+	*h.events = append(*h.events, h.name+" check")
+	if fee < h.minFee {
+		return errors.New(h.name + " fee too low")
+	}
+	return nil
+}
+
+// MempoolTxAccepted records a transaction accepted event.
+func (h *policyTxHook) MempoolTxAccepted(tx *coinutil.Tx) {
+	*h.events = append(*h.events, h.name+" accepted")
+}
+
+// TestHooks ensures block and transaction hooks registered with the server
+// receive their events and that transaction hooks veto the admission of
+// transactions into the memory pool in the order they were registered.
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	var events []string
+	s := &server{events: newEventBus(), txHooks: &txHooks{}}
+	s.RegisterBlockHook(&blockEventRecorder{events: &events, name: "block"})
+	s.RegisterTxHook(&policyTxHook{events: &events, name: "tx1", minFee: 10})
+	s.RegisterTxHook(&policyTxHook{events: &events, name: "tx2", minFee: 100})
+
+	tx := coinutil.NewTx(&wire.MsgTx{})
+	if err := s.txHooks.CheckMempoolTx(tx, 1000); err != nil {
+		t.Fatalf("CheckMempoolTx: unexpected error: %v", err)
+	}
+	err := s.txHooks.CheckMempoolTx(tx, 50)
+	if err == nil || err.Error() != "tx2 fee too low" {
+		t.Fatalf("CheckMempoolTx: got error %v - want tx2 fee too low",
+			err)
+	}
+	err = s.txHooks.CheckMempoolTx(tx, 5)
+	if err == nil || err.Error() != "tx1 fee too low" {
+		t.Fatalf("CheckMempoolTx: got error %v - want tx1 fee too low",
+			err)
+	}
+
+	block := coinutil.NewBlock(&wire.MsgBlock{})
+	s.events.PublishMempoolTxAccepted(tx)
+	s.events.PublishBlockConnected(block)
+	s.events.PublishBlockDisconnected(block)
+
+	want := []string{
+		"tx1 check", "tx2 check",
+		"tx1 check", "tx2 check",
+		"tx1 check",
+		"tx1 accepted", "tx2 accepted",
+		"block connected", "block disconnected",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("got events %v - want %v", events, want)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// inboundRateWindow is the window over which inbound connection
+	// attempts are counted against the rate limits.
+	inboundRateWindow = time.Minute
+
+	// inboundBackoffBase is how long a host is refused after exceeding
+	// the per-IP rate limit for the first time.  The duration doubles with
+	// every further offense up to inboundBackoffMax.
+	inboundBackoffBase = time.Second * 30
+
+	// inboundBackoffMax is the maximum duration a host is refused for.
+	inboundBackoffMax = time.Hour
+
+	// inboundOffenseExpiry is how long a host has to stay within the rate
+	// limit after its backoff ended before its offenses are forgotten.
+	inboundOffenseExpiry = time.Hour
+
+	// inboundFloodDuration is how long the server is considered to be
+	// under a connection flood after the global rate limit was exceeded.
+	inboundFloodDuration = time.Minute * 10
+
+	// inboundChallengeTimeout is how long an inbound peer challenged during
+	// a connection flood has to answer the challenge.
+	inboundChallengeTimeout = time.Second * 10
+)
+
+// inboundHost tracks the recent inbound connection attempts of a single host,
+// which is the network of the configured prefix length around an IP.  Hosts
+// are expired once they are idle and have no offenses left to remember.
+type inboundHost struct {
+	attempts     []time.Time
+	offenses     uint
+	blockedUntil time.Time
+}
+
+// inboundLimiter limits the rate of inbound connections per host and in total
+// to protect the server against connection floods.  IPs are grouped into hosts
+// by their network of the configured prefix length, so an attacker controlling
+// a whole IPv6 /64 is limited like a single IP.  Hosts exceeding their
+// limit are refused for a duration which doubles with every repeated offense.
+// Once the global limit is exceeded, the server is considered to be under a
+// connection flood and either refuses connections or, when challenges are
+// enabled, accepts them and leaves it to the handshake to challenge the peers.
+// Whitelisted hosts are never limited.  It is safe for concurrent access.
+type inboundLimiter struct {
+	mtx        sync.Mutex
+	perHost    int
+	global     int
+	challenge  bool
+	ipv4Mask   net.IPMask
+	ipv6Mask   net.IPMask
+	whitelist  []*net.IPNet
+	hosts      map[string]*inboundHost
+	attempts   []time.Time
+	floodUntil time.Time
+	lastPrune  time.Time
+}
+
+// newInboundLimiter returns a new limiter allowing the passed number of
+// inbound connection attempts per minute from each host and in total.  Zero
+// disables the respective limit.  IPv4 and IPv6 addresses are grouped into
+// hosts by the passed prefix lengths.  Connections exceeding the global limit
+// are accepted for a challenge instead of being refused when challenge is set.
+func newInboundLimiter(perHost, global int, challenge bool, ipv4Prefix,
+	ipv6Prefix int, whitelist []*net.IPNet) *inboundLimiter {
+
+	return &inboundLimiter{
+		perHost:   perHost,
+		global:    global,
+		challenge: challenge,
+		ipv4Mask:  net.CIDRMask(ipv4Prefix, net.IPv4len*8),
+		ipv6Mask:  net.CIDRMask(ipv6Prefix, net.IPv6len*8),
+		whitelist: whitelist,
+		hosts:     make(map[string]*inboundHost),
+	}
+}
+
+// IsWhitelisted returns whether the passed IP is exempt from the limits.
+func (l *inboundLimiter) IsWhitelisted(ip net.IP) bool {
+	for _, ipnet := range l.whitelist {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostKey returns the key of the host the passed IP belongs to, which is its
+// network of the configured prefix length in CIDR notation.
+func (l *inboundLimiter) hostKey(ip net.IP) string {
+	mask := l.ipv6Mask
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, mask = ip4, l.ipv4Mask
+	}
+	ipnet := net.IPNet{IP: ip.Mask(mask), Mask: mask}
+	return ipnet.String()
+}
+
+// recentAttempts returns the passed attempts without those older than the
+// rate window.
+func recentAttempts(attempts []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-inboundRateWindow)
+	i := 0
+	for i < len(attempts) && !attempts[i].After(cutoff) {
+		i++
+	}
+	return attempts[i:]
+}
+
+// prune expires the hosts which are idle, that is which have neither recent
+// attempts nor offenses to remember.  It runs at most once per rate window.
+//
+// This function MUST be called with the lock held.
+func (l *inboundLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < inboundRateWindow {
+		return
+	}
+	l.lastPrune = now
+	for key, host := range l.hosts {
+		host.attempts = recentAttempts(host.attempts, now)
+		expired := now.After(host.blockedUntil.Add(inboundOffenseExpiry))
+		if len(host.attempts) == 0 && (host.offenses == 0 || expired) {
+			delete(l.hosts, key)
+		}
+	}
+}
+
+// Allow records an inbound connection attempt from the passed IP at the passed
+// time and returns whether it is within the limits.  A host exceeding the
+// per-host limit is refused until its backoff ends.  All IPs within the prefix
+// length of a host count against its limit.
+func (l *inboundLimiter) Allow(ip net.IP, now time.Time) bool {
+	if l.IsWhitelisted(ip) {
+		return true
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.prune(now)
+	key := l.hostKey(ip)
+	host, ok := l.hosts[key]
+	if !ok {
+		host = &inboundHost{}
+		l.hosts[key] = host
+	}
+	if now.Before(host.blockedUntil) {
+		return false
+	}
+	if host.offenses > 0 &&
+		now.After(host.blockedUntil.Add(inboundOffenseExpiry)) {
+
+		host.offenses = 0
+	}
+
+	// Refuse the host with an exponentially increasing backoff when it
+	// exceeds its limit.
+	host.attempts = append(recentAttempts(host.attempts, now), now)
+	if l.perHost > 0 && len(host.attempts) > l.perHost {
+		backoff := inboundBackoffMax
+		if host.offenses < 7 {
+			backoff = inboundBackoffBase << host.offenses
+			if backoff > inboundBackoffMax {
+				backoff = inboundBackoffMax
+			}
+		}
+		host.offenses++
+		host.blockedUntil = now.Add(backoff)
+		host.attempts = nil
+		srvrLog.Infof("Refusing inbound connections from %s for %v "+
+			"after exceeding %d connections per minute", key,
+			backoff, l.perHost)
+		return false
+	}
+
+	// Remember the server is under a connection flood once the global
+	// limit is exceeded.
+	l.attempts = append(recentAttempts(l.attempts, now), now)
+	if l.global > 0 && len(l.attempts) > l.global {
+		if !now.Before(l.floodUntil) {
+			action := "refusing"
+			if l.challenge {
+				action = "challenging"
+			}
+			srvrLog.Warnf("Inbound connections exceed %d per minute "+
+				"-- %s new peers", l.global, action)
+		}
+		l.floodUntil = now.Add(inboundFloodDuration)
+		return l.challenge
+	}
+	return true
+}
+
+// UnderFlood returns whether the global rate limit was exceeded recently, in
+// which case peers connecting when challenges are enabled must pass one.
+func (l *inboundLimiter) UnderFlood(now time.Time) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return now.Before(l.floodUntil)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// TestInboundLimiterPerHost ensures hosts exceeding the per-host limit are
+// refused with a backoff which doubles with every repeated offense while other
+// and whitelisted hosts are still allowed.
+func TestInboundLimiterPerHost(t *testing.T) {
+	_, whitelisted, _ := net.ParseCIDR("10.0.0.0/8")
+	l := newInboundLimiter(2, 0, false, 32, 64, []*net.IPNet{whitelisted})
+	host := net.ParseIP("1.2.3.4")
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if !l.Allow(host, now) {
+			t.Fatalf("Allow: attempt %d refused", i)
+		}
+	}
+	if l.Allow(host, now) {
+		t.Fatal("Allow: attempt exceeding the limit allowed")
+	}
+	if !l.Allow(net.ParseIP("1.2.3.5"), now) {
+		t.Fatal("Allow: other host refused")
+	}
+	for i := 0; i < 5; i++ {
+		if !l.Allow(net.ParseIP("10.1.2.3"), now) {
+			t.Fatal("Allow: whitelisted host refused")
+		}
+	}
+
+	// The host is refused until the backoff ends.
+	now = now.Add(inboundBackoffBase - time.Second)
+	if l.Allow(host, now) {
+		t.Fatal("Allow: host allowed during backoff")
+	}
+	now = now.Add(time.Second)
+	if !l.Allow(host, now) || !l.Allow(host, now) {
+		t.Fatal("Allow: host refused after backoff")
+	}
+
+	// The backoff doubles for a repeated offense.
+	if l.Allow(host, now) {
+		t.Fatal("Allow: repeated offense allowed")
+	}
+	now = now.Add(inboundBackoffBase*2 - time.Second)
+	if l.Allow(host, now) {
+		t.Fatal("Allow: host allowed during doubled backoff")
+	}
+	now = now.Add(time.Second)
+	if !l.Allow(host, now) {
+		t.Fatal("Allow: host refused after doubled backoff")
+	}
+}
+
+// TestInboundLimiterGlobal ensures connections exceeding the global limit are
+// refused, or accepted for a challenge when challenges are enabled, and that
+// the flood is remembered.
+func TestInboundLimiterGlobal(t *testing.T) {
+	for _, challenge := range []bool{false, true} {
+		l := newInboundLimiter(0, 2, challenge, 32, 64, nil)
+		now := time.Now()
+		if !l.Allow(net.ParseIP("1.1.1.1"), now) ||
+			!l.Allow(net.ParseIP("2.2.2.2"), now) {
+
+			t.Fatalf("Allow: attempts within the limit refused")
+		}
+		if l.UnderFlood(now) {
+			t.Fatalf("UnderFlood: flood reported within the limit")
+		}
+		if got := l.Allow(net.ParseIP("3.3.3.3"), now); got != challenge {
+			t.Fatalf("Allow: got %v exceeding the limit with "+
+				"challenge %v", got, challenge)
+		}
+		if !l.UnderFlood(now.Add(inboundFloodDuration - time.Second)) {
+			t.Fatalf("UnderFlood: flood not reported")
+		}
+		if l.UnderFlood(now.Add(inboundFloodDuration)) {
+			t.Fatalf("UnderFlood: flood reported after it ended")
+		}
+		if !l.Allow(net.ParseIP("3.3.3.3"), now.Add(inboundRateWindow)) {
+			t.Fatalf("Allow: attempt refused after the rate window")
+		}
+	}
+}
+
+// TestInboundLimiterPrefix ensures IPs are limited together with the other IPs
+// of their network of the configured prefix length and that idle hosts are
+// expired.
+func TestInboundLimiterPrefix(t *testing.T) {
+	l := newInboundLimiter(1, 0, false, 24, 64, nil)
+	now := time.Now()
+
+	tests := []struct {
+		first, second string
+		sameHost      bool
+	}{
+		{"2001:db8::1", "2001:db8::ffff:1", true},
+		{"2001:db8:0:1::1", "2001:db8:0:2::1", false},
+		{"192.0.2.1", "192.0.2.200", true},
+		{"::ffff:198.51.100.1", "198.51.100.2", true},
+		{"203.0.113.1", "203.0.114.1", false},
+	}
+	for i, test := range tests {
+		if !l.Allow(net.ParseIP(test.first), now) {
+			t.Fatalf("Allow #%d: %s refused", i, test.first)
+		}
+		got := l.Allow(net.ParseIP(test.second), now)
+		if got == test.sameHost {
+			t.Fatalf("Allow #%d: got %v for %s after %s", i, got,
+				test.second, test.first)
+		}
+	}
+
+	// Hosts without recent attempts or offenses are expired.
+	l.Allow(net.ParseIP("2001:db8:1::1"), now.Add(inboundRateWindow))
+	if len(l.hosts) != 4 {
+		t.Fatalf("hosts: got %d after the rate window, want the 3 "+
+			"offending hosts and the new one", len(l.hosts))
+	}
+	l.Allow(net.ParseIP("2001:db8:1::1"), now.Add(inboundOffenseExpiry*2))
+	if len(l.hosts) != 1 {
+		t.Fatalf("hosts: got %d after the offenses expired, want 1",
+			len(l.hosts))
+	}
+}
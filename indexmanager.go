@@ -0,0 +1,281 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/conseweb/btclog"
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+var (
+	// errIndexChainChanged is returned while syncing an index when the
+	// main chain changed underneath the index manager.  The index is
+	// simply synced again.
+	errIndexChainChanged = errors.New("main chain changed while indexing")
+
+	// errIndexInterrupted is returned when the index manager is stopped in
+	// the middle of syncing an index.
+	errIndexInterrupted = errors.New("indexing interrupted")
+)
+
+// Indexer is implemented by the optional indexes maintained by the index
+// manager.  The manager keeps each index in line with the main chain, building
+// it up from the genesis block when it is first enabled and rewinding the
+// blocks which are disconnected by reorganizations, so an index only needs to
+// know how to add and remove a single block.
+type Indexer interface {
+	// Name returns the name of the index as used in log messages, such as
+	// "committed filter".
+	Name() string
+
+	// Init is called with the database holding the index when the index is
+	// added to the index manager, before it is first synced.
+	Init(db database.Db) error
+
+	// Tip returns the hash and height of the most recent block in the
+	// index, or a zero hash and -1 when the index hasn't been built.
+	Tip() (*wire.ShaHash, int32, error)
+
+	// ConnectBlock adds the passed main chain block, which builds on the
+	// tip of the index, to the index.
+	ConnectBlock(block *coinutil.Block) error
+
+	// DisconnectBlock removes the block at the tip of the index, whose hash
+	// and height are passed, once it is no longer in the main chain.  The
+	// block itself may no longer be available.  Indexes which keep the
+	// data of blocks on side chains may rewind further, to the most recent
+	// main chain block they hold.
+	DisconnectBlock(sha *wire.ShaHash, height int32) error
+}
+
+// managedIndex is an index added to the index manager along with its state.
+type managedIndex struct {
+	Indexer
+	log            btclog.Logger
+	progressLogger *blockProgressLogger
+	caughtUp       int32 // atomic
+}
+
+// indexManager maintains the optional indexes which are built from the blocks
+// of the main chain.
+//
+// Rather than applying connected and disconnected blocks individually, the
+// manager reconciles each index against the block database whenever it is
+// notified of a change.  This makes catching up on start up, after an index
+// is enabled and after reorganizations the same operation.
+type indexManager struct {
+	db       database.Db
+	indexes  []*managedIndex
+	started  int32
+	shutdown int32
+	quit     chan struct{}
+	wakeup   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// newIndexManager returns a new index manager for the indexes stored in the
+// passed database.  Use AddIndex to add the enabled indexes and Start to begin
+// building them.
+func newIndexManager(db database.Db) *indexManager {
+	return &indexManager{
+		db:     db,
+		quit:   make(chan struct{}),
+		wakeup: make(chan struct{}, 1),
+	}
+}
+
+// AddIndex initializes the passed index and adds it to the indexes maintained
+// by the manager.  The messages about the index are logged to the passed
+// logger.  It must be called before Start.
+func (m *indexManager) AddIndex(idx Indexer, log btclog.Logger) error {
+	if err := idx.Init(m.db); err != nil {
+		return err
+	}
+	m.indexes = append(m.indexes, &managedIndex{
+		Indexer: idx,
+		log:     log,
+		progressLogger: newBlockProgressLogger("Updated the "+
+			idx.Name()+" index with", log),
+	})
+	return nil
+}
+
+// Start begins building the indexes.  It does nothing when no index was added.
+func (m *indexManager) Start() {
+	// Already started?
+	if atomic.AddInt32(&m.started, 1) != 1 || len(m.indexes) == 0 {
+		return
+	}
+	srvrLog.Trace("Starting index manager")
+	m.wg.Add(1)
+	go m.indexHandler()
+}
+
+// Stop gracefully shuts down the index manager, waiting for the block
+// currently being indexed to finish.
+func (m *indexManager) Stop() error {
+	if len(m.indexes) == 0 {
+		return nil
+	}
+	if atomic.AddInt32(&m.shutdown, 1) != 1 {
+		srvrLog.Warnf("Index manager is already in the process of " +
+			"shutting down")
+		return nil
+	}
+	srvrLog.Infof("Index manager shutting down")
+	close(m.quit)
+	m.wg.Wait()
+	return nil
+}
+
+// IsCaughtUp returns whether the passed index has been built up to the best
+// height of the main chain as of the most recent sync.  It returns false for
+// indexes which weren't added to the manager.
+func (m *indexManager) IsCaughtUp(idx Indexer) bool {
+	for _, mi := range m.indexes {
+		if mi.Indexer == idx {
+			return atomic.LoadInt32(&mi.caughtUp) != 0
+		}
+	}
+	return false
+}
+
+// Notify informs the manager that the main chain changed.  It never blocks
+// since any number of pending notifications are handled by a single sync.
+func (m *indexManager) Notify() {
+	select {
+	case m.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// BlockConnected notifies the manager of a block connected to the main chain.
+// It is called by the event bus of the server.
+func (m *indexManager) BlockConnected(block *coinutil.Block) {
+	m.Notify()
+}
+
+// BlockDisconnected notifies the manager of a block disconnected from the main
+// chain.  It is called by the event bus of the server.
+func (m *indexManager) BlockDisconnected(block *coinutil.Block) {
+	m.Notify()
+}
+
+// indexHandler syncs the indexes with the main chain on start up and whenever
+// it is notified of a change.
+// NOTE: Must be run as a goroutine.
+func (m *indexManager) indexHandler() {
+out:
+	for {
+		for _, idx := range m.indexes {
+			err := m.syncIndex(idx)
+			for err == errIndexChainChanged {
+				idx.log.Debugf("%v -- resyncing", err)
+				err = m.syncIndex(idx)
+			}
+			switch err {
+			case nil:
+				atomic.StoreInt32(&idx.caughtUp, 1)
+
+			case errIndexInterrupted:
+				break out
+
+			default:
+				// Leave the index as is until the next block is
+				// connected rather than spinning on a persistent
+				// error.
+				idx.log.Errorf("Unable to update %s index: %v",
+					idx.Name(), err)
+			}
+		}
+
+		select {
+		case <-m.wakeup:
+		case <-m.quit:
+			break out
+		}
+	}
+	m.wg.Done()
+	srvrLog.Trace("Index manager done")
+}
+
+// syncIndex brings the passed index in line with the current main chain.  It
+// disconnects the blocks at the tip of the index which are no longer in the
+// main chain and then connects every block after the new tip.
+func (m *indexManager) syncIndex(idx *managedIndex) error {
+	_, bestHeight, err := m.db.NewestSha()
+	if err != nil {
+		return err
+	}
+	tipSha, tipHeight, err := idx.Tip()
+	if err != nil {
+		return err
+	}
+
+	// Rewind the blocks which have been disconnected from the main chain.
+	for tipHeight >= 0 {
+		if tipHeight <= bestHeight {
+			sha, err := m.db.FetchBlockShaByHeight(tipHeight)
+			if err != nil {
+				return err
+			}
+			if sha.IsEqual(tipSha) {
+				break
+			}
+		}
+
+		idx.log.Debugf("Rewinding %s index past block %v (height %d)",
+			idx.Name(), tipSha, tipHeight)
+		if err := idx.DisconnectBlock(tipSha, tipHeight); err != nil {
+			return err
+		}
+		tipSha, tipHeight, err = idx.Tip()
+		if err != nil {
+			return err
+		}
+	}
+
+	if bestHeight-tipHeight > 1 {
+		atomic.StoreInt32(&idx.caughtUp, 0)
+		idx.log.Infof("Building %s index from height %d to %d",
+			idx.Name(), tipHeight+1, bestHeight)
+	}
+	prevSha := *tipSha
+	for height := tipHeight + 1; height <= bestHeight; height++ {
+		select {
+		case <-m.quit:
+			return errIndexInterrupted
+		default:
+		}
+
+		sha, err := m.db.FetchBlockShaByHeight(height)
+		if err != nil {
+			return err
+		}
+		block, err := m.db.FetchBlockBySha(sha)
+		if err != nil {
+			return err
+		}
+
+		// Make sure the block builds on the one that was just indexed
+		// since the chain may have been reorganized in the meantime.
+		if height > 0 && block.MsgBlock().Header.PrevBlock != prevSha {
+			return errIndexChainChanged
+		}
+
+		if err := idx.ConnectBlock(block); err != nil {
+			return err
+		}
+		prevSha = *sha
+		idx.progressLogger.LogBlockHeight(block)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/conseweb/btclog"
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// testIndex is an index which records the hashes of the blocks connected to
+// it.
+type testIndex struct {
+	blocks       []wire.ShaHash
+	disconnected int
+}
+
+// Name returns the name of the index.  It is part of the Indexer interface.
+func (x *testIndex) Name() string { return "test" }
+
+// Init does nothing.  It is part of the Indexer interface.
+func (x *testIndex) Init(db database.Db) error { return nil }
+
+// Tip returns the most recent block connected to the index.  It is part of
+// the Indexer interface.
+func (x *testIndex) Tip() (*wire.ShaHash, int32, error) {
+	if len(x.blocks) == 0 {
+		return &wire.ShaHash{}, -1, nil
+	}
+	sha := x.blocks[len(x.blocks)-1]
+	return &sha, int32(len(x.blocks) - 1), nil
+}
+
+// ConnectBlock records the passed block after making sure it builds on the tip
+// of the index.  It is part of the Indexer interface.
+func (x *testIndex) ConnectBlock(block *coinutil.Block) error {
+	tipSha, tipHeight, _ := x.Tip()
+	if block.Height() != tipHeight+1 ||
+		(tipHeight >= 0 && block.MsgBlock().Header.PrevBlock != *tipSha) {
+
+		return fmt.Errorf("block %v (height %d) does not build on "+
+			"the tip", block.Sha(), block.Height())
+	}
+	x.blocks = append(x.blocks, *block.Sha())
+	return nil
+}
+
+// DisconnectBlock removes the block at the tip of the index.  It is part of
+// the Indexer interface.
+func (x *testIndex) DisconnectBlock(sha *wire.ShaHash, height int32) error {
+	tipSha, tipHeight, _ := x.Tip()
+	if !sha.IsEqual(tipSha) || height != tipHeight {
+		return fmt.Errorf("block %v (height %d) is not the tip", sha,
+			height)
+	}
+	x.blocks = x.blocks[:len(x.blocks)-1]
+	x.disconnected++
+	return nil
+}
+
+// checkTestIndex ensures the passed index holds the main chain of db.
+func checkTestIndex(t *testing.T, db database.Db, idx *testIndex) {
+	_, bestHeight, err := db.NewestSha()
+	if err != nil {
+		t.Fatalf("NewestSha: %v", err)
+	}
+	if len(idx.blocks) != int(bestHeight)+1 {
+		t.Fatalf("index holds %d blocks - want %d", len(idx.blocks),
+			bestHeight+1)
+	}
+	for height, sha := range idx.blocks {
+		mainSha, err := db.FetchBlockShaByHeight(int32(height))
+		if err != nil {
+			t.Fatalf("FetchBlockShaByHeight: %v", err)
+		}
+		if !mainSha.IsEqual(&sha) {
+			t.Fatalf("index holds block %v at height %d - want %v",
+				sha, height, mainSha)
+		}
+	}
+}
+
+// TestIndexManager ensures the index manager builds an index up to the tip of
+// the main chain and rewinds the blocks disconnected by a reorganization.
+func TestIndexManager(t *testing.T) {
+	db, err := database.CreateDB("memdb")
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	defer db.Close()
+	addTestBlocks(t, db, 20, 0x207fffff, 0)
+
+	idx := &testIndex{}
+	m := newIndexManager(db)
+	if err := m.AddIndex(idx, btclog.Disabled); err != nil {
+		t.Fatalf("AddIndex: %v", err)
+	}
+	if m.IsCaughtUp(idx) {
+		t.Fatalf("IsCaughtUp: index caught up before being built")
+	}
+	m.Start()
+	for i := 0; !m.IsCaughtUp(idx); i++ {
+		if i == 100 {
+			t.Fatalf("IsCaughtUp: index not caught up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.Stop()
+	checkTestIndex(t, db, idx)
+
+	// Replace the blocks after height 10 with a longer branch and sync the
+	// index again with a new manager as it would be after a restart.
+	forkSha, err := db.FetchBlockShaByHeight(10)
+	if err != nil {
+		t.Fatalf("FetchBlockShaByHeight: %v", err)
+	}
+	if err := db.DropAfterBlockBySha(forkSha); err != nil {
+		t.Fatalf("DropAfterBlockBySha: %v", err)
+	}
+	addTestBlocks(t, db, 15, 0x207fffff, 1)
+	m = newIndexManager(db)
+	if err := m.AddIndex(idx, btclog.Disabled); err != nil {
+		t.Fatalf("AddIndex: %v", err)
+	}
+	if err := m.syncIndex(m.indexes[0]); err != nil {
+		t.Fatalf("syncIndex: %v", err)
+	}
+	if idx.disconnected != 9 {
+		t.Fatalf("disconnected %d blocks - want 9", idx.disconnected)
+	}
+	checkTestIndex(t, db, idx)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/wire"
+)
+
+const (
+	// locatorCacheTTL is how long a response to a getblocks or getheaders
+	// message is reused for identical requests.  It is short since the
+	// point is to absorb bursts of freshly connected peers which all start
+	// syncing from the same locator, not to cache the chain.
+	locatorCacheTTL = time.Second * 30
+
+	// locatorCacheMaxEntries is the maximum number of responses cached at
+	// once.  The entry closest to expiring is evicted to make room.
+	locatorCacheMaxEntries = 100
+)
+
+// locatorCacheEntry is a cached response to a getblocks or getheaders message.
+// The invs and headers are shared between all peers the response is sent to,
+// so they must not be modified.
+type locatorCacheEntry struct {
+	invs         []*wire.InvVect
+	headers      []*wire.BlockHeader
+	autoContinue bool
+	expires      time.Time
+}
+
+// locatorCache caches the responses to getblocks and getheaders messages for
+// identical block locators and stop hashes for a short time.  Answering a
+// request walks the locator and reads up to 2000 headers from the database,
+// which would otherwise be repeated for every peer syncing from the same
+// point.  The cache is cleared whenever the best chain changes since the
+// responses depend on it.  It is safe for concurrent access.
+type locatorCache struct {
+	mtx        sync.Mutex
+	entries    map[wire.ShaHash]*locatorCacheEntry
+	generation uint64
+}
+
+// newLocatorCache returns a new empty locator cache.
+func newLocatorCache() *locatorCache {
+	return &locatorCache{
+		entries: make(map[wire.ShaHash]*locatorCacheEntry),
+	}
+}
+
+// locatorCacheKey returns the key a response to the passed command with the
+// passed locator and stop hash is cached under.
+func locatorCacheKey(command string, locator []*wire.ShaHash, hashStop *wire.ShaHash) wire.ShaHash {
+	var buf bytes.Buffer
+	buf.WriteString(command)
+	for _, hash := range locator {
+		buf.Write(hash[:])
+	}
+	buf.Write(hashStop[:])
+	return wire.DoubleSha256SH(buf.Bytes())
+}
+
+// Lookup returns the response cached under the passed key at the passed time
+// or nil when there is none.  The returned generation must be passed to Add
+// when caching a response computed after a miss.
+func (c *locatorCache) Lookup(key wire.ShaHash, now time.Time) (*locatorCacheEntry, uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && now.After(entry.expires) {
+		delete(c.entries, key)
+		entry = nil
+	}
+	return entry, c.generation
+}
+
+// Add caches the passed response under the passed key until the TTL passed.
+// The response is discarded when the best chain changed since the generation
+// returned by Lookup since it may be outdated already.
+func (c *locatorCache) Add(key wire.ShaHash, entry *locatorCacheEntry, generation uint64, now time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	// Evict the entry closest to expiring when the cache is full.
+	if len(c.entries) >= locatorCacheMaxEntries {
+		var oldestKey wire.ShaHash
+		var oldest *locatorCacheEntry
+		for k, e := range c.entries {
+			if oldest == nil || e.expires.Before(oldest.expires) {
+				oldestKey, oldest = k, e
+			}
+		}
+		delete(c.entries, oldestKey)
+	}
+
+	entry.expires = now.Add(locatorCacheTTL)
+	c.entries[key] = entry
+}
+
+// clear removes all cached responses.
+func (c *locatorCache) clear() {
+	c.mtx.Lock()
+	c.entries = make(map[wire.ShaHash]*locatorCacheEntry)
+	c.generation++
+	c.mtx.Unlock()
+}
+
+// BlockConnected clears the cache since the responses depend on the best
+// chain.  It is called by the event bus of the server.
+func (c *locatorCache) BlockConnected(block *coinutil.Block) {
+	c.clear()
+}
+
+// BlockDisconnected clears the cache since the responses depend on the best
+// chain.  It is called by the event bus of the server.
+func (c *locatorCache) BlockDisconnected(block *coinutil.Block) {
+	c.clear()
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestLocatorCacheKey ensures the cache key depends on the command, every
+// locator hash and the stop hash.
+func TestLocatorCacheKey(t *testing.T) {
+	locator := []*wire.ShaHash{{0x01}, {0x02}}
+	stop := wire.ShaHash{0x03}
+	key := locatorCacheKey(wire.CmdGetBlocks, locator, &stop)
+
+	if got := locatorCacheKey(wire.CmdGetBlocks, locator, &stop); got != key {
+		t.Fatalf("locatorCacheKey: identical requests got different keys")
+	}
+	others := []wire.ShaHash{
+		locatorCacheKey(wire.CmdGetHeaders, locator, &stop),
+		locatorCacheKey(wire.CmdGetBlocks, locator[:1], &stop),
+		locatorCacheKey(wire.CmdGetBlocks, []*wire.ShaHash{{0x02},
+			{0x01}}, &stop),
+		locatorCacheKey(wire.CmdGetBlocks, locator, &zeroHash),
+	}
+	for i, other := range others {
+		if other == key {
+			t.Errorf("locatorCacheKey #%d: different requests got the "+
+				"same key", i)
+		}
+	}
+}
+
+// TestLocatorCache ensures cached responses expire after the TTL, responses
+// computed before the best chain changed are discarded and the entry closest
+// to expiring is evicted when the cache is full.
+func TestLocatorCache(t *testing.T) {
+	c := newLocatorCache()
+	now := time.Now()
+	key := wire.ShaHash{0x01}
+
+	entry, generation := c.Lookup(key, now)
+	if entry != nil {
+		t.Fatalf("Lookup: got an entry from an empty cache")
+	}
+	want := &locatorCacheEntry{autoContinue: true}
+	c.Add(key, want, generation, now)
+	if entry, _ := c.Lookup(key, now.Add(locatorCacheTTL)); entry != want {
+		t.Fatalf("Lookup: got %v, want the cached entry", entry)
+	}
+	if entry, _ := c.Lookup(key, now.Add(locatorCacheTTL+1)); entry != nil {
+		t.Fatalf("Lookup: got an entry after the TTL")
+	}
+
+	// A response computed before a block was connected is outdated.
+	_, generation = c.Lookup(key, now)
+	c.BlockConnected(nil)
+	c.Add(key, want, generation, now)
+	if entry, _ := c.Lookup(key, now); entry != nil {
+		t.Fatalf("Lookup: got an entry computed before the chain " +
+			"changed")
+	}
+
+	// Disconnecting a block clears the cache.
+	_, generation = c.Lookup(key, now)
+	c.Add(key, want, generation, now)
+	c.BlockDisconnected(nil)
+	if entry, _ := c.Lookup(key, now); entry != nil {
+		t.Fatalf("Lookup: got an entry after the chain changed")
+	}
+
+	// Fill the cache with the first entry expiring first and ensure it is
+	// the one evicted.
+	_, generation = c.Lookup(key, now)
+	for i := 0; i < locatorCacheMaxEntries; i++ {
+		k := wire.ShaHash{byte(i), byte(i >> 8), 0xff}
+		c.Add(k, &locatorCacheEntry{}, generation,
+			now.Add(time.Duration(i)*time.Millisecond))
+	}
+	c.Add(key, want, generation, now.Add(time.Second))
+	if len(c.entries) != locatorCacheMaxEntries {
+		t.Fatalf("Add: got %d entries, want %d", len(c.entries),
+			locatorCacheMaxEntries)
+	}
+	if entry, _ := c.Lookup(wire.ShaHash{0, 0, 0xff}, now); entry != nil {
+		t.Fatalf("Add: the entry closest to expiring was not evicted")
+	}
+	if entry, _ := c.Lookup(key, now); entry != want {
+		t.Fatalf("Lookup: got %v, want the newest entry", entry)
+	}
+}
@@ -32,11 +32,14 @@ const (
 // function.
 var (
 	backendLog = seelog.Disabled
+	abixLog    = btclog.Disabled
 	adxrLog    = btclog.Disabled
 	amgrLog    = btclog.Disabled
 	bcdbLog    = btclog.Disabled
 	bmgrLog    = btclog.Disabled
 	btcdLog    = btclog.Disabled
+	caixLog    = btclog.Disabled
+	cfixLog    = btclog.Disabled
 	chanLog    = btclog.Disabled
 	discLog    = btclog.Disabled
 	minrLog    = btclog.Disabled
@@ -44,16 +47,21 @@ var (
 	rpcsLog    = btclog.Disabled
 	scrpLog    = btclog.Disabled
 	srvrLog    = btclog.Disabled
+	stxoLog    = btclog.Disabled
+	txflLog    = btclog.Disabled
 	txmpLog    = btclog.Disabled
 )
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
 var subsystemLoggers = map[string]btclog.Logger{
+	"ABIX": abixLog,
 	"ADXR": adxrLog,
 	"AMGR": amgrLog,
 	"BCDB": bcdbLog,
 	"BMGR": bmgrLog,
 	"XCND": btcdLog,
+	"CAIX": caixLog,
+	"CFIX": cfixLog,
 	"CHAN": chanLog,
 	"DISC": discLog,
 	"MINR": minrLog,
@@ -61,6 +69,8 @@ var subsystemLoggers = map[string]btclog.Logger{
 	"RPCS": rpcsLog,
 	"SCRP": scrpLog,
 	"SRVR": srvrLog,
+	"STXO": stxoLog,
+	"TXFL": txflLog,
 	"TXMP": txmpLog,
 }
 
@@ -89,6 +99,9 @@ func useLogger(subsystemID string, logger btclog.Logger) {
 	subsystemLoggers[subsystemID] = logger
 
 	switch subsystemID {
+	case "ABIX":
+		abixLog = logger
+
 	case "ADXR":
 		adxrLog = logger
 
@@ -106,6 +119,12 @@ func useLogger(subsystemID string, logger btclog.Logger) {
 	case "XCND":
 		btcdLog = logger
 
+	case "CAIX":
+		caixLog = logger
+
+	case "CFIX":
+		cfixLog = logger
+
 	case "CHAN":
 		chanLog = logger
 		blockchain.UseLogger(logger)
@@ -130,6 +149,12 @@ func useLogger(subsystemID string, logger btclog.Logger) {
 	case "SRVR":
 		srvrLog = logger
 
+	case "STXO":
+		stxoLog = logger
+
+	case "TXFL":
+		txflLog = logger
+
 	case "TXMP":
 		txmpLog = logger
 	}
@@ -176,9 +201,14 @@ func setLogLevel(subsystemID string, logLevel string) {
 		level = btclog.InfoLvl
 	}
 
-	// Create new logger for the subsystem if needed.
+	// Create new logger for the subsystem if needed.  The recent lines it
+	// logs are captured for the getlog RPC unless disabled.
 	if logger == btclog.Disabled {
 		logger = btclog.NewSubsystemLogger(backendLog, subsystemID+": ")
+		if logBufferLines > 0 {
+			logger = newCapturingLogger(logger, subsystemID,
+				logBufferLines)
+		}
 		useLogger(subsystemID, logger)
 	}
 	logger.SetLevel(level)
@@ -0,0 +1,252 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conseweb/btclog"
+)
+
+// logLineTimeFormat is the format of the timestamp of captured log lines.
+const logLineTimeFormat = "2006-01-02 15:04:05.000"
+
+// logBufferLines is the number of recent log lines kept in memory for each
+// subsystem.  It is set from the configuration before the subsystem loggers
+// are created.  Zero disables log capture.
+var logBufferLines int
+
+// logLevelAbbrevs maps log levels to the abbreviations used in log lines.
+var logLevelAbbrevs = map[btclog.LogLevel]string{
+	btclog.TraceLvl:    "TRC",
+	btclog.DebugLvl:    "DBG",
+	btclog.InfoLvl:     "INF",
+	btclog.WarnLvl:     "WRN",
+	btclog.ErrorLvl:    "ERR",
+	btclog.CriticalLvl: "CRT",
+}
+
+// logRing is a fixed size ring buffer of the most recent log lines of a
+// subsystem.
+type logRing struct {
+	sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// newLogRing returns a ring buffer which keeps the passed number of lines.
+func newLogRing(size int) *logRing {
+	return &logRing{lines: make([]string, size)}
+}
+
+// Append adds a line to the ring buffer, replacing the oldest line once the
+// buffer is full.
+//
+// This function is safe for concurrent access.
+func (r *logRing) Append(line string) {
+	r.Lock()
+	r.lines[r.next] = line
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+	r.Unlock()
+}
+
+// Last returns up to the passed number of the most recent lines, oldest
+// first.
+//
+// This function is safe for concurrent access.
+func (r *logRing) Last(count int) []string {
+	r.Lock()
+	defer r.Unlock()
+
+	available := r.next
+	if r.full {
+		available = len(r.lines)
+	}
+	if count > available {
+		count = available
+	}
+	lines := make([]string, 0, count)
+	start := r.next - count
+	if start < 0 {
+		start += len(r.lines)
+	}
+	for i := 0; i < count; i++ {
+		lines = append(lines, r.lines[(start+i)%len(r.lines)])
+	}
+	return lines
+}
+
+// capturingLogger is a btclog.Logger which records every message at or above
+// its level in a ring buffer before passing it on to the wrapped logger.  It
+// allows the recent log output of a subsystem to be retrieved remotely with
+// the getlog RPC.
+type capturingLogger struct {
+	btclog.Logger
+	subsystemID string
+	ring        *logRing
+}
+
+// newCapturingLogger returns a logger for the passed subsystem which keeps
+// the passed number of lines logged through it.
+func newCapturingLogger(logger btclog.Logger, subsystemID string, lines int) *capturingLogger {
+	return &capturingLogger{
+		Logger:      logger,
+		subsystemID: subsystemID,
+		ring:        newLogRing(lines),
+	}
+}
+
+// capture returns whether a message at the passed level is logged and, if
+// so, records the message produced by the passed function.  The message is
+// only formatted when it is logged.
+func (l *capturingLogger) capture(level btclog.LogLevel, msg func() string) (string, bool) {
+	if level < l.Level() {
+		return "", false
+	}
+	s := msg()
+	l.ring.Append(fmt.Sprintf("%s [%s] %s: %s",
+		time.Now().Format(logLineTimeFormat), logLevelAbbrevs[level],
+		l.subsystemID, s))
+	return s, true
+}
+
+// Lines returns up to the passed number of the most recently logged lines,
+// oldest first.
+func (l *capturingLogger) Lines(count int) []string {
+	return l.ring.Last(count)
+}
+
+// Tracef formats the message according to the format specifier, records it
+// and writes it with the trace level.
+func (l *capturingLogger) Tracef(format string, params ...interface{}) {
+	if s, ok := l.capture(btclog.TraceLvl, func() string {
+		return fmt.Sprintf(format, params...)
+	}); ok {
+		l.Logger.Trace(s)
+	}
+}
+
+// Debugf formats the message according to the format specifier, records it
+// and writes it with the debug level.
+func (l *capturingLogger) Debugf(format string, params ...interface{}) {
+	if s, ok := l.capture(btclog.DebugLvl, func() string {
+		return fmt.Sprintf(format, params...)
+	}); ok {
+		l.Logger.Debug(s)
+	}
+}
+
+// Infof formats the message according to the format specifier, records it
+// and writes it with the info level.
+func (l *capturingLogger) Infof(format string, params ...interface{}) {
+	if s, ok := l.capture(btclog.InfoLvl, func() string {
+		return fmt.Sprintf(format, params...)
+	}); ok {
+		l.Logger.Info(s)
+	}
+}
+
+// Warnf formats the message according to the format specifier, records it
+// and writes it with the warn level.
+func (l *capturingLogger) Warnf(format string, params ...interface{}) error {
+	if s, ok := l.capture(btclog.WarnLvl, func() string {
+		return fmt.Sprintf(format, params...)
+	}); ok {
+		return l.Logger.Warn(s)
+	}
+	return nil
+}
+
+// Errorf formats the message according to the format specifier, records it
+// and writes it with the error level.
+func (l *capturingLogger) Errorf(format string, params ...interface{}) error {
+	if s, ok := l.capture(btclog.ErrorLvl, func() string {
+		return fmt.Sprintf(format, params...)
+	}); ok {
+		return l.Logger.Error(s)
+	}
+	return nil
+}
+
+// Criticalf formats the message according to the format specifier, records
+// it and writes it with the critical level.
+func (l *capturingLogger) Criticalf(format string, params ...interface{}) error {
+	if s, ok := l.capture(btclog.CriticalLvl, func() string {
+		return fmt.Sprintf(format, params...)
+	}); ok {
+		return l.Logger.Critical(s)
+	}
+	return nil
+}
+
+// Trace formats the message using the default formats for its operands,
+// records it and writes it with the trace level.
+func (l *capturingLogger) Trace(v ...interface{}) {
+	if s, ok := l.capture(btclog.TraceLvl, func() string {
+		return fmt.Sprint(v...)
+	}); ok {
+		l.Logger.Trace(s)
+	}
+}
+
+// Debug formats the message using the default formats for its operands,
+// records it and writes it with the debug level.
+func (l *capturingLogger) Debug(v ...interface{}) {
+	if s, ok := l.capture(btclog.DebugLvl, func() string {
+		return fmt.Sprint(v...)
+	}); ok {
+		l.Logger.Debug(s)
+	}
+}
+
+// Info formats the message using the default formats for its operands,
+// records it and writes it with the info level.
+func (l *capturingLogger) Info(v ...interface{}) {
+	if s, ok := l.capture(btclog.InfoLvl, func() string {
+		return fmt.Sprint(v...)
+	}); ok {
+		l.Logger.Info(s)
+	}
+}
+
+// Warn formats the message using the default formats for its operands,
+// records it and writes it with the warn level.
+func (l *capturingLogger) Warn(v ...interface{}) error {
+	if s, ok := l.capture(btclog.WarnLvl, func() string {
+		return fmt.Sprint(v...)
+	}); ok {
+		return l.Logger.Warn(s)
+	}
+	return nil
+}
+
+// Error formats the message using the default formats for its operands,
+// records it and writes it with the error level.
+func (l *capturingLogger) Error(v ...interface{}) error {
+	if s, ok := l.capture(btclog.ErrorLvl, func() string {
+		return fmt.Sprint(v...)
+	}); ok {
+		return l.Logger.Error(s)
+	}
+	return nil
+}
+
+// Critical formats the message using the default formats for its operands,
+// records it and writes it with the critical level.
+func (l *capturingLogger) Critical(v ...interface{}) error {
+	if s, ok := l.capture(btclog.CriticalLvl, func() string {
+		return fmt.Sprint(v...)
+	}); ok {
+		return l.Logger.Critical(s)
+	}
+	return nil
+}
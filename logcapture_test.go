@@ -0,0 +1,116 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/conseweb/btclog"
+)
+
+// TestLogRing ensures the log ring buffer returns the most recent lines in
+// order before and after it wraps around.
+func TestLogRing(t *testing.T) {
+	ring := newLogRing(3)
+	if lines := ring.Last(5); len(lines) != 0 {
+		t.Fatalf("Last on empty ring: got %v - want none", lines)
+	}
+
+	ring.Append("a")
+	ring.Append("b")
+	if got, want := ring.Last(5), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Last before wrap: got %v - want %v", got, want)
+	}
+
+	ring.Append("c")
+	ring.Append("d")
+	ring.Append("e")
+	if got, want := ring.Last(5), []string{"c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Last after wrap: got %v - want %v", got, want)
+	}
+	if got, want := ring.Last(2), []string{"d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Last 2 after wrap: got %v - want %v", got, want)
+	}
+}
+
+// TestCapturingLogger ensures only messages at or above the level of the
+// logger are captured.
+func TestCapturingLogger(t *testing.T) {
+	logger := newCapturingLogger(btclog.NewSubsystemLogger(nil, "TEST: "),
+		"TEST", 10)
+	logger.SetLevel(btclog.InfoLvl)
+
+	logger.Debugf("debug %d", 1)
+	logger.Infof("info %d", 2)
+	logger.Warn("warn ", 3)
+	logger.Trace("trace")
+
+	lines := logger.Lines(10)
+	if len(lines) != 2 {
+		t.Fatalf("Lines: got %d lines %v - want 2", len(lines), lines)
+	}
+	if !strings.HasSuffix(lines[0], " [INF] TEST: info 2") {
+		t.Errorf("Lines: unexpected first line %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " [WRN] TEST: warn 3") {
+		t.Errorf("Lines: unexpected second line %q", lines[1])
+	}
+}
+
+// TestPersistDebugLevels ensures persisted debug levels are merged with the
+// ones persisted before.
+func TestPersistDebugLevels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debuglevels")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, debugLevelsFilename)
+
+	spec, err := loadPersistedDebugLevels(path)
+	if err != nil || spec != "" {
+		t.Fatalf("loadPersistedDebugLevels without file: got %q, %v",
+			spec, err)
+	}
+
+	if err := persistDebugLevels(path, "RPCS=debug,PEER=trace"); err != nil {
+		t.Fatalf("persistDebugLevels: %v", err)
+	}
+	if err := persistDebugLevels(path, "PEER=warn"); err != nil {
+		t.Fatalf("persistDebugLevels: %v", err)
+	}
+	spec, err = loadPersistedDebugLevels(path)
+	if err != nil {
+		t.Fatalf("loadPersistedDebugLevels: %v", err)
+	}
+	if want := "PEER=warn,RPCS=debug"; spec != want {
+		t.Fatalf("loadPersistedDebugLevels: got %q - want %q", spec, want)
+	}
+
+	// A single level applies to every subsystem.
+	if err := persistDebugLevels(path, "error"); err != nil {
+		t.Fatalf("persistDebugLevels: %v", err)
+	}
+	spec, err = loadPersistedDebugLevels(path)
+	if err != nil {
+		t.Fatalf("loadPersistedDebugLevels: %v", err)
+	}
+	levels := debugLevelPairs(spec)
+	if len(levels) != len(subsystemLoggers) {
+		t.Fatalf("persisted levels: got %d subsystems - want %d",
+			len(levels), len(subsystemLoggers))
+	}
+	for subsysID, level := range levels {
+		if level != "error" {
+			t.Errorf("persisted level of %s: got %q - want error",
+				subsysID, level)
+		}
+	}
+}
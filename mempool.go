@@ -15,6 +15,7 @@ import (
 
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/database"
 	"github.com/conseweb/stcd/mining"
 	"github.com/conseweb/stcd/txscript"
@@ -35,10 +36,13 @@ const (
 	// of big orphans.
 	maxOrphanTxSize = 5000
 
-	// maxSigOpsPerTx is the maximum number of signature operations
-	// in a single transaction we will relay or mine.  It is a fraction
-	// of the max signature operations for a block.
-	maxSigOpsPerTx = blockchain.MaxSigOpsPerBlock / 5
+	// maxDoubleSpends is the maximum number of double spending transactions
+	// remembered in order to only report each of them once.
+	maxDoubleSpends = 1000
+
+	// maxEvictedTxs is the maximum number of transactions evicted from the
+	// pool which are remembered so clients can fetch and resubmit them.
+	maxEvictedTxs = 1000
 )
 
 // mempoolTxDesc is a descriptor containing a transaction in the mempool along
@@ -51,8 +55,69 @@ type mempoolTxDesc struct {
 	StartingPriority float64
 }
 
+// txRemovalReason describes why a transaction was removed from the memory pool.
+type txRemovalReason uint8
+
+// These constants define the reasons a transaction is removed from the pool.
+const (
+	// txRemovedBlock indicates the transaction was included in a block
+	// connected to the main chain.
+	txRemovedBlock txRemovalReason = iota
+
+	// txRemovedConflict indicates the transaction spent an output which is
+	// also spent by a transaction of a block connected to the main chain,
+	// or depended on such a transaction.
+	txRemovedConflict
+
+	// txRemovedExpiry indicates the transaction wasn't mined before the
+	// configured expiry, or depended on such a transaction.
+	txRemovedExpiry
+
+	// txRemovedEviction indicates the transaction was evicted for another
+	// reason, such as depending on a transaction of a block disconnected
+	// from the main chain which could not be returned to the pool.
+	txRemovedEviction
+)
+
+// txRemovalReasonStrings maps the reasons a transaction is removed from the
+// pool to their names as reported to websocket clients.
+var txRemovalReasonStrings = map[txRemovalReason]string{
+	txRemovedBlock:    "block",
+	txRemovedConflict: "conflict",
+	txRemovedExpiry:   "expiry",
+	txRemovedEviction: "eviction",
+}
+
+// String returns the name of the removal reason.
+func (r txRemovalReason) String() string {
+	if s, ok := txRemovalReasonStrings[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown removal reason (%d)", uint8(r))
+}
+
+// evictedTxDesc is a descriptor of a transaction evicted from the mempool
+// because it stayed in the pool for longer than the configured expiry.
+type evictedTxDesc struct {
+	// Tx is the evicted transaction.
+	Tx *coinutil.Tx
+
+	// Added is the time the transaction was added to the pool.
+	Added time.Time
+
+	// Evicted is the time the transaction was evicted from the pool.
+	Evicted time.Time
+}
+
 // mempoolConfig is a descriptor containing the memory pool configuration.
 type mempoolConfig struct {
+	// CheckTx defines the function to call with each transaction which
+	// passed all other checks and the fee it pays right before it is added
+	// to the pool.  The transaction is rejected when it returns an error.
+	// It is called with the pool locked, so it must not call back into the
+	// pool.  If unset, no further checks are made.
+	CheckTx func(tx *coinutil.Tx, fee int64) error
+
 	// DisableRelayPriority defines whether to relay free or low-fee
 	// transactions that do not have enough priority to be relayed.
 	DisableRelayPriority bool
@@ -60,6 +125,11 @@ type mempoolConfig struct {
 	// EnableAddrIndex defines whether the address index should be enabled.
 	EnableAddrIndex bool
 
+	// Expiry defines how long a transaction may stay in the pool without
+	// being mined before it is evicted by ExpireTransactions.  Zero
+	// disables expiry.
+	Expiry time.Duration
+
 	// FetchTransactionStore defines the function to use to fetch
 	// transacation information.
 	FetchTransactionStore func(*coinutil.Tx, bool) (blockchain.TxStore, error)
@@ -68,6 +138,24 @@ type mempoolConfig struct {
 	// per minute that transactions with no fee are rate limited to.
 	FreeTxRelayLimit float64
 
+	// IsDeploymentActive defines the function to use to determine whether
+	// a rule change deployment is active for the next block.
+	IsDeploymentActive func(deploymentID uint32) (bool, error)
+
+	// MaxAncestorCount and MaxAncestorSize define the maximum number and
+	// total virtual size of the unconfirmed transactions in the pool a
+	// transaction may depend on, including the transaction itself.  Zero
+	// disables the respective limit.
+	MaxAncestorCount int
+	MaxAncestorSize  int64
+
+	// MaxDescendantCount and MaxDescendantSize define the maximum number
+	// and total virtual size of the transactions in the pool which may
+	// depend on an unconfirmed transaction, including the transaction
+	// itself.  Zero disables the respective limit.
+	MaxDescendantCount int
+	MaxDescendantSize  int64
+
 	// MaxOrphanTxs defines the maximum number of orphan transactions to
 	// keep in memory.
 	MaxOrphanTxs int
@@ -79,10 +167,34 @@ type mempoolConfig struct {
 	// NewestSha defines the function to retrieve the newest sha
 	NewestSha func() (*wire.ShaHash, int32, error)
 
+	// NotifyDoubleSpend defines the function to call when a valid
+	// transaction is rejected because it spends the passed inputs which are
+	// already spent by the passed conflicting transaction in the pool.  It
+	// is called once per conflicting transaction.  If unset, double spends
+	// are only logged.
+	NotifyDoubleSpend func(tx, conflict *coinutil.Tx, inputs []wire.OutPoint)
+
+	// NotifyTxAdded and NotifyTxRemoved define the functions to call when
+	// a transaction is added to or removed from the pool, including the
+	// transactions returned to the pool when a block is disconnected.
+	// They are called with the pool locked, once for each change and in
+	// the order of the changes, so they must not call back into the pool.
+	// If unset, the changes aren't reported.
+	NotifyTxAdded   func(txDesc *mempoolTxDesc)
+	NotifyTxRemoved func(tx *coinutil.Tx, reason txRemovalReason)
+
+	// Policy defines the rules which determine whether or not a
+	// transaction is standard.
+	Policy standardPolicy
+
 	// RelayNtfnChan defines the channel to send newly accepted transactions
 	// to.  If unset or set to nil, notifications will not be sent.
 	RelayNtfnChan chan *coinutil.Tx
 
+	// ScriptBudget defines the budget which limits the execution of the
+	// scripts of each transaction input.
+	ScriptBudget txscript.Budget
+
 	// SigCache defines a signature cache to use.
 	SigCache *txscript.SigCache
 
@@ -97,10 +209,13 @@ type txMemPool struct {
 	sync.RWMutex
 	cfg           mempoolConfig
 	pool          map[wire.ShaHash]*mempoolTxDesc
+	wtxids        map[wire.ShaHash]wire.ShaHash // maps wtxid to txid
 	orphans       map[wire.ShaHash]*coinutil.Tx
 	orphansByPrev map[wire.ShaHash]map[wire.ShaHash]*coinutil.Tx
 	addrindex     map[string]map[wire.ShaHash]struct{} // maps address to txs
 	outpoints     map[wire.OutPoint]*coinutil.Tx
+	doubleSpends  map[wire.ShaHash]struct{}
+	evicted       []*evictedTxDesc
 	lastUpdated   time.Time // last time pool was updated
 	pennyTotal    float64   // exponentially decaying total for penny spends.
 	lastPennyUnix int64     // unix time of last ``penny spend''
@@ -307,14 +422,16 @@ func (mp *txMemPool) HaveTransaction(hash *wire.ShaHash) bool {
 // RemoveTransaction.  See the comment for RemoveTransaction for more details.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *txMemPool) removeTransaction(tx *coinutil.Tx, removeRedeemers bool) {
+func (mp *txMemPool) removeTransaction(tx *coinutil.Tx, removeRedeemers bool,
+	reason txRemovalReason) {
+
 	txHash := tx.Sha()
 	if removeRedeemers {
 		// Remove any transactions which rely on this one.
 		for i := uint32(0); i < uint32(len(tx.MsgTx().TxOut)); i++ {
 			outpoint := wire.NewOutPoint(txHash, i)
 			if txRedeemer, exists := mp.outpoints[*outpoint]; exists {
-				mp.removeTransaction(txRedeemer, true)
+				mp.removeTransaction(txRedeemer, true, reason)
 			}
 		}
 	}
@@ -329,10 +446,16 @@ func (mp *txMemPool) removeTransaction(tx *coinutil.Tx, removeRedeemers bool) {
 		for _, txIn := range txDesc.Tx.MsgTx().TxIn {
 			delete(mp.outpoints, txIn.PreviousOutPoint)
 		}
+		if tx.MsgTx().HasWitness() {
+			delete(mp.wtxids, tx.MsgTx().WitnessHash())
+		}
 		delete(mp.pool, *txHash)
 		mp.lastUpdated = time.Now()
-	}
 
+		if mp.cfg.NotifyTxRemoved != nil {
+			mp.cfg.NotifyTxRemoved(tx, reason)
+		}
+	}
 }
 
 // removeTransactionFromAddrIndex removes the passed transaction from our
@@ -380,15 +503,18 @@ func (mp *txMemPool) removeScriptFromAddrIndex(pkScript []byte, tx *coinutil.Tx)
 // RemoveTransaction removes the passed transaction from the mempool. If
 // removeRedeemers flag is set, any transactions that redeem outputs from the
 // removed transaction will also be removed recursively from the mempool, as
-// they would otherwise become orphan.
+// they would otherwise become orphan.  The passed reason is reported for each
+// removed transaction.
 //
 // This function is safe for concurrent access.
-func (mp *txMemPool) RemoveTransaction(tx *coinutil.Tx, removeRedeemers bool) {
+func (mp *txMemPool) RemoveTransaction(tx *coinutil.Tx, removeRedeemers bool,
+	reason txRemovalReason) {
+
 	// Protect concurrent access.
 	mp.Lock()
 	defer mp.Unlock()
 
-	mp.removeTransaction(tx, removeRedeemers)
+	mp.removeTransaction(tx, removeRedeemers, reason)
 }
 
 // RemoveDoubleSpends removes all transactions which spend outputs spent by the
@@ -406,12 +532,90 @@ func (mp *txMemPool) RemoveDoubleSpends(tx *coinutil.Tx) {
 	for _, txIn := range tx.MsgTx().TxIn {
 		if txRedeemer, ok := mp.outpoints[txIn.PreviousOutPoint]; ok {
 			if !txRedeemer.Sha().IsEqual(tx.Sha()) {
-				mp.removeTransaction(txRedeemer, true)
+				mp.removeTransaction(txRedeemer, true,
+					txRemovedConflict)
 			}
 		}
 	}
 }
 
+// expireTransaction removes the passed transaction, along with all
+// transactions which rely on it, from the pool and remembers them as evicted
+// at the passed time.  The removed transactions are appended to the passed
+// slice, which is returned, with the redeemers before the transactions they
+// redeem.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *txMemPool) expireTransaction(tx *coinutil.Tx, now time.Time, expired []*coinutil.Tx) []*coinutil.Tx {
+	txDesc, exists := mp.pool[*tx.Sha()]
+	if !exists {
+		return expired
+	}
+
+	// Remove the transactions which rely on this one first since they
+	// would otherwise become orphans.
+	for i := uint32(0); i < uint32(len(tx.MsgTx().TxOut)); i++ {
+		outpoint := wire.NewOutPoint(tx.Sha(), i)
+		if txRedeemer, exists := mp.outpoints[*outpoint]; exists {
+			expired = mp.expireTransaction(txRedeemer, now, expired)
+		}
+	}
+
+	mp.removeTransaction(tx, false, txRemovedExpiry)
+	if len(mp.evicted) >= maxEvictedTxs {
+		mp.evicted = mp.evicted[1:]
+	}
+	mp.evicted = append(mp.evicted, &evictedTxDesc{
+		Tx:      tx,
+		Added:   txDesc.Added,
+		Evicted: now,
+	})
+	return append(expired, tx)
+}
+
+// ExpireTransactions evicts the transactions which were added to the pool
+// longer than the configured expiry before the passed time, along with all
+// transactions which rely on them, and returns the evicted transactions.  It
+// does nothing when expiry is disabled.
+//
+// This function is safe for concurrent access.
+func (mp *txMemPool) ExpireTransactions(now time.Time) []*coinutil.Tx {
+	if mp.cfg.Expiry <= 0 {
+		return nil
+	}
+
+	// Protect concurrent access.
+	mp.Lock()
+	defer mp.Unlock()
+
+	var stale []*coinutil.Tx
+	for _, txDesc := range mp.pool {
+		if now.Sub(txDesc.Added) > mp.cfg.Expiry {
+			stale = append(stale, txDesc.Tx)
+		}
+	}
+
+	var expired []*coinutil.Tx
+	for _, tx := range stale {
+		expired = mp.expireTransaction(tx, now, expired)
+	}
+	return expired
+}
+
+// EvictedTxDescs returns descriptors for the most recently expired
+// transactions, ordered from the oldest to the newest eviction.  Transactions
+// which have been added to the pool again since are included as well.
+//
+// This function is safe for concurrent access.
+func (mp *txMemPool) EvictedTxDescs() []*evictedTxDesc {
+	mp.RLock()
+	defer mp.RUnlock()
+
+	descs := make([]*evictedTxDesc, len(mp.evicted))
+	copy(descs, mp.evicted)
+	return descs
+}
+
 // addTransaction adds the passed transaction to the memory pool.  It should
 // not be called directly as it doesn't perform any validation.  This is a
 // helper for maybeAcceptTransaction.
@@ -420,7 +624,7 @@ func (mp *txMemPool) RemoveDoubleSpends(tx *coinutil.Tx) {
 func (mp *txMemPool) addTransaction(txStore blockchain.TxStore, tx *coinutil.Tx, height int32, fee int64) {
 	// Add the transaction to the pool and mark the referenced outpoints
 	// as spent by the pool.
-	mp.pool[*tx.Sha()] = &mempoolTxDesc{
+	txDesc := &mempoolTxDesc{
 		TxDesc: mining.TxDesc{
 			Tx:     tx,
 			Added:  time.Now(),
@@ -429,14 +633,25 @@ func (mp *txMemPool) addTransaction(txStore blockchain.TxStore, tx *coinutil.Tx,
 		},
 		StartingPriority: calcPriority(tx.MsgTx(), txStore, height),
 	}
+	mp.pool[*tx.Sha()] = txDesc
 	for _, txIn := range tx.MsgTx().TxIn {
 		mp.outpoints[txIn.PreviousOutPoint] = tx
 	}
+
+	// Transactions without witness data have a wtxid equal to their txid,
+	// so only the others need to be indexed by it.
+	if tx.MsgTx().HasWitness() {
+		mp.wtxids[tx.MsgTx().WitnessHash()] = *tx.Sha()
+	}
 	mp.lastUpdated = time.Now()
 
 	if mp.cfg.EnableAddrIndex {
 		mp.addTransactionToAddrIndex(tx)
 	}
+
+	if mp.cfg.NotifyTxAdded != nil {
+		mp.cfg.NotifyTxAdded(txDesc)
+	}
 }
 
 // addTransactionToAddrIndex adds all addresses related to the transaction to
@@ -527,6 +742,78 @@ func (mp *txMemPool) checkPoolDoubleSpend(tx *coinutil.Tx) error {
 	return nil
 }
 
+// reportDoubleSpend logs the passed transaction, which spends outputs already
+// spent by other transactions in the pool, and notifies the configured
+// callback about every conflicting transaction.  Only transactions which would
+// otherwise be valid are reported so nobody can raise false alarms by
+// relaying garbage which spends the same outputs, and each transaction is only
+// reported once.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *txMemPool) reportDoubleSpend(tx *coinutil.Tx, nextBlockHeight int32) {
+	txHash := tx.Sha()
+	if _, exists := mp.doubleSpends[*txHash]; exists {
+		return
+	}
+
+	// Group the conflicting inputs by the pool transaction spending them.
+	var conflicts []*coinutil.Tx
+	inputs := make(map[*coinutil.Tx][]wire.OutPoint)
+	for _, txIn := range tx.MsgTx().TxIn {
+		conflict, exists := mp.outpoints[txIn.PreviousOutPoint]
+		if !exists {
+			continue
+		}
+		if _, exists := inputs[conflict]; !exists {
+			conflicts = append(conflicts, conflict)
+		}
+		inputs[conflict] = append(inputs[conflict], txIn.PreviousOutPoint)
+	}
+
+	// Ensure the transaction is valid on its own.  The inputs spent by the
+	// conflicting pool transactions are looked up in the pool, so they are
+	// seen as unspent.
+	txStore, err := mp.fetchInputTransactions(tx, false)
+	if err != nil {
+		return
+	}
+	delete(txStore, *txHash)
+	for _, txD := range txStore {
+		if txD.Err != nil {
+			return
+		}
+	}
+	_, err = blockchain.CheckTransactionInputs(tx, nextBlockHeight, txStore)
+	if err != nil {
+		return
+	}
+	err = blockchain.ValidateTransactionScriptsWithBudget(tx, txStore,
+		txscript.StandardVerifyFlags, mp.cfg.SigCache,
+		mp.cfg.ScriptBudget)
+	if err != nil {
+		return
+	}
+
+	// Remember the transaction, evicting an arbitrary one when there are
+	// too many.
+	if len(mp.doubleSpends) >= maxDoubleSpends {
+		for hash := range mp.doubleSpends {
+			delete(mp.doubleSpends, hash)
+			break
+		}
+	}
+	mp.doubleSpends[*txHash] = struct{}{}
+
+	for _, conflict := range conflicts {
+		txmpLog.Warnf("Transaction %v double spends %v of transaction "+
+			"%v in the memory pool", txHash, inputs[conflict],
+			conflict.Sha())
+		if mp.cfg.NotifyDoubleSpend != nil {
+			mp.cfg.NotifyDoubleSpend(tx, conflict, inputs[conflict])
+		}
+	}
+}
+
 // fetchInputTransactions fetches the input transactions referenced by the
 // passed transaction.  First, it fetches from the main chain, then it tries to
 // fetch any missing inputs from the transaction pool.
@@ -571,6 +858,65 @@ func (mp *txMemPool) FetchTransaction(txHash *wire.ShaHash) (*coinutil.Tx, error
 	return nil, fmt.Errorf("transaction is not in the pool")
 }
 
+// FetchTxDesc returns the descriptor of the transaction with the passed hash
+// from the transaction pool.  This only fetches from the main transaction pool
+// and does not include orphans.
+//
+// This function is safe for concurrent access.
+func (mp *txMemPool) FetchTxDesc(txHash *wire.ShaHash) (*mempoolTxDesc, error) {
+	// Protect concurrent access.
+	mp.RLock()
+	defer mp.RUnlock()
+
+	if txDesc, exists := mp.pool[*txHash]; exists {
+		return txDesc, nil
+	}
+
+	return nil, fmt.Errorf("transaction is not in the pool")
+}
+
+// FetchTransactionByWitnessHash returns the transaction with the passed
+// witness hash (wtxid) from the transaction pool.  This only fetches from the
+// main transaction pool and does not include orphans.
+//
+// This function is safe for concurrent access.
+func (mp *txMemPool) FetchTransactionByWitnessHash(wtxid *wire.ShaHash) (*coinutil.Tx, error) {
+	// Protect concurrent access.
+	mp.RLock()
+	defer mp.RUnlock()
+
+	txHash := *wtxid
+	if hash, exists := mp.wtxids[*wtxid]; exists {
+		txHash = hash
+	}
+	if txDesc, exists := mp.pool[txHash]; exists {
+		return txDesc.Tx, nil
+	}
+
+	return nil, fmt.Errorf("transaction is not in the pool")
+}
+
+// HaveWitnessHash returns whether or not the transaction with the passed
+// witness hash (wtxid) exists in the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *txMemPool) HaveWitnessHash(wtxid *wire.ShaHash) bool {
+	_, err := mp.FetchTransactionByWitnessHash(wtxid)
+	return err == nil
+}
+
+// CheckSpend checks whether the passed outpoint is already spent by a
+// transaction in the memory pool.  If that's the case the spending transaction
+// will be returned, if not nil will be returned.
+//
+// This function is safe for concurrent access.
+func (mp *txMemPool) CheckSpend(op wire.OutPoint) *coinutil.Tx {
+	mp.RLock()
+	defer mp.RUnlock()
+
+	return mp.outpoints[op]
+}
+
 // FilterTransactionsByAddress returns all transactions currently in the
 // mempool that either create an output to the passed address or spend a
 // previously created ouput to the address.
@@ -592,12 +938,140 @@ func (mp *txMemPool) FilterTransactionsByAddress(addr coinutil.Address) ([]*coin
 	return nil, fmt.Errorf("address does not have any transactions in the pool")
 }
 
+// poolAncestors returns the transactions in the pool the passed transaction
+// depends on, directly or through other transactions in the pool.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *txMemPool) poolAncestors(tx *coinutil.Tx) map[wire.ShaHash]*mempoolTxDesc {
+	ancestors := make(map[wire.ShaHash]*mempoolTxDesc)
+	pending := []*coinutil.Tx{tx}
+	for len(pending) > 0 {
+		next := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, txIn := range next.MsgTx().TxIn {
+			prevHash := txIn.PreviousOutPoint.Hash
+			if _, seen := ancestors[prevHash]; seen {
+				continue
+			}
+			if txDesc, exists := mp.pool[prevHash]; exists {
+				ancestors[prevHash] = txDesc
+				pending = append(pending, txDesc.Tx)
+			}
+		}
+	}
+	return ancestors
+}
+
+// poolDescendants returns the transactions in the pool which depend on the
+// passed transaction, directly or through other transactions in the pool.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *txMemPool) poolDescendants(tx *coinutil.Tx) map[wire.ShaHash]*coinutil.Tx {
+	descendants := make(map[wire.ShaHash]*coinutil.Tx)
+	pending := []*coinutil.Tx{tx}
+	for len(pending) > 0 {
+		next := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for i := range next.MsgTx().TxOut {
+			outpoint := wire.NewOutPoint(next.Sha(), uint32(i))
+			redeemer, exists := mp.outpoints[*outpoint]
+			if !exists {
+				continue
+			}
+			if _, seen := descendants[*redeemer.Sha()]; seen {
+				continue
+			}
+			descendants[*redeemer.Sha()] = redeemer
+			pending = append(pending, redeemer)
+		}
+	}
+	return descendants
+}
+
+// checkChainLimits ensures adding the passed transaction with the passed
+// virtual size to the pool keeps its chain of unconfirmed ancestors, and the
+// unconfirmed descendants of each of those ancestors, within the configured
+// limits.  Long chains of unconfirmed transactions are expensive to track and
+// to order when generating block templates, and would otherwise let anybody
+// bloat the pool by repeatedly spending the change of their own transactions.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *txMemPool) checkChainLimits(tx *coinutil.Tx, txVSize int64) error {
+	txHash := tx.Sha()
+	ancestors := mp.poolAncestors(tx)
+	ancestorSize := txVSize
+	for _, txDesc := range ancestors {
+		ancestorSize += blockchain.GetTxVirtualSize(txDesc.Tx)
+	}
+
+	ancestorCount := len(ancestors) + 1
+	if mp.cfg.MaxAncestorCount > 0 &&
+		ancestorCount > mp.cfg.MaxAncestorCount {
+
+		str := fmt.Sprintf("transaction %v would have %d unconfirmed "+
+			"ancestors including itself, which exceeds the limit of "+
+			"%d (ancestor size %d vbytes)", txHash, ancestorCount,
+			mp.cfg.MaxAncestorCount, ancestorSize)
+		return txRejectError(RejectReasonTooLongChain,
+			wire.RejectNonstandard, str)
+	}
+	if mp.cfg.MaxAncestorSize > 0 && ancestorSize > mp.cfg.MaxAncestorSize {
+		str := fmt.Sprintf("transaction %v would have unconfirmed "+
+			"ancestors of %d vbytes including itself, which exceeds "+
+			"the limit of %d vbytes (%d ancestors)", txHash,
+			ancestorSize, mp.cfg.MaxAncestorSize, ancestorCount)
+		return txRejectError(RejectReasonTooLongChain,
+			wire.RejectNonstandard, str)
+	}
+
+	if mp.cfg.MaxDescendantCount <= 0 && mp.cfg.MaxDescendantSize <= 0 {
+		return nil
+	}
+	for ancestorHash, ancestor := range ancestors {
+		descendants := mp.poolDescendants(ancestor.Tx)
+		descendantSize := blockchain.GetTxVirtualSize(ancestor.Tx) +
+			txVSize
+		for _, descendant := range descendants {
+			descendantSize += blockchain.GetTxVirtualSize(descendant)
+		}
+
+		// The ancestor itself and the new transaction are counted
+		// along with the existing descendants.
+		descendantCount := len(descendants) + 2
+		if mp.cfg.MaxDescendantCount > 0 &&
+			descendantCount > mp.cfg.MaxDescendantCount {
+
+			str := fmt.Sprintf("transaction %v would give unconfirmed "+
+				"ancestor %v %d descendants including itself, "+
+				"which exceeds the limit of %d (descendant size "+
+				"%d vbytes)", txHash, ancestorHash,
+				descendantCount, mp.cfg.MaxDescendantCount,
+				descendantSize)
+			return txRejectError(RejectReasonTooLongChain,
+				wire.RejectNonstandard, str)
+		}
+		if mp.cfg.MaxDescendantSize > 0 &&
+			descendantSize > mp.cfg.MaxDescendantSize {
+
+			str := fmt.Sprintf("transaction %v would give unconfirmed "+
+				"ancestor %v descendants of %d vbytes including "+
+				"itself, which exceeds the limit of %d vbytes (%d "+
+				"descendants)", txHash, ancestorHash,
+				descendantSize, mp.cfg.MaxDescendantSize,
+				descendantCount)
+			return txRejectError(RejectReasonTooLongChain,
+				wire.RejectNonstandard, str)
+		}
+	}
+	return nil
+}
+
 // maybeAcceptTransaction is the internal function which implements the public
 // MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
 // more details.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bool) ([]*wire.ShaHash, error) {
+func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bool, maxFeeRate coinutil.Amount) ([]*wire.ShaHash, error) {
 	txHash := tx.Sha()
 
 	// Don't accept the transaction if it already exists in the pool.  This
@@ -605,7 +1079,8 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 	// be a quick check to weed out duplicates.
 	if mp.haveTransaction(txHash) {
 		str := fmt.Sprintf("already have transaction %v", txHash)
-		return nil, txRuleError(wire.RejectDuplicate, str)
+		return nil, txRejectError(RejectReasonAlreadyKnown,
+			wire.RejectDuplicate, str)
 	}
 
 	// Perform preliminary sanity checks on the transaction.  This makes
@@ -636,6 +1111,22 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 		return nil, txRuleError(wire.RejectNonstandard, str)
 	}
 
+	// Don't accept transactions with witness data until the segregated
+	// witness soft fork is active since they could not be mined and would
+	// be considered invalid by nodes which do not support it.
+	if tx.MsgTx().HasWitness() {
+		segwitActive, err := mp.cfg.IsDeploymentActive(
+			chaincfg.DeploymentSegwit)
+		if err != nil {
+			return nil, err
+		}
+		if !segwitActive {
+			str := fmt.Sprintf("transaction %v has witness data, "+
+				"but segwit isn't active yet", txHash)
+			return nil, txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+
 	// Get the current height of the main chain.  A standalone transaction
 	// will be mined into the next block at best, so it's height is at least
 	// one more than the current height.
@@ -651,7 +1142,7 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 	// forbid their relaying.
 	if !activeNetParams.RelayNonStdTxs {
 		err := checkTransactionStandard(tx, nextBlockHeight,
-			mp.cfg.TimeSource, mp.cfg.MinRelayTxFee)
+			mp.cfg.TimeSource, &mp.cfg.Policy)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -676,6 +1167,7 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 	// which examines the actual spend data and prevents double spends.
 	err = mp.checkPoolDoubleSpend(tx)
 	if err != nil {
+		mp.reportDoubleSpend(tx, nextBlockHeight)
 		return nil, err
 	}
 
@@ -696,8 +1188,8 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 	if txD, exists := txStore[*txHash]; exists && txD.Err == nil {
 		for _, isOutputSpent := range txD.Spent {
 			if !isOutputSpent {
-				return nil, txRuleError(wire.RejectDuplicate,
-					"transaction already exists")
+				return nil, txRejectError(RejectReasonAlreadyKnown,
+					wire.RejectDuplicate, "transaction already exists")
 			}
 		}
 	}
@@ -764,9 +1256,9 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 		return nil, err
 	}
 	numSigOps += blockchain.CountSigOps(tx)
-	if numSigOps > maxSigOpsPerTx {
+	if numSigOps > mp.cfg.Policy.MaxSigOpsPerTx {
 		str := fmt.Sprintf("transaction %v has too many sigops: %d > %d",
-			txHash, numSigOps, maxSigOpsPerTx)
+			txHash, numSigOps, mp.cfg.Policy.MaxSigOpsPerTx)
 		return nil, txRuleError(wire.RejectNonstandard, str)
 	}
 
@@ -781,13 +1273,33 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 	// which is more desirable.  Therefore, as long as the size of the
 	// transaction does not exceeed 1000 less than the reserved space for
 	// high-priority transactions, don't require a fee for it.
-	serializedSize := int64(tx.MsgTx().SerializeSize())
-	minFee := calcMinRequiredTxRelayFee(serializedSize, mp.cfg.MinRelayTxFee)
-	if serializedSize >= (defaultBlockPrioritySize-1000) && txFee < minFee {
+	//
+	// The virtual size is used for the fee calculations so the witness
+	// data is discounted the same way it is when limiting the weight of
+	// blocks.
+	txVSize := blockchain.GetTxVirtualSize(tx)
+	minFee := calcMinRequiredTxRelayFee(txVSize, mp.cfg.MinRelayTxFee)
+	if txVSize >= (defaultBlockPrioritySize-1000) && txFee < minFee {
 		str := fmt.Sprintf("transaction %v has %d fees which is under "+
 			"the required amount of %d", txHash, txFee,
 			minFee)
-		return nil, txRuleError(wire.RejectInsufficientFee, str)
+		return nil, txRejectError(RejectReasonInsufficientFee,
+			wire.RejectInsufficientFee, str)
+	}
+
+	// Don't allow transactions which pay an absurdly high fee when the
+	// caller has requested a maximum fee rate.  This protects against
+	// accidentally burning coins due to a mistake such as forgetting a
+	// change output.
+	if maxFeeRate > 0 {
+		maxFee := calcMinRequiredTxRelayFee(txVSize, maxFeeRate)
+		if txFee > maxFee {
+			str := fmt.Sprintf("transaction %v has %d fees which is "+
+				"over the maximum allowed amount of %d", txHash,
+				txFee, maxFee)
+			return nil, txRejectError(RejectReasonHighFee,
+				wire.RejectNonstandard, str)
+		}
 	}
 
 	// Require that free transactions have sufficient priority to be mined
@@ -801,10 +1313,17 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 			str := fmt.Sprintf("transaction %v has insufficient "+
 				"priority (%g <= %g)", txHash,
 				currentPriority, minHighPriority)
-			return nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, txRejectError(RejectReasonInsufficientFee,
+				wire.RejectInsufficientFee, str)
 		}
 	}
 
+	// Don't allow transactions which would make a chain of unconfirmed
+	// transactions in the pool exceed the configured limits.
+	if err := mp.checkChainLimits(tx, txVSize); err != nil {
+		return nil, err
+	}
+
 	// Free-to-relay transactions are rate limited here to prevent
 	// penny-flooding with tiny transactions as a form of attack.
 	if rateLimit && txFee < minFee {
@@ -819,11 +1338,12 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 		if mp.pennyTotal >= mp.cfg.FreeTxRelayLimit*10*1000 {
 			str := fmt.Sprintf("transaction %v has been rejected "+
 				"by the rate limiter due to low fees", txHash)
-			return nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, txRejectError(RejectReasonInsufficientFee,
+				wire.RejectInsufficientFee, str)
 		}
 		oldTotal := mp.pennyTotal
 
-		mp.pennyTotal += float64(serializedSize)
+		mp.pennyTotal += float64(txVSize)
 		txmpLog.Tracef("rate limit: curTotal %v, nextTotal: %v, "+
 			"limit %v", oldTotal, mp.pennyTotal,
 			mp.cfg.FreeTxRelayLimit*10*1000)
@@ -831,13 +1351,72 @@ func (mp *txMemPool) maybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 
 	// Verify crypto signatures for each input and reject the transaction if
 	// any don't verify.
-	err = blockchain.ValidateTransactionScripts(tx, txStore,
-		txscript.StandardVerifyFlags, mp.cfg.SigCache)
+	err = blockchain.ValidateTransactionScriptsWithBudget(tx, txStore,
+		txscript.StandardVerifyFlags, mp.cfg.SigCache,
+		mp.cfg.ScriptBudget)
 	if err != nil {
-		if cerr, ok := err.(blockchain.RuleError); ok {
+		cerr, ok := err.(blockchain.RuleError)
+		if !ok {
+			return nil, err
+		}
+
+		// Scripts which exceed the execution budget are rejected
+		// without spending more of it to check them again.
+		if cerr.ErrorCode == blockchain.ErrScriptBudgetExceeded {
 			return nil, chainRuleError(cerr)
 		}
-		return nil, err
+
+		// Check the scripts again with only the flags enforced by the
+		// consensus rules in order to tell transactions which are
+		// invalid apart from those which are merely non-standard, for
+		// example because of a high S value or a hybrid public key.
+		// The malleability checks are part of the consensus rules once
+		// their rule change is active.
+		mandatoryFlags := mandatoryVerifyFlags
+		malleabilityActive, err := mp.cfg.IsDeploymentActive(
+			chaincfg.DeploymentMalleability)
+		if err != nil {
+			return nil, err
+		}
+		if malleabilityActive {
+			mandatoryFlags |= malleabilityVerifyFlags
+		}
+		err = blockchain.ValidateTransactionScriptsWithBudget(tx,
+			txStore, mandatoryFlags, mp.cfg.SigCache,
+			mp.cfg.ScriptBudget)
+		if err != nil {
+			return nil, chainRuleError(cerr)
+		}
+
+		// Report the flag which the scripts fail so clients can tell
+		// which of the policy checks, such as the malleability ones,
+		// rejected the transaction.
+		flagName, flagErr := failedPolicyVerifyFlag(tx, txStore,
+			mandatoryFlags, mp.cfg.SigCache)
+		if flagName == "" {
+			str := fmt.Sprintf("transaction %v is not standard: "+
+				"non-mandatory script verify flag failed: %v",
+				txHash, cerr.Description)
+			return nil, txRuleError(wire.RejectNonstandard, str)
+		}
+		str := fmt.Sprintf("transaction %v is not standard: "+
+			"non-mandatory script verify flag %s failed: %v",
+			txHash, flagName, flagErr)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	// Give the custom policy registered with the server a chance to veto
+	// the transaction.  Hooks can choose the reject code by returning a
+	// RuleError, while other errors are reported as non-standard.
+	if mp.cfg.CheckTx != nil {
+		if err := mp.cfg.CheckTx(tx, txFee); err != nil {
+			if rerr, ok := err.(RuleError); ok {
+				return nil, rerr
+			}
+			str := fmt.Sprintf("transaction %v rejected by policy: %v",
+				txHash, err)
+			return nil, txRuleError(wire.RejectNonstandard, str)
+		}
 	}
 
 	// Add to transaction pool.
@@ -865,7 +1444,7 @@ func (mp *txMemPool) MaybeAcceptTransaction(tx *coinutil.Tx, isNew, rateLimit bo
 	mp.Lock()
 	defer mp.Unlock()
 
-	return mp.maybeAcceptTransaction(tx, isNew, rateLimit)
+	return mp.maybeAcceptTransaction(tx, isNew, rateLimit, 0)
 }
 
 // processOrphans is the internal function which implements the public
@@ -911,7 +1490,7 @@ func (mp *txMemPool) processOrphans(hash *wire.ShaHash) {
 			// Potentially accept the transaction into the
 			// transaction pool.
 			missingParents, err := mp.maybeAcceptTransaction(tx,
-				true, true)
+				true, true, 0)
 			if err != nil {
 				// TODO: Remove orphans that depend on this
 				// failed transaction.
@@ -969,8 +1548,12 @@ func (mp *txMemPool) ProcessOrphans(hash *wire.ShaHash) {
 // such as rejecting duplicate transactions, ensuring transactions follow all
 // rules, orphan transaction handling, and insertion into the memory pool.
 //
+// The maxFeeRate parameter specifies the maximum fee rate, in satoshi per
+// kilobyte, the transaction is allowed to pay.  Transactions which exceed it
+// are rejected.  A value of zero disables the check.
+//
 // This function is safe for concurrent access.
-func (mp *txMemPool) ProcessTransaction(tx *coinutil.Tx, allowOrphan, rateLimit bool) error {
+func (mp *txMemPool) ProcessTransaction(tx *coinutil.Tx, allowOrphan, rateLimit bool, maxFeeRate coinutil.Amount) error {
 	// Protect concurrent access.
 	mp.Lock()
 	defer mp.Unlock()
@@ -978,7 +1561,8 @@ func (mp *txMemPool) ProcessTransaction(tx *coinutil.Tx, allowOrphan, rateLimit
 	txmpLog.Tracef("Processing transaction %v", tx.Sha())
 
 	// Potentially accept the transaction to the memory pool.
-	missingParents, err := mp.maybeAcceptTransaction(tx, true, rateLimit)
+	missingParents, err := mp.maybeAcceptTransaction(tx, true, rateLimit,
+		maxFeeRate)
 	if err != nil {
 		return err
 	}
@@ -1010,7 +1594,8 @@ func (mp *txMemPool) ProcessTransaction(tx *coinutil.Tx, allowOrphan, rateLimit
 			str := fmt.Sprintf("orphan transaction %v references "+
 				"outputs of unknown or fully-spent "+
 				"transaction %v", tx.Sha(), missingParents[0])
-			return txRuleError(wire.RejectDuplicate, str)
+			return txRejectError(RejectReasonMissingInputs,
+				wire.RejectDuplicate, str)
 		}
 
 		// Potentially add the orphan transaction to the orphan pool.
@@ -1107,9 +1692,11 @@ func newTxMemPool(cfg *mempoolConfig) *txMemPool {
 	memPool := &txMemPool{
 		cfg:           *cfg,
 		pool:          make(map[wire.ShaHash]*mempoolTxDesc),
+		wtxids:        make(map[wire.ShaHash]wire.ShaHash),
 		orphans:       make(map[wire.ShaHash]*coinutil.Tx),
 		orphansByPrev: make(map[wire.ShaHash]map[wire.ShaHash]*coinutil.Tx),
 		outpoints:     make(map[wire.OutPoint]*coinutil.Tx),
+		doubleSpends:  make(map[wire.ShaHash]struct{}),
 	}
 	if cfg.EnableAddrIndex {
 		memPool.addrindex = make(map[string]map[wire.ShaHash]struct{})
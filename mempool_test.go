@@ -0,0 +1,216 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestExpireTransactions ensures transactions which stayed in the memory pool
+// for longer than the expiry are evicted along with the transactions relying
+// on them and remembered as evicted.
+func TestExpireTransactions(t *testing.T) {
+	mp := newTxMemPool(&mempoolConfig{Expiry: time.Hour})
+
+	// newTx returns a transaction spending the passed outpoint.
+	newTx := func(prevOut *wire.OutPoint) *coinutil.Tx {
+		msgTx := wire.NewMsgTx()
+		msgTx.AddTxIn(wire.NewTxIn(prevOut, nil))
+		msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+		return coinutil.NewTx(msgTx)
+	}
+	parent := newTx(&wire.OutPoint{Hash: wire.ShaHash{0x01}})
+	child := newTx(wire.NewOutPoint(parent.Sha(), 0))
+	other := newTx(&wire.OutPoint{Hash: wire.ShaHash{0x02}})
+
+	now := time.Now()
+	for _, tx := range []*coinutil.Tx{parent, child, other} {
+		mp.addTransaction(blockchain.TxStore{}, tx, 1, 0)
+	}
+	mp.pool[*parent.Sha()].Added = now.Add(-time.Hour * 2)
+	mp.pool[*child.Sha()].Added = now.Add(-time.Minute)
+	mp.pool[*other.Sha()].Added = now.Add(-time.Minute)
+
+	// The parent expired, so the child relying on it is evicted as well
+	// while the unrelated transaction stays.
+	expired := mp.ExpireTransactions(now)
+	if len(expired) != 2 || expired[0] != child || expired[1] != parent {
+		t.Fatalf("ExpireTransactions: got %v, want child and parent",
+			expired)
+	}
+	if mp.Count() != 1 || !mp.IsTransactionInPool(other.Sha()) {
+		t.Fatalf("ExpireTransactions: unexpected pool of %d "+
+			"transactions", mp.Count())
+	}
+	if mp.CheckSpend(*wire.NewOutPoint(parent.Sha(), 0)) != nil {
+		t.Fatalf("ExpireTransactions: outpoint spent by the evicted " +
+			"child is still spent by the pool")
+	}
+
+	evicted := mp.EvictedTxDescs()
+	if len(evicted) != 2 || evicted[0].Tx != child ||
+		evicted[1].Tx != parent || !evicted[1].Evicted.Equal(now) ||
+		!evicted[1].Added.Equal(now.Add(-time.Hour*2)) {
+
+		t.Fatalf("EvictedTxDescs: unexpected descriptors %+v", evicted)
+	}
+
+	// Nothing else expires until the remaining transaction is old enough.
+	if expired := mp.ExpireTransactions(now); len(expired) != 0 {
+		t.Fatalf("ExpireTransactions: got %v, want none", expired)
+	}
+	expired = mp.ExpireTransactions(now.Add(time.Hour))
+	if len(expired) != 1 || expired[0] != other {
+		t.Fatalf("ExpireTransactions: got %v, want the remaining "+
+			"transaction", expired)
+	}
+
+	// Expiry is disabled without a configured duration.
+	mp = newTxMemPool(&mempoolConfig{})
+	mp.addTransaction(blockchain.TxStore{}, parent, 1, 0)
+	if expired := mp.ExpireTransactions(now.Add(time.Hour * 1000)); len(expired) != 0 {
+		t.Fatalf("ExpireTransactions: got %v with expiry disabled",
+			expired)
+	}
+}
+
+// TestMempoolDeltas ensures every transaction added to or removed from the
+// memory pool is reported in order along with the reason of the removal.
+func TestMempoolDeltas(t *testing.T) {
+	var deltas []string
+	names := make(map[wire.ShaHash]string)
+	mp := newTxMemPool(&mempoolConfig{
+		Expiry: time.Hour,
+		NotifyTxAdded: func(txDesc *mempoolTxDesc) {
+			deltas = append(deltas, "add "+names[*txDesc.Tx.Sha()])
+		},
+		NotifyTxRemoved: func(tx *coinutil.Tx, reason txRemovalReason) {
+			deltas = append(deltas, "remove "+names[*tx.Sha()]+" "+
+				reason.String())
+		},
+	})
+
+	// newTx returns a transaction spending the passed outpoint.
+	newTx := func(name string, prevOut *wire.OutPoint) *coinutil.Tx {
+		msgTx := wire.NewMsgTx()
+		msgTx.AddTxIn(wire.NewTxIn(prevOut, nil))
+		msgTx.AddTxOut(wire.NewTxOut(int64(1000+len(names)),
+			[]byte{0x51}))
+		tx := coinutil.NewTx(msgTx)
+		names[*tx.Sha()] = name
+		return tx
+	}
+	mined := newTx("mined", &wire.OutPoint{Hash: wire.ShaHash{0x01}})
+	child := newTx("child", wire.NewOutPoint(mined.Sha(), 0))
+	conflicted := newTx("conflicted", &wire.OutPoint{Hash: wire.ShaHash{0x02}})
+	stale := newTx("stale", &wire.OutPoint{Hash: wire.ShaHash{0x03}})
+	conflict := newTx("conflict", &wire.OutPoint{Hash: wire.ShaHash{0x02}})
+
+	for _, tx := range []*coinutil.Tx{mined, child, conflicted, stale} {
+		mp.addTransaction(blockchain.TxStore{}, tx, 1, 0)
+	}
+	now := time.Now()
+	mp.pool[*stale.Sha()].Added = now.Add(-time.Hour * 2)
+
+	// The mined transaction and the transaction conflicting with the block
+	// leave the pool while the child of the mined transaction stays.  The
+	// child is evicted once the mined transaction couldn't return to the
+	// pool after its block was disconnected.
+	mp.RemoveTransaction(mined, false, txRemovedBlock)
+	mp.RemoveDoubleSpends(conflict)
+	mp.RemoveTransaction(mined, true, txRemovedEviction)
+	mp.ExpireTransactions(now)
+
+	want := []string{
+		"add mined", "add child", "add conflicted", "add stale",
+		"remove mined block",
+		"remove conflicted conflict",
+		"remove child eviction",
+		"remove stale expiry",
+	}
+	if !reflect.DeepEqual(deltas, want) {
+		t.Fatalf("deltas: got %v, want %v", deltas, want)
+	}
+}
+
+// TestChainLimits ensures transactions which would make a chain of unconfirmed
+// transactions in the memory pool exceed the ancestor or descendant limits are
+// rejected with an error naming the violated limit.
+func TestChainLimits(t *testing.T) {
+	// newTx returns a transaction spending the passed outpoints.
+	newTx := func(prevOuts ...*wire.OutPoint) *coinutil.Tx {
+		msgTx := wire.NewMsgTx()
+		for _, prevOut := range prevOuts {
+			msgTx.AddTxIn(wire.NewTxIn(prevOut, nil))
+		}
+		msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+		msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+		return coinutil.NewTx(msgTx)
+	}
+	parent := newTx(&wire.OutPoint{Hash: wire.ShaHash{0x01}})
+	child := newTx(wire.NewOutPoint(parent.Sha(), 0))
+	grandchild := newTx(wire.NewOutPoint(child.Sha(), 0))
+	sibling := newTx(wire.NewOutPoint(parent.Sha(), 1))
+	txVSize := blockchain.GetTxVirtualSize(grandchild)
+
+	tests := []struct {
+		name   string
+		cfg    mempoolConfig
+		tx     *coinutil.Tx
+		reject bool
+	}{
+		{"no limits", mempoolConfig{}, grandchild, false},
+		{"ancestor count", mempoolConfig{MaxAncestorCount: 3},
+			grandchild, false},
+		{"ancestor count exceeded", mempoolConfig{MaxAncestorCount: 2},
+			grandchild, true},
+		{"ancestor size", mempoolConfig{MaxAncestorSize: txVSize * 3},
+			grandchild, false},
+		{"ancestor size exceeded",
+			mempoolConfig{MaxAncestorSize: txVSize*3 - 1}, grandchild,
+			true},
+		{"descendant count", mempoolConfig{MaxDescendantCount: 3},
+			sibling, false},
+		{"descendant count of chain", mempoolConfig{MaxDescendantCount: 3},
+			grandchild, false},
+		{"descendant count of parent exceeded",
+			mempoolConfig{MaxDescendantCount: 2}, sibling, true},
+		{"descendant size exceeded",
+			mempoolConfig{MaxDescendantSize: txVSize*3 - 1}, sibling,
+			true},
+	}
+
+	for _, test := range tests {
+		mp := newTxMemPool(&test.cfg)
+		for _, tx := range []*coinutil.Tx{parent, child} {
+			mp.addTransaction(blockchain.TxStore{}, tx, 1, 0)
+		}
+
+		err := mp.checkChainLimits(test.tx,
+			blockchain.GetTxVirtualSize(test.tx))
+		if !test.reject {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		rerr, ok := err.(RuleError)
+		if !ok {
+			t.Errorf("%s: got %v, want a rule error", test.name, err)
+			continue
+		}
+		txErr, ok := rerr.Err.(TxRuleError)
+		if !ok || txErr.Reason != RejectReasonTooLongChain {
+			t.Errorf("%s: got %v, want a too long chain rejection",
+				test.name, err)
+		}
+	}
+}
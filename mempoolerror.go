@@ -5,6 +5,8 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/conseweb/stcd/blockchain"
 	"github.com/conseweb/stcd/wire"
 )
@@ -27,13 +29,67 @@ func (e RuleError) Error() string {
 	return e.Err.Error()
 }
 
+// TxRejectReason identifies the specific reason a transaction was rejected
+// from the memory pool.  It is more granular than the wire reject codes which
+// are limited to what the peer-to-peer protocol defines, and is primarily
+// intended to allow callers such as the RPC server to distinguish between the
+// various rejection conditions.
+type TxRejectReason int
+
+// These constants are used to identify the reason for a TxRuleError.
+const (
+	// RejectReasonOther indicates the transaction was rejected for a
+	// reason that does not have a more specific classification.
+	RejectReasonOther TxRejectReason = iota
+
+	// RejectReasonAlreadyKnown indicates the transaction already exists
+	// in the memory pool, the orphan pool, or the main chain.
+	RejectReasonAlreadyKnown
+
+	// RejectReasonMissingInputs indicates the transaction references
+	// outputs of unknown or fully-spent transactions.
+	RejectReasonMissingInputs
+
+	// RejectReasonInsufficientFee indicates the transaction does not pay
+	// enough fees or have enough priority to be accepted.
+	RejectReasonInsufficientFee
+
+	// RejectReasonTooLongChain indicates accepting the transaction would
+	// exceed the limits on chains of unconfirmed transactions.
+	RejectReasonTooLongChain
+
+	// RejectReasonHighFee indicates the transaction pays a fee that is
+	// higher than the maximum fee rate the caller is willing to accept.
+	RejectReasonHighFee
+)
+
+// Map of TxRejectReason values back to their constant names for pretty
+// printing.
+var txRejectReasonStrings = map[TxRejectReason]string{
+	RejectReasonOther:           "RejectReasonOther",
+	RejectReasonAlreadyKnown:    "RejectReasonAlreadyKnown",
+	RejectReasonMissingInputs:   "RejectReasonMissingInputs",
+	RejectReasonInsufficientFee: "RejectReasonInsufficientFee",
+	RejectReasonTooLongChain:    "RejectReasonTooLongChain",
+	RejectReasonHighFee:         "RejectReasonHighFee",
+}
+
+// String returns the TxRejectReason as a human-readable name.
+func (r TxRejectReason) String() string {
+	if s := txRejectReasonStrings[r]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown TxRejectReason (%d)", int(r))
+}
+
 // TxRuleError identifies a rule violation.  It is used to indicate that
 // processing of a transaction failed due to one of the many validation
 // rules.  The caller can use type assertions to determine if a failure was
-// specifically due to a rule violation and access the ErrorCode field to
-// ascertain the specific reason for the rule violation.
+// specifically due to a rule violation and access the RejectCode and Reason
+// fields to ascertain the specific reason for the rule violation.
 type TxRuleError struct {
 	RejectCode  wire.RejectCode // The code to send with reject messages
+	Reason      TxRejectReason  // The specific reason for the rejection
 	Description string          // Human readable description of the issue
 }
 
@@ -50,6 +106,15 @@ func txRuleError(c wire.RejectCode, desc string) RuleError {
 	}
 }
 
+// txRejectError creates an underlying TxRuleError with the given rejection
+// reason in addition to the reject code and description and returns a
+// RuleError that encapsulates it.
+func txRejectError(reason TxRejectReason, c wire.RejectCode, desc string) RuleError {
+	return RuleError{
+		Err: TxRuleError{RejectCode: c, Reason: reason, Description: desc},
+	}
+}
+
 // chainRuleError returns a RuleError that encapsulates the given
 // blockchain.RuleError.
 func chainRuleError(chainErr blockchain.RuleError) RuleError {
@@ -92,6 +157,11 @@ func extractRejectCode(err error) (wire.RejectCode, bool) {
 		case blockchain.ErrForkTooOld:
 			code = wire.RejectCheckpoint
 
+		// Rejected due to exceeding the local script execution budget,
+		// which does not make the transaction invalid.
+		case blockchain.ErrScriptBudgetExceeded:
+			code = wire.RejectNonstandard
+
 		// Everything else is due to the block or transaction being invalid.
 		default:
 			code = wire.RejectInvalid
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"bytes"
 	"container/heap"
 	"container/list"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/database"
 	"github.com/conseweb/stcd/mining"
 	"github.com/conseweb/stcd/txscript"
@@ -19,16 +21,9 @@ import (
 )
 
 const (
-	// generatedBlockVersion is the version of the block being generated.
-	// It is defined as a constant here rather than using the
-	// wire.BlockVersion constant since a change in the block version
-	// will require changes to the generated block.  Using the wire constant
-	// for generated block version could allow creation of invalid blocks
-	// for the updated version.
-	generatedBlockVersion = 4
-
 	// minHighPriority is the minimum priority value that allows a
-	// transaction to be considered high priority.
+	// transaction to be considered high priority.  It is the default of
+	// the minimum priority of the high-priority area of block templates.
 	minHighPriority = coinutil.SatoshiPerBitcoin * 144.0 / 250
 
 	// blockHeaderOverhead is the max number of bytes it takes to serialize
@@ -39,8 +34,80 @@ const (
 	// and is used to monitor BIP16 support as well as blocks that are
 	// generated via btcd.
 	coinbaseFlags = "/P2SH/btcd/"
+
+	// maxCoinbaseFlagsLen is the maximum length of custom coinbase flags.
+	// It leaves room in the coinbase script for the pushes of the largest
+	// block height and extra nonce along with the push of the flags
+	// themselves.
+	maxCoinbaseFlagsLen = blockchain.MaxCoinbaseScriptLen - 5 - 9 - 2
+
+	// maxTemplateExclusions is the maximum number of near-miss exclusions
+	// recorded in the selection audit trail of a block template.
+	maxTemplateExclusions = 25
+)
+
+// Reasons recorded in the selection audit trail of a block template for why a
+// transaction was selected.
+const (
+	// txSelectedPriority means the transaction was selected for the
+	// high-priority area of the block.
+	txSelectedPriority = "priority"
+
+	// txSelectedFeeRate means the transaction was selected by its fee per
+	// kilobyte.
+	txSelectedFeeRate = "feerate"
+
+	// txSelectedMinBlockSize means the transaction pays less than the
+	// minimum fee rate, but was selected to fill the minimum block size.
+	txSelectedMinBlockSize = "minblocksize"
+)
+
+// Reasons recorded in the selection audit trail of a block template for why a
+// transaction was excluded although it was otherwise ready for inclusion.
+const (
+	txExcludedBlockSize   = "blocksize"
+	txExcludedBlockWeight = "blockweight"
+	txExcludedSigOps      = "sigops"
+	txExcludedMinFeeRate  = "minfeerate"
 )
 
+// txSelection records why a transaction was selected for a block template.
+type txSelection struct {
+	// reason is one of the txSelected reasons and rank is the 1-based
+	// position of the transaction among those selected for the same
+	// reason.
+	reason string
+	rank   int
+
+	// inPackage is set when the transaction depends on transactions
+	// which were selected before it.
+	inPackage bool
+
+	feePerKB int64
+	priority float64
+}
+
+// txExclusion records a transaction which was ready for inclusion in a block
+// template, but was excluded by the policy or the block limits.
+type txExclusion struct {
+	hash     wire.ShaHash
+	reason   string
+	feePerKB int64
+	priority float64
+}
+
+// CoinbaseOptions customizes the coinbase transaction of a block template.
+// The zero value describes the standard coinbase.
+type CoinbaseOptions struct {
+	// Flags replaces the coinbase flags which are pushed after the extra
+	// nonce in the coinbase script when it is not nil.
+	Flags []byte
+
+	// PkScript replaces the public key script of the output which
+	// collects the block reward when it is not nil.
+	PkScript []byte
+}
+
 // txPrioItem houses a transaction along with extra information that allows the
 // transaction to be prioritized and track dependencies on other transactions
 // which have not been mined into a block yet.
@@ -50,6 +117,10 @@ type txPrioItem struct {
 	priority float64
 	feePerKB int64
 
+	// sortFeePerKB is the fee per kilobyte the transaction is ordered by.
+	// It differs from feePerKB when the policy weights recent transactions.
+	sortFeePerKB int64
+
 	// dependsOn holds a map of transaction hashes which this one depends
 	// on.  It will only be set when the transaction references other
 	// transactions in the source pool and hence must come after them in
@@ -117,7 +188,10 @@ func txPQByPriority(pq *txPriorityQueue, i, j int) bool {
 	// Using > here so that pop gives the highest priority item as opposed
 	// to the lowest.  Sort by priority first, then fee.
 	if pq.items[i].priority == pq.items[j].priority {
-		return pq.items[i].feePerKB > pq.items[j].feePerKB
+		if pq.items[i].sortFeePerKB == pq.items[j].sortFeePerKB {
+			return txPQByHash(pq, i, j)
+		}
+		return pq.items[i].sortFeePerKB > pq.items[j].sortFeePerKB
 	}
 	return pq.items[i].priority > pq.items[j].priority
 
@@ -128,10 +202,21 @@ func txPQByPriority(pq *txPriorityQueue, i, j int) bool {
 func txPQByFee(pq *txPriorityQueue, i, j int) bool {
 	// Using > here so that pop gives the highest fee item as opposed
 	// to the lowest.  Sort by fee first, then priority.
-	if pq.items[i].feePerKB == pq.items[j].feePerKB {
+	if pq.items[i].sortFeePerKB == pq.items[j].sortFeePerKB {
+		if pq.items[i].priority == pq.items[j].priority {
+			return txPQByHash(pq, i, j)
+		}
 		return pq.items[i].priority > pq.items[j].priority
 	}
-	return pq.items[i].feePerKB > pq.items[j].feePerKB
+	return pq.items[i].sortFeePerKB > pq.items[j].sortFeePerKB
+}
+
+// txPQByHash sorts a txPriorityQueue by transaction hash.  It breaks ties
+// between transactions with the same priority and fees so the selection of
+// transactions for a block template doesn't depend on the order they are
+// provided in.
+func txPQByHash(pq *txPriorityQueue, i, j int) bool {
+	return bytes.Compare(pq.items[i].tx.Sha()[:], pq.items[j].tx.Sha()[:]) < 0
 }
 
 // newTxPriorityQueue returns a new transaction priority queue that reserves the
@@ -154,13 +239,33 @@ func newTxPriorityQueue(reserve int, sortByFee bool) *txPriorityQueue {
 
 // BlockTemplate houses a block that has yet to be solved along with additional
 // details about the fees and the number of signature operations for each
-// transaction in the block.
+// transaction in the block, and the state of each rule change deployment the
+// block version was chosen for.
 type BlockTemplate struct {
-	block           *wire.MsgBlock
-	fees            []int64
-	sigOpCounts     []int64
-	height          int32
-	validPayAddress bool
+	block            *wire.MsgBlock
+	fees             []int64
+	sigOpCounts      []int64
+	height           int32
+	validPayAddress  bool
+	deploymentStates [chaincfg.DefinedDeployments]blockchain.ThresholdState
+
+	// witnessCommitment is the public key script of the coinbase output
+	// which commits to the witness data of the block.  It is nil when the
+	// segregated witness soft fork is not active.
+	witnessCommitment []byte
+
+	// utxoCommitment is the public key script of the coinbase output which
+	// commits to the unspent transaction outputs as of the previous block.
+	// It is nil when the UTXO commitment rule change is not active.
+	utxoCommitment []byte
+
+	// selections records why each transaction of the block was selected.
+	// The entry for the coinbase is empty.  exclusions holds the first
+	// transactions which were excluded by the policy or the block limits,
+	// in the order they were considered.  Together they form the audit
+	// trail of the transaction selection.
+	selections []txSelection
+	exclusions []txExclusion
 }
 
 // mergeTxStore adds all of the transactions in txStoreB to txStoreA.  The
@@ -180,31 +285,22 @@ func mergeTxStore(txStoreA blockchain.TxStore, txStoreB blockchain.TxStore) {
 // standardCoinbaseScript returns a standard script suitable for use as the
 // signature script of the coinbase transaction of a new block.  In particular,
 // it starts with the block height that is required by version 2 blocks and adds
-// the extra nonce as well as additional coinbase flags.
-func standardCoinbaseScript(nextBlockHeight int32, extraNonce uint64) ([]byte, error) {
+// the extra nonce as well as the passed coinbase flags.
+func standardCoinbaseScript(nextBlockHeight int32, extraNonce uint64, flags []byte) ([]byte, error) {
 	return txscript.NewScriptBuilder().AddInt64(int64(nextBlockHeight)).
-		AddInt64(int64(extraNonce)).AddData([]byte(coinbaseFlags)).
-		Script()
+		AddInt64(int64(extraNonce)).AddData(flags).Script()
 }
 
-// createCoinbaseTx returns a coinbase transaction paying an appropriate subsidy
-// based on the passed block height to the provided address.  When the address
-// is nil, the coinbase transaction will instead be redeemable by anyone.
+// createCoinbaseTx returns a coinbase transaction paying the passed subsidy to
+// the provided public key script.  When the script is nil, the coinbase
+// transaction will instead be redeemable by anyone.
 //
 // See the comment for NewBlockTemplate for more information about why the nil
-// address handling is useful.
-func createCoinbaseTx(coinbaseScript []byte, nextBlockHeight int32, addr coinutil.Address) (*coinutil.Tx, error) {
-	// Create the script to pay to the provided payment address if one was
-	// specified.  Otherwise create a script that allows the coinbase to be
-	// redeemable by anyone.
-	var pkScript []byte
-	if addr != nil {
-		var err error
-		pkScript, err = txscript.PayToAddrScript(addr)
-		if err != nil {
-			return nil, err
-		}
-	} else {
+// script handling is useful.
+func createCoinbaseTx(coinbaseScript []byte, subsidy int64, pkScript []byte) (*coinutil.Tx, error) {
+	// Create a script that allows the coinbase to be redeemable by anyone
+	// when no script to pay to was specified.
+	if pkScript == nil {
 		var err error
 		scriptBuilder := txscript.NewScriptBuilder()
 		pkScript, err = scriptBuilder.AddOp(txscript.OP_TRUE).Script()
@@ -223,13 +319,47 @@ func createCoinbaseTx(coinbaseScript []byte, nextBlockHeight int32, addr coinuti
 		Sequence:        wire.MaxTxInSequenceNum,
 	})
 	tx.AddTxOut(&wire.TxOut{
-		Value: blockchain.CalcBlockSubsidy(nextBlockHeight,
-			activeNetParams.Params),
+		Value:    subsidy,
 		PkScript: pkScript,
 	})
 	return coinutil.NewTx(tx), nil
 }
 
+// addWitnessCommitment adds the witness reserved value to the coinbase and an
+// output which commits to the witness data of the passed block transactions,
+// which must start with the coinbase.  The commitment output is replaced when
+// it has already been added.  The public key script of the commitment output
+// is returned.
+func addWitnessCommitment(coinbaseTx *coinutil.Tx, blockTxns []*coinutil.Tx) []byte {
+	// The witness of the coinbase is the reserved value which is combined
+	// with the witness merkle root to create the commitment.
+	var witnessNonce [blockchain.CoinbaseWitnessDataLen]byte
+	msgTx := coinbaseTx.MsgTx()
+	msgTx.TxIn[0].Witness = wire.TxWitness{witnessNonce[:]}
+
+	// The witness merkle root does not depend on the coinbase since its
+	// wtxid is defined to be all zeroes.
+	witnessMerkleTree := blockchain.BuildMerkleTreeStore(blockTxns, true)
+	witnessMerkleRoot := witnessMerkleTree[len(witnessMerkleTree)-1]
+
+	var witnessPreimage [wire.HashSize * 2]byte
+	copy(witnessPreimage[:], witnessMerkleRoot[:])
+	copy(witnessPreimage[wire.HashSize:], witnessNonce[:])
+	witnessCommitment := wire.DoubleSha256(witnessPreimage[:])
+
+	pkScript := make([]byte, 0, blockchain.CoinbaseWitnessPkScriptLength)
+	pkScript = append(pkScript, blockchain.WitnessMagicBytes...)
+	pkScript = append(pkScript, witnessCommitment...)
+
+	lastOut := msgTx.TxOut[len(msgTx.TxOut)-1]
+	if _, ok := blockchain.ExtractWitnessCommitment(coinbaseTx); ok {
+		lastOut.PkScript = pkScript
+	} else {
+		msgTx.AddTxOut(wire.NewTxOut(0, pkScript))
+	}
+	return pkScript
+}
+
 // spendTransaction updates the passed transaction store by marking the inputs
 // to the passed transaction as spent.  It also adds the passed transaction to
 // the store at the provided height.
@@ -267,6 +397,17 @@ func logSkippedDeps(tx *coinutil.Tx, deps *list.List) {
 	}
 }
 
+// weightedFeePerKB returns the fee per kilobyte the passed transaction is
+// ordered by when generating a block template.  The fee per kilobyte of
+// transactions which were added to the source pool more recently than the
+// RecentTxAge policy setting is multiplied by the RecentTxWeight setting.
+func weightedFeePerKB(policy *mining.Policy, txDesc *mining.TxDesc, feePerKB int64) int64 {
+	if policy.RecentTxAge <= 0 || time.Since(txDesc.Added) >= policy.RecentTxAge {
+		return feePerKB
+	}
+	return int64(float64(feePerKB) * policy.RecentTxWeight)
+}
+
 // minimumMedianTime returns the minimum allowed timestamp for a block building
 // on the end of the current best chain.  In particular, it is one second after
 // the median timestamp of the last several blocks per the chain consensus
@@ -369,19 +510,53 @@ func medianAdjustedTime(chainState *chainState, timeSource blockchain.MedianTime
 //  |  <= policy.BlockMinSize)          |   |
 //   -----------------------------------  --
 func NewBlockTemplate(policy *mining.Policy, server *server, payToAddress coinutil.Address) (*BlockTemplate, error) {
+	var pkScript []byte
+	if payToAddress != nil {
+		var err error
+		pkScript, err = txscript.PayToAddrScript(payToAddress)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newBlockTemplate(policy, server, pkScript)
+}
+
+// newBlockTemplate returns a new block template like NewBlockTemplate, except
+// the coinbase pays to the passed public key script, or is redeemable by anyone
+// when the script is nil.
+func newBlockTemplate(policy *mining.Policy, server *server, pkScript []byte) (*BlockTemplate, error) {
 	var txSource mining.TxSource = server.txMemPool
 	blockManager := server.blockManager
 	timeSource := server.timeSource
 	chainState := &blockManager.chainState
 
 	// Extend the most recently known best block.
+	// The block version signals the rule change deployments which are
+	// being voted on or are locked in.
 	chainState.Lock()
 	prevHash := chainState.newestHash
 	nextBlockHeight := chainState.newestHeight + 1
+	blockVersion := chainState.nextBlockVersion
+	deploymentStates := chainState.deploymentStates
+	blockVersionErr := chainState.deploymentStatesErr
 	chainState.Unlock()
+	if blockVersionErr != nil {
+		return nil, blockVersionErr
+	}
+
+	// Signal the block version set with the setblockversion command
+	// instead of the computed one on the test networks.
+	if version := server.BlockVersionOverride(); version != 0 {
+		blockVersion = version
+	}
+	segwitActive := deploymentStates[chaincfg.DeploymentSegwit] ==
+		blockchain.ThresholdActive
+	utxoCommitmentActive :=
+		deploymentStates[chaincfg.DeploymentUtxoCommitment] ==
+			blockchain.ThresholdActive
 
 	// Create a standard coinbase transaction paying to the provided
-	// address.  NOTE: The coinbase value will be updated to include the
+	// script.  NOTE: The coinbase value will be updated to include the
 	// fees from the selected transactions later after they have actually
 	// been selected.  It is created here to detect any errors early
 	// before potentially doing a lot of work below.  The extra nonce helps
@@ -389,17 +564,40 @@ func NewBlockTemplate(policy *mining.Policy, server *server, payToAddress coinut
 	// same value to the same public key address would otherwise be an
 	// identical transaction for block version 1).
 	extraNonce := uint64(0)
-	coinbaseScript, err := standardCoinbaseScript(nextBlockHeight, extraNonce)
+	coinbaseScript, err := standardCoinbaseScript(nextBlockHeight, extraNonce,
+		[]byte(coinbaseFlags))
 	if err != nil {
 		return nil, err
 	}
-	coinbaseTx, err := createCoinbaseTx(coinbaseScript, nextBlockHeight,
-		payToAddress)
+	subsidy := blockManager.CalcBlockSubsidy(nextBlockHeight)
+	coinbaseTx, err := createCoinbaseTx(coinbaseScript, subsidy, pkScript)
 	if err != nil {
 		return nil, err
 	}
 	numCoinbaseSigOps := int64(blockchain.CountSigOps(coinbaseTx))
 
+	// Once the UTXO commitment rule change is active, the coinbase must
+	// commit to the unspent transaction outputs as of the block being
+	// extended.  The commitment output is added before the witness
+	// commitment since the latter is the last output of the coinbase.
+	var utxoCommitment []byte
+	if utxoCommitmentActive {
+		utxoSetHash, err := blockManager.UtxoSetHash(prevHash)
+		if err != nil {
+			return nil, err
+		}
+		utxoCommitment = blockchain.UtxoCommitmentScript(utxoSetHash)
+		coinbaseTx.MsgTx().AddTxOut(wire.NewTxOut(0, utxoCommitment))
+	}
+
+	// Once the segregated witness soft fork is active, the coinbase must
+	// commit to the witness data of the block.  The commitment is added
+	// now so the size of the coinbase is known and is updated once the
+	// transactions have been selected.
+	if segwitActive {
+		addWitnessCommitment(coinbaseTx, []*coinutil.Tx{coinbaseTx})
+	}
+
 	// Get the current source transactions and create a priority queue to
 	// hold the transactions which are ready for inclusion into a block
 	// along with some priority related and fee metadata.  Reserve the same
@@ -454,6 +652,11 @@ mempoolLoop:
 			minrLog.Tracef("Skipping non-finalized tx %s", tx.Sha())
 			continue
 		}
+		if !segwitActive && tx.MsgTx().HasWitness() {
+			minrLog.Tracef("Skipping tx %s with witness data "+
+				"before segwit is active", tx.Sha())
+			continue
+		}
 
 		// Fetch all of the transactions referenced by the inputs to
 		// this transaction.  NOTE: This intentionally does not fetch
@@ -521,10 +724,13 @@ mempoolLoop:
 		// formula is: sum(inputValue * inputAge) / adjustedTxSize
 		prioItem.priority = calcPriority(tx.MsgTx(), txStore, nextBlockHeight)
 
-		// Calculate the fee in Satoshi/kB.
-		txSize := tx.MsgTx().SerializeSize()
-		prioItem.feePerKB = (txDesc.Fee * 1000) / int64(txSize)
+		// Calculate the fee in Satoshi/kB using the virtual size so
+		// the witness data is discounted.
+		txSize := blockchain.GetTxVirtualSize(tx)
+		prioItem.feePerKB = (txDesc.Fee * 1000) / txSize
 		prioItem.fee = txDesc.Fee
+		prioItem.sortFeePerKB = weightedFeePerKB(policy, txDesc,
+			prioItem.feePerKB)
 
 		// Add the transaction to the priority queue to mark it ready
 		// for inclusion in the block unless it has dependencies.
@@ -543,11 +749,33 @@ mempoolLoop:
 
 	// The starting block size is the size of the block header plus the max
 	// possible transaction count size, plus the size of the coinbase
-	// transaction.
-	blockSize := blockHeaderOverhead + uint32(coinbaseTx.MsgTx().SerializeSize())
+	// transaction.  The block size excludes witness data, which is limited
+	// by the block weight instead.
+	blockSize := blockHeaderOverhead +
+		uint32(coinbaseTx.MsgTx().SerializeSizeStripped())
+	blockWeight := int64(blockHeaderOverhead*blockchain.WitnessScaleFactor) +
+		blockchain.GetTransactionWeight(coinbaseTx)
 	blockSigOps := numCoinbaseSigOps
+	blockSigOpCost := numCoinbaseSigOps * blockchain.WitnessScaleFactor
 	totalFees := int64(0)
 
+	// Keep an audit trail of why transactions are selected along with the
+	// first transactions which are excluded by the policy or the block
+	// limits, which are the ones that came closest to being selected.
+	selections := make([]txSelection, 1, len(sourceTxns)+1)
+	selectionRanks := make(map[string]int)
+	var exclusions []txExclusion
+	exclude := func(item *txPrioItem, reason string) {
+		if len(exclusions) < maxTemplateExclusions {
+			exclusions = append(exclusions, txExclusion{
+				hash:     *item.tx.Sha(),
+				reason:   reason,
+				feePerKB: item.feePerKB,
+				priority: item.priority,
+			})
+		}
+	}
+
 	// Choose which transactions make it into the block.
 	for priorityQueue.Len() > 0 {
 		// Grab the highest priority (or highest fee per kilobyte
@@ -563,11 +791,22 @@ mempoolLoop:
 		delete(dependers, *tx.Sha())
 
 		// Enforce maximum block size.  Also check for overflow.
-		txSize := uint32(tx.MsgTx().SerializeSize())
+		txSize := uint32(tx.MsgTx().SerializeSizeStripped())
 		blockPlusTxSize := blockSize + txSize
 		if blockPlusTxSize < blockSize || blockPlusTxSize >= policy.BlockMaxSize {
 			minrLog.Tracef("Skipping tx %s because it would exceed "+
 				"the max block size", tx.Sha())
+			exclude(prioItem, txExcludedBlockSize)
+			logSkippedDeps(tx, deps)
+			continue
+		}
+
+		// Enforce maximum block weight.
+		txWeight := blockchain.GetTransactionWeight(tx)
+		if blockWeight+txWeight > blockchain.MaxBlockWeight {
+			minrLog.Tracef("Skipping tx %s because it would exceed "+
+				"the max block weight", tx.Sha())
+			exclude(prioItem, txExcludedBlockWeight)
 			logSkippedDeps(tx, deps)
 			continue
 		}
@@ -579,6 +818,7 @@ mempoolLoop:
 			blockSigOps+numSigOps > blockchain.MaxSigOpsPerBlock {
 			minrLog.Tracef("Skipping tx %s because it would "+
 				"exceed the maximum sigops per block", tx.Sha())
+			exclude(prioItem, txExcludedSigOps)
 			logSkippedDeps(tx, deps)
 			continue
 		}
@@ -596,10 +836,34 @@ mempoolLoop:
 			minrLog.Tracef("Skipping tx %s because it would "+
 				"exceed the maximum sigops per block (p2sh)",
 				tx.Sha())
+			exclude(prioItem, txExcludedSigOps)
 			logSkippedDeps(tx, deps)
 			continue
 		}
 
+		// Enforce the maximum signature operation cost per block once
+		// the segregated witness soft fork is active.
+		sigOpCost := numSigOps * blockchain.WitnessScaleFactor
+		if segwitActive {
+			numWitnessSigOps, err := blockchain.CountWitnessSigOps(tx,
+				false, blockTxStore)
+			if err != nil {
+				minrLog.Tracef("Skipping tx %s due to error in "+
+					"CountWitnessSigOps: %v", tx.Sha(), err)
+				logSkippedDeps(tx, deps)
+				continue
+			}
+			sigOpCost += int64(numWitnessSigOps)
+			if blockSigOpCost+sigOpCost > blockchain.MaxBlockSigOpsCost {
+				minrLog.Tracef("Skipping tx %s because it "+
+					"would exceed the maximum sigop cost "+
+					"per block", tx.Sha())
+				exclude(prioItem, txExcludedSigOps)
+				logSkippedDeps(tx, deps)
+				continue
+			}
+		}
+
 		// Skip free transactions once the block is larger than the
 		// minimum block size.
 		if sortedByFee &&
@@ -611,21 +875,24 @@ mempoolLoop:
 				"minBlockSize %d", tx.Sha(), prioItem.feePerKB,
 				policy.TxMinFreeFee, blockPlusTxSize,
 				policy.BlockMinSize)
+			exclude(prioItem, txExcludedMinFeeRate)
 			logSkippedDeps(tx, deps)
 			continue
 		}
 
 		// Prioritize by fee per kilobyte once the block is larger than
 		// the priority size or there are no more high-priority
-		// transactions.
+		// transactions.  A transaction which still fits is the final
+		// one of the high-priority area.
+		selectedByPriority := !sortedByFee
 		if !sortedByFee && (blockPlusTxSize >= policy.BlockPrioritySize ||
-			prioItem.priority <= minHighPriority) {
+			prioItem.priority <= policy.BlockMinPriority) {
 
 			minrLog.Tracef("Switching to sort by fees per "+
 				"kilobyte blockSize %d >= BlockPrioritySize "+
-				"%d || priority %.2f <= minHighPriority %.2f",
+				"%d || priority %.2f <= BlockMinPriority %.2f",
 				blockPlusTxSize, policy.BlockPrioritySize,
-				prioItem.priority, minHighPriority)
+				prioItem.priority, policy.BlockMinPriority)
 
 			sortedByFee = true
 			priorityQueue.SetLessFunc(txPQByFee)
@@ -637,7 +904,7 @@ mempoolLoop:
 			// final one in the high-priority section, so just fall
 			// though to the code below so it is added now.
 			if blockPlusTxSize > policy.BlockPrioritySize ||
-				prioItem.priority < minHighPriority {
+				prioItem.priority < policy.BlockMinPriority {
 
 				heap.Push(priorityQueue, prioItem)
 				continue
@@ -674,11 +941,30 @@ mempoolLoop:
 		// template.
 		blockTxns = append(blockTxns, tx)
 		blockSize += txSize
+		blockWeight += txWeight
 		blockSigOps += numSigOps
+		blockSigOpCost += sigOpCost
 		totalFees += prioItem.fee
 		txFees = append(txFees, prioItem.fee)
 		txSigOpCounts = append(txSigOpCounts, numSigOps)
 
+		// Record why the transaction was selected.
+		reason := txSelectedFeeRate
+		switch {
+		case selectedByPriority:
+			reason = txSelectedPriority
+		case prioItem.feePerKB < int64(policy.TxMinFreeFee):
+			reason = txSelectedMinBlockSize
+		}
+		selectionRanks[reason]++
+		selections = append(selections, txSelection{
+			reason:    reason,
+			rank:      selectionRanks[reason],
+			inPackage: prioItem.dependsOn != nil,
+			feePerKB:  prioItem.feePerKB,
+			priority:  prioItem.priority,
+		})
+
 		minrLog.Tracef("Adding tx %s (priority %.2f, feePerKB %.2f)",
 			prioItem.tx.Sha(), prioItem.priority, prioItem.feePerKB)
 
@@ -707,6 +993,12 @@ mempoolLoop:
 	coinbaseTx.MsgTx().TxOut[0].Value += totalFees
 	txFees[0] = -totalFees
 
+	// Commit to the witness data of the selected transactions.
+	var witnessCommitment []byte
+	if segwitActive {
+		witnessCommitment = addWitnessCommitment(coinbaseTx, blockTxns)
+	}
+
 	// Calculate the required difficulty for the block.  The timestamp
 	// is potentially adjusted to ensure it comes after the median time of
 	// the last several blocks per the chain consensus rules.
@@ -720,10 +1012,10 @@ mempoolLoop:
 	}
 
 	// Create a new block ready to be solved.
-	merkles := blockchain.BuildMerkleTreeStore(blockTxns)
+	merkles := blockchain.BuildMerkleTreeStore(blockTxns, false)
 	var msgBlock wire.MsgBlock
 	msgBlock.Header = wire.BlockHeader{
-		Version:    generatedBlockVersion,
+		Version:    blockVersion,
 		PrevBlock:  *prevHash,
 		MerkleRoot: *merkles[len(merkles)-1],
 		Timestamp:  ts,
@@ -745,16 +1037,22 @@ mempoolLoop:
 	}
 
 	minrLog.Debugf("Created new block template (%d transactions, %d in "+
-		"fees, %d signature operations, %d bytes, target difficulty "+
-		"%064x)", len(msgBlock.Transactions), totalFees, blockSigOps,
-		blockSize, blockchain.CompactToBig(msgBlock.Header.Bits))
+		"fees, %d signature operations, %d bytes, %d weight, target "+
+		"difficulty %064x)", len(msgBlock.Transactions), totalFees,
+		blockSigOps, blockSize, blockWeight,
+		blockchain.CompactToBig(msgBlock.Header.Bits))
 
 	return &BlockTemplate{
-		block:           &msgBlock,
-		fees:            txFees,
-		sigOpCounts:     txSigOpCounts,
-		height:          nextBlockHeight,
-		validPayAddress: payToAddress != nil,
+		block:             &msgBlock,
+		fees:              txFees,
+		sigOpCounts:       txSigOpCounts,
+		height:            nextBlockHeight,
+		validPayAddress:   pkScript != nil,
+		deploymentStates:  deploymentStates,
+		witnessCommitment: witnessCommitment,
+		utxoCommitment:    utxoCommitment,
+		selections:        selections,
+		exclusions:        exclusions,
 	}, nil
 }
 
@@ -788,12 +1086,43 @@ func UpdateBlockTime(msgBlock *wire.MsgBlock, bManager *blockManager) error {
 	return nil
 }
 
+// CustomizeCoinbase returns a copy of the coinbase transaction of the passed
+// block template which is customized according to the passed options.  The
+// coinbase script keeps the block height and an extra nonce of zero, but
+// carries the custom flags when they are given.
+//
+// The template itself is not modified, so a single template can be handed out
+// with differently customized coinbases.  Since the coinbase does not
+// contribute to the witness merkle root, the witness commitment of the
+// template remains valid.
+func CustomizeCoinbase(template *BlockTemplate, opts *CoinbaseOptions) (*wire.MsgTx, error) {
+	coinbase := template.block.Transactions[0].Copy()
+	if opts.Flags != nil {
+		if len(opts.Flags) > maxCoinbaseFlagsLen {
+			return nil, fmt.Errorf("coinbase flags of %d bytes are "+
+				"too long (max %d)", len(opts.Flags),
+				maxCoinbaseFlagsLen)
+		}
+		coinbaseScript, err := standardCoinbaseScript(template.height,
+			0, opts.Flags)
+		if err != nil {
+			return nil, err
+		}
+		coinbase.TxIn[0].SignatureScript = coinbaseScript
+	}
+	if opts.PkScript != nil {
+		coinbase.TxOut[0].PkScript = opts.PkScript
+	}
+	return coinbase, nil
+}
+
 // UpdateExtraNonce updates the extra nonce in the coinbase script of the passed
 // block by regenerating the coinbase script with the passed value and block
 // height.  It also recalculates and updates the new merkle root that results
 // from changing the coinbase script.
 func UpdateExtraNonce(msgBlock *wire.MsgBlock, blockHeight int32, extraNonce uint64) error {
-	coinbaseScript, err := standardCoinbaseScript(blockHeight, extraNonce)
+	coinbaseScript, err := standardCoinbaseScript(blockHeight, extraNonce,
+		[]byte(coinbaseFlags))
 	if err != nil {
 		return err
 	}
@@ -811,7 +1140,7 @@ func UpdateExtraNonce(msgBlock *wire.MsgBlock, blockHeight int32, extraNonce uin
 
 	// Recalculate the merkle root with the updated extra nonce.
 	block := coinutil.NewBlock(msgBlock)
-	merkles := blockchain.BuildMerkleTreeStore(block.Transactions())
+	merkles := blockchain.BuildMerkleTreeStore(block.Transactions(), false)
 	msgBlock.Header.MerkleRoot = *merkles[len(merkles)-1]
 	return nil
 }
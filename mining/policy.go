@@ -4,7 +4,11 @@
 
 package mining
 
-import "github.com/conseweb/coinutil"
+import (
+	"time"
+
+	"github.com/conseweb/coinutil"
+)
 
 // Policy houses the policy (configuration parameters) which is used to control
 // the generation of block templates.  See the documentation for
@@ -26,4 +30,19 @@ type Policy struct {
 	// required for a transaction to be treated as free for mining purposes
 	// (block template generation).
 	TxMinFreeFee coinutil.Amount
+
+	// BlockMinPriority is the minimum priority a transaction must have to
+	// be included in the high-priority area of a block template.
+	BlockMinPriority float64
+
+	// RecentTxAge is the age below which transactions are considered
+	// recent.  Zero disables the weighting of recent transactions.
+	RecentTxAge time.Duration
+
+	// RecentTxWeight is the factor, between 0 and 1, the fee per kilobyte
+	// of recent transactions is multiplied by when ordering transactions
+	// by fee.  Weighting recent transactions down favors transactions
+	// which have had time to propagate through the network, so blocks are
+	// less likely to contain transactions other miners have yet to see.
+	RecentTxWeight float64
 }
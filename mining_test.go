@@ -0,0 +1,144 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/mining"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestCustomizeCoinbase ensures customized coinbases carry the requested
+// flags and payout script while the template itself is left untouched.
+func TestCustomizeCoinbase(t *testing.T) {
+	coinbaseScript, err := standardCoinbaseScript(100, 0,
+		[]byte(coinbaseFlags))
+	if err != nil {
+		t.Fatalf("standardCoinbaseScript: %v", err)
+	}
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&wire.ShaHash{},
+			wire.MaxPrevOutIndex),
+		SignatureScript: coinbaseScript,
+		Sequence:        wire.MaxTxInSequenceNum,
+	})
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+	template := &BlockTemplate{
+		block:  &wire.MsgBlock{Transactions: []*wire.MsgTx{coinbase}},
+		height: 100,
+	}
+
+	flags := []byte("/pool/")
+	pkScript := []byte{0x00, 0x14, 0x01, 0x02}
+	customized, err := CustomizeCoinbase(template,
+		&CoinbaseOptions{Flags: flags, PkScript: pkScript})
+	if err != nil {
+		t.Fatalf("CustomizeCoinbase: %v", err)
+	}
+	wantScript, _ := standardCoinbaseScript(100, 0, flags)
+	if !bytes.Equal(customized.TxIn[0].SignatureScript, wantScript) {
+		t.Errorf("coinbase script: got %x - want %x",
+			customized.TxIn[0].SignatureScript, wantScript)
+	}
+	if !bytes.Equal(customized.TxOut[0].PkScript, pkScript) {
+		t.Errorf("payout script: got %x - want %x",
+			customized.TxOut[0].PkScript, pkScript)
+	}
+	if !bytes.Equal(coinbase.TxIn[0].SignatureScript, coinbaseScript) ||
+		!bytes.Equal(coinbase.TxOut[0].PkScript, []byte{0x51}) {
+		t.Errorf("template coinbase was modified")
+	}
+
+	// Flags which don't fit in the coinbase script are rejected.
+	tooLong := make([]byte, maxCoinbaseFlagsLen+1)
+	_, err = CustomizeCoinbase(template, &CoinbaseOptions{Flags: tooLong})
+	if err == nil {
+		t.Errorf("CustomizeCoinbase: expected error for %d bytes of flags",
+			len(tooLong))
+	}
+}
+
+// TestWeightedFeePerKB ensures only the fee per kilobyte of transactions which
+// are more recent than the policy allows is weighted.
+func TestWeightedFeePerKB(t *testing.T) {
+	policy := &mining.Policy{
+		RecentTxAge:    time.Minute,
+		RecentTxWeight: 0.5,
+	}
+	tests := []struct {
+		name  string
+		added time.Time
+		want  int64
+	}{
+		{"recent", time.Now().Add(-time.Second), 500},
+		{"old", time.Now().Add(-time.Hour), 1000},
+	}
+	for _, test := range tests {
+		txDesc := &mining.TxDesc{Added: test.added}
+		got := weightedFeePerKB(policy, txDesc, 1000)
+		if got != test.want {
+			t.Errorf("%s: got %d - want %d", test.name, got, test.want)
+		}
+	}
+
+	// Recent transactions are not weighted when the age is zero.
+	policy.RecentTxAge = 0
+	txDesc := &mining.TxDesc{Added: time.Now()}
+	if got := weightedFeePerKB(policy, txDesc, 1000); got != 1000 {
+		t.Errorf("disabled: got %d - want 1000", got)
+	}
+}
+
+// TestTemplateAuditResult ensures the selection audit trail of a block
+// template is reported for every transaction except the coinbase.
+func TestTemplateAuditResult(t *testing.T) {
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+	tx := wire.NewMsgTx()
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x52}))
+	excluded := wire.NewMsgTx()
+	excluded.AddTxOut(wire.NewTxOut(2000, []byte{0x53}))
+	template := &BlockTemplate{
+		block: &wire.MsgBlock{
+			Transactions: []*wire.MsgTx{coinbase, tx},
+		},
+		selections: []txSelection{{}, {
+			reason:    txSelectedFeeRate,
+			rank:      1,
+			inPackage: true,
+			feePerKB:  10000,
+		}},
+		exclusions: []txExclusion{{
+			hash:     excluded.TxSha(),
+			reason:   txExcludedMinFeeRate,
+			feePerKB: 500,
+		}},
+	}
+
+	audit := templateAuditResult(template)
+	if len(audit.Transactions) != 1 {
+		t.Fatalf("transactions: got %d - want 1", len(audit.Transactions))
+	}
+	got := audit.Transactions[0]
+	if got.TxID != tx.TxSha().String() || got.Reason != txSelectedFeeRate ||
+		got.Rank != 1 || !got.Package || got.FeeRate != 0.0001 {
+
+		t.Errorf("transaction: unexpected audit %+v", got)
+	}
+	if len(audit.Exclusions) != 1 {
+		t.Fatalf("exclusions: got %d - want 1", len(audit.Exclusions))
+	}
+	exclusion := audit.Exclusions[0]
+	if exclusion.TxID != excluded.TxSha().String() ||
+		exclusion.Reason != txExcludedMinFeeRate ||
+		exclusion.FeeRate != 0.000005 {
+
+		t.Errorf("exclusion: unexpected audit %+v", exclusion)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// misbehaviorHalfLife is the time it takes for the misbehavior score of a peer
+// to decay to half of its value.  Scores decay so peers which occasionally
+// send something unexpected, which is bound to happen with honest peers on a
+// large network, are not eventually banned, while peers misbehaving in quick
+// succession reach the ban threshold.
+const misbehaviorHalfLife = time.Minute * 10
+
+// misbehaviorReason identifies the kind of misbehavior a peer is scored for.
+type misbehaviorReason int
+
+// These constants define the kinds of misbehavior peers are scored for.
+const (
+	// misbehaviorInvalidBlock is a block or block header which violates
+	// the consensus rules or doesn't match a checkpoint.
+	misbehaviorInvalidBlock misbehaviorReason = iota
+
+	// misbehaviorUnrequested is a block or headers message which was
+	// not requested.
+	misbehaviorUnrequested
+
+	// misbehaviorInvFlood is inventory announced faster than it can be
+	// requested.
+	misbehaviorInvFlood
+
+	// misbehaviorOversizedMessage is a message exceeding the maximum
+	// payload size.
+	misbehaviorOversizedMessage
+
+	// misbehaviorStalling is a sync peer which failed to deliver requested
+	// blocks in time.
+	misbehaviorStalling
+
+	// numMisbehaviorReasons is the number of kinds of misbehavior.  It
+	// must be the final entry.
+	numMisbehaviorReasons
+)
+
+// misbehaviorReasonStrings maps the kinds of misbehavior to the names they
+// are reported with by the getpeerinfo RPC.
+var misbehaviorReasonStrings = [numMisbehaviorReasons]string{
+	misbehaviorInvalidBlock:     "invalidblock",
+	misbehaviorUnrequested:      "unrequested",
+	misbehaviorInvFlood:         "invflood",
+	misbehaviorOversizedMessage: "oversizedmessage",
+	misbehaviorStalling:         "stalling",
+}
+
+// misbehaviorWeights maps the kinds of misbehavior to the score they add.
+// Misbehavior which can't happen by accident gets a peer banned at once with
+// the default ban threshold of 100.
+var misbehaviorWeights = [numMisbehaviorReasons]float64{
+	misbehaviorInvalidBlock:     100,
+	misbehaviorUnrequested:      20,
+	misbehaviorInvFlood:         20,
+	misbehaviorOversizedMessage: 100,
+	misbehaviorStalling:         20,
+}
+
+// String returns the misbehaviorReason in human-readable form.
+func (r misbehaviorReason) String() string {
+	if r >= 0 && r < numMisbehaviorReasons {
+		return misbehaviorReasonStrings[r]
+	}
+	return fmt.Sprintf("Unknown misbehaviorReason (%d)", int(r))
+}
+
+// misbehaviorScore tracks the misbehavior of a peer as a separate score per
+// kind of misbehavior.  All scores decay exponentially with a half-life of
+// misbehaviorHalfLife.  It is safe for concurrent access.
+type misbehaviorScore struct {
+	mtx        sync.Mutex
+	scores     [numMisbehaviorReasons]float64
+	lastUpdate time.Time
+}
+
+// decay reduces the scores by the decay since the last update.
+//
+// This function MUST be called with the lock held.
+func (s *misbehaviorScore) decay(now time.Time) {
+	elapsed := now.Sub(s.lastUpdate)
+	s.lastUpdate = now
+	if elapsed <= 0 {
+		return
+	}
+	factor := math.Pow(0.5, float64(elapsed)/float64(misbehaviorHalfLife))
+	for i := range s.scores {
+		s.scores[i] *= factor
+	}
+}
+
+// total returns the sum of the scores rounded down.
+//
+// This function MUST be called with the lock held.
+func (s *misbehaviorScore) total() uint32 {
+	var total float64
+	for _, score := range s.scores {
+		total += score
+	}
+	return uint32(total)
+}
+
+// Increase adds the weight of the passed kind of misbehavior to its score at
+// the passed time and returns the resulting total score.
+func (s *misbehaviorScore) Increase(reason misbehaviorReason, now time.Time) uint32 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.decay(now)
+	s.scores[reason] += misbehaviorWeights[reason]
+	return s.total()
+}
+
+// Total returns the total score at the passed time.
+func (s *misbehaviorScore) Total(now time.Time) uint32 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.decay(now)
+	return s.total()
+}
+
+// Breakdown returns the score of every kind of misbehavior which hasn't
+// decayed to zero at the passed time, keyed by the name of the kind.
+func (s *misbehaviorScore) Breakdown(now time.Time) map[string]uint32 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.decay(now)
+	breakdown := make(map[string]uint32)
+	for reason, score := range s.scores {
+		if uint32(score) > 0 {
+			name := misbehaviorReason(reason).String()
+			breakdown[name] = uint32(score)
+		}
+	}
+	return breakdown
+}
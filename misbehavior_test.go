@@ -0,0 +1,77 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestMisbehaviorScore ensures misbehavior scores add up per kind of
+// misbehavior and decay with the configured half-life.
+func TestMisbehaviorScore(t *testing.T) {
+	var s misbehaviorScore
+	now := time.Now()
+
+	if got := s.Total(now); got != 0 {
+		t.Fatalf("Total: got %d, want 0", got)
+	}
+	if got := s.Increase(misbehaviorUnrequested, now); got != 20 {
+		t.Fatalf("Increase: got %d, want 20", got)
+	}
+	if got := s.Increase(misbehaviorInvFlood, now); got != 40 {
+		t.Fatalf("Increase: got %d, want 40", got)
+	}
+	if got := s.Increase(misbehaviorUnrequested, now); got != 60 {
+		t.Fatalf("Increase: got %d, want 60", got)
+	}
+	want := map[string]uint32{"unrequested": 40, "invflood": 20}
+	if got := s.Breakdown(now); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Breakdown: got %v, want %v", got, want)
+	}
+
+	// The scores halve with every half-life and vanish from the breakdown
+	// once they decayed to zero.
+	now = now.Add(misbehaviorHalfLife)
+	if got := s.Total(now); got != 30 {
+		t.Fatalf("Total: got %d, want 30 after one half-life", got)
+	}
+	now = now.Add(misbehaviorHalfLife * 5)
+	want = map[string]uint32{}
+	if got := s.Breakdown(now); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Breakdown: got %v, want %v", got, want)
+	}
+
+	// Misbehavior which can't happen by accident reaches the default ban
+	// threshold at once.
+	got := s.Increase(misbehaviorInvalidBlock, now)
+	if got < defaultBanThreshold {
+		t.Fatalf("Increase: got %d, want at least %d", got,
+			defaultBanThreshold)
+	}
+}
+
+// TestMisbehaviorReasonStringer tests the stringized output for the
+// misbehaviorReason type.
+func TestMisbehaviorReasonStringer(t *testing.T) {
+	tests := []struct {
+		in   misbehaviorReason
+		want string
+	}{
+		{misbehaviorInvalidBlock, "invalidblock"},
+		{misbehaviorUnrequested, "unrequested"},
+		{misbehaviorInvFlood, "invflood"},
+		{misbehaviorOversizedMessage, "oversizedmessage"},
+		{misbehaviorStalling, "stalling"},
+		{0xffff, "Unknown misbehaviorReason (65535)"},
+	}
+
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String: got %q, want %q", got, test.want)
+		}
+	}
+}
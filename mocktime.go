@@ -0,0 +1,91 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/conseweb/stcd/blockchain"
+)
+
+// mockTimeSource is the median time source of the server.  On the test
+// networks, its notion of the current time can be overridden with the
+// setmocktime command so integration tests of time-locked transactions don't
+// depend on the local clock.  The mocked time is used by everything which
+// takes the time from the source: the adjusted time for the checks of block
+// timestamps and transaction lock times, and the clock the timestamps of
+// peers are compared with.
+type mockTimeSource struct {
+	blockchain.MedianTimeSource
+
+	// mockTime is the mocked current time in seconds since the Unix
+	// epoch, or 0 when the local clock is used.  It must be accessed
+	// atomically.
+	mockTime int64
+}
+
+// Ensure the mockTimeSource type implements the MedianTimeSource interface.
+var _ blockchain.MedianTimeSource = (*mockTimeSource)(nil)
+
+// newMockTimeSource returns a new median time source which uses the local
+// clock until a mocked time is set.
+func newMockTimeSource() *mockTimeSource {
+	return &mockTimeSource{MedianTimeSource: blockchain.NewMedianTime()}
+}
+
+// SetMockTime overrides the current time with the passed time.  The zero time
+// restores the local clock.
+//
+// This function is safe for concurrent access.
+func (m *mockTimeSource) SetMockTime(t time.Time) {
+	var secs int64
+	if !t.IsZero() {
+		secs = t.Unix()
+	}
+	atomic.StoreInt64(&m.mockTime, secs)
+}
+
+// MockTime returns the mocked current time and whether one is set.
+//
+// This function is safe for concurrent access.
+func (m *mockTimeSource) MockTime() (time.Time, bool) {
+	secs := atomic.LoadInt64(&m.mockTime)
+	if secs == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
+
+// clockOffset returns the difference of the mocked time from the local clock,
+// or 0 when no time is mocked.
+func (m *mockTimeSource) clockOffset() time.Duration {
+	mockTime, ok := m.MockTime()
+	if !ok {
+		return 0
+	}
+	return mockTime.Sub(time.Unix(time.Now().Unix(), 0))
+}
+
+// AdjustedTime returns the current time adjusted by the median time offset of
+// the time samples.
+//
+// This function is safe for concurrent access and is part of the
+// blockchain.MedianTimeSource interface implementation.
+func (m *mockTimeSource) AdjustedTime() time.Time {
+	if mockTime, ok := m.MockTime(); ok {
+		return mockTime.Add(m.Offset())
+	}
+	return m.MedianTimeSource.AdjustedTime()
+}
+
+// AddTimeSample adds a time sample, such as the timestamp of a peer, which is
+// compared with the mocked time when one is set.
+//
+// This function is safe for concurrent access and is part of the
+// blockchain.MedianTimeSource interface implementation.
+func (m *mockTimeSource) AddTimeSample(id string, timeVal time.Time) {
+	m.MedianTimeSource.AddTimeSample(id, timeVal.Add(-m.clockOffset()))
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestMockTimeSource ensures the mocked time overrides the local clock for the
+// adjusted time and the time samples of peers, and that clearing it restores
+// the local clock.
+func TestMockTimeSource(t *testing.T) {
+	t.Parallel()
+
+	m := newMockTimeSource()
+	if _, ok := m.MockTime(); ok {
+		t.Fatalf("MockTime: unexpected mocked time for new source")
+	}
+	if d := time.Since(m.AdjustedTime()); d < -time.Second || d > time.Second {
+		t.Fatalf("AdjustedTime: got %v from the local clock", d)
+	}
+
+	mockTime := time.Unix(1231006505, 0)
+	m.SetMockTime(mockTime)
+	if got, ok := m.MockTime(); !ok || !got.Equal(mockTime) {
+		t.Fatalf("MockTime: got %v (%v) - want %v", got, ok, mockTime)
+	}
+	if got := m.AdjustedTime(); !got.Equal(mockTime) {
+		t.Fatalf("AdjustedTime: got %v - want %v", got, mockTime)
+	}
+
+	// Peers whose clocks are a minute ahead of the mocked time move the
+	// adjusted time by a minute, even though they are years away from the
+	// local clock.
+	for i := 0; i < 5; i++ {
+		m.AddTimeSample(fmt.Sprintf("peer%d", i),
+			mockTime.Add(time.Minute))
+	}
+	want := mockTime.Add(time.Minute)
+	if d := m.AdjustedTime().Sub(want); d < -time.Second || d > time.Second {
+		t.Fatalf("AdjustedTime with samples: got %v - want %v",
+			m.AdjustedTime(), want)
+	}
+
+	m.SetMockTime(time.Time{})
+	if _, ok := m.MockTime(); ok {
+		t.Fatalf("MockTime: unexpected mocked time after clearing it")
+	}
+	want = time.Now().Add(m.Offset())
+	if d := m.AdjustedTime().Sub(want); d < -time.Second || d > time.Second {
+		t.Fatalf("AdjustedTime after clearing: got %v - want %v",
+			m.AdjustedTime(), want)
+	}
+}
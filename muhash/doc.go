@@ -0,0 +1,20 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package muhash provides an incremental hash of a set of byte strings.
+
+Each element of the set is hashed to a number modulo the prime 2^3072 - 1103717
+and the hash of the set is the product of the numbers of its elements.  Since
+multiplication is commutative, elements can be added and removed in any order
+and the hash of the set only depends on the elements it holds.  This makes it
+cheap to keep the hash of a large set, such as the unspent transaction outputs
+of a block chain, up to date as it changes, and lets two parties compare sets
+built independently without iterating them in a canonical order.
+
+Adding an element which is already in the set or removing an element which is
+not in the set doesn't fail, so callers must only add elements once and only
+remove elements they added.
+*/
+package muhash
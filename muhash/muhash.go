@@ -0,0 +1,144 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package muhash
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/conseweb/fastsha256"
+)
+
+// SerializedSize is the size of a serialized hash.
+const SerializedSize = 384
+
+var (
+	// ErrInvalidSize is returned when deserializing a hash which isn't
+	// SerializedSize bytes long.
+	ErrInvalidSize = errors.New("serialized hash has an invalid size")
+
+	// ErrOutOfRange is returned when deserializing a hash which isn't a
+	// number between one and the prime modulus.
+	ErrOutOfRange = errors.New("serialized hash is out of range")
+)
+
+// prime is the prime 2^3072 - 1103717, the modulus of the group the elements
+// of the set are hashed into.
+var prime = func() *big.Int {
+	p := new(big.Int).Lsh(big.NewInt(1), SerializedSize*8)
+	return p.Sub(p, big.NewInt(1103717))
+}()
+
+// Hash is the hash of a set of byte strings.  The zero value is not usable;
+// use New or Deserialize to create a hash.
+//
+// The numbers of removed elements are multiplied into a separate denominator,
+// so only a single modular inverse is needed when the hash is serialized.
+type Hash struct {
+	numerator   *big.Int
+	denominator *big.Int
+}
+
+// New returns the hash of an empty set.
+func New() *Hash {
+	return &Hash{
+		numerator:   big.NewInt(1),
+		denominator: big.NewInt(1),
+	}
+}
+
+// Deserialize decodes a hash serialized with Serialize.
+func Deserialize(serialized []byte) (*Hash, error) {
+	if len(serialized) != SerializedSize {
+		return nil, ErrInvalidSize
+	}
+	numerator := new(big.Int).SetBytes(serialized)
+	if numerator.Sign() == 0 || numerator.Cmp(prime) >= 0 {
+		return nil, ErrOutOfRange
+	}
+	return &Hash{numerator: numerator, denominator: big.NewInt(1)}, nil
+}
+
+// Clone returns a copy of the hash which can be modified without affecting
+// the original.
+func (h *Hash) Clone() *Hash {
+	return &Hash{
+		numerator:   new(big.Int).Set(h.numerator),
+		denominator: new(big.Int).Set(h.denominator),
+	}
+}
+
+// element maps the passed data to its number in the group.  The SHA-256 of
+// the data is expanded to the size of the group by hashing it together with a
+// counter.
+func element(data []byte) *big.Int {
+	seed := fastsha256.Sum256(data)
+	var expanded [SerializedSize]byte
+	var block [fastsha256.Size + 1]byte
+	copy(block[:], seed[:])
+	for i := 0; i < SerializedSize/fastsha256.Size; i++ {
+		block[fastsha256.Size] = byte(i)
+		sum := fastsha256.Sum256(block[:])
+		copy(expanded[i*fastsha256.Size:], sum[:])
+	}
+	e := new(big.Int).SetBytes(expanded[:])
+	return e.Mod(e, prime)
+}
+
+// Add adds the passed data to the set.
+func (h *Hash) Add(data []byte) {
+	h.numerator.Mul(h.numerator, element(data))
+	h.numerator.Mod(h.numerator, prime)
+}
+
+// Remove removes the passed data from the set.
+func (h *Hash) Remove(data []byte) {
+	h.denominator.Mul(h.denominator, element(data))
+	h.denominator.Mod(h.denominator, prime)
+}
+
+// Combine updates the hash to the hash of the union of its set and the set of
+// the passed hash, which must not have any elements in common.  Elements
+// removed from the passed set are removed as well, so the hash of a set can
+// also be updated with the changes recorded in a hash which started out empty.
+func (h *Hash) Combine(other *Hash) {
+	h.numerator.Mul(h.numerator, other.numerator)
+	h.numerator.Mod(h.numerator, prime)
+	h.denominator.Mul(h.denominator, other.denominator)
+	h.denominator.Mod(h.denominator, prime)
+}
+
+// normalized returns the number the set maps to, which is the numerator with
+// the denominator folded in.  The hash is not modified, so hashes which are
+// only read may be shared.
+func (h *Hash) normalized() *big.Int {
+	if h.denominator.Cmp(big.NewInt(1)) == 0 {
+		return h.numerator
+	}
+	n := new(big.Int).ModInverse(h.denominator, prime)
+	n.Mul(n, h.numerator)
+	return n.Mod(n, prime)
+}
+
+// Serialize returns the serialized hash, which is the number the set maps to
+// as a big-endian number of SerializedSize bytes.
+func (h *Hash) Serialize() []byte {
+	serialized := make([]byte, SerializedSize)
+	numerator := h.normalized().Bytes()
+	copy(serialized[SerializedSize-len(numerator):], numerator)
+	return serialized
+}
+
+// IsEqual returns whether the passed hash is the hash of the same set.
+func (h *Hash) IsEqual(other *Hash) bool {
+	return h.normalized().Cmp(other.normalized()) == 0
+}
+
+// Digest returns the double SHA-256 of the serialized hash, which is a more
+// compact commitment to the set.
+func (h *Hash) Digest() [fastsha256.Size]byte {
+	first := fastsha256.Sum256(h.Serialize())
+	return fastsha256.Sum256(first[:])
+}
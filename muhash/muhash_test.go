@@ -0,0 +1,124 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package muhash_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/conseweb/stcd/muhash"
+)
+
+// TestHashSet ensures the hash of a set doesn't depend on the order elements
+// are added in, that removing an element undoes adding it and that hashes of
+// disjoint sets combine to the hash of their union.
+func TestHashSet(t *testing.T) {
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+
+	empty := muhash.New()
+	abc := muhash.New()
+	abc.Add(a)
+	abc.Add(b)
+	abc.Add(c)
+	cba := muhash.New()
+	cba.Add(c)
+	cba.Add(b)
+	cba.Add(a)
+	if !abc.IsEqual(cba) {
+		t.Errorf("hash depends on the order elements are added in")
+	}
+	if abc.IsEqual(empty) {
+		t.Errorf("hash didn't change when elements were added")
+	}
+
+	// Removing elements, including before they are added, leaves the
+	// hash of the remaining elements.
+	ac := muhash.New()
+	ac.Add(a)
+	ac.Add(c)
+	withoutB := abc.Clone()
+	withoutB.Remove(b)
+	if !withoutB.IsEqual(ac) {
+		t.Errorf("removing an element doesn't undo adding it")
+	}
+	if abc.IsEqual(withoutB) {
+		t.Errorf("removing an element from a clone changed the original")
+	}
+	removedFirst := muhash.New()
+	removedFirst.Remove(b)
+	removedFirst.Add(a)
+	removedFirst.Add(b)
+	removedFirst.Add(c)
+	if !removedFirst.IsEqual(ac) {
+		t.Errorf("hash depends on the order elements are removed in")
+	}
+
+	// Combining the hashes of disjoint sets gives the hash of their union,
+	// and combining with a hash recording removals applies them.
+	onlyB := muhash.New()
+	onlyB.Add(b)
+	combined := ac.Clone()
+	combined.Combine(onlyB)
+	if !combined.IsEqual(abc) {
+		t.Errorf("combined hash isn't the hash of the union")
+	}
+	removeB := muhash.New()
+	removeB.Remove(b)
+	combined.Combine(removeB)
+	if !combined.IsEqual(ac) {
+		t.Errorf("combining with a removal didn't remove the element")
+	}
+}
+
+// TestSerialize ensures hashes survive a serialization round trip and that
+// invalid serialized hashes are rejected.
+func TestSerialize(t *testing.T) {
+	h := muhash.New()
+	h.Add([]byte("a"))
+	h.Remove([]byte("b"))
+
+	serialized := h.Serialize()
+	if len(serialized) != muhash.SerializedSize {
+		t.Fatalf("Serialize: got %d bytes, want %d", len(serialized),
+			muhash.SerializedSize)
+	}
+	got, err := muhash.Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Deserialize: %v", err)
+	}
+	if !got.IsEqual(h) || !bytes.Equal(got.Serialize(), serialized) {
+		t.Errorf("Deserialize: round trip changed the hash")
+	}
+	if got.Digest() != h.Digest() {
+		t.Errorf("Digest: round trip changed the digest")
+	}
+
+	// The empty set serializes to one.
+	emptySerialized := muhash.New().Serialize()
+	want := make([]byte, muhash.SerializedSize)
+	want[muhash.SerializedSize-1] = 1
+	if !bytes.Equal(emptySerialized, want) {
+		t.Errorf("Serialize: empty set serialized to %x", emptySerialized)
+	}
+
+	tests := []struct {
+		name       string
+		serialized []byte
+		err        error
+	}{
+		{"short", serialized[1:], muhash.ErrInvalidSize},
+		{"long", append(serialized, 0x00), muhash.ErrInvalidSize},
+		{"zero", make([]byte, muhash.SerializedSize), muhash.ErrOutOfRange},
+		{"max", bytes.Repeat([]byte{0xff}, muhash.SerializedSize),
+			muhash.ErrOutOfRange},
+	}
+	for _, test := range tests {
+		_, err := muhash.Deserialize(test.serialized)
+		if err != test.err {
+			t.Errorf("Deserialize %s: got %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/peer"
+	"github.com/conseweb/stcd/wire"
+)
+
+// maxNetworkFaultDelay is the maximum time a network fault may delay each
+// message for.
+const maxNetworkFaultDelay = time.Minute
+
+// These constants define the directions of the messages a network fault
+// applies to.
+const (
+	networkFaultIn   = "in"
+	networkFaultOut  = "out"
+	networkFaultBoth = "both"
+)
+
+// networkFault drops or delays the messages exchanged with a peer to simulate
+// an unreliable network or a partition on the simulation test network.
+type networkFault struct {
+	// target is the id of the peer the fault applies to, or its address so
+	// the fault also applies after the peer reconnects.
+	target string
+
+	inbound  bool
+	outbound bool
+
+	// commands holds the commands of the messages the fault applies to.
+	// The fault applies to all messages when it is nil.
+	commands map[string]struct{}
+
+	dropRate float64
+	delay    time.Duration
+
+	// dropped and delayed count the messages the fault dropped and
+	// delayed.  They must be accessed atomically.
+	dropped uint64
+	delayed uint64
+}
+
+// newNetworkFault returns a network fault for the passed target from its
+// description by the setnetworkfault command.  An RPC error is returned when
+// the description is invalid.
+func newNetworkFault(target string, desc *btcjson.NetworkFault) (*networkFault, error) {
+	fault := &networkFault{
+		target:   target,
+		dropRate: desc.DropRate,
+		delay:    time.Duration(desc.Delay) * time.Millisecond,
+	}
+	switch desc.Direction {
+	case networkFaultIn:
+		fault.inbound = true
+	case networkFaultOut:
+		fault.outbound = true
+	case "", networkFaultBoth:
+		fault.inbound = true
+		fault.outbound = true
+	default:
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Direction must be one of in, out or both, " +
+				"not " + desc.Direction,
+		}
+	}
+	if fault.dropRate < 0 || fault.dropRate > 1 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Drop rate must be between 0 and 1",
+		}
+	}
+	if fault.delay < 0 || fault.delay > maxNetworkFaultDelay {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Delay must be between 0 and " +
+				strconv.Itoa(int(maxNetworkFaultDelay/time.Millisecond)) +
+				" milliseconds",
+		}
+	}
+	if fault.dropRate == 0 && fault.delay == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Fault must drop or delay messages",
+		}
+	}
+	if len(desc.Commands) > 0 {
+		fault.commands = make(map[string]struct{}, len(desc.Commands))
+		for _, command := range desc.Commands {
+			if command == "" || len(command) > wire.CommandSize {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: "Invalid message command: " +
+						strconv.Quote(command),
+				}
+			}
+			fault.commands[command] = struct{}{}
+		}
+	}
+	return fault, nil
+}
+
+// appliesTo returns whether the fault applies to the passed message exchanged
+// in the passed direction.
+func (f *networkFault) appliesTo(msg wire.Message, inbound bool) bool {
+	if (inbound && !f.inbound) || (!inbound && !f.outbound) {
+		return false
+	}
+	if f.commands == nil {
+		return true
+	}
+	_, ok := f.commands[msg.Command()]
+	return ok
+}
+
+// result returns the fault as reported by the getnetworkfaults command.
+func (f *networkFault) result() btcjson.NetworkFaultResult {
+	direction := networkFaultBoth
+	if !f.outbound {
+		direction = networkFaultIn
+	} else if !f.inbound {
+		direction = networkFaultOut
+	}
+	var commands []string
+	for command := range f.commands {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return btcjson.NetworkFaultResult{
+		Target:    f.target,
+		Direction: direction,
+		Commands:  commands,
+		DropRate:  f.dropRate,
+		Delay:     int64(f.delay / time.Millisecond),
+		Dropped:   atomic.LoadUint64(&f.dropped),
+		Delayed:   atomic.LoadUint64(&f.delayed),
+	}
+}
+
+// networkFaults holds the network faults set with the setnetworkfault command
+// by their targets.
+type networkFaults struct {
+	sync.Mutex
+	faults map[string]*networkFault
+}
+
+// newNetworkFaults returns a new empty set of network faults.
+func newNetworkFaults() *networkFaults {
+	return &networkFaults{faults: make(map[string]*networkFault)}
+}
+
+// set adds the passed fault, replacing the fault for the same target if any.
+//
+// This function is safe for concurrent access.
+func (n *networkFaults) set(fault *networkFault) {
+	n.Lock()
+	n.faults[fault.target] = fault
+	n.Unlock()
+}
+
+// clear removes the fault for the passed target, or all faults when the target
+// is empty.  It returns the number of faults removed.
+//
+// This function is safe for concurrent access.
+func (n *networkFaults) clear(target string) int {
+	n.Lock()
+	defer n.Unlock()
+
+	if target == "" {
+		cleared := len(n.faults)
+		n.faults = make(map[string]*networkFault)
+		return cleared
+	}
+	if _, ok := n.faults[target]; !ok {
+		return 0
+	}
+	delete(n.faults, target)
+	return 1
+}
+
+// results returns the faults as reported by the getnetworkfaults command,
+// ordered by their targets.
+//
+// This function is safe for concurrent access.
+func (n *networkFaults) results() []btcjson.NetworkFaultResult {
+	n.Lock()
+	defer n.Unlock()
+
+	targets := make([]string, 0, len(n.faults))
+	for target := range n.faults {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	results := make([]btcjson.NetworkFaultResult, 0, len(targets))
+	for _, target := range targets {
+		results = append(results, n.faults[target].result())
+	}
+	return results
+}
+
+// messageFault decides whether the passed message exchanged with the passed
+// peer is dropped and how long it is delayed by the faults targeting the peer
+// by its id or address.  The fault targeting the id takes precedence.
+//
+// This function is safe for concurrent access and is used as the MessageFault
+// callback of peers.
+func (n *networkFaults) messageFault(p *peer.Peer, msg wire.Message, inbound bool) (bool, time.Duration) {
+	n.Lock()
+	fault, ok := n.faults[strconv.FormatInt(int64(p.ID()), 10)]
+	if !ok {
+		fault, ok = n.faults[p.Addr()]
+	}
+	n.Unlock()
+	if !ok || !fault.appliesTo(msg, inbound) {
+		return false, 0
+	}
+
+	if fault.delay > 0 {
+		atomic.AddUint64(&fault.delayed, 1)
+	}
+	drop := fault.dropRate > 0 && rand.Float64() < fault.dropRate
+	if drop {
+		atomic.AddUint64(&fault.dropped, 1)
+	}
+	return drop, fault.delay
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/peer"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestNewNetworkFault ensures invalid descriptions of network faults are
+// rejected.
+func TestNewNetworkFault(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		fault btcjson.NetworkFault
+		valid bool
+	}{
+		{
+			name:  "drop all",
+			fault: btcjson.NetworkFault{DropRate: 1},
+			valid: true,
+		},
+		{
+			name: "delay inbound blocks",
+			fault: btcjson.NetworkFault{
+				Direction: "in",
+				Commands:  []string{"block"},
+				Delay:     100,
+			},
+			valid: true,
+		},
+		{
+			name:  "neither drop nor delay",
+			fault: btcjson.NetworkFault{Direction: "out"},
+		},
+		{
+			name:  "invalid direction",
+			fault: btcjson.NetworkFault{Direction: "up", DropRate: 1},
+		},
+		{
+			name:  "drop rate over 1",
+			fault: btcjson.NetworkFault{DropRate: 1.5},
+		},
+		{
+			name:  "negative delay",
+			fault: btcjson.NetworkFault{Delay: -1},
+		},
+		{
+			name:  "delay over the maximum",
+			fault: btcjson.NetworkFault{Delay: 60001},
+		},
+		{
+			name: "invalid command",
+			fault: btcjson.NetworkFault{
+				Commands: []string{"averylongcommand"},
+				DropRate: 1,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		_, err := newNetworkFault("1", &test.fault)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: invalid fault accepted", test.name)
+		}
+	}
+}
+
+// TestNetworkFaults ensures network faults apply to the messages of the peers
+// they target in the configured directions and that clearing them restores
+// the delivery of messages.
+func TestNetworkFaults(t *testing.T) {
+	t.Parallel()
+
+	p, err := peer.NewOutboundPeer(&peer.Config{}, "127.0.0.1:18555")
+	if err != nil {
+		t.Fatalf("NewOutboundPeer: unexpected error: %v", err)
+	}
+	faults := newNetworkFaults()
+	if drop, delay := faults.messageFault(p, wire.NewMsgPing(1), true); drop || delay != 0 {
+		t.Fatalf("messageFault: got %v, %v without faults", drop, delay)
+	}
+
+	// Drop the inbound blocks of the peer by its address.
+	fault, err := newNetworkFault(p.Addr(), &btcjson.NetworkFault{
+		Direction: "in",
+		Commands:  []string{"block"},
+		DropRate:  1,
+	})
+	if err != nil {
+		t.Fatalf("newNetworkFault: unexpected error: %v", err)
+	}
+	faults.set(fault)
+	block := wire.NewMsgBlock(&wire.BlockHeader{})
+	if drop, _ := faults.messageFault(p, block, true); !drop {
+		t.Fatalf("messageFault: inbound block not dropped")
+	}
+	if drop, _ := faults.messageFault(p, block, false); drop {
+		t.Fatalf("messageFault: outbound block dropped")
+	}
+	if drop, _ := faults.messageFault(p, wire.NewMsgPing(1), true); drop {
+		t.Fatalf("messageFault: inbound ping dropped")
+	}
+
+	// A fault for the id of the peer takes precedence over the one for its
+	// address.
+	fault, err = newNetworkFault("0", &btcjson.NetworkFault{Delay: 50})
+	if err != nil {
+		t.Fatalf("newNetworkFault: unexpected error: %v", err)
+	}
+	faults.set(fault)
+	drop, delay := faults.messageFault(p, block, true)
+	if drop || delay != 50*time.Millisecond {
+		t.Fatalf("messageFault: got %v, %v - want false, 50ms", drop,
+			delay)
+	}
+
+	results := faults.results()
+	if len(results) != 2 || results[0].Target != "0" ||
+		results[0].Delayed != 1 || results[1].Target != p.Addr() ||
+		results[1].Direction != "in" || results[1].Dropped != 1 {
+
+		t.Fatalf("results: got %+v", results)
+	}
+
+	if cleared := faults.clear("0"); cleared != 1 {
+		t.Fatalf("clear: got %d cleared - want 1", cleared)
+	}
+	if cleared := faults.clear(""); cleared != 1 {
+		t.Fatalf("clear all: got %d cleared - want 1", cleared)
+	}
+	if drop, delay := faults.messageFault(p, block, true); drop || delay != 0 {
+		t.Fatalf("messageFault: got %v, %v after clearing faults", drop,
+			delay)
+	}
+}
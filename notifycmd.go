@@ -0,0 +1,242 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/txscript"
+)
+
+const (
+	// notifyCmdWorkers is the number of external notification commands
+	// which may run concurrently.
+	notifyCmdWorkers = 4
+
+	// notifyCmdQueueSize is the number of external notification commands
+	// which may wait for a worker.  Commands are dropped with a warning
+	// once the queue is full so a slow or hanging command can never hold
+	// up block or transaction processing.
+	notifyCmdQueueSize = 100
+)
+
+// notifyCmd is an external notification command waiting to be run along with
+// the hash which replaces each %s in its arguments.
+type notifyCmd struct {
+	command string
+	hash    string
+}
+
+// notifyCmdArgs splits the passed command into its program and arguments
+// and replaces every %s in them with the passed hash.  The command is not
+// passed through a shell, so operators who need shell features such as
+// redirection must point the option at a script.
+func notifyCmdArgs(command, hash string) []string {
+	args := strings.Fields(command)
+	for i, arg := range args {
+		args[i] = strings.Replace(arg, "%s", hash, -1)
+	}
+	return args
+}
+
+// notifyCmdRunner runs the external commands configured with the blocknotify
+// and txnotify options.  It is subscribed to the event bus of the server and
+// queues a command for every connected block and every transaction paying to
+// one of the watched addresses, which a fixed number of workers run with a
+// timeout so the commands can't exhaust the resources of the node.
+type notifyCmdRunner struct {
+	server       *server
+	blockCommand string
+	txCommand    string
+	txAddrs      map[string]struct{}
+	timeout      time.Duration
+	started      int32
+	shutdown     int32
+	queue        chan *notifyCmd
+	quit         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// newNotifyCmdRunner returns a new runner for the configured notification
+// commands.  Use Start to begin running them.
+func newNotifyCmdRunner(s *server) *notifyCmdRunner {
+	txAddrs := make(map[string]struct{}, len(cfg.txNotifyAddrs))
+	for _, addr := range cfg.txNotifyAddrs {
+		txAddrs[addr.EncodeAddress()] = struct{}{}
+	}
+	return &notifyCmdRunner{
+		server:       s,
+		blockCommand: cfg.BlockNotify,
+		txCommand:    cfg.TxNotify,
+		txAddrs:      txAddrs,
+		timeout:      cfg.NotifyTimeout,
+		queue:        make(chan *notifyCmd, notifyCmdQueueSize),
+		quit:         make(chan struct{}),
+	}
+}
+
+// Start launches the workers which run the queued commands.
+func (r *notifyCmdRunner) Start() {
+	// Already started?
+	if atomic.AddInt32(&r.started, 1) != 1 {
+		return
+	}
+	for i := 0; i < notifyCmdWorkers; i++ {
+		r.wg.Add(1)
+		go r.worker()
+	}
+}
+
+// Stop shuts down the workers, killing any command which is still running.
+// Commands which haven't been started yet are discarded.
+func (r *notifyCmdRunner) Stop() {
+	if atomic.AddInt32(&r.shutdown, 1) != 1 {
+		return
+	}
+	close(r.quit)
+	r.wg.Wait()
+}
+
+// enqueue queues the passed command to be run with the passed hash.  It never
+// blocks, so the command is dropped when the queue is full.
+func (r *notifyCmdRunner) enqueue(command, hash string) {
+	select {
+	case r.queue <- &notifyCmd{command: command, hash: hash}:
+	default:
+		srvrLog.Warnf("Dropping notification command for %s since %d "+
+			"commands are already waiting to run", hash,
+			notifyCmdQueueSize)
+	}
+}
+
+// worker runs queued commands until the runner is stopped.
+//
+// This must be run as a goroutine.
+func (r *notifyCmdRunner) worker() {
+	defer r.wg.Done()
+	for {
+		select {
+		case n := <-r.queue:
+			r.run(n)
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// run executes the passed command and waits for it to exit.  The command is
+// killed when it runs longer than the configured timeout or the runner is
+// stopped.
+func (r *notifyCmdRunner) run(n *notifyCmd) {
+	args := notifyCmdArgs(n.command, n.hash)
+	if len(args) == 0 {
+		return
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		srvrLog.Errorf("Unable to run notification command %q: %v",
+			args[0], err)
+		return
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			srvrLog.Warnf("Notification command %q for %s failed: "+
+				"%v: %s", args[0], n.hash, err,
+				bytes.TrimSpace(output.Bytes()))
+			return
+		}
+		srvrLog.Debugf("Ran notification command %q for %s", args[0],
+			n.hash)
+
+	case <-timer.C:
+		cmd.Process.Kill()
+		<-done
+		srvrLog.Warnf("Killed notification command %q for %s after "+
+			"%v", args[0], n.hash, r.timeout)
+
+	case <-r.quit:
+		cmd.Process.Kill()
+		<-done
+	}
+}
+
+// watchedTx returns whether the passed transaction pays to any of the
+// addresses watched by the txnotify command.
+func (r *notifyCmdRunner) watchedTx(tx *coinutil.Tx) bool {
+	for _, txOut := range tx.MsgTx().TxOut {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			txOut.PkScript, activeNetParams.Params)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if _, ok := r.txAddrs[addr.EncodeAddress()]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BlockConnected queues the blocknotify command for the connected block and
+// the txnotify command for each of its transactions paying to a watched
+// address.  Nothing is run during the initial block download, which would
+// otherwise start a command for every block of the chain.  It is called by
+// the event bus of the server.
+func (r *notifyCmdRunner) BlockConnected(block *coinutil.Block) {
+	if r.server.blockManager.IsInitialBlockDownload() {
+		return
+	}
+	if r.blockCommand != "" {
+		r.enqueue(r.blockCommand, block.Sha().String())
+	}
+	if r.txCommand != "" {
+		for _, tx := range block.Transactions() {
+			if r.watchedTx(tx) {
+				r.enqueue(r.txCommand, tx.Sha().String())
+			}
+		}
+	}
+}
+
+// HeaderConnected queues the blocknotify command for a header connected to the
+// main chain of a node running in headers-only mode.  It is called by the
+// event bus of the server.
+func (r *notifyCmdRunner) HeaderConnected(header *blockchain.HeaderInfo) {
+	if r.blockCommand == "" ||
+		r.server.blockManager.IsInitialBlockDownload() {
+
+		return
+	}
+	r.enqueue(r.blockCommand, header.Hash.String())
+}
+
+// MempoolTxAccepted queues the txnotify command for a transaction accepted to
+// the memory pool which pays to a watched address.  It is called by the event
+// bus of the server.
+func (r *notifyCmdRunner) MempoolTxAccepted(tx *coinutil.Tx) {
+	if r.txCommand != "" && r.watchedTx(tx) {
+		r.enqueue(r.txCommand, tx.Sha().String())
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestNotifyCmdArgs ensures notification commands are split into their
+// arguments with every %s replaced by the hash.
+func TestNotifyCmdArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"", []string{}},
+		{"/bin/notify", []string{"/bin/notify"}},
+		{"/bin/notify %s", []string{"/bin/notify", "abcd"}},
+		{"  /bin/notify  -h=%s  %s%s ", []string{"/bin/notify",
+			"-h=abcd", "abcdabcd"}},
+	}
+
+	for _, test := range tests {
+		got := notifyCmdArgs(test.command, "abcd")
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("notifyCmdArgs(%q): got %q, want %q",
+				test.command, got, test.want)
+		}
+	}
+}
+
+// TestNotifyCmdRunnerWatchedTx ensures the txnotify command is only run for
+// transactions paying to a watched address.
+func TestNotifyCmdRunnerWatchedTx(t *testing.T) {
+	params := activeNetParams.Params
+	watched, err := coinutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20),
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	other, err := coinutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x02}, 20),
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	watchedScript, err := txscript.PayToAddrScript(watched)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+	otherScript, err := txscript.PayToAddrScript(other)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+
+	r := &notifyCmdRunner{
+		txCommand: "notify %s",
+		txAddrs:   map[string]struct{}{watched.EncodeAddress(): {}},
+		queue:     make(chan *notifyCmd, notifyCmdQueueSize),
+	}
+
+	msgTx := wire.NewMsgTx()
+	msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil))
+	msgTx.AddTxOut(wire.NewTxOut(1000, otherScript))
+	r.MempoolTxAccepted(coinutil.NewTx(msgTx))
+	if len(r.queue) != 0 {
+		t.Fatalf("MempoolTxAccepted: command queued for a transaction " +
+			"which doesn't pay to a watched address")
+	}
+
+	msgTx.AddTxOut(wire.NewTxOut(2000, watchedScript))
+	tx := coinutil.NewTx(msgTx)
+	r.MempoolTxAccepted(tx)
+	if len(r.queue) != 1 {
+		t.Fatalf("MempoolTxAccepted: got %d queued commands, want 1",
+			len(r.queue))
+	}
+	n := <-r.queue
+	if n.command != "notify %s" || n.hash != tx.Sha().String() {
+		t.Fatalf("MempoolTxAccepted: unexpected command %+v", n)
+	}
+}
+
+// TestNotifyCmdRunnerTimeout ensures commands which run longer than the
+// timeout are killed.
+func TestNotifyCmdRunnerTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep command is not available")
+	}
+
+	r := &notifyCmdRunner{
+		timeout: time.Millisecond * 100,
+		quit:    make(chan struct{}),
+	}
+	start := time.Now()
+	r.run(&notifyCmd{command: "sleep %s", hash: "10"})
+	if elapsed := time.Since(start); elapsed > time.Second*5 {
+		t.Fatalf("run: command was not killed after %v", elapsed)
+	}
+}
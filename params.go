@@ -5,6 +5,10 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+
 	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/wire"
 )
@@ -55,6 +59,40 @@ var simNetParams = params{
 	rpcPort: "16686",
 }
 
+// defaultCustomNetRPCPort is the RPC port used for a custom network when its
+// definition does not specify one.
+const defaultCustomNetRPCPort = "16688"
+
+// loadCustomNetParams reads the JSON definition of a custom network from the
+// passed file and registers it with chaincfg.  Besides the fields decoded by
+// chaincfg.DecodeParams, the definition may set the RPC port with an
+// "rpcport" field.
+func loadCustomNetParams(file string) (*params, error) {
+	serialized, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	chainParams, err := chaincfg.DecodeParams(bytes.NewReader(serialized))
+	if err != nil {
+		return nil, err
+	}
+	var extra struct {
+		RPCPort string `json:"rpcport"`
+	}
+	if err := json.Unmarshal(serialized, &extra); err != nil {
+		return nil, err
+	}
+	if extra.RPCPort == "" {
+		extra.RPCPort = defaultCustomNetRPCPort
+	}
+
+	if err := chaincfg.Register(chainParams); err != nil {
+		return nil, err
+	}
+	return &params{Params: chainParams, rpcPort: extra.RPCPort}, nil
+}
+
 // netName returns the name used when referring to a bitcoin network.  At the
 // time of writing, btcd currently places blocks for testnet version 3 in the
 // data and log directory "testnet", which does not match the Name field of the
@@ -0,0 +1,23 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package paymenturi parses and formats the payment request URIs defined by
+BIP0021.
+
+A payment URI consists of the URI scheme of the network, which is configured in
+the PaymentURIScheme field of chaincfg.Params, followed by the address to pay
+and optional query parameters:
+
+	bitcoin:<address>[?amount=<amount>][&label=<label>][&message=<message>]
+
+The amount is given in whole coins with up to eight decimal places.  Parameters
+which are not defined by BIP0021 are kept in the Params field of a URI, except
+that parameters prefixed with "req-" must be understood by the receiver, so
+URIs with such parameters are rejected.
+
+Addresses are decoded with bech32.DecodeAddress, so both base58 and native
+segwit addresses are supported, and they must belong to the passed network.
+*/
+package paymenturi
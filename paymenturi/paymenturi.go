@@ -0,0 +1,237 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package paymenturi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/bech32"
+	"github.com/conseweb/stcd/chaincfg"
+)
+
+var (
+	// ErrInvalidScheme describes an error where a payment URI does not use
+	// the payment URI scheme of the network it is decoded for.
+	ErrInvalidScheme = errors.New("payment URI does not use the scheme " +
+		"of the network")
+
+	// ErrWrongNetwork describes an error where the address of a payment
+	// URI belongs to a different network than the one the URI is decoded
+	// for.
+	ErrWrongNetwork = errors.New("payment URI address is for a " +
+		"different network")
+)
+
+// requiredParamPrefix is the prefix of the names of parameters which must be
+// understood by the receiver of a payment URI.
+const requiredParamPrefix = "req-"
+
+// maxAmountDecimals is the maximum number of decimal places of the amount of a
+// payment URI, which is given in whole coins.
+const maxAmountDecimals = 8
+
+// URI is a payment request as described by a BIP0021 payment URI.
+type URI struct {
+	// Address is the address to pay.
+	Address coinutil.Address
+
+	// Amount is the requested amount.  It is zero when no amount is
+	// requested.
+	Amount coinutil.Amount
+
+	// Label is the name of the recipient and Message describes the
+	// payment.  They are empty when not given.
+	Label   string
+	Message string
+
+	// Params holds the parameters which are not defined by BIP0021 keyed
+	// by their names.  It is nil when there are none.
+	Params map[string]string
+}
+
+// Decode parses the passed payment URI for the passed network.  The scheme of
+// the URI must be the PaymentURIScheme of the network, compared without regard
+// to case, and the address must belong to the network.  URIs with parameters
+// prefixed with "req-" are rejected since none of them are understood.
+func Decode(uri string, net *chaincfg.Params) (*URI, error) {
+	sep := strings.Index(uri, ":")
+	if sep < 0 || !strings.EqualFold(uri[:sep], net.PaymentURIScheme) {
+		return nil, ErrInvalidScheme
+	}
+	encodedAddr, query := uri[sep+1:], ""
+	if sep := strings.Index(encodedAddr, "?"); sep >= 0 {
+		encodedAddr, query = encodedAddr[:sep], encodedAddr[sep+1:]
+	}
+
+	addr, err := bech32.DecodeAddress(encodedAddr, net)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment URI address: %v", err)
+	}
+	if !addr.IsForNet(net) {
+		return nil, ErrWrongNetwork
+	}
+	result := &URI{Address: addr}
+	if query == "" {
+		return result, nil
+	}
+
+	seen := make(map[string]struct{})
+	for _, param := range strings.Split(query, "&") {
+		if param == "" {
+			continue
+		}
+		name, value := param, ""
+		if sep := strings.Index(param, "="); sep >= 0 {
+			name, value = param[:sep], param[sep+1:]
+		}
+		name, err := unescape(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payment URI parameter "+
+				"name %q: %v", param, err)
+		}
+		value, err = unescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of payment URI "+
+				"parameter %q: %v", name, err)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("duplicate payment URI "+
+				"parameter %q", name)
+		}
+		seen[name] = struct{}{}
+
+		switch name {
+		case "amount":
+			result.Amount, err = parseAmount(value)
+			if err != nil {
+				return nil, err
+			}
+
+		case "label":
+			result.Label = value
+
+		case "message":
+			result.Message = value
+
+		default:
+			if strings.HasPrefix(name, requiredParamPrefix) {
+				return nil, fmt.Errorf("unsupported required "+
+					"payment URI parameter %q", name)
+			}
+			if result.Params == nil {
+				result.Params = make(map[string]string)
+			}
+			result.Params[name] = value
+		}
+	}
+
+	return result, nil
+}
+
+// Encode returns the payment URI for the passed network describing the
+// payment request.  The amount is omitted unless it is positive, the label and
+// message are omitted when they are empty, and the other parameters are
+// ordered by their names.
+func (u *URI) Encode(net *chaincfg.Params) string {
+	var buf bytes.Buffer
+	buf.WriteString(net.PaymentURIScheme)
+	buf.WriteByte(':')
+	buf.WriteString(u.Address.EncodeAddress())
+
+	sep := byte('?')
+	writeParam := func(name, value string) {
+		buf.WriteByte(sep)
+		buf.WriteString(escape(name))
+		buf.WriteByte('=')
+		buf.WriteString(escape(value))
+		sep = '&'
+	}
+	if u.Amount > 0 {
+		writeParam("amount", formatAmount(u.Amount))
+	}
+	if u.Label != "" {
+		writeParam("label", u.Label)
+	}
+	if u.Message != "" {
+		writeParam("message", u.Message)
+	}
+	names := make([]string, 0, len(u.Params))
+	for name := range u.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeParam(name, u.Params[name])
+	}
+
+	return buf.String()
+}
+
+// parseAmount parses the passed amount in whole coins with up to eight decimal
+// places.  Unlike coinutil.NewAmount, it does not go through a floating point
+// number, so amounts are parsed exactly.
+func parseAmount(s string) (coinutil.Amount, error) {
+	whole, frac := s, ""
+	if sep := strings.Index(s, "."); sep >= 0 {
+		whole, frac = s[:sep], s[sep+1:]
+	}
+	if whole == "" && frac == "" || len(frac) > maxAmountDecimals ||
+		strings.TrimLeft(whole+frac, "0123456789") != "" ||
+		len(strings.TrimLeft(whole, "0")) > 10 {
+
+		return 0, fmt.Errorf("invalid payment URI amount %q", s)
+	}
+
+	frac += strings.Repeat("0", maxAmountDecimals-len(frac))
+	satoshi, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || satoshi > coinutil.MaxSatoshi {
+		return 0, fmt.Errorf("invalid payment URI amount %q", s)
+	}
+	return coinutil.Amount(satoshi), nil
+}
+
+// formatAmount formats the passed amount in whole coins without trailing
+// zeros in the decimal places.
+func formatAmount(a coinutil.Amount) string {
+	whole := int64(a) / coinutil.SatoshiPerBitcoin
+	frac := int64(a) % coinutil.SatoshiPerBitcoin
+	if frac == 0 {
+		return strconv.FormatInt(whole, 10)
+	}
+	s := fmt.Sprintf("%d.%08d", whole, frac)
+	return strings.TrimRight(s, "0")
+}
+
+// escape percent-encodes every byte of the passed string other than the
+// unreserved characters of RFC 3986.
+func escape(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z',
+			'0' <= c && c <= '9', c == '-', c == '.', c == '_',
+			c == '~':
+
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+// unescape decodes the percent-encoded bytes of the passed string.  Unlike in
+// HTML forms, a plus sign stands for itself rather than a space.
+func unescape(s string) (string, error) {
+	return url.QueryUnescape(strings.Replace(s, "+", "%2B", -1))
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package paymenturi_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/paymenturi"
+)
+
+// TestDecode ensures payment URIs are decoded as specified by BIP0021 and that
+// invalid ones are rejected.
+func TestDecode(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	tests := []struct {
+		name    string
+		uri     string
+		addr    string
+		amount  coinutil.Amount
+		label   string
+		message string
+		params  map[string]string
+		valid   bool
+	}{
+		{
+			name:  "address only",
+			uri:   "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			addr:  "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			valid: true,
+		},
+		{
+			name:  "scheme is case insensitive",
+			uri:   "BITCOIN:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			addr:  "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			valid: true,
+		},
+		{
+			name:  "segwit address",
+			uri:   "bitcoin:bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			addr:  "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			valid: true,
+		},
+		{
+			name: "all parameters",
+			uri: "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?" +
+				"amount=20.3&label=Luke-Jr&" +
+				"message=Donation%20for%20project%20xyz&" +
+				"somethingyoudontunderstand=50+1",
+			addr:    "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			amount:  2030000000,
+			label:   "Luke-Jr",
+			message: "Donation for project xyz",
+			params: map[string]string{
+				"somethingyoudontunderstand": "50+1",
+			},
+			valid: true,
+		},
+		{
+			name: "smallest amount",
+			uri: "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?" +
+				"amount=.00000001",
+			addr:   "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			amount: 1,
+			valid:  true,
+		},
+		{
+			name:  "wrong scheme",
+			uri:   "litecoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			valid: false,
+		},
+		{
+			name:  "no scheme",
+			uri:   "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			valid: false,
+		},
+		{
+			name:  "invalid address",
+			uri:   "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3",
+			valid: false,
+		},
+		{
+			name:  "address for another network",
+			uri:   "bitcoin:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+			valid: false,
+		},
+		{
+			name:  "required parameter",
+			uri:   "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?req-x=1",
+			valid: false,
+		},
+		{
+			name: "too many decimals",
+			uri: "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?" +
+				"amount=0.000000001",
+			valid: false,
+		},
+		{
+			name: "exponent amount",
+			uri: "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?" +
+				"amount=1e3",
+			valid: false,
+		},
+		{
+			name: "amount above the maximum",
+			uri: "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?" +
+				"amount=21000000.00000001",
+			valid: false,
+		},
+		{
+			name: "duplicate parameter",
+			uri: "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?" +
+				"label=a&label=b",
+			valid: false,
+		},
+	}
+
+	for _, test := range tests {
+		uri, err := paymenturi.Decode(test.uri, net)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: Decode: unexpected success", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Decode: unexpected error: %v", test.name,
+				err)
+			continue
+		}
+		if uri.Address.EncodeAddress() != test.addr {
+			t.Errorf("%s: unexpected address - got %s, want %s",
+				test.name, uri.Address.EncodeAddress(),
+				test.addr)
+		}
+		if uri.Amount != test.amount {
+			t.Errorf("%s: unexpected amount - got %d, want %d",
+				test.name, uri.Amount, test.amount)
+		}
+		if uri.Label != test.label || uri.Message != test.message {
+			t.Errorf("%s: unexpected label and message - got %q "+
+				"and %q, want %q and %q", test.name, uri.Label,
+				uri.Message, test.label, test.message)
+		}
+		if !reflect.DeepEqual(uri.Params, test.params) {
+			t.Errorf("%s: unexpected parameters - got %v, want %v",
+				test.name, uri.Params, test.params)
+		}
+	}
+}
+
+// TestEncode ensures payment URIs are encoded with the scheme of the network
+// and escaped parameters, and that they decode to the same payment request.
+func TestEncode(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	addr, err := coinutil.DecodeAddress(
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", net)
+	if err != nil {
+		t.Fatalf("DecodeAddress: unexpected error: %v", err)
+	}
+	uri := &paymenturi.URI{
+		Address: addr,
+		Amount:  2030000000,
+		Label:   "Luke-Jr",
+		Message: "Donation for project xyz & more",
+		Params:  map[string]string{"z": "1+1", "a": ""},
+	}
+
+	want := "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount=20.3&" +
+		"label=Luke-Jr&message=Donation%20for%20project%20xyz%20%26" +
+		"%20more&a=&z=1%2B1"
+	encoded := uri.Encode(net)
+	if encoded != want {
+		t.Fatalf("Encode: got %s, want %s", encoded, want)
+	}
+
+	decoded, err := paymenturi.Decode(encoded, net)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if decoded.Address.EncodeAddress() != addr.EncodeAddress() {
+		t.Errorf("Decode: unexpected address - got %s, want %s",
+			decoded.Address.EncodeAddress(), addr.EncodeAddress())
+	}
+	decoded.Address = addr
+	if !reflect.DeepEqual(decoded, uri) {
+		t.Errorf("Decode: got %+v, want %+v", decoded, uri)
+	}
+
+	// An address without any parameters has no query.
+	uri = &paymenturi.URI{Address: addr}
+	want = "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"
+	if encoded := uri.Encode(net); encoded != want {
+		t.Errorf("Encode: got %s, want %s", encoded, want)
+	}
+}
@@ -166,12 +166,39 @@ type MessageListeners struct {
 	// message.
 	OnMerkleBlock func(p *Peer, msg *wire.MsgMerkleBlock)
 
+	// OnGetCFilters is invoked when a peer receives a getcfilters bitcoin
+	// message.
+	OnGetCFilters func(p *Peer, msg *wire.MsgGetCFilters)
+
+	// OnGetCFHeaders is invoked when a peer receives a getcfheaders
+	// bitcoin message.
+	OnGetCFHeaders func(p *Peer, msg *wire.MsgGetCFHeaders)
+
+	// OnGetCFCheckpt is invoked when a peer receives a getcfcheckpt
+	// bitcoin message.
+	OnGetCFCheckpt func(p *Peer, msg *wire.MsgGetCFCheckpt)
+
+	// OnCFilter is invoked when a peer receives a cfilter bitcoin message.
+	OnCFilter func(p *Peer, msg *wire.MsgCFilter)
+
+	// OnCFHeaders is invoked when a peer receives a cfheaders bitcoin
+	// message.
+	OnCFHeaders func(p *Peer, msg *wire.MsgCFHeaders)
+
+	// OnCFCheckpt is invoked when a peer receives a cfcheckpt bitcoin
+	// message.
+	OnCFCheckpt func(p *Peer, msg *wire.MsgCFCheckpt)
+
 	// OnVersion is invoked when a peer receives a version bitcoin message.
 	OnVersion func(p *Peer, msg *wire.MsgVersion)
 
 	// OnVerAck is invoked when a peer receives a verack bitcoin message.
 	OnVerAck func(p *Peer, msg *wire.MsgVerAck)
 
+	// OnWTxIdRelay is invoked when a peer receives a wtxidrelay bitcoin
+	// message.
+	OnWTxIdRelay func(p *Peer, msg *wire.MsgWTxIdRelay)
+
 	// OnReject is invoked when a peer receives a reject bitcoin message.
 	OnReject func(p *Peer, msg *wire.MsgReject)
 
@@ -240,6 +267,13 @@ type Config struct {
 	// not send inv messages for transactions.
 	DisableRelayTx bool
 
+	// MessageFault specifies a callback which decides whether messages
+	// exchanged with the peer are dropped or delayed as if the network was
+	// unreliable.  Dropped messages are treated as lost on the wire, so
+	// the stall detection still applies to them.  This field can be
+	// omitted in which case messages are never dropped or delayed.
+	MessageFault MessageFaultFunc
+
 	// Listeners houses callback functions to be invoked on receiving peer
 	// messages.
 	Listeners MessageListeners
@@ -360,6 +394,13 @@ type AddrFunc func(remoteAddr *wire.NetAddress) *wire.NetAddress
 type HostToNetAddrFunc func(host string, port uint16,
 	services wire.ServiceFlag) (*wire.NetAddress, error)
 
+// MessageFaultFunc is a func which takes a message received from the peer when
+// inbound is true, or about to be sent to it otherwise, and returns whether the
+// message is dropped and how long it is delayed.  It is used to simulate
+// unreliable network conditions.
+type MessageFaultFunc func(p *Peer, msg wire.Message, inbound bool) (drop bool,
+	delay time.Duration)
+
 // NOTE: The overall data flow of a peer is split into 3 goroutines.  Inbound
 // messages are read via the inHandler goroutine and generally dispatched to
 // their own handler.  For inbound data-related messages such as blocks,
@@ -407,6 +448,7 @@ type Peer struct {
 	protocolVersion uint32
 	versionSent     bool
 	verAckReceived  bool
+	wtxidRelay      bool
 
 	knownInventory     *mruInventoryMap
 	prevGetBlocksMtx   sync.Mutex
@@ -637,6 +679,17 @@ func (p *Peer) VerAckReceived() bool {
 	return p.verAckReceived
 }
 
+// WTxIdRelay returns whether or not the peer and the remote peer have agreed to
+// announce and request transactions by their witness hash.
+//
+// This function is safe for concurrent access.
+func (p *Peer) WTxIdRelay() bool {
+	p.flagsMtx.Lock()
+	defer p.flagsMtx.Unlock()
+
+	return p.wtxidRelay
+}
+
 // ProtocolVersion returns the peer protocol version.
 //
 // This function is safe for concurrent access.
@@ -1047,11 +1100,38 @@ func (p *Peer) handleVersionMsg(msg *wire.MsgVersion) error {
 		}
 	}
 
+	// Offer to relay transactions by their witness hash when both peers
+	// support segregated witness.  This must be sent before the verack.
+	if p.cfg.Services&wire.SFNodeWitness == wire.SFNodeWitness &&
+		msg.Services&wire.SFNodeWitness == wire.SFNodeWitness {
+
+		p.QueueMessage(wire.NewMsgWTxIdRelay(), nil)
+	}
+
 	// Send verack.
 	p.QueueMessage(wire.NewMsgVerAck(), nil)
 	return nil
 }
 
+// handleWTxIdRelayMsg is invoked when a peer receives a wtxidrelay bitcoin
+// message.  Transactions are only relayed by their witness hash when both
+// peers support segregated witness, and the message is only valid before the
+// verack.
+func (p *Peer) handleWTxIdRelayMsg(msg *wire.MsgWTxIdRelay) error {
+	p.flagsMtx.Lock()
+	defer p.flagsMtx.Unlock()
+
+	if p.verAckReceived {
+		return errors.New("wtxidrelay received after verack")
+	}
+	if p.cfg.Services&wire.SFNodeWitness == wire.SFNodeWitness &&
+		p.services&wire.SFNodeWitness == wire.SFNodeWitness {
+
+		p.wtxidRelay = true
+	}
+	return nil
+}
+
 // isValidBIP0111 is a helper function for the bloom filter commands to check
 // BIP0111 compliance.
 func (p *Peer) isValidBIP0111(cmd string) bool {
@@ -1160,6 +1240,12 @@ func (p *Peer) writeMessage(msg wire.Message) error {
 		}
 	}
 
+	// Simulate a network fault for the message when requested.  A
+	// dropped message is treated as if it was sent successfully.
+	if drop, ok := p.messageFault(msg, false); !ok || drop {
+		return nil
+	}
+
 	// Use closures to log expensive operations so they are only run when
 	// the logging level requires it.
 	log.Debugf("%v", newLogClosure(func() string {
@@ -1196,6 +1282,35 @@ func (p *Peer) writeMessage(msg wire.Message) error {
 	return err
 }
 
+// messageFault applies the network fault the MessageFault callback, if any,
+// decides on for the passed message.  It waits for the message to be delayed
+// and returns whether the message is dropped.  False is returned for ok when
+// the peer disconnects while waiting.
+func (p *Peer) messageFault(msg wire.Message, inbound bool) (drop, ok bool) {
+	if p.cfg.MessageFault == nil {
+		return false, true
+	}
+
+	drop, delay := p.cfg.MessageFault(p, msg, inbound)
+	direction := "to"
+	if inbound {
+		direction = "from"
+	}
+	if delay > 0 {
+		log.Tracef("Delaying %s %s %s by %v", msg.Command(), direction,
+			p, delay)
+		select {
+		case <-time.After(delay):
+		case <-p.quit:
+			return false, false
+		}
+	}
+	if drop {
+		log.Tracef("Dropping %s %s %s", msg.Command(), direction, p)
+	}
+	return drop, true
+}
+
 // isAllowedByRegression returns whether or not the passed error is allowed by
 // regression tests without disconnecting the peer.  In particular, regression
 // tests need to be allowed to send malformed messages without the peer being
@@ -1491,6 +1606,15 @@ out:
 			}
 			break out
 		}
+
+		// Simulate a network fault for the message when requested.
+		if drop, ok := p.messageFault(rmsg, true); !ok {
+			break out
+		} else if drop {
+			idleTimer.Reset(idleTimeout)
+			continue
+		}
+
 		p.statsMtx.Lock()
 		p.lastRecv = time.Now()
 		p.statsMtx.Unlock()
@@ -1548,6 +1672,16 @@ out:
 				p.cfg.Listeners.OnVerAck(p, msg)
 			}
 
+		case *wire.MsgWTxIdRelay:
+			if err := p.handleWTxIdRelayMsg(msg); err != nil {
+				log.Infof("Peer %v sent an invalid wtxidrelay "+
+					"message: %v -- disconnecting", p, err)
+				break out
+			}
+			if p.cfg.Listeners.OnWTxIdRelay != nil {
+				p.cfg.Listeners.OnWTxIdRelay(p, msg)
+			}
+
 		case *wire.MsgGetAddr:
 			if p.cfg.Listeners.OnGetAddr != nil {
 				p.cfg.Listeners.OnGetAddr(p, msg)
@@ -1640,6 +1774,36 @@ out:
 				p.cfg.Listeners.OnMerkleBlock(p, msg)
 			}
 
+		case *wire.MsgGetCFilters:
+			if p.cfg.Listeners.OnGetCFilters != nil {
+				p.cfg.Listeners.OnGetCFilters(p, msg)
+			}
+
+		case *wire.MsgGetCFHeaders:
+			if p.cfg.Listeners.OnGetCFHeaders != nil {
+				p.cfg.Listeners.OnGetCFHeaders(p, msg)
+			}
+
+		case *wire.MsgGetCFCheckpt:
+			if p.cfg.Listeners.OnGetCFCheckpt != nil {
+				p.cfg.Listeners.OnGetCFCheckpt(p, msg)
+			}
+
+		case *wire.MsgCFilter:
+			if p.cfg.Listeners.OnCFilter != nil {
+				p.cfg.Listeners.OnCFilter(p, msg)
+			}
+
+		case *wire.MsgCFHeaders:
+			if p.cfg.Listeners.OnCFHeaders != nil {
+				p.cfg.Listeners.OnCFHeaders(p, msg)
+			}
+
+		case *wire.MsgCFCheckpt:
+			if p.cfg.Listeners.OnCFCheckpt != nil {
+				p.cfg.Listeners.OnCFCheckpt(p, msg)
+			}
+
 		case *wire.MsgReject:
 			if p.cfg.Listeners.OnReject != nil {
 				p.cfg.Listeners.OnReject(p, msg)
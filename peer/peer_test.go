@@ -371,6 +371,24 @@ func TestPeerListeners(t *testing.T) {
 			OnMerkleBlock: func(p *peer.Peer, msg *wire.MsgMerkleBlock) {
 				ok <- msg
 			},
+			OnGetCFilters: func(p *peer.Peer, msg *wire.MsgGetCFilters) {
+				ok <- msg
+			},
+			OnGetCFHeaders: func(p *peer.Peer, msg *wire.MsgGetCFHeaders) {
+				ok <- msg
+			},
+			OnGetCFCheckpt: func(p *peer.Peer, msg *wire.MsgGetCFCheckpt) {
+				ok <- msg
+			},
+			OnCFilter: func(p *peer.Peer, msg *wire.MsgCFilter) {
+				ok <- msg
+			},
+			OnCFHeaders: func(p *peer.Peer, msg *wire.MsgCFHeaders) {
+				ok <- msg
+			},
+			OnCFCheckpt: func(p *peer.Peer, msg *wire.MsgCFCheckpt) {
+				ok <- msg
+			},
 			OnVersion: func(p *peer.Peer, msg *wire.MsgVersion) {
 				ok <- msg
 			},
@@ -495,6 +513,30 @@ func TestPeerListeners(t *testing.T) {
 			"OnMerkleBlock",
 			wire.NewMsgMerkleBlock(wire.NewBlockHeader(&wire.ShaHash{}, &wire.ShaHash{}, 1, 1)),
 		},
+		{
+			"OnGetCFilters",
+			wire.NewMsgGetCFilters(wire.GCSFilterRegular, 0, &wire.ShaHash{}),
+		},
+		{
+			"OnGetCFHeaders",
+			wire.NewMsgGetCFHeaders(wire.GCSFilterRegular, 0, &wire.ShaHash{}),
+		},
+		{
+			"OnGetCFCheckpt",
+			wire.NewMsgGetCFCheckpt(wire.GCSFilterRegular, &wire.ShaHash{}),
+		},
+		{
+			"OnCFilter",
+			wire.NewMsgCFilter(wire.GCSFilterRegular, &wire.ShaHash{}, []byte{0x00}),
+		},
+		{
+			"OnCFHeaders",
+			wire.NewMsgCFHeaders(),
+		},
+		{
+			"OnCFCheckpt",
+			wire.NewMsgCFCheckpt(wire.GCSFilterRegular, &wire.ShaHash{}, 0),
+		},
 		// only one version message is allowed
 		// only one verack message is allowed
 		{
@@ -517,6 +559,108 @@ func TestPeerListeners(t *testing.T) {
 	outPeer.Shutdown()
 }
 
+// TestPeerMessageFault tests that messages are dropped and delayed as decided
+// by the MessageFault callback in both directions.
+func TestPeerMessageFault(t *testing.T) {
+	verack := make(chan struct{}, 2)
+	received := make(chan wire.Message, 10)
+	inCfg := &peer.Config{
+		Listeners: peer.MessageListeners{
+			OnGetAddr: func(p *peer.Peer, msg *wire.MsgGetAddr) {
+				received <- msg
+			},
+			OnMemPool: func(p *peer.Peer, msg *wire.MsgMemPool) {
+				received <- msg
+			},
+			OnAddr: func(p *peer.Peer, msg *wire.MsgAddr) {
+				received <- msg
+			},
+			OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+				verack <- struct{}{}
+			},
+		},
+		// Drop mempool messages received by the inbound peer and delay
+		// addr messages.
+		MessageFault: func(p *peer.Peer, msg wire.Message, inbound bool) (bool, time.Duration) {
+			if !inbound {
+				return false, 0
+			}
+			switch msg.(type) {
+			case *wire.MsgMemPool:
+				return true, 0
+			case *wire.MsgAddr:
+				return false, time.Millisecond * 50
+			}
+			return false, 0
+		},
+		UserAgentName:    "peer",
+		UserAgentVersion: "1.0",
+		ChainParams:      &chaincfg.MainNetParams,
+	}
+	outCfg := &peer.Config{
+		Listeners: peer.MessageListeners{
+			OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+				verack <- struct{}{}
+			},
+		},
+		// Drop getaddr messages sent by the outbound peer.
+		MessageFault: func(p *peer.Peer, msg wire.Message, inbound bool) (bool, time.Duration) {
+			_, ok := msg.(*wire.MsgGetAddr)
+			return ok && !inbound, 0
+		},
+		UserAgentName:    "peer",
+		UserAgentVersion: "1.0",
+		ChainParams:      &chaincfg.MainNetParams,
+	}
+	inConn, outConn := pipe(
+		&conn{raddr: "10.0.0.1:6682"},
+		&conn{raddr: "10.0.0.2:6682"},
+	)
+	inPeer := peer.NewInboundPeer(inCfg, inConn)
+	if err := inPeer.Start(); err != nil {
+		t.Fatalf("Start: unexpected err %v", err)
+	}
+	defer inPeer.Shutdown()
+	outPeer, err := peer.NewOutboundPeer(outCfg, "10.0.0.1:6682")
+	if err != nil {
+		t.Fatalf("NewOutboundPeer: unexpected err %v", err)
+	}
+	if err := outPeer.Connect(outConn); err != nil {
+		t.Fatalf("Connect: unexpected err %v", err)
+	}
+	defer outPeer.Shutdown()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-verack:
+		case <-time.After(time.Second * 1):
+			t.Fatalf("verack timeout")
+		}
+	}
+
+	// Only the addr message gets through, and only after the delay.
+	start := time.Now()
+	outPeer.QueueMessage(wire.NewMsgGetAddr(), nil)
+	outPeer.QueueMessage(wire.NewMsgMemPool(), nil)
+	outPeer.QueueMessage(wire.NewMsgAddr(), nil)
+	select {
+	case msg := <-received:
+		if _, ok := msg.(*wire.MsgAddr); !ok {
+			t.Fatalf("received dropped %s message", msg.Command())
+		}
+		if elapsed := time.Since(start); elapsed < time.Millisecond*50 {
+			t.Fatalf("addr message received after %v - want "+
+				"delay of at least 50ms", elapsed)
+		}
+	case <-time.After(time.Second * 1):
+		t.Fatalf("addr message timeout")
+	}
+	select {
+	case msg := <-received:
+		t.Fatalf("received unexpected %s message", msg.Command())
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
 // TestOutboundPeer tests that the outbound peer works as expected.
 func TestOutboundPeer(t *testing.T) {
 	// Use a mock NewestBlock func to test errs
@@ -19,6 +19,12 @@ const (
 	// for mining.
 	maxStandardTxSize = 100000
 
+	// maxStandardTxWeight is the maximum weight allowed for transactions
+	// that are considered standard.  It scales the maximum size by the
+	// witness scale factor so transactions without witness data are
+	// limited exactly as they are by the maximum size.
+	maxStandardTxWeight = maxStandardTxSize * blockchain.WitnessScaleFactor
+
 	// maxStandardSigScriptSize is the maximum size allowed for a
 	// transaction input signature script to be considered standard.  This
 	// value allows for a 15-of-15 CHECKMULTISIG pay-to-script-hash with
@@ -49,8 +55,83 @@ const (
 	// in a multi-signature transaction output script for it to be
 	// considered standard.
 	maxStandardMultiSigKeys = 3
+
+	// defaultMaxSigOpsPerTx is the default maximum number of signature
+	// operations in a single transaction we will relay or mine.  It is a
+	// fraction of the max signature operations for a block.
+	defaultMaxSigOpsPerTx = blockchain.MaxSigOpsPerBlock / 5
+
+	// defaultMaxDataCarrierOutputs is the default maximum number of
+	// nulldata outputs in a single transaction we will relay or mine.
+	defaultMaxDataCarrierOutputs = 1
+
+	// mandatoryVerifyFlags are the script flags which are enforced by the
+	// consensus rules.  Transactions which only fail script validation
+	// due to the additional checks in txscript.StandardVerifyFlags, such
+	// as the low S and canonical public key checks, are rejected as
+	// non-standard rather than invalid.
+	mandatoryVerifyFlags = txscript.ScriptBip16 |
+		txscript.ScriptVerifyDERSignatures |
+		txscript.ScriptVerifyCheckLockTimeVerify |
+		txscript.ScriptVerifyWitness
+
+	// malleabilityVerifyFlags are the script flags which become mandatory
+	// once the malleability rule change deployment is active.
+	malleabilityVerifyFlags = txscript.ScriptVerifySigPushOnly |
+		txscript.ScriptVerifyMinimalData |
+		txscript.ScriptVerifyCleanStack
 )
 
+// policyVerifyFlags are the script flags in txscript.StandardVerifyFlags which
+// are not enforced by the consensus rules along with the names they are
+// reported by when a transaction fails them.
+var policyVerifyFlags = []struct {
+	flag txscript.ScriptFlags
+	name string
+}{
+	{txscript.ScriptVerifySigPushOnly, "SIGPUSHONLY"},
+	{txscript.ScriptVerifyMinimalData, "MINIMALDATA"},
+	{txscript.ScriptVerifyCleanStack, "CLEANSTACK"},
+	{txscript.ScriptVerifyStrictEncoding, "STRICTENC"},
+	{txscript.ScriptVerifyLowS, "LOW_S"},
+	{txscript.ScriptVerifyCanonicalPubKeys, "CANONICAL_PUBKEYS"},
+	{txscript.ScriptStrictMultiSig, "NULLDUMMY"},
+	{txscript.ScriptDiscourageUpgradableNops, "DISCOURAGE_UPGRADABLE_NOPS"},
+	{txscript.ScriptVerifyDiscourageUpgradeableWitnessProgram,
+		"DISCOURAGE_UPGRADABLE_WITNESS_PROGRAM"},
+}
+
+// standardPolicy houses the rules, configurable at runtime, which determine
+// whether or not a transaction is standard and therefore relayed and
+// considered for mining.
+type standardPolicy struct {
+	// AcceptDataCarrier defines whether or not outputs which only carry
+	// data with a nulldata script are standard.
+	AcceptDataCarrier bool
+
+	// MaxDataCarrierSize is the maximum number of bytes a nulldata output
+	// may push.
+	MaxDataCarrierSize int
+
+	// MaxDataCarrierOutputs is the maximum number of nulldata outputs a
+	// transaction may have.
+	MaxDataCarrierOutputs int
+
+	// AcceptBareMultisig defines whether or not outputs which pay to a
+	// multi-signature script directly, rather than through a
+	// pay-to-script-hash, are standard.
+	AcceptBareMultisig bool
+
+	// DustRelayFee is the fee rate in Satoshi/kB used to determine whether
+	// an output is dust.  See isDust for details.
+	DustRelayFee coinutil.Amount
+
+	// MaxSigOpsPerTx is the maximum number of signature operations a
+	// transaction may contain, including those of the pay-to-script-hash
+	// redeem scripts it spends.
+	MaxSigOpsPerTx int
+}
+
 // calcMinRequiredTxRelayFee returns the minimum transaction fee required for a
 // transaction with the passed serialized size to be accepted into the memory
 // pool and relayed.
@@ -322,6 +403,21 @@ func isDust(txOut *wire.TxOut, minRelayTxFee coinutil.Amount) bool {
 	return txOut.Value*1000/(3*int64(totalSize)) < int64(minRelayTxFee)
 }
 
+// dustThreshold returns the smallest amount a pay-to-pubkey-hash output must
+// pay to not be considered dust based on the passed minimum transaction relay
+// fee.  It is derived from the same rule as isDust, so the threshold follows
+// the fee rate rather than being fixed.
+func dustThreshold(minRelayTxFee coinutil.Amount) coinutil.Amount {
+	// A pay-to-pubkey-hash output is 34 bytes and the input which redeems
+	// it is 148 bytes.  See isDust for the breakdown.
+	const totalSize = 34 + 148
+
+	// The output is dust when value*1000/(3*totalSize) is less than the
+	// fee, so the smallest value which is not dust is the fee times
+	// 3*totalSize/1000 rounded up.
+	return coinutil.Amount((int64(minRelayTxFee)*3*totalSize + 999) / 1000)
+}
+
 // checkTransactionStandard performs a series of checks on a transaction to
 // ensure it is a "standard" transaction.  A standard transaction is one that
 // conforms to several additional limiting cases over what is considered a
@@ -329,7 +425,7 @@ func isDust(txOut *wire.TxOut, minRelayTxFee coinutil.Amount) bool {
 // finalized, conforming to more stringent size constraints, having scripts
 // of recognized forms, and not containing "dust" outputs (those that are
 // so small it costs more to process them than they are worth).
-func checkTransactionStandard(tx *coinutil.Tx, height int32, timeSource blockchain.MedianTimeSource, minRelayTxFee coinutil.Amount) error {
+func checkTransactionStandard(tx *coinutil.Tx, height int32, timeSource blockchain.MedianTimeSource, policy *standardPolicy) error {
 	// The transaction must be a currently supported version.
 	msgTx := tx.MsgTx()
 	if msgTx.Version > wire.TxVersion || msgTx.Version < 1 {
@@ -349,12 +445,12 @@ func checkTransactionStandard(tx *coinutil.Tx, height int32, timeSource blockcha
 
 	// Since extremely large transactions with a lot of inputs can cost
 	// almost as much to process as the sender fees, limit the maximum
-	// size of a transaction.  This also helps mitigate CPU exhaustion
+	// weight of a transaction.  This also helps mitigate CPU exhaustion
 	// attacks.
-	serializedLen := msgTx.SerializeSize()
-	if serializedLen > maxStandardTxSize {
-		str := fmt.Sprintf("transaction size of %v is larger than max "+
-			"allowed size of %v", serializedLen, maxStandardTxSize)
+	txWeight := blockchain.GetTransactionWeight(tx)
+	if txWeight > maxStandardTxWeight {
+		str := fmt.Sprintf("weight of transaction %v is larger than max "+
+			"allowed weight of %v", txWeight, maxStandardTxWeight)
 		return txRuleError(wire.RejectNonstandard, str)
 	}
 
@@ -384,7 +480,31 @@ func checkTransactionStandard(tx *coinutil.Tx, height int32, timeSource blockcha
 	// be "dust" (except when the script is a null data script).
 	numNullDataOutputs := 0
 	for i, txOut := range msgTx.TxOut {
+		// Null data scripts are classified using the configured data
+		// carrier size rather than the default one.
 		scriptClass := txscript.GetScriptClass(txOut.PkScript)
+		if data, ok := txscript.NullDataPayload(txOut.PkScript); ok {
+			if !policy.AcceptDataCarrier {
+				str := fmt.Sprintf("transaction output %d: "+
+					"nulldata scripts are not accepted", i)
+				return txRuleError(wire.RejectNonstandard, str)
+			}
+			if len(data) > policy.MaxDataCarrierSize {
+				str := fmt.Sprintf("transaction output %d: "+
+					"nulldata script pushes %d bytes which "+
+					"is more than the allowed max of %d", i,
+					len(data), policy.MaxDataCarrierSize)
+				return txRuleError(wire.RejectNonstandard, str)
+			}
+			scriptClass = txscript.NullDataTy
+		}
+
+		if scriptClass == txscript.MultiSigTy && !policy.AcceptBareMultisig {
+			str := fmt.Sprintf("transaction output %d: bare "+
+				"multi-signature script", i)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+
 		err := checkPkScriptStandard(txOut.PkScript, scriptClass)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
@@ -403,23 +523,44 @@ func checkTransactionStandard(tx *coinutil.Tx, height int32, timeSource blockcha
 		// "dust".
 		if scriptClass == txscript.NullDataTy {
 			numNullDataOutputs++
-		} else if isDust(txOut, minRelayTxFee) {
+		} else if isDust(txOut, policy.DustRelayFee) {
 			str := fmt.Sprintf("transaction output %d: payment "+
 				"of %d is dust", i, txOut.Value)
 			return txRuleError(wire.RejectDust, str)
 		}
 	}
 
-	// A standard transaction must not have more than one output script that
-	// only carries data.
-	if numNullDataOutputs > 1 {
-		str := "more than one transaction output in a nulldata script"
+	// A standard transaction must not have more than the configured number
+	// of output scripts that only carry data.
+	if numNullDataOutputs > policy.MaxDataCarrierOutputs {
+		str := fmt.Sprintf("transaction has %d outputs in a nulldata "+
+			"script which is more than the allowed max of %d",
+			numNullDataOutputs, policy.MaxDataCarrierOutputs)
 		return txRuleError(wire.RejectNonstandard, str)
 	}
 
 	return nil
 }
 
+// failedPolicyVerifyFlag returns the name of the first script flag which is
+// only enforced by policy that the scripts of the passed transaction fail along
+// with the resulting error.  The passed mandatory flags are the flags enforced
+// by the consensus rules, which the scripts must already pass.  An empty name
+// is returned when the scripts pass all of the flags on their own.
+func failedPolicyVerifyFlag(tx *coinutil.Tx, txStore blockchain.TxStore, mandatoryFlags txscript.ScriptFlags, sigCache *txscript.SigCache) (string, error) {
+	for _, f := range policyVerifyFlags {
+		if mandatoryFlags&f.flag == f.flag {
+			continue
+		}
+		err := blockchain.ValidateTransactionScripts(tx, txStore,
+			mandatoryFlags|f.flag, sigCache)
+		if err != nil {
+			return f.name, err
+		}
+	}
+	return "", nil
+}
+
 // minInt is a helper function to return the minimum of two ints.  This avoids
 // a math import and the need to cast to floats.
 func minInt(a, b int) int {
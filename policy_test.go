@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/hex"
 	"testing"
 
 	"github.com/conseweb/coinutil"
@@ -276,6 +277,46 @@ func TestDust(t *testing.T) {
 	}
 }
 
+// TestDustThreshold ensures the dust threshold is the smallest amount which a
+// pay-to-pubkey-hash output must pay to not be considered dust by isDust.
+func TestDustThreshold(t *testing.T) {
+	pkScript := make([]byte, 25)
+	pkScript[0] = txscript.OP_DUP
+
+	tests := []struct {
+		relayFee  coinutil.Amount
+		threshold coinutil.Amount
+	}{
+		{0, 0},
+		{1, 1},
+		{1000, 546},
+		{1001, 547},
+		{3000, 1638},
+		{100000, 54600},
+	}
+	for _, test := range tests {
+		threshold := dustThreshold(test.relayFee)
+		if threshold != test.threshold {
+			t.Errorf("dustThreshold(%d): got %d, want %d",
+				test.relayFee, threshold, test.threshold)
+			continue
+		}
+		txOut := wire.TxOut{Value: int64(threshold), PkScript: pkScript}
+		if isDust(&txOut, test.relayFee) {
+			t.Errorf("dustThreshold(%d): %d is dust", test.relayFee,
+				threshold)
+		}
+		if threshold == 0 {
+			continue
+		}
+		txOut.Value--
+		if !isDust(&txOut, test.relayFee) {
+			t.Errorf("dustThreshold(%d): %d is not dust",
+				test.relayFee, txOut.Value)
+		}
+	}
+}
+
 // TestCheckTransactionStandard tests the checkTransactionStandard API.
 func TestCheckTransactionStandard(t *testing.T) {
 	// Create some dummy, but otherwise standard, data for transactions.
@@ -304,11 +345,50 @@ func TestCheckTransactionStandard(t *testing.T) {
 		Value:    100000000, // 1 BTC
 		PkScript: dummyPkScript,
 	}
+	bigNullDataScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_RETURN).
+		AddData(bytes.Repeat([]byte{0x01}, txscript.MaxDataCarrierSize+1)).
+		Script()
+	if err != nil {
+		t.Fatalf("NewScriptBuilder: unexpected error: %v", err)
+	}
+	pubKey, err := hex.DecodeString("0232abdc893e7f0631364d7fd01cb33d24da4" +
+		"5329a00357b3a7886211ab414d55a")
+	if err != nil {
+		t.Fatalf("DecodeString: unexpected error: %v", err)
+	}
+	bareMultiSigScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_1).AddData(pubKey).AddOp(txscript.OP_1).
+		AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		t.Fatalf("NewScriptBuilder: unexpected error: %v", err)
+	}
+
+	// The default policy mirrors the default configuration options.
+	defaultPolicy := standardPolicy{
+		AcceptDataCarrier:     true,
+		MaxDataCarrierSize:    txscript.MaxDataCarrierSize,
+		MaxDataCarrierOutputs: defaultMaxDataCarrierOutputs,
+		AcceptBareMultisig:    true,
+		DustRelayFee:          defaultMinRelayTxFee,
+		MaxSigOpsPerTx:        defaultMaxSigOpsPerTx,
+	}
+	noDataCarrierPolicy := defaultPolicy
+	noDataCarrierPolicy.AcceptDataCarrier = false
+	largeDataPolicy := defaultPolicy
+	largeDataPolicy.MaxDataCarrierSize = txscript.MaxDataCarrierSize + 1
+	multiDataPolicy := defaultPolicy
+	multiDataPolicy.MaxDataCarrierOutputs = 2
+	noBareMultiSigPolicy := defaultPolicy
+	noBareMultiSigPolicy.AcceptBareMultisig = false
+	noDustPolicy := defaultPolicy
+	noDustPolicy.DustRelayFee = 0
 
 	tests := []struct {
 		name       string
 		tx         wire.MsgTx
 		height     int32
+		policy     *standardPolicy // nil for defaultPolicy
 		isStandard bool
 		code       wire.RejectCode
 	}{
@@ -434,6 +514,24 @@ func TestCheckTransactionStandard(t *testing.T) {
 			isStandard: false,
 			code:       wire.RejectNonstandard,
 		},
+		{
+			name: "Two nulldata outputs within a larger data carrier count",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    0,
+					PkScript: []byte{txscript.OP_RETURN},
+				}, {
+					Value:    0,
+					PkScript: []byte{txscript.OP_RETURN},
+				}},
+				LockTime: 0,
+			},
+			height:     300000,
+			policy:     &multiDataPolicy,
+			isStandard: true,
+		},
 		{
 			name: "Dust output",
 			tx: wire.MsgTx{
@@ -463,13 +561,108 @@ func TestCheckTransactionStandard(t *testing.T) {
 			height:     300000,
 			isStandard: true,
 		},
+		{
+			name: "Nulldata output when data carriers are not accepted",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    0,
+					PkScript: []byte{txscript.OP_RETURN},
+				}},
+				LockTime: 0,
+			},
+			height:     300000,
+			policy:     &noDataCarrierPolicy,
+			isStandard: false,
+			code:       wire.RejectNonstandard,
+		},
+		{
+			name: "Nulldata output larger than the data carrier size",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    0,
+					PkScript: bigNullDataScript,
+				}},
+				LockTime: 0,
+			},
+			height:     300000,
+			isStandard: false,
+			code:       wire.RejectNonstandard,
+		},
+		{
+			name: "Nulldata output within a larger data carrier size",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    0,
+					PkScript: bigNullDataScript,
+				}},
+				LockTime: 0,
+			},
+			height:     300000,
+			policy:     &largeDataPolicy,
+			isStandard: true,
+		},
+		{
+			name: "Bare multisig output (standard)",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    100000000,
+					PkScript: bareMultiSigScript,
+				}},
+				LockTime: 0,
+			},
+			height:     300000,
+			isStandard: true,
+		},
+		{
+			name: "Bare multisig output when rejected by policy",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    100000000,
+					PkScript: bareMultiSigScript,
+				}},
+				LockTime: 0,
+			},
+			height:     300000,
+			policy:     &noBareMultiSigPolicy,
+			isStandard: false,
+			code:       wire.RejectNonstandard,
+		},
+		{
+			name: "Zero value output with no dust relay fee",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    0,
+					PkScript: dummyPkScript,
+				}},
+				LockTime: 0,
+			},
+			height:     300000,
+			policy:     &noDustPolicy,
+			isStandard: true,
+		},
 	}
 
 	timeSource := blockchain.NewMedianTime()
 	for _, test := range tests {
 		// Ensure standardness is as expected.
+		policy := test.policy
+		if policy == nil {
+			policy = &defaultPolicy
+		}
 		err := checkTransactionStandard(coinutil.NewTx(&test.tx),
-			test.height, timeSource, defaultMinRelayTxFee)
+			test.height, timeSource, policy)
 		if err == nil && test.isStandard {
 			// Test passes since function returned standard for a
 			// transaction which is intended to be standard.
@@ -509,3 +702,85 @@ func TestCheckTransactionStandard(t *testing.T) {
 		}
 	}
 }
+
+// TestFailedPolicyVerifyFlag ensures the script flag which is only enforced by
+// policy that a transaction fails is identified.
+func TestFailedPolicyVerifyFlag(t *testing.T) {
+	// The spent output drops the item pushed by the signature script, so
+	// any number of pushes satisfy it.
+	prevTx := wire.NewMsgTx()
+	prevTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&wire.ShaHash{},
+			wire.MaxPrevOutIndex),
+		SignatureScript: []byte{txscript.OP_1, txscript.OP_1},
+		Sequence:        wire.MaxTxInSequenceNum,
+	})
+	prevTx.AddTxOut(wire.NewTxOut(100000000, []byte{txscript.OP_DROP,
+		txscript.OP_TRUE}))
+	prev := coinutil.NewTx(prevTx)
+	txStore := blockchain.TxStore{
+		*prev.Sha(): &blockchain.TxData{
+			Tx:          prev,
+			Hash:        prev.Sha(),
+			BlockHeight: 1,
+			Spent:       make([]bool, 1),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		sigScript []byte
+		mandatory txscript.ScriptFlags
+		flag      string
+	}{
+		{
+			name:      "standard",
+			sigScript: []byte{txscript.OP_5},
+			mandatory: mandatoryVerifyFlags,
+			flag:      "",
+		},
+		{
+			name:      "not push only",
+			sigScript: []byte{txscript.OP_5, txscript.OP_NOP},
+			mandatory: mandatoryVerifyFlags,
+			flag:      "SIGPUSHONLY",
+		},
+		{
+			name:      "non-minimal push",
+			sigScript: []byte{txscript.OP_DATA_1, 0x05},
+			mandatory: mandatoryVerifyFlags,
+			flag:      "MINIMALDATA",
+		},
+		{
+			name:      "unclean stack",
+			sigScript: []byte{txscript.OP_5, txscript.OP_5},
+			mandatory: mandatoryVerifyFlags,
+			flag:      "CLEANSTACK",
+		},
+		{
+			name:      "non-minimal push with mandatory push only",
+			sigScript: []byte{txscript.OP_DATA_1, 0x05},
+			mandatory: mandatoryVerifyFlags |
+				txscript.ScriptVerifySigPushOnly,
+			flag: "MINIMALDATA",
+		},
+	}
+
+	for _, test := range tests {
+		tx := wire.NewMsgTx()
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prev.Sha(), 0),
+			test.sigScript))
+		tx.AddTxOut(wire.NewTxOut(90000000, []byte{txscript.OP_TRUE}))
+
+		flag, err := failedPolicyVerifyFlag(coinutil.NewTx(tx), txStore,
+			test.mandatory, nil)
+		if flag != test.flag {
+			t.Errorf("%s: got flag %q (%v), want %q", test.name, flag,
+				err, test.flag)
+		}
+		if (flag == "") != (err == nil) {
+			t.Errorf("%s: got flag %q with error %v", test.name, flag,
+				err)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"github.com/conseweb/stcd/btcjson"
+)
+
+// rawBlockSubscription houses the state of a websocket client subscribed to
+// the raw blocks of the main chain with the subscriberawblocks command.
+type rawBlockSubscription struct {
+	wsc    *wsClient
+	binary bool
+
+	// nextHeight is the height of the next main chain block to send to the
+	// client and disconnects counts the blocks disconnected from the main
+	// chain since the client subscribed.  They are protected by the
+	// notifier lock.
+	nextHeight  int32
+	disconnects uint64
+
+	// trigger is signalled when blocks are connected to the main chain.
+	// It is buffered so signalling never blocks.  quit is closed when the
+	// subscription is cancelled.
+	trigger chan struct{}
+	quit    chan struct{}
+}
+
+// rawBlockNotifier streams the serialized blocks of the main chain to the
+// websocket clients subscribed with the subscriberawblocks command, so
+// archival mirrors can stay in sync without polling getblock.
+//
+// Each subscription is served by its own goroutine which sends blocks one at
+// a time and waits for each of them to be written, so a slow client only
+// holds up its own stream and a backfill never queues more than one block.
+type rawBlockNotifier struct {
+	sync.Mutex
+	server        *rpcServer
+	subscriptions map[chan struct{}]*rawBlockSubscription
+}
+
+// newRawBlockNotifier returns a new raw block notifier for the passed RPC
+// server.
+func newRawBlockNotifier(server *rpcServer) *rawBlockNotifier {
+	return &rawBlockNotifier{
+		server:        server,
+		subscriptions: make(map[chan struct{}]*rawBlockSubscription),
+	}
+}
+
+// Subscribe registers the passed websocket client for raw block notifications
+// starting with the main chain block at the passed height, replacing any
+// earlier registration of the client.  Blocks up to the current best block are
+// sent right away.
+//
+// This function is safe for concurrent access.
+func (n *rawBlockNotifier) Subscribe(wsc *wsClient, binary bool, fromHeight int32) {
+	sub := &rawBlockSubscription{
+		wsc:        wsc,
+		binary:     binary,
+		nextHeight: fromHeight,
+		trigger:    make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+	}
+
+	n.Lock()
+	if old, ok := n.subscriptions[wsc.quit]; ok {
+		close(old.quit)
+	}
+	n.subscriptions[wsc.quit] = sub
+	n.Unlock()
+
+	wsc.wg.Add(1)
+	go n.stream(sub)
+}
+
+// Unsubscribe removes the raw block notification registration of the passed
+// websocket client, if any.
+//
+// This function is safe for concurrent access.
+func (n *rawBlockNotifier) Unsubscribe(wsc *wsClient) {
+	n.Lock()
+	if sub, ok := n.subscriptions[wsc.quit]; ok {
+		close(sub.quit)
+		delete(n.subscriptions, wsc.quit)
+	}
+	n.Unlock()
+}
+
+// Subscribed returns whether the passed websocket client is registered for raw
+// block notifications.
+//
+// This function is safe for concurrent access.
+func (n *rawBlockNotifier) Subscribed(wsc *wsClient) bool {
+	n.Lock()
+	_, ok := n.subscriptions[wsc.quit]
+	n.Unlock()
+	return ok
+}
+
+// NotifyBlockConnected informs the notifier that a block has been connected to
+// the main chain so it is sent to all registered clients.
+//
+// This function is safe for concurrent access.
+func (n *rawBlockNotifier) NotifyBlockConnected() {
+	n.Lock()
+	for _, sub := range n.subscriptions {
+		select {
+		case sub.trigger <- struct{}{}:
+		default:
+		}
+	}
+	n.Unlock()
+}
+
+// NotifyBlockDisconnected informs the notifier that the block at the passed
+// height has been disconnected from the main chain.  Clients which were
+// already sent the block are sent the block replacing it once it is connected.
+//
+// This function is safe for concurrent access.
+func (n *rawBlockNotifier) NotifyBlockDisconnected(height int32) {
+	n.Lock()
+	for _, sub := range n.subscriptions {
+		if sub.nextHeight > height {
+			sub.nextHeight = height
+		}
+		sub.disconnects++
+	}
+	n.Unlock()
+}
+
+// next returns the height of the next block to send to the client of the
+// passed subscription along with the number of blocks disconnected so far.
+//
+// This function is safe for concurrent access.
+func (n *rawBlockNotifier) next(sub *rawBlockSubscription) (int32, uint64) {
+	n.Lock()
+	defer n.Unlock()
+	return sub.nextHeight, sub.disconnects
+}
+
+// sent records that the block at the passed height was sent to the client of
+// the passed subscription, unless a block was disconnected since next returned
+// the passed number of disconnected blocks.  The block may then no longer be
+// part of the main chain, so the height is left to the disconnect.
+//
+// This function is safe for concurrent access.
+func (n *rawBlockNotifier) sent(sub *rawBlockSubscription, height int32, disconnects uint64) {
+	n.Lock()
+	if sub.disconnects == disconnects && sub.nextHeight == height {
+		sub.nextHeight = height + 1
+	}
+	n.Unlock()
+}
+
+// send sends the main chain block at the passed height to the client of the
+// passed subscription and blocks until it has been written.
+func (n *rawBlockNotifier) send(sub *rawBlockSubscription, height int32) error {
+	db := n.server.server.db
+	sha, err := db.FetchBlockShaByHeight(height)
+	if err != nil {
+		return err
+	}
+	block, err := db.FetchBlockBySha(sha)
+	if err != nil {
+		return err
+	}
+	serialized, err := block.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if sub.binary {
+		return sub.wsc.SendFrame(serialized, true)
+	}
+	ntfn := btcjson.NewRawBlockNtfn(height, hex.EncodeToString(serialized))
+	marshalledJSON, err := marshalNotification(ntfn, newNtfnEventID())
+	if err != nil {
+		return err
+	}
+	return sub.wsc.SendFrame(marshalledJSON, false)
+}
+
+// stream sends the main chain blocks to the client of the passed subscription
+// as they are connected until the subscription is cancelled or the client
+// disconnects.  It must be run as a goroutine.
+func (n *rawBlockNotifier) stream(sub *rawBlockSubscription) {
+	defer func() {
+		n.Lock()
+		if n.subscriptions[sub.wsc.quit] == sub {
+			delete(n.subscriptions, sub.wsc.quit)
+		}
+		n.Unlock()
+		sub.wsc.wg.Done()
+	}()
+
+	db := n.server.server.db
+	for {
+		// Send the blocks the client is missing up to the best block.
+		// A block which is disconnected while it is sent is sent again
+		// after the block replacing it is connected.
+		for {
+			select {
+			case <-sub.quit:
+				return
+			default:
+			}
+
+			_, bestHeight, err := db.NewestSha()
+			if err != nil {
+				rpcsLog.Errorf("Unable to stream raw blocks to %s: "+
+					"%v", sub.wsc.addr, err)
+				return
+			}
+			height, disconnects := n.next(sub)
+			if height > bestHeight {
+				break
+			}
+			err = n.send(sub, height)
+			if err == ErrClientQuit {
+				return
+			}
+			if err != nil {
+				// Try again when the block was disconnected
+				// while it was fetched.
+				_, bestHeight, _ = db.NewestSha()
+				if height <= bestHeight {
+					rpcsLog.Errorf("Unable to send block %d "+
+						"to %s: %v", height,
+						sub.wsc.addr, err)
+					return
+				}
+				continue
+			}
+			n.sent(sub, height, disconnects)
+		}
+
+		select {
+		case <-sub.trigger:
+		case <-sub.quit:
+			return
+		case <-sub.wsc.quit:
+			return
+		}
+	}
+}
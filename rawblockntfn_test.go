@@ -0,0 +1,88 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+// TestRawBlockNotifierDisconnect ensures a raw block subscription moves past
+// the blocks sent to the client and rewinds to the height of disconnected
+// blocks, including blocks disconnected while they were being sent.
+func TestRawBlockNotifierDisconnect(t *testing.T) {
+	n := newRawBlockNotifier(nil)
+	wsc := &wsClient{quit: make(chan struct{})}
+	sub := &rawBlockSubscription{
+		wsc:        wsc,
+		nextHeight: 10,
+		trigger:    make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+	}
+	n.subscriptions[wsc.quit] = sub
+
+	height, disconnects := n.next(sub)
+	n.sent(sub, height, disconnects)
+	if height, _ := n.next(sub); height != 11 {
+		t.Fatalf("next after sending: got %d - want 11", height)
+	}
+
+	// Disconnecting a block which was already sent rewinds to it.
+	n.NotifyBlockDisconnected(10)
+	if height, _ := n.next(sub); height != 10 {
+		t.Fatalf("next after disconnect: got %d - want 10", height)
+	}
+
+	// A block disconnected while it is being sent is sent again.
+	height, disconnects = n.next(sub)
+	n.NotifyBlockDisconnected(10)
+	n.sent(sub, height, disconnects)
+	if height, _ := n.next(sub); height != 10 {
+		t.Fatalf("next after disconnect while sending: got %d - want 10",
+			height)
+	}
+
+	// Disconnecting blocks which weren't sent yet leaves the height alone.
+	n.NotifyBlockDisconnected(20)
+	if height, _ := n.next(sub); height != 10 {
+		t.Fatalf("next after disconnecting an unsent block: got %d - "+
+			"want 10", height)
+	}
+
+	// Signalling never blocks, even when nobody drains the trigger.
+	n.NotifyBlockConnected()
+	n.NotifyBlockConnected()
+
+	n.Unsubscribe(wsc)
+	select {
+	case <-sub.quit:
+	default:
+		t.Fatalf("unsubscribing did not cancel the subscription")
+	}
+	if len(n.subscriptions) != 0 {
+		t.Fatalf("subscription was not removed")
+	}
+}
+
+// TestSendFrame ensures frames are passed to the output handler as binary or
+// text messages and that sending fails once the client disconnects.
+func TestSendFrame(t *testing.T) {
+	wsc := &wsClient{
+		sendChan: make(chan wsResponse),
+		quit:     make(chan struct{}),
+	}
+	go func() {
+		r := <-wsc.sendChan
+		r.doneChan <- r.binary && string(r.msg) == "block"
+	}()
+	if err := wsc.SendFrame([]byte("block"), true); err != nil {
+		t.Fatalf("SendFrame: %v", err)
+	}
+
+	close(wsc.quit)
+	if err := wsc.SendFrame([]byte("block"), true); err != ErrClientQuit {
+		t.Fatalf("SendFrame after disconnect: got %v - want %v", err,
+			ErrClientQuit)
+	}
+}
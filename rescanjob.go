@@ -0,0 +1,197 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+const (
+	// maxRescanJobs is the maximum number of rescan jobs a websocket client
+	// may run concurrently.
+	maxRescanJobs = 4
+
+	// maxKeptRescanJobs is the maximum number of rescan jobs, including
+	// ones which are no longer running, remembered for a websocket client
+	// so their status can still be queried.  The oldest jobs which are no
+	// longer running are forgotten first.
+	maxKeptRescanJobs = 16
+)
+
+// These constants define the states of a rescan job as reported by the
+// getrescanstatus command.
+const (
+	rescanJobRunning  = "running"
+	rescanJobFinished = "finished"
+	rescanJobCanceled = "canceled"
+	rescanJobFailed   = "failed"
+)
+
+// rescanJob is a rescan started with the startrescan command, which runs in
+// the background instead of blocking the request until it completes.
+type rescanJob struct {
+	mu sync.Mutex
+
+	id          string
+	beginHeight int32
+	endHeight   int32
+
+	// ctx is canceled with cancel when the job is canceled with the
+	// cancelrescan command or the client disconnects.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// state, lastHeight, lastHash and err describe the progress of the
+	// job.  They are protected by mu.
+	state      string
+	lastHeight int32
+	lastHash   *wire.ShaHash
+	err        error
+}
+
+// progress records the passed block as the last one rescanned by the job.
+func (j *rescanJob) progress(blk *coinutil.Block) {
+	j.mu.Lock()
+	j.lastHeight = blk.Height()
+	j.lastHash = blk.Sha()
+	j.mu.Unlock()
+}
+
+// finish records the outcome of the job given the error the rescan returned.
+func (j *rescanJob) finish(err error) {
+	j.mu.Lock()
+	switch {
+	case err == nil:
+		j.state = rescanJobFinished
+	case err == errRescanClientQuit:
+		j.state = rescanJobCanceled
+	default:
+		j.state = rescanJobFailed
+		j.err = err
+	}
+	j.mu.Unlock()
+
+	// Release the resources of the context now that the job is done.
+	j.cancel()
+}
+
+// running returns whether the job has not finished yet.
+func (j *rescanJob) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.state == rescanJobRunning
+}
+
+// status returns the progress of the job as reported by the getrescanstatus
+// command.
+func (j *rescanJob) status() *btcjson.GetRescanStatusResult {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	result := &btcjson.GetRescanStatusResult{
+		ID:          j.id,
+		State:       j.state,
+		BeginHeight: j.beginHeight,
+		EndHeight:   j.endHeight,
+		Height:      j.lastHeight,
+	}
+	if j.endHeight == database.AllShas {
+		result.EndHeight = -1
+	}
+	if j.lastHash != nil {
+		result.Hash = j.lastHash.String()
+	}
+	if rpcErr, ok := j.err.(*btcjson.RPCError); ok {
+		result.Error = rpcErr.Message
+	} else if j.err != nil {
+		result.Error = j.err.Error()
+	}
+	return result
+}
+
+// rescanJobs holds the rescan jobs started by a websocket client.
+type rescanJobs struct {
+	sync.Mutex
+	nextID uint64
+	jobs   map[string]*rescanJob
+
+	// order holds the ids of the jobs from the oldest to the newest.
+	order []string
+}
+
+// newRescanJobs returns a new empty set of rescan jobs.
+func newRescanJobs() *rescanJobs {
+	return &rescanJobs{jobs: make(map[string]*rescanJob)}
+}
+
+// add creates a new running job for a rescan of the passed height range whose
+// context is derived from the passed one.  An ErrRPCRateLimited error is
+// returned when the maximum number of jobs are running already.
+//
+// This function is safe for concurrent access.
+func (r *rescanJobs) add(ctx context.Context, beginHeight, endHeight int32) (*rescanJob, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	running := 0
+	for _, job := range r.jobs {
+		if job.running() {
+			running++
+		}
+	}
+	if running >= maxRescanJobs {
+		return nil, rateLimitedError("Too many rescan jobs are "+
+			"running (max %d) -- try again later", maxRescanJobs)
+	}
+
+	// Forget the oldest jobs which are done to make room for the new one.
+	for i := 0; i < len(r.order) && len(r.order) >= maxKeptRescanJobs; {
+		id := r.order[i]
+		if r.jobs[id].running() {
+			i++
+			continue
+		}
+		delete(r.jobs, id)
+		r.order = append(r.order[:i], r.order[i+1:]...)
+	}
+
+	r.nextID++
+	job := &rescanJob{
+		id:          fmt.Sprintf("rescan-%d", r.nextID),
+		beginHeight: beginHeight,
+		endHeight:   endHeight,
+		state:       rescanJobRunning,
+		lastHeight:  -1,
+	}
+	job.ctx, job.cancel = context.WithCancel(ctx)
+	r.jobs[job.id] = job
+	r.order = append(r.order, job.id)
+	return job, nil
+}
+
+// lookup returns the job with the passed id, or an RPC error if no such job
+// is remembered.
+//
+// This function is safe for concurrent access.
+func (r *rescanJobs) lookup(id string) (*rescanJob, error) {
+	r.Lock()
+	job, ok := r.jobs[id]
+	r.Unlock()
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "No rescan job with id " + id,
+		}
+	}
+	return job, nil
+}
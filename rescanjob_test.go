@@ -0,0 +1,128 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/database"
+)
+
+// TestRescanJobs ensures the number of running rescan jobs of a client is
+// limited and the oldest jobs which are no longer running are forgotten to
+// make room for new ones.
+func TestRescanJobs(t *testing.T) {
+	t.Parallel()
+
+	jobs := newRescanJobs()
+	var running []*rescanJob
+	for i := 0; i < maxRescanJobs; i++ {
+		job, err := jobs.add(context.Background(), 0, database.AllShas)
+		if err != nil {
+			t.Fatalf("add #%d: unexpected error: %v", i, err)
+		}
+		running = append(running, job)
+	}
+	_, err := jobs.add(context.Background(), 0, database.AllShas)
+	if rpcErr, ok := err.(*btcjson.RPCError); !ok ||
+		rpcErr.Code != btcjson.ErrRPCRateLimited {
+
+		t.Fatalf("add over the limit: got %v - want rate limited", err)
+	}
+
+	// Finishing a job makes room for another one.
+	first := running[0]
+	first.finish(nil)
+	job, err := jobs.add(context.Background(), 0, 10)
+	if err != nil {
+		t.Fatalf("add after finish: unexpected error: %v", err)
+	}
+	job.finish(nil)
+	if job, err := jobs.lookup(first.id); err != nil || job != first {
+		t.Fatalf("lookup: got %v (%v) - want finished job", job, err)
+	}
+
+	// Fill the jobs with finished ones and ensure the oldest are
+	// forgotten while the running ones are kept.
+	for i := 0; i < maxKeptRescanJobs; i++ {
+		job, err := jobs.add(context.Background(), 0, 10)
+		if err != nil {
+			t.Fatalf("add finished #%d: unexpected error: %v", i, err)
+		}
+		job.finish(nil)
+	}
+	if _, err := jobs.lookup(first.id); err == nil {
+		t.Fatalf("lookup: oldest finished job %s not forgotten",
+			first.id)
+	}
+	for _, job := range running[1:] {
+		if _, err := jobs.lookup(job.id); err != nil {
+			t.Fatalf("lookup: running job %s forgotten", job.id)
+		}
+	}
+	if len(jobs.jobs) != maxKeptRescanJobs || len(jobs.order) != maxKeptRescanJobs {
+		t.Fatalf("kept %d jobs (%d ordered) - want %d", len(jobs.jobs),
+			len(jobs.order), maxKeptRescanJobs)
+	}
+}
+
+// TestRescanJobStatus ensures the status of rescan jobs reflects their
+// progress and outcome, and that canceling the parent context cancels them.
+func TestRescanJobStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := newRescanJobs()
+	job, err := jobs.add(ctx, 5, database.AllShas)
+	if err != nil {
+		t.Fatalf("add: unexpected error: %v", err)
+	}
+	status := job.status()
+	if status.State != rescanJobRunning || status.BeginHeight != 5 ||
+		status.EndHeight != -1 || status.Height != -1 ||
+		status.Hash != "" {
+
+		t.Fatalf("status: got %+v for new job", status)
+	}
+
+	// Canceling the parent context, as happens when the client
+	// disconnects, cancels the job.
+	cancel()
+	select {
+	case <-job.ctx.Done():
+	default:
+		t.Fatalf("job context not canceled with its parent")
+	}
+	job.finish(errRescanClientQuit)
+	if status := job.status(); status.State != rescanJobCanceled {
+		t.Fatalf("status: got state %q - want %q", status.State,
+			rescanJobCanceled)
+	}
+
+	// Failed jobs report the reason.
+	job, err = jobs.add(context.Background(), 0, 10)
+	if err != nil {
+		t.Fatalf("add: unexpected error: %v", err)
+	}
+	job.finish(&ErrRescanReorg)
+	status = job.status()
+	if status.State != rescanJobFailed || status.EndHeight != 10 ||
+		status.Error != ErrRescanReorg.Message {
+
+		t.Fatalf("status: got %+v for failed job", status)
+	}
+	job, err = jobs.add(context.Background(), 0, 10)
+	if err != nil {
+		t.Fatalf("add: unexpected error: %v", err)
+	}
+	job.finish(errors.New("database closed"))
+	if status := job.status(); status.Error != "database closed" {
+		t.Fatalf("status: got error %q - want %q", status.Error,
+			"database closed")
+	}
+}
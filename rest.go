@@ -0,0 +1,327 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/wire"
+)
+
+// restPathPrefix is the path below which REST requests are served.
+const restPathPrefix = "/rest/"
+
+// restMaxHeaders is the maximum number of headers returned by a single
+// headers request.
+const restMaxHeaders = 2000
+
+// restFormat identifies the representation in which a REST resource is
+// returned.  It is selected by the extension of the requested path.
+type restFormat int
+
+// These constants define the representations of REST resources.
+const (
+	restFormatBinary restFormat = iota
+	restFormatHex
+	restFormatJSON
+)
+
+// restFormats maps path extensions to the representation they select.
+var restFormats = map[string]restFormat{
+	"bin":  restFormatBinary,
+	"hex":  restFormatHex,
+	"json": restFormatJSON,
+}
+
+// errRESTNotFound indicates a REST request for a path which does not identify
+// a resource or for a representation the resource is not available in.
+var errRESTNotFound = errors.New("resource not found")
+
+// parseRESTPath splits the passed path, which is relative to restPathPrefix,
+// into its elements and returns them along with the requested format, which
+// is given as the extension of the final element.
+func parseRESTPath(path string) ([]string, restFormat, error) {
+	elems := strings.Split(path, "/")
+	last := elems[len(elems)-1]
+	dot := strings.LastIndex(last, ".")
+	if dot == -1 {
+		return nil, 0, errors.New("no output format specified " +
+			"(available: bin, hex, json)")
+	}
+	format, ok := restFormats[last[dot+1:]]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown output format %q "+
+			"(available: bin, hex, json)", last[dot+1:])
+	}
+	elems[len(elems)-1] = last[:dot]
+	for _, elem := range elems {
+		if elem == "" {
+			return nil, 0, errRESTNotFound
+		}
+	}
+	return elems, format, nil
+}
+
+// parseRESTHash decodes the passed hash of a REST path.  Unlike
+// wire.NewShaHashFromStr, it only accepts complete hashes so that paths remain
+// canonical.
+func parseRESTHash(hashStr string) (*wire.ShaHash, error) {
+	if len(hashStr) != wire.MaxHashStringSize {
+		return nil, fmt.Errorf("invalid hash %q", hashStr)
+	}
+	hash, err := wire.NewShaHashFromStr(hashStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash %q", hashStr)
+	}
+	return hash, nil
+}
+
+// restErrorStatus returns the HTTP status code for an error which occurred
+// while serving a REST request.
+func restErrorStatus(err error) int {
+	if err == errRESTNotFound {
+		return http.StatusNotFound
+	}
+	if rpcErr, ok := err.(*btcjson.RPCError); ok {
+		// Missing blocks and transactions are reported by the RPC
+		// handlers with the same code as invalid addresses or keys.
+		if rpcErr.Code == btcjson.ErrRPCBlockNotFound {
+			return http.StatusNotFound
+		}
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// restTx returns the transaction with the passed hash from the memory pool or
+// the block database.
+func (s *rpcServer) restTx(ctx context.Context, hashStr string, format restFormat) (interface{}, error) {
+	if _, err := parseRESTHash(hashStr); err != nil {
+		return nil, err
+	}
+	verbose := 0
+	if format == restFormatJSON {
+		verbose = 1
+	}
+	c := &btcjson.GetRawTransactionCmd{Txid: hashStr, Verbose: &verbose}
+	return handleGetRawTransaction(s, c, ctx)
+}
+
+// restBlock returns the block with the passed hash.  The JSON representation
+// only includes the details of the transactions when txDetails is set.
+func (s *rpcServer) restBlock(ctx context.Context, hashStr string, txDetails bool, format restFormat) (interface{}, error) {
+	if _, err := parseRESTHash(hashStr); err != nil {
+		return nil, err
+	}
+	verbose := format == restFormatJSON
+	c := &btcjson.GetBlockCmd{
+		Hash:      hashStr,
+		Verbose:   &verbose,
+		VerboseTx: &txDetails,
+	}
+	return handleGetBlock(s, c, ctx)
+}
+
+// restHeaders returns up to count headers of the main chain starting with the
+// header of the block with the passed hash.
+func (s *rpcServer) restHeaders(ctx context.Context, countStr, hashStr string, format restFormat) (interface{}, error) {
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count < 1 || count > restMaxHeaders {
+		return nil, fmt.Errorf("header count must be between 1 and %d "+
+			"(not %q)", restMaxHeaders, countStr)
+	}
+	hash, err := parseRESTHash(hashStr)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.server.db
+	height, err := db.FetchBlockHeightBySha(hash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+	hashes := make([]*wire.ShaHash, 0, count)
+	for i := 0; i < count; i++ {
+		hash, err := db.FetchBlockShaByHeight(height + int32(i))
+		if err != nil {
+			break
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if format == restFormatJSON {
+		headers := make([]interface{}, 0, len(hashes))
+		for _, hash := range hashes {
+			c := &btcjson.GetBlockHeaderCmd{Hash: hash.String()}
+			header, err := handleGetBlockHeader(s, c, ctx)
+			if err != nil {
+				return nil, err
+			}
+			headers = append(headers, header)
+		}
+		return headers, nil
+	}
+
+	var buf bytes.Buffer
+	for _, hash := range hashes {
+		header, err := db.FetchBlockHeaderBySha(hash)
+		if err != nil {
+			context := "Failed to get block header"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		if err := header.Serialize(&buf); err != nil {
+			context := "Failed to serialize block header"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// restResource dispatches a REST request to the function serving the
+// requested resource.  The returned value is either the hex encoded
+// serialization of the resource or, for the JSON format, a value which is
+// marshalled as the response.
+func (s *rpcServer) restResource(ctx context.Context, elems []string, format restFormat) (interface{}, error) {
+	switch {
+	case len(elems) == 2 && elems[0] == "tx":
+		return s.restTx(ctx, elems[1], format)
+
+	case len(elems) == 2 && elems[0] == "block":
+		return s.restBlock(ctx, elems[1], true, format)
+
+	case len(elems) == 3 && elems[0] == "block" && elems[1] == "notxdetails":
+		return s.restBlock(ctx, elems[2], false, format)
+
+	case len(elems) == 3 && elems[0] == "headers":
+		return s.restHeaders(ctx, elems[1], elems[2], format)
+	}
+
+	// The remaining resources are only available as JSON.
+	if format != restFormatJSON {
+		return nil, errRESTNotFound
+	}
+	switch {
+	case len(elems) == 1 && elems[0] == "chaininfo":
+		return handleGetBlockChainInfo(s, nil, ctx)
+
+	case len(elems) == 2 && elems[0] == "mempool" && elems[1] == "info":
+		return handleGetMempoolInfo(s, nil, ctx)
+
+	case len(elems) == 2 && elems[0] == "mempool" && elems[1] == "contents":
+		verbose := true
+		c := &btcjson.GetRawMempoolCmd{Verbose: &verbose}
+		return handleGetRawMempool(s, c, ctx)
+	}
+	return nil, errRESTNotFound
+}
+
+// restHandler returns the handler of REST requests on the regular or read-only
+// listeners.
+func (s *rpcServer) restHandler(readOnly bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.handleREST(w, r, readOnly)
+	}
+}
+
+// handleREST serves unauthenticated, bitcoind compatible REST requests.  The
+// final element of every path carries an extension which selects whether the
+// resource is returned in binary, hex or JSON form:
+//
+//	/rest/tx/<hash>.<bin|hex|json>
+//	/rest/block/<hash>.<bin|hex|json>
+//	/rest/block/notxdetails/<hash>.<bin|hex|json>
+//	/rest/headers/<count>/<hash>.<bin|hex|json>
+//	/rest/chaininfo.json
+//	/rest/mempool/info.json
+//	/rest/mempool/contents.json
+//
+// The readOnly flag selects whether the request counts against the client
+// limit of the read-only or the regular listeners.
+func (s *rpcServer) handleREST(w http.ResponseWriter, r *http.Request,
+	readOnly bool) {
+
+	if r.Method != "GET" {
+		http.Error(w, "405 Method not allowed.",
+			http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Limit the number of connections to max allowed.
+	if s.limitConnections(w, r.RemoteAddr, readOnly) {
+		return
+	}
+
+	// Keep track of the number of connected clients and in-flight
+	// requests.
+	if !s.beginRequest(w) {
+		return
+	}
+	defer s.endRequest()
+	s.incrementClients(readOnly)
+	defer s.decrementClients(readOnly)
+
+	path := strings.TrimPrefix(r.URL.Path, restPathPrefix)
+	elems, format, err := parseRESTPath(path)
+	if err == nil {
+		var result interface{}
+		result, err = s.restResource(r.Context(), elems, format)
+		if err == nil {
+			s.writeRESTResult(w, result, format)
+			return
+		}
+	}
+	http.Error(w, err.Error(), restErrorStatus(err))
+}
+
+// writeRESTResult writes the result of a REST request in the requested format.
+func (s *rpcServer) writeRESTResult(w http.ResponseWriter, result interface{}, format restFormat) {
+	var contentType string
+	var body []byte
+	switch format {
+	case restFormatJSON:
+		marshalled, err := json.Marshal(result)
+		if err != nil {
+			rpcsLog.Errorf("Failed to marshal REST result: %v", err)
+			http.Error(w, "500 Internal server error.",
+				http.StatusInternalServerError)
+			return
+		}
+		contentType = "application/json"
+		body = append(marshalled, '\n')
+
+	case restFormatHex:
+		contentType = "text/plain"
+		body = []byte(result.(string) + "\n")
+
+	case restFormatBinary:
+		serialized, err := hex.DecodeString(result.(string))
+		if err != nil {
+			rpcsLog.Errorf("Failed to decode REST result: %v", err)
+			http.Error(w, "500 Internal server error.",
+				http.StatusInternalServerError)
+			return
+		}
+		contentType = "application/octet-stream"
+		body = serialized
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if _, err := w.Write(body); err != nil {
+		rpcsLog.Errorf("Failed to write REST response: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/stcd/btcjson"
+)
+
+// TestParseRESTPath ensures REST paths are split into their elements and the
+// requested format.
+func TestParseRESTPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path   string
+		elems  []string
+		format restFormat
+		status int
+	}{
+		{path: "chaininfo.json", elems: []string{"chaininfo"},
+			format: restFormatJSON},
+		{path: "tx/abc.hex", elems: []string{"tx", "abc"},
+			format: restFormatHex},
+		{path: "headers/5/abc.bin", elems: []string{"headers", "5", "abc"},
+			format: restFormatBinary},
+		{path: "block/abc.tar.json", elems: []string{"block", "abc.tar"},
+			format: restFormatJSON},
+		{path: "tx/abc", status: http.StatusBadRequest},
+		{path: "tx/abc.xml", status: http.StatusBadRequest},
+		{path: "tx//abc.json", status: http.StatusNotFound},
+		{path: ".json", status: http.StatusNotFound},
+	}
+
+	for i, test := range tests {
+		elems, format, err := parseRESTPath(test.path)
+		if test.status != 0 {
+			if err == nil {
+				t.Errorf("#%d (%s): expected error", i, test.path)
+				continue
+			}
+			if status := restErrorStatus(err); status != test.status {
+				t.Errorf("#%d (%s): got status %d - want %d", i,
+					test.path, status, test.status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d (%s): unexpected error: %v", i, test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(elems, test.elems) || format != test.format {
+			t.Errorf("#%d (%s): got %q, %d - want %q, %d", i,
+				test.path, elems, format, test.elems, test.format)
+		}
+	}
+}
+
+// TestRESTErrorStatus ensures errors returned by the RPC handlers map to the
+// expected HTTP status codes.
+func TestRESTErrorStatus(t *testing.T) {
+	t.Parallel()
+
+	notFound := &btcjson.RPCError{
+		Code:    btcjson.ErrRPCNoTxInfo,
+		Message: "No information available about transaction",
+	}
+	if status := restErrorStatus(notFound); status != http.StatusNotFound {
+		t.Errorf("missing transaction: got status %d - want %d", status,
+			http.StatusNotFound)
+	}
+	internal := internalRPCError("failure", "context")
+	if status := restErrorStatus(internal); status != http.StatusInternalServerError {
+		t.Errorf("internal error: got status %d - want %d", status,
+			http.StatusInternalServerError)
+	}
+	if _, err := parseRESTHash("abc"); restErrorStatus(err) != http.StatusBadRequest {
+		t.Errorf("short hash: got status %d - want %d",
+			restErrorStatus(err), http.StatusBadRequest)
+	}
+}
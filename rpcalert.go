@@ -0,0 +1,55 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxAlertMessageLen is the maximum length of the message of an alert
+	// broadcast with the broadcastalert command.
+	maxAlertMessageLen = 1024
+
+	// alertWebhookTimeout is the maximum time posting an alert to a
+	// webhook may take.
+	alertWebhookTimeout = 10 * time.Second
+)
+
+// alertSeverities holds the severities an alert broadcast with the
+// broadcastalert command may have.
+var alertSeverities = map[string]struct{}{
+	"info":     struct{}{},
+	"warning":  struct{}{},
+	"critical": struct{}{},
+}
+
+// postAlertWebhooks posts the passed marshalled alert notification to each of
+// the passed webhook URLs.  The requests are made in the background and
+// failures are only logged, so unreachable webhooks never hold up the alert.
+func postAlertWebhooks(webhooks []string, marshalledJSON []byte) {
+	client := &http.Client{Timeout: alertWebhookTimeout}
+	for _, webhook := range webhooks {
+		go func(webhook string) {
+			resp, err := client.Post(webhook, "application/json",
+				bytes.NewReader(marshalledJSON))
+			if err != nil {
+				rpcsLog.Warnf("Failed to post alert to webhook %s: %v",
+					webhook, err)
+				return
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				rpcsLog.Warnf("Webhook %s rejected alert: %s", webhook,
+					resp.Status)
+			}
+		}(webhook)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestPostAlertWebhooks ensures alerts are posted as JSON to every webhook.
+func TestPostAlertWebhooks(t *testing.T) {
+	t.Parallel()
+
+	bodies := make(chan string, 2)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != "POST" ||
+			r.Header.Get("Content-Type") != "application/json" {
+
+			t.Errorf("unexpected %s request with content type %q",
+				r.Method, r.Header.Get("Content-Type"))
+		}
+		bodies <- string(body)
+	}
+	server1 := httptest.NewServer(http.HandlerFunc(handler))
+	defer server1.Close()
+	server2 := httptest.NewServer(http.HandlerFunc(handler))
+	defer server2.Close()
+
+	alert := `{"jsonrpc":"1.0","method":"alert","params":["maintenance",` +
+		`"info",1431542226],"id":null}`
+	postAlertWebhooks([]string{server1.URL, server2.URL}, []byte(alert))
+	for i := 0; i < 2; i++ {
+		select {
+		case body := <-bodies:
+			if body != alert {
+				t.Errorf("webhook got %s - want %s", body, alert)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for webhook %d", i+1)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// rpcAuthMaxLockout is the longest a client address is locked out
+	// after failing to authenticate.  Lockouts double in length from the
+	// configured one up to this maximum.
+	rpcAuthMaxLockout = time.Hour * 24
+
+	// rpcAuthFailureExpiry is how long the failed authentication attempts
+	// and lockouts of a client address are remembered after its last
+	// failure or the end of its last lockout, whichever is later.
+	rpcAuthFailureExpiry = time.Hour
+
+	// rpcAuthPruneInterval is the minimum time between forgetting the
+	// failures of client addresses which expired.
+	rpcAuthPruneInterval = time.Minute
+)
+
+// errAuthLockedOut is returned when a client address attempts to authenticate
+// while it is locked out.
+var errAuthLockedOut = errors.New("locked out after repeated " +
+	"authentication failures")
+
+// authFailures tracks the failed authentication attempts of a client address.
+type authFailures struct {
+	// failures is the number of failed attempts since the last lockout.
+	failures int
+
+	// lockouts is the number of times the address has been locked out,
+	// which determines the length of the next lockout.
+	lockouts int
+
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// rpcAuthLimiter protects the credentials of the RPC server against guessing
+// by locking out client addresses after repeated failed authentication
+// attempts.  Every lockout of the same address is twice as long as the previous
+// one, up to rpcAuthMaxLockout.  Clients are identified by their IP address so
+// reconnecting doesn't reset their failures.
+//
+// Attempts by locked out addresses are refused without checking their
+// credentials.  The failures of an address are forgotten once it
+// authenticates successfully.
+type rpcAuthLimiter struct {
+	sync.Mutex
+	maxFailures int
+	lockout     time.Duration
+	fail2ban    bool
+	clients     map[string]*authFailures
+	lastPrune   time.Time
+
+	// now returns the current time.  It is replaced by tests.
+	now func() time.Time
+}
+
+// newRPCAuthLimiter returns an authentication limiter which locks out client
+// addresses for the passed duration after maxFailures failed attempts.  A
+// maxFailures of 0 disables the lockouts.  When fail2ban is set, every failed
+// or refused attempt is logged in a fixed format holding only the IP address
+// of the client so it can be matched by intrusion prevention tools such as
+// fail2ban.
+func newRPCAuthLimiter(maxFailures int, lockout time.Duration, fail2ban bool) *rpcAuthLimiter {
+	return &rpcAuthLimiter{
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		fail2ban:    fail2ban,
+		clients:     make(map[string]*authFailures),
+		now:         time.Now,
+	}
+}
+
+// remoteHost returns the host part of the passed remote address.
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// logFailure logs a failed authentication attempt of the client with the
+// passed remote address over the passed transport.
+func (l *rpcAuthLimiter) logFailure(remoteAddr, transport string) {
+	if l.fail2ban {
+		rpcsLog.Warnf("Failed RPC authentication from %s",
+			remoteHost(remoteAddr))
+		return
+	}
+	rpcsLog.Warnf("RPC %s authentication failure from %s", transport,
+		remoteAddr)
+}
+
+// Allow returns errAuthLockedOut when the client with the passed remote
+// address is locked out and may not attempt to authenticate over the passed
+// transport.
+//
+// This function is safe for concurrent access.
+func (l *rpcAuthLimiter) Allow(remoteAddr, transport string) error {
+	if l.maxFailures == 0 {
+		return nil
+	}
+	host := remoteHost(remoteAddr)
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPrune) >= rpcAuthPruneInterval {
+		l.prune(now)
+	}
+
+	client, ok := l.clients[host]
+	if !ok || !now.Before(client.lockedUntil) {
+		return nil
+	}
+	if l.fail2ban {
+		l.logFailure(remoteAddr, transport)
+	} else {
+		rpcsLog.Debugf("Refused RPC %s authentication attempt from "+
+			"%s which is locked out until %v", transport,
+			remoteAddr, client.lockedUntil)
+	}
+	return errAuthLockedOut
+}
+
+// Failure records a failed authentication attempt of the client with the
+// passed remote address over the passed transport and locks the address out
+// once it failed too often.
+//
+// This function is safe for concurrent access.
+func (l *rpcAuthLimiter) Failure(remoteAddr, transport string) {
+	l.logFailure(remoteAddr, transport)
+	if l.maxFailures == 0 {
+		return
+	}
+	host := remoteHost(remoteAddr)
+
+	l.Lock()
+	defer l.Unlock()
+
+	client, ok := l.clients[host]
+	if !ok {
+		client = &authFailures{}
+		l.clients[host] = client
+	}
+	now := l.now()
+	client.lastFailure = now
+	client.failures++
+	if client.failures < l.maxFailures {
+		return
+	}
+
+	lockout := l.lockout
+	for i := 0; i < client.lockouts && lockout < rpcAuthMaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > rpcAuthMaxLockout {
+		lockout = rpcAuthMaxLockout
+	}
+	client.failures = 0
+	client.lockouts++
+	client.lockedUntil = now.Add(lockout)
+	rpcsLog.Warnf("Locking out RPC client %s for %v after %d failed "+
+		"authentication attempts", host, lockout, l.maxFailures)
+}
+
+// Success forgets the failed authentication attempts of the client with the
+// passed remote address.
+//
+// This function is safe for concurrent access.
+func (l *rpcAuthLimiter) Success(remoteAddr string) {
+	if l.maxFailures == 0 {
+		return
+	}
+	host := remoteHost(remoteAddr)
+
+	l.Lock()
+	delete(l.clients, host)
+	l.Unlock()
+}
+
+// prune forgets the failures of the client addresses which have neither
+// failed nor been locked out for rpcAuthFailureExpiry.  The limiter lock must
+// be held.
+func (l *rpcAuthLimiter) prune(now time.Time) {
+	for host, client := range l.clients {
+		if now.Sub(client.lastFailure) >= rpcAuthFailureExpiry &&
+			now.Sub(client.lockedUntil) >= rpcAuthFailureExpiry {
+
+			delete(l.clients, host)
+		}
+	}
+	l.lastPrune = now
+}
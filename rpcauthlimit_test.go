@@ -0,0 +1,115 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRPCAuthLimiter ensures client addresses are locked out after the
+// configured number of failed authentication attempts, that every further
+// lockout is twice as long and that successful attempts and other addresses
+// are not affected.
+func TestRPCAuthLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000000, 0)
+	limiter := newRPCAuthLimiter(3, time.Minute, false)
+	limiter.now = func() time.Time { return now }
+
+	// failAll fails the passed number of attempts from the passed address,
+	// which must all be allowed.
+	failAll := func(remoteAddr string, n int) {
+		for i := 0; i < n; i++ {
+			if err := limiter.Allow(remoteAddr, "HTTP"); err != nil {
+				t.Fatalf("attempt #%d from %s: unexpected error: %v",
+					i, remoteAddr, err)
+			}
+			limiter.Failure(remoteAddr, "HTTP")
+		}
+	}
+
+	// The address is locked out once it failed too often, independent of
+	// the port it connects from.
+	failAll("10.0.0.1:1234", 3)
+	if err := limiter.Allow("10.0.0.1:5678", "websocket"); err != errAuthLockedOut {
+		t.Fatalf("attempt after failures: got %v - want %v", err,
+			errAuthLockedOut)
+	}
+	if err := limiter.Allow("10.0.0.2:1234", "HTTP"); err != nil {
+		t.Fatalf("other address: unexpected error: %v", err)
+	}
+
+	// The second lockout is twice as long as the first.
+	now = now.Add(time.Minute)
+	failAll("10.0.0.1:1234", 3)
+	now = now.Add(time.Minute)
+	if err := limiter.Allow("10.0.0.1:1234", "HTTP"); err != errAuthLockedOut {
+		t.Fatalf("attempt during second lockout: got %v - want %v",
+			err, errAuthLockedOut)
+	}
+	now = now.Add(time.Minute)
+
+	// A successful attempt forgets the failures.
+	failAll("10.0.0.1:1234", 2)
+	limiter.Success("10.0.0.1:1234")
+	failAll("10.0.0.1:1234", 2)
+	if err := limiter.Allow("10.0.0.1:1234", "HTTP"); err != nil {
+		t.Fatalf("attempt after success: unexpected error: %v", err)
+	}
+
+	// Failures of addresses which are no longer locked out expire.
+	failAll("10.0.0.2:1234", 3)
+	now = now.Add(time.Minute + rpcAuthFailureExpiry)
+	if err := limiter.Allow("10.0.0.3:1234", "HTTP"); err != nil {
+		t.Fatalf("attempt after expiry: unexpected error: %v", err)
+	}
+	if len(limiter.clients) != 0 {
+		t.Fatalf("clients after prune: got %d - want 0",
+			len(limiter.clients))
+	}
+}
+
+// TestRPCAuthLimiterMaxLockout ensures lockouts do not grow past
+// rpcAuthMaxLockout.
+func TestRPCAuthLimiterMaxLockout(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000000, 0)
+	limiter := newRPCAuthLimiter(1, time.Hour, false)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 70; i++ {
+		limiter.Failure("10.0.0.1:1234", "HTTP")
+		lockout := limiter.clients["10.0.0.1"].lockedUntil.Sub(now)
+		if lockout <= 0 || lockout > rpcAuthMaxLockout {
+			t.Fatalf("lockout #%d: got %v - want between 0 and %v",
+				i, lockout, rpcAuthMaxLockout)
+		}
+	}
+	lockout := limiter.clients["10.0.0.1"].lockedUntil.Sub(now)
+	if lockout != rpcAuthMaxLockout {
+		t.Fatalf("lockout: got %v - want %v", lockout,
+			rpcAuthMaxLockout)
+	}
+}
+
+// TestRPCAuthLimiterDisabled ensures no address is locked out when the
+// maximum number of failures is 0.
+func TestRPCAuthLimiterDisabled(t *testing.T) {
+	t.Parallel()
+
+	limiter := newRPCAuthLimiter(0, time.Minute, true)
+	for i := 0; i < 10; i++ {
+		limiter.Failure("10.0.0.1:1234", "websocket")
+	}
+	if err := limiter.Allow("10.0.0.1:1234", "websocket"); err != nil {
+		t.Fatalf("attempt after failures: unexpected error: %v", err)
+	}
+	if len(limiter.clients) != 0 {
+		t.Fatalf("clients: got %d - want 0", len(limiter.clients))
+	}
+}
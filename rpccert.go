@@ -0,0 +1,365 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/conseweb/stcd/acme"
+)
+
+const (
+	// rpcCertCheckInterval is how often the RPC TLS certificate and key
+	// files are checked for changes.
+	rpcCertCheckInterval = time.Minute
+
+	// acmeRenewBefore is how long before its expiry a certificate obtained
+	// through ACME is renewed.
+	acmeRenewBefore = time.Hour * 24 * 30
+
+	// acmeCheckInterval is how often the RPC TLS certificate is checked
+	// for whether it needs to be renewed through ACME.
+	acmeCheckInterval = time.Hour * 12
+
+	// acmeRetryInterval is how long to wait before trying again after
+	// failing to obtain a certificate through ACME.
+	acmeRetryInterval = time.Hour
+
+	// acmeAccountKeyFilename is the name of the file in the data directory
+	// holding the key of the ACME account.
+	acmeAccountKeyFilename = "acme_account.key"
+)
+
+// certReloader serves the RPC TLS certificate and reloads it from its files
+// when they change or the process receives SIGHUP, so certificates can be
+// replaced without restarting the server.  A certificate which fails to load,
+// for example because only one of the files was replaced so far, is ignored
+// and the previous one is kept.
+type certReloader struct {
+	sync.RWMutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+}
+
+// newCertReloader returns a certReloader for the passed certificate and key
+// files, which must hold a valid key pair.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// modTimes returns the modification times of the certificate and key files.
+func (r *certReloader) modTimes() (time.Time, time.Time) {
+	var certMod, keyMod time.Time
+	if fi, err := os.Stat(r.certFile); err == nil {
+		certMod = fi.ModTime()
+	}
+	if fi, err := os.Stat(r.keyFile); err == nil {
+		keyMod = fi.ModTime()
+	}
+	return certMod, keyMod
+}
+
+// Reload loads the key pair from the certificate and key files.  The current
+// certificate is kept when they don't hold a valid key pair.
+//
+// This function is safe for concurrent access.
+func (r *certReloader) Reload() error {
+	certMod, keyMod := r.modTimes()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.Unlock()
+	return nil
+}
+
+// changed returns whether the certificate or key file changed since the key
+// pair was last loaded.
+//
+// This function is safe for concurrent access.
+func (r *certReloader) changed() bool {
+	certMod, keyMod := r.modTimes()
+
+	r.RLock()
+	defer r.RUnlock()
+	return !certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod)
+}
+
+// Certificate returns the certificate being served.
+//
+// This function is safe for concurrent access.
+func (r *certReloader) Certificate() *tls.Certificate {
+	r.RLock()
+	cert := r.cert
+	r.RUnlock()
+	return cert
+}
+
+// GetCertificate returns the certificate being served.  It is used as the
+// GetCertificate function of the TLS configuration of the RPC listeners.
+//
+// This function is safe for concurrent access.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// reload reloads the key pair and logs the outcome.
+func (r *certReloader) reload(reason string) {
+	if err := r.Reload(); err != nil {
+		rpcsLog.Errorf("Unable to reload RPC TLS certificate after %s: "+
+			"%v", reason, err)
+		return
+	}
+	rpcsLog.Infof("Reloaded RPC TLS certificate after %s (expires %v)",
+		reason, r.Certificate().Leaf.NotAfter)
+}
+
+// run reloads the key pair whenever the process receives SIGHUP or the files
+// change.  It must be run as a goroutine.
+func (r *certReloader) run(quit <-chan int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(rpcCertCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-hup:
+			r.reload("SIGHUP")
+
+		case <-ticker.C:
+			if r.changed() {
+				r.reload("file change")
+			}
+
+		case <-quit:
+			return
+		}
+	}
+}
+
+// certNeedsRenewal returns whether the passed certificate must be replaced
+// because it is not valid for all of the passed domains or expires within
+// acmeRenewBefore of the passed time.
+func certNeedsRenewal(cert *tls.Certificate, domains []string, now time.Time) bool {
+	if cert == nil || cert.Leaf == nil {
+		return true
+	}
+	for _, domain := range domains {
+		if cert.Leaf.VerifyHostname(domain) != nil {
+			return true
+		}
+	}
+	return now.Add(acmeRenewBefore).After(cert.Leaf.NotAfter)
+}
+
+// acmeRenewer obtains the RPC TLS certificate for the configured domains from
+// an ACME certificate authority and renews it before it expires.  The
+// certificate and its key are written to the RPC certificate and key files
+// and loaded by the certReloader.
+type acmeRenewer struct {
+	client     *acme.Client
+	domains    []string
+	contact    []string
+	listen     string
+	reloader   *certReloader
+	registered bool
+}
+
+// newACMERenewer returns an acmeRenewer for the configured domains which
+// stores the certificates for the passed reloader.  The ACME account key is
+// loaded from the data directory, or generated when it doesn't exist yet.
+func newACMERenewer(reloader *certReloader) (*acmeRenewer, error) {
+	key, err := loadACMEAccountKey(filepath.Join(cfg.DataDir,
+		acmeAccountKeyFilename))
+	if err != nil {
+		return nil, err
+	}
+	var contact []string
+	if cfg.RPCACMEEmail != "" {
+		contact = []string{"mailto:" + cfg.RPCACMEEmail}
+	}
+	return &acmeRenewer{
+		client: &acme.Client{
+			DirectoryURL: cfg.RPCACMEDirectory,
+			Key:          key,
+			HTTPClient:   &http.Client{Timeout: time.Minute},
+		},
+		domains:  cfg.RPCACMEDomains,
+		contact:  contact,
+		listen:   cfg.RPCACMEListen,
+		reloader: reloader,
+	}, nil
+}
+
+// loadACMEAccountKey loads the ACME account key from the passed file, or
+// generates a new key and writes it to the file when it doesn't exist.
+func loadACMEAccountKey(path string) (*ecdsa.PrivateKey, error) {
+	if pemKey, err := ioutil.ReadFile(path); err == nil {
+		block, _ := pem.Decode(pemKey)
+		if block == nil {
+			return nil, errors.New("no key in ACME account key file")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeECKey(path, key); err != nil {
+		return nil, err
+	}
+	rpcsLog.Infof("Generated ACME account key %s", path)
+	return key, nil
+}
+
+// writeECKey writes the passed key PEM-encoded to the passed file.  The file is
+// written next to the old one and renamed over it, so a crash never leaves a
+// truncated file behind.
+func writeECKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: der,
+	})
+	return writeFileAtomic(path, pemKey, 0600)
+}
+
+// writeFileAtomic writes the passed data to the passed file next to the old
+// one and renames it over the old one.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// renew obtains a new certificate for the domains, writes it and its key to
+// the RPC certificate and key files and reloads them.  The http-01 challenges
+// are served on the configured ACME listen address while the certificate is
+// obtained.
+func (a *acmeRenewer) renew() error {
+	if !a.registered {
+		if err := a.client.Register(a.contact); err != nil {
+			return err
+		}
+		a.registered = true
+	}
+
+	listener, err := net.Listen("tcp", a.listen)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	responder := acme.NewHTTP01Responder()
+	go http.Serve(listener, responder)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	chain, err := a.client.ObtainCertificate(a.domains, key, responder)
+	if err != nil {
+		return err
+	}
+
+	// Write the key first since the certificate files are only reloaded
+	// once they hold a matching pair.
+	if err := writeECKey(a.reloader.keyFile, key); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(a.reloader.certFile, chain, 0666); err != nil {
+		return err
+	}
+	return a.reloader.Reload()
+}
+
+// run renews the certificate whenever it is about to expire or doesn't cover
+// all of the domains.  It must be run as a goroutine.
+func (a *acmeRenewer) run(quit <-chan int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		wait := acmeCheckInterval
+		cert := a.reloader.Certificate()
+		if certNeedsRenewal(cert, a.domains, time.Now()) {
+			rpcsLog.Infof("Obtaining RPC TLS certificate for %v "+
+				"through ACME", a.domains)
+
+			// Obtaining a certificate can take minutes, so it is
+			// done in its own goroutine to not hold up shutdown.
+			done := make(chan error, 1)
+			go func() {
+				done <- a.renew()
+			}()
+			var err error
+			select {
+			case err = <-done:
+			case <-quit:
+				return
+			}
+			if err != nil {
+				rpcsLog.Errorf("Unable to obtain RPC TLS "+
+					"certificate through ACME: %v", err)
+				wait = acmeRetryInterval
+			} else {
+				rpcsLog.Infof("Obtained RPC TLS certificate "+
+					"for %v (expires %v)", a.domains,
+					a.reloader.Certificate().Leaf.NotAfter)
+			}
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-quit:
+			return
+		}
+	}
+}
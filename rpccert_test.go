@@ -0,0 +1,142 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+)
+
+// writeTestCertPair writes a new self-signed certificate valid until the
+// passed time for the passed hosts and its key to the passed files.
+func writeTestCertPair(t *testing.T, certFile, keyFile string, validUntil time.Time, hosts []string) {
+	cert, key, err := coinutil.NewTLSCertPair("test", validUntil, hosts)
+	if err != nil {
+		t.Fatalf("NewTLSCertPair: unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(certFile, cert, 0600); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, key, 0600); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v", err)
+	}
+}
+
+// TestCertReloader ensures the RPC TLS certificate is reloaded when its files
+// change and the previous one is kept while they don't hold a valid pair.
+func TestCertReloader(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "rpccert")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "rpc.cert")
+	keyFile := filepath.Join(dir, "rpc.key")
+
+	firstExpiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	writeTestCertPair(t, certFile, keyFile, firstExpiry, nil)
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader: unexpected error: %v", err)
+	}
+	cert, err := reloader.GetCertificate(nil)
+	if err != nil || !cert.Leaf.NotAfter.Equal(firstExpiry) {
+		t.Fatalf("GetCertificate: got %v (%v) - want certificate "+
+			"expiring %v", cert.Leaf.NotAfter, err, firstExpiry)
+	}
+	if reloader.changed() {
+		t.Fatalf("changed: unexpected change before replacing files")
+	}
+
+	// A certificate without its key is not loaded.
+	secondExpiry := firstExpiry.Add(time.Hour)
+	cert2, _, err := coinutil.NewTLSCertPair("test", secondExpiry, nil)
+	if err != nil {
+		t.Fatalf("NewTLSCertPair: unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(certFile, cert2, 0600); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v", err)
+	}
+	os.Chtimes(certFile, time.Now(), time.Now().Add(time.Minute))
+	if !reloader.changed() {
+		t.Fatalf("changed: replaced certificate file not detected")
+	}
+	if err := reloader.Reload(); err == nil {
+		t.Fatalf("Reload: unexpected success with mismatched key")
+	}
+	if cert := reloader.Certificate(); !cert.Leaf.NotAfter.Equal(firstExpiry) {
+		t.Fatalf("Certificate after failed reload: got expiry %v - "+
+			"want %v", cert.Leaf.NotAfter, firstExpiry)
+	}
+
+	// The new pair is served once both files were replaced.
+	writeTestCertPair(t, certFile, keyFile, secondExpiry, nil)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+	if cert := reloader.Certificate(); !cert.Leaf.NotAfter.Equal(secondExpiry) {
+		t.Fatalf("Certificate after reload: got expiry %v - want %v",
+			cert.Leaf.NotAfter, secondExpiry)
+	}
+	if reloader.changed() {
+		t.Fatalf("changed: unexpected change after reload")
+	}
+}
+
+// TestCertNeedsRenewal ensures certificates are renewed through ACME when they
+// don't cover all of the domains or are about to expire.
+func TestCertNeedsRenewal(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "rpccert")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "rpc.cert")
+	keyFile := filepath.Join(dir, "rpc.key")
+
+	now := time.Now()
+	expiry := now.Add(acmeRenewBefore * 2)
+	writeTestCertPair(t, certFile, keyFile, expiry,
+		[]string{"node.example.com"})
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader: unexpected error: %v", err)
+	}
+	cert := reloader.Certificate()
+
+	tests := []struct {
+		name    string
+		domains []string
+		now     time.Time
+		renew   bool
+	}{
+		{"covered", []string{"node.example.com"}, now, false},
+		{"other domain", []string{"node.example.com", "rpc.example.com"},
+			now, true},
+		{"about to expire", []string{"node.example.com"},
+			expiry.Add(-acmeRenewBefore / 2), true},
+		{"expired", []string{"node.example.com"}, expiry.Add(time.Hour),
+			true},
+	}
+	for _, test := range tests {
+		renew := certNeedsRenewal(cert, test.domains, test.now)
+		if renew != test.renew {
+			t.Errorf("%s: got %v - want %v", test.name, renew,
+				test.renew)
+		}
+	}
+	if !certNeedsRenewal(nil, []string{"node.example.com"}, now) {
+		t.Errorf("no certificate: got false - want true")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// errListenerClosed is returned by the Accept method of a connection limited
+// listener once it is closed.
+var errListenerClosed = errors.New("listener closed")
+
+// connLimiter limits the number of open connections accepted by a group of
+// listeners, such as all of the regular or all of the read-only RPC
+// listeners.  Unlike the limit on the number of HTTP clients served
+// concurrently, it also covers idle keep-alive connections and connections
+// which are still sending their request, so one group of listeners can't
+// exhaust the file descriptors the others need.
+//
+// Listeners block in Accept while the limit is reached, which leaves new
+// connections waiting in the backlog of the operating system until an open
+// one is closed.
+type connLimiter struct {
+	// sem holds a token for every open connection.  It is nil when the
+	// number of connections is unlimited.
+	sem   chan struct{}
+	count int32
+}
+
+// newConnLimiter returns a connLimiter which allows the passed number of open
+// connections.  A maximum of 0 disables the limit, but the connections are
+// still counted.
+func newConnLimiter(max int) *connLimiter {
+	l := &connLimiter{}
+	if max > 0 {
+		l.sem = make(chan struct{}, max)
+	}
+	return l
+}
+
+// Count returns the number of open connections accepted by the listeners of
+// the limiter.
+//
+// This function is safe for concurrent access.
+func (l *connLimiter) Count() int {
+	return int(atomic.LoadInt32(&l.count))
+}
+
+// Listener returns a listener accepting connections from the passed one
+// within the limit.
+func (l *connLimiter) Listener(listener net.Listener) net.Listener {
+	return &limitListener{
+		Listener: listener,
+		limiter:  l,
+		done:     make(chan struct{}),
+	}
+}
+
+// release returns the token of a closed connection.
+func (l *connLimiter) release() {
+	atomic.AddInt32(&l.count, -1)
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+// limitListener is a net.Listener whose connections count against the limit of
+// a connLimiter.
+type limitListener struct {
+	net.Listener
+	limiter   *connLimiter
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Accept waits for the number of open connections to be below the limit and
+// returns the next connection.  It is part of the net.Listener interface.
+func (l *limitListener) Accept() (net.Conn, error) {
+	if l.limiter.sem != nil {
+		select {
+		case l.limiter.sem <- struct{}{}:
+		case <-l.done:
+			return nil, errListenerClosed
+		}
+	}
+	atomic.AddInt32(&l.limiter.count, 1)
+
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		l.limiter.release()
+		return nil, err
+	}
+	return &limitConn{Conn: conn, limiter: l.limiter}, nil
+}
+
+// Close stops the listener, including an Accept waiting for a connection to be
+// closed.  It is part of the net.Listener interface.
+func (l *limitListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	return l.Listener.Close()
+}
+
+// limitConn is a connection accepted by a limitListener, which releases its
+// place in the limit once it is closed.
+type limitConn struct {
+	net.Conn
+	limiter     *connLimiter
+	releaseOnce sync.Once
+}
+
+// Close closes the connection.  It is part of the net.Conn interface.
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.limiter.release)
+	return err
+}
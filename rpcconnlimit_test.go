@@ -0,0 +1,127 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// TestConnLimiter ensures listeners of a connection limiter only accept
+// connections while the number of open ones is below the limit and that
+// closing a listener unblocks its Accept.
+func TestConnLimiter(t *testing.T) {
+	t.Parallel()
+
+	limiter := newConnLimiter(2)
+	rawListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: unexpected error: %v", err)
+	}
+	listener := limiter.Listener(rawListener)
+	defer listener.Close()
+
+	// Dial more connections than the limit allows.
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", rawListener.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial #%d: unexpected error: %v", i, err)
+		}
+		defer conn.Close()
+	}
+
+	accepted := make(chan net.Conn, 3)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	// Only the first two connections are accepted until one is closed.
+	var conns []net.Conn
+	for i := 0; i < 2; i++ {
+		select {
+		case conn := <-accepted:
+			conns = append(conns, conn)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("Accept #%d: timed out", i)
+		}
+	}
+	select {
+	case <-accepted:
+		t.Fatalf("Accept: unexpected connection over the limit")
+	case <-time.After(time.Millisecond * 100):
+	}
+	if count := limiter.Count(); count != 2 {
+		t.Fatalf("Count: got %d - want 2", count)
+	}
+
+	// Closing a connection twice releases its place once.
+	conns[0].Close()
+	conns[0].Close()
+	select {
+	case conn := <-accepted:
+		conns = append(conns, conn)
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Accept after close: timed out")
+	}
+	if count := limiter.Count(); count != 2 {
+		t.Fatalf("Count after close: got %d - want 2", count)
+	}
+
+	// Closing the listener unblocks the Accept waiting for a place.
+	listener.Close()
+	select {
+	case _, ok := <-accepted:
+		if ok {
+			t.Fatalf("Accept after listener close: unexpected " +
+				"connection")
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Accept after listener close: timed out")
+	}
+	for _, conn := range conns[1:] {
+		conn.Close()
+	}
+	if count := limiter.Count(); count != 0 {
+		t.Fatalf("Count after closing all: got %d - want 0", count)
+	}
+}
+
+// TestConnLimiterUnlimited ensures connection limiters without a limit still
+// count the open connections.
+func TestConnLimiterUnlimited(t *testing.T) {
+	t.Parallel()
+
+	limiter := newConnLimiter(0)
+	rawListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: unexpected error: %v", err)
+	}
+	listener := limiter.Listener(rawListener)
+	defer listener.Close()
+
+	for i := 0; i < 5; i++ {
+		conn, err := net.Dial("tcp", rawListener.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial #%d: unexpected error: %v", i, err)
+		}
+		defer conn.Close()
+		accepted, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("Accept #%d: unexpected error: %v", i, err)
+		}
+		defer accepted.Close()
+	}
+	if count := limiter.Count(); count != 5 {
+		t.Fatalf("Count: got %d - want 5", count)
+	}
+}
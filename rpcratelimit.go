@@ -0,0 +1,208 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/conseweb/stcd/btcjson"
+)
+
+// rpcLimiterPruneInterval is the minimum time between removing the token
+// buckets of clients which have been idle long enough for their buckets to
+// refill completely.
+const rpcLimiterPruneInterval = time.Minute
+
+// rpcMethodClass classifies RPC methods by how costly they are to serve.
+// Every class has its own rate limit.
+type rpcMethodClass int
+
+// These constants define the classes of RPC methods.
+const (
+	rpcMethodCheap rpcMethodClass = iota
+	rpcMethodExpensive
+
+	numRPCMethodClasses
+)
+
+// String returns the class as a human-readable string.
+func (c rpcMethodClass) String() string {
+	if c == rpcMethodExpensive {
+		return "expensive"
+	}
+	return "cheap"
+}
+
+// rpcExpensiveMethods is the set of methods which may load large amounts of
+// data from the database or run for a long time.  They are subject to the
+// expensive rate limit and the limit of concurrently served expensive
+// requests.
+var rpcExpensiveMethods = map[string]struct{}{
+	"dumputxoset":           struct{}{},
+	"getblock":              struct{}{},
+	"loadutxoset":           struct{}{},
+	"rescan":                struct{}{},
+	"rescanfilter":          struct{}{},
+	"searchrawtransactions": struct{}{},
+	"verifychain":           struct{}{},
+}
+
+// rpcMethodClassOf returns the class of the passed method.
+func rpcMethodClassOf(method string) rpcMethodClass {
+	if _, ok := rpcExpensiveMethods[method]; ok {
+		return rpcMethodExpensive
+	}
+	return rpcMethodCheap
+}
+
+// tokenBucket tracks the number of requests a client may make before it is
+// rate limited.  It holds at most as many tokens as are refilled per second,
+// but at least one, so clients may burst up to a second worth of requests.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// refill adds the tokens which accumulated since the bucket was last updated
+// at the passed rate per second.
+func (b *tokenBucket) refill(rate float64, now time.Time) {
+	burst := math.Max(rate, 1)
+	elapsed := now.Sub(b.updated).Seconds()
+	b.tokens = math.Min(burst, b.tokens+elapsed*rate)
+	b.updated = now
+}
+
+// rpcLimiterKey identifies the token bucket of a client address and method
+// class.
+type rpcLimiterKey struct {
+	host  string
+	class rpcMethodClass
+}
+
+// rpcRateLimiter enforces per-client request rate limits for each method
+// class as well as a server-wide limit of expensive requests which are served
+// concurrently.  Clients are identified by their IP address so reconnecting
+// doesn't reset their limits.
+type rpcRateLimiter struct {
+	sync.Mutex
+	rates        [numRPCMethodClasses]float64
+	maxExpensive int
+	expensive    int
+	buckets      map[rpcLimiterKey]*tokenBucket
+	lastPrune    time.Time
+
+	// now returns the current time.  It is replaced by tests.
+	now func() time.Time
+}
+
+// newRPCRateLimiter returns a rate limiter which allows each client address
+// cheapRate cheap and expensiveRate expensive requests per second and serves
+// at most maxExpensive expensive requests at once.  A limit of 0 disables the
+// respective limit.
+func newRPCRateLimiter(cheapRate, expensiveRate float64, maxExpensive int) *rpcRateLimiter {
+	l := &rpcRateLimiter{
+		maxExpensive: maxExpensive,
+		buckets:      make(map[rpcLimiterKey]*tokenBucket),
+		now:          time.Now,
+	}
+	l.rates[rpcMethodCheap] = cheapRate
+	l.rates[rpcMethodExpensive] = expensiveRate
+	return l
+}
+
+// rateLimitedError returns the error replied to rate limited requests.
+func rateLimitedError(format string, args ...interface{}) *btcjson.RPCError {
+	return &btcjson.RPCError{
+		Code:    btcjson.ErrRPCRateLimited,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Allow takes a token from the bucket of the client with the passed remote
+// address for the class of the passed method.  An ErrRPCRateLimited error is
+// returned when the bucket is empty.
+//
+// This function is safe for concurrent access.
+func (l *rpcRateLimiter) Allow(remoteAddr, method string) error {
+	class := rpcMethodClassOf(method)
+	rate := l.rates[class]
+	if rate == 0 {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPrune) >= rpcLimiterPruneInterval {
+		l.prune(now)
+	}
+
+	key := rpcLimiterKey{host: host, class: class}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: math.Max(rate, 1), updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.refill(rate, now)
+	if bucket.tokens < 1 {
+		return rateLimitedError("Rate limit of %g %s requests per "+
+			"second exceeded", rate, class)
+	}
+	bucket.tokens--
+	return nil
+}
+
+// prune removes the buckets which have refilled completely since they are
+// indistinguishable from new ones.  The limiter lock must be held.
+func (l *rpcRateLimiter) prune(now time.Time) {
+	for key, bucket := range l.buckets {
+		rate := l.rates[key.class]
+		bucket.refill(rate, now)
+		if bucket.tokens >= math.Max(rate, 1) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// AcquireSlot reserves one of the slots for concurrently served expensive
+// requests when the passed method is expensive.  The returned function must
+// be called once the request has been served to give the slot back.  An
+// ErrRPCRateLimited error is returned when all slots are in use.
+//
+// This function is safe for concurrent access.
+func (l *rpcRateLimiter) AcquireSlot(method string) (func(), error) {
+	if l.maxExpensive == 0 || rpcMethodClassOf(method) != rpcMethodExpensive {
+		return func() {}, nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.expensive >= l.maxExpensive {
+		return nil, rateLimitedError("Too many expensive requests are "+
+			"in progress (max %d) -- try again later",
+			l.maxExpensive)
+	}
+	l.expensive++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.Lock()
+			l.expensive--
+			l.Unlock()
+		})
+	}, nil
+}
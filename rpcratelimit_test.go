@@ -0,0 +1,112 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/btcjson"
+)
+
+// isRateLimited returns whether the passed error is a rate limit error.
+func isRateLimited(err error) bool {
+	rpcErr, ok := err.(*btcjson.RPCError)
+	return ok && rpcErr.Code == btcjson.ErrRPCRateLimited
+}
+
+// TestRPCRateLimiterAllow ensures clients are limited to the configured
+// number of requests per second for each method class, independent of each
+// other.
+func TestRPCRateLimiterAllow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000000, 0)
+	limiter := newRPCRateLimiter(2, 0.5, 0)
+	limiter.now = func() time.Time { return now }
+
+	// A client may burst up to a second worth of cheap requests.
+	for i := 0; i < 2; i++ {
+		if err := limiter.Allow("10.0.0.1:1234", "getinfo"); err != nil {
+			t.Fatalf("cheap request #%d: unexpected error: %v", i, err)
+		}
+	}
+	err := limiter.Allow("10.0.0.1:5678", "getblockcount")
+	if !isRateLimited(err) {
+		t.Fatalf("cheap request over limit: got %v - want rate "+
+			"limited error", err)
+	}
+
+	// Expensive requests and other clients are limited separately.
+	if err := limiter.Allow("10.0.0.1:1234", "getblock"); err != nil {
+		t.Fatalf("expensive request: unexpected error: %v", err)
+	}
+	if err := limiter.Allow("10.0.0.1:1234", "searchrawtransactions"); !isRateLimited(err) {
+		t.Fatalf("expensive request over limit: got %v - want rate "+
+			"limited error", err)
+	}
+	if err := limiter.Allow("10.0.0.2:1234", "getinfo"); err != nil {
+		t.Fatalf("other client: unexpected error: %v", err)
+	}
+
+	// Tokens are refilled over time.
+	now = now.Add(time.Second)
+	if err := limiter.Allow("10.0.0.1:1234", "getinfo"); err != nil {
+		t.Fatalf("cheap request after refill: unexpected error: %v", err)
+	}
+	if err := limiter.Allow("10.0.0.1:1234", "getblock"); !isRateLimited(err) {
+		t.Fatalf("expensive request after partial refill: got %v - "+
+			"want rate limited error", err)
+	}
+	now = now.Add(time.Second)
+	if err := limiter.Allow("10.0.0.1:1234", "getblock"); err != nil {
+		t.Fatalf("expensive request after refill: unexpected error: %v",
+			err)
+	}
+
+	// Buckets which refilled completely are pruned.
+	now = now.Add(rpcLimiterPruneInterval)
+	if err := limiter.Allow("10.0.0.3:1234", "getinfo"); err != nil {
+		t.Fatalf("request after prune: unexpected error: %v", err)
+	}
+	if len(limiter.buckets) != 1 {
+		t.Fatalf("buckets after prune: got %d - want 1",
+			len(limiter.buckets))
+	}
+}
+
+// TestRPCRateLimiterSlots ensures the number of concurrently served expensive
+// requests is limited and cheap requests are not.
+func TestRPCRateLimiterSlots(t *testing.T) {
+	t.Parallel()
+
+	limiter := newRPCRateLimiter(0, 0, 1)
+	release, err := limiter.AcquireSlot("rescan")
+	if err != nil {
+		t.Fatalf("AcquireSlot: unexpected error: %v", err)
+	}
+	if _, err := limiter.AcquireSlot("getblock"); !isRateLimited(err) {
+		t.Fatalf("AcquireSlot over limit: got %v - want rate limited "+
+			"error", err)
+	}
+	releaseCheap, err := limiter.AcquireSlot("getinfo")
+	if err != nil {
+		t.Fatalf("AcquireSlot cheap: unexpected error: %v", err)
+	}
+	releaseCheap()
+
+	// Releasing more than once must not free additional slots.
+	release()
+	release()
+	release, err = limiter.AcquireSlot("getblock")
+	if err != nil {
+		t.Fatalf("AcquireSlot after release: unexpected error: %v", err)
+	}
+	if _, err := limiter.AcquireSlot("getblock"); !isRateLimited(err) {
+		t.Fatalf("AcquireSlot after double release: got %v - want "+
+			"rate limited error", err)
+	}
+	release()
+}
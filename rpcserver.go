@@ -5,7 +5,9 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/subtle"
 	"crypto/tls"
 	"encoding/base64"
@@ -14,13 +16,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"math"
 	"math/big"
 	"math/rand"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,23 +33,20 @@ import (
 
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/fastsha256"
+	"github.com/conseweb/stcd/bech32"
 	"github.com/conseweb/stcd/blockchain"
 	"github.com/conseweb/stcd/btcec"
 	"github.com/conseweb/stcd/btcjson"
 	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/database"
 	"github.com/conseweb/stcd/mining"
+	"github.com/conseweb/stcd/paymenturi"
 	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
 	"github.com/conseweb/websocket"
 )
 
 const (
-	// rpcAuthTimeoutSeconds is the number of seconds a connection to the
-	// RPC server is allowed to stay open without authenticating before it
-	// is closed.
-	rpcAuthTimeoutSeconds = 10
-
 	// uint256Size is the number of bytes needed to represent an unsigned
 	// 256-bit integer.
 	uint256Size = 32
@@ -80,6 +81,16 @@ const (
 
 	// maxProtocolVersion is the max protocol version the server supports.
 	maxProtocolVersion = 70002
+
+	// defaultMaxRawTxFeeRate is the default maximum fee rate, in satoshi
+	// per kilobyte, a transaction submitted via the sendrawtransaction RPC
+	// is allowed to pay before it is considered absurdly high and rejected.
+	// This is 0.1 BTC/kB to match bitcoind.
+	defaultMaxRawTxFeeRate = coinutil.Amount(10000000)
+
+	// gbtMaxCachedCoinbases is the maximum number of customized coinbases
+	// cached for a block template.
+	gbtMaxCachedCoinbases = 64
 )
 
 var (
@@ -104,7 +115,7 @@ var (
 	// block template generated by the getblocktemplate RPC.    It is
 	// declared here to avoid the overhead of creating the slice on every
 	// invocation for constant data.
-	gbtCapabilities = []string{"proposal"}
+	gbtCapabilities = []string{"proposal", "auditsource"}
 )
 
 // Errors
@@ -124,53 +135,103 @@ var (
 	}
 )
 
-type commandHandler func(*rpcServer, interface{}, <-chan struct{}) (interface{}, error)
+type commandHandler func(*rpcServer, interface{}, context.Context) (interface{}, error)
 
 // rpcHandlers maps RPC command strings to appropriate handler functions.
 // This is set by init because help references rpcHandlers and thus causes
 // a dependency loop.
 var rpcHandlers map[string]commandHandler
 var rpcHandlersBeforeInit = map[string]commandHandler{
-	"addnode":               handleAddNode,
-	"createrawtransaction":  handleCreateRawTransaction,
-	"debuglevel":            handleDebugLevel,
-	"decoderawtransaction":  handleDecodeRawTransaction,
-	"decodescript":          handleDecodeScript,
-	"generate":              handleGenerate,
-	"getaddednodeinfo":      handleGetAddedNodeInfo,
-	"getbestblock":          handleGetBestBlock,
-	"getbestblockhash":      handleGetBestBlockHash,
-	"getblock":              handleGetBlock,
-	"getblockcount":         handleGetBlockCount,
-	"getblockhash":          handleGetBlockHash,
-	"getblockheader":        handleGetBlockHeader,
-	"getblocktemplate":      handleGetBlockTemplate,
-	"getconnectioncount":    handleGetConnectionCount,
-	"getcurrentnet":         handleGetCurrentNet,
-	"getdifficulty":         handleGetDifficulty,
-	"getgenerate":           handleGetGenerate,
-	"gethashespersec":       handleGetHashesPerSec,
-	"getinfo":               handleGetInfo,
-	"getmempoolinfo":        handleGetMempoolInfo,
-	"getmininginfo":         handleGetMiningInfo,
-	"getnettotals":          handleGetNetTotals,
-	"getnetworkhashps":      handleGetNetworkHashPS,
-	"getpeerinfo":           handleGetPeerInfo,
-	"getrawmempool":         handleGetRawMempool,
-	"getrawtransaction":     handleGetRawTransaction,
-	"gettxout":              handleGetTxOut,
-	"getwork":               handleGetWork,
-	"help":                  handleHelp,
-	"node":                  handleNode,
-	"ping":                  handlePing,
-	"searchrawtransactions": handleSearchRawTransactions,
-	"sendrawtransaction":    handleSendRawTransaction,
-	"setgenerate":           handleSetGenerate,
-	"stop":                  handleStop,
-	"submitblock":           handleSubmitBlock,
-	"validateaddress":       handleValidateAddress,
-	"verifychain":           handleVerifyChain,
-	"verifymessage":         handleVerifyMessage,
+	"addfilterentry":           handleAddFilterEntry,
+	"addnode":                  handleAddNode,
+	"backupchainstate":         handleBackupChainState,
+	"broadcastalert":           handleBroadcastAlert,
+	"clearnetworkfaults":       handleClearNetworkFaults,
+	"compactdatabase":          handleCompactDatabase,
+	"createmultisig":           handleCreateMultisig,
+	"createrawtransaction":     handleCreateRawTransaction,
+	"debuglevel":               handleDebugLevel,
+	"debugscript":              handleDebugScript,
+	"decodepaymenturi":         handleDecodePaymentURI,
+	"decoderawtransaction":     handleDecodeRawTransaction,
+	"decodescript":             handleDecodeScript,
+	"dumputxoset":              handleDumpUtxoSet,
+	"findorphanchains":         handleFindOrphanChains,
+	"fundrawtransaction":       handleFundRawTransaction,
+	"generate":                 handleGenerate,
+	"generatetodescriptor":     handleGenerateToDescriptor,
+	"getaddednodeinfo":         handleGetAddedNodeInfo,
+	"getaddressbalance":        handleGetAddressBalance,
+	"getaddressdeltas":         handleGetAddressDeltas,
+	"getaddressutxos":          handleGetAddressUtxos,
+	"getallsubscriptions":      handleGetAllSubscriptions,
+	"getapischema":             handleGetAPISchema,
+	"getbestblock":             handleGetBestBlock,
+	"getbestblockhash":         handleGetBestBlockHash,
+	"getblock":                 handleGetBlock,
+	"getblockchaininfo":        handleGetBlockChainInfo,
+	"getblockcount":            handleGetBlockCount,
+	"getblockfilter":           handleGetBlockFilter,
+	"getblockhash":             handleGetBlockHash,
+	"getblockheader":           handleGetBlockHeader,
+	"getblockstats":            handleGetBlockStats,
+	"getblocktemplate":         handleGetBlockTemplate,
+	"getcoinageinfo":           handleGetCoinAgeInfo,
+	"getchainstates":           handleGetChainStates,
+	"getconnectioncount":       handleGetConnectionCount,
+	"getcurrentnet":            handleGetCurrentNet,
+	"getdatabaseinfo":          handleGetDatabaseInfo,
+	"getdbcachestats":          handleGetDBCacheStats,
+	"getdifficulty":            handleGetDifficulty,
+	"getdifficultyhistory":     handleGetDifficultyHistory,
+	"getevictedtransactions":   handleGetEvictedTransactions,
+	"getgenerate":              handleGetGenerate,
+	"gethashespersec":          handleGetHashesPerSec,
+	"getimportstatus":          handleGetImportStatus,
+	"getinfo":                  handleGetInfo,
+	"getlog":                   handleGetLog,
+	"getmempoolinfo":           handleGetMempoolInfo,
+	"getmininginfo":            handleGetMiningInfo,
+	"getnettotals":             handleGetNetTotals,
+	"getnetworkhashps":         handleGetNetworkHashPS,
+	"getnetworkfaults":         handleGetNetworkFaults,
+	"getnetworkinfo":           handleGetNetworkInfo,
+	"getnetworktopology":       handleGetNetworkTopology,
+	"getpeerinfo":              handleGetPeerInfo,
+	"getpolicyinfo":            handleGetPolicyInfo,
+	"getrawmempool":            handleGetRawMempool,
+	"getrawtransaction":        handleGetRawTransaction,
+	"getrpcinfo":               handleGetRPCInfo,
+	"getspendinginfo":          handleGetSpendingInfo,
+	"getsyncstatus":            handleGetSyncStatus,
+	"gettxout":                 handleGetTxOut,
+	"gettxoutsetinfo":          handleGetTxOutSetInfo,
+	"getunconfirmedbroadcasts": handleGetUnconfirmedBroadcasts,
+	"getutxocommitment":        handleGetUtxoCommitment,
+	"getwork":                  handleGetWork,
+	"help":                     handleHelp,
+	"loadutxoset":              handleLoadUtxoSet,
+	"node":                     handleNode,
+	"ping":                     handlePing,
+	"removebroadcast":          handleRemoveBroadcast,
+	"removefilterentry":        handleRemoveFilterEntry,
+	"searchrawtransactions":    handleSearchRawTransactions,
+	"sendrawtransaction":       handleSendRawTransaction,
+	"setblocktemplatepolicy":   handleSetBlockTemplatePolicy,
+	"setblockversion":          handleSetBlockVersion,
+	"setnetworkfault":          handleSetNetworkFault,
+	"setgenerate":              handleSetGenerate,
+	"setmocktime":              handleSetMockTime,
+	"signmessagewithprivkey":   handleSignMessageWithPrivKey,
+	"signrawtransaction":       handleSignRawTransaction,
+	"stop":                     handleStop,
+	"submitblock":              handleSubmitBlock,
+	"validateaddress":          handleValidateAddress,
+	"verifychain":              handleVerifyChain,
+	"verifymessage":            handleVerifyMessage,
+	"waitforblock":             handleWaitForBlock,
+	"waitforblockheight":       handleWaitForBlockHeight,
+	"waitfornewblock":          handleWaitForNewBlock,
 }
 
 // list of commands that we recognise, but for which btcd has no support because
@@ -180,7 +241,6 @@ var rpcAskWallet = map[string]struct{}{
 	"addmultisigaddress":     struct{}{},
 	"backupwallet":           struct{}{},
 	"createencryptedwallet":  struct{}{},
-	"createmultisig":         struct{}{},
 	"dumpprivkey":            struct{}{},
 	"dumpwallet":             struct{}{},
 	"encryptwallet":          struct{}{},
@@ -193,7 +253,6 @@ var rpcAskWallet = map[string]struct{}{
 	"getreceivedbyaccount":   struct{}{},
 	"getreceivedbyaddress":   struct{}{},
 	"gettransaction":         struct{}{},
-	"gettxoutsetinfo":        struct{}{},
 	"getunconfirmedbalance":  struct{}{},
 	"getwalletinfo":          struct{}{},
 	"importprivkey":          struct{}{},
@@ -215,7 +274,6 @@ var rpcAskWallet = map[string]struct{}{
 	"setaccount":             struct{}{},
 	"settxfee":               struct{}{},
 	"signmessage":            struct{}{},
-	"signrawtransaction":     struct{}{},
 	"walletlock":             struct{}{},
 	"walletpassphrase":       struct{}{},
 	"walletpassphrasechange": struct{}{},
@@ -223,48 +281,123 @@ var rpcAskWallet = map[string]struct{}{
 
 // Commands that are currently unimplemented, but should ultimately be.
 var rpcUnimplemented = map[string]struct{}{
-	"estimatefee":       struct{}{},
-	"estimatepriority":  struct{}{},
-	"getblockchaininfo": struct{}{},
-	"getchaintips":      struct{}{},
-	"getnetworkinfo":    struct{}{},
+	"estimatefee":      struct{}{},
+	"estimatepriority": struct{}{},
+	"getchaintips":     struct{}{},
 }
 
 // Commands that are available to a limited user
 var rpcLimited = map[string]struct{}{
 	// Websockets commands
+	"cancelrescan":          struct{}{},
+	"getrescanstatus":       struct{}{},
+	"loadtxfilter":          struct{}{},
 	"notifyblocks":          struct{}{},
+	"notifyclockskew":       struct{}{},
 	"notifynewtransactions": struct{}{},
 	"notifyreceived":        struct{}{},
 	"notifyspent":           struct{}{},
+	"removetxfilter":        struct{}{},
+	"replayevents":          struct{}{},
 	"rescan":                struct{}{},
+	"rescanfilter":          struct{}{},
 	"session":               struct{}{},
+	"startrescan":           struct{}{},
 
 	// Websockets AND HTTP/S commands
-	"help": struct{}{},
+	"getapischema": struct{}{},
+	"help":         struct{}{},
 
 	// HTTP/S-only commands
-	"createrawtransaction":  struct{}{},
-	"decoderawtransaction":  struct{}{},
-	"decodescript":          struct{}{},
-	"getbestblock":          struct{}{},
-	"getbestblockhash":      struct{}{},
-	"getblock":              struct{}{},
-	"getblockcount":         struct{}{},
-	"getblockhash":          struct{}{},
-	"getcurrentnet":         struct{}{},
-	"getdifficulty":         struct{}{},
-	"getinfo":               struct{}{},
-	"getnettotals":          struct{}{},
-	"getnetworkhashps":      struct{}{},
-	"getrawmempool":         struct{}{},
-	"getrawtransaction":     struct{}{},
-	"gettxout":              struct{}{},
-	"searchrawtransactions": struct{}{},
-	"sendrawtransaction":    struct{}{},
-	"submitblock":           struct{}{},
-	"validateaddress":       struct{}{},
-	"verifymessage":         struct{}{},
+	"createmultisig":         struct{}{},
+	"createrawtransaction":   struct{}{},
+	"decodepaymenturi":       struct{}{},
+	"decoderawtransaction":   struct{}{},
+	"decodescript":           struct{}{},
+	"getaddressbalance":      struct{}{},
+	"getaddressdeltas":       struct{}{},
+	"getaddressutxos":        struct{}{},
+	"getbestblock":           struct{}{},
+	"getbestblockhash":       struct{}{},
+	"getblock":               struct{}{},
+	"getblockchaininfo":      struct{}{},
+	"getblockcount":          struct{}{},
+	"getblockfilter":         struct{}{},
+	"getblockhash":           struct{}{},
+	"getblockstats":          struct{}{},
+	"getchainstates":         struct{}{},
+	"getcoinageinfo":         struct{}{},
+	"getcurrentnet":          struct{}{},
+	"getdbcachestats":        struct{}{},
+	"getdifficulty":          struct{}{},
+	"getdifficultyhistory":   struct{}{},
+	"getevictedtransactions": struct{}{},
+	"getimportstatus":        struct{}{},
+	"getinfo":                struct{}{},
+	"getnettotals":           struct{}{},
+	"getnetworkhashps":       struct{}{},
+	"getnetworkinfo":         struct{}{},
+	"getpolicyinfo":          struct{}{},
+	"getrawmempool":          struct{}{},
+	"getrawtransaction":      struct{}{},
+	"getspendinginfo":        struct{}{},
+	"getsyncstatus":          struct{}{},
+	"gettxout":               struct{}{},
+	"gettxoutsetinfo":        struct{}{},
+	"getutxocommitment":      struct{}{},
+	"searchrawtransactions":  struct{}{},
+	"sendrawtransaction":     struct{}{},
+	"submitblock":            struct{}{},
+	"validateaddress":        struct{}{},
+	"verifymessage":          struct{}{},
+	"waitforblock":           struct{}{},
+	"waitforblockheight":     struct{}{},
+	"waitfornewblock":        struct{}{},
+}
+
+// Commands that are available on the read-only RPC listeners.  These only
+// query the state of the node and never change it, nor do they reveal
+// information about its peers.
+var rpcReadOnly = map[string]struct{}{
+	"createmultisig":         struct{}{},
+	"createrawtransaction":   struct{}{},
+	"decodepaymenturi":       struct{}{},
+	"decoderawtransaction":   struct{}{},
+	"decodescript":           struct{}{},
+	"getaddressbalance":      struct{}{},
+	"getaddressdeltas":       struct{}{},
+	"getaddressutxos":        struct{}{},
+	"getapischema":           struct{}{},
+	"getbestblock":           struct{}{},
+	"getbestblockhash":       struct{}{},
+	"getblock":               struct{}{},
+	"getblockchaininfo":      struct{}{},
+	"getblockcount":          struct{}{},
+	"getblockfilter":         struct{}{},
+	"getblockhash":           struct{}{},
+	"getblockheader":         struct{}{},
+	"getblockstats":          struct{}{},
+	"getchainstates":         struct{}{},
+	"getcoinageinfo":         struct{}{},
+	"getcurrentnet":          struct{}{},
+	"getdifficulty":          struct{}{},
+	"getdifficultyhistory":   struct{}{},
+	"getevictedtransactions": struct{}{},
+	"getmempoolinfo":         struct{}{},
+	"getnetworkhashps":       struct{}{},
+	"getrawmempool":          struct{}{},
+	"getrawtransaction":      struct{}{},
+	"getspendinginfo":        struct{}{},
+	"gettxout":               struct{}{},
+	"gettxoutsetinfo":        struct{}{},
+	"getutxocommitment":      struct{}{},
+	"help":                   struct{}{},
+	"searchrawtransactions":  struct{}{},
+	"validateaddress":        struct{}{},
+	"verifymessage":          struct{}{},
+	"waitforblock":           struct{}{},
+	"waitforblockheight":     struct{}{},
+	"waitfornewblock":        struct{}{},
 }
 
 // builderScript is a convenience function which is used for hard-coded scripts
@@ -339,6 +472,17 @@ type gbtWorkState struct {
 	template      *BlockTemplate
 	notifyMap     map[wire.ShaHash]map[int64]chan struct{}
 	timeSource    blockchain.MedianTimeSource
+
+	// coinbases caches the customized coinbases of the current template
+	// by the options they were customized with.  It is cleared whenever
+	// the coinbase of the template changes.
+	coinbases map[gbtCoinbaseKey]*btcjson.GetBlockTemplateResultTx
+}
+
+// gbtCoinbaseKey identifies the options a cached coinbase was customized with.
+type gbtCoinbaseKey struct {
+	flags    string
+	pkScript string
 }
 
 // newGbtWorkState returns a new instance of a gbtWorkState with all internal
@@ -352,19 +496,43 @@ func newGbtWorkState(timeSource blockchain.MedianTimeSource) *gbtWorkState {
 
 // handleUnimplemented is the handler for commands that should ultimately be
 // supported but are not yet implemented.
-func handleUnimplemented(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleUnimplemented(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	return nil, ErrRPCUnimplemented
 }
 
 // handleAskWallet is the handler for commands that are recognized as valid, but
 // are unable to answer correctly since it involves wallet state.
 // These commands will be implemented in btcwallet.
-func handleAskWallet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleAskWallet(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	return nil, ErrRPCNoWallet
 }
 
+// handleAddFilterEntry implements the addfilterentry command.
+func handleAddFilterEntry(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.AddFilterEntryCmd)
+
+	entry, err := parseTxBlacklistEntry(c.Entry, activeNetParams.Params)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid filter entry: " + err.Error(),
+		}
+	}
+	if !s.server.txBlacklist.Add(entry) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Entry is already in the transaction blacklist",
+		}
+	}
+
+	// Stop relaying the transactions which were accepted into the memory
+	// pool before they were banned.
+	s.server.txBlacklist.Evict(s.server.txMemPool)
+	return nil, nil
+}
+
 // handleAddNode handles addnode commands.
-func handleAddNode(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleAddNode(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.AddNodeCmd)
 
 	addr := normalizeAddress(c.Addr, activeNetParams.DefaultPort)
@@ -395,7 +563,7 @@ func handleAddNode(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 }
 
 // handleNode handles node commands.
-func handleNode(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleNode(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.NodeCmd)
 
 	var addr string
@@ -508,8 +676,261 @@ func messageToHex(msg wire.Message) (string, error) {
 	return hex.EncodeToString(buf.Bytes()), nil
 }
 
+// handleBackupChainState implements the backupchainstate command.
+func handleBackupChainState(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.BackupChainStateCmd)
+	if cfg.DbType == "memdb" {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "memdb does not support backups",
+		}
+	}
+
+	// The backup is named like the block database, so the destination
+	// directory can be used as the data directory of a node.
+	destDir := cleanAndExpandPath(c.DestDir)
+	if !filepath.IsAbs(destDir) {
+		destDir = filepath.Join(cfg.DataDir, destDir)
+	}
+	path := filepath.Join(destDir, filepath.Base(blockDbPath(cfg.DbType)))
+	if _, err := os.Stat(path); err == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: path + " already exists",
+		}
+	}
+	if err := os.MkdirAll(destDir, 0700); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Failed to create backup directory: " + err.Error(),
+		}
+	}
+
+	// Abort the backup when the server shuts down.
+	interrupt := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.quit:
+			close(interrupt)
+		case <-done:
+		}
+	}()
+
+	rpcsLog.Infof("Backing up the block database to %s", path)
+	sha, height, err := s.server.db.Backup(path, interrupt)
+	if err == database.ErrInterrupted {
+		return nil, ErrClientQuit
+	}
+	if err != nil {
+		context := "Failed to back up the block database"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	rpcsLog.Infof("Backed up the block database at block %v (height %d) "+
+		"to %s", sha, height, path)
+
+	return &btcjson.BackupChainStateResult{
+		Path:   path,
+		Hash:   sha.String(),
+		Height: height,
+	}, nil
+}
+
+// handleCompactDatabase implements the compactdatabase command.
+func handleCompactDatabase(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	if cfg.DbType == "memdb" {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "memdb does not support compaction",
+		}
+	}
+	if err := s.server.dbCompactor.Compact(nil); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: err.Error(),
+		}
+	}
+	return nil, nil
+}
+
+// networkFaultTarget returns the target of a network fault passed to the
+// setnetworkfault and clearnetworkfaults commands in canonical form.  The target
+// is either the id of a peer or its address, which is normalized to include
+// the default port of the network.
+func networkFaultTarget(target string) (string, error) {
+	if id, err := strconv.ParseUint(target, 10, 32); err == nil {
+		return strconv.FormatUint(id, 10), nil
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil || net.ParseIP(target) != nil {
+		return normalizeAddress(target, activeNetParams.DefaultPort), nil
+	}
+	return "", &btcjson.RPCError{
+		Code:    btcjson.ErrRPCInvalidParameter,
+		Message: "invalid address or node ID",
+	}
+}
+
+// simNetOnlyError returns an error stating the passed command is only available
+// on the simulation test network, or nil when the server is running on it.
+func simNetOnlyError(command string) error {
+	if cfg.SimNet {
+		return nil
+	}
+	return &btcjson.RPCError{
+		Code: btcjson.ErrRPCMisc,
+		Message: command + " is only available on the simulation test " +
+			"network",
+	}
+}
+
+// handleBroadcastAlert implements the broadcastalert command.
+func handleBroadcastAlert(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.BroadcastAlertCmd)
+	if c.Message == "" || len(c.Message) > maxAlertMessageLen {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Message must be between 1 and %d "+
+				"bytes long", maxAlertMessageLen),
+		}
+	}
+	severity := "info"
+	if c.Severity != nil {
+		severity = *c.Severity
+	}
+	if _, ok := alertSeverities[severity]; !ok {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Severity must be one of info, warning or " +
+				"critical, not " + severity,
+		}
+	}
+
+	ntfn := btcjson.NewAlertNtfn(c.Message, severity, time.Now().Unix())
+	marshalledJSON, err := marshalNotification(ntfn, newNtfnEventID())
+	if err != nil {
+		context := "Failed to marshal alert notification"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	rpcsLog.Infof("Broadcasting %s alert: %s", severity, c.Message)
+	result := &btcjson.BroadcastAlertResult{
+		Clients: s.ntfnMgr.NumClients(),
+	}
+	s.ntfnMgr.NotifyAlert(marshalledJSON)
+	if c.Webhooks == nil || *c.Webhooks {
+		result.Webhooks = len(cfg.RPCAlertWebhooks)
+		postAlertWebhooks(cfg.RPCAlertWebhooks, marshalledJSON)
+	}
+	return result, nil
+}
+
+// handleClearNetworkFaults implements the clearnetworkfaults command.
+func handleClearNetworkFaults(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.ClearNetworkFaultsCmd)
+
+	if err := simNetOnlyError("clearnetworkfaults"); err != nil {
+		return nil, err
+	}
+	var target string
+	if c.Target != nil {
+		var err error
+		target, err = networkFaultTarget(*c.Target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cleared := s.server.networkFaults.clear(target)
+	if target != "" && cleared == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "No network fault for " + target,
+		}
+	}
+	rpcsLog.Infof("Cleared network faults (%d removed)", cleared)
+	return nil, nil
+}
+
+// handleCreateMultisig handles createmultisig commands.
+func handleCreateMultisig(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.CreateMultisigCmd)
+
+	// Ensure the number of required signatures and provided keys are sane.
+	if c.NRequired < 1 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "A multisignature address must require at least one key to redeem",
+		}
+	}
+	if len(c.Keys) < c.NRequired {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Not enough keys supplied (got %d "+
+				"keys, but need at least %d to redeem)",
+				len(c.Keys), c.NRequired),
+		}
+	}
+	if len(c.Keys) > 16 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Number of keys involved in the multisignature address creation > 16",
+		}
+	}
+
+	// Decode each of the keys.  Only hex-encoded public keys are supported
+	// since there is no wallet available to look up the public key for an
+	// address.
+	pubKeys := make([]*coinutil.AddressPubKey, 0, len(c.Keys))
+	for _, key := range c.Keys {
+		addr, err := coinutil.DecodeAddress(key, s.server.chainParams)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid public key: " + key,
+			}
+		}
+		pubKey, ok := addr.(*coinutil.AddressPubKey)
+		if !ok {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Only hex-encoded public keys are " +
+					"supported: " + key,
+			}
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	// Create the redeem script and ensure it can actually be redeemed
+	// with a pay-to-script-hash input.
+	script, err := txscript.MultiSigScript(pubKeys, c.NRequired)
+	if err != nil {
+		context := "Failed to create multisig script"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if len(script) > txscript.MaxScriptElementSize {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Redeem script exceeds size limit: "+
+				"%d > %d", len(script),
+				txscript.MaxScriptElementSize),
+		}
+	}
+
+	address, err := coinutil.NewAddressScriptHash(script, s.server.chainParams)
+	if err != nil {
+		context := "Failed to convert script to pay-to-script-hash"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return btcjson.CreateMultiSigResult{
+		Address:      address.EncodeAddress(),
+		RedeemScript: hex.EncodeToString(script),
+	}, nil
+}
+
 // handleCreateRawTransaction handles createrawtransaction commands.
-func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleCreateRawTransaction(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.CreateRawTransactionCmd)
 
 	// Validate the locktime, if given.
@@ -540,9 +961,16 @@ func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 
 	// Add all transaction outputs to the transaction after performing
 	// some validity checks.
-	for encodedAddr, amount := range c.Amounts {
+	for encodedAddr, value := range c.Amounts {
+		// The data of the OP_RETURN output is added after the
+		// outputs paying to the addresses.
+		if encodedAddr == btcjson.TransactionOutputDataKey {
+			continue
+		}
+
 		// Ensure amount is in the valid range for monetary amounts.
-		if amount <= 0 || amount > coinutil.MaxSatoshi {
+		amount, ok := value.(float64)
+		if !ok || amount <= 0 || amount > coinutil.MaxSatoshi {
 			return nil, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCType,
 				Message: "Invalid amount",
@@ -550,7 +978,7 @@ func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 		}
 
 		// Decode the provided address.
-		addr, err := coinutil.DecodeAddress(encodedAddr,
+		addr, err := bech32.DecodeAddress(encodedAddr,
 			activeNetParams.Params)
 		if err != nil {
 			return nil, &btcjson.RPCError{
@@ -565,6 +993,8 @@ func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 		switch addr.(type) {
 		case *coinutil.AddressPubKeyHash:
 		case *coinutil.AddressScriptHash:
+		case *bech32.AddressWitnessPubKeyHash:
+		case *bech32.AddressWitnessScriptHash:
 		default:
 			return nil, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCInvalidAddressOrKey,
@@ -597,6 +1027,23 @@ func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 		mtx.AddTxOut(txOut)
 	}
 
+	// Add a provably-prunable output which carries the data, if given.
+	if value, ok := c.Amounts[btcjson.TransactionOutputDataKey]; ok {
+		hexStr, ok := value.(string)
+		if !ok {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCType,
+				Message: "Invalid data",
+			}
+		}
+		maxSize := s.server.txMemPool.cfg.Policy.MaxDataCarrierSize
+		txOut, err := createDataTxOut(hexStr, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		mtx.AddTxOut(txOut)
+	}
+
 	// Set the Locktime, if given.
 	if c.LockTime != nil {
 		mtx.LockTime = uint32(*c.LockTime)
@@ -613,8 +1060,35 @@ func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 	return mtxHex, nil
 }
 
+// createDataTxOut returns a zero-value transaction output with a
+// provably-prunable (OP_RETURN) public key script carrying the passed
+// hex-encoded data.  The data may not be larger than maxSize, the number of
+// bytes the standardness policy of the memory pool allows a nulldata output to
+// carry.  This is a helper for handleCreateRawTransaction.
+func createDataTxOut(hexStr string, maxSize int) (*wire.TxOut, error) {
+	data, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+	if len(data) > maxSize {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Data size of %d bytes exceeds "+
+				"the maximum of %d", len(data), maxSize),
+		}
+	}
+
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).
+		AddData(data).Script()
+	if err != nil {
+		context := "Failed to generate data script"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return wire.NewTxOut(0, pkScript), nil
+}
+
 // handleDebugLevel handles debuglevel commands.
-func handleDebugLevel(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleDebugLevel(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.DebugLevelCmd)
 
 	// Special show command to list supported subsystems.
@@ -623,6 +1097,18 @@ func handleDebugLevel(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 			supportedSubsystems()), nil
 	}
 
+	// Special clearpersisted command to forget the persisted levels.  The
+	// current levels are left untouched.
+	debugLevelsFile := filepath.Join(cfg.DataDir, debugLevelsFilename)
+	if c.LevelSpec == "clearpersisted" {
+		err := os.Remove(debugLevelsFile)
+		if err != nil && !os.IsNotExist(err) {
+			context := "Failed to remove persisted debug levels"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		return "Done.", nil
+	}
+
 	err := parseAndSetDebugLevels(c.LevelSpec)
 	if err != nil {
 		return nil, &btcjson.RPCError{
@@ -631,9 +1117,90 @@ func handleDebugLevel(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 		}
 	}
 
+	// Persist the levels so they survive a restart when requested.
+	if c.Persist != nil && *c.Persist {
+		err := persistDebugLevels(debugLevelsFile, c.LevelSpec)
+		if err != nil {
+			context := "Failed to persist debug levels"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	}
+
 	return "Done.", nil
 }
 
+// hexStackItems returns the hex encoding of each of the passed stack items.
+func hexStackItems(items [][]byte) []string {
+	hexItems := make([]string, 0, len(items))
+	for _, item := range items {
+		hexItems = append(hexItems, hex.EncodeToString(item))
+	}
+	return hexItems
+}
+
+// handleDebugScript handles debugscript commands.
+func handleDebugScript(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.DebugScriptCmd)
+
+	// Convert the hex scripts to bytes.
+	scripts := make([][]byte, 0, 2)
+	for _, hexStr := range []string{c.SigScript, c.PkScript} {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		script, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		scripts = append(scripts, script)
+	}
+	sigScript, pkScript := scripts[0], scripts[1]
+
+	// The scripts are executed as the only input of a transaction which
+	// spends the only output of another transaction paying to the public
+	// key script, so signatures are checked against that transaction.
+	creditTx := wire.NewMsgTx()
+	creditTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&wire.ShaHash{},
+		wire.MaxPrevOutIndex), []byte{txscript.OP_0, txscript.OP_0}))
+	creditTx.AddTxOut(wire.NewTxOut(0, pkScript))
+	creditHash := creditTx.TxSha()
+	spendTx := wire.NewMsgTx()
+	spendTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&creditHash, 0),
+		sigScript))
+	spendTx.AddTxOut(wire.NewTxOut(0, nil))
+
+	// Only the consensus rules apply unless the standard verification
+	// flags were requested.
+	flags := txscript.ScriptBip16
+	if *c.Standard {
+		flags = txscript.StandardVerifyFlags
+	}
+
+	result := btcjson.DebugScriptResult{
+		Trace: make([]btcjson.DebugScriptStep, 0),
+	}
+	hook := func(step *txscript.StepInfo) {
+		result.Trace = append(result.Trace, btcjson.DebugScriptStep{
+			Script:   step.ScriptIndex,
+			Offset:   step.OpcodeIndex,
+			Opcode:   step.Opcode,
+			Stack:    hexStackItems(step.Stack),
+			AltStack: hexStackItems(step.AltStack),
+		})
+	}
+	vm, err := txscript.NewDebugEngine(pkScript, spendTx, 0, flags, nil,
+		0, hook)
+	if err == nil {
+		err = vm.Execute()
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return &result, nil
+	}
+	result.Valid = true
+	return &result, nil
+}
+
 // createVinList returns a slice of JSON objects for the inputs of the passed
 // transaction.
 func createVinList(mtx *wire.MsgTx) []btcjson.Vin {
@@ -660,6 +1227,13 @@ func createVinList(mtx *wire.MsgTx) []btcjson.Vin {
 			Asm: disbuf,
 			Hex: hex.EncodeToString(txIn.SignatureScript),
 		}
+		if len(txIn.Witness) != 0 {
+			vinEntry.Witness = make([]string, 0, len(txIn.Witness))
+			for _, item := range txIn.Witness {
+				vinEntry.Witness = append(vinEntry.Witness,
+					hex.EncodeToString(item))
+			}
+		}
 	}
 
 	return vinList
@@ -863,9 +1437,13 @@ func createTxRawResult(chainParams *chaincfg.Params, mtx *wire.MsgTx,
 		return nil, err
 	}
 
+	witnessHash := mtx.WitnessHash()
 	txReply := &btcjson.TxRawResult{
 		Hex:      mtxHex,
 		Txid:     txHash,
+		Hash:     witnessHash.String(),
+		Size:     int32(mtx.SerializeSize()),
+		Vsize:    int32(blockchain.GetTxVirtualSize(coinutil.NewTx(mtx))),
 		Vout:     createVoutList(mtx, chainParams, nil),
 		Vin:      createVinList(mtx),
 		Version:  mtx.Version,
@@ -883,8 +1461,29 @@ func createTxRawResult(chainParams *chaincfg.Params, mtx *wire.MsgTx,
 	return txReply, nil
 }
 
+// handleDecodePaymentURI handles decodepaymenturi commands.
+func handleDecodePaymentURI(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.DecodePaymentURICmd)
+
+	uri, err := paymenturi.Decode(c.URI, s.server.chainParams)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid payment URI: " + err.Error(),
+		}
+	}
+
+	return btcjson.DecodePaymentURIResult{
+		Address: uri.Address.EncodeAddress(),
+		Amount:  uri.Amount.ToBTC(),
+		Label:   uri.Label,
+		Message: uri.Message,
+		Params:  uri.Params,
+	}, nil
+}
+
 // handleDecodeRawTransaction handles decoderawtransaction commands.
-func handleDecodeRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleDecodeRawTransaction(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.DecodeRawTransactionCmd)
 
 	// Deserialize the transaction.
@@ -917,7 +1516,7 @@ func handleDecodeRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 }
 
 // handleDecodeScript handles decodescript commands.
-func handleDecodeScript(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleDecodeScript(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.DecodeScriptCmd)
 
 	// Convert the hex script to bytes.
@@ -962,36 +1561,465 @@ func handleDecodeScript(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	return reply, nil
 }
 
-// handleGenerate handles generate commands.
-func handleGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Respond with an error if there are no addresses to pay the
-	// created blocks to.
-	if len(cfg.miningAddrs) == 0 {
-		return nil, &btcjson.RPCError{
-			Code: btcjson.ErrRPCInternal.Code,
-			Message: "No payment addresses specified " +
-				"via --miningaddr",
-		}
-	}
+const (
+	// unsignedTxInSize is the serialized size of a transaction input with
+	// an empty signature script.  It consists of the 32-byte previous
+	// output hash, the 4-byte previous output index, a single byte for
+	// the signature script length, and the 4-byte sequence number.
+	unsignedTxInSize = 32 + 4 + 1 + 4
+
+	// estP2PKHTxInSize is the estimated serialized size of a transaction
+	// input which redeems a pay-to-pubkey-hash output.  The signature
+	// script consists of a push of a maximum size DER signature with the
+	// hash type byte appended (73 bytes) followed by a push of a
+	// compressed public key (33 bytes).
+	estP2PKHTxInSize = unsignedTxInSize + 1 + 73 + 1 + 33
+
+	// estChangeTxOutSize is the estimated serialized size of a change
+	// output paying to a pay-to-pubkey-hash address.  It consists of the
+	// 8-byte value, a single byte for the script length, and the 25-byte
+	// script.
+	estChangeTxOutSize = 8 + 1 + 25
+)
 
-	c := cmd.(*btcjson.GenerateCmd)
+// fundingUtxo houses an unspent transaction output which is a candidate to
+// fund a transaction via the fundrawtransaction command.
+type fundingUtxo struct {
+	outPoint wire.OutPoint
+	amount   int64
+}
 
-	// Respond with an error if the client is requesting 0 blocks to be generated.
-	if c.NumBlocks == 0 {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInternal.Code,
-			Message: "Please request a nonzero number of blocks to generate.",
+// fetchAddrIndexUtxos returns the confirmed unspent outputs which pay to the
+// passed addresses by looking up their transactions in the address index.
+// Failing lookups are returned as database errors rather than skipped so an
+// incomplete result is never mistaken for addresses without unspent outputs.
+// Addresses of a type the index does not support are reported as invalid.
+func fetchAddrIndexUtxos(s *rpcServer, addrs []coinutil.Address) ([]fundingUtxo, error) {
+	var utxos []fundingUtxo
+	for _, addr := range addrs {
+		encodedAddr := addr.EncodeAddress()
+		txReplies, _, err := s.server.db.FetchTxsForAddr(addr, 0,
+			math.MaxInt32, false)
+		if err == database.ErrUnsupportedAddressType {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Address type is not supported by the " +
+					"address index: " + encodedAddr,
+			}
+		}
+		if err != nil {
+			rpcsLog.Errorf("Error looking up transactions of "+
+				"address %s: %v", encodedAddr, err)
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDatabase,
+				Message: "Database error: " + err.Error(),
+			}
+		}
+		for _, txReply := range txReplies {
+			// The address index does not track which outputs are
+			// spent, so look up the spend information for the
+			// transaction directly.
+			txList, err := s.server.db.FetchTxBySha(txReply.Sha)
+			if err == nil && len(txList) == 0 {
+				err = fmt.Errorf("indexed transaction %v not "+
+					"found", txReply.Sha)
+			}
+			if err != nil {
+				rpcsLog.Errorf("Error looking up transaction %v: %v",
+					txReply.Sha, err)
+				return nil, &btcjson.RPCError{
+					Code:    btcjson.ErrRPCDatabase,
+					Message: "Database error: " + err.Error(),
+				}
+			}
+			lastTx := txList[len(txList)-1]
+			for i, txOut := range lastTx.Tx.TxOut {
+				if i < len(lastTx.TxSpent) && lastTx.TxSpent[i] {
+					continue
+				}
+				_, outAddrs, _, _ := txscript.ExtractPkScriptAddrs(
+					txOut.PkScript, s.server.chainParams)
+				if len(outAddrs) != 1 ||
+					outAddrs[0].EncodeAddress() != encodedAddr {
+					continue
+				}
+				utxos = append(utxos, fundingUtxo{
+					outPoint: *wire.NewOutPoint(txReply.Sha,
+						uint32(i)),
+					amount: txOut.Value,
+				})
+			}
 		}
 	}
+	return utxos, nil
+}
 
-	// Create a reply
-	reply := make([]string, c.NumBlocks)
-
-	blockHashes, err := s.server.cpuMiner.GenerateNBlocks(c.NumBlocks)
-	if err != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInternal.Code,
-			Message: err.Error(),
+// estimateFundedTxSize returns the estimated serialized size of the passed
+// transaction once all of its inputs which do not yet have a signature script
+// are signed.  Every such input is assumed to redeem a pay-to-pubkey-hash
+// output.
+func estimateFundedTxSize(mtx *wire.MsgTx) int {
+	size := mtx.SerializeSize()
+	for _, txIn := range mtx.TxIn {
+		if len(txIn.SignatureScript) == 0 {
+			size += estP2PKHTxInSize - unsignedTxInSize
+		}
+	}
+	return size
+}
+
+// utxoSnapshotPath returns the full path of a UTXO snapshot file.  Relative
+// paths are interpreted relative to the data directory.
+func utxoSnapshotPath(path string) string {
+	path = cleanAndExpandPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.DataDir, path)
+	}
+	return path
+}
+
+// handleDumpUtxoSet implements the dumputxoset command.
+//
+// The snapshot is first written to a temporary file which is only renamed to
+// the requested path once it is complete, so a partially written snapshot is
+// never mistaken for a valid one.
+func handleDumpUtxoSet(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.DumpUtxoSetCmd)
+	path := utxoSnapshotPath(c.Path)
+	if _, err := os.Stat(path); err == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: path + " already exists",
+		}
+	}
+
+	_, height, err := s.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get best block"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	coins, baseHash, err := buildUtxoSet(s.server.db, height)
+	if err != nil {
+		context := "Failed to build UTXO set"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	tmpPath := path + ".incomplete"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Failed to create snapshot file: " + err.Error(),
+		}
+	}
+	header := &utxoSnapshotHeader{
+		net:        activeNetParams.Net,
+		baseHash:   *baseHash,
+		baseHeight: height,
+		numCoins:   uint64(len(coins)),
+	}
+	w := bufio.NewWriter(f)
+	setHash, err := writeUtxoSnapshot(w, header, coins)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		context := "Failed to write UTXO snapshot"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	rpcsLog.Infof("Wrote UTXO snapshot of %d coins at height %d to %s",
+		len(coins), height, path)
+
+	return &btcjson.DumpUtxoSetResult{
+		CoinsWritten: int64(len(coins)),
+		BaseHash:     baseHash.String(),
+		BaseHeight:   height,
+		Path:         path,
+		TxOutSetHash: setHash.String(),
+	}, nil
+}
+
+// handleFindOrphanChains implements the findorphanchains command.
+func handleFindOrphanChains(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	chains := s.server.blockManager.blockChain.OrphanChains()
+	results := make([]btcjson.FindOrphanChainsResult, 0, len(chains))
+	for _, chain := range chains {
+		results = append(results, btcjson.FindOrphanChainsResult{
+			RootPrevHash: chain.RootPrevHash.String(),
+			Length:       chain.Length,
+			Blocks:       chain.NumBlocks,
+			Size:         chain.TotalSize,
+			TipHash:      chain.TipHash.String(),
+		})
+	}
+	return results, nil
+}
+
+// handleFundRawTransaction handles fundrawtransaction commands.
+//
+// Since btcd does not have a wallet, the outputs used to fund the transaction
+// are selected from a list of unspent outputs provided by the caller, or, when
+// the address index is enabled, from the confirmed unspent outputs of the
+// provided addresses.  The required fee is calculated from the provided fee
+// rate, or the minimum relay fee rate when one is not provided.
+func handleFundRawTransaction(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.FundRawTransactionCmd)
+
+	// Deserialize the transaction to fund.
+	hexStr := c.HexTx
+	if len(hexStr)%2 != 0 {
+		hexStr = "0" + hexStr
+	}
+	serializedTx, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+	var mtx wire.MsgTx
+	err = mtx.Deserialize(bytes.NewReader(serializedTx))
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "TX decode failed: " + err.Error(),
+		}
+	}
+	if len(mtx.TxOut) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Transaction must have at least one output",
+		}
+	}
+
+	// Decode the change address and ensure it is for the active network.
+	params := s.server.chainParams
+	changeAddr, err := bech32.DecodeAddress(c.Options.ChangeAddress, params)
+	if err != nil || !changeAddr.IsForNet(params) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid change address: " +
+				c.Options.ChangeAddress,
+		}
+	}
+	changeScript, err := txscript.PayToAddrScript(changeAddr)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Unsupported change address type: " +
+				c.Options.ChangeAddress,
+		}
+	}
+
+	feeRate := cfg.minRelayTxFee
+	if c.Options.FeeRate != nil {
+		feeRate, err = coinutil.NewAmount(*c.Options.FeeRate)
+		if err != nil || feeRate < 0 {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid fee rate",
+			}
+		}
+	}
+
+	// Gather the candidate outputs to fund the transaction with.
+	var candidates []fundingUtxo
+	switch {
+	case len(c.Options.Utxos) > 0:
+		candidates = make([]fundingUtxo, 0, len(c.Options.Utxos))
+		for _, utxo := range c.Options.Utxos {
+			txHash, err := wire.NewShaHashFromStr(utxo.Txid)
+			if err != nil {
+				return nil, rpcDecodeHexError(utxo.Txid)
+			}
+			amount, err := coinutil.NewAmount(utxo.Amount)
+			if err != nil || amount <= 0 {
+				return nil, &btcjson.RPCError{
+					Code:    btcjson.ErrRPCType,
+					Message: "Invalid amount",
+				}
+			}
+			candidates = append(candidates, fundingUtxo{
+				outPoint: *wire.NewOutPoint(txHash, utxo.Vout),
+				amount:   int64(amount),
+			})
+		}
+
+	case len(c.Options.Addresses) > 0:
+		if !cfg.AddrIndex {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCMisc,
+				Message: "Address index must be enabled (--addrindex)",
+			}
+		}
+		if !s.server.indexManager.IsCaughtUp(s.server.addrIndexer) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCMisc,
+				Message: "Address index has not yet caught up " +
+					"to the current best height",
+			}
+		}
+		addrs := make([]coinutil.Address, 0, len(c.Options.Addresses))
+		for _, encodedAddr := range c.Options.Addresses {
+			addr, err := bech32.DecodeAddress(encodedAddr, params)
+			if err != nil {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidAddressOrKey,
+					Message: "Invalid address or key: " +
+						err.Error(),
+				}
+			}
+			addrs = append(addrs, addr)
+		}
+		candidates, err = fetchAddrIndexUtxos(s, addrs)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Either utxos or addresses must be provided " +
+				"to fund the transaction",
+		}
+	}
+
+	// Tally the value of the outputs and any inputs the transaction
+	// already has.
+	var outputTotal, inputTotal int64
+	for _, txOut := range mtx.TxOut {
+		outputTotal += txOut.Value
+	}
+	usedOutPoints := make(map[wire.OutPoint]struct{}, len(mtx.TxIn))
+	for _, txIn := range mtx.TxIn {
+		prevOut, err := fetchPrevOut(s, &txIn.PreviousOutPoint)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCNoTxInfo,
+				Message: "Unable to find input: " + err.Error(),
+			}
+		}
+		inputTotal += prevOut.Value
+		usedOutPoints[txIn.PreviousOutPoint] = struct{}{}
+	}
+
+	// Select the largest candidates first to keep the number of inputs,
+	// and therefore the fee, low.
+	sort.Sort(sort.Reverse(fundingUtxosByAmount(candidates)))
+
+	// Add inputs until they cover the outputs plus the fee required for
+	// the transaction including a change output.
+	changeOut := wire.NewTxOut(0, changeScript)
+	var fee int64
+	for i := 0; ; i++ {
+		size := estimateFundedTxSize(&mtx) + estChangeTxOutSize
+		fee = calcMinRequiredTxRelayFee(int64(size), feeRate)
+		if inputTotal >= outputTotal+fee {
+			break
+		}
+		if i >= len(candidates) {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCWalletInsufficientFunds,
+				Message: "Insufficient funds",
+			}
+		}
+
+		// Skip outputs which are already spent by the transaction
+		// itself or by a transaction in the memory pool.
+		utxo := &candidates[i]
+		if _, ok := usedOutPoints[utxo.outPoint]; ok {
+			continue
+		}
+		if s.server.txMemPool.CheckSpend(utxo.outPoint) != nil {
+			continue
+		}
+		usedOutPoints[utxo.outPoint] = struct{}{}
+		mtx.AddTxIn(wire.NewTxIn(&utxo.outPoint, nil))
+		inputTotal += utxo.amount
+	}
+
+	// Add the change output unless it would be dust, in which case the
+	// remaining value is added to the fee instead.
+	changePos := -1
+	changeOut.Value = inputTotal - outputTotal - fee
+	if changeOut.Value > 0 && !isDust(changeOut, cfg.minRelayTxFee) {
+		mtx.AddTxOut(changeOut)
+		changePos = len(mtx.TxOut) - 1
+	} else {
+		fee = inputTotal - outputTotal
+	}
+
+	mtxHex, err := messageToHex(&mtx)
+	if err != nil {
+		return nil, err
+	}
+	return &btcjson.FundRawTransactionResult{
+		Hex:       mtxHex,
+		Fee:       coinutil.Amount(fee).ToBTC(),
+		ChangePos: changePos,
+	}, nil
+}
+
+// fundingUtxosByAmount provides a sort.Interface for a slice of funding
+// outputs which sorts them by amount.
+type fundingUtxosByAmount []fundingUtxo
+
+// Len returns the number of funding outputs in the slice.  It is part of the
+// sort.Interface implementation.
+func (s fundingUtxosByAmount) Len() int {
+	return len(s)
+}
+
+// Swap swaps the funding outputs at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s fundingUtxosByAmount) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the funding output with index i has a smaller amount
+// than the one with index j.  It is part of the sort.Interface implementation.
+func (s fundingUtxosByAmount) Less(i, j int) bool {
+	return s[i].amount < s[j].amount
+}
+
+// handleGenerate handles generate commands.
+func handleGenerate(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	// Respond with an error if there are no addresses to pay the
+	// created blocks to.
+	if len(cfg.miningAddrs) == 0 {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInternal.Code,
+			Message: "No payment addresses specified " +
+				"via --miningaddr",
+		}
+	}
+
+	c := cmd.(*btcjson.GenerateCmd)
+
+	// Respond with an error if the client is requesting 0 blocks to be generated.
+	if c.NumBlocks == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInternal.Code,
+			Message: "Please request a nonzero number of blocks to generate.",
+		}
+	}
+
+	// Create a reply
+	reply := make([]string, c.NumBlocks)
+
+	blockHashes, err := s.server.cpuMiner.GenerateNBlocks(c.NumBlocks, nil)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInternal.Code,
+			Message: err.Error(),
 		}
 	}
 
@@ -1004,8 +2032,53 @@ func handleGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return reply, nil
 }
 
+// handleGenerateToDescriptor handles generatetodescriptor commands.
+func handleGenerateToDescriptor(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GenerateToDescriptorCmd)
+
+	// Paying the coinbase to arbitrary scripts is only meant for tests of
+	// the consensus rules, so it is limited to the test networks blocks
+	// can be generated on at will.
+	if !cfg.RegressionTest && !cfg.SimNet {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "generatetodescriptor is only available on the " +
+				"regression and simulation test networks",
+		}
+	}
+	if c.NumBlocks == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Please request a nonzero number of blocks to generate.",
+		}
+	}
+	pkScript, err := parseOutputDescriptor(c.Descriptor,
+		activeNetParams.Params)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid descriptor: " + err.Error(),
+		}
+	}
+
+	blockHashes, err := s.server.cpuMiner.GenerateNBlocks(c.NumBlocks,
+		pkScript)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInternal.Code,
+			Message: err.Error(),
+		}
+	}
+
+	reply := make([]string, len(blockHashes))
+	for i, hash := range blockHashes {
+		reply[i] = hash.String()
+	}
+	return reply, nil
+}
+
 // handleGetAddedNodeInfo handles getaddednodeinfo commands.
-func handleGetAddedNodeInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetAddedNodeInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetAddedNodeInfoCmd)
 
 	// Retrieve a list of persistent (added) peers from the bitcoin server
@@ -1087,8 +2160,313 @@ func handleGetAddedNodeInfo(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	return results, nil
 }
 
+// addrBalanceIndexAddrs ensures the address balance index is able to serve
+// requests and decodes the passed addresses, skipping duplicates.
+func addrBalanceIndexAddrs(s *rpcServer, addrStrs []string) ([]coinutil.Address, error) {
+	if !cfg.AddrBalanceIndex {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "Address balance index must be enabled " +
+				"(--addrbalanceindex)",
+		}
+	}
+	if !s.server.indexManager.IsCaughtUp(s.server.addrBalanceIndexer) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "Address balance index has not yet caught up " +
+				"to the current best height",
+		}
+	}
+	if len(addrStrs) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "No addresses specified",
+		}
+	}
+
+	addrs := make([]coinutil.Address, 0, len(addrStrs))
+	seen := make(map[string]struct{}, len(addrStrs))
+	for _, addrStr := range addrStrs {
+		addr, err := coinutil.DecodeAddress(addrStr, s.server.chainParams)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid address or key: " + err.Error(),
+			}
+		}
+		switch addr.(type) {
+		case *coinutil.AddressPubKeyHash, *coinutil.AddressScriptHash,
+			*coinutil.AddressPubKey:
+		default:
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Address type is not supported by the " +
+					"address balance index: " + addrStr,
+			}
+		}
+
+		encoded := addr.EncodeAddress()
+		if _, ok := seen[encoded]; ok {
+			continue
+		}
+		seen[encoded] = struct{}{}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// handleGetAddressBalance implements the getaddressbalance command.
+func handleGetAddressBalance(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressBalanceCmd)
+	addrs, err := addrBalanceIndexAddrs(s, c.Request.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetAddressBalanceResult
+	for _, addr := range addrs {
+		balance, err := s.server.db.FetchAddrBalance(addr)
+		if err != nil {
+			context := "Failed to fetch address balance"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		result.Balance += balance.Balance
+		result.Received += balance.Received
+	}
+	return &result, nil
+}
+
+// addrDeltasByHeight provides a sort.Interface for a slice of address delta
+// results which sorts them by the order of their transactions in the chain.
+type addrDeltasByHeight []btcjson.AddressDeltaResult
+
+// Len returns the number of deltas in the slice.  It is part of the
+// sort.Interface implementation.
+func (s addrDeltasByHeight) Len() int {
+	return len(s)
+}
+
+// Swap swaps the deltas at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s addrDeltasByHeight) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the delta with index i is in a transaction which comes
+// before the one of the delta with index j in the chain.  It is part of the
+// sort.Interface implementation.
+func (s addrDeltasByHeight) Less(i, j int) bool {
+	if s[i].Height != s[j].Height {
+		return s[i].Height < s[j].Height
+	}
+	return s[i].BlockIndex < s[j].BlockIndex
+}
+
+// handleGetAddressDeltas implements the getaddressdeltas command.
+func handleGetAddressDeltas(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressDeltasCmd)
+	addrs, err := addrBalanceIndexAddrs(s, c.Request.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	// The range is open at either end when no height is given.
+	start, end := c.Request.Start, c.Request.End
+	if start < 0 || end < 0 || (end > 0 && end < start) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid start or end height",
+		}
+	}
+	if end == 0 {
+		_, end, err = s.server.db.FetchAddrBalanceIndexTip()
+		if err != nil && err != database.ErrAddrBalanceIndexDoesNotExist {
+			context := "Failed to fetch address balance index tip"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	}
+
+	result := make([]btcjson.AddressDeltaResult, 0)
+	for _, addr := range addrs {
+		deltas, err := s.server.db.FetchAddrDeltas(addr, start, end)
+		if err != nil {
+			context := "Failed to fetch address deltas"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		encoded := addr.EncodeAddress()
+		for _, delta := range deltas {
+			result = append(result, btcjson.AddressDeltaResult{
+				Satoshis:   delta.Amount,
+				TxID:       delta.TxSha.String(),
+				Index:      delta.Index,
+				BlockIndex: delta.BlockIndex,
+				Height:     delta.Height,
+				Address:    encoded,
+			})
+		}
+	}
+
+	// The deltas of each address are already in chain order, so a stable
+	// sort keeps the inputs of a transaction before its outputs.
+	sort.Stable(addrDeltasByHeight(result))
+	return result, nil
+}
+
+// addrUtxosByHeight provides a sort.Interface for a slice of address unspent
+// output results which sorts them by the height of the block which created
+// them.
+type addrUtxosByHeight []btcjson.AddressUtxoResult
+
+// Len returns the number of unspent outputs in the slice.  It is part of the
+// sort.Interface implementation.
+func (s addrUtxosByHeight) Len() int {
+	return len(s)
+}
+
+// Swap swaps the unspent outputs at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s addrUtxosByHeight) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the unspent output with index i was created in a block
+// before the one with index j.  It is part of the sort.Interface
+// implementation.
+func (s addrUtxosByHeight) Less(i, j int) bool {
+	return s[i].Height < s[j].Height
+}
+
+// handleGetAddressUtxos implements the getaddressutxos command.
+func handleGetAddressUtxos(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressUtxosCmd)
+	addrs, err := addrBalanceIndexAddrs(s, c.Request.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]btcjson.AddressUtxoResult, 0)
+	for _, addr := range addrs {
+		utxos, err := s.server.db.FetchAddrUtxos(addr)
+		if err != nil {
+			context := "Failed to fetch address unspent outputs"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		encoded := addr.EncodeAddress()
+		for _, utxo := range utxos {
+			result = append(result, btcjson.AddressUtxoResult{
+				Address:     encoded,
+				TxID:        utxo.OutPoint.Hash.String(),
+				OutputIndex: utxo.OutPoint.Index,
+				Script:      hex.EncodeToString(utxo.PkScript),
+				Satoshis:    utxo.Value,
+				Height:      utxo.Height,
+			})
+		}
+	}
+	sort.Stable(addrUtxosByHeight(result))
+	return result, nil
+}
+
+// handleGetAllSubscriptions implements the getallsubscriptions command.
+func handleGetAllSubscriptions(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	subs := s.ntfnMgr.Subscriptions(nil)
+	results := make([]btcjson.ClientSubscriptionsResult, 0, len(subs))
+	for _, sub := range subs {
+		var ntfns []string
+		if sub.blocks {
+			ntfns = append(ntfns, "blocks")
+		}
+		if sub.headers {
+			ntfns = append(ntfns, "blockheaders")
+		}
+		if s.templateNtfn.Subscribed(sub.wsc) {
+			ntfns = append(ntfns, "blocktemplate")
+		}
+		if s.rawBlockNtfn.Subscribed(sub.wsc) {
+			ntfns = append(ntfns, "rawblocks")
+		}
+		if sub.clockSkew {
+			ntfns = append(ntfns, "clockskew")
+		}
+		if sub.peerEvents {
+			ntfns = append(ntfns, "peerevents")
+		}
+		if sub.newTxs {
+			ntfns = append(ntfns, "newtransactions")
+		}
+		if ntfns == nil {
+			ntfns = []string{}
+		}
+
+		filters := sub.wsc.loadedFilters()
+		var filterEntries int
+		for _, f := range filters {
+			addrs, outPoints := f.size()
+			filterEntries += addrs + outPoints
+		}
+
+		results = append(results, btcjson.ClientSubscriptionsResult{
+			Addr:          sub.wsc.addr,
+			SessionID:     sub.wsc.sessionID,
+			Notifications: ntfns,
+			Addresses:     len(sub.addrs),
+			OutPoints:     len(sub.outPoints),
+			ScriptHashes:  len(sub.scriptHashes),
+			Filters:       len(filters),
+			FilterEntries: filterEntries,
+		})
+	}
+	sort.Sort(clientSubsByEntries(results))
+	return results, nil
+}
+
+// clientSubsByEntries provides a sort.Interface for a slice of client
+// subscription results which sorts them by the number of addresses,
+// outpoints, script hashes and filter entries the clients watch, from the
+// most to the fewest.  Clients watching the same number are sorted by their
+// addresses.
+type clientSubsByEntries []btcjson.ClientSubscriptionsResult
+
+// entries returns the number of addresses, outpoints, script hashes and
+// filter entries watched by the client with the passed index.
+func (s clientSubsByEntries) entries(i int) int {
+	return s[i].Addresses + s[i].OutPoints + s[i].ScriptHashes +
+		s[i].FilterEntries
+}
+
+// Len returns the number of clients in the slice.  It is part of the
+// sort.Interface implementation.
+func (s clientSubsByEntries) Len() int {
+	return len(s)
+}
+
+// Swap swaps the clients at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s clientSubsByEntries) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the client with index i watches more entries than the
+// client with index j.  It is part of the sort.Interface implementation.
+func (s clientSubsByEntries) Less(i, j int) bool {
+	if ei, ej := s.entries(i), s.entries(j); ei != ej {
+		return ei > ej
+	}
+	return s[i].Addr < s[j].Addr
+}
+
+// handleGetAPISchema implements the getapischema command.
+func handleGetAPISchema(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	apiSchema, err := s.helpCacher.rpcAPISchema()
+	if err != nil {
+		context := "Failed to generate API schema"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return apiSchema, nil
+}
+
 // handleGetBestBlock implements the getbestblock command.
-func handleGetBestBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBestBlock(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	// All other "get block" commands give either the height, the
 	// hash, or both but require the block SHA.  This gets both for
 	// the best block.
@@ -1108,7 +2486,7 @@ func handleGetBestBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 }
 
 // handleGetBestBlockHash implements the getbestblockhash command.
-func handleGetBestBlockHash(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBestBlockHash(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	sha, _, err := s.server.db.NewestSha()
 	if err != nil {
 		rpcsLog.Errorf("Error getting newest sha: %v", err)
@@ -1142,7 +2520,7 @@ func getDifficultyRatio(bits uint32) float64 {
 }
 
 // handleGetBlock implements the getblock command.
-func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBlock(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetBlockCmd)
 
 	sha, err := wire.NewShaHashFromStr(c.Hash)
@@ -1185,6 +2563,13 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	}
 
 	blockHeader := &blk.MsgBlock().Header
+	medianTime, err := blockchain.PastMedianTime(blockHeader, idx,
+		s.server.db.FetchBlockHeaderBySha)
+	if err != nil {
+		context := "Failed to calculate median time"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
 	blockReply := btcjson.GetBlockVerboseResult{
 		Hash:          c.Hash,
 		Version:       blockHeader.Version,
@@ -1192,6 +2577,7 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 		PreviousHash:  blockHeader.PrevBlock.String(),
 		Nonce:         blockHeader.Nonce,
 		Time:          blockHeader.Timestamp.Unix(),
+		MedianTime:    medianTime.Unix(),
 		Confirmations: uint64(1 + maxIdx - idx),
 		Height:        int64(idx),
 		Size:          int32(len(buf)),
@@ -1211,6 +2597,11 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 		txns := blk.Transactions()
 		rawTxns := make([]btcjson.TxRawResult, len(txns))
 		for i, tx := range txns {
+			// Decoding every transaction of a large block takes a
+			// while, so stop once the client is gone.
+			if ctx.Err() != nil {
+				return nil, ErrClientQuit
+			}
 			rawTxn, err := createTxRawResult(s.server.chainParams,
 				tx.MsgTx(), tx.Sha().String(), blockHeader,
 				sha.String(), idx, maxIdx)
@@ -1236,8 +2627,95 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return blockReply, nil
 }
 
+// handleGetBlockChainInfo implements the getblockchaininfo command.
+func handleGetBlockChainInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	sha, height, err := s.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get newest hash"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	blkHeader, err := s.server.db.FetchBlockHeaderBySha(sha)
+	if err != nil {
+		context := "Failed to get block header"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	medianTime, err := blockchain.PastMedianTime(blkHeader, height,
+		s.server.db.FetchBlockHeaderBySha)
+	if err != nil {
+		context := "Failed to calculate median time"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	chainWork, err := s.chainWork.ChainWork(height)
+	if err != nil {
+		context := "Failed to calculate chain work"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	// Report the state of each rule change deployment for the next block.
+	deploymentStates, err := s.server.blockManager.chainState.DeploymentStates()
+	if err != nil {
+		context := "Failed to obtain deployment states"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	softForks := make(map[string]*btcjson.Bip9SoftForkDescription,
+		len(deploymentStates))
+	for id, state := range deploymentStates {
+		deployment := &s.server.chainParams.Deployments[id]
+		softForks[chaincfg.DeploymentNames[id]] = &btcjson.Bip9SoftForkDescription{
+			Status:    thresholdStateName(state),
+			Bit:       deployment.BitNumber,
+			StartTime: int64(deployment.StartTime),
+			Timeout:   int64(deployment.ExpireTime),
+		}
+	}
+
+	// Estimate the verification progress from the height of the sync peer
+	// and the age of the best block.
+	bm := s.server.blockManager
+	status := bm.SyncStatus()
+	tipAge := s.server.timeSource.AdjustedTime().Sub(blkHeader.Timestamp)
+
+	// Headers are not downloaded ahead of their blocks, so the best known
+	// header is always the best block.
+	return &btcjson.GetBlockChainInfoResult{
+		Chain:         s.server.chainParams.Name,
+		Blocks:        height,
+		Headers:       height,
+		BestBlockHash: sha.String(),
+		Difficulty:    getDifficultyRatio(blkHeader.Bits),
+		MedianTime:    medianTime.Unix(),
+		VerificationProgress: verificationProgress(height,
+			status.syncPeerHeight, tipAge),
+		InitialBlockDownload: bm.IsInitialBlockDownload(),
+		ChainWork:            fmt.Sprintf("%064x", chainWork),
+		TimeOffset:           int64(s.server.timeSource.Offset().Seconds()),
+		Warnings:             s.server.clockSkewWarning(),
+		Bip9SoftForks:        softForks,
+	}, nil
+}
+
+// thresholdStateName returns the name a rule change deployment state is
+// reported by over RPC.
+func thresholdStateName(state blockchain.ThresholdState) string {
+	switch state {
+	case blockchain.ThresholdDefined:
+		return "defined"
+	case blockchain.ThresholdStarted:
+		return "started"
+	case blockchain.ThresholdLockedIn:
+		return "lockedin"
+	case blockchain.ThresholdActive:
+		return "active"
+	case blockchain.ThresholdFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
 // handleGetBlockCount implements the getblockcount command.
-func handleGetBlockCount(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBlockCount(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	_, maxIdx, err := s.server.db.NewestSha()
 	if err != nil {
 		rpcsLog.Errorf("Error getting newest sha: %v", err)
@@ -1250,8 +2728,55 @@ func handleGetBlockCount(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	return maxIdx, nil
 }
 
+// handleGetBlockFilter implements the getblockfilter command.
+func handleGetBlockFilter(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	if !cfg.CFIndex {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Committed filter index must be enabled (--cfindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetBlockFilterCmd)
+	if c.FilterType != nil && *c.FilterType != "basic" {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Unknown filtertype: " + *c.FilterType,
+		}
+	}
+
+	sha, err := wire.NewShaHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+
+	filter, header, err := s.server.db.FetchCFilterBySha(sha)
+	if err == database.ErrCFilterMissing {
+		if _, err := s.server.db.FetchBlockHeightBySha(sha); err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Block not found",
+			}
+		}
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "Filter not yet available, the committed " +
+				"filter index is still being built",
+		}
+	}
+	if err != nil {
+		context := "Failed to fetch committed filter"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return &btcjson.GetBlockFilterResult{
+		Filter: hex.EncodeToString(filter),
+		Header: header.String(),
+	}, nil
+}
+
 // handleGetBlockHash implements the getblockhash command.
-func handleGetBlockHash(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBlockHash(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetBlockHashCmd)
 	sha, err := s.server.db.FetchBlockShaByHeight(int32(c.Index))
 	if err != nil {
@@ -1265,7 +2790,7 @@ func handleGetBlockHash(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 }
 
 // handleGetBlockHeader implements the getblockheader command.
-func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBlockHeader(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetBlockHeaderCmd)
 
 	sha, err := wire.NewShaHashFromStr(c.Hash)
@@ -1274,39 +2799,65 @@ func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	}
 
 	if c.Verbose == nil || *c.Verbose {
-		blk, err := s.server.db.FetchBlockBySha(sha)
+		db := s.server.db
+		blkHeader, err := db.FetchBlockHeaderBySha(sha)
 		if err != nil {
 			return nil, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCInvalidAddressOrKey,
 				Message: "Invalid address or key: " + err.Error(),
 			}
 		}
+		height, err := db.FetchBlockHeightBySha(sha)
+		if err != nil {
+			context := "Failed to get block height"
+			return nil, internalRPCError(err.Error(), context)
+		}
 
-		_, maxIdx, err := s.server.db.NewestSha()
+		_, maxIdx, err := db.NewestSha()
 		if err != nil {
 			context := "Failed to get newest hash"
 			return nil, internalRPCError(err.Error(), context)
 		}
 
 		var shaNextStr string
-		shaNext, err := s.server.db.FetchBlockShaByHeight(blk.Height() + 1)
+		shaNext, err := db.FetchBlockShaByHeight(height + 1)
 		if err == nil {
 			shaNextStr = shaNext.String()
 		}
 
-		msgBlock := blk.MsgBlock()
+		// The genesis block has no previous block.
+		var shaPrevStr string
+		if height > 0 {
+			shaPrevStr = blkHeader.PrevBlock.String()
+		}
+
+		medianTime, err := blockchain.PastMedianTime(blkHeader, height,
+			db.FetchBlockHeaderBySha)
+		if err != nil {
+			context := "Failed to calculate median time"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		chainWork, err := s.chainWork.ChainWork(height)
+		if err != nil {
+			context := "Failed to calculate chain work"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
 		blockHeaderReply := btcjson.GetBlockHeaderVerboseResult{
 			Hash:          c.Hash,
-			Confirmations: uint64(1 + maxIdx - blk.Height()),
-			Height:        int32(blk.Height()),
-			Version:       msgBlock.Header.Version,
-			MerkleRoot:    msgBlock.Header.MerkleRoot.String(),
+			Confirmations: uint64(1 + maxIdx - height),
+			Height:        height,
+			Version:       blkHeader.Version,
+			MerkleRoot:    blkHeader.MerkleRoot.String(),
 			NextHash:      shaNextStr,
-			PreviousHash:  msgBlock.Header.PrevBlock.String(),
-			Nonce:         uint64(msgBlock.Header.Nonce),
-			Time:          msgBlock.Header.Timestamp.Unix(),
-			Bits:          strconv.FormatInt(int64(msgBlock.Header.Bits), 16),
-			Difficulty:    getDifficultyRatio(msgBlock.Header.Bits),
+			PreviousHash:  shaPrevStr,
+			Nonce:         uint64(blkHeader.Nonce),
+			Time:          blkHeader.Timestamp.Unix(),
+			MedianTime:    medianTime.Unix(),
+			Bits:          strconv.FormatInt(int64(blkHeader.Bits), 16),
+			Difficulty:    getDifficultyRatio(blkHeader.Bits),
+			ChainWork:     fmt.Sprintf("%064x", chainWork),
 		}
 		return blockHeaderReply, nil
 	}
@@ -1329,6 +2880,103 @@ func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	return hex.EncodeToString(buf.Bytes()), nil
 }
 
+// avgCoinAge returns the average age in days of the outputs spent by a block
+// with the passed coin age statistics, weighted by their value.
+func avgCoinAge(coinAge *database.BlockCoinAge) float64 {
+	if coinAge.SpentValue == 0 {
+		return 0
+	}
+	spent := coinutil.Amount(coinAge.SpentValue).ToBTC()
+	return coinAge.CoinDaysDestroyed / spent
+}
+
+// handleGetBlockStats implements the getblockstats command.
+func handleGetBlockStats(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockStatsCmd)
+
+	sha, err := wire.NewShaHashFromStr(c.Hash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Hash)
+	}
+	blk, err := s.server.db.FetchBlockBySha(sha)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+
+	// Like the statistics of Bitcoin Core, the coinbase only counts
+	// towards the number of outputs.
+	txns := blk.Transactions()
+	result := &btcjson.GetBlockStatsResult{
+		Hash:    c.Hash,
+		Height:  blk.Height(),
+		Time:    blk.MsgBlock().Header.Timestamp.Unix(),
+		Txs:     int64(len(txns)),
+		Subsidy: s.server.blockManager.CalcBlockSubsidy(blk.Height()),
+	}
+	for _, tx := range txns {
+		mtx := tx.MsgTx()
+		result.Outs += int64(len(mtx.TxOut))
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+
+		result.Ins += int64(len(mtx.TxIn))
+		result.TotalSize += int64(mtx.SerializeSize())
+
+		// The fee is the value of the outputs spent by the transaction
+		// less the value of the outputs it creates.
+		var totalIn, totalOut int64
+		for _, txOut := range mtx.TxOut {
+			totalOut += txOut.Value
+		}
+		for _, txIn := range mtx.TxIn {
+			prevOut := &txIn.PreviousOutPoint
+			txList, err := s.server.db.FetchTxBySha(&prevOut.Hash)
+			if err != nil || len(txList) == 0 {
+				context := "Failed to fetch input transaction"
+				if err == nil {
+					err = fmt.Errorf("transaction %v not found",
+						prevOut.Hash)
+				}
+				return nil, internalRPCError(err.Error(), context)
+			}
+			prevTx := txList[len(txList)-1].Tx
+			if prevOut.Index >= uint32(len(prevTx.TxOut)) {
+				context := "Failed to fetch input transaction"
+				errStr := fmt.Sprintf("output %v does not exist",
+					prevOut)
+				return nil, internalRPCError(errStr, context)
+			}
+			totalIn += prevTx.TxOut[prevOut.Index].Value
+		}
+		result.TotalOut += totalOut
+		result.TotalFee += totalIn - totalOut
+	}
+	result.UtxoIncrease = result.Outs - result.Ins
+	if result.Txs > 1 {
+		result.AvgFee = result.TotalFee / (result.Txs - 1)
+	}
+
+	// Include the coin age statistics when they have been indexed.
+	if cfg.CoinAgeIndex {
+		coinAge, err := s.server.db.FetchCoinAgeBySha(sha)
+		if err != nil && err != database.ErrCoinAgeMissing {
+			context := "Failed to fetch coin age statistics"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		if err == nil {
+			avgAge := avgCoinAge(coinAge)
+			result.CoinDaysDestroyed = &coinAge.CoinDaysDestroyed
+			result.AvgCoinAge = &avgAge
+		}
+	}
+
+	return result, nil
+}
+
 // encodeTemplateID encodes the passed details into an ID that can be used to
 // uniquely identify a block template.
 func encodeTemplateID(prevHash *wire.ShaHash, lastGenerated time.Time) string {
@@ -1519,7 +3167,7 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		// block template doesn't include the coinbase, so the caller
 		// will ultimately create their own coinbase which pays to the
 		// appropriate address(es).
-		blkTemplate, err := NewBlockTemplate(s.policy, s.server, payAddr)
+		blkTemplate, err := NewBlockTemplate(s.policy.Policy(), s.server, payAddr)
 		if err != nil {
 			return internalRPCError("Failed to create new block "+
 				"template: "+err.Error(), "")
@@ -1542,6 +3190,7 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		// Update work state to ensure another block template isn't
 		// generated until needed.
 		state.template = template
+		state.coinbases = nil
 		state.lastGenerated = time.Now()
 		state.lastTxUpdate = lastTxUpdate
 		state.prevHash = latestHash
@@ -1581,10 +3230,11 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 			}
 			template.block.Transactions[0].TxOut[0].PkScript = pkScript
 			template.validPayAddress = true
+			state.coinbases = nil
 
 			// Update the merkle root.
 			block := coinutil.NewBlock(template.block)
-			merkles := blockchain.BuildMerkleTreeStore(block.Transactions())
+			merkles := blockchain.BuildMerkleTreeStore(block.Transactions(), false)
 			template.block.Header.MerkleRoot = *merkles[len(merkles)-1]
 		}
 
@@ -1607,12 +3257,76 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 	return nil
 }
 
+// coinbaseResult returns the coinbase of the current block template, which is
+// customized according to the passed options when they are not nil, as a
+// template result transaction.  Customized coinbases are cached so repeated
+// requests with the same options don't rebuild them.
+//
+// This function MUST be called with the state locked.
+func (state *gbtWorkState) coinbaseResult(opts *CoinbaseOptions) (*btcjson.GetBlockTemplateResultTx, error) {
+	var key gbtCoinbaseKey
+	if opts != nil {
+		key = gbtCoinbaseKey{
+			flags:    string(opts.Flags),
+			pkScript: string(opts.PkScript),
+		}
+		if result, ok := state.coinbases[key]; ok {
+			return result, nil
+		}
+	}
+
+	template := state.template
+	tx := template.block.Transactions[0]
+	sigOps := template.sigOpCounts[0]
+	if opts != nil {
+		var err error
+		tx, err = CustomizeCoinbase(template, opts)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: err.Error(),
+			}
+		}
+		sigOps = int64(blockchain.CountSigOps(coinutil.NewTx(tx)))
+	}
+
+	// Serialize the transaction for conversion to hex.
+	txBuf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+	if err := tx.Serialize(txBuf); err != nil {
+		context := "Failed to serialize transaction"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	witnessHash := tx.WitnessHash()
+	result := &btcjson.GetBlockTemplateResultTx{
+		Data:    hex.EncodeToString(txBuf.Bytes()),
+		TxID:    tx.TxSha().String(),
+		Hash:    witnessHash.String(),
+		Depends: []int64{},
+		Fee:     template.fees[0],
+		SigOps:  sigOps,
+		Weight:  blockchain.GetTransactionWeight(coinutil.NewTx(tx)),
+	}
+
+	if opts != nil {
+		if state.coinbases == nil ||
+			len(state.coinbases) >= gbtMaxCachedCoinbases {
+
+			state.coinbases = make(map[gbtCoinbaseKey]*btcjson.GetBlockTemplateResultTx)
+		}
+		state.coinbases[key] = result
+	}
+	return result, nil
+}
+
 // blockTemplateResult returns the current block template associated with the
 // state as a btcjson.GetBlockTemplateResult that is ready to be encoded to JSON
-// and returned to the caller.
+// and returned to the caller.  The coinbase is customized according to the
+// passed options when they are not nil and the transaction selection audit
+// trail is included when auditSource is set.
 //
 // This function MUST be called with the state locked.
-func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld *bool) (*btcjson.GetBlockTemplateResult, error) {
+func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld *bool, opts *CoinbaseOptions, auditSource bool) (*btcjson.GetBlockTemplateResult, error) {
 	// Ensure the timestamps are still in valid range for the template.
 	// This should really only ever happen if the local clock is changed
 	// after the template is generated, but it's important to avoid serving
@@ -1671,12 +3385,15 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 			return nil, internalRPCError(err.Error(), context)
 		}
 
+		witnessHash := tx.WitnessHash()
 		resultTx := btcjson.GetBlockTemplateResultTx{
 			Data:    hex.EncodeToString(txBuf.Bytes()),
-			Hash:    txHash.String(),
+			TxID:    txHash.String(),
+			Hash:    witnessHash.String(),
 			Depends: depends,
 			Fee:     template.fees[i],
 			SigOps:  template.sigOpCounts[i],
+			Weight:  blockchain.GetTransactionWeight(coinutil.NewTx(tx)),
 		}
 		transactions = append(transactions, resultTx)
 	}
@@ -1693,7 +3410,8 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 		Height:       int64(template.height),
 		PreviousHash: header.PrevBlock.String(),
 		SigOpLimit:   blockchain.MaxSigOpsPerBlock,
-		SizeLimit:    wire.MaxBlockPayload,
+		SizeLimit:    blockchain.MaxBlockBaseSize,
+		WeightLimit:  blockchain.MaxBlockWeight,
 		Transactions: transactions,
 		Version:      header.Version,
 		LongPollID:   templateID,
@@ -1705,13 +3423,58 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 		NonceRange:   gbtNonceRange,
 		Capabilities: gbtCapabilities,
 	}
+
+	// Active rule change deployments are rules the block must follow while
+	// those being voted on or locked in are available to signal.  None of
+	// them are required to be signaled.
+	for id, deploymentState := range template.deploymentStates {
+		name := chaincfg.DeploymentNames[id]
+		switch deploymentState {
+		case blockchain.ThresholdActive:
+			reply.Rules = append(reply.Rules, name)
+
+		case blockchain.ThresholdStarted, blockchain.ThresholdLockedIn:
+			if reply.VbAvailable == nil {
+				reply.VbAvailable = make(map[string]uint32)
+			}
+			bit := activeNetParams.Deployments[id].BitNumber
+			reply.VbAvailable[name] = uint32(bit)
+		}
+	}
+
+	// Provide the witness commitment so miners which build their own
+	// coinbase are able to include it.
+	if template.witnessCommitment != nil {
+		reply.DefaultWitnessCommitment = hex.EncodeToString(
+			template.witnessCommitment)
+	}
+
+	// Likewise for the UTXO commitment.
+	if template.utxoCommitment != nil {
+		reply.DefaultUtxoCommitment = hex.EncodeToString(
+			template.utxoCommitment)
+	}
+
+	if auditSource {
+		reply.AuditSource = templateAuditResult(template)
+	}
+
 	if useCoinbaseValue {
+		// Custom coinbase flags replace the default ones the caller is
+		// asked to include in its coinbase.
 		reply.CoinbaseAux = gbtCoinbaseAux
+		if opts != nil && opts.Flags != nil {
+			reply.CoinbaseAux = &btcjson.GetBlockTemplateResultAux{
+				Flags: hex.EncodeToString(builderScript(txscript.
+					NewScriptBuilder().AddData(opts.Flags))),
+			}
+		}
 		reply.CoinbaseValue = &msgBlock.Transactions[0].TxOut[0].Value
 	} else {
 		// Ensure the template has a valid payment address associated
-		// with it when a full coinbase is requested.
-		if !template.validPayAddress {
+		// with it when a full coinbase is requested, unless the caller
+		// supplied the script to pay to.
+		if !template.validPayAddress && (opts == nil || opts.PkScript == nil) {
 			return nil, &btcjson.RPCError{
 				Code: btcjson.ErrRPCInternal.Code,
 				Message: "A coinbase transaction has been " +
@@ -1721,23 +3484,11 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 			}
 		}
 
-		// Serialize the transaction for conversion to hex.
-		tx := msgBlock.Transactions[0]
-		txBuf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
-		if err := tx.Serialize(txBuf); err != nil {
-			context := "Failed to serialize transaction"
-			return nil, internalRPCError(err.Error(), context)
-		}
-
-		resultTx := btcjson.GetBlockTemplateResultTx{
-			Data:    hex.EncodeToString(txBuf.Bytes()),
-			Hash:    tx.TxSha().String(),
-			Depends: []int64{},
-			Fee:     template.fees[0],
-			SigOps:  template.sigOpCounts[0],
+		resultTx, err := state.coinbaseResult(opts)
+		if err != nil {
+			return nil, err
 		}
-
-		reply.CoinbaseTxn = &resultTx
+		reply.CoinbaseTxn = resultTx
 	}
 
 	return &reply, nil
@@ -1753,14 +3504,16 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 // has passed without finding a solution.
 //
 // See https://en.bitcoin.it/wiki/BIP_0022 for more details.
-func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbaseValue bool, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbaseValue bool, opts *CoinbaseOptions, auditSource bool, ctx context.Context) (interface{}, error) {
 	state := s.gbtWorkState
 	state.Lock()
 	// The state unlock is intentionally not deferred here since it needs to
 	// be manually unlocked before waiting for a notification about block
 	// template changes.
 
-	if err := state.updateBlockTemplate(s, useCoinbaseValue); err != nil {
+	payAddrOptional := !requiresPayAddress(useCoinbaseValue, opts)
+
+	if err := state.updateBlockTemplate(s, payAddrOptional); err != nil {
 		state.Unlock()
 		return nil, err
 	}
@@ -1769,7 +3522,8 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	// provided by the caller is invalid.
 	prevHash, lastGenerated, err := decodeTemplateID(longPollID)
 	if err != nil {
-		result, err := state.blockTemplateResult(useCoinbaseValue, nil)
+		result, err := state.blockTemplateResult(useCoinbaseValue, nil,
+			opts, auditSource)
 		if err != nil {
 			state.Unlock()
 			return nil, err
@@ -1791,7 +3545,7 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 		// already been found and added to the block chain.
 		submitOld := prevHash.IsEqual(prevTemplateHash)
 		result, err := state.blockTemplateResult(useCoinbaseValue,
-			&submitOld)
+			&submitOld, opts, auditSource)
 		if err != nil {
 			state.Unlock()
 			return nil, err
@@ -1811,7 +3565,12 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	select {
 	// When the client closes before it's time to send a reply, just return
 	// now so the goroutine doesn't hang around.
-	case <-closeChan:
+	case <-ctx.Done():
+		return nil, ErrClientQuit
+
+	// Give up when the server shuts down so it doesn't wait for the
+	// long poll to finish.
+	case <-s.quit:
 		return nil, ErrClientQuit
 
 	// Wait until signal received to send the reply.
@@ -1823,7 +3582,7 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	state.Lock()
 	defer state.Unlock()
 
-	if err := state.updateBlockTemplate(s, useCoinbaseValue); err != nil {
+	if err := state.updateBlockTemplate(s, payAddrOptional); err != nil {
 		return nil, err
 	}
 
@@ -1831,7 +3590,8 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	// block template depending on whether or not a solution has already
 	// been found and added to the block chain.
 	submitOld := prevHash.IsEqual(&state.template.block.Header.PrevBlock)
-	result, err := state.blockTemplateResult(useCoinbaseValue, &submitOld)
+	result, err := state.blockTemplateResult(useCoinbaseValue, &submitOld,
+		opts, auditSource)
 	if err != nil {
 		return nil, err
 	}
@@ -1839,51 +3599,140 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	return result, nil
 }
 
-// handleGetBlockTemplateRequest is a helper for handleGetBlockTemplate which
-// deals with generating and returning block templates to the caller.  It
-// handles both long poll requests as specified by BIP 0022 as well as regular
-// requests.  In addition, it detects the capabilities reported by the caller
-// in regards to whether or not it supports creating its own coinbase (the
-// coinbasetxn and coinbasevalue capabilities) and modifies the returned block
-// template accordingly.
-func handleGetBlockTemplateRequest(s *rpcServer, request *btcjson.TemplateRequest, closeChan <-chan struct{}) (interface{}, error) {
-	// Extract the relevant passed capabilities and restrict the result to
-	// either a coinbase value or a coinbase transaction object depending on
-	// the request.  Default to only providing a coinbase value.
-	useCoinbaseValue := true
-	if request != nil {
-		var hasCoinbaseValue, hasCoinbaseTxn bool
-		for _, capability := range request.Capabilities {
-			switch capability {
-			case "coinbasetxn":
-				hasCoinbaseTxn = true
-			case "coinbasevalue":
-				hasCoinbaseValue = true
-			}
+// gbtUseCoinbaseValue returns whether a block template for the passed request
+// should only provide a coinbase value as opposed to a full coinbase
+// transaction object.  A coinbase value is provided unless the caller only
+// reports the coinbasetxn capability.
+func gbtUseCoinbaseValue(request *btcjson.TemplateRequest) bool {
+	if request == nil {
+		return true
+	}
+
+	var hasCoinbaseValue, hasCoinbaseTxn bool
+	for _, capability := range request.Capabilities {
+		switch capability {
+		case "coinbasetxn":
+			hasCoinbaseTxn = true
+		case "coinbasevalue":
+			hasCoinbaseValue = true
 		}
+	}
+	return !hasCoinbaseTxn || hasCoinbaseValue
+}
 
-		if hasCoinbaseTxn && !hasCoinbaseValue {
-			useCoinbaseValue = false
+// gbtAuditSource returns whether the caller reports the auditsource capability
+// and hence requests the transaction selection audit trail of the block
+// template.
+func gbtAuditSource(request *btcjson.TemplateRequest) bool {
+	if request == nil {
+		return false
+	}
+	for _, capability := range request.Capabilities {
+		if capability == "auditsource" {
+			return true
 		}
 	}
+	return false
+}
 
-	// When a coinbase transaction has been requested, respond with an error
-	// if there are no addresses to pay the created block template to.
-	if !useCoinbaseValue && len(cfg.miningAddrs) == 0 {
-		return nil, &btcjson.RPCError{
-			Code: btcjson.ErrRPCInternal.Code,
-			Message: "A coinbase transaction has been requested, " +
-				"but the server has not been configured with " +
-				"any payment addresses via --miningaddr",
+// templateAuditResult returns the transaction selection audit trail of the
+// passed block template as the auditsource field of a getblocktemplate result.
+func templateAuditResult(template *BlockTemplate) *btcjson.GetBlockTemplateResultAudit {
+	txns := template.block.Transactions
+	audit := &btcjson.GetBlockTemplateResultAudit{
+		Transactions: make([]btcjson.GetBlockTemplateResultAuditTx, 0,
+			len(template.selections)),
+		Exclusions: make([]btcjson.GetBlockTemplateResultExclusion, 0,
+			len(template.exclusions)),
+	}
+
+	// The first entry of the selections is for the coinbase.
+	for i := 1; i < len(template.selections) && i < len(txns); i++ {
+		selection := &template.selections[i]
+		audit.Transactions = append(audit.Transactions,
+			btcjson.GetBlockTemplateResultAuditTx{
+				TxID:     txns[i].TxSha().String(),
+				Reason:   selection.reason,
+				Rank:     selection.rank,
+				Package:  selection.inPackage,
+				FeeRate:  coinutil.Amount(selection.feePerKB).ToBTC(),
+				Priority: selection.priority,
+			})
+	}
+	for _, exclusion := range template.exclusions {
+		audit.Exclusions = append(audit.Exclusions,
+			btcjson.GetBlockTemplateResultExclusion{
+				TxID:     exclusion.hash.String(),
+				Reason:   exclusion.reason,
+				FeeRate:  coinutil.Amount(exclusion.feePerKB).ToBTC(),
+				Priority: exclusion.priority,
+			})
+	}
+	return audit
+}
+
+// gbtCoinbaseOptions decodes the coinbase customization of the passed template
+// request.  Nil is returned when the request doesn't customize the coinbase.
+func gbtCoinbaseOptions(request *btcjson.TemplateRequest) (*CoinbaseOptions, error) {
+	if request == nil ||
+		(request.CoinbaseFlags == "" && request.PayoutScript == "") {
+
+		return nil, nil
+	}
+
+	var opts CoinbaseOptions
+	if request.CoinbaseFlags != "" {
+		flags, err := hex.DecodeString(request.CoinbaseFlags)
+		if err != nil {
+			return nil, rpcDecodeHexError(request.CoinbaseFlags)
+		}
+		if len(flags) > maxCoinbaseFlagsLen {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Coinbase flags of %d "+
+					"bytes are too long (max %d)",
+					len(flags), maxCoinbaseFlagsLen),
+			}
+		}
+		opts.Flags = flags
+	}
+	if request.PayoutScript != "" {
+		pkScript, err := hex.DecodeString(request.PayoutScript)
+		if err != nil {
+			return nil, rpcDecodeHexError(request.PayoutScript)
+		}
+
+		// Refuse scripts nobody can be expected to spend so a typo
+		// doesn't burn the block reward.
+		class := txscript.GetScriptClass(pkScript)
+		if class == txscript.NonStandardTy || class == txscript.NullDataTy {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "The payout script must be a standard " +
+					"script which can be spent",
+			}
 		}
+		opts.PkScript = pkScript
 	}
+	return &opts, nil
+}
 
+// requiresPayAddress returns whether a block template must pay to one of the
+// configured mining addresses, which is the case when the caller requested a
+// full coinbase without supplying the script to pay to.
+func requiresPayAddress(useCoinbaseValue bool, opts *CoinbaseOptions) bool {
+	return !useCoinbaseValue && (opts == nil || opts.PkScript == nil)
+}
+
+// checkTemplateReady returns an error when no work should be generated or
+// accepted because the server is unable to act on it.
+func checkTemplateReady(s *rpcServer) error {
 	// Return an error if there are no peers connected since there is no
 	// way to relay a found block or receive transactions to work on.
 	// However, allow this state when running in the regression test or
 	// simulation test mode.
 	if !(cfg.RegressionTest || cfg.SimNet) && s.server.ConnectedCount() == 0 {
-		return nil, &btcjson.RPCError{
+		return &btcjson.RPCError{
 			Code:    btcjson.ErrRPCClientNotConnected,
 			Message: "Bitcoin is not connected",
 		}
@@ -1892,18 +3741,58 @@ func handleGetBlockTemplateRequest(s *rpcServer, request *btcjson.TemplateReques
 	// No point in generating or accepting work before the chain is synced.
 	_, currentHeight := s.server.blockManager.chainState.Best()
 	if currentHeight != 0 && !s.server.blockManager.IsCurrent() {
-		return nil, &btcjson.RPCError{
+		return &btcjson.RPCError{
 			Code:    btcjson.ErrRPCClientInInitialDownload,
 			Message: "Bitcoin is downloading blocks...",
 		}
 	}
+	return nil
+}
+
+// handleGetBlockTemplateRequest is a helper for handleGetBlockTemplate which
+// deals with generating and returning block templates to the caller.  It
+// handles both long poll requests as specified by BIP 0022 as well as regular
+// requests.  In addition, it detects the capabilities reported by the caller
+// in regards to whether or not it supports creating its own coinbase (the
+// coinbasetxn and coinbasevalue capabilities) and modifies the returned block
+// template accordingly.
+func handleGetBlockTemplateRequest(s *rpcServer, request *btcjson.TemplateRequest, ctx context.Context) (interface{}, error) {
+	// Restrict the result to either a coinbase value or a coinbase
+	// transaction object depending on the request.
+	useCoinbaseValue := gbtUseCoinbaseValue(request)
+
+	// Include the transaction selection audit trail when requested.
+	auditSource := gbtAuditSource(request)
+
+	// Decode the coinbase customization requested by the caller.
+	opts, err := gbtCoinbaseOptions(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// When a coinbase transaction has been requested, respond with an error
+	// if there are no addresses to pay the created block template to.
+	payAddrOptional := !requiresPayAddress(useCoinbaseValue, opts)
+	if !payAddrOptional && len(cfg.miningAddrs) == 0 {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInternal.Code,
+			Message: "A coinbase transaction has been requested, " +
+				"but the server has not been configured with " +
+				"any payment addresses via --miningaddr",
+		}
+	}
+
+	// Don't generate or accept work the server can't act on.
+	if err := checkTemplateReady(s); err != nil {
+		return nil, err
+	}
 
 	// When a long poll ID was provided, this is a long poll request by the
 	// client to be notified when block template referenced by the ID should
 	// be replaced with a new one.
 	if request != nil && request.LongPollID != "" {
 		return handleGetBlockTemplateLongPoll(s, request.LongPollID,
-			useCoinbaseValue, closeChan)
+			useCoinbaseValue, opts, auditSource, ctx)
 	}
 
 	// Protect concurrent access when updating block templates.
@@ -1917,10 +3806,11 @@ func handleGetBlockTemplateRequest(s *rpcServer, request *btcjson.TemplateReques
 	// seconds since the last template was generated.  Otherwise, the
 	// timestamp for the existing block template is updated (and possibly
 	// the difficulty on testnet per the consesus rules).
-	if err := state.updateBlockTemplate(s, useCoinbaseValue); err != nil {
+	if err := state.updateBlockTemplate(s, payAddrOptional); err != nil {
 		return nil, err
 	}
-	return state.blockTemplateResult(useCoinbaseValue, nil)
+	return state.blockTemplateResult(useCoinbaseValue, nil, opts,
+		auditSource)
 }
 
 // chainErrToGBTErrString converts an error returned from btcchain to a string
@@ -2085,7 +3975,7 @@ func handleGetBlockTemplateProposal(s *rpcServer, request *btcjson.TemplateReque
 //
 // See https://en.bitcoin.it/wiki/BIP_0022 and
 // https://en.bitcoin.it/wiki/BIP_0023 for more details.
-func handleGetBlockTemplate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBlockTemplate(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetBlockTemplateCmd)
 	request := c.Request
 
@@ -2097,7 +3987,7 @@ func handleGetBlockTemplate(s *rpcServer, cmd interface{}, closeChan <-chan stru
 
 	switch mode {
 	case "template":
-		return handleGetBlockTemplateRequest(s, request, closeChan)
+		return handleGetBlockTemplateRequest(s, request, ctx)
 	case "proposal":
 		return handleGetBlockTemplateProposal(s, request)
 	}
@@ -2108,18 +3998,171 @@ func handleGetBlockTemplate(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	}
 }
 
+// handleGetCoinAgeInfo implements the getcoinageinfo command.
+func handleGetCoinAgeInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	if !cfg.CoinAgeIndex {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Coin age index must be enabled (--coinageindex)",
+		}
+	}
+
+	// Default to the best block of the main chain when no block is given.
+	c := cmd.(*btcjson.GetCoinAgeInfoCmd)
+	var sha *wire.ShaHash
+	var height int32
+	var err error
+	if c.Hash == nil {
+		sha, height, err = s.server.db.NewestSha()
+		if err != nil {
+			context := "Failed to get newest hash"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	} else {
+		sha, err = wire.NewShaHashFromStr(*c.Hash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.Hash)
+		}
+		height, err = s.server.db.FetchBlockHeightBySha(sha)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found",
+			}
+		}
+	}
+
+	coinAge, err := s.server.db.FetchCoinAgeBySha(sha)
+	if err == database.ErrCoinAgeMissing {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "Coin age statistics not yet available, the " +
+				"coin age index is still being built",
+		}
+	}
+	if err != nil {
+		context := "Failed to fetch coin age statistics"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return &btcjson.GetCoinAgeInfoResult{
+		Hash:                   sha.String(),
+		Height:                 height,
+		SpentValue:             coinutil.Amount(coinAge.SpentValue).ToBTC(),
+		CoinDaysDestroyed:      coinAge.CoinDaysDestroyed,
+		AvgCoinAge:             avgCoinAge(coinAge),
+		TotalCoinDaysDestroyed: coinAge.TotalCoinDaysDestroyed,
+	}, nil
+}
+
+// handleGetChainStates implements the getchainstates command.
+func handleGetChainStates(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	bm := s.server.blockManager
+	hash, height := bm.chainState.Best()
+	result := &btcjson.GetChainStatesResult{
+		Headers: height,
+		ChainStates: []btcjson.ChainStateResult{{
+			Blocks:        height,
+			BestBlockHash: hash.String(),
+			Validated:     true,
+		}},
+	}
+
+	// The chain state built on a UTXO snapshot is only reported while it
+	// is used.
+	snapshot := bm.snapshot
+	if snapshot == nil || snapshot.isDropped() {
+		return result, nil
+	}
+	if _, headers := bm.snapshotHeaders.BestHeader(); headers > height {
+		result.Headers = headers
+	}
+	tipHash, tipHeight := snapshot.tip()
+	result.ChainStates = append(result.ChainStates, btcjson.ChainStateResult{
+		Blocks:            tipHeight,
+		BestBlockHash:     tipHash.String(),
+		SnapshotBlockHash: snapshot.baseHash.String(),
+		Validated:         snapshot.isValidated(),
+	})
+	return result, nil
+}
+
 // handleGetConnectionCount implements the getconnectioncount command.
-func handleGetConnectionCount(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetConnectionCount(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	return s.server.ConnectedCount(), nil
 }
 
 // handleGetCurrentNet implements the getcurrentnet command.
-func handleGetCurrentNet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetCurrentNet(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	return s.server.chainParams.Net, nil
 }
 
+// cacheLookupsResult returns the result for the passed hit and miss counters
+// of a kind of cache lookup.  The hit rate is zero when there haven't been
+// any lookups.
+func cacheLookupsResult(hits, misses uint64) btcjson.CacheLookupsResult {
+	result := btcjson.CacheLookupsResult{Hits: hits, Misses: misses}
+	if total := hits + misses; total != 0 {
+		result.HitRate = float64(hits) / float64(total)
+	}
+	return result
+}
+
+// handleGetDatabaseInfo implements the getdatabaseinfo command.
+func handleGetDatabaseInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	_, height, err := s.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get best block"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	result := &btcjson.GetDatabaseInfoResult{
+		Type:   cfg.DbType,
+		Height: height,
+	}
+
+	// Memdb doesn't store anything on disk, so it has no sizes.
+	sizes, err := s.server.db.FetchDatabaseSizes()
+	if err != nil && err != database.ErrNotImplemented {
+		context := "Failed to get database sizes"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if len(sizes) > 0 {
+		result.Sizes = make(map[string]int64, len(sizes))
+	}
+	for _, size := range sizes {
+		result.Size += size.Size
+		result.Sizes[size.Name] = size.Size
+	}
+
+	status := s.server.dbCompactor.Status()
+	result.Compacting = status.running
+	if cfg.compactWindow != nil {
+		result.CompactWindow = cfg.compactWindow.String()
+	}
+	if !status.lastStart.IsZero() {
+		result.LastCompactionTime = status.lastStart.Unix()
+		result.LastCompactionDuration = status.lastDuration.Seconds()
+	}
+	if status.lastErr != nil {
+		result.LastCompactionError = status.lastErr.Error()
+	}
+	return result, nil
+}
+
+// handleGetDBCacheStats implements the getdbcachestats command.
+func handleGetDBCacheStats(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	stats := s.server.db.FetchCacheStats()
+	return &btcjson.GetDBCacheStatsResult{
+		Entries:       stats.Entries,
+		MaxEntries:    stats.MaxEntries,
+		HeightLookups: cacheLookupsResult(stats.HeightHits, stats.HeightMisses),
+		HashLookups:   cacheLookupsResult(stats.ShaHits, stats.ShaMisses),
+		HeaderLookups: cacheLookupsResult(stats.HeaderHits, stats.HeaderMisses),
+	}, nil
+}
+
 // handleGetDifficulty implements the getdifficulty command.
-func handleGetDifficulty(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetDifficulty(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	sha, _, err := s.server.db.NewestSha()
 	if err != nil {
 		rpcsLog.Errorf("Error getting sha: %v", err)
@@ -2139,19 +4182,136 @@ func handleGetDifficulty(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	return getDifficultyRatio(blockHeader.Bits), nil
 }
 
+// handleGetDifficultyHistory implements the getdifficultyhistory command.
+func handleGetDifficultyHistory(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetDifficultyHistoryCmd)
+
+	_, newestHeight, err := s.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get newest hash"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	// Use the current best block height when the passed height is
+	// negative.
+	endHeight := int32(-1)
+	if c.Height != nil {
+		endHeight = int32(*c.Height)
+	}
+	if endHeight > newestHeight {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCOutOfRange,
+			Message: "Block number out of range",
+		}
+	}
+	if endHeight < 0 {
+		endHeight = newestHeight
+	}
+
+	numPeriods := int32(10)
+	if c.Periods != nil {
+		numPeriods = int32(*c.Periods)
+	}
+	if numPeriods <= 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Number of periods must be positive",
+		}
+	}
+
+	// Find the retarget periods to report, ending with the one which
+	// contains the end height and not starting before the beginning of the
+	// chain.
+	lastPeriod := endHeight / blockchain.BlocksPerRetarget
+	firstPeriod := lastPeriod - numPeriods + 1
+	if firstPeriod < 0 {
+		firstPeriod = 0
+	}
+
+	// The difficulty of a period is the one of its first block.  Only the
+	// block headers are fetched, so the history can be computed without
+	// loading any full blocks.
+	results := make([]btcjson.GetDifficultyHistoryResult, 0,
+		lastPeriod-firstPeriod+1)
+	for period := firstPeriod; period <= lastPeriod; period++ {
+		height := period * blockchain.BlocksPerRetarget
+		hash, err := s.server.db.FetchBlockShaByHeight(height)
+		if err != nil {
+			context := "Failed to fetch block hash"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		header, err := s.server.db.FetchBlockHeaderBySha(hash)
+		if err != nil {
+			context := "Failed to fetch block header"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		// The hash rate of a period is estimated from the timestamp of
+		// the last block of the previous period, when there is one,
+		// through the last block of the period.
+		periodEnd := height + blockchain.BlocksPerRetarget - 1
+		if periodEnd > endHeight {
+			periodEnd = endHeight
+		}
+		periodStart := height - 1
+		if periodStart < 0 {
+			periodStart = 0
+		}
+		hashesPerSec, err := calcNetworkHashPS(s, periodStart, periodEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, btcjson.GetDifficultyHistoryResult{
+			Height:        height,
+			EndHeight:     periodEnd,
+			Hash:          hash.String(),
+			Time:          header.Timestamp.Unix(),
+			Bits:          strconv.FormatInt(int64(header.Bits), 16),
+			Difficulty:    getDifficultyRatio(header.Bits),
+			NetworkHashPS: hashesPerSec,
+		})
+	}
+
+	return results, nil
+}
+
 // handleGetGenerate implements the getgenerate command.
-func handleGetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetGenerate(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	return s.server.cpuMiner.IsMining(), nil
 }
 
 // handleGetHashesPerSec implements the gethashespersec command.
-func handleGetHashesPerSec(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetHashesPerSec(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	return int64(s.server.cpuMiner.HashesPerSecond()), nil
 }
 
+// handleGetImportStatus implements the getimportstatus command.
+func handleGetImportStatus(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	status := s.server.blockImporter.Status()
+	_, height, err := s.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get best block"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	result := &btcjson.GetImportStatusResult{
+		Active:          status.active,
+		File:            status.file,
+		FilesRemaining:  status.filesRemaining,
+		BlocksProcessed: status.blocksProcessed,
+		BlocksImported:  status.blocksImported,
+		Height:          height,
+	}
+	if status.err != nil {
+		result.Error = status.err.Error()
+	}
+	return result, nil
+}
+
 // handleGetInfo implements the getinfo command. We only return the fields
 // that are not related to wallet functionality.
-func handleGetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	// We require the current block height and sha.
 	sha, height, err := s.server.db.NewestSha()
 	if err != nil {
@@ -2174,13 +4334,48 @@ func handleGetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 		Difficulty:      getDifficultyRatio(blkHeader.Bits),
 		TestNet:         cfg.TestNet3,
 		RelayFee:        cfg.minRelayTxFee.ToBTC(),
+		Errors:          s.server.clockSkewWarning(),
 	}
 
 	return ret, nil
 }
 
+// handleGetLog implements the getlog command.
+func handleGetLog(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetLogCmd)
+
+	logger, ok := subsystemLoggers[c.Subsystem]
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("The specified subsystem [%v] is "+
+				"invalid -- supported subsytems %v", c.Subsystem,
+				supportedSubsystems()),
+		}
+	}
+	count := 100
+	if c.Count != nil {
+		count = *c.Count
+	}
+	if count < 1 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Count must be positive",
+		}
+	}
+
+	capturing, ok := logger.(*capturingLogger)
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Log capture is disabled -- see the logbuffer option",
+		}
+	}
+	return capturing.Lines(count), nil
+}
+
 // handleGetMempoolInfo implements the getmempoolinfo command.
-func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetMempoolInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	mempoolTxns := s.server.txMemPool.TxDescs()
 
 	var numBytes int64
@@ -2198,7 +4393,7 @@ func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 
 // handleGetMiningInfo implements the getmininginfo command. We only return the
 // fields that are not related to wallet functionality.
-func handleGetMiningInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetMiningInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	sha, height, err := s.server.db.NewestSha()
 	if err != nil {
 		context := "Failed to get newest hash"
@@ -2219,7 +4414,7 @@ func handleGetMiningInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	// use of the existing getnetworkhashps handler.
 	gnhpsCmd := btcjson.NewGetNetworkHashPSCmd(nil, nil)
 	networkHashesPerSecIface, err := handleGetNetworkHashPS(s, gnhpsCmd,
-		closeChan)
+		ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -2247,7 +4442,7 @@ func handleGetMiningInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 }
 
 // handleGetNetTotals implements the getnettotals command.
-func handleGetNetTotals(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetNetTotals(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	totalBytesRecv, totalBytesSent := s.server.NetTotals()
 	reply := &btcjson.GetNetTotalsResult{
 		TotalBytesRecv: totalBytesRecv,
@@ -2258,7 +4453,7 @@ func handleGetNetTotals(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 }
 
 // handleGetNetworkHashPS implements the getnetworkhashps command.
-func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	// Note: All valid error return paths should return an int64.
 	// Literal zeros are inferred as int, and won't coerce to int64
 	// because the return value is an interface{}.
@@ -2306,24 +4501,99 @@ func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	rpcsLog.Debugf("Calculating network hashes per second from %d to %d",
 		startHeight, endHeight)
 
+	hashesPerSec, err := calcNetworkHashPS(s, startHeight, endHeight)
+	if err != nil {
+		return nil, err
+	}
+	return hashesPerSec, nil
+}
+
+// networksInfo returns the reachability of the networks the server can connect
+// to for the getnetworkinfo command.
+func networksInfo() []btcjson.NetworksResult {
+	onionProxy := cfg.OnionProxy
+	if onionProxy == "" {
+		onionProxy = cfg.Proxy
+	}
+	onionReachable := !cfg.NoOnion && onionProxy != ""
+	if !onionReachable {
+		onionProxy = ""
+	}
+
+	return []btcjson.NetworksResult{
+		{Name: "ipv4", Reachable: true, Proxy: cfg.Proxy},
+		{Name: "ipv6", Reachable: true, Proxy: cfg.Proxy},
+		{
+			Name:      "onion",
+			Limited:   !onionReachable,
+			Reachable: onionReachable,
+			Proxy:     onionProxy,
+		},
+	}
+}
+
+// handleGetNetworkInfo implements the getnetworkinfo command.
+func handleGetNetworkInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	localAddrs := s.server.addrManager.LocalAddresses()
+	localAddrsResult := make([]btcjson.LocalAddressesResult, 0,
+		len(localAddrs))
+	for _, la := range localAddrs {
+		localAddrsResult = append(localAddrsResult,
+			btcjson.LocalAddressesResult{
+				Address: la.NetAddress.IP.String(),
+				Port:    la.NetAddress.Port,
+				Score:   int32(la.Score),
+			})
+	}
+
+	// The dust limit is derived from the fee rate used to determine
+	// whether an output is dust, which follows the minimum relay fee
+	// unless it is configured separately.
+	mpCfg := &s.server.txMemPool.cfg
+	dustRelayFee := mpCfg.Policy.DustRelayFee
+	ret := &btcjson.GetNetworkInfoResult{
+		Version:         int32(1000000*appMajor + 10000*appMinor + 100*appPatch),
+		ProtocolVersion: int32(maxProtocolVersion),
+		TimeOffset:      int64(s.server.timeSource.Offset().Seconds()),
+		Connections:     s.server.ConnectedCount(),
+		Networks:        networksInfo(),
+		RelayFee:        mpCfg.MinRelayTxFee.ToBTC(),
+		DustRelayFee:    dustRelayFee.ToBTC(),
+		DustLimit:       dustThreshold(dustRelayFee).ToBTC(),
+		LocalAddresses:  localAddrsResult,
+	}
+
+	return ret, nil
+}
+
+// calcNetworkHashPS returns the estimated network hashes per second between the
+// passed heights of the main chain.  The work of the blocks after startHeight
+// up to and including endHeight is divided by the time between the earliest
+// and latest block timestamps in that range.  Only the block headers are
+// fetched from the database.
+func calcNetworkHashPS(s *rpcServer, startHeight, endHeight int32) (int64, error) {
+	hashes, err := s.server.db.FetchHeightRange(startHeight, endHeight+1)
+	if err != nil || len(hashes) != int(endHeight-startHeight+1) {
+		if err == nil {
+			err = fmt.Errorf("expected %d hashes, got %d",
+				endHeight-startHeight+1, len(hashes))
+		}
+		context := "Failed to fetch block hash"
+		return 0, internalRPCError(err.Error(), context)
+	}
+
 	// Find the min and max block timestamps as well as calculate the total
 	// amount of work that happened between the start and end blocks.
 	var minTimestamp, maxTimestamp time.Time
 	totalWork := big.NewInt(0)
-	for curHeight := startHeight; curHeight <= endHeight; curHeight++ {
-		hash, err := s.server.db.FetchBlockShaByHeight(curHeight)
-		if err != nil {
-			context := "Failed to fetch block hash"
-			return nil, internalRPCError(err.Error(), context)
-		}
-
-		header, err := s.server.db.FetchBlockHeaderBySha(hash)
+	for i := range hashes {
+		header, err := s.server.db.FetchBlockHeaderBySha(&hashes[i])
 		if err != nil {
 			context := "Failed to fetch block header"
-			return nil, internalRPCError(err.Error(), context)
+			return 0, internalRPCError(err.Error(), context)
 		}
 
-		if curHeight == startHeight {
+		if i == 0 {
 			minTimestamp = header.Timestamp
 			maxTimestamp = minTimestamp
 		} else {
@@ -2343,18 +4613,43 @@ func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	// time difference.
 	timeDiff := int64(maxTimestamp.Sub(minTimestamp) / time.Second)
 	if timeDiff == 0 {
-		return int64(0), nil
+		return 0, nil
+	}
+
+	hashesPerSec := new(big.Int).Div(totalWork, big.NewInt(timeDiff))
+	return hashesPerSec.Int64(), nil
+}
+
+// handleGetNetworkFaults implements the getnetworkfaults command.
+func handleGetNetworkFaults(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	if err := simNetOnlyError("getnetworkfaults"); err != nil {
+		return nil, err
+	}
+	return s.server.networkFaults.results(), nil
+}
+
+// handleGetNetworkTopology implements the getnetworktopology command.
+func handleGetNetworkTopology(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	peers := s.server.Peers()
+	topologyPeers := make([]topologyPeer, 0, len(peers))
+	for _, sp := range peers {
+		if sp.NA() == nil {
+			continue
+		}
+		topologyPeers = append(topologyPeers, topologyPeer{
+			na:      sp.NA(),
+			inbound: sp.Inbound(),
+		})
 	}
-
-	hashesPerSec := new(big.Int).Div(totalWork, big.NewInt(timeDiff))
-	return hashesPerSec.Int64(), nil
+	return networkTopology(topologyPeers, cfg.asMap), nil
 }
 
 // handleGetPeerInfo implements the getpeerinfo command.
-func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetPeerInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	peers := s.server.Peers()
 	syncPeer := s.server.blockManager.SyncPeer()
 	infos := make([]*btcjson.GetPeerInfoResult, 0, len(peers))
+	now := time.Now()
 	for _, p := range peers {
 		statsSnap := p.StatsSnapshot()
 		info := &btcjson.GetPeerInfoResult{
@@ -2373,11 +4668,18 @@ func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 			Inbound:        statsSnap.Inbound,
 			StartingHeight: statsSnap.StartingHeight,
 			CurrentHeight:  statsSnap.LastBlock,
-			BanScore:       0,
+			BanScore:       int32(p.misbehavior.Total(now)),
 			SyncNode:       p == syncPeer,
 		}
+		if breakdown := p.misbehavior.Breakdown(now); len(breakdown) > 0 {
+			info.BanScoreReasons = make(map[string]int32,
+				len(breakdown))
+			for reason, score := range breakdown {
+				info.BanScoreReasons[reason] = int32(score)
+			}
+		}
 		if p.LastPingNonce() != 0 {
-			wait := float64(time.Now().Sub(statsSnap.LastPingTime).Nanoseconds())
+			wait := float64(now.Sub(statsSnap.LastPingTime).Nanoseconds())
 			// We actually want microseconds.
 			info.PingWait = wait / 1000
 		}
@@ -2386,8 +4688,32 @@ func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return infos, nil
 }
 
+// handleGetPolicyInfo implements the getpolicyinfo command.
+func handleGetPolicyInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	mpCfg := &s.server.txMemPool.cfg
+	policy := &mpCfg.Policy
+	result := &btcjson.GetPolicyInfoResult{
+		RelayNonStd:              activeNetParams.RelayNonStdTxs,
+		MinRelayTxFee:            mpCfg.MinRelayTxFee.ToBTC(),
+		DustRelayFee:             policy.DustRelayFee.ToBTC(),
+		AcceptDataCarrier:        policy.AcceptDataCarrier,
+		MaxDataCarrierSize:       policy.MaxDataCarrierSize,
+		MaxDataCarrierOutputs:    policy.MaxDataCarrierOutputs,
+		AcceptBareMultisig:       policy.AcceptBareMultisig,
+		MaxSigOpsPerTx:           policy.MaxSigOpsPerTx,
+		MaxStandardTxWeight:      maxStandardTxWeight,
+		MaxStandardSigScriptSize: maxStandardSigScriptSize,
+		MaxStandardMultiSigKeys:  maxStandardMultiSigKeys,
+		MaxAncestorCount:         mpCfg.MaxAncestorCount,
+		MaxAncestorSize:          mpCfg.MaxAncestorSize,
+		MaxDescendantCount:       mpCfg.MaxDescendantCount,
+		MaxDescendantSize:        mpCfg.MaxDescendantSize,
+	}
+	return result, nil
+}
+
 // handleGetRawMempool implements the getrawmempool command.
-func handleGetRawMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetRawMempool(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetRawMempoolCmd)
 	mp := s.server.txMemPool
 	descs := mp.TxDescs()
@@ -2450,7 +4776,7 @@ func handleGetRawMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 }
 
 // handleGetRawTransaction implements the getrawtransaction command.
-func handleGetRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetRawTransaction(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetRawTransactionCmd)
 
 	// Convert the provided transaction hash hex to a ShaHash.
@@ -2561,8 +4887,211 @@ func reverseUint32Array(b []byte) {
 	}
 }
 
+// handleGetRPCInfo implements the getrpcinfo command.
+func handleGetRPCInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	watched := s.ntfnMgr.Watched()
+	websockets := make([]btcjson.WebsocketClientInfo, 0, len(watched))
+	for _, w := range watched {
+		websockets = append(websockets, btcjson.WebsocketClientInfo{
+			Addr:             w.wsc.addr,
+			SessionID:        w.wsc.sessionID,
+			WatchedAddrs:     w.addrs,
+			WatchedOutPoints: w.outPoints,
+		})
+	}
+	sort.Sort(wsClientsByAddr(websockets))
+
+	return &btcjson.GetRPCInfoResult{
+		HTTPClients:         int(atomic.LoadInt32(&s.numClients)),
+		MaxHTTPClients:      cfg.RPCMaxClients,
+		ReadOnlyClients:     int(atomic.LoadInt32(&s.numROClients)),
+		MaxReadOnlyClients:  cfg.RPCMaxROClients,
+		Connections:         s.conns.Count(),
+		MaxConnections:      cfg.RPCMaxConns,
+		ReadOnlyConns:       s.roConns.Count(),
+		MaxReadOnlyConns:    cfg.RPCMaxROConns,
+		MaxWebsockets:       cfg.RPCMaxWebsockets,
+		MaxWatchedAddrs:     cfg.RPCMaxWatchAddrs,
+		MaxWatchedOutPoints: cfg.RPCMaxWatchOuts,
+		Websockets:          websockets,
+	}, nil
+}
+
+// wsClientsByAddr provides a sort.Interface for a slice of websocket client
+// infos which sorts them by their remote addresses.
+type wsClientsByAddr []btcjson.WebsocketClientInfo
+
+// Len returns the number of clients in the slice.  It is part of the
+// sort.Interface implementation.
+func (s wsClientsByAddr) Len() int {
+	return len(s)
+}
+
+// Swap swaps the clients at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s wsClientsByAddr) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the remote address of the client with index i sorts
+// before the one of the client with index j.  It is part of the sort.Interface
+// implementation.
+func (s wsClientsByAddr) Less(i, j int) bool {
+	return s[i].Addr < s[j].Addr
+}
+
+// handleGetSpendingInfo implements the getspendinginfo command.
+func handleGetSpendingInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	if !cfg.STXOIndex {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Spent output index must be enabled (--stxoindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetSpendingInfoCmd)
+	txHash, err := wire.NewShaHashFromStr(c.Txid)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Txid)
+	}
+	includeMempool := true
+	if c.IncludeMempool != nil {
+		includeMempool = *c.IncludeMempool
+	}
+
+	// Look up the transaction which created the output to ensure it
+	// exists.  Unconfirmed transactions can only have been spent by other
+	// transactions in the memory pool.
+	var mtx *wire.MsgTx
+	confirmed := false
+	txList, err := s.server.db.FetchTxBySha(txHash)
+	if err == nil && len(txList) != 0 {
+		mtx = txList[len(txList)-1].Tx
+		confirmed = true
+	} else if includeMempool {
+		tx, err := s.server.txMemPool.FetchTransaction(txHash)
+		if err == nil {
+			mtx = tx.MsgTx()
+		}
+	}
+	if mtx == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoTxInfo,
+			Message: "No information available about transaction",
+		}
+	}
+	if c.Vout >= uint32(len(mtx.TxOut)) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidTxVout,
+			Message: "Ouput index number (vout) does not exist " +
+				"for transaction.",
+		}
+	}
+
+	result := &btcjson.GetSpendingInfoResult{
+		TxID: c.Txid,
+		Vout: c.Vout,
+	}
+	outPoint := wire.NewOutPoint(txHash, c.Vout)
+	if confirmed {
+		spend, err := s.server.db.FetchSpendingTx(outPoint)
+		if err != nil && err != database.ErrSpendMissing {
+			context := "Failed to fetch spending transaction"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		// The index is only rewound after the indexer is notified of
+		// a disconnected block, so ignore spends from blocks which are
+		// no longer in the main chain.
+		if spend != nil {
+			sha, err := s.server.db.FetchBlockShaByHeight(spend.Height)
+			if err != nil || !sha.IsEqual(&spend.BlkSha) {
+				spend = nil
+			}
+		}
+		if spend != nil {
+			result.Spent = true
+			result.SpentBy = &btcjson.SpendingInputResult{
+				TxID:      spend.TxSha.String(),
+				Vin:       spend.InputIndex,
+				BlockHash: spend.BlkSha.String(),
+				Height:    spend.Height,
+			}
+			return result, nil
+		}
+
+		if !s.server.indexManager.IsCaughtUp(s.server.stxoIndexer) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCMisc,
+				Message: "Spending information not yet available, " +
+					"the spent output index is still being built",
+			}
+		}
+	}
+
+	if includeMempool {
+		if tx := s.server.txMemPool.CheckSpend(*outPoint); tx != nil {
+			for i, txIn := range tx.MsgTx().TxIn {
+				if txIn.PreviousOutPoint != *outPoint {
+					continue
+				}
+				result.Spent = true
+				result.SpentBy = &btcjson.SpendingInputResult{
+					TxID: tx.Sha().String(),
+					Vin:  uint32(i),
+				}
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// handleGetSyncStatus implements the getsyncstatus command.
+func handleGetSyncStatus(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	sha, height, err := s.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get newest hash"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	// The best header is the tip of the chain in headers-only mode.
+	bm := s.server.blockManager
+	var blkHeader *wire.BlockHeader
+	if cfg.HeadersOnly {
+		sha, height = bm.headerChain.BestHeader()
+		blkHeader, _, _, err = bm.headerChain.HeaderByHash(sha)
+	} else {
+		blkHeader, err = s.server.db.FetchBlockHeaderBySha(sha)
+	}
+	if err != nil {
+		context := "Failed to get block header"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	status := bm.SyncStatus()
+	tipAge := s.server.timeSource.AdjustedTime().Sub(blkHeader.Timestamp)
+	if tipAge < 0 {
+		tipAge = 0
+	}
+	return &btcjson.GetSyncStatusResult{
+		InitialBlockDownload: bm.IsInitialBlockDownload(),
+		Current:              status.current,
+		Blocks:               height,
+		BestBlockHash:        sha.String(),
+		TipTime:              blkHeader.Timestamp.Unix(),
+		TipAge:               int64(tipAge.Seconds()),
+		SyncPeer:             status.syncPeerAddr,
+		SyncPeerHeight:       status.syncPeerHeight,
+		VerificationProgress: verificationProgress(height,
+			status.syncPeerHeight, tipAge),
+		Stalls: status.stalls,
+	}, nil
+}
+
 // handleGetTxOut handles gettxout commands.
-func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetTxOut(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetTxOutCmd)
 
 	// Convert the provided transaction hash hex to a ShaHash.
@@ -2571,6 +5100,13 @@ func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 		return nil, rpcDecodeHexError(c.Txid)
 	}
 
+	// While the main chain is catching up with the UTXO snapshot the node
+	// is bootstrapped from, the output is looked up in the snapshot.  The
+	// memory pool is empty during that time.
+	if s.server.blockManager.snapshotAhead() {
+		return snapshotTxOut(s, wire.NewOutPoint(txHash, c.Vout)), nil
+	}
+
 	// If requested and the tx is available in the mempool try to fetch it
 	// from there, otherwise attempt to fetch from the block database.
 	var mtx *wire.MsgTx
@@ -2676,6 +5212,201 @@ func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return txOutReply, nil
 }
 
+// snapshotTxOut returns the gettxout result for the passed outpoint as of the
+// tip of the UTXO snapshot the node is bootstrapped from, or nil when it is
+// not an unspent output.  The snapshot doesn't keep the transactions or the
+// blocks the outputs were created in, so the best block is the tip of the
+// snapshot and the transaction version is not known.
+func snapshotTxOut(s *rpcServer, outPoint *wire.OutPoint) interface{} {
+	coin, tipHash, tipHeight := s.server.blockManager.snapshot.fetchCoin(outPoint)
+	if coin == nil {
+		return nil
+	}
+
+	// Ignore the errors like handleGetTxOut does.
+	script := coin.pkScript
+	disbuf, _ := txscript.DisasmString(script)
+	scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(script,
+		s.server.chainParams)
+	addresses := make([]string, len(addrs))
+	for i, addr := range addrs {
+		addresses[i] = addr.EncodeAddress()
+	}
+
+	return &btcjson.GetTxOutResult{
+		BestBlock:     tipHash.String(),
+		Confirmations: int64(1 + tipHeight - coin.height),
+		Value:         coinutil.Amount(coin.amount).ToUnit(coinutil.AmountBTC),
+		ScriptPubKey: btcjson.ScriptPubKeyResult{
+			Asm:       disbuf,
+			Hex:       hex.EncodeToString(script),
+			ReqSigs:   int32(reqSigs),
+			Type:      scriptClass.String(),
+			Addresses: addresses,
+		},
+		Coinbase: coin.coinbase,
+	}
+}
+
+// handleGetTxOutSetInfo implements the gettxoutsetinfo command.
+//
+// The muhash hash type returns the rolling hash of the unspent transaction
+// outputs the chain maintains as blocks are connected, so unlike the other
+// hash types it doesn't need to iterate the set and omits its statistics.
+func handleGetTxOutSetInfo(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.GetTxOutSetInfoCmd)
+	hashType := "hash_serialized"
+	if c.HashType != nil {
+		hashType = *c.HashType
+	}
+	switch hashType {
+	case "hash_serialized", "muhash", "none":
+	default:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Unknown hash_type: " + hashType,
+		}
+	}
+
+	sha, height, err := s.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get best block"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if hashType == "muhash" {
+		utxoSetHash, err := s.server.blockManager.UtxoSetHash(sha)
+		if err != nil {
+			context := "Failed to calculate the UTXO set hash"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		return &btcjson.GetTxOutSetInfoResult{
+			Height:    height,
+			BestBlock: sha.String(),
+			MuHash:    utxoSetHash.String(),
+		}, nil
+	}
+
+	coins, baseHash, err := buildUtxoSet(s.server.db, height)
+	if err != nil {
+		context := "Failed to build UTXO set"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	result := &btcjson.GetTxOutSetInfoResult{
+		Height:    height,
+		BestBlock: baseHash.String(),
+		TxOuts:    int64(len(coins)),
+	}
+	var totalAmount int64
+	for i, coin := range coins {
+		// The coins are sorted by outpoint, so the outputs of each
+		// transaction are next to each other.
+		if i == 0 || coin.outPoint.Hash != coins[i-1].outPoint.Hash {
+			result.Transactions++
+		}
+		totalAmount += coin.amount
+	}
+	result.TotalAmount = coinutil.Amount(totalAmount).ToBTC()
+
+	// The set hash of a snapshot only commits to its coins, so it is the
+	// same as the one of a snapshot taken with dumputxoset at this block.
+	if hashType == "hash_serialized" {
+		setHash, err := writeUtxoSnapshot(ioutil.Discard,
+			&utxoSnapshotHeader{}, coins)
+		if err != nil {
+			context := "Failed to hash UTXO set"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		result.HashSerialized = setHash.String()
+	}
+	return result, nil
+}
+
+// handleGetEvictedTransactions implements the getevictedtransactions command.
+func handleGetEvictedTransactions(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	descs := s.server.txMemPool.EvictedTxDescs()
+	results := make([]btcjson.EvictedTransactionResult, 0, len(descs))
+	for _, desc := range descs {
+		hexTx, err := messageToHex(desc.Tx.MsgTx())
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, btcjson.EvictedTransactionResult{
+			TxID:    desc.Tx.Sha().String(),
+			Hex:     hexTx,
+			Added:   desc.Added.Unix(),
+			Evicted: desc.Evicted.Unix(),
+		})
+	}
+	return results, nil
+}
+
+// handleGetUnconfirmedBroadcasts implements the getunconfirmedbroadcasts
+// command.
+func handleGetUnconfirmedBroadcasts(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	broadcasts := s.server.UnconfirmedBroadcasts()
+	results := make([]btcjson.UnconfirmedBroadcastResult, 0, len(broadcasts))
+	for _, broadcast := range broadcasts {
+		results = append(results, btcjson.UnconfirmedBroadcastResult{
+			TxID:          broadcast.tx.Sha().String(),
+			Submitted:     broadcast.submitted.Unix(),
+			LastBroadcast: broadcast.lastBroadcast.Unix(),
+			Broadcasts:    broadcast.broadcasts,
+		})
+	}
+	return results, nil
+}
+
+// handleGetUtxoCommitment implements the getutxocommitment command.
+func handleGetUtxoCommitment(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	// Default to the best block of the main chain when no block is given.
+	c := cmd.(*btcjson.GetUtxoCommitmentCmd)
+	var sha *wire.ShaHash
+	var height int32
+	var err error
+	if c.Hash == nil {
+		sha, height, err = s.server.db.NewestSha()
+		if err != nil {
+			context := "Failed to get newest hash"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	} else {
+		sha, err = wire.NewShaHashFromStr(*c.Hash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.Hash)
+		}
+		height, err = s.server.db.FetchBlockHeightBySha(sha)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found",
+			}
+		}
+	}
+
+	utxoSetHash, err := s.server.blockManager.UtxoSetHash(sha)
+	if err != nil {
+		context := "Failed to calculate the UTXO set hash"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	block, err := s.server.db.FetchBlockBySha(sha)
+	if err != nil {
+		context := "Failed to fetch block"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	result := &btcjson.GetUtxoCommitmentResult{
+		Hash:        sha.String(),
+		Height:      height,
+		UtxoSetHash: utxoSetHash.String(),
+	}
+	commitment, ok := blockchain.ExtractUtxoCommitment(
+		block.Transactions()[0])
+	if ok {
+		result.Commitment = commitment.String()
+	}
+	return result, nil
+}
+
 // handleGetWorkRequest is a helper for handleGetWork which deals with
 // generating and returning work to the caller.
 //
@@ -2709,7 +5440,7 @@ func handleGetWorkRequest(s *rpcServer) (interface{}, error) {
 
 		// Choose a payment address at random.
 		payToAddr := cfg.miningAddrs[rand.Intn(len(cfg.miningAddrs))]
-		template, err := NewBlockTemplate(s.policy, s.server, payToAddr)
+		template, err := NewBlockTemplate(s.policy.Policy(), s.server, payToAddr)
 		if err != nil {
 			context := "Failed to create new block template"
 			return nil, internalRPCError(err.Error(), context)
@@ -2898,7 +5629,7 @@ func handleGetWorkSubmission(s *rpcServer, hexData string) (interface{}, error)
 	msgBlock.Header.Timestamp = submittedHeader.Timestamp
 	msgBlock.Header.Nonce = submittedHeader.Nonce
 	msgBlock.Transactions[0].TxIn[0].SignatureScript = blockInfo.signatureScript
-	merkles := blockchain.BuildMerkleTreeStore(block.Transactions())
+	merkles := blockchain.BuildMerkleTreeStore(block.Transactions(), false)
 	msgBlock.Header.MerkleRoot = *merkles[len(merkles)-1]
 
 	// Ensure the submitted block hash is less than the target difficulty.
@@ -2945,7 +5676,7 @@ func handleGetWorkSubmission(s *rpcServer, hexData string) (interface{}, error)
 }
 
 // handleGetWork implements the getwork command.
-func handleGetWork(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetWork(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.GetWorkCmd)
 
 	// Respond with an error if there are no addresses to pay the created
@@ -2957,24 +5688,9 @@ func handleGetWork(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 		}
 	}
 
-	// Return an error if there are no peers connected since there is no
-	// way to relay a found block or receive transactions to work on.
-	// However, allow this state when running in the regression test or
-	// simulation test mode.
-	if !(cfg.RegressionTest || cfg.SimNet) && s.server.ConnectedCount() == 0 {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCClientNotConnected,
-			Message: "Bitcoin is not connected",
-		}
-	}
-
-	// No point in generating or accepting work before the chain is synced.
-	_, currentHeight := s.server.blockManager.chainState.Best()
-	if currentHeight != 0 && !s.server.blockManager.IsCurrent() {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCClientInInitialDownload,
-			Message: "Bitcoin is downloading blocks...",
-		}
+	// Don't generate or accept work the server can't act on.
+	if err := checkTemplateReady(s); err != nil {
+		return nil, err
 	}
 
 	// Protect concurrent access from multiple RPC invocations for work
@@ -2994,7 +5710,7 @@ func handleGetWork(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 }
 
 // handleHelp implements the help command.
-func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleHelp(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.HelpCmd)
 
 	// Provide a usage overview of all commands when no specific command
@@ -3032,15 +5748,146 @@ func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 	return help, nil
 }
 
+// handleLoadUtxoSet implements the loadutxoset command.
+//
+// The block database stores full blocks rather than a standalone UTXO set, so
+// a snapshot can't be used to seed it.  Instead, the snapshot is verified to
+// be intact, to be for the active network, and to match the UTXO set of the
+// local main chain at the snapshot's base block.  Bootstrapping a node from a
+// snapshot is done with the --assumeutxo option instead.
+func handleLoadUtxoSet(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.LoadUtxoSetCmd)
+	path := utxoSnapshotPath(c.Path)
+
+	var expectedHash *wire.ShaHash
+	if c.ExpectedHash != nil {
+		var err error
+		expectedHash, err = wire.NewShaHashFromStr(*c.ExpectedHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.ExpectedHash)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Failed to open snapshot file: " + err.Error(),
+		}
+	}
+	header, coins, setHash, err := readUtxoSnapshot(bufio.NewReader(f))
+	f.Close()
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "Invalid UTXO snapshot: " + err.Error(),
+		}
+	}
+	if header.net != activeNetParams.Net {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCVerify,
+			Message: fmt.Sprintf("UTXO snapshot is for network %v, "+
+				"not %v", header.net, activeNetParams.Net),
+		}
+	}
+	if expectedHash != nil && !expectedHash.IsEqual(setHash) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCVerify,
+			Message: fmt.Sprintf("UTXO snapshot hash %v does not "+
+				"match the expected hash %v", setHash, expectedHash),
+		}
+	}
+
+	// The base block must be part of the main chain at the recorded height
+	// for the local UTXO set to be comparable.
+	sha, err := s.server.db.FetchBlockShaByHeight(header.baseHeight)
+	if err != nil || !sha.IsEqual(&header.baseHash) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCBlockNotFound,
+			Message: fmt.Sprintf("Base block %v is not in the main "+
+				"chain at height %d", header.baseHash,
+				header.baseHeight),
+		}
+	}
+
+	localCoins, _, err := buildUtxoSet(s.server.db, header.baseHeight)
+	if err != nil {
+		context := "Failed to build UTXO set"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	localHeader := *header
+	localHeader.numCoins = uint64(len(localCoins))
+	localHash, err := writeUtxoSnapshot(ioutil.Discard, &localHeader,
+		localCoins)
+	if err != nil {
+		context := "Failed to hash UTXO set"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if !localHash.IsEqual(setHash) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCVerify,
+			Message: fmt.Sprintf("UTXO snapshot hash %v does not "+
+				"match the local UTXO set hash %v", setHash,
+				localHash),
+		}
+	}
+
+	return &btcjson.LoadUtxoSetResult{
+		Coins:        int64(len(coins)),
+		BaseHash:     header.baseHash.String(),
+		BaseHeight:   header.baseHeight,
+		TxOutSetHash: setHash.String(),
+	}, nil
+}
+
 // handlePing implements the ping command.
-func handlePing(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handlePing(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	// Ask server to ping \o_
 	nonce, err := wire.RandomUint64()
 	if err != nil {
 		return nil, internalRPCError("Not sending ping - failed to "+
 			"generate nonce: "+err.Error(), "")
 	}
-	s.server.BroadcastMessage(wire.NewMsgPing(nonce))
+	s.server.BroadcastMessage(wire.NewMsgPing(nonce))
+
+	return nil, nil
+}
+
+// handleRemoveBroadcast implements the removebroadcast command.
+func handleRemoveBroadcast(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.RemoveBroadcastCmd)
+
+	txHash, err := wire.NewShaHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+	if !s.server.RemoveBroadcast(txHash) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoTxInfo,
+			Message: "Transaction is not being rebroadcast",
+		}
+	}
+
+	return nil, nil
+}
+
+// handleRemoveFilterEntry implements the removefilterentry command.
+func handleRemoveFilterEntry(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.RemoveFilterEntryCmd)
+
+	entry, err := parseTxBlacklistEntry(c.Entry, activeNetParams.Params)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid filter entry: " + err.Error(),
+		}
+	}
+	if !s.server.txBlacklist.Remove(entry) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Entry is not in the transaction blacklist",
+		}
+	}
 
 	return nil, nil
 }
@@ -3083,14 +5930,14 @@ func getMempoolTxsForAddressRange(s *rpcServer, addr coinutil.Address, skip int,
 }
 
 // handleSearchRawTransaction implements the searchrawtransactions command.
-func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleSearchRawTransactions(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	if !cfg.AddrIndex {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
 			Message: "Address index must be enabled (--addrindex)",
 		}
 	}
-	if !s.server.addrIndexer.IsCaughtUp() {
+	if !s.server.indexManager.IsCaughtUp(s.server.addrIndexer) {
 		return nil, &btcjson.RPCError{
 			Code: btcjson.ErrRPCMisc,
 			Message: "Address index has not yet caught up to the " +
@@ -3101,7 +5948,7 @@ func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan
 	c := cmd.(*btcjson.SearchRawTransactionsCmd)
 
 	// Attempt to decode the supplied address.
-	addr, err := coinutil.DecodeAddress(c.Address, s.server.chainParams)
+	addr, err := bech32.DecodeAddress(c.Address, s.server.chainParams)
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCInvalidAddressOrKey,
@@ -3201,6 +6048,12 @@ func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan
 
 	rawTxns := make([]btcjson.SearchRawTransactionsResult, len(addressTxs), len(addressTxs))
 	for i, txReply := range addressTxs {
+		// Looking up the previous outputs of the transactions takes a
+		// while, so stop once the client is gone.
+		if ctx.Err() != nil {
+			return nil, ErrClientQuit
+		}
+
 		txHash := txReply.Sha.String()
 		mtx := txReply.Tx
 
@@ -3244,7 +6097,7 @@ func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan
 }
 
 // handleSendRawTransaction implements the sendrawtransaction command.
-func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleSendRawTransaction(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.SendRawTransactionCmd)
 	// Deserialize and send off to tx relay
 	hexStr := c.HexTx
@@ -3264,26 +6117,35 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 		}
 	}
 
+	// Determine the maximum fee rate the transaction is allowed to pay.
+	// The check is disabled entirely when high fees are explicitly allowed
+	// or a max fee rate of zero is provided.
+	maxFeeRate := defaultMaxRawTxFeeRate
+	if c.AllowHighFees != nil && *c.AllowHighFees {
+		maxFeeRate = 0
+	} else if c.MaxFeeRate != nil {
+		maxFeeRate, err = coinutil.NewAmount(*c.MaxFeeRate)
+		if err != nil || maxFeeRate < 0 {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid max fee rate",
+			}
+		}
+	}
+
 	tx := coinutil.NewTx(msgtx)
-	err = s.server.txMemPool.ProcessTransaction(tx, false, false)
+	err = s.server.txMemPool.ProcessTransaction(tx, false, false,
+		maxFeeRate)
 	if err != nil {
 		// When the error is a rule error, it means the transaction was
 		// simply rejected as opposed to something actually going wrong,
-		// so log it as such.  Otherwise, something really did go wrong,
-		// so log it as an actual error.  In both cases, a JSON-RPC
-		// error is returned to the client with the deserialization
-		// error code (to match bitcoind behavior).
+		// so log it as such.  Otherwise, something really did go wrong
+		// and it is logged as an actual error during the conversion.
 		if _, ok := err.(RuleError); ok {
 			rpcsLog.Debugf("Rejected transaction %v: %v", tx.Sha(),
 				err)
-		} else {
-			rpcsLog.Errorf("Failed to process transaction %v: %v",
-				tx.Sha(), err)
-		}
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCDeserialization,
-			Message: "TX rejected: " + err.Error(),
 		}
+		return nil, txRejectToRPCError(err)
 	}
 
 	// Keep track of all the sendrawtransaction request txns so that they
@@ -3294,8 +6156,180 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 	return tx.Sha().String(), nil
 }
 
+// txRejectToRPCError converts an error returned from the memory pool when
+// processing a transaction to a JSON-RPC error with a code that identifies the
+// specific reason for the rejection.  Errors which are not rule violations are
+// treated as internal errors since they indicate something actually went
+// wrong.
+func txRejectToRPCError(err error) *btcjson.RPCError {
+	rerr, ok := err.(RuleError)
+	if !ok {
+		return internalRPCError(err.Error(), "Failed to process transaction")
+	}
+
+	code := btcjson.ErrRPCTxRejected
+	if txErr, ok := rerr.Err.(TxRuleError); ok {
+		switch txErr.Reason {
+		case RejectReasonAlreadyKnown:
+			code = btcjson.ErrRPCTxAlreadyKnown
+		case RejectReasonMissingInputs:
+			code = btcjson.ErrRPCTxMissingInputs
+		case RejectReasonInsufficientFee:
+			code = btcjson.ErrRPCTxInsufficientFee
+		case RejectReasonTooLongChain:
+			code = btcjson.ErrRPCTxTooLongMempoolChain
+		case RejectReasonHighFee:
+			code = btcjson.ErrRPCTxHighFee
+		}
+	}
+
+	return &btcjson.RPCError{
+		Code:    code,
+		Message: "TX rejected: " + err.Error(),
+	}
+}
+
+// blockTemplatePolicyResult returns the passed mining policy as the result of
+// the setblocktemplatepolicy command.
+func blockTemplatePolicyResult(policy *mining.Policy) *btcjson.BlockTemplatePolicyResult {
+	return &btcjson.BlockTemplatePolicyResult{
+		BlockMaxSize:      policy.BlockMaxSize,
+		BlockMinSize:      policy.BlockMinSize,
+		BlockPrioritySize: policy.BlockPrioritySize,
+		MinFeeRate:        policy.TxMinFreeFee.ToBTC(),
+		MinPriority:       policy.BlockMinPriority,
+		RecentTxAge:       int64(policy.RecentTxAge / time.Second),
+		RecentTxWeight:    policy.RecentTxWeight,
+	}
+}
+
+// handleSetBlockTemplatePolicy implements the setblocktemplatepolicy command.
+func handleSetBlockTemplatePolicy(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.SetBlockTemplatePolicyCmd)
+
+	// Apply the requested changes to the current policy.  Without any
+	// changes the current policy is returned.
+	policy := s.policy.Policy()
+	changes := c.Policy
+	if changes == nil {
+		return blockTemplatePolicyResult(policy), nil
+	}
+	if changes.BlockMaxSize != nil {
+		policy.BlockMaxSize = *changes.BlockMaxSize
+	}
+	if changes.BlockMinSize != nil {
+		policy.BlockMinSize = *changes.BlockMinSize
+	}
+	if changes.BlockPrioritySize != nil {
+		policy.BlockPrioritySize = *changes.BlockPrioritySize
+	}
+	if changes.MinFeeRate != nil {
+		minFeeRate, err := coinutil.NewAmount(*changes.MinFeeRate)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid minimum fee rate: " + err.Error(),
+			}
+		}
+		policy.TxMinFreeFee = minFeeRate
+	}
+	if changes.MinPriority != nil {
+		policy.BlockMinPriority = *changes.MinPriority
+	}
+	if changes.RecentTxAge != nil {
+		policy.RecentTxAge = time.Duration(*changes.RecentTxAge) *
+			time.Second
+	}
+	if changes.RecentTxWeight != nil {
+		policy.RecentTxWeight = *changes.RecentTxWeight
+	}
+	if err := s.policy.SetPolicy(policy); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid block template policy: " + err.Error(),
+		}
+	}
+
+	// Force the next getblocktemplate call to generate a template with the
+	// new policy rather than handing out the cached one.
+	state := s.gbtWorkState
+	state.Lock()
+	state.prevHash = nil
+	state.Unlock()
+
+	rpcsLog.Infof("Block template policy changed: %+v",
+		blockTemplatePolicyResult(policy))
+	return blockTemplatePolicyResult(policy), nil
+}
+
+// handleSetBlockVersion implements the setblockversion command.
+func handleSetBlockVersion(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.SetBlockVersionCmd)
+
+	// Signaling arbitrary versions would vote for rule changes regardless
+	// of the deployments the node implements, so it is limited to the test
+	// networks where it is used to exercise the version bits deployments.
+	if !cfg.RegressionTest && !cfg.SimNet {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "setblockversion is only available on the " +
+				"regression and simulation test networks",
+		}
+	}
+	if c.Version < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Block version must not be negative",
+		}
+	}
+
+	s.server.SetBlockVersionOverride(c.Version)
+
+	// Force the next getblocktemplate and getwork calls to generate a
+	// template with the new version rather than handing out the cached
+	// one.
+	state := s.gbtWorkState
+	state.Lock()
+	state.prevHash = nil
+	state.Unlock()
+	s.workState.Lock()
+	s.workState.prevHash = nil
+	s.workState.Unlock()
+
+	if c.Version == 0 {
+		rpcsLog.Infof("Restored the computed block version")
+	} else {
+		rpcsLog.Infof("Block templates signal version %#08x", c.Version)
+	}
+	return nil, nil
+}
+
+// handleSetNetworkFault implements the setnetworkfault command.
+func handleSetNetworkFault(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.SetNetworkFaultCmd)
+
+	// Dropping and delaying messages is only meant for testing how the
+	// server deals with an unreliable network or partitions, so it is
+	// limited to the simulation test network.
+	if err := simNetOnlyError("setnetworkfault"); err != nil {
+		return nil, err
+	}
+	target, err := networkFaultTarget(c.Target)
+	if err != nil {
+		return nil, err
+	}
+	fault, err := newNetworkFault(target, &c.Fault)
+	if err != nil {
+		return nil, err
+	}
+
+	s.server.networkFaults.set(fault)
+	rpcsLog.Infof("Set network fault for %s: %+v", target, c.Fault)
+	return nil, nil
+}
+
 // handleSetGenerate implements the setgenerate command.
-func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleSetGenerate(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.SetGenerateCmd)
 
 	// Disable generation regardless of the provided generate flag if the
@@ -3330,14 +6364,250 @@ func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return nil, nil
 }
 
+// signRawTxHashTypes maps the flags accepted by the signrawtransaction RPC to
+// their associated signature hash types.
+var signRawTxHashTypes = map[string]txscript.SigHashType{
+	"ALL":                 txscript.SigHashAll,
+	"NONE":                txscript.SigHashNone,
+	"SINGLE":              txscript.SigHashSingle,
+	"ALL|ANYONECANPAY":    txscript.SigHashAll | txscript.SigHashAnyOneCanPay,
+	"NONE|ANYONECANPAY":   txscript.SigHashNone | txscript.SigHashAnyOneCanPay,
+	"SINGLE|ANYONECANPAY": txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+}
+
+// fetchPrevOut attempts to look up the transaction output referenced by the
+// passed outpoint from the memory pool and then the main chain.
+func fetchPrevOut(s *rpcServer, outPoint *wire.OutPoint) (*wire.TxOut, error) {
+	var mtx *wire.MsgTx
+	tx, err := s.server.txMemPool.FetchTransaction(&outPoint.Hash)
+	if err == nil {
+		mtx = tx.MsgTx()
+	} else {
+		txList, err := s.server.db.FetchTxBySha(&outPoint.Hash)
+		if err != nil || len(txList) == 0 {
+			return nil, fmt.Errorf("unable to find transaction %v",
+				outPoint.Hash)
+		}
+		mtx = txList[len(txList)-1].Tx
+	}
+
+	if outPoint.Index >= uint32(len(mtx.TxOut)) {
+		return nil, fmt.Errorf("output %v does not exist", outPoint)
+	}
+	return mtx.TxOut[outPoint.Index], nil
+}
+
+// handleSignRawTransaction implements the signrawtransaction command.
+//
+// Since btcd does not have a wallet, the private keys used to sign must be
+// provided with each call.  The keys are only used for the duration of the
+// call and are never stored.  Previous outputs which are not provided by the
+// caller are looked up in the memory pool and the main chain.
+func handleSignRawTransaction(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.SignRawTransactionCmd)
+
+	// Deserialize the transaction to sign.
+	hexStr := c.RawTx
+	if len(hexStr)%2 != 0 {
+		hexStr = "0" + hexStr
+	}
+	serializedTx, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+	var mtx wire.MsgTx
+	err = mtx.Deserialize(bytes.NewReader(serializedTx))
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "TX decode failed: " + err.Error(),
+		}
+	}
+
+	hashType := txscript.SigHashAll
+	if c.Flags != nil {
+		var ok bool
+		hashType, ok = signRawTxHashTypes[*c.Flags]
+		if !ok {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid sighash parameter",
+			}
+		}
+	}
+
+	// There is no wallet to provide keys, so they must be supplied.
+	if c.PrivKeys == nil || len(*c.PrivKeys) == 0 {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Private keys must be provided since btcd " +
+				"does not have a wallet",
+		}
+	}
+
+	// Decode the private keys and key them by the pay-to-pubkey-hash
+	// address they are able to sign for.
+	params := s.server.chainParams
+	keys := make(map[string]*coinutil.WIF, len(*c.PrivKeys))
+	for _, key := range *c.PrivKeys {
+		wif, err := coinutil.DecodeWIF(key)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid private key: " + err.Error(),
+			}
+		}
+		if !wif.IsForNet(params) {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Private key for wrong network",
+			}
+		}
+		addr, err := coinutil.NewAddressPubKey(wif.SerializePubKey(),
+			params)
+		if err != nil {
+			context := "Failed to create address from private key"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		keys[addr.EncodeAddress()] = wif
+	}
+
+	// Load the previous output scripts and redeem scripts provided by the
+	// caller.
+	prevScripts := make(map[wire.OutPoint][]byte)
+	redeemScripts := make(map[string][]byte)
+	if c.Inputs != nil {
+		for _, input := range *c.Inputs {
+			txHash, err := wire.NewShaHashFromStr(input.Txid)
+			if err != nil {
+				return nil, rpcDecodeHexError(input.Txid)
+			}
+			pkScript, err := hex.DecodeString(input.ScriptPubKey)
+			if err != nil {
+				return nil, rpcDecodeHexError(input.ScriptPubKey)
+			}
+
+			// A redeem script is only required for
+			// pay-to-script-hash outputs.
+			if input.RedeemScript != "" {
+				redeemScript, err := hex.DecodeString(input.RedeemScript)
+				if err != nil {
+					return nil, rpcDecodeHexError(input.RedeemScript)
+				}
+				addr, err := coinutil.NewAddressScriptHash(
+					redeemScript, params)
+				if err != nil {
+					context := "Failed to convert redeem " +
+						"script to pay-to-script-hash"
+					return nil, internalRPCError(err.Error(),
+						context)
+				}
+				redeemScripts[addr.EncodeAddress()] = redeemScript
+			}
+
+			outPoint := wire.OutPoint{Hash: *txHash, Index: input.Vout}
+			prevScripts[outPoint] = pkScript
+		}
+	}
+
+	getKey := txscript.KeyClosure(func(addr coinutil.Address) (*btcec.PrivateKey, bool, error) {
+		wif, ok := keys[addr.EncodeAddress()]
+		if !ok {
+			return nil, false, errors.New("no key for address")
+		}
+		return wif.PrivKey, wif.CompressPubKey, nil
+	})
+	getScript := txscript.ScriptClosure(func(addr coinutil.Address) ([]byte, error) {
+		script, ok := redeemScripts[addr.EncodeAddress()]
+		if !ok {
+			return nil, errors.New("no script for address")
+		}
+		return script, nil
+	})
+
+	// Sign each input that can be signed with the provided keys and
+	// verify the result.  Inputs which fail to sign or verify are reported
+	// as errors rather than failing the entire request so partially signed
+	// transactions can be passed along to other signers.
+	var signErrors []btcjson.SignRawTransactionError
+	for i, txIn := range mtx.TxIn {
+		// The amount of the spent output is only known when it is
+		// looked up rather than provided by the caller.
+		var inputAmount int64
+		pkScript, ok := prevScripts[txIn.PreviousOutPoint]
+		if !ok {
+			prevOut, err := fetchPrevOut(s, &txIn.PreviousOutPoint)
+			if err != nil {
+				signErrors = append(signErrors,
+					newSignRawTxError(txIn, err))
+				continue
+			}
+			pkScript = prevOut.PkScript
+			inputAmount = prevOut.Value
+		}
+
+		// SigHashSingle inputs can only be signed if there is a
+		// corresponding output.
+		if hashType&txscript.SigHashSingle != txscript.SigHashSingle ||
+			i < len(mtx.TxOut) {
+
+			script, err := txscript.SignTxOutput(params, &mtx, i,
+				pkScript, hashType, getKey, getScript,
+				txIn.SignatureScript)
+			if err != nil {
+				signErrors = append(signErrors,
+					newSignRawTxError(txIn, err))
+				continue
+			}
+			txIn.SignatureScript = script
+		}
+
+		vm, err := txscript.NewEngine(pkScript, &mtx, i,
+			txscript.StandardVerifyFlags, s.server.sigCache, inputAmount)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if err != nil {
+			signErrors = append(signErrors, newSignRawTxError(txIn, err))
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(mtx.SerializeSize())
+	if err := mtx.Serialize(&buf); err != nil {
+		context := "Failed to serialize transaction"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return btcjson.SignRawTransactionResult{
+		Hex:      hex.EncodeToString(buf.Bytes()),
+		Complete: len(signErrors) == 0,
+		Errors:   signErrors,
+	}, nil
+}
+
+// newSignRawTxError returns the signrawtransaction error result for the passed
+// transaction input and error.
+func newSignRawTxError(txIn *wire.TxIn, err error) btcjson.SignRawTransactionError {
+	return btcjson.SignRawTransactionError{
+		TxID:      txIn.PreviousOutPoint.Hash.String(),
+		Vout:      txIn.PreviousOutPoint.Index,
+		ScriptSig: hex.EncodeToString(txIn.SignatureScript),
+		Sequence:  txIn.Sequence,
+		Error:     err.Error(),
+	}
+}
+
 // handleStop implements the stop command.
-func handleStop(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	s.server.Stop()
+func handleStop(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	// Stop the server asynchronously since the RPC server waits for all
+	// in-flight requests, including this one, when it shuts down.
+	go s.server.Stop()
 	return "btcd stopping.", nil
 }
 
 // handleSubmitBlock implements the submitblock command.
-func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleSubmitBlock(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.SubmitBlockCmd)
 
 	// Deserialize the submitted block.
@@ -3368,11 +6638,11 @@ func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 }
 
 // handleValidateAddress implements the validateaddress command.
-func handleValidateAddress(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleValidateAddress(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.ValidateAddressCmd)
 
 	result := btcjson.ValidateAddressChainResult{}
-	addr, err := coinutil.DecodeAddress(c.Address, activeNetParams.Params)
+	addr, err := bech32.DecodeAddress(c.Address, activeNetParams.Params)
 	if err != nil {
 		// Return the default value (false) for IsValid.
 		return result, nil
@@ -3381,76 +6651,227 @@ func handleValidateAddress(s *rpcServer, cmd interface{}, closeChan <-chan struc
 	result.Address = addr.EncodeAddress()
 	result.IsValid = true
 
-	return result, nil
-}
-
-func verifyChain(db database.Db, level, depth int32, timeSource blockchain.MedianTimeSource) error {
-	_, curHeight64, err := db.NewestSha()
+	// Describe the script outputs paying to the address are locked with.
+	pkScript, err := txscript.PayToAddrScript(addr)
 	if err != nil {
-		rpcsLog.Errorf("Verify is unable to fetch current block "+
-			"height: %v", err)
+		return result, nil
+	}
+	result.ScriptPubKey = hex.EncodeToString(pkScript)
+	introspectScript(&result, pkScript)
+	switch addr.(type) {
+	case *coinutil.AddressScriptHash:
+		result.IsScript = true
+	case *bech32.AddressWitnessPubKeyHash:
+		result.IsWitness = true
+	case *bech32.AddressWitnessScriptHash:
+		result.IsScript = true
+		result.IsWitness = true
+	}
+	if result.IsWitness {
+		result.WitnessProgram = hex.EncodeToString(addr.ScriptAddress())
 	}
-	curHeight := int32(curHeight64)
 
-	finishHeight := curHeight - depth
-	if finishHeight < 0 {
-		finishHeight = 0
+	// The redeem script of a pay-to-script-hash address is only known once
+	// an output paying to the address has been spent, so look for a spend
+	// in the address index when it is available.
+	scriptHashAddr, ok := addr.(*coinutil.AddressScriptHash)
+	if !ok || !cfg.AddrIndex ||
+		!s.server.indexManager.IsCaughtUp(s.server.addrIndexer) {
+
+		return result, nil
+	}
+	redeemScript, txHash, err := findRedeemScript(s.server.db,
+		scriptHashAddr)
+	if err != nil {
+		context := "Failed to search for redeem script"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if redeemScript != nil {
+		result.RedeemScript = hex.EncodeToString(redeemScript)
+		result.RedeemScriptTxID = txHash.String()
+		result.RedeemScriptType = introspectScript(&result,
+			redeemScript).String()
 	}
-	rpcsLog.Infof("Verifying chain for %d blocks at level %d",
-		curHeight-finishHeight, level)
 
-	for height := curHeight; height > finishHeight; height-- {
-		// Level 0 just looks up the block.
-		sha, err := db.FetchBlockShaByHeight(height)
-		if err != nil {
-			rpcsLog.Errorf("Verify is unable to fetch block at "+
-				"height %d: %v", height, err)
-			return err
+	return result, nil
+}
+
+// introspectScript sets the number of signatures required by the passed
+// script along with the public keys embedded in it in the passed result and
+// returns the class of the script.  The script class is also set in the result
+// unless it was already set.
+func introspectScript(result *btcjson.ValidateAddressChainResult, script []byte) txscript.ScriptClass {
+	class, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(script,
+		activeNetParams.Params)
+	if result.Script == "" {
+		result.Script = class.String()
+	}
+	result.SigsRequired = int32(reqSigs)
+	for _, addr := range addrs {
+		if pubKeyAddr, ok := addr.(*coinutil.AddressPubKey); ok {
+			result.PubKeys = append(result.PubKeys,
+				hex.EncodeToString(pubKeyAddr.ScriptAddress()))
 		}
+	}
+	return class
+}
 
-		block, err := db.FetchBlockBySha(sha)
-		if err != nil {
-			rpcsLog.Errorf("Verify is unable to fetch block at "+
-				"sha %v height %d: %v", sha, height, err)
-			return err
+// findRedeemScript searches the transactions of the passed pay-to-script-hash
+// address in the address index for an input which spends an output paying to
+// the address and returns the redeem script revealed by the input along with
+// the hash of its transaction.  A nil script is returned when no such input
+// has been indexed.
+func findRedeemScript(db database.Db, addr *coinutil.AddressScriptHash) ([]byte, *wire.ShaHash, error) {
+	txReplies, _, err := db.FetchTxsForAddr(addr, 0, math.MaxInt32, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	scriptHash := addr.ScriptAddress()
+	for _, txReply := range txReplies {
+		for _, txIn := range txReply.Tx.TxIn {
+			// The redeem script is the last item pushed by the
+			// signature script of an input spending the address.
+			pushes, err := txscript.PushedData(txIn.SignatureScript)
+			if err != nil || len(pushes) == 0 {
+				continue
+			}
+			script := pushes[len(pushes)-1]
+			if bytes.Equal(coinutil.Hash160(script), scriptHash) {
+				return script, txReply.Sha, nil
+			}
 		}
+	}
 
-		// Level 1 does basic chain sanity checks.
-		if level > 0 {
-			err := blockchain.CheckBlockSanity(block,
-				activeNetParams.PowLimit, timeSource)
-			if err != nil {
-				rpcsLog.Errorf("Verify is unable to "+
-					"validate block at sha %v height "+
-					"%d: %v", sha, height, err)
+	return nil, nil, nil
+}
+
+// verifyChain performs the checks of the passed level on the passed number of
+// most recent blocks, or all blocks when it is 0.  The most thorough level
+// also checks the optional indexes.  The verification stops with the error of
+// the passed context once it is canceled.
+func verifyChain(ctx context.Context, db database.Db, level, depth int32, timeSource blockchain.MedianTimeSource) error {
+	rpcsLog.Infof("Verifying chain for %d blocks at level %d", depth, level)
+	err := checkBlocks(ctx, db, level, depth, timeSource)
+	if err != nil && err == ctx.Err() {
+		rpcsLog.Infof("Chain verify stopped: %v", err)
+		return err
+	}
+	if err != nil {
+		rpcsLog.Errorf("Verify failed: %v", err)
+		return err
+	}
+	if level >= checkLevelIndexes {
+		for _, index := range optionalIndexes(db) {
+			if err := checkIndex(&index); err != nil {
+				rpcsLog.Errorf("Verify of the %s failed: %v",
+					index.name, err)
 				return err
 			}
 		}
 	}
-	rpcsLog.Infof("Chain verify completed successfully")
+	rpcsLog.Infof("Chain verify completed successfully")
+
+	return nil
+}
+
+// handleVerifyChain implements the verifychain command.
+func handleVerifyChain(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.VerifyChainCmd)
+
+	var checkLevel, checkDepth int32
+	if c.CheckLevel != nil {
+		checkLevel = *c.CheckLevel
+	}
+	if c.CheckDepth != nil {
+		checkDepth = *c.CheckDepth
+	}
+
+	err := verifyChain(ctx, s.server.db, checkLevel, checkDepth,
+		s.server.timeSource)
+	if err != nil && err == ctx.Err() {
+		return nil, ErrClientQuit
+	}
+	return err == nil, nil
+}
+
+// signedMessageMagic is the prefix of the messages signed by the
+// signmessagewithprivkey command and verified by the verifymessage command.
+// It keeps signed messages from being valid transactions and matches the
+// message format used by Bitcoin.
+const signedMessageMagic = "Bitcoin Signed Message:\n"
+
+// signedMessageHash returns the hash of the passed message which is signed to
+// sign the message.
+func signedMessageHash(message string) []byte {
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, signedMessageMagic)
+	wire.WriteVarString(&buf, 0, message)
+	return wire.DoubleSha256(buf.Bytes())
+}
+
+// handleSetMockTime implements the setmocktime command.
+func handleSetMockTime(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.SetMockTimeCmd)
+
+	// Overriding the time would break the consensus rules on the main
+	// and public test networks.
+	if !cfg.RegressionTest && !cfg.SimNet {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "setmocktime is only available on the regression " +
+				"and simulation test networks",
+		}
+	}
+	if c.Timestamp < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Timestamp must not be negative",
+		}
+	}
 
-	return nil
+	if c.Timestamp == 0 {
+		s.server.timeSource.SetMockTime(time.Time{})
+		rpcsLog.Infof("Restored the local clock as the current time")
+		return nil, nil
+	}
+	mockTime := time.Unix(c.Timestamp, 0)
+	s.server.timeSource.SetMockTime(mockTime)
+	rpcsLog.Infof("Mocked the current time as %v", mockTime)
+	return nil, nil
 }
 
-// handleVerifyChain implements the verifychain command.
-func handleVerifyChain(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	c := cmd.(*btcjson.VerifyChainCmd)
+// handleSignMessageWithPrivKey implements the signmessagewithprivkey command.
+func handleSignMessageWithPrivKey(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.SignMessageWithPrivKeyCmd)
 
-	var checkLevel, checkDepth int32
-	if c.CheckLevel != nil {
-		checkLevel = *c.CheckLevel
+	wif, err := coinutil.DecodeWIF(c.PrivKey)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid private key: " + err.Error(),
+		}
 	}
-	if c.CheckDepth != nil {
-		checkDepth = *c.CheckDepth
+	if !wif.IsForNet(s.server.chainParams) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Private key for wrong network",
+		}
 	}
 
-	err := verifyChain(s.server.db, checkLevel, checkDepth,
-		s.server.timeSource)
-	return err == nil, nil
+	// The signature records whether the key is compressed so verifiers
+	// recover the public key of the right pay-to-pubkey-hash address.
+	sig, err := btcec.SignCompact(btcec.S256(), wif.PrivKey,
+		signedMessageHash(c.Message), wif.CompressPubKey)
+	if err != nil {
+		context := "Failed to sign message"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return base64.StdEncoding.EncodeToString(sig), nil
 }
 
 // handleVerifyMessage implements the verifymessage command.
-func handleVerifyMessage(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+func handleVerifyMessage(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
 	c := cmd.(*btcjson.VerifyMessageCmd)
 
 	// Decode the provided address.
@@ -3481,10 +6902,7 @@ func handleVerifyMessage(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 
 	// Validate the signature - this just shows that it was valid at all.
 	// we will compare it with the key next.
-	var buf bytes.Buffer
-	wire.WriteVarString(&buf, 0, "Bitcoin Signed Message:\n")
-	wire.WriteVarString(&buf, 0, c.Message)
-	expectedMessageHash := wire.DoubleSha256(buf.Bytes())
+	expectedMessageHash := signedMessageHash(c.Message)
 	pk, wasCompressed, err := btcec.RecoverCompact(btcec.S256(), sig,
 		expectedMessageHash)
 	if err != nil {
@@ -3513,117 +6931,366 @@ func handleVerifyMessage(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	return address.EncodeAddress() == c.Address, nil
 }
 
+// tipNotifier lets RPC handlers wait for the tip of the best chain to change.
+type tipNotifier struct {
+	sync.Mutex
+	changed chan struct{}
+}
+
+// newTipNotifier returns a new instance of a tipNotifier.
+func newTipNotifier() *tipNotifier {
+	return &tipNotifier{changed: make(chan struct{})}
+}
+
+// NotifyTipChanged wakes everything waiting for the tip of the best chain to
+// change.  It must be called whenever a block is connected to or disconnected
+// from the main chain.
+//
+// This function is safe for concurrent access.
+func (n *tipNotifier) NotifyTipChanged() {
+	n.Lock()
+	close(n.changed)
+	n.changed = make(chan struct{})
+	n.Unlock()
+}
+
+// tipChangedChan returns a channel which is closed the next time the tip of
+// the best chain changes.
+//
+// This function is safe for concurrent access.
+func (n *tipNotifier) tipChangedChan() <-chan struct{} {
+	n.Lock()
+	c := n.changed
+	n.Unlock()
+	return c
+}
+
+// waitForChainTip blocks until the passed function reports that the tip of
+// the best chain is the one being waited for, the timeout in milliseconds
+// elapses, the client disconnects or the server shuts down.  A timeout of 0
+// never elapses.  Except when the client disconnected, the best chain tip at
+// that moment is returned.
+func waitForChainTip(s *rpcServer, timeout int64, done func(hash *wire.ShaHash, height int32) bool, ctx context.Context) (interface{}, error) {
+	if timeout < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Timeout must not be negative",
+		}
+	}
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(time.Duration(timeout) * time.Millisecond)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+
+	for {
+		// Get the channel before the current tip so a tip change
+		// between the two can't be missed.
+		tipChanged := s.tipNotifier.tipChangedChan()
+		hash, height, err := s.server.db.NewestSha()
+		if err != nil {
+			context := "Failed to get newest hash"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		if cfg.HeadersOnly {
+			hash, height = s.server.blockManager.headerChain.BestHeader()
+		}
+		result := &btcjson.WaitForBlockResult{
+			Hash:   hash.String(),
+			Height: height,
+		}
+		if done(hash, height) {
+			return result, nil
+		}
+
+		select {
+		case <-tipChanged:
+		case <-timeoutChan:
+			return result, nil
+		case <-s.quit:
+			return result, nil
+		case <-ctx.Done():
+			return nil, ErrClientQuit
+		}
+	}
+}
+
+// handleWaitForBlock implements the waitforblock command.
+func handleWaitForBlock(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.WaitForBlockCmd)
+
+	hash, err := wire.NewShaHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+	done := func(tip *wire.ShaHash, height int32) bool {
+		return tip.IsEqual(hash)
+	}
+	return waitForChainTip(s, *c.Timeout, done, ctx)
+}
+
+// handleWaitForBlockHeight implements the waitforblockheight command.
+func handleWaitForBlockHeight(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.WaitForBlockHeightCmd)
+
+	done := func(tip *wire.ShaHash, height int32) bool {
+		return height >= c.Height
+	}
+	return waitForChainTip(s, *c.Timeout, done, ctx)
+}
+
+// handleWaitForNewBlock implements the waitfornewblock command.
+func handleWaitForNewBlock(s *rpcServer, cmd interface{}, ctx context.Context) (interface{}, error) {
+	c := cmd.(*btcjson.WaitForNewBlockCmd)
+
+	// Wait for the tip to differ from the one at the time of the request.
+	var startTip *wire.ShaHash
+	done := func(tip *wire.ShaHash, height int32) bool {
+		if startTip == nil {
+			startTip = tip
+			return false
+		}
+		return !tip.IsEqual(startTip)
+	}
+	return waitForChainTip(s, *c.Timeout, done, ctx)
+}
+
 // rpcServer holds the items the rpc server may need to access (config,
 // shutdown, main server, etc.)
 type rpcServer struct {
 	started      int32
 	shutdown     int32
-	policy       *mining.Policy
+	policy       *blockTemplatePolicy
 	server       *server
 	authsha      [fastsha256.Size]byte
 	limitauthsha [fastsha256.Size]byte
+	readonlyauth *[fastsha256.Size]byte
 	ntfnMgr      *wsNotificationManager
 	numClients   int32
-	statusLines  map[int]string
-	statusLock   sync.RWMutex
+	numROClients int32
 	wg           sync.WaitGroup
 	listeners    []net.Listener
+	readOnlyLns  []net.Listener
+	httpServers  []*http.Server
+	conns        *connLimiter
+	roConns      *connLimiter
 	workState    *workState
 	gbtWorkState *gbtWorkState
 	helpCacher   *helpCacher
+	chainWork    *chainWorkCache
+	tipNotifier  *tipNotifier
+	rateLimiter  *rpcRateLimiter
+	authLimiter  *rpcAuthLimiter
+	templateNtfn *blockTemplateNotifier
+	rawBlockNtfn *rawBlockNotifier
+	certReloader *certReloader
+	acmeRenewer  *acmeRenewer
 	quit         chan int
+
+	// requests tracks the HTTP requests and websocket connections being
+	// served so shutdown can wait for them.  No new ones are started once
+	// draining is set.
+	reqLock  sync.Mutex
+	draining bool
+	requests sync.WaitGroup
 }
 
-// httpStatusLine returns a response Status-Line (RFC 2616 Section 6.1)
-// for the given request and response status code.  This function was lifted and
-// adapted from the standard library HTTP server code since it's not exported.
-func (s *rpcServer) httpStatusLine(req *http.Request, code int) string {
-	// Fast path:
-	key := code
-	proto11 := req.ProtoAtLeast(1, 1)
-	if !proto11 {
-		key = -key
-	}
-	s.statusLock.RLock()
-	line, ok := s.statusLines[key]
-	s.statusLock.RUnlock()
-	if ok {
-		return line
+// blockHeaderInfo returns the header information of the passed block which is
+// sent to the websocket clients registered for block header notifications.
+func blockHeaderInfo(block *coinutil.Block) *blockchain.HeaderInfo {
+	return &blockchain.HeaderInfo{
+		Header: &block.MsgBlock().Header,
+		Hash:   block.Sha(),
+		Height: block.Height(),
 	}
+}
 
-	// Slow path:
-	proto := "HTTP/1.0"
-	if proto11 {
-		proto = "HTTP/1.1"
-	}
-	codeStr := strconv.Itoa(code)
-	text := http.StatusText(code)
-	if text != "" {
-		line = proto + " " + codeStr + " " + text + "\r\n"
-		s.statusLock.Lock()
-		s.statusLines[key] = line
-		s.statusLock.Unlock()
-	} else {
-		text = "status code " + codeStr
-		line = proto + " " + codeStr + " " + text + "\r\n"
-	}
+// BlockConnected notifies websocket clients of the connected block and its
+// header and wakes the clients waiting for the chain tip to change.  It is
+// called by the event bus of the server.
+func (s *rpcServer) BlockConnected(block *coinutil.Block) {
+	s.ntfnMgr.NotifyBlockConnected(block)
+	s.ntfnMgr.NotifyHeaderConnected(blockHeaderInfo(block))
+	s.rawBlockNtfn.NotifyBlockConnected()
+	s.tipNotifier.NotifyTipChanged()
+}
 
-	return line
+// BlockDisconnected notifies websocket clients of the disconnected block and
+// its header and wakes the clients waiting for the chain tip to change.  It is
+// called by the event bus of the server.
+func (s *rpcServer) BlockDisconnected(block *coinutil.Block) {
+	s.ntfnMgr.NotifyBlockDisconnected(block)
+	s.ntfnMgr.NotifyHeaderDisconnected(blockHeaderInfo(block))
+	s.rawBlockNtfn.NotifyBlockDisconnected(block.Height())
+	s.tipNotifier.NotifyTipChanged()
 }
 
-// writeHTTPResponseHeaders writes the necessary response headers prior to
-// writing an HTTP body given a request to use for protocol negotiation, headers
-// to write, a status code, and a writer.
-func (s *rpcServer) writeHTTPResponseHeaders(req *http.Request, headers http.Header, code int, w io.Writer) error {
-	_, err := io.WriteString(w, s.httpStatusLine(req, code))
-	if err != nil {
-		return err
-	}
+// HeaderConnected notifies websocket clients of a header connected to the main
+// chain of a node running in headers-only mode and wakes the clients waiting
+// for the chain tip to change.  It is called by the event bus of the server.
+func (s *rpcServer) HeaderConnected(header *blockchain.HeaderInfo) {
+	s.ntfnMgr.NotifyHeaderConnected(header)
+	s.tipNotifier.NotifyTipChanged()
+}
 
-	err = headers.Write(w)
-	if err != nil {
-		return err
-	}
+// HeaderDisconnected notifies websocket clients of a header disconnected from
+// the main chain of a node running in headers-only mode and wakes the clients
+// waiting for the chain tip to change.  It is called by the event bus of the
+// server.
+func (s *rpcServer) HeaderDisconnected(header *blockchain.HeaderInfo) {
+	s.ntfnMgr.NotifyHeaderDisconnected(header)
+	s.tipNotifier.NotifyTipChanged()
+}
 
-	_, err = io.WriteString(w, "\r\n")
-	if err != nil {
-		return err
+// ChainReorganized notifies websocket clients of a reorganization of the main
+// chain.  It is called by the event bus of the server.
+func (s *rpcServer) ChainReorganized(reorg *blockchain.ChainReorganization) {
+	s.ntfnMgr.NotifyChainReorg(reorg)
+}
+
+// MempoolTxAccepted notifies websocket clients of a transaction accepted into
+// the memory pool and lets the block template notifiers know the memory pool
+// changed.  It is called by the event bus of the server.
+func (s *rpcServer) MempoolTxAccepted(tx *coinutil.Tx) {
+	s.ntfnMgr.NotifyMempoolTx(tx, true)
+
+	// Potentially notify any getblocktemplate long poll clients about
+	// stale block templates due to the new transaction.
+	s.gbtWorkState.NotifyMempoolTx(s.server.txMemPool.LastUpdated())
+
+	// Push fresh block templates to websocket clients once enough new
+	// fee-paying transactions have arrived.  Templates aren't pushed
+	// during the initial block download since they would build on a stale
+	// block.
+	if !s.server.blockManager.IsInitialBlockDownload() {
+		s.templateNtfn.NotifyMempoolTx(tx)
 	}
+}
 
-	return nil
+// MempoolTxExpired notifies websocket clients of a transaction evicted from the
+// memory pool because it wasn't mined before the configured expiry.  It is
+// called by the event bus of the server.
+func (s *rpcServer) MempoolTxExpired(tx *coinutil.Tx) {
+	s.ntfnMgr.NotifyTxExpired(tx)
+}
+
+// MempoolTxAdded notifies the websocket clients streaming the memory pool
+// deltas of a transaction added to the pool.  It is called by the event bus of
+// the server.
+func (s *rpcServer) MempoolTxAdded(txDesc *mempoolTxDesc) {
+	s.ntfnMgr.NotifyMempoolTxAdded(txDesc)
+}
+
+// MempoolTxRemoved notifies the websocket clients streaming the memory pool
+// deltas of a transaction removed from the pool.  It is called by the event bus
+// of the server.
+func (s *rpcServer) MempoolTxRemoved(tx *coinutil.Tx, reason txRemovalReason) {
+	s.ntfnMgr.NotifyMempoolTxRemoved(tx, reason)
 }
 
-// Stop is used by server.go to stop the rpc listener.
+// Stop is used by server.go to stop the rpc listener.  It stops accepting
+// connections, notifies websocket clients of the shutdown and gives in-flight
+// requests the configured grace period to complete.  Afterwards, remaining
+// websocket clients are disconnected and long polls are aborted.  It only
+// returns once all requests have finished, so the caller may safely shut down
+// the subsystems they use.
 func (s *rpcServer) Stop() error {
 	if atomic.AddInt32(&s.shutdown, 1) != 1 {
 		rpcsLog.Infof("RPC server is already in the process of shutting down")
 		return nil
 	}
 	rpcsLog.Warnf("RPC server shutting down")
-	for _, listener := range s.listeners {
+	for _, listener := range append(s.listeners, s.readOnlyLns...) {
 		err := listener.Close()
 		if err != nil {
 			rpcsLog.Errorf("Problem shutting down rpc: %v", err)
 			return err
 		}
 	}
+
+	// Close idle keep-alive connections and the others once their
+	// requests are served.
+	for _, server := range s.httpServers {
+		server.SetKeepAlivesEnabled(false)
+	}
+
+	// Refuse new requests on connections which were already accepted and
+	// wait up to the grace period for the in-flight requests.
+	s.reqLock.Lock()
+	s.draining = true
+	s.reqLock.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.requests.Wait()
+		close(done)
+	}()
+	s.ntfnMgr.NotifyShutdown(cfg.RPCShutdownGrace)
+	select {
+	case <-done:
+	case <-time.After(cfg.RPCShutdownGrace):
+		rpcsLog.Infof("RPC shutdown grace period expired -- " +
+			"disconnecting remaining clients")
+	}
+
+	// Disconnect the remaining websocket clients, which also aborts their
+	// rescans, abort long polls and wait for all requests to finish.
 	s.ntfnMgr.Shutdown()
 	s.ntfnMgr.WaitForShutdown()
 	close(s.quit)
+	<-done
+	if s.ntfnMgr.journal != nil {
+		if err := s.ntfnMgr.journal.Close(); err != nil {
+			rpcsLog.Errorf("Unable to close event journal: %v", err)
+		}
+	}
 	s.wg.Wait()
 	rpcsLog.Infof("RPC server shutdown complete")
 	return nil
 }
 
+// beginRequest registers a request or websocket connection as in-flight so
+// shutdown waits for it.  It responds with a 503 service unavailable and
+// returns false when the server is shutting down.  endRequest must be called
+// once a request for which true was returned has been served.
+//
+// This function is safe for concurrent access.
+func (s *rpcServer) beginRequest(w http.ResponseWriter) bool {
+	s.reqLock.Lock()
+	defer s.reqLock.Unlock()
+
+	if s.draining {
+		http.Error(w, "503 Server shutting down.",
+			http.StatusServiceUnavailable)
+		return false
+	}
+	s.requests.Add(1)
+	return true
+}
+
+// endRequest marks a request registered with beginRequest as served.
+//
+// This function is safe for concurrent access.
+func (s *rpcServer) endRequest() {
+	s.requests.Done()
+}
+
 // limitConnections responds with a 503 service unavailable and returns true if
-// adding another client would exceed the maximum allow RPC clients.
+// adding another client would exceed the maximum allow RPC clients.  Clients
+// of the read-only listeners are limited separately from the others, so busy
+// block explorers can't starve the regular clients.
 //
 // This function is safe for concurrent access.
-func (s *rpcServer) limitConnections(w http.ResponseWriter, remoteAddr string) bool {
-	if int(atomic.LoadInt32(&s.numClients)+1) > cfg.RPCMaxClients {
+func (s *rpcServer) limitConnections(w http.ResponseWriter, remoteAddr string,
+	readOnly bool) bool {
+
+	numClients, maxClients := &s.numClients, cfg.RPCMaxClients
+	if readOnly {
+		numClients, maxClients = &s.numROClients, cfg.RPCMaxROClients
+	}
+	if int(atomic.LoadInt32(numClients)+1) > maxClients {
 		rpcsLog.Infof("Max RPC clients exceeded [%d] - "+
-			"disconnecting client %s", cfg.RPCMaxClients,
-			remoteAddr)
+			"disconnecting client %s", maxClients, remoteAddr)
 		http.Error(w, "503 Too busy.  Try again later.",
 			http.StatusServiceUnavailable)
 		return true
@@ -3631,21 +7298,31 @@ func (s *rpcServer) limitConnections(w http.ResponseWriter, remoteAddr string) b
 	return false
 }
 
-// incrementClients adds one to the number of connected RPC clients.  Note
-// this only applies to standard clients.  Websocket clients have their own
-// limits and are tracked separately.
+// incrementClients adds one to the number of connected RPC clients of the
+// regular or read-only listeners.  Note this only applies to standard
+// clients.  Websocket clients have their own limits and are tracked
+// separately.
 //
 // This function is safe for concurrent access.
-func (s *rpcServer) incrementClients() {
+func (s *rpcServer) incrementClients(readOnly bool) {
+	if readOnly {
+		atomic.AddInt32(&s.numROClients, 1)
+		return
+	}
 	atomic.AddInt32(&s.numClients, 1)
 }
 
-// decrementClients subtracts one from the number of connected RPC clients.
-// Note this only applies to standard clients.  Websocket clients have their own
-// limits and are tracked separately.
+// decrementClients subtracts one from the number of connected RPC clients of
+// the regular or read-only listeners.  Note this only applies to standard
+// clients.  Websocket clients have their own limits and are tracked
+// separately.
 //
 // This function is safe for concurrent access.
-func (s *rpcServer) decrementClients() {
+func (s *rpcServer) decrementClients(readOnly bool) {
+	if readOnly {
+		atomic.AddInt32(&s.numROClients, -1)
+		return
+	}
 	atomic.AddInt32(&s.numClients, -1)
 }
 
@@ -3654,7 +7331,8 @@ func (s *rpcServer) decrementClients() {
 // does not match the username and password expected, a non-nil error is
 // returned.
 //
-// This check is time-constant.
+// This check is time-constant.  Clients which are locked out by the
+// authentication limiter are refused without checking their credentials.
 //
 // The first bool return value signifies auth success (true if successful) and
 // the second bool return value specifies whether the user can change the state
@@ -3672,6 +7350,9 @@ func (s *rpcServer) checkAuth(r *http.Request, require bool) (bool, bool,
 
 		return false, false, nil
 	}
+	if err := s.authLimiter.Allow(r.RemoteAddr, "HTTP"); err != nil {
+		return false, false, err
+	}
 
 	authsha := fastsha256.Sum256([]byte(authhdr[0]))
 
@@ -3679,20 +7360,49 @@ func (s *rpcServer) checkAuth(r *http.Request, require bool) (bool, bool,
 	// are probably expected to have a higher volume of calls
 	limitcmp := subtle.ConstantTimeCompare(authsha[:], s.limitauthsha[:])
 	if limitcmp == 1 {
+		s.authLimiter.Success(r.RemoteAddr)
 		return true, false, nil
 	}
 
 	// Check for admin-level auth
 	cmp := subtle.ConstantTimeCompare(authsha[:], s.authsha[:])
 	if cmp == 1 {
+		s.authLimiter.Success(r.RemoteAddr)
 		return true, true, nil
 	}
 
 	// Request's auth doesn't match either user
-	rpcsLog.Warnf("RPC authentication failure from %s", r.RemoteAddr)
+	s.authLimiter.Failure(r.RemoteAddr, "HTTP")
 	return false, false, errors.New("auth failure")
 }
 
+// checkReadOnlyAuth checks the HTTP Basic authentication supplied by a client
+// of a read-only listener.  No authentication is required when no read-only
+// credentials are configured.  Like checkAuth, it refuses clients which are
+// locked out by the authentication limiter.
+func (s *rpcServer) checkReadOnlyAuth(r *http.Request) error {
+	if s.readonlyauth == nil {
+		return nil
+	}
+	authhdr := r.Header["Authorization"]
+	if len(authhdr) <= 0 {
+		rpcsLog.Warnf("Read-only RPC authentication failure from %s",
+			r.RemoteAddr)
+		return errors.New("auth failure")
+	}
+	if err := s.authLimiter.Allow(r.RemoteAddr, "read-only HTTP"); err != nil {
+		return err
+	}
+	authsha := fastsha256.Sum256([]byte(authhdr[0]))
+	cmp := subtle.ConstantTimeCompare(authsha[:], s.readonlyauth[:])
+	if cmp == 1 {
+		s.authLimiter.Success(r.RemoteAddr)
+		return nil
+	}
+	s.authLimiter.Failure(r.RemoteAddr, "read-only HTTP")
+	return errors.New("auth failure")
+}
+
 // parsedRPCCmd represents a JSON-RPC request object that has been parsed into
 // a known concrete command along with any error that might have happened while
 // parsing it.
@@ -3707,8 +7417,13 @@ type parsedRPCCmd struct {
 // command and runs the appropriate handler to reply to the command.  Any
 // commands which are not recognized or not implemented will return an error
 // suitable for use in replies.
-func (s *rpcServer) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct{}) (interface{}, error) {
+func (s *rpcServer) standardCmdResult(cmd *parsedRPCCmd, ctx context.Context) (interface{}, error) {
 	handler, ok := rpcHandlers[cmd.method]
+	if cfg.HeadersOnly {
+		if h, exists := rpcHeadersOnlyHandlers[cmd.method]; exists {
+			handler = h
+		}
+	}
 	if ok {
 		goto handled
 	}
@@ -3725,7 +7440,23 @@ func (s *rpcServer) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct
 	return nil, btcjson.ErrRPCMethodNotFound
 handled:
 
-	return handler(s, cmd.cmd, closeChan)
+	return handler(s, cmd.cmd, ctx)
+}
+
+// limitedCmdResult runs the handler of a standard command like
+// standardCmdResult once the command passes the rate limits of the client with
+// the passed remote address.
+func (s *rpcServer) limitedCmdResult(remoteAddr string, cmd *parsedRPCCmd, ctx context.Context) (interface{}, error) {
+	if err := s.rateLimiter.Allow(remoteAddr, cmd.method); err != nil {
+		return nil, err
+	}
+	release, err := s.rateLimiter.AcquireSlot(cmd.method)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return s.standardCmdResult(cmd, ctx)
 }
 
 // parseCmd parses a JSON-RPC request object into known concrete command.  The
@@ -3737,6 +7468,17 @@ func parseCmd(request *btcjson.Request) *parsedRPCCmd {
 	parsedCmd.id = request.ID
 	parsedCmd.method = request.Method
 
+	// Validate the parameters of every method with a schema the same way
+	// before unmarshalling them.  The schema also catches the missing
+	// fields of objects which unmarshal to their zero values otherwise.
+	if schema, ok := rpcMethodSchemas[request.Method]; ok {
+		if err := btcjson.ValidateParams(schema, request.Params); err != nil {
+			parsedCmd.err = btcjson.NewRPCError(
+				btcjson.ErrRPCInvalidParams.Code, err.Error())
+			return &parsedCmd
+		}
+	}
+
 	cmd, err := btcjson.UnmarshalCmd(request)
 	if err != nil {
 		// When the error is because the method is not registered,
@@ -3775,9 +7517,10 @@ func createMarshalledReply(id, result interface{}, replyErr error) ([]byte, erro
 	return btcjson.MarshalResponse(id, result, jsonErr)
 }
 
-// jsonRPCRead handles reading and responding to RPC messages.
+// jsonRPCRead handles reading and responding to RPC messages.  Only the
+// methods in the passed set may be called unless it is nil.
 func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request,
-	isAdmin bool) {
+	allowed map[string]struct{}) {
 	if atomic.LoadInt32(&s.shutdown) != 0 {
 		return
 	}
@@ -3793,32 +7536,12 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	// Unfortunately, the http server doesn't provide the ability to
-	// change the read deadline for the new connection and having one breaks
-	// long polling.  However, not having a read deadline on the initial
-	// connection would mean clients can connect and idle forever.  Thus,
-	// hijack the connecton from the HTTP server, clear the read deadline,
-	// and handle writing the response manually.
-	hj, ok := w.(http.Hijacker)
-	if !ok {
-		errMsg := "webserver doesn't support hijacking"
-		rpcsLog.Warnf(errMsg)
-		errCode := http.StatusInternalServerError
-		http.Error(w, strconv.FormatInt(int64(errCode), 10)+" "+errMsg,
-			errCode)
-		return
-	}
-	conn, buf, err := hj.Hijack()
-	if err != nil {
-		rpcsLog.Warnf("Failed to hijack HTTP connection: %v", err)
-		errCode := http.StatusInternalServerError
-		http.Error(w, strconv.FormatInt(int64(errCode), 10)+" "+
-			err.Error(), errCode)
-		return
-	}
-	defer conn.Close()
-	defer buf.Flush()
-	conn.SetReadDeadline(timeZeroVal)
+	// The read timeout of the HTTP server only covers reading the
+	// request.  Clear the read deadline now that it was read, since it
+	// would otherwise cancel long polls such as getblocktemplate once it
+	// expires.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(timeZeroVal)
 
 	// Attempt to parse the raw body into a JSON-RPC request.
 	var responseID interface{}
@@ -3842,19 +7565,9 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request,
 		// set it for the response.
 		responseID = request.ID
 
-		// Setup a close notifier.  Since the connection is hijacked,
-		// the CloseNotifer on the ResponseWriter is not available.
-		closeChan := make(chan struct{}, 1)
-		go func() {
-			_, err := conn.Read(make([]byte, 1))
-			if err != nil {
-				close(closeChan)
-			}
-		}()
-
 		// Check if the user is limited and set error if method unauthorized
-		if !isAdmin {
-			if _, ok := rpcLimited[request.Method]; !ok {
+		if allowed != nil {
+			if _, ok := allowed[request.Method]; !ok {
 				jsonErr = &btcjson.RPCError{
 					Code:    btcjson.ErrRPCInvalidParams.Code,
 					Message: "limited user not authorized for this method",
@@ -3869,7 +7582,11 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request,
 			if parsedCmd.err != nil {
 				jsonErr = parsedCmd.err
 			} else {
-				result, jsonErr = s.standardCmdResult(parsedCmd, closeChan)
+				// The context of the request is canceled once
+				// the client disconnects, which stops the
+				// handler.
+				result, jsonErr = s.limitedCmdResult(r.RemoteAddr,
+					parsedCmd, r.Context())
 			}
 		}
 	}
@@ -3881,13 +7598,12 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	// Write the response.
-	err = s.writeHTTPResponseHeaders(r, w.Header(), http.StatusOK, buf)
-	if err != nil {
-		rpcsLog.Error(err)
-		return
+	// Write the response within the write timeout, which starts now so
+	// it doesn't include the time spent handling the request.
+	if cfg.RPCWriteTimeout > 0 {
+		rc.SetWriteDeadline(time.Now().Add(cfg.RPCWriteTimeout))
 	}
-	if _, err := buf.Write(msg); err != nil {
+	if _, err := w.Write(msg); err != nil {
 		rpcsLog.Errorf("Failed to write marshalled reply: %v", err)
 	}
 }
@@ -3906,26 +7622,23 @@ func (s *rpcServer) Start() {
 
 	rpcsLog.Trace("Starting RPC server")
 	rpcServeMux := http.NewServeMux()
-	httpServer := &http.Server{
-		Handler: rpcServeMux,
-
-		// Timeout connections which don't complete the initial
-		// handshake within the allowed timeframe.
-		ReadTimeout: time.Second * rpcAuthTimeoutSeconds,
-	}
+	httpServer := newRPCHTTPServer(rpcServeMux)
 	rpcServeMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Connection", "close")
 		w.Header().Set("Content-Type", "application/json")
-		r.Close = true
 
 		// Limit the number of connections to max allowed.
-		if s.limitConnections(w, r.RemoteAddr) {
+		if s.limitConnections(w, r.RemoteAddr, false) {
 			return
 		}
 
-		// Keep track of the number of connected clients.
-		s.incrementClients()
-		defer s.decrementClients()
+		// Keep track of the number of connected clients and in-flight
+		// requests.
+		if !s.beginRequest(w) {
+			return
+		}
+		defer s.endRequest()
+		s.incrementClients(false)
+		defer s.decrementClients(false)
 		_, isAdmin, err := s.checkAuth(r, true)
 		if err != nil {
 			jsonAuthFail(w)
@@ -3933,17 +7646,37 @@ func (s *rpcServer) Start() {
 		}
 
 		// Read and respond to the request.
-		s.jsonRPCRead(w, r, isAdmin)
+		allowed := rpcLimited
+		if isAdmin {
+			allowed = nil
+		}
+		s.jsonRPCRead(w, r, allowed)
 	})
 
 	// Websocket endpoint.
 	rpcServeMux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if !s.beginRequest(w) {
+			return
+		}
+		defer s.endRequest()
 		authenticated, isAdmin, err := s.checkAuth(r, false)
 		if err != nil {
 			jsonAuthFail(w)
 			return
 		}
 
+		// Clients may opt into only being notified about transactions
+		// paying to their addresses once the transactions are mined.
+		var confirmedRecvTx bool
+		switch r.URL.Query().Get("recvtx") {
+		case "", "all":
+		case "confirmed":
+			confirmedRecvTx = true
+		default:
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
 		// Attempt to upgrade the connection to a websocket connection
 		// using the default size for read/write buffers.
 		ws, err := websocket.Upgrade(w, r, nil, 0, 0)
@@ -3955,9 +7688,15 @@ func (s *rpcServer) Start() {
 			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
 			return
 		}
-		s.WebsocketHandler(ws, r.RemoteAddr, authenticated, isAdmin)
+		s.WebsocketHandler(ws, r.RemoteAddr, authenticated, isAdmin,
+			confirmedRecvTx)
 	})
 
+	// Unauthenticated REST endpoint.
+	if cfg.REST {
+		rpcServeMux.HandleFunc(restPathPrefix, s.restHandler(false))
+	}
+
 	for _, listener := range s.listeners {
 		s.wg.Add(1)
 		go func(listener net.Listener) {
@@ -3968,7 +7707,65 @@ func (s *rpcServer) Start() {
 		}(listener)
 	}
 
+	// The read-only listeners only serve the query methods and, when
+	// enabled, REST requests.  Websockets are not available.
+	readOnlyMux := http.NewServeMux()
+	readOnlyServer := newRPCHTTPServer(readOnlyMux)
+	readOnlyMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		// Limit the number of connections to max allowed.
+		if s.limitConnections(w, r.RemoteAddr, true) {
+			return
+		}
+
+		// Keep track of the number of connected clients and in-flight
+		// requests.
+		if !s.beginRequest(w) {
+			return
+		}
+		defer s.endRequest()
+		s.incrementClients(true)
+		defer s.decrementClients(true)
+		if err := s.checkReadOnlyAuth(r); err != nil {
+			jsonAuthFail(w)
+			return
+		}
+
+		// Read and respond to the request.
+		s.jsonRPCRead(w, r, rpcReadOnly)
+	})
+	if cfg.REST {
+		readOnlyMux.HandleFunc(restPathPrefix, s.restHandler(true))
+	}
+	s.httpServers = []*http.Server{httpServer, readOnlyServer}
+	for _, listener := range s.readOnlyLns {
+		s.wg.Add(1)
+		go func(listener net.Listener) {
+			rpcsLog.Infof("Read-only RPC server listening on %s",
+				listener.Addr())
+			readOnlyServer.Serve(listener)
+			rpcsLog.Tracef("Read-only RPC listener done for %s",
+				listener.Addr())
+			s.wg.Done()
+		}(listener)
+	}
+
 	s.ntfnMgr.Start()
+
+	s.wg.Add(1)
+	go s.templateNtfn.run(s.quit, &s.wg)
+
+	// Reload the TLS certificate when it changes and keep it renewed
+	// through ACME when configured.
+	if s.certReloader != nil {
+		s.wg.Add(1)
+		go s.certReloader.run(s.quit, &s.wg)
+	}
+	if s.acmeRenewer != nil {
+		s.wg.Add(1)
+		go s.acmeRenewer.run(s.quit, &s.wg)
+	}
 }
 
 // genCertPair generates a key/cert pair to the paths provided.
@@ -3996,15 +7793,22 @@ func genCertPair(certFile, keyFile string) error {
 }
 
 // newRPCServer returns a new instance of the rpcServer struct.
-func newRPCServer(listenAddrs []string, policy *mining.Policy, s *server) (*rpcServer, error) {
+func newRPCServer(listenAddrs []string, policy *blockTemplatePolicy, s *server) (*rpcServer, error) {
 	rpc := rpcServer{
 		policy:       policy,
 		server:       s,
-		statusLines:  make(map[int]string),
 		workState:    newWorkState(),
 		gbtWorkState: newGbtWorkState(s.timeSource),
-		helpCacher:   newHelpCacher(),
-		quit:         make(chan int),
+		helpCacher:   newHelpCacher(cfg.RPCHelpLocale),
+		chainWork:    newChainWorkCache(s.db),
+		tipNotifier:  newTipNotifier(),
+		rateLimiter: newRPCRateLimiter(cfg.RPCRateLimit,
+			cfg.RPCExpensiveLimit, cfg.RPCMaxExpensive),
+		authLimiter: newRPCAuthLimiter(cfg.RPCAuthFailures,
+			cfg.RPCAuthLockout, cfg.RPCAuthFail2Ban),
+		conns:   newConnLimiter(cfg.RPCMaxConns),
+		roConns: newConnLimiter(cfg.RPCMaxROConns),
+		quit:    make(chan int),
 	}
 	if cfg.RPCUser != "" && cfg.RPCPass != "" {
 		login := cfg.RPCUser + ":" + cfg.RPCPass
@@ -4016,10 +7820,27 @@ func newRPCServer(listenAddrs []string, policy *mining.Policy, s *server) (*rpcS
 		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
 		rpc.limitauthsha = fastsha256.Sum256([]byte(auth))
 	}
+	if cfg.RPCReadOnlyUser != "" && cfg.RPCReadOnlyPass != "" {
+		login := cfg.RPCReadOnlyUser + ":" + cfg.RPCReadOnlyPass
+		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
+		authsha := fastsha256.Sum256([]byte(auth))
+		rpc.readonlyauth = &authsha
+	}
 	rpc.ntfnMgr = newWsNotificationManager(&rpc)
+	rpc.templateNtfn = newBlockTemplateNotifier(&rpc)
+	rpc.rawBlockNtfn = newRawBlockNotifier(&rpc)
+	if cfg.EventJournal > 0 {
+		journalPath := filepath.Join(cfg.DataDir, eventJournalFilename)
+		journal, err := openEventJournal(journalPath, cfg.EventJournal)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open event journal: %v",
+				err)
+		}
+		rpc.ntfnMgr.journal = journal
+	}
 
 	// Setup TLS if not disabled.
-	listenFunc := net.Listen
+	var tlsConfig *tls.Config
 	if !cfg.DisableTLS {
 		// Generate the TLS cert and key file if both don't already
 		// exist.
@@ -4029,22 +7850,94 @@ func newRPCServer(listenAddrs []string, policy *mining.Policy, s *server) (*rpcS
 				return nil, err
 			}
 		}
-		keypair, err := tls.LoadX509KeyPair(cfg.RPCCert, cfg.RPCKey)
+		reloader, err := newCertReloader(cfg.RPCCert, cfg.RPCKey)
 		if err != nil {
 			return nil, err
 		}
+		rpc.certReloader = reloader
+		if len(cfg.RPCACMEDomains) > 0 {
+			rpc.acmeRenewer, err = newACMERenewer(reloader)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tlsConfig = &tls.Config{
+			GetCertificate: reloader.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+
+		// Offer HTTP/2 to clients which support it.  The HTTP
+		// servers enable it for the connections which negotiate it.
+		if !cfg.RPCNoHTTP2 {
+			tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+		}
+	}
 
-		tlsConfig := tls.Config{
-			Certificates: []tls.Certificate{keypair},
-			MinVersion:   tls.VersionTLS12,
+	// listenFunc returns a function which listens on an address with
+	// TLS, unless it is disabled, and counts the connections against the
+	// passed limiter.  The limit is applied to the raw connections so the
+	// HTTP servers still see the TLS connections.
+	listenFunc := func(limiter *connLimiter) func(string, string) (net.Listener, error) {
+		return func(network, laddr string) (net.Listener, error) {
+			listener, err := net.Listen(network, laddr)
+			if err != nil {
+				return nil, err
+			}
+			listener = limiter.Listener(listener)
+			if tlsConfig != nil {
+				listener = tls.NewListener(listener, tlsConfig)
+			}
+			return listener, nil
 		}
+	}
+
+	listeners, err := rpcListen(listenFunc(rpc.conns), listenAddrs)
+	if err != nil {
+		return nil, err
+	}
+	if len(listeners) == 0 {
+		return nil, errors.New("RPCS: No valid listen address")
+	}
+	rpc.listeners = listeners
 
-		// Change the standard net.Listen function to the tls one.
-		listenFunc = func(net string, laddr string) (net.Listener, error) {
-			return tls.Listen(net, laddr, &tlsConfig)
+	if len(cfg.RPCReadOnlyListen) > 0 {
+		listeners, err := rpcListen(listenFunc(rpc.roConns),
+			cfg.RPCReadOnlyListen)
+		if err != nil {
+			return nil, err
+		}
+		if len(listeners) == 0 {
+			return nil, errors.New("RPCS: No valid read-only listen " +
+				"address")
 		}
+		rpc.readOnlyLns = listeners
 	}
 
+	return &rpc, nil
+}
+
+// newRPCHTTPServer returns an HTTP server for RPC listeners which serves the
+// passed handler with the configured timeouts and limits.  Keep-alive
+// connections are disabled when the idle timeout is 0.
+func newRPCHTTPServer(handler http.Handler) *http.Server {
+	server := &http.Server{
+		Handler: handler,
+
+		// Timeout connections which don't send a request within the
+		// allowed timeframe.
+		ReadTimeout:    cfg.RPCReadTimeout,
+		IdleTimeout:    cfg.RPCIdleTimeout,
+		MaxHeaderBytes: cfg.RPCMaxHeaderBytes,
+	}
+	server.SetKeepAlivesEnabled(cfg.RPCIdleTimeout > 0)
+	return server
+}
+
+// rpcListen listens on the passed addresses with the passed listen function
+// and returns the listeners.  Addresses which can't be listened on are logged
+// and skipped.
+func rpcListen(listenFunc func(string, string) (net.Listener, error), listenAddrs []string) ([]net.Listener, error) {
 	// TODO(oga) this code is similar to that in server, should be
 	// factored into something shared.
 	ipv4ListenAddrs, ipv6ListenAddrs, _, err := parseListeners(listenAddrs)
@@ -4070,13 +7963,7 @@ func newRPCServer(listenAddrs []string, policy *mining.Policy, s *server) (*rpcS
 		}
 		listeners = append(listeners, listener)
 	}
-	if len(listeners) == 0 {
-		return nil, errors.New("RPCS: No valid listen address")
-	}
-
-	rpc.listeners = listeners
-
-	return &rpc, nil
+	return listeners, nil
 }
 
 func init() {
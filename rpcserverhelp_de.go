@@ -0,0 +1,131 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+// helpDescsDeDE defines the German translations of the help labels and of the
+// descriptions of the most commonly used methods.  The descriptions it lacks
+// are shown in English.
+var helpDescsDeDE = map[string]string{
+	// Help labels, JSON types and examples.
+	"help-arguments":       "Argumente",
+	"help-arguments-none":  "Keine",
+	"help-result":          "Ergebnis",
+	"help-result-nothing":  "Nichts",
+	"help-default":         "Standard",
+	"help-optional":        "optional",
+	"help-required":        "erforderlich",
+	"json-type-numeric":    "numerisch",
+	"json-type-string":     "Zeichenkette",
+	"json-type-bool":       "boolesch",
+	"json-type-array":      "Array aus ",
+	"json-type-object":     "Objekt",
+	"json-type-value":      "Wert",
+	"json-example-string":  "Wert",
+	"json-example-unknown": "unbekannt",
+
+	// AddNodeCmd help.
+	"addnode--synopsis": "Versucht, einen dauerhaften Peer hinzuzufügen oder zu entfernen.",
+	"addnode-addr":      "IP-Adresse und Port des betroffenen Peers",
+	"addnode-subcmd":    "'add' fügt einen dauerhaften Peer hinzu, 'remove' entfernt einen dauerhaften Peer und 'onetry' versucht eine einzelne Verbindung zu einem Peer",
+
+	// GetAPISchemaCmd help.
+	"getapischema--synopsis": "Gibt eine maschinenlesbare Beschreibung aller RPC- und Websocket-Methoden und aller Websocket-Benachrichtigungen zurück.\n" +
+		"Die Beschreibungen sind die der Hilfe und eignen sich zum Erzeugen von Client-Bibliotheken.",
+
+	// GetBestBlockHashCmd help.
+	"getbestblockhash--synopsis": "Gibt den Hash des besten (neuesten) Blocks der längsten Blockchain zurück.",
+	"getbestblockhash--result0":  "Der hexadezimal kodierte Block-Hash",
+
+	// GetBlockCmd help.
+	"getblock--synopsis":   "Gibt Informationen über einen Block anhand seines Hashes zurück.",
+	"getblock-hash":        "Der Hash des Blocks",
+	"getblock-verbose":     "Gibt den Block als JSON-Objekt statt als hexadezimal kodierte Zeichenkette zurück",
+	"getblock-verbosetx":   "Gibt jede Transaktion als JSON-Objekt zurück und gilt nur, wenn verbose gesetzt ist (btcd-Erweiterung)",
+	"getblock--condition0": "verbose=false",
+	"getblock--condition1": "verbose=true",
+	"getblock--result0":    "Hexadezimal kodierte Bytes des serialisierten Blocks",
+
+	// GetBlockCountCmd help.
+	"getblockcount--synopsis": "Gibt die Anzahl der Blöcke der längsten Blockchain zurück.",
+	"getblockcount--result0":  "Die aktuelle Anzahl der Blöcke",
+
+	// GetBlockHashCmd help.
+	"getblockhash--synopsis": "Gibt den Hash des Blocks der besten Blockchain in der angegebenen Höhe zurück.",
+	"getblockhash-index":     "Die Höhe des Blocks",
+	"getblockhash--result0":  "Der Hash des Blocks",
+
+	// GetBlockHeaderCmd help.
+	"getblockheader--synopsis":   "Gibt Informationen über einen Blockheader anhand seines Hashes zurück.",
+	"getblockheader-hash":        "Der Hash des Blocks",
+	"getblockheader-verbose":     "Gibt den Blockheader als JSON-Objekt statt als hexadezimal kodierte Zeichenkette zurück",
+	"getblockheader--condition0": "verbose=false",
+	"getblockheader--condition1": "verbose=true",
+	"getblockheader--result0":    "Der hexadezimal kodierte Blockheader",
+
+	// GetConnectionCountCmd help.
+	"getconnectioncount--synopsis": "Gibt die Anzahl der aktiven Verbindungen zu anderen Peers zurück.",
+	"getconnectioncount--result0":  "Die Anzahl der Verbindungen",
+
+	// GetDifficultyCmd help.
+	"getdifficulty--synopsis": "Gibt die Proof-of-Work-Schwierigkeit als Vielfaches der minimalen Schwierigkeit zurück.",
+	"getdifficulty--result0":  "Die Schwierigkeit",
+
+	// GetInfoCmd help.
+	"getinfo--synopsis": "Gibt ein JSON-Objekt mit verschiedenen Zustandsinformationen zurück.",
+
+	// GetMempoolInfoCmd help.
+	"getmempoolinfo--synopsis": "Gibt Informationen über den Speicherpool zurück.",
+
+	// GetPeerInfoCmd help.
+	"getpeerinfo--synopsis": "Gibt Daten über jeden verbundenen Peer als Array von JSON-Objekten zurück.",
+
+	// GetRawMempoolCmd help.
+	"getrawmempool--synopsis":   "Gibt Informationen über alle Transaktionen zurück, die sich derzeit im Speicherpool befinden.",
+	"getrawmempool-verbose":     "Gibt ein JSON-Objekt zurück, wenn true, oder ein Array von Transaktions-Hashes, wenn false",
+	"getrawmempool--condition0": "verbose=false",
+	"getrawmempool--condition1": "verbose=true",
+	"getrawmempool--result0":    "Array von Transaktions-Hashes",
+
+	// GetRawTransactionCmd help.
+	"getrawtransaction--synopsis":   "Gibt Informationen über eine Transaktion anhand ihres Hashes zurück.",
+	"getrawtransaction-txid":        "Der Hash der Transaktion",
+	"getrawtransaction-verbose":     "Gibt die Transaktion als JSON-Objekt statt als hexadezimal kodierte Zeichenkette zurück",
+	"getrawtransaction--condition0": "verbose=false",
+	"getrawtransaction--condition1": "verbose=true",
+	"getrawtransaction--result0":    "Hexadezimal kodierte Bytes der serialisierten Transaktion",
+
+	// GetTxOutCmd help.
+	"gettxout--synopsis":      "Gibt Informationen über einen nicht ausgegebenen Transaktionsausgang zurück.",
+	"gettxout-txid":           "Der Hash der Transaktion",
+	"gettxout-vout":           "Der Index des Ausgangs",
+	"gettxout-includemempool": "Bezieht den Speicherpool ein, wenn true",
+
+	// HelpCmd help.
+	"help--synopsis":   "Gibt eine Liste aller Befehle oder die Hilfe zu einem angegebenen Befehl zurück.",
+	"help-command":     "Der Befehl, zu dem die Hilfe abgerufen wird",
+	"help--condition0": "kein Befehl angegeben",
+	"help--condition1": "Befehl angegeben",
+	"help--result0":    "Liste der Befehle",
+	"help--result1":    "Hilfe zum angegebenen Befehl",
+
+	// PingCmd help.
+	"ping--synopsis": "Stellt einen Ping an jeden verbundenen Peer in die Warteschlange.\n" +
+		"Die Ping-Zeiten liefert getpeerinfo in den Feldern pingtime und pingwait.",
+
+	// SendRawTransactionCmd help.
+	"sendrawtransaction--synopsis":     "Übermittelt die serialisierte, hexadezimal kodierte Transaktion an den lokalen Peer und leitet sie an das Netzwerk weiter.",
+	"sendrawtransaction-hextx":         "Serialisierte, hexadezimal kodierte signierte Transaktion",
+	"sendrawtransaction-allowhighfees": "Ob unverhältnismäßig hohe Gebühren erlaubt sind (hebt maxfeerate auf, wenn true)",
+	"sendrawtransaction-maxfeerate":    "Weist Transaktionen mit einer höheren Gebührenrate in BTC/kB zurück (0 akzeptiert jede Gebührenrate, Standard ist 0.1)",
+	"sendrawtransaction--result0":      "Der Hash der Transaktion",
+
+	// StopCmd help.
+	"stop--synopsis": "Beendet btcd.",
+	"stop--result0":  "Die Zeichenkette 'btcd stopping.'",
+
+	// ValidateAddressCmd help.
+	"validateaddress--synopsis": "Prüft, ob eine Adresse gültig ist, und beschreibt die Skripte, mit denen an sie zahlende Ausgänge gesperrt sind.",
+	"validateaddress-address":   "Die zu prüfende Bitcoin-Adresse",
+}
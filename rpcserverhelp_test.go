@@ -4,62 +4,500 @@
 
 package main
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/conseweb/stcd/btcjson"
+)
+
+// apiSchemaFile is the file which houses the API schema returned by the
+// getapischema command.
+const apiSchemaFile = "docs/api_schema.json"
+
+// updateAPISchema makes TestAPISchemaFile rewrite the API schema file instead
+// of checking it.  It is set by go generate.
+var updateAPISchema = flag.Bool("updateapischema", false,
+	"rewrite "+apiSchemaFile+" from the current method schemas")
+
+// rpcResultTypes specifies the result types that each RPC and websocket method
+// can return.  TestMethodSchemas uses them to ensure the results described by
+// the method schemas match the types the methods actually return.  Each result
+// type must be a pointer to the type (or nil to indicate no return value).
+var rpcResultTypes = map[string][]interface{}{
+	"addfilterentry":           nil,
+	"addnode":                  nil,
+	"backupchainstate":         []interface{}{(*btcjson.BackupChainStateResult)(nil)},
+	"broadcastalert":           []interface{}{(*btcjson.BroadcastAlertResult)(nil)},
+	"clearnetworkfaults":       nil,
+	"compactdatabase":          nil,
+	"createmultisig":           []interface{}{(*btcjson.CreateMultiSigResult)(nil)},
+	"createrawtransaction":     []interface{}{(*string)(nil)},
+	"debuglevel":               []interface{}{(*string)(nil), (*string)(nil)},
+	"debugscript":              []interface{}{(*btcjson.DebugScriptResult)(nil)},
+	"decodepaymenturi":         []interface{}{(*btcjson.DecodePaymentURIResult)(nil)},
+	"decoderawtransaction":     []interface{}{(*btcjson.TxRawDecodeResult)(nil)},
+	"decodescript":             []interface{}{(*btcjson.DecodeScriptResult)(nil)},
+	"dumputxoset":              []interface{}{(*btcjson.DumpUtxoSetResult)(nil)},
+	"findorphanchains":         []interface{}{(*[]btcjson.FindOrphanChainsResult)(nil)},
+	"fundrawtransaction":       []interface{}{(*btcjson.FundRawTransactionResult)(nil)},
+	"generate":                 []interface{}{(*[]string)(nil)},
+	"generatetodescriptor":     []interface{}{(*[]string)(nil)},
+	"getaddednodeinfo":         []interface{}{(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
+	"getaddressbalance":        []interface{}{(*btcjson.GetAddressBalanceResult)(nil)},
+	"getaddressdeltas":         []interface{}{(*[]btcjson.AddressDeltaResult)(nil)},
+	"getaddressutxos":          []interface{}{(*[]btcjson.AddressUtxoResult)(nil)},
+	"getallsubscriptions":      []interface{}{(*[]btcjson.ClientSubscriptionsResult)(nil)},
+	"getapischema":             []interface{}{(*btcjson.GetAPISchemaResult)(nil)},
+	"getbestblock":             []interface{}{(*btcjson.GetBestBlockResult)(nil)},
+	"getbestblockhash":         []interface{}{(*string)(nil)},
+	"getblock":                 []interface{}{(*string)(nil), (*btcjson.GetBlockVerboseResult)(nil)},
+	"getblockchaininfo":        []interface{}{(*btcjson.GetBlockChainInfoResult)(nil)},
+	"getblockcount":            []interface{}{(*int64)(nil)},
+	"getblockfilter":           []interface{}{(*btcjson.GetBlockFilterResult)(nil)},
+	"getblockhash":             []interface{}{(*string)(nil)},
+	"getblockheader":           []interface{}{(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
+	"getblockstats":            []interface{}{(*btcjson.GetBlockStatsResult)(nil)},
+	"getblocktemplate":         []interface{}{(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
+	"getcoinageinfo":           []interface{}{(*btcjson.GetCoinAgeInfoResult)(nil)},
+	"getchainstates":           []interface{}{(*btcjson.GetChainStatesResult)(nil)},
+	"getconnectioncount":       []interface{}{(*int32)(nil)},
+	"getcurrentnet":            []interface{}{(*uint32)(nil)},
+	"getdatabaseinfo":          []interface{}{(*btcjson.GetDatabaseInfoResult)(nil)},
+	"getdbcachestats":          []interface{}{(*btcjson.GetDBCacheStatsResult)(nil)},
+	"getdifficulty":            []interface{}{(*float64)(nil)},
+	"getdifficultyhistory":     []interface{}{(*[]btcjson.GetDifficultyHistoryResult)(nil)},
+	"getevictedtransactions":   []interface{}{(*[]btcjson.EvictedTransactionResult)(nil)},
+	"getgenerate":              []interface{}{(*bool)(nil)},
+	"gethashespersec":          []interface{}{(*float64)(nil)},
+	"getimportstatus":          []interface{}{(*btcjson.GetImportStatusResult)(nil)},
+	"getinfo":                  []interface{}{(*btcjson.InfoChainResult)(nil)},
+	"getlog":                   []interface{}{(*[]string)(nil)},
+	"getmempoolinfo":           []interface{}{(*btcjson.GetMempoolInfoResult)(nil)},
+	"getmininginfo":            []interface{}{(*btcjson.GetMiningInfoResult)(nil)},
+	"getnettotals":             []interface{}{(*btcjson.GetNetTotalsResult)(nil)},
+	"getnetworkhashps":         []interface{}{(*int64)(nil)},
+	"getnetworkfaults":         []interface{}{(*[]btcjson.NetworkFaultResult)(nil)},
+	"getnetworkinfo":           []interface{}{(*btcjson.GetNetworkInfoResult)(nil)},
+	"getnetworktopology":       []interface{}{(*btcjson.GetNetworkTopologyResult)(nil)},
+	"getpeerinfo":              []interface{}{(*[]btcjson.GetPeerInfoResult)(nil)},
+	"getpolicyinfo":            []interface{}{(*btcjson.GetPolicyInfoResult)(nil)},
+	"getrawmempool":            []interface{}{(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
+	"getrawtransaction":        []interface{}{(*string)(nil), (*btcjson.TxRawResult)(nil)},
+	"getrpcinfo":               []interface{}{(*btcjson.GetRPCInfoResult)(nil)},
+	"getspendinginfo":          []interface{}{(*btcjson.GetSpendingInfoResult)(nil)},
+	"getsyncstatus":            []interface{}{(*btcjson.GetSyncStatusResult)(nil)},
+	"gettxout":                 []interface{}{(*btcjson.GetTxOutResult)(nil)},
+	"gettxoutsetinfo":          []interface{}{(*btcjson.GetTxOutSetInfoResult)(nil)},
+	"getunconfirmedbroadcasts": []interface{}{(*[]btcjson.UnconfirmedBroadcastResult)(nil)},
+	"getutxocommitment":        []interface{}{(*btcjson.GetUtxoCommitmentResult)(nil)},
+	"getwork":                  []interface{}{(*btcjson.GetWorkResult)(nil), (*bool)(nil)},
+	"node":                     nil,
+	"help":                     []interface{}{(*string)(nil), (*string)(nil)},
+	"loadutxoset":              []interface{}{(*btcjson.LoadUtxoSetResult)(nil)},
+	"ping":                     nil,
+	"removebroadcast":          nil,
+	"removefilterentry":        nil,
+	"searchrawtransactions":    []interface{}{(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
+	"sendrawtransaction":       []interface{}{(*string)(nil)},
+	"setblocktemplatepolicy":   []interface{}{(*btcjson.BlockTemplatePolicyResult)(nil)},
+	"setblockversion":          nil,
+	"setnetworkfault":          nil,
+	"setgenerate":              nil,
+	"setmocktime":              nil,
+	"signmessagewithprivkey":   []interface{}{(*string)(nil)},
+	"signrawtransaction":       []interface{}{(*btcjson.SignRawTransactionResult)(nil)},
+	"stop":                     []interface{}{(*string)(nil)},
+	"submitblock":              []interface{}{nil, (*string)(nil)},
+	"validateaddress":          []interface{}{(*btcjson.ValidateAddressChainResult)(nil)},
+	"verifychain":              []interface{}{(*bool)(nil)},
+	"verifymessage":            []interface{}{(*bool)(nil)},
+	"waitforblock":             []interface{}{(*btcjson.WaitForBlockResult)(nil)},
+	"waitforblockheight":       []interface{}{(*btcjson.WaitForBlockResult)(nil)},
+	"waitfornewblock":          []interface{}{(*btcjson.WaitForBlockResult)(nil)},
+
+	// Websocket commands.
+	"session":                   []interface{}{(*btcjson.SessionResult)(nil)},
+	"getsubscriptions":          []interface{}{(*btcjson.GetSubscriptionsResult)(nil)},
+	"notifyblocks":              nil,
+	"notifyblockheaders":        nil,
+	"notifyblocktemplate":       nil,
+	"stopnotifyblocks":          nil,
+	"stopnotifyblockheaders":    nil,
+	"stopnotifyblocktemplate":   nil,
+	"notifyclockskew":           nil,
+	"stopnotifyclockskew":       nil,
+	"notifyconfirmations":       nil,
+	"stopnotifyconfirmations":   nil,
+	"notifypeerevents":          nil,
+	"stopnotifypeerevents":      nil,
+	"notifynewtransactions":     nil,
+	"stopnotifynewtransactions": nil,
+	"notifyreceived":            nil,
+	"stopnotifyreceived":        nil,
+	"subscriberawblocks":        nil,
+	"unsubscriberawblocks":      nil,
+	"subscribemempool":          nil,
+	"unsubscribemempool":        nil,
+	"subscribescripthash":       nil,
+	"unsubscribescripthash":     nil,
+	"notifyspent":               nil,
+	"stopnotifyspent":           nil,
+	"rescan":                    nil,
+	"loadtxfilter":              nil,
+	"removetxfilter":            nil,
+	"rescanfilter":              nil,
+	"startrescan":               []interface{}{(*string)(nil)},
+	"getrescanstatus":           []interface{}{(*btcjson.GetRescanStatusResult)(nil)},
+	"cancelrescan":              nil,
+	"replayevents":              nil,
+}
+
+// handlerMethods returns the methods of every RPC and websocket handler.
+func handlerMethods() []string {
+	methods := make([]string, 0, len(rpcHandlers)+len(wsHandlers))
+	for _, handlers := range []interface{}{rpcHandlers, wsHandlers} {
+		for _, k := range reflect.ValueOf(handlers).MapKeys() {
+			methods = append(methods, k.String())
+		}
+	}
+	return methods
+}
 
 // TestHelp ensures the help is reasonably accurate by checking that every
-// command specified also has result types defined and the one-line usage and
-// help text can be generated for them.
+// command specified also has a schema and the one-line usage and help text can
+// be generated for them in every locale.
 func TestHelp(t *testing.T) {
-	// Ensure there are result types specified for every handler.
-	for k := range rpcHandlers {
-		if _, ok := rpcResultTypes[k]; !ok {
+	// Ensure there is a schema specified for every handler.
+	for _, method := range handlerMethods() {
+		if _, ok := rpcMethodSchemas[method]; !ok {
 			t.Errorf("RPC handler defined for method '%v' without "+
-				"also specifying result types", k)
-			continue
+				"also specifying a schema", method)
 		}
+	}
 
+	for _, locale := range helpLocales() {
+		// Ensure the usage for every command can be generated without
+		// errors.
+		helpCacher := newHelpCacher(locale)
+		if _, err := helpCacher.rpcUsage(true); err != nil {
+			t.Fatalf("Failed to generate one-line usage (%s): %v",
+				locale, err)
+		}
+		if _, err := helpCacher.rpcUsage(true); err != nil {
+			t.Fatalf("Failed to generate one-line usage (%s, "+
+				"cached): %v", locale, err)
+		}
+
+		// Ensure the help and schema for every command can be
+		// generated without errors.
+		for _, method := range handlerMethods() {
+			if _, err := helpCacher.rpcMethodHelp(method); err != nil {
+				t.Errorf("Failed to generate help for method "+
+					"'%v' (%s): %v", method, locale, err)
+				continue
+			}
+			if _, err := helpCacher.rpcMethodHelp(method); err != nil {
+				t.Errorf("Failed to generate help for method "+
+					"'%v' (%s, cached): %v", method, locale,
+					err)
+				continue
+			}
+			if _, err := helpCacher.rpcMethodSchema(method); err != nil {
+				t.Errorf("Failed to generate schema for method "+
+					"'%v' (%s): %v", method, locale, err)
+			}
+		}
 	}
-	for k := range wsHandlers {
-		if _, ok := rpcResultTypes[k]; !ok {
-			t.Errorf("RPC handler defined for method '%v' without "+
-				"also specifying result types", k)
-			continue
+}
+
+// sameSchemaType returns whether the passed type schemas describe the same
+// JSON type regardless of their descriptions.  An object without fields or
+// values describes a recursive type and matches any object.
+func sameSchemaType(a, b *btcjson.TypeSchema) bool {
+	if a.Type != b.Type || a.Decimal != b.Decimal {
+		return false
+	}
+	if a.Type == "object" && (a.Fields == nil && a.Values == nil ||
+		b.Fields == nil && b.Values == nil) {
+
+		return true
+	}
+	if (a.Items == nil) != (b.Items == nil) ||
+		(a.Values == nil) != (b.Values == nil) ||
+		(a.Entries == nil) != (b.Entries == nil) {
+
+		return false
+	}
+	if a.Items != nil && !sameSchemaType(a.Items, b.Items) {
+		return false
+	}
+	if a.Values != nil && !sameSchemaType(a.Values, b.Values) {
+		return false
+	}
+	if a.TypeName != b.TypeName || len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	for i := range a.Fields {
+		fa, fb := &a.Fields[i], &b.Fields[i]
+		if fa.Name != fb.Name || fa.Optional != fb.Optional ||
+			!sameSchemaType(&fa.TypeSchema, &fb.TypeSchema) {
+
+			return false
 		}
+	}
+	return true
+}
 
+// checkMethodSchema returns an error when the declared schema of the provided
+// method does not match the schema generated from its registered command type
+// and the passed result types.
+func checkMethodSchema(method string, schema *btcjson.MethodSchema, resultTypes []interface{}) error {
+	if schema.Method != method {
+		return fmt.Errorf("schema is for method '%v'", schema.Method)
 	}
+	want, err := btcjson.GenerateSchema(method, nil, resultTypes...)
+	if jerr, ok := err.(btcjson.Error); err != nil && (!ok ||
+		jerr.ErrorCode != btcjson.ErrMissingDescription) {
 
-	// Ensure the usage for every command can be generated without errors.
-	helpCacher := newHelpCacher()
-	if _, err := helpCacher.rpcUsage(true); err != nil {
-		t.Fatalf("Failed to generate one-line usage: %v", err)
+		return err
 	}
-	if _, err := helpCacher.rpcUsage(true); err != nil {
-		t.Fatalf("Failed to generate one-line usage (cached): %v", err)
+
+	if schema.Usage != want.Usage {
+		return fmt.Errorf("usage %q - want %q", schema.Usage, want.Usage)
 	}
+	if schema.Websocket != want.Websocket ||
+		schema.Notification != want.Notification {
 
-	// Ensure the help for every command can be generated without errors.
-	for k := range rpcHandlers {
-		if _, err := helpCacher.rpcMethodHelp(k); err != nil {
-			t.Errorf("Failed to generate help for method '%v': %v",
-				k, err)
-			continue
+		return fmt.Errorf("websocket %v and notification %v - want %v "+
+			"and %v", schema.Websocket, schema.Notification,
+			want.Websocket, want.Notification)
+	}
+
+	if len(schema.Params) != len(want.Params) {
+		return fmt.Errorf("%d params - want %d", len(schema.Params),
+			len(want.Params))
+	}
+	for i := range schema.Params {
+		got, want := &schema.Params[i], &want.Params[i]
+		if got.Name != want.Name || got.Optional != want.Optional ||
+			fmt.Sprint(got.Default) != fmt.Sprint(want.Default) ||
+			!sameSchemaType(&got.TypeSchema, &want.TypeSchema) {
+
+			return fmt.Errorf("param #%d '%s' does not match the "+
+				"registered command", i+1, got.Name)
 		}
-		if _, err := helpCacher.rpcMethodHelp(k); err != nil {
-			t.Errorf("Failed to generate help for method '%v'"+
-				"(cached): %v", k, err)
-			continue
+	}
+
+	if len(schema.Results) != len(want.Results) {
+		return fmt.Errorf("%d results - want %d", len(schema.Results),
+			len(want.Results))
+	}
+	for i := range schema.Results {
+		got, want := &schema.Results[i], &want.Results[i]
+		if (got.Condition == "") != (want.Condition == "") ||
+			(got.Description == "") != (want.Description == "") ||
+			!sameSchemaType(&got.TypeSchema, &want.TypeSchema) {
+
+			return fmt.Errorf("result #%d does not match the result "+
+				"types", i)
 		}
 	}
-	for k := range wsHandlers {
-		if _, err := helpCacher.rpcMethodHelp(k); err != nil {
-			t.Errorf("Failed to generate help for method '%v': %v",
-				k, err)
+	return nil
+}
+
+// TestMethodSchemas ensures the declared schema of every method and
+// notification matches its registered command type and result types, so the
+// help and the validation of parameters agree with the commands the server
+// actually accepts and returns.
+func TestMethodSchemas(t *testing.T) {
+	for method, schema := range rpcMethodSchemas {
+		resultTypes, ok := rpcResultTypes[method]
+		if !ok {
+			t.Errorf("Schema defined for method '%v' without also "+
+				"specifying result types", method)
 			continue
 		}
-		if _, err := helpCacher.rpcMethodHelp(k); err != nil {
-			t.Errorf("Failed to generate help for method '%v'"+
-				"(cached): %v", k, err)
+		if err := checkMethodSchema(method, schema, resultTypes); err != nil {
+			t.Errorf("Mismatched schema for method '%v': %v", method,
+				err)
+		}
+	}
+	for method, schema := range rpcNotificationSchemas {
+		if err := checkMethodSchema(method, schema, nil); err != nil {
+			t.Errorf("Mismatched schema for notification '%v': %v",
+				method, err)
+		}
+		if !schema.Notification {
+			t.Errorf("Schema for notification '%v' is not marked as "+
+				"a notification", method)
+		}
+	}
+}
+
+// TestAPISchemaFile ensures the API schema can be generated without errors,
+// describes every method and notification, and matches the schema file.
+func TestAPISchemaFile(t *testing.T) {
+	apiSchema, err := newHelpCacher(defaultHelpLocale).rpcAPISchema()
+	if err != nil {
+		t.Fatalf("Failed to generate API schema: %v", err)
+	}
+	if got, want := len(apiSchema.Notifications), len(rpcNotificationSchemas); got != want {
+		t.Errorf("API schema has %d notifications - want %d", got, want)
+	}
+	for method := range wsHandlers {
+		if _, ok := apiSchema.Methods[method]; !ok {
+			t.Errorf("API schema lacks websocket method '%v'", method)
+		}
+	}
+
+	serialized, err := json.MarshalIndent(apiSchema, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal API schema: %v", err)
+	}
+	serialized = append(serialized, '\n')
+
+	if *updateAPISchema {
+		err := ioutil.WriteFile(apiSchemaFile, serialized, 0644)
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", apiSchemaFile, err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(apiSchemaFile)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", apiSchemaFile, err)
+	}
+	if !bytes.Equal(serialized, want) {
+		t.Errorf("%s is out of date - run go generate to update it",
+			apiSchemaFile)
+	}
+}
+
+// TestLocalizedHelp ensures the help of a locale uses its translations where
+// available and the descriptions of the schemas otherwise, and that every
+// translation is of a description which exists.
+func TestLocalizedHelp(t *testing.T) {
+	help, err := newHelpCacher("de_DE").rpcMethodHelp("help")
+	if err != nil {
+		t.Fatalf("rpcMethodHelp: unexpected error: %v", err)
+	}
+	for _, want := range []string{helpDescsDeDE["help--synopsis"],
+		helpDescsDeDE["help-command"], helpDescsDeDE["help-arguments"]} {
+
+		if !strings.Contains(help, want) {
+			t.Errorf("rpcMethodHelp: help lacks %q:\n%s", want, help)
+		}
+	}
+
+	schema, err := newHelpCacher("de_DE").rpcMethodSchema("getblock")
+	if err != nil {
+		t.Fatalf("rpcMethodSchema: unexpected error: %v", err)
+	}
+	if got, want := schema.Synopsis, helpDescsDeDE["getblock--synopsis"]; got != want {
+		t.Errorf("getblock synopsis: got %q - want %q", got, want)
+	}
+	want := rpcMethodSchemas["getblock"].Results[1].Fields[0].Description
+	if got := schema.Results[1].Fields[0].Description; got != want {
+		t.Errorf("getblock result field: got %q - want %q", got, want)
+	}
+
+	// Replace every translated description with a marker and ensure each
+	// marker shows up in the localized schemas.  The help labels are
+	// only used by the help text.
+	for locale, descs := range helpDescs {
+		markers := make(map[string]string, len(descs))
+		for key := range descs {
+			if strings.HasPrefix(key, "help-") ||
+				strings.HasPrefix(key, "json-") {
+
+				continue
+			}
+			markers[key] = "@" + key + "@"
+		}
+
+		var localized []*btcjson.MethodSchema
+		for _, schemas := range []map[string]*btcjson.MethodSchema{
+			rpcMethodSchemas, rpcNotificationSchemas} {
+
+			for _, schema := range schemas {
+				localized = append(localized,
+					btcjson.LocalizeSchema(schema, markers))
+			}
+		}
+		serialized, err := json.Marshal(localized)
+		if err != nil {
+			t.Fatalf("Failed to marshal schemas: %v", err)
+		}
+		for key, marker := range markers {
+			if !bytes.Contains(serialized, []byte(marker)) {
+				t.Errorf("%s: translation of unknown description "+
+					"'%s'", locale, key)
+			}
+		}
+	}
+}
+
+// TestParseCmdValidation ensures the parameters of requests are validated
+// against the schemas of their methods before they are parsed.
+func TestParseCmdValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		params []string
+		err    bool
+	}{
+		{
+			name:   "valid params",
+			method: "getblock",
+			params: []string{`"00"`, `false`},
+		},
+		{
+			name:   "wrong param type",
+			method: "getblock",
+			params: []string{`"00"`, `"false"`},
+			err:    true,
+		},
+		{
+			name:   "missing object field",
+			method: "createrawtransaction",
+			params: []string{`[{"txid":"00"}]`, `{}`},
+			err:    true,
+		},
+		{
+			name:   "fractional integer",
+			method: "getblockhash",
+			params: []string{`1.5`},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		request := &btcjson.Request{Jsonrpc: "1.0", Method: test.method}
+		for _, param := range test.params {
+			request.Params = append(request.Params,
+				json.RawMessage(param))
+		}
+		parsedCmd := parseCmd(request)
+		if !test.err {
+			if parsedCmd.err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name,
+					parsedCmd.err)
+			}
 			continue
 		}
+		if parsedCmd.err == nil ||
+			parsedCmd.err.Code != btcjson.ErrRPCInvalidParams.Code {
+
+			t.Errorf("%s: got error %v - want invalid params",
+				test.name, parsedCmd.err)
+		}
 	}
 }
@@ -0,0 +1,2997 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/conseweb/stcd/btcjson"
+)
+
+// The schemas in this file are the declarative description of every RPC and
+// websocket method and every websocket notification supported by the server.
+// They are the source of the help text, the API schema returned by
+// getapischema and the validation of the parameters of every call.  Their
+// descriptions are in the default help locale and are translated by the help
+// description bundles in rpcserverhelp.go.
+//
+// The keys of the translated descriptions are derived from the schemas:
+//   "<method>--synopsis"          The synopsis of a method
+//   "<method>-<param>"            The description of a parameter
+//   "<typename>-<field>"          The description of an object field
+//   "<method>--condition<#>"      The condition which triggers a result
+//   "<method>--result<#>"         The description of a result
+//   "<key>--key", "<key>--value"  The names of the keys and values of the
+//   and "<key>--desc"             entries of an object with arbitrary keys
+//                                 described by <key>
+//
+// When a method is added, a schema must be added here as well.  TestHelp fails
+// when any method lacks a schema and TestMethodSchemas fails when a schema does
+// not match the registered command and result types of its method.
+
+// The following are the schemas of the types which have no fields.
+var (
+	schemaNumeric = btcjson.TypeSchema{Type: "numeric"}
+	schemaDecimal = btcjson.TypeSchema{Type: "numeric", Decimal: true}
+	schemaString  = btcjson.TypeSchema{Type: "string"}
+	schemaBool    = btcjson.TypeSchema{Type: "boolean"}
+	schemaValue   = btcjson.TypeSchema{Type: "value"}
+	schemaNull    = btcjson.TypeSchema{Type: "null"}
+	schemaObject  = btcjson.TypeSchema{Type: "object"}
+)
+
+// schemaArray returns the schema of an array with elements of the passed type.
+func schemaArray(items btcjson.TypeSchema) btcjson.TypeSchema {
+	return btcjson.TypeSchema{Type: "array", Items: &items}
+}
+
+// schemaMap returns the schema of an object with arbitrary keys and values of
+// the passed type.  The key and value name the keys and values of its entries
+// in the help.
+func schemaMap(values btcjson.TypeSchema, key, value, desc string) btcjson.TypeSchema {
+	return btcjson.TypeSchema{
+		Type:   "object",
+		Values: &values,
+		Entries: &btcjson.EntrySchema{
+			Key:         key,
+			Value:       value,
+			Description: desc,
+		},
+	}
+}
+
+// rpcMethodSchemas describes the RPC and websocket methods by name.
+var rpcMethodSchemas = map[string]*btcjson.MethodSchema{
+	"addfilterentry": {
+		Method:   "addfilterentry",
+		Synopsis: "Bans transactions from the memory pool.\nMatching transactions are neither accepted into the memory pool nor relayed, and those already in it are evicted along with their descendants.\nBlocks are not affected.  Rejections and changes are logged to the TXFL subsystem.\nThe entry is not written to the file loaded with the txblacklist option.",
+		Usage:    `addfilterentry "entry"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "entry", Description: "The entry to add: a txid, an outpoint as <txid>:<index>, an output script as script:<hex>, a prefix of output scripts as script:<hex>* or an address as address:<address>", TypeSchema: schemaString},
+		},
+	},
+	"addnode": {
+		Method:   "addnode",
+		Synopsis: "Attempts to add or remove a persistent peer.",
+		Usage:    `addnode "addr" "add|remove|onetry"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "addr", Description: "IP address and port of the peer to operate on", TypeSchema: schemaString},
+			{Name: "subcmd", Description: "'add' to add a persistent peer, 'remove' to remove a persistent peer, or 'onetry' to try a single connection to a peer", TypeSchema: schemaString},
+		},
+	},
+	"backupchainstate": {
+		Method:   "backupchainstate",
+		Synopsis: "Writes a consistent copy of the block database, including the optional indexes, as of the current best block to a new database while the server keeps running.\nThe copy is named like the block database, so the destination directory can be used as the network data directory of a node.",
+		Usage:    `backupchainstate "destdir"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "destdir", Description: "The directory to write the copy to, relative to the data directory unless absolute (must not already contain a block database)", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaBackupChainStateResult},
+		},
+	},
+	"broadcastalert": {
+		Method:   "broadcastalert",
+		Synopsis: "Sends an alert notification with an operator-defined message to all connected websocket clients, for example to announce maintenance windows.\nThe alert is also posted as JSON to the webhooks configured with --rpcalertwebhook unless disabled.",
+		Usage:    `broadcastalert "message" ("info|warning|critical" webhooks=true)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "message", Description: "The message of the alert (at most 1024 bytes)", TypeSchema: schemaString},
+			{Name: "severity", Description: "The severity of the alert: info, warning or critical", Optional: true, Default: "info", TypeSchema: schemaString},
+			{Name: "webhooks", Description: "Whether to also post the alert to the configured webhooks", Optional: true, Default: true, TypeSchema: schemaBool},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaBroadcastAlertResult},
+		},
+	},
+	"cancelrescan": {
+		Method:    "cancelrescan",
+		Synopsis:  "Cancel a rescan job started with startrescan.  Canceling a job which is no longer running has no effect.",
+		Usage:     `cancelrescan "id"`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "id", Description: "The id of the rescan job", TypeSchema: schemaString},
+		},
+	},
+	"clearnetworkfaults": {
+		Method:   "clearnetworkfaults",
+		Synopsis: "Removes the network fault set with setnetworkfault for a peer, or all network faults when no peer is given (simnet only).",
+		Usage:    `clearnetworkfaults ("target")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "target", Description: "The id or address of the peer to remove the fault of", Optional: true, TypeSchema: schemaString},
+		},
+	},
+	"compactdatabase": {
+		Method:   "compactdatabase",
+		Synopsis: "Starts compacting the database in the background to reclaim the space used by deleted and overwritten data, such as that of dropped indexes.\nOnly one compaction runs at a time.  Its progress and outcome are reported by getdatabaseinfo.",
+		Usage:    "compactdatabase",
+		Params:   []btcjson.ParamSchema{},
+	},
+	"createmultisig": {
+		Method:   "createmultisig",
+		Synopsis: "Creates a multi-signature address that requires the specified number of the provided public keys to redeem.",
+		Usage:    `createmultisig nrequired ["key",...]`,
+		Params: []btcjson.ParamSchema{
+			{Name: "nrequired", Description: "The number of signatures required to redeem outputs paid to the address", TypeSchema: schemaNumeric},
+			{Name: "keys", Description: "Array of hex-encoded public keys", TypeSchema: schemaArray(schemaString)},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaCreateMultiSigResult},
+		},
+	},
+	"createrawtransaction": {
+		Method:   "createrawtransaction",
+		Synopsis: "Returns a new transaction spending the provided inputs and sending to the provided addresses.\nThe transaction inputs are not signed in the created transaction.\nThe signrawtransaction RPC command must be used to sign the resulting transaction.",
+		Usage:    `createrawtransaction [{"txid":"value","vout":n},...] {"address":amount,...,"data":"hex"} (locktime)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "inputs", Description: "The inputs to the transaction", TypeSchema: schemaArray(schemaTransactionInput)},
+			{Name: "amounts", Description: "JSON object with the destination addresses as keys and amounts as values, and optionally \"data\" as a key with hex-encoded data as the value", TypeSchema: schemaMap(schemaValue, "address", "n.nnn", "The destination address as the key and the amount in BTC as the value, or \"data\" as the key and hex-encoded data to include in a provably-prunable OP_RETURN output after the outputs paying to the addresses as the value")},
+			{Name: "locktime", Description: "Locktime value; a non-zero value will also locktime-activate the inputs", Optional: true, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "Hex-encoded bytes of the serialized transaction", TypeSchema: schemaString},
+		},
+	},
+	"debuglevel": {
+		Method:   "debuglevel",
+		Synopsis: "Dynamically changes the debug logging level.\nThe levelspec can either a debug level or of the form:\n<subsystem>=<level>,<subsystem2>=<level2>,...\nThe valid debug levels are trace, debug, info, warn, error, and critical.\nThe valid subsystems are AMGR, ADXR, BCDB, BMGR, XCND, CHAN, DISC, PEER, RPCS, SCRP, SRVR, and TXMP.\nThe keyword 'show' will return a list of the available subsystems.\nWhen persist is set, the levels are saved in the data directory and applied again on restart, overriding the configured ones.\nFinally the keyword 'clearpersisted' forgets the persisted levels without changing the current ones.",
+		Usage:    `debuglevel "levelspec" (persist=false)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "levelspec", Description: "The debug level(s) to use or the keyword 'show' or 'clearpersisted'", TypeSchema: schemaString},
+			{Name: "persist", Description: "Whether or not to persist the levels across restarts", Optional: true, Default: false, TypeSchema: schemaBool},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "levelspec!=show", Description: "The string 'Done.'", TypeSchema: schemaString},
+			{Condition: "levelspec=show", Description: "The list of subsystems", TypeSchema: schemaString},
+		},
+	},
+	"debugscript": {
+		Method:   "debugscript",
+		Synopsis: "Executes a signature script and public key script pair and returns the state of the script engine after each opcode.\nThe scripts are executed as the only input of a transaction spending the only output of another transaction which pays to the public key script, so signature checks are done against that transaction.",
+		Usage:    `debugscript "sigscript" "pkscript" (standard=false)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "sigscript", Description: "Hex-encoded signature script", TypeSchema: schemaString},
+			{Name: "pkscript", Description: "Hex-encoded public key script", TypeSchema: schemaString},
+			{Name: "standard", Description: "Also apply the standardness rules of the memory pool rather than only the consensus rules", Optional: true, Default: false, TypeSchema: schemaBool},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaDebugScriptResult},
+		},
+	},
+	"decodepaymenturi": {
+		Method:   "decodepaymenturi",
+		Synopsis: "Returns a JSON object describing the payment request of the provided BIP0021 payment URI.\nThe URI must use the payment URI scheme of the network and pay an address of the network, and it must not have required (req-) parameters.",
+		Usage:    `decodepaymenturi "uri"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "uri", Description: "The payment URI to decode", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaDecodePaymentURIResult},
+		},
+	},
+	"decoderawtransaction": {
+		Method:   "decoderawtransaction",
+		Synopsis: "Returns a JSON object representing the provided serialized, hex-encoded transaction.",
+		Usage:    `decoderawtransaction "hextx"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hextx", Description: "Serialized, hex-encoded transaction", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaTxRawDecodeResult},
+		},
+	},
+	"decodescript": {
+		Method:   "decodescript",
+		Synopsis: "Returns a JSON object with information about the provided hex-encoded script.",
+		Usage:    `decodescript "hexscript"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hexscript", Description: "Hex-encoded script", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaDecodeScriptResult},
+		},
+	},
+	"dumputxoset": {
+		Method:   "dumputxoset",
+		Synopsis: "Writes the unspent transaction outputs of the main chain as of the current best block to a snapshot file.\nOutputs are sorted by outpoint so the same chain state always produces the same file, and the file ends with a hash committing to every output.",
+		Usage:    `dumputxoset "path"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "path", Description: "The path of the snapshot file, relative to the data directory unless absolute (must not already exist)", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaDumpUtxoSetResult},
+		},
+	},
+	"findorphanchains": {
+		Method:   "findorphanchains",
+		Synopsis: "Returns the chains of orphan blocks, which are blocks whose parent is not known, sorted by descending total size.\nOrphans which descend from the same unknown block are reported as a single chain.",
+		Usage:    "findorphanchains",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaFindOrphanChainsResult)},
+		},
+	},
+	"fundrawtransaction": {
+		Method:   "fundrawtransaction",
+		Synopsis: "Adds inputs to a transaction until it has enough value to cover its outputs and fee, and adds a change output if needed.\nThe inputs are selected from the provided unspent outputs, or from the confirmed unspent outputs of the provided addresses when the address index is enabled (--addrindex).\nThe added inputs are not signed, so the signrawtransaction RPC command must be used to sign the resulting transaction.",
+		Usage:    `fundrawtransaction "hextx" {"changeaddress":"value","feerate":feerate,"utxos":[{"txid":"value","vout":n,"amount":n.nnn},...],"addresses":["address",...]}`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hextx", Description: "Serialized, hex-encoded transaction to fund", TypeSchema: schemaString},
+			{Name: "options", Description: "Options which control how the transaction is funded", TypeSchema: schemaFundRawTransactionOpts},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaFundRawTransactionResult},
+		},
+	},
+	"generate": {
+		Method:   "generate",
+		Synopsis: "Generates a set number of blocks (simnet or regtest only) and returns a JSON\n array of their hashes.",
+		Usage:    "generate numblocks",
+		Params: []btcjson.ParamSchema{
+			{Name: "numblocks", Description: "Number of blocks to generate", TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "The hashes, in order, of blocks generated by the call", TypeSchema: schemaArray(schemaString)},
+		},
+	},
+	"generatetodescriptor": {
+		Method:   "generatetodescriptor",
+		Synopsis: "Generates a set number of blocks whose coinbase pays to the script described by an output descriptor (simnet or regtest only)\nand returns a JSON array of their hashes.  The raw(HEX) and addr(ADDRESS) descriptors are supported, optionally followed by their checksum.",
+		Usage:    `generatetodescriptor numblocks "descriptor"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "numblocks", Description: "Number of blocks to generate", TypeSchema: schemaNumeric},
+			{Name: "descriptor", Description: "The output descriptor of the script the coinbase of the blocks pays to", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "The hashes, in order, of blocks generated by the call", TypeSchema: schemaArray(schemaString)},
+		},
+	},
+	"getaddednodeinfo": {
+		Method:   "getaddednodeinfo",
+		Synopsis: "Returns information about manually added (persistent) peers.",
+		Usage:    `getaddednodeinfo dns ("node")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "dns", Description: "Specifies whether the returned data is a JSON object including DNS and connection information, or just a list of added peers", TypeSchema: schemaBool},
+			{Name: "node", Description: "Only return information about this specific peer instead of all added peers", Optional: true, TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "dns=false", Description: "List of added peers", TypeSchema: schemaArray(schemaString)},
+			{Condition: "dns=true", TypeSchema: schemaArray(schemaGetAddedNodeInfoResult)},
+		},
+	},
+	"getaddressbalance": {
+		Method:   "getaddressbalance",
+		Synopsis: "Returns the confirmed balance of addresses.  Requires the address balance index (--addrbalanceindex).",
+		Usage:    `getaddressbalance {"addresses":["address",...]}`,
+		Params: []btcjson.ParamSchema{
+			{Name: "request", Description: "Request object", TypeSchema: schemaAddressRequest},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetAddressBalanceResult},
+		},
+	},
+	"getaddressdeltas": {
+		Method:   "getaddressdeltas",
+		Synopsis: "Returns the changes to the confirmed balance of addresses made by each input and output of the main chain, in chain order.  Requires the address balance index (--addrbalanceindex).",
+		Usage:    `getaddressdeltas {"addresses":["address",...],"start":n,"end":n}`,
+		Params: []btcjson.ParamSchema{
+			{Name: "request", Description: "Request object", TypeSchema: schemaAddressDeltasRequest},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaAddressDeltaResult)},
+		},
+	},
+	"getaddressutxos": {
+		Method:   "getaddressutxos",
+		Synopsis: "Returns the confirmed unspent outputs of addresses ordered by the height of the block which created them.  Requires the address balance index (--addrbalanceindex).",
+		Usage:    `getaddressutxos {"addresses":["address",...]}`,
+		Params: []btcjson.ParamSchema{
+			{Name: "request", Description: "Request object", TypeSchema: schemaAddressRequest},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaAddressUtxoResult)},
+		},
+	},
+	"getallsubscriptions": {
+		Method:   "getallsubscriptions",
+		Synopsis: "Returns the number of notifications each connected websocket client is registered for, ordered from the client watching the most addresses, outpoints, script hashes and filter entries to the one watching the fewest.\nThis helps finding clients which keep adding registrations without removing them.",
+		Usage:    "getallsubscriptions",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaClientSubscriptionsResult)},
+		},
+	},
+	"getapischema": {
+		Method:   "getapischema",
+		Synopsis: "Returns a machine-readable description of every RPC and websocket method and of every websocket notification.\nThe descriptions are the ones of the help and are suitable for generating client libraries.",
+		Usage:    "getapischema",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetAPISchemaResult},
+		},
+	},
+	"getbestblock": {
+		Method:   "getbestblock",
+		Synopsis: "Get block height and hash of best block in the main chain.",
+		Usage:    "getbestblock",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetBestBlockResult},
+		},
+	},
+	"getbestblockhash": {
+		Method:   "getbestblockhash",
+		Synopsis: "Returns the hash of the of the best (most recent) block in the longest block chain.",
+		Usage:    "getbestblockhash",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{Description: "The hex-encoded block hash", TypeSchema: schemaString},
+		},
+	},
+	"getblock": {
+		Method:   "getblock",
+		Synopsis: "Returns information about a block given its hash.",
+		Usage:    `getblock "hash" (verbose=true verbosetx=false)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "The hash of the block", TypeSchema: schemaString},
+			{Name: "verbose", Description: "Specifies the block is returned as a JSON object instead of hex-encoded string", Optional: true, Default: true, TypeSchema: schemaBool},
+			{Name: "verbosetx", Description: "Specifies that each transaction is returned as a JSON object and only applies if the verbose flag is true (btcd extension)", Optional: true, Default: false, TypeSchema: schemaBool},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "verbose=false", Description: "Hex-encoded bytes of the serialized block", TypeSchema: schemaString},
+			{Condition: "verbose=true", TypeSchema: schemaGetBlockVerboseResult},
+		},
+	},
+	"getblockchaininfo": {
+		Method:   "getblockchaininfo",
+		Synopsis: "Returns information about the current state of the block chain.",
+		Usage:    "getblockchaininfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetBlockChainInfoResult},
+		},
+	},
+	"getblockcount": {
+		Method:   "getblockcount",
+		Synopsis: "Returns the number of blocks in the longest block chain.",
+		Usage:    "getblockcount",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{Description: "The current block count", TypeSchema: schemaNumeric},
+		},
+	},
+	"getblockfilter": {
+		Method:   "getblockfilter",
+		Synopsis: "Returns the committed filter (BIP0158) for the given block.  Requires the committed filter index (--cfindex).",
+		Usage:    `getblockfilter "blockhash" (filtertype="basic")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "blockhash", Description: "The hash of the block", TypeSchema: schemaString},
+			{Name: "filtertype", Description: "The type of filter to return (only basic is currently supported)", Optional: true, Default: "basic", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetBlockFilterResult},
+		},
+	},
+	"getblockhash": {
+		Method:   "getblockhash",
+		Synopsis: "Returns hash of the block in best block chain at the given height.",
+		Usage:    "getblockhash index",
+		Params: []btcjson.ParamSchema{
+			{Name: "index", Description: "The block height", TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "The block hash", TypeSchema: schemaString},
+		},
+	},
+	"getblockheader": {
+		Method:   "getblockheader",
+		Synopsis: "Returns information about a block header given its hash.",
+		Usage:    `getblockheader "hash" (verbose=true)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "The hash of the block", TypeSchema: schemaString},
+			{Name: "verbose", Description: "Specifies the block header is returned as a JSON object instead of hex-encoded string", Optional: true, Default: true, TypeSchema: schemaBool},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "verbose=false", Description: "The block header hash", TypeSchema: schemaString},
+			{Condition: "verbose=true", TypeSchema: schemaGetBlockHeaderVerboseResult},
+		},
+	},
+	"getblockstats": {
+		Method:   "getblockstats",
+		Synopsis: "Returns statistics about the transactions of a block of the main chain.\nThe coin age statistics are included when the coin age index is enabled (--coinageindex) and has indexed the block.",
+		Usage:    `getblockstats "hash"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "The hash of the block", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetBlockStatsResult},
+		},
+	},
+	"getblocktemplate": {
+		Method:   "getblocktemplate",
+		Synopsis: "Returns a JSON object with information necessary to construct a block to mine or accepts a proposal to validate.\nSee BIP0022 and BIP0023 for the full specification.",
+		Usage:    `getblocktemplate ({"mode":"value","capabilities":["capability",...],"longpollid":"value","sigoplimit":sigoplimit,"sizelimit":sizelimit,"maxversion":n,"target":"value","data":"value","workid":"value","coinbaseflags":"value","payoutscript":"value"})`,
+		Params: []btcjson.ParamSchema{
+			{Name: "request", Description: "Request object which controls the mode and several parameters", Optional: true, TypeSchema: schemaTemplateRequest},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "mode=template", TypeSchema: schemaGetBlockTemplateResult},
+			{Condition: "mode=proposal, rejected", Description: "An error string which represents why the proposal was rejected or nothing if accepted", TypeSchema: schemaString},
+			{Condition: "mode=proposal, accepted", TypeSchema: schemaNull},
+		},
+	},
+	"getchainstates": {
+		Method:   "getchainstates",
+		Synopsis: "Returns the chain states of the node.\nBesides the main chain, a node bootstrapped from a UTXO snapshot with --assumeutxo has a chain state built on the snapshot until the main chain caught up with it.",
+		Usage:    "getchainstates",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetChainStatesResult},
+		},
+	},
+	"getcoinageinfo": {
+		Method:   "getcoinageinfo",
+		Synopsis: "Returns the coin age statistics of a block of the main chain.  Requires the coin age index (--coinageindex).\nThe age of a spent output is the time between the timestamps of the blocks which created and spent it.",
+		Usage:    `getcoinageinfo ("hash")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "The hash of the block (default: the best block)", Optional: true, TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetCoinAgeInfoResult},
+		},
+	},
+	"getconnectioncount": {
+		Method:   "getconnectioncount",
+		Synopsis: "Returns the number of active connections to other peers.",
+		Usage:    "getconnectioncount",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{Description: "The number of connections", TypeSchema: schemaNumeric},
+		},
+	},
+	"getcurrentnet": {
+		Method:   "getcurrentnet",
+		Synopsis: "Get bitcoin network the server is running on.",
+		Usage:    "getcurrentnet",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{Description: "The network identifer", TypeSchema: schemaNumeric},
+		},
+	},
+	"getdatabaseinfo": {
+		Method:   "getdatabaseinfo",
+		Synopsis: "Returns the approximate on-disk size of the database broken down by the chain data and each optional index, and the status of the database compaction.",
+		Usage:    "getdatabaseinfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetDatabaseInfoResult},
+		},
+	},
+	"getdbcachestats": {
+		Method:   "getdbcachestats",
+		Synopsis: "Returns the statistics of the database cache of block hashes, heights and headers which serves the repeated lookups made while rescanning, building block locators and serving headers to peers.",
+		Usage:    "getdbcachestats",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetDBCacheStatsResult},
+		},
+	},
+	"getdifficulty": {
+		Method:   "getdifficulty",
+		Synopsis: "Returns the proof-of-work difficulty as a multiple of the minimum difficulty.",
+		Usage:    "getdifficulty",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{Description: "The difficulty", TypeSchema: schemaDecimal},
+		},
+	},
+	"getdifficultyhistory": {
+		Method:   "getdifficultyhistory",
+		Synopsis: "Returns the proof-of-work difficulty and estimated network hashes per second of each difficulty retarget period.\nThe history is computed from the block headers of the main chain.",
+		Usage:    "getdifficultyhistory (periods=10 height=-1)",
+		Params: []btcjson.ParamSchema{
+			{Name: "periods", Description: "The maximum number of retarget periods to return", Optional: true, Default: 10, TypeSchema: schemaNumeric},
+			{Name: "height", Description: "Return the periods up to and including the one containing this height or -1 for current best chain block height", Optional: true, Default: -1, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaGetDifficultyHistoryResult)},
+		},
+	},
+	"getevictedtransactions": {
+		Method:   "getevictedtransactions",
+		Synopsis: "Returns the most recent transactions evicted from the memory pool because they were not mined before the expiry configured with --mempoolexpiry, ordered from the oldest to the newest eviction.\nTransactions which rely on an evicted transaction are evicted along with it.  The transactions may be resubmitted with sendrawtransaction.",
+		Usage:    "getevictedtransactions",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaEvictedTransactionResult)},
+		},
+	},
+	"getgenerate": {
+		Method:   "getgenerate",
+		Synopsis: "Returns if the server is set to generate coins (mine) or not.",
+		Usage:    "getgenerate",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{Description: "True if mining, false if not", TypeSchema: schemaBool},
+		},
+	},
+	"gethashespersec": {
+		Method:   "gethashespersec",
+		Synopsis: "Returns a recent hashes per second performance measurement while generating coins (mining).",
+		Usage:    "gethashespersec",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{Description: "The number of hashes per second", TypeSchema: schemaDecimal},
+		},
+	},
+	"getimportstatus": {
+		Method:   "getimportstatus",
+		Synopsis: "Returns the progress of importing blocks from the bootstrap files passed via --loadblock or placed in the blocks/import directory.",
+		Usage:    "getimportstatus",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetImportStatusResult},
+		},
+	},
+	"getinfo": {
+		Method:   "getinfo",
+		Synopsis: "Returns a JSON object containing various state info.",
+		Usage:    "getinfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaInfoChainResult},
+		},
+	},
+	"getlog": {
+		Method:   "getlog",
+		Synopsis: "Returns the most recent lines logged by a subsystem, oldest first.\nOnly lines at or above the level of the subsystem at the time they were logged are kept, up to the number set with the logbuffer option.",
+		Usage:    `getlog "subsystem" (count=100)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "subsystem", Description: "The subsystem to return the log lines of", TypeSchema: schemaString},
+			{Name: "count", Description: "The maximum number of lines to return", Optional: true, Default: 100, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "The log lines", TypeSchema: schemaArray(schemaString)},
+		},
+	},
+	"getmempoolinfo": {
+		Method:   "getmempoolinfo",
+		Synopsis: "Returns memory pool information",
+		Usage:    "getmempoolinfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetMempoolInfoResult},
+		},
+	},
+	"getmininginfo": {
+		Method:   "getmininginfo",
+		Synopsis: "Returns a JSON object containing mining-related information.",
+		Usage:    "getmininginfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetMiningInfoResult},
+		},
+	},
+	"getnettotals": {
+		Method:   "getnettotals",
+		Synopsis: "Returns a JSON object containing network traffic statistics.",
+		Usage:    "getnettotals",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetNetTotalsResult},
+		},
+	},
+	"getnetworkfaults": {
+		Method:   "getnetworkfaults",
+		Synopsis: "Returns the network faults set with setnetworkfault along with the number of messages they dropped and delayed (simnet only).",
+		Usage:    "getnetworkfaults",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaNetworkFaultResult)},
+		},
+	},
+	"getnetworkhashps": {
+		Method:   "getnetworkhashps",
+		Synopsis: "Returns the estimated network hashes per second for the block heights provided by the parameters.\nHistorical hash rates are estimated by passing the height of the last block of the window to estimate over.",
+		Usage:    "getnetworkhashps (blocks=120 height=-1)",
+		Params: []btcjson.ParamSchema{
+			{Name: "blocks", Description: "The number of blocks in the window, or -1 for blocks since last difficulty change", Optional: true, Default: 120, TypeSchema: schemaNumeric},
+			{Name: "height", Description: "Perform estimate ending with this height or -1 for current best chain block height", Optional: true, Default: -1, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "Estimated hashes per second", TypeSchema: schemaNumeric},
+		},
+	},
+	"getnetworkinfo": {
+		Method:   "getnetworkinfo",
+		Synopsis: "Returns a JSON object containing network-related information.",
+		Usage:    "getnetworkinfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetNetworkInfoResult},
+		},
+	},
+	"getnetworktopology": {
+		Method:   "getnetworktopology",
+		Synopsis: "Returns how the connected peers are distributed over network groups, and over autonomous systems when an AS map is loaded with --asmap.\nOutbound peers concentrated in few groups or autonomous systems are easier to eclipse by a single network operator.",
+		Usage:    "getnetworktopology",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetNetworkTopologyResult},
+		},
+	},
+	"getpeerinfo": {
+		Method:   "getpeerinfo",
+		Synopsis: "Returns data about each connected network peer as an array of json objects.",
+		Usage:    "getpeerinfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaGetPeerInfoResult)},
+		},
+	},
+	"getpolicyinfo": {
+		Method:   "getpolicyinfo",
+		Synopsis: "Returns the effective standardness policy the memory pool applies to transactions it accepts and relays.",
+		Usage:    "getpolicyinfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetPolicyInfoResult},
+		},
+	},
+	"getrawmempool": {
+		Method:   "getrawmempool",
+		Synopsis: "Returns information about all of the transactions currently in the memory pool.",
+		Usage:    "getrawmempool (verbose=false)",
+		Params: []btcjson.ParamSchema{
+			{Name: "verbose", Description: "Returns JSON object when true or an array of transaction hashes when false", Optional: true, Default: false, TypeSchema: schemaBool},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "verbose=false", Description: "Array of transaction hashes", TypeSchema: schemaArray(schemaString)},
+			{Condition: "verbose=true", TypeSchema: schemaGetRawMempoolVerboseResult},
+		},
+	},
+	"getrawtransaction": {
+		Method:   "getrawtransaction",
+		Synopsis: "Returns information about a transaction given its hash.",
+		Usage:    `getrawtransaction "txid" (verbose=0)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "verbose", Description: "Specifies the transaction is returned as a JSON object instead of a hex-encoded string", Optional: true, Default: 0, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "verbose=false", Description: "Hex-encoded bytes of the serialized transaction", TypeSchema: schemaString},
+			{Condition: "verbose=true", TypeSchema: schemaTxRawResult},
+		},
+	},
+	"getrescanstatus": {
+		Method:    "getrescanstatus",
+		Synopsis:  "Return the progress of a rescan job started with startrescan.",
+		Usage:     `getrescanstatus "id"`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "id", Description: "The id of the rescan job", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetRescanStatusResult},
+		},
+	},
+	"getrpcinfo": {
+		Method:   "getrpcinfo",
+		Synopsis: "Returns the number of connected RPC clients and the number of addresses and outpoints each websocket client watches along with the limits configured for them.",
+		Usage:    "getrpcinfo",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetRPCInfoResult},
+		},
+	},
+	"getspendinginfo": {
+		Method:   "getspendinginfo",
+		Synopsis: "Returns the transaction input which spends an output.  Requires the spent output index (--stxoindex).",
+		Usage:    `getspendinginfo "txid" vout (includemempool=true)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "The hash of the transaction which created the output", TypeSchema: schemaString},
+			{Name: "vout", Description: "The index of the output", TypeSchema: schemaNumeric},
+			{Name: "includemempool", Description: "Include transactions in the memory pool which spend the output", Optional: true, Default: true, TypeSchema: schemaBool},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetSpendingInfoResult},
+		},
+	},
+	"getsubscriptions": {
+		Method:    "getsubscriptions",
+		Synopsis:  "Returns the notifications the websocket client is currently registered for.",
+		Usage:     "getsubscriptions",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetSubscriptionsResult},
+		},
+	},
+	"getsyncstatus": {
+		Method:   "getsyncstatus",
+		Synopsis: "Returns how far the server is with downloading and verifying the block chain from its peers.\nRelayed transactions are not accepted into the memory pool and block templates are not pushed to websocket clients during the initial block download.",
+		Usage:    "getsyncstatus",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetSyncStatusResult},
+		},
+	},
+	"gettxout": {
+		Method:   "gettxout",
+		Synopsis: "Returns information about an unspent transaction output..",
+		Usage:    `gettxout "txid" vout (includemempool=true)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "vout", Description: "The index of the output", TypeSchema: schemaNumeric},
+			{Name: "includemempool", Description: "Include the mempool when true", Optional: true, Default: true, TypeSchema: schemaBool},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetTxOutResult},
+		},
+	},
+	"gettxoutsetinfo": {
+		Method:   "gettxoutsetinfo",
+		Synopsis: "Returns statistics about the unspent transaction outputs of the main chain.\nThe muhash hash type returns the rolling hash of the set the chain maintains as blocks are connected without iterating the set, which allows the sets of two nodes to be compared cheaply.\nThe first call with the muhash hash type can take a long time when the hashes of past blocks haven't been calculated yet.",
+		Usage:    `gettxoutsetinfo (hashtype="hash_serialized")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hashtype", Description: "The hash of the set to return: 'hash_serialized' for the double sha256 of the set serialized like in snapshot files, 'muhash' for the rolling hash, or 'none'", Optional: true, Default: "hash_serialized", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetTxOutSetInfoResult},
+		},
+	},
+	"getunconfirmedbroadcasts": {
+		Method:   "getunconfirmedbroadcasts",
+		Synopsis: "Returns the transactions submitted with sendrawtransaction which are not confirmed yet, ordered from the oldest to the newest submission.\nThey are rebroadcast periodically until they are confirmed, evicted from the memory pool or removed with removebroadcast.",
+		Usage:    "getunconfirmedbroadcasts",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaArray(schemaUnconfirmedBroadcastResult)},
+		},
+	},
+	"getutxocommitment": {
+		Method:   "getutxocommitment",
+		Synopsis: "Returns the hash of the unspent transaction outputs as of a block of the main chain.\nOnce the utxocommitment rule change is active, the coinbase of the next block commits to this hash, which allows a UTXO set loaded from a snapshot to be verified.\nThe first call can take a long time when the hashes of past blocks haven't been calculated yet.",
+		Usage:    `getutxocommitment ("hash")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "The hash of the block (default: the best block)", Optional: true, TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaGetUtxoCommitmentResult},
+		},
+	},
+	"getwork": {
+		Method:   "getwork",
+		Synopsis: "(DEPRECATED - Use getblocktemplate instead) Returns formatted hash data to work on or checks and submits solved data.",
+		Usage:    `getwork ("data")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "data", Description: "Hex-encoded data to check", Optional: true, TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "no data provided", TypeSchema: schemaGetWorkResult},
+			{Condition: "data provided", Description: "Whether or not the solved data is valid and was added to the chain", TypeSchema: schemaBool},
+		},
+	},
+	"help": {
+		Method:   "help",
+		Synopsis: "Returns a list of all commands or help for a specified command.",
+		Usage:    `help ("command")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "command", Description: "The command to retrieve help for", Optional: true, TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "no command provided", Description: "List of commands", TypeSchema: schemaString},
+			{Condition: "command specified", Description: "Help for specified command", TypeSchema: schemaString},
+		},
+	},
+	"loadtxfilter": {
+		Method:    "loadtxfilter",
+		Synopsis:  "Load, reload or add to a named transaction filter.\nA single websocket connection may load several filters, for example one per wallet.\nTransactions paying to a filter address are sent as filteredrecvtx notifications and transactions spending a filter outpoint as filteredredeemingtx notifications, both tagged with the filter id.\nMatching outputs are automatically added to the outpoints of the filter.",
+		Usage:     `loadtxfilter "filterid" reload ["address",...] [{"hash":"value","index":n},...]`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "filterid", Description: "Client-chosen identifier of the filter", TypeSchema: schemaString},
+			{Name: "reload", Description: "Replace the filter if it is already loaded instead of adding to it", TypeSchema: schemaBool},
+			{Name: "addresses", Description: "List of addresses to add to the filter", TypeSchema: schemaArray(schemaString)},
+			{Name: "outpoints", Description: "List of unspent transaction outpoints to add to the filter", TypeSchema: schemaArray(schemaOutPoint)},
+		},
+	},
+	"loadutxoset": {
+		Method:   "loadutxoset",
+		Synopsis: "Verifies a snapshot file written by dumputxoset.\nThe snapshot must be intact, be for the active network, and match the unspent transaction outputs of the main chain at the block it was taken at.",
+		Usage:    `loadutxoset "path" ("expectedhash")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "path", Description: "The path of the snapshot file, relative to the data directory unless absolute", TypeSchema: schemaString},
+			{Name: "expectedhash", Description: "The expected hash of the unspent transaction output set", Optional: true, TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaLoadUtxoSetResult},
+		},
+	},
+	"node": {
+		Method:   "node",
+		Synopsis: "Attempts to add or remove a peer.",
+		Usage:    `node "connect|remove|disconnect" "target" ("perm|temp")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "subcmd", Description: "'disconnect' to remove all matching non-persistent peers, 'remove' to remove a persistent peer, or 'connect' to connect to a peer", TypeSchema: schemaString},
+			{Name: "target", Description: "Either the IP address and port of the peer to operate on, or a valid peer ID.", TypeSchema: schemaString},
+			{Name: "connectsubcmd", Description: "'perm' to make the connected peer a permanent one, 'temp' to try a single connect to a peer", Optional: true, TypeSchema: schemaString},
+		},
+	},
+	"notifyblockheaders": {
+		Method:    "notifyblockheaders",
+		Synopsis:  "Request notifications for whenever a block header is connected or disconnected from the main (best) chain.\nThe notifications are sent with and without --headersonly, along with a chainreorg notification for each reorganization.",
+		Usage:     "notifyblockheaders",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"notifyblocks": {
+		Method:    "notifyblocks",
+		Synopsis:  "Request notifications for whenever a block is connected or disconnected from the main (best) chain.",
+		Usage:     "notifyblocks",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"notifyblocktemplate": {
+		Method:    "notifyblocktemplate",
+		Synopsis:  "Request a fresh block template whenever the best chain changes or enough new fee-paying transactions arrive in the memory pool.\nThe templates are sent with the blocktemplate notification.  A template is sent right away once registered.",
+		Usage:     `notifyblocktemplate ({"mode":"value","capabilities":["capability",...],"longpollid":"value","sigoplimit":sigoplimit,"sizelimit":sizelimit,"maxversion":n,"target":"value","data":"value","workid":"value","coinbaseflags":"value","payoutscript":"value"})`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "request", Description: "Request object interpreted the same way as the template request of getblocktemplate (long polling is not supported)", Optional: true, TypeSchema: schemaTemplateRequest},
+		},
+	},
+	"notifyclockskew": {
+		Method:    "notifyclockskew",
+		Synopsis:  "Request notifications for whenever the local clock starts or stops differing from the median time of peers by more than the configured maximum.",
+		Usage:     "notifyclockskew",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"notifyconfirmations": {
+		Method:    "notifyconfirmations",
+		Synopsis:  "Send a single txconfirmed notification once a transaction has at least the passed number of confirmations in the main chain.\nThe notification is sent right away when the transaction already has enough confirmations.\nWhen the block containing the transaction is disconnected before then, the confirmations are counted again once the transaction is mined in another block.\nRegistering the same transaction again replaces the number of confirmations waited for.",
+		Usage:     `notifyconfirmations "txid" confirmations`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "confirmations", Description: "The number of confirmations to wait for (at least 1)", TypeSchema: schemaNumeric},
+		},
+	},
+	"notifynewtransactions": {
+		Method:    "notifynewtransactions",
+		Synopsis:  "Send either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
+		Usage:     "notifynewtransactions (verbose=false)",
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "verbose", Description: "Specifies which type of notification to receive. If verbose is true, then the caller receives txacceptedverbose, otherwise the caller receives txaccepted", Optional: true, Default: false, TypeSchema: schemaBool},
+		},
+	},
+	"notifypeerevents": {
+		Method:    "notifypeerevents",
+		Synopsis:  "Request notifications for whenever a peer connects or disconnects and whenever the peer the block chain is synced from changes.\nThe events are sent with the peerconnected, peerdisconnected and syncpeerchanged notifications.",
+		Usage:     "notifypeerevents",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"notifyreceived": {
+		Method:    "notifyreceived",
+		Synopsis:  "Send a recvtx notification when a transaction added to mempool or appears in a newly-attached block contains a txout pkScript sending to any of the passed addresses.\nMatching outpoints are automatically registered for redeemingtx notifications.",
+		Usage:     `notifyreceived ["address",...]`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "addresses", Description: "List of address to receive notifications about", TypeSchema: schemaArray(schemaString)},
+		},
+	},
+	"notifyspent": {
+		Method:    "notifyspent",
+		Synopsis:  "Send a redeemingtx notification when a transaction spending an outpoint appears in mempool (if relayed to this btcd instance) and when such a transaction first appears in a newly-attached block.",
+		Usage:     `notifyspent [{"hash":"value","index":n},...]`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "outpoints", Description: "List of transaction outpoints to monitor.", TypeSchema: schemaArray(schemaOutPoint)},
+		},
+	},
+	"ping": {
+		Method:   "ping",
+		Synopsis: "Queues a ping to be sent to each connected peer.\nPing times are provided by getpeerinfo via the pingtime and pingwait fields.",
+		Usage:    "ping",
+		Params:   []btcjson.ParamSchema{},
+	},
+	"removebroadcast": {
+		Method:   "removebroadcast",
+		Synopsis: "Stops rebroadcasting a transaction submitted with sendrawtransaction.\nThe transaction is left in the memory pool.",
+		Usage:    `removebroadcast "txid"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+		},
+	},
+	"removefilterentry": {
+		Method:   "removefilterentry",
+		Synopsis: "Lifts a ban added with addfilterentry or loaded with the txblacklist option.\nTransactions which were rejected are not reconsidered until they are relayed again.",
+		Usage:    `removefilterentry "entry"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "entry", Description: "The entry to remove, in any of the forms accepted by addfilterentry", TypeSchema: schemaString},
+		},
+	},
+	"removetxfilter": {
+		Method:    "removetxfilter",
+		Synopsis:  "Remove addresses and outpoints from a loaded transaction filter, or unload the entire filter when neither is specified.",
+		Usage:     `removetxfilter "filterid" (["address",...] [{"hash":"value","index":n},...])`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "filterid", Description: "Identifier of the filter", TypeSchema: schemaString},
+			{Name: "addresses", Description: "List of addresses to remove from the filter", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "outpoints", Description: "List of transaction outpoints to remove from the filter", Optional: true, TypeSchema: schemaArray(schemaOutPoint)},
+		},
+	},
+	"replayevents": {
+		Method:    "replayevents",
+		Synopsis:  "Replay the block and transaction events recorded in the event journal since the passed block or journal sequence number.\nEach event is sent as a replayedevent notification, followed by a replayfinished notification with the sequence number of the most recent event.\nRequires the event journal to be enabled with --eventjournal.",
+		Usage:     `replayevents "since"`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "since", Description: "Hash of a connected block or a journal sequence number in decimal after which to replay events", TypeSchema: schemaString},
+		},
+	},
+	"rescan": {
+		Method:    "rescan",
+		Synopsis:  "Rescan block chain for transactions to addresses.\nWhen the endblock parameter is omitted, the rescan continues through the best block in the main chain.\nRescan results are sent as recvtx and redeemingtx notifications.\nThis call returns once the rescan completes.",
+		Usage:     `rescan "beginblock" ["address",...] [{"hash":"value","index":n},...] ("endblock")`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "beginblock", Description: "Hash of the first block to begin rescanning", TypeSchema: schemaString},
+			{Name: "addresses", Description: "List of addresses to include in the rescan", TypeSchema: schemaArray(schemaString)},
+			{Name: "outpoints", Description: "List of transaction outpoints to include in the rescan", TypeSchema: schemaArray(schemaOutPoint)},
+			{Name: "endblock", Description: "Hash of final block to rescan", Optional: true, TypeSchema: schemaString},
+		},
+	},
+	"rescanfilter": {
+		Method:    "rescanfilter",
+		Synopsis:  "Rescan block chain for transactions matching a loaded transaction filter.\nWhen the endblock parameter is omitted, the rescan continues through the best block in the main chain and the outpoints of the filter are updated with the results.\nRescan results are sent as filteredrecvtx and filteredredeemingtx notifications tagged with the filter id.\nThis call returns once the rescan completes.",
+		Usage:     `rescanfilter "filterid" "beginblock" ("endblock")`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "filterid", Description: "Identifier of the filter to rescan", TypeSchema: schemaString},
+			{Name: "beginblock", Description: "Hash of the first block to begin rescanning", TypeSchema: schemaString},
+			{Name: "endblock", Description: "Hash of final block to rescan", Optional: true, TypeSchema: schemaString},
+		},
+	},
+	"searchrawtransactions": {
+		Method:   "searchrawtransactions",
+		Synopsis: "Returns raw data for transactions involving the passed address.\nReturned transactions are pulled from both the database, and transactions currently in the mempool.\nTransactions pulled from the mempool will have the 'confirmations' field set to 0.\nUsage of this RPC requires the optional --addrindex flag to be activated, otherwise all responses will simply return with an error stating the address index has not yet been built.\nSimilarly, until the address index has caught up with the current best height, all requests will return an error response in order to avoid serving stale data.",
+		Usage:    `searchrawtransactions "address" (verbose=1 skip=0 count=100 vinextra=0 reverse=false ["filteraddr",...])`,
+		Params: []btcjson.ParamSchema{
+			{Name: "address", Description: "The Bitcoin address to search for", TypeSchema: schemaString},
+			{Name: "verbose", Description: "Specifies the transaction is returned as a JSON object instead of hex-encoded string", Optional: true, Default: 1, TypeSchema: schemaNumeric},
+			{Name: "skip", Description: "The number of leading transactions to leave out of the final response", Optional: true, Default: 0, TypeSchema: schemaNumeric},
+			{Name: "count", Description: "The maximum number of transactions to return", Optional: true, Default: 100, TypeSchema: schemaNumeric},
+			{Name: "vinextra", Description: "Specify that extra data from previous output will be returned in vin", Optional: true, Default: 0, TypeSchema: schemaNumeric},
+			{Name: "reverse", Description: "Specifies that the transactions should be returned in reverse chronological order", Optional: true, Default: false, TypeSchema: schemaBool},
+			{Name: "filteraddrs", Description: "Address list.  Only inputs or outputs with matching address will be returned", Optional: true, TypeSchema: schemaArray(schemaString)},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "verbose=0", Description: "Hex-encoded serialized transaction", TypeSchema: schemaString},
+			{Condition: "verbose=1", TypeSchema: schemaArray(schemaSearchRawTransactionsResult)},
+		},
+	},
+	"sendrawtransaction": {
+		Method:   "sendrawtransaction",
+		Synopsis: "Submits the serialized, hex-encoded transaction to the local peer and relays it to the network.",
+		Usage:    `sendrawtransaction "hextx" (allowhighfees=false maxfeerate)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hextx", Description: "Serialized, hex-encoded signed transaction", TypeSchema: schemaString},
+			{Name: "allowhighfees", Description: "Whether or not to allow insanely high fees (overrides maxfeerate when true)", Optional: true, Default: false, TypeSchema: schemaBool},
+			{Name: "maxfeerate", Description: "Reject transactions whose fee rate is higher than this value in BTC/kB (0 to accept any fee rate, defaults to 0.1)", Optional: true, TypeSchema: schemaDecimal},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "The hash of the transaction", TypeSchema: schemaString},
+		},
+	},
+	"session": {
+		Method:    "session",
+		Synopsis:  "Return details regarding a websocket client's current connection session.",
+		Usage:     "session",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaSessionResult},
+		},
+	},
+	"setblocktemplatepolicy": {
+		Method:   "setblocktemplatepolicy",
+		Synopsis: "Changes the policy block templates are generated with and returns the resulting policy.\nThe changes apply to every block template generated afterwards and last until the server is restarted.",
+		Usage:    `setblocktemplatepolicy ({"blockmaxsize":blockmaxsize,"blockminsize":blockminsize,"blockprioritysize":blockprioritysize,"minfeerate":minfeerate,"minpriority":minpriority,"recenttxage":recenttxage,"recenttxweight":recenttxweight})`,
+		Params: []btcjson.ParamSchema{
+			{Name: "policy", Description: "The policy settings to change -- settings which are omitted are left unchanged", Optional: true, TypeSchema: schemaBlockTemplatePolicy},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaBlockTemplatePolicyResult},
+		},
+	},
+	"setblockversion": {
+		Method:   "setblockversion",
+		Synopsis: "Override the version of the block templates generated afterwards, which determines the rule change deployments the blocks signal for.\nOnly available on the regression and simulation test networks.",
+		Usage:    "setblockversion version",
+		Params: []btcjson.ParamSchema{
+			{Name: "version", Description: "The block version to signal, or 0 to use the version computed from the deployments again", TypeSchema: schemaNumeric},
+		},
+	},
+	"setgenerate": {
+		Method:   "setgenerate",
+		Synopsis: "Set the server to generate coins (mine) or not.",
+		Usage:    "setgenerate generate (genproclimit=-1)",
+		Params: []btcjson.ParamSchema{
+			{Name: "generate", Description: "Use true to enable generation, false to disable it", TypeSchema: schemaBool},
+			{Name: "genproclimit", Description: "The number of processors (cores) to limit generation to or -1 for default", Optional: true, Default: -1, TypeSchema: schemaNumeric},
+		},
+	},
+	"setmocktime": {
+		Method:   "setmocktime",
+		Synopsis: "Override the current time of the node for the adjusted time used by block timestamp and transaction lock time checks and for the comparison with the timestamps of peers.\nOnly available on the regression and simulation test networks.",
+		Usage:    "setmocktime timestamp",
+		Params: []btcjson.ParamSchema{
+			{Name: "timestamp", Description: "The current time in seconds since 1 Jan 1970 GMT, or 0 to use the local clock again", TypeSchema: schemaNumeric},
+		},
+	},
+	"setnetworkfault": {
+		Method:   "setnetworkfault",
+		Synopsis: "Drops or delays the messages exchanged with a peer to simulate an unreliable network or a partition (simnet only).\nThe fault replaces the previous one for the peer and lasts until it is removed with clearnetworkfaults.  A fault for the address of a peer also applies after the peer reconnects, while a fault for its id takes precedence.",
+		Usage:    `setnetworkfault "target" {"direction":"value","commands":["command",...],"droprate":n.nnn,"delay":n}`,
+		Params: []btcjson.ParamSchema{
+			{Name: "target", Description: "The id or address of the peer", TypeSchema: schemaString},
+			{Name: "fault", Description: "The messages to drop or delay", TypeSchema: schemaNetworkFault},
+		},
+	},
+	"signmessagewithprivkey": {
+		Method:   "signmessagewithprivkey",
+		Synopsis: "Sign a message with a private key.\nThe message is prefixed with the signed message magic like Bitcoin messages, so the signature can be checked with verifymessage against the pay-to-pubkey-hash address of the key.",
+		Usage:    `signmessagewithprivkey "privkey" "message"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "privkey", Description: "The WIF-encoded private key to sign the message with", TypeSchema: schemaString},
+			{Name: "message", Description: "The message to sign", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "The base-64 encoded compact signature of the message", TypeSchema: schemaString},
+		},
+	},
+	"signrawtransaction": {
+		Method:   "signrawtransaction",
+		Synopsis: "Signs the inputs of the serialized, hex-encoded transaction using the provided private keys.\nThe private keys are only used for the duration of the call and are never stored.\nPrevious outputs that are not provided are looked up in the memory pool and the main chain.",
+		Usage:    `signrawtransaction "rawtx" ([{"txid":"value","vout":n,"scriptpubkey":"value","redeemscript":"value"},...] ["privkey",...] flags="ALL")`,
+		Params: []btcjson.ParamSchema{
+			{Name: "rawtx", Description: "Serialized, hex-encoded transaction to sign", TypeSchema: schemaString},
+			{Name: "inputs", Description: "The previous outputs being spent by the transaction", Optional: true, TypeSchema: schemaArray(schemaRawTxInput)},
+			{Name: "privkeys", Description: "Array of WIF-encoded private keys to sign with", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "flags", Description: "The signature hash type: 'ALL', 'NONE', or 'SINGLE', optionally combined with '|ANYONECANPAY'", Optional: true, Default: "ALL", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaSignRawTransactionResult},
+		},
+	},
+	"startrescan": {
+		Method:    "startrescan",
+		Synopsis:  "Start a rescan like the rescan command as a job running in the background and return the id of the job right away.\nRescan results are sent as filteredrecvtx and filteredredeemingtx notifications, and progress as filteredrescanprogress and filteredrescanfinished notifications, all tagged with the job id.\nUse getrescanstatus to poll the progress of the job and cancelrescan to stop it.  Jobs are canceled when the client disconnects.",
+		Usage:     `startrescan "beginblock" ["address",...] [{"hash":"value","index":n},...] ("endblock")`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "beginblock", Description: "Hash of the first block to begin rescanning", TypeSchema: schemaString},
+			{Name: "addresses", Description: "List of addresses to include in the rescan", TypeSchema: schemaArray(schemaString)},
+			{Name: "outpoints", Description: "List of transaction outpoints to include in the rescan", TypeSchema: schemaArray(schemaOutPoint)},
+			{Name: "endblock", Description: "Hash of final block to rescan", Optional: true, TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "The id of the rescan job", TypeSchema: schemaString},
+		},
+	},
+	"stop": {
+		Method:   "stop",
+		Synopsis: "Shutdown btcd.",
+		Usage:    "stop",
+		Params:   []btcjson.ParamSchema{},
+		Results: []btcjson.ResultSchema{
+			{Description: "The string 'btcd stopping.'", TypeSchema: schemaString},
+		},
+	},
+	"stopnotifyblockheaders": {
+		Method:    "stopnotifyblockheaders",
+		Synopsis:  "Cancel registered notifications for whenever a block header is connected or disconnected from the main (best) chain.",
+		Usage:     "stopnotifyblockheaders",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"stopnotifyblocks": {
+		Method:    "stopnotifyblocks",
+		Synopsis:  "Cancel registered notifications for whenever a block is connected or disconnected from the main (best) chain.",
+		Usage:     "stopnotifyblocks",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"stopnotifyblocktemplate": {
+		Method:    "stopnotifyblocktemplate",
+		Synopsis:  "Cancel registered block template notifications.",
+		Usage:     "stopnotifyblocktemplate",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"stopnotifyclockskew": {
+		Method:    "stopnotifyclockskew",
+		Synopsis:  "Cancel registered notifications for whenever the local clock starts or stops differing from the median time of peers.",
+		Usage:     "stopnotifyclockskew",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"stopnotifyconfirmations": {
+		Method:    "stopnotifyconfirmations",
+		Synopsis:  "Cancel a registered confirmations notification for the passed transaction.",
+		Usage:     `stopnotifyconfirmations "txid"`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+		},
+	},
+	"stopnotifynewtransactions": {
+		Method:    "stopnotifynewtransactions",
+		Synopsis:  "Stop sending either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
+		Usage:     "stopnotifynewtransactions",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"stopnotifypeerevents": {
+		Method:    "stopnotifypeerevents",
+		Synopsis:  "Cancel registered peer event notifications.",
+		Usage:     "stopnotifypeerevents",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"stopnotifyreceived": {
+		Method:    "stopnotifyreceived",
+		Synopsis:  "Cancel registered receive notifications for each passed address.",
+		Usage:     `stopnotifyreceived ["address",...]`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "addresses", Description: "List of address to cancel receive notifications for", TypeSchema: schemaArray(schemaString)},
+		},
+	},
+	"stopnotifyspent": {
+		Method:    "stopnotifyspent",
+		Synopsis:  "Cancel registered spending notifications for each passed outpoint.",
+		Usage:     `stopnotifyspent [{"hash":"value","index":n},...]`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "outpoints", Description: "List of transaction outpoints to stop monitoring.", TypeSchema: schemaArray(schemaOutPoint)},
+		},
+	},
+	"submitblock": {
+		Method:   "submitblock",
+		Synopsis: "Attempts to submit a new serialized, hex-encoded block to the network.",
+		Usage:    `submitblock "hexblock" ({"workid":"value"})`,
+		Params: []btcjson.ParamSchema{
+			{Name: "hexblock", Description: "Serialized, hex-encoded block", TypeSchema: schemaString},
+			{Name: "options", Description: "This parameter is currently ignored", Optional: true, TypeSchema: schemaSubmitBlockOptions},
+		},
+		Results: []btcjson.ResultSchema{
+			{Condition: "Block successfully submitted", TypeSchema: schemaNull},
+			{Condition: "Block rejected", Description: "The reason the block was rejected", TypeSchema: schemaString},
+		},
+	},
+	"subscribemempool": {
+		Method:    "subscribemempool",
+		Synopsis:  "Stream every transaction added to or removed from the mempool, so the mempool can be mirrored without diffing getrawmempool results.\nAdditions are sent as mempooltxadded and removals as mempooltxremoved notifications, in the order the mempool changed.\nTo build a mirror, subscribe first and then apply the notifications to the result of getrawmempool, ignoring additions of known and removals of unknown transactions.",
+		Usage:     "subscribemempool",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"subscriberawblocks": {
+		Method:    "subscriberawblocks",
+		Synopsis:  "Stream the serialized blocks of the main chain as they are connected, starting with the block at the passed height, so archival mirrors can stay in sync without polling getblock.\nThe blocks are sent one at a time in order of height, each once it has been written to the client.  When the main chain is reorganized, the blocks replacing the disconnected blocks are sent again starting from the fork point.",
+		Usage:     `subscriberawblocks (encoding="hex" fromheight)`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "encoding", Description: "How blocks are sent: 'hex' for rawblock notifications or 'binary' for binary websocket frames which only hold the serialized block", Optional: true, Default: "hex", TypeSchema: schemaString},
+			{Name: "fromheight", Description: "The height of the first block to send, which backfills the blocks up to the best block (default: only blocks connected from now on)", Optional: true, TypeSchema: schemaNumeric},
+		},
+	},
+	"subscribescripthash": {
+		Method:    "subscribescripthash",
+		Synopsis:  "Send a scripthashtx notification when a transaction added to mempool or appears in a newly-attached block pays to or spends from an output script with any of the passed script hashes.\nA script hash is the SHA256 hash of an output script in the reversed byte order of the Electrum protocol.",
+		Usage:     `subscribescripthash ["scripthash",...]`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "scripthashes", Description: "List of hex-encoded script hashes to receive notifications about", TypeSchema: schemaArray(schemaString)},
+		},
+	},
+	"unsubscribemempool": {
+		Method:    "unsubscribemempool",
+		Synopsis:  "Cancel the mempool stream registered with subscribemempool.",
+		Usage:     "unsubscribemempool",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"unsubscriberawblocks": {
+		Method:    "unsubscriberawblocks",
+		Synopsis:  "Cancel the raw block stream registered with subscriberawblocks.",
+		Usage:     "unsubscriberawblocks",
+		Websocket: true,
+		Params:    []btcjson.ParamSchema{},
+	},
+	"unsubscribescripthash": {
+		Method:    "unsubscribescripthash",
+		Synopsis:  "Cancel registered script hash notifications for each passed script hash.",
+		Usage:     `unsubscribescripthash ["scripthash",...]`,
+		Websocket: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "scripthashes", Description: "List of hex-encoded script hashes to cancel notifications for", TypeSchema: schemaArray(schemaString)},
+		},
+	},
+	"validateaddress": {
+		Method:   "validateaddress",
+		Synopsis: "Verify an address is valid and describe the script outputs paying to it are locked with.",
+		Usage:    `validateaddress "address"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "address", Description: "Bitcoin address to validate", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaValidateAddressChainResult},
+		},
+	},
+	"verifychain": {
+		Method:   "verifychain",
+		Synopsis: "Verifies the block chain database.\nThe actual checks performed by the checklevel parameter are implementation specific.\nFor btcd this is:\nchecklevel=0 - Look up each block and ensure it can be loaded from the database.\nchecklevel=1 - Perform basic context-free sanity checks on each block.\nchecklevel=2 - Ensure the transactions of each block are indexed and the outputs they spend are marked spent.\nchecklevel=3 - Ensure the tips of the optional indexes can be loaded.",
+		Usage:    "verifychain (checklevel=3 checkdepth=288)",
+		Params: []btcjson.ParamSchema{
+			{Name: "checklevel", Description: "How thorough the block verification is", Optional: true, Default: 3, TypeSchema: schemaNumeric},
+			{Name: "checkdepth", Description: "The number of most recent blocks to check, 0 for all of them", Optional: true, Default: 288, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "Whether or not the chain verified", TypeSchema: schemaBool},
+		},
+	},
+	"verifymessage": {
+		Method:   "verifymessage",
+		Synopsis: "Verify a signed message.",
+		Usage:    `verifymessage "address" "signature" "message"`,
+		Params: []btcjson.ParamSchema{
+			{Name: "address", Description: "The bitcoin address to use for the signature", TypeSchema: schemaString},
+			{Name: "signature", Description: "The base-64 encoded signature provided by the signer", TypeSchema: schemaString},
+			{Name: "message", Description: "The signed message", TypeSchema: schemaString},
+		},
+		Results: []btcjson.ResultSchema{
+			{Description: "Whether or not the signature verified", TypeSchema: schemaBool},
+		},
+	},
+	"waitforblock": {
+		Method:   "waitforblock",
+		Synopsis: "Waits until the block with the given hash is the tip of the best chain or the timeout expires and returns the tip at that time.",
+		Usage:    `waitforblock "blockhash" (timeout=0)`,
+		Params: []btcjson.ParamSchema{
+			{Name: "blockhash", Description: "The hash of the block to wait for", TypeSchema: schemaString},
+			{Name: "timeout", Description: "The maximum time to wait in milliseconds (0 waits indefinitely)", Optional: true, Default: 0, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaWaitForBlockResult},
+		},
+	},
+	"waitforblockheight": {
+		Method:   "waitforblockheight",
+		Synopsis: "Waits until the best chain reaches at least the given height or the timeout expires and returns the tip at that time.",
+		Usage:    "waitforblockheight height (timeout=0)",
+		Params: []btcjson.ParamSchema{
+			{Name: "height", Description: "The height to wait for", TypeSchema: schemaNumeric},
+			{Name: "timeout", Description: "The maximum time to wait in milliseconds (0 waits indefinitely)", Optional: true, Default: 0, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaWaitForBlockResult},
+		},
+	},
+	"waitfornewblock": {
+		Method:   "waitfornewblock",
+		Synopsis: "Waits until the tip of the best chain changes or the timeout expires and returns the tip at that time.",
+		Usage:    "waitfornewblock (timeout=0)",
+		Params: []btcjson.ParamSchema{
+			{Name: "timeout", Description: "The maximum time to wait in milliseconds (0 waits indefinitely)", Optional: true, Default: 0, TypeSchema: schemaNumeric},
+		},
+		Results: []btcjson.ResultSchema{
+			{TypeSchema: schemaWaitForBlockResult},
+		},
+	},
+}
+
+// rpcNotificationSchemas describes the websocket notifications sent by the
+// server by name.  They have no results.
+var rpcNotificationSchemas = map[string]*btcjson.MethodSchema{
+	"alert": {
+		Method:       "alert",
+		Synopsis:     "Notifies all websocket clients of an alert broadcast by the operator of the server with broadcastalert.",
+		Usage:        `alert "message" "severity" time`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "message", Description: "The message of the alert", TypeSchema: schemaString},
+			{Name: "severity", Description: "The severity of the alert: info, warning or critical", TypeSchema: schemaString},
+			{Name: "time", Description: "The time the alert was broadcast in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+		},
+	},
+	"blockconnected": {
+		Method:       "blockconnected",
+		Synopsis:     "Notifies when a block has been added to the main chain.",
+		Usage:        `blockconnected "hash" height time`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "Hex-encoded bytes of the attached block hash", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the attached block", TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Unix time of the attached block", TypeSchema: schemaNumeric},
+		},
+	},
+	"blockdisconnected": {
+		Method:       "blockdisconnected",
+		Synopsis:     "Notifies when a block has been removed from the main chain.",
+		Usage:        `blockdisconnected "hash" height time`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "Hex-encoded bytes of the disconnected block hash", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the disconnected block", TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Unix time of the disconnected block", TypeSchema: schemaNumeric},
+		},
+	},
+	"blockheaderconnected": {
+		Method:       "blockheaderconnected",
+		Synopsis:     "Notifies when a block header has been added to the main chain.",
+		Usage:        `blockheaderconnected "hash" height "header"`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "Hex-encoded bytes of the attached block hash", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the attached block", TypeSchema: schemaNumeric},
+			{Name: "header", Description: "Hex-encoded serialized block header", TypeSchema: schemaString},
+		},
+	},
+	"blockheaderdisconnected": {
+		Method:       "blockheaderdisconnected",
+		Synopsis:     "Notifies when a block header has been removed from the main chain.",
+		Usage:        `blockheaderdisconnected "hash" height "header"`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "Hex-encoded bytes of the disconnected block hash", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the disconnected block", TypeSchema: schemaNumeric},
+			{Name: "header", Description: "Hex-encoded serialized block header", TypeSchema: schemaString},
+		},
+	},
+	"blocktemplate": {
+		Method:       "blocktemplate",
+		Synopsis:     "Notifies a client registered with notifyblocktemplate of a fresh block template to work on.",
+		Usage:        `blocktemplate {"bits":"value","curtime":n,"height":n,"previoushash":"value","sigoplimit":n,"sizelimit":n,"transactions":[{"data":"value","txid":"value","hash":"value","depends":[depend,...],"fee":n,"sigops":n,"weight":n},...],"version":n,"coinbaseaux":coinbaseaux,"coinbasetxn":coinbasetxn,"coinbasevalue":coinbasevalue,"workid":"value","longpollid":"value","longpolluri":"value","submitold":submitold,"target":"value","expires":n,"maxtime":n,"mintime":n,"mutable":["mutable",...],"noncerange":"value","capabilities":["capability",...],"rejectreasion":"value","rules":["rul",...],"vbavailable":vbavailable,"vbrequired":n,"weightlimit":n,"defaultwitnesscommitment":"value","defaultutxocommitment":"value","auditsource":auditsource}`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "template", Description: "Block template in the same format as the result of getblocktemplate", TypeSchema: schemaGetBlockTemplateResult},
+		},
+	},
+	"chainreorg": {
+		Method:       "chainreorg",
+		Synopsis:     "Notifies once per reorganization of the main chain, after the blockdisconnected and blockconnected notifications of the reorganization.",
+		Usage:        `chainreorg "forkhash" forkheight ["detached",...] ["attached",...] depth`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "forkhash", Description: "Hex-encoded bytes of the hash of the common ancestor of the old and new main chain", TypeSchema: schemaString},
+			{Name: "forkheight", Description: "Height of the common ancestor of the old and new main chain", TypeSchema: schemaNumeric},
+			{Name: "detached", Description: "Hashes of the disconnected blocks in the order they were disconnected, starting with the old best block", TypeSchema: schemaArray(schemaString)},
+			{Name: "attached", Description: "Hashes of the connected blocks in the order they were connected, ending with the new best block", TypeSchema: schemaArray(schemaString)},
+			{Name: "depth", Description: "Number of disconnected blocks", TypeSchema: schemaNumeric},
+		},
+	},
+	"clockskew": {
+		Method:       "clockskew",
+		Synopsis:     "Notifies when the local clock starts differing from the median time of peers by more than the configured maximum, and again once it is back within the maximum.",
+		Usage:        "clockskew offset skewed",
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "offset", Description: "Seconds the median time of peers is ahead of the local clock (negative when behind)", TypeSchema: schemaNumeric},
+			{Name: "skewed", Description: "Whether the offset is larger than the configured maximum", TypeSchema: schemaBool},
+		},
+	},
+	"doublespendseen": {
+		Method:       "doublespendseen",
+		Synopsis:     "Notifies when an otherwise valid transaction has been rejected from the mempool because it spends outputs already spent by a mempool transaction.",
+		Usage:        `doublespendseen "txid" "conflicttxid" [{"hash":"value","index":n},...]`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "Hash of the rejected double spending transaction", TypeSchema: schemaString},
+			{Name: "conflicttxid", Description: "Hash of the mempool transaction it conflicts with", TypeSchema: schemaString},
+			{Name: "inputs", Description: "Outpoints spent by both transactions", TypeSchema: schemaArray(schemaOutPoint)},
+		},
+	},
+	"filteredrecvtx": {
+		Method:       "filteredrecvtx",
+		Synopsis:     "Same as recvtx, but sent for an output paying to an address of a loaded transaction filter and tagged with the id of the filter.",
+		Usage:        `filteredrecvtx "filterid" "hextx" ({"height":n,"hash":"value","index":n,"time":n})`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "filterid", Description: "Identifier of the matching transaction filter", TypeSchema: schemaString},
+			{Name: "hextx", Description: "Full transaction encoded as a hex string", TypeSchema: schemaString},
+			{Name: "block", Description: "Details about the block containing the transaction, omitted for mempool transactions", Optional: true, TypeSchema: schemaBlockDetails},
+		},
+	},
+	"filteredredeemingtx": {
+		Method:       "filteredredeemingtx",
+		Synopsis:     "Same as redeemingtx, but sent for a transaction spending an outpoint of a loaded transaction filter and tagged with the id of the filter.",
+		Usage:        `filteredredeemingtx "filterid" "hextx" ({"height":n,"hash":"value","index":n,"time":n})`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "filterid", Description: "Identifier of the matching transaction filter", TypeSchema: schemaString},
+			{Name: "hextx", Description: "Full transaction encoded as a hex string", TypeSchema: schemaString},
+			{Name: "block", Description: "Details about the block containing the transaction, omitted for mempool transactions", Optional: true, TypeSchema: schemaBlockDetails},
+		},
+	},
+	"filteredrescanfinished": {
+		Method:       "filteredrescanfinished",
+		Synopsis:     "Notifies a client that the rescanfilter of the given filter, or the rescan job with the given id, has completed and no further rescan notifications for it will be sent.",
+		Usage:        `filteredrescanfinished "filterid" "hash" height time`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "filterid", Description: "Identifier of the rescanned transaction filter or rescan job", TypeSchema: schemaString},
+			{Name: "hash", Description: "Hash of the last rescanned block", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the last rescanned block", TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Unix time of the last rescanned block", TypeSchema: schemaNumeric},
+		},
+	},
+	"filteredrescanprogress": {
+		Method:       "filteredrescanprogress",
+		Synopsis:     "Notifies a client with the current progress at periodic intervals when a long-running rescanfilter or rescan job started with startrescan is underway.",
+		Usage:        `filteredrescanprogress "filterid" "hash" height time`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "filterid", Description: "Identifier of the rescanned transaction filter or rescan job", TypeSchema: schemaString},
+			{Name: "hash", Description: "Hash of the last processed block", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the last processed block", TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Unix time of the last processed block", TypeSchema: schemaNumeric},
+		},
+	},
+	"mempooltxadded": {
+		Method:       "mempooltxadded",
+		Synopsis:     "Notifies a client registered with subscribemempool that a transaction has been added to the mempool, including transactions returned to the mempool when a block is disconnected.",
+		Usage:        `mempooltxadded "txid" vsize fee time`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "Hex-encoded bytes of the transaction hash", TypeSchema: schemaString},
+			{Name: "vsize", Description: "The virtual size of the transaction", TypeSchema: schemaNumeric},
+			{Name: "fee", Description: "The fee paid by the transaction in BTC", TypeSchema: schemaDecimal},
+			{Name: "time", Description: "The time the transaction was added to the mempool in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+		},
+	},
+	"mempooltxremoved": {
+		Method:       "mempooltxremoved",
+		Synopsis:     "Notifies a client registered with subscribemempool that a transaction has been removed from the mempool.",
+		Usage:        `mempooltxremoved "txid" "reason"`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "Hex-encoded bytes of the transaction hash", TypeSchema: schemaString},
+			{Name: "reason", Description: "Why the transaction was removed: 'block' when it was mined, 'conflict' when it or a transaction it depends on conflicted with a mined transaction, 'expiry' when it or a transaction it depends on was not mined before the configured expiry, or 'eviction' for any other reason", TypeSchema: schemaString},
+		},
+	},
+	"peerconnected": {
+		Method:       "peerconnected",
+		Synopsis:     "Notifies when a peer has completed the version handshake and was added to the connected peers.",
+		Usage:        `peerconnected {"id":n,"addr":"value","inbound":inbound,"persistent":persistent,"services":"value","version":n,"subver":"value","startingheight":n,"currentheight":n,"conntime":n,"bytessent":n,"bytesrecv":n}`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "peer", Description: "Details of the peer", TypeSchema: schemaPeerDetails},
+		},
+	},
+	"peerdisconnected": {
+		Method:       "peerdisconnected",
+		Synopsis:     "Notifies when a connected peer has disconnected.",
+		Usage:        `peerdisconnected {"id":n,"addr":"value","inbound":inbound,"persistent":persistent,"services":"value","version":n,"subver":"value","startingheight":n,"currentheight":n,"conntime":n,"bytessent":n,"bytesrecv":n}`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "peer", Description: "Details of the peer at the time it disconnected", TypeSchema: schemaPeerDetails},
+		},
+	},
+	"recvtx": {
+		Method:       "recvtx",
+		Synopsis:     "Notifies when a transaction with an output paying to an address registered with notifyreceived is accepted to the mempool or mined into a block.",
+		Usage:        `recvtx "hextx" ({"height":n,"hash":"value","index":n,"time":n})`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "hextx", Description: "Full transaction encoded as a hex string", TypeSchema: schemaString},
+			{Name: "block", Description: "Details about the block containing the transaction, omitted for mempool transactions", Optional: true, TypeSchema: schemaBlockDetails},
+		},
+	},
+	"redeemingtx": {
+		Method:       "redeemingtx",
+		Synopsis:     "Notifies when a transaction spending an outpoint registered with notifyspent is accepted to the mempool or mined into a block.",
+		Usage:        `redeemingtx "hextx" ({"height":n,"hash":"value","index":n,"time":n})`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "hextx", Description: "Full transaction encoded as a hex string", TypeSchema: schemaString},
+			{Name: "block", Description: "Details about the block containing the transaction, omitted for mempool transactions", Optional: true, TypeSchema: schemaBlockDetails},
+		},
+	},
+	"replayedevent": {
+		Method:       "replayedevent",
+		Synopsis:     "Notifies a client of an event recorded in the event journal during a replayevents call.",
+		Usage:        `replayedevent {"sequence":n,"event":"value","hash":"value","height":n,"time":n,"amount":n.nnn}`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "event", Description: "The journaled event", TypeSchema: schemaJournalEvent},
+		},
+	},
+	"replayfinished": {
+		Method:       "replayfinished",
+		Synopsis:     "Notifies a client that the replayevents call has sent all journaled events.",
+		Usage:        "replayfinished sequence",
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "sequence", Description: "Journal sequence number of the most recent event", TypeSchema: schemaNumeric},
+		},
+	},
+	"rescanfinished": {
+		Method:       "rescanfinished",
+		Synopsis:     "Notifies a client that the rescan has completed and no further notifications will be sent.",
+		Usage:        `rescanfinished "hash" height time`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "Hash of the last rescanned block", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the last rescanned block", TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Unix time of the last rescanned block", TypeSchema: schemaNumeric},
+		},
+	},
+	"rescanprogress": {
+		Method:       "rescanprogress",
+		Synopsis:     "Notifies a client with the current progress at periodic intervals when a long-running rescan is underway.",
+		Usage:        `rescanprogress "hash" height time`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "hash", Description: "Hash of the last processed block", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the last processed block", TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Unix time of the last processed block", TypeSchema: schemaNumeric},
+		},
+	},
+	"scripthashtx": {
+		Method:       "scripthashtx",
+		Synopsis:     "Notifies when a transaction paying to or spending from an output script with a script hash registered with subscribescripthash is accepted to the mempool or mined into a block.\nA transaction touching several registered scripts is notified once for each of them.",
+		Usage:        `scripthashtx "scripthash" "hextx" ({"height":n,"hash":"value","index":n,"time":n})`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "scripthash", Description: "Hex-encoded script hash of the touched output script", TypeSchema: schemaString},
+			{Name: "hextx", Description: "Full transaction encoded as a hex string", TypeSchema: schemaString},
+			{Name: "block", Description: "Details about the block containing the transaction, omitted for mempool transactions", Optional: true, TypeSchema: schemaBlockDetails},
+		},
+	},
+	"shutdown": {
+		Method:       "shutdown",
+		Synopsis:     "Notifies all websocket clients that the server is shutting down.",
+		Usage:        "shutdown graceperiod",
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "graceperiod", Description: "Number of seconds until the server disconnects all websocket clients", TypeSchema: schemaNumeric},
+		},
+	},
+	"syncpeerchanged": {
+		Method:       "syncpeerchanged",
+		Synopsis:     "Notifies when the peer the block chain is synced from changes.",
+		Usage:        `syncpeerchanged ({"id":n,"addr":"value","inbound":inbound,"persistent":persistent,"services":"value","version":n,"subver":"value","startingheight":n,"currentheight":n,"conntime":n,"bytessent":n,"bytesrecv":n})`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "peer", Description: "Details of the new sync peer, omitted when there is no peer to sync from", Optional: true, TypeSchema: schemaPeerDetails},
+		},
+	},
+	"txaccepted": {
+		Method:       "txaccepted",
+		Synopsis:     "Notifies when a new transaction has been accepted to the mempool and the client has requested standard transaction details.",
+		Usage:        `txaccepted "txid" amount`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "Hex-encoded bytes of the transaction hash", TypeSchema: schemaString},
+			{Name: "amount", Description: "Sum of the value of all the transaction outputs", TypeSchema: schemaDecimal},
+		},
+	},
+	"txacceptedverbose": {
+		Method:       "txacceptedverbose",
+		Synopsis:     "Notifies when a new transaction has been accepted to the mempool and the client has requested verbose transaction details.",
+		Usage:        `txacceptedverbose {"hex":"value","txid":"value","hash":"value","size":n,"vsize":n,"version":n,"locktime":n,"vin":[{"coinbase":"value","txid":"value","vout":n,"scriptsig":scriptsig,"witness":["witnes",...],"sequence":n},...],"vout":[{"value":n.nnn,"n":n,"scriptpubkey":{"asm":"value","hex":"value","reqsigs":n,"type":"value","addresses":["address",...]}},...],"blockhash":"value","confirmations":n,"time":n,"blocktime":n}`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "rawtx", Description: "The transaction in the same format as the verbose result of getrawtransaction", TypeSchema: schemaTxRawResult},
+		},
+	},
+	"txconfirmed": {
+		Method:       "txconfirmed",
+		Synopsis:     "Notifies a client registered with notifyconfirmations that a transaction has reached the requested number of confirmations.",
+		Usage:        `txconfirmed "txid" confirmations "blockhash" blockheight`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "Hex-encoded bytes of the transaction hash", TypeSchema: schemaString},
+			{Name: "confirmations", Description: "The number of confirmations of the transaction", TypeSchema: schemaNumeric},
+			{Name: "blockhash", Description: "The hash of the block containing the transaction", TypeSchema: schemaString},
+			{Name: "blockheight", Description: "The height of the block containing the transaction", TypeSchema: schemaNumeric},
+		},
+	},
+	"txexpired": {
+		Method:       "txexpired",
+		Synopsis:     "Notifies when a transaction has been evicted from the mempool because it was not mined before the configured expiry.\nClients registered with notifynewtransactions, and clients watching an outpoint spent by or an address paid by the transaction, receive the notification.",
+		Usage:        `txexpired "txid"`,
+		Websocket:    true,
+		Notification: true,
+		Params: []btcjson.ParamSchema{
+			{Name: "txid", Description: "Hex-encoded bytes of the transaction hash", TypeSchema: schemaString},
+		},
+	},
+}
+
+// The following are the schemas of the objects with a fixed set of fields used
+// by the methods and notifications.
+var (
+	schemaAddressDeltaResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "addressdeltaresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "satoshis", Description: "The change to the balance in satoshi, which is negative for inputs", TypeSchema: schemaNumeric},
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "index", Description: "The index of the input or output", TypeSchema: schemaNumeric},
+			{Name: "blockindex", Description: "The position of the transaction within its block", TypeSchema: schemaNumeric},
+			{Name: "height", Description: "The height of the block containing the transaction", TypeSchema: schemaNumeric},
+			{Name: "address", Description: "The address whose balance changed", TypeSchema: schemaString},
+		},
+	}
+
+	schemaAddressDeltasRequest = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "addressdeltasrequest",
+		Fields: []btcjson.FieldSchema{
+			{Name: "addresses", Description: "The addresses to query", TypeSchema: schemaArray(schemaString)},
+			{Name: "start", Description: "The height of the first block to return the balance changes of (default: 0)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "end", Description: "The height of the last block to return the balance changes of (default: the best block)", Optional: true, TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaAddressRequest = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "addressrequest",
+		Fields: []btcjson.FieldSchema{
+			{Name: "addresses", Description: "The addresses to query", TypeSchema: schemaArray(schemaString)},
+		},
+	}
+
+	schemaAddressUtxoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "addressutxoresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "address", Description: "The address the output pays", TypeSchema: schemaString},
+			{Name: "txid", Description: "The hash of the transaction which created the output", TypeSchema: schemaString},
+			{Name: "outputIndex", Description: "The index of the output", TypeSchema: schemaNumeric},
+			{Name: "script", Description: "The hex-encoded public key script of the output", TypeSchema: schemaString},
+			{Name: "satoshis", Description: "The value of the output in satoshi", TypeSchema: schemaNumeric},
+			{Name: "height", Description: "The height of the block which created the output", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaBackupChainStateResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "backupchainstateresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "path", Description: "The full path of the copy", TypeSchema: schemaString},
+			{Name: "hash", Description: "The hash of the most recent block in the copy", TypeSchema: schemaString},
+			{Name: "height", Description: "The height of the most recent block in the copy", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaBip9SoftForkDescription = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "bip9softforkdescription",
+		Fields: []btcjson.FieldSchema{
+			{Name: "status", Description: "The state of the deployment (defined, started, lockedin, active, or failed)", TypeSchema: schemaString},
+			{Name: "bit", Description: "The block version bit used to signal the deployment", TypeSchema: schemaNumeric},
+			{Name: "startTime", Description: "The median block time after which voting on the deployment starts", TypeSchema: schemaNumeric},
+			{Name: "timeout", Description: "The median block time after which the deployment fails if it has not locked in", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaBlockDetails = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "blockdetails",
+		Fields: []btcjson.FieldSchema{
+			{Name: "height", Description: "Height of the block containing the transaction", TypeSchema: schemaNumeric},
+			{Name: "hash", Description: "Hex-encoded bytes of the block hash", TypeSchema: schemaString},
+			{Name: "index", Description: "Index of the transaction within the block", TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Unix time of the block", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaBlockTemplatePolicy = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "blocktemplatepolicy",
+		Fields: []btcjson.FieldSchema{
+			{Name: "blockmaxsize", Description: "Maximum block size in bytes", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "blockminsize", Description: "Minimum block size in bytes, which is filled with transactions regardless of their fee rate", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "blockprioritysize", Description: "Size in bytes of the area for high-priority transactions regardless of their fee rate", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "minfeerate", Description: "Minimum fee rate in BTC/kB of transactions included beyond the minimum block size and the high-priority area", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "minpriority", Description: "Minimum priority of transactions included in the high-priority area", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "recenttxage", Description: "Age in seconds below which transactions are weighted by the recent transaction weight (0 disables the weighting)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "recenttxweight", Description: "Factor greater than 0 and at most 1 the fee rate of recent transactions is multiplied by when ordering transactions by fee", Optional: true, TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaBlockTemplatePolicyResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "blocktemplatepolicyresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "blockmaxsize", Description: "Maximum block size in bytes", TypeSchema: schemaNumeric},
+			{Name: "blockminsize", Description: "Minimum block size in bytes", TypeSchema: schemaNumeric},
+			{Name: "blockprioritysize", Description: "Size in bytes of the area for high-priority transactions", TypeSchema: schemaNumeric},
+			{Name: "minfeerate", Description: "Minimum fee rate in BTC/kB of transactions included beyond the minimum block size and the high-priority area", TypeSchema: schemaDecimal},
+			{Name: "minpriority", Description: "Minimum priority of transactions included in the high-priority area", TypeSchema: schemaDecimal},
+			{Name: "recenttxage", Description: "Age in seconds below which transactions are considered recent", TypeSchema: schemaNumeric},
+			{Name: "recenttxweight", Description: "Factor the fee rate of recent transactions is multiplied by when ordering transactions by fee", TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaBroadcastAlertResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "broadcastalertresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "clients", Description: "The number of websocket clients the alert is sent to", TypeSchema: schemaNumeric},
+			{Name: "webhooks", Description: "The number of webhooks the alert is posted to", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaCacheLookupsResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "cachelookupsresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hits", Description: "The number of lookups served from the cache", TypeSchema: schemaNumeric},
+			{Name: "misses", Description: "The number of lookups which had to go to disk", TypeSchema: schemaNumeric},
+			{Name: "hitrate", Description: "The fraction of lookups served from the cache or 0 when there haven't been any", TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaChainStateResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "chainstateresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "blocks", Description: "The height of the latest block of the chain state", TypeSchema: schemaNumeric},
+			{Name: "bestblockhash", Description: "The hash of the latest block of the chain state", TypeSchema: schemaString},
+			{Name: "snapshot_blockhash", Description: "The hash of the block the UTXO snapshot was taken at (only for the chain state built on a snapshot)", Optional: true, TypeSchema: schemaString},
+			{Name: "validated", Description: "Whether the blocks of the chain state are fully validated, which is the case for a snapshot once it matches the main chain", TypeSchema: schemaBool},
+		},
+	}
+
+	schemaClientSubscriptionsResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "clientsubscriptionsresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "addr", Description: "The remote address of the client", TypeSchema: schemaString},
+			{Name: "sessionid", Description: "The session ID of the client", TypeSchema: schemaNumeric},
+			{Name: "notifications", Description: "The notifications the client is registered for which are not tied to addresses or outpoints (blocks, blockheaders, blocktemplate, rawblocks, clockskew, peerevents and newtransactions)", TypeSchema: schemaArray(schemaString)},
+			{Name: "addresses", Description: "The number of addresses registered with notifyreceived", TypeSchema: schemaNumeric},
+			{Name: "outpoints", Description: "The number of outpoints registered with notifyspent", TypeSchema: schemaNumeric},
+			{Name: "scripthashes", Description: "The number of script hashes registered with subscribescripthash", TypeSchema: schemaNumeric},
+			{Name: "filters", Description: "The number of transaction filters loaded with loadtxfilter", TypeSchema: schemaNumeric},
+			{Name: "filterentries", Description: "The total number of addresses and outpoints watched by the transaction filters", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaConfirmationsSubscription = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "confirmationssubscription",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "confirmations", Description: "The number of confirmations the notification is waiting for", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaCreateMultiSigResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "createmultisigresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "address", Description: "The pay-to-script-hash address", TypeSchema: schemaString},
+			{Name: "redeemScript", Description: "The hex-encoded redeem script which must be provided to redeem outputs paid to the address", TypeSchema: schemaString},
+		},
+	}
+
+	schemaDebugScriptResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "debugscriptresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "valid", Description: "Whether or not the scripts executed successfully", TypeSchema: schemaBool},
+			{Name: "error", Description: "The reason the scripts failed (only when valid is false)", Optional: true, TypeSchema: schemaString},
+			{Name: "trace", Description: "The state of the script engine after each executed opcode", TypeSchema: schemaArray(schemaDebugScriptStep)},
+		},
+	}
+
+	schemaDebugScriptStep = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "debugscriptstep",
+		Fields: []btcjson.FieldSchema{
+			{Name: "script", Description: "The index of the script the opcode belongs to (0 is the signature script, 1 the public key script, and 2 a redeem or witness script)", TypeSchema: schemaNumeric},
+			{Name: "offset", Description: "The index of the opcode within its script", TypeSchema: schemaNumeric},
+			{Name: "opcode", Description: "The disassembled opcode", TypeSchema: schemaString},
+			{Name: "stack", Description: "The hex-encoded data stack items after the opcode was executed with the top item last", TypeSchema: schemaArray(schemaString)},
+			{Name: "altstack", Description: "The hex-encoded alternate stack items after the opcode was executed with the top item last", TypeSchema: schemaArray(schemaString)},
+		},
+	}
+
+	schemaDecodePaymentURIResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "decodepaymenturiresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "address", Description: "The address to pay", TypeSchema: schemaString},
+			{Name: "amount", Description: "The requested amount in bitcoins (only when an amount is requested)", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "label", Description: "The name of the recipient (only when given)", Optional: true, TypeSchema: schemaString},
+			{Name: "message", Description: "The description of the payment (only when given)", Optional: true, TypeSchema: schemaString},
+			{Name: "params", Description: "The parameters which are not defined by BIP0021 (only when given)", Optional: true, TypeSchema: schemaMap(schemaString, "name", "value", "The parameter name as the key and its value as the value")},
+		},
+	}
+
+	schemaDecodeScriptResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "decodescriptresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "asm", Description: "Disassembly of the script", TypeSchema: schemaString},
+			{Name: "reqSigs", Description: "The number of required signatures", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "type", Description: "The type of the script (e.g. 'pubkeyhash')", TypeSchema: schemaString},
+			{Name: "addresses", Description: "The bitcoin addresses associated with this script", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "p2sh", Description: "The script hash for use in pay-to-script-hash transactions", TypeSchema: schemaString},
+		},
+	}
+
+	schemaDumpUtxoSetResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "dumputxosetresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "coins_written", Description: "The number of unspent outputs written", TypeSchema: schemaNumeric},
+			{Name: "base_hash", Description: "The hash of the block the snapshot was taken at", TypeSchema: schemaString},
+			{Name: "base_height", Description: "The height of the block the snapshot was taken at", TypeSchema: schemaNumeric},
+			{Name: "path", Description: "The full path of the written snapshot file", TypeSchema: schemaString},
+			{Name: "txoutset_hash", Description: "The hash of the unspent transaction output set", TypeSchema: schemaString},
+		},
+	}
+
+	schemaEntrySchema = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "entryschema",
+		Fields: []btcjson.FieldSchema{
+			{Name: "key", Description: "The name of the keys", TypeSchema: schemaString},
+			{Name: "value", Description: "The name of the values", TypeSchema: schemaString},
+			{Name: "description", Description: "The description of the entries", TypeSchema: schemaString},
+		},
+	}
+
+	schemaEvictedTransactionResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "evictedtransactionresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "hex", Description: "The serialized, hex-encoded transaction", TypeSchema: schemaString},
+			{Name: "added", Description: "The time the transaction was added to the memory pool in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "evicted", Description: "The time the transaction was evicted from the memory pool in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaFieldSchema = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "fieldschema",
+		Fields: []btcjson.FieldSchema{
+			{Name: "name", Description: "The name of the object field", TypeSchema: schemaString},
+			{Name: "description", Description: "The description of the object field", TypeSchema: schemaString},
+			{Name: "optional", Description: "Whether the field may be omitted", Optional: true, TypeSchema: schemaBool},
+			{Name: "type", Description: "The JSON type (numeric, string, boolean, array, object, value or null)", TypeSchema: schemaString},
+			{Name: "decimal", Description: "Whether a number may have a fractional part", Optional: true, TypeSchema: schemaBool},
+			{Name: "items", Description: "The type of the elements of an array", Optional: true, TypeSchema: schemaTypeSchema},
+			{Name: "typename", Description: "The name of an object with a fixed set of fields", Optional: true, TypeSchema: schemaString},
+			{Name: "fields", Description: "The fields of an object with a fixed set of fields", Optional: true, TypeSchema: schemaArray(schemaObject)},
+			{Name: "values", Description: "The type of the values of an object with arbitrary keys", Optional: true, TypeSchema: schemaTypeSchema},
+			{Name: "entries", Description: "The names of the keys and values of an object with arbitrary keys", Optional: true, TypeSchema: schemaEntrySchema},
+		},
+	}
+
+	schemaFindOrphanChainsResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "findorphanchainsresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "rootprevhash", Description: "The hash of the unknown block the orphans descend from", TypeSchema: schemaString},
+			{Name: "length", Description: "The number of blocks in the longest chain of orphans", TypeSchema: schemaNumeric},
+			{Name: "blocks", Description: "The total number of orphans which descend from the unknown block", TypeSchema: schemaNumeric},
+			{Name: "size", Description: "The total serialized size of the orphans in bytes", TypeSchema: schemaNumeric},
+			{Name: "tiphash", Description: "The hash of the last block in the longest chain of orphans", TypeSchema: schemaString},
+		},
+	}
+
+	schemaFundRawTransactionOpts = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "fundrawtransactionopts",
+		Fields: []btcjson.FieldSchema{
+			{Name: "changeAddress", Description: "The address to send any change to", TypeSchema: schemaString},
+			{Name: "feeRate", Description: "The fee rate to pay in BTC/kB (defaults to the minimum relay fee rate)", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "utxos", Description: "The unspent outputs which may be used to fund the transaction", Optional: true, TypeSchema: schemaArray(schemaFundRawTransactionUtxo)},
+			{Name: "addresses", Description: "Addresses whose unspent outputs may be used to fund the transaction when no utxos are provided (requires --addrindex)", Optional: true, TypeSchema: schemaArray(schemaString)},
+		},
+	}
+
+	schemaFundRawTransactionResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "fundrawtransactionresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hex", Description: "The hex-encoded funded transaction", TypeSchema: schemaString},
+			{Name: "fee", Description: "The fee paid by the transaction in BTC", TypeSchema: schemaDecimal},
+			{Name: "changepos", Description: "The index of the added change output, or -1 if no change output was added", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaFundRawTransactionUtxo = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "fundrawtransactionutxo",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the transaction which contains the output", TypeSchema: schemaString},
+			{Name: "vout", Description: "The index of the output", TypeSchema: schemaNumeric},
+			{Name: "amount", Description: "The value of the output in BTC", TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaGetAPISchemaResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getapischemaresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "methods", Description: "The schemas of the RPC and websocket methods", TypeSchema: schemaMap(schemaMethodSchema, "method", "schema", "The schema of the method")},
+			{Name: "notifications", Description: "The schemas of the websocket notifications, which only have parameters", TypeSchema: schemaMap(schemaMethodSchema, "notification", "schema", "The schema of the notification")},
+		},
+	}
+
+	schemaGetAddedNodeInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getaddednodeinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "addednode", Description: "The ip address or domain of the added peer", TypeSchema: schemaString},
+			{Name: "connected", Description: "Whether or not the peer is currently connected", Optional: true, TypeSchema: schemaBool},
+			{Name: "addresses", Description: "DNS lookup and connection information about the peer", Optional: true, TypeSchema: schemaArray(schemaGetAddedNodeInfoResultAddr)},
+		},
+	}
+
+	schemaGetAddedNodeInfoResultAddr = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getaddednodeinforesultaddr",
+		Fields: []btcjson.FieldSchema{
+			{Name: "address", Description: "The ip address for this DNS entry", TypeSchema: schemaString},
+			{Name: "connected", Description: "The connection 'direction' (inbound/outbound/false)", TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetAddressBalanceResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getaddressbalanceresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "balance", Description: "The total confirmed balance of the addresses in satoshi", TypeSchema: schemaNumeric},
+			{Name: "received", Description: "The total value in satoshi of the confirmed outputs paying the addresses", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaGetBestBlockResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getbestblockresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hash", Description: "Hex-encoded bytes of the best block hash", TypeSchema: schemaString},
+			{Name: "height", Description: "Height of the best block", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaGetBlockChainInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblockchaininforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "chain", Description: "The name of the network the server is running on", TypeSchema: schemaString},
+			{Name: "blocks", Description: "The height of the best block in the main chain", TypeSchema: schemaNumeric},
+			{Name: "headers", Description: "The height of the best known block header", TypeSchema: schemaNumeric},
+			{Name: "bestblockhash", Description: "The hash of the best block in the main chain", TypeSchema: schemaString},
+			{Name: "difficulty", Description: "The proof-of-work difficulty of the best block as a multiple of the minimum difficulty", TypeSchema: schemaDecimal},
+			{Name: "mediantime", Description: "The median block time of the previous 11 blocks, including the best block, in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "verificationprogress", Description: "An estimate of the fraction of the block chain which has been downloaded and verified", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "initialblockdownload", Description: "Whether or not the server is still performing the initial download of the block chain", TypeSchema: schemaBool},
+			{Name: "chainwork", Description: "The total number of hashes expected to produce the main chain, in hex", TypeSchema: schemaString},
+			{Name: "timeoffset", Description: "The number of seconds the local clock is adjusted by based on the median time of peers", TypeSchema: schemaNumeric},
+			{Name: "warnings", Description: "Any warnings about the state of the server, such as a wrong local clock", TypeSchema: schemaString},
+			{Name: "bip9_softforks", Description: "The state of each rule change deployment defined by BIP0009 for the next block", TypeSchema: schemaMap(schemaBip9SoftForkDescription, "name", "{status, bit, startTime, timeout}", "The deployment name as the key and its state as the value")},
+		},
+	}
+
+	schemaGetBlockFilterResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblockfilterresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "filter", Description: "The hex-encoded serialized filter", TypeSchema: schemaString},
+			{Name: "header", Description: "The filter header which commits to the filter and the header of the previous block's filter", TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetBlockHeaderVerboseResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblockheaderverboseresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hash", Description: "The hash of the block (same as provided)", TypeSchema: schemaString},
+			{Name: "confirmations", Description: "The number of confirmations", TypeSchema: schemaNumeric},
+			{Name: "height", Description: "The height of the block in the block chain", TypeSchema: schemaNumeric},
+			{Name: "version", Description: "The block version", TypeSchema: schemaNumeric},
+			{Name: "merkleroot", Description: "Root hash of the merkle tree", TypeSchema: schemaString},
+			{Name: "time", Description: "The block time in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "mediantime", Description: "The median block time of the previous 11 blocks, including this one, in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "nonce", Description: "The block nonce", TypeSchema: schemaNumeric},
+			{Name: "bits", Description: "The bits which represent the block difficulty", TypeSchema: schemaString},
+			{Name: "difficulty", Description: "The proof-of-work difficulty as a multiple of the minimum difficulty", TypeSchema: schemaDecimal},
+			{Name: "chainwork", Description: "The hex-encoded total amount of work in the main chain up to and including this block", TypeSchema: schemaString},
+			{Name: "previousblockhash", Description: "The hash of the previous block", Optional: true, TypeSchema: schemaString},
+			{Name: "nextblockhash", Description: "The hash of the next block (only if there is one)", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetBlockStatsResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblockstatsresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hash", Description: "The hash of the block", TypeSchema: schemaString},
+			{Name: "height", Description: "The height of the block", TypeSchema: schemaNumeric},
+			{Name: "time", Description: "The block time in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "txs", Description: "The number of transactions including the coinbase", TypeSchema: schemaNumeric},
+			{Name: "ins", Description: "The number of inputs excluding the coinbase", TypeSchema: schemaNumeric},
+			{Name: "outs", Description: "The number of outputs including the coinbase", TypeSchema: schemaNumeric},
+			{Name: "total_size", Description: "The total serialized size of the transactions excluding the coinbase", TypeSchema: schemaNumeric},
+			{Name: "total_out", Description: "The total value in satoshi of the outputs excluding the coinbase", TypeSchema: schemaNumeric},
+			{Name: "totalfee", Description: "The total fee in satoshi paid by the transactions", TypeSchema: schemaNumeric},
+			{Name: "avgfee", Description: "The average fee in satoshi paid by the transactions excluding the coinbase", TypeSchema: schemaNumeric},
+			{Name: "subsidy", Description: "The block subsidy in satoshi", TypeSchema: schemaNumeric},
+			{Name: "utxo_increase", Description: "The number of outputs created less the number of outputs spent", TypeSchema: schemaNumeric},
+			{Name: "coindaysdestroyed", Description: "The sum of the value in coins of each spent output multiplied by its age in days (only with --coinageindex)", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "avgcoinage", Description: "The average age in days of the spent outputs weighted by their value (only with --coinageindex)", Optional: true, TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaGetBlockTemplateResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblocktemplateresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "bits", Description: "Hex-encoded compressed difficulty", TypeSchema: schemaString},
+			{Name: "curtime", Description: "Current time as seen by the server (recommended for block time); must fall within mintime/maxtime rules", TypeSchema: schemaNumeric},
+			{Name: "height", Description: "Height of the block to be solved", TypeSchema: schemaNumeric},
+			{Name: "previousblockhash", Description: "Hex-encoded big-endian hash of the previous block", TypeSchema: schemaString},
+			{Name: "sigoplimit", Description: "Number of sigops allowed in blocks ", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "sizelimit", Description: "Number of bytes allowed in blocks", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "transactions", Description: "Array of transactions as JSON objects", TypeSchema: schemaArray(schemaGetBlockTemplateResultTx)},
+			{Name: "version", Description: "The block version", TypeSchema: schemaNumeric},
+			{Name: "coinbaseaux", Description: "Data that should be included in the coinbase signature script", Optional: true, TypeSchema: schemaGetBlockTemplateResultAux},
+			{Name: "coinbasetxn", Description: "Information about the coinbase transaction", Optional: true, TypeSchema: schemaGetBlockTemplateResultTx},
+			{Name: "coinbasevalue", Description: "Total amount available for the coinbase in Satoshi", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "workid", Description: "This value must be returned with result if provided (not provided)", Optional: true, TypeSchema: schemaString},
+			{Name: "longpollid", Description: "Identifier for long poll request which allows monitoring for expiration", Optional: true, TypeSchema: schemaString},
+			{Name: "longpolluri", Description: "An alternate URI to use for long poll requests if provided (not provided)", Optional: true, TypeSchema: schemaString},
+			{Name: "submitold", Description: "Not applicable", Optional: true, TypeSchema: schemaBool},
+			{Name: "target", Description: "Hex-encoded big-endian number which valid results must be less than", Optional: true, TypeSchema: schemaString},
+			{Name: "expires", Description: "Maximum number of seconds (starting from when the server sent the response) this work is valid for", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "maxtime", Description: "Maximum allowed time", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "mintime", Description: "Minimum allowed time", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "mutable", Description: "List of mutations the server explicitly allows", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "noncerange", Description: "Two concatenated hex-encoded big-endian 32-bit integers which represent the valid ranges of nonces the miner may scan", Optional: true, TypeSchema: schemaString},
+			{Name: "capabilities", Description: "List of server capabilities including 'proposal' to indicate support for block proposals and 'auditsource' to indicate support for the transaction selection audit trail", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "reject-reason", Description: "Reason the proposal was invalid as-is (only applies to proposal responses)", Optional: true, TypeSchema: schemaString},
+			{Name: "rules", Description: "The rule change deployments which are active for the block", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "vbavailable", Description: "The rule change deployments which may be signaled by setting their bit in the block version", Optional: true, TypeSchema: schemaMap(schemaNumeric, "name", "bit", "The deployment name as the key and its bit number as the value")},
+			{Name: "vbrequired", Description: "The bits which must be set in the block version (always 0)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "weightlimit", Description: "Maximum weight allowed in blocks", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "default_witness_commitment", Description: "Hex-encoded public key script of the coinbase output which commits to the witness data (only once segwit is active)", Optional: true, TypeSchema: schemaString},
+			{Name: "default_utxo_commitment", Description: "Hex-encoded public key script of the coinbase output which commits to the unspent transaction outputs (only once utxocommitment is active)", Optional: true, TypeSchema: schemaString},
+			{Name: "auditsource", Description: "Audit trail of the transaction selection (only with the 'auditsource' capability; btcd extension)", Optional: true, TypeSchema: schemaGetBlockTemplateResultAudit},
+		},
+	}
+
+	schemaGetBlockTemplateResultAudit = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblocktemplateresultaudit",
+		Fields: []btcjson.FieldSchema{
+			{Name: "transactions", Description: "Why each transaction of the template was selected, in the order of the 'transactions' list", TypeSchema: schemaArray(schemaGetBlockTemplateResultAuditTx)},
+			{Name: "exclusions", Description: "The first transactions which were excluded by the policy or the block limits, in the order they were considered", TypeSchema: schemaArray(schemaGetBlockTemplateResultExclusion)},
+		},
+	}
+
+	schemaGetBlockTemplateResultAuditTx = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblocktemplateresultaudittx",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "Hex-encoded transaction hash excluding witness data", TypeSchema: schemaString},
+			{Name: "reason", Description: "Why the transaction was selected: 'priority' for the high-priority area, 'feerate' by its fee rate or 'minblocksize' to fill the minimum block size", TypeSchema: schemaString},
+			{Name: "rank", Description: "1-based position of the transaction among those selected for the same reason", TypeSchema: schemaNumeric},
+			{Name: "package", Description: "Whether the transaction depends on transactions selected before it", TypeSchema: schemaBool},
+			{Name: "feerate", Description: "The fee rate of the transaction in BTC/kB", TypeSchema: schemaDecimal},
+			{Name: "priority", Description: "The priority of the transaction", TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaGetBlockTemplateResultAux = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblocktemplateresultaux",
+		Fields: []btcjson.FieldSchema{
+			{Name: "flags", Description: "Hex-encoded byte-for-byte data to include in the coinbase signature script", TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetBlockTemplateResultExclusion = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblocktemplateresultexclusion",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "Hex-encoded transaction hash excluding witness data", TypeSchema: schemaString},
+			{Name: "reason", Description: "Why the transaction was excluded: 'blocksize', 'blockweight' or 'sigops' when it would exceed the block limits, or 'minfeerate' when it pays less than the minimum fee rate", TypeSchema: schemaString},
+			{Name: "feerate", Description: "The fee rate of the transaction in BTC/kB", TypeSchema: schemaDecimal},
+			{Name: "priority", Description: "The priority of the transaction", TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaGetBlockTemplateResultTx = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblocktemplateresulttx",
+		Fields: []btcjson.FieldSchema{
+			{Name: "data", Description: "Hex-encoded transaction data (byte-for-byte)", TypeSchema: schemaString},
+			{Name: "txid", Description: "Hex-encoded transaction hash excluding witness data (little endian if treated as a 256-bit number)", TypeSchema: schemaString},
+			{Name: "hash", Description: "Hex-encoded transaction hash including witness data (little endian if treated as a 256-bit number)", TypeSchema: schemaString},
+			{Name: "depends", Description: "Other transactions before this one (by 1-based index in the 'transactions'  list) that must be present in the final block if this one is", TypeSchema: schemaArray(schemaNumeric)},
+			{Name: "fee", Description: "Difference in value between transaction inputs and outputs (in Satoshi)", TypeSchema: schemaNumeric},
+			{Name: "sigops", Description: "Total number of signature operations as counted for purposes of block limits", TypeSchema: schemaNumeric},
+			{Name: "weight", Description: "The weight of the transaction as counted for purposes of block limits", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaGetBlockVerboseResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getblockverboseresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hash", Description: "The hash of the block (same as provided)", TypeSchema: schemaString},
+			{Name: "confirmations", Description: "The number of confirmations", TypeSchema: schemaNumeric},
+			{Name: "size", Description: "The size of the block", TypeSchema: schemaNumeric},
+			{Name: "height", Description: "The height of the block in the block chain", TypeSchema: schemaNumeric},
+			{Name: "version", Description: "The block version", TypeSchema: schemaNumeric},
+			{Name: "merkleroot", Description: "Root hash of the merkle tree", TypeSchema: schemaString},
+			{Name: "tx", Description: "The transaction hashes (only when verbosetx=false)", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "rawtx", Description: "The transactions as JSON objects (only when verbosetx=true)", Optional: true, TypeSchema: schemaArray(schemaTxRawResult)},
+			{Name: "time", Description: "The block time in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "mediantime", Description: "The median block time of the previous 11 blocks, including this one, in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "nonce", Description: "The block nonce", TypeSchema: schemaNumeric},
+			{Name: "bits", Description: "The bits which represent the block difficulty", TypeSchema: schemaString},
+			{Name: "difficulty", Description: "The proof-of-work difficulty as a multiple of the minimum difficulty", TypeSchema: schemaDecimal},
+			{Name: "previousblockhash", Description: "The hash of the previous block", TypeSchema: schemaString},
+			{Name: "nextblockhash", Description: "The hash of the next block (only if there is one)", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetChainStatesResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getchainstatesresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "headers", Description: "The height of the best known block header", TypeSchema: schemaNumeric},
+			{Name: "chainstates", Description: "The chain states, with the one which is furthest ahead last", TypeSchema: schemaArray(schemaChainStateResult)},
+		},
+	}
+
+	schemaGetCoinAgeInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getcoinageinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hash", Description: "The hash of the block", TypeSchema: schemaString},
+			{Name: "height", Description: "The height of the block", TypeSchema: schemaNumeric},
+			{Name: "spentvalue", Description: "The total value in coins of the outputs spent by the block", TypeSchema: schemaDecimal},
+			{Name: "coindaysdestroyed", Description: "The sum of the value in coins of each output spent by the block multiplied by its age in days", TypeSchema: schemaDecimal},
+			{Name: "avgcoinage", Description: "The average age in days of the outputs spent by the block weighted by their value", TypeSchema: schemaDecimal},
+			{Name: "totalcoindaysdestroyed", Description: "The coin days destroyed by every block of the main chain up to and including the block", TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaGetDBCacheStatsResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getdbcachestatsresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "entries", Description: "The number of blocks currently cached", TypeSchema: schemaNumeric},
+			{Name: "maxentries", Description: "The number of blocks at which the least recently used ones start to be evicted", TypeSchema: schemaNumeric},
+			{Name: "heightlookups", Description: "The lookups of the hash of the block at a height", TypeSchema: schemaCacheLookupsResult},
+			{Name: "hashlookups", Description: "The lookups of the height of a block by its hash", TypeSchema: schemaCacheLookupsResult},
+			{Name: "headerlookups", Description: "The lookups of a block header by its hash", TypeSchema: schemaCacheLookupsResult},
+		},
+	}
+
+	schemaGetDatabaseInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getdatabaseinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "type", Description: "The database backend", TypeSchema: schemaString},
+			{Name: "height", Description: "The height of the most recent block in the database", TypeSchema: schemaNumeric},
+			{Name: "size", Description: "The approximate number of bytes used on disk, which doesn't include data that was written recently", TypeSchema: schemaNumeric},
+			{Name: "sizes", Description: "The approximate number of bytes used on disk by each part of the database (only when the backend stores data on disk)", Optional: true, TypeSchema: schemaMap(schemaNumeric, "name", "n", "'chain' for the blocks and transactions, or the name of an optional index, and its size in bytes")},
+			{Name: "compacting", Description: "Whether the database is being compacted", TypeSchema: schemaBool},
+			{Name: "compactwindow", Description: "The daily time window in local time during which the database is compacted (only when configured)", Optional: true, TypeSchema: schemaString},
+			{Name: "lastcompactiontime", Description: "The time the last finished compaction started in seconds since 1 Jan 1970 GMT (only when the database was compacted since start up)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "lastcompactionduration", Description: "How long the last finished compaction took in seconds (only when the database was compacted since start up)", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "lastcompactionerror", Description: "The error the last finished compaction failed with (only when it failed)", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetDifficultyHistoryResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getdifficultyhistoryresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "height", Description: "The height of the first block of the period", TypeSchema: schemaNumeric},
+			{Name: "endheight", Description: "The height of the last block of the period, which is the requested height for the period containing it", TypeSchema: schemaNumeric},
+			{Name: "hash", Description: "The hash of the first block of the period", TypeSchema: schemaString},
+			{Name: "time", Description: "The timestamp of the first block of the period", TypeSchema: schemaNumeric},
+			{Name: "bits", Description: "The encoded proof-of-work target of the period", TypeSchema: schemaString},
+			{Name: "difficulty", Description: "The proof-of-work difficulty of the period as a multiple of the minimum difficulty", TypeSchema: schemaDecimal},
+			{Name: "networkhashps", Description: "The estimated network hashes per second over the period", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaGetImportStatusResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getimportstatusresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "active", Description: "Whether or not blocks are currently being imported", TypeSchema: schemaBool},
+			{Name: "file", Description: "The file blocks are currently being imported from", Optional: true, TypeSchema: schemaString},
+			{Name: "filesremaining", Description: "The number of files which have not been fully imported yet, including the current file", TypeSchema: schemaNumeric},
+			{Name: "blocksprocessed", Description: "The number of blocks read from the files so far", TypeSchema: schemaNumeric},
+			{Name: "blocksimported", Description: "The number of blocks read from the files which were added to the block chain", TypeSchema: schemaNumeric},
+			{Name: "height", Description: "The height of the best block", TypeSchema: schemaNumeric},
+			{Name: "error", Description: "The most recent error encountered while importing, if any", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetMempoolInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getmempoolinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "size", Description: "Number of transactions in the mempool", TypeSchema: schemaNumeric},
+			{Name: "bytes", Description: "Size in bytes of the mempool", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaGetMiningInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getmininginforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "blocks", Description: "Height of the latest best block", TypeSchema: schemaNumeric},
+			{Name: "currentblocksize", Description: "Size of the latest best block", TypeSchema: schemaNumeric},
+			{Name: "currentblocktx", Description: "Number of transactions in the latest best block", TypeSchema: schemaNumeric},
+			{Name: "difficulty", Description: "Current target difficulty", TypeSchema: schemaDecimal},
+			{Name: "errors", Description: "Any current errors", TypeSchema: schemaString},
+			{Name: "generate", Description: "Whether or not server is set to generate coins", TypeSchema: schemaBool},
+			{Name: "genproclimit", Description: "Number of processors to use for coin generation (-1 when disabled)", TypeSchema: schemaNumeric},
+			{Name: "hashespersec", Description: "Recent hashes per second performance measurement while generating coins", TypeSchema: schemaNumeric},
+			{Name: "networkhashps", Description: "Estimated network hashes per second for the most recent blocks", TypeSchema: schemaNumeric},
+			{Name: "pooledtx", Description: "Number of transactions in the memory pool", TypeSchema: schemaNumeric},
+			{Name: "testnet", Description: "Whether or not server is using testnet", TypeSchema: schemaBool},
+		},
+	}
+
+	schemaGetNetTotalsResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getnettotalsresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "totalbytesrecv", Description: "Total bytes received", TypeSchema: schemaNumeric},
+			{Name: "totalbytessent", Description: "Total bytes sent", TypeSchema: schemaNumeric},
+			{Name: "timemillis", Description: "Number of milliseconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaGetNetworkInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getnetworkinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "version", Description: "The version of the server", TypeSchema: schemaNumeric},
+			{Name: "protocolversion", Description: "The latest supported protocol version", TypeSchema: schemaNumeric},
+			{Name: "timeoffset", Description: "The time offset", TypeSchema: schemaNumeric},
+			{Name: "connections", Description: "The number of connected peers", TypeSchema: schemaNumeric},
+			{Name: "networks", Description: "The reachability of each network", TypeSchema: schemaArray(schemaNetworksResult)},
+			{Name: "relayfee", Description: "The minimum relay fee for non-free transactions in BTC/KB", TypeSchema: schemaDecimal},
+			{Name: "dustrelayfee", Description: "The fee rate in BTC/KB used to determine whether an output is dust", TypeSchema: schemaDecimal},
+			{Name: "dustlimit", Description: "The smallest amount in BTC a pay-to-pubkey-hash output must pay to not be considered dust", TypeSchema: schemaDecimal},
+			{Name: "localaddresses", Description: "The local addresses advertised to peers", TypeSchema: schemaArray(schemaLocalAddressesResult)},
+		},
+	}
+
+	schemaGetNetworkTopologyResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getnetworktopologyresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "asmap", Description: "Whether an AS map is loaded", TypeSchema: schemaBool},
+			{Name: "peers", Description: "The number of connected peers", TypeSchema: schemaNumeric},
+			{Name: "inbound", Description: "The number of inbound peers", TypeSchema: schemaNumeric},
+			{Name: "outbound", Description: "The number of outbound peers", TypeSchema: schemaNumeric},
+			{Name: "outboundnetgroups", Description: "The number of network groups with outbound peers", TypeSchema: schemaNumeric},
+			{Name: "maxnetgroupshare", Description: "The largest share of the outbound peers in one network group, between 0 and 1", TypeSchema: schemaDecimal},
+			{Name: "outboundasns", Description: "The number of known autonomous systems with outbound peers", TypeSchema: schemaNumeric},
+			{Name: "maxasnshare", Description: "The largest share of the outbound peers in one known autonomous system, between 0 and 1", TypeSchema: schemaDecimal},
+			{Name: "netgroups", Description: "The network groups of the peers, ordered by their numbers of peers", TypeSchema: schemaArray(schemaNetworkTopologyGroup)},
+			{Name: "asns", Description: "The autonomous systems of the peers, ordered by their numbers of peers -- empty without an AS map", TypeSchema: schemaArray(schemaNetworkTopologyGroup)},
+		},
+	}
+
+	schemaGetPeerInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getpeerinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "id", Description: "A unique node ID", TypeSchema: schemaNumeric},
+			{Name: "addr", Description: "The ip address and port of the peer", TypeSchema: schemaString},
+			{Name: "addrlocal", Description: "Local address", Optional: true, TypeSchema: schemaString},
+			{Name: "services", Description: "Services bitmask which represents the services supported by the peer", TypeSchema: schemaString},
+			{Name: "lastsend", Description: "Time the last message was received in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "lastrecv", Description: "Time the last message was sent in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "bytessent", Description: "Total bytes sent", TypeSchema: schemaNumeric},
+			{Name: "bytesrecv", Description: "Total bytes received", TypeSchema: schemaNumeric},
+			{Name: "conntime", Description: "Time the connection was made in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "timeoffset", Description: "The time offset of the peer", TypeSchema: schemaNumeric},
+			{Name: "pingtime", Description: "Number of microseconds the last ping took", TypeSchema: schemaDecimal},
+			{Name: "pingwait", Description: "Number of microseconds a queued ping has been waiting for a response", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "version", Description: "The protocol version of the peer", TypeSchema: schemaNumeric},
+			{Name: "subver", Description: "The user agent of the peer", TypeSchema: schemaString},
+			{Name: "inbound", Description: "Whether or not the peer is an inbound connection", TypeSchema: schemaBool},
+			{Name: "startingheight", Description: "The latest block height the peer knew about when the connection was established", TypeSchema: schemaNumeric},
+			{Name: "currentheight", Description: "The current height of the peer", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "banscore", Description: "The misbehavior score of the peer, which decays over time -- the peer is banned once it reaches the ban threshold", TypeSchema: schemaNumeric},
+			{Name: "banscorereasons", Description: "The misbehavior score of the peer broken down by kind of misbehavior", Optional: true, TypeSchema: schemaMap(schemaNumeric, "reason", "score", "The kind of misbehavior (invalidblock, unrequested, invflood, oversizedmessage or stalling) as the key and its score as the value")},
+			{Name: "syncnode", Description: "Whether or not the peer is the sync peer", TypeSchema: schemaBool},
+		},
+	}
+
+	schemaGetPolicyInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getpolicyinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "relaynonstd", Description: "Whether or not non-standard transactions are accepted and relayed", TypeSchema: schemaBool},
+			{Name: "minrelaytxfee", Description: "The minimum relay fee for non-free transactions in BTC/KB", TypeSchema: schemaDecimal},
+			{Name: "dustrelayfee", Description: "The fee rate in BTC/KB used to determine whether an output is dust", TypeSchema: schemaDecimal},
+			{Name: "acceptdatacarrier", Description: "Whether or not transactions with null data (OP_RETURN) outputs are accepted", TypeSchema: schemaBool},
+			{Name: "maxdatacarriersize", Description: "The maximum number of bytes of data allowed in a null data (OP_RETURN) output", TypeSchema: schemaNumeric},
+			{Name: "maxdatacarrieroutputs", Description: "The maximum number of null data (OP_RETURN) outputs allowed in a transaction", TypeSchema: schemaNumeric},
+			{Name: "acceptbaremultisig", Description: "Whether or not transactions with bare multi-signature outputs are accepted", TypeSchema: schemaBool},
+			{Name: "maxsigopspertx", Description: "The maximum number of signature operations allowed in a transaction", TypeSchema: schemaNumeric},
+			{Name: "maxstandardtxweight", Description: "The maximum weight of a standard transaction", TypeSchema: schemaNumeric},
+			{Name: "maxstandardsigscriptsize", Description: "The maximum size of a standard signature script", TypeSchema: schemaNumeric},
+			{Name: "maxstandardmultisigkeys", Description: "The maximum number of public keys in a standard multi-signature script", TypeSchema: schemaNumeric},
+			{Name: "maxancestorcount", Description: "The maximum number of unconfirmed ancestors of a transaction, including itself (0 when unlimited)", TypeSchema: schemaNumeric},
+			{Name: "maxancestorsize", Description: "The maximum virtual size in bytes of the unconfirmed ancestors of a transaction, including itself (0 when unlimited)", TypeSchema: schemaNumeric},
+			{Name: "maxdescendantcount", Description: "The maximum number of descendants of an unconfirmed transaction, including itself (0 when unlimited)", TypeSchema: schemaNumeric},
+			{Name: "maxdescendantsize", Description: "The maximum virtual size in bytes of the descendants of an unconfirmed transaction, including itself (0 when unlimited)", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaGetRPCInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getrpcinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "httpclients", Description: "The number of HTTP POST requests being served on the standard listeners", TypeSchema: schemaNumeric},
+			{Name: "maxhttpclients", Description: "The maximum number of HTTP POST requests served concurrently on the standard listeners (--rpcmaxclients)", TypeSchema: schemaNumeric},
+			{Name: "readonlyclients", Description: "The number of HTTP POST requests being served on the read-only listeners", TypeSchema: schemaNumeric},
+			{Name: "maxreadonlyclients", Description: "The maximum number of HTTP POST requests served concurrently on the read-only listeners (--rpcreadonlymaxclients)", TypeSchema: schemaNumeric},
+			{Name: "connections", Description: "The number of open connections on the standard listeners, including idle keep-alive and websocket connections", TypeSchema: schemaNumeric},
+			{Name: "maxconnections", Description: "The maximum number of open connections on the standard listeners, 0 when unlimited (--rpcmaxconns)", TypeSchema: schemaNumeric},
+			{Name: "readonlyconnections", Description: "The number of open connections on the read-only listeners, including idle keep-alive connections", TypeSchema: schemaNumeric},
+			{Name: "maxreadonlyconnections", Description: "The maximum number of open connections on the read-only listeners, 0 when unlimited (--rpcreadonlymaxconns)", TypeSchema: schemaNumeric},
+			{Name: "maxwebsockets", Description: "The maximum number of websocket clients (--rpcmaxwebsockets)", TypeSchema: schemaNumeric},
+			{Name: "maxwatchedaddrs", Description: "The maximum number of addresses each websocket client may watch, 0 when unlimited (--rpcmaxwatchedaddrs)", TypeSchema: schemaNumeric},
+			{Name: "maxwatchedoutpoints", Description: "The maximum number of outpoints each websocket client may watch, 0 when unlimited (--rpcmaxwatchedoutpoints)", TypeSchema: schemaNumeric},
+			{Name: "websockets", Description: "The connected websocket clients ordered by their remote addresses", TypeSchema: schemaArray(schemaWebsocketClientInfo)},
+		},
+	}
+
+	schemaGetRawMempoolVerboseResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getrawmempoolverboseresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "size", Description: "Transaction size in bytes", TypeSchema: schemaNumeric},
+			{Name: "fee", Description: "Transaction fee in xcoins", TypeSchema: schemaDecimal},
+			{Name: "time", Description: "Local time transaction entered pool in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "height", Description: "Block height when transaction entered the pool", TypeSchema: schemaNumeric},
+			{Name: "startingpriority", Description: "Priority when transaction entered the pool", TypeSchema: schemaDecimal},
+			{Name: "currentpriority", Description: "Current priority", TypeSchema: schemaDecimal},
+			{Name: "depends", Description: "Unconfirmed transactions used as inputs for this transaction", TypeSchema: schemaArray(schemaString)},
+		},
+	}
+
+	schemaGetRescanStatusResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getrescanstatusresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "id", Description: "The id of the rescan job", TypeSchema: schemaString},
+			{Name: "state", Description: "The state of the job: running, finished, canceled or failed", TypeSchema: schemaString},
+			{Name: "beginheight", Description: "Height of the first block to rescan", TypeSchema: schemaNumeric},
+			{Name: "endheight", Description: "Height of the final block to rescan, or -1 when the rescan continues through the best block", TypeSchema: schemaNumeric},
+			{Name: "height", Description: "Height of the last rescanned block, or -1 when no block was rescanned yet", TypeSchema: schemaNumeric},
+			{Name: "hash", Description: "Hash of the last rescanned block", Optional: true, TypeSchema: schemaString},
+			{Name: "error", Description: "The reason the job failed", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetSpendingInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getspendinginforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the transaction which created the output", TypeSchema: schemaString},
+			{Name: "vout", Description: "The index of the output", TypeSchema: schemaNumeric},
+			{Name: "spent", Description: "Whether or not the output has been spent", TypeSchema: schemaBool},
+			{Name: "spentby", Description: "The input which spends the output (omitted when unspent)", Optional: true, TypeSchema: schemaSpendingInputResult},
+		},
+	}
+
+	schemaGetSubscriptionsResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getsubscriptionsresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "blocks", Description: "Whether the client is registered with notifyblocks", TypeSchema: schemaBool},
+			{Name: "blockheaders", Description: "Whether the client is registered with notifyblockheaders", TypeSchema: schemaBool},
+			{Name: "blocktemplate", Description: "Whether the client is registered with notifyblocktemplate", TypeSchema: schemaBool},
+			{Name: "rawblocks", Description: "Whether the client is registered with subscriberawblocks", TypeSchema: schemaBool},
+			{Name: "clockskew", Description: "Whether the client is registered with notifyclockskew", TypeSchema: schemaBool},
+			{Name: "peerevents", Description: "Whether the client is registered with notifypeerevents", TypeSchema: schemaBool},
+			{Name: "mempool", Description: "Whether the client is registered with subscribemempool", TypeSchema: schemaBool},
+			{Name: "newtransactions", Description: "Whether the client is registered with notifynewtransactions", TypeSchema: schemaBool},
+			{Name: "verbosetransactions", Description: "Whether the new transaction notifications are verbose", TypeSchema: schemaBool},
+			{Name: "addresses", Description: "The addresses registered with notifyreceived", TypeSchema: schemaArray(schemaString)},
+			{Name: "outpoints", Description: "The outpoints registered with notifyspent", TypeSchema: schemaArray(schemaOutPoint)},
+			{Name: "scripthashes", Description: "The script hashes registered with subscribescripthash", TypeSchema: schemaArray(schemaString)},
+			{Name: "filters", Description: "The transaction filters loaded with loadtxfilter", TypeSchema: schemaArray(schemaTxFilterSubscription)},
+			{Name: "confirmations", Description: "The transactions registered with notifyconfirmations", TypeSchema: schemaArray(schemaConfirmationsSubscription)},
+		},
+	}
+
+	schemaGetSyncStatusResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getsyncstatusresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "initialblockdownload", Description: "Whether or not the server is still performing the initial download of the block chain.  Once cleared, it is not set again", TypeSchema: schemaBool},
+			{Name: "current", Description: "Whether or not the server believes it is synced with the connected peers", TypeSchema: schemaBool},
+			{Name: "blocks", Description: "The height of the best block in the main chain", TypeSchema: schemaNumeric},
+			{Name: "bestblockhash", Description: "The hash of the best block in the main chain", TypeSchema: schemaString},
+			{Name: "tiptime", Description: "The timestamp of the best block in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "tipage", Description: "The number of seconds since the timestamp of the best block", TypeSchema: schemaNumeric},
+			{Name: "syncpeer", Description: "The address of the peer the block chain is downloaded from (omitted when there is none)", Optional: true, TypeSchema: schemaString},
+			{Name: "syncpeerheight", Description: "The latest block height of the sync peer (omitted when there is no sync peer)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "verificationprogress", Description: "An estimate of the fraction of the block chain which has been downloaded and verified", TypeSchema: schemaDecimal},
+			{Name: "stalls", Description: "The number of times sync peers failed to deliver requested blocks in time per host (omitted when none stalled)", Optional: true, TypeSchema: schemaMap(schemaNumeric, "host", "stalls", "The host of the sync peer as the key and the number of times it stalled as the value")},
+		},
+	}
+
+	schemaGetTxOutResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "gettxoutresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "bestblock", Description: "The block hash that contains the transaction output", TypeSchema: schemaString},
+			{Name: "confirmations", Description: "The number of confirmations", TypeSchema: schemaNumeric},
+			{Name: "value", Description: "The transaction amount in BTC", TypeSchema: schemaDecimal},
+			{Name: "scriptPubKey", Description: "The public key script used to pay coins as a JSON object", TypeSchema: schemaScriptPubKeyResult},
+			{Name: "version", Description: "The transaction version", TypeSchema: schemaNumeric},
+			{Name: "coinbase", Description: "Whether or not the transaction is a coinbase", TypeSchema: schemaBool},
+		},
+	}
+
+	schemaGetTxOutSetInfoResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "gettxoutsetinforesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "height", Description: "The height of the best block", TypeSchema: schemaNumeric},
+			{Name: "bestblock", Description: "The hash of the best block", TypeSchema: schemaString},
+			{Name: "transactions", Description: "The number of transactions with unspent outputs (omitted for muhash)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "txouts", Description: "The number of unspent transaction outputs (omitted for muhash)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "hash_serialized", Description: "The double sha256 of the serialized set, which matches the set hash of a snapshot taken with dumputxoset (only for hash_serialized)", Optional: true, TypeSchema: schemaString},
+			{Name: "muhash", Description: "The rolling hash of the set, which the UTXO commitment of the next block commits to (only for muhash)", Optional: true, TypeSchema: schemaString},
+			{Name: "total_amount", Description: "The total amount of the unspent outputs in BTC (omitted for muhash)", Optional: true, TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaGetUtxoCommitmentResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getutxocommitmentresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hash", Description: "The hash of the block", TypeSchema: schemaString},
+			{Name: "height", Description: "The height of the block", TypeSchema: schemaNumeric},
+			{Name: "utxosethash", Description: "The hash of the unspent transaction outputs as of the block", TypeSchema: schemaString},
+			{Name: "commitment", Description: "The hash of the unspent transaction outputs as of the previous block the coinbase of the block commits to (omitted when it has none)", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaGetWorkResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "getworkresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "data", Description: "Hex-encoded block data", TypeSchema: schemaString},
+			{Name: "hash1", Description: "(DEPRECATED) Hex-encoded formatted hash buffer", TypeSchema: schemaString},
+			{Name: "midstate", Description: "(DEPRECATED) Hex-encoded precomputed hash state after hashing first half of the data", TypeSchema: schemaString},
+			{Name: "target", Description: "Hex-encoded little-endian hash target", TypeSchema: schemaString},
+		},
+	}
+
+	schemaInfoChainResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "infochainresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "version", Description: "The version of the server", TypeSchema: schemaNumeric},
+			{Name: "protocolversion", Description: "The latest supported protocol version", TypeSchema: schemaNumeric},
+			{Name: "blocks", Description: "The number of blocks processed", TypeSchema: schemaNumeric},
+			{Name: "timeoffset", Description: "The time offset", TypeSchema: schemaNumeric},
+			{Name: "connections", Description: "The number of connected peers", TypeSchema: schemaNumeric},
+			{Name: "proxy", Description: "The proxy used by the server", TypeSchema: schemaString},
+			{Name: "difficulty", Description: "The current target difficulty", TypeSchema: schemaDecimal},
+			{Name: "testnet", Description: "Whether or not server is using testnet", TypeSchema: schemaBool},
+			{Name: "relayfee", Description: "The minimum relay fee for non-free transactions in BTC/KB", TypeSchema: schemaDecimal},
+			{Name: "errors", Description: "Any current errors", TypeSchema: schemaString},
+		},
+	}
+
+	schemaJournalEvent = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "journalevent",
+		Fields: []btcjson.FieldSchema{
+			{Name: "sequence", Description: "Journal sequence number of the event", TypeSchema: schemaNumeric},
+			{Name: "event", Description: "The notification which announced the event: blockconnected, blockdisconnected or txaccepted", TypeSchema: schemaString},
+			{Name: "hash", Description: "The block hash or transaction hash", TypeSchema: schemaString},
+			{Name: "height", Description: "The block height, omitted for transactions", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "time", Description: "The block time, or the time the transaction was accepted", TypeSchema: schemaNumeric},
+			{Name: "amount", Description: "Sum of the transaction outputs, omitted for blocks", Optional: true, TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaLoadUtxoSetResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "loadutxosetresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "coins", Description: "The number of unspent outputs in the snapshot", TypeSchema: schemaNumeric},
+			{Name: "base_hash", Description: "The hash of the block the snapshot was taken at", TypeSchema: schemaString},
+			{Name: "base_height", Description: "The height of the block the snapshot was taken at", TypeSchema: schemaNumeric},
+			{Name: "txoutset_hash", Description: "The hash of the unspent transaction output set", TypeSchema: schemaString},
+		},
+	}
+
+	schemaLocalAddressesResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "localaddressesresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "address", Description: "The local address", TypeSchema: schemaString},
+			{Name: "port", Description: "The port of the local address", TypeSchema: schemaNumeric},
+			{Name: "score", Description: "The relative score of the local address", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaMethodSchema = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "methodschema",
+		Fields: []btcjson.FieldSchema{
+			{Name: "method", Description: "The name of the method", TypeSchema: schemaString},
+			{Name: "synopsis", Description: "The description of the method", TypeSchema: schemaString},
+			{Name: "usage", Description: "The one-line usage of the method", TypeSchema: schemaString},
+			{Name: "websocket", Description: "Whether the method is only available to websocket clients", Optional: true, TypeSchema: schemaBool},
+			{Name: "notification", Description: "Whether the method is a notification sent by the server", Optional: true, TypeSchema: schemaBool},
+			{Name: "params", Description: "The positional parameters of the method", TypeSchema: schemaArray(schemaParamSchema)},
+			{Name: "results", Description: "The possible results of the method", Optional: true, TypeSchema: schemaArray(schemaResultSchema)},
+		},
+	}
+
+	schemaNetworkFault = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "networkfault",
+		Fields: []btcjson.FieldSchema{
+			{Name: "direction", Description: "The direction of the messages to drop or delay: in, out or both (default: both)", Optional: true, TypeSchema: schemaString},
+			{Name: "commands", Description: "The commands of the messages to drop or delay, such as block or inv (default: all messages)", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "droprate", Description: "The probability between 0 and 1 each message is dropped with", Optional: true, TypeSchema: schemaDecimal},
+			{Name: "delay", Description: "The time in milliseconds to delay each message by, up to one minute", Optional: true, TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaNetworkFaultResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "networkfaultresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "target", Description: "The id or address of the peer the fault applies to", TypeSchema: schemaString},
+			{Name: "direction", Description: "The direction of the messages the fault applies to (in, out or both)", TypeSchema: schemaString},
+			{Name: "commands", Description: "The commands of the messages the fault applies to, or all messages when omitted", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "droprate", Description: "The probability between 0 and 1 each message is dropped with", TypeSchema: schemaDecimal},
+			{Name: "delay", Description: "The time in milliseconds each message is delayed by", TypeSchema: schemaNumeric},
+			{Name: "dropped", Description: "The number of messages the fault dropped", TypeSchema: schemaNumeric},
+			{Name: "delayed", Description: "The number of messages the fault delayed", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaNetworkTopologyGroup = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "networktopologygroup",
+		Fields: []btcjson.FieldSchema{
+			{Name: "group", Description: "The network group, such as 1.2.0.0 for IPv4 addresses, or the AS number such as AS13335 (unknown for addresses not in the AS map)", TypeSchema: schemaString},
+			{Name: "name", Description: "The name of the autonomous system when the AS map names it", Optional: true, TypeSchema: schemaString},
+			{Name: "peers", Description: "The number of peers in the group", TypeSchema: schemaNumeric},
+			{Name: "inbound", Description: "The number of inbound peers in the group", TypeSchema: schemaNumeric},
+			{Name: "outbound", Description: "The number of outbound peers in the group", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaNetworksResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "networksresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "name", Description: "The name of the network (ipv4, ipv6 or onion)", TypeSchema: schemaString},
+			{Name: "limited", Description: "Whether or not connections to the network are disabled", TypeSchema: schemaBool},
+			{Name: "reachable", Description: "Whether or not the network is reachable", TypeSchema: schemaBool},
+			{Name: "proxy", Description: "The proxy used for the network, if any", TypeSchema: schemaString},
+		},
+	}
+
+	schemaOutPoint = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "outpoint",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hash", Description: "The hex-encoded bytes of the outpoint hash", TypeSchema: schemaString},
+			{Name: "index", Description: "The index of the outpoint", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaParamSchema = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "paramschema",
+		Fields: []btcjson.FieldSchema{
+			{Name: "name", Description: "The name of the parameter", TypeSchema: schemaString},
+			{Name: "description", Description: "The description of the parameter", TypeSchema: schemaString},
+			{Name: "optional", Description: "Whether the parameter may be omitted", Optional: true, TypeSchema: schemaBool},
+			{Name: "default", Description: "The value used when the parameter is omitted", Optional: true, TypeSchema: schemaValue},
+			{Name: "type", Description: "The JSON type (numeric, string, boolean, array, object, value or null)", TypeSchema: schemaString},
+			{Name: "decimal", Description: "Whether a number may have a fractional part", Optional: true, TypeSchema: schemaBool},
+			{Name: "items", Description: "The type of the elements of an array", Optional: true, TypeSchema: schemaTypeSchema},
+			{Name: "typename", Description: "The name of an object with a fixed set of fields", Optional: true, TypeSchema: schemaString},
+			{Name: "fields", Description: "The fields of an object with a fixed set of fields", Optional: true, TypeSchema: schemaArray(schemaFieldSchema)},
+			{Name: "values", Description: "The type of the values of an object with arbitrary keys", Optional: true, TypeSchema: schemaTypeSchema},
+			{Name: "entries", Description: "The names of the keys and values of an object with arbitrary keys", Optional: true, TypeSchema: schemaEntrySchema},
+		},
+	}
+
+	schemaPeerDetails = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "peerdetails",
+		Fields: []btcjson.FieldSchema{
+			{Name: "id", Description: "A unique node ID", TypeSchema: schemaNumeric},
+			{Name: "addr", Description: "The ip address and port of the peer", TypeSchema: schemaString},
+			{Name: "inbound", Description: "Whether or not the peer is an inbound connection", TypeSchema: schemaBool},
+			{Name: "persistent", Description: "Whether or not the peer is a persistent connection which is retried when it drops", TypeSchema: schemaBool},
+			{Name: "services", Description: "Services bitmask which represents the services supported by the peer", TypeSchema: schemaString},
+			{Name: "version", Description: "The protocol version of the peer", TypeSchema: schemaNumeric},
+			{Name: "subver", Description: "The user agent of the peer", TypeSchema: schemaString},
+			{Name: "startingheight", Description: "The latest block height the peer knew about when the connection was established", TypeSchema: schemaNumeric},
+			{Name: "currentheight", Description: "The current height of the peer", TypeSchema: schemaNumeric},
+			{Name: "conntime", Description: "Time the connection was made in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "bytessent", Description: "Total bytes sent", TypeSchema: schemaNumeric},
+			{Name: "bytesrecv", Description: "Total bytes received", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaPrevOut = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "prevout",
+		Fields: []btcjson.FieldSchema{
+			{Name: "addresses", Description: "previous output addresses", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "value", Description: "previous output value", TypeSchema: schemaDecimal},
+		},
+	}
+
+	schemaRawTxInput = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "rawtxinput",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the previous transaction", TypeSchema: schemaString},
+			{Name: "vout", Description: "The index of the previous transaction output", TypeSchema: schemaNumeric},
+			{Name: "scriptPubKey", Description: "The hex-encoded public key script of the previous output", TypeSchema: schemaString},
+			{Name: "redeemScript", Description: "The hex-encoded redeem script (only required for pay-to-script-hash outputs)", TypeSchema: schemaString},
+		},
+	}
+
+	schemaResultSchema = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "resultschema",
+		Fields: []btcjson.FieldSchema{
+			{Name: "condition", Description: "The condition under which the result is returned when there is more than one possible result", Optional: true, TypeSchema: schemaString},
+			{Name: "description", Description: "The description of the result when it is not an object", Optional: true, TypeSchema: schemaString},
+			{Name: "type", Description: "The JSON type (numeric, string, boolean, array, object, value or null)", TypeSchema: schemaString},
+			{Name: "decimal", Description: "Whether a number may have a fractional part", Optional: true, TypeSchema: schemaBool},
+			{Name: "items", Description: "The type of the elements of an array", Optional: true, TypeSchema: schemaTypeSchema},
+			{Name: "typename", Description: "The name of an object with a fixed set of fields", Optional: true, TypeSchema: schemaString},
+			{Name: "fields", Description: "The fields of an object with a fixed set of fields", Optional: true, TypeSchema: schemaArray(schemaFieldSchema)},
+			{Name: "values", Description: "The type of the values of an object with arbitrary keys", Optional: true, TypeSchema: schemaTypeSchema},
+			{Name: "entries", Description: "The names of the keys and values of an object with arbitrary keys", Optional: true, TypeSchema: schemaEntrySchema},
+		},
+	}
+
+	schemaScriptPubKeyResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "scriptpubkeyresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "asm", Description: "Disassembly of the script", TypeSchema: schemaString},
+			{Name: "hex", Description: "Hex-encoded bytes of the script", Optional: true, TypeSchema: schemaString},
+			{Name: "reqSigs", Description: "The number of required signatures", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "type", Description: "The type of the script (e.g. 'pubkeyhash')", TypeSchema: schemaString},
+			{Name: "addresses", Description: "The bitcoin addresses associated with this script", Optional: true, TypeSchema: schemaArray(schemaString)},
+		},
+	}
+
+	schemaScriptSig = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "scriptsig",
+		Fields: []btcjson.FieldSchema{
+			{Name: "asm", Description: "Disassembly of the script", TypeSchema: schemaString},
+			{Name: "hex", Description: "Hex-encoded bytes of the script", TypeSchema: schemaString},
+		},
+	}
+
+	schemaSearchRawTransactionsResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "searchrawtransactionsresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hex", Description: "Hex-encoded transaction", Optional: true, TypeSchema: schemaString},
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "version", Description: "The transaction version", TypeSchema: schemaNumeric},
+			{Name: "locktime", Description: "The transaction lock time", TypeSchema: schemaNumeric},
+			{Name: "vin", Description: "The transaction inputs as JSON objects", TypeSchema: schemaArray(schemaVinPrevOut)},
+			{Name: "vout", Description: "The transaction outputs as JSON objects", TypeSchema: schemaArray(schemaVout)},
+			{Name: "blockhash", Description: "Hash of the block the transaction is part of", Optional: true, TypeSchema: schemaString},
+			{Name: "confirmations", Description: "Number of confirmations of the block", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Transaction time in seconds since 1 Jan 1970 GMT", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "blocktime", Description: "Block time in seconds since the 1 Jan 1970 GMT", Optional: true, TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaSessionResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "sessionresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "sessionid", Description: "The unique session ID for a client's websocket connection.", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaSignRawTransactionError = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "signrawtransactionerror",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the previous transaction", TypeSchema: schemaString},
+			{Name: "vout", Description: "The index of the previous transaction output", TypeSchema: schemaNumeric},
+			{Name: "scriptSig", Description: "The hex-encoded signature script", TypeSchema: schemaString},
+			{Name: "sequence", Description: "The script sequence number", TypeSchema: schemaNumeric},
+			{Name: "error", Description: "The reason the input could not be signed or verified", TypeSchema: schemaString},
+		},
+	}
+
+	schemaSignRawTransactionResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "signrawtransactionresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hex", Description: "The hex-encoded transaction with any signatures that could be applied", TypeSchema: schemaString},
+			{Name: "complete", Description: "Whether or not all inputs are signed and verified", TypeSchema: schemaBool},
+			{Name: "errors", Description: "Details about inputs which could not be signed or verified", Optional: true, TypeSchema: schemaArray(schemaSignRawTransactionError)},
+		},
+	}
+
+	schemaSpendingInputResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "spendinginputresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the spending transaction", TypeSchema: schemaString},
+			{Name: "vin", Description: "The index of the spending input", TypeSchema: schemaNumeric},
+			{Name: "blockhash", Description: "The hash of the block containing the spending transaction (omitted when it is in the memory pool)", Optional: true, TypeSchema: schemaString},
+			{Name: "height", Description: "The height of the block containing the spending transaction (omitted when it is in the memory pool)", Optional: true, TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaSubmitBlockOptions = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "submitblockoptions",
+		Fields: []btcjson.FieldSchema{
+			{Name: "workid", Description: "This parameter is currently ignored", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaTemplateRequest = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "templaterequest",
+		Fields: []btcjson.FieldSchema{
+			{Name: "mode", Description: "This is 'template', 'proposal', or omitted", Optional: true, TypeSchema: schemaString},
+			{Name: "capabilities", Description: "List of capabilities, including 'auditsource' to request the transaction selection audit trail", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "longpollid", Description: "The long poll ID of a job to monitor for expiration; required and valid only for long poll requests ", Optional: true, TypeSchema: schemaString},
+			{Name: "sigoplimit", Description: "Number of signature operations allowed in blocks (this parameter is ignored)", Optional: true, TypeSchema: schemaValue},
+			{Name: "sizelimit", Description: "Number of bytes allowed in blocks (this parameter is ignored)", Optional: true, TypeSchema: schemaValue},
+			{Name: "maxversion", Description: "Highest supported block version number (this parameter is ignored)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "target", Description: "The desired target for the block template (this parameter is ignored)", Optional: true, TypeSchema: schemaString},
+			{Name: "data", Description: "Hex-encoded block data (only for mode=proposal)", Optional: true, TypeSchema: schemaString},
+			{Name: "workid", Description: "The server provided workid if provided in block template (not applicable)", Optional: true, TypeSchema: schemaString},
+			{Name: "coinbaseflags", Description: "Hex-encoded data to include in the coinbase signature script instead of the default flags (btcd extension)", Optional: true, TypeSchema: schemaString},
+			{Name: "payoutscript", Description: "Hex-encoded public key script the coinbase pays to instead of the configured mining addresses; only used with the coinbasetxn capability (btcd extension)", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaTransactionInput = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "transactioninput",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the input transaction", TypeSchema: schemaString},
+			{Name: "vout", Description: "The specific output of the input transaction to redeem", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaTxFilterSubscription = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "txfiltersubscription",
+		Fields: []btcjson.FieldSchema{
+			{Name: "id", Description: "The id of the filter", TypeSchema: schemaString},
+			{Name: "addresses", Description: "The number of addresses watched by the filter", TypeSchema: schemaNumeric},
+			{Name: "outpoints", Description: "The number of unspent outpoints watched by the filter", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaTxRawDecodeResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "txrawdecoderesult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "version", Description: "The transaction version", TypeSchema: schemaNumeric},
+			{Name: "locktime", Description: "The transaction lock time", TypeSchema: schemaNumeric},
+			{Name: "vin", Description: "The transaction inputs as JSON objects", TypeSchema: schemaArray(schemaVin)},
+			{Name: "vout", Description: "The transaction outputs as JSON objects", TypeSchema: schemaArray(schemaVout)},
+		},
+	}
+
+	schemaTxRawResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "txrawresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hex", Description: "Hex-encoded transaction", TypeSchema: schemaString},
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "hash", Description: "The hash of the transaction including witness data", Optional: true, TypeSchema: schemaString},
+			{Name: "size", Description: "The serialized size of the transaction including witness data", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "vsize", Description: "The virtual size of the transaction (weight divided by 4, rounded up)", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "version", Description: "The transaction version", TypeSchema: schemaNumeric},
+			{Name: "locktime", Description: "The transaction lock time", TypeSchema: schemaNumeric},
+			{Name: "vin", Description: "The transaction inputs as JSON objects", TypeSchema: schemaArray(schemaVin)},
+			{Name: "vout", Description: "The transaction outputs as JSON objects", TypeSchema: schemaArray(schemaVout)},
+			{Name: "blockhash", Description: "Hash of the block the transaction is part of", Optional: true, TypeSchema: schemaString},
+			{Name: "confirmations", Description: "Number of confirmations of the block", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "time", Description: "Transaction time in seconds since 1 Jan 1970 GMT", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "blocktime", Description: "Block time in seconds since the 1 Jan 1970 GMT", Optional: true, TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaTypeSchema = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "typeschema",
+		Fields: []btcjson.FieldSchema{
+			{Name: "type", Description: "The JSON type (numeric, string, boolean, array, object, value or null)", TypeSchema: schemaString},
+			{Name: "decimal", Description: "Whether a number may have a fractional part", Optional: true, TypeSchema: schemaBool},
+			{Name: "items", Description: "The type of the elements of an array", Optional: true, TypeSchema: schemaObject},
+			{Name: "typename", Description: "The name of an object with a fixed set of fields", Optional: true, TypeSchema: schemaString},
+			{Name: "fields", Description: "The fields of an object with a fixed set of fields", Optional: true, TypeSchema: schemaArray(schemaObject)},
+			{Name: "values", Description: "The type of the values of an object with arbitrary keys", Optional: true, TypeSchema: schemaObject},
+			{Name: "entries", Description: "The names of the keys and values of an object with arbitrary keys", Optional: true, TypeSchema: schemaEntrySchema},
+		},
+	}
+
+	schemaUnconfirmedBroadcastResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "unconfirmedbroadcastresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "txid", Description: "The hash of the transaction", TypeSchema: schemaString},
+			{Name: "submitted", Description: "The time the transaction was submitted in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "lastbroadcast", Description: "The time the transaction was last broadcast in seconds since 1 Jan 1970 GMT", TypeSchema: schemaNumeric},
+			{Name: "broadcasts", Description: "The number of times the transaction has been broadcast", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaValidateAddressChainResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "validateaddresschainresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "isvalid", Description: "Whether or not the address is valid", TypeSchema: schemaBool},
+			{Name: "address", Description: "The bitcoin address (only when isvalid is true)", Optional: true, TypeSchema: schemaString},
+			{Name: "scriptPubKey", Description: "The hex-encoded script outputs paying to the address are locked with", Optional: true, TypeSchema: schemaString},
+			{Name: "script", Description: "The type of the script outputs paying to the address are locked with (nonstandard, pubkey, pubkeyhash, scripthash, multisig, nulldata, witness_v0_keyhash, witness_v0_scripthash)", Optional: true, TypeSchema: schemaString},
+			{Name: "isscript", Description: "Whether or not the address pays to a script hash", Optional: true, TypeSchema: schemaBool},
+			{Name: "iswitness", Description: "Whether or not the address pays to a witness program", Optional: true, TypeSchema: schemaBool},
+			{Name: "witnessprogram", Description: "The hex-encoded witness program (only for witness addresses)", Optional: true, TypeSchema: schemaString},
+			{Name: "sigsrequired", Description: "The number of signatures required to spend outputs paying to the address, taken from the redeem script when it is known", Optional: true, TypeSchema: schemaNumeric},
+			{Name: "pubkeys", Description: "The hex-encoded public keys embedded in the script, or in the redeem script when it is known", Optional: true, TypeSchema: schemaArray(schemaString)},
+			{Name: "redeemscript", Description: "The hex-encoded redeem script revealed by a spend of an output paying to the address (only for pay-to-script-hash addresses when the address index is enabled and such a spend has been indexed)", Optional: true, TypeSchema: schemaString},
+			{Name: "redeemscripttype", Description: "The type of the redeem script (only when the redeem script is known)", Optional: true, TypeSchema: schemaString},
+			{Name: "redeemscripttxid", Description: "The hash of the transaction which revealed the redeem script (only when the redeem script is known)", Optional: true, TypeSchema: schemaString},
+		},
+	}
+
+	schemaVin = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "vin",
+		Fields: []btcjson.FieldSchema{
+			{Name: "coinbase", Description: "The hex-encoded bytes of the signature script (coinbase txns only)", TypeSchema: schemaString},
+			{Name: "txid", Description: "The hash of the origin transaction (non-coinbase txns only)", TypeSchema: schemaString},
+			{Name: "vout", Description: "The index of the output being redeemed from the origin transaction (non-coinbase txns only)", TypeSchema: schemaNumeric},
+			{Name: "scriptSig", Description: "The signature script used to redeem the origin transaction as a JSON object (non-coinbase txns only)", TypeSchema: schemaScriptSig},
+			{Name: "txinwitness", Description: "The hex-encoded witness data of the input (only for inputs with witness data)", TypeSchema: schemaArray(schemaString)},
+			{Name: "sequence", Description: "The script sequence number", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaVinPrevOut = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "vinprevout",
+		Fields: []btcjson.FieldSchema{
+			{Name: "coinbase", Description: "The hex-encoded bytes of the signature script (coinbase txns only)", TypeSchema: schemaString},
+			{Name: "txid", Description: "The hash of the origin transaction (non-coinbase txns only)", TypeSchema: schemaString},
+			{Name: "vout", Description: "The index of the output being redeemed from the origin transaction (non-coinbase txns only)", TypeSchema: schemaNumeric},
+			{Name: "scriptSig", Description: "The signature script used to redeem the origin transaction as a JSON object (non-coinbase txns only)", TypeSchema: schemaScriptSig},
+			{Name: "prevOut", Description: "Data from the origin transaction output with index vout.", TypeSchema: schemaPrevOut},
+			{Name: "sequence", Description: "The script sequence number", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaVout = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "vout",
+		Fields: []btcjson.FieldSchema{
+			{Name: "value", Description: "The amount in BTC", TypeSchema: schemaDecimal},
+			{Name: "n", Description: "The index of this transaction output", TypeSchema: schemaNumeric},
+			{Name: "scriptPubKey", Description: "The public key script used to pay coins as a JSON object", TypeSchema: schemaScriptPubKeyResult},
+		},
+	}
+
+	schemaWaitForBlockResult = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "waitforblockresult",
+		Fields: []btcjson.FieldSchema{
+			{Name: "hash", Description: "The hash of the tip of the best chain", TypeSchema: schemaString},
+			{Name: "height", Description: "The height of the tip of the best chain", TypeSchema: schemaNumeric},
+		},
+	}
+
+	schemaWebsocketClientInfo = btcjson.TypeSchema{
+		Type:     "object",
+		TypeName: "websocketclientinfo",
+		Fields: []btcjson.FieldSchema{
+			{Name: "addr", Description: "The remote address of the client", TypeSchema: schemaString},
+			{Name: "sessionid", Description: "The session ID of the client", TypeSchema: schemaNumeric},
+			{Name: "watchedaddrs", Description: "The number of addresses and script hashes the client watches, including those of its transaction filters", TypeSchema: schemaNumeric},
+			{Name: "watchedoutpoints", Description: "The number of outpoints the client watches, including those of its transaction filters", TypeSchema: schemaNumeric},
+		},
+	}
+)
@@ -7,6 +7,7 @@ package main
 import (
 	"bytes"
 	"container/list"
+	"context"
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
@@ -14,13 +15,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/fastsha256"
 	"github.com/conseweb/golangcrypto/ripemd160"
+	"github.com/conseweb/stcd/bech32"
+	"github.com/conseweb/stcd/blockchain"
 	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/database"
 	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
@@ -34,6 +41,20 @@ const (
 	// handler since notifications have their own queueing mechanism
 	// independent of the send channel buffer.
 	websocketSendBufferSize = 50
+
+	// wsFanOutWorkers is the number of workers which queue notifications
+	// to websocket clients concurrently.
+	wsFanOutWorkers = 4
+
+	// wsFanOutBatchSize is the number of websocket clients a fan-out worker
+	// queues a notification to at once.  Notifications for at most this
+	// many clients are queued by the notification handler itself.
+	wsFanOutBatchSize = 64
+
+	// wsMaxConfirmationRequests is the maximum number of transactions a
+	// websocket client may wait for the confirmations of with the
+	// notifyconfirmations command at once.
+	wsMaxConfirmationRequests = 10000
 )
 
 // timeZeroVal is simply the zero value for a time.Time and is used to avoid
@@ -49,17 +70,41 @@ type wsCommandHandler func(*wsClient, interface{}) (interface{}, error)
 // causes a dependency loop.
 var wsHandlers map[string]wsCommandHandler
 var wsHandlersBeforeInit = map[string]wsCommandHandler{
+	"cancelrescan":              handleCancelRescan,
+	"getrescanstatus":           handleGetRescanStatus,
+	"getsubscriptions":          handleGetSubscriptions,
 	"help":                      handleWebsocketHelp,
+	"loadtxfilter":              handleLoadTxFilter,
+	"notifyblockheaders":        handleNotifyBlockHeaders,
 	"notifyblocks":              handleNotifyBlocks,
+	"notifyblocktemplate":       handleNotifyBlockTemplate,
+	"notifyclockskew":           handleNotifyClockSkew,
+	"notifyconfirmations":       handleNotifyConfirmations,
 	"notifynewtransactions":     handleNotifyNewTransactions,
+	"notifypeerevents":          handleNotifyPeerEvents,
 	"notifyreceived":            handleNotifyReceived,
 	"notifyspent":               handleNotifySpent,
+	"removetxfilter":            handleRemoveTxFilter,
+	"replayevents":              handleReplayEvents,
 	"session":                   handleSession,
+	"stopnotifyblockheaders":    handleStopNotifyBlockHeaders,
 	"stopnotifyblocks":          handleStopNotifyBlocks,
+	"stopnotifyblocktemplate":   handleStopNotifyBlockTemplate,
+	"stopnotifyclockskew":       handleStopNotifyClockSkew,
+	"stopnotifyconfirmations":   handleStopNotifyConfirmations,
 	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
+	"stopnotifypeerevents":      handleStopNotifyPeerEvents,
 	"stopnotifyspent":           handleStopNotifySpent,
 	"stopnotifyreceived":        handleStopNotifyReceived,
+	"subscribemempool":          handleSubscribeMempool,
+	"subscriberawblocks":        handleSubscribeRawBlocks,
+	"subscribescripthash":       handleSubscribeScriptHash,
+	"unsubscribemempool":        handleUnsubscribeMempool,
+	"unsubscriberawblocks":      handleUnsubscribeRawBlocks,
+	"unsubscribescripthash":     handleUnsubscribeScriptHash,
 	"rescan":                    handleRescan,
+	"rescanfilter":              handleRescanFilter,
+	"startrescan":               handleStartRescan,
 }
 
 // wsAsyncHandlers holds the websocket commands which should be run
@@ -67,7 +112,9 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 // operations to run concurrently (and one at a time) while still responding
 // to the majority of normal requests which can be answered quickly.
 var wsAsyncHandlers = map[string]struct{}{
-	"rescan": struct{}{},
+	"replayevents": struct{}{},
+	"rescan":       struct{}{},
+	"rescanfilter": struct{}{},
 }
 
 // WebsocketHandler handles a new websocket client by creating a new wsClient,
@@ -76,7 +123,7 @@ var wsAsyncHandlers = map[string]struct{}{
 // server handler which runs each new connection in a new goroutine thereby
 // satisfying the requirement.
 func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
-	authenticated bool, isAdmin bool) {
+	authenticated bool, isAdmin bool, confirmedRecvTx bool) {
 
 	// Clear the read deadline that was set before the websocket hijacked
 	// the connection.
@@ -95,7 +142,8 @@ func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
 	// Create a new websocket client to handle the new websocket connection
 	// and wait for it to shutdown.  Once it has shutdown (and hence
 	// disconnected), remove it and any notifications it registered for.
-	client, err := newWebsocketClient(s, conn, remoteAddr, authenticated, isAdmin)
+	client, err := newWebsocketClient(s, conn, remoteAddr, authenticated,
+		isAdmin, confirmedRecvTx)
 	if err != nil {
 		rpcsLog.Errorf("Failed to serve client %s: %v", remoteAddr, err)
 		conn.Close()
@@ -105,6 +153,7 @@ func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
 	client.Start()
 	client.WaitForShutdown()
 	s.ntfnMgr.RemoveClient(client)
+	s.templateNtfn.Unsubscribe(client)
 	rpcsLog.Infof("Disconnected websocket client %s", remoteAddr)
 }
 
@@ -131,6 +180,19 @@ type wsNotificationManager struct {
 	// Access channel for current number of connected clients.
 	numClients chan int
 
+	// journal records block and transaction events so they can be
+	// replayed later.  It is nil when the event journal is disabled.
+	journal *eventJournal
+
+	// fanOut feeds the fan-out workers with batches of clients to queue
+	// a marshalled notification to.
+	fanOut chan *fanOutBatch
+
+	// eventID is the id of the event notificationHandler is currently
+	// handling.  Every notification sent about the event carries it.  It
+	// is only accessed by notificationHandler.
+	eventID uint64
+
 	// Shutdown handling
 	wg   sync.WaitGroup
 	quit chan struct{}
@@ -184,6 +246,75 @@ out:
 	close(out)
 }
 
+// fanOutBatch houses a marshalled notification to queue to a batch of
+// websocket clients along with the wait group to mark done once it has been
+// queued to all of them.
+type fanOutBatch struct {
+	clients        []*wsClient
+	marshalledJSON []byte
+	done           *sync.WaitGroup
+}
+
+// fanOutHandler queues notifications to the batches of websocket clients it
+// receives.  Several of them run concurrently so the time it takes until the
+// last of thousands of clients has a notification queued doesn't grow with
+// the number of clients as quickly.  It must be run as a goroutine.
+func (m *wsNotificationManager) fanOutHandler() {
+out:
+	for {
+		select {
+		case batch := <-m.fanOut:
+			for _, wsc := range batch.clients {
+				wsc.QueueNotification(batch.marshalledJSON)
+			}
+			batch.done.Done()
+
+		case <-m.quit:
+			break out
+		}
+	}
+	m.wg.Done()
+}
+
+// queueToClients queues the passed marshalled notification to every passed
+// websocket client.  The same marshalled bytes are shared by all of them.
+// Notifications for more clients than fit into a single fan-out batch are
+// split into batches which are queued by the fan-out workers concurrently.
+// It returns once the notification has been queued to every client so the
+// notifications each client receives keep their order.
+func (m *wsNotificationManager) queueToClients(clients map[chan struct{}]*wsClient,
+	marshalledJSON []byte) {
+
+	if len(clients) <= wsFanOutBatchSize {
+		for _, wsc := range clients {
+			wsc.QueueNotification(marshalledJSON)
+		}
+		return
+	}
+
+	batches := make([]*wsClient, 0, len(clients))
+	for _, wsc := range clients {
+		batches = append(batches, wsc)
+	}
+	var done sync.WaitGroup
+out:
+	for len(batches) > 0 {
+		n := wsFanOutBatchSize
+		if n > len(batches) {
+			n = len(batches)
+		}
+		done.Add(1)
+		select {
+		case m.fanOut <- &fanOutBatch{batches[:n], marshalledJSON, &done}:
+		case <-m.quit:
+			done.Done()
+			break out
+		}
+		batches = batches[n:]
+	}
+	done.Wait()
+}
+
 // queueHandler maintains a queue of notifications and notification handler
 // control messages.
 func (m *wsNotificationManager) queueHandler() {
@@ -218,6 +349,45 @@ func (m *wsNotificationManager) NotifyBlockDisconnected(block *coinutil.Block) {
 	}
 }
 
+// NotifyChainReorg passes a reorganization of the best chain to the
+// notification manager for block notification processing.
+func (m *wsNotificationManager) NotifyChainReorg(reorg *blockchain.ChainReorganization) {
+	// As NotifyChainReorg will be called by the block manager and the RPC
+	// server may no longer be running, use a select statement to unblock
+	// enqueueing the notification once the RPC server has begun shutting
+	// down.
+	select {
+	case m.queueNotification <- (*notificationChainReorg)(reorg):
+	case <-m.quit:
+	}
+}
+
+// NotifyHeaderConnected passes a header newly-connected to the best chain to
+// the notification manager for header notification processing.
+func (m *wsNotificationManager) NotifyHeaderConnected(header *blockchain.HeaderInfo) {
+	// As NotifyHeaderConnected will be called by the block manager and the
+	// RPC server may no longer be running, use a select statement to
+	// unblock enqueueing the notification once the RPC server has begun
+	// shutting down.
+	select {
+	case m.queueNotification <- (*notificationHeaderConnected)(header):
+	case <-m.quit:
+	}
+}
+
+// NotifyHeaderDisconnected passes a header disconnected from the best chain to
+// the notification manager for header notification processing.
+func (m *wsNotificationManager) NotifyHeaderDisconnected(header *blockchain.HeaderInfo) {
+	// As NotifyHeaderDisconnected will be called by the block manager and
+	// the RPC server may no longer be running, use a select statement to
+	// unblock enqueueing the notification once the RPC server has begun
+	// shutting down.
+	select {
+	case m.queueNotification <- (*notificationHeaderDisconnected)(header):
+	case <-m.quit:
+	}
+}
+
 // NotifyMempoolTx passes a transaction accepted by mempool to the
 // notification manager for transaction notification processing.  If
 // isNew is true, the tx is is a new transaction, rather than one
@@ -238,24 +408,218 @@ func (m *wsNotificationManager) NotifyMempoolTx(tx *coinutil.Tx, isNew bool) {
 	}
 }
 
+// NotifyTxExpired passes a transaction evicted from the memory pool because it
+// wasn't mined before the configured expiry to the notification manager for
+// expiry notification processing.
+func (m *wsNotificationManager) NotifyTxExpired(tx *coinutil.Tx) {
+	n := (*notificationTxExpired)(tx)
+
+	// As NotifyTxExpired will be called by the server and the RPC server
+	// may no longer be running, use a select statement to unblock
+	// enqueueing the notification once the RPC server has begun shutting
+	// down.
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
+// NotifyMempoolTxAdded passes a transaction added to the memory pool to the
+// notification manager for mempool delta notification processing.
+func (m *wsNotificationManager) NotifyMempoolTxAdded(txDesc *mempoolTxDesc) {
+	n := &notificationMempoolTxAdded{
+		tx:    txDesc.Tx,
+		fee:   txDesc.Fee,
+		added: txDesc.Added,
+	}
+
+	// As NotifyMempoolTxAdded will be called by mempool and the RPC server
+	// may no longer be running, use a select statement to unblock
+	// enqueueing the notification once the RPC server has begun shutting
+	// down.
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
+// NotifyMempoolTxRemoved passes a transaction removed from the memory pool and
+// the reason to the notification manager for mempool delta notification
+// processing.
+func (m *wsNotificationManager) NotifyMempoolTxRemoved(tx *coinutil.Tx, reason txRemovalReason) {
+	n := &notificationMempoolTxRemoved{
+		tx:     tx,
+		reason: reason,
+	}
+
+	// As NotifyMempoolTxRemoved will be called by mempool and the RPC
+	// server may no longer be running, use a select statement to unblock
+	// enqueueing the notification once the RPC server has begun shutting
+	// down.
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
+// NotifyClockSkew passes a change in whether the local clock differs from the
+// median time of peers by more than the configured maximum to the
+// notification manager for clock skew notification processing.
+func (m *wsNotificationManager) NotifyClockSkew(offset time.Duration, skewed bool) {
+	n := &notificationClockSkew{
+		offset: offset,
+		skewed: skewed,
+	}
+
+	// As NotifyClockSkew will be called by the server and the RPC server
+	// may no longer be running, use a select statement to unblock
+	// enqueueing the notification once the RPC server has begun shutting
+	// down.
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
+// peerDetails returns the details of the passed peer reported by peer event
+// notifications.
+func peerDetails(sp *serverPeer) *btcjson.PeerDetails {
+	statsSnap := sp.StatsSnapshot()
+	return &btcjson.PeerDetails{
+		ID:             statsSnap.ID,
+		Addr:           statsSnap.Addr,
+		Inbound:        statsSnap.Inbound,
+		Persistent:     sp.persistent,
+		Services:       fmt.Sprintf("%08d", uint64(statsSnap.Services)),
+		Version:        statsSnap.Version,
+		SubVer:         statsSnap.UserAgent,
+		StartingHeight: statsSnap.StartingHeight,
+		CurrentHeight:  statsSnap.LastBlock,
+		ConnTime:       statsSnap.ConnTime.Unix(),
+		BytesSent:      statsSnap.BytesSent,
+		BytesRecv:      statsSnap.BytesRecv,
+	}
+}
+
+// notifyPeerEvent passes a peer event with the passed notification method to
+// the notification manager for peer event notification processing.  The
+// details of the peer are gathered right away so they reflect the peer at the
+// time of the event.
+func (m *wsNotificationManager) notifyPeerEvent(method string, sp *serverPeer) {
+	n := &notificationPeerEvent{method: method}
+	if sp != nil {
+		n.peer = peerDetails(sp)
+	}
+
+	// As peer events are notified by the server and the block manager and
+	// the RPC server may no longer be running, use a select statement to
+	// unblock enqueueing the notification once the RPC server has begun
+	// shutting down.
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
+// NotifyPeerConnected passes a peer which was added to the connected peers of
+// the server to the notification manager for peer event notification
+// processing.
+func (m *wsNotificationManager) NotifyPeerConnected(sp *serverPeer) {
+	m.notifyPeerEvent(btcjson.PeerConnectedNtfnMethod, sp)
+}
+
+// NotifyPeerDisconnected passes a peer which was removed from the connected
+// peers of the server to the notification manager for peer event
+// notification processing.
+func (m *wsNotificationManager) NotifyPeerDisconnected(sp *serverPeer) {
+	m.notifyPeerEvent(btcjson.PeerDisconnectedNtfnMethod, sp)
+}
+
+// NotifySyncPeerChanged passes the new peer the block manager syncs from, or
+// nil when there is none, to the notification manager for peer event
+// notification processing.
+func (m *wsNotificationManager) NotifySyncPeerChanged(sp *serverPeer) {
+	m.notifyPeerEvent(btcjson.SyncPeerChangedNtfnMethod, sp)
+}
+
+// NotifyDoubleSpend passes a transaction rejected by the memory pool because
+// it spends the passed inputs already spent by the passed conflicting pool
+// transaction to the notification manager for double spend notification
+// processing.
+func (m *wsNotificationManager) NotifyDoubleSpend(tx, conflict *coinutil.Tx,
+	inputs []wire.OutPoint) {
+
+	n := &notificationDoubleSpend{
+		tx:       tx,
+		conflict: conflict,
+		inputs:   inputs,
+	}
+
+	// As NotifyDoubleSpend will be called by mempool and the RPC server
+	// may no longer be running, use a select statement to unblock
+	// enqueueing the notification once the RPC server has begun
+	// shutting down.
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
 // Notification types
 type notificationBlockConnected coinutil.Block
 type notificationBlockDisconnected coinutil.Block
+type notificationChainReorg blockchain.ChainReorganization
+type notificationHeaderConnected blockchain.HeaderInfo
+type notificationHeaderDisconnected blockchain.HeaderInfo
 type notificationTxAcceptedByMempool struct {
 	isNew bool
 	tx    *coinutil.Tx
 }
+type notificationTxExpired coinutil.Tx
+type notificationMempoolTxAdded struct {
+	tx    *coinutil.Tx
+	fee   int64
+	added time.Time
+}
+type notificationMempoolTxRemoved struct {
+	tx     *coinutil.Tx
+	reason txRemovalReason
+}
+type notificationClockSkew struct {
+	offset time.Duration
+	skewed bool
+}
+type notificationPeerEvent struct {
+	method string
+	peer   *btcjson.PeerDetails
+}
+type notificationDoubleSpend struct {
+	tx       *coinutil.Tx
+	conflict *coinutil.Tx
+	inputs   []wire.OutPoint
+}
+type notificationShutdown time.Duration
+type notificationAlert []byte
 
 // Notification control requests
 type notificationRegisterClient wsClient
 type notificationUnregisterClient wsClient
 type notificationRegisterBlocks wsClient
 type notificationUnregisterBlocks wsClient
+type notificationRegisterHeaders wsClient
+type notificationUnregisterHeaders wsClient
+type notificationRegisterClockSkew wsClient
+type notificationUnregisterClockSkew wsClient
+type notificationRegisterPeerEvents wsClient
+type notificationUnregisterPeerEvents wsClient
+type notificationRegisterMempool wsClient
+type notificationUnregisterMempool wsClient
 type notificationRegisterNewMempoolTxs wsClient
 type notificationUnregisterNewMempoolTxs wsClient
 type notificationRegisterSpent struct {
 	wsc *wsClient
 	ops []*wire.OutPoint
+	err chan error
 }
 type notificationUnregisterSpent struct {
 	wsc *wsClient
@@ -264,11 +628,69 @@ type notificationUnregisterSpent struct {
 type notificationRegisterAddr struct {
 	wsc   *wsClient
 	addrs []string
+	err   chan error
 }
 type notificationUnregisterAddr struct {
 	wsc  *wsClient
 	addr string
 }
+type notificationRegisterScriptHashes struct {
+	wsc          *wsClient
+	scriptHashes []wire.ShaHash
+	err          chan error
+}
+type notificationUnregisterScriptHashes struct {
+	wsc          *wsClient
+	scriptHashes []wire.ShaHash
+}
+type notificationRegisterConfirmations struct {
+	wsc           *wsClient
+	txSha         *wire.ShaHash
+	confirmations int32
+	err           chan error
+}
+type notificationUnregisterConfirmations struct {
+	wsc   *wsClient
+	txSha *wire.ShaHash
+}
+type notificationQuerySubscriptions struct {
+	wsc   *wsClient
+	reply chan []*wsClientSubscriptions
+}
+type notificationCheckWatchLimits struct {
+	wsc       *wsClient
+	addrs     int
+	outPoints int
+	err       chan error
+}
+type notificationQueryWatched struct {
+	reply chan []*wsClientWatched
+}
+
+// wsClientWatched describes the number of addresses and outpoints a websocket
+// client watches.
+type wsClientWatched struct {
+	wsc       *wsClient
+	addrs     int
+	outPoints int
+}
+
+// wsClientSubscriptions describes the notifications a websocket client is
+// registered for with the notification manager.
+type wsClientSubscriptions struct {
+	wsc           *wsClient
+	blocks        bool
+	headers       bool
+	newTxs        bool
+	verboseTxs    bool
+	clockSkew     bool
+	peerEvents    bool
+	mempool       bool
+	addrs         []string
+	outPoints     []wire.OutPoint
+	scriptHashes  []wire.ShaHash
+	confirmations map[wire.ShaHash]int32
+}
 
 // notificationHandler reads notifications and control messages from the queue
 // handler and processes one at a time.
@@ -284,9 +706,48 @@ func (m *wsNotificationManager) notificationHandler() {
 	// Where possible, the quit channel is used as the unique id for a client
 	// since it is quite a bit more efficient than using the entire struct.
 	blockNotifications := make(map[chan struct{}]*wsClient)
+	headerNotifications := make(map[chan struct{}]*wsClient)
 	txNotifications := make(map[chan struct{}]*wsClient)
+	clockSkewNotifications := make(map[chan struct{}]*wsClient)
+	peerEventNotifications := make(map[chan struct{}]*wsClient)
+	mempoolNotifications := make(map[chan struct{}]*wsClient)
 	watchedOutPoints := make(map[wire.OutPoint]map[chan struct{}]*wsClient)
 	watchedAddrs := make(map[string]map[chan struct{}]*wsClient)
+	watchedScriptHashes := make(map[wire.ShaHash]map[chan struct{}]*wsClient)
+	watchedConfirmations := make(map[wire.ShaHash]*txConfirmationRequests)
+
+	// subscriptions returns the notifications the passed client is
+	// registered for.
+	subscriptions := func(wsc *wsClient) *wsClientSubscriptions {
+		subs := &wsClientSubscriptions{
+			wsc:          wsc,
+			verboseTxs:   wsc.verboseTxUpdates,
+			addrs:        make([]string, 0, len(wsc.addrRequests)),
+			outPoints:    make([]wire.OutPoint, 0, len(wsc.spentRequests)),
+			scriptHashes: make([]wire.ShaHash, 0, len(wsc.scriptHashRequests)),
+			confirmations: make(map[wire.ShaHash]int32,
+				len(wsc.confirmationRequests)),
+		}
+		_, subs.blocks = blockNotifications[wsc.quit]
+		_, subs.headers = headerNotifications[wsc.quit]
+		_, subs.newTxs = txNotifications[wsc.quit]
+		_, subs.clockSkew = clockSkewNotifications[wsc.quit]
+		_, subs.peerEvents = peerEventNotifications[wsc.quit]
+		_, subs.mempool = mempoolNotifications[wsc.quit]
+		for addr := range wsc.addrRequests {
+			subs.addrs = append(subs.addrs, addr)
+		}
+		for op := range wsc.spentRequests {
+			subs.outPoints = append(subs.outPoints, op)
+		}
+		for scriptHash := range wsc.scriptHashRequests {
+			subs.scriptHashes = append(subs.scriptHashes, scriptHash)
+		}
+		for txSha, confirmations := range wsc.confirmationRequests {
+			subs.confirmations[txSha] = confirmations
+		}
+		return subs
+	}
 
 out:
 	for {
@@ -296,33 +757,131 @@ out:
 				// queueHandler quit.
 				break out
 			}
+			m.eventID = newNtfnEventID()
 			switch n := n.(type) {
 			case *notificationBlockConnected:
 				block := (*coinutil.Block)(n)
+				m.journalBlock(journalBlockConnected, block)
+
+				// The serialized txs are shared by the watched
+				// request and filter passes below.
+				hexes := newTxHexes(block)
 
 				// Skip iterating through all txs if no
 				// tx notification requests exist.
 				if len(watchedOutPoints) != 0 || len(watchedAddrs) != 0 {
 					for _, tx := range block.Transactions() {
 						m.notifyForTx(watchedOutPoints,
-							watchedAddrs, tx, block)
+							watchedAddrs, tx, block, hexes)
+					}
+				}
+				if len(watchedScriptHashes) != 0 {
+					blockTxs := make(map[wire.ShaHash]*coinutil.Tx)
+					for _, tx := range block.Transactions() {
+						blockTxs[*tx.Sha()] = tx
+					}
+					for _, tx := range block.Transactions() {
+						m.notifyForScriptHashes(
+							watchedScriptHashes, tx,
+							block, blockTxs, hexes)
 					}
 				}
 
+				for _, tx := range block.Transactions() {
+					m.notifyFilteredTx(clients, tx, block, hexes)
+				}
+
 				if len(blockNotifications) != 0 {
 					m.notifyBlockConnected(blockNotifications,
 						block)
 				}
 
+				if len(watchedConfirmations) != 0 {
+					m.notifyConfirmations(watchedConfirmations,
+						block)
+				}
+
 			case *notificationBlockDisconnected:
+				block := (*coinutil.Block)(n)
+				m.journalBlock(journalBlockDisconnected, block)
 				m.notifyBlockDisconnected(blockNotifications,
-					(*coinutil.Block)(n))
+					block)
+				m.rearmConfirmationRequests(watchedConfirmations,
+					block)
+
+			case *notificationChainReorg:
+				// Clients which registered for both blocks and
+				// headers only receive the reorganization once.
+				reorgClients := blockNotifications
+				if len(headerNotifications) != 0 {
+					reorgClients = make(map[chan struct{}]*wsClient)
+					for quit, wsc := range blockNotifications {
+						reorgClients[quit] = wsc
+					}
+					for quit, wsc := range headerNotifications {
+						reorgClients[quit] = wsc
+					}
+				}
+				m.notifyChainReorg(reorgClients,
+					(*blockchain.ChainReorganization)(n))
+
+			case *notificationHeaderConnected:
+				m.notifyHeader(headerNotifications,
+					btcjson.BlockHeaderConnectedNtfnMethod,
+					(*blockchain.HeaderInfo)(n))
+
+			case *notificationHeaderDisconnected:
+				m.notifyHeader(headerNotifications,
+					btcjson.BlockHeaderDisconnectedNtfnMethod,
+					(*blockchain.HeaderInfo)(n))
 
 			case *notificationTxAcceptedByMempool:
+				if n.isNew {
+					m.journalTx(n.tx)
+				}
 				if n.isNew && len(txNotifications) != 0 {
 					m.notifyForNewTx(txNotifications, n.tx)
 				}
-				m.notifyForTx(watchedOutPoints, watchedAddrs, n.tx, nil)
+				hexes := newTxHexes(nil)
+				m.notifyForTx(watchedOutPoints, watchedAddrs, n.tx,
+					nil, hexes)
+				m.notifyForScriptHashes(watchedScriptHashes, n.tx,
+					nil, nil, hexes)
+				m.notifyFilteredTx(clients, n.tx, nil, hexes)
+
+			case *notificationMempoolTxAdded:
+				if len(mempoolNotifications) != 0 {
+					m.notifyMempoolTxAdded(mempoolNotifications,
+						n)
+				}
+
+			case *notificationMempoolTxRemoved:
+				if len(mempoolNotifications) != 0 {
+					m.notifyMempoolTxRemoved(
+						mempoolNotifications, n)
+				}
+
+			case *notificationClockSkew:
+				m.notifyClockSkew(clockSkewNotifications,
+					n.offset, n.skewed)
+
+			case *notificationPeerEvent:
+				m.notifyPeerEventToClients(peerEventNotifications, n)
+
+			case *notificationDoubleSpend:
+				m.notifyDoubleSpend(txNotifications,
+					watchedOutPoints, watchedAddrs, n)
+
+			case *notificationTxExpired:
+				m.notifyTxExpired(txNotifications,
+					watchedOutPoints, watchedAddrs,
+					(*coinutil.Tx)(n))
+
+			case *notificationShutdown:
+				m.notifyShutdown(clients, time.Duration(*n))
+
+			case notificationAlert:
+				m.queueToClients(clients, n)
 
 			case *notificationRegisterBlocks:
 				wsc := (*wsClient)(n)
@@ -332,6 +891,38 @@ out:
 				wsc := (*wsClient)(n)
 				delete(blockNotifications, wsc.quit)
 
+			case *notificationRegisterHeaders:
+				wsc := (*wsClient)(n)
+				headerNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterHeaders:
+				wsc := (*wsClient)(n)
+				delete(headerNotifications, wsc.quit)
+
+			case *notificationRegisterClockSkew:
+				wsc := (*wsClient)(n)
+				clockSkewNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterClockSkew:
+				wsc := (*wsClient)(n)
+				delete(clockSkewNotifications, wsc.quit)
+
+			case *notificationRegisterPeerEvents:
+				wsc := (*wsClient)(n)
+				peerEventNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterPeerEvents:
+				wsc := (*wsClient)(n)
+				delete(peerEventNotifications, wsc.quit)
+
+			case *notificationRegisterMempool:
+				wsc := (*wsClient)(n)
+				mempoolNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterMempool:
+				wsc := (*wsClient)(n)
+				delete(mempoolNotifications, wsc.quit)
+
 			case *notificationRegisterClient:
 				wsc := (*wsClient)(n)
 				clients[wsc.quit] = wsc
@@ -341,7 +932,11 @@ out:
 				// Remove any requests made by the client as well as
 				// the client itself.
 				delete(blockNotifications, wsc.quit)
+				delete(headerNotifications, wsc.quit)
 				delete(txNotifications, wsc.quit)
+				delete(clockSkewNotifications, wsc.quit)
+				delete(peerEventNotifications, wsc.quit)
+				delete(mempoolNotifications, wsc.quit)
 				for k := range wsc.spentRequests {
 					op := k
 					m.removeSpentRequest(watchedOutPoints, wsc, &op)
@@ -349,20 +944,96 @@ out:
 				for addr := range wsc.addrRequests {
 					m.removeAddrRequest(watchedAddrs, wsc, addr)
 				}
+				for k := range wsc.scriptHashRequests {
+					scriptHash := k
+					m.removeScriptHashRequest(watchedScriptHashes,
+						wsc, &scriptHash)
+				}
+				for k := range wsc.confirmationRequests {
+					txSha := k
+					m.removeConfirmationRequest(
+						watchedConfirmations, wsc, &txSha)
+				}
 				delete(clients, wsc.quit)
 
 			case *notificationRegisterSpent:
-				m.addSpentRequests(watchedOutPoints, n.wsc, n.ops)
+				var added int
+				for _, op := range n.ops {
+					if _, ok := n.wsc.spentRequests[*op]; !ok {
+						added++
+					}
+				}
+				err := checkWatchLimits(n.wsc, 0, added)
+				if err == nil {
+					m.addSpentRequests(watchedOutPoints, n.wsc,
+						n.ops)
+				}
+				n.err <- err
 
 			case *notificationUnregisterSpent:
 				m.removeSpentRequest(watchedOutPoints, n.wsc, n.op)
 
 			case *notificationRegisterAddr:
-				m.addAddrRequests(watchedAddrs, n.wsc, n.addrs)
+				var added int
+				for _, addr := range n.addrs {
+					if _, ok := n.wsc.addrRequests[addr]; !ok {
+						added++
+					}
+				}
+				err := checkWatchLimits(n.wsc, added, 0)
+				if err == nil {
+					m.addAddrRequests(watchedAddrs, n.wsc, n.addrs)
+				}
+				n.err <- err
 
 			case *notificationUnregisterAddr:
 				m.removeAddrRequest(watchedAddrs, n.wsc, n.addr)
 
+			case *notificationRegisterScriptHashes:
+				var added int
+				for _, scriptHash := range n.scriptHashes {
+					_, ok := n.wsc.scriptHashRequests[scriptHash]
+					if !ok {
+						added++
+					}
+				}
+				err := checkWatchLimits(n.wsc, added, 0)
+				if err == nil {
+					m.addScriptHashRequests(watchedScriptHashes,
+						n.wsc, n.scriptHashes)
+				}
+				n.err <- err
+
+			case *notificationRegisterConfirmations:
+				n.err <- m.addConfirmationRequest(
+					watchedConfirmations, n.wsc, n.txSha,
+					n.confirmations)
+
+			case *notificationUnregisterConfirmations:
+				m.removeConfirmationRequest(watchedConfirmations,
+					n.wsc, n.txSha)
+
+			case *notificationCheckWatchLimits:
+				n.err <- checkWatchLimits(n.wsc, n.addrs, n.outPoints)
+
+			case *notificationQueryWatched:
+				watched := make([]*wsClientWatched, 0, len(clients))
+				for _, wsc := range clients {
+					addrs, outPoints := wsc.watched()
+					watched = append(watched, &wsClientWatched{
+						wsc:       wsc,
+						addrs:     addrs,
+						outPoints: outPoints,
+					})
+				}
+				n.reply <- watched
+
+			case *notificationUnregisterScriptHashes:
+				for i := range n.scriptHashes {
+					m.removeScriptHashRequest(watchedScriptHashes,
+						n.wsc, &n.scriptHashes[i])
+				}
+
 			case *notificationRegisterNewMempoolTxs:
 				wsc := (*wsClient)(n)
 				txNotifications[wsc.quit] = wsc
@@ -371,6 +1042,18 @@ out:
 				wsc := (*wsClient)(n)
 				delete(txNotifications, wsc.quit)
 
+			case *notificationQuerySubscriptions:
+				var subs []*wsClientSubscriptions
+				if n.wsc != nil {
+					subs = append(subs, subscriptions(n.wsc))
+				} else {
+					for _, wsc := range clients {
+						subs = append(subs,
+							subscriptions(wsc))
+					}
+				}
+				n.reply <- subs
+
 			default:
 				rpcsLog.Warn("Unhandled notification type")
 			}
@@ -389,6 +1072,45 @@ out:
 	m.wg.Done()
 }
 
+// journalBlock records a block connected or disconnected event in the event
+// journal, if enabled.
+func (m *wsNotificationManager) journalBlock(typ journalEventType, block *coinutil.Block) {
+	if m.journal == nil {
+		return
+	}
+	err := m.journal.Record(journalEvent{
+		typ:    typ,
+		hash:   *block.Sha(),
+		height: block.Height(),
+		time:   block.MsgBlock().Header.Timestamp.Unix(),
+	})
+	if err != nil {
+		rpcsLog.Errorf("Unable to journal block %v: %v", block.Sha(), err)
+	}
+}
+
+// journalTx records a transaction accepted event in the event journal, if
+// enabled.
+func (m *wsNotificationManager) journalTx(tx *coinutil.Tx) {
+	if m.journal == nil {
+		return
+	}
+	var amount int64
+	for _, txOut := range tx.MsgTx().TxOut {
+		amount += txOut.Value
+	}
+	err := m.journal.Record(journalEvent{
+		typ:    journalTxAccepted,
+		hash:   *tx.Sha(),
+		time:   time.Now().Unix(),
+		amount: amount,
+	})
+	if err != nil {
+		rpcsLog.Errorf("Unable to journal transaction %v: %v", tx.Sha(),
+			err)
+	}
+}
+
 // NumClients returns the number of clients actively being served.
 func (m *wsNotificationManager) NumClients() (n int) {
 	select {
@@ -398,41 +1120,140 @@ func (m *wsNotificationManager) NumClients() (n int) {
 	return
 }
 
-// RegisterBlockUpdates requests block update notifications to the passed
-// websocket client.
-func (m *wsNotificationManager) RegisterBlockUpdates(wsc *wsClient) {
-	m.queueNotification <- (*notificationRegisterBlocks)(wsc)
-}
-
-// UnregisterBlockUpdates removes block update notifications for the passed
-// websocket client.
-func (m *wsNotificationManager) UnregisterBlockUpdates(wsc *wsClient) {
-	m.queueNotification <- (*notificationUnregisterBlocks)(wsc)
+// Subscriptions returns the notifications the passed websocket client is
+// registered for with the manager, or those of every connected client when the
+// client is nil.  Registrations requested before the call are reflected in the
+// result.  Nil is returned once the manager has shut down.
+func (m *wsNotificationManager) Subscriptions(wsc *wsClient) []*wsClientSubscriptions {
+	// The reply is buffered so the notification handler never blocks on it
+	// when the manager shuts down before it is received.
+	reply := make(chan []*wsClientSubscriptions, 1)
+	select {
+	case m.queueNotification <- &notificationQuerySubscriptions{wsc, reply}:
+	case <-m.quit:
+		return nil
+	}
+	select {
+	case subs := <-reply:
+		return subs
+	case <-m.quit:
+		return nil
+	}
 }
 
-// notifyBlockConnected notifies websocket clients that have registered for
-// block updates when a block is connected to the main chain.
-func (*wsNotificationManager) notifyBlockConnected(clients map[chan struct{}]*wsClient,
-	block *coinutil.Block) {
-
-	// Notify interested websocket clients about the connected block.
-	ntfn := btcjson.NewBlockConnectedNtfn(block.Sha().String(),
-		int32(block.Height()), block.MsgBlock().Header.Timestamp.Unix())
-	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
-	if err != nil {
-		rpcsLog.Error("Failed to marshal block connected notification: "+
-			"%v", err)
-		return
-	}
-	for _, wsc := range clients {
-		wsc.QueueNotification(marshalledJSON)
+// CheckWatchLimits returns an RPC error when the passed websocket client would
+// watch more addresses or outpoints than allowed after watching the passed
+// numbers of additional ones.
+func (m *wsNotificationManager) CheckWatchLimits(wsc *wsClient, addrs, outPoints int) error {
+	n := &notificationCheckWatchLimits{
+		wsc:       wsc,
+		addrs:     addrs,
+		outPoints: outPoints,
+		err:       make(chan error, 1),
 	}
+	return m.queueAndWait(n, n.err)
 }
 
-// notifyBlockDisconnected notifies websocket clients that have registered for
+// Watched returns the number of addresses and outpoints watched by each
+// connected websocket client.  Nil is returned once the manager has shut down.
+func (m *wsNotificationManager) Watched() []*wsClientWatched {
+	reply := make(chan []*wsClientWatched, 1)
+	select {
+	case m.queueNotification <- &notificationQueryWatched{reply}:
+	case <-m.quit:
+		return nil
+	}
+	select {
+	case watched := <-reply:
+		return watched
+	case <-m.quit:
+		return nil
+	}
+}
+
+// queueAndWait queues the passed request for the notification handler and
+// waits for the result it sends on errChan, which must be buffered so the
+// handler never blocks on it.  Nil is returned once the manager has shut down.
+func (m *wsNotificationManager) queueAndWait(n interface{}, errChan chan error) error {
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+		return nil
+	}
+	select {
+	case err := <-errChan:
+		return err
+	case <-m.quit:
+		return nil
+	}
+}
+
+// checkWatchLimits returns an RPC error when the passed websocket client would
+// watch more addresses or outpoints than allowed by the rpcmaxwatchedaddrs and
+// rpcmaxwatchedoutpoints options after watching the passed numbers of
+// additional ones.  It must only be called by the notification handler since it
+// accesses the registrations owned by it.
+func checkWatchLimits(wsc *wsClient, addrs, outPoints int) error {
+	watchedAddrs, watchedOutPoints := wsc.watched()
+	if cfg.RPCMaxWatchAddrs > 0 && addrs > 0 &&
+		watchedAddrs+addrs > cfg.RPCMaxWatchAddrs {
+
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCWatchLimit,
+			Message: fmt.Sprintf("Client may watch at most %d "+
+				"addresses -- watching %d, requested %d more",
+				cfg.RPCMaxWatchAddrs, watchedAddrs, addrs),
+		}
+	}
+	if cfg.RPCMaxWatchOuts > 0 && outPoints > 0 &&
+		watchedOutPoints+outPoints > cfg.RPCMaxWatchOuts {
+
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCWatchLimit,
+			Message: fmt.Sprintf("Client may watch at most %d "+
+				"outpoints -- watching %d, requested %d more",
+				cfg.RPCMaxWatchOuts, watchedOutPoints, outPoints),
+		}
+	}
+	return nil
+}
+
+// RegisterBlockUpdates requests block update notifications to the passed
+// websocket client.
+func (m *wsNotificationManager) RegisterBlockUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterBlocks)(wsc)
+}
+
+// UnregisterBlockUpdates removes block update notifications for the passed
+// websocket client.
+func (m *wsNotificationManager) UnregisterBlockUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterBlocks)(wsc)
+}
+
+// notifyBlockConnected notifies websocket clients that have registered for
+// block updates when a block is connected to the main chain.
+func (m *wsNotificationManager) notifyBlockConnected(clients map[chan struct{}]*wsClient,
+	block *coinutil.Block) {
+
+	// Notify interested websocket clients about the connected block.
+	ntfn := btcjson.NewBlockConnectedNtfn(block.Sha().String(),
+		int32(block.Height()), block.MsgBlock().Header.Timestamp.Unix())
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Error("Failed to marshal block connected notification: "+
+			"%v", err)
+		return
+	}
+	m.queueToClients(clients, marshalledJSON)
+}
+
+// notifyBlockDisconnected notifies websocket clients that have registered for
 // block updates when a block is disconnected from the main chain (due to a
-// reorganize).
-func (*wsNotificationManager) notifyBlockDisconnected(clients map[chan struct{}]*wsClient, block *coinutil.Block) {
+// reorganize).  Since all notifications pass through the single notification
+// queue in the order the block manager publishes them, the blocks disconnected
+// by a reorganization are notified from the old tip down before any block of
+// the new main chain is notified as connected.
+func (m *wsNotificationManager) notifyBlockDisconnected(clients map[chan struct{}]*wsClient, block *coinutil.Block) {
 	// Skip notification creation if no clients have requested block
 	// connected/disconnected notifications.
 	if len(clients) == 0 {
@@ -442,15 +1263,388 @@ func (*wsNotificationManager) notifyBlockDisconnected(clients map[chan struct{}]
 	// Notify interested websocket clients about the disconnected block.
 	ntfn := btcjson.NewBlockDisconnectedNtfn(block.Sha().String(),
 		int32(block.Height()), block.MsgBlock().Header.Timestamp.Unix())
-	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
 	if err != nil {
 		rpcsLog.Error("Failed to marshal block disconnected "+
 			"notification: %v", err)
 		return
 	}
-	for _, wsc := range clients {
-		wsc.QueueNotification(marshalledJSON)
+	m.queueToClients(clients, marshalledJSON)
+}
+
+// chainReorgNtfn returns the chainreorg notification for the passed
+// reorganization.
+func chainReorgNtfn(reorg *blockchain.ChainReorganization) *btcjson.ChainReorgNtfn {
+	detached := make([]string, 0, len(reorg.Detached))
+	for _, hash := range reorg.Detached {
+		detached = append(detached, hash.String())
+	}
+	attached := make([]string, 0, len(reorg.Attached))
+	for _, hash := range reorg.Attached {
+		attached = append(attached, hash.String())
+	}
+	return btcjson.NewChainReorgNtfn(reorg.ForkHash.String(),
+		reorg.ForkHeight, detached, attached)
+}
+
+// notifyChainReorg notifies websocket clients that have registered for block
+// updates when the main chain is reorganized.  It is sent after the block
+// disconnected and connected notifications of the reorganization.
+func (m *wsNotificationManager) notifyChainReorg(clients map[chan struct{}]*wsClient,
+	reorg *blockchain.ChainReorganization) {
+
+	// Skip notification creation if no clients have requested block
+	// notifications.
+	if len(clients) == 0 {
+		return
+	}
+
+	marshalledJSON, err := marshalNotification(chainReorgNtfn(reorg), m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal chain reorganization "+
+			"notification: %v", err)
+		return
+	}
+	m.queueToClients(clients, marshalledJSON)
+}
+
+// RegisterHeaderUpdates requests block header update notifications to the
+// passed websocket client.
+func (m *wsNotificationManager) RegisterHeaderUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterHeaders)(wsc)
+}
+
+// UnregisterHeaderUpdates removes block header update notifications for the
+// passed websocket client.
+func (m *wsNotificationManager) UnregisterHeaderUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterHeaders)(wsc)
+}
+
+// notifyHeader notifies websocket clients that have registered for block
+// header updates when a header is connected to or disconnected from the main
+// chain.  The method selects between the blockheaderconnected and
+// blockheaderdisconnected notifications.
+func (m *wsNotificationManager) notifyHeader(clients map[chan struct{}]*wsClient,
+	method string, header *blockchain.HeaderInfo) {
+
+	// Skip notification creation if no clients have requested header
+	// notifications.
+	if len(clients) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := header.Header.Serialize(&buf); err != nil {
+		rpcsLog.Errorf("Failed to serialize block header: %v", err)
+		return
+	}
+	hash := header.Hash.String()
+	headerHex := hex.EncodeToString(buf.Bytes())
+	var ntfn interface{}
+	if method == btcjson.BlockHeaderConnectedNtfnMethod {
+		ntfn = btcjson.NewBlockHeaderConnectedNtfn(hash, header.Height,
+			headerHex)
+	} else {
+		ntfn = btcjson.NewBlockHeaderDisconnectedNtfn(hash,
+			header.Height, headerHex)
+	}
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal %s notification: %v", method,
+			err)
+		return
+	}
+	m.queueToClients(clients, marshalledJSON)
+}
+
+// RegisterClockSkewUpdates requests clock skew notifications to the passed
+// websocket client.
+func (m *wsNotificationManager) RegisterClockSkewUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterClockSkew)(wsc)
+}
+
+// UnregisterClockSkewUpdates removes clock skew notifications for the passed
+// websocket client.
+func (m *wsNotificationManager) UnregisterClockSkewUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterClockSkew)(wsc)
+}
+
+// notifyClockSkew notifies websocket clients that have registered for clock
+// skew updates when the local clock becomes skewed from the median time of
+// peers or returns to within the configured maximum.
+func (m *wsNotificationManager) notifyClockSkew(clients map[chan struct{}]*wsClient,
+	offset time.Duration, skewed bool) {
+
+	// Skip notification creation if no clients have requested clock skew
+	// notifications.
+	if len(clients) == 0 {
+		return
+	}
+
+	ntfn := btcjson.NewClockSkewNtfn(int64(offset.Seconds()), skewed)
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal clock skew notification: %v",
+			err)
+		return
+	}
+	m.queueToClients(clients, marshalledJSON)
+}
+
+// RegisterPeerEventUpdates requests peer event notifications to the passed
+// websocket client.
+func (m *wsNotificationManager) RegisterPeerEventUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterPeerEvents)(wsc)
+}
+
+// UnregisterPeerEventUpdates removes peer event notifications for the passed
+// websocket client.
+func (m *wsNotificationManager) UnregisterPeerEventUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterPeerEvents)(wsc)
+}
+
+// notifyPeerEventToClients notifies websocket clients that have registered for
+// peer event updates when a peer connects or disconnects, or when the peer the
+// block chain is synced from changes.
+func (m *wsNotificationManager) notifyPeerEventToClients(clients map[chan struct{}]*wsClient,
+	n *notificationPeerEvent) {
+
+	// Skip notification creation if no clients have requested peer event
+	// notifications.
+	if len(clients) == 0 {
+		return
+	}
+
+	var ntfn interface{}
+	switch n.method {
+	case btcjson.PeerConnectedNtfnMethod:
+		ntfn = btcjson.NewPeerConnectedNtfn(*n.peer)
+	case btcjson.PeerDisconnectedNtfnMethod:
+		ntfn = btcjson.NewPeerDisconnectedNtfn(*n.peer)
+	default:
+		ntfn = btcjson.NewSyncPeerChangedNtfn(n.peer)
+	}
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal %s notification: %v",
+			n.method, err)
+		return
+	}
+	m.queueToClients(clients, marshalledJSON)
+}
+
+// doubleSpendRecipients returns the websocket clients interested in the passed
+// double spend.  Those are the clients which registered for new memory pool
+// transactions, watch one of the conflicting outpoints or watch an address
+// paid by either of the conflicting transactions.
+func doubleSpendRecipients(txClients map[chan struct{}]*wsClient,
+	ops map[wire.OutPoint]map[chan struct{}]*wsClient,
+	addrs map[string]map[chan struct{}]*wsClient, n *notificationDoubleSpend,
+	params *chaincfg.Params) map[chan struct{}]*wsClient {
+
+	recipients := make(map[chan struct{}]*wsClient)
+	for quit, wsc := range txClients {
+		recipients[quit] = wsc
+	}
+	for _, op := range n.inputs {
+		for quit, wsc := range ops[op] {
+			recipients[quit] = wsc
+		}
+	}
+	if len(addrs) == 0 {
+		return recipients
+	}
+	for _, tx := range []*coinutil.Tx{n.tx, n.conflict} {
+		for _, txOut := range tx.MsgTx().TxOut {
+			_, txAddrs, _, err := txscript.ExtractPkScriptAddrs(
+				txOut.PkScript, params)
+			if err != nil {
+				continue
+			}
+			for _, txAddr := range txAddrs {
+				cmap := addrs[txAddr.EncodeAddress()]
+				for quit, wsc := range cmap {
+					recipients[quit] = wsc
+				}
+			}
+		}
+	}
+	return recipients
+}
+
+// notifyDoubleSpend sends a doublespendseen notification to every websocket
+// client interested in the passed double spend.  Each client is notified at
+// most once.
+func (m *wsNotificationManager) notifyDoubleSpend(txClients map[chan struct{}]*wsClient,
+	ops map[wire.OutPoint]map[chan struct{}]*wsClient,
+	addrs map[string]map[chan struct{}]*wsClient, n *notificationDoubleSpend) {
+
+	recipients := doubleSpendRecipients(txClients, ops, addrs, n,
+		m.server.server.chainParams)
+
+	// Skip notification creation if nobody is interested.
+	if len(recipients) == 0 {
+		return
+	}
+
+	inputs := make([]btcjson.OutPoint, 0, len(n.inputs))
+	for _, op := range n.inputs {
+		inputs = append(inputs, btcjson.OutPoint{
+			Hash:  op.Hash.String(),
+			Index: op.Index,
+		})
+	}
+	ntfn := btcjson.NewDoubleSpendSeenNtfn(n.tx.Sha().String(),
+		n.conflict.Sha().String(), inputs)
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal double spend notification: "+
+			"%v", err)
+		return
 	}
+	m.queueToClients(recipients, marshalledJSON)
+}
+
+// expiredTxRecipients returns the websocket clients interested in the expiry
+// of the passed transaction.  Those are the clients which registered for new
+// memory pool transactions, watch one of the outpoints spent by the
+// transaction or watch an address it pays to.
+func expiredTxRecipients(txClients map[chan struct{}]*wsClient,
+	ops map[wire.OutPoint]map[chan struct{}]*wsClient,
+	addrs map[string]map[chan struct{}]*wsClient, tx *coinutil.Tx,
+	params *chaincfg.Params) map[chan struct{}]*wsClient {
+
+	recipients := make(map[chan struct{}]*wsClient)
+	for quit, wsc := range txClients {
+		recipients[quit] = wsc
+	}
+	for _, txIn := range tx.MsgTx().TxIn {
+		for quit, wsc := range ops[txIn.PreviousOutPoint] {
+			recipients[quit] = wsc
+		}
+	}
+	if len(addrs) == 0 {
+		return recipients
+	}
+	for _, txOut := range tx.MsgTx().TxOut {
+		_, txAddrs, _, err := txscript.ExtractPkScriptAddrs(
+			txOut.PkScript, params)
+		if err != nil {
+			continue
+		}
+		for _, txAddr := range txAddrs {
+			for quit, wsc := range addrs[txAddr.EncodeAddress()] {
+				recipients[quit] = wsc
+			}
+		}
+	}
+	return recipients
+}
+
+// notifyTxExpired sends a txexpired notification to every websocket client
+// interested in the passed transaction evicted from the memory pool.  Each
+// client is notified at most once.
+func (m *wsNotificationManager) notifyTxExpired(txClients map[chan struct{}]*wsClient,
+	ops map[wire.OutPoint]map[chan struct{}]*wsClient,
+	addrs map[string]map[chan struct{}]*wsClient, tx *coinutil.Tx) {
+
+	recipients := expiredTxRecipients(txClients, ops, addrs, tx,
+		m.server.server.chainParams)
+
+	// Skip notification creation if nobody is interested.
+	if len(recipients) == 0 {
+		return
+	}
+
+	ntfn := btcjson.NewTxExpiredNtfn(tx.Sha().String())
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal tx expired notification: %v",
+			err)
+		return
+	}
+	m.queueToClients(recipients, marshalledJSON)
+}
+
+// NotifyShutdown passes the grace period of a server shutdown to the
+// notification manager so all websocket clients can be notified that they
+// will be disconnected.
+func (m *wsNotificationManager) NotifyShutdown(gracePeriod time.Duration) {
+	n := notificationShutdown(gracePeriod)
+	select {
+	case m.queueNotification <- &n:
+	case <-m.quit:
+	}
+}
+
+// notifyShutdown notifies all websocket clients that the server is shutting
+// down and will disconnect them once the grace period has passed.
+func (m *wsNotificationManager) notifyShutdown(clients map[chan struct{}]*wsClient,
+	gracePeriod time.Duration) {
+
+	ntfn := btcjson.NewShutdownNtfn(int64(gracePeriod.Seconds()))
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal shutdown notification: %v",
+			err)
+		return
+	}
+	m.queueToClients(clients, marshalledJSON)
+}
+
+// NotifyAlert passes a marshalled alert notification broadcast by the
+// operator to the notification manager so it is sent to all websocket
+// clients.
+func (m *wsNotificationManager) NotifyAlert(marshalledJSON []byte) {
+	select {
+	case m.queueNotification <- notificationAlert(marshalledJSON):
+	case <-m.quit:
+	}
+}
+
+// RegisterMempoolUpdates requests notifications to the passed websocket client
+// for every transaction added to or removed from the memory pool.
+func (m *wsNotificationManager) RegisterMempoolUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterMempool)(wsc)
+}
+
+// UnregisterMempoolUpdates removes mempool delta notifications for the passed
+// websocket client.
+func (m *wsNotificationManager) UnregisterMempoolUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterMempool)(wsc)
+}
+
+// notifyMempoolTxAdded sends a mempooltxadded notification to the passed
+// websocket clients.
+func (m *wsNotificationManager) notifyMempoolTxAdded(clients map[chan struct{}]*wsClient,
+	n *notificationMempoolTxAdded) {
+
+	ntfn := btcjson.NewMempoolTxAddedNtfn(n.tx.Sha().String(),
+		blockchain.GetTxVirtualSize(n.tx), coinutil.Amount(n.fee).ToBTC(),
+		n.added.Unix())
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal mempooltxadded notification: "+
+			"%v", err)
+		return
+	}
+	m.queueToClients(clients, marshalledJSON)
+}
+
+// notifyMempoolTxRemoved sends a mempooltxremoved notification to the passed
+// websocket clients.
+func (m *wsNotificationManager) notifyMempoolTxRemoved(clients map[chan struct{}]*wsClient,
+	n *notificationMempoolTxRemoved) {
+
+	ntfn := btcjson.NewMempoolTxRemovedNtfn(n.tx.Sha().String(),
+		n.reason.String())
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal mempooltxremoved notification: "+
+			"%v", err)
+		return
+	}
+	m.queueToClients(clients, marshalledJSON)
 }
 
 // RegisterNewMempoolTxsUpdates requests notifications to the passed websocket
@@ -477,52 +1671,56 @@ func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClie
 	}
 
 	ntfn := btcjson.NewTxAcceptedNtfn(txShaStr, coinutil.Amount(amount).ToBTC())
-	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	marshalledJSON, err := marshalNotification(ntfn, m.eventID)
 	if err != nil {
 		rpcsLog.Errorf("Failed to marshal tx notification: %s", err.Error())
 		return
 	}
 
-	var verboseNtfn *btcjson.TxAcceptedVerboseNtfn
-	var marshalledJSONVerbose []byte
-	for _, wsc := range clients {
+	// Clients which requested verbose notifications are sent a different
+	// notification which is only created when there are any of them.
+	plainClients := make(map[chan struct{}]*wsClient, len(clients))
+	verboseClients := make(map[chan struct{}]*wsClient)
+	for quit, wsc := range clients {
 		if wsc.verboseTxUpdates {
-			if marshalledJSONVerbose != nil {
-				wsc.QueueNotification(marshalledJSONVerbose)
-				continue
-			}
-
-			net := m.server.server.chainParams
-			rawTx, err := createTxRawResult(net, mtx, txShaStr, nil,
-				"", 0, 0)
-			if err != nil {
-				return
-			}
-
-			verboseNtfn = btcjson.NewTxAcceptedVerboseNtfn(*rawTx)
-			marshalledJSONVerbose, err = btcjson.MarshalCmd(nil,
-				verboseNtfn)
-			if err != nil {
-				rpcsLog.Errorf("Failed to marshal verbose tx "+
-					"notification: %s", err.Error())
-				return
-			}
-			wsc.QueueNotification(marshalledJSONVerbose)
+			verboseClients[quit] = wsc
 		} else {
-			wsc.QueueNotification(marshalledJSON)
+			plainClients[quit] = wsc
 		}
 	}
+	m.queueToClients(plainClients, marshalledJSON)
+	if len(verboseClients) == 0 {
+		return
+	}
+
+	net := m.server.server.chainParams
+	rawTx, err := createTxRawResult(net, mtx, txShaStr, nil, "", 0, 0)
+	if err != nil {
+		return
+	}
+	verboseNtfn := btcjson.NewTxAcceptedVerboseNtfn(*rawTx)
+	marshalledJSONVerbose, err := marshalNotification(verboseNtfn, m.eventID)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal verbose tx notification: %s",
+			err.Error())
+		return
+	}
+	m.queueToClients(verboseClients, marshalledJSONVerbose)
 }
 
 // RegisterSpentRequests requests a notification when each of the passed
 // outpoints is confirmed spent (contained in a block connected to the main
 // chain) for the passed websocket client.  The request is automatically
-// removed once the notification has been sent.
-func (m *wsNotificationManager) RegisterSpentRequests(wsc *wsClient, ops []*wire.OutPoint) {
-	m.queueNotification <- &notificationRegisterSpent{
+// removed once the notification has been sent.  An RPC error is returned and
+// none of the outpoints are registered when the client would watch more
+// outpoints than allowed.
+func (m *wsNotificationManager) RegisterSpentRequests(wsc *wsClient, ops []*wire.OutPoint) error {
+	n := &notificationRegisterSpent{
 		wsc: wsc,
 		ops: ops,
+		err: make(chan error, 1),
 	}
+	return m.queueAndWait(n, n.err)
 }
 
 // addSpentRequests modifies a map of watched outpoints to sets of websocket
@@ -583,6 +1781,185 @@ func (*wsNotificationManager) removeSpentRequest(ops map[wire.OutPoint]map[chan
 	}
 }
 
+// txConfirmationRequests tracks a transaction websocket clients requested a
+// txconfirmed notification for with the notifyconfirmations command.
+type txConfirmationRequests struct {
+	// block and height identify the main chain block containing the
+	// transaction.  block is nil while the transaction isn't mined.
+	block  *wire.ShaHash
+	height int32
+
+	// clients holds the clients waiting for the notification.  The number
+	// of confirmations each of them waits for is kept in the
+	// confirmationRequests map of the client.
+	clients map[chan struct{}]*wsClient
+}
+
+// RegisterConfirmationRequest requests a txconfirmed notification for the
+// passed websocket client once the passed transaction has the passed number of
+// confirmations.  Registering the transaction again replaces the number of
+// confirmations of the previous request.  The request is automatically removed
+// once the notification has been sent.  An RPC error is returned when the
+// client would wait for more transactions than allowed.
+func (m *wsNotificationManager) RegisterConfirmationRequest(wsc *wsClient,
+	txSha *wire.ShaHash, confirmations int32) error {
+
+	n := &notificationRegisterConfirmations{
+		wsc:           wsc,
+		txSha:         txSha,
+		confirmations: confirmations,
+		err:           make(chan error, 1),
+	}
+	return m.queueAndWait(n, n.err)
+}
+
+// addConfirmationRequest modifies a map of watched transactions to add a
+// request of the websocket client wsc for a txconfirmed notification once the
+// passed transaction has the passed number of confirmations.  The block
+// database is consulted for transactions which aren't watched yet since they
+// may already be mined, in which case the notification is sent right away if
+// the transaction is already deep enough.
+func (m *wsNotificationManager) addConfirmationRequest(txs map[wire.ShaHash]*txConfirmationRequests,
+	wsc *wsClient, txSha *wire.ShaHash, confirmations int32) error {
+
+	_, ok := wsc.confirmationRequests[*txSha]
+	if !ok && len(wsc.confirmationRequests) >= wsMaxConfirmationRequests {
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCWatchLimit,
+			Message: fmt.Sprintf("Client may wait for the "+
+				"confirmations of at most %d transactions",
+				wsMaxConfirmationRequests),
+		}
+	}
+
+	db := m.server.server.db
+	req, ok := txs[*txSha]
+	if !ok {
+		req = &txConfirmationRequests{
+			height:  -1,
+			clients: make(map[chan struct{}]*wsClient),
+		}
+		replies, err := db.FetchTxBySha(txSha)
+		if err == nil && len(replies) != 0 {
+			reply := replies[len(replies)-1]
+			req.block = reply.BlkSha
+			req.height = reply.Height
+		}
+		txs[*txSha] = req
+	}
+	wsc.confirmationRequests[*txSha] = confirmations
+	req.clients[wsc.quit] = wsc
+
+	if req.block != nil {
+		_, bestHeight, err := db.NewestSha()
+		if err != nil {
+			return err
+		}
+		m.notifyConfirmedTx(txs, txSha, req, bestHeight)
+	}
+	return nil
+}
+
+// UnregisterConfirmationRequest removes the request of the passed websocket
+// client for a txconfirmed notification about the passed transaction.
+func (m *wsNotificationManager) UnregisterConfirmationRequest(wsc *wsClient, txSha *wire.ShaHash) {
+	m.queueNotification <- &notificationUnregisterConfirmations{
+		wsc:   wsc,
+		txSha: txSha,
+	}
+}
+
+// removeConfirmationRequest modifies a map of watched transactions to remove
+// the request of the websocket client wsc for a txconfirmed notification about
+// the passed transaction.  The transaction is no longer watched once wsc was
+// the last client waiting for it.
+func (*wsNotificationManager) removeConfirmationRequest(txs map[wire.ShaHash]*txConfirmationRequests,
+	wsc *wsClient, txSha *wire.ShaHash) {
+
+	delete(wsc.confirmationRequests, *txSha)
+
+	req, ok := txs[*txSha]
+	if !ok {
+		rpcsLog.Warnf("Attempt to remove nonexistent confirmations "+
+			"request for websocket client %s", wsc.addr)
+		return
+	}
+	delete(req.clients, wsc.quit)
+	if len(req.clients) == 0 {
+		delete(txs, *txSha)
+	}
+}
+
+// notifyConfirmedTx sends a txconfirmed notification to each client waiting
+// for the passed mined transaction which has at least as many confirmations as
+// the client waits for with the passed best height, and removes the requests
+// of the notified clients.
+func (m *wsNotificationManager) notifyConfirmedTx(txs map[wire.ShaHash]*txConfirmationRequests,
+	txSha *wire.ShaHash, req *txConfirmationRequests, bestHeight int32) {
+
+	confirmations := bestHeight - req.height + 1
+	var marshalled []byte
+	for quit, wsc := range req.clients {
+		if confirmations < wsc.confirmationRequests[*txSha] {
+			continue
+		}
+		if marshalled == nil {
+			ntfn := btcjson.NewTxConfirmedNtfn(txSha.String(),
+				confirmations, req.block.String(), req.height)
+			var err error
+			marshalled, err = marshalNotification(ntfn, m.eventID)
+			if err != nil {
+				rpcsLog.Errorf("Failed to marshal txconfirmed "+
+					"notification: %v", err)
+				return
+			}
+		}
+		wsc.QueueNotification(marshalled)
+		delete(wsc.confirmationRequests, *txSha)
+		delete(req.clients, quit)
+	}
+	if len(req.clients) == 0 {
+		delete(txs, *txSha)
+	}
+}
+
+// notifyConfirmations records the passed block connected to the main chain as
+// the block of the watched transactions it contains and sends the txconfirmed
+// notifications of the transactions which have reached the number of
+// confirmations waited for.
+func (m *wsNotificationManager) notifyConfirmations(txs map[wire.ShaHash]*txConfirmationRequests,
+	block *coinutil.Block) {
+
+	for _, tx := range block.Transactions() {
+		if req, ok := txs[*tx.Sha()]; ok {
+			req.block = block.Sha()
+			req.height = block.Height()
+		}
+	}
+	for k, req := range txs {
+		if req.block == nil {
+			continue
+		}
+		txSha := k
+		m.notifyConfirmedTx(txs, &txSha, req, block.Height())
+	}
+}
+
+// rearmConfirmationRequests marks the watched transactions contained in the
+// passed block disconnected from the main chain as no longer mined.  Their
+// confirmations are counted again once they are mined in another block, so no
+// txconfirmed notification is sent based on blocks of a stale chain.
+func (*wsNotificationManager) rearmConfirmationRequests(txs map[wire.ShaHash]*txConfirmationRequests,
+	block *coinutil.Block) {
+
+	for _, req := range txs {
+		if req.block != nil && req.block.IsEqual(block.Sha()) {
+			req.block = nil
+			req.height = -1
+		}
+	}
+}
+
 // txHexString returns the serialized transaction encoded in hexadecimal.
 func txHexString(tx *coinutil.Tx) string {
 	buf := bytes.NewBuffer(make([]byte, 0, tx.MsgTx().SerializeSize()))
@@ -591,6 +1968,78 @@ func txHexString(tx *coinutil.Tx) string {
 	return hex.EncodeToString(buf.Bytes())
 }
 
+// txHexes caches the hex encoded serializations of the transactions of a
+// single block or memory pool notification.  A transaction which matches
+// several kinds of requests, such as a watched address and a loaded filter,
+// is then only encoded once.
+//
+// The transactions of a block are not serialized again at all.  They are cut
+// from the serialized block, which the block caches once it has been stored
+// or loaded from the database.
+type txHexes struct {
+	block   *coinutil.Block
+	txStart []int
+	hexes   map[*coinutil.Tx]string
+}
+
+// newTxHexes returns a new cache for the transactions of a notification.  The
+// block is nil for memory pool notifications.
+func newTxHexes(block *coinutil.Block) *txHexes {
+	return &txHexes{
+		block: block,
+		hexes: make(map[*coinutil.Tx]string),
+	}
+}
+
+// blockTxBytes returns the bytes of tx within the serialized block of the
+// cache, or nil when tx is not one of the transactions of the block.
+func (c *txHexes) blockTxBytes(tx *coinutil.Tx) []byte {
+	if c.block == nil || tx.Index() < 0 {
+		return nil
+	}
+	if blockTx, err := c.block.Tx(tx.Index()); err != nil || blockTx != tx {
+		return nil
+	}
+	serialized, err := c.block.Bytes()
+	if err != nil {
+		return nil
+	}
+
+	// Find where each transaction starts from the size of the header,
+	// the transaction count and the transactions before it.
+	if c.txStart == nil {
+		msgBlock := c.block.MsgBlock()
+		numTxns := len(msgBlock.Transactions)
+		c.txStart = make([]int, numTxns+1)
+		c.txStart[0] = wire.MaxBlockHeaderPayload +
+			wire.VarIntSerializeSize(uint64(numTxns))
+		for i, msgTx := range msgBlock.Transactions {
+			c.txStart[i+1] = c.txStart[i] + msgTx.SerializeSize()
+		}
+	}
+	end := c.txStart[tx.Index()+1]
+	if end > len(serialized) {
+		return nil
+	}
+	return serialized[c.txStart[tx.Index()]:end]
+}
+
+// get returns the hex encoded serialization of tx, encoding it only the first
+// time it is requested.
+func (c *txHexes) get(tx *coinutil.Tx) string {
+	if txHex, ok := c.hexes[tx]; ok {
+		return txHex
+	}
+	var txHex string
+	if serialized := c.blockTxBytes(tx); serialized != nil {
+		txHex = hex.EncodeToString(serialized)
+	} else {
+		txHex = txHexString(tx)
+	}
+	c.hexes[tx] = txHex
+	return txHex
+}
+
 // blockDetails creates a BlockDetails struct to include in btcws notifications
 // from a block and a transaction's block index.
 func blockDetails(block *coinutil.Block, txIndex int) *btcjson.BlockDetails {
@@ -606,11 +2055,37 @@ func blockDetails(block *coinutil.Block, txIndex int) *btcjson.BlockDetails {
 }
 
 // newRedeemingTxNotification returns a new marshalled redeemingtx notification
-// with the passed parameters.
-func newRedeemingTxNotification(txHex string, index int, block *coinutil.Block) ([]byte, error) {
+// with the passed parameters, or a filteredredeemingtx notification tagged with
+// filterID if it is not empty.
+func newRedeemingTxNotification(filterID, txHex string, index int,
+	block *coinutil.Block, eventID uint64) ([]byte, error) {
+
+	// Create and marshal the notification.
+	var ntfn interface{}
+	if filterID == "" {
+		ntfn = btcjson.NewRedeemingTxNtfn(txHex, blockDetails(block, index))
+	} else {
+		ntfn = btcjson.NewFilteredRedeemingTxNtfn(filterID, txHex,
+			blockDetails(block, index))
+	}
+	return marshalNotification(ntfn, eventID)
+}
+
+// newRecvTxNotification returns a new marshalled recvtx notification with the
+// passed parameters, or a filteredrecvtx notification tagged with filterID if
+// it is not empty.
+func newRecvTxNotification(filterID, txHex string, index int,
+	block *coinutil.Block, eventID uint64) ([]byte, error) {
+
 	// Create and marshal the notification.
-	ntfn := btcjson.NewRedeemingTxNtfn(txHex, blockDetails(block, index))
-	return btcjson.MarshalCmd(nil, ntfn)
+	var ntfn interface{}
+	if filterID == "" {
+		ntfn = btcjson.NewRecvTxNtfn(txHex, blockDetails(block, index))
+	} else {
+		ntfn = btcjson.NewFilteredRecvTxNtfn(filterID, txHex,
+			blockDetails(block, index))
+	}
+	return marshalNotification(ntfn, eventID)
 }
 
 // notifyForTxOuts examines each transaction output, notifying interested
@@ -618,14 +2093,17 @@ func newRedeemingTxNotification(txHex string, index int, block *coinutil.Block)
 // address.  A spent notification request is automatically registered for
 // the client for each matching output.
 func (m *wsNotificationManager) notifyForTxOuts(ops map[wire.OutPoint]map[chan struct{}]*wsClient,
-	addrs map[string]map[chan struct{}]*wsClient, tx *coinutil.Tx, block *coinutil.Block) {
+	addrs map[string]map[chan struct{}]*wsClient, tx *coinutil.Tx, block *coinutil.Block,
+	hexes *txHexes) {
 
 	// Nothing to do if nobody is listening for address notifications.
 	if len(addrs) == 0 {
 		return
 	}
 
-	txHex := ""
+	// The notification is the same for every matching output, so it is
+	// only marshalled once.
+	var marshalledJSON []byte
 	wscNotified := make(map[chan struct{}]struct{})
 	for i, txOut := range tx.MsgTx().TxOut {
 		_, txAddrs, _, err := txscript.ExtractPkScriptAddrs(
@@ -640,22 +2118,25 @@ func (m *wsNotificationManager) notifyForTxOuts(ops map[wire.OutPoint]map[chan s
 				continue
 			}
 
-			if txHex == "" {
-				txHex = txHexString(tx)
-			}
-			ntfn := btcjson.NewRecvTxNtfn(txHex, blockDetails(block,
-				tx.Index()))
-
-			marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
-			if err != nil {
-				rpcsLog.Errorf("Failed to marshal processedtx notification: %v", err)
-				continue
+			if marshalledJSON == nil {
+				marshalledJSON, err = newRecvTxNotification("",
+					hexes.get(tx), tx.Index(), block, m.eventID)
+				if err != nil {
+					rpcsLog.Errorf("Failed to marshal processedtx notification: %v", err)
+					continue
+				}
 			}
 
 			op := []*wire.OutPoint{wire.NewOutPoint(tx.Sha(), uint32(i))}
 			for wscQuit, wsc := range cmap {
 				m.addSpentRequests(ops, wsc, op)
 
+				// Clients which opted into confirmed
+				// notifications only hear about the
+				// transaction once it is mined.
+				if block == nil && wsc.confirmedRecvTx {
+					continue
+				}
 				if _, ok := wscNotified[wscQuit]; !ok {
 					wscNotified[wscQuit] = struct{}{}
 					wsc.QueueNotification(marshalledJSON)
@@ -669,13 +2150,14 @@ func (m *wsNotificationManager) notifyForTxOuts(ops map[wire.OutPoint]map[chan s
 // notifying websocket clients of outputs spending to a watched address
 // and inputs spending a watched outpoint.
 func (m *wsNotificationManager) notifyForTx(ops map[wire.OutPoint]map[chan struct{}]*wsClient,
-	addrs map[string]map[chan struct{}]*wsClient, tx *coinutil.Tx, block *coinutil.Block) {
+	addrs map[string]map[chan struct{}]*wsClient, tx *coinutil.Tx, block *coinutil.Block,
+	hexes *txHexes) {
 
 	if len(ops) != 0 {
-		m.notifyForTxIns(ops, tx, block)
+		m.notifyForTxIns(ops, tx, block, hexes)
 	}
 	if len(addrs) != 0 {
-		m.notifyForTxOuts(ops, addrs, tx, block)
+		m.notifyForTxOuts(ops, addrs, tx, block, hexes)
 	}
 }
 
@@ -684,25 +2166,28 @@ func (m *wsNotificationManager) notifyForTx(ops map[wire.OutPoint]map[chan struc
 // spend a watched output.  If block is non-nil, any matching spent
 // requests are removed.
 func (m *wsNotificationManager) notifyForTxIns(ops map[wire.OutPoint]map[chan struct{}]*wsClient,
-	tx *coinutil.Tx, block *coinutil.Block) {
+	tx *coinutil.Tx, block *coinutil.Block, hexes *txHexes) {
 
 	// Nothing to do if nobody is watching outpoints.
 	if len(ops) == 0 {
 		return
 	}
 
-	txHex := ""
+	// The notification is the same for every matching input, so it is
+	// only marshalled once.
+	var marshalledJSON []byte
 	wscNotified := make(map[chan struct{}]struct{})
 	for _, txIn := range tx.MsgTx().TxIn {
 		prevOut := &txIn.PreviousOutPoint
 		if cmap, ok := ops[*prevOut]; ok {
-			if txHex == "" {
-				txHex = txHexString(tx)
-			}
-			marshalledJSON, err := newRedeemingTxNotification(txHex, tx.Index(), block)
-			if err != nil {
-				rpcsLog.Warnf("Failed to marshal redeemingtx notification: %v", err)
-				continue
+			if marshalledJSON == nil {
+				var err error
+				marshalledJSON, err = newRedeemingTxNotification("",
+					hexes.get(tx), tx.Index(), block, m.eventID)
+				if err != nil {
+					rpcsLog.Warnf("Failed to marshal redeemingtx notification: %v", err)
+					continue
+				}
 			}
 			for wscQuit, wsc := range cmap {
 				if block != nil {
@@ -718,13 +2203,60 @@ func (m *wsNotificationManager) notifyForTxIns(ops map[wire.OutPoint]map[chan st
 	}
 }
 
+// notifyFilteredTx examines the inputs and outputs of the passed transaction
+// against every transaction filter loaded by the passed websocket clients,
+// sending filteredredeemingtx and filteredrecvtx notifications tagged with the
+// id of each matching filter.  Outputs paying to a filter are added to its
+// watched outpoints.  Clients which opted into confirmed recvtx notifications
+// are not sent filteredrecvtx notifications for mempool transactions.
+func (m *wsNotificationManager) notifyFilteredTx(clients map[chan struct{}]*wsClient,
+	tx *coinutil.Tx, block *coinutil.Block, hexes *txHexes) {
+
+	for _, wsc := range clients {
+		for _, f := range wsc.loadedFilters() {
+			spends, receives := f.matchTx(tx, block != nil,
+				m.server.server.chainParams)
+			if !spends && !receives {
+				continue
+			}
+
+			txHex := hexes.get(tx)
+			if spends {
+				marshalledJSON, err := newRedeemingTxNotification(
+					f.id, txHex, tx.Index(), block, m.eventID)
+				if err != nil {
+					rpcsLog.Warnf("Failed to marshal "+
+						"filteredredeemingtx notification: %v",
+						err)
+				} else {
+					wsc.QueueNotification(marshalledJSON)
+				}
+			}
+			if receives && (block != nil || !wsc.confirmedRecvTx) {
+				marshalledJSON, err := newRecvTxNotification(
+					f.id, txHex, tx.Index(), block, m.eventID)
+				if err != nil {
+					rpcsLog.Warnf("Failed to marshal "+
+						"filteredrecvtx notification: %v", err)
+				} else {
+					wsc.QueueNotification(marshalledJSON)
+				}
+			}
+		}
+	}
+}
+
 // RegisterTxOutAddressRequests requests notifications to the passed websocket
-// client when a transaction output spends to the passed address.
-func (m *wsNotificationManager) RegisterTxOutAddressRequests(wsc *wsClient, addrs []string) {
-	m.queueNotification <- &notificationRegisterAddr{
+// client when a transaction output spends to the passed address.  An RPC error
+// is returned and none of the addresses are registered when the client would
+// watch more addresses than allowed.
+func (m *wsNotificationManager) RegisterTxOutAddressRequests(wsc *wsClient, addrs []string) error {
+	n := &notificationRegisterAddr{
 		wsc:   wsc,
 		addrs: addrs,
+		err:   make(chan error, 1),
 	}
+	return m.queueAndWait(n, n.err)
 }
 
 // addAddrRequests adds the websocket client wsc to the address to client set
@@ -783,6 +2315,177 @@ func (*wsNotificationManager) removeAddrRequest(addrs map[string]map[chan struct
 	}
 }
 
+// scriptHash returns the hash of the passed output script which identifies it
+// in script hash subscriptions.  It is the SHA256 hash of the script, whose
+// string form uses the reversed byte order of the Electrum protocol.
+func scriptHash(pkScript []byte) wire.ShaHash {
+	return wire.ShaHash(fastsha256.Sum256(pkScript))
+}
+
+// RegisterScriptHashRequests requests notifications to the passed websocket
+// client when a transaction pays to or spends from an output script with one
+// of the passed script hashes.  Script hashes count as addresses towards the
+// limit of watched addresses, and an RPC error is returned and none of them are
+// registered when the client would watch more addresses than allowed.
+func (m *wsNotificationManager) RegisterScriptHashRequests(wsc *wsClient, scriptHashes []wire.ShaHash) error {
+	n := &notificationRegisterScriptHashes{
+		wsc:          wsc,
+		scriptHashes: scriptHashes,
+		err:          make(chan error, 1),
+	}
+	return m.queueAndWait(n, n.err)
+}
+
+// addScriptHashRequests adds the websocket client wsc to the script hash to
+// client set shMap so wsc will be notified for any mempool or block
+// transaction paying to or spending from a script with one of the passed
+// script hashes.
+func (*wsNotificationManager) addScriptHashRequests(shMap map[wire.ShaHash]map[chan struct{}]*wsClient,
+	wsc *wsClient, scriptHashes []wire.ShaHash) {
+
+	for _, sh := range scriptHashes {
+		// Track the request in the client as well so it can be quickly
+		// be removed on disconnect.
+		wsc.scriptHashRequests[sh] = struct{}{}
+
+		// Add the client to the set of clients to notify when the
+		// script is seen.  Create the map as needed.
+		cmap, ok := shMap[sh]
+		if !ok {
+			cmap = make(map[chan struct{}]*wsClient)
+			shMap[sh] = cmap
+		}
+		cmap[wsc.quit] = wsc
+	}
+}
+
+// UnregisterScriptHashRequests removes the requests from the passed websocket
+// client to be notified about transactions touching the scripts with the
+// passed script hashes.
+func (m *wsNotificationManager) UnregisterScriptHashRequests(wsc *wsClient, scriptHashes []wire.ShaHash) {
+	m.queueNotification <- &notificationUnregisterScriptHashes{
+		wsc:          wsc,
+		scriptHashes: scriptHashes,
+	}
+}
+
+// removeScriptHashRequest removes the websocket client wsc from the script
+// hash to client set shMap so it will no longer be notified about
+// transactions touching the script with the passed script hash.
+func (*wsNotificationManager) removeScriptHashRequest(shMap map[wire.ShaHash]map[chan struct{}]*wsClient,
+	wsc *wsClient, sh *wire.ShaHash) {
+
+	// Remove the request tracking from the client.
+	delete(wsc.scriptHashRequests, *sh)
+
+	// Remove the client from the list to notify.
+	cmap, ok := shMap[*sh]
+	if !ok {
+		rpcsLog.Warnf("Attempt to remove nonexistent script hash "+
+			"request %v for websocket client %s", sh, wsc.addr)
+		return
+	}
+	delete(cmap, wsc.quit)
+
+	// Remove the map entry altogether if there are no more clients
+	// interested in it.
+	if len(cmap) == 0 {
+		delete(shMap, *sh)
+	}
+}
+
+// spentPkScript returns the output script of the passed outpoint.  The output
+// is looked up in the passed transactions of the block being notified, the
+// memory pool and the block database in that order.  Nil is returned when the
+// output can't be found.
+func (m *wsNotificationManager) spentPkScript(op *wire.OutPoint, blockTxs map[wire.ShaHash]*coinutil.Tx) []byte {
+	var msgTx *wire.MsgTx
+	if tx, ok := blockTxs[op.Hash]; ok {
+		msgTx = tx.MsgTx()
+	} else if tx, err := m.server.server.txMemPool.FetchTransaction(&op.Hash); err == nil {
+		msgTx = tx.MsgTx()
+	} else {
+		replies, err := m.server.server.db.FetchTxBySha(&op.Hash)
+		if err != nil || len(replies) == 0 {
+			return nil
+		}
+		msgTx = replies[len(replies)-1].Tx
+	}
+
+	if op.Index >= uint32(len(msgTx.TxOut)) {
+		return nil
+	}
+	return msgTx.TxOut[op.Index].PkScript
+}
+
+// notifyForScriptHashes sends a scripthashtx notification to the websocket
+// clients subscribed to the hash of any output script the passed transaction
+// pays to or spends from.  A client subscribed to several of the touched
+// scripts receives one notification for each of them.  Clients which opted
+// into confirmed recvtx notifications are only notified about mempool
+// transactions spending from the scripts.  The transactions of the block being
+// notified are passed in blockTxs so the outputs they create and spend within
+// the block can be found.
+func (m *wsNotificationManager) notifyForScriptHashes(scriptHashes map[wire.ShaHash]map[chan struct{}]*wsClient,
+	tx *coinutil.Tx, block *coinutil.Block, blockTxs map[wire.ShaHash]*coinutil.Tx,
+	hexes *txHexes) {
+
+	// Nothing to do if nobody is subscribed to a script hash.
+	if len(scriptHashes) == 0 {
+		return
+	}
+
+	// spentFrom holds the matched script hashes the transaction spends
+	// from rather than only pays to.
+	var matched []wire.ShaHash
+	spentFrom := make(map[wire.ShaHash]struct{})
+	addMatch := func(pkScript []byte, spends bool) {
+		sh := scriptHash(pkScript)
+		if _, ok := scriptHashes[sh]; !ok {
+			return
+		}
+		if spends {
+			spentFrom[sh] = struct{}{}
+		}
+		for i := range matched {
+			if matched[i] == sh {
+				return
+			}
+		}
+		matched = append(matched, sh)
+	}
+	for _, txOut := range tx.MsgTx().TxOut {
+		addMatch(txOut.PkScript, false)
+	}
+	if !blockchain.IsCoinBase(tx) {
+		for _, txIn := range tx.MsgTx().TxIn {
+			pkScript := m.spentPkScript(&txIn.PreviousOutPoint,
+				blockTxs)
+			if pkScript != nil {
+				addMatch(pkScript, true)
+			}
+		}
+	}
+
+	for i := range matched {
+		ntfn := btcjson.NewScriptHashTxNtfn(matched[i].String(),
+			hexes.get(tx), blockDetails(block, tx.Index()))
+		marshalledJSON, err := marshalNotification(ntfn, m.eventID)
+		if err != nil {
+			rpcsLog.Errorf("Failed to marshal scripthashtx "+
+				"notification: %v", err)
+			continue
+		}
+		_, spends := spentFrom[matched[i]]
+		for _, wsc := range scriptHashes[matched[i]] {
+			if block == nil && !spends && wsc.confirmedRecvTx {
+				continue
+			}
+			wsc.QueueNotification(marshalledJSON)
+		}
+	}
+}
+
 // AddClient adds the passed websocket client to the notification manager.
 func (m *wsNotificationManager) AddClient(wsc *wsClient) {
 	m.queueNotification <- (*notificationRegisterClient)(wsc)
@@ -800,9 +2503,12 @@ func (m *wsNotificationManager) RemoveClient(wsc *wsClient) {
 // Start starts the goroutines required for the manager to queue and process
 // websocket client notifications.
 func (m *wsNotificationManager) Start() {
-	m.wg.Add(2)
+	m.wg.Add(2 + wsFanOutWorkers)
 	go m.queueHandler()
 	go m.notificationHandler()
+	for i := 0; i < wsFanOutWorkers; i++ {
+		go m.fanOutHandler()
+	}
 }
 
 // WaitForShutdown blocks until all notification manager goroutines have
@@ -825,6 +2531,7 @@ func newWsNotificationManager(server *rpcServer) *wsNotificationManager {
 		queueNotification: make(chan interface{}),
 		notificationMsgs:  make(chan interface{}),
 		numClients:        make(chan int),
+		fanOut:            make(chan *fanOutBatch),
 		quit:              make(chan struct{}),
 	}
 }
@@ -833,6 +2540,7 @@ func newWsNotificationManager(server *rpcServer) *wsNotificationManager {
 // well as a channel to reply on when the message is sent.
 type wsResponse struct {
 	msg      []byte
+	binary   bool
 	doneChan chan bool
 }
 
@@ -875,6 +2583,12 @@ type wsClient struct {
 	// false means its access is only to the limited set of RPC calls.
 	isAdmin bool
 
+	// confirmedRecvTx specifies whether the client opted to only receive
+	// recvtx notifications for transactions once they are mined rather than
+	// both when they are accepted into the memory pool and when they are
+	// mined.  It is set when the client connects.
+	confirmedRecvTx bool
+
 	// sessionID is a random ID generated for each client when connected.
 	// These IDs may be queried by a client using the session RPC.  A change
 	// to the session ID indicates that the client reconnected.
@@ -889,11 +2603,30 @@ type wsClient struct {
 	// when a wallet disconnects.  Owned by the notification manager.
 	addrRequests map[string]struct{}
 
+	// scriptHashRequests is a set of script hashes the client has
+	// subscribed to.  It is maintained here so all subscriptions can be
+	// removed when the client disconnects.  Owned by the notification
+	// manager.
+	scriptHashRequests map[wire.ShaHash]struct{}
+
 	// spentRequests is a set of unspent Outpoints a wallet has requested
 	// notifications for when they are spent by a processed transaction.
 	// Owned by the notification manager.
 	spentRequests map[wire.OutPoint]struct{}
 
+	// confirmationRequests maps the hashes of the transactions the client
+	// requested a txconfirmed notification for to the number of
+	// confirmations it waits for.  Owned by the notification manager.
+	confirmationRequests map[wire.ShaHash]int32
+
+	// filters maps the ids of the transaction filters loaded by the client
+	// to the filters.  It is protected by the embedded mutex.
+	filters map[string]*wsClientFilter
+
+	// rescans holds the rescan jobs started by the client with the
+	// startrescan command.
+	rescans *rescanJobs
+
 	// Networking infrastructure.
 	asyncStarted bool
 	asyncChan    chan *parsedRPCCmd
@@ -901,6 +2634,12 @@ type wsClient struct {
 	sendChan     chan wsResponse
 	quit         chan struct{}
 	wg           sync.WaitGroup
+
+	// ctx is canceled when the client disconnects.  It is passed to the
+	// handlers of the commands of the client so they stop working on
+	// requests nobody waits for anymore.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // handleMessage is the main handler for incoming requests.  It enforces
@@ -933,17 +2672,24 @@ func (c *wsClient) handleMessage(msg []byte) {
 			return
 		}
 
-		// Check credentials.
+		// Check credentials unless the client address is locked out
+		// after failing to authenticate too often.
+		limiter := c.server.authLimiter
+		if err := limiter.Allow(c.addr, "websocket"); err != nil {
+			c.Disconnect()
+			return
+		}
 		login := authCmd.Username + ":" + authCmd.Passphrase
 		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
 		authSha := fastsha256.Sum256([]byte(auth))
 		cmp := subtle.ConstantTimeCompare(authSha[:], c.server.authsha[:])
 		limitcmp := subtle.ConstantTimeCompare(authSha[:], c.server.limitauthsha[:])
 		if cmp != 1 && limitcmp != 1 {
-			rpcsLog.Warnf("Auth failure.")
+			limiter.Failure(c.addr, "websocket")
 			c.Disconnect()
 			return
 		}
+		limiter.Success(c.addr)
 		c.authenticated = true
 		c.isAdmin = cmp == 1
 
@@ -1025,6 +2771,15 @@ func (c *wsClient) handleMessage(msg []byte) {
 		return
 	}
 
+	// Reject the command when the client exceeds its request rate limits.
+	// Long-running commands only reserve a slot for expensive requests
+	// once they are run by the async handler.
+	limiter := c.server.rateLimiter
+	if err := limiter.Allow(c.addr, cmd.method); err != nil {
+		c.sendErrorReply(cmd, err)
+		return
+	}
+
 	// When the command is marked as a long-running command, send it off
 	// to the asyncHander goroutine for processing.
 	if _, ok := wsAsyncHandlers[cmd.method]; ok {
@@ -1040,13 +2795,20 @@ func (c *wsClient) handleMessage(msg []byte) {
 		return
 	}
 
+	release, err := limiter.AcquireSlot(cmd.method)
+	if err != nil {
+		c.sendErrorReply(cmd, err)
+		return
+	}
+	defer release()
+
 	// Lookup the websocket extension for the command and if it doesn't
 	// exist fallback to handling the command as a standard command.
 	wsHandler, ok := wsHandlers[cmd.method]
 	if !ok {
 		// No websocket-specific handler so handle like a legacy
 		// RPC connection.
-		result, jsonErr := c.server.standardCmdResult(cmd, nil)
+		result, jsonErr := c.server.standardCmdResult(cmd, c.ctx)
 		reply, err := createMarshalledReply(cmd.id, result, jsonErr)
 		if err != nil {
 			rpcsLog.Errorf("Failed to marshal reply for <%s> "+
@@ -1069,6 +2831,18 @@ func (c *wsClient) handleMessage(msg []byte) {
 	c.SendMessage(reply, nil)
 }
 
+// sendErrorReply sends a reply to the passed command which reports the passed
+// error.
+func (c *wsClient) sendErrorReply(cmd *parsedRPCCmd, jsonErr error) {
+	reply, err := createMarshalledReply(cmd.id, nil, jsonErr)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal reply for <%s> command: %v",
+			cmd.method, err)
+		return
+	}
+	c.SendMessage(reply, nil)
+}
+
 // inHandler handles all incoming messages for the websocket connection.  It
 // must be run as a goroutine.
 func (c *wsClient) inHandler() {
@@ -1094,10 +2868,55 @@ out:
 		c.handleMessage(msg)
 	}
 
-	// Ensure the connection is closed.
-	c.Disconnect()
-	c.wg.Done()
-	rpcsLog.Tracef("Websocket client input handler done for %s", c.addr)
+	// Ensure the connection is closed.
+	c.Disconnect()
+	c.wg.Done()
+	rpcsLog.Tracef("Websocket client input handler done for %s", c.addr)
+}
+
+// addNotificationField returns the passed marshalled notification with the
+// passed numeric field added.  Notifications which aren't a JSON object are
+// returned unchanged.
+func addNotificationField(marshalledJSON []byte, field string, value uint64) []byte {
+	n := len(marshalledJSON)
+	if n < 2 || marshalledJSON[0] != '{' || marshalledJSON[n-1] != '}' {
+		return marshalledJSON
+	}
+	stamped := make([]byte, 0, n+len(field)+24)
+	stamped = append(stamped, marshalledJSON[:n-1]...)
+
+	// Only separate the field from existing ones.
+	if len(bytes.TrimSpace(marshalledJSON[1:n-1])) != 0 {
+		stamped = append(stamped, ',')
+	}
+	stamped = append(stamped, '"')
+	stamped = append(stamped, field...)
+	stamped = append(stamped, '"', ':')
+	stamped = strconv.AppendUint(stamped, value, 10)
+	return append(stamped, '}')
+}
+
+// ntfnEventIDs is the number of event ids handed out so far.  It must be
+// accessed atomically.
+var ntfnEventIDs uint64
+
+// newNtfnEventID returns a new event id.  Event ids are unique for the lifetime
+// of the server.
+func newNtfnEventID() uint64 {
+	return atomic.AddUint64(&ntfnEventIDs, 1)
+}
+
+// marshalNotification marshals the passed websocket notification and adds the
+// passed event id to it as its eventid field.  Every notification sent about
+// the same event must be marshalled with the same id, obtained once from
+// newNtfnEventID, so clients can use it to recognize the notifications of an
+// event they receive more than once, such as over several connections.
+func marshalNotification(ntfn interface{}, eventID uint64) ([]byte, error) {
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		return nil, err
+	}
+	return addNotificationField(marshalledJSON, "eventid", eventID), nil
 }
 
 // notificationQueueHandler handles the queueing of outgoing notifications for
@@ -1106,8 +2925,12 @@ out:
 // slow clients could bog down the other systems (such as the mempool or block
 // manager) which are queueing the data.  The data is passed on to outHandler to
 // actually be written.  It must be run as a goroutine.
+//
+// Each notification is numbered in the order it is queued, starting at 1 for
+// every connection, so clients can detect notifications they missed.
 func (c *wsClient) notificationQueueHandler() {
 	ntfnSentChan := make(chan bool, 1) // nonblocking sync
+	var seq uint64
 
 	// pendingNtfns is used as a queue for notifications that are ready to
 	// be sent once there are no outstanding notifications currently being
@@ -1118,8 +2941,24 @@ func (c *wsClient) notificationQueueHandler() {
 	// future, not knowing what has and hasn't been sent to the outHandler
 	// (and thus who should respond to the done channel) would be
 	// problematic without using this approach.
-	pendingNtfns := list.New()
+	//
+	// When notification batching is enabled, the pending notifications
+	// are combined into frames of up to the configured number of bytes.
+	// A notification arriving while nothing is being sent is then held
+	// back for up to the configured batch interval so more notifications
+	// can join its frame.
+	pendingNtfns := newWsNotificationQueue(cfg.RPCWSBatchBytes)
+	batchInterval := cfg.RPCWSBatchInterval
+	if cfg.RPCWSBatchBytes == 0 {
+		batchInterval = 0
+	}
+	var flushTimer <-chan time.Time
 	waiting := false
+	flush := func() {
+		flushTimer = nil
+		c.SendMessage(pendingNtfns.nextFrame(), ntfnSentChan)
+		waiting = true
+	}
 out:
 	for {
 		select {
@@ -1129,28 +2968,37 @@ out:
 		// queue the message to be sent once the other pending messages
 		// are sent.
 		case msg := <-c.ntfnChan:
-			if !waiting {
-				c.SendMessage(msg, ntfnSentChan)
-			} else {
-				pendingNtfns.PushBack(msg)
+			seq++
+			pendingNtfns.push(addNotificationField(msg, "seq", seq))
+			if waiting {
+				continue
 			}
-			waiting = true
+			if batchInterval > 0 && !pendingNtfns.full() {
+				if flushTimer == nil {
+					flushTimer = time.After(batchInterval)
+				}
+				continue
+			}
+			flush()
+
+		// This channel is notified when the batch interval of the
+		// pending notifications has passed.
+		case <-flushTimer:
+			flush()
 
 		// This channel is notified when a notification has been sent
 		// across the network socket.
 		case <-ntfnSentChan:
 			// No longer waiting if there are no more messages in
 			// the pending messages queue.
-			next := pendingNtfns.Front()
-			if next == nil {
+			if pendingNtfns.len() == 0 {
 				waiting = false
 				continue
 			}
 
 			// Notify the outHandler about the next item to
 			// asynchronously send.
-			msg := pendingNtfns.Remove(next).([]byte)
-			c.SendMessage(msg, ntfnSentChan)
+			flush()
 
 		case <-c.quit:
 			break out
@@ -1173,6 +3021,78 @@ cleanup:
 		"for %s", c.addr)
 }
 
+// wsNotificationQueue queues the notifications of a websocket client which are
+// waiting to be sent.  When batching is enabled, consecutive notifications are
+// combined into a single frame holding a JSON array of them, which saves a
+// write for every notification joining a frame.
+type wsNotificationQueue struct {
+	pending   *list.List
+	size      int
+	batchSize int
+}
+
+// newWsNotificationQueue returns a new empty notification queue which combines
+// notifications into frames of up to batchSize bytes.  Batching is disabled
+// when batchSize is zero.
+func newWsNotificationQueue(batchSize int) *wsNotificationQueue {
+	return &wsNotificationQueue{
+		pending:   list.New(),
+		batchSize: batchSize,
+	}
+}
+
+// push adds the passed marshalled notification to the end of the queue.
+func (q *wsNotificationQueue) push(msg []byte) {
+	q.pending.PushBack(msg)
+	q.size += len(msg)
+}
+
+// len returns the number of queued notifications.
+func (q *wsNotificationQueue) len() int {
+	return q.pending.Len()
+}
+
+// full returns whether the queued notifications fill at least a whole frame,
+// so there is no point in waiting for more of them.  It always returns false
+// when batching is disabled.
+func (q *wsNotificationQueue) full() bool {
+	return q.batchSize > 0 && q.size >= q.batchSize
+}
+
+// pop removes the notification at the front of the queue and returns it.
+func (q *wsNotificationQueue) pop() []byte {
+	msg := q.pending.Remove(q.pending.Front()).([]byte)
+	q.size -= len(msg)
+	return msg
+}
+
+// nextFrame removes the notifications to send in the next frame from the front
+// of the queue and returns the frame.  A notification is sent on its own when
+// batching is disabled or no other notification fits into the frame with it.
+// Otherwise, the frame is a JSON array of as many notifications as fit into
+// the batch size.  The queue must not be empty.
+func (q *wsNotificationQueue) nextFrame() []byte {
+	msg := q.pop()
+	fits := func(frameLen int) bool {
+		next := q.pending.Front()
+		// Account for the separating comma and closing bracket.
+		return next != nil && frameLen+len(next.Value.([]byte))+2 <=
+			q.batchSize
+	}
+	if q.batchSize == 0 || !fits(len(msg)+1) {
+		return msg
+	}
+
+	frame := make([]byte, 0, q.batchSize)
+	frame = append(frame, '[')
+	frame = append(frame, msg...)
+	for fits(len(frame)) {
+		frame = append(frame, ',')
+		frame = append(frame, q.pop()...)
+	}
+	return append(frame, ']')
+}
+
 // outHandler handles all outgoing messages for the websocket connection.  It
 // must be run as a goroutine.  It uses a buffered channel to serialize output
 // messages while allowing the sender to continue running asynchronously.  It
@@ -1184,7 +3104,11 @@ out:
 		// closed.
 		select {
 		case r := <-c.sendChan:
-			err := c.conn.WriteMessage(websocket.TextMessage, r.msg)
+			messageType := websocket.TextMessage
+			if r.binary {
+				messageType = websocket.BinaryMessage
+			}
+			err := c.conn.WriteMessage(messageType, r.msg)
 			if err != nil {
 				c.Disconnect()
 				break out
@@ -1237,6 +3161,13 @@ func (c *wsClient) asyncHandler() {
 			return
 		}
 
+		release, err := c.server.rateLimiter.AcquireSlot(parsedCmd.method)
+		if err != nil {
+			c.sendErrorReply(parsedCmd, err)
+			return
+		}
+		defer release()
+
 		// Invoke the handler and marshal and send response.
 		result, jsonErr := wsHandler(c, parsedCmd.cmd)
 		reply, err := createMarshalledReply(parsedCmd.id, result,
@@ -1327,6 +3258,24 @@ func (c *wsClient) SendMessage(marshalledJSON []byte, doneChan chan bool) {
 // to the client having already been disconnected or dropped.
 var ErrClientQuit = errors.New("client quit")
 
+// SendFrame sends the passed frame to the websocket client as a binary or text
+// message and blocks until it has been written.  Unlike QueueNotification,
+// this throttles callers streaming large amounts of data to the rate the
+// client reads it.  ErrClientQuit is returned when the client disconnects
+// before the frame is written.
+func (c *wsClient) SendFrame(frame []byte, binary bool) error {
+	doneChan := make(chan bool, 1)
+	select {
+	case c.sendChan <- wsResponse{msg: frame, binary: binary, doneChan: doneChan}:
+	case <-c.quit:
+		return ErrClientQuit
+	}
+	if !<-doneChan {
+		return ErrClientQuit
+	}
+	return nil
+}
+
 // QueueNotification queues the passed notification to be sent to the websocket
 // client.  This function, as the name implies, is only intended for
 // notifications since it has additional logic to prevent other subsystems, such
@@ -1366,6 +3315,7 @@ func (c *wsClient) Disconnect() {
 
 	rpcsLog.Tracef("Disconnecting websocket client %s", c.addr)
 	close(c.quit)
+	c.cancel()
 	c.conn.Close()
 	c.disconnected = true
 }
@@ -1394,26 +3344,35 @@ func (c *wsClient) WaitForShutdown() {
 // incoming and outgoing messages in separate goroutines complete with queueing
 // and asynchrous handling for long-running operations.
 func newWebsocketClient(server *rpcServer, conn *websocket.Conn,
-	remoteAddr string, authenticated bool, isAdmin bool) (*wsClient, error) {
+	remoteAddr string, authenticated bool, isAdmin bool,
+	confirmedRecvTx bool) (*wsClient, error) {
 
 	sessionID, err := wire.RandomUint64()
 	if err != nil {
 		return nil, err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	client := &wsClient{
-		conn:          conn,
-		addr:          remoteAddr,
-		authenticated: authenticated,
-		isAdmin:       isAdmin,
-		sessionID:     sessionID,
-		server:        server,
-		addrRequests:  make(map[string]struct{}),
-		spentRequests: make(map[wire.OutPoint]struct{}),
-		ntfnChan:      make(chan []byte, 1),        // nonblocking sync
-		asyncChan:     make(chan *parsedRPCCmd, 1), // nonblocking sync
-		sendChan:      make(chan wsResponse, websocketSendBufferSize),
-		quit:          make(chan struct{}),
+		conn:                 conn,
+		addr:                 remoteAddr,
+		authenticated:        authenticated,
+		isAdmin:              isAdmin,
+		confirmedRecvTx:      confirmedRecvTx,
+		sessionID:            sessionID,
+		server:               server,
+		addrRequests:         make(map[string]struct{}),
+		scriptHashRequests:   make(map[wire.ShaHash]struct{}),
+		spentRequests:        make(map[wire.OutPoint]struct{}),
+		confirmationRequests: make(map[wire.ShaHash]int32),
+		filters:              make(map[string]*wsClientFilter),
+		rescans:              newRescanJobs(),
+		ntfnChan:             make(chan []byte, 1),        // nonblocking sync
+		asyncChan:            make(chan *parsedRPCCmd, 1), // nonblocking sync
+		sendChan:             make(chan wsResponse, websocketSendBufferSize),
+		quit:                 make(chan struct{}),
+		ctx:                  ctx,
+		cancel:               cancel,
 	}
 	return client, nil
 }
@@ -1472,12 +3431,215 @@ func handleNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
 	return nil, nil
 }
 
+// handleNotifyBlockHeaders implements the notifyblockheaders command extension
+// for websocket connections.
+func handleNotifyBlockHeaders(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterHeaderUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyBlockTemplate implements the notifyblocktemplate command
+// extension for websocket connections.  The request is interpreted the same
+// way as the template request of getblocktemplate.
+func handleNotifyBlockTemplate(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyBlockTemplateCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	if cmd.Request != nil && cmd.Request.Mode != "" &&
+		cmd.Request.Mode != "template" {
+
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid mode",
+		}
+	}
+	if cmd.Request != nil && cmd.Request.LongPollID != "" {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Long polling is not supported for notifications",
+		}
+	}
+
+	useCoinbaseValue := gbtUseCoinbaseValue(cmd.Request)
+	opts, err := gbtCoinbaseOptions(cmd.Request)
+	if err != nil {
+		return nil, err
+	}
+	if requiresPayAddress(useCoinbaseValue, opts) && len(cfg.miningAddrs) == 0 {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInternal.Code,
+			Message: "A coinbase transaction has been requested, " +
+				"but the server has not been configured with " +
+				"any payment addresses via --miningaddr",
+		}
+	}
+
+	wsc.server.templateNtfn.Subscribe(&templateSubscription{
+		wsc:              wsc,
+		useCoinbaseValue: useCoinbaseValue,
+		opts:             opts,
+		auditSource:      gbtAuditSource(cmd.Request),
+	})
+	return nil, nil
+}
+
+// handleNotifyClockSkew implements the notifyclockskew command extension for
+// websocket connections.
+func handleNotifyClockSkew(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterClockSkewUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyPeerEvents implements the notifypeerevents command extension
+// for websocket connections.
+func handleNotifyPeerEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterPeerEventUpdates(wsc)
+	return nil, nil
+}
+
+// handleGetSubscriptions implements the getsubscriptions command extension for
+// websocket connections.
+func handleGetSubscriptions(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	result := &btcjson.GetSubscriptionsResult{
+		BlockTemplate: wsc.server.templateNtfn.Subscribed(wsc),
+		RawBlocks:     wsc.server.rawBlockNtfn.Subscribed(wsc),
+		Addresses:     []string{},
+		OutPoints:     []btcjson.OutPoint{},
+		ScriptHashes:  []string{},
+		Filters:       []btcjson.TxFilterSubscription{},
+		Confirmations: []btcjson.ConfirmationsSubscription{},
+	}
+	if subs := wsc.server.ntfnMgr.Subscriptions(wsc); len(subs) == 1 {
+		sub := subs[0]
+		result.Blocks = sub.blocks
+		result.BlockHeaders = sub.headers
+		result.ClockSkew = sub.clockSkew
+		result.PeerEvents = sub.peerEvents
+		result.Mempool = sub.mempool
+		result.NewTransactions = sub.newTxs
+		result.VerboseTransactions = sub.newTxs && sub.verboseTxs
+		result.Addresses = append(result.Addresses, sub.addrs...)
+		sort.Strings(result.Addresses)
+		for _, op := range sub.outPoints {
+			result.OutPoints = append(result.OutPoints,
+				btcjson.OutPoint{
+					Hash:  op.Hash.String(),
+					Index: op.Index,
+				})
+		}
+		sort.Sort(outPointsByHash(result.OutPoints))
+		for _, scriptHash := range sub.scriptHashes {
+			result.ScriptHashes = append(result.ScriptHashes,
+				scriptHash.String())
+		}
+		sort.Strings(result.ScriptHashes)
+		for txSha, confirmations := range sub.confirmations {
+			result.Confirmations = append(result.Confirmations,
+				btcjson.ConfirmationsSubscription{
+					TxID:          txSha.String(),
+					Confirmations: confirmations,
+				})
+		}
+		sort.Sort(confirmationsByTxID(result.Confirmations))
+	}
+	for _, f := range wsc.loadedFilters() {
+		addrs, outPoints := f.size()
+		result.Filters = append(result.Filters,
+			btcjson.TxFilterSubscription{
+				ID:        f.id,
+				Addresses: addrs,
+				OutPoints: outPoints,
+			})
+	}
+	sort.Sort(filtersByID(result.Filters))
+	return result, nil
+}
+
+// outPointsByHash provides a sort.Interface for a slice of outpoints which
+// sorts them by their transaction hashes and output indexes.
+type outPointsByHash []btcjson.OutPoint
+
+// Len returns the number of outpoints in the slice.  It is part of the
+// sort.Interface implementation.
+func (s outPointsByHash) Len() int {
+	return len(s)
+}
+
+// Swap swaps the outpoints at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s outPointsByHash) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the outpoint with index i sorts before the outpoint with
+// index j.  It is part of the sort.Interface implementation.
+func (s outPointsByHash) Less(i, j int) bool {
+	if s[i].Hash != s[j].Hash {
+		return s[i].Hash < s[j].Hash
+	}
+	return s[i].Index < s[j].Index
+}
+
+// filtersByID provides a sort.Interface for a slice of transaction filters
+// which sorts them by their ids.
+type filtersByID []btcjson.TxFilterSubscription
+
+// Len returns the number of filters in the slice.  It is part of the
+// sort.Interface implementation.
+func (s filtersByID) Len() int {
+	return len(s)
+}
+
+// Swap swaps the filters at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s filtersByID) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the id of the filter with index i sorts before the id of
+// the filter with index j.  It is part of the sort.Interface implementation.
+func (s filtersByID) Less(i, j int) bool {
+	return s[i].ID < s[j].ID
+}
+
+// confirmationsByTxID provides a sort.Interface for a slice of confirmations
+// requests which sorts them by their transaction hashes.
+type confirmationsByTxID []btcjson.ConfirmationsSubscription
+
+// Len returns the number of requests in the slice.  It is part of the
+// sort.Interface implementation.
+func (s confirmationsByTxID) Len() int {
+	return len(s)
+}
+
+// Swap swaps the requests at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s confirmationsByTxID) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the transaction hash of the request with index i sorts
+// before the one of the request with index j.  It is part of the
+// sort.Interface implementation.
+func (s confirmationsByTxID) Less(i, j int) bool {
+	return s[i].TxID < s[j].TxID
+}
+
 // handleSession implements the session command extension for websocket
 // connections.
 func handleSession(wsc *wsClient, icmd interface{}) (interface{}, error) {
 	return &btcjson.SessionResult{SessionID: wsc.sessionID}, nil
 }
 
+// handleStopNotifyBlockHeaders implements the stopnotifyblockheaders command
+// extension for websocket connections.
+func handleStopNotifyBlockHeaders(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterHeaderUpdates(wsc)
+	return nil, nil
+}
+
 // handleStopNotifyBlocks implements the stopnotifyblocks command extension for
 // websocket connections.
 func handleStopNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
@@ -1485,6 +3647,27 @@ func handleStopNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error
 	return nil, nil
 }
 
+// handleStopNotifyBlockTemplate implements the stopnotifyblocktemplate command
+// extension for websocket connections.
+func handleStopNotifyBlockTemplate(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.templateNtfn.Unsubscribe(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyClockSkew implements the stopnotifyclockskew command
+// extension for websocket connections.
+func handleStopNotifyClockSkew(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterClockSkewUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyPeerEvents implements the stopnotifypeerevents command
+// extension for websocket connections.
+func handleStopNotifyPeerEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterPeerEventUpdates(wsc)
+	return nil, nil
+}
+
 // handleNotifySpent implements the notifyspent command extension for
 // websocket connections.
 func handleNotifySpent(wsc *wsClient, icmd interface{}) (interface{}, error) {
@@ -1498,7 +3681,53 @@ func handleNotifySpent(wsc *wsClient, icmd interface{}) (interface{}, error) {
 		return nil, err
 	}
 
-	wsc.server.ntfnMgr.RegisterSpentRequests(wsc, outpoints)
+	err = wsc.server.ntfnMgr.RegisterSpentRequests(wsc, outpoints)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handleNotifyConfirmations implements the notifyconfirmations command
+// extension for websocket connections.
+func handleNotifyConfirmations(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyConfirmationsCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	txSha, err := wire.NewShaHashFromStr(cmd.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(cmd.TxID)
+	}
+	if cmd.Confirmations < 1 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "The number of confirmations must be at least 1",
+		}
+	}
+
+	err = wsc.server.ntfnMgr.RegisterConfirmationRequest(wsc, txSha,
+		cmd.Confirmations)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handleStopNotifyConfirmations implements the stopnotifyconfirmations command
+// extension for websocket connections.
+func handleStopNotifyConfirmations(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.StopNotifyConfirmationsCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	txSha, err := wire.NewShaHashFromStr(cmd.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(cmd.TxID)
+	}
+	wsc.server.ntfnMgr.UnregisterConfirmationRequest(wsc, txSha)
 	return nil, nil
 }
 
@@ -1522,64 +3751,274 @@ func handleStopNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface
 	return nil, nil
 }
 
-// handleNotifyReceived implements the notifyreceived command extension for
-// websocket connections.
-func handleNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
-	cmd, ok := icmd.(*btcjson.NotifyReceivedCmd)
+// handleNotifyReceived implements the notifyreceived command extension for
+// websocket connections.
+func handleNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyReceivedCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	// Decode addresses to validate input, but the strings slice is used
+	// directly if these are all ok.
+	err := checkAddressValidity(cmd.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	err = wsc.server.ntfnMgr.RegisterTxOutAddressRequests(wsc, cmd.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handleStopNotifySpent implements the stopnotifyspent command extension for
+// websocket connections.
+func handleStopNotifySpent(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.StopNotifySpentCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	outpoints, err := deserializeOutpoints(cmd.OutPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, outpoint := range outpoints {
+		wsc.server.ntfnMgr.UnregisterSpentRequest(wsc, outpoint)
+	}
+
+	return nil, nil
+}
+
+// handleStopNotifyReceived implements the stopnotifyreceived command extension
+// for websocket connections.
+func handleStopNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.StopNotifyReceivedCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	// Decode addresses to validate input, but the strings slice is used
+	// directly if these are all ok.
+	err := checkAddressValidity(cmd.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range cmd.Addresses {
+		wsc.server.ntfnMgr.UnregisterTxOutAddressRequest(wsc, addr)
+	}
+
+	return nil, nil
+}
+
+// deserializeScriptHashes decodes the passed hex-encoded script hashes, which
+// use the reversed byte order of the Electrum protocol.
+func deserializeScriptHashes(serialized []string) ([]wire.ShaHash, error) {
+	scriptHashes := make([]wire.ShaHash, 0, len(serialized))
+	for _, s := range serialized {
+		if len(s) != wire.MaxHashStringSize {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid script hash: " + s,
+			}
+		}
+		sh, err := wire.NewShaHashFromStr(s)
+		if err != nil {
+			return nil, rpcDecodeHexError(s)
+		}
+		scriptHashes = append(scriptHashes, *sh)
+	}
+	return scriptHashes, nil
+}
+
+// handleSubscribeScriptHash implements the subscribescripthash command
+// extension for websocket connections.
+func handleSubscribeScriptHash(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.SubscribeScriptHashCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	scriptHashes, err := deserializeScriptHashes(cmd.ScriptHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = wsc.server.ntfnMgr.RegisterScriptHashRequests(wsc, scriptHashes)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handleUnsubscribeScriptHash implements the unsubscribescripthash command
+// extension for websocket connections.
+func handleUnsubscribeScriptHash(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.UnsubscribeScriptHashCmd)
 	if !ok {
 		return nil, btcjson.ErrRPCInternal
 	}
 
-	// Decode addresses to validate input, but the strings slice is used
-	// directly if these are all ok.
-	err := checkAddressValidity(cmd.Addresses)
+	scriptHashes, err := deserializeScriptHashes(cmd.ScriptHashes)
 	if err != nil {
 		return nil, err
 	}
 
-	wsc.server.ntfnMgr.RegisterTxOutAddressRequests(wsc, cmd.Addresses)
+	wsc.server.ntfnMgr.UnregisterScriptHashRequests(wsc, scriptHashes)
 	return nil, nil
 }
 
-// handleStopNotifySpent implements the stopnotifyspent command extension for
+// handleSubscribeMempool implements the subscribemempool command extension for
 // websocket connections.
-func handleStopNotifySpent(wsc *wsClient, icmd interface{}) (interface{}, error) {
-	cmd, ok := icmd.(*btcjson.StopNotifySpentCmd)
+func handleSubscribeMempool(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterMempoolUpdates(wsc)
+	return nil, nil
+}
+
+// handleUnsubscribeMempool implements the unsubscribemempool command extension
+// for websocket connections.
+func handleUnsubscribeMempool(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterMempoolUpdates(wsc)
+	return nil, nil
+}
+
+// handleSubscribeRawBlocks implements the subscriberawblocks command extension
+// for websocket connections.  The main chain blocks from the passed height
+// onwards are sent to the client as they are connected, either as rawblock
+// notifications or as binary frames holding only the serialized block.
+func handleSubscribeRawBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.SubscribeRawBlocksCmd)
 	if !ok {
 		return nil, btcjson.ErrRPCInternal
 	}
 
-	outpoints, err := deserializeOutpoints(cmd.OutPoints)
-	if err != nil {
-		return nil, err
+	binary := false
+	if cmd.Encoding != nil {
+		switch *cmd.Encoding {
+		case "hex":
+		case "binary":
+			binary = true
+		default:
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Unknown encoding: " + *cmd.Encoding,
+			}
+		}
 	}
 
-	for _, outpoint := range outpoints {
-		wsc.server.ntfnMgr.UnregisterSpentRequest(wsc, outpoint)
+	// Default to the blocks connected from now on.
+	_, bestHeight, err := wsc.server.server.db.NewestSha()
+	if err != nil {
+		context := "Failed to get best block"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	fromHeight := bestHeight + 1
+	if cmd.FromHeight != nil {
+		fromHeight = *cmd.FromHeight
+		if fromHeight < 0 || fromHeight > bestHeight+1 {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Height %d is out of range "+
+					"[0, %d]", fromHeight, bestHeight+1),
+			}
+		}
 	}
 
+	wsc.server.rawBlockNtfn.Subscribe(wsc, binary, fromHeight)
 	return nil, nil
 }
 
-// handleStopNotifyReceived implements the stopnotifyreceived command extension
-// for websocket connections.
-func handleStopNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
-	cmd, ok := icmd.(*btcjson.StopNotifyReceivedCmd)
+// handleUnsubscribeRawBlocks implements the unsubscriberawblocks command
+// extension for websocket connections.
+func handleUnsubscribeRawBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.rawBlockNtfn.Unsubscribe(wsc)
+	return nil, nil
+}
+
+// handleReplayEvents implements the replayevents command extension for
+// websocket connections.  All journaled events following the passed block hash
+// or sequence number are sent as replayedevent notifications, followed by a
+// replayfinished notification with the sequence number of the last event.
+func handleReplayEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.ReplayEventsCmd)
 	if !ok {
 		return nil, btcjson.ErrRPCInternal
 	}
 
-	// Decode addresses to validate input, but the strings slice is used
-	// directly if these are all ok.
-	err := checkAddressValidity(cmd.Addresses)
+	journal := wsc.server.ntfnMgr.journal
+	if journal == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The event journal is disabled -- enable it with --eventjournal",
+		}
+	}
+
+	// The since parameter is either a block hash or a decimal sequence
+	// number.  Block hashes are always 64 hex characters, which can't be
+	// mistaken for a 64-bit sequence number.
+	var events []journalEvent
+	var lastSeq uint64
+	var err error
+	if len(cmd.Since) == 2*wire.HashSize {
+		hash, hashErr := wire.NewShaHashFromStr(cmd.Since)
+		if hashErr != nil {
+			return nil, rpcDecodeHexError(cmd.Since)
+		}
+		events, lastSeq, err = journal.EventsSinceBlock(hash)
+	} else {
+		seq, parseErr := strconv.ParseUint(cmd.Since, 10, 64)
+		if parseErr != nil {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "Since must be a block hash or a " +
+					"sequence number: " + cmd.Since,
+			}
+		}
+		events, lastSeq, err = journal.EventsSinceSeq(seq)
+	}
 	if err != nil {
-		return nil, err
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: err.Error(),
+		}
 	}
 
-	for _, addr := range cmd.Addresses {
-		wsc.server.ntfnMgr.UnregisterTxOutAddressRequest(wsc, addr)
+	for i := range events {
+		e := &events[i]
+		event := btcjson.JournalEvent{
+			Sequence: e.seq,
+			Event:    journalEventMethods[e.typ],
+			Hash:     e.hash.String(),
+			Height:   e.height,
+			Time:     e.time,
+			Amount:   coinutil.Amount(e.amount).ToBTC(),
+		}
+		marshalledJSON, err := marshalNotification(
+			btcjson.NewReplayedEventNtfn(event), newNtfnEventID())
+		if err != nil {
+			rpcsLog.Errorf("Failed to marshal replayed event "+
+				"notification: %v", err)
+			continue
+		}
+		if err := wsc.QueueNotification(marshalledJSON); err == ErrClientQuit {
+			return nil, nil
+		}
 	}
 
+	marshalledJSON, err := marshalNotification(
+		btcjson.NewReplayFinishedNtfn(lastSeq), newNtfnEventID())
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal replay finished "+
+			"notification: %v", err)
+		return nil, nil
+	}
+	// The replay is finished, so we don't care whether the client has
+	// disconnected at this point, so discard error.
+	_ = wsc.QueueNotification(marshalledJSON)
 	return nil, nil
 }
 
@@ -1589,7 +4028,7 @@ func handleStopNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, err
 // properly, the function returns an error. Otherwise, nil is returned.
 func checkAddressValidity(addrs []string) error {
 	for _, addr := range addrs {
-		_, err := coinutil.DecodeAddress(addr, activeNetParams.Params)
+		_, err := bech32.DecodeAddress(addr, activeNetParams.Params)
 		if err != nil {
 			return &btcjson.RPCError{
 				Code: btcjson.ErrRPCInvalidAddressOrKey,
@@ -1616,15 +4055,302 @@ func deserializeOutpoints(serializedOuts []btcjson.OutPoint) ([]*wire.OutPoint,
 	return outpoints, nil
 }
 
+// rescanKeys houses the lookup keys of a rescan or a loaded transaction
+// filter.  Addresses are split by type so matching transaction outputs does
+// not require encoding every output address as a string.
 type rescanKeys struct {
 	fallbacks           map[string]struct{}
 	pubKeyHashes        map[[ripemd160.Size]byte]struct{}
 	scriptHashes        map[[ripemd160.Size]byte]struct{}
 	compressedPubKeys   map[[33]byte]struct{}
 	uncompressedPubKeys map[[65]byte]struct{}
+	witnessPubKeyHashes map[[20]byte]struct{}
+	witnessScriptHashes map[[32]byte]struct{}
 	unspent             map[wire.OutPoint]struct{}
 }
 
+// newRescanKeys returns a new set of rescan lookup keys with all maps
+// allocated.
+func newRescanKeys() *rescanKeys {
+	return &rescanKeys{
+		fallbacks:           map[string]struct{}{},
+		pubKeyHashes:        map[[ripemd160.Size]byte]struct{}{},
+		scriptHashes:        map[[ripemd160.Size]byte]struct{}{},
+		compressedPubKeys:   map[[33]byte]struct{}{},
+		uncompressedPubKeys: map[[65]byte]struct{}{},
+		witnessPubKeyHashes: map[[20]byte]struct{}{},
+		witnessScriptHashes: map[[32]byte]struct{}{},
+		unspent:             map[wire.OutPoint]struct{}{},
+	}
+}
+
+// addAddress adds the passed address to the lookup keys.  It returns false
+// without adding anything when the address is a pubkey of unknown length.
+func (r *rescanKeys) addAddress(addr coinutil.Address) bool {
+	switch a := addr.(type) {
+	case *coinutil.AddressPubKeyHash:
+		r.pubKeyHashes[*a.Hash160()] = struct{}{}
+
+	case *coinutil.AddressScriptHash:
+		r.scriptHashes[*a.Hash160()] = struct{}{}
+
+	case *bech32.AddressWitnessPubKeyHash:
+		r.witnessPubKeyHashes[*a.Hash160()] = struct{}{}
+
+	case *bech32.AddressWitnessScriptHash:
+		var scriptHash [32]byte
+		copy(scriptHash[:], a.WitnessProgram())
+		r.witnessScriptHashes[scriptHash] = struct{}{}
+
+	case *coinutil.AddressPubKey:
+		pubkeyBytes := a.ScriptAddress()
+		switch len(pubkeyBytes) {
+		case 33: // Compressed
+			var key [33]byte
+			copy(key[:], pubkeyBytes)
+			r.compressedPubKeys[key] = struct{}{}
+
+		case 65: // Uncompressed
+			var key [65]byte
+			copy(key[:], pubkeyBytes)
+			r.uncompressedPubKeys[key] = struct{}{}
+
+		default:
+			return false
+		}
+
+	default:
+		// A new address type must have been added.  Use encoded
+		// payment address string as a fallback until a fast path
+		// is added.
+		r.fallbacks[addr.EncodeAddress()] = struct{}{}
+	}
+	return true
+}
+
+// removeAddress removes the passed address from the lookup keys.
+func (r *rescanKeys) removeAddress(addr coinutil.Address) {
+	switch a := addr.(type) {
+	case *coinutil.AddressPubKeyHash:
+		delete(r.pubKeyHashes, *a.Hash160())
+
+	case *coinutil.AddressScriptHash:
+		delete(r.scriptHashes, *a.Hash160())
+
+	case *bech32.AddressWitnessPubKeyHash:
+		delete(r.witnessPubKeyHashes, *a.Hash160())
+
+	case *bech32.AddressWitnessScriptHash:
+		var scriptHash [32]byte
+		copy(scriptHash[:], a.WitnessProgram())
+		delete(r.witnessScriptHashes, scriptHash)
+
+	case *coinutil.AddressPubKey:
+		pubkeyBytes := a.ScriptAddress()
+		switch len(pubkeyBytes) {
+		case 33: // Compressed
+			var key [33]byte
+			copy(key[:], pubkeyBytes)
+			delete(r.compressedPubKeys, key)
+
+		case 65: // Uncompressed
+			var key [65]byte
+			copy(key[:], pubkeyBytes)
+			delete(r.uncompressedPubKeys, key)
+		}
+
+	default:
+		delete(r.fallbacks, addr.EncodeAddress())
+	}
+}
+
+// merge adds all lookup keys of other to the receiver.
+func (r *rescanKeys) merge(other *rescanKeys) {
+	for k := range other.fallbacks {
+		r.fallbacks[k] = struct{}{}
+	}
+	for k := range other.pubKeyHashes {
+		r.pubKeyHashes[k] = struct{}{}
+	}
+	for k := range other.scriptHashes {
+		r.scriptHashes[k] = struct{}{}
+	}
+	for k := range other.compressedPubKeys {
+		r.compressedPubKeys[k] = struct{}{}
+	}
+	for k := range other.uncompressedPubKeys {
+		r.uncompressedPubKeys[k] = struct{}{}
+	}
+	for k := range other.witnessPubKeyHashes {
+		r.witnessPubKeyHashes[k] = struct{}{}
+	}
+	for k := range other.witnessScriptHashes {
+		r.witnessScriptHashes[k] = struct{}{}
+	}
+	for k := range other.unspent {
+		r.unspent[k] = struct{}{}
+	}
+}
+
+// size returns the number of addresses and unspent outpoints of the lookup
+// keys.  Public keys count as addresses.
+func (r *rescanKeys) size() (addrs, outPoints int) {
+	addrs = len(r.fallbacks) + len(r.pubKeyHashes) + len(r.scriptHashes) +
+		len(r.compressedPubKeys) + len(r.uncompressedPubKeys) +
+		len(r.witnessPubKeyHashes) + len(r.witnessScriptHashes)
+	return addrs, len(r.unspent)
+}
+
+// newEntries returns the number of addresses and unspent outpoints of the
+// passed lookup keys which are not lookup keys of r, that is by how much
+// merging them would grow r.
+func (r *rescanKeys) newEntries(other *rescanKeys) (addrs, outPoints int) {
+	for k := range other.fallbacks {
+		if _, ok := r.fallbacks[k]; !ok {
+			addrs++
+		}
+	}
+	for k := range other.pubKeyHashes {
+		if _, ok := r.pubKeyHashes[k]; !ok {
+			addrs++
+		}
+	}
+	for k := range other.scriptHashes {
+		if _, ok := r.scriptHashes[k]; !ok {
+			addrs++
+		}
+	}
+	for k := range other.compressedPubKeys {
+		if _, ok := r.compressedPubKeys[k]; !ok {
+			addrs++
+		}
+	}
+	for k := range other.uncompressedPubKeys {
+		if _, ok := r.uncompressedPubKeys[k]; !ok {
+			addrs++
+		}
+	}
+	for k := range other.witnessPubKeyHashes {
+		if _, ok := r.witnessPubKeyHashes[k]; !ok {
+			addrs++
+		}
+	}
+	for k := range other.witnessScriptHashes {
+		if _, ok := r.witnessScriptHashes[k]; !ok {
+			addrs++
+		}
+	}
+	for k := range other.unspent {
+		if _, ok := r.unspent[k]; !ok {
+			outPoints++
+		}
+	}
+	return addrs, outPoints
+}
+
+// clone returns a deep copy of the lookup keys.
+func (r *rescanKeys) clone() *rescanKeys {
+	keys := newRescanKeys()
+	keys.merge(r)
+	return keys
+}
+
+// matchesAddress returns whether the passed address is one of the lookup keys.
+// Outputs paying to the pubkey of a P2PKH lookup address match as well.
+func (r *rescanKeys) matchesAddress(addr coinutil.Address) bool {
+	switch a := addr.(type) {
+	case *coinutil.AddressPubKeyHash:
+		_, ok := r.pubKeyHashes[*a.Hash160()]
+		return ok
+
+	case *coinutil.AddressScriptHash:
+		_, ok := r.scriptHashes[*a.Hash160()]
+		return ok
+
+	case *bech32.AddressWitnessPubKeyHash:
+		_, ok := r.witnessPubKeyHashes[*a.Hash160()]
+		return ok
+
+	case *bech32.AddressWitnessScriptHash:
+		var scriptHash [32]byte
+		copy(scriptHash[:], a.WitnessProgram())
+		_, ok := r.witnessScriptHashes[scriptHash]
+		return ok
+
+	case *coinutil.AddressPubKey:
+		switch sa := a.ScriptAddress(); len(sa) {
+		case 33: // Compressed
+			var key [33]byte
+			copy(key[:], sa)
+			if _, ok := r.compressedPubKeys[key]; ok {
+				return true
+			}
+
+		case 65: // Uncompressed
+			var key [65]byte
+			copy(key[:], sa)
+			if _, ok := r.uncompressedPubKeys[key]; ok {
+				return true
+			}
+
+		default:
+			rpcsLog.Warnf("Skipping rescanned pubkey of unknown "+
+				"serialized length %d", len(sa))
+			return false
+		}
+
+		// If the transaction output pays to the pubkey of
+		// a rescanned P2PKH address, include it as well.
+		pkh := a.AddressPubKeyHash()
+		_, ok := r.pubKeyHashes[*pkh.Hash160()]
+		return ok
+
+	default:
+		// A new address type must have been added.  Encode as a
+		// payment address string and check the fallback map.
+		_, ok := r.fallbacks[addr.EncodeAddress()]
+		return ok
+	}
+}
+
+// matchTx examines the inputs and outputs of the passed transaction and
+// returns whether any input spends one of the unspent outpoints and whether
+// any output pays to one of the lookup addresses.  Matching outputs are added
+// to the unspent outpoints, and spent outpoints are removed when removeSpent
+// is true.
+func (r *rescanKeys) matchTx(tx *coinutil.Tx, removeSpent bool,
+	params *chaincfg.Params) (spends, receives bool) {
+
+	for _, txin := range tx.MsgTx().TxIn {
+		if _, ok := r.unspent[txin.PreviousOutPoint]; ok {
+			if removeSpent {
+				delete(r.unspent, txin.PreviousOutPoint)
+			}
+			spends = true
+		}
+	}
+
+	for txOutIdx, txout := range tx.MsgTx().TxOut {
+		_, addrs, _, _ := txscript.ExtractPkScriptAddrs(
+			txout.PkScript, params)
+
+		for _, addr := range addrs {
+			if !r.matchesAddress(addr) {
+				continue
+			}
+
+			outpoint := wire.OutPoint{
+				Hash:  *tx.Sha(),
+				Index: uint32(txOutIdx),
+			}
+			r.unspent[outpoint] = struct{}{}
+			receives = true
+		}
+	}
+
+	return spends, receives
+}
+
 // unspentSlice returns a slice of currently-unspent outpoints for the rescan
 // lookup keys.  This is primarily intended to be used to register outpoints
 // for continuous notifications after a rescan has completed.
@@ -1644,135 +4370,58 @@ var ErrRescanReorg = btcjson.RPCError{
 	Message: "Reorganize",
 }
 
-// rescanBlock rescans all transactions in a single block.  This is a helper
-// function for handleRescan.
-func rescanBlock(wsc *wsClient, lookups *rescanKeys, blk *coinutil.Block) {
+// errRescanClientQuit is returned by rescanBlockRange when the websocket client
+// disconnected before the rescan finished.
+var errRescanClientQuit = errors.New("websocket client disconnected")
+
+// rescanBlock rescans all transactions in a single block.  Notifications are
+// tagged with filterID unless it is empty.  This is a helper function for
+// rescanBlockRange.
+func rescanBlock(wsc *wsClient, lookups *rescanKeys, filterID string, blk *coinutil.Block) {
+	// The notifications about the block share the event id of its rescan.
+	eventID := newNtfnEventID()
+	hexes := newTxHexes(blk)
 	for _, tx := range blk.Transactions() {
-		// Hexadecimal representation of this tx.  Only created if
-		// needed, and reused for later notifications if already made.
-		var txHex string
-
 		// All inputs and outputs must be iterated through to correctly
 		// modify the unspent map, however, just a single notification
 		// for any matching transaction inputs or outputs should be
 		// created and sent.
-		spentNotified := false
-		recvNotified := false
-
-		for _, txin := range tx.MsgTx().TxIn {
-			if _, ok := lookups.unspent[txin.PreviousOutPoint]; ok {
-				delete(lookups.unspent, txin.PreviousOutPoint)
-
-				if spentNotified {
-					continue
-				}
+		spends, receives := lookups.matchTx(tx, true,
+			wsc.server.server.chainParams)
+		if !spends && !receives {
+			continue
+		}
 
-				if txHex == "" {
-					txHex = txHexString(tx)
-				}
-				marshalledJSON, err := newRedeemingTxNotification(txHex, tx.Index(), blk)
-				if err != nil {
-					rpcsLog.Errorf("Failed to marshal redeemingtx notification: %v", err)
-					continue
-				}
+		txHex := hexes.get(tx)
+		if spends {
+			marshalledJSON, err := newRedeemingTxNotification(filterID,
+				txHex, tx.Index(), blk, eventID)
+			if err != nil {
+				rpcsLog.Errorf("Failed to marshal redeemingtx notification: %v", err)
+				continue
+			}
 
-				err = wsc.QueueNotification(marshalledJSON)
-				// Stop the rescan early if the websocket client
-				// disconnected.
-				if err == ErrClientQuit {
-					return
-				}
-				spentNotified = true
+			err = wsc.QueueNotification(marshalledJSON)
+			// Stop the rescan early if the websocket client
+			// disconnected.
+			if err == ErrClientQuit {
+				return
 			}
 		}
 
-		for txOutIdx, txout := range tx.MsgTx().TxOut {
-			_, addrs, _, _ := txscript.ExtractPkScriptAddrs(
-				txout.PkScript, wsc.server.server.chainParams)
-
-			for _, addr := range addrs {
-				switch a := addr.(type) {
-				case *coinutil.AddressPubKeyHash:
-					if _, ok := lookups.pubKeyHashes[*a.Hash160()]; !ok {
-						continue
-					}
-
-				case *coinutil.AddressScriptHash:
-					if _, ok := lookups.scriptHashes[*a.Hash160()]; !ok {
-						continue
-					}
-
-				case *coinutil.AddressPubKey:
-					found := false
-					switch sa := a.ScriptAddress(); len(sa) {
-					case 33: // Compressed
-						var key [33]byte
-						copy(key[:], sa)
-						if _, ok := lookups.compressedPubKeys[key]; ok {
-							found = true
-						}
-
-					case 65: // Uncompressed
-						var key [65]byte
-						copy(key[:], sa)
-						if _, ok := lookups.uncompressedPubKeys[key]; ok {
-							found = true
-						}
-
-					default:
-						rpcsLog.Warnf("Skipping rescanned pubkey of unknown "+
-							"serialized length %d", len(sa))
-						continue
-					}
-
-					// If the transaction output pays to the pubkey of
-					// a rescanned P2PKH address, include it as well.
-					if !found {
-						pkh := a.AddressPubKeyHash()
-						if _, ok := lookups.pubKeyHashes[*pkh.Hash160()]; !ok {
-							continue
-						}
-					}
-
-				default:
-					// A new address type must have been added.  Encode as a
-					// payment address string and check the fallback map.
-					addrStr := addr.EncodeAddress()
-					_, ok := lookups.fallbacks[addrStr]
-					if !ok {
-						continue
-					}
-				}
-
-				outpoint := wire.OutPoint{
-					Hash:  *tx.Sha(),
-					Index: uint32(txOutIdx),
-				}
-				lookups.unspent[outpoint] = struct{}{}
-
-				if recvNotified {
-					continue
-				}
-
-				if txHex == "" {
-					txHex = txHexString(tx)
-				}
-				ntfn := btcjson.NewRecvTxNtfn(txHex,
-					blockDetails(blk, tx.Index()))
-
-				marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
-				if err != nil {
-					rpcsLog.Errorf("Failed to marshal recvtx notification: %v", err)
-					return
-				}
+		if receives {
+			marshalledJSON, err := newRecvTxNotification(filterID,
+				txHex, tx.Index(), blk, eventID)
+			if err != nil {
+				rpcsLog.Errorf("Failed to marshal recvtx notification: %v", err)
+				return
+			}
 
-				err = wsc.QueueNotification(marshalledJSON)
-				// Stop the rescan early if the websocket client
-				// disconnected.
-				if err == ErrClientQuit {
-					return
-				}
-				recvNotified = true
+			err = wsc.QueueNotification(marshalledJSON)
+			// Stop the rescan early if the websocket client
+			// disconnected.
+			if err == ErrClientQuit {
+				return
 			}
 		}
 	}
@@ -1810,141 +4459,108 @@ func recoverFromReorg(db database.Db, minBlock, maxBlock int32,
 	if jsonErr != nil {
 		return nil, jsonErr
 	}
-	return hashList, nil
-}
-
-// descendantBlock returns the appropiate JSON-RPC error if a current block
-// fetched during a reorganize is not a direct child of the parent block hash.
-func descendantBlock(prevHash *wire.ShaHash, curBlock *coinutil.Block) error {
-	curHash := &curBlock.MsgBlock().Header.PrevBlock
-	if !prevHash.IsEqual(curHash) {
-		rpcsLog.Errorf("Stopping rescan for reorged block %v "+
-			"(replaced by block %v)", prevHash, curHash)
-		return &ErrRescanReorg
-	}
-	return nil
-}
-
-// handleRescan implements the rescan command extension for websocket
-// connections.
-//
-// NOTE: This does not smartly handle reorgs, and fixing requires database
-// changes (for safe, concurrent access to full block ranges, and support
-// for other chains than the best chain).  It will, however, detect whether
-// a reorg removed a block that was previously processed, and result in the
-// handler erroring.  Clients must handle this by finding a block still in
-// the chain (perhaps from a rescanprogress notification) to resume their
-// rescan.
-func handleRescan(wsc *wsClient, icmd interface{}) (interface{}, error) {
-	cmd, ok := icmd.(*btcjson.RescanCmd)
-	if !ok {
-		return nil, btcjson.ErrRPCInternal
-	}
-
-	outpoints := make([]*wire.OutPoint, 0, len(cmd.OutPoints))
-	for i := range cmd.OutPoints {
-		blockHash, err := wire.NewShaHashFromStr(cmd.OutPoints[i].Hash)
-		if err != nil {
-			return nil, rpcDecodeHexError(cmd.OutPoints[i].Hash)
-		}
-		index := cmd.OutPoints[i].Index
-		outpoints = append(outpoints, wire.NewOutPoint(blockHash, index))
-	}
-
-	numAddrs := len(cmd.Addresses)
-	if numAddrs == 1 {
-		rpcsLog.Info("Beginning rescan for 1 address")
-	} else {
-		rpcsLog.Infof("Beginning rescan for %d addresses", numAddrs)
-	}
-
-	// Build lookup maps.
-	lookups := rescanKeys{
-		fallbacks:           map[string]struct{}{},
-		pubKeyHashes:        map[[ripemd160.Size]byte]struct{}{},
-		scriptHashes:        map[[ripemd160.Size]byte]struct{}{},
-		compressedPubKeys:   map[[33]byte]struct{}{},
-		uncompressedPubKeys: map[[65]byte]struct{}{},
-		unspent:             map[wire.OutPoint]struct{}{},
-	}
-	var compressedPubkey [33]byte
-	var uncompressedPubkey [65]byte
-	for _, addrStr := range cmd.Addresses {
-		addr, err := coinutil.DecodeAddress(addrStr, activeNetParams.Params)
-		if err != nil {
-			jsonErr := btcjson.RPCError{
-				Code: btcjson.ErrRPCInvalidAddressOrKey,
-				Message: "Rescan address " + addrStr + ": " +
-					err.Error(),
-			}
-			return nil, &jsonErr
-		}
-		switch a := addr.(type) {
-		case *coinutil.AddressPubKeyHash:
-			lookups.pubKeyHashes[*a.Hash160()] = struct{}{}
-
-		case *coinutil.AddressScriptHash:
-			lookups.scriptHashes[*a.Hash160()] = struct{}{}
-
-		case *coinutil.AddressPubKey:
-			pubkeyBytes := a.ScriptAddress()
-			switch len(pubkeyBytes) {
-			case 33: // Compressed
-				copy(compressedPubkey[:], pubkeyBytes)
-				lookups.compressedPubKeys[compressedPubkey] = struct{}{}
-
-			case 65: // Uncompressed
-				copy(uncompressedPubkey[:], pubkeyBytes)
-				lookups.uncompressedPubKeys[uncompressedPubkey] = struct{}{}
-
-			default:
-				jsonErr := btcjson.RPCError{
-					Code:    btcjson.ErrRPCInvalidAddressOrKey,
-					Message: "Pubkey " + addrStr + " is of unknown length",
-				}
-				return nil, &jsonErr
-			}
+	return hashList, nil
+}
 
-		default:
-			// A new address type must have been added.  Use encoded
-			// payment address string as a fallback until a fast path
-			// is added.
-			lookups.fallbacks[addrStr] = struct{}{}
-		}
+// descendantBlock returns the appropiate JSON-RPC error if a current block
+// fetched during a reorganize is not a direct child of the parent block hash.
+func descendantBlock(prevHash *wire.ShaHash, curBlock *coinutil.Block) error {
+	curHash := &curBlock.MsgBlock().Header.PrevBlock
+	if !prevHash.IsEqual(curHash) {
+		rpcsLog.Errorf("Stopping rescan for reorged block %v "+
+			"(replaced by block %v)", prevHash, curHash)
+		return &ErrRescanReorg
 	}
-	for _, outpoint := range outpoints {
-		lookups.unspent[*outpoint] = struct{}{}
+	return nil
+}
+
+// newRescanProgressNotification returns a new marshalled rescanprogress
+// notification for the passed block, or a filteredrescanprogress notification
+// tagged with filterID if it is not empty.
+func newRescanProgressNotification(filterID string, blk *coinutil.Block) ([]byte, error) {
+	hash := blk.Sha().String()
+	height := int32(blk.Height())
+	blockTime := blk.MsgBlock().Header.Timestamp.Unix()
+
+	var ntfn interface{}
+	if filterID == "" {
+		ntfn = btcjson.NewRescanProgressNtfn(hash, height, blockTime)
+	} else {
+		ntfn = btcjson.NewFilteredRescanProgressNtfn(filterID, hash,
+			height, blockTime)
 	}
+	return marshalNotification(ntfn, newNtfnEventID())
+}
 
-	db := wsc.server.server.db
+// newRescanFinishedNotification returns a new marshalled rescanfinished
+// notification for the passed block, or a filteredrescanfinished notification
+// tagged with filterID if it is not empty.
+func newRescanFinishedNotification(filterID string, blk *coinutil.Block) ([]byte, error) {
+	hash := blk.Sha().String()
+	height := int32(blk.Height())
+	blockTime := blk.MsgBlock().Header.Timestamp.Unix()
+
+	var ntfn interface{}
+	if filterID == "" {
+		ntfn = btcjson.NewRescanFinishedNtfn(hash, height, blockTime)
+	} else {
+		ntfn = btcjson.NewFilteredRescanFinishedNtfn(filterID, hash,
+			height, blockTime)
+	}
+	return marshalNotification(ntfn, newNtfnEventID())
+}
 
-	minBlockSha, err := wire.NewShaHashFromStr(cmd.BeginBlock)
+// fetchRescanRange returns the heights of the begin block and the optional end
+// block of a rescan.  The end height is database.AllShas when no end block is
+// given.
+func fetchRescanRange(db database.Db, beginBlock string, endBlock *string) (int32, int32, error) {
+	minBlockSha, err := wire.NewShaHashFromStr(beginBlock)
 	if err != nil {
-		return nil, rpcDecodeHexError(cmd.BeginBlock)
+		return 0, 0, rpcDecodeHexError(beginBlock)
 	}
 	minBlock, err := db.FetchBlockHeightBySha(minBlockSha)
 	if err != nil {
-		return nil, &btcjson.RPCError{
+		return 0, 0, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCBlockNotFound,
 			Message: "Error getting block: " + err.Error(),
 		}
 	}
 
 	maxBlock := database.AllShas
-	if cmd.EndBlock != nil {
-		maxBlockSha, err := wire.NewShaHashFromStr(*cmd.EndBlock)
+	if endBlock != nil {
+		maxBlockSha, err := wire.NewShaHashFromStr(*endBlock)
 		if err != nil {
-			return nil, rpcDecodeHexError(*cmd.EndBlock)
+			return 0, 0, rpcDecodeHexError(*endBlock)
 		}
 		maxBlock, err = db.FetchBlockHeightBySha(maxBlockSha)
 		if err != nil {
-			return nil, &btcjson.RPCError{
+			return 0, 0, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCBlockNotFound,
 				Message: "Error getting block: " + err.Error(),
 			}
 		}
 	}
 
+	return minBlock, maxBlock, nil
+}
+
+// rescanBlockRange rescans the main chain blocks in the passed height range
+// for transactions matching the lookup keys, sending notifications (tagged
+// with filterID unless it is empty) to the websocket client.  When maxBlock is
+// database.AllShas, the rescan continues through the best block and caughtUp
+// is invoked while the block manager is paused, so that the client can be set
+// up for continuous notifications without missing any blocks.  The optional
+// progress function is invoked with every rescanned block.
+//
+// The last rescanned block is returned, which is nil when no blocks were
+// rescanned.  errRescanClientQuit is returned if the client disconnected or
+// the passed context was canceled.
+func rescanBlockRange(ctx context.Context, wsc *wsClient, lookups *rescanKeys,
+	filterID string, minBlock, maxBlock int32, progress func(*coinutil.Block),
+	caughtUp func()) (*coinutil.Block, error) {
+
+	db := wsc.server.server.db
+
 	// lastBlock and lastBlockHash track the previously-rescanned block.
 	// They equal nil when no previous blocks have been rescanned.
 	var lastBlock *coinutil.Block
@@ -1992,9 +4608,7 @@ fetchRange:
 			again := true
 			if err == nil && (lastBlockHash == nil || *lastBlockHash == *curHash) {
 				again = false
-				n := wsc.server.ntfnMgr
-				n.RegisterSpentRequests(wsc, lookups.unspentSlice())
-				n.RegisterTxOutAddressRequests(wsc, cmd.Addresses)
+				caughtUp()
 			}
 			close(pauseGuard)
 			if err != nil {
@@ -2032,8 +4646,7 @@ fetchRange:
 				// attempt to handle the reorg.
 				if maxBlock != database.AllShas {
 					rpcsLog.Errorf("Stopping rescan for "+
-						"reorged block %v",
-						cmd.EndBlock)
+						"reorged block %v", hashList[i])
 					return nil, &ErrRescanReorg
 				}
 
@@ -2068,16 +4681,21 @@ fetchRange:
 			}
 
 			// A select statement is used to stop rescans if the
-			// client requesting the rescan has disconnected.
+			// client requesting the rescan has disconnected or
+			// canceled it.
 			select {
-			case <-wsc.quit:
+			case <-ctx.Done():
 				rpcsLog.Debugf("Stopped rescan at height %v "+
-					"for disconnected client", blk.Height())
-				return nil, nil
+					"for disconnected client or canceled "+
+					"job", blk.Height())
+				return nil, errRescanClientQuit
 			default:
-				rescanBlock(wsc, &lookups, blk)
+				rescanBlock(wsc, lookups, filterID, blk)
 				lastBlock = blk
 				lastBlockHash = blk.Sha()
+				if progress != nil {
+					progress(blk)
+				}
 			}
 
 			// Periodically notify the client of the progress
@@ -2089,10 +4707,7 @@ fetchRange:
 				continue
 			}
 
-			n := btcjson.NewRescanProgressNtfn(hashList[i].String(),
-				int32(blk.Height()),
-				blk.MsgBlock().Header.Timestamp.Unix())
-			mn, err := btcjson.MarshalCmd(nil, n)
+			mn, err := newRescanProgressNotification(filterID, blk)
 			if err != nil {
 				rpcsLog.Errorf("Failed to marshal rescan "+
 					"progress notification: %v", err)
@@ -2103,24 +4718,28 @@ fetchRange:
 				// Finished if the client disconnected.
 				rpcsLog.Debugf("Stopped rescan at height %v "+
 					"for disconnected client", blk.Height())
-				return nil, nil
+				return nil, errRescanClientQuit
 			}
 		}
 
 		minBlock += int32(len(hashList))
 	}
 
-	// Notify websocket client of the finished rescan.  Due to how btcd
-	// asynchronously queues notifications to not block calling code,
-	// there is no guarantee that any of the notifications created during
-	// rescan (such as rescanprogress, recvtx and redeemingtx) will be
-	// received before the rescan RPC returns.  Therefore, another method
-	// is needed to safely inform clients that all rescan notifications have
-	// been sent.
-	n := btcjson.NewRescanFinishedNtfn(lastBlockHash.String(),
-		int32(lastBlock.Height()),
-		lastBlock.MsgBlock().Header.Timestamp.Unix())
-	if mn, err := btcjson.MarshalCmd(nil, n); err != nil {
+	return lastBlock, nil
+}
+
+// notifyRescanFinished sends the websocket client a notification that a rescan
+// which ended at lastBlock has finished.  Due to how btcd asynchronously queues
+// notifications to not block calling code, there is no guarantee that any of
+// the notifications created during rescan (such as rescanprogress, recvtx and
+// redeemingtx) will be received before the rescan RPC returns.  Therefore,
+// another method is needed to safely inform clients that all rescan
+// notifications have been sent.
+func notifyRescanFinished(wsc *wsClient, filterID string, lastBlock *coinutil.Block) {
+	if lastBlock == nil {
+		return
+	}
+	if mn, err := newRescanFinishedNotification(filterID, lastBlock); err != nil {
 		rpcsLog.Errorf("Failed to marshal rescan finished "+
 			"notification: %v", err)
 	} else {
@@ -2128,11 +4747,487 @@ fetchRange:
 		// has disconnected at this point, so discard error.
 		_ = wsc.QueueNotification(mn)
 	}
+}
+
+// newRescanLookups returns the lookup keys for a rescan of the passed addresses
+// and outpoints.
+func newRescanLookups(addresses []string, outPoints []btcjson.OutPoint) (*rescanKeys, error) {
+	lookups := newRescanKeys()
+	for _, addrStr := range addresses {
+		addr, err := bech32.DecodeAddress(addrStr, activeNetParams.Params)
+		if err != nil {
+			jsonErr := btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Rescan address " + addrStr + ": " +
+					err.Error(),
+			}
+			return nil, &jsonErr
+		}
+		if !lookups.addAddress(addr) {
+			jsonErr := btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Pubkey " + addrStr + " is of unknown length",
+			}
+			return nil, &jsonErr
+		}
+	}
+	for i := range outPoints {
+		blockHash, err := wire.NewShaHashFromStr(outPoints[i].Hash)
+		if err != nil {
+			return nil, rpcDecodeHexError(outPoints[i].Hash)
+		}
+		index := outPoints[i].Index
+		lookups.unspent[*wire.NewOutPoint(blockHash, index)] = struct{}{}
+	}
+	return lookups, nil
+}
+
+// runRescan rescans the passed height range for the addresses and the lookup
+// keys of a rescan or startrescan command, sending notifications tagged with
+// tag unless it is empty.  When the rescan continues through the best block,
+// the client is registered for continuous notifications about the addresses
+// and the unspent outpoints.  A rescanfinished notification is sent once the
+// rescan completes.
+//
+// errRescanClientQuit is returned if the client disconnected or the passed
+// context was canceled.
+func runRescan(ctx context.Context, wsc *wsClient, tag string, addresses []string,
+	lookups *rescanKeys, minBlock, maxBlock int32, progress func(*coinutil.Block)) error {
+
+	// The registrations for continuous notifications are refused when the
+	// client would watch more addresses or outpoints than allowed, which
+	// fails the rescan once it is done.
+	var registerErr error
+	lastBlock, err := rescanBlockRange(ctx, wsc, lookups, tag, minBlock,
+		maxBlock, progress, func() {
+			n := wsc.server.ntfnMgr
+			registerErr = n.RegisterTxOutAddressRequests(wsc,
+				addresses)
+			if registerErr != nil {
+				return
+			}
+			registerErr = n.RegisterSpentRequests(wsc,
+				lookups.unspentSlice())
+		})
+	if err != nil {
+		return err
+	}
+	if registerErr != nil {
+		return registerErr
+	}
+
+	// Notify websocket client of the finished rescan.
+	notifyRescanFinished(wsc, tag, lastBlock)
+	return nil
+}
+
+// handleRescan implements the rescan command extension for websocket
+// connections.
+//
+// NOTE: This does not smartly handle reorgs, and fixing requires database
+// changes (for safe, concurrent access to full block ranges, and support
+// for other chains than the best chain).  It will, however, detect whether
+// a reorg removed a block that was previously processed, and result in the
+// handler erroring.  Clients must handle this by finding a block still in
+// the chain (perhaps from a rescanprogress notification) to resume their
+// rescan.
+func handleRescan(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.RescanCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	numAddrs := len(cmd.Addresses)
+	if numAddrs == 1 {
+		rpcsLog.Info("Beginning rescan for 1 address")
+	} else {
+		rpcsLog.Infof("Beginning rescan for %d addresses", numAddrs)
+	}
+
+	// Build lookup maps.
+	lookups, err := newRescanLookups(cmd.Addresses, cmd.OutPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	minBlock, maxBlock, err := fetchRescanRange(wsc.server.server.db,
+		cmd.BeginBlock, cmd.EndBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	err = runRescan(wsc.ctx, wsc, "", cmd.Addresses, lookups, minBlock,
+		maxBlock, nil)
+	if err == errRescanClientQuit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	rpcsLog.Info("Finished rescan")
 	return nil, nil
 }
 
+// handleStartRescan implements the startrescan command extension for websocket
+// connections.  It starts a rescan like the rescan command as a job running in
+// the background and returns the id of the job right away.  The notifications
+// of the rescan are tagged with the job id, and its progress is reported by
+// the getrescanstatus command.
+func handleStartRescan(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.StartRescanCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	lookups, err := newRescanLookups(cmd.Addresses, cmd.OutPoints)
+	if err != nil {
+		return nil, err
+	}
+	minBlock, maxBlock, err := fetchRescanRange(wsc.server.server.db,
+		cmd.BeginBlock, cmd.EndBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := wsc.rescans.add(wsc.ctx, minBlock, maxBlock)
+	if err != nil {
+		return nil, err
+	}
+	rpcsLog.Infof("Beginning rescan job %s for %d addresses", job.id,
+		len(cmd.Addresses))
+
+	wsc.wg.Add(1)
+	go func() {
+		defer wsc.wg.Done()
+
+		err := runRescan(job.ctx, wsc, job.id, cmd.Addresses, lookups,
+			minBlock, maxBlock, job.progress)
+		job.finish(err)
+		switch {
+		case err == nil:
+			rpcsLog.Infof("Finished rescan job %s", job.id)
+		case err == errRescanClientQuit:
+			rpcsLog.Infof("Canceled rescan job %s", job.id)
+		default:
+			rpcsLog.Infof("Rescan job %s failed: %v", job.id, err)
+		}
+	}()
+
+	return job.id, nil
+}
+
+// handleGetRescanStatus implements the getrescanstatus command extension for
+// websocket connections.
+func handleGetRescanStatus(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.GetRescanStatusCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	job, err := wsc.rescans.lookup(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	return job.status(), nil
+}
+
+// handleCancelRescan implements the cancelrescan command extension for
+// websocket connections.  Canceling a job which is no longer running has no
+// effect.
+func handleCancelRescan(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.CancelRescanCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	job, err := wsc.rescans.lookup(cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	job.cancel()
+	return nil, nil
+}
+
+// wsClientFilter is a named transaction filter loaded by a websocket client.
+// A single connection may load several filters, for example to serve multiple
+// wallets, and every notification created for a filter is tagged with its id.
+type wsClientFilter struct {
+	mu sync.Mutex
+
+	// id is the client-chosen identifier of the filter.
+	id string
+
+	// keys holds the addresses and unspent outpoints watched by the
+	// filter.  It is protected by mu.
+	keys *rescanKeys
+}
+
+// matchTx examines the passed transaction for inputs spending or outputs
+// paying to the filter, updating the watched outpoints accordingly.  Spent
+// outpoints are only removed once the spending transaction is mined, as
+// indicated by mined.
+func (f *wsClientFilter) matchTx(tx *coinutil.Tx, mined bool,
+	params *chaincfg.Params) (spends, receives bool) {
+
+	f.mu.Lock()
+	spends, receives = f.keys.matchTx(tx, mined, params)
+	f.mu.Unlock()
+	return spends, receives
+}
+
+// size returns the number of addresses and unspent outpoints watched by the
+// filter.
+func (f *wsClientFilter) size() (addrs, outPoints int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.keys.size()
+}
+
+// watched returns the number of addresses, including script hashes, and
+// outpoints watched by the client, including the entries of its transaction
+// filters.  It must only be called by the notification handler since it
+// accesses the registrations owned by it.
+func (c *wsClient) watched() (addrs, outPoints int) {
+	addrs = len(c.addrRequests) + len(c.scriptHashRequests)
+	outPoints = len(c.spentRequests)
+	for _, f := range c.loadedFilters() {
+		filterAddrs, filterOutPoints := f.size()
+		addrs += filterAddrs
+		outPoints += filterOutPoints
+	}
+	return addrs, outPoints
+}
+
+// loadedFilters returns the transaction filters currently loaded by the
+// client.
+func (c *wsClient) loadedFilters() []*wsClientFilter {
+	c.Lock()
+	defer c.Unlock()
+
+	filters := make([]*wsClientFilter, 0, len(c.filters))
+	for _, f := range c.filters {
+		filters = append(filters, f)
+	}
+	return filters
+}
+
+// lookupFilter returns the transaction filter loaded by the client with the
+// passed id, or an RPC error if no such filter exists.
+func (c *wsClient) lookupFilter(id string) (*wsClientFilter, error) {
+	c.Lock()
+	f, ok := c.filters[id]
+	c.Unlock()
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "No transaction filter loaded with id " + id,
+		}
+	}
+	return f, nil
+}
+
+// decodeFilterAddresses decodes the passed addresses into the lookup keys.
+func decodeFilterAddresses(keys *rescanKeys, addrs []string) error {
+	for _, addrStr := range addrs {
+		addr, err := bech32.DecodeAddress(addrStr, activeNetParams.Params)
+		if err == nil && keys.addAddress(addr) {
+			continue
+		}
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidAddressOrKey,
+			Message: fmt.Sprintf("Invalid address or key: %v",
+				addrStr),
+		}
+	}
+	return nil
+}
+
+// handleLoadTxFilter implements the loadtxfilter command extension for
+// websocket connections.
+func handleLoadTxFilter(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.LoadTxFilterCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	if cmd.FilterID == "" {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Filter id must not be empty",
+		}
+	}
+
+	// Decode all addresses and outpoints before touching the filter so
+	// that an invalid parameter leaves it unchanged.
+	keys := newRescanKeys()
+	if err := decodeFilterAddresses(keys, cmd.Addresses); err != nil {
+		return nil, err
+	}
+	outpoints, err := deserializeOutpoints(cmd.OutPoints)
+	if err != nil {
+		return nil, err
+	}
+	for _, outpoint := range outpoints {
+		keys.unspent[*outpoint] = struct{}{}
+	}
+
+	// Refuse to grow the filter beyond the limits of watched addresses and
+	// outpoints.  A reloaded filter replaces the entries of the old one.
+	wsc.Lock()
+	f, ok := wsc.filters[cmd.FilterID]
+	wsc.Unlock()
+	var addAddrs, addOutPoints int
+	switch {
+	case !ok:
+		addAddrs, addOutPoints = keys.size()
+	case cmd.Reload:
+		addAddrs, addOutPoints = keys.size()
+		oldAddrs, oldOutPoints := f.size()
+		addAddrs -= oldAddrs
+		addOutPoints -= oldOutPoints
+	default:
+		f.mu.Lock()
+		addAddrs, addOutPoints = f.keys.newEntries(keys)
+		f.mu.Unlock()
+	}
+	err = wsc.server.ntfnMgr.CheckWatchLimits(wsc, addAddrs, addOutPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	wsc.Lock()
+	f, ok = wsc.filters[cmd.FilterID]
+	if !ok || cmd.Reload {
+		wsc.filters[cmd.FilterID] = &wsClientFilter{
+			id:   cmd.FilterID,
+			keys: keys,
+		}
+		wsc.Unlock()
+		return nil, nil
+	}
+	wsc.Unlock()
+
+	f.mu.Lock()
+	f.keys.merge(keys)
+	f.mu.Unlock()
+	return nil, nil
+}
+
+// handleRemoveTxFilter implements the removetxfilter command extension for
+// websocket connections.
+func handleRemoveTxFilter(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.RemoveTxFilterCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	f, err := wsc.lookupFilter(cmd.FilterID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unload the entire filter when neither addresses nor outpoints are
+	// specified.
+	if cmd.Addresses == nil && cmd.OutPoints == nil {
+		wsc.Lock()
+		delete(wsc.filters, cmd.FilterID)
+		wsc.Unlock()
+		return nil, nil
+	}
+
+	var addrs []coinutil.Address
+	if cmd.Addresses != nil {
+		for _, addrStr := range *cmd.Addresses {
+			addr, err := bech32.DecodeAddress(addrStr,
+				activeNetParams.Params)
+			if err != nil {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidAddressOrKey,
+					Message: fmt.Sprintf("Invalid address "+
+						"or key: %v", addrStr),
+				}
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	var outpoints []*wire.OutPoint
+	if cmd.OutPoints != nil {
+		outpoints, err = deserializeOutpoints(*cmd.OutPoints)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.mu.Lock()
+	for _, addr := range addrs {
+		f.keys.removeAddress(addr)
+	}
+	for _, outpoint := range outpoints {
+		delete(f.keys.unspent, *outpoint)
+	}
+	f.mu.Unlock()
+	return nil, nil
+}
+
+// handleRescanFilter implements the rescanfilter command extension for
+// websocket connections.  It behaves like rescan, but uses the addresses and
+// outpoints of a loaded transaction filter and tags all notifications with the
+// filter id.  When the rescan continues through the best block, the unspent
+// outpoints of the filter are updated with the rescan results.
+func handleRescanFilter(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.RescanFilterCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	f, err := wsc.lookupFilter(cmd.FilterID)
+	if err != nil {
+		return nil, err
+	}
+
+	minBlock, maxBlock, err := fetchRescanRange(wsc.server.server.db,
+		cmd.BeginBlock, cmd.EndBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rescan a copy of the filter so the filter itself is not locked for
+	// the duration of the rescan.
+	f.mu.Lock()
+	lookups := f.keys.clone()
+	f.mu.Unlock()
+	initialUnspent := lookups.unspentSlice()
+
+	rpcsLog.Infof("Beginning rescan for transaction filter %q", f.id)
+
+	lastBlock, err := rescanBlockRange(wsc.ctx, wsc, lookups, f.id,
+		minBlock, maxBlock, nil, func() {
+			f.mu.Lock()
+			for _, op := range initialUnspent {
+				if _, ok := lookups.unspent[*op]; !ok {
+					delete(f.keys.unspent, *op)
+				}
+			}
+			for op := range lookups.unspent {
+				f.keys.unspent[op] = struct{}{}
+			}
+			f.mu.Unlock()
+		})
+	if err == errRescanClientQuit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	notifyRescanFinished(wsc, f.id, lastBlock)
+
+	rpcsLog.Infof("Finished rescan for transaction filter %q", f.id)
+	return nil, nil
+}
+
 func init() {
 	wsHandlers = wsHandlersBeforeInit
 }
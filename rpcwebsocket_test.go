@@ -0,0 +1,1190 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/mining"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestRescanKeysMatchTx ensures the lookup keys used by rescans and loaded
+// transaction filters match transactions paying to their addresses and
+// spending their outpoints, and track the unspent outpoints accordingly.
+func TestRescanKeysMatchTx(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	watched, err := coinutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20),
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	other, err := coinutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x02}, 20),
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	watchedScript, err := txscript.PayToAddrScript(watched)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+	otherScript, err := txscript.PayToAddrScript(other)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+
+	keys := newRescanKeys()
+	if !keys.addAddress(watched) {
+		t.Fatalf("addAddress: address was not added")
+	}
+
+	// A transaction paying to the watched address must match as received
+	// and add its output to the unspent outpoints.
+	msgTx := wire.NewMsgTx()
+	msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil))
+	msgTx.AddTxOut(wire.NewTxOut(1000, otherScript))
+	msgTx.AddTxOut(wire.NewTxOut(2000, watchedScript))
+	fundingTx := coinutil.NewTx(msgTx)
+	spends, receives := keys.matchTx(fundingTx, true, params)
+	if spends || !receives {
+		t.Fatalf("matchTx funding: got spends %v, receives %v - "+
+			"want false, true", spends, receives)
+	}
+	fundedOut := wire.OutPoint{Hash: *fundingTx.Sha(), Index: 1}
+	if _, ok := keys.unspent[fundedOut]; !ok || len(keys.unspent) != 1 {
+		t.Fatalf("matchTx funding: unexpected unspent outpoints %v",
+			keys.unspent)
+	}
+
+	// An unmined transaction spending the output must match as spending
+	// without removing the outpoint.
+	msgTx = wire.NewMsgTx()
+	msgTx.AddTxIn(wire.NewTxIn(&fundedOut, nil))
+	msgTx.AddTxOut(wire.NewTxOut(1500, otherScript))
+	spendingTx := coinutil.NewTx(msgTx)
+	spends, receives = keys.matchTx(spendingTx, false, params)
+	if !spends || receives {
+		t.Fatalf("matchTx unmined spend: got spends %v, receives %v - "+
+			"want true, false", spends, receives)
+	}
+	if _, ok := keys.unspent[fundedOut]; !ok {
+		t.Fatalf("matchTx unmined spend: outpoint was removed")
+	}
+
+	// Once mined, the spent outpoint must be removed.
+	keys.matchTx(spendingTx, true, params)
+	if len(keys.unspent) != 0 {
+		t.Fatalf("matchTx mined spend: unexpected unspent outpoints %v",
+			keys.unspent)
+	}
+
+	// Removing the address must stop matching further payments, while a
+	// clone taken before must keep matching.
+	clone := keys.clone()
+	keys.removeAddress(watched)
+	if _, receives := keys.matchTx(fundingTx, true, params); receives {
+		t.Fatalf("matchTx after removeAddress: transaction matched")
+	}
+	if _, receives := clone.matchTx(fundingTx, true, params); !receives {
+		t.Fatalf("matchTx on clone: transaction did not match")
+	}
+}
+
+// TestDoubleSpendRecipients ensures double spends are sent to the clients
+// registered for new transactions, watching a conflicting outpoint or
+// watching an address paid by either transaction, and to nobody else.
+func TestDoubleSpendRecipients(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	addr, err := coinutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20),
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+
+	spentOut := wire.OutPoint{Index: 1}
+	msgTx := wire.NewMsgTx()
+	msgTx.AddTxIn(wire.NewTxIn(&spentOut, nil))
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	conflict := wire.NewMsgTx()
+	conflict.AddTxIn(wire.NewTxIn(&spentOut, nil))
+	conflict.AddTxOut(wire.NewTxOut(1000, pkScript))
+	n := &notificationDoubleSpend{
+		tx:       coinutil.NewTx(msgTx),
+		conflict: coinutil.NewTx(conflict),
+		inputs:   []wire.OutPoint{spentOut},
+	}
+
+	newClient := func() *wsClient {
+		return &wsClient{quit: make(chan struct{})}
+	}
+	txClient, opClient, addrClient, other := newClient(), newClient(),
+		newClient(), newClient()
+	txClients := map[chan struct{}]*wsClient{txClient.quit: txClient}
+	ops := map[wire.OutPoint]map[chan struct{}]*wsClient{
+		spentOut:                {opClient.quit: opClient, txClient.quit: txClient},
+		wire.OutPoint{Index: 2}: {other.quit: other},
+	}
+	addrs := map[string]map[chan struct{}]*wsClient{
+		addr.EncodeAddress(): {addrClient.quit: addrClient},
+	}
+
+	recipients := doubleSpendRecipients(txClients, ops, addrs, n, params)
+	if len(recipients) != 3 {
+		t.Fatalf("recipients: got %d - want 3", len(recipients))
+	}
+	for _, wsc := range []*wsClient{txClient, opClient, addrClient} {
+		if recipients[wsc.quit] != wsc {
+			t.Errorf("recipients: missing client %p", wsc)
+		}
+	}
+	if _, ok := recipients[other.quit]; ok {
+		t.Errorf("recipients: unexpected client watching another " +
+			"outpoint")
+	}
+}
+
+// TestExpiredTxRecipients ensures expired transactions are sent to the clients
+// registered for new transactions, watching an outpoint spent by the
+// transaction or watching an address it pays to, and to nobody else.
+func TestExpiredTxRecipients(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	addr, err := coinutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20),
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+
+	spentOut := wire.OutPoint{Index: 1}
+	msgTx := wire.NewMsgTx()
+	msgTx.AddTxIn(wire.NewTxIn(&spentOut, nil))
+	msgTx.AddTxOut(wire.NewTxOut(1000, pkScript))
+
+	newClient := func() *wsClient {
+		return &wsClient{quit: make(chan struct{})}
+	}
+	txClient, opClient, addrClient, other := newClient(), newClient(),
+		newClient(), newClient()
+	txClients := map[chan struct{}]*wsClient{txClient.quit: txClient}
+	ops := map[wire.OutPoint]map[chan struct{}]*wsClient{
+		spentOut:                {opClient.quit: opClient},
+		wire.OutPoint{Index: 2}: {other.quit: other},
+	}
+	addrs := map[string]map[chan struct{}]*wsClient{
+		addr.EncodeAddress(): {addrClient.quit: addrClient},
+	}
+
+	recipients := expiredTxRecipients(txClients, ops, addrs,
+		coinutil.NewTx(msgTx), params)
+	if len(recipients) != 3 {
+		t.Fatalf("recipients: got %d - want 3", len(recipients))
+	}
+	for _, wsc := range []*wsClient{txClient, opClient, addrClient} {
+		if recipients[wsc.quit] != wsc {
+			t.Errorf("recipients: missing client %p", wsc)
+		}
+	}
+	if _, ok := recipients[other.quit]; ok {
+		t.Errorf("recipients: unexpected client watching another " +
+			"outpoint")
+	}
+}
+
+// TestTxHexes ensures the cached hex of a transaction matches its
+// serialization and is only computed once per notification.
+func TestTxHexes(t *testing.T) {
+	msgTx := wire.NewMsgTx()
+	msgTx.AddTxOut(wire.NewTxOut(1, []byte{0x51}))
+	tx := coinutil.NewTx(msgTx)
+
+	hexes := newTxHexes(nil)
+	want := txHexString(tx)
+	if got := hexes.get(tx); got != want {
+		t.Fatalf("get: got %s - want %s", got, want)
+	}
+
+	// Changing the transaction after it was cached must not change the
+	// cached hex since it is not serialized again.
+	msgTx.LockTime = 1
+	if got := hexes.get(tx); got != want {
+		t.Errorf("get after change: got %s - want cached %s", got, want)
+	}
+
+	// The transactions of a block are cut from the serialized block,
+	// including their witness data.
+	witnessTx := wire.NewMsgTx()
+	witnessTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil))
+	witnessTx.TxIn[0].Witness = wire.TxWitness{{0x01, 0x02}}
+	witnessTx.AddTxOut(wire.NewTxOut(2, []byte{0x52}))
+	msgBlock := &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{msgTx, witnessTx, msgTx.Copy()},
+	}
+	block := coinutil.NewBlock(msgBlock)
+	hexes = newTxHexes(block)
+	for i, blockTx := range block.Transactions() {
+		want := txHexString(blockTx)
+		if got := hexes.blockTxBytes(blockTx); got == nil {
+			t.Errorf("blockTxBytes #%d: transaction not found in "+
+				"block", i)
+		}
+		if got := hexes.get(blockTx); got != want {
+			t.Errorf("get #%d: got %s - want %s", i, got, want)
+		}
+	}
+
+	// Transactions which are not part of the block are serialized.
+	if hexes.blockTxBytes(tx) != nil {
+		t.Errorf("blockTxBytes: found transaction outside of block")
+	}
+	if got, want := hexes.get(tx), txHexString(tx); got != want {
+		t.Errorf("get outside of block: got %s - want %s", got, want)
+	}
+}
+
+// TestChainReorgNtfn ensures the chainreorg notification describes the fork
+// point and the detached and attached blocks of a reorganization in order.
+func TestChainReorgNtfn(t *testing.T) {
+	hashes := make([]wire.ShaHash, 4)
+	for i := range hashes {
+		hashes[i][0] = byte(i)
+	}
+	reorg := &blockchain.ChainReorganization{
+		ForkHash:   &hashes[0],
+		ForkHeight: 10,
+		Detached:   []*wire.ShaHash{&hashes[1]},
+		Attached:   []*wire.ShaHash{&hashes[2], &hashes[3]},
+	}
+
+	want := &btcjson.ChainReorgNtfn{
+		ForkHash:   hashes[0].String(),
+		ForkHeight: 10,
+		Detached:   []string{hashes[1].String()},
+		Attached:   []string{hashes[2].String(), hashes[3].String()},
+		Depth:      1,
+	}
+	if got := chainReorgNtfn(reorg); !reflect.DeepEqual(got, want) {
+		t.Errorf("chainReorgNtfn: got %+v - want %+v", got, want)
+	}
+}
+
+// newFanOutTestClients returns the passed number of websocket clients whose
+// queued notifications are passed to the returned channel along with the
+// function to stop them.
+func newFanOutTestClients(n int) (map[chan struct{}]*wsClient,
+	chan *wsClient, func()) {
+
+	clients := make(map[chan struct{}]*wsClient, n)
+	received := make(chan *wsClient, n)
+	quit := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wsc := &wsClient{
+			ntfnChan: make(chan []byte),
+			quit:     make(chan struct{}),
+		}
+		clients[wsc.quit] = wsc
+		go func() {
+			for {
+				select {
+				case <-wsc.ntfnChan:
+					received <- wsc
+				case <-quit:
+					return
+				}
+			}
+		}()
+	}
+	return clients, received, func() { close(quit) }
+}
+
+// newFanOutTestManager returns a notification manager running only its
+// fan-out workers.
+func newFanOutTestManager() *wsNotificationManager {
+	m := &wsNotificationManager{
+		fanOut: make(chan *fanOutBatch),
+		quit:   make(chan struct{}),
+	}
+	m.wg.Add(wsFanOutWorkers)
+	for i := 0; i < wsFanOutWorkers; i++ {
+		go m.fanOutHandler()
+	}
+	return m
+}
+
+// TestQueueToClients ensures a notification is queued exactly once to every
+// client both when it is queued directly and when it is split into batches
+// for the fan-out workers.
+func TestQueueToClients(t *testing.T) {
+	m := newFanOutTestManager()
+	defer func() {
+		close(m.quit)
+		m.wg.Wait()
+	}()
+
+	for _, n := range []int{1, wsFanOutBatchSize, 5*wsFanOutBatchSize + 3} {
+		clients, received, stop := newFanOutTestClients(n)
+		m.queueToClients(clients, []byte("{}"))
+
+		seen := make(map[*wsClient]int, n)
+		for i := 0; i < n; i++ {
+			seen[<-received]++
+		}
+		stop()
+		if len(seen) != n {
+			t.Errorf("queueToClients (%d clients): notified %d "+
+				"clients", n, len(seen))
+		}
+		for wsc, count := range seen {
+			if count != 1 {
+				t.Errorf("queueToClients (%d clients): client "+
+					"%p notified %d times", n, wsc, count)
+			}
+		}
+	}
+}
+
+// benchmarkQueueToClients performs a benchmark on how long it takes until a
+// notification is queued to 4096 websocket clients using the passed function.
+func benchmarkQueueToClients(b *testing.B, queue func(map[chan struct{}]*wsClient, []byte)) {
+	const numClients = 4096
+	clients, received, stop := newFanOutTestClients(numClients)
+	defer stop()
+	marshalledJSON := []byte(`{"jsonrpc":"1.0","method":"blockconnected"}`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		queue(clients, marshalledJSON)
+		for j := 0; j < numClients; j++ {
+			<-received
+		}
+	}
+}
+
+// BenchmarkQueueToClientsSerial performs a benchmark on queueing a
+// notification to every client one after the other.
+func BenchmarkQueueToClientsSerial(b *testing.B) {
+	benchmarkQueueToClients(b, func(clients map[chan struct{}]*wsClient,
+		marshalledJSON []byte) {
+
+		for _, wsc := range clients {
+			wsc.QueueNotification(marshalledJSON)
+		}
+	})
+}
+
+// BenchmarkQueueToClients performs a benchmark on queueing a notification to
+// every client through the fan-out workers.
+func BenchmarkQueueToClients(b *testing.B) {
+	m := newFanOutTestManager()
+	defer func() {
+		close(m.quit)
+		m.wg.Wait()
+	}()
+	benchmarkQueueToClients(b, m.queueToClients)
+}
+
+// TestWsNotificationQueue ensures queued notifications are combined into
+// frames holding JSON arrays of as many notifications as fit into the batch
+// size, and are sent individually when batching is disabled.
+func TestWsNotificationQueue(t *testing.T) {
+	ntfns := []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":"large"}`,
+		`{"n":4}`}
+	tests := []struct {
+		batchSize int
+		frames    []string
+	}{
+		{0, ntfns},
+		// Notifications which don't fit into a frame with the next
+		// one are not wrapped in an array.
+		{17, []string{`[{"n":1},{"n":2}]`, `{"n":3}`, `{"n":"large"}`,
+			`{"n":4}`}},
+		{100, []string{`[{"n":1},{"n":2},{"n":3},{"n":"large"},{"n":4}]`}},
+	}
+
+	for _, test := range tests {
+		q := newWsNotificationQueue(test.batchSize)
+		for _, ntfn := range ntfns {
+			q.push([]byte(ntfn))
+		}
+		var frames []string
+		for q.len() != 0 {
+			frames = append(frames, string(q.nextFrame()))
+		}
+		if !reflect.DeepEqual(frames, test.frames) {
+			t.Errorf("batch size %d: got frames %q - want %q",
+				test.batchSize, frames, test.frames)
+		}
+		if q.size != 0 {
+			t.Errorf("batch size %d: %d bytes left after sending "+
+				"every frame", test.batchSize, q.size)
+		}
+	}
+
+	q := newWsNotificationQueue(16)
+	q.push([]byte(ntfns[0]))
+	q.push([]byte(ntfns[1]))
+	if q.full() {
+		t.Errorf("full: got true for 14 of 16 bytes")
+	}
+	q.push([]byte(ntfns[2]))
+	if !q.full() {
+		t.Errorf("full: got false for 21 of 16 bytes")
+	}
+}
+
+// TestAddNotificationField ensures sequence numbers and event ids are added to
+// marshalled notifications.
+func TestAddNotificationField(t *testing.T) {
+	tests := []struct {
+		ntfn  string
+		field string
+		value uint64
+		want  string
+	}{
+		{
+			ntfn:  `{"jsonrpc":"1.0","method":"blockconnected","params":[],"id":null}`,
+			field: "seq",
+			value: 1,
+			want:  `{"jsonrpc":"1.0","method":"blockconnected","params":[],"id":null,"seq":1}`,
+		},
+		{
+			ntfn:  `{"method":"blockconnected","eventid":7}`,
+			field: "seq",
+			value: 18446744073709551615,
+			want:  `{"method":"blockconnected","eventid":7,"seq":18446744073709551615}`,
+		},
+		{
+			ntfn:  `{}`,
+			field: "seq",
+			value: 18446744073709551615,
+			want:  `{"seq":18446744073709551615}`,
+		},
+		{
+			ntfn:  `[1,2]`,
+			field: "seq",
+			value: 2,
+			want:  `[1,2]`,
+		},
+	}
+
+	for i, test := range tests {
+		got := string(addNotificationField([]byte(test.ntfn), test.field,
+			test.value))
+		if got != test.want {
+			t.Errorf("addNotificationField #%d: got %s - want %s", i,
+				got, test.want)
+		}
+	}
+
+	// Every event gets a new id, which marshalled notifications carry.
+	eventID := newNtfnEventID()
+	if eventID == 0 || newNtfnEventID() <= eventID {
+		t.Errorf("newNtfnEventID: event ids are not increasing")
+	}
+	marshalledJSON, err := marshalNotification(
+		btcjson.NewBlockConnectedNtfn("hash", 1, 0), eventID)
+	if err != nil {
+		t.Fatalf("marshalNotification: %v", err)
+	}
+	var ntfn struct {
+		EventID uint64 `json:"eventid"`
+	}
+	if err := json.Unmarshal(marshalledJSON, &ntfn); err != nil {
+		t.Fatalf("marshalNotification: %v", err)
+	}
+	if ntfn.EventID != eventID {
+		t.Errorf("marshalNotification: got event id %d - want %d",
+			ntfn.EventID, eventID)
+	}
+}
+
+// TestNotifyForScriptHashes ensures clients subscribed to a script hash are
+// notified once about each transaction paying to or spending from the script,
+// including outputs created and spent within the same block.
+func TestNotifyForScriptHashes(t *testing.T) {
+	watchedScript := []byte{txscript.OP_TRUE}
+	otherScript := []byte{txscript.OP_TRUE, txscript.OP_TRUE}
+
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&wire.ShaHash{},
+		wire.MaxPrevOutIndex), []byte{0x51}))
+	coinbase.AddTxOut(wire.NewTxOut(50, otherScript))
+	coinbaseHash := coinbase.TxSha()
+	pay := wire.NewMsgTx()
+	pay.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&coinbaseHash, 0), nil))
+	pay.AddTxOut(wire.NewTxOut(10, watchedScript))
+	pay.AddTxOut(wire.NewTxOut(20, watchedScript))
+	payHash := pay.TxSha()
+	spend := wire.NewMsgTx()
+	spend.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&payHash, 1), nil))
+	spend.AddTxOut(wire.NewTxOut(15, otherScript))
+
+	msgBlock := wire.NewMsgBlock(wire.NewBlockHeader(&wire.ShaHash{},
+		&wire.ShaHash{}, 0, 0))
+	msgBlock.AddTransaction(coinbase)
+	msgBlock.AddTransaction(pay)
+	msgBlock.AddTransaction(spend)
+	block := coinutil.NewBlock(msgBlock)
+	blockTxs := make(map[wire.ShaHash]*coinutil.Tx)
+	for _, tx := range block.Transactions() {
+		blockTxs[*tx.Sha()] = tx
+	}
+
+	wsc := &wsClient{
+		ntfnChan:           make(chan []byte, 10),
+		scriptHashRequests: make(map[wire.ShaHash]struct{}),
+		quit:               make(chan struct{}),
+	}
+	m := &wsNotificationManager{}
+	watched := make(map[wire.ShaHash]map[chan struct{}]*wsClient)
+	sh := scriptHash(watchedScript)
+	m.addScriptHashRequests(watched, wsc, []wire.ShaHash{sh})
+
+	hexes := newTxHexes(block)
+	for _, tx := range block.Transactions() {
+		m.notifyForScriptHashes(watched, tx, block, blockTxs, hexes)
+	}
+	if len(wsc.ntfnChan) != 2 {
+		t.Fatalf("got %d notifications, want 2", len(wsc.ntfnChan))
+	}
+	for _, tx := range block.Transactions()[1:] {
+		marshalled := <-wsc.ntfnChan
+		var ntfn struct {
+			Params []interface{} `json:"params"`
+		}
+		if err := json.Unmarshal(marshalled, &ntfn); err != nil {
+			t.Fatalf("failed to unmarshal notification: %v", err)
+		}
+		if ntfn.Params[0] != sh.String() ||
+			ntfn.Params[1] != hexes.get(tx) {
+
+			t.Errorf("unexpected notification %s for tx %v",
+				marshalled, tx.Sha())
+		}
+	}
+
+	// No notifications are sent once the client unsubscribed.
+	m.removeScriptHashRequest(watched, wsc, &sh)
+	if len(watched) != 0 || len(wsc.scriptHashRequests) != 0 {
+		t.Fatalf("subscription was not removed")
+	}
+	for _, tx := range block.Transactions() {
+		m.notifyForScriptHashes(watched, tx, block, blockTxs, hexes)
+	}
+	if len(wsc.ntfnChan) != 0 {
+		t.Fatalf("got %d notifications after unsubscribing",
+			len(wsc.ntfnChan))
+	}
+}
+
+// TestNotifyForTxOutsConfirmedRecvTx ensures clients which opted into confirmed
+// recvtx notifications are only notified about transactions paying to their
+// addresses once they are mined, while other clients are notified about them
+// when they are accepted into the memory pool as well.
+func TestNotifyForTxOutsConfirmedRecvTx(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	addr, err := coinutil.NewAddressPubKeyHash(make([]byte, 20), params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+	msgTx := wire.NewMsgTx()
+	msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&wire.ShaHash{}, 0), nil))
+	msgTx.AddTxOut(wire.NewTxOut(10, pkScript))
+	tx := coinutil.NewTx(msgTx)
+	block := coinutil.NewBlock(wire.NewMsgBlock(wire.NewBlockHeader(
+		&wire.ShaHash{}, &wire.ShaHash{}, 0, 0)))
+
+	newClient := func(confirmedRecvTx bool) *wsClient {
+		return &wsClient{
+			confirmedRecvTx: confirmedRecvTx,
+			spentRequests:   make(map[wire.OutPoint]struct{}),
+			ntfnChan:        make(chan []byte, 2),
+			quit:            make(chan struct{}),
+		}
+	}
+	all, confirmed := newClient(false), newClient(true)
+	m := &wsNotificationManager{
+		server: &rpcServer{server: &server{chainParams: params}},
+	}
+	ops := make(map[wire.OutPoint]map[chan struct{}]*wsClient)
+	addrs := map[string]map[chan struct{}]*wsClient{
+		addr.EncodeAddress(): {all.quit: all, confirmed.quit: confirmed},
+	}
+
+	m.notifyForTxOuts(ops, addrs, tx, nil, newTxHexes(nil))
+	if len(all.ntfnChan) != 1 || len(confirmed.ntfnChan) != 0 {
+		t.Fatalf("mempool transaction: got %d and %d notifications - "+
+			"want 1 and 0", len(all.ntfnChan), len(confirmed.ntfnChan))
+	}
+	m.notifyForTxOuts(ops, addrs, tx, block, newTxHexes(block))
+	if len(all.ntfnChan) != 2 || len(confirmed.ntfnChan) != 1 {
+		t.Fatalf("mined transaction: got %d and %d notifications - "+
+			"want 2 and 1", len(all.ntfnChan), len(confirmed.ntfnChan))
+	}
+
+	// Both clients watch the spends of the output either way.
+	if len(all.spentRequests) != 1 || len(confirmed.spentRequests) != 1 {
+		t.Fatalf("got %d and %d spent requests - want 1 and 1",
+			len(all.spentRequests), len(confirmed.spentRequests))
+	}
+}
+
+// TestFilteredAndScriptHashConfirmedRecvTx ensures clients which opted into
+// confirmed recvtx notifications are not sent filteredrecvtx and scripthashtx
+// notifications about mempool transactions which only pay to them, while they
+// are still notified about mempool transactions spending their outputs and
+// about mined transactions.
+func TestFilteredAndScriptHashConfirmedRecvTx(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	var pkHash [20]byte
+	addr, err := coinutil.NewAddressPubKeyHash(pkHash[:], params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+	otherScript := []byte{txscript.OP_TRUE}
+
+	// newTx returns a transaction spending the passed outpoint and paying
+	// to the passed script.
+	newTx := func(prevOut *wire.OutPoint, pkScript []byte) *coinutil.Tx {
+		msgTx := wire.NewMsgTx()
+		msgTx.AddTxIn(wire.NewTxIn(prevOut, nil))
+		msgTx.AddTxOut(wire.NewTxOut(10, pkScript))
+		return coinutil.NewTx(msgTx)
+	}
+	funding := newTx(&wire.OutPoint{Hash: wire.ShaHash{0x01}}, otherScript)
+	pay := newTx(wire.NewOutPoint(funding.Sha(), 0), pkScript)
+	spend := newTx(wire.NewOutPoint(pay.Sha(), 0), otherScript)
+	blockTxs := map[wire.ShaHash]*coinutil.Tx{
+		*funding.Sha(): funding,
+		*pay.Sha():     pay,
+	}
+	block := coinutil.NewBlock(wire.NewMsgBlock(wire.NewBlockHeader(
+		&wire.ShaHash{}, &wire.ShaHash{}, 0, 0)))
+
+	m := &wsNotificationManager{
+		server: &rpcServer{server: &server{chainParams: params}},
+	}
+	watched := make(map[wire.ShaHash]map[chan struct{}]*wsClient)
+	clients := make(map[chan struct{}]*wsClient)
+	newClient := func(confirmedRecvTx bool) *wsClient {
+		keys := newRescanKeys()
+		keys.pubKeyHashes[pkHash] = struct{}{}
+		wsc := &wsClient{
+			confirmedRecvTx:    confirmedRecvTx,
+			scriptHashRequests: make(map[wire.ShaHash]struct{}),
+			filters: map[string]*wsClientFilter{
+				"filter": {id: "filter", keys: keys},
+			},
+			ntfnChan: make(chan []byte, 6),
+			quit:     make(chan struct{}),
+		}
+		m.addScriptHashRequests(watched, wsc,
+			[]wire.ShaHash{scriptHash(pkScript)})
+		clients[wsc.quit] = wsc
+		return wsc
+	}
+	all, confirmed := newClient(false), newClient(true)
+
+	tests := []struct {
+		name       string
+		tx         *coinutil.Tx
+		block      *coinutil.Block
+		wantCounts [2]int
+	}{
+		{"mempool payment", pay, nil, [2]int{2, 0}},
+		{"mempool spend", spend, nil, [2]int{4, 2}},
+		{"mined payment", pay, block, [2]int{6, 4}},
+	}
+	for _, test := range tests {
+		m.eventID = newNtfnEventID()
+		hexes := newTxHexes(test.block)
+		m.notifyForScriptHashes(watched, test.tx, test.block, blockTxs,
+			hexes)
+		m.notifyFilteredTx(clients, test.tx, test.block, hexes)
+		counts := [2]int{len(all.ntfnChan), len(confirmed.ntfnChan)}
+		if counts != test.wantCounts {
+			t.Fatalf("%s: got %v notifications - want %v", test.name,
+				counts, test.wantCounts)
+		}
+	}
+
+	// The notifications about the mined transaction carry the id of the
+	// event.
+	for i := 0; i < 4; i++ {
+		marshalledJSON := <-confirmed.ntfnChan
+		if i < 2 {
+			continue
+		}
+		var ntfn struct {
+			EventID uint64 `json:"eventid"`
+		}
+		if err := json.Unmarshal(marshalledJSON, &ntfn); err != nil {
+			t.Fatalf("failed to unmarshal notification: %v", err)
+		}
+		if ntfn.EventID != m.eventID {
+			t.Errorf("got event id %d - want %d", ntfn.EventID,
+				m.eventID)
+		}
+	}
+}
+
+// TestSubscriptions ensures the notification manager reports the registrations
+// of a single client as well as of every connected client, including those
+// requested right before the query.
+func TestSubscriptions(t *testing.T) {
+	savedCfg := cfg
+	cfg = &config{}
+	defer func() { cfg = savedCfg }()
+
+	m := newWsNotificationManager(&rpcServer{})
+	m.Start()
+	defer func() {
+		m.Shutdown()
+		m.WaitForShutdown()
+	}()
+
+	// The clients are marked disconnected so the manager doesn't try to
+	// close their connections when it shuts down.
+	newClient := func(addr string) *wsClient {
+		return &wsClient{
+			disconnected:       true,
+			addr:               addr,
+			addrRequests:       make(map[string]struct{}),
+			scriptHashRequests: make(map[wire.ShaHash]struct{}),
+			spentRequests:      make(map[wire.OutPoint]struct{}),
+			quit:               make(chan struct{}),
+		}
+	}
+	wsc1, wsc2 := newClient("client1"), newClient("client2")
+	m.AddClient(wsc1)
+	m.AddClient(wsc2)
+
+	op := wire.OutPoint{Index: 1}
+	m.RegisterBlockUpdates(wsc1)
+	m.RegisterTxOutAddressRequests(wsc1, []string{"addr1", "addr2"})
+	m.RegisterSpentRequests(wsc1, []*wire.OutPoint{&op})
+	m.RegisterClockSkewUpdates(wsc2)
+	m.RegisterPeerEventUpdates(wsc2)
+
+	subs := m.Subscriptions(wsc1)
+	if len(subs) != 1 {
+		t.Fatalf("Subscriptions: got %d clients - want 1", len(subs))
+	}
+	sub := subs[0]
+	sort.Strings(sub.addrs)
+	if !sub.blocks || sub.headers || sub.newTxs || sub.clockSkew ||
+		sub.peerEvents {
+
+		t.Errorf("Subscriptions: unexpected notifications %+v", sub)
+	}
+	if !reflect.DeepEqual(sub.addrs, []string{"addr1", "addr2"}) {
+		t.Errorf("Subscriptions: got addresses %v - want [addr1 "+
+			"addr2]", sub.addrs)
+	}
+	if !reflect.DeepEqual(sub.outPoints, []wire.OutPoint{op}) {
+		t.Errorf("Subscriptions: got outpoints %v - want [%v]",
+			sub.outPoints, op)
+	}
+
+	m.UnregisterBlockUpdates(wsc1)
+	subs = m.Subscriptions(nil)
+	if len(subs) != 2 {
+		t.Fatalf("Subscriptions: got %d clients - want 2", len(subs))
+	}
+	for _, sub := range subs {
+		switch sub.wsc {
+		case wsc1:
+			if sub.blocks || len(sub.addrs) != 2 {
+				t.Errorf("Subscriptions: unexpected registrations "+
+					"of client 1 %+v", sub)
+			}
+		case wsc2:
+			if !sub.clockSkew || !sub.peerEvents ||
+				len(sub.addrs) != 0 {
+
+				t.Errorf("Subscriptions: unexpected registrations "+
+					"of client 2 %+v", sub)
+			}
+		default:
+			t.Errorf("Subscriptions: unexpected client %v", sub.wsc.addr)
+		}
+	}
+}
+
+// TestReorgNotificationOrder ensures the block notifications of a
+// reorganization reach clients in the order the block manager publishes them:
+// the disconnected blocks from the old tip down, then the connected blocks and
+// finally the reorganization itself.
+func TestReorgNotificationOrder(t *testing.T) {
+	savedCfg := cfg
+	cfg = &config{}
+	defer func() { cfg = savedCfg }()
+
+	m := newWsNotificationManager(&rpcServer{})
+	m.Start()
+	defer func() {
+		m.Shutdown()
+		m.WaitForShutdown()
+	}()
+
+	wsc := &wsClient{
+		addrRequests:       make(map[string]struct{}),
+		scriptHashRequests: make(map[wire.ShaHash]struct{}),
+		spentRequests:      make(map[wire.OutPoint]struct{}),
+		ntfnChan:           make(chan []byte, 5),
+		quit:               make(chan struct{}),
+	}
+	m.AddClient(wsc)
+	m.RegisterBlockUpdates(wsc)
+
+	// The hashes of the blocks are cached before they are passed to the
+	// manager since computing them is not safe for concurrent access.
+	newBlock := func(height int32, nonce uint32) *coinutil.Block {
+		header := wire.NewBlockHeader(&wire.ShaHash{}, &wire.ShaHash{},
+			0x207fffff, nonce)
+		block := coinutil.NewBlock(wire.NewMsgBlock(header))
+		block.SetHeight(height)
+		block.Sha()
+		return block
+	}
+	old1, old2 := newBlock(1, 1), newBlock(2, 2)
+	new1, new2 := newBlock(1, 3), newBlock(2, 4)
+	m.NotifyBlockDisconnected(old2)
+	m.NotifyBlockDisconnected(old1)
+	m.NotifyBlockConnected(new1)
+	m.NotifyBlockConnected(new2)
+	m.NotifyChainReorg(&blockchain.ChainReorganization{
+		Detached: []*wire.ShaHash{old2.Sha(), old1.Sha()},
+		Attached: []*wire.ShaHash{new1.Sha(), new2.Sha()},
+		ForkHash: &wire.ShaHash{},
+	})
+
+	want := []struct {
+		method string
+		hash   string
+	}{
+		{btcjson.BlockDisconnectedNtfnMethod, old2.Sha().String()},
+		{btcjson.BlockDisconnectedNtfnMethod, old1.Sha().String()},
+		{btcjson.BlockConnectedNtfnMethod, new1.Sha().String()},
+		{btcjson.BlockConnectedNtfnMethod, new2.Sha().String()},
+		{btcjson.ChainReorgNtfnMethod, ""},
+	}
+	for i, w := range want {
+		var ntfn struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(<-wsc.ntfnChan, &ntfn); err != nil {
+			t.Fatalf("notification #%d: %v", i, err)
+		}
+		if ntfn.Method != w.method {
+			t.Fatalf("notification #%d: got method %s - want %s", i,
+				ntfn.Method, w.method)
+		}
+		if w.hash != "" && string(ntfn.Params[0]) != `"`+w.hash+`"` {
+			t.Fatalf("notification #%d: got block %s - want %s", i,
+				ntfn.Params[0], w.hash)
+		}
+	}
+
+	// Remove the client and wait for the removal so the manager doesn't
+	// try to close its connection when it shuts down.
+	m.RemoveClient(wsc)
+	m.Subscriptions(nil)
+}
+
+// TestWatchLimits ensures registrations which would make a client watch more
+// addresses or outpoints than allowed are refused as a whole, that entries the
+// client already watches and the entries of its filters are accounted for, and
+// that the limits are per client.
+func TestWatchLimits(t *testing.T) {
+	savedCfg := cfg
+	cfg = &config{RPCMaxWatchAddrs: 3, RPCMaxWatchOuts: 2}
+	defer func() { cfg = savedCfg }()
+
+	m := newWsNotificationManager(&rpcServer{})
+	m.Start()
+	defer func() {
+		m.Shutdown()
+		m.WaitForShutdown()
+	}()
+
+	// The clients are marked disconnected so the manager doesn't try to
+	// close their connections when it shuts down.
+	newClient := func(addr string) *wsClient {
+		return &wsClient{
+			disconnected:       true,
+			addr:               addr,
+			addrRequests:       make(map[string]struct{}),
+			scriptHashRequests: make(map[wire.ShaHash]struct{}),
+			spentRequests:      make(map[wire.OutPoint]struct{}),
+			filters:            make(map[string]*wsClientFilter),
+			quit:               make(chan struct{}),
+		}
+	}
+	wsc1, wsc2 := newClient("client1"), newClient("client2")
+	m.AddClient(wsc1)
+	m.AddClient(wsc2)
+
+	// isWatchLimit returns whether the passed error is the RPC error
+	// returned when a limit would be exceeded.
+	isWatchLimit := func(err error) bool {
+		rpcErr, ok := err.(*btcjson.RPCError)
+		return ok && rpcErr.Code == btcjson.ErrRPCWatchLimit
+	}
+
+	err := m.RegisterTxOutAddressRequests(wsc1, []string{"addr1", "addr2"})
+	if err != nil {
+		t.Fatalf("RegisterTxOutAddressRequests: unexpected error: %v",
+			err)
+	}
+	// Addresses which are already watched don't count again.
+	err = m.RegisterTxOutAddressRequests(wsc1, []string{"addr2", "addr3"})
+	if err != nil {
+		t.Fatalf("RegisterTxOutAddressRequests: unexpected error: %v",
+			err)
+	}
+	// Script hashes count as addresses.
+	err = m.RegisterScriptHashRequests(wsc1, []wire.ShaHash{{0x01}})
+	if !isWatchLimit(err) {
+		t.Fatalf("RegisterScriptHashRequests: got error %v - want "+
+			"watch limit error", err)
+	}
+
+	ops := []*wire.OutPoint{{Index: 0}, {Index: 1}, {Index: 2}}
+	err = m.RegisterSpentRequests(wsc1, ops)
+	if !isWatchLimit(err) {
+		t.Fatalf("RegisterSpentRequests: got error %v - want watch "+
+			"limit error", err)
+	}
+	// Refused registrations are not registered in part.
+	if err := m.RegisterSpentRequests(wsc1, ops[:2]); err != nil {
+		t.Fatalf("RegisterSpentRequests: unexpected error: %v", err)
+	}
+
+	// The entries of transaction filters count towards the limits.
+	keys := newRescanKeys()
+	keys.fallbacks["addr4"] = struct{}{}
+	wsc2.filters["filter"] = &wsClientFilter{id: "filter", keys: keys}
+	if err := m.CheckWatchLimits(wsc2, 2, 2); err != nil {
+		t.Fatalf("CheckWatchLimits: unexpected error: %v", err)
+	}
+	if err := m.CheckWatchLimits(wsc2, 3, 0); !isWatchLimit(err) {
+		t.Fatalf("CheckWatchLimits: got error %v - want watch limit "+
+			"error", err)
+	}
+
+	watched := make(map[string][2]int)
+	for _, w := range m.Watched() {
+		watched[w.wsc.addr] = [2]int{w.addrs, w.outPoints}
+	}
+	want := map[string][2]int{"client1": {3, 2}, "client2": {1, 0}}
+	if !reflect.DeepEqual(watched, want) {
+		t.Errorf("Watched: got %v - want %v", watched, want)
+	}
+}
+
+// TestConfirmationRequests ensures a txconfirmed notification is sent once a
+// watched transaction reaches the number of confirmations a client waits for,
+// that the confirmations are counted again when the block containing the
+// transaction is disconnected, and that the request is removed once notified.
+func TestConfirmationRequests(t *testing.T) {
+	msgTx := wire.NewMsgTx()
+	msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&wire.ShaHash{}, 0), nil))
+	msgTx.AddTxOut(wire.NewTxOut(10, nil))
+	tx := coinutil.NewTx(msgTx)
+
+	newBlock := func(height int32, nonce uint32, withTx bool) *coinutil.Block {
+		header := wire.NewBlockHeader(&wire.ShaHash{}, &wire.ShaHash{},
+			0x207fffff, nonce)
+		msgBlock := wire.NewMsgBlock(header)
+		if withTx {
+			msgBlock.AddTransaction(msgTx)
+		}
+		block := coinutil.NewBlock(msgBlock)
+		block.SetHeight(height)
+		return block
+	}
+
+	newClient := func(confirmations int32) *wsClient {
+		return &wsClient{
+			confirmationRequests: map[wire.ShaHash]int32{
+				*tx.Sha(): confirmations,
+			},
+			ntfnChan: make(chan []byte, 1),
+			quit:     make(chan struct{}),
+		}
+	}
+	two, three := newClient(2), newClient(3)
+	m := &wsNotificationManager{}
+	txs := map[wire.ShaHash]*txConfirmationRequests{
+		*tx.Sha(): {
+			height: -1,
+			clients: map[chan struct{}]*wsClient{
+				two.quit:   two,
+				three.quit: three,
+			},
+		},
+	}
+
+	// The transaction is mined at height 10 and then reorganized out of
+	// the main chain before it has two confirmations.
+	old10, old11 := newBlock(10, 1, true), newBlock(11, 2, false)
+	m.notifyConfirmations(txs, old10)
+	m.rearmConfirmationRequests(txs, old11)
+	m.rearmConfirmationRequests(txs, old10)
+	m.notifyConfirmations(txs, newBlock(10, 3, false))
+	if len(two.ntfnChan) != 0 || len(three.ntfnChan) != 0 {
+		t.Fatalf("got notifications before the confirmations")
+	}
+
+	// The transaction is mined again at height 11 so it has two
+	// confirmations at height 12 and three at height 13.
+	new11 := newBlock(11, 4, true)
+	m.notifyConfirmations(txs, new11)
+	m.notifyConfirmations(txs, newBlock(12, 5, false))
+	if len(two.ntfnChan) != 1 || len(three.ntfnChan) != 0 {
+		t.Fatalf("height 12: got %d and %d notifications - want 1 and 0",
+			len(two.ntfnChan), len(three.ntfnChan))
+	}
+	m.notifyConfirmations(txs, newBlock(13, 6, false))
+	if len(three.ntfnChan) != 1 {
+		t.Fatalf("height 13: got %d notifications - want 1",
+			len(three.ntfnChan))
+	}
+
+	for _, test := range []struct {
+		wsc           *wsClient
+		confirmations int32
+	}{
+		{two, 2},
+		{three, 3},
+	} {
+		var req btcjson.Request
+		if err := json.Unmarshal(<-test.wsc.ntfnChan, &req); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		cmd, err := btcjson.UnmarshalCmd(&req)
+		if err != nil {
+			t.Fatalf("UnmarshalCmd: %v", err)
+		}
+		want := btcjson.NewTxConfirmedNtfn(tx.Sha().String(),
+			test.confirmations, new11.Sha().String(), 11)
+		if !reflect.DeepEqual(cmd, want) {
+			t.Fatalf("got notification %+v - want %+v", cmd, want)
+		}
+		if len(test.wsc.confirmationRequests) != 0 {
+			t.Fatalf("request not removed after the notification")
+		}
+	}
+	if len(txs) != 0 {
+		t.Fatalf("transaction still watched after the notifications")
+	}
+}
+
+// TestMempoolNotifications ensures clients registered with subscribemempool
+// receive the additions to and removals from the memory pool in order, and
+// that other clients don't.
+func TestMempoolNotifications(t *testing.T) {
+	savedCfg := cfg
+	cfg = &config{}
+	defer func() { cfg = savedCfg }()
+
+	m := newWsNotificationManager(&rpcServer{})
+	m.Start()
+	defer func() {
+		m.Shutdown()
+		m.WaitForShutdown()
+	}()
+
+	newClient := func() *wsClient {
+		return &wsClient{
+			addrRequests:       make(map[string]struct{}),
+			scriptHashRequests: make(map[wire.ShaHash]struct{}),
+			spentRequests:      make(map[wire.OutPoint]struct{}),
+			ntfnChan:           make(chan []byte, 2),
+			quit:               make(chan struct{}),
+		}
+	}
+	subscribed, other := newClient(), newClient()
+	m.AddClient(subscribed)
+	m.AddClient(other)
+	m.RegisterMempoolUpdates(subscribed)
+
+	// The hash of the transaction is cached before it is passed to the
+	// manager since computing it is not safe for concurrent access.
+	msgTx := wire.NewMsgTx()
+	msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&wire.ShaHash{}, 0), nil))
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	tx := coinutil.NewTx(msgTx)
+	tx.Sha()
+	added := time.Unix(1431542226, 0)
+	m.NotifyMempoolTxAdded(&mempoolTxDesc{
+		TxDesc: mining.TxDesc{Tx: tx, Added: added, Fee: 10000},
+	})
+	m.NotifyMempoolTxRemoved(tx, txRemovedBlock)
+
+	want := []interface{}{
+		btcjson.NewMempoolTxAddedNtfn(tx.Sha().String(),
+			blockchain.GetTxVirtualSize(tx), 0.0001, added.Unix()),
+		btcjson.NewMempoolTxRemovedNtfn(tx.Sha().String(), "block"),
+	}
+	for i, w := range want {
+		var req btcjson.Request
+		if err := json.Unmarshal(<-subscribed.ntfnChan, &req); err != nil {
+			t.Fatalf("notification #%d: %v", i, err)
+		}
+		ntfn, err := btcjson.UnmarshalCmd(&req)
+		if err != nil {
+			t.Fatalf("notification #%d: %v", i, err)
+		}
+		if !reflect.DeepEqual(ntfn, w) {
+			t.Fatalf("notification #%d: got %+v - want %+v", i, ntfn,
+				w)
+		}
+	}
+
+	// Wait for the manager to process everything queued before making
+	// sure the other client was left out.
+	subs := m.Subscriptions(nil)
+	if len(other.ntfnChan) != 0 {
+		t.Fatalf("unsubscribed client got %d notifications",
+			len(other.ntfnChan))
+	}
+	for _, sub := range subs {
+		if sub.mempool != (sub.wsc == subscribed) {
+			t.Fatalf("client %p reported with mempool subscription "+
+				"%v", sub.wsc, sub.mempool)
+		}
+	}
+
+	m.RemoveClient(subscribed)
+	m.RemoveClient(other)
+	m.Subscriptions(nil)
+}
@@ -22,9 +22,9 @@ import (
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/coinutil/bloom"
 	"github.com/conseweb/stcd/addrmgr"
-	"github.com/conseweb/stcd/blockchain"
 	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/gcs"
 	"github.com/conseweb/stcd/mining"
 	"github.com/conseweb/stcd/peer"
 	"github.com/conseweb/stcd/txscript"
@@ -41,7 +41,7 @@ const (
 const (
 	// defaultServices describes the default services that are supported by
 	// the server.
-	defaultServices = wire.SFNodeNetwork | wire.SFNodeBloom
+	defaultServices = wire.SFNodeNetwork | wire.SFNodeBloom | wire.SFNodeWitness
 
 	// defaultMaxOutbound is the default number of max outbound peers.
 	defaultMaxOutbound = 8
@@ -56,6 +56,11 @@ const (
 	// retry logic uses a backoff mechanism which increases the interval
 	// base done the number of retries that have been done.
 	maxConnectionRetryInterval = time.Minute * 5
+
+	// mempoolExpiryInterval is how often the memory pool is checked for
+	// transactions which have stayed in it for longer than the configured
+	// expiry.
+	mempoolExpiryInterval = time.Minute * 10
 )
 
 var (
@@ -83,6 +88,19 @@ type broadcastInventoryAdd relayMsg
 // needs to be removed from the rebroadcast map
 type broadcastInventoryDel *wire.InvVect
 
+// getBroadcastsMsg is a type used to request the transactions in the
+// rebroadcast map.
+type getBroadcastsMsg struct {
+	reply chan []*unconfirmedBroadcast
+}
+
+// removeBroadcastMsg is a type used to request the removal of a transaction
+// from the rebroadcast map.  The reply reports whether it was present.
+type removeBroadcastMsg struct {
+	txHash *wire.ShaHash
+	reply  chan bool
+}
+
 // relayMsg packages an inventory vector along with the newly discovered
 // inventory so the relay has access to that information.
 type relayMsg struct {
@@ -176,9 +194,23 @@ type server struct {
 	bytesSent            uint64     // Total bytes sent by all peers since start.
 	addrManager          *addrmgr.AddrManager
 	sigCache             *txscript.SigCache
+	events               *eventBus
+	txHooks              *txHooks
+	txBlacklist          *txBlacklist
 	rpcServer            *rpcServer
 	blockManager         *blockManager
 	addrIndexer          *addrIndexer
+	indexManager         *indexManager
+	cfIndexer            *cfIndexer
+	coinAgeIndexer       *coinAgeIndexer
+	stxoIndexer          *stxoIndexer
+	addrBalanceIndexer   *addrBalanceIndexer
+	blockImporter        *blockImporter
+	notifyCmdRunner      *notifyCmdRunner
+	inboundLimiter       *inboundLimiter
+	locatorCache         *locatorCache
+	dbCompactor          *dbCompactor
+	networkFaults        *networkFaults
 	txMemPool            *txMemPool
 	cpuMiner             *CPUMiner
 	relayNtfnChan        chan *coinutil.Tx
@@ -197,8 +229,19 @@ type server struct {
 	quit                 chan struct{}
 	nat                  NAT
 	db                   database.Db
-	timeSource           blockchain.MedianTimeSource
+	timeSource           *mockTimeSource
 	services             wire.ServiceFlag
+
+	// clockSkewed is set while the local clock differs from the median
+	// time of peers by more than the configured maximum.  It is protected
+	// by clockSkewMtx.
+	clockSkewMtx sync.Mutex
+	clockSkewed  bool
+
+	// blockVersion is the version of the block templates set with the
+	// setblockversion command, or 0 when the computed version is used.  It
+	// must be accessed atomically.
+	blockVersion int32
 }
 
 // serverPeer extends the peer to maintain state shared by the server and
@@ -216,8 +259,17 @@ type serverPeer struct {
 	requestedBlocks map[wire.ShaHash]struct{}
 	filter          *bloom.Filter
 	knownAddresses  map[string]struct{}
+	misbehavior     misbehaviorScore
 	quit            chan struct{}
 
+	// The following fields hold the version message of an inbound peer
+	// connecting during a connection flood until it answered the ping
+	// with the nonce it was challenged with.  They are protected by
+	// challengeMtx.
+	challengeMtx   sync.Mutex
+	challengeNonce uint64
+	challengeMsg   *wire.MsgVersion
+
 	// The following chans are used to sync blockmanager and server.
 	txProcessed    chan struct{}
 	blockProcessed chan struct{}
@@ -271,6 +323,31 @@ func (sp *serverPeer) relayTxDisabled() bool {
 	return sp.disableRelayTx
 }
 
+// witnessEnabled returns whether or not the peer advertised support for
+// segregated witness and is therefore able to receive witness data.
+// It is safe for concurrent access.
+func (sp *serverPeer) witnessEnabled() bool {
+	return sp.Services()&wire.SFNodeWitness == wire.SFNodeWitness
+}
+
+// addBanScore increases the misbehavior score of the peer for the passed kind
+// of misbehavior.  The peer is banned and disconnected once its score reaches
+// the ban threshold, in which case true is returned.
+func (sp *serverPeer) addBanScore(reason misbehaviorReason, detail string) bool {
+	score := sp.misbehavior.Increase(reason, time.Now())
+	peerLog.Warnf("Misbehaving peer %s: %s (%s) -- ban score increased "+
+		"to %d", sp, reason, detail, score)
+	if score < cfg.BanThreshold {
+		return false
+	}
+
+	peerLog.Warnf("Misbehaving peer %s reached the ban threshold of %d "+
+		"-- banning and disconnecting", sp, cfg.BanThreshold)
+	sp.server.BanPeer(sp)
+	sp.Disconnect()
+	return true
+}
+
 // pushAddrMsg sends an addr message to the connected peer using the provided
 // addresses.
 func (sp *serverPeer) pushAddrMsg(addresses []*wire.NetAddress) {
@@ -290,13 +367,164 @@ func (sp *serverPeer) pushAddrMsg(addresses []*wire.NetAddress) {
 	sp.addKnownAddresses(known)
 }
 
+// checkClockSkew compares the local clock with the median time of peers and
+// warns when they differ by more than the configured maximum.  The warning is
+// only logged, and websocket clients only notified, when the local clock
+// becomes skewed or returns to within the maximum.
+func (s *server) checkClockSkew() {
+	offset := s.timeSource.MedianOffset()
+	skewed := offset > cfg.MaxClockSkew || offset < -cfg.MaxClockSkew
+
+	s.clockSkewMtx.Lock()
+	changed := skewed != s.clockSkewed
+	s.clockSkewed = skewed
+	s.clockSkewMtx.Unlock()
+	if !changed {
+		return
+	}
+
+	if skewed {
+		srvrLog.Warnf("Local clock differs from the median time of "+
+			"peers by %v -- please check your date and time are "+
+			"correct", offset)
+	} else {
+		srvrLog.Infof("Local clock is back within %v of the median "+
+			"time of peers", cfg.MaxClockSkew)
+	}
+	if s.rpcServer != nil {
+		s.rpcServer.ntfnMgr.NotifyClockSkew(offset, skewed)
+	}
+}
+
+// SetBlockVersionOverride sets the version of new block templates to the passed
+// version instead of the one computed from the rule change deployments.  A
+// version of 0 restores the computed version.
+//
+// This function is safe for concurrent access.
+func (s *server) SetBlockVersionOverride(version int32) {
+	atomic.StoreInt32(&s.blockVersion, version)
+}
+
+// BlockVersionOverride returns the version set with SetBlockVersionOverride,
+// or 0 when new block templates use the computed version.
+//
+// This function is safe for concurrent access.
+func (s *server) BlockVersionOverride() int32 {
+	return atomic.LoadInt32(&s.blockVersion)
+}
+
+// clockSkewWarning returns a warning to report via RPC when the local clock
+// differs from the median time of peers by more than the configured maximum.
+// An empty string is returned otherwise.
+func (s *server) clockSkewWarning() string {
+	s.clockSkewMtx.Lock()
+	skewed := s.clockSkewed
+	s.clockSkewMtx.Unlock()
+	if !skewed {
+		return ""
+	}
+
+	return fmt.Sprintf("Local clock differs from the median time of peers "+
+		"by %v -- please check your date and time are correct",
+		s.timeSource.MedianOffset())
+}
+
+// notifyDoubleSpend passes a valid transaction the memory pool rejected because
+// it double spends the passed inputs of a pool transaction on to the websocket
+// clients interested in either transaction.
+func (s *server) notifyDoubleSpend(tx, conflict *coinutil.Tx,
+	inputs []wire.OutPoint) {
+
+	if s.rpcServer != nil {
+		s.rpcServer.ntfnMgr.NotifyDoubleSpend(tx, conflict, inputs)
+	}
+}
+
 // OnVersion is invoked when a peer receives a version bitcoin message
 // and is used to negotiate the protocol version details as well as kick start
-// the communications.
+// the communications.  Inbound peers connecting during a connection flood are
+// challenged first when challenges are enabled.
 func (sp *serverPeer) OnVersion(p *peer.Peer, msg *wire.MsgVersion) {
+	if cfg.InboundChallenge && p.Inbound() &&
+		sp.server.inboundLimiter.UnderFlood(time.Now()) &&
+		!sp.server.inboundLimiter.IsWhitelisted(p.NA().IP) {
+
+		sp.challenge(p, msg)
+		return
+	}
+	sp.acceptVersion(p, msg)
+}
+
+// challenge sends a ping with a random nonce to an inbound peer connecting
+// during a connection flood.  The peer is only added to the server once it
+// answered with the same nonce, which costs a flooding attacker a round trip
+// for every connection, and is disconnected if it doesn't answer in time.
+func (sp *serverPeer) challenge(p *peer.Peer, msg *wire.MsgVersion) {
+	// Peers this old don't answer pings.
+	if p.ProtocolVersion() <= wire.BIP0031Version {
+		srvrLog.Debugf("Inbound peer %s is too old to be challenged "+
+			"during connection flood -- disconnecting", p)
+		p.Disconnect()
+		return
+	}
+
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		srvrLog.Errorf("Unable to generate challenge nonce: %v", err)
+		p.Disconnect()
+		return
+	}
+	sp.challengeMtx.Lock()
+	sp.challengeNonce = nonce
+	sp.challengeMsg = msg
+	sp.challengeMtx.Unlock()
+
+	srvrLog.Debugf("Challenging inbound peer %s during connection flood", p)
+	p.QueueMessage(wire.NewMsgPing(nonce), nil)
+	time.AfterFunc(inboundChallengeTimeout, func() {
+		if sp.challenged() {
+			srvrLog.Debugf("Inbound peer %s did not answer challenge "+
+				"within %v -- disconnecting", p,
+				inboundChallengeTimeout)
+			p.Disconnect()
+		}
+	})
+}
+
+// challenged returns whether the peer has yet to answer its challenge.
+// Messages from challenged peers are ignored.
+func (sp *serverPeer) challenged() bool {
+	sp.challengeMtx.Lock()
+	defer sp.challengeMtx.Unlock()
+
+	return sp.challengeMsg != nil
+}
+
+// OnPong is invoked when a peer receives a pong bitcoin message.  A challenged
+// peer answering with the nonce it was challenged with is added to the server.
+func (sp *serverPeer) OnPong(p *peer.Peer, msg *wire.MsgPong) {
+	sp.challengeMtx.Lock()
+	versionMsg := sp.challengeMsg
+	passed := versionMsg != nil && msg.Nonce == sp.challengeNonce
+	if passed {
+		sp.challengeMsg = nil
+		sp.challengeNonce = 0
+	}
+	sp.challengeMtx.Unlock()
+
+	if passed {
+		srvrLog.Debugf("Inbound peer %s answered challenge", p)
+		sp.acceptVersion(p, versionMsg)
+	}
+}
+
+// acceptVersion adds a peer whose version message has been received to the
+// server.
+func (sp *serverPeer) acceptVersion(p *peer.Peer, msg *wire.MsgVersion) {
 	// Add the remote peer time as a sample for creating an offset against
 	// the local clock to keep the network time in sync.
 	sp.server.timeSource.AddTimeSample(p.Addr(), msg.Timestamp)
+	sp.server.checkClockSkew()
 
 	// Signal the block manager this peer is a new sync candidate.
 	sp.server.blockManager.NewPeer(sp)
@@ -398,12 +626,20 @@ func (sp *serverPeer) OnMemPool(p *peer.Peer, msg *wire.MsgMemPool) {
 // handler this does not serialize all transactions through a single thread
 // transactions don't rely on the previous one in a linear fashion like blocks.
 func (sp *serverPeer) OnTx(p *peer.Peer, msg *wire.MsgTx) {
+	if sp.challenged() {
+		return
+	}
+
 	// Add the transaction to the known inventory for the peer.
 	// Convert the raw MsgTx to a coinutil.Tx which provides some convenience
 	// methods and things such as hash caching.
 	tx := coinutil.NewTx(msg)
 	iv := wire.NewInvVect(wire.InvTypeTx, tx.Sha())
 	p.AddKnownInventory(iv)
+	if msg.HasWitness() {
+		witnessHash := msg.WitnessHash()
+		p.AddKnownInventory(wire.NewInvVect(wire.InvTypeWTx, &witnessHash))
+	}
 
 	// Queue the transaction up to be handled by the block manager and
 	// intentionally block further receives until the transaction is fully
@@ -417,6 +653,10 @@ func (sp *serverPeer) OnTx(p *peer.Peer, msg *wire.MsgTx) {
 // OnBlock is invoked when a peer receives a block bitcoin message.  It
 // blocks until the bitcoin block has been fully processed.
 func (sp *serverPeer) OnBlock(p *peer.Peer, msg *wire.MsgBlock, buf []byte) {
+	if sp.challenged() {
+		return
+	}
+
 	// Convert the raw MsgBlock to a coinutil.Block which provides some
 	// convenience methods and things such as hash caching.
 	block := coinutil.NewBlockFromBlockAndBytes(msg, buf)
@@ -445,12 +685,18 @@ func (sp *serverPeer) OnBlock(p *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 // accordingly.  We pass the message down to blockmanager which will call
 // QueueMessage with any appropriate responses.
 func (sp *serverPeer) OnInv(p *peer.Peer, msg *wire.MsgInv) {
+	if sp.challenged() {
+		return
+	}
 	sp.server.blockManager.QueueInv(msg, sp)
 }
 
 // OnHeaders is invoked when a peer receives a headers bitcoin
 // message.  The message is passed down to the block manager.
 func (sp *serverPeer) OnHeaders(p *peer.Peer, msg *wire.MsgHeaders) {
+	if sp.challenged() {
+		return
+	}
 	sp.server.blockManager.QueueHeaders(msg, sp)
 }
 
@@ -478,10 +724,13 @@ func (sp *serverPeer) OnGetData(p *peer.Peer, msg *wire.MsgGetData) {
 		}
 		var err error
 		switch iv.Type {
-		case wire.InvTypeTx:
-			err = sp.server.pushTxMsg(sp, &iv.Hash, c, waitChan)
-		case wire.InvTypeBlock:
-			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan)
+		case wire.InvTypeTx, wire.InvTypeWitnessTx, wire.InvTypeWTx:
+			err = sp.server.pushTxMsg(sp, &iv.Hash, iv.Type, c,
+				waitChan)
+		case wire.InvTypeBlock, wire.InvTypeWitnessBlock:
+			witness := iv.Type == wire.InvTypeWitnessBlock
+			err = sp.server.pushBlockMsg(sp, &iv.Hash, witness, c,
+				waitChan)
 		case wire.InvTypeFilteredBlock:
 			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan)
 		default:
@@ -519,16 +768,55 @@ func (sp *serverPeer) OnGetData(p *peer.Peer, msg *wire.MsgGetData) {
 }
 
 // OnGetBlocks is invoked when a peer receives a getblocks bitcoin
-// message.
+// message.  Responses are cached for a short time so peers sending identical
+// requests don't repeat the database lookups.
 func (sp *serverPeer) OnGetBlocks(p *peer.Peer, msg *wire.MsgGetBlocks) {
-	db := sp.server.db
+	cache := sp.server.locatorCache
+	key := locatorCacheKey(msg.Command(), msg.BlockLocatorHashes,
+		&msg.HashStop)
+	now := time.Now()
+	entry, generation := cache.Lookup(key, now)
+	if entry == nil {
+		var err error
+		entry, err = sp.server.locateBlocks(msg.BlockLocatorHashes,
+			&msg.HashStop)
+		if err != nil {
+			peerLog.Warnf("Block lookup failed: %v", err)
+			return
+		}
+		cache.Add(key, entry, generation, now)
+	}
+
+	// Send the inventory message if there is anything to send.
+	if len(entry.invs) > 0 {
+		invMsg := wire.NewMsgInvSizeHint(uint(len(entry.invs)))
+		for _, iv := range entry.invs {
+			invMsg.AddInvVect(iv)
+		}
+		invListLen := len(invMsg.InvList)
+		if entry.autoContinue && invListLen == wire.MaxBlocksPerMsg {
+			// Intentionally use a copy of the final hash so there
+			// is not a reference into the inventory slice which
+			// would prevent the entire slice from being eligible
+			// for GC as soon as it's sent.
+			continueHash := invMsg.InvList[invListLen-1].Hash
+			sp.continueHash = &continueHash
+		}
+		p.QueueMessage(invMsg, nil)
+	}
+}
+
+// locateBlocks returns the response to a getblocks message with the passed
+// block locator and stop hash.
+func (s *server) locateBlocks(locator []*wire.ShaHash, hashStop *wire.ShaHash) (*locatorCacheEntry, error) {
+	db := s.db
 
 	// Return all block hashes to the latest one (up to max per message) if
 	// no stop hash was specified.
 	// Attempt to find the ending index of the stop hash if specified.
 	endIdx := database.AllShas
-	if !msg.HashStop.IsEqual(&zeroHash) {
-		height, err := db.FetchBlockHeightBySha(&msg.HashStop)
+	if !hashStop.IsEqual(&zeroHash) {
+		height, err := db.FetchBlockHeightBySha(hashStop)
 		if err == nil {
 			endIdx = height + 1
 		}
@@ -540,7 +828,7 @@ func (sp *serverPeer) OnGetBlocks(p *peer.Peer, msg *wire.MsgGetBlocks) {
 	// over with the genesis block if unknown block locators are provided.
 	// This mirrors the behavior in the reference implementation.
 	startIdx := int32(1)
-	for _, hash := range msg.BlockLocatorHashes {
+	for _, hash := range locator {
 		height, err := db.FetchBlockHeightBySha(hash)
 		if err == nil {
 			// Start with the next hash since we know this one.
@@ -550,25 +838,23 @@ func (sp *serverPeer) OnGetBlocks(p *peer.Peer, msg *wire.MsgGetBlocks) {
 	}
 
 	// Don't attempt to fetch more than we can put into a single message.
-	autoContinue := false
+	entry := &locatorCacheEntry{}
 	if endIdx-startIdx > wire.MaxBlocksPerMsg {
 		endIdx = startIdx + wire.MaxBlocksPerMsg
-		autoContinue = true
+		entry.autoContinue = true
 	}
 
-	// Generate inventory message.
+	// Generate the inventory.
 	//
 	// The FetchBlockBySha call is limited to a maximum number of hashes
 	// per invocation.  Since the maximum number of inventory per message
 	// might be larger, call it multiple times with the appropriate indices
 	// as needed.
-	invMsg := wire.NewMsgInv()
 	for start := startIdx; start < endIdx; {
 		// Fetch the inventory from the block database.
 		hashList, err := db.FetchHeightRange(start, endIdx)
 		if err != nil {
-			peerLog.Warnf("Block lookup failed: %v", err)
-			return
+			return nil, err
 		}
 
 		// The database did not return any further hashes.  Break out of
@@ -577,54 +863,34 @@ func (sp *serverPeer) OnGetBlocks(p *peer.Peer, msg *wire.MsgGetBlocks) {
 			break
 		}
 
-		// Add block inventory to the message.
+		// Add block inventory to the response.
 		for _, hash := range hashList {
 			hashCopy := hash
 			iv := wire.NewInvVect(wire.InvTypeBlock, &hashCopy)
-			invMsg.AddInvVect(iv)
+			entry.invs = append(entry.invs, iv)
 		}
 		start += int32(len(hashList))
 	}
-
-	// Send the inventory message if there is anything to send.
-	if len(invMsg.InvList) > 0 {
-		invListLen := len(invMsg.InvList)
-		if autoContinue && invListLen == wire.MaxBlocksPerMsg {
-			// Intentionally use a copy of the final hash so there
-			// is not a reference into the inventory slice which
-			// would prevent the entire slice from being eligible
-			// for GC as soon as it's sent.
-			continueHash := invMsg.InvList[invListLen-1].Hash
-			sp.continueHash = &continueHash
-		}
-		p.QueueMessage(invMsg, nil)
-	}
+	return entry, nil
 }
 
 // OnGetHeaders is invoked when a peer receives a getheaders bitcoin
-// message.
+// message.  Responses are cached for a short time so peers sending identical
+// requests don't repeat the database lookups.
 func (sp *serverPeer) OnGetHeaders(p *peer.Peer, msg *wire.MsgGetHeaders) {
 	// Ignore getheaders requests if not in sync.
 	if !sp.server.blockManager.IsCurrent() {
 		return
 	}
 
-	db := sp.server.db
-
-	// Attempt to look up the height of the provided stop hash.
-	endIdx := database.AllShas
-	height, err := db.FetchBlockHeightBySha(&msg.HashStop)
-	if err == nil {
-		endIdx = height + 1
-	}
-
 	// There are no block locators so a specific header is being requested
 	// as identified by the stop hash.
+	db := sp.server.db
 	if len(msg.BlockLocatorHashes) == 0 {
 		// No blocks with the stop hash were found so there is nothing
 		// to do.  Just return.  This behavior mirrors the reference
 		// implementation.
-		if endIdx == database.AllShas {
+		if _, err := db.FetchBlockHeightBySha(&msg.HashStop); err != nil {
 			return
 		}
 
@@ -642,13 +908,48 @@ func (sp *serverPeer) OnGetHeaders(p *peer.Peer, msg *wire.MsgGetHeaders) {
 		return
 	}
 
+	cache := sp.server.locatorCache
+	key := locatorCacheKey(msg.Command(), msg.BlockLocatorHashes,
+		&msg.HashStop)
+	now := time.Now()
+	entry, generation := cache.Lookup(key, now)
+	if entry == nil {
+		var err error
+		entry, err = sp.server.locateHeaders(msg.BlockLocatorHashes,
+			&msg.HashStop)
+		if err != nil {
+			peerLog.Warnf("Header lookup failed: %v", err)
+			return
+		}
+		cache.Add(key, entry, generation, now)
+	}
+
+	headersMsg := wire.NewMsgHeaders()
+	for _, header := range entry.headers {
+		headersMsg.AddBlockHeader(header)
+	}
+	p.QueueMessage(headersMsg, nil)
+}
+
+// locateHeaders returns the response to a getheaders message with the passed
+// block locator, which must not be empty, and stop hash.
+func (s *server) locateHeaders(locator []*wire.ShaHash, hashStop *wire.ShaHash) (*locatorCacheEntry, error) {
+	db := s.db
+
+	// Attempt to look up the height of the provided stop hash.
+	endIdx := database.AllShas
+	height, err := db.FetchBlockHeightBySha(hashStop)
+	if err == nil {
+		endIdx = height + 1
+	}
+
 	// Find the most recent known block based on the block locator.
 	// Use the block after the genesis block if no other blocks in the
 	// provided locator are known.  This does mean the client will start
 	// over with the genesis block if unknown block locators are provided.
 	// This mirrors the behavior in the reference implementation.
 	startIdx := int32(1)
-	for _, hash := range msg.BlockLocatorHashes {
+	for _, hash := range locator {
 		height, err := db.FetchBlockHeightBySha(hash)
 		if err == nil {
 			// Start with the next hash since we know this one.
@@ -662,19 +963,18 @@ func (sp *serverPeer) OnGetHeaders(p *peer.Peer, msg *wire.MsgGetHeaders) {
 		endIdx = startIdx + wire.MaxBlockHeadersPerMsg
 	}
 
-	// Generate headers message and send it.
+	// Generate the headers.
 	//
 	// The FetchHeightRange call is limited to a maximum number of hashes
 	// per invocation.  Since the maximum number of headers per message
 	// might be larger, call it multiple times with the appropriate indices
 	// as needed.
-	headersMsg := wire.NewMsgHeaders()
+	entry := &locatorCacheEntry{}
 	for start := startIdx; start < endIdx; {
 		// Fetch the inventory from the block database.
 		hashList, err := db.FetchHeightRange(start, endIdx)
 		if err != nil {
-			peerLog.Warnf("Header lookup failed: %v", err)
-			return
+			return nil, err
 		}
 
 		// The database did not return any further hashes.  Break out of
@@ -683,7 +983,7 @@ func (sp *serverPeer) OnGetHeaders(p *peer.Peer, msg *wire.MsgGetHeaders) {
 			break
 		}
 
-		// Add headers to the message.
+		// Add headers to the response.
 		for _, hash := range hashList {
 			header, err := db.FetchBlockHeaderBySha(&hash)
 			if err != nil {
@@ -691,16 +991,169 @@ func (sp *serverPeer) OnGetHeaders(p *peer.Peer, msg *wire.MsgGetHeaders) {
 					"failed: %v", err)
 				continue
 			}
-			headersMsg.AddBlockHeader(header)
+			entry.headers = append(entry.headers, header)
 		}
 
 		// Start at the next block header after the latest one on the
 		// next loop iteration.
 		start += int32(len(hashList))
 	}
+	return entry, nil
+}
+
+// fetchCFRange returns the hashes of the main chain blocks from startHeight
+// through the block identified by stopHash for a committed filter request.
+// False is returned when the filter index is disabled, the request is for an
+// unsupported filter type, the stop hash is not in the main chain, or the
+// range is invalid or spans more than maxResults blocks.
+func (sp *serverPeer) fetchCFRange(filterType wire.FilterType, startHeight uint32,
+	stopHash *wire.ShaHash, maxResults uint32) ([]wire.ShaHash, bool) {
+
+	// Ignore committed filter requests if the index is disabled.
+	if !cfg.CFIndex {
+		return nil, false
+	}
+	if filterType != wire.GCSFilterRegular {
+		peerLog.Debugf("%s requested unsupported filter type %v",
+			sp.Peer, filterType)
+		return nil, false
+	}
+
+	db := sp.server.db
+	stopHeight, err := db.FetchBlockHeightBySha(stopHash)
+	if err != nil {
+		peerLog.Debugf("%s requested filters for unknown block %v",
+			sp.Peer, stopHash)
+		return nil, false
+	}
+	if startHeight > uint32(stopHeight) ||
+		uint32(stopHeight)-startHeight >= maxResults {
+
+		peerLog.Debugf("%s requested invalid filter range %d to %d",
+			sp.Peer, startHeight, stopHeight)
+		return nil, false
+	}
+
+	hashList, err := db.FetchHeightRange(int32(startHeight), stopHeight+1)
+	if err != nil {
+		peerLog.Warnf("Block lookup failed: %v", err)
+		return nil, false
+	}
+	return hashList, true
+}
+
+// OnGetCFilters is invoked when a peer receives a getcfilters bitcoin
+// message.  A cfilter message is sent for each block in the requested range
+// which has been indexed.
+func (sp *serverPeer) OnGetCFilters(p *peer.Peer, msg *wire.MsgGetCFilters) {
+	hashList, ok := sp.fetchCFRange(msg.FilterType, msg.StartHeight,
+		&msg.StopHash, wire.MaxGetCFiltersReqRange)
+	if !ok {
+		return
+	}
+
+	for i := range hashList {
+		hash := &hashList[i]
+		filter, _, err := sp.server.db.FetchCFilterBySha(hash)
+		if err != nil {
+			// The index has not caught up to this block yet.
+			peerLog.Debugf("Unable to fetch filter for block %v: %v",
+				hash, err)
+			return
+		}
+		p.QueueMessage(wire.NewMsgCFilter(msg.FilterType, hash, filter),
+			nil)
+	}
+}
+
+// OnGetCFHeaders is invoked when a peer receives a getcfheaders bitcoin
+// message.  The filter hashes of the requested range are sent along with the
+// filter header of the block preceding it.
+func (sp *serverPeer) OnGetCFHeaders(p *peer.Peer, msg *wire.MsgGetCFHeaders) {
+	hashList, ok := sp.fetchCFRange(msg.FilterType, msg.StartHeight,
+		&msg.StopHash, wire.MaxCFHeadersPerMsg)
+	if !ok {
+		return
+	}
+
+	db := sp.server.db
+	headersMsg := wire.NewMsgCFHeaders()
+	headersMsg.FilterType = msg.FilterType
+	headersMsg.StopHash = msg.StopHash
+
+	// The header preceding the genesis block is all zeros.
+	if msg.StartHeight > 0 {
+		prevHash, err := db.FetchBlockShaByHeight(int32(msg.StartHeight) - 1)
+		if err != nil {
+			peerLog.Warnf("Block lookup failed: %v", err)
+			return
+		}
+		_, prevHeader, err := db.FetchCFilterBySha(prevHash)
+		if err != nil {
+			peerLog.Debugf("Unable to fetch filter header for "+
+				"block %v: %v", prevHash, err)
+			return
+		}
+		headersMsg.PrevFilterHeader = *prevHeader
+	}
+
+	for i := range hashList {
+		filter, _, err := db.FetchCFilterBySha(&hashList[i])
+		if err != nil {
+			peerLog.Debugf("Unable to fetch filter for block %v: %v",
+				&hashList[i], err)
+			return
+		}
+		filterHash := gcs.FilterHash(filter)
+		headersMsg.AddCFHash(&filterHash)
+	}
+
 	p.QueueMessage(headersMsg, nil)
 }
 
+// OnGetCFCheckpt is invoked when a peer receives a getcfcheckpt bitcoin
+// message.  The filter header of every wire.CFCheckptInterval'th block up to
+// the stop hash is sent.
+func (sp *serverPeer) OnGetCFCheckpt(p *peer.Peer, msg *wire.MsgGetCFCheckpt) {
+	// Ignore committed filter requests if the index is disabled.
+	if !cfg.CFIndex {
+		return
+	}
+	if msg.FilterType != wire.GCSFilterRegular {
+		peerLog.Debugf("%s requested unsupported filter type %v",
+			p, msg.FilterType)
+		return
+	}
+
+	db := sp.server.db
+	stopHeight, err := db.FetchBlockHeightBySha(&msg.StopHash)
+	if err != nil {
+		peerLog.Debugf("%s requested filter checkpoints for unknown "+
+			"block %v", p, msg.StopHash)
+		return
+	}
+
+	numCheckpts := int(stopHeight / wire.CFCheckptInterval)
+	checkptMsg := wire.NewMsgCFCheckpt(msg.FilterType, &msg.StopHash,
+		numCheckpts)
+	for i := 1; i <= numCheckpts; i++ {
+		hash, err := db.FetchBlockShaByHeight(int32(i * wire.CFCheckptInterval))
+		if err != nil {
+			peerLog.Warnf("Block lookup failed: %v", err)
+			return
+		}
+		_, header, err := db.FetchCFilterBySha(hash)
+		if err != nil {
+			peerLog.Debugf("Unable to fetch filter header for "+
+				"block %v: %v", hash, err)
+			return
+		}
+		checkptMsg.AddCFHeader(header)
+	}
+
+	p.QueueMessage(checkptMsg, nil)
+}
+
 // OnFilterAdd is invoked when a peer receives a filteradd bitcoin
 // message and is used by remote peers to add data to an already loaded bloom
 // filter.  The peer will be disconnected if a filter is not loaded when this
@@ -819,6 +1272,14 @@ func (sp *serverPeer) OnAddr(p *peer.Peer, msg *wire.MsgAddr) {
 // the bytes received by the server.
 func (sp *serverPeer) OnRead(p *peer.Peer, bytesRead int, msg wire.Message, err error) {
 	sp.server.AddBytesReceived(uint64(bytesRead))
+
+	// Messages exceeding the maximum payload size are never sent by
+	// honest peers.
+	if merr, ok := err.(*wire.MessageError); ok &&
+		merr.ErrorCode == wire.ErrPayloadTooLarge {
+
+		sp.addBanScore(misbehaviorOversizedMessage, merr.Description)
+	}
 }
 
 // OnWrite is invoked when a peer sends a message and it is used to update
@@ -867,13 +1328,62 @@ func (s *server) RemoveRebroadcastInventory(iv *wire.InvVect) {
 	s.modifyRebroadcastInv <- broadcastInventoryDel(iv)
 }
 
+// UnconfirmedBroadcasts returns the transactions submitted through the RPC
+// server which are still being rebroadcast ordered from the oldest to the
+// newest submission.
+func (s *server) UnconfirmedBroadcasts() []*unconfirmedBroadcast {
+	// Ignore if shutting down.
+	if atomic.LoadInt32(&s.shutdown) != 0 {
+		return nil
+	}
+
+	replyChan := make(chan []*unconfirmedBroadcast)
+	s.modifyRebroadcastInv <- getBroadcastsMsg{reply: replyChan}
+	return <-replyChan
+}
+
+// RemoveBroadcast stops rebroadcasting the transaction with the passed hash
+// and returns whether it was being rebroadcast.
+func (s *server) RemoveBroadcast(txHash *wire.ShaHash) bool {
+	// Ignore if shutting down.
+	if atomic.LoadInt32(&s.shutdown) != 0 {
+		return false
+	}
+
+	replyChan := make(chan bool)
+	s.modifyRebroadcastInv <- removeBroadcastMsg{txHash: txHash, reply: replyChan}
+	return <-replyChan
+}
+
+// stripWitness returns a copy of the passed transaction without any witness
+// data for peers which did not request it.  The transaction itself is returned
+// when it has no witness data.
+func stripWitness(msgTx *wire.MsgTx) *wire.MsgTx {
+	if !msgTx.HasWitness() {
+		return msgTx
+	}
+	stripped := msgTx.Copy()
+	for _, txIn := range stripped.TxIn {
+		txIn.Witness = nil
+	}
+	return stripped
+}
+
 // pushTxMsg sends a tx message for the provided transaction hash to the
-// connected peer.  An error is returned if the transaction hash is not known.
-func (s *server) pushTxMsg(sp *serverPeer, sha *wire.ShaHash, doneChan, waitChan chan struct{}) error {
+// connected peer.  The hash is a witness hash for the InvTypeWTx inventory
+// type and witness data is only included when it was requested.  An error is
+// returned if the transaction hash is not known.
+func (s *server) pushTxMsg(sp *serverPeer, sha *wire.ShaHash, invType wire.InvType, doneChan, waitChan chan struct{}) error {
 	// Attempt to fetch the requested transaction from the pool.  A
 	// call could be made to check for existence first, but simply trying
 	// to fetch a missing transaction results in the same behavior.
-	tx, err := s.txMemPool.FetchTransaction(sha)
+	var tx *coinutil.Tx
+	var err error
+	if invType == wire.InvTypeWTx {
+		tx, err = s.txMemPool.FetchTransactionByWitnessHash(sha)
+	} else {
+		tx, err = s.txMemPool.FetchTransaction(sha)
+	}
 	if err != nil {
 		peerLog.Tracef("Unable to fetch tx %v from transaction "+
 			"pool: %v", sha, err)
@@ -889,14 +1399,19 @@ func (s *server) pushTxMsg(sp *serverPeer, sha *wire.ShaHash, doneChan, waitChan
 		<-waitChan
 	}
 
-	sp.QueueMessage(tx.MsgTx(), doneChan)
+	msgTx := tx.MsgTx()
+	if invType == wire.InvTypeTx {
+		msgTx = stripWitness(msgTx)
+	}
+	sp.QueueMessage(msgTx, doneChan)
 
 	return nil
 }
 
 // pushBlockMsg sends a block message for the provided block hash to the
-// connected peer.  An error is returned if the block hash is not known.
-func (s *server) pushBlockMsg(sp *serverPeer, sha *wire.ShaHash, doneChan, waitChan chan struct{}) error {
+// connected peer.  The witness data of the transactions is only included when
+// requested.  An error is returned if the block hash is not known.
+func (s *server) pushBlockMsg(sp *serverPeer, sha *wire.ShaHash, witness bool, doneChan, waitChan chan struct{}) error {
 	blk, err := s.db.FetchBlockBySha(sha)
 	if err != nil {
 		peerLog.Tracef("Unable to fetch requested block sha %v: %v",
@@ -921,7 +1436,18 @@ func (s *server) pushBlockMsg(sp *serverPeer, sha *wire.ShaHash, doneChan, waitC
 	if !sendInv {
 		dc = doneChan
 	}
-	sp.QueueMessage(blk.MsgBlock(), dc)
+	msgBlock := blk.MsgBlock()
+	if !witness {
+		stripped := *msgBlock
+		stripped.Transactions = make([]*wire.MsgTx, 0,
+			len(msgBlock.Transactions))
+		for _, tx := range msgBlock.Transactions {
+			stripped.Transactions = append(stripped.Transactions,
+				stripWitness(tx))
+		}
+		msgBlock = &stripped
+	}
+	sp.QueueMessage(msgBlock, dc)
 
 	// When the peer requests the final block that was advertised in
 	// response to a getblocks message which requested more blocks than
@@ -1099,6 +1625,10 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		delete(state.pendingPeers, sp.Addr())
 	}
 
+	if s.rpcServer != nil {
+		s.rpcServer.ntfnMgr.NotifyPeerConnected(sp)
+	}
+
 	return true
 }
 
@@ -1134,6 +1664,9 @@ func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
 		}
 		delete(list, sp.ID())
 		srvrLog.Debugf("Removed peer %s", sp)
+		if s.rpcServer != nil {
+			s.rpcServer.ntfnMgr.NotifyPeerDisconnected(sp)
+		}
 		return
 	}
 
@@ -1169,7 +1702,8 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 			return
 		}
 
-		if msg.invVect.Type == wire.InvTypeTx {
+		invVect := msg.invVect
+		if invVect.Type == wire.InvTypeTx {
 			// Don't relay the transaction to the peer when it has
 			// transaction relaying disabled.
 			if sp.relayTxDisabled() {
@@ -1177,8 +1711,8 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 			}
 			// Don't relay the transaction if there is a bloom
 			// filter loaded and the transaction doesn't match it.
+			tx, ok := msg.data.(*coinutil.Tx)
 			if sp.filter.IsLoaded() {
-				tx, ok := msg.data.(*coinutil.Tx)
 				if !ok {
 					peerLog.Warnf("Underlying data for tx" +
 						" inv relay is not a transaction")
@@ -1189,12 +1723,20 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 					return
 				}
 			}
+
+			// Announce the transaction by its witness hash to
+			// peers which negotiated wtxid relay.
+			if ok && sp.WTxIdRelay() {
+				witnessHash := tx.MsgTx().WitnessHash()
+				invVect = wire.NewInvVect(wire.InvTypeWTx,
+					&witnessHash)
+			}
 		}
 
 		// Queue the inventory to be relayed with the next batch.
 		// It will be ignored if the peer is already known to
 		// have the inventory.
-		sp.QueueInventory(msg.invVect)
+		sp.QueueInventory(invVect)
 	})
 }
 
@@ -1368,24 +1910,28 @@ func disconnectPeer(peerList map[int32]*serverPeer, compareFunc func(*serverPeer
 
 // newPeerConfig returns the configuration for the given serverPeer.
 func newPeerConfig(sp *serverPeer) *peer.Config {
-	return &peer.Config{
+	config := &peer.Config{
 		Listeners: peer.MessageListeners{
-			OnVersion:     sp.OnVersion,
-			OnMemPool:     sp.OnMemPool,
-			OnTx:          sp.OnTx,
-			OnBlock:       sp.OnBlock,
-			OnInv:         sp.OnInv,
-			OnHeaders:     sp.OnHeaders,
-			OnGetData:     sp.OnGetData,
-			OnGetBlocks:   sp.OnGetBlocks,
-			OnGetHeaders:  sp.OnGetHeaders,
-			OnFilterAdd:   sp.OnFilterAdd,
-			OnFilterClear: sp.OnFilterClear,
-			OnFilterLoad:  sp.OnFilterLoad,
-			OnGetAddr:     sp.OnGetAddr,
-			OnAddr:        sp.OnAddr,
-			OnRead:        sp.OnRead,
-			OnWrite:       sp.OnWrite,
+			OnVersion:      sp.OnVersion,
+			OnPong:         sp.OnPong,
+			OnMemPool:      sp.OnMemPool,
+			OnTx:           sp.OnTx,
+			OnBlock:        sp.OnBlock,
+			OnInv:          sp.OnInv,
+			OnHeaders:      sp.OnHeaders,
+			OnGetData:      sp.OnGetData,
+			OnGetBlocks:    sp.OnGetBlocks,
+			OnGetHeaders:   sp.OnGetHeaders,
+			OnGetCFilters:  sp.OnGetCFilters,
+			OnGetCFHeaders: sp.OnGetCFHeaders,
+			OnGetCFCheckpt: sp.OnGetCFCheckpt,
+			OnFilterAdd:    sp.OnFilterAdd,
+			OnFilterClear:  sp.OnFilterClear,
+			OnFilterLoad:   sp.OnFilterLoad,
+			OnGetAddr:      sp.OnGetAddr,
+			OnAddr:         sp.OnAddr,
+			OnRead:         sp.OnRead,
+			OnWrite:        sp.OnWrite,
 
 			// Note: The reference client currently bans peers that send alerts
 			// not signed with its key.  We could verify against their key, but
@@ -1403,6 +1949,13 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 		Services:         sp.server.services,
 		DisableRelayTx:   false,
 	}
+
+	// Messages are only subject to the faults set with the setnetworkfault
+	// command on the simulation test network.
+	if cfg.SimNet {
+		config.MessageFault = sp.server.networkFaults.messageFault
+	}
+	return config
 }
 
 // listenHandler is the main listener which accepts incoming connections for the
@@ -1419,6 +1972,17 @@ func (s *server) listenHandler(listener net.Listener) {
 			}
 			continue
 		}
+
+		// Refuse connections exceeding the inbound rate limits.
+		if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok &&
+			!s.inboundLimiter.Allow(tcpAddr.IP, time.Now()) {
+
+			srvrLog.Debugf("Refused inbound connection from %s",
+				conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+
 		sp := newServerPeer(s, false)
 		sp.Peer = peer.NewInboundPeer(newPeerConfig(sp), conn)
 		sp.Start()
@@ -1570,6 +2134,7 @@ func (s *server) peerHandler() {
 	// in this handler.
 	s.addrManager.Start()
 	s.blockManager.Start()
+	s.blockImporter.Start()
 
 	srvrLog.Tracef("Starting peer handler")
 
@@ -1725,9 +2290,11 @@ out:
 		}
 	}
 
-	if cfg.AddrIndex {
-		s.addrIndexer.Stop()
+	s.indexManager.Stop()
+	if s.notifyCmdRunner != nil {
+		s.notifyCmdRunner.Stop()
 	}
+	s.blockImporter.Stop()
 	s.blockManager.Stop()
 	s.addrManager.Stop()
 
@@ -1906,13 +2473,14 @@ func (s *server) UpdatePeerHeights(latestBlkSha *wire.ShaHash, latestHeight int3
 	}
 }
 
-// rebroadcastHandler keeps track of user submitted inventories that we have
+// rebroadcastHandler keeps track of user submitted transactions that we have
 // sent out but have not yet made it into a block. We periodically rebroadcast
-// them in case our peers restarted or otherwise lost track of them.
+// them in case our peers restarted or otherwise lost track of them, and stop
+// once they have been evicted from the memory pool.
 func (s *server) rebroadcastHandler() {
 	// Wait 5 min before first tx rebroadcast.
 	timer := time.NewTimer(5 * time.Minute)
-	pendingInvs := make(map[wire.InvVect]interface{})
+	broadcasts := newBroadcastManager()
 
 out:
 	for {
@@ -1922,35 +2490,45 @@ out:
 			inv := wire.NewInvVect(wire.InvTypeTx, tx.Sha())
 			s.RelayInventory(inv, tx)
 
-			if s.rpcServer != nil {
-				// Notify websocket clients about mempool transactions.
-				s.rpcServer.ntfnMgr.NotifyMempoolTx(tx, true)
-
-				// Potentially notify any getblocktemplate long poll clients
-				// about stale block templates due to the new transaction.
-				s.rpcServer.gbtWorkState.NotifyMempoolTx(s.txMemPool.LastUpdated())
-			}
+			// Let the websocket clients and the block template
+			// notifiers know about the new transaction.
+			s.events.PublishMempoolTxAccepted(tx)
 
 		case riv := <-s.modifyRebroadcastInv:
 			switch msg := riv.(type) {
-			// Incoming InvVects are added to our map of RPC txs.
+			// Incoming transactions are added to our map of RPC
+			// txs.
 			case broadcastInventoryAdd:
-				pendingInvs[*msg.invVect] = msg.data
+				if tx, ok := msg.data.(*coinutil.Tx); ok {
+					broadcasts.Add(tx, time.Now())
+				}
 
 			// When an InvVect has been added to a block, we can
 			// now remove it, if it was present.
 			case broadcastInventoryDel:
-				if _, ok := pendingInvs[*msg]; ok {
-					delete(pendingInvs, *msg)
-				}
+				broadcasts.Remove(&msg.Hash)
+
+			case getBroadcastsMsg:
+				msg.reply <- broadcasts.Broadcasts()
+
+			case removeBroadcastMsg:
+				msg.reply <- broadcasts.Remove(msg.txHash)
 			}
 
 		case <-timer.C:
-			// Any inventory we have has not made it into a block
-			// yet. We periodically resubmit them until they have.
-			for iv, data := range pendingInvs {
-				ivCopy := iv
-				s.RelayInventory(&ivCopy, data)
+			// Any transaction we have has not made it into a block
+			// yet. We periodically resubmit them until they have
+			// or they are evicted from the memory pool.
+			evicted := broadcasts.Rebroadcast(
+				s.txMemPool.IsTransactionInPool,
+				func(tx *coinutil.Tx) {
+					iv := wire.NewInvVect(wire.InvTypeTx, tx.Sha())
+					s.RelayInventory(iv, tx)
+				}, time.Now())
+			for _, txHash := range evicted {
+				srvrLog.Infof("Stopped rebroadcasting transaction "+
+					"%v which is no longer in the memory pool",
+					txHash)
 			}
 
 			// Process at a random time up to 30mins (in seconds)
@@ -1978,6 +2556,37 @@ cleanup:
 	s.wg.Done()
 }
 
+// mempoolExpiryHandler periodically evicts the transactions which have not
+// been mined within the configured expiry from the memory pool and lets the
+// subscribers of the event bus know about each of them.
+//
+// This must be run as a goroutine.
+func (s *server) mempoolExpiryHandler() {
+	ticker := time.NewTicker(mempoolExpiryInterval)
+	defer ticker.Stop()
+
+out:
+	for {
+		select {
+		case <-ticker.C:
+			expired := s.txMemPool.ExpireTransactions(time.Now())
+			for _, tx := range expired {
+				txmpLog.Debugf("Evicted expired transaction %v",
+					tx.Sha())
+				s.events.PublishMempoolTxExpired(tx)
+			}
+			if len(expired) > 0 {
+				txmpLog.Infof("Evicted %d expired transactions "+
+					"from the memory pool", len(expired))
+			}
+
+		case <-s.quit:
+			break out
+		}
+	}
+	s.wg.Done()
+}
+
 // Start begins accepting connections from peers.
 func (s *server) Start() {
 	// Already started?
@@ -2004,6 +2613,16 @@ func (s *server) Start() {
 		go s.upnpUpdateThread()
 	}
 
+	if cfg.MempoolExpiry > 0 {
+		s.wg.Add(1)
+		go s.mempoolExpiryHandler()
+	}
+
+	if cfg.compactWindow != nil {
+		s.wg.Add(1)
+		go s.dbCompactionHandler()
+	}
+
 	if !cfg.DisableRPC {
 		s.wg.Add(1)
 
@@ -2019,8 +2638,10 @@ func (s *server) Start() {
 		s.cpuMiner.Start()
 	}
 
-	if cfg.AddrIndex {
-		s.addrIndexer.Start()
+	s.indexManager.Start()
+
+	if s.notifyCmdRunner != nil {
+		s.notifyCmdRunner.Start()
 	}
 }
 
@@ -2047,7 +2668,9 @@ func (s *server) Stop() error {
 	// Stop the CPU miner if needed
 	s.cpuMiner.Stop()
 
-	// Shutdown the RPC server if it's not disabled.
+	// Shutdown the RPC server if it's not disabled.  This waits for the
+	// in-flight requests and rescans, so it must happen before the block
+	// manager is stopped and the database is closed.
 	if !cfg.DisableRPC {
 		s.rpcServer.Stop()
 	}
@@ -2214,6 +2837,14 @@ func newServer(listenAddrs []string, db database.Db, chainParams *chaincfg.Param
 	if cfg.NoPeerBloomFilters {
 		services &^= wire.SFNodeBloom
 	}
+	if cfg.CFIndex {
+		services |= wire.SFNodeCF
+	}
+
+	// Nodes running in headers-only mode don't have any blocks to serve.
+	if cfg.HeadersOnly {
+		services &^= wire.SFNodeNetwork | wire.SFNodeBloom
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, btcdLookup)
 
@@ -2359,54 +2990,139 @@ func newServer(listenAddrs []string, db database.Db, chainParams *chaincfg.Param
 		peerHeightsUpdate:    make(chan updatePeerHeightsMsg),
 		nat:                  nat,
 		db:                   db,
-		timeSource:           blockchain.NewMedianTime(),
+		timeSource:           newMockTimeSource(),
 		services:             services,
 		sigCache:             txscript.NewSigCache(cfg.SigCacheMaxSize),
-	}
+		events:               newEventBus(),
+		txHooks:              &txHooks{},
+		inboundLimiter: newInboundLimiter(cfg.InboundRatePerIP,
+			cfg.InboundRate, cfg.InboundChallenge, cfg.InboundIPv4Prefix,
+			cfg.InboundIPv6Prefix, cfg.whitelists),
+		locatorCache:  newLocatorCache(),
+		dbCompactor:   newDBCompactor(db, cfg.compactWindow),
+		networkFaults: newNetworkFaults(),
+	}
+	s.events.Subscribe(s.locatorCache)
 	bm, err := newBlockManager(&s)
 	if err != nil {
 		return nil, err
 	}
 	s.blockManager = bm
 
+	// Create the standardness policy for the memory pool based on the
+	// configuration options.
+	stdPolicy := standardPolicy{
+		AcceptDataCarrier:     !cfg.NoDataCarrier,
+		MaxDataCarrierSize:    cfg.DataCarrierSize,
+		MaxDataCarrierOutputs: cfg.DataCarrierCount,
+		AcceptBareMultisig:    !cfg.RejectBareMultisig,
+		DustRelayFee:          cfg.dustRelayFee,
+		MaxSigOpsPerTx:        cfg.MaxSigOpsPerTx,
+	}
 	txC := mempoolConfig{
+		CheckTx:               s.txHooks.CheckMempoolTx,
 		DisableRelayPriority:  cfg.NoRelayPriority,
 		EnableAddrIndex:       cfg.AddrIndex,
+		Expiry:                time.Duration(cfg.MempoolExpiry) * time.Hour,
 		FetchTransactionStore: s.blockManager.blockChain.FetchTransactionStore,
 		FreeTxRelayLimit:      cfg.FreeTxRelayLimit,
+		IsDeploymentActive:    s.blockManager.chainState.IsDeploymentActive,
+		MaxAncestorCount:      cfg.MaxAncestors,
+		MaxAncestorSize:       int64(cfg.MaxAncestorSize) * 1000,
+		MaxDescendantCount:    cfg.MaxDescendants,
+		MaxDescendantSize:     int64(cfg.MaxDescendantSize) * 1000,
 		MaxOrphanTxs:          cfg.MaxOrphanTxs,
 		MinRelayTxFee:         cfg.minRelayTxFee,
 		NewestSha:             s.db.NewestSha,
+		NotifyDoubleSpend:     s.notifyDoubleSpend,
+		NotifyTxAdded:         s.events.PublishMempoolTxAdded,
+		NotifyTxRemoved:       s.events.PublishMempoolTxRemoved,
+		Policy:                stdPolicy,
 		RelayNtfnChan:         s.relayNtfnChan,
+		ScriptBudget:          cfg.scriptBudget,
 		SigCache:              s.sigCache,
 		TimeSource:            s.timeSource,
 	}
 	s.txMemPool = newTxMemPool(&txC)
 
+	// Enforce the transaction blacklist.  It is always registered so entries
+	// can be added with the addfilterentry command when no blacklist was
+	// loaded on start up.
+	s.txBlacklist = cfg.txBlacklist
+	if s.txBlacklist == nil {
+		s.txBlacklist = newTxBlacklist()
+	} else {
+		txflLog.Infof("Loaded %d transaction blacklist entries from %s",
+			s.txBlacklist.Len(), cfg.TxBlacklist)
+	}
+	s.RegisterTxHook(s.txBlacklist)
+
 	// Create the mining policy based on the configuration options.
 	// NOTE: The CPU miner relies on the mempool, so the mempool has to be
 	// created before calling the function to create the CPU miner.
-	policy := mining.Policy{
+	policy := newBlockTemplatePolicy(mining.Policy{
 		BlockMinSize:      cfg.BlockMinSize,
 		BlockMaxSize:      cfg.BlockMaxSize,
 		BlockPrioritySize: cfg.BlockPrioritySize,
-		TxMinFreeFee:      cfg.minRelayTxFee,
-	}
-	s.cpuMiner = newCPUMiner(&policy, &s)
+		TxMinFreeFee:      cfg.blockMinFeeRate,
+		BlockMinPriority:  cfg.BlockMinPriority,
+		RecentTxAge:       cfg.RecentTxAge,
+		RecentTxWeight:    cfg.RecentTxWeight,
+	})
+	s.cpuMiner = newCPUMiner(policy, &s)
 
+	// The optional indexes which are built from the blocks of the main
+	// chain are maintained by the index manager.
+	s.indexManager = newIndexManager(s.db)
 	if cfg.AddrIndex {
-		ai, err := newAddrIndexer(&s)
+		s.addrIndexer = newAddrIndexer()
+		err := s.indexManager.AddIndex(s.addrIndexer, adxrLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.CFIndex {
+		s.cfIndexer = newCFIndexer()
+		if err := s.indexManager.AddIndex(s.cfIndexer, cfixLog); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.CoinAgeIndex {
+		s.coinAgeIndexer = newCoinAgeIndexer()
+		err := s.indexManager.AddIndex(s.coinAgeIndexer, caixLog)
 		if err != nil {
 			return nil, err
 		}
-		s.addrIndexer = ai
+	}
+	if cfg.STXOIndex {
+		s.stxoIndexer = newSTXOIndexer()
+		err := s.indexManager.AddIndex(s.stxoIndexer, stxoLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.AddrBalanceIndex {
+		s.addrBalanceIndexer = newAddrBalanceIndexer()
+		err := s.indexManager.AddIndex(s.addrBalanceIndexer, abixLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.events.Subscribe(s.indexManager)
+
+	s.blockImporter = newBlockImporter(&s)
+
+	if cfg.BlockNotify != "" || cfg.TxNotify != "" {
+		s.notifyCmdRunner = newNotifyCmdRunner(&s)
+		s.events.Subscribe(s.notifyCmdRunner)
 	}
 
 	if !cfg.DisableRPC {
-		s.rpcServer, err = newRPCServer(cfg.RPCListeners, &policy, &s)
+		s.rpcServer, err = newRPCServer(cfg.RPCListeners, policy, &s)
 		if err != nil {
 			return nil, err
 		}
+		s.events.Subscribe(s.rpcServer)
 	}
 
 	return &s, nil
@@ -0,0 +1,87 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/wire"
+)
+
+// stxoIndexer maintains the spent output index which maps each output spent
+// in the main chain to the transaction input which spends it.
+//
+// Since the spends are stored by output rather than by block, disconnected
+// blocks are removed using the outputs recorded for each block since the
+// blocks themselves are no longer available.
+type stxoIndexer struct {
+	db database.Db
+}
+
+// Ensure the stxoIndexer type implements the Indexer interface.
+var _ Indexer = (*stxoIndexer)(nil)
+
+// newSTXOIndexer creates a new spent output indexer to be added to the index
+// manager.
+func newSTXOIndexer() *stxoIndexer {
+	return &stxoIndexer{}
+}
+
+// Name returns the name of the index.  It is part of the Indexer interface.
+func (x *stxoIndexer) Name() string {
+	return "spent output"
+}
+
+// Init sets the database holding the index.  It is part of the Indexer
+// interface.
+func (x *stxoIndexer) Init(db database.Db) error {
+	x.db = db
+	return nil
+}
+
+// Tip returns the hash and height of the most recent block in the index.  It
+// is part of the Indexer interface.
+func (x *stxoIndexer) Tip() (*wire.ShaHash, int32, error) {
+	sha, height, err := x.db.FetchSTXOIndexTip()
+	if err == database.ErrSTXOIndexDoesNotExist {
+		err = nil
+	}
+	return sha, height, err
+}
+
+// ConnectBlock indexes the outputs spent by the passed block.  It is part of
+// the Indexer interface.
+func (x *stxoIndexer) ConnectBlock(block *coinutil.Block) error {
+	return x.db.UpdateSTXOIndexForBlock(block.Sha(), block.Height(),
+		&block.MsgBlock().Header.PrevBlock, blockSpends(block))
+}
+
+// DisconnectBlock removes the spends of the block at the tip of the index.  It
+// is part of the Indexer interface.
+func (x *stxoIndexer) DisconnectBlock(sha *wire.ShaHash, height int32) error {
+	return x.db.RewindSTXOIndex()
+}
+
+// blockSpends returns the outputs spent by the passed block along with the
+// transaction inputs which spend them.
+func blockSpends(block *coinutil.Block) []database.SpentOutput {
+	var spends []database.SpentOutput
+	for _, tx := range block.Transactions() {
+		// Coinbases don't have any inputs.
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+
+		for i, txIn := range tx.MsgTx().TxIn {
+			spends = append(spends, database.SpentOutput{
+				OutPoint:   txIn.PreviousOutPoint,
+				TxSha:      *tx.Sha(),
+				InputIndex: uint32(i),
+			})
+		}
+	}
+	return spends
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// expectedBlockSpacing is the average time between blocks the chain is
+// designed for.  It is used to estimate how many blocks are missing when no
+// sync peer has told us how long the chain is.
+const expectedBlockSpacing = time.Minute * 10
+
+// syncStatus describes how far the block manager is with downloading and
+// verifying the block chain from its peers.
+type syncStatus struct {
+	// current is whether the block manager believes it is synced with the
+	// connected peers.
+	current bool
+
+	// syncPeerAddr and syncPeerHeight are the address and the latest block
+	// height of the peer the chain is downloaded from.  The address is
+	// empty when there is no sync peer.
+	syncPeerAddr   string
+	syncPeerHeight int32
+
+	// stalls is the number of times the sync peers from each host failed
+	// to deliver requested blocks in time.
+	stalls map[string]int32
+}
+
+// verificationProgress returns an estimate of the fraction of the block chain
+// which has been downloaded and verified given the height and age of the best
+// block and the latest block height of the sync peer, which is zero when there
+// is no sync peer.
+//
+// The sync peer height is the best estimate of the length of the chain.  When
+// there is none, or it is behind the best block, the number of missing blocks
+// is estimated from the age of the best block instead.
+func verificationProgress(height, syncPeerHeight int32, tipAge time.Duration) float64 {
+	if height <= 0 {
+		return 0
+	}
+
+	expected := syncPeerHeight
+	if expected <= height {
+		expected = height
+		if tipAge > expectedBlockSpacing {
+			expected += int32(tipAge / expectedBlockSpacing)
+		}
+	}
+	return float64(height) / float64(expected)
+}
+
+// updateInitialBlockDownload clears the initial block download flag once the
+// block manager believes it is synced with its peers.  The flag is never set
+// again afterwards, so a node which falls behind temporarily, for instance
+// after being suspended, keeps relaying transactions and notifying clients as
+// usual while it catches up.
+//
+// This function must only be called from the block handler goroutine or before
+// it is started.
+func (b *blockManager) updateInitialBlockDownload() {
+	if atomic.LoadInt32(&b.initialDownload) == 0 || !b.current() {
+		return
+	}
+
+	atomic.StoreInt32(&b.initialDownload, 0)
+	_, height := b.chainState.Best()
+	if cfg.HeadersOnly {
+		_, height = b.headerChain.BestHeader()
+	}
+	bmgrLog.Infof("Initial block download complete at height %d", height)
+}
+
+// IsInitialBlockDownload returns whether the node is still performing the
+// initial download of the block chain.  Behavior which is meaningless until
+// the node has caught up with the network, such as accepting relayed
+// transactions into the memory pool, is suppressed while it is.
+//
+// This function is safe for concurrent access.
+func (b *blockManager) IsInitialBlockDownload() bool {
+	return atomic.LoadInt32(&b.initialDownload) != 0
+}
+
+// SyncStatus returns whether the block manager believes it is synced with the
+// connected peers along with the sync peer it downloads the chain from.
+func (b *blockManager) SyncStatus() *syncStatus {
+	reply := make(chan *syncStatus)
+	b.msgChan <- getSyncStatusMsg{reply: reply}
+	return <-reply
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestVerificationProgress ensures the verification progress is estimated from
+// the sync peer height when it is ahead of the best block and from the age of
+// the best block otherwise.
+func TestVerificationProgress(t *testing.T) {
+	tests := []struct {
+		name           string
+		height         int32
+		syncPeerHeight int32
+		tipAge         time.Duration
+		want           float64
+	}{
+		{
+			name: "genesis only",
+			want: 0,
+		},
+		{
+			name:           "behind sync peer",
+			height:         100,
+			syncPeerHeight: 400,
+			tipAge:         time.Hour * 24 * 365,
+			want:           0.25,
+		},
+		{
+			name:   "no sync peer with old tip",
+			height: 300,
+			tipAge: expectedBlockSpacing * 100,
+			want:   0.75,
+		},
+		{
+			name:           "sync peer behind with old tip",
+			height:         300,
+			syncPeerHeight: 200,
+			tipAge:         expectedBlockSpacing * 300,
+			want:           0.5,
+		},
+		{
+			name:   "recent tip",
+			height: 300,
+			tipAge: time.Minute,
+			want:   1,
+		},
+		{
+			name:           "synced with sync peer",
+			height:         300,
+			syncPeerHeight: 300,
+			tipAge:         time.Minute * 5,
+			want:           1,
+		},
+	}
+
+	for _, test := range tests {
+		got := verificationProgress(test.height, test.syncPeerHeight,
+			test.tipAge)
+		if got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/conseweb/stcd/mining"
+)
+
+// blockTemplatePolicy houses the mining policy used to generate block
+// templates.  The policy is configured with command line options and may be
+// changed at runtime with the setblocktemplatepolicy RPC, so every block
+// template is generated with a snapshot of the policy at that time.
+type blockTemplatePolicy struct {
+	sync.RWMutex
+	policy mining.Policy
+}
+
+// newBlockTemplatePolicy returns a block template policy initialized with the
+// passed policy.
+func newBlockTemplatePolicy(policy mining.Policy) *blockTemplatePolicy {
+	return &blockTemplatePolicy{policy: policy}
+}
+
+// Policy returns a copy of the current mining policy.
+//
+// This function is safe for concurrent access.
+func (p *blockTemplatePolicy) Policy() *mining.Policy {
+	p.RLock()
+	policy := p.policy
+	p.RUnlock()
+	return &policy
+}
+
+// SetPolicy replaces the current mining policy with the passed one after
+// ensuring it is sane.
+//
+// This function is safe for concurrent access.
+func (p *blockTemplatePolicy) SetPolicy(policy *mining.Policy) error {
+	if err := checkTemplatePolicy(policy); err != nil {
+		return err
+	}
+
+	p.Lock()
+	p.policy = *policy
+	p.Unlock()
+	return nil
+}
+
+// checkTemplatePolicy returns an error when the passed mining policy has
+// settings block templates can't sensibly be generated with.
+func checkTemplatePolicy(policy *mining.Policy) error {
+	if policy.BlockMaxSize < blockMaxSizeMin ||
+		policy.BlockMaxSize > blockMaxSizeMax {
+
+		return fmt.Errorf("the max block size must be in between %d "+
+			"and %d -- got %d", blockMaxSizeMin, blockMaxSizeMax,
+			policy.BlockMaxSize)
+	}
+	if policy.BlockMinSize > policy.BlockMaxSize {
+		return fmt.Errorf("the min block size of %d exceeds the max "+
+			"block size of %d", policy.BlockMinSize,
+			policy.BlockMaxSize)
+	}
+	if policy.BlockPrioritySize > policy.BlockMaxSize {
+		return fmt.Errorf("the priority area size of %d exceeds the "+
+			"max block size of %d", policy.BlockPrioritySize,
+			policy.BlockMaxSize)
+	}
+	if policy.TxMinFreeFee < 0 {
+		return fmt.Errorf("the min fee rate may not be negative -- "+
+			"got %v", policy.TxMinFreeFee)
+	}
+	if policy.BlockMinPriority < 0 {
+		return fmt.Errorf("the min priority may not be negative -- "+
+			"got %v", policy.BlockMinPriority)
+	}
+	if policy.RecentTxAge < 0 {
+		return fmt.Errorf("the recent transaction age may not be "+
+			"negative -- got %v", policy.RecentTxAge)
+	}
+	if policy.RecentTxWeight <= 0 || policy.RecentTxWeight > 1 {
+		return fmt.Errorf("the recent transaction weight must be "+
+			"greater than 0 and at most 1 -- got %v",
+			policy.RecentTxWeight)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/conseweb/stcd/mining"
+)
+
+// TestBlockTemplatePolicy ensures invalid policies are rejected without
+// changing the current policy and that callers receive copies of it.
+func TestBlockTemplatePolicy(t *testing.T) {
+	p := newBlockTemplatePolicy(mining.Policy{
+		BlockMaxSize:      defaultBlockMaxSize,
+		BlockPrioritySize: defaultBlockPrioritySize,
+		BlockMinPriority:  minHighPriority,
+		RecentTxWeight:    defaultRecentTxWeight,
+	})
+
+	invalid := []func(*mining.Policy){
+		func(policy *mining.Policy) { policy.BlockMaxSize = blockMaxSizeMin - 1 },
+		func(policy *mining.Policy) { policy.BlockMaxSize = blockMaxSizeMax + 1 },
+		func(policy *mining.Policy) { policy.BlockMinSize = policy.BlockMaxSize + 1 },
+		func(policy *mining.Policy) { policy.BlockPrioritySize = policy.BlockMaxSize + 1 },
+		func(policy *mining.Policy) { policy.TxMinFreeFee = -1 },
+		func(policy *mining.Policy) { policy.BlockMinPriority = -1 },
+		func(policy *mining.Policy) { policy.RecentTxAge = -1 },
+		func(policy *mining.Policy) { policy.RecentTxWeight = 0 },
+		func(policy *mining.Policy) { policy.RecentTxWeight = 1.5 },
+	}
+	for i, change := range invalid {
+		policy := p.Policy()
+		change(policy)
+		if err := p.SetPolicy(policy); err == nil {
+			t.Errorf("SetPolicy #%d: expected error for %+v", i, policy)
+		}
+	}
+	if got := p.Policy().BlockMaxSize; got != defaultBlockMaxSize {
+		t.Fatalf("BlockMaxSize after invalid changes: got %d - want %d",
+			got, defaultBlockMaxSize)
+	}
+
+	policy := p.Policy()
+	policy.BlockPrioritySize = 0
+	policy.RecentTxWeight = 0.5
+	if err := p.SetPolicy(policy); err != nil {
+		t.Fatalf("SetPolicy: unexpected error: %v", err)
+	}
+	policy.RecentTxWeight = 0.25
+	got := p.Policy()
+	if got.BlockPrioritySize != 0 || got.RecentTxWeight != 0.5 {
+		t.Fatalf("Policy: got %+v - want priority size 0 and weight 0.5",
+			got)
+	}
+}
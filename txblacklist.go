@@ -0,0 +1,340 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+const (
+	// txBlacklistScriptPrefix and txBlacklistAddressPrefix start the
+	// entries of the transaction blacklist which ban output scripts.
+	txBlacklistScriptPrefix  = "script:"
+	txBlacklistAddressPrefix = "address:"
+)
+
+// txBlacklistEntry is a single entry of the transaction blacklist.  Exactly
+// one of its txID, outPoint and script fields is set.
+type txBlacklistEntry struct {
+	// txID bans the transaction with the hash.
+	txID *wire.ShaHash
+
+	// outPoint bans the transactions spending the output.
+	outPoint *wire.OutPoint
+
+	// script bans the transactions with outputs paying to the script, or
+	// to any script starting with it when prefix is set.
+	script []byte
+	prefix bool
+}
+
+// String returns the entry in the form it is parsed from, except that address
+// entries are returned as the script entries they are stored as.
+func (e *txBlacklistEntry) String() string {
+	switch {
+	case e.txID != nil:
+		return e.txID.String()
+	case e.outPoint != nil:
+		return e.outPoint.String()
+	case e.prefix:
+		return txBlacklistScriptPrefix + hex.EncodeToString(e.script) + "*"
+	default:
+		return txBlacklistScriptPrefix + hex.EncodeToString(e.script)
+	}
+}
+
+// parseTxBlacklistEntry parses an entry of the transaction blacklist.  The
+// entry is one of:
+//
+//	<txid>                 bans the transaction
+//	<txid>:<index>         bans the transactions spending the output
+//	script:<hex>           bans the transactions paying to the script
+//	script:<hex>*          bans the transactions paying to any script
+//	                       starting with the bytes
+//	address:<address>      bans the transactions paying to the address
+//
+// Addresses must belong to the passed network.
+func parseTxBlacklistEntry(s string, params *chaincfg.Params) (*txBlacklistEntry, error) {
+	switch {
+	case strings.HasPrefix(s, txBlacklistScriptPrefix):
+		pattern := strings.TrimPrefix(s, txBlacklistScriptPrefix)
+		prefix := strings.HasSuffix(pattern, "*")
+		pattern = strings.TrimSuffix(pattern, "*")
+		script, err := hex.DecodeString(pattern)
+		if err != nil || len(script) == 0 {
+			return nil, fmt.Errorf("invalid script %q", pattern)
+		}
+		return &txBlacklistEntry{script: script, prefix: prefix}, nil
+
+	case strings.HasPrefix(s, txBlacklistAddressPrefix):
+		encoded := strings.TrimPrefix(s, txBlacklistAddressPrefix)
+		addr, err := coinutil.DecodeAddress(encoded, params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %v", encoded,
+				err)
+		}
+		if !addr.IsForNet(params) {
+			return nil, fmt.Errorf("address %q is on the wrong "+
+				"network", encoded)
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &txBlacklistEntry{script: script}, nil
+	}
+
+	txID, index := s, ""
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		txID, index = s[:i], s[i+1:]
+	}
+	if len(txID) != wire.MaxHashStringSize {
+		return nil, fmt.Errorf("invalid transaction hash %q", txID)
+	}
+	hash, err := wire.NewShaHashFromStr(txID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction hash %q", txID)
+	}
+	if index == "" && len(txID) == len(s) {
+		return &txBlacklistEntry{txID: hash}, nil
+	}
+	vout, err := strconv.ParseUint(index, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output index %q", index)
+	}
+	return &txBlacklistEntry{
+		outPoint: wire.NewOutPoint(hash, uint32(vout)),
+	}, nil
+}
+
+// txBlacklist holds the transactions, outputs and output scripts banned by
+// the operator.  It is registered as a transaction hook so the memory pool
+// refuses to accept, and therefore to relay, the transactions it matches.
+// Blocks are not affected since the blacklist is local policy.
+//
+// The blacklist is loaded from the file given with the txblacklist option,
+// where each line holds an entry in the form accepted by
+// parseTxBlacklistEntry, and edited with the addfilterentry and
+// removefilterentry commands.  Every rejection and change is logged to the
+// TXFL subsystem as an audit trail.
+type txBlacklist struct {
+	sync.RWMutex
+	txIDs     map[wire.ShaHash]struct{}
+	outPoints map[wire.OutPoint]struct{}
+	scripts   map[string]struct{}
+	prefixes  map[string]struct{}
+}
+
+// newTxBlacklist returns a new empty transaction blacklist.
+func newTxBlacklist() *txBlacklist {
+	return &txBlacklist{
+		txIDs:     make(map[wire.ShaHash]struct{}),
+		outPoints: make(map[wire.OutPoint]struct{}),
+		scripts:   make(map[string]struct{}),
+		prefixes:  make(map[string]struct{}),
+	}
+}
+
+// parseTxBlacklist reads a transaction blacklist from the passed reader.
+// Empty lines and lines starting with '#' are ignored.
+func parseTxBlacklist(r io.Reader, params *chaincfg.Params) (*txBlacklist, error) {
+	b := newTxBlacklist()
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		entry, err := parseTxBlacklistEntry(line, params)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		b.add(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// loadTxBlacklist reads the transaction blacklist from the passed file.
+func loadTxBlacklist(file string, params *chaincfg.Params) (*txBlacklist, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseTxBlacklist(f, params)
+}
+
+// add adds the passed entry to the blacklist and returns whether it was not
+// already there.
+//
+// This function MUST be called with the blacklist lock held (for writes).
+func (b *txBlacklist) add(entry *txBlacklistEntry) bool {
+	var exists bool
+	switch {
+	case entry.txID != nil:
+		_, exists = b.txIDs[*entry.txID]
+		b.txIDs[*entry.txID] = struct{}{}
+	case entry.outPoint != nil:
+		_, exists = b.outPoints[*entry.outPoint]
+		b.outPoints[*entry.outPoint] = struct{}{}
+	case entry.prefix:
+		_, exists = b.prefixes[string(entry.script)]
+		b.prefixes[string(entry.script)] = struct{}{}
+	default:
+		_, exists = b.scripts[string(entry.script)]
+		b.scripts[string(entry.script)] = struct{}{}
+	}
+	return !exists
+}
+
+// remove removes the passed entry from the blacklist and returns whether it
+// was there.
+//
+// This function MUST be called with the blacklist lock held (for writes).
+func (b *txBlacklist) remove(entry *txBlacklistEntry) bool {
+	var exists bool
+	switch {
+	case entry.txID != nil:
+		_, exists = b.txIDs[*entry.txID]
+		delete(b.txIDs, *entry.txID)
+	case entry.outPoint != nil:
+		_, exists = b.outPoints[*entry.outPoint]
+		delete(b.outPoints, *entry.outPoint)
+	case entry.prefix:
+		_, exists = b.prefixes[string(entry.script)]
+		delete(b.prefixes, string(entry.script))
+	default:
+		_, exists = b.scripts[string(entry.script)]
+		delete(b.scripts, string(entry.script))
+	}
+	return exists
+}
+
+// Add adds the passed entry to the blacklist.  It returns false when the entry
+// is already there.
+//
+// This function is safe for concurrent access.
+func (b *txBlacklist) Add(entry *txBlacklistEntry) bool {
+	b.Lock()
+	added := b.add(entry)
+	b.Unlock()
+	if added {
+		txflLog.Infof("Added blacklist entry %v", entry)
+	}
+	return added
+}
+
+// Remove removes the passed entry from the blacklist.  It returns false when
+// the entry is not there.
+//
+// This function is safe for concurrent access.
+func (b *txBlacklist) Remove(entry *txBlacklistEntry) bool {
+	b.Lock()
+	removed := b.remove(entry)
+	b.Unlock()
+	if removed {
+		txflLog.Infof("Removed blacklist entry %v", entry)
+	}
+	return removed
+}
+
+// Len returns the number of entries of the blacklist.
+//
+// This function is safe for concurrent access.
+func (b *txBlacklist) Len() int {
+	b.RLock()
+	defer b.RUnlock()
+
+	return len(b.txIDs) + len(b.outPoints) + len(b.scripts) +
+		len(b.prefixes)
+}
+
+// match returns why the passed transaction is banned, or an empty string when
+// no entry of the blacklist matches it.
+//
+// This function MUST be called with the blacklist lock held (for reads).
+func (b *txBlacklist) match(tx *coinutil.Tx) string {
+	if _, ok := b.txIDs[*tx.Sha()]; ok {
+		return "the transaction is banned"
+	}
+	if len(b.outPoints) != 0 {
+		for i, txIn := range tx.MsgTx().TxIn {
+			_, ok := b.outPoints[txIn.PreviousOutPoint]
+			if ok {
+				return fmt.Sprintf("input %d spends banned "+
+					"output %v", i, txIn.PreviousOutPoint)
+			}
+		}
+	}
+	for i, txOut := range tx.MsgTx().TxOut {
+		if _, ok := b.scripts[string(txOut.PkScript)]; ok {
+			return fmt.Sprintf("output %d pays to banned script "+
+				"%x", i, txOut.PkScript)
+		}
+		for prefix := range b.prefixes {
+			if bytes.HasPrefix(txOut.PkScript, []byte(prefix)) {
+				return fmt.Sprintf("output %d pays to script %x "+
+					"matching banned pattern %x*", i,
+					txOut.PkScript, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// CheckMempoolTx rejects the transactions matched by the blacklist.  It is
+// part of the TxHook interface.
+func (b *txBlacklist) CheckMempoolTx(tx *coinutil.Tx, fee int64) error {
+	b.RLock()
+	reason := b.match(tx)
+	b.RUnlock()
+	if reason == "" {
+		return nil
+	}
+
+	txflLog.Infof("Rejected transaction %v: %s", tx.Sha(), reason)
+	return errors.New(reason)
+}
+
+// MempoolTxAccepted does nothing.  It is part of the TxHook interface.
+func (b *txBlacklist) MempoolTxAccepted(tx *coinutil.Tx) {}
+
+// Evict removes the transactions matched by the blacklist, along with the
+// transactions spending their outputs, from the passed memory pool.  It is used
+// after entries are added so the transactions which were accepted before are
+// no longer relayed or mined.
+//
+// This function is safe for concurrent access.
+func (b *txBlacklist) Evict(mp *txMemPool) {
+	for _, txD := range mp.TxDescs() {
+		b.RLock()
+		reason := b.match(txD.Tx)
+		b.RUnlock()
+		if reason == "" {
+			continue
+		}
+
+		txflLog.Infof("Evicted transaction %v from the memory pool: %s",
+			txD.Tx.Sha(), reason)
+		mp.RemoveTransaction(txD.Tx, true, txRemovedEviction)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestParseTxBlacklist ensures the entries of a transaction blacklist file are
+// parsed and invalid lines are reported.
+func TestParseTxBlacklist(t *testing.T) {
+	params := activeNetParams.Params
+	addr, err := coinutil.NewAddressPubKeyHash(bytes.Repeat([]byte{0x01}, 20),
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	addrScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+
+	txID := strings.Repeat("ab", 32)
+	file := "# Banned transactions\n" +
+		"\n" +
+		txID + "\n" +
+		"  " + txID + ":3  \n" +
+		"script:6a04deadbeef\n" +
+		"script:6a*\n" +
+		"address:" + addr.EncodeAddress() + "\n"
+	b, err := parseTxBlacklist(strings.NewReader(file), params)
+	if err != nil {
+		t.Fatalf("parseTxBlacklist: %v", err)
+	}
+	if b.Len() != 5 {
+		t.Fatalf("blacklist holds %d entries - want 5", b.Len())
+	}
+	// The address is stored as the script it pays to.
+	for _, s := range []string{txID, txID + ":3", "script:6a04deadbeef",
+		"script:6a*", "script:" + hex.EncodeToString(addrScript)} {
+		entry, err := parseTxBlacklistEntry(s, params)
+		if err != nil {
+			t.Fatalf("parseTxBlacklistEntry(%q): %v", s, err)
+		}
+		if entry.String() != s {
+			t.Errorf("entry %q is formatted as %q", s, entry)
+		}
+		if b.add(entry) {
+			t.Errorf("entry %q was not loaded", s)
+		}
+	}
+
+	tests := []string{
+		txID[:60],
+		txID + ":x",
+		"script:",
+		"script:6g",
+		"address:" + txID,
+	}
+	for _, line := range tests {
+		_, err := parseTxBlacklist(strings.NewReader("\n"+line), params)
+		if err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+			t.Errorf("parseTxBlacklist(%q): got error %v - want "+
+				"error on line 2", line, err)
+		}
+	}
+}
+
+// TestTxBlacklist ensures the transaction blacklist rejects the transactions
+// matched by its entries and evicts them from the memory pool.
+func TestTxBlacklist(t *testing.T) {
+	params := activeNetParams.Params
+
+	// newTx returns a transaction spending the passed outpoint and paying
+	// to the passed script.
+	newTx := func(prevOut *wire.OutPoint, pkScript []byte) *coinutil.Tx {
+		msgTx := wire.NewMsgTx()
+		msgTx.AddTxIn(wire.NewTxIn(prevOut, nil))
+		msgTx.AddTxOut(wire.NewTxOut(1000, pkScript))
+		return coinutil.NewTx(msgTx)
+	}
+	parent := newTx(&wire.OutPoint{Hash: wire.ShaHash{0x01}}, []byte{0x51})
+	child := newTx(wire.NewOutPoint(parent.Sha(), 0), []byte{0x52})
+	other := newTx(&wire.OutPoint{Hash: wire.ShaHash{0x02}},
+		[]byte{0x6a, 0x01, 0x02})
+
+	tests := []struct {
+		entry  string
+		banned []*coinutil.Tx
+	}{
+		{parent.Sha().String(), []*coinutil.Tx{parent}},
+		{parent.Sha().String() + ":0", []*coinutil.Tx{child}},
+		{"script:52", []*coinutil.Tx{child}},
+		{"script:6a*", []*coinutil.Tx{other}},
+		{"script:6a", nil},
+	}
+	for _, test := range tests {
+		b := newTxBlacklist()
+		entry, err := parseTxBlacklistEntry(test.entry, params)
+		if err != nil {
+			t.Fatalf("parseTxBlacklistEntry(%q): %v", test.entry, err)
+		}
+		if !b.Add(entry) {
+			t.Fatalf("Add(%q): entry not added", test.entry)
+		}
+		if b.Add(entry) {
+			t.Fatalf("Add(%q): entry added twice", test.entry)
+		}
+		for _, tx := range []*coinutil.Tx{parent, child, other} {
+			var want bool
+			for _, banned := range test.banned {
+				want = want || banned == tx
+			}
+			err := b.CheckMempoolTx(tx, 0)
+			if (err != nil) != want {
+				t.Errorf("CheckMempoolTx with entry %q: got %v "+
+					"for %v - want banned %v", test.entry,
+					err, tx.Sha(), want)
+			}
+		}
+		if !b.Remove(entry) || b.Remove(entry) {
+			t.Fatalf("Remove(%q): entry not removed once",
+				test.entry)
+		}
+		if err := b.CheckMempoolTx(parent, 0); err != nil {
+			t.Errorf("CheckMempoolTx with entry %q removed: %v",
+				test.entry, err)
+		}
+	}
+
+	// Banning the parent after it was accepted evicts it and its child
+	// from the memory pool.
+	mp := newTxMemPool(&mempoolConfig{})
+	for _, tx := range []*coinutil.Tx{parent, child, other} {
+		mp.addTransaction(blockchain.TxStore{}, tx, 1, 0)
+	}
+	b := newTxBlacklist()
+	entry, err := parseTxBlacklistEntry(parent.Sha().String(), params)
+	if err != nil {
+		t.Fatalf("parseTxBlacklistEntry: %v", err)
+	}
+	b.Add(entry)
+	b.Evict(mp)
+	if mp.HaveTransaction(parent.Sha()) || mp.HaveTransaction(child.Sha()) ||
+		!mp.HaveTransaction(other.Sha()) {
+
+		t.Fatalf("Evict: pool holds %d transactions - want only %v",
+			mp.Count(), other.Sha())
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"testing"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// benchNumInputs is the number of inputs of the transaction the signature hash
+// benchmarks calculate the signature hashes of.
+const benchNumInputs = 1000
+
+// benchSigHashTx returns a transaction with the passed number of inputs, each
+// of which has a typical pay-to-pubkey-hash signature script, along with the
+// pay-to-pubkey-hash script its inputs are signed with.
+func benchSigHashTx(numInputs int) (*wire.MsgTx, []parsedOpcode) {
+	pkScript := []byte{OP_DUP, OP_HASH160, OP_DATA_20}
+	pkScript = append(pkScript, make([]byte, 20)...)
+	pkScript = append(pkScript, OP_EQUALVERIFY, OP_CHECKSIG)
+	parsedScript, err := parseScript(pkScript)
+	if err != nil {
+		panic(err)
+	}
+
+	// A signature and a compressed public key.
+	sigScript := make([]byte, 107)
+	sigScript[0] = OP_DATA_72
+	sigScript[73] = OP_DATA_33
+
+	tx := wire.NewMsgTx()
+	for i := 0; i < numInputs; i++ {
+		var hash wire.ShaHash
+		hash[0] = byte(i)
+		hash[1] = byte(i >> 8)
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: *wire.NewOutPoint(&hash, uint32(i)),
+			SignatureScript:  sigScript,
+			Sequence:         wire.MaxTxInSequenceNum,
+		})
+	}
+	tx.AddTxOut(wire.NewTxOut(100000000, pkScript))
+	tx.AddTxOut(wire.NewTxOut(200000000, pkScript))
+	return tx, parsedScript
+}
+
+// BenchmarkCalcSigHash benchmarks calculating the legacy signature hashes of
+// all of the inputs of a transaction with many inputs.
+func BenchmarkCalcSigHash(b *testing.B) {
+	tx, script := benchSigHashTx(benchNumInputs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for idx := range tx.TxIn {
+			calcSignatureHash(script, SigHashAll, tx, idx)
+		}
+	}
+}
+
+// BenchmarkCalcWitnessSigHash benchmarks calculating the version 0 witness
+// signature hashes of all of the inputs of a transaction with many inputs when
+// the partial signature hashes of the transaction are only calculated once.
+func BenchmarkCalcWitnessSigHash(b *testing.B) {
+	tx, script := benchSigHashTx(benchNumInputs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sigHashes := NewTxSigHashes(tx)
+		for idx := range tx.TxIn {
+			_, err := calcWitnessSignatureHash(script, sigHashes,
+				SigHashAll, tx, idx, 100000000)
+			if err != nil {
+				b.Fatalf("calcWitnessSignatureHash: %v", err)
+			}
+		}
+	}
+}
+
+// BenchmarkCalcWitnessSigHashNoCache benchmarks calculating the version 0
+// witness signature hashes of all of the inputs of a transaction with many
+// inputs when the partial signature hashes of the transaction are calculated
+// for each input.
+func BenchmarkCalcWitnessSigHashNoCache(b *testing.B) {
+	tx, script := benchSigHashTx(benchNumInputs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for idx := range tx.TxIn {
+			_, err := calcWitnessSignatureHash(script, nil,
+				SigHashAll, tx, idx, 100000000)
+			if err != nil {
+				b.Fatalf("calcWitnessSignatureHash: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"time"
+)
+
+// Budget limits the work a script engine may do to execute the scripts it was
+// created for.  The consensus limits on the size of scripts and the number of
+// operations they execute already bound the work, but callers validating
+// scripts from untrusted sources may use a budget to bound it further so a
+// malicious script can not hold up a validation worker for long.
+//
+// The zero value imposes no limit.
+type Budget struct {
+	// MaxSteps is the maximum number of opcodes the engine executes,
+	// counted across all of the scripts it runs.  Zero means no limit.
+	MaxSteps int
+
+	// Timeout is the maximum amount of time the engine spends executing
+	// the scripts, starting with the first opcode it executes.  Zero means
+	// no limit.
+	Timeout time.Duration
+}
+
+// SetBudget sets the budget which limits the work the engine does to execute
+// its scripts.  It must be called before the engine executes the first opcode.
+func (vm *Engine) SetBudget(budget Budget) {
+	vm.budget = budget
+}
+
+// spendBudget accounts for the execution of an opcode and returns
+// ErrBudgetExceeded once the budget of the engine has been spent.
+func (vm *Engine) spendBudget() error {
+	if vm.budget.MaxSteps > 0 && vm.numSteps >= vm.budget.MaxSteps {
+		return ErrBudgetExceeded
+	}
+	vm.numSteps++
+
+	if vm.budget.Timeout > 0 {
+		now := time.Now()
+		if vm.deadline.IsZero() {
+			vm.deadline = now.Add(vm.budget.Timeout)
+		} else if now.After(vm.deadline) {
+			return ErrBudgetExceeded
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"github.com/conseweb/stcd/wire"
+)
+
+// StepInfo houses the state of the script engine after an opcode has been
+// executed.  The stacks are copies, so they may be retained and modified by
+// the caller.
+type StepInfo struct {
+	// ScriptIndex is the index of the script the opcode belongs to.  Index
+	// 0 is the signature script and 1 is the public key script.  Any
+	// further scripts are the redeem script of a pay-to-script-hash or
+	// the script of a witness program.
+	ScriptIndex int
+
+	// OpcodeIndex is the offset of the opcode within its script.
+	OpcodeIndex int
+
+	// Opcode is the disassembly of the executed opcode.
+	Opcode string
+
+	// Stack and AltStack are the contents of the data and alternate
+	// stacks after the opcode was executed, with the top item last.  For
+	// the final opcode of a script they reflect the stacks the next
+	// script starts with, so the alternate stack is always empty and the
+	// stack of a pay-to-script-hash redeem script no longer includes the
+	// script itself.
+	Stack    [][]byte
+	AltStack [][]byte
+}
+
+// StepHook is the signature of the callback a debug engine invokes after each
+// opcode it executes.
+type StepHook func(step *StepInfo)
+
+// NewDebugEngine returns a new script engine for the provided public key
+// script, transaction, and input index which invokes the passed hook after
+// each opcode executed by Execute.  It is otherwise identical to an engine
+// created by NewEngine.
+func NewDebugEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags,
+	sigCache *SigCache, inputAmount int64, hook StepHook) (*Engine, error) {
+
+	vm, err := NewEngine(scriptPubKey, tx, txIdx, flags, sigCache,
+		inputAmount)
+	if err != nil {
+		return nil, err
+	}
+	vm.stepHook = hook
+	return vm, nil
+}
+
+// callStepHook invokes the step hook of the engine, if any, for the opcode at
+// the passed position, which has just been executed.
+func (vm *Engine) callStepHook(scriptIdx, scriptOff int) {
+	if vm.stepHook == nil {
+		return
+	}
+	vm.stepHook(&StepInfo{
+		ScriptIndex: scriptIdx,
+		OpcodeIndex: scriptOff,
+		Opcode:      vm.scripts[scriptIdx][scriptOff].print(false),
+		Stack:       vm.GetStack(),
+		AltStack:    vm.GetAltStack(),
+	})
+}
@@ -5,8 +5,10 @@
 package txscript
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/conseweb/stcd/btcec"
 	"github.com/conseweb/stcd/wire"
@@ -64,6 +66,21 @@ const (
 	// ScriptVerifyStrictEncoding defines that signature scripts and
 	// public keys must follow the strict encoding requirements.
 	ScriptVerifyStrictEncoding
+
+	// ScriptVerifyWitness defines whether or not to verify a transaction
+	// output using a witness program template.  This is BIP0141.  This
+	// flag should never be used without the ScriptBip16 flag.
+	ScriptVerifyWitness
+
+	// ScriptVerifyDiscourageUpgradeableWitnessProgram makes witness
+	// programs with versions 1-16 non-standard.  This flag must not be
+	// used for consensus critical code.
+	ScriptVerifyDiscourageUpgradeableWitnessProgram
+
+	// ScriptVerifyCanonicalPubKeys defines that public keys must be either
+	// compressed or uncompressed.  Unlike ScriptVerifyStrictEncoding, it
+	// does not place any requirements on the signature hash type.
+	ScriptVerifyCanonicalPubKeys
 )
 
 const (
@@ -92,8 +109,16 @@ type Engine struct {
 	numOps          int
 	flags           ScriptFlags
 	sigCache        *SigCache
-	bip16           bool     // treat execution as pay-to-script-hash
-	savedFirstStack [][]byte // stack from first script for bip16 scripts
+	bip16           bool         // treat execution as pay-to-script-hash
+	savedFirstStack [][]byte     // stack from first script for bip16 scripts
+	witnessVersion  int          // version of the spent witness program
+	witnessProgram  []byte       // spent witness program, nil if none
+	inputAmount     int64        // amount of the spent output
+	sigHashes       *TxSigHashes // partial signature hashes, may be nil
+	stepHook        StepHook     // invoked after each opcode, may be nil
+	budget          Budget       // limits the work done by Execute
+	numSteps        int          // opcodes executed so far
+	deadline        time.Time    // end of the execution time budget
 }
 
 // hasFlag returns whether the script engine instance has the passed flag set.
@@ -101,6 +126,78 @@ func (vm *Engine) hasFlag(flag ScriptFlags) bool {
 	return vm.flags&flag == flag
 }
 
+// isWitnessVersionActive returns whether or not the engine is verifying the
+// spend of a witness program of the passed version.
+func (vm *Engine) isWitnessVersionActive(version int) bool {
+	return vm.witnessProgram != nil && vm.witnessVersion == version
+}
+
+// verifyWitnessProgram validates the witness of the input being verified
+// against the spent witness program.  For version 0 programs, the script to
+// execute is appended to the scripts of the engine and the stack is set to
+// the witness items it operates on.
+func (vm *Engine) verifyWitnessProgram(witness [][]byte) error {
+	if vm.witnessVersion != 0 {
+		// Spends of witness programs with unknown versions succeed so
+		// they may be given meaning by future soft forks, unless that
+		// is explicitly discouraged.
+		if vm.hasFlag(ScriptVerifyDiscourageUpgradeableWitnessProgram) {
+			return ErrDiscourageUpgradableWitnessProgram
+		}
+		vm.witnessProgram = nil
+		vm.SetStack([][]byte{{1}})
+		return nil
+	}
+
+	var script []byte
+	switch len(vm.witnessProgram) {
+	// The witness of a pay-to-witness-pub-key-hash spend is exactly a
+	// signature and a public key, which are checked with a regular
+	// pay-to-pubkey-hash script.
+	case payToWitnessPubKeyHashDataSize:
+		if len(witness) != 2 {
+			return ErrWitnessProgramMismatch
+		}
+		var err error
+		script, err = payToPubKeyHashScript(vm.witnessProgram)
+		if err != nil {
+			return err
+		}
+
+	// The last item of the witness of a pay-to-witness-script-hash spend
+	// is the script to execute, which must hash to the program.
+	case payToWitnessScriptHashDataSize:
+		if len(witness) == 0 {
+			return ErrWitnessProgramEmpty
+		}
+		script = witness[len(witness)-1]
+		if len(script) > maxScriptSize {
+			return ErrStackLongScript
+		}
+		if !bytes.Equal(witnessScriptHash(script), vm.witnessProgram) {
+			return ErrWitnessProgramMismatch
+		}
+		witness = witness[:len(witness)-1]
+
+	default:
+		return ErrWitnessProgramWrongLength
+	}
+
+	for _, item := range witness {
+		if len(item) > MaxScriptElementSize {
+			return ErrStackElementTooBig
+		}
+	}
+
+	pops, err := parseScript(script)
+	if err != nil {
+		return err
+	}
+	vm.scripts = append(vm.scripts, pops)
+	vm.SetStack(witness)
+	return nil
+}
+
 // isBranchExecuting returns whether or not the current conditional branch is
 // actively executing.  For example, when the data stack has an OP_FALSE on it
 // and an OP_IF is encountered, the branch is inactive until an OP_ELSE or
@@ -224,8 +321,9 @@ func (vm *Engine) CheckErrorCondition(finalScript bool) error {
 	if vm.scriptIdx < len(vm.scripts) {
 		return ErrStackScriptUnfinished
 	}
-	if finalScript && vm.hasFlag(ScriptVerifyCleanStack) &&
-		vm.dstack.Depth() != 1 {
+	// Version 0 witness scripts must always leave a clean stack.
+	if finalScript && (vm.hasFlag(ScriptVerifyCleanStack) ||
+		vm.isWitnessVersionActive(0)) && vm.dstack.Depth() != 1 {
 
 		return ErrStackCleanStack
 	} else if vm.dstack.Depth() < 1 {
@@ -263,6 +361,12 @@ func (vm *Engine) Step() (done bool, err error) {
 	}
 	opcode := &vm.scripts[vm.scriptIdx][vm.scriptOff]
 
+	// Stop once the execution budget set by the caller has been spent.
+	err = vm.spendBudget()
+	if err != nil {
+		return true, err
+	}
+
 	// Execute the opcode while taking into account several things such as
 	// disabled opcodes, illegal opcodes, maximum allowed operations per
 	// script, maximum script element sizes, and conditionals.
@@ -303,16 +407,33 @@ func (vm *Engine) Step() (done bool, err error) {
 				return false, err
 			}
 
-			script := vm.savedFirstStack[len(vm.savedFirstStack)-1]
-			pops, err := parseScript(script)
-			if err != nil {
+			// A witness program nested in the script hash is
+			// verified against the witness rather than executed.
+			if vm.witnessProgram != nil {
+				witness := vm.tx.TxIn[vm.txIdx].Witness
+				if err := vm.verifyWitnessProgram(witness); err != nil {
+					return false, err
+				}
+			} else {
+				script := vm.savedFirstStack[len(vm.savedFirstStack)-1]
+				pops, err := parseScript(script)
+				if err != nil {
+					return false, err
+				}
+				vm.scripts = append(vm.scripts, pops)
+
+				// Set stack to be the stack from first script
+				// minus the script itself
+				vm.SetStack(vm.savedFirstStack[:len(vm.savedFirstStack)-1])
+			}
+		} else if vm.scriptIdx == 1 && vm.witnessProgram != nil {
+			// The public key script is a witness program, so verify
+			// the witness next.
+			vm.scriptIdx++
+			witness := vm.tx.TxIn[vm.txIdx].Witness
+			if err := vm.verifyWitnessProgram(witness); err != nil {
 				return false, err
 			}
-			vm.scripts = append(vm.scripts, pops)
-
-			// Set stack to be the stack from first script minus the
-			// script itself
-			vm.SetStack(vm.savedFirstStack[:len(vm.savedFirstStack)-1])
 		} else {
 			vm.scriptIdx++
 		}
@@ -341,10 +462,12 @@ func (vm *Engine) Execute() (err error) {
 			return fmt.Sprintf("stepping %v", dis)
 		}))
 
+		scriptIdx, scriptOff := vm.scriptIdx, vm.scriptOff
 		done, err = vm.Step()
 		if err != nil {
 			return err
 		}
+		vm.callStepHook(scriptIdx, scriptOff)
 		log.Tracef("%v", newLogClosure(func() string {
 			var dstr, astr string
 
@@ -385,7 +508,9 @@ func (vm *Engine) checkHashTypeEncoding(hashType SigHashType) error {
 // checkPubKeyEncoding returns whether or not the passed public key adheres to
 // the strict encoding requirements if enabled.
 func (vm *Engine) checkPubKeyEncoding(pubKey []byte) error {
-	if !vm.hasFlag(ScriptVerifyStrictEncoding) {
+	if !vm.hasFlag(ScriptVerifyStrictEncoding) &&
+		!vm.hasFlag(ScriptVerifyCanonicalPubKeys) {
+
 		return nil
 	}
 
@@ -576,10 +701,42 @@ func (vm *Engine) SetAltStack(data [][]byte) {
 	setStack(&vm.astack, data)
 }
 
+// txSigHashes returns the partial signature hashes of the transaction, which
+// are calculated the first time they are needed when the engine was created
+// without them.
+func (vm *Engine) txSigHashes() *TxSigHashes {
+	if vm.sigHashes == nil {
+		vm.sigHashes = NewTxSigHashes(&vm.tx)
+	}
+	return vm.sigHashes
+}
+
+// NewEngineWithSigHashes returns a new script engine for the provided public
+// key script, transaction, and input index which uses the passed partial
+// signature hashes of the transaction to verify the signatures of witness
+// program spends.  They must be the hashes returned by NewTxSigHashes for the
+// transaction.  Sharing them between the engines verifying the inputs of a
+// transaction avoids calculating them once per input, which would make the
+// cost of verifying all of the inputs quadratic in the size of the
+// transaction.  It is otherwise identical to an engine created by NewEngine.
+func NewEngineWithSigHashes(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags,
+	sigCache *SigCache, sigHashes *TxSigHashes, inputAmount int64) (*Engine, error) {
+
+	vm, err := NewEngine(scriptPubKey, tx, txIdx, flags, sigCache,
+		inputAmount)
+	if err != nil {
+		return nil, err
+	}
+	vm.sigHashes = sigHashes
+	return vm, nil
+}
+
 // NewEngine returns a new script engine for the provided public key script,
 // transaction, and input index.  The flags modify the behavior of the script
-// engine according to the description provided by each flag.
-func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags, sigCache *SigCache) (*Engine, error) {
+// engine according to the description provided by each flag.  The input
+// amount is the value of the output being spent, which is only used to verify
+// the signatures of witness program spends.
+func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags, sigCache *SigCache, inputAmount int64) (*Engine, error) {
 	// The provided transaction input index must refer to a valid input.
 	if txIdx < 0 || txIdx >= len(tx.TxIn) {
 		return nil, ErrInvalidIndex
@@ -594,11 +751,18 @@ func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags
 	// allowing the clean stack flag without the P2SH flag would make it
 	// possible to have a situation where P2SH would not be a soft fork when
 	// it should be.
-	vm := Engine{flags: flags, sigCache: sigCache}
+	vm := Engine{flags: flags, sigCache: sigCache, inputAmount: inputAmount}
 	if vm.hasFlag(ScriptVerifyCleanStack) && !vm.hasFlag(ScriptBip16) {
 		return nil, ErrInvalidFlags
 	}
 
+	// The witness flag (ScriptVerifyWitness) is not allowed without the
+	// pay-to-script-hash flag either since witness programs may be nested
+	// in pay-to-script-hash outputs.
+	if vm.hasFlag(ScriptVerifyWitness) && !vm.hasFlag(ScriptBip16) {
+		return nil, ErrInvalidFlags
+	}
+
 	// The signature script must only contain data pushes when the
 	// associated flag is set.
 	if vm.hasFlag(ScriptVerifySigPushOnly) && !IsPushOnlyScript(scriptSig) {
@@ -636,6 +800,50 @@ func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags
 		}
 		vm.bip16 = true
 	}
+
+	// Determine whether the input spends a witness program, either directly
+	// or nested in a pay-to-script-hash output, so the witness is verified
+	// once the public key script has been executed.
+	witness := tx.TxIn[txIdx].Witness
+	if vm.hasFlag(ScriptVerifyWitness) {
+		var program []byte
+		switch {
+		case IsWitnessProgram(scriptPubKey):
+			// The signature script must be empty to prevent it
+			// from malleating the transaction.
+			if len(scriptSig) != 0 {
+				return nil, ErrWitnessMalleated
+			}
+			program = scriptPubKey
+
+		case vm.bip16 && len(vm.scripts[0]) > 0:
+			// The signature script must be exactly a single
+			// canonical push of the witness program.
+			redeemScript := vm.scripts[0][len(vm.scripts[0])-1].data
+			if IsWitnessProgram(redeemScript) {
+				push, err := NewScriptBuilder().AddData(redeemScript).Script()
+				if err != nil {
+					return nil, err
+				}
+				if !bytes.Equal(scriptSig, push) {
+					return nil, ErrWitnessMalleatedP2SH
+				}
+				program = redeemScript
+			}
+		}
+
+		if program != nil {
+			version, data, err := ExtractWitnessProgramInfo(program)
+			if err != nil {
+				return nil, err
+			}
+			vm.witnessVersion = version
+			vm.witnessProgram = data
+		} else if len(witness) != 0 {
+			return nil, ErrWitnessUnexpected
+		}
+	}
+
 	if vm.hasFlag(ScriptVerifyMinimalData) {
 		vm.dstack.verifyMinimalData = true
 		vm.astack.verifyMinimalData = true
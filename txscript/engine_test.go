@@ -5,8 +5,13 @@
 package txscript_test
 
 import (
+	"bytes"
 	"testing"
+	"time"
 
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/fastsha256"
+	"github.com/conseweb/stcd/btcec"
 	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
 )
@@ -62,7 +67,7 @@ func TestBadPC(t *testing.T) {
 	pkScript := []byte{txscript.OP_NOP}
 
 	for _, test := range pcTests {
-		vm, err := txscript.NewEngine(pkScript, tx, 0, 0, nil)
+		vm, err := txscript.NewEngine(pkScript, tx, 0, 0, nil, 0)
 		if err != nil {
 			t.Errorf("Failed to create script: %v", err)
 		}
@@ -84,6 +89,140 @@ func TestBadPC(t *testing.T) {
 	}
 }
 
+// TestDebugEngine ensures the step hook of a debug engine is invoked for each
+// executed opcode with the expected stacks.
+func TestDebugEngine(t *testing.T) {
+	t.Parallel()
+
+	tx := &wire.MsgTx{
+		Version: 1,
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: wire.OutPoint{Index: 0},
+			SignatureScript:  []byte{txscript.OP_2, txscript.OP_3},
+			Sequence:         wire.MaxTxInSequenceNum,
+		}},
+		TxOut: []*wire.TxOut{{Value: 0}},
+	}
+	pkScript := []byte{txscript.OP_TOALTSTACK, txscript.OP_5,
+		txscript.OP_FROMALTSTACK, txscript.OP_ADD, txscript.OP_8,
+		txscript.OP_EQUAL}
+
+	var steps []txscript.StepInfo
+	hook := func(step *txscript.StepInfo) {
+		steps = append(steps, *step)
+	}
+	vm, err := txscript.NewDebugEngine(pkScript, tx, 0, 0, nil, 0, hook)
+	if err != nil {
+		t.Fatalf("NewDebugEngine: unexpected error: %v", err)
+	}
+	if err := vm.Execute(); err != nil {
+		t.Fatalf("Execute: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		scriptIdx int
+		opcodeIdx int
+		opcode    string
+		stack     [][]byte
+		altStack  [][]byte
+	}{
+		{0, 0, "OP_2", [][]byte{{2}}, nil},
+		{0, 1, "OP_3", [][]byte{{2}, {3}}, nil},
+		{1, 0, "OP_TOALTSTACK", [][]byte{{2}}, [][]byte{{3}}},
+		{1, 1, "OP_5", [][]byte{{2}, {5}}, [][]byte{{3}}},
+		{1, 2, "OP_FROMALTSTACK", [][]byte{{2}, {5}, {3}}, nil},
+		{1, 3, "OP_ADD", [][]byte{{2}, {8}}, nil},
+		{1, 4, "OP_8", [][]byte{{2}, {8}, {8}}, nil},
+		{1, 5, "OP_EQUAL", [][]byte{{2}, {1}}, nil},
+	}
+	if len(steps) != len(tests) {
+		t.Fatalf("unexpected number of steps - got %d, want %d",
+			len(steps), len(tests))
+	}
+	for i, test := range tests {
+		step := &steps[i]
+		if step.ScriptIndex != test.scriptIdx ||
+			step.OpcodeIndex != test.opcodeIdx ||
+			step.Opcode != test.opcode {
+
+			t.Errorf("step #%d: unexpected opcode - got %02x:%04x %s, "+
+				"want %02x:%04x %s", i, step.ScriptIndex,
+				step.OpcodeIndex, step.Opcode, test.scriptIdx,
+				test.opcodeIdx, test.opcode)
+			continue
+		}
+		if !stacksEqual(step.Stack, test.stack) {
+			t.Errorf("step #%d: unexpected stack - got %x, want %x",
+				i, step.Stack, test.stack)
+		}
+		if !stacksEqual(step.AltStack, test.altStack) {
+			t.Errorf("step #%d: unexpected alt stack - got %x, "+
+				"want %x", i, step.AltStack, test.altStack)
+		}
+	}
+}
+
+// stacksEqual returns whether or not the passed stacks hold the same items.
+func stacksEqual(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestBudget ensures the execution of scripts fails with ErrBudgetExceeded
+// once the engine has executed more opcodes or spent more time than allowed by
+// its budget.
+func TestBudget(t *testing.T) {
+	t.Parallel()
+
+	tx := &wire.MsgTx{
+		Version: 1,
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: wire.OutPoint{Index: 0},
+			SignatureScript:  []byte{txscript.OP_2, txscript.OP_3},
+			Sequence:         wire.MaxTxInSequenceNum,
+		}},
+		TxOut: []*wire.TxOut{{Value: 0}},
+	}
+	pkScript := []byte{txscript.OP_ADD, txscript.OP_5, txscript.OP_EQUAL}
+
+	tests := []struct {
+		name   string
+		budget txscript.Budget
+		hook   txscript.StepHook
+		err    error
+	}{
+		{"no budget", txscript.Budget{}, nil, nil},
+		{"enough steps", txscript.Budget{MaxSteps: 5}, nil, nil},
+		{"too few steps", txscript.Budget{MaxSteps: 4}, nil,
+			txscript.ErrBudgetExceeded},
+		{"enough time", txscript.Budget{Timeout: time.Hour}, nil, nil},
+		{"too little time", txscript.Budget{Timeout: time.Nanosecond},
+			func(*txscript.StepInfo) { time.Sleep(time.Millisecond) },
+			txscript.ErrBudgetExceeded},
+	}
+	for _, test := range tests {
+		vm, err := txscript.NewDebugEngine(pkScript, tx, 0, 0, nil, 0,
+			test.hook)
+		if err != nil {
+			t.Errorf("%s: NewDebugEngine: unexpected error: %v",
+				test.name, err)
+			continue
+		}
+		vm.SetBudget(test.budget)
+		if err := vm.Execute(); err != test.err {
+			t.Errorf("%s: Execute: got %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+}
+
 // TestCheckErrorCondition tests the execute early test in CheckErrorCondition()
 // since most code paths are tested elsewhere.
 func TestCheckErrorCondition(t *testing.T) {
@@ -133,7 +272,7 @@ func TestCheckErrorCondition(t *testing.T) {
 		txscript.OP_TRUE,
 	}
 
-	vm, err := txscript.NewEngine(pkScript, tx, 0, 0, nil)
+	vm, err := txscript.NewEngine(pkScript, tx, 0, 0, nil, 0)
 	if err != nil {
 		t.Errorf("failed to create script: %v", err)
 	}
@@ -179,6 +318,7 @@ func TestInvalidFlagCombinations(t *testing.T) {
 
 	tests := []txscript.ScriptFlags{
 		txscript.ScriptVerifyCleanStack,
+		txscript.ScriptVerifyWitness,
 	}
 
 	// tx with almost empty scripts.
@@ -214,7 +354,7 @@ func TestInvalidFlagCombinations(t *testing.T) {
 	pkScript := []byte{txscript.OP_NOP}
 
 	for i, test := range tests {
-		_, err := txscript.NewEngine(pkScript, tx, 0, test, nil)
+		_, err := txscript.NewEngine(pkScript, tx, 0, test, nil, 0)
 		if err != txscript.ErrInvalidFlags {
 			t.Fatalf("TestInvalidFlagCombinations #%d unexpected "+
 				"error: %v", i, err)
@@ -267,16 +407,24 @@ func TestCheckPubKeyEncoding(t *testing.T) {
 		},
 	}
 
-	flags := txscript.ScriptVerifyStrictEncoding
-	for _, test := range tests {
-		err := txscript.TstCheckPubKeyEncoding(test.key, flags)
-		if err != nil && test.isValid {
-			t.Errorf("checkSignatureEncoding test '%s' failed "+
-				"when it should have succeeded: %v", test.name,
-				err)
-		} else if err == nil && !test.isValid {
-			t.Errorf("checkSignatureEncooding test '%s' succeeded "+
-				"when it should have failed", test.name)
+	// Both the strict encoding and the canonical public key flags must
+	// enforce the public key encoding.
+	allFlags := []txscript.ScriptFlags{
+		txscript.ScriptVerifyStrictEncoding,
+		txscript.ScriptVerifyCanonicalPubKeys,
+	}
+	for _, flags := range allFlags {
+		for _, test := range tests {
+			err := txscript.TstCheckPubKeyEncoding(test.key, flags)
+			if err != nil && test.isValid {
+				t.Errorf("checkSignatureEncoding test '%s' "+
+					"(flags %x) failed when it should have "+
+					"succeeded: %v", test.name, flags, err)
+			} else if err == nil && !test.isValid {
+				t.Errorf("checkSignatureEncooding test '%s' "+
+					"(flags %x) succeeded when it should "+
+					"have failed", test.name, flags)
+			}
 		}
 	}
 
@@ -452,3 +600,172 @@ func TestCheckSignatureEncoding(t *testing.T) {
 		}
 	}
 }
+
+// TestWitnessPrograms ensures spends of witness programs, either directly or
+// nested in pay-to-script-hash outputs, are verified against the witness of
+// the spending input.
+func TestWitnessPrograms(t *testing.T) {
+	t.Parallel()
+
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x01}, 32))
+	pubKey := privKey.PubKey().SerializeCompressed()
+	pubKeyHash := coinutil.Hash160(pubKey)
+	const amount = 50000
+
+	p2wpkh := append([]byte{txscript.OP_0, txscript.OP_DATA_20}, pubKeyHash...)
+	witnessScript := append([]byte{txscript.OP_DATA_33}, pubKey...)
+	witnessScript = append(witnessScript, txscript.OP_CHECKSIG)
+	scriptHash := fastsha256.Sum256(witnessScript)
+	p2wsh := append([]byte{txscript.OP_0, txscript.OP_DATA_32}, scriptHash[:]...)
+	p2sh := append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20},
+		coinutil.Hash160(p2wpkh)...)
+	p2sh = append(p2sh, txscript.OP_EQUAL)
+	v1 := append([]byte{txscript.OP_1, txscript.OP_DATA_20}, pubKeyHash...)
+
+	// newSpend returns a transaction which spends an output with the passed
+	// signature script.
+	newSpend := func(sigScript []byte) *wire.MsgTx {
+		tx := wire.NewMsgTx()
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Index: 0},
+			SignatureScript:  sigScript,
+			Sequence:         wire.MaxTxInSequenceNum,
+		})
+		tx.AddTxOut(wire.NewTxOut(amount-1000, []byte{txscript.OP_TRUE}))
+		return tx
+	}
+
+	// signP2WPKH signs the passed transaction as a spend of the pubkey hash
+	// witness program of the test key for the given amount.
+	signP2WPKH := func(tx *wire.MsgTx, signAmount int64) *wire.MsgTx {
+		witness, err := txscript.WitnessSignature(tx, 0, signAmount,
+			txscript.SigHashAll, privKey, true)
+		if err != nil {
+			t.Fatalf("WitnessSignature: unexpected error: %v", err)
+		}
+		tx.TxIn[0].Witness = witness
+		return tx
+	}
+
+	// signP2WSH signs the passed transaction as a spend of the script hash
+	// witness program, with the witness script appended to the witness.
+	signP2WSH := func(tx *wire.MsgTx, script []byte) *wire.MsgTx {
+		sig, err := txscript.RawTxInWitnessSignature(tx, 0, amount,
+			witnessScript, txscript.SigHashAll, privKey)
+		if err != nil {
+			t.Fatalf("RawTxInWitnessSignature: unexpected error: %v",
+				err)
+		}
+		tx.TxIn[0].Witness = wire.TxWitness{sig, script}
+		return tx
+	}
+
+	nestedSigScript := append([]byte{byte(len(p2wpkh))}, p2wpkh...)
+	witnessFlags := txscript.ScriptBip16 | txscript.ScriptVerifyWitness
+
+	tests := []struct {
+		name     string
+		pkScript []byte
+		tx       *wire.MsgTx
+		flags    txscript.ScriptFlags
+		valid    bool
+		err      error
+	}{
+		{
+			name:     "p2wpkh",
+			pkScript: p2wpkh,
+			tx:       signP2WPKH(newSpend(nil), amount),
+			flags:    txscript.StandardVerifyFlags,
+			valid:    true,
+		},
+		{
+			name:     "p2wpkh signed with wrong amount",
+			pkScript: p2wpkh,
+			tx:       signP2WPKH(newSpend(nil), amount+1),
+			flags:    txscript.StandardVerifyFlags,
+			err:      txscript.ErrStackScriptFailed,
+		},
+		{
+			name:     "p2wpkh with signature script",
+			pkScript: p2wpkh,
+			tx: signP2WPKH(newSpend([]byte{txscript.OP_TRUE}),
+				amount),
+			flags: txscript.StandardVerifyFlags,
+			err:   txscript.ErrWitnessMalleated,
+		},
+		{
+			name:     "p2wsh",
+			pkScript: p2wsh,
+			tx:       signP2WSH(newSpend(nil), witnessScript),
+			flags:    txscript.StandardVerifyFlags,
+			valid:    true,
+		},
+		{
+			name:     "p2wsh with mismatched witness script",
+			pkScript: p2wsh,
+			tx: signP2WSH(newSpend(nil),
+				append(witnessScript, txscript.OP_NOP)),
+			flags: txscript.StandardVerifyFlags,
+			err:   txscript.ErrWitnessProgramMismatch,
+		},
+		{
+			name:     "p2wpkh nested in p2sh",
+			pkScript: p2sh,
+			tx:       signP2WPKH(newSpend(nestedSigScript), amount),
+			flags:    txscript.StandardVerifyFlags,
+			valid:    true,
+		},
+		{
+			name:     "witness for non-witness output",
+			pkScript: []byte{txscript.OP_TRUE},
+			tx:       signP2WPKH(newSpend(nil), amount),
+			flags:    txscript.StandardVerifyFlags,
+			err:      txscript.ErrWitnessUnexpected,
+		},
+		{
+			name:     "unknown witness version",
+			pkScript: v1,
+			tx:       signP2WPKH(newSpend(nil), amount),
+			flags:    witnessFlags,
+			valid:    true,
+		},
+		{
+			name:     "discouraged unknown witness version",
+			pkScript: v1,
+			tx:       signP2WPKH(newSpend(nil), amount),
+			flags:    txscript.StandardVerifyFlags,
+			err:      txscript.ErrDiscourageUpgradableWitnessProgram,
+		},
+	}
+
+	// Each test is run by an engine which calculates the partial signature
+	// hashes of the transaction itself and one which is passed them.
+	for _, test := range tests {
+		sigHashes := txscript.NewTxSigHashes(test.tx)
+		for _, shared := range []bool{false, true} {
+			var vm *txscript.Engine
+			var err error
+			if shared {
+				vm, err = txscript.NewEngineWithSigHashes(
+					test.pkScript, test.tx, 0, test.flags,
+					nil, sigHashes, amount)
+			} else {
+				vm, err = txscript.NewEngine(test.pkScript,
+					test.tx, 0, test.flags, nil, amount)
+			}
+			if err == nil {
+				err = vm.Execute()
+			}
+			if test.valid && err != nil {
+				t.Errorf("%s (shared hashes %v): unexpected "+
+					"error: %v", test.name, shared, err)
+				continue
+			}
+			if !test.valid && err != test.err {
+				t.Errorf("%s (shared hashes %v): unexpected "+
+					"error: got %v, want %v", test.name,
+					shared, err, test.err)
+			}
+		}
+	}
+}
@@ -133,6 +133,42 @@ var (
 	// is set and the script contains push operations that do not use
 	// the minimal opcode required.
 	ErrStackMinimalData = errors.New("non-minimally encoded script number")
+
+	// ErrWitnessProgramEmpty is returned when a pay-to-witness-script-hash
+	// output is spent with an empty witness.
+	ErrWitnessProgramEmpty = errors.New("witness program empty")
+
+	// ErrWitnessProgramMismatch is returned when the witness script of a
+	// pay-to-witness-script-hash output does not match the program, or a
+	// pay-to-witness-pub-key-hash output is spent with a witness that does
+	// not consist of exactly a signature and a public key.
+	ErrWitnessProgramMismatch = errors.New("witness program mismatch")
+
+	// ErrWitnessProgramWrongLength is returned when a version 0 witness
+	// program is neither 20 nor 32 bytes.
+	ErrWitnessProgramWrongLength = errors.New("witness program has " +
+		"wrong length")
+
+	// ErrWitnessMalleated is returned when an output paying directly to a
+	// witness program is spent with a non-empty signature script.
+	ErrWitnessMalleated = errors.New("witness program spent with " +
+		"non-empty signature script")
+
+	// ErrWitnessMalleatedP2SH is returned when a witness program nested in
+	// a pay-to-script-hash output is spent with a signature script which
+	// does anything other than push the program.
+	ErrWitnessMalleatedP2SH = errors.New("nested witness program spent " +
+		"with signature script which is not a single push")
+
+	// ErrWitnessUnexpected is returned when an input which does not spend
+	// a witness program has witness data.
+	ErrWitnessUnexpected = errors.New("unexpected witness data")
+
+	// ErrDiscourageUpgradableWitnessProgram is returned when the
+	// ScriptVerifyDiscourageUpgradeableWitnessProgram flag is set and an
+	// output with an unknown witness program version is spent.
+	ErrDiscourageUpgradableWitnessProgram = errors.New("spend of " +
+		"witness program with unknown version")
 )
 
 var (
@@ -151,4 +187,9 @@ var (
 	// ErrBadNumRequired is returned from MultiSigScript when nrequired is
 	// larger than the number of provided public keys.
 	ErrBadNumRequired = errors.New("more signatures required than keys present")
+
+	// ErrBudgetExceeded is returned when an engine has executed more
+	// opcodes or spent more time executing scripts than allowed by the
+	// budget set with SetBudget.
+	ErrBudgetExceeded = errors.New("script execution budget exceeded")
 )
@@ -1906,12 +1906,23 @@ func opcodeCheckSig(op *parsedOpcode, vm *Engine) error {
 	// Get script starting from the most recent OP_CODESEPARATOR.
 	subScript := vm.subScript()
 
-	// Remove the signature since there is no way for a signature to sign
-	// itself.
-	subScript = removeOpcodeByData(subScript, fullSigBytes)
-
-	// Generate the signature hash based on the signature hash type.
-	hash := calcSignatureHash(subScript, hashType, &vm.tx, vm.txIdx)
+	// Generate the signature hash based on the signature hash type.  The
+	// version 0 witness signature hash commits to the signature script
+	// without modification, while the legacy one removes the signature
+	// since there is no way for a signature to sign itself.
+	var hash []byte
+	if vm.isWitnessVersionActive(0) {
+		var err error
+		hash, err = calcWitnessSignatureHash(subScript,
+			vm.txSigHashes(), hashType, &vm.tx, vm.txIdx,
+			vm.inputAmount)
+		if err != nil {
+			return err
+		}
+	} else {
+		subScript = removeOpcodeByData(subScript, fullSigBytes)
+		hash = calcSignatureHash(subScript, hashType, &vm.tx, vm.txIdx)
+	}
 
 	pubKey, err := btcec.ParsePubKey(pkBytes, btcec.S256())
 	if err != nil {
@@ -2060,9 +2071,12 @@ func opcodeCheckMultiSig(op *parsedOpcode, vm *Engine) error {
 	script := vm.subScript()
 
 	// Remove any of the signatures since there is no way for a signature to
-	// sign itself.
-	for _, sigInfo := range signatures {
-		script = removeOpcodeByData(script, sigInfo.signature)
+	// sign itself.  This does not apply to the version 0 witness signature
+	// hash which commits to the script without modification.
+	if !vm.isWitnessVersionActive(0) {
+		for _, sigInfo := range signatures {
+			script = removeOpcodeByData(script, sigInfo.signature)
+		}
 	}
 
 	success := true
@@ -2144,7 +2158,17 @@ func opcodeCheckMultiSig(op *parsedOpcode, vm *Engine) error {
 		}
 
 		// Generate the signature hash based on the signature hash type.
-		hash := calcSignatureHash(script, hashType, &vm.tx, vm.txIdx)
+		var hash []byte
+		if vm.isWitnessVersionActive(0) {
+			hash, err = calcWitnessSignatureHash(script,
+				vm.txSigHashes(), hashType, &vm.tx, vm.txIdx,
+				vm.inputAmount)
+			if err != nil {
+				return err
+			}
+		} else {
+			hash = calcSignatureHash(script, hashType, &vm.tx, vm.txIdx)
+		}
 
 		var valid bool
 		if vm.sigCache != nil {
@@ -7,9 +7,11 @@ package txscript
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/conseweb/fastsha256"
 	"github.com/conseweb/stcd/wire"
 )
 
@@ -41,6 +43,16 @@ const (
 	MaxScriptElementSize  = 520 // Max bytes pushable to the stack.
 )
 
+const (
+	// payToWitnessPubKeyHashDataSize is the size of the witness program's
+	// data push for a pay-to-witness-pub-key-hash output.
+	payToWitnessPubKeyHashDataSize = 20
+
+	// payToWitnessScriptHashDataSize is the size of the witness program's
+	// data push for a pay-to-witness-script-hash output.
+	payToWitnessScriptHashDataSize = 32
+)
+
 // isSmallInt returns whether or not the opcode is considered a small integer,
 // which is an OP_0, or OP_1 through OP_16.
 func isSmallInt(op *opcode) bool {
@@ -69,6 +81,34 @@ func IsPayToScriptHash(script []byte) bool {
 	return isScriptHash(pops)
 }
 
+// IsWitnessProgram returns true if the passed script is a witness program as
+// defined by BIP0141.  A witness program is a version byte pushed with OP_0
+// or OP_1 through OP_16, followed by a single direct data push of 2 to 40
+// bytes.
+func IsWitnessProgram(script []byte) bool {
+	if len(script) < 4 || len(script) > 42 {
+		return false
+	}
+	if script[0] != OP_0 && (script[0] < OP_1 || script[0] > OP_16) {
+		return false
+	}
+	return int(script[1])+2 == len(script)
+}
+
+// ExtractWitnessProgramInfo returns the version and program of the passed
+// witness program script.  An error is returned when the script is not a
+// witness program.
+func ExtractWitnessProgramInfo(script []byte) (int, []byte, error) {
+	if !IsWitnessProgram(script) {
+		return 0, nil, errors.New("script is not a witness program")
+	}
+	version := 0
+	if script[0] != OP_0 {
+		version = int(script[0] - (OP_1 - 1))
+	}
+	return version, script[2:], nil
+}
+
 // isPushOnly returns true if the script only pushes data, false otherwise.
 func isPushOnly(pops []parsedOpcode) bool {
 	// NOTE: This function does NOT verify opcodes directly since it is
@@ -288,7 +328,8 @@ func calcSignatureHash(script []parsedOpcode, hashType SigHashType, tx *wire.Msg
 	// hash of 1.  This in turn presents an opportunity for attackers to
 	// cleverly construct transactions which can steal those coins provided
 	// they can reuse signatures.
-	if hashType&sigHashMask == SigHashSingle && idx >= len(tx.TxOut) {
+	baseType := hashType & sigHashMask
+	if baseType == SigHashSingle && idx >= len(tx.TxOut) {
 		var hash wire.ShaHash
 		hash[0] = 0x01
 		return hash[:]
@@ -297,67 +338,172 @@ func calcSignatureHash(script []parsedOpcode, hashType SigHashType, tx *wire.Msg
 	// Remove all instances of OP_CODESEPARATOR from the script.
 	script = removeOpcode(script, OP_CODESEPARATOR)
 
-	// Make a deep copy of the transaction, zeroing out the script for all
-	// inputs that are not currently being processed.
-	txCopy := tx.Copy()
-	for i := range txCopy.TxIn {
-		if i == idx {
-			// UnparseScript cannot fail here because removeOpcode
-			// above only returns a valid script.
-			sigScript, _ := unparseScript(script)
-			txCopy.TxIn[idx].SignatureScript = sigScript
-		} else {
-			txCopy.TxIn[i].SignatureScript = nil
+	// UnparseScript cannot fail here because removeOpcode above only
+	// returns a valid script.
+	sigScript, _ := unparseScript(script)
+
+	// The signature hash is the double sha256 of a modified copy of the
+	// transaction followed by the hash type (encoded as a 4-byte
+	// little-endian value).  Rather than making a deep copy of the
+	// transaction and modifying it, the modified copy is serialized
+	// directly, which avoids copying every script of the transaction for
+	// each of its inputs.  Witness data is never part of the hash.
+	//
+	// In the modified copy, the script of the input being signed is
+	// replaced by the passed script and the scripts of all other inputs
+	// are empty.
+	var b bytes.Buffer
+	var buf [8]byte
+	b.Grow(tx.SerializeSizeStripped() + len(sigScript) + 4)
+	binary.LittleEndian.PutUint32(buf[:4], uint32(tx.Version))
+	b.Write(buf[:4])
+	writeTxIn := func(txIn *wire.TxIn, sigScript []byte, sequence uint32) {
+		b.Write(txIn.PreviousOutPoint.Hash[:])
+		binary.LittleEndian.PutUint32(buf[:4], txIn.PreviousOutPoint.Index)
+		b.Write(buf[:4])
+		wire.WriteVarBytes(&b, 0, sigScript)
+		binary.LittleEndian.PutUint32(buf[:4], sequence)
+		b.Write(buf[:4])
+	}
+	if hashType&SigHashAnyOneCanPay != 0 {
+		// Only the input being signed is part of the copy.
+		wire.WriteVarInt(&b, 0, 1)
+		writeTxIn(tx.TxIn[idx], sigScript, tx.TxIn[idx].Sequence)
+	} else {
+		// The sequence numbers of the other inputs are zeroed out when
+		// the outputs they are bound to aren't signed.
+		wire.WriteVarInt(&b, 0, uint64(len(tx.TxIn)))
+		for i, txIn := range tx.TxIn {
+			switch {
+			case i == idx:
+				writeTxIn(txIn, sigScript, txIn.Sequence)
+			case baseType == SigHashNone || baseType == SigHashSingle:
+				writeTxIn(txIn, nil, 0)
+			default:
+				writeTxIn(txIn, nil, txIn.Sequence)
+			}
 		}
 	}
 
-	switch hashType & sigHashMask {
+	switch baseType {
 	case SigHashNone:
-		txCopy.TxOut = txCopy.TxOut[0:0] // Empty slice.
-		for i := range txCopy.TxIn {
-			if i != idx {
-				txCopy.TxIn[i].Sequence = 0
-			}
-		}
+		// None of the outputs are signed.
+		wire.WriteVarInt(&b, 0, 0)
 
 	case SigHashSingle:
-		// Resize output array to up to and including requested index.
-		txCopy.TxOut = txCopy.TxOut[:idx+1]
-
-		// All but current output get zeroed out.
+		// The outputs up to and including the one with the same index
+		// as the input are part of the copy, but all except the last
+		// of them are zeroed out.
+		wire.WriteVarInt(&b, 0, uint64(idx+1))
 		for i := 0; i < idx; i++ {
-			txCopy.TxOut[i].Value = -1
-			txCopy.TxOut[i].PkScript = nil
-		}
-
-		// Sequence on all other inputs is 0, too.
-		for i := range txCopy.TxIn {
-			if i != idx {
-				txCopy.TxIn[i].Sequence = 0
-			}
+			writeSigHashTxOut(&b, &wire.TxOut{Value: -1})
 		}
+		writeSigHashTxOut(&b, tx.TxOut[idx])
 
 	default:
 		// Consensus treats undefined hashtypes like normal SigHashAll
 		// for purposes of hash generation.
-		fallthrough
-	case SigHashOld:
-		fallthrough
-	case SigHashAll:
-		// Nothing special here.
+		wire.WriteVarInt(&b, 0, uint64(len(tx.TxOut)))
+		for _, txOut := range tx.TxOut {
+			writeSigHashTxOut(&b, txOut)
+		}
 	}
-	if hashType&SigHashAnyOneCanPay != 0 {
-		txCopy.TxIn = txCopy.TxIn[idx : idx+1]
-		idx = 0
+
+	binary.LittleEndian.PutUint32(buf[:4], tx.LockTime)
+	b.Write(buf[:4])
+	binary.LittleEndian.PutUint32(buf[:4], uint32(hashType))
+	b.Write(buf[:4])
+	return wire.DoubleSha256(b.Bytes())
+}
+
+// calcWitnessSignatureHash calculates the signature hash used by inputs which
+// spend version 0 witness programs as defined by BIP0143.  Unlike the legacy
+// signature hash, it commits to the amount of the output being spent and
+// its cost does not grow quadratically with the size of the transaction as
+// long as the partial hashes of the transaction are only calculated once.
+// They are calculated when the passed partial hashes are nil.
+func calcWitnessSignatureHash(script []parsedOpcode, sigHashes *TxSigHashes, hashType SigHashType, tx *wire.MsgTx, idx int, amount int64) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, ErrInvalidIndex
+	}
+	if sigHashes == nil {
+		sigHashes = NewTxSigHashes(tx)
+	}
+
+	var buf [8]byte
+	var zeroHash wire.ShaHash
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+	baseType := hashType & sigHashMask
+
+	// The hash of all of the previous outputs being spent, unless only
+	// the current input is signed.
+	hashPrevOuts := zeroHash[:]
+	if !anyoneCanPay {
+		hashPrevOuts = sigHashes.HashPrevOuts[:]
+	}
+
+	// The hash of all of the input sequence numbers, unless only the
+	// current input is signed or the other inputs are free to change their
+	// sequence numbers.
+	hashSequence := zeroHash[:]
+	if !anyoneCanPay && baseType != SigHashSingle && baseType != SigHashNone {
+		hashSequence = sigHashes.HashSequence[:]
+	}
+
+	// The hash of all of the outputs, just the output with the same index
+	// as the input, or none of them depending on the hash type.
+	hashOutputs := zeroHash[:]
+	if baseType != SigHashSingle && baseType != SigHashNone {
+		hashOutputs = sigHashes.HashOutputs[:]
+	} else if baseType == SigHashSingle && idx < len(tx.TxOut) {
+		var b bytes.Buffer
+		writeSigHashTxOut(&b, tx.TxOut[idx])
+		hashOutputs = wire.DoubleSha256(b.Bytes())
+	}
+
+	// The script code is the script from the most recent
+	// OP_CODESEPARATOR.  Unlike the legacy signature hash, any other
+	// OP_CODESEPARATORs are not removed.
+	scriptCode, err := unparseScript(script)
+	if err != nil {
+		return nil, err
 	}
 
-	// The final hash is the double sha256 of both the serialized modified
-	// transaction and the hash type (encoded as a 4-byte little-endian
-	// value) appended.
-	var wbuf bytes.Buffer
-	txCopy.Serialize(&wbuf)
-	binary.Write(&wbuf, binary.LittleEndian, hashType)
-	return wire.DoubleSha256(wbuf.Bytes())
+	var sigHash bytes.Buffer
+	txIn := tx.TxIn[idx]
+	binary.LittleEndian.PutUint32(buf[:4], uint32(tx.Version))
+	sigHash.Write(buf[:4])
+	sigHash.Write(hashPrevOuts)
+	sigHash.Write(hashSequence)
+	sigHash.Write(txIn.PreviousOutPoint.Hash[:])
+	binary.LittleEndian.PutUint32(buf[:4], txIn.PreviousOutPoint.Index)
+	sigHash.Write(buf[:4])
+	wire.WriteVarBytes(&sigHash, 0, scriptCode)
+	binary.LittleEndian.PutUint64(buf[:], uint64(amount))
+	sigHash.Write(buf[:])
+	binary.LittleEndian.PutUint32(buf[:4], txIn.Sequence)
+	sigHash.Write(buf[:4])
+	sigHash.Write(hashOutputs)
+	binary.LittleEndian.PutUint32(buf[:4], tx.LockTime)
+	sigHash.Write(buf[:4])
+	binary.LittleEndian.PutUint32(buf[:4], uint32(hashType))
+	sigHash.Write(buf[:4])
+
+	return wire.DoubleSha256(sigHash.Bytes()), nil
+}
+
+// CalcWitnessSigHash returns the signature hash defined by BIP0143 for the
+// input idx of the given transaction which spends an output of the passed
+// amount.  The script is the witness script for pay-to-witness-script-hash
+// inputs, or the pay-to-pubkey-hash script for the hashed public key of
+// pay-to-witness-pub-key-hash inputs.
+func CalcWitnessSigHash(script []byte, hashType SigHashType, tx *wire.MsgTx, idx int, amount int64) ([]byte, error) {
+	parsedScript, err := parseScript(script)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse output script: %v", err)
+	}
+	return calcWitnessSignatureHash(parsedScript, nil, hashType, tx,
+		idx, amount)
 }
 
 // asSmallInt returns the passed opcode, which must be true according to
@@ -460,6 +606,64 @@ func GetPreciseSigOpCount(scriptSig, scriptPubKey []byte, bip16 bool) int {
 	return getSigOpCount(shPops, true)
 }
 
+// GetWitnessSigOpCount returns the number of signature operations executed by
+// the witness of an input which spends the passed public key script, either
+// directly or nested in a pay-to-script-hash script.  The witness signature
+// operations are not scaled, unlike the legacy ones which are counted in
+// full against the block limit.
+func GetWitnessSigOpCount(sigScript, pkScript []byte, witness wire.TxWitness) int {
+	// Spends of witness programs directly from the public key script.
+	if IsWitnessProgram(pkScript) {
+		return getWitnessSigOps(pkScript, witness)
+	}
+
+	// Spends of witness programs nested in a pay-to-script-hash script.
+	// The witness program is the only item pushed by the signature script.
+	if IsPayToScriptHash(pkScript) && IsPushOnlyScript(sigScript) {
+		pops, _ := parseScript(sigScript)
+		if len(pops) > 0 {
+			redeemScript := pops[len(pops)-1].data
+			if IsWitnessProgram(redeemScript) {
+				return getWitnessSigOps(redeemScript, witness)
+			}
+		}
+	}
+
+	return 0
+}
+
+// getWitnessSigOps returns the number of signature operations executed by the
+// passed witness for the given witness program.  Only version 0 programs
+// have any defined signature operations.
+func getWitnessSigOps(witnessProgram []byte, witness wire.TxWitness) int {
+	version, program, err := ExtractWitnessProgramInfo(witnessProgram)
+	if err != nil || version != 0 {
+		return 0
+	}
+
+	switch len(program) {
+	case payToWitnessPubKeyHashDataSize:
+		return 1
+
+	case payToWitnessScriptHashDataSize:
+		if len(witness) == 0 {
+			return 0
+		}
+		witnessScript := witness[len(witness)-1]
+		pops, _ := parseScript(witnessScript)
+		return getSigOpCount(pops, true)
+	}
+
+	return 0
+}
+
+// witnessScriptHash returns the program of a pay-to-witness-script-hash output
+// for the passed witness script.
+func witnessScriptHash(witnessScript []byte) []byte {
+	hash := fastsha256.Sum256(witnessScript)
+	return hash[:]
+}
+
 // IsUnspendable returns whether the passed public key script is unspendable, or
 // guaranteed to fail at execution.  This allows inputs to be pruned instantly
 // when entering the UTXO set.
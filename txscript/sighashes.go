@@ -0,0 +1,81 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// TxSigHashes houses the partial hashes of a transaction which the version 0
+// witness signature hash defined by BIP0143 of each of its inputs commits to.
+// Calculating them once per transaction rather than once per signature keeps
+// the cost of verifying the inputs of a transaction linear in its size.
+//
+// The hashes only depend on the transaction, so they may be shared by the
+// script engines verifying its inputs concurrently.
+type TxSigHashes struct {
+	HashPrevOuts wire.ShaHash
+	HashSequence wire.ShaHash
+	HashOutputs  wire.ShaHash
+}
+
+// NewTxSigHashes returns the partial signature hashes of the passed
+// transaction.
+func NewTxSigHashes(tx *wire.MsgTx) *TxSigHashes {
+	return &TxSigHashes{
+		HashPrevOuts: calcHashPrevOuts(tx),
+		HashSequence: calcHashSequence(tx),
+		HashOutputs:  calcHashOutputs(tx),
+	}
+}
+
+// calcHashPrevOuts returns the double SHA-256 of the previous outpoints of all
+// of the inputs of the passed transaction.
+func calcHashPrevOuts(tx *wire.MsgTx) wire.ShaHash {
+	var b bytes.Buffer
+	var buf [4]byte
+	b.Grow(len(tx.TxIn) * (wire.HashSize + 4))
+	for _, txIn := range tx.TxIn {
+		b.Write(txIn.PreviousOutPoint.Hash[:])
+		binary.LittleEndian.PutUint32(buf[:], txIn.PreviousOutPoint.Index)
+		b.Write(buf[:])
+	}
+	return wire.DoubleSha256SH(b.Bytes())
+}
+
+// calcHashSequence returns the double SHA-256 of the sequence numbers of all of
+// the inputs of the passed transaction.
+func calcHashSequence(tx *wire.MsgTx) wire.ShaHash {
+	var b bytes.Buffer
+	var buf [4]byte
+	b.Grow(len(tx.TxIn) * 4)
+	for _, txIn := range tx.TxIn {
+		binary.LittleEndian.PutUint32(buf[:], txIn.Sequence)
+		b.Write(buf[:])
+	}
+	return wire.DoubleSha256SH(b.Bytes())
+}
+
+// calcHashOutputs returns the double SHA-256 of the serialized outputs of the
+// passed transaction.
+func calcHashOutputs(tx *wire.MsgTx) wire.ShaHash {
+	var b bytes.Buffer
+	for _, txOut := range tx.TxOut {
+		writeSigHashTxOut(&b, txOut)
+	}
+	return wire.DoubleSha256SH(b.Bytes())
+}
+
+// writeSigHashTxOut writes the passed output to the passed buffer the way
+// signature hashes serialize it.
+func writeSigHashTxOut(b *bytes.Buffer, txOut *wire.TxOut) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(txOut.Value))
+	b.Write(buf[:])
+	wire.WriteVarBytes(b, 0, txOut.PkScript)
+}
@@ -32,6 +32,53 @@ func RawTxInSignature(tx *wire.MsgTx, idx int, subScript []byte,
 	return append(signature.Serialize(), byte(hashType)), nil
 }
 
+// RawTxInWitnessSignature returns the serialized ECDSA signature for the input
+// idx of the given transaction, which spends a witness program output of the
+// passed amount, with hashType appended to it.  The subScript is the witness
+// script for pay-to-witness-script-hash outputs, or the pay-to-pubkey-hash
+// script of the public key for pay-to-witness-pubkey-hash outputs.
+func RawTxInWitnessSignature(tx *wire.MsgTx, idx int, amount int64,
+	subScript []byte, hashType SigHashType, key *btcec.PrivateKey) ([]byte, error) {
+
+	hash, err := CalcWitnessSigHash(subScript, hashType, tx, idx, amount)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := key.Sign(hash)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign tx input: %s", err)
+	}
+
+	return append(signature.Serialize(), byte(hashType)), nil
+}
+
+// WitnessSignature creates the witness for tx to spend an output of the passed
+// amount which pays to the version 0 witness pubkey hash of privKey.  The
+// witness consists of the signature followed by the serialized public key.
+func WitnessSignature(tx *wire.MsgTx, idx int, amount int64, hashType SigHashType,
+	privKey *btcec.PrivateKey, compress bool) (wire.TxWitness, error) {
+
+	pk := (*btcec.PublicKey)(&privKey.PublicKey)
+	var pkData []byte
+	if compress {
+		pkData = pk.SerializeCompressed()
+	} else {
+		pkData = pk.SerializeUncompressed()
+	}
+
+	subScript, err := payToPubKeyHashScript(coinutil.Hash160(pkData))
+	if err != nil {
+		return nil, err
+	}
+	sig, err := RawTxInWitnessSignature(tx, idx, amount, subScript,
+		hashType, privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return wire.TxWitness{sig, pkData}, nil
+}
+
 // SignatureScript creates an input signature script for tx to spend BTC sent
 // from a previous output to the owner of privKey. tx must include all
 // transaction inputs and outputs, however txin scripts are allowed to be filled
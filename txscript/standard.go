@@ -6,6 +6,7 @@ package txscript
 
 import (
 	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/bech32"
 	"github.com/conseweb/stcd/chaincfg"
 )
 
@@ -27,12 +28,16 @@ const (
 	StandardVerifyFlags = ScriptBip16 |
 		ScriptVerifyDERSignatures |
 		ScriptVerifyStrictEncoding |
+		ScriptVerifySigPushOnly |
 		ScriptVerifyMinimalData |
 		ScriptStrictMultiSig |
 		ScriptDiscourageUpgradableNops |
 		ScriptVerifyCleanStack |
 		ScriptVerifyCheckLockTimeVerify |
-		ScriptVerifyLowS
+		ScriptVerifyLowS |
+		ScriptVerifyCanonicalPubKeys |
+		ScriptVerifyWitness |
+		ScriptVerifyDiscourageUpgradeableWitnessProgram
 )
 
 // ScriptClass is an enumeration for the list of standard types of script.
@@ -40,12 +45,14 @@ type ScriptClass byte
 
 // Classes of script payment known about in the blockchain.
 const (
-	NonStandardTy ScriptClass = iota // None of the recognized forms.
-	PubKeyTy                         // Pay pubkey.
-	PubKeyHashTy                     // Pay pubkey hash.
-	ScriptHashTy                     // Pay to script hash.
-	MultiSigTy                       // Multi signature.
-	NullDataTy                       // Empty data-only (provably prunable).
+	NonStandardTy         ScriptClass = iota // None of the recognized forms.
+	PubKeyTy                                 // Pay pubkey.
+	PubKeyHashTy                             // Pay pubkey hash.
+	ScriptHashTy                             // Pay to script hash.
+	MultiSigTy                               // Multi signature.
+	NullDataTy                               // Empty data-only (provably prunable).
+	WitnessV0PubKeyHashTy                    // Pay witness pubkey hash.
+	WitnessV0ScriptHashTy                    // Pay witness script hash.
 )
 
 // scriptClassToName houses the human-readable strings which describe each
@@ -57,6 +64,9 @@ var scriptClassToName = []string{
 	ScriptHashTy:  "scripthash",
 	MultiSigTy:    "multisig",
 	NullDataTy:    "nulldata",
+
+	WitnessV0PubKeyHashTy: "witness_v0_keyhash",
+	WitnessV0ScriptHashTy: "witness_v0_scripthash",
 }
 
 // String implements the Stringer interface by returning the name of
@@ -90,6 +100,22 @@ func isPubkeyHash(pops []parsedOpcode) bool {
 
 }
 
+// isWitnessPubKeyHash returns true if the passed script is a version 0
+// pay-to-witness-pubkey-hash script, false otherwise.
+func isWitnessPubKeyHash(pops []parsedOpcode) bool {
+	return len(pops) == 2 &&
+		pops[0].opcode.value == OP_0 &&
+		pops[1].opcode.value == OP_DATA_20
+}
+
+// isWitnessScriptHash returns true if the passed script is a version 0
+// pay-to-witness-script-hash script, false otherwise.
+func isWitnessScriptHash(pops []parsedOpcode) bool {
+	return len(pops) == 2 &&
+		pops[0].opcode.value == OP_0 &&
+		pops[1].opcode.value == OP_DATA_32
+}
+
 // isMultiSig returns true if the passed script is a multisig transaction, false
 // otherwise.
 func isMultiSig(pops []parsedOpcode) bool {
@@ -150,6 +176,10 @@ func typeOfScript(pops []parsedOpcode) ScriptClass {
 		return PubKeyHashTy
 	} else if isScriptHash(pops) {
 		return ScriptHashTy
+	} else if isWitnessPubKeyHash(pops) {
+		return WitnessV0PubKeyHashTy
+	} else if isWitnessScriptHash(pops) {
+		return WitnessV0ScriptHashTy
 	} else if isMultiSig(pops) {
 		return MultiSigTy
 	} else if isNullData(pops) {
@@ -169,6 +199,25 @@ func GetScriptClass(script []byte) ScriptClass {
 	return typeOfScript(pops)
 }
 
+// NullDataPayload returns the data pushed by the passed script along with
+// whether or not it has the form of a nulldata script, which is a single
+// OP_RETURN optionally followed by a single data push.  Unlike GetScriptClass,
+// the size of the pushed data is not limited to MaxDataCarrierSize, so callers
+// are able to enforce their own limit.  The data is nil for a lone OP_RETURN.
+func NullDataPayload(script []byte) ([]byte, bool) {
+	pops, err := parseScript(script)
+	if err != nil || len(pops) == 0 || pops[0].opcode.value != OP_RETURN {
+		return nil, false
+	}
+	switch {
+	case len(pops) == 1:
+		return nil, true
+	case len(pops) == 2 && pops[1].opcode.value <= OP_PUSHDATA4:
+		return pops[1].data, true
+	}
+	return nil, false
+}
+
 // expectedInputs returns the number of arguments required by a script.
 // If the script is of unknown type such that the number can not be determined
 // then -1 is returned. We are an internal function and thus assume that class
@@ -186,6 +235,11 @@ func expectedInputs(pops []parsedOpcode, class ScriptClass) int {
 		// Not including script.  That is handled by the caller.
 		return 1
 
+	case WitnessV0PubKeyHashTy, WitnessV0ScriptHashTy:
+		// Witness programs are spent by the witness, so the signature
+		// script does not push anything.
+		return 0
+
 	case MultiSigTy:
 		// Standard multisig has a push a small number for the number
 		// of sigs and number of keys.  Check the first push instruction
@@ -323,6 +377,13 @@ func payToPubKeyScript(serializedPubKey []byte) ([]byte, error) {
 		AddOp(OP_CHECKSIG).Script()
 }
 
+// payToWitnessProgramScript creates a new script to pay a transaction output
+// to a version 0 witness program.  It is expected that the input is a valid
+// 20-byte pubkey hash or 32-byte script hash.
+func payToWitnessProgramScript(witnessProgram []byte) ([]byte, error) {
+	return NewScriptBuilder().AddOp(OP_0).AddData(witnessProgram).Script()
+}
+
 // PayToAddrScript creates a new script to pay a transaction output to a the
 // specified address.
 func PayToAddrScript(addr coinutil.Address) ([]byte, error) {
@@ -344,6 +405,18 @@ func PayToAddrScript(addr coinutil.Address) ([]byte, error) {
 			return nil, ErrUnsupportedAddress
 		}
 		return payToPubKeyScript(addr.ScriptAddress())
+
+	case *bech32.AddressWitnessPubKeyHash:
+		if addr == nil {
+			return nil, ErrUnsupportedAddress
+		}
+		return payToWitnessProgramScript(addr.ScriptAddress())
+
+	case *bech32.AddressWitnessScriptHash:
+		if addr == nil {
+			return nil, ErrUnsupportedAddress
+		}
+		return payToWitnessProgramScript(addr.ScriptAddress())
 	}
 
 	return nil, ErrUnsupportedAddress
@@ -458,6 +531,30 @@ func ExtractPkScriptAddrs(pkScript []byte, chainParams *chaincfg.Params) (Script
 			}
 		}
 
+	case WitnessV0PubKeyHashTy:
+		// A pay-to-witness-pubkey-hash script is of the form:
+		//  OP_0 <20-byte hash>
+		// Therefore the pubkey hash is the 2nd item on the stack.
+		// Skip the pubkey hash if it's invalid for some reason.
+		requiredSigs = 1
+		addr, err := bech32.NewAddressWitnessPubKeyHash(pops[1].data,
+			chainParams)
+		if err == nil {
+			addrs = append(addrs, addr)
+		}
+
+	case WitnessV0ScriptHashTy:
+		// A pay-to-witness-script-hash script is of the form:
+		//  OP_0 <32-byte hash>
+		// Therefore the script hash is the 2nd item on the stack.
+		// Skip the script hash if it's invalid for some reason.
+		requiredSigs = 1
+		addr, err := bech32.NewAddressWitnessScriptHash(pops[1].data,
+			chainParams)
+		if err == nil {
+			addrs = append(addrs, addr)
+		}
+
 	case NullDataTy:
 		// Null data transactions have no addresses or required
 		// signatures.
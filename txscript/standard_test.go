@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"encoding/hex"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/bech32"
 	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/txscript"
 )
@@ -83,6 +85,34 @@ func newAddressScriptHash(scriptHash []byte) coinutil.Address {
 	return addr
 }
 
+// newAddressWitnessPubKeyHash returns a new bech32.AddressWitnessPubKeyHash
+// from the provided witness program.  It panics if an error occurs.  This is
+// only used in the tests as a helper since the only way it can fail is if
+// there is an error in the test source code.
+func newAddressWitnessPubKeyHash(program []byte) coinutil.Address {
+	addr, err := bech32.NewAddressWitnessPubKeyHash(program,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		panic("invalid witness pubkey hash in test source")
+	}
+
+	return addr
+}
+
+// newAddressWitnessScriptHash returns a new bech32.AddressWitnessScriptHash
+// from the provided witness program.  It panics if an error occurs.  This is
+// only used in the tests as a helper since the only way it can fail is if
+// there is an error in the test source code.
+func newAddressWitnessScriptHash(program []byte) coinutil.Address {
+	addr, err := bech32.NewAddressWitnessScriptHash(program,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		panic("invalid witness script hash in test source")
+	}
+
+	return addr
+}
+
 // TestExtractPkScriptAddrs ensures that extracting the type, addresses, and
 // number of required signatures from PkScripts works as intended.
 func TestExtractPkScriptAddrs(t *testing.T) {
@@ -339,6 +369,29 @@ func TestExtractPkScriptAddrs(t *testing.T) {
 			reqSigs: 1,
 			class:   txscript.MultiSigTy,
 		},
+		{
+			name: "witness v0 pubkeyhash",
+			script: decodeHex("0014751e76e8199196d454941c45d1b3a3" +
+				"23f1433bd6"),
+			addrs: []coinutil.Address{
+				newAddressWitnessPubKeyHash(decodeHex("751e76" +
+					"e8199196d454941c45d1b3a323f1433bd6")),
+			},
+			reqSigs: 1,
+			class:   txscript.WitnessV0PubKeyHashTy,
+		},
+		{
+			name: "witness v0 scripthash",
+			script: decodeHex("00201863143c14c5166804bd19203356da" +
+				"136c985678cd4d27a1b8c6329604903262"),
+			addrs: []coinutil.Address{
+				newAddressWitnessScriptHash(decodeHex("1863143" +
+					"c14c5166804bd19203356da136c985678cd4d27a1" +
+					"b8c6329604903262")),
+			},
+			reqSigs: 1,
+			class:   txscript.WitnessV0ScriptHashTy,
+		},
 		{
 			name:    "empty script",
 			script:  []byte{},
@@ -620,10 +673,29 @@ func TestPayToAddrScript(t *testing.T) {
 			nil,
 		},
 
+		// pay-to-witness-pubkey-hash address on mainnet
+		{
+			newAddressWitnessPubKeyHash(decodeHex("751e76e8199196" +
+				"d454941c45d1b3a323f1433bd6")),
+			"0 DATA_20 0x751e76e8199196d454941c45d1b3a323f1433bd6",
+			nil,
+		},
+		// pay-to-witness-script-hash address on mainnet
+		{
+			newAddressWitnessScriptHash(decodeHex("1863143c14c516" +
+				"6804bd19203356da136c985678cd4d27a1b8c63296049" +
+				"03262")),
+			"0 DATA_32 0x1863143c14c5166804bd19203356da136c985678" +
+				"cd4d27a1b8c6329604903262",
+			nil,
+		},
+
 		// Supported address types with nil pointers.
 		{(*coinutil.AddressPubKeyHash)(nil), "", txscript.ErrUnsupportedAddress},
 		{(*coinutil.AddressScriptHash)(nil), "", txscript.ErrUnsupportedAddress},
 		{(*coinutil.AddressPubKey)(nil), "", txscript.ErrUnsupportedAddress},
+		{(*bech32.AddressWitnessPubKeyHash)(nil), "", txscript.ErrUnsupportedAddress},
+		{(*bech32.AddressWitnessScriptHash)(nil), "", txscript.ErrUnsupportedAddress},
 
 		// Unsupported address type.
 		{&bogusAddress{}, "", txscript.ErrUnsupportedAddress},
@@ -847,6 +919,23 @@ var scriptClassTests = []scriptClassTest{
 			"9ae88 EQUAL",
 		class: txscript.ScriptHashTy,
 	},
+	{
+		name:   "witness v0 pubkeyhash",
+		script: "0 DATA_20 0x1d0f172a0ecb48aee1be1f2687d2963ae33f71a1",
+		class:  txscript.WitnessV0PubKeyHashTy,
+	},
+	{
+		name: "witness v0 scripthash",
+		script: "0 DATA_32 0x9f7fd096d37ed2c0e3f7f0cfc924beef4ffceb68" +
+			"0ad6b4c4e48a2e5b1c66e5a0",
+		class: txscript.WitnessV0ScriptHashTy,
+	},
+	{
+		// Unknown witness program versions are not standard.
+		name:   "witness v1",
+		script: "1 DATA_20 0x1d0f172a0ecb48aee1be1f2687d2963ae33f71a1",
+		class:  txscript.NonStandardTy,
+	},
 	{
 		// Nulldata with no data at all.
 		name:   "nulldata",
@@ -958,6 +1047,43 @@ func TestScriptClass(t *testing.T) {
 	}
 }
 
+// TestNullDataPayload ensures the data pushed by nulldata scripts is returned
+// regardless of its size and that other scripts are not treated as nulldata.
+func TestNullDataPayload(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+		data   string
+		isNull bool
+	}{
+		{"no data", "RETURN", "", true},
+		{"small data", "RETURN DATA_8 0x046708afdb0fe554",
+			"046708afdb0fe554", true},
+		{"more than max data carrier size", "RETURN PUSHDATA1 0x51 0x" +
+			strings.Repeat("ab", 81), strings.Repeat("ab", 81), true},
+		{"small int push", "RETURN 4", "", false},
+		{"additional opcode", "RETURN 4 TRUE", "", false},
+		{"no return", "DATA_2 0x0102", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, test := range tests {
+		script := mustParseShortForm(test.script)
+		data, isNull := txscript.NullDataPayload(script)
+		if isNull != test.isNull {
+			t.Errorf("%s: unexpected nulldata result - got %v, "+
+				"want %v", test.name, isNull, test.isNull)
+			continue
+		}
+		if hex.EncodeToString(data) != test.data {
+			t.Errorf("%s: unexpected data - got %x, want %s",
+				test.name, data, test.data)
+		}
+	}
+}
+
 // TestStringifyClass ensures the script class string returns the expected
 // string for each script class.
 func TestStringifyClass(t *testing.T) {
@@ -998,6 +1124,16 @@ func TestStringifyClass(t *testing.T) {
 			class:    txscript.NullDataTy,
 			stringed: "nulldata",
 		},
+		{
+			name:     "witnessv0pubkeyhashty",
+			class:    txscript.WitnessV0PubKeyHashTy,
+			stringed: "witness_v0_keyhash",
+		},
+		{
+			name:     "witnessv0scripthashty",
+			class:    txscript.WitnessV0ScriptHashTy,
+			stringed: "witness_v0_scripthash",
+		},
 		{
 			name:     "broken",
 			class:    txscript.ScriptClass(255),
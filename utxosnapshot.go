@@ -0,0 +1,296 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/conseweb/fastsha256"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// A UTXO snapshot is laid out as follows:
+//
+//   magic "utxo" || version (uint32) || network (uint32) ||
+//   base block hash || base block height (uint32) || number of coins (uint64) ||
+//   coins... || set hash
+//
+// Each coin is serialized as:
+//
+//   tx hash || output index (uint32) || height<<1 | coinbase (uint32) ||
+//   amount (int64) || script length (uint32) || script
+//
+// All integers are little endian.  Coins are sorted by outpoint so a given
+// chain state always produces the same file.  The set hash is the double
+// sha256 of the serialized coins and commits to the entire set.
+const (
+	// utxoSnapshotVersion is the current version of the snapshot format.
+	utxoSnapshotVersion = 1
+
+	// maxSnapshotScriptSize is the largest output script accepted while
+	// reading a snapshot.  It matches the maximum script size allowed by
+	// the script engine.
+	maxSnapshotScriptSize = 10000
+)
+
+// utxoSnapshotMagic identifies a UTXO snapshot file.
+var utxoSnapshotMagic = [4]byte{'u', 't', 'x', 'o'}
+
+var (
+	// errUtxoSnapshotChainChanged is returned when the main chain is
+	// reorganized while a UTXO set is being built.
+	errUtxoSnapshotChainChanged = errors.New("main chain changed while " +
+		"building the UTXO set")
+
+	// errUtxoSnapshotHashMismatch is returned when the set hash stored in
+	// a snapshot does not match its contents.
+	errUtxoSnapshotHashMismatch = errors.New("UTXO snapshot hash does " +
+		"not match its contents")
+)
+
+// utxoSnapshotCoin is a single unspent transaction output in a snapshot.
+type utxoSnapshotCoin struct {
+	outPoint wire.OutPoint
+	height   int32
+	coinbase bool
+	amount   int64
+	pkScript []byte
+}
+
+// utxoSnapshotHeader describes the chain state a snapshot was taken at.
+type utxoSnapshotHeader struct {
+	net        wire.StonecoinNet
+	baseHash   wire.ShaHash
+	baseHeight int32
+	numCoins   uint64
+}
+
+// utxoSnapshotCoins attaches the methods of sort.Interface to a slice of
+// coins to sort them by outpoint.
+type utxoSnapshotCoins []*utxoSnapshotCoin
+
+func (s utxoSnapshotCoins) Len() int      { return len(s) }
+func (s utxoSnapshotCoins) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s utxoSnapshotCoins) Less(i, j int) bool {
+	cmp := bytes.Compare(s[i].outPoint.Hash[:], s[j].outPoint.Hash[:])
+	if cmp != 0 {
+		return cmp < 0
+	}
+	return s[i].outPoint.Index < s[j].outPoint.Index
+}
+
+// buildUtxoSet returns the unspent transaction outputs of the main chain as
+// of the block at the passed height, sorted by outpoint, along with the hash
+// of that block.  The block database does not keep a standalone UTXO set, so
+// the set is built by applying every block from the genesis block onwards.
+// Provably unspendable outputs are not included.
+func buildUtxoSet(db database.Db, stopHeight int32) ([]*utxoSnapshotCoin, *wire.ShaHash, error) {
+	utxos := make(map[wire.OutPoint]*utxoSnapshotCoin)
+	var prevHash wire.ShaHash
+	for start := int32(0); start <= stopHeight; {
+		hashList, err := db.FetchHeightRange(start, stopHeight+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(hashList) == 0 {
+			return nil, nil, errUtxoSnapshotChainChanged
+		}
+
+		for i := range hashList {
+			height := start + int32(i)
+			block, err := db.FetchBlockBySha(&hashList[i])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// Make sure the block builds on the previous one since
+			// the chain may have been reorganized in the meantime.
+			if height > 0 &&
+				block.MsgBlock().Header.PrevBlock != prevHash {
+				return nil, nil, errUtxoSnapshotChainChanged
+			}
+			prevHash = hashList[i]
+
+			for _, tx := range block.Transactions() {
+				isCoinBase := blockchain.IsCoinBase(tx)
+				if !isCoinBase {
+					for _, txIn := range tx.MsgTx().TxIn {
+						delete(utxos, txIn.PreviousOutPoint)
+					}
+				}
+
+				for txOutIdx, txOut := range tx.MsgTx().TxOut {
+					if len(txOut.PkScript) > 0 &&
+						txOut.PkScript[0] == txscript.OP_RETURN {
+						continue
+					}
+					outPoint := wire.NewOutPoint(tx.Sha(),
+						uint32(txOutIdx))
+					utxos[*outPoint] = &utxoSnapshotCoin{
+						outPoint: *outPoint,
+						height:   height,
+						coinbase: isCoinBase,
+						amount:   txOut.Value,
+						pkScript: txOut.PkScript,
+					}
+				}
+			}
+		}
+		start += int32(len(hashList))
+	}
+
+	coins := make(utxoSnapshotCoins, 0, len(utxos))
+	for _, coin := range utxos {
+		coins = append(coins, coin)
+	}
+	sort.Sort(coins)
+
+	return coins, &prevHash, nil
+}
+
+// writeUtxoSnapshot serializes a snapshot with the passed header and coins to
+// w and returns the set hash.  The coins must already be sorted by outpoint.
+func writeUtxoSnapshot(w io.Writer, header *utxoSnapshotHeader, coins []*utxoSnapshotCoin) (wire.ShaHash, error) {
+	var buf bytes.Buffer
+	buf.Write(utxoSnapshotMagic[:])
+	binary.Write(&buf, binary.LittleEndian, uint32(utxoSnapshotVersion))
+	binary.Write(&buf, binary.LittleEndian, uint32(header.net))
+	buf.Write(header.baseHash[:])
+	binary.Write(&buf, binary.LittleEndian, uint32(header.baseHeight))
+	binary.Write(&buf, binary.LittleEndian, uint64(len(coins)))
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return wire.ShaHash{}, err
+	}
+
+	hasher := fastsha256.New()
+	cw := io.MultiWriter(w, hasher)
+	for _, coin := range coins {
+		buf.Reset()
+		buf.Write(coin.outPoint.Hash[:])
+		heightAndCoinbase := uint32(coin.height) << 1
+		if coin.coinbase {
+			heightAndCoinbase |= 1
+		}
+		binary.Write(&buf, binary.LittleEndian, coin.outPoint.Index)
+		binary.Write(&buf, binary.LittleEndian, heightAndCoinbase)
+		binary.Write(&buf, binary.LittleEndian, coin.amount)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(coin.pkScript)))
+		buf.Write(coin.pkScript)
+		if _, err := cw.Write(buf.Bytes()); err != nil {
+			return wire.ShaHash{}, err
+		}
+	}
+
+	setHash := wire.ShaHash(fastsha256.Sum256(hasher.Sum(nil)))
+	if _, err := w.Write(setHash[:]); err != nil {
+		return wire.ShaHash{}, err
+	}
+	return setHash, nil
+}
+
+// readUtxoSnapshot deserializes a snapshot from r and returns its header,
+// coins, and set hash.  An error is returned if the set hash stored in the
+// snapshot does not match the coins it contains.
+func readUtxoSnapshot(r io.Reader) (*utxoSnapshotHeader, []*utxoSnapshotCoin, *wire.ShaHash, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, nil, nil, err
+	}
+	if magic != utxoSnapshotMagic {
+		return nil, nil, nil, errors.New("not a UTXO snapshot")
+	}
+
+	var version, net, baseHeight uint32
+	var header utxoSnapshotHeader
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, nil, nil, err
+	}
+	if version != utxoSnapshotVersion {
+		return nil, nil, nil, fmt.Errorf("unsupported UTXO snapshot "+
+			"version %d", version)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &net); err != nil {
+		return nil, nil, nil, err
+	}
+	if _, err := io.ReadFull(r, header.baseHash[:]); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &baseHeight); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header.numCoins); err != nil {
+		return nil, nil, nil, err
+	}
+	header.net = wire.StonecoinNet(net)
+	header.baseHeight = int32(baseHeight)
+
+	// The coin count is untrusted, so don't preallocate based on it.
+	hasher := fastsha256.New()
+	cr := io.TeeReader(r, hasher)
+	var coins []*utxoSnapshotCoin
+	var prevCoin *utxoSnapshotCoin
+	for i := uint64(0); i < header.numCoins; i++ {
+		var coin utxoSnapshotCoin
+		var heightAndCoinbase, scriptLen uint32
+		if _, err := io.ReadFull(cr, coin.outPoint.Hash[:]); err != nil {
+			return nil, nil, nil, err
+		}
+		err := binary.Read(cr, binary.LittleEndian, &coin.outPoint.Index)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		err = binary.Read(cr, binary.LittleEndian, &heightAndCoinbase)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		err = binary.Read(cr, binary.LittleEndian, &coin.amount)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		err = binary.Read(cr, binary.LittleEndian, &scriptLen)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if scriptLen > maxSnapshotScriptSize {
+			return nil, nil, nil, fmt.Errorf("coin %d script is "+
+				"too large [size %d, max %d]", i, scriptLen,
+				maxSnapshotScriptSize)
+		}
+		coin.pkScript = make([]byte, scriptLen)
+		if _, err := io.ReadFull(cr, coin.pkScript); err != nil {
+			return nil, nil, nil, err
+		}
+		coin.height = int32(heightAndCoinbase >> 1)
+		coin.coinbase = heightAndCoinbase&1 == 1
+
+		// Enforce the canonical ordering so a snapshot has exactly one
+		// valid serialization.
+		if prevCoin != nil && !utxoSnapshotCoins([]*utxoSnapshotCoin{
+			prevCoin, &coin}).Less(0, 1) {
+			return nil, nil, nil, fmt.Errorf("coin %d is out of "+
+				"order", i)
+		}
+		prevCoin = &coin
+		coins = append(coins, &coin)
+	}
+
+	var setHash wire.ShaHash
+	if _, err := io.ReadFull(r, setHash[:]); err != nil {
+		return nil, nil, nil, err
+	}
+	if setHash != wire.ShaHash(fastsha256.Sum256(hasher.Sum(nil))) {
+		return nil, nil, nil, errUtxoSnapshotHashMismatch
+	}
+
+	return &header, coins, &setHash, nil
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/database"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestUtxoSnapshot ensures the UTXO set built from the main chain omits spent
+// and unspendable outputs, round trips through the snapshot format, and that
+// tampering with a snapshot is detected.
+func TestUtxoSnapshot(t *testing.T) {
+	db, err := database.CreateDB("memdb")
+	if err != nil {
+		t.Fatalf("CreateDB: %v", err)
+	}
+	defer db.Close()
+
+	addTestBlocks(t, db, 5, 0x207fffff, 0)
+
+	// Add a block which spends the output of the first coinbase and
+	// creates a spendable and an unspendable output.
+	prevHash, height, err := db.NewestSha()
+	if err != nil {
+		t.Fatalf("NewestSha: %v", err)
+	}
+	firstHash, err := db.FetchBlockShaByHeight(0)
+	if err != nil {
+		t.Fatalf("FetchBlockShaByHeight: %v", err)
+	}
+	firstBlock, err := db.FetchBlockBySha(firstHash)
+	if err != nil {
+		t.Fatalf("FetchBlockBySha: %v", err)
+	}
+	spentOutPoint := wire.NewOutPoint(firstBlock.Transactions()[0].Sha(), 0)
+
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff},
+		[]byte{byte(height + 1), 0, 0}))
+	coinbase.AddTxOut(wire.NewTxOut(1, []byte{0x51}))
+	spend := wire.NewMsgTx()
+	spend.AddTxIn(wire.NewTxIn(spentOutPoint, nil))
+	spend.AddTxOut(wire.NewTxOut(1, []byte{0x52}))
+	spend.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN, 0x01, 0x01}))
+
+	merkleRoot := coinbase.TxSha()
+	block := wire.NewMsgBlock(wire.NewBlockHeader(prevHash, &merkleRoot,
+		0x207fffff, 0))
+	block.AddTransaction(coinbase)
+	block.AddTransaction(spend)
+	if _, err := db.InsertBlock(coinutil.NewBlock(block)); err != nil {
+		t.Fatalf("InsertBlock: %v", err)
+	}
+
+	coins, baseHash, err := buildUtxoSet(db, height+1)
+	if err != nil {
+		t.Fatalf("buildUtxoSet: %v", err)
+	}
+	if *baseHash != block.BlockSha() {
+		t.Fatalf("buildUtxoSet: base hash %v, want %v", baseHash,
+			block.BlockSha())
+	}
+
+	// Every coinbase but the first is unspent, plus the spendable output
+	// of the spending transaction.
+	if len(coins) != 6 {
+		t.Fatalf("buildUtxoSet: got %d coins, want 6", len(coins))
+	}
+	for i, coin := range coins {
+		if coin.outPoint == *spentOutPoint {
+			t.Fatalf("buildUtxoSet: spent output %v included",
+				spentOutPoint)
+		}
+		if coin.pkScript[0] == txscript.OP_RETURN {
+			t.Fatalf("buildUtxoSet: unspendable output included")
+		}
+		if i > 0 && !utxoSnapshotCoins(coins).Less(i-1, i) {
+			t.Fatalf("buildUtxoSet: coins are not sorted")
+		}
+	}
+
+	header := &utxoSnapshotHeader{
+		net:        wire.SimNet,
+		baseHash:   *baseHash,
+		baseHeight: height + 1,
+		numCoins:   uint64(len(coins)),
+	}
+	var buf bytes.Buffer
+	setHash, err := writeUtxoSnapshot(&buf, header, coins)
+	if err != nil {
+		t.Fatalf("writeUtxoSnapshot: %v", err)
+	}
+	serialized := buf.Bytes()
+
+	gotHeader, gotCoins, gotHash, err := readUtxoSnapshot(
+		bytes.NewReader(serialized))
+	if err != nil {
+		t.Fatalf("readUtxoSnapshot: %v", err)
+	}
+	if !reflect.DeepEqual(gotHeader, header) {
+		t.Fatalf("readUtxoSnapshot: header %+v, want %+v", gotHeader,
+			header)
+	}
+	if !reflect.DeepEqual(gotCoins, coins) {
+		t.Fatalf("readUtxoSnapshot: coins do not match")
+	}
+	if *gotHash != setHash {
+		t.Fatalf("readUtxoSnapshot: hash %v, want %v", gotHash, setHash)
+	}
+
+	// Writing the same set again must produce identical bytes.
+	var buf2 bytes.Buffer
+	if _, err := writeUtxoSnapshot(&buf2, header, coins); err != nil {
+		t.Fatalf("writeUtxoSnapshot: %v", err)
+	}
+	if !bytes.Equal(buf2.Bytes(), serialized) {
+		t.Fatalf("writeUtxoSnapshot: output is not deterministic")
+	}
+
+	// Flip a bit in the amount of the first coin.
+	tampered := make([]byte, len(serialized))
+	copy(tampered, serialized)
+	tampered[4+4+4+32+4+8+32+4+4] ^= 0x01
+	_, _, _, err = readUtxoSnapshot(bytes.NewReader(tampered))
+	if err != errUtxoSnapshotHashMismatch {
+		t.Fatalf("readUtxoSnapshot: tampered snapshot: got %v, want %v",
+			err, errUtxoSnapshotHashMismatch)
+	}
+
+	// Truncated snapshots must be rejected.
+	_, _, _, err = readUtxoSnapshot(bytes.NewReader(serialized[:len(serialized)-1]))
+	if err == nil {
+		t.Fatalf("readUtxoSnapshot: truncated snapshot accepted")
+	}
+}
@@ -342,6 +342,60 @@ func BenchmarkDeserializeTx(b *testing.B) {
 	}
 }
 
+// BenchmarkTxFromBytes performs a benchmark on how long it takes to decode a
+// transaction directly from bytes.
+func BenchmarkTxFromBytes(b *testing.B) {
+	var buf bytes.Buffer
+	genesisCoinbaseTx.Serialize(&buf)
+	txBytes := buf.Bytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var tx MsgTx
+	for i := 0; i < b.N; i++ {
+		tx.FromBytes(txBytes)
+	}
+}
+
+// benchBlockBytes returns the serialized bytes of a block with 2000
+// transactions for the block decoding benchmarks.
+func benchBlockBytes() []byte {
+	block := blockOne
+	block.Transactions = make([]*MsgTx, 2000)
+	for i := range block.Transactions {
+		block.Transactions[i] = &genesisCoinbaseTx
+	}
+	var buf bytes.Buffer
+	block.Serialize(&buf)
+	return buf.Bytes()
+}
+
+// BenchmarkDeserializeBlock performs a benchmark on how long it takes to
+// deserialize a block with many transactions.
+func BenchmarkDeserializeBlock(b *testing.B) {
+	blockBytes := benchBlockBytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var block MsgBlock
+	for i := 0; i < b.N; i++ {
+		block.Deserialize(bytes.NewReader(blockBytes))
+	}
+}
+
+// BenchmarkBlockFromBytes performs a benchmark on how long it takes to decode
+// a block with many transactions directly from bytes.
+func BenchmarkBlockFromBytes(b *testing.B) {
+	blockBytes := benchBlockBytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var block MsgBlock
+	for i := 0; i < b.N; i++ {
+		block.FromBytes(blockBytes)
+	}
+}
+
 // BenchmarkSerializeTx performs a benchmark on how long it takes to serialize
 // a transaction.
 func BenchmarkSerializeTx(b *testing.B) {
@@ -0,0 +1,64 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+)
+
+// BlockVersionProofOfStake is the bit of the block version which marks a block
+// as a proof-of-stake block.  It lies within the range of bits used by rule
+// change deployments, so no deployment may be defined on this bit.
+//
+// The bit only carries this meaning from the height at which a network enables
+// proof-of-stake blocks.  Proof-of-stake blocks are serialized like any other
+// block, so the bit does not change how blocks are encoded.
+const BlockVersionProofOfStake int32 = 1 << 27
+
+// MaxBlockSignatureLen is the maximum number of bytes the signature of a
+// proof-of-stake block can be.  It leaves room for a DER encoded ECDSA
+// signature along with any additional data a consensus implementation needs.
+// The signature is carried by an output of the coinbase transaction so the
+// block hash commits to it.
+const MaxBlockSignatureLen = 128
+
+// BlockType identifies how a block proves it is allowed to extend the block
+// chain.
+type BlockType uint8
+
+// These constants define the block types.
+const (
+	// BlockTypePoW identifies a block whose header hash satisfies the
+	// target difficulty.
+	BlockTypePoW BlockType = iota
+
+	// BlockTypePoS identifies a block which is signed by the owner of the
+	// stake it is created with.
+	BlockTypePoS
+)
+
+// Map of BlockType values back to their constant names for pretty printing.
+var blockTypeStrings = map[BlockType]string{
+	BlockTypePoW: "BlockTypePoW",
+	BlockTypePoS: "BlockTypePoS",
+}
+
+// String returns the BlockType in human-readable form.
+func (t BlockType) String() string {
+	if s, ok := blockTypeStrings[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown BlockType (%d)", uint8(t))
+}
+
+// BlockType returns the type of the block the header belongs to as indicated
+// by its version.  Whether the block chain honors a proof-of-stake type also
+// depends on the height of the block, which the header does not record.
+func (h *BlockHeader) BlockType() BlockType {
+	if h.Version&BlockVersionProofOfStake != 0 {
+		return BlockTypePoS
+	}
+	return BlockTypePoW
+}
@@ -5,6 +5,7 @@
 package wire
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/binary"
 	"fmt"
@@ -17,6 +18,48 @@ import (
 // MaxVarIntPayload is the maximum payload size for a variable length integer.
 const MaxVarIntPayload = 9
 
+// maxPreallocBytes is the maximum number of bytes allocated up front for a
+// variable length element based on the length read from a message.  Larger
+// elements only grow as their data actually arrives so a malicious length
+// prefix can't cause more memory to be allocated than the peer sends.
+const maxPreallocBytes = 64 * 1024
+
+// binaryFreeListMaxItems is the number of buffers to keep in the free list
+// used for reading fixed size fields.
+const binaryFreeListMaxItems = 1024
+
+// binaryFreeList defines a concurrent safe free list of byte slices (up to the
+// maximum number defined by the binaryFreeListMaxItems constant) that have a
+// length of 8 and thus support reading up to a uint64.  The scratch buffers
+// used to read fixed size fields would otherwise escape to the heap through
+// the io.Reader interface and cost an allocation for every field decoded.
+type binaryFreeList chan []byte
+
+// Borrow returns a byte slice of length 8 from the free list.  A new buffer is
+// allocated if there are not any available on the free list.
+func (l binaryFreeList) Borrow() []byte {
+	var buf []byte
+	select {
+	case buf = <-l:
+	default:
+		buf = make([]byte, 8)
+	}
+	return buf[:8]
+}
+
+// Return puts the provided byte slice back on the free list.  The buffer MUST
+// have been obtained via the Borrow function and therefore have a cap of 8.
+func (l binaryFreeList) Return(buf []byte) {
+	select {
+	case l <- buf:
+	default:
+		// Let it go to the garbage collector.
+	}
+}
+
+// scratchBuffers is the free list of scratch buffers used when decoding.
+var scratchBuffers binaryFreeList = make(chan []byte, binaryFreeListMaxItems)
+
 // errNonCanonicalVarInt is the common format string used for non-canonically
 // encoded variable length integer errors.
 var errNonCanonicalVarInt = "non-canonical varint %x - discriminant %x must " +
@@ -25,7 +68,8 @@ var errNonCanonicalVarInt = "non-canonical varint %x - discriminant %x must " +
 // readElement reads the next sequence of bytes from r using little endian
 // depending on the concrete type of element pointed to.
 func readElement(r io.Reader, element interface{}) error {
-	var scratch [8]byte
+	scratch := scratchBuffers.Borrow()
+	defer scratchBuffers.Return(scratch)
 
 	// Attempt to read the element based on the concrete type via fast
 	// type assertions first.
@@ -154,6 +198,15 @@ func readElement(r io.Reader, element interface{}) error {
 		}
 		*e = RejectCode(b[0])
 		return nil
+
+	case *FilterType:
+		b := scratch[0:1]
+		_, err := io.ReadFull(r, b)
+		if err != nil {
+			return err
+		}
+		*e = FilterType(b[0])
+		return nil
 	}
 
 	// Fall back to the slower binary.Read if a fast path was not available
@@ -304,6 +357,15 @@ func writeElement(w io.Writer, element interface{}) error {
 			return err
 		}
 		return nil
+
+	case FilterType:
+		b := scratch[0:1]
+		b[0] = uint8(e)
+		_, err := w.Write(b)
+		if err != nil {
+			return err
+		}
+		return nil
 	}
 
 	// Fall back to the slower binary.Write if a fast path was not available
@@ -325,7 +387,9 @@ func writeElements(w io.Writer, elements ...interface{}) error {
 
 // readVarInt reads a variable length integer from r and returns it as a uint64.
 func readVarInt(r io.Reader, pver uint32) (uint64, error) {
-	var b [8]byte
+	b := scratchBuffers.Borrow()
+	defer scratchBuffers.Return(b)
+
 	_, err := io.ReadFull(r, b[0:1])
 	if err != nil {
 		return 0, err
@@ -335,11 +399,11 @@ func readVarInt(r io.Reader, pver uint32) (uint64, error) {
 	discriminant := uint8(b[0])
 	switch discriminant {
 	case 0xff:
-		_, err := io.ReadFull(r, b[:])
+		_, err := io.ReadFull(r, b)
 		if err != nil {
 			return 0, err
 		}
-		rv = binary.LittleEndian.Uint64(b[:])
+		rv = binary.LittleEndian.Uint64(b)
 
 		// The encoding is not canonical if the value could have been
 		// encoded using fewer bytes.
@@ -354,7 +418,7 @@ func readVarInt(r io.Reader, pver uint32) (uint64, error) {
 		if err != nil {
 			return 0, err
 		}
-		rv = uint64(binary.LittleEndian.Uint32(b[:]))
+		rv = uint64(binary.LittleEndian.Uint32(b))
 
 		// The encoding is not canonical if the value could have been
 		// encoded using fewer bytes.
@@ -369,7 +433,7 @@ func readVarInt(r io.Reader, pver uint32) (uint64, error) {
 		if err != nil {
 			return 0, err
 		}
-		rv = uint64(binary.LittleEndian.Uint16(b[:]))
+		rv = uint64(binary.LittleEndian.Uint16(b))
 
 		// The encoding is not canonical if the value could have been
 		// encoded using fewer bytes.
@@ -444,25 +508,35 @@ func VarIntSerializeSize(val uint64) int {
 // string.  A variable length string is encoded as a variable length integer
 // containing the length of the string followed by the bytes that represent the
 // string itself.  An error is returned if the length is greater than the
-// maximum block payload size since it helps protect against memory exhaustion
-// attacks and forced panics through malformed messages.
+// maximum message payload size since it helps protect against memory
+// exhaustion attacks and forced panics through malformed messages.
 func ReadVarString(r io.Reader, pver uint32) (string, error) {
+	return readVarString(r, pver, MaxMessagePayload,
+		"variable length string")
+}
+
+// readVarString reads a variable length string from r and returns it as a Go
+// string.  An error is returned if the length is greater than the passed
+// maxAllowed parameter.  The fieldName parameter is only used for the error
+// message so it provides more context in the error.
+func readVarString(r io.Reader, pver uint32, maxAllowed uint32,
+	fieldName string) (string, error) {
+
 	count, err := readVarInt(r, pver)
 	if err != nil {
 		return "", err
 	}
 
 	// Prevent variable length strings that are larger than the maximum
-	// message size.  It would be possible to cause memory exhaustion and
+	// allowed size.  It would be possible to cause memory exhaustion and
 	// panics without a sane upper bound on this count.
-	if count > MaxMessagePayload {
-		str := fmt.Sprintf("variable length string is too long "+
-			"[count %d, max %d]", count, MaxMessagePayload)
-		return "", messageError("ReadVarString", str)
+	if count > uint64(maxAllowed) {
+		str := fmt.Sprintf("%s is too long [count %d, max %d]",
+			fieldName, count, maxAllowed)
+		return "", limitError("ReadVarString", ErrElementTooLarge, str)
 	}
 
-	buf := make([]byte, count)
-	_, err = io.ReadFull(r, buf)
+	buf, err := readBytes(r, count)
 	if err != nil {
 		return "", err
 	}
@@ -505,15 +579,36 @@ func readVarBytes(r io.Reader, pver uint32, maxAllowed uint32,
 	if count > uint64(maxAllowed) {
 		str := fmt.Sprintf("%s is larger than the max allowed size "+
 			"[count %d, max %d]", fieldName, count, maxAllowed)
-		return nil, messageError("readVarBytes", str)
+		return nil, limitError("readVarBytes", ErrElementTooLarge, str)
 	}
 
-	b := make([]byte, count)
-	_, err = io.ReadFull(r, b)
+	return readBytes(r, count)
+}
+
+// readBytes reads exactly count bytes from r.  Up to maxPreallocBytes are
+// allocated up front and any more only as the data is read, so the memory used
+// is bounded by the data which is actually available rather than the count.
+// Like io.ReadFull, the error is io.EOF only if no bytes were read.
+func readBytes(r io.Reader, count uint64) ([]byte, error) {
+	if count <= maxPreallocBytes {
+		b := make([]byte, count)
+		_, err := io.ReadFull(r, b)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(maxPreallocBytes)
+	n, err := io.CopyN(&buf, r, int64(count))
 	if err != nil {
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
 		return nil, err
 	}
-	return b, nil
+	return buf.Bytes(), nil
 }
 
 // writeVarInt serializes a variable length byte array to w as a varInt
@@ -532,6 +627,18 @@ func writeVarBytes(w io.Writer, pver uint32, bytes []byte) error {
 	return nil
 }
 
+// WriteVarInt serializes val to w using a variable number of bytes depending
+// on its value.
+func WriteVarInt(w io.Writer, pver uint32, val uint64) error {
+	return writeVarInt(w, pver, val)
+}
+
+// WriteVarBytes serializes a variable length byte array to w as a varInt
+// containing the number of bytes, followed by the bytes themselves.
+func WriteVarBytes(w io.Writer, pver uint32, bytes []byte) error {
+	return writeVarBytes(w, pver, bytes)
+}
+
 // randomUint64 returns a cryptographically random uint64 value.  This
 // unexported version takes a reader primarily to ensure the error paths
 // can be properly tested by passing a fake reader in the tests.
@@ -709,6 +709,48 @@ func TestVarBytesOverflowErrors(t *testing.T) {
 
 }
 
+// TestVarBytesLengthPrefix ensures variable length byte arrays which are
+// larger than allowed are reported as too large, and that a length prefix
+// larger than the data which follows it results in the same errors as reading
+// too little data with io.ReadFull.
+func TestVarBytesLengthPrefix(t *testing.T) {
+	pver := wire.ProtocolVersion
+
+	// Varint for a length of 1 MiB.
+	lengthPrefix := []byte{0xfe, 0x00, 0x00, 0x10, 0x00}
+
+	tests := []struct {
+		name string
+		buf  []byte // Wire encoding
+		max  uint32 // Max allowed length
+		err  error  // Expected error
+	}{
+		{"no data", lengthPrefix, wire.MaxMessagePayload, io.EOF},
+		{"short data", append(lengthPrefix, make([]byte, 10)...),
+			wire.MaxMessagePayload, io.ErrUnexpectedEOF},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		rbuf := bytes.NewReader(test.buf)
+		_, err := wire.TstReadVarBytes(rbuf, pver, test.max,
+			"test payload")
+		if err != test.err {
+			t.Errorf("%s: wrong error got: %v, want: %v", test.name,
+				err, test.err)
+		}
+	}
+
+	// Longer data than allowed must be reported as too large.
+	rbuf := bytes.NewReader(lengthPrefix)
+	_, err := wire.TstReadVarBytes(rbuf, pver, 1024, "test payload")
+	msgErr, ok := err.(*wire.MessageError)
+	if !ok || msgErr.ErrorCode != wire.ErrElementTooLarge {
+		t.Errorf("too large: wrong error got: %v, want: %v", err,
+			wire.ErrElementTooLarge)
+	}
+}
+
 // TestRandomUint64 exercises the randomness of the random number generator on
 // the system by ensuring the probability of the generated numbers.  If the RNG
 // is evenly distributed as a proper cryptographic RNG should be, there really
@@ -147,6 +147,13 @@ io.ErrShortWrite, or of type wire.MessageError.  This allows the caller to
 differentiate between general IO errors and malformed messages through type
 assertions.
 
+Decoding never trusts the counts and lengths read from a message.  Element
+counts and the lengths of variable length fields such as scripts and strings
+are checked against per-element limits, some of which depend on the protocol
+version, and memory is only allocated as the data actually arrives.  The
+ErrorCode field of a wire.MessageError identifies messages exceeding these
+limits, such as ErrTooManyElements and ErrElementTooLarge.
+
 Bitcoin Improvement Proposals
 
 This package includes spec changes outlined by the following BIPs:
@@ -8,16 +8,59 @@ import (
 	"fmt"
 )
 
+// ErrorCode identifies the kind of issue with a message.
+type ErrorCode int
+
+// These constants are used to identify a specific MessageError.
+const (
+	// ErrMalformedMessage indicates a message which can't be decoded or
+	// encoded for a reason not covered by the other error codes.  It is
+	// the error code of every MessageError unless stated otherwise.
+	ErrMalformedMessage ErrorCode = iota
+
+	// ErrPayloadTooLarge indicates the payload of a message is larger than
+	// the maximum allowed for all messages or for messages of its type
+	// at the negotiated protocol version.
+	ErrPayloadTooLarge
+
+	// ErrTooManyElements indicates a message has more elements of a kind,
+	// such as addresses, inventory vectors or transaction inputs, than
+	// allowed at the negotiated protocol version.
+	ErrTooManyElements
+
+	// ErrElementTooLarge indicates a variable length element of a message,
+	// such as a script or a string, is larger than allowed.
+	ErrElementTooLarge
+)
+
+// Map of ErrorCode values back to their constant names for pretty printing.
+var errorCodeStrings = map[ErrorCode]string{
+	ErrMalformedMessage: "ErrMalformedMessage",
+	ErrPayloadTooLarge:  "ErrPayloadTooLarge",
+	ErrTooManyElements:  "ErrTooManyElements",
+	ErrElementTooLarge:  "ErrElementTooLarge",
+}
+
+// String returns the ErrorCode as a human-readable name.
+func (e ErrorCode) String() string {
+	if s := errorCodeStrings[e]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown ErrorCode (%d)", int(e))
+}
+
 // MessageError describes an issue with a message.
 // An example of some potential issues are messages from the wrong bitcoin
 // network, invalid commands, mismatched checksums, and exceeding max payloads.
 //
 // This provides a mechanism for the caller to type assert the error to
 // differentiate between general io errors such as io.EOF and issues that
-// resulted from malformed messages.
+// resulted from malformed messages.  The ErrorCode tells messages which exceed
+// the allocation limits apart from otherwise malformed ones.
 type MessageError struct {
-	Func        string // Function name
-	Description string // Human readable description of the issue
+	Func        string    // Function name
+	ErrorCode   ErrorCode // Describes the kind of issue
+	Description string    // Human readable description of the issue
 }
 
 // Error satisfies the error interface and prints human-readable errors.
@@ -32,3 +75,9 @@ func (e *MessageError) Error() string {
 func messageError(f string, desc string) *MessageError {
 	return &MessageError{Func: f, Description: desc}
 }
+
+// limitError creates an error with the given error code for the given
+// function and description of a limit a message exceeds.
+func limitError(f string, c ErrorCode, desc string) *MessageError {
+	return &MessageError{Func: f, ErrorCode: c, Description: desc}
+}
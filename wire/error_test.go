@@ -0,0 +1,35 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire_test
+
+import (
+	"testing"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestErrorCodeStringer tests the stringized output for the ErrorCode type.
+func TestErrorCodeStringer(t *testing.T) {
+	tests := []struct {
+		in   wire.ErrorCode
+		want string
+	}{
+		{wire.ErrMalformedMessage, "ErrMalformedMessage"},
+		{wire.ErrPayloadTooLarge, "ErrPayloadTooLarge"},
+		{wire.ErrTooManyElements, "ErrTooManyElements"},
+		{wire.ErrElementTooLarge, "ErrElementTooLarge"},
+		{0xffff, "Unknown ErrorCode (65535)"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := test.in.String()
+		if result != test.want {
+			t.Errorf("String #%d\n got: %s want: %s", i, result,
+				test.want)
+			continue
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// byteReader decodes the fields of a serialized message directly from a byte
+// slice.  Fixed size fields are decoded in place and variable length byte
+// arrays are returned as subslices of the buffer instead of being copied, so
+// decoding does not allocate beyond the decoded structures themselves.
+type byteReader struct {
+	buf []byte
+	off int
+}
+
+// next returns the next n bytes of the buffer and advances past them.  The
+// returned slice is capped to n bytes so appending to it never overwrites the
+// rest of the buffer.  Like io.ReadFull, the error is io.EOF only if no bytes
+// remain and io.ErrUnexpectedEOF if fewer than n bytes remain.
+func (r *byteReader) next(n uint64) ([]byte, error) {
+	remaining := uint64(len(r.buf) - r.off)
+	if n > remaining {
+		if remaining == 0 {
+			return nil, io.EOF
+		}
+		r.off = len(r.buf)
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	end := r.off + int(n)
+	b := r.buf[r.off:end:end]
+	r.off = end
+	return b, nil
+}
+
+// allocCount returns the number of elements to allocate up front for count
+// elements which each take at least minSize bytes.  Since no more elements
+// than fit into the rest of the buffer can be decoded, the count is limited
+// accordingly so a malicious count can't cause a large allocation.  Decoding
+// more elements than returned always fails before the allocation is exceeded.
+func (r *byteReader) allocCount(count, minSize uint64) uint64 {
+	if max := uint64(len(r.buf)-r.off)/minSize + 1; count > max {
+		return max
+	}
+	return count
+}
+
+// uint32 reads the next little endian uint32 from the buffer.
+func (r *byteReader) uint32() (uint32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// uint64 reads the next little endian uint64 from the buffer.
+func (r *byteReader) uint64() (uint64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// hash reads the next hash from the buffer into h.
+func (r *byteReader) hash(h *ShaHash) error {
+	b, err := r.next(HashSize)
+	if err != nil {
+		return err
+	}
+	copy(h[:], b)
+	return nil
+}
+
+// varInt reads the next variable length integer from the buffer.  It applies
+// the same canonical encoding rules as readVarInt.
+func (r *byteReader) varInt(pver uint32) (uint64, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+
+	var rv, min uint64
+	discriminant := b[0]
+	switch discriminant {
+	case 0xff:
+		rv, err = r.uint64()
+		min = 0x100000000
+
+	case 0xfe:
+		var v uint32
+		v, err = r.uint32()
+		rv, min = uint64(v), 0x10000
+
+	case 0xfd:
+		b, err = r.next(2)
+		if err == nil {
+			rv = uint64(binary.LittleEndian.Uint16(b))
+		}
+		min = 0xfd
+
+	default:
+		return uint64(discriminant), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// The encoding is not canonical if the value could have been encoded
+	// using fewer bytes.
+	if rv < min {
+		return 0, messageError("readVarInt", fmt.Sprintf(
+			errNonCanonicalVarInt, rv, discriminant, min))
+	}
+	return rv, nil
+}
+
+// varBytes reads the next variable length byte array from the buffer and
+// returns it without copying.  It applies the same size limit as readVarBytes.
+func (r *byteReader) varBytes(pver uint32, maxAllowed uint32,
+	fieldName string) ([]byte, error) {
+
+	count, err := r.varInt(pver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prevent byte array larger than the max message size.
+	if count > uint64(maxAllowed) {
+		str := fmt.Sprintf("%s is larger than the max allowed size "+
+			"[count %d, max %d]", fieldName, count, maxAllowed)
+		return nil, limitError("readVarBytes", ErrElementTooLarge, str)
+	}
+
+	return r.next(count)
+}
+
+// txIn reads the next transaction input from the buffer into ti.
+func (r *byteReader) txIn(pver uint32, ti *TxIn) error {
+	err := r.hash(&ti.PreviousOutPoint.Hash)
+	if err != nil {
+		return err
+	}
+	ti.PreviousOutPoint.Index, err = r.uint32()
+	if err != nil {
+		return err
+	}
+
+	ti.SignatureScript, err = r.varBytes(pver, maxScriptSize,
+		"transaction input signature script")
+	if err != nil {
+		return err
+	}
+
+	ti.Sequence, err = r.uint32()
+	return err
+}
+
+// txOut reads the next transaction output from the buffer into to.
+func (r *byteReader) txOut(pver uint32, to *TxOut) error {
+	value, err := r.uint64()
+	if err != nil {
+		return err
+	}
+	to.Value = int64(value)
+
+	to.PkScript, err = r.varBytes(pver, maxScriptSize,
+		"transaction output public key script")
+	return err
+}
+
+// txWitness reads the next transaction input witness from the buffer.
+func (r *byteReader) txWitness(pver uint32) (TxWitness, error) {
+	count, err := r.varInt(pver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prevent more witness items than could possibly fit into a block.
+	if count > maxWitnessItemsPerInput {
+		str := fmt.Sprintf("too many witness items to fit into max "+
+			"message size [count %d, max %d]", count,
+			maxWitnessItemsPerInput)
+		return nil, limitError("readTxWitness", ErrTooManyElements, str)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	witness := make(TxWitness, r.allocCount(count, 1))
+	for i := uint64(0); i < count; i++ {
+		witness[i], err = r.varBytes(pver, maxWitnessItemSize,
+			"transaction input witness item")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return witness, nil
+}
+
+// FromBytes decodes the serialized transaction in b into the receiver.  It
+// accepts the same encoding as Deserialize, but avoids the allocations and
+// copies of decoding through an io.Reader: the scripts and witness items of
+// the transaction reference b directly and the inputs and outputs are each
+// allocated at once.  The caller must therefore not modify b afterwards.
+// Any bytes following the transaction are ignored.
+func (msg *MsgTx) FromBytes(b []byte) error {
+	r := byteReader{buf: b}
+	return msg.fromBytes(&r, 0)
+}
+
+// fromBytes decodes the next transaction from r into the receiver.
+func (msg *MsgTx) fromBytes(r *byteReader, pver uint32) error {
+	version, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	msg.Version = int32(version)
+
+	count, err := r.varInt(pver)
+	if err != nil {
+		return err
+	}
+
+	// A zero input count is the marker for a transaction serialized with
+	// witness data when it is followed by the witness flag.  Otherwise,
+	// the byte following it is the start of the output count.
+	var hasWitness bool
+	if count == TxFlagMarker {
+		flag, err := r.next(1)
+		if err != nil {
+			return err
+		}
+		if flag[0] == WitnessFlag {
+			hasWitness = true
+			count, err = r.varInt(pver)
+			if err != nil {
+				return err
+			}
+		} else {
+			r.off--
+		}
+	}
+
+	// Prevent more input transactions than could possibly fit into a
+	// message.
+	if count > uint64(maxTxInPerMessage) {
+		str := fmt.Sprintf("too many input transactions to fit into "+
+			"max message size [count %d, max %d]", count,
+			maxTxInPerMessage)
+		return limitError("MsgTx.FromBytes", ErrTooManyElements, str)
+	}
+
+	n := r.allocCount(count, minTxInPayload)
+	txIns := make([]TxIn, n)
+	msg.TxIn = make([]*TxIn, n)
+	for i := uint64(0); i < count; i++ {
+		err = r.txIn(pver, &txIns[i])
+		if err != nil {
+			return err
+		}
+		msg.TxIn[i] = &txIns[i]
+	}
+
+	count, err = r.varInt(pver)
+	if err != nil {
+		return err
+	}
+
+	// Prevent more output transactions than could possibly fit into a
+	// message.
+	if count > uint64(maxTxOutPerMessage) {
+		str := fmt.Sprintf("too many output transactions to fit into "+
+			"max message size [count %d, max %d]", count,
+			maxTxOutPerMessage)
+		return limitError("MsgTx.FromBytes", ErrTooManyElements, str)
+	}
+
+	n = r.allocCount(count, minTxOutPayload)
+	txOuts := make([]TxOut, n)
+	msg.TxOut = make([]*TxOut, n)
+	for i := uint64(0); i < count; i++ {
+		err = r.txOut(pver, &txOuts[i])
+		if err != nil {
+			return err
+		}
+		msg.TxOut[i] = &txOuts[i]
+	}
+
+	if hasWitness {
+		for _, ti := range msg.TxIn {
+			ti.Witness, err = r.txWitness(pver)
+			if err != nil {
+				return err
+			}
+		}
+		if !msg.HasWitness() {
+			return messageError("MsgTx.FromBytes", "transaction "+
+				"is serialized with witness data, but has none")
+		}
+	}
+
+	msg.LockTime, err = r.uint32()
+	return err
+}
+
+// FromBytes decodes the serialized block in b into the receiver.  It accepts
+// the same encoding as Deserialize, but avoids the allocations and copies of
+// decoding through an io.Reader: the scripts and witness items of the
+// transactions and the block signature reference b directly and the
+// transactions are allocated at once.  The caller must therefore not modify b
+// afterwards, and b is kept alive for as long as any of the transactions are.
+// Any bytes following the block are ignored.
+func (msg *MsgBlock) FromBytes(b []byte) error {
+	r := byteReader{buf: b}
+	return msg.fromBytes(&r, 0)
+}
+
+// fromBytes decodes the next block from r into the receiver.
+func (msg *MsgBlock) fromBytes(r *byteReader, pver uint32) error {
+	bh := &msg.Header
+	version, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	bh.Version = int32(version)
+	if err := r.hash(&bh.PrevBlock); err != nil {
+		return err
+	}
+	if err := r.hash(&bh.MerkleRoot); err != nil {
+		return err
+	}
+	sec, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	bh.Timestamp = time.Unix(int64(sec), 0)
+	bh.Bits, err = r.uint32()
+	if err != nil {
+		return err
+	}
+	bh.Nonce, err = r.uint32()
+	if err != nil {
+		return err
+	}
+
+	txCount, err := r.varInt(pver)
+	if err != nil {
+		return err
+	}
+
+	// Prevent more transactions than could possibly fit into a block.
+	if txCount > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions to fit into a block "+
+			"[count %d, max %d]", txCount, maxTxPerBlock)
+		return limitError("MsgBlock.FromBytes", ErrTooManyElements, str)
+	}
+
+	n := r.allocCount(txCount, minTxPayload)
+	txns := make([]MsgTx, n)
+	msg.Transactions = make([]*MsgTx, n)
+	for i := uint64(0); i < txCount; i++ {
+		err := txns[i].fromBytes(r, pver)
+		if err != nil {
+			return err
+		}
+		msg.Transactions[i] = &txns[i]
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/stcd/wire"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// sameDecodeError returns whether the errors returned by decoding the same
+// data from bytes and from a reader are equivalent.  Message errors only need
+// to agree on the error code since they name the function which failed.
+func sameDecodeError(fromBytes, fromReader error) bool {
+	if bErr, ok := fromBytes.(*wire.MessageError); ok {
+		rErr, ok := fromReader.(*wire.MessageError)
+		return ok && bErr.ErrorCode == rErr.ErrorCode
+	}
+	return fromBytes == fromReader
+}
+
+// TestFromBytes ensures decoding transactions and blocks directly from bytes
+// produces the same result as deserializing them from a reader, including the
+// errors for every truncation of the data.
+func TestFromBytes(t *testing.T) {
+	witnessTx := multiTx.Copy()
+	witnessTx.TxIn[0].Witness = wire.TxWitness{{0x01, 0x02}, {0x03}}
+	var witnessTxBytes bytes.Buffer
+	if err := witnessTx.Serialize(&witnessTxBytes); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+
+	posBlock := blockOne
+	posBlock.Header.Version |= wire.BlockVersionProofOfStake
+	var posBlockBytes bytes.Buffer
+	if err := posBlock.Serialize(&posBlockBytes); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+
+	txTests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"multiTx", multiTxEncoded},
+		{"witnessTx", witnessTxBytes.Bytes()},
+	}
+	for _, test := range txTests {
+		for i := 0; i <= len(test.buf); i++ {
+			buf := test.buf[:i]
+			var tx, want wire.MsgTx
+			err := tx.FromBytes(buf)
+			wantErr := want.Deserialize(bytes.NewReader(buf))
+			if !sameDecodeError(err, wantErr) {
+				t.Errorf("FromBytes %s (%d bytes): got error %v, "+
+					"want %v", test.name, i, err, wantErr)
+				continue
+			}
+			if err == nil && !reflect.DeepEqual(&tx, &want) {
+				t.Errorf("FromBytes %s (%d bytes)\n got: %s "+
+					"want: %s", test.name, i, spew.Sdump(&tx),
+					spew.Sdump(&want))
+			}
+		}
+	}
+
+	blockTests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"blockOne", blockOneBytes},
+		{"posBlock", posBlockBytes.Bytes()},
+	}
+	for _, test := range blockTests {
+		for i := 0; i <= len(test.buf); i++ {
+			buf := test.buf[:i]
+			var block, want wire.MsgBlock
+			err := block.FromBytes(buf)
+			wantErr := want.Deserialize(bytes.NewReader(buf))
+			if !sameDecodeError(err, wantErr) {
+				t.Errorf("FromBytes %s (%d bytes): got error %v, "+
+					"want %v", test.name, i, err, wantErr)
+				continue
+			}
+			if err == nil && !reflect.DeepEqual(&block, &want) {
+				t.Errorf("FromBytes %s (%d bytes)\n got: %s "+
+					"want: %s", test.name, i, spew.Sdump(&block),
+					spew.Sdump(&want))
+			}
+		}
+	}
+
+	// A block which claims more transactions than could fit into a block
+	// must be rejected before allocating them.
+	overflow := append([]byte{}, blockOneBytes[:80]...)
+	overflow = append(overflow, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff)
+	var block wire.MsgBlock
+	err := block.FromBytes(overflow)
+	if msgErr, ok := err.(*wire.MessageError); !ok ||
+		msgErr.ErrorCode != wire.ErrTooManyElements {
+
+		t.Errorf("FromBytes (overflow): got error %v, want %v", err,
+			wire.ErrTooManyElements)
+	}
+
+	// A transaction count which fits into a block but not into the data
+	// must fail once the data runs out.
+	truncated := append([]byte{}, blockOneBytes[:80]...)
+	truncated = append(truncated, 0xfe, 0x00, 0x00, 0x01, 0x00)
+	if err := block.FromBytes(truncated); err != io.EOF {
+		t.Errorf("FromBytes (truncated): got error %v, want %v", err,
+			io.EOF)
+	}
+}
+
+// TestFromBytesAliasing ensures the scripts decoded from bytes reference the
+// passed data and can't be appended to in a way that overwrites it.
+func TestFromBytesAliasing(t *testing.T) {
+	buf := append([]byte{}, multiTxEncoded...)
+	var tx wire.MsgTx
+	if err := tx.FromBytes(buf); err != nil {
+		t.Fatalf("FromBytes: unexpected error: %v", err)
+	}
+
+	script := tx.TxIn[0].SignatureScript
+	if cap(script) != len(script) {
+		t.Errorf("FromBytes: signature script has cap %d, want %d",
+			cap(script), len(script))
+	}
+	_ = append(script, 0x00)
+	if !bytes.Equal(buf, multiTxEncoded) {
+		t.Errorf("FromBytes: appending to a script modified the data")
+	}
+}
@@ -21,20 +21,32 @@ const (
 // InvType represents the allowed types of inventory vectors.  See InvVect.
 type InvType uint32
 
+// InvWitnessFlag denotes that the inventory vector type is requesting, or
+// sending a version which includes witness data (BIP0144).
+const InvWitnessFlag = 1 << 30
+
 // These constants define the various supported inventory vector types.
 const (
-	InvTypeError         InvType = 0
-	InvTypeTx            InvType = 1
-	InvTypeBlock         InvType = 2
-	InvTypeFilteredBlock InvType = 3
+	InvTypeError                InvType = 0
+	InvTypeTx                   InvType = 1
+	InvTypeBlock                InvType = 2
+	InvTypeFilteredBlock        InvType = 3
+	InvTypeWTx                  InvType = 5
+	InvTypeWitnessBlock         InvType = InvTypeBlock | InvWitnessFlag
+	InvTypeWitnessTx            InvType = InvTypeTx | InvWitnessFlag
+	InvTypeFilteredWitnessBlock InvType = InvTypeFilteredBlock | InvWitnessFlag
 )
 
 // Map of service flags back to their constant names for pretty printing.
 var ivStrings = map[InvType]string{
-	InvTypeError:         "ERROR",
-	InvTypeTx:            "MSG_TX",
-	InvTypeBlock:         "MSG_BLOCK",
-	InvTypeFilteredBlock: "MSG_FILTERED_BLOCK",
+	InvTypeError:                "ERROR",
+	InvTypeTx:                   "MSG_TX",
+	InvTypeBlock:                "MSG_BLOCK",
+	InvTypeFilteredBlock:        "MSG_FILTERED_BLOCK",
+	InvTypeWTx:                  "MSG_WTX",
+	InvTypeWitnessBlock:         "MSG_WITNESS_BLOCK",
+	InvTypeWitnessTx:            "MSG_WITNESS_TX",
+	InvTypeFilteredWitnessBlock: "MSG_FILTERED_WITNESS_BLOCK",
 }
 
 // String returns the InvType in human-readable form.
@@ -22,6 +22,9 @@ func TestInvTypeStringer(t *testing.T) {
 		{wire.InvTypeError, "ERROR"},
 		{wire.InvTypeTx, "MSG_TX"},
 		{wire.InvTypeBlock, "MSG_BLOCK"},
+		{wire.InvTypeWTx, "MSG_WTX"},
+		{wire.InvTypeWitnessBlock, "MSG_WITNESS_BLOCK"},
+		{wire.InvTypeWitnessTx, "MSG_WITNESS_TX"},
 		{0xffffffff, "Unknown InvType (4294967295)"},
 	}
 
@@ -26,27 +26,34 @@ const MaxMessagePayload = (1024 * 1024 * 32) // 32MB
 
 // Commands used in bitcoin message headers which describe the type of message.
 const (
-	CmdVersion     = "version"
-	CmdVerAck      = "verack"
-	CmdGetAddr     = "getaddr"
-	CmdAddr        = "addr"
-	CmdGetBlocks   = "getblocks"
-	CmdInv         = "inv"
-	CmdGetData     = "getdata"
-	CmdNotFound    = "notfound"
-	CmdBlock       = "block"
-	CmdTx          = "tx"
-	CmdGetHeaders  = "getheaders"
-	CmdHeaders     = "headers"
-	CmdPing        = "ping"
-	CmdPong        = "pong"
-	CmdAlert       = "alert"
-	CmdMemPool     = "mempool"
-	CmdFilterAdd   = "filteradd"
-	CmdFilterClear = "filterclear"
-	CmdFilterLoad  = "filterload"
-	CmdMerkleBlock = "merkleblock"
-	CmdReject      = "reject"
+	CmdVersion      = "version"
+	CmdVerAck       = "verack"
+	CmdGetAddr      = "getaddr"
+	CmdAddr         = "addr"
+	CmdGetBlocks    = "getblocks"
+	CmdInv          = "inv"
+	CmdGetData      = "getdata"
+	CmdNotFound     = "notfound"
+	CmdBlock        = "block"
+	CmdTx           = "tx"
+	CmdGetHeaders   = "getheaders"
+	CmdHeaders      = "headers"
+	CmdPing         = "ping"
+	CmdPong         = "pong"
+	CmdAlert        = "alert"
+	CmdMemPool      = "mempool"
+	CmdFilterAdd    = "filteradd"
+	CmdFilterClear  = "filterclear"
+	CmdFilterLoad   = "filterload"
+	CmdMerkleBlock  = "merkleblock"
+	CmdReject       = "reject"
+	CmdGetCFilters  = "getcfilters"
+	CmdCFilter      = "cfilter"
+	CmdGetCFHeaders = "getcfheaders"
+	CmdCFHeaders    = "cfheaders"
+	CmdGetCFCheckpt = "getcfcheckpt"
+	CmdCFCheckpt    = "cfcheckpt"
+	CmdWTxIdRelay   = "wtxidrelay"
 )
 
 // Message is an interface that describes a bitcoin message.  A type that
@@ -128,6 +135,27 @@ func makeEmptyMessage(command string) (Message, error) {
 	case CmdReject:
 		msg = &MsgReject{}
 
+	case CmdGetCFilters:
+		msg = &MsgGetCFilters{}
+
+	case CmdCFilter:
+		msg = &MsgCFilter{}
+
+	case CmdGetCFHeaders:
+		msg = &MsgGetCFHeaders{}
+
+	case CmdCFHeaders:
+		msg = &MsgCFHeaders{}
+
+	case CmdGetCFCheckpt:
+		msg = &MsgGetCFCheckpt{}
+
+	case CmdCFCheckpt:
+		msg = &MsgCFCheckpt{}
+
+	case CmdWTxIdRelay:
+		msg = &MsgWTxIdRelay{}
+
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
@@ -198,7 +226,8 @@ func WriteMessageN(w io.Writer, msg Message, pver uint32, btcnet StonecoinNet) (
 	if len(cmd) > CommandSize {
 		str := fmt.Sprintf("command [%s] is too long [max %v]",
 			cmd, CommandSize)
-		return totalBytes, messageError("WriteMessage", str)
+		return totalBytes, limitError("WriteMessage",
+			ErrElementTooLarge, str)
 	}
 	copy(command[:], []byte(cmd))
 
@@ -216,7 +245,8 @@ func WriteMessageN(w io.Writer, msg Message, pver uint32, btcnet StonecoinNet) (
 		str := fmt.Sprintf("message payload is too large - encoded "+
 			"%d bytes, but maximum message payload is %d bytes",
 			lenp, MaxMessagePayload)
-		return totalBytes, messageError("WriteMessage", str)
+		return totalBytes, limitError("WriteMessage",
+			ErrPayloadTooLarge, str)
 	}
 
 	// Enforce maximum message payload based on the message type.
@@ -225,7 +255,8 @@ func WriteMessageN(w io.Writer, msg Message, pver uint32, btcnet StonecoinNet) (
 		str := fmt.Sprintf("message payload is too large - encoded "+
 			"%d bytes, but maximum message payload size for "+
 			"messages of type [%s] is %d.", lenp, cmd, mpl)
-		return totalBytes, messageError("WriteMessage", str)
+		return totalBytes, limitError("WriteMessage",
+			ErrPayloadTooLarge, str)
 	}
 
 	// Create header for the message.
@@ -289,7 +320,8 @@ func ReadMessageN(r io.Reader, pver uint32, btcnet StonecoinNet) (int, Message,
 		str := fmt.Sprintf("message payload is too large - header "+
 			"indicates %d bytes, but max message payload is %d "+
 			"bytes.", hdr.length, MaxMessagePayload)
-		return totalBytes, nil, nil, messageError("ReadMessage", str)
+		return totalBytes, nil, nil, limitError("ReadMessage",
+			ErrPayloadTooLarge, str)
 
 	}
 
@@ -325,7 +357,8 @@ func ReadMessageN(r io.Reader, pver uint32, btcnet StonecoinNet) (int, Message,
 		str := fmt.Sprintf("payload exceeds max length - header "+
 			"indicates %v bytes, but max payload size for "+
 			"messages of type [%v] is %v.", hdr.length, command, mpl)
-		return totalBytes, nil, nil, messageError("ReadMessage", str)
+		return totalBytes, nil, nil, limitError("ReadMessage",
+			ErrPayloadTooLarge, str)
 	}
 
 	// Read payload.
@@ -346,10 +379,18 @@ func ReadMessageN(r io.Reader, pver uint32, btcnet StonecoinNet) (int, Message,
 		return totalBytes, nil, nil, messageError("ReadMessage", str)
 	}
 
-	// Unmarshal message.  NOTE: This must be a *bytes.Buffer since the
-	// MsgVersion BtcDecode function requires it.
-	pr := bytes.NewBuffer(payload)
-	err = msg.BtcDecode(pr, pver)
+	// Unmarshal message.  Blocks and transactions are decoded directly from
+	// the payload, which is not modified afterwards, to avoid allocating
+	// every script separately.  NOTE: Other messages must be decoded from a
+	// *bytes.Buffer since the MsgVersion BtcDecode function requires it.
+	switch m := msg.(type) {
+	case *MsgBlock:
+		err = m.fromBytes(&byteReader{buf: payload}, pver)
+	case *MsgTx:
+		err = m.fromBytes(&byteReader{buf: payload}, pver)
+	default:
+		err = msg.BtcDecode(bytes.NewBuffer(payload), pver)
+	}
 	if err != nil {
 		return totalBytes, nil, nil, err
 	}
@@ -75,6 +75,13 @@ func TestMessage(t *testing.T) {
 	bh := wire.NewBlockHeader(&wire.ShaHash{}, &wire.ShaHash{}, 0, 0)
 	msgMerkleBlock := wire.NewMsgMerkleBlock(bh)
 	msgReject := wire.NewMsgReject("block", wire.RejectDuplicate, "duplicate block")
+	msgGetCFilters := wire.NewMsgGetCFilters(wire.GCSFilterRegular, 0, &wire.ShaHash{})
+	msgCFilter := wire.NewMsgCFilter(wire.GCSFilterRegular, &wire.ShaHash{}, []byte{0x01})
+	msgGetCFHeaders := wire.NewMsgGetCFHeaders(wire.GCSFilterRegular, 0, &wire.ShaHash{})
+	msgCFHeaders := wire.NewMsgCFHeaders()
+	msgGetCFCheckpt := wire.NewMsgGetCFCheckpt(wire.GCSFilterRegular, &wire.ShaHash{})
+	msgCFCheckpt := wire.NewMsgCFCheckpt(wire.GCSFilterRegular, &wire.ShaHash{}, 0)
+	msgWTxIdRelay := wire.NewMsgWTxIdRelay()
 
 	tests := []struct {
 		in     wire.Message      // Value to encode
@@ -104,6 +111,13 @@ func TestMessage(t *testing.T) {
 		{msgFilterLoad, msgFilterLoad, pver, wire.MainNet, 35},
 		{msgMerkleBlock, msgMerkleBlock, pver, wire.MainNet, 110},
 		{msgReject, msgReject, pver, wire.MainNet, 79},
+		{msgGetCFilters, msgGetCFilters, pver, wire.MainNet, 61},
+		{msgCFilter, msgCFilter, pver, wire.MainNet, 59},
+		{msgGetCFHeaders, msgGetCFHeaders, pver, wire.MainNet, 61},
+		{msgCFHeaders, msgCFHeaders, pver, wire.MainNet, 90},
+		{msgGetCFCheckpt, msgGetCFCheckpt, pver, wire.MainNet, 57},
+		{msgCFCheckpt, msgCFCheckpt, pver, wire.MainNet, 58},
+		{msgWTxIdRelay, msgWTxIdRelay, pver, wire.MainNet, 24},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -32,7 +32,7 @@ func (msg *MsgAddr) AddAddress(na *NetAddress) error {
 	if len(msg.AddrList)+1 > MaxAddrPerMsg {
 		str := fmt.Sprintf("too many addresses in message [max %v]",
 			MaxAddrPerMsg)
-		return messageError("MsgAddr.AddAddress", str)
+		return limitError("MsgAddr.AddAddress", ErrTooManyElements, str)
 	}
 
 	msg.AddrList = append(msg.AddrList, na)
@@ -63,11 +63,13 @@ func (msg *MsgAddr) BtcDecode(r io.Reader, pver uint32) error {
 		return err
 	}
 
-	// Limit to max addresses per message.
-	if count > MaxAddrPerMsg {
-		str := fmt.Sprintf("too many addresses for message "+
-			"[count %v, max %v]", count, MaxAddrPerMsg)
-		return messageError("MsgAddr.BtcDecode", str)
+	// Limit to max addresses per message for the protocol version.
+	maxAddrs := maxAddrPerMsg(pver)
+	if count > maxAddrs {
+		str := fmt.Sprintf("too many addresses for message of "+
+			"protocol version %v [count %v, max %v]", pver, count,
+			maxAddrs)
+		return limitError("MsgAddr.BtcDecode", ErrTooManyElements, str)
 	}
 
 	msg.AddrList = make([]*NetAddress, 0, count)
@@ -91,13 +93,13 @@ func (msg *MsgAddr) BtcEncode(w io.Writer, pver uint32) error {
 	if pver < MultipleAddressVersion && count > 1 {
 		str := fmt.Sprintf("too many addresses for message of "+
 			"protocol version %v [count %v, max 1]", pver, count)
-		return messageError("MsgAddr.BtcEncode", str)
+		return limitError("MsgAddr.BtcEncode", ErrTooManyElements, str)
 
 	}
 	if count > MaxAddrPerMsg {
 		str := fmt.Sprintf("too many addresses for message "+
 			"[count %v, max %v]", count, MaxAddrPerMsg)
-		return messageError("MsgAddr.BtcEncode", str)
+		return limitError("MsgAddr.BtcEncode", ErrTooManyElements, str)
 	}
 
 	err := writeVarInt(w, pver, uint64(count))
@@ -133,6 +135,15 @@ func (msg *MsgAddr) MaxPayloadLength(pver uint32) uint32 {
 	return MaxVarIntPayload + (MaxAddrPerMsg * maxNetAddressPayload(pver))
 }
 
+// maxAddrPerMsg returns the maximum number of addresses an addr message may
+// contain at the passed protocol version.
+func maxAddrPerMsg(pver uint32) uint64 {
+	if pver < MultipleAddressVersion {
+		return 1
+	}
+	return MaxAddrPerMsg
+}
+
 // NewMsgAddr returns a new bitcoin addr message that conforms to the
 // Message interface.  See MsgAddr for details.
 func NewMsgAddr() *MsgAddr {
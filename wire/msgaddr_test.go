@@ -274,6 +274,9 @@ func TestAddrWireErrors(t *testing.T) {
 		// Force error with greater than max inventory vectors for
 		// protocol versions before multiple addresses were allowed.
 		{maxAddr, maxAddrEncoded, pverMA - 1, 3, wireErr, wireErr},
+		// Force error with more than one address for protocol versions
+		// before multiple addresses were allowed.
+		{baseAddr, baseAddrEncoded, pverMA - 1, 1, wireErr, wireErr},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -159,7 +159,7 @@ func (alert *Alert) Serialize(w io.Writer, pver uint32) error {
 	if count > maxCountSetCancel {
 		str := fmt.Sprintf("too many cancel alert IDs for alert "+
 			"[count %v, max %v]", count, maxCountSetCancel)
-		return messageError("Alert.Serialize", str)
+		return limitError("Alert.Serialize", ErrTooManyElements, str)
 	}
 	err = writeVarInt(w, pver, uint64(count))
 	if err != nil {
@@ -181,7 +181,7 @@ func (alert *Alert) Serialize(w io.Writer, pver uint32) error {
 	if count > maxCountSetSubVer {
 		str := fmt.Sprintf("too many sub versions for alert "+
 			"[count %v, max %v]", count, maxCountSetSubVer)
-		return messageError("Alert.Serialize", str)
+		return limitError("Alert.Serialize", ErrTooManyElements, str)
 	}
 	err = writeVarInt(w, pver, uint64(count))
 	if err != nil {
@@ -232,7 +232,7 @@ func (alert *Alert) Deserialize(r io.Reader, pver uint32) error {
 	if count > maxCountSetCancel {
 		str := fmt.Sprintf("too many cancel alert IDs for alert "+
 			"[count %v, max %v]", count, maxCountSetCancel)
-		return messageError("Alert.Deserialize", str)
+		return limitError("Alert.Deserialize", ErrTooManyElements, str)
 	}
 	alert.SetCancel = make([]int32, count)
 	for i := 0; i < int(count); i++ {
@@ -256,7 +256,7 @@ func (alert *Alert) Deserialize(r io.Reader, pver uint32) error {
 	if count > maxCountSetSubVer {
 		str := fmt.Sprintf("too many sub versions for alert "+
 			"[count %v, max %v]", count, maxCountSetSubVer)
-		return messageError("Alert.Deserialize", str)
+		return limitError("Alert.Deserialize", ErrTooManyElements, str)
 	}
 	alert.SetSubVer = make([]string, count)
 	for i := 0; i < int(count); i++ {
@@ -343,7 +343,7 @@ type MsgAlert struct {
 func (msg *MsgAlert) BtcDecode(r io.Reader, pver uint32) error {
 	var err error
 
-	msg.SerializedPayload, err = readVarBytes(r, pver, MaxMessagePayload,
+	msg.SerializedPayload, err = readVarBytes(r, pver, maxAlertSize,
 		"alert serialized payload")
 	if err != nil {
 		return err
@@ -354,7 +354,7 @@ func (msg *MsgAlert) BtcDecode(r io.Reader, pver uint32) error {
 		msg.Payload = nil
 	}
 
-	msg.Signature, err = readVarBytes(r, pver, MaxMessagePayload,
+	msg.Signature, err = readVarBytes(r, pver, maxSignatureSize,
 		"alert signature")
 	if err != nil {
 		return err
@@ -21,7 +21,10 @@ const defaultTransactionAlloc = 2048
 const MaxBlocksPerMsg = 500
 
 // MaxBlockPayload is the maximum bytes a block message can be in bytes.
-const MaxBlockPayload = 1000000 // Not actually 1MB which would be 1024 * 1024
+// After segregated witness, the limit on the size of a block without its
+// witness data is 1,000,000 bytes while the limit on its weight allows the
+// witness data to bring it up to this size.
+const MaxBlockPayload = 4000000
 
 // maxTxPerBlock is the maximum number of transactions that could
 // possibly fit into a block.
@@ -75,7 +78,7 @@ func (msg *MsgBlock) BtcDecode(r io.Reader, pver uint32) error {
 	if txCount > maxTxPerBlock {
 		str := fmt.Sprintf("too many transactions to fit into a block "+
 			"[count %d, max %d]", txCount, maxTxPerBlock)
-		return messageError("MsgBlock.BtcDecode", str)
+		return limitError("MsgBlock.BtcDecode", ErrTooManyElements, str)
 	}
 
 	msg.Transactions = make([]*MsgTx, 0, txCount)
@@ -133,7 +136,8 @@ func (msg *MsgBlock) DeserializeTxLoc(r *bytes.Buffer) ([]TxLoc, error) {
 	if txCount > maxTxPerBlock {
 		str := fmt.Sprintf("too many transactions to fit into a block "+
 			"[count %d, max %d]", txCount, maxTxPerBlock)
-		return nil, messageError("MsgBlock.DeserializeTxLoc", str)
+		return nil, limitError("MsgBlock.DeserializeTxLoc",
+			ErrTooManyElements, str)
 	}
 
 	// Deserialize each transaction while keeping track of its location
@@ -195,8 +199,32 @@ func (msg *MsgBlock) Serialize(w io.Writer) error {
 	return msg.BtcEncode(w, 0)
 }
 
+// SerializeNoWitness encodes the block to w in the same manner as Serialize,
+// but leaves out the witness data of all of the transactions.  This is the
+// serialization sent to peers which do not support segregated witness.
+func (msg *MsgBlock) SerializeNoWitness(w io.Writer) error {
+	err := writeBlockHeader(w, 0, &msg.Header)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, 0, uint64(len(msg.Transactions)))
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range msg.Transactions {
+		err = tx.SerializeNoWitness(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // SerializeSize returns the number of bytes it would take to serialize the
-// the block.
+// the block, including the witness data of its transactions.
 func (msg *MsgBlock) SerializeSize() int {
 	// Block header bytes + Serialized varint size for the number of
 	// transactions.
@@ -209,6 +237,20 @@ func (msg *MsgBlock) SerializeSize() int {
 	return n
 }
 
+// SerializeSizeStripped returns the number of bytes it would take to serialize
+// the block without the witness data of its transactions.
+func (msg *MsgBlock) SerializeSizeStripped() int {
+	// Block header bytes + Serialized varint size for the number of
+	// transactions.
+	n := blockHeaderLen + VarIntSerializeSize(uint64(len(msg.Transactions)))
+
+	for _, tx := range msg.Transactions {
+		n += tx.SerializeSizeStripped()
+	}
+
+	return n
+}
+
 // Command returns the protocol command string for the message.  This is part
 // of the Message interface implementation.
 func (msg *MsgBlock) Command() string {
@@ -36,7 +36,7 @@ func TestBlock(t *testing.T) {
 
 	// Ensure max payload is expected value for latest protocol version.
 	// Num addresses (varInt) + max allowed addresses.
-	wantPayload := uint32(1000000)
+	wantPayload := uint32(4000000)
 	maxPayload := msg.MaxPayloadLength(pver)
 	if maxPayload != wantPayload {
 		t.Errorf("MaxPayloadLength: wrong max payload length for "+
@@ -475,6 +475,53 @@ func TestBlockSerializeSize(t *testing.T) {
 	}
 }
 
+// TestBlockProofOfStake tests the serialization of proof-of-stake blocks, which
+// only differ from proof-of-work blocks by their version.
+func TestBlockProofOfStake(t *testing.T) {
+	posBlock := blockOne
+	posBlock.Header.Version |= wire.BlockVersionProofOfStake
+	posBlockBytes := append([]byte{}, blockOneBytes...)
+	posBlockBytes[3] = 0x08
+
+	if got := posBlock.Header.BlockType(); got != wire.BlockTypePoS {
+		t.Errorf("BlockType: got %v, want %v", got, wire.BlockTypePoS)
+	}
+	if got := blockOne.Header.BlockType(); got != wire.BlockTypePoW {
+		t.Errorf("BlockType: got %v, want %v", got, wire.BlockTypePoW)
+	}
+
+	var buf bytes.Buffer
+	if err := posBlock.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), posBlockBytes) {
+		t.Fatalf("Serialize\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(posBlockBytes))
+	}
+	if size := posBlock.SerializeSize(); size != len(posBlockBytes) {
+		t.Errorf("SerializeSize: got %d, want %d", size,
+			len(posBlockBytes))
+	}
+
+	var block wire.MsgBlock
+	if err := block.Deserialize(bytes.NewReader(posBlockBytes)); err != nil {
+		t.Fatalf("Deserialize: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&block, &posBlock) {
+		t.Fatalf("Deserialize\n got: %s want: %s", spew.Sdump(&block),
+			spew.Sdump(&posBlock))
+	}
+	var txLocBlock wire.MsgBlock
+	_, err := txLocBlock.DeserializeTxLoc(bytes.NewBuffer(posBlockBytes))
+	if err != nil {
+		t.Fatalf("DeserializeTxLoc: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&txLocBlock, &posBlock) {
+		t.Fatalf("DeserializeTxLoc\n got: %s want: %s",
+			spew.Sdump(&txLocBlock), spew.Sdump(&posBlock))
+	}
+}
+
 var blockOne = wire.MsgBlock{
 	Header: wire.BlockHeader{
 		Version: 1,
@@ -0,0 +1,136 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// CFCheckptInterval is the gap (in number of blocks) between each
+	// filter header checkpoint.
+	CFCheckptInterval = 1000
+
+	// maxCFCheckptsPerMsg is the maximum number of filter header
+	// checkpoints that fit in a single cfcheckpt message.
+	maxCFCheckptsPerMsg = (MaxMessagePayload - 1 - HashSize -
+		MaxVarIntPayload) / HashSize
+)
+
+// MsgCFCheckpt implements the Message interface and represents a bitcoin
+// cfcheckpt message.  It is used to deliver the filter header of every
+// CFCheckptInterval'th block up to StopHash in response to a getcfcheckpt
+// (MsgGetCFCheckpt) message.
+//
+// Use the AddCFHeader function to build up the list of filter headers.
+type MsgCFCheckpt struct {
+	FilterType    FilterType
+	StopHash      ShaHash
+	FilterHeaders []*ShaHash
+}
+
+// AddCFHeader adds a new filter header to the message.
+func (msg *MsgCFCheckpt) AddCFHeader(header *ShaHash) error {
+	if len(msg.FilterHeaders)+1 > maxCFCheckptsPerMsg {
+		str := fmt.Sprintf("too many filter headers in message [max %v]",
+			maxCFCheckptsPerMsg)
+		return limitError("MsgCFCheckpt.AddCFHeader",
+			ErrTooManyElements, str)
+	}
+
+	msg.FilterHeaders = append(msg.FilterHeaders, header)
+	return nil
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCFCheckpt) BtcDecode(r io.Reader, pver uint32) error {
+	err := readElements(r, &msg.FilterType, &msg.StopHash)
+	if err != nil {
+		return err
+	}
+
+	// Read number of filter headers and limit to max.
+	count, err := readVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxCFCheckptsPerMsg {
+		str := fmt.Sprintf("too many filter headers for message "+
+			"[count %v, max %v]", count, maxCFCheckptsPerMsg)
+		return limitError("MsgCFCheckpt.BtcDecode",
+			ErrTooManyElements, str)
+	}
+
+	// Create a contiguous slice of hashes to deserialize into in order to
+	// reduce the number of allocations.
+	headers := make([]ShaHash, count)
+	msg.FilterHeaders = make([]*ShaHash, 0, count)
+	for i := uint64(0); i < count; i++ {
+		header := &headers[i]
+		err := readElement(r, header)
+		if err != nil {
+			return err
+		}
+		msg.AddCFHeader(header)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCFCheckpt) BtcEncode(w io.Writer, pver uint32) error {
+	count := len(msg.FilterHeaders)
+	if count > maxCFCheckptsPerMsg {
+		str := fmt.Sprintf("too many filter headers for message "+
+			"[count %v, max %v]", count, maxCFCheckptsPerMsg)
+		return limitError("MsgCFCheckpt.BtcEncode",
+			ErrTooManyElements, str)
+	}
+
+	err := writeElements(w, msg.FilterType, &msg.StopHash)
+	if err != nil {
+		return err
+	}
+
+	err = writeVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, header := range msg.FilterHeaders {
+		err := writeElement(w, header)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCFCheckpt) Command() string {
+	return CmdCFCheckpt
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCFCheckpt) MaxPayloadLength(pver uint32) uint32 {
+	return MaxMessagePayload
+}
+
+// NewMsgCFCheckpt returns a new bitcoin cfcheckpt message that conforms to
+// the Message interface.  See MsgCFCheckpt for details.
+func NewMsgCFCheckpt(filterType FilterType, stopHash *ShaHash,
+	headersCount int) *MsgCFCheckpt {
+	return &MsgCFCheckpt{
+		FilterType:    filterType,
+		StopHash:      *stopHash,
+		FilterHeaders: make([]*ShaHash, 0, headersCount),
+	}
+}
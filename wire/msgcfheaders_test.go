@@ -0,0 +1,144 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestCFHeadersWire tests the filter header related messages wire encode and
+// decode against the latest protocol version.
+func TestCFHeadersWire(t *testing.T) {
+	pver := wire.ProtocolVersion
+
+	stopHash := wire.ShaHash{0x01}
+	prevHeader := wire.ShaHash{0x02}
+	filterHash := wire.ShaHash{0x03}
+
+	getHeaders := wire.NewMsgGetCFHeaders(wire.GCSFilterRegular, 1, &stopHash)
+	getHeadersEncoded := append([]byte{0x00, 0x01, 0x00, 0x00, 0x00},
+		stopHash[:]...)
+
+	headers := wire.NewMsgCFHeaders()
+	headers.StopHash = stopHash
+	headers.PrevFilterHeader = prevHeader
+	headers.AddCFHash(&filterHash)
+	headersEncoded := append([]byte{0x00}, stopHash[:]...)
+	headersEncoded = append(headersEncoded, prevHeader[:]...)
+	headersEncoded = append(headersEncoded, 0x01)
+	headersEncoded = append(headersEncoded, filterHash[:]...)
+
+	getCheckpt := wire.NewMsgGetCFCheckpt(wire.GCSFilterRegular, &stopHash)
+	getCheckptEncoded := append([]byte{0x00}, stopHash[:]...)
+
+	checkpt := wire.NewMsgCFCheckpt(wire.GCSFilterRegular, &stopHash, 2)
+	checkpt.AddCFHeader(&prevHeader)
+	checkpt.AddCFHeader(&filterHash)
+	checkptEncoded := append([]byte{0x00}, stopHash[:]...)
+	checkptEncoded = append(checkptEncoded, 0x02)
+	checkptEncoded = append(checkptEncoded, prevHeader[:]...)
+	checkptEncoded = append(checkptEncoded, filterHash[:]...)
+
+	tests := []struct {
+		in  wire.Message // Message to encode
+		out wire.Message // Empty message to decode into
+		buf []byte       // Wire encoding
+		cmd string       // Expected command
+	}{
+		{getHeaders, &wire.MsgGetCFHeaders{}, getHeadersEncoded,
+			"getcfheaders"},
+		{headers, &wire.MsgCFHeaders{}, headersEncoded, "cfheaders"},
+		{getCheckpt, &wire.MsgGetCFCheckpt{}, getCheckptEncoded,
+			"getcfcheckpt"},
+		{checkpt, &wire.MsgCFCheckpt{}, checkptEncoded, "cfcheckpt"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		if cmd := test.in.Command(); cmd != test.cmd {
+			t.Errorf("Command #%d: got %v want %v", i, cmd, test.cmd)
+			continue
+		}
+
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %x want: %x", i,
+				buf.Bytes(), test.buf)
+			continue
+		}
+
+		// Decode the message from wire format.
+		rbuf := bytes.NewReader(test.buf)
+		err = test.out.BtcDecode(rbuf, pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+
+		// The constructors preallocate capacity which decoding does
+		// not, so compare the re-encoded form.
+		var rebuf bytes.Buffer
+		if err := test.out.BtcEncode(&rebuf, pver); err != nil {
+			t.Errorf("BtcEncode decoded #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(rebuf.Bytes(), test.buf) {
+			t.Errorf("BtcDecode #%d\n got: %v want: %v", i,
+				test.out, test.in)
+			continue
+		}
+	}
+}
+
+// TestCFHeadersTooMany ensures cfheaders messages with more than the maximum
+// allowed number of filter hashes are rejected.
+func TestCFHeadersTooMany(t *testing.T) {
+	pver := wire.ProtocolVersion
+
+	msg := wire.NewMsgCFHeaders()
+	for i := 0; i < wire.MaxCFHeadersPerMsg; i++ {
+		if err := msg.AddCFHash(&wire.ShaHash{}); err != nil {
+			t.Fatalf("AddCFHash #%d: unexpected error %v", i, err)
+		}
+	}
+	if err := msg.AddCFHash(&wire.ShaHash{}); err == nil {
+		t.Errorf("AddCFHash: expected error adding hash past max")
+	}
+
+	// Encode a message which claims to hold one more than the max.
+	encoded := make([]byte, 1+wire.HashSize*2)
+	encoded = append(encoded, 0xfd, 0xd1, 0x07)
+	var readmsg wire.MsgCFHeaders
+	err := readmsg.BtcDecode(bytes.NewReader(encoded), pver)
+	if _, ok := err.(*wire.MessageError); !ok {
+		t.Errorf("BtcDecode: expected MessageError, got %v", err)
+	}
+
+	// Ensure the decoded form of a valid message matches.
+	valid := wire.NewMsgCFHeaders()
+	valid.AddCFHash(&wire.ShaHash{0x01})
+	var buf bytes.Buffer
+	if err := valid.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("BtcEncode: unexpected error %v", err)
+	}
+	readmsg = wire.MsgCFHeaders{}
+	if err := readmsg.BtcDecode(&buf, pver); err != nil {
+		t.Fatalf("BtcDecode: unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(readmsg.FilterHashes, valid.FilterHashes) {
+		t.Errorf("BtcDecode: got %v want %v", readmsg.FilterHashes,
+			valid.FilterHashes)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/stcd/wire"
+)
+
+// TestCFilterWire tests the MsgGetCFilters and MsgCFilter wire encode and
+// decode against the latest protocol version.
+func TestCFilterWire(t *testing.T) {
+	pver := wire.ProtocolVersion
+
+	stopHash := wire.ShaHash{0x01, 0x02}
+	getMsg := wire.NewMsgGetCFilters(wire.GCSFilterRegular, 0x0a0b, &stopHash)
+	getEncoded := append([]byte{0x00, 0x0b, 0x0a, 0x00, 0x00},
+		stopHash[:]...)
+
+	filterMsg := wire.NewMsgCFilter(wire.GCSFilterRegular, &stopHash,
+		[]byte{0x03, 0xde, 0xad})
+	filterEncoded := append([]byte{0x00}, stopHash[:]...)
+	filterEncoded = append(filterEncoded, 0x03, 0x03, 0xde, 0xad)
+
+	tests := []struct {
+		in   wire.Message // Message to encode
+		out  wire.Message // Empty message to decode into
+		buf  []byte       // Wire encoding
+		cmd  string       // Expected command
+		pmax uint32       // Expected max payload
+	}{
+		{getMsg, &wire.MsgGetCFilters{}, getEncoded, "getcfilters", 37},
+		{filterMsg, &wire.MsgCFilter{}, filterEncoded, "cfilter", 262182},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		if cmd := test.in.Command(); cmd != test.cmd {
+			t.Errorf("Command #%d: got %v want %v", i, cmd, test.cmd)
+			continue
+		}
+		if pmax := test.in.MaxPayloadLength(pver); pmax != test.pmax {
+			t.Errorf("MaxPayloadLength #%d: got %v want %v", i,
+				pmax, test.pmax)
+			continue
+		}
+
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BtcEncode(&buf, pver)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BtcEncode #%d\n got: %x want: %x", i,
+				buf.Bytes(), test.buf)
+			continue
+		}
+
+		// Decode the message from wire format.
+		rbuf := bytes.NewReader(test.buf)
+		err = test.out.BtcDecode(rbuf, pver)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.out, test.in) {
+			t.Errorf("BtcDecode #%d\n got: %v want: %v", i,
+				test.out, test.in)
+			continue
+		}
+	}
+}
+
+// TestCFilterOversized ensures filters larger than the maximum allowed size
+// are rejected in both directions.
+func TestCFilterOversized(t *testing.T) {
+	pver := wire.ProtocolVersion
+
+	data := make([]byte, wire.MaxCFilterDataSize+1)
+	msg := wire.NewMsgCFilter(wire.GCSFilterRegular, &wire.ShaHash{}, data)
+
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver)
+	if _, ok := err.(*wire.MessageError); !ok {
+		t.Errorf("BtcEncode: expected MessageError, got %v", err)
+	}
+
+	// Encode the oversized length prefix by hand since the message itself
+	// refuses to.
+	encoded := make([]byte, 1+wire.HashSize)
+	encoded = append(encoded, 0xfe, 0x01, 0x00, 0x04, 0x00)
+	var readmsg wire.MsgCFilter
+	err = readmsg.BtcDecode(bytes.NewReader(encoded), pver)
+	if _, ok := err.(*wire.MessageError); !ok {
+		t.Errorf("BtcDecode: expected MessageError, got %v", err)
+	}
+}
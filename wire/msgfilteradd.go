@@ -57,7 +57,8 @@ func (msg *MsgFilterAdd) BtcEncode(w io.Writer, pver uint32) error {
 	if size > MaxFilterAddDataSize {
 		str := fmt.Sprintf("filteradd size too large for message "+
 			"[size %v, max %v]", size, MaxFilterAddDataSize)
-		return messageError("MsgFilterAdd.BtcEncode", str)
+		return limitError("MsgFilterAdd.BtcEncode",
+			ErrElementTooLarge, str)
 	}
 
 	err := writeVarBytes(w, pver, msg.Data)
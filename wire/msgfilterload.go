@@ -73,7 +73,8 @@ func (msg *MsgFilterLoad) BtcDecode(r io.Reader, pver uint32) error {
 	if msg.HashFuncs > MaxFilterLoadHashFuncs {
 		str := fmt.Sprintf("too many filter hash functions for message "+
 			"[count %v, max %v]", msg.HashFuncs, MaxFilterLoadHashFuncs)
-		return messageError("MsgFilterLoad.BtcDecode", str)
+		return limitError("MsgFilterLoad.BtcDecode",
+			ErrTooManyElements, str)
 	}
 
 	return nil
@@ -92,13 +93,15 @@ func (msg *MsgFilterLoad) BtcEncode(w io.Writer, pver uint32) error {
 	if size > MaxFilterLoadFilterSize {
 		str := fmt.Sprintf("filterload filter size too large for message "+
 			"[size %v, max %v]", size, MaxFilterLoadFilterSize)
-		return messageError("MsgFilterLoad.BtcEncode", str)
+		return limitError("MsgFilterLoad.BtcEncode",
+			ErrElementTooLarge, str)
 	}
 
 	if msg.HashFuncs > MaxFilterLoadHashFuncs {
 		str := fmt.Sprintf("too many filter hash functions for message "+
 			"[count %v, max %v]", msg.HashFuncs, MaxFilterLoadHashFuncs)
-		return messageError("MsgFilterLoad.BtcEncode", str)
+		return limitError("MsgFilterLoad.BtcEncode",
+			ErrTooManyElements, str)
 	}
 
 	err := writeVarBytes(w, pver, msg.Filter)
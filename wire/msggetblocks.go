@@ -39,7 +39,8 @@ func (msg *MsgGetBlocks) AddBlockLocatorHash(hash *ShaHash) error {
 	if len(msg.BlockLocatorHashes)+1 > MaxBlockLocatorsPerMsg {
 		str := fmt.Sprintf("too many block locator hashes for message [max %v]",
 			MaxBlockLocatorsPerMsg)
-		return messageError("MsgGetBlocks.AddBlockLocatorHash", str)
+		return limitError("MsgGetBlocks.AddBlockLocatorHash",
+			ErrTooManyElements, str)
 	}
 
 	msg.BlockLocatorHashes = append(msg.BlockLocatorHashes, hash)
@@ -62,7 +63,8 @@ func (msg *MsgGetBlocks) BtcDecode(r io.Reader, pver uint32) error {
 	if count > MaxBlockLocatorsPerMsg {
 		str := fmt.Sprintf("too many block locator hashes for message "+
 			"[count %v, max %v]", count, MaxBlockLocatorsPerMsg)
-		return messageError("MsgGetBlocks.BtcDecode", str)
+		return limitError("MsgGetBlocks.BtcDecode",
+			ErrTooManyElements, str)
 	}
 
 	msg.BlockLocatorHashes = make([]*ShaHash, 0, count)
@@ -90,7 +92,8 @@ func (msg *MsgGetBlocks) BtcEncode(w io.Writer, pver uint32) error {
 	if count > MaxBlockLocatorsPerMsg {
 		str := fmt.Sprintf("too many block locator hashes for message "+
 			"[count %v, max %v]", count, MaxBlockLocatorsPerMsg)
-		return messageError("MsgGetBlocks.BtcEncode", str)
+		return limitError("MsgGetBlocks.BtcEncode",
+			ErrTooManyElements, str)
 	}
 
 	err := writeElement(w, msg.ProtocolVersion)
@@ -0,0 +1,69 @@
+// Copyright (c) 2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import "io"
+
+// MaxGetCFiltersReqRange is the maximum number of filters that may be
+// requested in a single getcfilters message.
+const MaxGetCFiltersReqRange = 1000
+
+// FilterType is used to represent a committed block filter type.
+type FilterType uint8
+
+const (
+	// GCSFilterRegular is the regular (basic) filter type which commits
+	// to the scripts created and spent by a block.
+	GCSFilterRegular FilterType = iota
+)
+
+// MsgGetCFilters implements the Message interface and represents a bitcoin
+// getcfilters message.  It is used to request committed filters for a range
+// of blocks starting at StartHeight and ending with the block identified by
+// StopHash.  Each filter is returned via a cfilter message (MsgCFilter).
+type MsgGetCFilters struct {
+	FilterType  FilterType
+	StartHeight uint32
+	StopHash    ShaHash
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCFilters) BtcDecode(r io.Reader, pver uint32) error {
+	return readElements(r, &msg.FilterType, &msg.StartHeight,
+		&msg.StopHash)
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCFilters) BtcEncode(w io.Writer, pver uint32) error {
+	return writeElements(w, msg.FilterType, msg.StartHeight,
+		&msg.StopHash)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetCFilters) Command() string {
+	return CmdGetCFilters
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetCFilters) MaxPayloadLength(pver uint32) uint32 {
+	// Filter type + uint32 + block hash
+	return 1 + 4 + HashSize
+}
+
+// NewMsgGetCFilters returns a new bitcoin getcfilters message that conforms
+// to the Message interface using the passed parameters and defaults for the
+// remaining fields.
+func NewMsgGetCFilters(filterType FilterType, startHeight uint32,
+	stopHash *ShaHash) *MsgGetCFilters {
+	return &MsgGetCFilters{
+		FilterType:  filterType,
+		StartHeight: startHeight,
+		StopHash:    *stopHash,
+	}
+}
@@ -28,7 +28,8 @@ func (msg *MsgGetData) AddInvVect(iv *InvVect) error {
 	if len(msg.InvList)+1 > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [max %v]",
 			MaxInvPerMsg)
-		return messageError("MsgGetData.AddInvVect", str)
+		return limitError("MsgGetData.AddInvVect",
+			ErrTooManyElements, str)
 	}
 
 	msg.InvList = append(msg.InvList, iv)
@@ -46,7 +47,8 @@ func (msg *MsgGetData) BtcDecode(r io.Reader, pver uint32) error {
 	// Limit to max inventory vectors per message.
 	if count > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [%v]", count)
-		return messageError("MsgGetData.BtcDecode", str)
+		return limitError("MsgGetData.BtcDecode",
+			ErrTooManyElements, str)
 	}
 
 	msg.InvList = make([]*InvVect, 0, count)
@@ -69,7 +71,8 @@ func (msg *MsgGetData) BtcEncode(w io.Writer, pver uint32) error {
 	count := len(msg.InvList)
 	if count > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [%v]", count)
-		return messageError("MsgGetData.BtcEncode", str)
+		return limitError("MsgGetData.BtcEncode",
+			ErrTooManyElements, str)
 	}
 
 	err := writeVarInt(w, pver, uint64(count))
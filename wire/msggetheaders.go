@@ -36,7 +36,8 @@ func (msg *MsgGetHeaders) AddBlockLocatorHash(hash *ShaHash) error {
 	if len(msg.BlockLocatorHashes)+1 > MaxBlockLocatorsPerMsg {
 		str := fmt.Sprintf("too many block locator hashes for message [max %v]",
 			MaxBlockLocatorsPerMsg)
-		return messageError("MsgGetHeaders.AddBlockLocatorHash", str)
+		return limitError("MsgGetHeaders.AddBlockLocatorHash",
+			ErrTooManyElements, str)
 	}
 
 	msg.BlockLocatorHashes = append(msg.BlockLocatorHashes, hash)
@@ -59,7 +60,8 @@ func (msg *MsgGetHeaders) BtcDecode(r io.Reader, pver uint32) error {
 	if count > MaxBlockLocatorsPerMsg {
 		str := fmt.Sprintf("too many block locator hashes for message "+
 			"[count %v, max %v]", count, MaxBlockLocatorsPerMsg)
-		return messageError("MsgGetHeaders.BtcDecode", str)
+		return limitError("MsgGetHeaders.BtcDecode",
+			ErrTooManyElements, str)
 	}
 
 	msg.BlockLocatorHashes = make([]*ShaHash, 0, count)
@@ -88,7 +90,8 @@ func (msg *MsgGetHeaders) BtcEncode(w io.Writer, pver uint32) error {
 	if count > MaxBlockLocatorsPerMsg {
 		str := fmt.Sprintf("too many block locator hashes for message "+
 			"[count %v, max %v]", count, MaxBlockLocatorsPerMsg)
-		return messageError("MsgGetHeaders.BtcEncode", str)
+		return limitError("MsgGetHeaders.BtcEncode",
+			ErrTooManyElements, str)
 	}
 
 	err := writeElement(w, msg.ProtocolVersion)
@@ -27,7 +27,8 @@ func (msg *MsgHeaders) AddBlockHeader(bh *BlockHeader) error {
 	if len(msg.Headers)+1 > MaxBlockHeadersPerMsg {
 		str := fmt.Sprintf("too many block headers in message [max %v]",
 			MaxBlockHeadersPerMsg)
-		return messageError("MsgHeaders.AddBlockHeader", str)
+		return limitError("MsgHeaders.AddBlockHeader",
+			ErrTooManyElements, str)
 	}
 
 	msg.Headers = append(msg.Headers, bh)
@@ -46,7 +47,8 @@ func (msg *MsgHeaders) BtcDecode(r io.Reader, pver uint32) error {
 	if count > MaxBlockHeadersPerMsg {
 		str := fmt.Sprintf("too many block headers for message "+
 			"[count %v, max %v]", count, MaxBlockHeadersPerMsg)
-		return messageError("MsgHeaders.BtcDecode", str)
+		return limitError("MsgHeaders.BtcDecode",
+			ErrTooManyElements, str)
 	}
 
 	msg.Headers = make([]*BlockHeader, 0, count)
@@ -82,7 +84,8 @@ func (msg *MsgHeaders) BtcEncode(w io.Writer, pver uint32) error {
 	if count > MaxBlockHeadersPerMsg {
 		str := fmt.Sprintf("too many block headers for message "+
 			"[count %v, max %v]", count, MaxBlockHeadersPerMsg)
-		return messageError("MsgHeaders.BtcEncode", str)
+		return limitError("MsgHeaders.BtcEncode",
+			ErrTooManyElements, str)
 	}
 
 	err := writeVarInt(w, pver, uint64(count))
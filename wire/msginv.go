@@ -36,7 +36,7 @@ func (msg *MsgInv) AddInvVect(iv *InvVect) error {
 	if len(msg.InvList)+1 > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [max %v]",
 			MaxInvPerMsg)
-		return messageError("MsgInv.AddInvVect", str)
+		return limitError("MsgInv.AddInvVect", ErrTooManyElements, str)
 	}
 
 	msg.InvList = append(msg.InvList, iv)
@@ -54,7 +54,7 @@ func (msg *MsgInv) BtcDecode(r io.Reader, pver uint32) error {
 	// Limit to max inventory vectors per message.
 	if count > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [%v]", count)
-		return messageError("MsgInv.BtcDecode", str)
+		return limitError("MsgInv.BtcDecode", ErrTooManyElements, str)
 	}
 
 	msg.InvList = make([]*InvVect, 0, count)
@@ -77,7 +77,7 @@ func (msg *MsgInv) BtcEncode(w io.Writer, pver uint32) error {
 	count := len(msg.InvList)
 	if count > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [%v]", count)
-		return messageError("MsgInv.BtcEncode", str)
+		return limitError("MsgInv.BtcEncode", ErrTooManyElements, str)
 	}
 
 	err := writeVarInt(w, pver, uint64(count))
@@ -31,7 +31,8 @@ func (msg *MsgMerkleBlock) AddTxHash(hash *ShaHash) error {
 	if len(msg.Hashes)+1 > maxTxPerBlock {
 		str := fmt.Sprintf("too many tx hashes for message [max %v]",
 			maxTxPerBlock)
-		return messageError("MsgMerkleBlock.AddTxHash", str)
+		return limitError("MsgMerkleBlock.AddTxHash",
+			ErrTooManyElements, str)
 	}
 
 	msg.Hashes = append(msg.Hashes, hash)
@@ -65,7 +66,8 @@ func (msg *MsgMerkleBlock) BtcDecode(r io.Reader, pver uint32) error {
 	if count > maxTxPerBlock {
 		str := fmt.Sprintf("too many transaction hashes for message "+
 			"[count %v, max %v]", count, maxTxPerBlock)
-		return messageError("MsgMerkleBlock.BtcDecode", str)
+		return limitError("MsgMerkleBlock.BtcDecode",
+			ErrTooManyElements, str)
 	}
 
 	msg.Hashes = make([]*ShaHash, 0, count)
@@ -101,13 +103,15 @@ func (msg *MsgMerkleBlock) BtcEncode(w io.Writer, pver uint32) error {
 	if numHashes > maxTxPerBlock {
 		str := fmt.Sprintf("too many transaction hashes for message "+
 			"[count %v, max %v]", numHashes, maxTxPerBlock)
-		return messageError("MsgMerkleBlock.BtcDecode", str)
+		return limitError("MsgMerkleBlock.BtcDecode",
+			ErrTooManyElements, str)
 	}
 	numFlagBytes := len(msg.Flags)
 	if numFlagBytes > maxFlagsPerMerkleBlock {
 		str := fmt.Sprintf("too many flag bytes for message [count %v, "+
 			"max %v]", numFlagBytes, maxFlagsPerMerkleBlock)
-		return messageError("MsgMerkleBlock.BtcDecode", str)
+		return limitError("MsgMerkleBlock.BtcDecode",
+			ErrTooManyElements, str)
 	}
 
 	err := writeBlockHeader(w, pver, &msg.Header)
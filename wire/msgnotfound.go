@@ -25,7 +25,8 @@ func (msg *MsgNotFound) AddInvVect(iv *InvVect) error {
 	if len(msg.InvList)+1 > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [max %v]",
 			MaxInvPerMsg)
-		return messageError("MsgNotFound.AddInvVect", str)
+		return limitError("MsgNotFound.AddInvVect",
+			ErrTooManyElements, str)
 	}
 
 	msg.InvList = append(msg.InvList, iv)
@@ -43,7 +44,8 @@ func (msg *MsgNotFound) BtcDecode(r io.Reader, pver uint32) error {
 	// Limit to max inventory vectors per message.
 	if count > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [%v]", count)
-		return messageError("MsgNotFound.BtcDecode", str)
+		return limitError("MsgNotFound.BtcDecode",
+			ErrTooManyElements, str)
 	}
 
 	msg.InvList = make([]*InvVect, 0, count)
@@ -66,7 +68,8 @@ func (msg *MsgNotFound) BtcEncode(w io.Writer, pver uint32) error {
 	count := len(msg.InvList)
 	if count > MaxInvPerMsg {
 		str := fmt.Sprintf("too many invvect in message [%v]", count)
-		return messageError("MsgNotFound.BtcEncode", str)
+		return limitError("MsgNotFound.BtcEncode",
+			ErrTooManyElements, str)
 	}
 
 	err := writeVarInt(w, pver, uint64(count))
@@ -79,7 +79,7 @@ func (msg *MsgReject) BtcDecode(r io.Reader, pver uint32) error {
 	}
 
 	// Command that was rejected.
-	cmd, err := ReadVarString(r, pver)
+	cmd, err := readVarString(r, pver, CommandSize, "rejected command")
 	if err != nil {
 		return err
 	}
@@ -23,6 +23,15 @@ const (
 	// MaxPrevOutIndex is the maximum index the index field of a previous
 	// outpoint can be.
 	MaxPrevOutIndex uint32 = 0xffffffff
+
+	// TxFlagMarker is the first byte of the flag field used to indicate a
+	// transaction is serialized with witness data.  It takes the place of
+	// the input count, which can never be zero for a valid transaction.
+	TxFlagMarker = 0x00
+
+	// WitnessFlag is the flag which follows the marker to indicate the
+	// transaction is serialized with witness data (BIP0144).
+	WitnessFlag = 0x01
 )
 
 // defaultTxInOutAlloc is the default size used for the backing array for
@@ -39,16 +48,18 @@ const (
 	minTxInPayload = 9 + HashSize
 
 	// maxTxInPerMessage is the maximum number of transactions inputs that
-	// a transaction which fits into a message could possibly have.
-	maxTxInPerMessage = (MaxMessagePayload / minTxInPayload) + 1
+	// a transaction which fits into a message could possibly have.  A
+	// transaction is never larger than a block.
+	maxTxInPerMessage = (MaxBlockPayload / minTxInPayload) + 1
 
 	// minTxOutPayload is the minimum payload size for a transaction output.
 	// Value 8 bytes + Varint for PkScript length 1 byte.
 	minTxOutPayload = 9
 
 	// maxTxOutPerMessage is the maximum number of transactions outputs that
-	// a transaction which fits into a message could possibly have.
-	maxTxOutPerMessage = (MaxMessagePayload / minTxOutPayload) + 1
+	// a transaction which fits into a message could possibly have.  A
+	// transaction is never larger than a block.
+	maxTxOutPerMessage = (MaxBlockPayload / minTxOutPayload) + 1
 
 	// minTxPayload is the minimum payload size for a transaction.  Note
 	// that any realistically usable transaction must have at least one
@@ -58,6 +69,22 @@ const (
 	// number of transaction outputs 1 byte + LockTime 4 bytes + min input
 	// payload + min output payload.
 	minTxPayload = 10
+
+	// maxWitnessItemsPerInput is the maximum number of witness items that
+	// can be read for a single input.  Each item needs at least one byte
+	// for its length, so no more than this can fit into a block.
+	maxWitnessItemsPerInput = 500000
+
+	// maxWitnessItemSize is the maximum number of bytes allowed for a
+	// single witness item.  The largest items are witness scripts, which
+	// are limited to 10,000 bytes by the script engine, so this is a
+	// generous upper bound.
+	maxWitnessItemSize = 11000
+
+	// maxScriptSize is the maximum number of bytes allowed for a signature
+	// or public key script.  Public key scripts aren't limited by the
+	// consensus rules other than by having to fit into a block.
+	maxScriptSize = MaxBlockPayload
 )
 
 // OutPoint defines a bitcoin data type that is used to track previous
@@ -91,15 +118,34 @@ func (o OutPoint) String() string {
 	return string(buf)
 }
 
+// TxWitness defines the witness for a transaction input.  It is a stack of
+// data items which is evaluated in place of the signature script when the
+// input spends a witness program (BIP0141).
+type TxWitness [][]byte
+
+// SerializeSize returns the number of bytes it would take to serialize the
+// the witness.
+func (t TxWitness) SerializeSize() int {
+	// Serialized varint size for the number of items + serialized varint
+	// size for the length of each item + item bytes.
+	n := VarIntSerializeSize(uint64(len(t)))
+	for _, item := range t {
+		n += VarIntSerializeSize(uint64(len(item))) + len(item)
+	}
+	return n
+}
+
 // TxIn defines a bitcoin transaction input.
 type TxIn struct {
 	PreviousOutPoint OutPoint
 	SignatureScript  []byte
+	Witness          TxWitness
 	Sequence         uint32
 }
 
 // SerializeSize returns the number of bytes it would take to serialize the
-// the transaction input.
+// the transaction input.  The witness is not included since it is serialized
+// separately from the input.
 func (t *TxIn) SerializeSize() int {
 	// Outpoint Hash 32 bytes + Outpoint Index 4 bytes + Sequence 4 bytes +
 	// serialized varint size for the length of SignatureScript +
@@ -165,17 +211,42 @@ func (msg *MsgTx) AddTxOut(to *TxOut) {
 	msg.TxOut = append(msg.TxOut, to)
 }
 
-// TxSha generates the ShaHash name for the transaction.
+// TxSha generates the ShaHash name for the transaction.  The witness data is
+// not included, so the hash stays the same when only the witness changes.
 func (msg *MsgTx) TxSha() ShaHash {
 	// Encode the transaction and calculate double sha256 on the result.
 	// Ignore the error returns since the only way the encode could fail
 	// is being out of memory or due to nil pointers, both of which would
 	// cause a run-time panic.
+	buf := bytes.NewBuffer(make([]byte, 0, msg.SerializeSizeStripped()))
+	_ = msg.SerializeNoWitness(buf)
+	return DoubleSha256SH(buf.Bytes())
+}
+
+// WitnessHash generates the hash of the transaction serialized with its
+// witness data, which is also known as the wtxid.  It is the same as TxSha
+// when the transaction does not have any witness data.
+func (msg *MsgTx) WitnessHash() ShaHash {
+	if !msg.HasWitness() {
+		return msg.TxSha()
+	}
+
 	buf := bytes.NewBuffer(make([]byte, 0, msg.SerializeSize()))
 	_ = msg.Serialize(buf)
 	return DoubleSha256SH(buf.Bytes())
 }
 
+// HasWitness returns whether or not any of the transaction inputs have
+// witness data.
+func (msg *MsgTx) HasWitness() bool {
+	for _, txIn := range msg.TxIn {
+		if len(txIn.Witness) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // Copy creates a deep copy of a transaction so that the original does not get
 // modified when the copy is manipulated.
 func (msg *MsgTx) Copy() *MsgTx {
@@ -205,11 +276,23 @@ func (msg *MsgTx) Copy() *MsgTx {
 			copy(newScript, oldScript[:oldScriptLen])
 		}
 
+		// Deep copy the old witness.
+		var newWitness TxWitness
+		if len(oldTxIn.Witness) != 0 {
+			newWitness = make(TxWitness, len(oldTxIn.Witness))
+			for i, oldItem := range oldTxIn.Witness {
+				newItem := make([]byte, len(oldItem))
+				copy(newItem, oldItem)
+				newWitness[i] = newItem
+			}
+		}
+
 		// Create new txIn with the deep copied data and append it to
 		// new Tx.
 		newTxIn := TxIn{
 			PreviousOutPoint: newOutPoint,
 			SignatureScript:  newScript,
+			Witness:          newWitness,
 			Sequence:         oldTxIn.Sequence,
 		}
 		newTx.TxIn = append(newTx.TxIn, &newTxIn)
@@ -239,22 +322,46 @@ func (msg *MsgTx) Copy() *MsgTx {
 }
 
 // BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// Transactions serialized both with and without witness data are accepted.
 // This is part of the Message interface implementation.
 // See Deserialize for decoding transactions stored to disk, such as in a
 // database, as opposed to decoding transactions from the wire.
 func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32) error {
-	var buf [4]byte
-	_, err := io.ReadFull(r, buf[:])
+	buf := scratchBuffers.Borrow()[:4]
+	defer scratchBuffers.Return(buf)
+
+	_, err := io.ReadFull(r, buf)
 	if err != nil {
 		return err
 	}
-	msg.Version = int32(binary.LittleEndian.Uint32(buf[:]))
+	msg.Version = int32(binary.LittleEndian.Uint32(buf))
 
 	count, err := readVarInt(r, pver)
 	if err != nil {
 		return err
 	}
 
+	// A zero input count is the marker for a transaction serialized with
+	// witness data when it is followed by the witness flag.  Otherwise, it
+	// is a transaction without any inputs and the byte that was read is
+	// the start of the output count.
+	var hasWitness bool
+	if count == TxFlagMarker {
+		var flag [1]byte
+		if _, err := io.ReadFull(r, flag[:]); err != nil {
+			return err
+		}
+		if flag[0] == WitnessFlag {
+			hasWitness = true
+			count, err = readVarInt(r, pver)
+			if err != nil {
+				return err
+			}
+		} else {
+			r = io.MultiReader(bytes.NewReader(flag[:]), r)
+		}
+	}
+
 	// Prevent more input transactions than could possibly fit into a
 	// message.  It would be possible to cause memory exhaustion and panics
 	// without a sane upper bound on this count.
@@ -262,7 +369,7 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32) error {
 		str := fmt.Sprintf("too many input transactions to fit into "+
 			"max message size [count %d, max %d]", count,
 			maxTxInPerMessage)
-		return messageError("MsgTx.BtcDecode", str)
+		return limitError("MsgTx.BtcDecode", ErrTooManyElements, str)
 	}
 
 	msg.TxIn = make([]*TxIn, count)
@@ -287,7 +394,7 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32) error {
 		str := fmt.Sprintf("too many output transactions to fit into "+
 			"max message size [count %d, max %d]", count,
 			maxTxOutPerMessage)
-		return messageError("MsgTx.BtcDecode", str)
+		return limitError("MsgTx.BtcDecode", ErrTooManyElements, str)
 	}
 
 	msg.TxOut = make([]*TxOut, count)
@@ -300,11 +407,27 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32) error {
 		msg.TxOut[i] = &to
 	}
 
-	_, err = io.ReadFull(r, buf[:])
+	// The witness for each input follows the outputs.  A witness
+	// serialization without any witness data is not allowed since the
+	// transaction must then be serialized without it.
+	if hasWitness {
+		for _, ti := range msg.TxIn {
+			ti.Witness, err = readTxWitness(r, pver)
+			if err != nil {
+				return err
+			}
+		}
+		if !msg.HasWitness() {
+			return messageError("MsgTx.BtcDecode", "transaction "+
+				"is serialized with witness data, but has none")
+		}
+	}
+
+	_, err = io.ReadFull(r, buf)
 	if err != nil {
 		return err
 	}
-	msg.LockTime = binary.LittleEndian.Uint32(buf[:])
+	msg.LockTime = binary.LittleEndian.Uint32(buf)
 
 	return nil
 }
@@ -327,10 +450,17 @@ func (msg *MsgTx) Deserialize(r io.Reader) error {
 }
 
 // BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// The witness data is included when the transaction has any.
 // This is part of the Message interface implementation.
 // See Serialize for encoding transactions to be stored to disk, such as in a
 // database, as opposed to encoding transactions for the wire.
 func (msg *MsgTx) BtcEncode(w io.Writer, pver uint32) error {
+	return msg.encode(w, pver, msg.HasWitness())
+}
+
+// encode encodes the transaction to w using the bitcoin protocol encoding
+// either with or without its witness data.
+func (msg *MsgTx) encode(w io.Writer, pver uint32, withWitness bool) error {
 	var buf [4]byte
 	binary.LittleEndian.PutUint32(buf[:], uint32(msg.Version))
 	_, err := w.Write(buf[:])
@@ -338,6 +468,13 @@ func (msg *MsgTx) BtcEncode(w io.Writer, pver uint32) error {
 		return err
 	}
 
+	if withWitness {
+		_, err = w.Write([]byte{TxFlagMarker, WitnessFlag})
+		if err != nil {
+			return err
+		}
+	}
+
 	count := uint64(len(msg.TxIn))
 	err = writeVarInt(w, pver, count)
 	if err != nil {
@@ -364,6 +501,15 @@ func (msg *MsgTx) BtcEncode(w io.Writer, pver uint32) error {
 		}
 	}
 
+	if withWitness {
+		for _, ti := range msg.TxIn {
+			err = writeTxWitness(w, pver, ti.Witness)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	binary.LittleEndian.PutUint32(buf[:], msg.LockTime)
 	_, err = w.Write(buf[:])
 	if err != nil {
@@ -391,9 +537,31 @@ func (msg *MsgTx) Serialize(w io.Writer) error {
 
 }
 
+// SerializeNoWitness encodes the transaction to w in the same manner as
+// Serialize, but leaves out any witness data.  This is the serialization the
+// transaction hash and legacy signature hashes are calculated over.
+func (msg *MsgTx) SerializeNoWitness(w io.Writer) error {
+	return msg.encode(w, 0, false)
+}
+
 // SerializeSize returns the number of bytes it would take to serialize the
-// the transaction.
+// the transaction, including its witness data if it has any.
 func (msg *MsgTx) SerializeSize() int {
+	n := msg.SerializeSizeStripped()
+	if msg.HasWitness() {
+		// Marker and flag 2 bytes + witness for each input.
+		n += 2
+		for _, txIn := range msg.TxIn {
+			n += txIn.Witness.SerializeSize()
+		}
+	}
+
+	return n
+}
+
+// SerializeSizeStripped returns the number of bytes it would take to serialize
+// the transaction without its witness data.
+func (msg *MsgTx) SerializeSizeStripped() int {
 	// Version 4 bytes + LockTime 4 bytes + Serialized varint size for the
 	// number of transaction inputs and outputs.
 	n := 8 + VarIntSerializeSize(uint64(len(msg.TxIn))) +
@@ -435,11 +603,14 @@ func (msg *MsgTx) PkScriptLocs() []int {
 	// The starting offset in the serialized transaction of the first
 	// transaction output is:
 	//
-	// Version 4 bytes + serialized varint size for the number of
-	// transaction inputs and outputs + serialized size of each transaction
-	// input.
+	// Version 4 bytes + marker and flag 2 bytes when there is witness
+	// data + serialized varint size for the number of transaction inputs
+	// and outputs + serialized size of each transaction input.
 	n := 4 + VarIntSerializeSize(uint64(len(msg.TxIn))) +
 		VarIntSerializeSize(uint64(numTxOut))
+	if msg.HasWitness() {
+		n += 2
+	}
 	for _, txIn := range msg.TxIn {
 		n += txIn.SerializeSize()
 	}
@@ -479,12 +650,14 @@ func readOutPoint(r io.Reader, pver uint32, version int32, op *OutPoint) error {
 		return err
 	}
 
-	var buf [4]byte
-	_, err = io.ReadFull(r, buf[:])
+	buf := scratchBuffers.Borrow()[:4]
+	defer scratchBuffers.Return(buf)
+
+	_, err = io.ReadFull(r, buf)
 	if err != nil {
 		return err
 	}
-	op.Index = binary.LittleEndian.Uint32(buf[:])
+	op.Index = binary.LittleEndian.Uint32(buf)
 	return nil
 }
 
@@ -508,25 +681,25 @@ func writeOutPoint(w io.Writer, pver uint32, version int32, op *OutPoint) error
 // readTxIn reads the next sequence of bytes from r as a transaction input
 // (TxIn).
 func readTxIn(r io.Reader, pver uint32, version int32, ti *TxIn) error {
-	var op OutPoint
-	err := readOutPoint(r, pver, version, &op)
+	err := readOutPoint(r, pver, version, &ti.PreviousOutPoint)
 	if err != nil {
 		return err
 	}
-	ti.PreviousOutPoint = op
 
-	ti.SignatureScript, err = readVarBytes(r, pver, MaxMessagePayload,
+	ti.SignatureScript, err = readVarBytes(r, pver, maxScriptSize,
 		"transaction input signature script")
 	if err != nil {
 		return err
 	}
 
-	var buf [4]byte
-	_, err = io.ReadFull(r, buf[:])
+	buf := scratchBuffers.Borrow()[:4]
+	defer scratchBuffers.Return(buf)
+
+	_, err = io.ReadFull(r, buf)
 	if err != nil {
 		return err
 	}
-	ti.Sequence = binary.LittleEndian.Uint32(buf[:])
+	ti.Sequence = binary.LittleEndian.Uint32(buf)
 
 	return nil
 }
@@ -557,14 +730,16 @@ func writeTxIn(w io.Writer, pver uint32, version int32, ti *TxIn) error {
 // readTxOut reads the next sequence of bytes from r as a transaction output
 // (TxOut).
 func readTxOut(r io.Reader, pver uint32, version int32, to *TxOut) error {
-	var buf [8]byte
-	_, err := io.ReadFull(r, buf[:])
+	buf := scratchBuffers.Borrow()
+	defer scratchBuffers.Return(buf)
+
+	_, err := io.ReadFull(r, buf)
 	if err != nil {
 		return err
 	}
-	to.Value = int64(binary.LittleEndian.Uint64(buf[:]))
+	to.Value = int64(binary.LittleEndian.Uint64(buf))
 
-	to.PkScript, err = readVarBytes(r, pver, MaxMessagePayload,
+	to.PkScript, err = readVarBytes(r, pver, maxScriptSize,
 		"transaction output public key script")
 	if err != nil {
 		return err
@@ -589,3 +764,50 @@ func writeTxOut(w io.Writer, pver uint32, version int32, to *TxOut) error {
 	}
 	return nil
 }
+
+// readTxWitness reads the next sequence of bytes from r as the witness for a
+// transaction input.
+func readTxWitness(r io.Reader, pver uint32) (TxWitness, error) {
+	count, err := readVarInt(r, pver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prevent more witness items than could possibly fit into a block.
+	// It would be possible to cause memory exhaustion and panics without
+	// a sane upper bound on this count.
+	if count > maxWitnessItemsPerInput {
+		str := fmt.Sprintf("too many witness items to fit into max "+
+			"message size [count %d, max %d]", count,
+			maxWitnessItemsPerInput)
+		return nil, limitError("readTxWitness", ErrTooManyElements, str)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	witness := make(TxWitness, count)
+	for i := uint64(0); i < count; i++ {
+		witness[i], err = readVarBytes(r, pver, maxWitnessItemSize,
+			"transaction input witness item")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return witness, nil
+}
+
+// writeTxWitness encodes the witness for a transaction input to w.
+func writeTxWitness(w io.Writer, pver uint32, witness TxWitness) error {
+	err := writeVarInt(w, pver, uint64(len(witness)))
+	if err != nil {
+		return err
+	}
+	for _, item := range witness {
+		err = writeVarBytes(w, pver, item)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
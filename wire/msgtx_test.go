@@ -36,7 +36,7 @@ func TestTx(t *testing.T) {
 
 	// Ensure max payload is expected value for latest protocol version.
 	// Num addresses (varInt) + max allowed addresses.
-	wantPayload := uint32(1000 * 1000)
+	wantPayload := uint32(4000 * 1000)
 	maxPayload := msg.MaxPayloadLength(pver)
 	if maxPayload != wantPayload {
 		t.Errorf("MaxPayloadLength: wrong max payload length for "+
@@ -176,6 +176,137 @@ func TestTxSha(t *testing.T) {
 	}
 }
 
+// TestTxWitness tests the serialization of transactions with witness data and
+// the hashes calculated over them.
+func TestTxWitness(t *testing.T) {
+	witnessTx := &wire.MsgTx{
+		Version: 1,
+		TxIn: []*wire.TxIn{
+			{
+				PreviousOutPoint: wire.OutPoint{
+					Hash:  wire.ShaHash{0x01},
+					Index: 1,
+				},
+				SignatureScript: []byte{},
+				Witness:         wire.TxWitness{{0x01, 0x02}, {0x03}},
+				Sequence:        0xffffffff,
+			},
+		},
+		TxOut: []*wire.TxOut{
+			{
+				Value:    0x1234,
+				PkScript: []byte{0x00, 0x01, 0xaa},
+			},
+		},
+		LockTime: 0,
+	}
+	strippedEncoded := []byte{
+		0x01, 0x00, 0x00, 0x00, // Version
+		0x01, // Varint for number of input transactions
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Previous output hash
+		0x01, 0x00, 0x00, 0x00, // Prevous output index
+		0x00,                   // Varint for length of signature script
+		0xff, 0xff, 0xff, 0xff, // Sequence
+		0x01,                                           // Varint for number of output transactions
+		0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Transaction amount
+		0x03,             // Varint for length of pk script
+		0x00, 0x01, 0xaa, // Pk script
+		0x00, 0x00, 0x00, 0x00, // Lock time
+	}
+	witnessEncoded := make([]byte, 0, len(strippedEncoded)+10)
+	witnessEncoded = append(witnessEncoded, strippedEncoded[:4]...)
+	witnessEncoded = append(witnessEncoded, 0x00, 0x01) // Marker and flag
+	witnessEncoded = append(witnessEncoded, strippedEncoded[4:len(strippedEncoded)-4]...)
+	witnessEncoded = append(witnessEncoded,
+		0x02,             // Varint for number of witness items
+		0x02, 0x01, 0x02, // First witness item
+		0x01, 0x03, // Second witness item
+	)
+	witnessEncoded = append(witnessEncoded, 0x00, 0x00, 0x00, 0x00)
+
+	// Ensure the witness is included in the serialization and sizes.
+	var buf bytes.Buffer
+	if err := witnessTx.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), witnessEncoded) {
+		t.Errorf("Serialize\n got: %s want: %s", spew.Sdump(buf.Bytes()),
+			spew.Sdump(witnessEncoded))
+	}
+	if size := witnessTx.SerializeSize(); size != len(witnessEncoded) {
+		t.Errorf("SerializeSize: got %d, want %d", size,
+			len(witnessEncoded))
+	}
+
+	// Ensure the witness is left out of the stripped serialization.
+	buf.Reset()
+	if err := witnessTx.SerializeNoWitness(&buf); err != nil {
+		t.Fatalf("SerializeNoWitness: unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), strippedEncoded) {
+		t.Errorf("SerializeNoWitness\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(strippedEncoded))
+	}
+	if size := witnessTx.SerializeSizeStripped(); size != len(strippedEncoded) {
+		t.Errorf("SerializeSizeStripped: got %d, want %d", size,
+			len(strippedEncoded))
+	}
+
+	// Ensure the transaction hash does not commit to the witness while the
+	// witness hash does.
+	wantHash := wire.DoubleSha256SH(strippedEncoded)
+	if txHash := witnessTx.TxSha(); txHash != wantHash {
+		t.Errorf("TxSha: got %v, want %v", txHash, wantHash)
+	}
+	wantWitnessHash := wire.DoubleSha256SH(witnessEncoded)
+	if hash := witnessTx.WitnessHash(); hash != wantWitnessHash {
+		t.Errorf("WitnessHash: got %v, want %v", hash, wantWitnessHash)
+	}
+
+	// Ensure both serializations decode to the expected transaction.
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(witnessEncoded)); err != nil {
+		t.Fatalf("Deserialize: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&tx, witnessTx) {
+		t.Errorf("Deserialize\n got: %s want: %s", spew.Sdump(&tx),
+			spew.Sdump(witnessTx))
+	}
+	var strippedTx wire.MsgTx
+	err := strippedTx.Deserialize(bytes.NewReader(strippedEncoded))
+	if err != nil {
+		t.Fatalf("Deserialize: unexpected error: %v", err)
+	}
+	if strippedTx.HasWitness() {
+		t.Errorf("Deserialize: stripped transaction has witness")
+	}
+	if strippedTx.TxSha() != wantHash {
+		t.Errorf("Deserialize: stripped transaction has hash %v, "+
+			"want %v", strippedTx.TxSha(), wantHash)
+	}
+
+	// Ensure a copy keeps the witness.
+	txCopy := witnessTx.Copy()
+	if !reflect.DeepEqual(txCopy.TxIn[0].Witness, witnessTx.TxIn[0].Witness) {
+		t.Errorf("Copy\n got: %s want: %s",
+			spew.Sdump(txCopy.TxIn[0].Witness),
+			spew.Sdump(witnessTx.TxIn[0].Witness))
+	}
+
+	// Ensure a witness serialization without any witness data is rejected.
+	emptyWitness := append([]byte{}, witnessEncoded[:len(witnessEncoded)-10]...)
+	emptyWitness = append(emptyWitness, 0x00) // No witness items
+	emptyWitness = append(emptyWitness, 0x00, 0x00, 0x00, 0x00)
+	err = tx.Deserialize(bytes.NewReader(emptyWitness))
+	if _, ok := err.(*wire.MessageError); !ok {
+		t.Errorf("Deserialize: did not reject empty witness - got "+
+			"%v", err)
+	}
+}
+
 // TestTxWire tests the MsgTx wire encode and decode for various numbers
 // of transaction inputs and outputs and protocol versions.
 func TestTxWire(t *testing.T) {
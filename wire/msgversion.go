@@ -115,11 +115,9 @@ func (msg *MsgVersion) BtcDecode(r io.Reader, pver uint32) error {
 		}
 	}
 	if buf.Len() > 0 {
-		userAgent, err := ReadVarString(buf, pver)
-		if err != nil {
-			return err
-		}
-		err = validateUserAgent(userAgent)
+		// Reject overly long user agents before reading them.
+		userAgent, err := readVarString(buf, pver, MaxUserAgentLen,
+			"user agent")
 		if err != nil {
 			return err
 		}
@@ -269,7 +267,7 @@ func validateUserAgent(userAgent string) error {
 	if len(userAgent) > MaxUserAgentLen {
 		str := fmt.Sprintf("user agent too long [len %v, max %v]",
 			len(userAgent), MaxUserAgentLen)
-		return messageError("MsgVersion", str)
+		return limitError("MsgVersion", ErrElementTooLarge, str)
 	}
 	return nil
 }
@@ -58,6 +58,14 @@ const (
 	// SFNodeBloom is a flag used to indiciate a peer supports bloom
 	// filtering.
 	SFNodeBloom
+
+	// SFNodeWitness is a flag used to indicate a peer supports blocks and
+	// transactions including witness data (BIP0144).
+	SFNodeWitness
+
+	// SFNodeCF is a flag used to indicate a peer supports committed
+	// block filters (BIP0157).
+	SFNodeCF ServiceFlag = 1 << 6
 )
 
 // Map of service flags back to their constant names for pretty printing.
@@ -65,6 +73,8 @@ var sfStrings = map[ServiceFlag]string{
 	SFNodeNetwork: "SFNodeNetwork",
 	SFNodeGetUTXO: "SFNodeGetUTXO",
 	SFNodeBloom:   "SFNodeBloom",
+	SFNodeWitness: "SFNodeWitness",
+	SFNodeCF:      "SFNodeCF",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -73,6 +83,8 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeNetwork,
 	SFNodeGetUTXO,
 	SFNodeBloom,
+	SFNodeWitness,
+	SFNodeCF,
 }
 
 // String returns the ServiceFlag in human-readable form.
@@ -20,7 +20,9 @@ func TestServiceFlagStringer(t *testing.T) {
 		{wire.SFNodeNetwork, "SFNodeNetwork"},
 		{wire.SFNodeGetUTXO, "SFNodeGetUTXO"},
 		{wire.SFNodeBloom, "SFNodeBloom"},
-		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|0xfffffff8"},
+		{wire.SFNodeWitness, "SFNodeWitness"},
+		{wire.SFNodeCF, "SFNodeCF"},
+		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|SFNodeWitness|SFNodeCF|0xffffffb0"},
 	}
 
 	t.Logf("Running %d tests", len(tests))